@@ -37,6 +37,9 @@ const _ = grpc.SupportPackageIsVersion7
 type BundleAPIClient interface {
 	// GetSecret returns the matching RAW secret value according to requested path.
 	GetSecret(ctx context.Context, in *GetSecretRequest, opts ...grpc.CallOption) (*GetSecretResponse, error)
+	// WatchPackages streams package change events of a namespace. The first
+	// event is a synthetic sync event carrying the current package digests.
+	WatchPackages(ctx context.Context, in *WatchPackagesRequest, opts ...grpc.CallOption) (BundleAPI_WatchPackagesClient, error)
 }
 
 type bundleAPIClient struct {
@@ -56,12 +59,47 @@ func (c *bundleAPIClient) GetSecret(ctx context.Context, in *GetSecretRequest, o
 	return out, nil
 }
 
+func (c *bundleAPIClient) WatchPackages(ctx context.Context, in *WatchPackagesRequest, opts ...grpc.CallOption) (BundleAPI_WatchPackagesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_BundleAPI_serviceDesc.Streams[0], "/harp.bundle.v1.BundleAPI/WatchPackages", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &bundleAPIWatchPackagesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type BundleAPI_WatchPackagesClient interface {
+	Recv() (*WatchPackagesResponse, error)
+	grpc.ClientStream
+}
+
+type bundleAPIWatchPackagesClient struct {
+	grpc.ClientStream
+}
+
+func (x *bundleAPIWatchPackagesClient) Recv() (*WatchPackagesResponse, error) {
+	m := new(WatchPackagesResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // BundleAPIServer is the server API for BundleAPI service.
 // All implementations must embed UnimplementedBundleAPIServer
 // for forward compatibility
 type BundleAPIServer interface {
 	// GetSecret returns the matching RAW secret value according to requested path.
 	GetSecret(context.Context, *GetSecretRequest) (*GetSecretResponse, error)
+	// WatchPackages streams package change events of a namespace. The first
+	// event is a synthetic sync event carrying the current package digests.
+	WatchPackages(*WatchPackagesRequest, BundleAPI_WatchPackagesServer) error
 	mustEmbedUnimplementedBundleAPIServer()
 }
 
@@ -72,6 +110,9 @@ type UnimplementedBundleAPIServer struct {
 func (UnimplementedBundleAPIServer) GetSecret(context.Context, *GetSecretRequest) (*GetSecretResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetSecret not implemented")
 }
+func (UnimplementedBundleAPIServer) WatchPackages(*WatchPackagesRequest, BundleAPI_WatchPackagesServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchPackages not implemented")
+}
 func (UnimplementedBundleAPIServer) mustEmbedUnimplementedBundleAPIServer() {}
 
 // UnsafeBundleAPIServer may be embedded to opt out of forward compatibility for this service.
@@ -103,6 +144,27 @@ func _BundleAPI_GetSecret_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _BundleAPI_WatchPackages_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchPackagesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BundleAPIServer).WatchPackages(m, &bundleAPIWatchPackagesServer{stream})
+}
+
+type BundleAPI_WatchPackagesServer interface {
+	Send(*WatchPackagesResponse) error
+	grpc.ServerStream
+}
+
+type bundleAPIWatchPackagesServer struct {
+	grpc.ServerStream
+}
+
+func (x *bundleAPIWatchPackagesServer) Send(m *WatchPackagesResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 var _BundleAPI_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "harp.bundle.v1.BundleAPI",
 	HandlerType: (*BundleAPIServer)(nil),
@@ -112,6 +174,12 @@ var _BundleAPI_serviceDesc = grpc.ServiceDesc{
 			Handler:    _BundleAPI_GetSecret_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchPackages",
+			Handler:       _BundleAPI_WatchPackages_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "harp/bundle/v1/bundle_api.proto",
 }
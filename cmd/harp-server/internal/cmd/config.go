@@ -0,0 +1,85 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	iconfig "github.com/elastic/harp/cmd/harp-server/internal/config"
+	"github.com/elastic/harp/pkg/sdk/config"
+	"github.com/elastic/harp/pkg/sdk/log"
+)
+
+var configValidateFile string
+
+// -----------------------------------------------------------------------------
+
+var configValidateCmd = func() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "validate",
+		Short:   "Validate a configuration file",
+		Example: `$ harp-server config validate -f config.yaml`,
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := cmd.Context()
+
+			_, err := iconfig.Load(configValidateFile)
+			if err != nil {
+				var verr *config.ValidationError
+				if errors.As(err, &verr) {
+					fmt.Fprintln(cmd.ErrOrStderr(), verr.Error())
+					log.For(ctx).Fatal("Invalid configuration", zap.Int("problems", len(verr.Problems)))
+				}
+				log.For(ctx).Fatal("Unable to load configuration", zap.Error(err))
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "config: '%s' is valid\n", configValidateFile)
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVarP(&configValidateFile, "file", "f", "", "Configuration file to validate")
+	log.CheckErr("unable to mark 'file' flag as required.", cmd.MarkFlagRequired("file"))
+
+	return cmd
+}
+
+// -----------------------------------------------------------------------------
+
+// validateConfig resolves secret references and checks the effective
+// configuration before starting listeners.
+func validateConfig() {
+	var doc *config.Document
+	if cfgFile != "" {
+		var err error
+		doc, err = config.ParseFile(cfgFile)
+		if err != nil {
+			log.Bg().Fatal("Unable to parse settings", zap.Error(err))
+		}
+	}
+
+	if err := conf.Resolve(cfgFile, doc, nil); err != nil {
+		log.Bg().Fatal("Unable to resolve settings", zap.Error(err))
+	}
+	if err := conf.Validate(cfgFile, doc); err != nil {
+		log.Bg().Fatal("Invalid settings", zap.Error(err))
+	}
+}
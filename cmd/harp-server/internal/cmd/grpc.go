@@ -31,7 +31,11 @@ import (
 	"github.com/elastic/harp/pkg/sdk/platform"
 )
 
-var grpcNamespaces []string
+var (
+	grpcNamespaces      []string
+	grpcOverlays        []string
+	grpcExpiredPackages string
+)
 
 // -----------------------------------------------------------------------------
 
@@ -44,6 +48,8 @@ var grpcCmd = func() *cobra.Command {
 
 	// Parameters
 	cmd.Flags().StringSliceVarP(&grpcNamespaces, "namespace", "n", nil, "namespace mapping (ns:url)")
+	cmd.Flags().StringSliceVar(&grpcOverlays, "overlay", nil, "local overlay container mapping (ns:path)")
+	cmd.Flags().StringVar(&grpcExpiredPackages, "expired-packages", "", "expired package policy applied when loading containers (keep, drop, error)")
 	log.CheckErr("unable to mark 'namespace' flag as required.", cmd.MarkFlagRequired("namespace"))
 
 	return cmd
@@ -68,11 +74,22 @@ func runGRPCServer(cmd *cobra.Command, args []string) {
 		Instrumentation: conf.Instrumentation,
 		Network:         conf.GRPC.Network,
 		Address:         conf.GRPC.Listen,
+		GracePeriod:     conf.Shutdown.Duration(),
+		Cleanup:         cleanup,
 		Builder: func(ln net.Listener, group *run.Group) {
 			// Override config
 			if err := overrideBackendConfig(conf, grpcNamespaces); err != nil {
 				log.For(ctx).Fatal("Unable to parse backend mapping", zap.Error(err))
 			}
+			if err := overrideBackendOverlay(conf, grpcOverlays); err != nil {
+				log.For(ctx).Fatal("Unable to parse overlay mapping", zap.Error(err))
+			}
+			if grpcExpiredPackages != "" {
+				conf.Bundle.ExpiredPackages = grpcExpiredPackages
+			}
+
+			// Validate settings
+			validateConfig()
 
 			server, err := grpc.New(ctx, conf)
 			if err != nil {
@@ -86,7 +103,19 @@ func runGRPCServer(cmd *cobra.Command, args []string) {
 				},
 				func(e error) {
 					log.For(ctx).Info("Shutting gRPC server down")
-					server.GracefulStop()
+					err := platform.Drain(ctx, conf.Shutdown.Duration(),
+						func(context.Context) error {
+							server.GracefulStop()
+							return nil
+						},
+						func() error {
+							server.Stop()
+							return nil
+						},
+					)
+					if err != nil {
+						log.For(ctx).Error("Unable to drain in-flight requests", zap.Error(err))
+					}
 				},
 			)
 		},
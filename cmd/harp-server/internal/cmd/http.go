@@ -21,8 +21,8 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"net/url"
 	"strings"
-	"time"
 
 	"github.com/oklog/run"
 	"github.com/spf13/cobra"
@@ -35,7 +35,11 @@ import (
 	"github.com/elastic/harp/pkg/sdk/platform"
 )
 
-var httpNamespaces []string
+var (
+	httpNamespaces      []string
+	httpOverlays        []string
+	httpExpiredPackages string
+)
 
 // -----------------------------------------------------------------------------
 
@@ -49,6 +53,8 @@ var httpCmd = func() *cobra.Command {
 	// Parameters
 	cmd.Flags().StringSliceVarP(&httpNamespaces, "namespace", "n", nil, "namespace mapping (ns:url)")
 	log.CheckErr("unable to mark 'namespace' flag as required.", cmd.MarkFlagRequired("namespace"))
+	cmd.Flags().StringSliceVar(&httpOverlays, "overlay", nil, "local overlay container mapping (ns:path)")
+	cmd.Flags().StringVar(&httpExpiredPackages, "expired-packages", "", "expired package policy applied when loading containers (keep, drop, error)")
 
 	return cmd
 }
@@ -72,11 +78,22 @@ func runHTTPServer(cmd *cobra.Command, args []string) {
 		Instrumentation: conf.Instrumentation,
 		Network:         conf.HTTP.Network,
 		Address:         conf.HTTP.Listen,
+		GracePeriod:     conf.Shutdown.Duration(),
+		Cleanup:         cleanup,
 		Builder: func(ln net.Listener, group *run.Group) {
 			// Override config
 			if err := overrideBackendConfig(conf, httpNamespaces); err != nil {
 				log.For(ctx).Fatal("Unable to parse namespace mapping", zap.Error(err))
 			}
+			if err := overrideBackendOverlay(conf, httpOverlays); err != nil {
+				log.For(ctx).Fatal("Unable to parse overlay mapping", zap.Error(err))
+			}
+			if httpExpiredPackages != "" {
+				conf.Bundle.ExpiredPackages = httpExpiredPackages
+			}
+
+			// Validate settings
+			validateConfig()
 
 			server, err := http.New(ctx, conf)
 			if err != nil {
@@ -96,10 +113,8 @@ func runHTTPServer(cmd *cobra.Command, args []string) {
 				func(e error) {
 					log.For(ctx).Info("Shutting HTTP server down")
 
-					shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
-					defer cancel()
-					if err := server.Shutdown(shutdownCtx); err != nil {
-						log.For(ctx).Fatal("Server Shutdown Failed", zap.Error(err))
+					if err := platform.DrainHTTP(ctx, server, conf.Shutdown.Duration()); err != nil {
+						log.For(ctx).Error("Unable to drain in-flight requests", zap.Error(err))
 					}
 				},
 			)
@@ -139,3 +154,46 @@ func overrideBackendConfig(cfg *config.Configuration, backends []string) error {
 
 	return nil
 }
+
+func overrideBackendOverlay(cfg *config.Configuration, overlays []string) error {
+	// Parse overlay mapping declaration
+	for _, decl := range overlays {
+		parts := strings.SplitN(decl, ":", 2)
+
+		// Mapping must have 2 parts
+		if len(parts) != 2 {
+			return fmt.Errorf("unable to parse overlay declaration, invalid part count")
+		}
+
+		ns := parts[0]
+		overlayPath := parts[1]
+
+		found := false
+		for i, b := range cfg.Backends {
+			if b.NS != ns {
+				continue
+			}
+
+			// Add overlay to backend URL
+			u, err := url.Parse(b.URL)
+			if err != nil {
+				return fmt.Errorf("unable to parse backend '%s' URL: %w", ns, err)
+			}
+			if !strings.HasPrefix(u.Scheme, "bundle") {
+				return fmt.Errorf("overlay is only supported by bundle backends, '%s' uses '%s'", ns, u.Scheme)
+			}
+			q := u.Query()
+			q.Set("overlay", overlayPath)
+			u.RawQuery = q.Encode()
+			cfg.Backends[i].URL = u.String()
+			found = true
+
+			log.Bg().Debug("Backend overlay", zap.String("ns", ns), zap.String("path", overlayPath))
+		}
+		if !found {
+			return fmt.Errorf("unable to apply overlay, namespace '%s' is not declared", ns)
+		}
+	}
+
+	return nil
+}
@@ -18,6 +18,9 @@
 package cmd
 
 import (
+	"context"
+
+	"github.com/awnumar/memguard"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 
@@ -53,7 +56,10 @@ var mainCmd = func() *cobra.Command {
 
 	// Register sub commands
 	cmd.AddCommand(version.Command())
-	cmd.AddCommand(configcmd.NewConfigCommand(conf, envPrefix))
+
+	configCmd := configcmd.NewConfigCommand(conf, envPrefix)
+	configCmd.AddCommand(configValidateCmd())
+	cmd.AddCommand(configCmd)
 
 	cmd.AddCommand(httpCmd())
 	cmd.AddCommand(vaultCmd())
@@ -82,3 +88,11 @@ func initConfig() {
 		log.Bg().Fatal("Unable to load settings", zap.Error(err))
 	}
 }
+
+// cleanup releases process resources once all listeners are drained.
+func cleanup(ctx context.Context) {
+	log.For(ctx).Info("Wiping protected memory buffers")
+
+	// Destroy all guarded buffers holding unsealed keys
+	memguard.Purge()
+}
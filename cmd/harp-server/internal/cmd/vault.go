@@ -20,7 +20,6 @@ package cmd
 import (
 	"context"
 	"net"
-	"time"
 
 	"github.com/oklog/run"
 	"github.com/spf13/cobra"
@@ -32,7 +31,11 @@ import (
 	"github.com/elastic/harp/pkg/sdk/platform"
 )
 
-var vaultNamespaces []string
+var (
+	vaultNamespaces      []string
+	vaultOverlays        []string
+	vaultExpiredPackages string
+)
 
 // -----------------------------------------------------------------------------
 
@@ -45,6 +48,8 @@ var vaultCmd = func() *cobra.Command {
 
 	// Parameters
 	cmd.Flags().StringSliceVarP(&vaultNamespaces, "namespace", "n", nil, "namespace mapping (ns:url)")
+	cmd.Flags().StringSliceVar(&vaultOverlays, "overlay", nil, "local overlay container mapping (ns:path)")
+	cmd.Flags().StringVar(&vaultExpiredPackages, "expired-packages", "", "expired package policy applied when loading containers (keep, drop, error)")
 	log.CheckErr("unable to mark 'namespace' flag as required.", cmd.MarkFlagRequired("namespace"))
 
 	return cmd
@@ -69,11 +74,22 @@ func runVaultServer(cmd *cobra.Command, args []string) {
 		Instrumentation: conf.Instrumentation,
 		Network:         conf.Vault.Network,
 		Address:         conf.Vault.Listen,
+		GracePeriod:     conf.Shutdown.Duration(),
+		Cleanup:         cleanup,
 		Builder: func(ln net.Listener, group *run.Group) {
 			// Override config
 			if err := overrideBackendConfig(conf, vaultNamespaces); err != nil {
 				log.For(ctx).Fatal("Unable to parse backend mapping", zap.Error(err))
 			}
+			if err := overrideBackendOverlay(conf, vaultOverlays); err != nil {
+				log.For(ctx).Fatal("Unable to parse overlay mapping", zap.Error(err))
+			}
+			if vaultExpiredPackages != "" {
+				conf.Bundle.ExpiredPackages = vaultExpiredPackages
+			}
+
+			// Validate settings
+			validateConfig()
 
 			server, err := vault.New(ctx, conf)
 			if err != nil {
@@ -93,10 +109,8 @@ func runVaultServer(cmd *cobra.Command, args []string) {
 				func(e error) {
 					log.For(ctx).Info("Shutting Vault API server down")
 
-					shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
-					defer cancel()
-					if err := server.Shutdown(shutdownCtx); err != nil {
-						log.For(ctx).Fatal("Server Shutdown Failed", zap.Error(err))
+					if err := platform.DrainHTTP(ctx, server, conf.Shutdown.Duration()); err != nil {
+						log.For(ctx).Error("Unable to drain in-flight requests", zap.Error(err))
 					}
 				},
 			)
@@ -36,7 +36,9 @@ type Configuration struct {
 			PrivateKeyPath               string `toml:"privateKeyPath" default:"" comment:"Private Key path"`
 			CACertificatePath            string `toml:"caCertificatePath" default:"" comment:"CA Certificate Path"`
 			ClientAuthenticationRequired bool   `toml:"clientAuthenticationRequired" default:"false" comment:"Force client authentication"`
+			WorkloadAPISocket            string `toml:"workloadAPISocket" default:"" comment:"SPIRE Workload API socket used to fetch rotated client trust bundles instead of caCertificatePath (ex: unix:///run/spire/sockets/agent.sock)"`
 		} `toml:"TLS" comment:"TLS Socket settings"`
+		Admin Admin `toml:"Admin" comment:"Admin API settings"`
 	} `toml:"HTTP" comment:"###############################\n HTTP Settings \n##############################"`
 	Vault struct {
 		Network string `toml:"network" default:"tcp" comment:"Network class used for listen (tcp, tcp4, tcp6, unixsocket)"`
@@ -47,6 +49,7 @@ type Configuration struct {
 			PrivateKeyPath               string `toml:"privateKeyPath" default:"" comment:"Private Key path"`
 			CACertificatePath            string `toml:"caCertificatePath" default:"" comment:"CA Certificate Path"`
 			ClientAuthenticationRequired bool   `toml:"clientAuthenticationRequired" default:"false" comment:"Force client authentication"`
+			WorkloadAPISocket            string `toml:"workloadAPISocket" default:"" comment:"SPIRE Workload API socket used to fetch rotated client trust bundles instead of caCertificatePath (ex: unix:///run/spire/sockets/agent.sock)"`
 		} `toml:"TLS" comment:"TLS Socket settings"`
 	} `toml:"Vault" comment:"###############################\n Vault Settings \n##############################"`
 	GRPC struct {
@@ -58,16 +61,91 @@ type Configuration struct {
 			PrivateKeyPath               string `toml:"privateKeyPath" default:"" comment:"Private Key path"`
 			CACertificatePath            string `toml:"caCertificatePath" default:"" comment:"CA Certificate Path"`
 			ClientAuthenticationRequired bool   `toml:"clientAuthenticationRequired" default:"false" comment:"Force client authentication"`
+			WorkloadAPISocket            string `toml:"workloadAPISocket" default:"" comment:"SPIRE Workload API socket used to fetch rotated client trust bundles instead of caCertificatePath (ex: unix:///run/spire/sockets/agent.sock)"`
 		} `toml:"TLS" comment:"TLS Socket settings"`
+		Watch struct {
+			StallTimeout string `toml:"stallTimeout" default:"30s" comment:"Watch consumers not reading pending changes for this duration are disconnected"`
+		} `toml:"Watch" comment:"Package watch settings"`
 	} `toml:"gRPC" comment:"###############################\n gRPC Settings \n##############################"`
 
+	Shutdown Shutdown `toml:"Shutdown" comment:"###############################\n Shutdown \n##############################"`
+
 	Backends []Backend `toml:"Backends" default:"" comment:"###############################\n Backends \n##############################"`
 
-	Keyring []string `toml:"Keyring" default:"" comment:"###############################\n Container Keyring \n##############################"`
+	Templates []Template `toml:"Templates" default:"" comment:"###############################\n Rendered templates \n##############################"`
+
+	Keyring []string `toml:"Keyring" default:"" sensitive:"true" comment:"###############################\n Container Keyring \n##############################"`
+
+	Bundle struct {
+		ExpiredPackages        string `toml:"expiredPackages" default:"keep" comment:"Expired package policy applied when loading containers (keep, drop, error)"`
+		ValueDecryptionKey     string `toml:"valueDecryptionKey" comment:"AES-GCM key used to decrypt externally encrypted values ('enc:aes-gcm:...') when loading containers"`
+		ValueDecryptionFailure string `toml:"valueDecryptionFailure" default:"error" comment:"Value decryption failure policy (error, hide)"`
+		Mmap                   bool   `toml:"mmap" default:"false" comment:"Load containers through a memory-mapped shared cache to share memory between processes"`
+		SharedCacheDir         string `toml:"sharedCacheDir" default:"" comment:"Shared cache directory used by memory-mapped loading, defaults to /dev/shm when tmpfs-backed"`
+	} `toml:"Bundle" comment:"###############################\n Bundle loading \n##############################"`
+
+	Replication Replication `toml:"Replication" comment:"###############################\n Warm standby replication \n##############################"`
+
+	Secrets struct {
+		AllowWorldReadableFiles bool `toml:"allowWorldReadableFiles" default:"false" comment:"Allow file:// references to world-readable files"`
+	} `toml:"Secrets" comment:"###############################\n Secret references \n##############################"`
+}
+
+// MinAdminKeySize is the minimal shared admin key size in bytes.
+const MinAdminKeySize = 16
+
+// Admin represents admin API settings
+type Admin struct {
+	Enabled   bool   `toml:"enabled" default:"false" comment:"Expose the admin API (/admin/v1), requests must be HMAC signed"`
+	Key       string `toml:"key" default:"" sensitive:"true" comment:"Shared HMAC signing key, usually given as file:// or vault:// reference"`
+	KeyPath   string `toml:"keyPath" default:"" comment:"Shared HMAC signing key file path, used when key is blank"`
+	Skew      string `toml:"skew" default:"5m" comment:"Maximum allowed clock difference between clients and server"`
+	MaxNonces int    `toml:"maxNonces" default:"10000" comment:"Maximum count of tracked request nonces"`
+}
+
+// Shutdown represents graceful shutdown settings
+type Shutdown struct {
+	GracePeriod string `toml:"gracePeriod" default:"30s" comment:"Maximum duration allowed to drain in-flight requests before closing remaining connections"`
 }
 
 // Backend represents backend mapping settings
 type Backend struct {
 	NS  string `toml:"ns" default:"" comment:"Backend mount namespace"`
 	URL string `toml:"url" default:"" comment:"Backend settings url"`
+
+	Transformations []Transformation `toml:"transformations" default:"" comment:"Response transformations applied per client identity"`
+
+	Cache Cache `toml:"cache" comment:"Read cache settings"`
+
+	ReloadInterval string `toml:"reloadInterval" default:"" comment:"Namespace reload interval used to serve updated containers and notify package watchers, reload is disabled when blank (ex: 1m)"`
+
+	AllowedSPIFFEIDs []string `toml:"allowedSpiffeIDs" default:"" comment:"Allowed client SPIFFE ID patterns (exact, '/*' suffixed prefix, or trust domain only), all clients are allowed when empty"`
+}
+
+// Cache represents backend read cache settings
+type Cache struct {
+	TTL        string `toml:"ttl" default:"" comment:"Cached response lifetime, cache is disabled when blank (ex: 30s)"`
+	MaxEntries int    `toml:"maxEntries" default:"1024" comment:"Maximum number of cached responses"`
+}
+
+// Transformation represents a response transformation profile
+type Transformation struct {
+	Profile string      `toml:"profile" default:"" comment:"Profile name recorded in audit logs"`
+	Clients []string    `toml:"clients" default:"" comment:"Client identity matchers (certificate common name glob)"`
+	Fields  []FieldRule `toml:"fields" default:"" comment:"Field transformation rules"`
+}
+
+// FieldRule represents a secret field transformation
+type FieldRule struct {
+	Field  string `toml:"field" default:"" comment:"Secret field name"`
+	Action string `toml:"action" default:"" comment:"Applied action (drop, mask, hash)"`
+}
+
+// Template represents server-side rendered template settings
+type Template struct {
+	NS      string `toml:"ns" default:"" comment:"Backend namespace used to resolve secrets"`
+	Name    string `toml:"name" default:"" comment:"Template name exposed in the URL"`
+	Path    string `toml:"path" default:"" comment:"Template file path"`
+	Format  string `toml:"format" default:"json" comment:"Rendered content format (json, yaml, properties)"`
+	Timeout string `toml:"timeout" default:"5s" comment:"Rendering timeout"`
 }
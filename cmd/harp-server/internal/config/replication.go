@@ -0,0 +1,135 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package config
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gosimple/slug"
+
+	"github.com/elastic/harp/pkg/sdk/security/crypto"
+)
+
+// Replication roles
+const (
+	// ReplicationPrimary exposes the signed namespace snapshot.
+	ReplicationPrimary = "primary"
+	// ReplicationReplica polls the primary snapshot.
+	ReplicationReplica = "replica"
+)
+
+// defaultReplicationInterval is the replica polling interval used when blank.
+const defaultReplicationInterval = 30 * time.Second
+
+// Replication represents warm standby replication settings
+type Replication struct {
+	Role                string `toml:"role" default:"" comment:"Replication role (primary, replica), replication is disabled when blank"`
+	SigningKey          string `toml:"signingKey" default:"" sensitive:"true" comment:"Primary: Ed25519 snapshot signing key (PEM or JWK), usually given as file:// or vault:// reference"`
+	Primary             string `toml:"primary" default:"" comment:"Replica: primary snapshot URL (ex: https://harp-primary:8080/admin/v1/replication/snapshot)"`
+	VerificationKeyPath string `toml:"verificationKeyPath" default:"" comment:"Replica: primary Ed25519 snapshot verification key file path (PEM or JWK)"`
+	AdminKey            string `toml:"adminKey" default:"" sensitive:"true" comment:"Replica: primary admin API key used to sign snapshot requests"`
+	CACertificatePath   string `toml:"caCertificatePath" default:"" comment:"Replica: CA certificate path used to verify the primary, system roots are used when blank"`
+	Interval            string `toml:"interval" default:"30s" comment:"Replica: primary polling interval"`
+}
+
+// PollInterval returns the replica polling interval, it falls back to the
+// default when blank or invalid.
+func (r *Replication) PollInterval() time.Duration {
+	d, err := time.ParseDuration(r.Interval)
+	if err != nil || d <= 0 {
+		return defaultReplicationInterval
+	}
+
+	return d
+}
+
+// RequestKey returns the primary admin API key used to sign snapshot
+// requests, surrounding whitespaces are ignored as done by the admin API.
+func (r *Replication) RequestKey() []byte {
+	return bytes.TrimSpace([]byte(r.AdminKey))
+}
+
+// SnapshotSigningKey decodes the primary snapshot signing key.
+func (r *Replication) SnapshotSigningKey() (ed25519.PrivateKey, error) {
+	signer, err := crypto.ParseSigningKey([]byte(r.SigningKey))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode snapshot signing key: %w", err)
+	}
+
+	key, ok := signer.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("snapshot signing key must be an Ed25519 key, got %T", signer)
+	}
+
+	return key, nil
+}
+
+// SnapshotVerificationKey reads the primary snapshot verification key.
+func (r *Replication) SnapshotVerificationKey() (ed25519.PublicKey, error) {
+	raw, err := ioutil.ReadFile(r.VerificationKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read snapshot verification key: %w", err)
+	}
+	pub, err := crypto.ParseVerificationKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode snapshot verification key: %w", err)
+	}
+
+	key, ok := pub.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("snapshot verification key must be an Ed25519 key, got %T", pub)
+	}
+
+	return key, nil
+}
+
+// ReplicatedNamespaces returns the backend URL of namespaces served from a
+// container, indexed by namespace name.
+func (c *Configuration) ReplicatedNamespaces() map[string]string {
+	out := map[string]string{}
+	for _, b := range c.Backends {
+		u, err := url.Parse(b.URL)
+		if err != nil || !strings.HasPrefix(u.Scheme, "bundle") || u.Scheme == "bundle+stdin" {
+			continue
+		}
+		out[slug.Make(strings.TrimPrefix(b.NS, "/"))] = b.URL
+	}
+
+	return out
+}
+
+// ReplicaTargets returns the local container path of namespaces served from a
+// container file, indexed by namespace name.
+func (c *Configuration) ReplicaTargets() map[string]string {
+	out := map[string]string{}
+	for _, b := range c.Backends {
+		u, err := url.Parse(b.URL)
+		if err != nil || (u.Scheme != "bundle" && u.Scheme != "bundle+file") || u.Path == "" {
+			continue
+		}
+		out[slug.Make(strings.TrimPrefix(b.NS, "/"))] = u.Path
+	}
+
+	return out
+}
@@ -0,0 +1,214 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+
+	"github.com/elastic/harp/pkg/sdk/config"
+	"github.com/elastic/harp/pkg/vault/kv"
+)
+
+const (
+	fileRefPrefix  = "file://"
+	vaultRefPrefix = "vault://"
+)
+
+// envRegexp matches '${NAME}' variables, '$${NAME}' is an escaped literal.
+var envRegexp = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// SecretGetter retrieves the secret data stored at the given Vault path.
+type SecretGetter func(path string) (map[string]interface{}, error)
+
+// Resolver expands environment variables of all configuration values and
+// resolves 'file://' and 'vault://' references of sensitive ones.
+type Resolver struct {
+	// LookupEnv returns environment variable values, os.LookupEnv is used
+	// when nil.
+	LookupEnv func(key string) (string, bool)
+	// Vault retrieves Vault secrets, a client initialized from VAULT_*
+	// environment variables is used when nil.
+	Vault SecretGetter
+}
+
+// Resolve expands environment variables and secret references in place. All
+// undefined variables and unresolvable references are returned as a single
+// error. The optional document is used to report file line numbers.
+func (c *Configuration) Resolve(cfgFile string, doc *config.Document, res *Resolver) error {
+	if res == nil {
+		res = &Resolver{}
+	}
+	r := config.NewReport(cfgFile, doc)
+
+	// Expand environment variables first, references may use them.
+	walkStrings(reflect.ValueOf(c).Elem(), "", false, func(path string, v reflect.Value, _ bool) {
+		v.SetString(res.expand(r, path, v.String()))
+	})
+	if err := r.Err(); err != nil {
+		return err
+	}
+
+	// Resolve references of sensitive values
+	walkStrings(reflect.ValueOf(c).Elem(), "", false, func(path string, v reflect.Value, sensitive bool) {
+		if !sensitive {
+			return
+		}
+		value, err := res.resolve(v.String(), c.Secrets.AllowWorldReadableFiles)
+		if err != nil {
+			r.Add(path, "%v", err)
+			return
+		}
+		v.SetString(value)
+	})
+
+	return r.Err()
+}
+
+// -----------------------------------------------------------------------------
+
+func (res *Resolver) expand(r *config.Report, path, value string) string {
+	lookup := res.LookupEnv
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+
+	return envRegexp.ReplaceAllStringFunc(value, func(m string) string {
+		// Escaped variable
+		if strings.HasPrefix(m, "$$") {
+			return m[1:]
+		}
+
+		name := m[2 : len(m)-1]
+		v, ok := lookup(name)
+		if !ok {
+			r.Add(path, "undefined environment variable '%s'", name)
+		}
+		return v
+	})
+}
+
+func (res *Resolver) resolve(value string, allowWorldReadable bool) (string, error) {
+	switch {
+	case strings.HasPrefix(value, fileRefPrefix):
+		return readFileRef(strings.TrimPrefix(value, fileRefPrefix), allowWorldReadable)
+	case strings.HasPrefix(value, vaultRefPrefix):
+		return res.readVaultRef(strings.TrimPrefix(value, vaultRefPrefix))
+	default:
+	}
+
+	// Not a reference
+	return value, nil
+}
+
+func readFileRef(path string, allowWorldReadable bool) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("file reference must declare a path")
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to access '%s': %w", path, err)
+	}
+	if fi.IsDir() {
+		return "", fmt.Errorf("'%s' is a directory", path)
+	}
+	if fi.Mode().Perm()&0o004 != 0 && !allowWorldReadable {
+		return "", fmt.Errorf("'%s' must not be world-readable (%s), restrict its permissions or enable Secrets.allowWorldReadableFiles", path, fi.Mode().Perm())
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read '%s': %w", path, err)
+	}
+
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+func (res *Resolver) readVaultRef(ref string) (string, error) {
+	// Split path and field
+	idx := strings.LastIndex(ref, "#")
+	if idx < 0 {
+		return "", fmt.Errorf("vault reference '%s' must declare a field as '<path>#<field>'", ref)
+	}
+	path, field := ref[:idx], ref[idx+1:]
+	if path == "" || field == "" {
+		return "", fmt.Errorf("vault reference '%s' must declare a field as '<path>#<field>'", ref)
+	}
+
+	// Initialize Vault connection on first use
+	if res.Vault == nil {
+		client, err := api.NewClient(api.DefaultConfig())
+		if err != nil {
+			return "", fmt.Errorf("unable to initialize vault connection: %w", err)
+		}
+		res.Vault = SecretGetter(kv.SecretGetter(client))
+	}
+
+	data, err := res.Vault(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read vault secret '%s': %w", path, err)
+	}
+	raw, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("vault secret '%s' has no '%s' field", path, field)
+	}
+	value, ok := raw.(string)
+	if !ok {
+		return "", fmt.Errorf("vault secret '%s' field '%s' is not a string", path, field)
+	}
+
+	return value, nil
+}
+
+// walkStrings calls fn for each string value of the given settings, using the
+// validation path of the value. Fields tagged 'sensitive:"true"' mark their
+// values as sensitive.
+func walkStrings(v reflect.Value, path string, sensitive bool, fn func(path string, v reflect.Value, sensitive bool)) {
+	switch v.Kind() {
+	case reflect.String:
+		fn(path, v, sensitive)
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			name := strings.Split(f.Tag.Get("toml"), ",")[0]
+			if name == "" {
+				name = f.Name
+			}
+			if path != "" {
+				name = path + "." + name
+			}
+			walkStrings(v.Field(i), name, sensitive || f.Tag.Get("sensitive") == "true", fn)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			walkStrings(v.Index(i), fmt.Sprintf("%s[%d]", path, i), sensitive, fn)
+		}
+	default:
+	}
+}
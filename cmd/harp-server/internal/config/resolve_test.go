@@ -0,0 +1,218 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/elastic/harp/pkg/sdk/config"
+)
+
+func testEnv(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+}
+
+func assertProblems(t *testing.T, err error, want ...string) {
+	t.Helper()
+
+	var verr *config.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	if len(verr.Problems) != len(want) {
+		t.Fatalf("expected %d problems, got %d:\n%s", len(want), len(verr.Problems), verr.Error())
+	}
+	for i, p := range verr.Problems {
+		if !strings.HasPrefix(p.String(), want[i]) {
+			t.Errorf("problem %d: expected prefix %q, got %q", i, want[i], p.String())
+		}
+	}
+}
+
+func TestResolve_Env(t *testing.T) {
+	c := &Configuration{}
+	c.HTTP.Listen = "${HOST}:${PORT}"
+	c.Backends = []Backend{{NS: "app", URL: "bundle:///${DATA_DIR}/app.bundle?literal=$${DATA_DIR}"}}
+	c.Keyring = []string{"${KEY}"}
+
+	err := c.Resolve("", nil, &Resolver{
+		LookupEnv: testEnv(map[string]string{
+			"HOST":     "",
+			"PORT":     "8443",
+			"DATA_DIR": "/var/lib/harp",
+			"KEY":      "harp-key",
+		}),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.HTTP.Listen != ":8443" {
+		t.Errorf("unexpected listen address %q", c.HTTP.Listen)
+	}
+	if c.Backends[0].URL != "bundle:////var/lib/harp/app.bundle?literal=${DATA_DIR}" {
+		t.Errorf("unexpected backend url %q", c.Backends[0].URL)
+	}
+	if c.Keyring[0] != "harp-key" {
+		t.Errorf("unexpected keyring %v", c.Keyring)
+	}
+}
+
+func TestResolve_StrictMissing(t *testing.T) {
+	path := writeConfig(t, `
+HTTP:
+  listen: ${HOST}:${PORT}
+Backends:
+  - ns: app
+    url: bundle:///${DATA_DIR}/app.bundle
+Keyring:
+  - vault://${MISSING_PATH}#key
+`)
+	c := &Configuration{}
+	doc, err := config.Decode(c, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vaultCalled := false
+	err = c.Resolve(path, doc, &Resolver{
+		LookupEnv: testEnv(map[string]string{"PORT": "8080"}),
+		Vault: func(string) (map[string]interface{}, error) {
+			vaultCalled = true
+			return nil, nil
+		},
+	})
+	assertProblems(t, err,
+		"line 3: HTTP.listen: undefined environment variable 'HOST'",
+		"line 6: Backends[0].url: undefined environment variable 'DATA_DIR'",
+		"line 8: Keyring[0]: undefined environment variable 'MISSING_PATH'",
+	)
+	if vaultCalled {
+		t.Error("references must not be resolved when variables are missing")
+	}
+}
+
+func TestResolve_File(t *testing.T) {
+	dir := t.TempDir()
+	private := filepath.Join(dir, "private.key")
+	if err := ioutil.WriteFile(private, []byte("private-key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	public := filepath.Join(dir, "public.key")
+	if err := ioutil.WriteFile(public, []byte("public-key"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	env := testEnv(map[string]string{"KEY_DIR": dir})
+
+	// Private file
+	c := &Configuration{}
+	c.Keyring = []string{"file://${KEY_DIR}/private.key", "inline-key"}
+	c.Backends = []Backend{{NS: "app", URL: "file://" + dir}}
+	if err := c.Resolve("", nil, &Resolver{LookupEnv: env}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Keyring[0] != "private-key" || c.Keyring[1] != "inline-key" {
+		t.Errorf("unexpected keyring %v", c.Keyring)
+	}
+	if c.Backends[0].URL != "file://"+dir {
+		t.Errorf("non sensitive values must not be resolved, got %q", c.Backends[0].URL)
+	}
+
+	// World-readable and missing files
+	c = &Configuration{}
+	c.Keyring = []string{"file://" + public}
+	c.HTTP.Admin.Key = "file://" + filepath.Join(dir, "missing.key")
+	err := c.Resolve("", nil, &Resolver{LookupEnv: env})
+	assertProblems(t, err,
+		"HTTP.Admin.key: unable to access",
+		"Keyring[0]: '"+public+"' must not be world-readable",
+	)
+
+	// Override
+	c = &Configuration{}
+	c.Keyring = []string{"file://" + public}
+	c.Secrets.AllowWorldReadableFiles = true
+	if err := c.Resolve("", nil, &Resolver{LookupEnv: env}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Keyring[0] != "public-key" {
+		t.Errorf("unexpected keyring %v", c.Keyring)
+	}
+}
+
+func TestResolve_Vault(t *testing.T) {
+	secrets := map[string]map[string]interface{}{
+		"secret/harp/server": {
+			"admin":   "admin-key",
+			"keyring": "harp-key",
+			"count":   2,
+		},
+	}
+	res := &Resolver{
+		LookupEnv: testEnv(map[string]string{"ENV": "harp"}),
+		Vault: func(path string) (map[string]interface{}, error) {
+			if path == "secret/failing" {
+				return nil, errors.New("permission denied")
+			}
+			return secrets[path], nil
+		},
+	}
+
+	c := &Configuration{}
+	c.HTTP.Admin.Key = "vault://secret/${ENV}/server#admin"
+	c.Keyring = []string{"vault://secret/harp/server#keyring"}
+	if err := c.Resolve("", nil, res); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.HTTP.Admin.Key != "admin-key" || c.Keyring[0] != "harp-key" {
+		t.Errorf("unexpected resolved values %q %v", c.HTTP.Admin.Key, c.Keyring)
+	}
+
+	c = &Configuration{}
+	c.Keyring = []string{
+		"vault://secret/harp/server",
+		"vault://secret/harp/server#missing",
+		"vault://secret/harp/server#count",
+		"vault://secret/failing#key",
+	}
+	err := c.Resolve("", nil, res)
+	assertProblems(t, err,
+		"Keyring[0]: vault reference 'secret/harp/server' must declare a field",
+		"Keyring[1]: vault secret 'secret/harp/server' has no 'missing' field",
+		"Keyring[2]: vault secret 'secret/harp/server' field 'count' is not a string",
+		"Keyring[3]: unable to read vault secret 'secret/failing': permission denied",
+	)
+}
+
+func TestLoad_ResolvedBeforeValidation(t *testing.T) {
+	t.Setenv("HARP_TEST_BACKEND_SCHEME", "ftp")
+
+	_, err := Load(writeConfig(t, `
+Backends:
+  - ns: secrets
+    url: ${HARP_TEST_BACKEND_SCHEME}://host/secrets
+`))
+	assertProblems(t, err, "line 4: Backends[0].url: unsupported backend type 'ftp'")
+}
@@ -0,0 +1,94 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/elastic/harp/pkg/server/storage"
+	"github.com/elastic/harp/pkg/server/storage/decorators/authz"
+	"github.com/elastic/harp/pkg/server/storage/decorators/cache"
+	"github.com/elastic/harp/pkg/server/storage/decorators/mask"
+)
+
+// Decorators returns the engine decorators built from backend settings.
+// Invalid cache settings, transformation profiles or SPIFFE ID patterns are
+// rejected.
+func (b *Backend) Decorators() ([]func(storage.Engine) storage.Engine, error) {
+	decorators := []func(storage.Engine) storage.Engine{}
+
+	// Cache raw engine responses, transformations are applied per request
+	if b.Cache.TTL != "" {
+		ttl, err := time.ParseDuration(b.Cache.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cache ttl for backend '%s': %w", b.NS, err)
+		}
+
+		d, err := cache.Decorator(b.NS, cache.Options{
+			TTL:        ttl,
+			MaxEntries: b.Cache.MaxEntries,
+		})
+		if err != nil {
+			return nil, err
+		}
+		decorators = append(decorators, d)
+	}
+
+	// Response transformations
+	if len(b.Transformations) > 0 {
+		d, err := b.transformer()
+		if err != nil {
+			return nil, err
+		}
+		decorators = append(decorators, d)
+	}
+
+	// Authorize clients before any cached response is served
+	if len(b.AllowedSPIFFEIDs) > 0 {
+		d, err := authz.SPIFFE(b.NS, b.AllowedSPIFFEIDs)
+		if err != nil {
+			return nil, err
+		}
+		decorators = append(decorators, d)
+	}
+
+	// No error
+	return decorators, nil
+}
+
+func (b *Backend) transformer() (func(storage.Engine) storage.Engine, error) {
+	// Convert profiles
+	profiles := make([]mask.Profile, 0, len(b.Transformations))
+	for _, t := range b.Transformations {
+		p := mask.Profile{
+			Name:    t.Profile,
+			Clients: t.Clients,
+		}
+		for _, f := range t.Fields {
+			p.Fields = append(p.Fields, mask.FieldRule{
+				Field:  f.Field,
+				Action: mask.Action(f.Action),
+			})
+		}
+		profiles = append(profiles, p)
+	}
+
+	// Build decorator
+	return mask.Transformer(b.NS, profiles)
+}
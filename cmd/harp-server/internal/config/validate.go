@@ -0,0 +1,358 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gosimple/slug"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/decryptor"
+	"github.com/elastic/harp/pkg/sdk/config"
+	"github.com/elastic/harp/pkg/server/spiffe"
+	"github.com/elastic/harp/pkg/server/storage"
+)
+
+// backendRule describes the URL parts required by a backend type.
+type backendRule struct {
+	host   bool
+	path   bool
+	params []string
+}
+
+var backendRules = map[string]backendRule{
+	"bundle":        {path: true},
+	"bundle+file":   {path: true},
+	"bundle+stdin":  {},
+	"bundle+http":   {host: true, path: true},
+	"bundle+https":  {host: true, path: true},
+	"bundle+s3":     {host: true, path: true},
+	"bundle+gcs":    {host: true, path: true},
+	"bundle+azblob": {host: true, path: true},
+	"file":          {path: true},
+	"vault":         {path: true},
+	"s3":            {host: true},
+	"gcs":           {host: true},
+	"azblob":        {host: true},
+	"hybrid":        {path: true, params: []string{"container"}},
+}
+
+// Load decodes the given configuration file and validates it.
+func Load(cfgFile string) (*Configuration, error) {
+	conf := &Configuration{}
+
+	// Decode the file
+	doc, err := config.Decode(conf, cfgFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve environment variables and secret references
+	if err := conf.Resolve(cfgFile, doc, nil); err != nil {
+		return nil, err
+	}
+
+	// Validate settings
+	if err := conf.Validate(cfgFile, doc); err != nil {
+		return nil, err
+	}
+
+	// No error
+	return conf, nil
+}
+
+// Validate checks the configuration and returns all detected problems as a
+// single error. The optional document is used to report unknown fields and
+// file line numbers.
+func (c *Configuration) Validate(cfgFile string, doc *config.Document) error {
+	r := config.NewReport(cfgFile, doc)
+
+	// Unknown fields
+	r.Merge(doc.UnknownFields(c)...)
+
+	// Listeners
+	validateTLS(r, "HTTP", c.HTTP.UseTLS, c.HTTP.TLS.CertificatePath, c.HTTP.TLS.PrivateKeyPath, c.HTTP.TLS.CACertificatePath, c.HTTP.TLS.ClientAuthenticationRequired, c.HTTP.TLS.WorkloadAPISocket)
+	validateTLS(r, "Vault", c.Vault.UseTLS, c.Vault.TLS.CertificatePath, c.Vault.TLS.PrivateKeyPath, c.Vault.TLS.CACertificatePath, c.Vault.TLS.ClientAuthenticationRequired, c.Vault.TLS.WorkloadAPISocket)
+	validateTLS(r, "gRPC", c.GRPC.UseTLS, c.GRPC.TLS.CertificatePath, c.GRPC.TLS.PrivateKeyPath, c.GRPC.TLS.CACertificatePath, c.GRPC.TLS.ClientAuthenticationRequired, c.GRPC.TLS.WorkloadAPISocket)
+
+	// Admin API
+	validateAdmin(r, &c.HTTP.Admin)
+
+	// Shutdown
+	if c.Shutdown.GracePeriod != "" {
+		if d, err := time.ParseDuration(c.Shutdown.GracePeriod); err != nil {
+			r.Add("Shutdown.gracePeriod", "invalid duration '%s'", c.Shutdown.GracePeriod)
+		} else if d <= 0 {
+			r.Add("Shutdown.gracePeriod", "must be positive")
+		}
+	}
+
+	// Watch
+	if c.GRPC.Watch.StallTimeout != "" {
+		if d, err := time.ParseDuration(c.GRPC.Watch.StallTimeout); err != nil {
+			r.Add("gRPC.Watch.stallTimeout", "invalid duration '%s'", c.GRPC.Watch.StallTimeout)
+		} else if d < 0 {
+			r.Add("gRPC.Watch.stallTimeout", "must not be negative")
+		}
+	}
+
+	// Bundle loading
+	if _, err := bundle.ParseExpiredPackagePolicy(c.Bundle.ExpiredPackages); err != nil {
+		r.Add("Bundle.expiredPackages", "invalid policy '%s', expected keep, drop or error", c.Bundle.ExpiredPackages)
+	}
+	if _, err := bundle.ParseDecryptFailurePolicy(c.Bundle.ValueDecryptionFailure); err != nil {
+		r.Add("Bundle.valueDecryptionFailure", "invalid policy '%s', expected error or hide", c.Bundle.ValueDecryptionFailure)
+	}
+	if c.Bundle.ValueDecryptionKey != "" {
+		if _, err := decryptor.AESGCM(c.Bundle.ValueDecryptionKey); err != nil {
+			r.Add("Bundle.valueDecryptionKey", "invalid AES-GCM key")
+		}
+	}
+	if c.Bundle.SharedCacheDir != "" && !c.Bundle.Mmap {
+		r.Add("Bundle.sharedCacheDir", "requires memory-mapped loading (mmap)")
+	}
+
+	// Backends
+	namespaces := map[string]int{}
+	for i := range c.Backends {
+		validateBackend(r, i, &c.Backends[i], namespaces)
+	}
+
+	// Replication
+	validateReplication(r, c)
+
+	return r.Err()
+}
+
+// -----------------------------------------------------------------------------
+
+func validateTLS(r *config.Report, section string, useTLS bool, certPath, keyPath, caPath string, clientAuth bool, workloadSocket string) {
+	if !useTLS {
+		return
+	}
+
+	validateFile(r, section+".TLS.certificatePath", certPath, true)
+	validateFile(r, section+".TLS.privateKeyPath", keyPath, true)
+
+	// Client trust bundle is fetched from SPIRE agent
+	if workloadSocket != "" {
+		if caPath != "" {
+			r.Add(section+".TLS.workloadAPISocket", "must not be used with caCertificatePath")
+		}
+		return
+	}
+	validateFile(r, section+".TLS.caCertificatePath", caPath, clientAuth)
+}
+
+func validateAdmin(r *config.Report, a *Admin) {
+	if !a.Enabled {
+		return
+	}
+
+	// Shared key is either given as value or file path
+	if a.Key == "" {
+		validateFile(r, "HTTP.Admin.keyPath", a.KeyPath, true)
+	} else if a.KeyPath != "" {
+		r.Add("HTTP.Admin.keyPath", "must not be used with key")
+	}
+	if a.Skew != "" {
+		if d, err := time.ParseDuration(a.Skew); err != nil {
+			r.Add("HTTP.Admin.skew", "invalid duration '%s'", a.Skew)
+		} else if d <= 0 {
+			r.Add("HTTP.Admin.skew", "must be positive")
+		}
+	}
+	if a.MaxNonces < 0 {
+		r.Add("HTTP.Admin.maxNonces", "must not be negative")
+	}
+}
+
+func validateReplication(r *config.Report, c *Configuration) {
+	rep := &c.Replication
+
+	switch rep.Role {
+	case "":
+	case ReplicationPrimary:
+		// Snapshot is exposed by the admin API
+		if !c.HTTP.Admin.Enabled {
+			r.Add("Replication.role", "primary role requires the admin API to be enabled")
+		}
+		if rep.SigningKey == "" {
+			r.Add("Replication.signingKey", "must not be blank")
+		} else if _, err := rep.SnapshotSigningKey(); err != nil {
+			r.Add("Replication.signingKey", "%v", err)
+		}
+		if len(c.ReplicatedNamespaces()) == 0 {
+			r.Add("Replication.role", "no container backend to replicate")
+		}
+	case ReplicationReplica:
+		if u, err := url.Parse(rep.Primary); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			r.Add("Replication.primary", "must be an http(s) URL")
+		}
+		validateFile(r, "Replication.verificationKeyPath", rep.VerificationKeyPath, true)
+		if rep.VerificationKeyPath != "" {
+			if _, err := rep.SnapshotVerificationKey(); err != nil {
+				r.Add("Replication.verificationKeyPath", "%v", err)
+			}
+		}
+		if len(rep.RequestKey()) < MinAdminKeySize {
+			r.Add("Replication.adminKey", "must be at least %d bytes long", MinAdminKeySize)
+		}
+		validateFile(r, "Replication.caCertificatePath", rep.CACertificatePath, false)
+		if rep.Interval != "" {
+			if d, err := time.ParseDuration(rep.Interval); err != nil {
+				r.Add("Replication.interval", "invalid duration '%s'", rep.Interval)
+			} else if d <= 0 {
+				r.Add("Replication.interval", "must be positive")
+			}
+		}
+		if len(c.ReplicaTargets()) == 0 {
+			r.Add("Replication.role", "replica role requires at least one container file backend")
+		}
+	default:
+		r.Add("Replication.role", "invalid role '%s', expected primary or replica", rep.Role)
+	}
+}
+
+func validateFile(r *config.Report, path, value string, required bool) {
+	if value == "" {
+		if required {
+			r.Add(path, "must not be blank")
+		}
+		return
+	}
+
+	fi, err := os.Stat(value)
+	switch {
+	case err != nil:
+		r.Add(path, "unable to access '%s': %v", value, err)
+	case fi.IsDir():
+		r.Add(path, "'%s' is a directory", value)
+	default:
+	}
+}
+
+func validateBackend(r *config.Report, idx int, b *Backend, namespaces map[string]int) {
+	path := fmt.Sprintf("Backends[%d]", idx)
+
+	// Namespace
+	if strings.TrimSpace(b.NS) == "" {
+		r.Add(path+".ns", "namespace must not be blank")
+	} else {
+		key := slug.Make(strings.TrimPrefix(b.NS, "/"))
+		if prev, ok := namespaces[key]; ok {
+			r.Add(path+".ns", "duplicate namespace '%s', already declared by Backends[%d]", b.NS, prev)
+		} else {
+			namespaces[key] = idx
+		}
+	}
+
+	// Backend URL
+	if strings.TrimSpace(b.URL) == "" {
+		r.Add(path+".url", "url must not be blank")
+	} else {
+		validateURL(r, path+".url", b.URL)
+	}
+
+	// Cache settings
+	if b.Cache.TTL != "" {
+		if _, err := time.ParseDuration(b.Cache.TTL); err != nil {
+			r.Add(path+".cache.ttl", "invalid duration '%s'", b.Cache.TTL)
+		}
+	}
+	if b.Cache.MaxEntries < 0 {
+		r.Add(path+".cache.maxEntries", "must not be negative")
+	}
+
+	// Reload interval
+	if b.ReloadInterval != "" {
+		if d, err := time.ParseDuration(b.ReloadInterval); err != nil {
+			r.Add(path+".reloadInterval", "invalid duration '%s'", b.ReloadInterval)
+		} else if d <= 0 {
+			r.Add(path+".reloadInterval", "must be positive")
+		}
+	}
+
+	// SPIFFE ID patterns
+	for i, p := range b.AllowedSPIFFEIDs {
+		if _, err := spiffe.ParsePattern(p); err != nil {
+			r.Add(fmt.Sprintf("%s.allowedSpiffeIDs[%d]", path, i), "%v", err)
+		}
+	}
+
+	// Transformation profiles
+	if len(b.Transformations) > 0 {
+		b := *b
+		b.Cache = Cache{}
+		b.AllowedSPIFFEIDs = nil
+		if _, err := b.Decorators(); err != nil {
+			r.Add(path+".transformations", "%v", err)
+		}
+	}
+}
+
+func validateURL(r *config.Report, path, raw string) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		r.Add(path, "invalid url: %v", err)
+		return
+	}
+
+	// Check backend type
+	if u.Scheme == "" {
+		r.Add(path, "url '%s' must declare a backend type as scheme", raw)
+		return
+	}
+	registered := false
+	schemes := storage.Schemes()
+	for _, s := range schemes {
+		if s == u.Scheme {
+			registered = true
+			break
+		}
+	}
+	if !registered {
+		r.Add(path, "unsupported backend type '%s', expected one of %s", u.Scheme, strings.Join(schemes, ", "))
+		return
+	}
+
+	// Check required parts
+	rule := backendRules[u.Scheme]
+	if rule.host && u.Host == "" {
+		r.Add(path, "'%s' backend url requires a host", u.Scheme)
+	}
+	if rule.path && strings.Trim(u.Path, "/") == "" {
+		r.Add(path, "'%s' backend url requires a path", u.Scheme)
+	}
+	q := u.Query()
+	for _, p := range rule.params {
+		if q.Get(p) == "" {
+			r.Add(path, "'%s' backend url requires the '%s' parameter", u.Scheme, p)
+		}
+	}
+
+	// Nested container backend
+	if u.Scheme == "hybrid" && q.Get("container") != "" {
+		validateURL(r, path, q.Get("container"))
+	}
+}
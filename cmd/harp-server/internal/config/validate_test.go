@@ -0,0 +1,319 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/elastic/harp/pkg/sdk/config"
+
+	_ "github.com/elastic/harp/pkg/server/storage/backends/container"
+	_ "github.com/elastic/harp/pkg/server/storage/backends/file"
+	_ "github.com/elastic/harp/pkg/server/storage/backends/hybrid"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "harp-server-config")
+	if err != nil {
+		t.Fatalf("unable to create temporary directory: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "config.yaml")
+	if err := ioutil.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("unable to write configuration: %v", err)
+	}
+
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		content string
+		want    []string
+	}{
+		{
+			desc: "valid",
+			content: `
+Backends:
+  - ns: secrets
+    url: bundle:///tmp/secrets.bundle
+  - ns: app
+    url: hybrid:///secret?container=bundle%2Bfile%3A%2F%2F%2Ftmp%2Fapp.bundle
+    cache:
+      ttl: 30s
+`,
+		},
+		{
+			desc: "unknown field",
+			content: `
+Backends:
+  - ns: secrets
+    url: bundle:///tmp/secrets.bundle
+    urls: bundle:///tmp/other.bundle
+`,
+			want: []string{"line 5: Backends[0].urls: unknown field"},
+		},
+		{
+			desc: "required fields",
+			content: `
+Backends:
+  - url: bundle:///tmp/secrets.bundle
+  - ns: app
+`,
+			want: []string{
+				"line 3: Backends[0].ns: namespace must not be blank",
+				"line 4: Backends[1].url: url must not be blank",
+			},
+		},
+		{
+			desc: "unsupported scheme",
+			content: `
+Backends:
+  - ns: secrets
+    url: ftp://host/secrets
+`,
+			want: []string{"line 4: Backends[0].url: unsupported backend type 'ftp'"},
+		},
+		{
+			desc: "missing url parts",
+			content: `
+Backends:
+  - ns: secrets
+    url: bundle+https:///secrets.bundle
+  - ns: app
+    url: hybrid:///secret?container=ftp://host
+`,
+			want: []string{
+				"line 4: Backends[0].url: 'bundle+https' backend url requires a host",
+				"line 6: Backends[1].url: unsupported backend type 'ftp'",
+			},
+		},
+		{
+			desc: "tls files",
+			content: `
+HTTP:
+  useTLS: true
+  TLS:
+    certificatePath: /non-existent/cert.pem
+    clientAuthenticationRequired: true
+`,
+			want: []string{
+				"line 4: HTTP.TLS.privateKeyPath: must not be blank",
+				"line 4: HTTP.TLS.caCertificatePath: must not be blank",
+				"line 5: HTTP.TLS.certificatePath: unable to access '/non-existent/cert.pem'",
+			},
+		},
+		{
+			desc: "admin settings",
+			content: `
+HTTP:
+  Admin:
+    enabled: true
+    skew: 5 minutes
+`,
+			want: []string{
+				"line 3: HTTP.Admin.keyPath: must not be blank",
+				"line 5: HTTP.Admin.skew: invalid duration '5 minutes'",
+			},
+		},
+		{
+			desc: "spiffe settings",
+			content: `
+HTTP:
+  useTLS: true
+  TLS:
+    certificatePath: /non-existent/cert.pem
+    privateKeyPath: /non-existent/key.pem
+    caCertificatePath: /non-existent/ca.pem
+    clientAuthenticationRequired: true
+    workloadAPISocket: unix:///run/spire/sockets/agent.sock
+Backends:
+  - ns: secrets
+    url: bundle:///tmp/secrets.bundle
+    allowedSpiffeIDs:
+      - spiffe://example.org/ns/prod/*
+      - https://example.org
+`,
+			want: []string{
+				"line 5: HTTP.TLS.certificatePath: unable to access '/non-existent/cert.pem'",
+				"line 6: HTTP.TLS.privateKeyPath: unable to access '/non-existent/key.pem'",
+				"line 9: HTTP.TLS.workloadAPISocket: must not be used with caCertificatePath",
+				"line 15: Backends[0].allowedSpiffeIDs[1]: invalid SPIFFE ID pattern 'https://example.org'",
+			},
+		},
+		{
+			desc: "duplicate namespace",
+			content: `
+Backends:
+  - ns: secrets
+    url: bundle:///tmp/secrets.bundle
+  - ns: /secrets
+    url: bundle:///tmp/other.bundle
+`,
+			want: []string{"line 5: Backends[1].ns: duplicate namespace '/secrets', already declared by Backends[0]"},
+		},
+		{
+			desc: "invalid cache",
+			content: `
+Backends:
+  - ns: secrets
+    url: bundle:///tmp/secrets.bundle
+    cache:
+      ttl: forever
+`,
+			want: []string{"line 6: Backends[0].cache.ttl: invalid duration 'forever'"},
+		},
+		{
+			desc: "invalid expired package policy",
+			content: `
+Bundle:
+  expiredPackages: purge
+`,
+			want: []string{"line 3: Bundle.expiredPackages: invalid policy 'purge', expected keep, drop or error"},
+		},
+		{
+			desc: "invalid value decryption settings",
+			content: `
+Bundle:
+  valueDecryptionKey: not-a-key
+  valueDecryptionFailure: drop
+`,
+			want: []string{
+				"line 3: Bundle.valueDecryptionKey: invalid AES-GCM key",
+				"line 4: Bundle.valueDecryptionFailure: invalid policy 'drop', expected error or hide",
+			},
+		},
+		{
+			desc: "shared cache without mmap",
+			content: `
+Bundle:
+  sharedCacheDir: /dev/shm/harp
+`,
+			want: []string{"line 3: Bundle.sharedCacheDir: requires memory-mapped loading (mmap)"},
+		},
+		{
+			desc: "invalid reload interval",
+			content: `
+Backends:
+  - ns: secrets
+    url: bundle:///tmp/secrets.bundle
+    reloadInterval: 0s
+`,
+			want: []string{"line 5: Backends[0].reloadInterval: must be positive"},
+		},
+		{
+			desc: "invalid shutdown grace period",
+			content: `
+Shutdown:
+  gracePeriod: 0s
+`,
+			want: []string{"line 3: Shutdown.gracePeriod: must be positive"},
+		},
+		{
+			desc: "invalid replication role",
+			content: `
+Replication:
+  role: standby
+`,
+			want: []string{"line 3: Replication.role: invalid role 'standby', expected primary or replica"},
+		},
+		{
+			desc: "invalid replication primary",
+			content: `
+Backends:
+  - ns: secrets
+    url: bundle:///tmp/secrets.bundle
+Replication:
+  role: primary
+`,
+			want: []string{
+				"line 5: Replication.signingKey: must not be blank",
+				"line 6: Replication.role: primary role requires the admin API to be enabled",
+			},
+		},
+		{
+			desc: "invalid replication replica",
+			content: `
+Backends:
+  - ns: secrets
+    url: bundle+https://primary/secrets.bundle
+Replication:
+  role: replica
+  primary: ftp://primary
+  adminKey: short
+  interval: 0s
+`,
+			want: []string{
+				"line 5: Replication.verificationKeyPath: must not be blank",
+				"line 6: Replication.role: replica role requires at least one container file backend",
+				"line 7: Replication.primary: must be an http(s) URL",
+				"line 8: Replication.adminKey: must be at least 16 bytes long",
+				"line 9: Replication.interval: must be positive",
+			},
+		},
+		{
+			desc: "aggregated",
+			content: `
+Backends:
+  - ns: secrets
+    url: ftp://host
+    bogus: 1
+  - ns: secrets
+    url: bundle:///tmp/secrets.bundle
+`,
+			want: []string{
+				"line 4: Backends[0].url: unsupported backend type 'ftp'",
+				"line 5: Backends[0].bogus: unknown field",
+				"line 6: Backends[1].ns: duplicate namespace 'secrets'",
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			_, err := Load(writeConfig(t, tC.content))
+			if len(tC.want) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var verr *config.ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("expected a validation error, got %v", err)
+			}
+			if len(verr.Problems) != len(tC.want) {
+				t.Fatalf("expected %d problems, got %d:\n%s", len(tC.want), len(verr.Problems), verr.Error())
+			}
+			for i, p := range verr.Problems {
+				if !strings.HasPrefix(p.String(), tC.want[i]) {
+					t.Errorf("problem %d: expected prefix %q, got %q", i, tC.want[i], p.String())
+				}
+			}
+		})
+	}
+}
@@ -19,23 +19,58 @@ package server
 
 import (
 	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
 
 	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/sdk/convert"
+	"github.com/elastic/harp/pkg/sdk/log"
 	"github.com/elastic/harp/pkg/server/manager"
+	"github.com/elastic/harp/pkg/server/storage"
+	"github.com/elastic/harp/pkg/server/watch"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 )
 
+// DefaultStallTimeout is the default duration after which a watch consumer
+// not reading its pending changes is disconnected.
+const DefaultStallTimeout = 30 * time.Second
+
+// Option defines BundleAPI server option function.
+type Option func(*grpcBundleServer)
+
+// WithStallTimeout sets the duration after which a watch consumer not reading
+// its pending changes is disconnected, zero disables the check.
+func WithStallTimeout(d time.Duration) Option {
+	return func(s *grpcBundleServer) {
+		s.stallTimeout = d
+	}
+}
+
 // Bundle returns an gRPC requests handler for BundleAPI.
-func Bundle(bm manager.Backend) bundlev1.BundleAPIServer {
-	return &grpcBundleServer{
-		bm: bm,
+func Bundle(bm manager.Backend, opts ...Option) bundlev1.BundleAPIServer {
+	s := &grpcBundleServer{
+		bm:           bm,
+		stallTimeout: DefaultStallTimeout,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+
+	return s
 }
 
 type grpcBundleServer struct {
 	bundlev1.UnimplementedBundleAPIServer
-	bm manager.Backend
+	bm           manager.Backend
+	stallTimeout time.Duration
 }
 
 func (s *grpcBundleServer) GetSecret(ctx context.Context, req *bundlev1.GetSecretRequest) (*bundlev1.GetSecretResponse, error) {
@@ -50,12 +85,42 @@ func (s *grpcBundleServer) GetSecret(ctx context.Context, req *bundlev1.GetSecre
 		return nil, status.Errorf(codes.InvalidArgument, "path could not be blank")
 	}
 
+	// Resolve client identity from transport
+	ctx = withClientIdentity(ctx)
+
 	// Delegate to engine to retrieve secret
+	ctx, source := storage.WithSource(ctx)
 	content, err := s.bm.GetSecret(ctx, req.Namespace, req.Path)
+	if src := source.Get(); src != "" {
+		// Best effort, header is informative
+		_ = grpc.SetHeader(ctx, metadata.Pairs(strings.ToLower(storage.SourceHeader), src))
+	}
+	if errors.Is(err, storage.ErrAccessDenied) {
+		return nil, status.Errorf(codes.PermissionDenied, "Secret '%s' access is denied in '%s' namespace", req.Path, req.Namespace)
+	}
+	if errors.Is(err, storage.ErrSecretQuarantined) {
+		log.For(ctx).Warn("Quarantined secret access refused", zap.Error(err), zap.String("namespace", req.Namespace), zap.String("path", req.Path))
+		return nil, status.Errorf(codes.FailedPrecondition, "Secret '%s' is unavailable in '%s' namespace", req.Path, req.Namespace)
+	}
 	if err != nil {
 		return nil, status.Errorf(codes.NotFound, "Secret '%s' could not be retrieved from '%s' namespace", req.Path, req.Namespace)
 	}
 
+	// Encode as CBOR when requested
+	if req.Cbor {
+		payload, err := convert.ContentToCBOR(content)
+		if err != nil {
+			log.For(ctx).Error("unable to encode secret", zap.Error(err), zap.String("namespace", req.Namespace), zap.String("path", req.Path))
+			return nil, status.Errorf(codes.Internal, "Secret '%s' could not be encoded", req.Path)
+		}
+
+		return &bundlev1.GetSecretResponse{
+			Namespace:   req.Namespace,
+			Path:        req.Path,
+			CborContent: payload,
+		}, nil
+	}
+
 	// Return result
 	return &bundlev1.GetSecretResponse{
 		Namespace: req.Namespace,
@@ -63,3 +128,113 @@ func (s *grpcBundleServer) GetSecret(ctx context.Context, req *bundlev1.GetSecre
 		Content:   content,
 	}, nil
 }
+
+func (s *grpcBundleServer) WatchPackages(req *bundlev1.WatchPackagesRequest, stream bundlev1.BundleAPI_WatchPackagesServer) error {
+	// Check arguments
+	if req == nil {
+		return status.Errorf(codes.InvalidArgument, "request is nil")
+	}
+	if req.Namespace == "" {
+		return status.Errorf(codes.InvalidArgument, "namespace could not be blank")
+	}
+
+	// Check watch support
+	w, ok := s.bm.(manager.Watcher)
+	if !ok {
+		return status.Errorf(codes.Unimplemented, "Namespace watch is not supported")
+	}
+
+	// Resolve client identity from transport
+	ctx := withClientIdentity(stream.Context())
+
+	// Subscribe to namespace changes
+	sub, digests, err := w.Watch(ctx, req.Namespace, req.Prefix, s.stallTimeout)
+	switch {
+	case errors.Is(err, manager.ErrNamespaceNotFound):
+		return status.Errorf(codes.NotFound, "Namespace '%s' could not be found", req.Namespace)
+	case errors.Is(err, storage.ErrAccessDenied):
+		return status.Errorf(codes.PermissionDenied, "Namespace '%s' access is denied", req.Namespace)
+	case errors.Is(err, storage.ErrListNotSupported):
+		return status.Errorf(codes.FailedPrecondition, "Namespace '%s' could not be watched", req.Namespace)
+	case err != nil:
+		log.For(ctx).Error("unable to watch namespace", zap.Error(err), zap.String("namespace", req.Namespace))
+		return status.Errorf(codes.Internal, "Namespace '%s' could not be watched", req.Namespace)
+	default:
+	}
+	defer sub.Close()
+
+	// Forward changes asynchronously, a consumer blocking the stream is
+	// disconnected by returning from the handler.
+	errs := make(chan error, 1)
+	go func() {
+		errs <- forwardChanges(ctx, stream, req.Namespace, digests, sub)
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-sub.Done():
+		if errors.Is(sub.Err(), watch.ErrSlowConsumer) {
+			log.For(ctx).Warn("Slow watch consumer disconnected", zap.String("namespace", req.Namespace))
+			return status.Errorf(codes.ResourceExhausted, "Watch consumer is too slow")
+		}
+		return nil
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+func withClientIdentity(ctx context.Context) context.Context {
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			return storage.WithClientIdentity(ctx, storage.ClientIdentityFromTLS(&tlsInfo.State))
+		}
+	}
+	return ctx
+}
+
+func forwardChanges(ctx context.Context, stream bundlev1.BundleAPI_WatchPackagesServer, namespace string, digests map[string]string, sub *watch.Subscription) error {
+	// Send initial digests
+	sync := &bundlev1.WatchPackagesResponse{
+		Namespace: namespace,
+		Type:      bundlev1.ChangeType_CHANGE_TYPE_SYNC,
+		Digests:   make([]*bundlev1.PackageDigest, 0, len(digests)),
+	}
+	for path, digest := range digests {
+		sync.Digests = append(sync.Digests, &bundlev1.PackageDigest{Path: path, Digest: digest})
+	}
+	sort.Slice(sync.Digests, func(i, j int) bool {
+		return sync.Digests[i].Path < sync.Digests[j].Path
+	})
+	if err := stream.Send(sync); err != nil {
+		return err
+	}
+
+	for {
+		// Wait for the next change
+		c, err := sub.Next(ctx)
+		switch {
+		case errors.Is(err, watch.ErrSlowConsumer):
+			return status.Errorf(codes.ResourceExhausted, "Watch consumer is too slow")
+		case err != nil:
+			// Client is gone or subscription is closed
+			return nil
+		default:
+		}
+
+		if err := stream.Send(&bundlev1.WatchPackagesResponse{
+			Namespace: namespace,
+			Type:      changeTypes[c.Type],
+			Path:      c.ID,
+			Digest:    c.Digest,
+		}); err != nil {
+			return err
+		}
+	}
+}
+
+var changeTypes = map[storage.ChangeType]bundlev1.ChangeType{
+	storage.ChangeCreated: bundlev1.ChangeType_CHANGE_TYPE_CREATED,
+	storage.ChangeUpdated: bundlev1.ChangeType_CHANGE_TYPE_UPDATED,
+	storage.ChangeDeleted: bundlev1.ChangeType_CHANGE_TYPE_DELETED,
+}
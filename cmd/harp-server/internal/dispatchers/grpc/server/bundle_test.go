@@ -0,0 +1,97 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/ugorji/go/codec"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/server/storage"
+)
+
+type memoryBackend map[string]string
+
+func (m memoryBackend) GetSecret(_ context.Context, ns, id string) ([]byte, error) {
+	v, ok := m[ns+id]
+	if !ok {
+		return nil, storage.ErrSecretNotFound
+	}
+	return []byte(v), nil
+}
+
+func (m memoryBackend) Register(context.Context, string, string, ...func(storage.Engine) storage.Engine) error {
+	return nil
+}
+
+func (m memoryBackend) GetNameSpace(context.Context, string) (storage.Engine, error) {
+	return nil, nil
+}
+
+func TestBundle_GetSecret_CBOR(t *testing.T) {
+	s := Bundle(memoryBackend{
+		"secrets/app/database": `{"user":"harp","password":"foo","port":5432}`,
+		"secrets/app/raw":      `not a json document`,
+	})
+
+	for _, path := range []string{"/app/database", "/app/raw"} {
+		t.Run(path, func(t *testing.T) {
+			plain, err := s.GetSecret(context.Background(), &bundlev1.GetSecretRequest{Namespace: "secrets", Path: path})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			compact, err := s.GetSecret(context.Background(), &bundlev1.GetSecretRequest{Namespace: "secrets", Path: path, Cbor: true})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(compact.Content) != 0 || len(compact.CborContent) == 0 {
+				t.Fatalf("only CBOR content must be set, got %+v", compact)
+			}
+
+			// Decode CBOR payload
+			h := &codec.CborHandle{}
+			h.MapType = reflect.TypeOf(map[string]interface{}(nil))
+			var got interface{}
+			if err := codec.NewDecoderBytes(compact.CborContent, h).Decode(&got); err != nil {
+				t.Fatalf("unable to decode CBOR content: %v", err)
+			}
+
+			// Compare with the plain content
+			var want interface{}
+			if err := json.Unmarshal(plain.Content, &want); err != nil {
+				// Non JSON content is sent as a byte string
+				want = plain.Content
+			} else {
+				raw, err := json.Marshal(got)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := json.Unmarshal(raw, &got); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !reflect.DeepEqual(want, got) {
+				t.Errorf("content mismatch, want %v, got %v", want, got)
+			}
+		})
+	}
+}
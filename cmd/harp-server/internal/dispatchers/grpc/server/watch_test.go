@@ -0,0 +1,319 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+	"github.com/elastic/harp/pkg/server/manager"
+	"github.com/elastic/harp/pkg/server/storage"
+	_ "github.com/elastic/harp/pkg/server/storage/backends/container"
+	"github.com/elastic/harp/pkg/server/watch"
+)
+
+func serve(t *testing.T, bm manager.Backend, opts ...Option) bundlev1.BundleAPIClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1024)
+	srv := grpc.NewServer()
+	bundlev1.RegisterBundleAPIServer(srv, Bundle(bm, opts...))
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithInsecure(),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}),
+		// Static flow control windows, so that a consumer not reading blocks the stream
+		grpc.WithInitialWindowSize(1<<16),
+		grpc.WithInitialConnWindowSize(1<<16),
+	)
+	if err != nil {
+		t.Fatalf("unable to dial server: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return bundlev1.NewBundleAPIClient(conn)
+}
+
+func writeBundle(t *testing.T, path string, secrets map[string]string) {
+	t.Helper()
+
+	b := testbundle.New()
+	for pkg, value := range secrets {
+		b.Package(pkg).Secret("value", value)
+	}
+	if err := ioutil.WriteFile(path, testbundle.Container(t, b.Build()), 0o600); err != nil {
+		t.Fatalf("unable to write bundle: %v", err)
+	}
+}
+
+func recv(t *testing.T, stream bundlev1.BundleAPI_WatchPackagesClient) *bundlev1.WatchPackagesResponse {
+	t.Helper()
+
+	res, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("unable to receive event: %v", err)
+	}
+	return res
+}
+
+func TestBundle_WatchPackages_Reload(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Prepare namespace
+	path := filepath.Join(t.TempDir(), "secrets.bundle")
+	writeBundle(t, path, map[string]string{
+		"app/database": "foo",
+		"app/cache":    "bar",
+		"infra/dns":    "baz",
+	})
+	bm := manager.Default()
+	if err := bm.Register(ctx, "secrets", "bundle://"+path); err != nil {
+		t.Fatalf("unable to register namespace: %v", err)
+	}
+	client := serve(t, bm)
+
+	// Unknown namespace
+	stream, err := client.WatchPackages(ctx, &bundlev1.WatchPackagesRequest{Namespace: "unknown"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound, got %v", err)
+	}
+
+	// Watch application packages
+	stream, err = client.WatchPackages(ctx, &bundlev1.WatchPackagesRequest{Namespace: "secrets", Prefix: "app"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Initial synthetic event
+	sync := recv(t, stream)
+	if sync.Type != bundlev1.ChangeType_CHANGE_TYPE_SYNC {
+		t.Fatalf("expected a sync event, got %v", sync.Type)
+	}
+	if len(sync.Digests) != 2 || sync.Digests[0].Path != "/app/cache" || sync.Digests[1].Path != "/app/database" {
+		t.Fatalf("unexpected sync digests: %v", sync.Digests)
+	}
+	before := map[string]string{}
+	for _, d := range sync.Digests {
+		if d.Digest == "" {
+			t.Fatalf("digest of '%s' must not be blank", d.Path)
+		}
+		before[d.Path] = d.Digest
+	}
+
+	// Update container and reload namespace
+	writeBundle(t, path, map[string]string{
+		"app/database": "updated",
+		"app/queue":    "qux",
+		"infra/dns":    "updated",
+	})
+	if err := bm.(manager.Watcher).Reload(ctx, "secrets"); err != nil {
+		t.Fatalf("unable to reload namespace: %v", err)
+	}
+
+	want := []struct {
+		path string
+		typ  bundlev1.ChangeType
+	}{
+		{"/app/cache", bundlev1.ChangeType_CHANGE_TYPE_DELETED},
+		{"/app/database", bundlev1.ChangeType_CHANGE_TYPE_UPDATED},
+		{"/app/queue", bundlev1.ChangeType_CHANGE_TYPE_CREATED},
+	}
+	for _, w := range want {
+		ev := recv(t, stream)
+		if ev.Path != w.path || ev.Type != w.typ {
+			t.Fatalf("expected %v on '%s', got %v on '%s'", w.typ, w.path, ev.Type, ev.Path)
+		}
+		if ev.Namespace != "secrets" {
+			t.Errorf("unexpected namespace '%s'", ev.Namespace)
+		}
+		switch w.typ {
+		case bundlev1.ChangeType_CHANGE_TYPE_DELETED:
+			if ev.Digest != "" {
+				t.Errorf("deleted package must not have a digest, got '%s'", ev.Digest)
+			}
+		case bundlev1.ChangeType_CHANGE_TYPE_UPDATED:
+			if ev.Digest == "" || ev.Digest == before[w.path] {
+				t.Errorf("expected a new digest for '%s', got '%s'", w.path, ev.Digest)
+			}
+		default:
+			if ev.Digest == "" {
+				t.Errorf("expected a digest for '%s'", w.path)
+			}
+		}
+	}
+
+	// Reloading an unchanged container emits nothing
+	if err := bm.(manager.Watcher).Reload(ctx, "secrets"); err != nil {
+		t.Fatalf("unable to reload namespace: %v", err)
+	}
+	writeBundle(t, path, map[string]string{
+		"app/database": "updated",
+		"app/queue":    "quux",
+	})
+	if err := bm.(manager.Watcher).Reload(ctx, "secrets"); err != nil {
+		t.Fatalf("unable to reload namespace: %v", err)
+	}
+	if ev := recv(t, stream); ev.Path != "/app/queue" || ev.Type != bundlev1.ChangeType_CHANGE_TYPE_UPDATED {
+		t.Fatalf("expected the queue package update, got %v on '%s'", ev.Type, ev.Path)
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+type hubBackend struct {
+	memoryBackend
+	hub *watch.Hub
+	sub chan *watch.Subscription
+}
+
+func (b *hubBackend) Watch(_ context.Context, namespace, prefix string, stall time.Duration) (*watch.Subscription, map[string]string, error) {
+	sub := b.hub.Subscribe(namespace, prefix, stall)
+	b.sub <- sub
+	return sub, map[string]string{}, nil
+}
+
+func (b *hubBackend) Reload(context.Context, string) error {
+	return nil
+}
+
+func (b *hubBackend) publishUpdates(count, paths int) map[string]string {
+	last := map[string]string{}
+	for i := 0; i < count; i++ {
+		id := fmt.Sprintf("/app/%d", i%paths)
+		last[id] = fmt.Sprintf("%064d", i)
+		b.hub.Publish("secrets", storage.Change{ID: id, Type: storage.ChangeUpdated, Digest: last[id]})
+	}
+	return last
+}
+
+func TestBundle_WatchPackages_Coalescing(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bm := &hubBackend{hub: watch.NewHub(), sub: make(chan *watch.Subscription, 1)}
+	client := serve(t, bm, WithStallTimeout(0))
+
+	stream, err := client.WatchPackages(ctx, &bundlev1.WatchPackagesRequest{Namespace: "secrets"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ev := recv(t, stream); ev.Type != bundlev1.ChangeType_CHANGE_TYPE_SYNC {
+		t.Fatalf("expected a sync event, got %v", ev.Type)
+	}
+	sub := <-bm.sub
+
+	// Publish more updates than the transport can buffer without reading
+	const published = 20000
+	last := bm.publishUpdates(published, 10)
+
+	// Wait for the last published digest of every path
+	received := 0
+	seen := map[string]string{}
+	for !sameDigests(seen, last) {
+		ev := recv(t, stream)
+		if ev.Type != bundlev1.ChangeType_CHANGE_TYPE_UPDATED {
+			t.Fatalf("unexpected event type %v", ev.Type)
+		}
+		seen[ev.Path] = ev.Digest
+		received++
+	}
+	if received >= published {
+		t.Fatalf("intermediate events must be coalesced, received %d events", received)
+	}
+	if sub.Coalesced() == 0 {
+		t.Fatalf("expected coalesced changes")
+	}
+}
+
+func TestBundle_WatchPackages_SlowConsumer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bm := &hubBackend{hub: watch.NewHub(), sub: make(chan *watch.Subscription, 1)}
+	client := serve(t, bm, WithStallTimeout(50*time.Millisecond))
+
+	stream, err := client.WatchPackages(ctx, &bundlev1.WatchPackagesRequest{Namespace: "secrets"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ev := recv(t, stream); ev.Type != bundlev1.ChangeType_CHANGE_TYPE_SYNC {
+		t.Fatalf("expected a sync event, got %v", ev.Type)
+	}
+	sub := <-bm.sub
+
+	// Keep changes pending without reading the stream
+	timeout := time.After(5 * time.Second)
+	for done := false; !done; {
+		bm.publishUpdates(1000, 100)
+		select {
+		case <-sub.Done():
+			done = true
+		case <-timeout:
+			t.Fatal("slow consumer must be disconnected")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	// Drain buffered events
+	for {
+		_, err := stream.Recv()
+		if err == nil {
+			continue
+		}
+		if status.Code(err) != codes.ResourceExhausted {
+			t.Fatalf("expected ResourceExhausted, got %v", err)
+		}
+		break
+	}
+}
+
+func sameDigests(got, want map[string]string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for k, v := range want {
+		if got[k] != v {
+			return false
+		}
+	}
+	return true
+}
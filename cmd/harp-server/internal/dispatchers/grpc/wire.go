@@ -22,6 +22,7 @@ package grpc
 import (
 	"context"
 	"crypto/tls"
+	"time"
 
 	"github.com/google/wire"
 	"go.uber.org/zap"
@@ -34,23 +35,61 @@ import (
 	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
 	"github.com/elastic/harp/cmd/harp-server/internal/config"
 	"github.com/elastic/harp/cmd/harp-server/internal/dispatchers/grpc/server"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/decryptor"
 	"github.com/elastic/harp/pkg/sdk/log"
 	"github.com/elastic/harp/pkg/sdk/tlsconfig"
+	"github.com/elastic/harp/pkg/server/spiffe"
 	"github.com/elastic/harp/pkg/server/manager"
 	"github.com/elastic/harp/pkg/server/storage/backends/container"
 	vpath "github.com/elastic/harp/pkg/vault/path"
 )
 
 func backendManager(ctx context.Context, cfg *config.Configuration) (manager.Backend, error) {
+	// Apply expired package policy before loading containers, settings are
+	// already validated
+	expiredPolicy, _ := bundle.ParseExpiredPackagePolicy(cfg.Bundle.ExpiredPackages)
+	container.SetExpiredPackagePolicy(expiredPolicy)
+
+	// Apply memory-mapped loading settings
+	container.SetMmap(cfg.Bundle.Mmap, cfg.Bundle.SharedCacheDir)
+
+	// Apply value decryption settings
+	decryptPolicy, _ := bundle.ParseDecryptFailurePolicy(cfg.Bundle.ValueDecryptionFailure)
+	if cfg.Bundle.ValueDecryptionKey != "" {
+		d, err := decryptor.AESGCM(cfg.Bundle.ValueDecryptionKey)
+		if err != nil {
+			return nil, err
+		}
+		container.SetValueDecryptor(d, decryptPolicy)
+	}
+
 	// Initialize default manager
 	bm := manager.Default()
 
 	// Backends
 	for _, b := range cfg.Backends {
+		// Build response transformations
+		decorators, err := b.Decorators()
+		if err != nil {
+			return nil, err
+		}
+
 		// Register namespace engine
-		if err := bm.Register(ctx, vpath.SanitizePath(b.NS), b.URL); err != nil {
+		if err := bm.Register(ctx, vpath.SanitizePath(b.NS), b.URL, decorators...); err != nil {
 			return nil, err
 		}
+
+		// Reload namespace periodically to notify package watchers
+		if b.ReloadInterval != "" {
+			interval, err := time.ParseDuration(b.ReloadInterval)
+			if err != nil {
+				return nil, err
+			}
+			if w, ok := bm.(manager.Watcher); ok {
+				go manager.ReloadEvery(ctx, w, vpath.SanitizePath(b.NS), interval)
+			}
+		}
 	}
 
 	// No error
@@ -85,12 +124,28 @@ func grpcServer(ctx context.Context, cfg *config.Configuration, bm manager.Backe
 			return nil, err
 		}
 
+		// Fetch rotated client trust bundles from SPIRE Workload API
+		if cfg.GRPC.TLS.WorkloadAPISocket != "" {
+			source, err := spiffe.WatchBundles(ctx, cfg.GRPC.TLS.WorkloadAPISocket)
+			if err != nil {
+				log.For(ctx).Error("Unable to fetch trust bundles from SPIRE Workload API", zap.Error(err))
+				return nil, err
+			}
+			tlsConfig = spiffe.ServerConfig(tlsConfig, source)
+		}
+
 		// Create the TLS credentials
 		sopts = append(sopts, grpc.Creds(credentials.NewTLS(tlsConfig)))
 	} else {
 		log.For(ctx).Info("No transport encryption enabled for gRPC server")
 	}
 
+	// Watch consumer stall timeout
+	stallTimeout, err := time.ParseDuration(cfg.GRPC.Watch.StallTimeout)
+	if err != nil {
+		stallTimeout = server.DefaultStallTimeout
+	}
+
 	// Initialize the server
 	grpcServer := grpc.NewServer(sopts...)
 
@@ -99,7 +154,7 @@ func grpcServer(ctx context.Context, cfg *config.Configuration, bm manager.Backe
 	healthpb.RegisterHealthServer(grpcServer, healthServer)
 
 	// Register services
-	bundlev1.RegisterBundleAPIServer(grpcServer, server.Bundle(bm))
+	bundlev1.RegisterBundleAPIServer(grpcServer, server.Bundle(bm, server.WithStallTimeout(stallTimeout)))
 	healthServer.SetServingStatus("harp.bundle.v1", healthpb.HealthCheckResponse_SERVING)
 
 	// Reflection
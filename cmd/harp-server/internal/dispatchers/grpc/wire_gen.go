@@ -8,11 +8,15 @@ package grpc
 import (
 	"context"
 	"crypto/tls"
+	"time"
 	"github.com/elastic/harp/api/gen/go/harp/bundle/v1"
 	"github.com/elastic/harp/cmd/harp-server/internal/config"
 	"github.com/elastic/harp/cmd/harp-server/internal/dispatchers/grpc/server"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/decryptor"
 	"github.com/elastic/harp/pkg/sdk/log"
 	"github.com/elastic/harp/pkg/sdk/tlsconfig"
+	"github.com/elastic/harp/pkg/server/spiffe"
 	"github.com/elastic/harp/pkg/server/manager"
 	"github.com/elastic/harp/pkg/server/storage/backends/container"
 	"github.com/elastic/harp/pkg/vault/path"
@@ -41,14 +45,40 @@ func setup(ctx context.Context, cfg *config.Configuration) (*grpc.Server, error)
 // wire.go:
 
 func backendManager(ctx context.Context, cfg *config.Configuration) (manager.Backend, error) {
+	expiredPolicy, _ := bundle.ParseExpiredPackagePolicy(cfg.Bundle.ExpiredPackages)
+	container.SetExpiredPackagePolicy(expiredPolicy)
+	container.SetMmap(cfg.Bundle.Mmap, cfg.Bundle.SharedCacheDir)
+
+	decryptPolicy, _ := bundle.ParseDecryptFailurePolicy(cfg.Bundle.ValueDecryptionFailure)
+	if cfg.Bundle.ValueDecryptionKey != "" {
+		d, err := decryptor.AESGCM(cfg.Bundle.ValueDecryptionKey)
+		if err != nil {
+			return nil, err
+		}
+		container.SetValueDecryptor(d, decryptPolicy)
+	}
 
 	bm := manager.Default()
 
 	for _, b := range cfg.Backends {
 
-		if err := bm.Register(ctx, path.SanitizePath(b.NS), b.URL); err != nil {
+		decorators, err := b.Decorators()
+		if err != nil {
+			return nil, err
+		}
+		if err := bm.Register(ctx, path.SanitizePath(b.NS), b.URL, decorators...); err != nil {
 			return nil, err
 		}
+
+		if b.ReloadInterval != "" {
+			interval, err := time.ParseDuration(b.ReloadInterval)
+			if err != nil {
+				return nil, err
+			}
+			if w, ok := bm.(manager.Watcher); ok {
+				go manager.ReloadEvery(ctx, w, path.SanitizePath(b.NS), interval)
+			}
+		}
 	}
 
 	return bm, nil
@@ -77,15 +107,29 @@ func grpcServer(ctx context.Context, cfg *config.Configuration, bm manager.Backe
 			return nil, err
 		}
 
+		// Fetch rotated client trust bundles from SPIRE Workload API
+		if cfg.GRPC.TLS.WorkloadAPISocket != "" {
+			source, err := spiffe.WatchBundles(ctx, cfg.GRPC.TLS.WorkloadAPISocket)
+			if err != nil {
+				log.For(ctx).Error("Unable to fetch trust bundles from SPIRE Workload API", zap.Error(err))
+				return nil, err
+			}
+			tlsConfig = spiffe.ServerConfig(tlsConfig, source)
+		}
+
 		sopts = append(sopts, grpc.Creds(credentials.NewTLS(tlsConfig)))
 	} else {
 		log.For(ctx).Info("No transport encryption enabled for gRPC server")
 	}
+	stallTimeout, err := time.ParseDuration(cfg.GRPC.Watch.StallTimeout)
+	if err != nil {
+		stallTimeout = server.DefaultStallTimeout
+	}
 	grpcServer2 := grpc.NewServer(sopts...)
 
 	healthServer := health.NewServer()
 	grpc_health_v1.RegisterHealthServer(grpcServer2, healthServer)
-	bundlev1.RegisterBundleAPIServer(grpcServer2, server.Bundle(bm))
+	bundlev1.RegisterBundleAPIServer(grpcServer2, server.Bundle(bm, server.WithStallTimeout(stallTimeout)))
 	healthServer.SetServingStatus("harp.bundle.v1", grpc_health_v1.HealthCheckResponse_SERVING)
 	reflection.Register(grpcServer2)
 
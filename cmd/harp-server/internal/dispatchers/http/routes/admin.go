@@ -0,0 +1,110 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+
+	"github.com/elastic/harp/cmd/harp-server/internal/config"
+	"github.com/elastic/harp/pkg/sdk/httpsign"
+	"github.com/elastic/harp/pkg/server/replication"
+)
+
+// Admin returns an HTTP router for admin endpoints. All requests must be HMAC
+// signed using the shared admin key. The replica state is reported by the
+// status endpoint when given.
+func Admin(ctx context.Context, cfg *config.Configuration, replica *replication.Replica) (http.Handler, error) {
+	// Load shared key
+	key := []byte(cfg.HTTP.Admin.Key)
+	if len(key) == 0 {
+		var err error
+		key, err = ioutil.ReadFile(cfg.HTTP.Admin.KeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read admin key: %w", err)
+		}
+	}
+	key = bytes.TrimSpace(key)
+	if len(key) < config.MinAdminKeySize {
+		return nil, fmt.Errorf("admin key must be at least %d bytes long", config.MinAdminKeySize)
+	}
+
+	// Build verifier
+	opts := []httpsign.Option{}
+	if cfg.HTTP.Admin.Skew != "" {
+		d, errSkew := time.ParseDuration(cfg.HTTP.Admin.Skew)
+		if errSkew != nil {
+			return nil, fmt.Errorf("invalid admin clock skew: %w", errSkew)
+		}
+		opts = append(opts, httpsign.WithSkew(d))
+	}
+	if cfg.HTTP.Admin.MaxNonces > 0 {
+		opts = append(opts, httpsign.WithNonceCapacity(cfg.HTTP.Admin.MaxNonces))
+	}
+	verifier, err := httpsign.NewVerifier(key, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize admin request verifier: %w", err)
+	}
+
+	r := chi.NewRouter()
+	r.Use(verifier.Middleware)
+	r.Get("/status", status(cfg, replica))
+
+	// Replication snapshot
+	if cfg.Replication.Role == config.ReplicationPrimary {
+		signingKey, err := cfg.Replication.SnapshotSigningKey()
+		if err != nil {
+			return nil, err
+		}
+		source, err := replication.NewSource(signingKey, cfg.ReplicatedNamespaces())
+		if err != nil {
+			return nil, fmt.Errorf("unable to initialize replication snapshot: %w", err)
+		}
+		r.Get("/replication/snapshot", source.ServeHTTP)
+	}
+
+	// No error
+	return r, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func status(cfg *config.Configuration, replica *replication.Replica) http.HandlerFunc {
+	namespaces := make([]string, 0, len(cfg.Backends))
+	for _, b := range cfg.Backends {
+		namespaces = append(namespaces, clean(b.NS))
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		doc := map[string]interface{}{
+			"status":     "ok",
+			"namespaces": namespaces,
+		}
+		if replica != nil {
+			doc["replication"] = replica.Status()
+		}
+
+		_ = writeDocument(w, doc, negotiate(r))
+	}
+}
@@ -0,0 +1,204 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/crypto/nacl/box"
+
+	"github.com/elastic/harp/cmd/harp-server/internal/config"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+	"github.com/elastic/harp/pkg/container"
+	"github.com/elastic/harp/pkg/sdk/httpsign"
+	"github.com/elastic/harp/pkg/server/replication"
+)
+
+func TestAdmin(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "admin.key")
+	if err := ioutil.WriteFile(keyPath, []byte("0123456789abcdef0123456789abcdef\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Configuration{
+		Backends: []config.Backend{{NS: "secrets"}, {NS: "/app"}},
+	}
+	cfg.HTTP.Admin = config.Admin{Enabled: true, KeyPath: keyPath, Skew: "1m"}
+
+	h, err := Admin(context.Background(), cfg, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Unsigned request
+	if rec := get(h, "/status", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401, got %d", rec.Code)
+	}
+
+	// Signed request, trailing newline of the key file is ignored
+	req := httptest.NewRequest(http.MethodGet, "/status", nil)
+	if err := httpsign.Sign(req, []byte("0123456789abcdef0123456789abcdef")); err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	var resp struct {
+		Status     string   `json:"status"`
+		Namespaces []string `json:"namespaces"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unable to decode status: %v", err)
+	}
+	if resp.Status != "ok" || !reflect.DeepEqual(resp.Namespaces, []string{"secrets", "app"}) {
+		t.Errorf("unexpected status %+v", resp)
+	}
+
+	// Replayed request
+	replay := httptest.NewRequest(http.MethodGet, "/status", nil)
+	replay.Header = req.Header.Clone()
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, replay)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401 for replayed request, got %d", rec.Code)
+	}
+
+	// Weak key
+	if err := ioutil.WriteFile(keyPath, []byte("short"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Admin(context.Background(), cfg, nil); err == nil {
+		t.Error("error should be raised for short admin key")
+	}
+}
+
+func TestAdmin_Replication(t *testing.T) {
+	adminKey := []byte("0123456789abcdef0123456789abcdef")
+	dir := t.TempDir()
+
+	// Sealed namespace container
+	b := testbundle.New()
+	b.Package("app/production/security/harp/v1.0.0/server/database").Secret("user", "admin")
+	c, err := bundle.ToContainer(b.Build())
+	if err != nil {
+		t.Fatal(err)
+	}
+	peer, _, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := container.Seal(c, peer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := container.Dump(&buf, sealed); err != nil {
+		t.Fatal(err)
+	}
+	containerPath := filepath.Join(dir, "secrets.bundle")
+	if err := ioutil.WriteFile(containerPath, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Snapshot signing key
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Configuration{
+		Backends: []config.Backend{{NS: "secrets", URL: fmt.Sprintf("bundle://%s?cid=unused", containerPath)}},
+	}
+	cfg.HTTP.Admin = config.Admin{Enabled: true, Key: string(adminKey)}
+	cfg.Replication = config.Replication{
+		Role:       config.ReplicationPrimary,
+		SigningKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+	}
+
+	h, err := Admin(context.Background(), cfg, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Signed snapshot
+	req := httptest.NewRequest(http.MethodGet, "/replication/snapshot", nil)
+	if err := httpsign.Sign(req, adminKey); err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if err := replication.Verify(rec.Body.Bytes(), rec.Header().Get(replication.SignatureHeader), pub); err != nil {
+		t.Fatalf("unable to verify snapshot: %v", err)
+	}
+	var snapshot replication.Snapshot
+	if err := json.Unmarshal(rec.Body.Bytes(), &snapshot); err != nil {
+		t.Fatalf("unable to decode snapshot: %v", err)
+	}
+	if len(snapshot.Namespaces) != 1 || snapshot.Namespaces[0].Name != "secrets" || !bytes.Equal(snapshot.Namespaces[0].Container, buf.Bytes()) {
+		t.Errorf("unexpected snapshot %+v", snapshot)
+	}
+
+	// Replica status
+	r, err := replication.NewReplica("http://primary", pub, map[string]string{"secrets": containerPath}, func(context.Context, string) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Replication = config.Replication{}
+	h, err = Admin(context.Background(), cfg, r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/status", nil)
+	if err := httpsign.Sign(req, adminKey); err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	var status struct {
+		Replication *replication.Status `json:"replication"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("unable to decode status: %v", err)
+	}
+	if status.Replication == nil || status.Replication.Primary != "http://primary" {
+		t.Errorf("replication status must be reported, got %s", rec.Body.String())
+	}
+}
@@ -18,22 +18,30 @@
 package routes
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 
 	"go.uber.org/zap"
 
+	"github.com/elastic/harp/pkg/sdk/convert"
 	"github.com/elastic/harp/pkg/sdk/log"
 	"github.com/elastic/harp/pkg/sdk/value/encryption"
 	"github.com/elastic/harp/pkg/server/storage"
 )
 
 // Backend returns a backend http request handler.
-func backend(namespace string, engine storage.Engine) http.HandlerFunc {
+func backend(namespace string, engines engineFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		// Retrieve current namespace engine
+		engine, ok := currentEngine(w, r, engines)
+		if !ok {
+			return
+		}
+
 		var (
-			ctx    = r.Context()
+			ctx    = storage.WithClientIdentity(r.Context(), storage.ClientIdentityFromTLS(r.TLS))
 			id     = r.URL.Path
 			keyRaw = r.URL.Query().Get("key")
 		)
@@ -41,18 +49,60 @@ func backend(namespace string, engine storage.Engine) http.HandlerFunc {
 		// Remove namespace prefix
 		identifier := strings.TrimPrefix(id, fmt.Sprintf("/%s", namespace))
 
+		// Negotiate response format
+		mediaType := negotiate(r)
+		w.Header().Add("Vary", "Accept")
+
+		// Check digest before reading the value when supported
+		ctx, source := storage.WithSource(ctx)
+		if de, ok := engine.(storage.DigestEngine); ok {
+			d, err := de.Digest(ctx, identifier)
+			if errors.Is(err, storage.ErrSecretNotFound) {
+				http.Error(w, "secret not found", http.StatusNotFound)
+				return
+			}
+			if quarantined(w, r, err) {
+				return
+			}
+			if err != nil {
+				log.For(ctx).Error("unable to retrieve secret digest from engine", zap.Error(err), zap.String("url", r.URL.String()))
+				http.Error(w, "unable to retrieve secret", http.StatusBadRequest)
+				return
+			}
+			if notModified(w, r, d, mediaType) {
+				return
+			}
+		}
+
 		// Retrieve secret from engine
 		secret, err := engine.Get(ctx, identifier)
-		if err == storage.ErrSecretNotFound {
+		if src := source.Get(); src != "" {
+			w.Header().Set(storage.SourceHeader, src)
+		}
+		if errors.Is(err, storage.ErrSecretNotFound) {
 			http.Error(w, "secret not found", http.StatusNotFound)
 			return
 		}
+		if errors.Is(err, storage.ErrAccessDenied) {
+			http.Error(w, "access denied", http.StatusForbidden)
+			return
+		}
+		if quarantined(w, r, err) {
+			return
+		}
 		if err != nil {
 			log.For(ctx).Error("unable to retrieve secret from engine", zap.Error(err), zap.String("url", r.URL.String()))
 			http.Error(w, "unable to retrieve secret", http.StatusBadRequest)
 			return
 		}
 
+		// Compute digest from value
+		if _, ok := engine.(storage.DigestEngine); !ok {
+			if notModified(w, r, storage.ContentDigest(secret), mediaType) {
+				return
+			}
+		}
+
 		// key is defined
 		if keyRaw != "" {
 			// Retrieve transformer from key
@@ -72,8 +122,170 @@ func backend(namespace string, engine storage.Engine) http.HandlerFunc {
 			}
 		}
 
+		// Encode response
+		body, err := encodeSecret(secret, mediaType)
+		if err != nil {
+			log.For(ctx).Error("unable to encode secret", zap.Error(err), zap.String("url", r.URL.String()))
+			http.Error(w, "unable to encode secret", http.StatusInternalServerError)
+			return
+		}
+		if mediaType == convert.CBORContentType {
+			w.Header().Set("Content-Type", convert.CBORContentType)
+		}
+
 		// Send result
 		w.WriteHeader(http.StatusOK)
-		fmt.Fprintf(w, "%s", secret)
+		fmt.Fprintf(w, "%s", body)
 	}
 }
+
+// digest returns a backend secret digest http request handler.
+func digest(namespace string, engines engineFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Retrieve current namespace engine
+		engine, ok := currentEngine(w, r, engines)
+		if !ok {
+			return
+		}
+
+		var (
+			ctx = storage.WithClientIdentity(r.Context(), storage.ClientIdentityFromTLS(r.TLS))
+			id  = r.URL.Path
+		)
+
+		// Remove namespace and route prefix
+		identifier := strings.TrimPrefix(id, fmt.Sprintf("/%s/digest", namespace))
+
+		// Negotiate response format
+		mediaType := negotiate(r)
+		w.Header().Add("Vary", "Accept")
+
+		// Retrieve digest from engine
+		ctx, source := storage.WithSource(ctx)
+		d, err := storage.GetDigest(ctx, engine, identifier)
+		if src := source.Get(); src != "" {
+			w.Header().Set(storage.SourceHeader, src)
+		}
+		if errors.Is(err, storage.ErrSecretNotFound) {
+			http.Error(w, "secret not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, storage.ErrAccessDenied) {
+			http.Error(w, "access denied", http.StatusForbidden)
+			return
+		}
+		if quarantined(w, r, err) {
+			return
+		}
+		if err != nil {
+			log.For(ctx).Error("unable to retrieve secret digest from engine", zap.Error(err), zap.String("url", r.URL.String()))
+			http.Error(w, "unable to retrieve secret digest", http.StatusBadRequest)
+			return
+		}
+		if notModified(w, r, d, mediaType) {
+			return
+		}
+
+		// Send result
+		if err := writeDocument(w, d, mediaType); err != nil {
+			log.For(ctx).Error("unable to write secret digest", zap.Error(err))
+		}
+	}
+}
+
+// list returns a backend secret listing http request handler. Pagination is
+// enabled using `limit` and `after` query parameters.
+func list(namespace string, engines engineFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Retrieve current namespace engine
+		engine, ok := currentEngine(w, r, engines)
+		if !ok {
+			return
+		}
+
+		var (
+			ctx = storage.WithClientIdentity(r.Context(), storage.ClientIdentityFromTLS(r.TLS))
+			id  = r.URL.Path
+		)
+
+		// Remove namespace and route prefix
+		prefix := strings.TrimPrefix(id, fmt.Sprintf("/%s/list", namespace))
+
+		// Negotiate response format
+		mediaType := negotiate(r)
+		w.Header().Add("Vary", "Accept")
+
+		// Parse pagination parameters
+		req, err := storage.PageRequestFromQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Retrieve page from engine
+		page, err := storage.List(ctx, engine, prefix, req)
+		switch {
+		case errors.Is(err, storage.ErrListNotSupported):
+			http.Error(w, "listing not supported", http.StatusMethodNotAllowed)
+			return
+		case errors.Is(err, storage.ErrInvalidCursor), errors.Is(err, storage.ErrInvalidLimit):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, storage.ErrAccessDenied):
+			http.Error(w, "access denied", http.StatusForbidden)
+			return
+		case err != nil:
+			log.For(ctx).Error("unable to list secrets from engine", zap.Error(err), zap.String("url", r.URL.String()))
+			http.Error(w, "unable to list secrets", http.StatusBadRequest)
+			return
+		default:
+		}
+		if page.Next != "" {
+			w.Header().Set(storage.NextCursorHeader, page.Next)
+		}
+
+		// Send result
+		if err := writeDocument(w, map[string]interface{}{"keys": page.Keys}, mediaType); err != nil {
+			log.For(ctx).Error("unable to write secret listing", zap.Error(err))
+		}
+	}
+}
+
+// quarantined replies with 410 when the secret is quarantined. The reason is
+// logged, never sent to the client.
+func quarantined(w http.ResponseWriter, r *http.Request, err error) bool {
+	if !errors.Is(err, storage.ErrSecretQuarantined) {
+		return false
+	}
+
+	log.For(r.Context()).Warn("Quarantined secret access refused", zap.Error(err), zap.String("url", r.URL.String()))
+	http.Error(w, "secret unavailable", http.StatusGone)
+	return true
+}
+
+// currentEngine retrieves the current namespace engine, and replies with an
+// internal error when the namespace is not available.
+func currentEngine(w http.ResponseWriter, r *http.Request, engines engineFunc) (storage.Engine, bool) {
+	engine, err := engines(r.Context())
+	if err != nil {
+		log.For(r.Context()).Error("unable to retrieve namespace engine", zap.Error(err), zap.String("url", r.URL.String()))
+		http.Error(w, "unable to retrieve namespace", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return engine, true
+}
+
+// notModified sets the ETag header and replies with 304 when the client
+// representation is up to date.
+func notModified(w http.ResponseWriter, r *http.Request, d *storage.Digest, mediaType string) bool {
+	tag := etag(d, mediaType)
+	w.Header().Set("ETag", tag)
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" && storage.MatchETag(inm, tag) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,216 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/elastic/harp/cmd/harp-server/internal/config"
+	"github.com/elastic/harp/pkg/server/manager"
+	"github.com/elastic/harp/pkg/server/storage"
+	"github.com/elastic/harp/pkg/server/storage/decorators/authz"
+)
+
+type sourceEngine struct {
+	memoryEngine
+	source string
+}
+
+func (e *sourceEngine) Get(ctx context.Context, id string) ([]byte, error) {
+	storage.SetSource(ctx, e.source)
+	return e.memoryEngine.Get(ctx, id)
+}
+
+type staticManager map[string]storage.Engine
+
+func (m staticManager) GetSecret(ctx context.Context, ns, id string) ([]byte, error) {
+	return m[ns].Get(ctx, id)
+}
+
+func (m staticManager) Register(context.Context, string, string, ...func(storage.Engine) storage.Engine) error {
+	return nil
+}
+
+func (m staticManager) GetNameSpace(_ context.Context, ns string) (storage.Engine, error) {
+	e, ok := m[ns]
+	if !ok {
+		return nil, manager.ErrNamespaceNotFound
+	}
+	return e, nil
+}
+
+func TestBackends_Source(t *testing.T) {
+	cfg := &config.Configuration{
+		Backends: []config.Backend{{NS: "secrets"}},
+	}
+	bm := staticManager{
+		"secrets": &sourceEngine{
+			memoryEngine: memoryEngine{"/app/database": `{"user":"harp"}`},
+			source:       "stale-cache",
+		},
+	}
+
+	h, err := Backends(context.Background(), cfg, bm)
+	if err != nil {
+		t.Fatalf("unable to build router: %v", err)
+	}
+
+	testCases := []struct {
+		desc       string
+		method     string
+		path       string
+		wantStatus int
+		wantSource string
+	}{
+		{desc: "read", method: http.MethodGet, path: "/secrets/app/database", wantStatus: http.StatusOK, wantSource: "stale-cache"},
+		{desc: "not found", method: http.MethodGet, path: "/secrets/app/missing", wantStatus: http.StatusNotFound, wantSource: "stale-cache"},
+		{desc: "write", method: http.MethodPut, path: "/secrets/app/database", wantStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(tC.method, tC.path, nil))
+
+			if rec.Code != tC.wantStatus {
+				t.Errorf("expected status %d, got %d", tC.wantStatus, rec.Code)
+			}
+			if got := rec.Header().Get(storage.SourceHeader); got != tC.wantSource {
+				t.Errorf("expected source %q, got %q", tC.wantSource, got)
+			}
+		})
+	}
+}
+
+func TestBackends_SPIFFE(t *testing.T) {
+	cfg := &config.Configuration{
+		Backends: []config.Backend{{NS: "secrets"}},
+	}
+	d, err := authz.SPIFFE("secrets", []string{"spiffe://example.org/ns/prod/*"})
+	if err != nil {
+		t.Fatalf("unable to build decorator: %v", err)
+	}
+	bm := staticManager{
+		"secrets": d(memoryEngine{"/app/database": `{"user":"harp"}`}),
+	}
+
+	h, err := Backends(context.Background(), cfg, bm)
+	if err != nil {
+		t.Fatalf("unable to build router: %v", err)
+	}
+
+	// svid returns a verified TLS connection state with the given SPIFFE ID.
+	svid := func(id string) *tls.ConnectionState {
+		u, _ := url.Parse(id)
+		return &tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{{URIs: []*url.URL{u}}}},
+		}
+	}
+
+	// commonName returns a verified TLS connection state of a certificate
+	// without SPIFFE ID.
+	commonName := func(cn string) *tls.ConnectionState {
+		return &tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: cn}}}},
+		}
+	}
+
+	testCases := []struct {
+		desc       string
+		tls        *tls.ConnectionState
+		wantStatus int
+	}{
+		{desc: "allowed", tls: svid("spiffe://example.org/ns/prod/sa/app"), wantStatus: http.StatusOK},
+		{desc: "denied", tls: svid("spiffe://example.org/ns/dev/sa/app"), wantStatus: http.StatusForbidden},
+		{desc: "common name SPIFFE ID", tls: commonName("spiffe://example.org/ns/prod/sa/app"), wantStatus: http.StatusForbidden},
+		{desc: "anonymous", wantStatus: http.StatusForbidden},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/secrets/app/database", nil)
+			req.TLS = tC.tls
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tC.wantStatus {
+				t.Errorf("expected status %d, got %d", tC.wantStatus, rec.Code)
+			}
+		})
+	}
+}
+
+type quarantineEngine struct {
+	memoryEngine
+	quarantined map[string]string
+}
+
+func (e *quarantineEngine) Get(ctx context.Context, id string) ([]byte, error) {
+	if reason, ok := e.quarantined[id]; ok {
+		return nil, fmt.Errorf("package '%s' is quarantined (%s): %w", id, reason, storage.ErrSecretQuarantined)
+	}
+	return e.memoryEngine.Get(ctx, id)
+}
+
+func TestBackends_Quarantined(t *testing.T) {
+	cfg := &config.Configuration{
+		Backends: []config.Backend{{NS: "secrets"}},
+	}
+	bm := staticManager{
+		"secrets": &quarantineEngine{
+			memoryEngine: memoryEngine{"/app/database": `{"user":"harp"}`},
+			quarantined:  map[string]string{"/app/leaked": "leaked in CI logs"},
+		},
+	}
+
+	h, err := Backends(context.Background(), cfg, bm)
+	if err != nil {
+		t.Fatalf("unable to build router: %v", err)
+	}
+
+	testCases := []struct {
+		desc       string
+		path       string
+		wantStatus int
+	}{
+		{desc: "active", path: "/secrets/app/database", wantStatus: http.StatusOK},
+		{desc: "quarantined", path: "/secrets/app/leaked", wantStatus: http.StatusGone},
+		{desc: "quarantined digest", path: "/secrets/digest/app/leaked", wantStatus: http.StatusGone},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tC.path, nil))
+
+			if rec.Code != tC.wantStatus {
+				t.Errorf("expected status %d, got %d", tC.wantStatus, rec.Code)
+			}
+			if strings.Contains(rec.Body.String(), "leaked in CI logs") {
+				t.Error("quarantine reason must not be sent to the client")
+			}
+		})
+	}
+}
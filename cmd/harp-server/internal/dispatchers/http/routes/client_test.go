@@ -0,0 +1,141 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-chi/chi"
+
+	"github.com/elastic/harp/cmd/harp-server/internal/config"
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+	"github.com/elastic/harp/pkg/client"
+	"github.com/elastic/harp/pkg/server/storage"
+	"github.com/elastic/harp/pkg/server/storage/decorators/authz"
+)
+
+func TestBackends_Client(t *testing.T) {
+	ctx := context.Background()
+
+	// Prepare backends
+	b := testbundle.New().
+		Package("app/production/database").Secret("user", "harp").
+		Package("app/production/queue").Secret("token", "foo").
+		Package("app/staging/database").Secret("user", "test").
+		Build()
+	path := filepath.Join(t.TempDir(), "secrets.bundle")
+	if err := ioutil.WriteFile(path, testbundle.Container(t, b), 0o600); err != nil {
+		t.Fatalf("unable to write container: %v", err)
+	}
+	engine, err := storage.Build("bundle://" + path)
+	if err != nil {
+		t.Fatalf("unable to load container: %v", err)
+	}
+	restricted, err := authz.SPIFFE("restricted", []string{"spiffe://example.org/app"})
+	if err != nil {
+		t.Fatalf("unable to build authorization decorator: %v", err)
+	}
+
+	h, err := Backends(ctx, &config.Configuration{
+		Backends: []config.Backend{{NS: "secrets"}, {NS: "restricted"}},
+	}, staticManager{"secrets": engine, "restricted": restricted(engine)})
+	if err != nil {
+		t.Fatalf("unable to build router: %v", err)
+	}
+
+	// Expose the API as the server does
+	var notModified int32
+	r := chi.NewRouter()
+	r.Mount("/api/v1", http.StripPrefix("/api/v1", h))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code == http.StatusNotModified {
+			atomic.AddInt32(&notModified, 1)
+		}
+		for k, v := range rec.Header() {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(rec.Code)
+		_, _ = w.Write(rec.Body.Bytes())
+	}))
+	defer srv.Close()
+
+	c, err := client.New(srv.URL, client.WithPageSize(1))
+	if err != nil {
+		t.Fatalf("unable to create client: %v", err)
+	}
+
+	t.Run("conditional get", func(t *testing.T) {
+		first, err := c.GetSecret(ctx, "secrets", "app/production/database")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		second, err := c.GetSecret(ctx, "secrets", "app/production/database")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(first) != string(second) || len(first) == 0 {
+			t.Fatalf("cached secret mismatch: %s != %s", first, second)
+		}
+		if atomic.LoadInt32(&notModified) != 1 {
+			t.Fatalf("expected the second request to be served as not modified")
+		}
+
+		d, err := c.GetDigest(ctx, "secrets", "app/production/database")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if d.Value == "" {
+			t.Fatal("expected digest value")
+		}
+	})
+
+	t.Run("paginated listing", func(t *testing.T) {
+		keys, err := c.ListPaths(ctx, "secrets", "app/production")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(keys, []string{"database", "queue"}) {
+			t.Fatalf("unexpected keys: %v", keys)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		if _, err := c.GetSecret(ctx, "secrets", "app/missing"); !errors.Is(err, client.ErrNotFound) {
+			t.Fatalf("expected not found error, got %v", err)
+		}
+	})
+
+	t.Run("unauthenticated client", func(t *testing.T) {
+		if _, err := c.GetSecret(ctx, "restricted", "app/production/database"); !errors.Is(err, client.ErrForbidden) {
+			t.Fatalf("expected forbidden error, got %v", err)
+		}
+		if _, err := c.ListPaths(ctx, "restricted", ""); !errors.Is(err, client.ErrForbidden) {
+			t.Fatalf("expected forbidden error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,166 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/cmd/harp-server/internal/config"
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+	"github.com/elastic/harp/pkg/server/storage"
+	_ "github.com/elastic/harp/pkg/server/storage/backends/container"
+)
+
+// loadContainer writes the bundle as a container file and loads it as a
+// fresh backend, as done on server restart.
+func loadContainer(t *testing.T, b *bundlev1.Bundle) http.Handler {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "secrets.bundle")
+	if err := ioutil.WriteFile(path, testbundle.Container(t, b), 0o600); err != nil {
+		t.Fatalf("unable to write container: %v", err)
+	}
+
+	engine, err := storage.Build("bundle://" + path)
+	if err != nil {
+		t.Fatalf("unable to load container: %v", err)
+	}
+
+	h, err := Backends(context.Background(), &config.Configuration{
+		Backends: []config.Backend{{NS: "secrets"}},
+	}, staticManager{"secrets": engine})
+	if err != nil {
+		t.Fatalf("unable to build router: %v", err)
+	}
+
+	return h
+}
+
+func get(h http.Handler, path, etag string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestBackends_ConditionalGet(t *testing.T) {
+	original := testbundle.New().
+		Package("app/database").Secret("user", "harp").Secret("password", "foo").
+		Package("app/queue").Secret("token", "bar").
+		Build()
+	updated := testbundle.New().
+		Package("app/database").Secret("user", "harp").Secret("password", "changed").
+		Package("app/queue").Secret("token", "bar").
+		Build()
+
+	// Initial read
+	rec := get(loadContainer(t, original), "/secrets/app/database", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected ETag header")
+	}
+
+	testCases := []struct {
+		desc       string
+		b          *bundlev1.Bundle
+		path       string
+		wantStatus int
+	}{
+		{desc: "reload without changes", b: original, path: "/secrets/app/database", wantStatus: http.StatusNotModified},
+		{desc: "reload with changes", b: updated, path: "/secrets/app/database", wantStatus: http.StatusOK},
+		{desc: "digest without changes", b: original, path: "/secrets/digest/app/database", wantStatus: http.StatusNotModified},
+		{desc: "digest with changes", b: updated, path: "/secrets/digest/app/database", wantStatus: http.StatusOK},
+		{desc: "other package", b: original, path: "/secrets/app/queue", wantStatus: http.StatusOK},
+		{desc: "not found", b: original, path: "/secrets/digest/app/missing", wantStatus: http.StatusNotFound},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			rec := get(loadContainer(t, tC.b), tC.path, etag)
+			if rec.Code != tC.wantStatus {
+				t.Fatalf("expected status %d, got %d", tC.wantStatus, rec.Code)
+			}
+			if rec.Code == http.StatusNotFound {
+				return
+			}
+			if got := rec.Header().Get("ETag"); (got == etag) != (tC.wantStatus == http.StatusNotModified) {
+				t.Errorf("unexpected ETag %s (previous %s)", got, etag)
+			}
+			if rec.Code == http.StatusNotModified && rec.Body.Len() != 0 {
+				t.Errorf("304 response must not have a body, got %q", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestBackends_Digest(t *testing.T) {
+	h := loadContainer(t, testbundle.New().Package("app/database").Secret("user", "harp").Build())
+
+	rec := get(h, "/secrets/digest/app/database", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var d storage.Digest
+	if err := json.Unmarshal(rec.Body.Bytes(), &d); err != nil {
+		t.Fatalf("unable to decode digest: %v", err)
+	}
+	if d.Value == "" || d.ETag() != rec.Header().Get("ETag") {
+		t.Errorf("unexpected digest %+v", d)
+	}
+
+	// Digest and value routes share the same entity tag
+	if got := get(h, "/secrets/app/database", "").Header().Get("ETag"); got != d.ETag() {
+		t.Errorf("expected ETag %s, got %s", d.ETag(), got)
+	}
+}
+
+func TestBackends_ContentDigest(t *testing.T) {
+	engine := memoryEngine{"/app/database": `{"user":"harp"}`}
+	h, err := Backends(context.Background(), &config.Configuration{
+		Backends: []config.Backend{{NS: "secrets"}},
+	}, staticManager{"secrets": engine})
+	if err != nil {
+		t.Fatalf("unable to build router: %v", err)
+	}
+
+	etag := get(h, "/secrets/app/database", "").Header().Get("ETag")
+	if rec := get(h, "/secrets/app/database", etag); rec.Code != http.StatusNotModified {
+		t.Errorf("expected status 304, got %d", rec.Code)
+	}
+
+	engine["/app/database"] = `{"user":"admin"}`
+	if rec := get(h, "/secrets/app/database", etag); rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+	if rec := get(h, "/secrets/digest/app/database", etag); rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,110 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+	"github.com/elastic/harp/pkg/server/storage"
+)
+
+func listKeys(t *testing.T, h http.Handler, path string) ([]string, string) {
+	t.Helper()
+
+	rec := get(h, path, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200 for %s, got %d (%s)", path, rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Keys []string `json:"keys"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unable to decode listing: %v", err)
+	}
+
+	return resp.Keys, rec.Header().Get(storage.NextCursorHeader)
+}
+
+func TestBackends_List(t *testing.T) {
+	pb := testbundle.New().Package("infra/vault").Secret("token", "foo")
+	for i := 0; i < 25; i++ {
+		pb = pb.Package(fmt.Sprintf("app/pkg-%02d", i)).Secret("k", "v")
+	}
+	b := pb.Build()
+
+	t.Run("unpaginated", func(t *testing.T) {
+		keys, next := listKeys(t, loadContainer(t, b), "/secrets/list")
+		if !reflect.DeepEqual(keys, []string{"app/", "infra/"}) || next != "" {
+			t.Errorf("unexpected listing %v (next %q)", keys, next)
+		}
+
+		keys, next = listKeys(t, loadContainer(t, b), "/secrets/list/app")
+		if len(keys) != 25 || next != "" {
+			t.Errorf("unexpected listing %v (next %q)", keys, next)
+		}
+	})
+
+	t.Run("cursor stable across reloads", func(t *testing.T) {
+		var (
+			all   []string
+			after string
+			pages int
+		)
+		for {
+			// Every page is served by a freshly loaded container
+			keys, next := listKeys(t, loadContainer(t, b), "/secrets/list/app/?limit=10&after="+after)
+			all = append(all, keys...)
+			pages++
+			if next == "" {
+				break
+			}
+			if next != keys[len(keys)-1] {
+				t.Fatalf("cursor %q must be the last key of the page", next)
+			}
+			after = next
+		}
+
+		if pages != 3 || len(all) != 25 {
+			t.Fatalf("expected 25 keys in 3 pages, got %d keys in %d pages", len(all), pages)
+		}
+		for i, k := range all {
+			if k != fmt.Sprintf("pkg-%02d", i) {
+				t.Fatalf("unexpected key %q at %d", k, i)
+			}
+		}
+	})
+
+	t.Run("invalid parameters", func(t *testing.T) {
+		h := loadContainer(t, b)
+		for _, path := range []string{
+			"/secrets/list/app?after=pkg-01/nested",
+			"/secrets/list/app?limit=0",
+			"/secrets/list/app?limit=abc",
+		} {
+			if rec := get(h, path, ""); rec.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400 for %s, got %d", path, rec.Code)
+			}
+		}
+	})
+}
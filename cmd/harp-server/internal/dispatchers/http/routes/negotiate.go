@@ -0,0 +1,118 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/harp/pkg/sdk/convert"
+	"github.com/elastic/harp/pkg/server/storage"
+)
+
+const jsonContentType = "application/json"
+
+// negotiate returns the response media type selected from the request Accept
+// header. CBOR is used only when explicitly preferred, JSON is the fallback
+// for missing, wildcard or unknown media types.
+func negotiate(r *http.Request) string {
+	var (
+		jsonQ, cborQ     float64 = -1, -1
+		jsonPos, cborPos int
+	)
+
+	for i, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		// Parse quality factor
+		q := 1.0
+		if raw, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(raw, 64); err != nil {
+				continue
+			}
+		}
+
+		switch mediaType {
+		case convert.CBORContentType:
+			if q > cborQ {
+				cborQ, cborPos = q, i
+			}
+		case jsonContentType, "application/*", "*/*":
+			if q > jsonQ {
+				jsonQ, jsonPos = q, i
+			}
+		default:
+		}
+	}
+
+	// Prefer the first listed media type on equal quality
+	if cborQ > 0 && (cborQ > jsonQ || (cborQ == jsonQ && cborPos < jsonPos)) {
+		return convert.CBORContentType
+	}
+
+	return jsonContentType
+}
+
+// etag returns the representation specific entity tag of the given digest.
+func etag(d *storage.Digest, mediaType string) string {
+	if mediaType == convert.CBORContentType {
+		return fmt.Sprintf("%q", d.Value+"+cbor")
+	}
+	return d.ETag()
+}
+
+// encodeSecret returns the secret payload in the given media type.
+func encodeSecret(secret []byte, mediaType string) ([]byte, error) {
+	if mediaType != convert.CBORContentType {
+		return secret, nil
+	}
+
+	return convert.ContentToCBOR(secret)
+}
+
+// writeDocument sends the given value encoded in the given media type.
+func writeDocument(w http.ResponseWriter, v interface{}, mediaType string) error {
+	// Encode as JSON first to share the same logical structure
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("unable to encode response: %w", err)
+	}
+
+	if mediaType == convert.CBORContentType {
+		body, err = convert.JSONtoCBOR(body)
+		if err != nil {
+			return fmt.Errorf("unable to encode response: %w", err)
+		}
+		w.Header().Set("Content-Type", convert.CBORContentType)
+	} else {
+		body = append(body, '\n')
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	}
+
+	// Send result
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(body)
+	return err
+}
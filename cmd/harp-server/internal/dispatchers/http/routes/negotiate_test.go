@@ -0,0 +1,150 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/ugorji/go/codec"
+
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+)
+
+func Test_negotiate(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		accept string
+		want   string
+	}{
+		{desc: "missing", want: jsonContentType},
+		{desc: "json", accept: "application/json", want: jsonContentType},
+		{desc: "cbor", accept: "application/cbor", want: "application/cbor"},
+		{desc: "cbor with wildcard fallback", accept: "application/cbor, */*;q=0.1", want: "application/cbor"},
+		{desc: "json preferred", accept: "application/cbor;q=0.5, application/json", want: jsonContentType},
+		{desc: "first listed on tie", accept: "application/cbor, application/json", want: "application/cbor"},
+		{desc: "cbor refused", accept: "application/cbor;q=0", want: jsonContentType},
+		{desc: "wildcard", accept: "*/*", want: jsonContentType},
+		{desc: "unknown", accept: "text/html", want: jsonContentType},
+		{desc: "malformed", accept: "application/cbor;q=high, ;;", want: jsonContentType},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tC.accept != "" {
+				req.Header.Set("Accept", tC.accept)
+			}
+			if got := negotiate(req); got != tC.want {
+				t.Errorf("expected %q, got %q", tC.want, got)
+			}
+		})
+	}
+}
+
+func getWithAccept(h http.Handler, path, accept, etag string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Accept", accept)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+// decodeCBOR decodes a CBOR payload and normalizes it as a JSON value.
+func decodeCBOR(t *testing.T, payload []byte) interface{} {
+	t.Helper()
+
+	h := &codec.CborHandle{}
+	h.MapType = reflect.TypeOf(map[string]interface{}(nil))
+
+	var v interface{}
+	if err := codec.NewDecoderBytes(payload, h).Decode(&v); err != nil {
+		t.Fatalf("unable to decode CBOR response: %v", err)
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("unable to encode value: %v", err)
+	}
+
+	return decodeJSON(t, raw)
+}
+
+func decodeJSON(t *testing.T, payload []byte) interface{} {
+	t.Helper()
+
+	var v interface{}
+	if err := json.Unmarshal(payload, &v); err != nil {
+		t.Fatalf("unable to decode JSON response: %v", err)
+	}
+	return v
+}
+
+func TestBackends_CBOR(t *testing.T) {
+	h := loadContainer(t, testbundle.New().
+		Package("app/database").Secret("user", "harp").Secret("password", "foo").Secret("port", 5432).
+		Package("app/queue").Secret("token", "bar").
+		Build())
+
+	for _, path := range []string{"/secrets/app/database", "/secrets/digest/app/database", "/secrets/list/app"} {
+		t.Run(path, func(t *testing.T) {
+			jsonRec := getWithAccept(h, path, "application/json", "")
+			cborRec := getWithAccept(h, path, "application/cbor", "")
+			if jsonRec.Code != http.StatusOK || cborRec.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d and %d", jsonRec.Code, cborRec.Code)
+			}
+
+			// Same logical structure
+			if got := cborRec.Header().Get("Content-Type"); got != "application/cbor" {
+				t.Errorf("unexpected content type %q", got)
+			}
+			want, got := decodeJSON(t, jsonRec.Body.Bytes()), decodeCBOR(t, cborRec.Body.Bytes())
+			if !reflect.DeepEqual(want, got) {
+				t.Errorf("response mismatch, json %v, cbor %v", want, got)
+			}
+
+			// Deterministic encoding
+			again := getWithAccept(h, path, "application/cbor", "")
+			if !bytes.Equal(cborRec.Body.Bytes(), again.Body.Bytes()) {
+				t.Error("CBOR encoding must be deterministic")
+			}
+			if got := cborRec.Header().Get("Vary"); got != "Accept" {
+				t.Errorf("expected Vary header, got %q", got)
+			}
+		})
+	}
+
+	// Representations have distinct entity tags
+	jsonTag := getWithAccept(h, "/secrets/app/database", "application/json", "").Header().Get("ETag")
+	cborTag := getWithAccept(h, "/secrets/app/database", "application/cbor", "").Header().Get("ETag")
+	if jsonTag == cborTag {
+		t.Errorf("JSON and CBOR entity tags must differ, got %s", jsonTag)
+	}
+	if rec := getWithAccept(h, "/secrets/app/database", "application/cbor", cborTag); rec.Code != http.StatusNotModified {
+		t.Errorf("expected status 304, got %d", rec.Code)
+	}
+	if rec := getWithAccept(h, "/secrets/app/database", "application/cbor", jsonTag); rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,252 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/server/storage"
+)
+
+const (
+	pemContentType    = "application/x-pem-file"
+	textContentType   = "text/plain"
+	binaryContentType = "application/octet-stream"
+	base64ContentType = "application/base64"
+)
+
+// raw returns a raw secret value http request handler. The request path is
+// `/raw/<namespace>/<package path>/<key>` and the response body is the value
+// content with a Content-Type inferred from the value type.
+func raw(namespace string, engines engineFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Retrieve current namespace engine
+		engine, ok := currentEngine(w, r, engines)
+		if !ok {
+			return
+		}
+
+		var (
+			ctx = storage.WithClientIdentity(r.Context(), storage.ClientIdentityFromTLS(r.TLS))
+			id  = r.URL.Path
+		)
+
+		// Remove route and namespace prefix
+		identifier := strings.TrimPrefix(id, fmt.Sprintf("/raw/%s", namespace))
+
+		// Split package path and secret key
+		idx := strings.LastIndex(identifier, "/")
+		if idx <= 0 || idx == len(identifier)-1 {
+			http.Error(w, "raw value path must reference a secret key", http.StatusBadRequest)
+			return
+		}
+		path, key := identifier[:idx], identifier[idx+1:]
+
+		// Retrieve secret from engine
+		ctx, source := storage.WithSource(ctx)
+		secret, err := engine.Get(ctx, path)
+		if src := source.Get(); src != "" {
+			w.Header().Set(storage.SourceHeader, src)
+		}
+		if errors.Is(err, storage.ErrSecretNotFound) {
+			http.Error(w, "secret not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, storage.ErrAccessDenied) {
+			http.Error(w, "access denied", http.StatusForbidden)
+			return
+		}
+		if quarantined(w, r, err) {
+			return
+		}
+		if err != nil {
+			log.For(ctx).Error("unable to retrieve secret from engine", zap.Error(err), zap.String("url", r.URL.String()))
+			http.Error(w, "unable to retrieve secret", http.StatusBadRequest)
+			return
+		}
+
+		// Extract secret value
+		value, mediaType, err := rawValue(secret, key)
+		if errors.Is(err, bundle.ErrSecretKeyNotFound) {
+			http.Error(w, "secret not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.For(ctx).Error("unable to extract secret value", zap.Error(err), zap.String("url", r.URL.String()))
+			http.Error(w, "unable to extract secret value", http.StatusInternalServerError)
+			return
+		}
+
+		// Negotiate response format
+		w.Header().Add("Vary", "Accept")
+		body, contentType, ok := negotiateRaw(r, value, mediaType)
+		if !ok {
+			http.Error(w, "secret value can't be converted to an acceptable media type", http.StatusNotAcceptable)
+			return
+		}
+
+		// Send result
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(body)
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// rawValue extracts the given key from the secret JSON content and returns
+// the value content with its inferred media type.
+func rawValue(secret []byte, key string) ([]byte, string, error) {
+	// Decode secret content
+	var data map[string]interface{}
+	if err := json.Unmarshal(secret, &data); err != nil {
+		return nil, "", fmt.Errorf("unable to decode secret content: %w", err)
+	}
+
+	v, ok := data[key]
+	if !ok {
+		return nil, "", fmt.Errorf("unable to read '%s': %w", key, bundle.ErrSecretKeyNotFound)
+	}
+
+	// Structured values are exposed as JSON
+	s, ok := v.(string)
+	if !ok {
+		body, err := json.Marshal(v)
+		if err != nil {
+			return nil, "", fmt.Errorf("unable to encode secret value: %w", err)
+		}
+		return body, jsonContentType, nil
+	}
+
+	// Infer media type from value type
+	typed := bundle.Sniff([]byte(s))
+	switch typed.Kind {
+	case bundle.KindPEMCertificate, bundle.KindPEMPrivateKey:
+		return typed.Raw, pemContentType, nil
+	case bundle.KindJSON:
+		return typed.Raw, jsonContentType, nil
+	case bundle.KindBase64Blob:
+		// Binary values are base64 encoded in secret JSON content, keep
+		// base64 looking text values untouched.
+		if !utf8.Valid(typed.Blob) {
+			return typed.Blob, binaryContentType, nil
+		}
+	case bundle.KindBytes:
+		return typed.Raw, binaryContentType, nil
+	default:
+	}
+
+	return typed.Raw, textContentType, nil
+}
+
+// negotiateRaw returns the response body and content type selected from the
+// request Accept header. The value is sent as is when its media type is
+// acceptable, text values can be sent as text/plain, and any value can be
+// base64 encoded as application/base64 or text/plain (binary values only).
+func negotiateRaw(r *http.Request, value []byte, mediaType string) ([]byte, string, bool) {
+	accept := r.Header.Get("Accept")
+	if strings.TrimSpace(accept) == "" {
+		return value, withCharset(mediaType), true
+	}
+
+	var (
+		binary                         = mediaType == binaryContentType
+		valueQ, textQ, base64Q float64 = -1, -1, -1
+	)
+	for _, part := range strings.Split(accept, ",") {
+		accepted, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		// Parse quality factor
+		q := 1.0
+		if raw, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(raw, 64); err != nil {
+				continue
+			}
+		}
+
+		if matchMediaType(accepted, mediaType) && q > valueQ {
+			valueQ = q
+		}
+		if matchMediaType(accepted, textContentType) && q > textQ {
+			textQ = q
+		}
+		if matchMediaType(accepted, base64ContentType) && q > base64Q {
+			base64Q = q
+		}
+	}
+
+	// Prefer the value media type, then text, then base64 on equal quality
+	switch {
+	case valueQ > 0 && valueQ >= textQ && valueQ >= base64Q:
+		return value, withCharset(mediaType), true
+	case textQ > 0 && textQ >= base64Q:
+		if binary {
+			return encodeBase64(value), "text/plain; charset=us-ascii", true
+		}
+		return value, "text/plain; charset=utf-8", true
+	case base64Q > 0:
+		return encodeBase64(value), base64ContentType, true
+	default:
+	}
+
+	return nil, "", false
+}
+
+// matchMediaType returns true when the accepted media range matches the
+// given media type.
+func matchMediaType(accepted, mediaType string) bool {
+	if accepted == "*/*" || accepted == mediaType {
+		return true
+	}
+	if strings.HasSuffix(accepted, "/*") {
+		return strings.HasPrefix(mediaType, strings.TrimSuffix(accepted, "*"))
+	}
+	return false
+}
+
+// withCharset adds the charset parameter to text media types.
+func withCharset(mediaType string) string {
+	switch mediaType {
+	case textContentType, jsonContentType, pemContentType:
+		return mediaType + "; charset=utf-8"
+	default:
+	}
+	return mediaType
+}
+
+func encodeBase64(value []byte) []byte {
+	out := make([]byte, base64.StdEncoding.EncodedLen(len(value)))
+	base64.StdEncoding.Encode(out, value)
+	return out
+}
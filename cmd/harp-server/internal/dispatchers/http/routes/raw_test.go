@@ -0,0 +1,180 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/elastic/harp/cmd/harp-server/internal/config"
+	"github.com/elastic/harp/pkg/server/storage/decorators/authz"
+)
+
+func rawTestEngine(t *testing.T) (*quarantineEngine, []byte, []byte) {
+	t.Helper()
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	blob := []byte{0x00, 0x01, 0xfe, 0xff, 0x80, 0x81, 0x00, 0x7f, 0xc3, 0x28, 0x00, 0x01, 0xfe, 0xff}
+
+	content, err := json.Marshal(map[string]interface{}{
+		"user":   "harp",
+		"token":  "dGhpcyBpcyBhIHRleHQ=",
+		"tls":    string(keyPEM),
+		"config": `{"debug":true}`,
+		"nested": map[string]interface{}{"port": 5432},
+		"blob":   blob,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &quarantineEngine{
+		memoryEngine: memoryEngine{"/app/database": string(content)},
+		quarantined:  map[string]string{"/app/leaked": "leaked in CI logs"},
+	}, keyPEM, blob
+}
+
+func TestBackends_Raw(t *testing.T) {
+	engine, keyPEM, blob := rawTestEngine(t)
+
+	h, err := Backends(context.Background(), &config.Configuration{
+		Backends: []config.Backend{{NS: "secrets"}},
+	}, staticManager{"secrets": engine})
+	if err != nil {
+		t.Fatalf("unable to build router: %v", err)
+	}
+
+	testCases := []struct {
+		desc            string
+		path            string
+		accept          string
+		wantStatus      int
+		wantContentType string
+		wantBody        []byte
+	}{
+		{desc: "text", path: "/raw/secrets/app/database/user", wantStatus: http.StatusOK, wantContentType: "text/plain; charset=utf-8", wantBody: []byte("harp")},
+		{desc: "base64 looking text", path: "/raw/secrets/app/database/token", wantStatus: http.StatusOK, wantContentType: "text/plain; charset=utf-8", wantBody: []byte("dGhpcyBpcyBhIHRleHQ=")},
+		{desc: "pem", path: "/raw/secrets/app/database/tls", wantStatus: http.StatusOK, wantContentType: "application/x-pem-file; charset=utf-8", wantBody: keyPEM},
+		{desc: "json", path: "/raw/secrets/app/database/config", wantStatus: http.StatusOK, wantContentType: "application/json; charset=utf-8", wantBody: []byte(`{"debug":true}`)},
+		{desc: "structured", path: "/raw/secrets/app/database/nested", wantStatus: http.StatusOK, wantContentType: "application/json; charset=utf-8", wantBody: []byte(`{"port":5432}`)},
+		{desc: "binary", path: "/raw/secrets/app/database/blob", wantStatus: http.StatusOK, wantContentType: "application/octet-stream", wantBody: blob},
+		{desc: "binary wildcard", path: "/raw/secrets/app/database/blob", accept: "*/*", wantStatus: http.StatusOK, wantContentType: "application/octet-stream", wantBody: blob},
+		{desc: "binary as base64", path: "/raw/secrets/app/database/blob", accept: "application/base64", wantStatus: http.StatusOK, wantContentType: "application/base64", wantBody: []byte(base64.StdEncoding.EncodeToString(blob))},
+		{desc: "binary as text", path: "/raw/secrets/app/database/blob", accept: "text/plain", wantStatus: http.StatusOK, wantContentType: "text/plain; charset=us-ascii", wantBody: []byte(base64.StdEncoding.EncodeToString(blob))},
+		{desc: "pem as text", path: "/raw/secrets/app/database/tls", accept: "text/*", wantStatus: http.StatusOK, wantContentType: "text/plain; charset=utf-8", wantBody: keyPEM},
+		{desc: "text as base64", path: "/raw/secrets/app/database/user", accept: "application/base64, text/plain;q=0.5", wantStatus: http.StatusOK, wantContentType: "application/base64", wantBody: []byte("aGFycA==")},
+		{desc: "not acceptable", path: "/raw/secrets/app/database/user", accept: "application/cbor", wantStatus: http.StatusNotAcceptable},
+		{desc: "unknown key", path: "/raw/secrets/app/database/password", wantStatus: http.StatusNotFound},
+		{desc: "unknown package", path: "/raw/secrets/app/missing/user", wantStatus: http.StatusNotFound},
+		{desc: "quarantined", path: "/raw/secrets/app/leaked/user", wantStatus: http.StatusGone},
+		{desc: "directory", path: "/raw/secrets/app/database/", wantStatus: http.StatusBadRequest},
+		{desc: "missing key", path: "/raw/secrets/database", wantStatus: http.StatusBadRequest},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tC.path, nil)
+			if tC.accept != "" {
+				req.Header.Set("Accept", tC.accept)
+			}
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tC.wantStatus {
+				t.Fatalf("expected status %d, got %d (%s)", tC.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tC.wantStatus != http.StatusOK {
+				return
+			}
+			if got := rec.Header().Get("Content-Type"); got != tC.wantContentType {
+				t.Errorf("expected content type %q, got %q", tC.wantContentType, got)
+			}
+			if !bytes.Equal(rec.Body.Bytes(), tC.wantBody) {
+				t.Errorf("expected body %q, got %q", tC.wantBody, rec.Body.Bytes())
+			}
+		})
+	}
+}
+
+func TestBackends_RawSPIFFE(t *testing.T) {
+	engine, _, _ := rawTestEngine(t)
+
+	d, err := authz.SPIFFE("secrets", []string{"spiffe://example.org/ns/prod/*"})
+	if err != nil {
+		t.Fatalf("unable to build decorator: %v", err)
+	}
+	h, err := Backends(context.Background(), &config.Configuration{
+		Backends: []config.Backend{{NS: "secrets"}},
+	}, staticManager{"secrets": d(engine)})
+	if err != nil {
+		t.Fatalf("unable to build router: %v", err)
+	}
+
+	// svid returns a verified TLS connection state with the given SPIFFE ID.
+	svid := func(id string) *tls.ConnectionState {
+		u, _ := url.Parse(id)
+		return &tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{{URIs: []*url.URL{u}}}},
+		}
+	}
+
+	testCases := []struct {
+		desc       string
+		tls        *tls.ConnectionState
+		wantStatus int
+	}{
+		{desc: "allowed", tls: svid("spiffe://example.org/ns/prod/sa/app"), wantStatus: http.StatusOK},
+		{desc: "denied", tls: svid("spiffe://example.org/ns/dev/sa/app"), wantStatus: http.StatusForbidden},
+		{desc: "anonymous", wantStatus: http.StatusForbidden},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/raw/secrets/app/database/user", nil)
+			req.TLS = tC.tls
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tC.wantStatus {
+				t.Errorf("expected status %d, got %d", tC.wantStatus, rec.Code)
+			}
+			if tC.wantStatus == http.StatusOK && rec.Body.String() != "harp" {
+				t.Errorf("unexpected body %q", rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/nacl/box"
+
+	"github.com/elastic/harp/cmd/harp-server/internal/config"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+	"github.com/elastic/harp/pkg/container"
+	"github.com/elastic/harp/pkg/server/manager"
+	"github.com/elastic/harp/pkg/server/replication"
+)
+
+const replicatedSecretPath = "app/production/security/harp/v1.0.0/server/database"
+
+// sealedContainer returns a container holding the given password, sealed for
+// the given public key.
+func sealedContainer(t *testing.T, password string, peer *[32]byte) []byte {
+	t.Helper()
+
+	b := testbundle.New()
+	b.Package(replicatedSecretPath).Secret("password", password)
+	c, err := bundle.ToContainer(b.Build())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := container.Seal(c, peer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := container.Dump(&buf, sealed); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestBackends_Replicated(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// Both servers unseal the namespace container with the same identity
+	peer, identity, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cid := base64.RawURLEncoding.EncodeToString(identity[:])
+
+	newNamespace := func(name string) (manager.Backend, string) {
+		path := filepath.Join(dir, name+".bundle")
+		if err := ioutil.WriteFile(path, sealedContainer(t, "v1", peer), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		bm := manager.Default()
+		if err := bm.Register(ctx, "app", fmt.Sprintf("bundle://%s?cid=%s", path, cid)); err != nil {
+			t.Fatalf("unable to register namespace: %v", err)
+		}
+		return bm, path
+	}
+	primary, primaryPath := newNamespace("primary")
+	replica, replicaPath := newNamespace("replica")
+
+	// Replica router is built once, as done on server start
+	h, err := Backends(ctx, &config.Configuration{
+		Backends: []config.Backend{{NS: "app"}},
+	}, replica)
+	if err != nil {
+		t.Fatalf("unable to build router: %v", err)
+	}
+
+	// Expose the primary snapshot
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := replication.NewSource(priv, map[string]string{"app": fmt.Sprintf("bundle://%s?cid=%s", primaryPath, cid)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ts := httptest.NewServer(src)
+	defer ts.Close()
+
+	r, err := replication.NewReplica(ts.URL, pub, map[string]string{"app": replicaPath}, replica.(manager.Watcher).Reload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertPassword := func(password string) {
+		t.Helper()
+
+		for _, path := range []string{
+			"/app/" + replicatedSecretPath,
+			"/raw/app/" + replicatedSecretPath + "/password",
+		} {
+			rec := get(h, path, "")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("%s: expected status 200, got %d (%s)", path, rec.Code, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), password) {
+				t.Errorf("%s: expected password %q, got %s", path, password, rec.Body.String())
+			}
+		}
+		if rec := get(h, "/app/list/", ""); rec.Code != http.StatusOK {
+			t.Errorf("expected list status 200, got %d (%s)", rec.Code, rec.Body.String())
+		}
+	}
+	assertPassword("v1")
+
+	// Primary updates the namespace
+	if err := ioutil.WriteFile(primaryPath, sealedContainer(t, "v2", peer), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := primary.(manager.Watcher).Reload(ctx, "app"); err != nil {
+		t.Fatalf("unable to reload primary namespace: %v", err)
+	}
+
+	// Replicated secret is served by the router built before the reload
+	if err := r.Sync(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if st := r.Status().Namespaces["app"]; st.State != replication.StateInSync {
+		t.Fatalf("expected in-sync namespace, got %+v", st)
+	}
+	assertPassword("v2")
+}
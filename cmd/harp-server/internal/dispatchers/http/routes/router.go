@@ -30,6 +30,7 @@ import (
 	"github.com/elastic/harp/cmd/harp-server/internal/config"
 	"github.com/elastic/harp/pkg/sdk/log"
 	"github.com/elastic/harp/pkg/server/manager"
+	"github.com/elastic/harp/pkg/server/storage"
 )
 
 // Backends returns an HTTP router for backends
@@ -38,17 +39,24 @@ func Backends(ctx context.Context, cfg *config.Configuration, bm manager.Backend
 
 	// Backends
 	for _, b := range cfg.Backends {
-		// Retrieve backend engine
-		engine, err := bm.GetNameSpace(ctx, b.NS)
-		if err != nil {
+		// Check backend registration
+		if _, err := bm.GetNameSpace(ctx, b.NS); err != nil {
 			return nil, err
 		}
 
+		// Resolve engine on each request, namespaces engines are replaced
+		// on reload
+		engine := namespaceEngine(bm, b.NS)
+
 		// Wrap engine with handler
 		ns := clean(b.NS)
 		r.Route(fmt.Sprintf("/%s", ns), func(r chi.Router) {
+			r.Get("/digest/*", digest(ns, engine))
+			r.Get("/list", list(ns, engine))
+			r.Get("/list/*", list(ns, engine))
 			r.Get("/*", backend(ns, engine))
 		})
+		r.Get(fmt.Sprintf("/raw/%s/*", ns), raw(ns, engine))
 
 		log.For(ctx).Info("Bakend registered", zap.String("path", b.NS))
 	}
@@ -57,6 +65,17 @@ func Backends(ctx context.Context, cfg *config.Configuration, bm manager.Backend
 	return r, nil
 }
 
+// engineFunc returns the current engine of a namespace.
+type engineFunc func(context.Context) (storage.Engine, error)
+
+// namespaceEngine returns an engine resolver retrieving the namespace engine
+// from the backend manager.
+func namespaceEngine(bm manager.Backend, ns string) engineFunc {
+	return func(ctx context.Context) (storage.Engine, error) {
+		return bm.GetNameSpace(ctx, ns)
+	}
+}
+
 func clean(ns string) string {
 	// Remove any starting "/"
 	ns = strings.TrimPrefix(ns, "/")
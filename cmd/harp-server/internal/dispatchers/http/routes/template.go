@@ -0,0 +1,148 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/cmd/harp-server/internal/config"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/server/manager"
+	"github.com/elastic/harp/pkg/server/storage"
+	"github.com/elastic/harp/pkg/template/engine"
+)
+
+const defaultTemplateTimeout = 5 * time.Second
+
+var templateContentTypes = map[string]string{
+	"json":       "application/json",
+	"yaml":       "application/yaml",
+	"properties": "text/x-java-properties; charset=utf-8",
+}
+
+// Templates returns an HTTP router for server-side rendered templates.
+func Templates(ctx context.Context, cfg *config.Configuration, bm manager.Backend) (http.Handler, error) {
+	r := chi.NewRouter()
+
+	for _, t := range cfg.Templates {
+		// Check settings
+		if t.Name == "" {
+			return nil, fmt.Errorf("template name must not be blank")
+		}
+		format := strings.ToLower(t.Format)
+		if format == "" {
+			format = "json"
+		}
+		contentType, ok := templateContentTypes[format]
+		if !ok {
+			return nil, fmt.Errorf("unsupported format '%s' for template '%s'", t.Format, t.Name)
+		}
+		timeout := defaultTemplateTimeout
+		if t.Timeout != "" {
+			d, err := time.ParseDuration(t.Timeout)
+			if err != nil {
+				return nil, fmt.Errorf("invalid timeout for template '%s': %w", t.Name, err)
+			}
+			timeout = d
+		}
+
+		// Check backend registration
+		if _, err := bm.GetNameSpace(ctx, t.NS); err != nil {
+			return nil, fmt.Errorf("unable to retrieve namespace '%s' for template '%s': %w", t.NS, t.Name, err)
+		}
+
+		// Load template content
+		content, err := ioutil.ReadFile(t.Path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read template '%s': %w", t.Name, err)
+		}
+
+		ns := clean(t.NS)
+		r.Get(fmt.Sprintf("/%s/%s", ns, t.Name), renderTemplate(t.Name, string(content), contentType, timeout, namespaceEngine(bm, t.NS)))
+
+		log.For(ctx).Info("Template registered", zap.String("namespace", ns), zap.String("name", t.Name))
+	}
+
+	// Return no error
+	return r, nil
+}
+
+// -----------------------------------------------------------------------------
+
+// renderTemplate returns a template rendering http request handler.
+func renderTemplate(name, content, contentType string, timeout time.Duration, engines engineFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := storage.WithClientIdentity(r.Context(), storage.ClientIdentityFromTLS(r.TLS))
+
+		// Retrieve current namespace engine
+		e, ok := currentEngine(w, r, engines)
+		if !ok {
+			return
+		}
+
+		// Render the template against namespace secrets
+		out, err := engine.RenderSafe(name, content, []engine.SecretReaderFunc{engineSecretReader(ctx, e)}, nil, timeout)
+		if errors.Is(err, storage.ErrAccessDenied) {
+			http.Error(w, "access denied", http.StatusForbidden)
+			return
+		}
+		if quarantined(w, r, err) {
+			return
+		}
+		if err != nil {
+			log.For(ctx).Error("unable to render template", zap.String("name", name), zap.Error(err))
+			http.Error(w, "unable to render template", http.StatusInternalServerError)
+			return
+		}
+
+		// Send result
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, out)
+	}
+}
+
+// engineSecretReader adapts a storage engine as a template secret reader.
+func engineSecretReader(ctx context.Context, e storage.Engine) engine.SecretReaderFunc {
+	return func(path string) (map[string]interface{}, error) {
+		// Retrieve secret from engine
+		raw, err := e.Get(ctx, fmt.Sprintf("/%s", strings.TrimPrefix(path, "/")))
+		if err != nil {
+			return nil, err
+		}
+
+		// Decode secret map
+		var secrets map[string]interface{}
+		if err := json.Unmarshal(raw, &secrets); err != nil {
+			return nil, fmt.Errorf("unable to decode secret '%s' as a map: %w", path, err)
+		}
+
+		// No error
+		return secrets, nil
+	}
+}
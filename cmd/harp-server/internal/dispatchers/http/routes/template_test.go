@@ -0,0 +1,119 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elastic/harp/pkg/server/storage"
+)
+
+type memoryEngine map[string]string
+
+func (e memoryEngine) Get(_ context.Context, id string) ([]byte, error) {
+	v, ok := e[id]
+	if !ok {
+		return nil, storage.ErrSecretNotFound
+	}
+	return []byte(v), nil
+}
+
+func Test_renderTemplate(t *testing.T) {
+	engine := memoryEngine{
+		"/app/production/security/harp/v1.0.0/server/database": `{"user":"harp","password":"foo"}`,
+	}
+
+	testCases := []struct {
+		desc        string
+		template    string
+		wantStatus  int
+		wantBody    string
+		wantNoMatch string
+	}{
+		{
+			desc:       "rendering",
+			template:   `{{ with secret "app/production/security/harp/v1.0.0/server/database" }}db.user={{ .user }}{{ end }}`,
+			wantStatus: http.StatusOK,
+			wantBody:   "db.user=harp",
+		},
+		{
+			desc:        "missing secret",
+			template:    `{{ with secret "app/production/security/harp/v1.0.0/server/missing" }}{{ .user }}{{ end }}`,
+			wantStatus:  http.StatusInternalServerError,
+			wantBody:    "unable to render template",
+			wantNoMatch: "missing",
+		},
+		{
+			desc:        "file access denied",
+			template:    `{{ readFile "/etc/passwd" }}`,
+			wantStatus:  http.StatusInternalServerError,
+			wantBody:    "unable to render template",
+			wantNoMatch: "passwd",
+		},
+		{
+			desc:       "environment access denied",
+			template:   `{{ env "HOME" }}`,
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   "unable to render template",
+		},
+		{
+			desc:       "network access denied",
+			template:   `{{ getHostByName "localhost" }}`,
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   "unable to render template",
+		},
+		{
+			desc:       "safe functions",
+			template:   `{{ "harp" | upper | quote }}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `"HARP"`,
+		},
+		{
+			desc:       "timeout",
+			template:   `{{ range $i := until 100000000 }}{{ end }}`,
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   "unable to render template",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			h := renderTemplate("config", tC.template, templateContentTypes["properties"], 50*time.Millisecond, namespaceEngine(staticManager{"ns": engine}, "ns"))
+
+			rec := httptest.NewRecorder()
+			h(rec, httptest.NewRequest(http.MethodGet, "/template/ns/config", nil))
+
+			if rec.Code != tC.wantStatus {
+				t.Errorf("expected status %d, got %d", tC.wantStatus, rec.Code)
+			}
+			if !strings.Contains(rec.Body.String(), tC.wantBody) {
+				t.Errorf("expected body to contain %q, got %q", tC.wantBody, rec.Body.String())
+			}
+			if tC.wantNoMatch != "" && strings.Contains(rec.Body.String(), tC.wantNoMatch) {
+				t.Errorf("body must not echo template, got %q", rec.Body.String())
+			}
+			if rec.Code == http.StatusOK && rec.Header().Get("Content-Type") != templateContentTypes["properties"] {
+				t.Errorf("unexpected content type %q", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
@@ -22,6 +22,8 @@ package http
 import (
 	"context"
 	"crypto/tls"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -32,20 +34,49 @@ import (
 
 	"github.com/elastic/harp/cmd/harp-server/internal/config"
 	"github.com/elastic/harp/cmd/harp-server/internal/dispatchers/http/routes"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/decryptor"
+	"github.com/elastic/harp/pkg/sdk/httpsign"
 	"github.com/elastic/harp/pkg/sdk/log"
 	"github.com/elastic/harp/pkg/sdk/tlsconfig"
+	"github.com/elastic/harp/pkg/server/spiffe"
 	"github.com/elastic/harp/pkg/server/manager"
+	"github.com/elastic/harp/pkg/server/replication"
 	"github.com/elastic/harp/pkg/server/storage/backends/container"
 )
 
 func backendManager(ctx context.Context, cfg *config.Configuration) (manager.Backend, error) {
+	// Apply expired package policy before loading containers, settings are
+	// already validated
+	expiredPolicy, _ := bundle.ParseExpiredPackagePolicy(cfg.Bundle.ExpiredPackages)
+	container.SetExpiredPackagePolicy(expiredPolicy)
+
+	// Apply memory-mapped loading settings
+	container.SetMmap(cfg.Bundle.Mmap, cfg.Bundle.SharedCacheDir)
+
+	// Apply value decryption settings
+	decryptPolicy, _ := bundle.ParseDecryptFailurePolicy(cfg.Bundle.ValueDecryptionFailure)
+	if cfg.Bundle.ValueDecryptionKey != "" {
+		d, err := decryptor.AESGCM(cfg.Bundle.ValueDecryptionKey)
+		if err != nil {
+			return nil, err
+		}
+		container.SetValueDecryptor(d, decryptPolicy)
+	}
+
 	// Initialize default manager
 	bm := manager.Default()
 
 	// Backends
 	for _, b := range cfg.Backends {
+		// Build response transformations
+		decorators, err := b.Decorators()
+		if err != nil {
+			return nil, err
+		}
+
 		// Register namespace engine
-		if err := bm.Register(ctx, b.NS, b.URL); err != nil {
+		if err := bm.Register(ctx, b.NS, b.URL, decorators...); err != nil {
 			return nil, err
 		}
 	}
@@ -54,7 +85,48 @@ func backendManager(ctx context.Context, cfg *config.Configuration) (manager.Bac
 	return bm, nil
 }
 
-func httpServer(ctx context.Context, cfg *config.Configuration, bm manager.Backend) (*http.Server, error) {
+func replica(ctx context.Context, cfg *config.Configuration, bm manager.Backend) (*replication.Replica, error) {
+	// Replicas only
+	if cfg.Replication.Role != config.ReplicationReplica {
+		return nil, nil
+	}
+
+	// Settings are already validated
+	key, err := cfg.Replication.SnapshotVerificationKey()
+	if err != nil {
+		return nil, err
+	}
+	w, ok := bm.(manager.Watcher)
+	if !ok {
+		return nil, errors.New("backend manager doesn't support namespace reload")
+	}
+
+	// Sign snapshot requests for the primary admin API
+	tlsConfig, err := tlsconfig.Client(&tlsconfig.Options{
+		CAFile: cfg.Replication.CACertificatePath,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to build replication TLS configuration: %w", err)
+	}
+	client := &http.Client{
+		Timeout:   time.Minute,
+		Transport: httpsign.Transport(cfg.Replication.RequestKey(), &http.Transport{TLSClientConfig: tlsConfig}),
+	}
+
+	r, err := replication.NewReplica(cfg.Replication.Primary, key, cfg.ReplicaTargets(), w.Reload, replication.WithHTTPClient(client))
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize replication: %w", err)
+	}
+
+	// Poll the primary
+	go r.Run(ctx, cfg.Replication.PollInterval())
+	log.For(ctx).Info("Replicating namespaces from primary", zap.String("primary", cfg.Replication.Primary))
+
+	// No error
+	return r, nil
+}
+
+func httpServer(ctx context.Context, cfg *config.Configuration, bm manager.Backend, rep *replication.Replica) (*http.Server, error) {
 	r := chi.NewRouter()
 
 	// middleware stack
@@ -78,6 +150,28 @@ func httpServer(ctx context.Context, cfg *config.Configuration, bm manager.Backe
 		r.Mount("/", http.StripPrefix("/api/v1", backendRouter))
 	})
 
+	// Rendered templates endpoint
+	templateRouter, err := routes.Templates(ctx, cfg, bm)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Route("/template", func(r chi.Router) {
+		r.Mount("/", http.StripPrefix("/template", templateRouter))
+	})
+
+	// Admin endpoint
+	if cfg.HTTP.Admin.Enabled {
+		adminRouter, err := routes.Admin(ctx, cfg, rep)
+		if err != nil {
+			return nil, err
+		}
+
+		r.Route("/admin/v1", func(r chi.Router) {
+			r.Mount("/", http.StripPrefix("/admin/v1", adminRouter))
+		})
+	}
+
 	// Assign router to server
 	server := &http.Server{
 		ReadTimeout:       5 * time.Second,
@@ -107,6 +201,16 @@ func httpServer(ctx context.Context, cfg *config.Configuration, bm manager.Backe
 			return nil, err
 		}
 
+		// Fetch rotated client trust bundles from SPIRE Workload API
+		if cfg.HTTP.TLS.WorkloadAPISocket != "" {
+			source, err := spiffe.WatchBundles(ctx, cfg.HTTP.TLS.WorkloadAPISocket)
+			if err != nil {
+				log.For(ctx).Error("Unable to fetch trust bundles from SPIRE Workload API", zap.Error(err))
+				return nil, err
+			}
+			tlsConfig = spiffe.ServerConfig(tlsConfig, source)
+		}
+
 		// Create the TLS credentials
 		server.TLSConfig = tlsConfig
 	} else {
@@ -122,6 +226,7 @@ func httpServer(ctx context.Context, cfg *config.Configuration, bm manager.Backe
 func setup(ctx context.Context, cfg *config.Configuration) (*http.Server, error) {
 	wire.Build(
 		backendManager,
+		replica,
 		httpServer,
 	)
 	return &http.Server{}, nil
@@ -8,11 +8,18 @@ package http
 import (
 	"context"
 	"crypto/tls"
+	"errors"
+	"fmt"
 	"github.com/elastic/harp/cmd/harp-server/internal/config"
 	"github.com/elastic/harp/cmd/harp-server/internal/dispatchers/http/routes"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/decryptor"
+	"github.com/elastic/harp/pkg/sdk/httpsign"
 	"github.com/elastic/harp/pkg/sdk/log"
 	"github.com/elastic/harp/pkg/sdk/tlsconfig"
+	"github.com/elastic/harp/pkg/server/spiffe"
 	"github.com/elastic/harp/pkg/server/manager"
+	"github.com/elastic/harp/pkg/server/replication"
 	"github.com/elastic/harp/pkg/server/storage/backends/container"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
@@ -28,7 +35,11 @@ func setup(ctx context.Context, cfg *config.Configuration) (*http.Server, error)
 	if err != nil {
 		return nil, err
 	}
-	server, err := httpServer(ctx, cfg, backend)
+	replicationReplica, err := replica(ctx, cfg, backend)
+	if err != nil {
+		return nil, err
+	}
+	server, err := httpServer(ctx, cfg, backend, replicationReplica)
 	if err != nil {
 		return nil, err
 	}
@@ -38,12 +49,28 @@ func setup(ctx context.Context, cfg *config.Configuration) (*http.Server, error)
 // wire.go:
 
 func backendManager(ctx context.Context, cfg *config.Configuration) (manager.Backend, error) {
+	expiredPolicy, _ := bundle.ParseExpiredPackagePolicy(cfg.Bundle.ExpiredPackages)
+	container.SetExpiredPackagePolicy(expiredPolicy)
+	container.SetMmap(cfg.Bundle.Mmap, cfg.Bundle.SharedCacheDir)
+
+	decryptPolicy, _ := bundle.ParseDecryptFailurePolicy(cfg.Bundle.ValueDecryptionFailure)
+	if cfg.Bundle.ValueDecryptionKey != "" {
+		d, err := decryptor.AESGCM(cfg.Bundle.ValueDecryptionKey)
+		if err != nil {
+			return nil, err
+		}
+		container.SetValueDecryptor(d, decryptPolicy)
+	}
 
 	bm := manager.Default()
 
 	for _, b := range cfg.Backends {
 
-		if err := bm.Register(ctx, b.NS, b.URL); err != nil {
+		decorators, err := b.Decorators()
+		if err != nil {
+			return nil, err
+		}
+		if err := bm.Register(ctx, b.NS, b.URL, decorators...); err != nil {
 			return nil, err
 		}
 	}
@@ -51,7 +78,43 @@ func backendManager(ctx context.Context, cfg *config.Configuration) (manager.Bac
 	return bm, nil
 }
 
-func httpServer(ctx context.Context, cfg *config.Configuration, bm manager.Backend) (*http.Server, error) {
+func replica(ctx context.Context, cfg *config.Configuration, bm manager.Backend) (*replication.Replica, error) {
+	if cfg.Replication.Role != config.ReplicationReplica {
+		return nil, nil
+	}
+
+	key, err := cfg.Replication.SnapshotVerificationKey()
+	if err != nil {
+		return nil, err
+	}
+	w, ok := bm.(manager.Watcher)
+	if !ok {
+		return nil, errors.New("backend manager doesn't support namespace reload")
+	}
+
+	tlsConfig, err := tlsconfig.Client(&tlsconfig.Options{
+		CAFile: cfg.Replication.CACertificatePath,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to build replication TLS configuration: %w", err)
+	}
+	client := &http.Client{
+		Timeout:   time.Minute,
+		Transport: httpsign.Transport(cfg.Replication.RequestKey(), &http.Transport{TLSClientConfig: tlsConfig}),
+	}
+
+	r, err := replication.NewReplica(cfg.Replication.Primary, key, cfg.ReplicaTargets(), w.Reload, replication.WithHTTPClient(client))
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize replication: %w", err)
+	}
+
+	go r.Run(ctx, cfg.Replication.PollInterval())
+	log.For(ctx).Info("Replicating namespaces from primary", zap.String("primary", cfg.Replication.Primary))
+
+	return r, nil
+}
+
+func httpServer(ctx context.Context, cfg *config.Configuration, bm manager.Backend, rep *replication.Replica) (*http.Server, error) {
 	r := chi.NewRouter()
 
 	r.Use(middleware.RequestID)
@@ -70,6 +133,27 @@ func httpServer(ctx context.Context, cfg *config.Configuration, bm manager.Backe
 		r.Mount("/", http.StripPrefix("/api/v1", backendRouter))
 	})
 
+	templateRouter, err := routes.Templates(ctx, cfg, bm)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Route("/template", func(r chi.Router) {
+		r.Mount("/", http.StripPrefix("/template", templateRouter))
+	})
+
+	// Admin endpoint
+	if cfg.HTTP.Admin.Enabled {
+		adminRouter, err := routes.Admin(ctx, cfg, rep)
+		if err != nil {
+			return nil, err
+		}
+
+		r.Route("/admin/v1", func(r chi.Router) {
+			r.Mount("/", http.StripPrefix("/admin/v1", adminRouter))
+		})
+	}
+
 	server := &http.Server{
 		ReadTimeout:       5 * time.Second,
 		WriteTimeout:      5 * time.Second,
@@ -96,6 +180,16 @@ func httpServer(ctx context.Context, cfg *config.Configuration, bm manager.Backe
 			return nil, err
 		}
 
+		// Fetch rotated client trust bundles from SPIRE Workload API
+		if cfg.HTTP.TLS.WorkloadAPISocket != "" {
+			source, err := spiffe.WatchBundles(ctx, cfg.HTTP.TLS.WorkloadAPISocket)
+			if err != nil {
+				log.For(ctx).Error("Unable to fetch trust bundles from SPIRE Workload API", zap.Error(err))
+				return nil, err
+			}
+			tlsConfig = spiffe.ServerConfig(tlsConfig, source)
+		}
+
 		server.TLSConfig = tlsConfig
 	} else {
 		log.For(ctx).Info("No transport encryption enabled for HTTP server")
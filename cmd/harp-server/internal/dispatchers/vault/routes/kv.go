@@ -18,7 +18,8 @@
 package routes
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -35,31 +36,68 @@ import (
 	vpath "github.com/elastic/harp/pkg/vault/path"
 )
 
+func init() {
+	// Vault uses the LIST verb to enumerate secrets
+	chi.RegisterMethod("LIST")
+}
+
 // KVHandler initializes Vault KV API handler for given bundle
 func KVHandler(bm manager.Backend) http.Handler {
-	r := chi.NewRouter()
+	return newVaultKVHandler(bm).routes()
+}
+
+// KV is an alias to map for readability.
+type KV map[string]interface{}
+
+type vaultKVHandler struct {
+	bm       manager.Backend
+	wraps    *wrappingStore
+	payloads *payloadCache
+}
 
+func newVaultKVHandler(bm manager.Backend) *vaultKVHandler {
 	// Initialize controler
 	ctrl := &vaultKVHandler{
-		bm: bm,
+		bm:       bm,
+		wraps:    newWrappingStore(DefaultMaxWrappedEntries, DefaultMaxWrappedBytes),
+		payloads: newPayloadCache(DefaultMaxCachedPayloads, DefaultMaxCachedPayloadBytes),
 	}
 
-	// Map routes
-	r.Get("/v1/sys/seal-status", ctrl.sealStatus())
-	r.Get("/v1/sys/leader", ctrl.leaderStatus())
-	r.Put("/v1/auth/token/renew-self", ctrl.selfRenew())
-	r.Get("/v1/secret/config", ctrl.getConfig())
-	r.Get("/v1/sys/internal/ui/mounts/*", ctrl.getMount())
-	r.Get("/v1/secret/data/*", ctrl.getSecret())
+	// Drop cached payloads when a namespace is reloaded
+	if n, ok := bm.(manager.ReloadNotifier); ok {
+		n.OnReload(func(string) {
+			ctrl.payloads.Invalidate()
+		})
+	}
 
-	return r
+	return ctrl
 }
 
-// KV is an alias to map for readability.
-type KV map[string]interface{}
+func (h *vaultKVHandler) routes() http.Handler {
+	r := chi.NewRouter()
 
-type vaultKVHandler struct {
-	bm manager.Backend
+	// Map routes
+	r.Get("/v1/sys/seal-status", h.sealStatus())
+	r.Get("/v1/sys/leader", h.leaderStatus())
+	r.Put("/v1/auth/token/renew-self", h.selfRenew())
+	r.Get("/v1/sys/internal/ui/mounts/*", h.getMount())
+	r.Put("/v1/sys/wrapping/unwrap", h.unwrap())
+	r.Post("/v1/sys/wrapping/unwrap", h.unwrap())
+	r.Put("/v1/sys/wrapping/lookup", h.lookup())
+	r.Post("/v1/sys/wrapping/lookup", h.lookup())
+
+	// Secret routes support response wrapping
+	r.Group(func(r chi.Router) {
+		r.Use(h.wrap)
+		r.Get("/v1/secret/config", h.getConfig())
+		r.Get("/v1/secret/data/*", h.getSecret())
+	})
+
+	// Listing is never wrapped
+	r.MethodFunc("LIST", "/v1/secret/metadata/*", h.listSecrets())
+	r.Get("/v1/secret/metadata/*", h.listSecrets())
+
+	return r
 }
 
 func (h *vaultKVHandler) sealStatus() http.HandlerFunc {
@@ -130,7 +168,7 @@ func (h *vaultKVHandler) getConfig() http.HandlerFunc {
 
 func (h *vaultKVHandler) getSecret() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
+		ctx := storage.WithClientIdentity(r.Context(), storage.ClientIdentityFromTLS(r.TLS))
 
 		// Get namespace from headers
 		ns := slug.Make(r.Header.Get("X-Vault-Namespace"))
@@ -142,33 +180,141 @@ func (h *vaultKVHandler) getSecret() http.HandlerFunc {
 		p := strings.TrimPrefix(r.URL.Path, "/v1/secret/data")
 
 		// Retrieve secret from engine
+		ctx, source := storage.WithSource(ctx)
 		secret, err := h.bm.GetSecret(ctx, vpath.SanitizePath(ns), p)
-		if err == storage.ErrSecretNotFound {
+		if src := source.Get(); src != "" {
+			w.Header().Set(storage.SourceHeader, src)
+		}
+		if errors.Is(err, storage.ErrSecretNotFound) {
 			http.Error(w, "secret not found", http.StatusNotFound)
 			return
 		}
+		if errors.Is(err, storage.ErrAccessDenied) {
+			http.Error(w, "permission denied", http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, storage.ErrSecretQuarantined) {
+			log.For(ctx).Warn("Quarantined secret access refused", zap.Error(err), zap.String("url", r.URL.String()))
+			http.Error(w, "secret unavailable", http.StatusGone)
+			return
+		}
 		if err != nil {
 			log.For(ctx).Error("unable to retrieve secret from engine", zap.Error(err), zap.String("url", r.URL.String()))
 			http.Error(w, "unable to retrieve secret", http.StatusBadRequest)
 			return
 		}
 
-		// Decode secret as JSON
-		var data interface{}
-		if err := json.Unmarshal(secret, &data); err != nil {
+		// Conditional request
+		d, err := h.digest(ctx, vpath.SanitizePath(ns), p, secret)
+		if err != nil {
+			log.For(ctx).Error("unable to retrieve secret digest from engine", zap.Error(err), zap.String("url", r.URL.String()))
+			http.Error(w, "unable to retrieve secret", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("ETag", d.ETag())
+		if inm := r.Header.Get("If-None-Match"); inm != "" && storage.MatchETag(inm, d.ETag()) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		// Serialize secret payload once per value
+		payload, err := h.payloads.payload(vpath.SanitizePath(ns)+p, secret)
+		if err != nil {
 			log.For(ctx).Error("unable to decode secret from engine", zap.Error(err), zap.String("url", r.URL.String()))
 			http.Error(w, "unable to decode secret", http.StatusBadRequest)
 			return
 		}
 
+		// Send response
+		withSecret(w, r, payload)
+	}
+}
+
+func (h *vaultKVHandler) listSecrets() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := storage.WithClientIdentity(r.Context(), storage.ClientIdentityFromTLS(r.TLS))
+
+		// Only listing is supported on metadata
+		if r.Method == http.MethodGet && r.URL.Query().Get("list") != "true" {
+			http.Error(w, "unsupported operation", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// Get namespace from headers
+		ns := slug.Make(r.Header.Get("X-Vault-Namespace"))
+		if ns == "" {
+			ns = "root"
+		}
+
+		// Extract path
+		p := strings.TrimPrefix(r.URL.Path, "/v1/secret/metadata")
+
+		// Parse opt-in pagination, Vault clients list everything.
+		req, err := storage.PageRequestFromQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Retrieve engine
+		engine, err := h.bm.GetNameSpace(ctx, vpath.SanitizePath(ns))
+		if errors.Is(err, manager.ErrNamespaceNotFound) {
+			http.Error(w, "namespace not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.For(ctx).Error("unable to retrieve namespace engine", zap.Error(err), zap.String("url", r.URL.String()))
+			http.Error(w, "unable to list secrets", http.StatusBadRequest)
+			return
+		}
+
+		// Retrieve page from engine
+		page, err := storage.List(ctx, engine, p, req)
+		switch {
+		case errors.Is(err, storage.ErrListNotSupported):
+			http.Error(w, "unsupported operation", http.StatusMethodNotAllowed)
+			return
+		case errors.Is(err, storage.ErrInvalidCursor), errors.Is(err, storage.ErrInvalidLimit):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, storage.ErrAccessDenied):
+			http.Error(w, "permission denied", http.StatusForbidden)
+			return
+		case err != nil:
+			log.For(ctx).Error("unable to list secrets from engine", zap.Error(err), zap.String("url", r.URL.String()))
+			http.Error(w, "unable to list secrets", http.StatusBadRequest)
+			return
+		default:
+		}
+
+		// Vault returns not found for empty listings
+		if len(page.Keys) == 0 {
+			http.Error(w, "secret not found", http.StatusNotFound)
+			return
+		}
+		if page.Next != "" {
+			w.Header().Set(storage.NextCursorHeader, page.Next)
+		}
+
 		// Send response
 		with(w, r, http.StatusOK, &KV{
 			"data": &KV{
-				"data": data,
-			},
-			"metadata": &KV{
-				"version": "1",
+				"keys": page.Keys,
 			},
 		})
 	}
 }
+
+// digest returns the secret digest from the namespace engine if supported, or
+// computed from the secret value.
+func (h *vaultKVHandler) digest(ctx context.Context, ns, id string, secret []byte) (*storage.Digest, error) {
+	engine, err := h.bm.GetNameSpace(ctx, ns)
+	if err != nil {
+		return nil, err
+	}
+	if de, ok := engine.(storage.DigestEngine); ok {
+		return de.Digest(ctx, id)
+	}
+
+	return storage.ContentDigest(secret), nil
+}
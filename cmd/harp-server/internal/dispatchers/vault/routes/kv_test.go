@@ -0,0 +1,154 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/elastic/harp/pkg/server/storage"
+)
+
+func TestGetSecret_ConditionalGet(t *testing.T) {
+	bm := staticManager{"root/app/database": `{"user":"harp"}`}
+	h := KVHandler(bm)
+
+	get := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/secret/data/app/database", nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected ETag header")
+	}
+
+	if rec := get(etag); rec.Code != http.StatusNotModified {
+		t.Errorf("expected status 304, got %d", rec.Code)
+	}
+
+	bm["root/app/database"] = `{"user":"admin"}`
+	if rec := get(etag); rec.Code != http.StatusOK {
+		t.Errorf("expected status 200 after change, got %d", rec.Code)
+	}
+}
+
+// listManager exposes static secrets through a listing engine.
+type listManager struct {
+	staticManager
+}
+
+func (m listManager) GetNameSpace(_ context.Context, ns string) (storage.Engine, error) {
+	return listEngine{ns: ns, secrets: m.staticManager}, nil
+}
+
+type listEngine struct {
+	ns      string
+	secrets staticManager
+}
+
+func (e listEngine) Get(ctx context.Context, id string) ([]byte, error) {
+	return e.secrets.GetSecret(ctx, e.ns, id)
+}
+
+func (e listEngine) List(_ context.Context, prefix string, req storage.PageRequest) (*storage.Page, error) {
+	index := []string{}
+	for k := range e.secrets {
+		if strings.HasPrefix(k, e.ns+"/") {
+			index = append(index, strings.TrimPrefix(k, e.ns))
+		}
+	}
+	sort.Strings(index)
+
+	return storage.Paginate(index, "/"+strings.Trim(prefix, "/"), req)
+}
+
+func TestListSecrets(t *testing.T) {
+	h := KVHandler(listManager{staticManager{
+		"root/app/database": `{"user":"harp"}`,
+		"root/app/queue":    `{"token":"foo"}`,
+		"root/app/cache/1":  `{"token":"bar"}`,
+	}})
+
+	list := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	keys := func(rec *httptest.ResponseRecorder) []string {
+		var resp struct {
+			Data struct {
+				Keys []string `json:"keys"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("unable to decode listing: %v", err)
+		}
+		return resp.Data.Keys
+	}
+
+	testCases := []struct {
+		desc       string
+		method     string
+		path       string
+		wantStatus int
+		wantKeys   []string
+		wantNext   string
+	}{
+		{desc: "vault list verb", method: "LIST", path: "/v1/secret/metadata/app", wantStatus: http.StatusOK, wantKeys: []string{"cache/", "database", "queue"}},
+		{desc: "vault list query", method: http.MethodGet, path: "/v1/secret/metadata/app/?list=true", wantStatus: http.StatusOK, wantKeys: []string{"cache/", "database", "queue"}},
+		{desc: "first page", method: "LIST", path: "/v1/secret/metadata/app?limit=2", wantStatus: http.StatusOK, wantKeys: []string{"cache/", "database"}, wantNext: "database"},
+		{desc: "last page", method: "LIST", path: "/v1/secret/metadata/app?limit=2&after=database", wantStatus: http.StatusOK, wantKeys: []string{"queue"}},
+		{desc: "empty", method: "LIST", path: "/v1/secret/metadata/missing", wantStatus: http.StatusNotFound},
+		{desc: "invalid cursor", method: "LIST", path: "/v1/secret/metadata/app?after=cache/1", wantStatus: http.StatusBadRequest},
+		{desc: "metadata read", method: http.MethodGet, path: "/v1/secret/metadata/app/database", wantStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			rec := list(tC.method, tC.path)
+			if rec.Code != tC.wantStatus {
+				t.Fatalf("expected status %d, got %d (%s)", tC.wantStatus, rec.Code, rec.Body.String())
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+			if got := keys(rec); !reflect.DeepEqual(got, tC.wantKeys) {
+				t.Errorf("expected keys %v, got %v", tC.wantKeys, got)
+			}
+			if got := rec.Header().Get(storage.NextCursorHeader); got != tC.wantNext {
+				t.Errorf("expected next cursor %q, got %q", tC.wantNext, got)
+			}
+		})
+	}
+}
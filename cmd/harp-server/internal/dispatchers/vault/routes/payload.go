@@ -0,0 +1,206 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/elastic/harp/pkg/sdk/log"
+)
+
+const (
+	// DefaultMaxCachedPayloads is the maximum number of pre-serialized secret
+	// payloads kept in memory.
+	DefaultMaxCachedPayloads = 16384
+	// DefaultMaxCachedPayloadBytes is the maximum size of all cached secret
+	// values and their serialization.
+	DefaultMaxCachedPayloadBytes = 32 << 20
+)
+
+// maxPooledBufferSize prevents oversized buffers from being kept in the pool.
+const maxPooledBufferSize = 64 << 10
+
+// -----------------------------------------------------------------------------
+
+// payloadCache keeps the JSON serialization of secret values. Entries are
+// only used when the engine value is unchanged, so a reloaded or decorated
+// engine never gets a stale payload.
+type payloadCache struct {
+	sync.Mutex
+
+	maxEntries int
+	maxBytes   int
+	size       int
+	generation uint64
+	entries    map[string]*list.Element
+	lru        *list.List
+}
+
+type payloadEntry struct {
+	key     string
+	raw     []byte
+	payload []byte
+}
+
+func (e *payloadEntry) size() int {
+	return len(e.key) + len(e.raw) + len(e.payload)
+}
+
+// newPayloadCache returns a payload cache, caching is disabled when
+// maxEntries or maxBytes is not positive.
+func newPayloadCache(maxEntries, maxBytes int) *payloadCache {
+	return &payloadCache{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		entries:    map[string]*list.Element{},
+		lru:        list.New(),
+	}
+}
+
+// payload returns the JSON serialization of the given secret value.
+func (c *payloadCache) payload(key string, raw []byte) ([]byte, error) {
+	// Lookup the cache
+	c.Lock()
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*payloadEntry)
+		if bytes.Equal(e.raw, raw) {
+			c.lru.MoveToFront(el)
+			c.Unlock()
+			return e.payload, nil
+		}
+	}
+	generation := c.generation
+	c.Unlock()
+
+	// Normalize secret value
+	var data interface{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, err
+	}
+	payload, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep a private copy of the engine value
+	c.store(generation, &payloadEntry{
+		key:     key,
+		raw:     append([]byte(nil), raw...),
+		payload: payload,
+	})
+
+	return payload, nil
+}
+
+// Invalidate drops all cached payloads. It is called when a namespace engine
+// is reloaded.
+func (c *payloadCache) Invalidate() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.generation++
+	c.entries = map[string]*list.Element{}
+	c.lru.Init()
+	c.size = 0
+}
+
+func (c *payloadCache) store(generation uint64, e *payloadEntry) {
+	c.Lock()
+	defer c.Unlock()
+
+	// Disabled, invalidated during serialization or larger than the budget
+	if c.maxEntries <= 0 || generation != c.generation || e.size() > c.maxBytes {
+		return
+	}
+
+	// Replace existing entry
+	if el, ok := c.entries[e.key]; ok {
+		c.remove(el)
+	}
+	c.entries[e.key] = c.lru.PushFront(e)
+	c.size += e.size()
+
+	// Evict least recently used entries
+	for c.lru.Len() > c.maxEntries || c.size > c.maxBytes {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *payloadCache) remove(el *list.Element) {
+	e := el.Value.(*payloadEntry)
+	c.lru.Remove(el)
+	delete(c.entries, e.key)
+	c.size -= e.size()
+}
+
+// -----------------------------------------------------------------------------
+
+// secretEnvelope is the Vault KV v2 read response. The pre-serialized secret
+// payload is spliced as is in the envelope.
+type secretEnvelope struct {
+	payload []byte
+	buf     bytes.Buffer
+}
+
+var envelopePool = sync.Pool{
+	New: func() interface{} {
+		return &secretEnvelope{}
+	},
+}
+
+// MarshalJSON encodes the envelope, matching `{"data":{"data":...},"metadata":{"version":"1"}}`.
+func (e *secretEnvelope) MarshalJSON() ([]byte, error) {
+	e.buf.Reset()
+	e.buf.WriteString(`{"data":{"data":`)
+	e.buf.Write(e.payload)
+	e.buf.WriteString(`},"metadata":{"version":"1"}}`)
+
+	return e.buf.Bytes(), nil
+}
+
+// withSecret sends the secret envelope using a pooled encoder.
+func withSecret(w http.ResponseWriter, r *http.Request, payload []byte) {
+	e, _ := envelopePool.Get().(*secretEnvelope)
+	defer func() {
+		// Never keep request data in pooled objects
+		e.payload = nil
+		e.buf.Reset()
+		if e.buf.Cap() <= maxPooledBufferSize {
+			envelopePool.Put(e)
+		}
+	}()
+
+	e.payload = payload
+	body, _ := e.MarshalJSON()
+
+	// Set content type header
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	// Write status
+	w.WriteHeader(http.StatusOK)
+
+	// Write response, writers must not retain the body
+	_, err := w.Write(body)
+	log.CheckErrCtx(r.Context(), "Unable to write response", err)
+}
@@ -0,0 +1,273 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+func payloadHandler(bm staticManager, maxEntries int) http.Handler {
+	ctrl := &vaultKVHandler{
+		bm:       bm,
+		wraps:    newWrappingStore(DefaultMaxWrappedEntries, DefaultMaxWrappedBytes),
+		payloads: newPayloadCache(maxEntries, DefaultMaxCachedPayloadBytes),
+	}
+	return ctrl.routes()
+}
+
+func TestGetSecret_Envelope(t *testing.T) {
+	values := []string{
+		`{"user":"harp","port":5432}`,
+		`{"b":{"z":[1,2.5,true,null]},"a":"<script>&</script>"}`,
+		`"plain"`,
+		`{"unicode":"été","big":12345678901234567890}`,
+	}
+
+	for i, v := range values {
+		t.Run(fmt.Sprintf("value %d", i), func(t *testing.T) {
+			h := payloadHandler(staticManager{"root/app/secret": v}, DefaultMaxCachedPayloads)
+
+			// Expected legacy encoding
+			var data interface{}
+			if err := json.Unmarshal([]byte(v), &data); err != nil {
+				t.Fatal(err)
+			}
+			want, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(&KV{
+				"data":     &KV{"data": data},
+				"metadata": &KV{"version": "1"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Uncached and cached responses
+			for j := 0; j < 2; j++ {
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/secret/data/app/secret", nil))
+				if rec.Code != http.StatusOK {
+					t.Fatalf("unexpected status %d", rec.Code)
+				}
+				if got := rec.Body.String(); got != string(want) {
+					t.Errorf("unexpected envelope\ngot:  %s\nwant: %s", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPayloadCache(t *testing.T) {
+	c := newPayloadCache(2, DefaultMaxCachedPayloadBytes)
+
+	first, err := c.payload("root/a", []byte(`{"v": 1}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(first) != `{"v":1}` {
+		t.Errorf("unexpected payload %q", first)
+	}
+
+	// Hit returns the same serialization
+	if again, _ := c.payload("root/a", []byte(`{"v": 1}`)); &again[0] != &first[0] {
+		t.Error("payload should be served from cache")
+	}
+
+	// Changed value is serialized again
+	if changed, _ := c.payload("root/a", []byte(`{"v": 2}`)); string(changed) != `{"v":2}` {
+		t.Errorf("unexpected payload %q", changed)
+	}
+
+	// Least recently used entries are evicted
+	_, _ = c.payload("root/b", []byte(`{}`))
+	_, _ = c.payload("root/c", []byte(`{}`))
+	if _, ok := c.entries["root/a"]; ok || c.lru.Len() != 2 {
+		t.Errorf("unexpected cache entries %d", c.lru.Len())
+	}
+
+	// Invalidation drops everything
+	c.Invalidate()
+	if c.lru.Len() != 0 || len(c.entries) != 0 {
+		t.Error("cache should be empty after invalidation")
+	}
+
+	// Invalid values are not cached
+	if _, err := c.payload("root/d", []byte(`{`)); err == nil {
+		t.Error("error should be raised for invalid json")
+	}
+
+	// Disabled cache
+	d := newPayloadCache(0, DefaultMaxCachedPayloadBytes)
+	if _, err := d.payload("root/a", []byte(`{}`)); err != nil || d.lru.Len() != 0 {
+		t.Errorf("disabled cache must not keep entries, got %v", err)
+	}
+}
+
+func TestPayloadCache_MaxBytes(t *testing.T) {
+	value := []byte(fmt.Sprintf(`{"pad":%q}`, strings.Repeat("a", 100)))
+	entrySize := len("root/0") + 2*len(value)
+	c := newPayloadCache(DefaultMaxCachedPayloads, 3*entrySize)
+
+	// Least recently used entries are evicted to fit the budget
+	for i := 0; i < 5; i++ {
+		if _, err := c.payload(fmt.Sprintf("root/%d", i), value); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if c.lru.Len() != 3 || c.size != 3*entrySize {
+		t.Errorf("unexpected cache usage: %d entries, %d bytes", c.lru.Len(), c.size)
+	}
+	if _, ok := c.entries["root/1"]; ok {
+		t.Error("least recently used entry should be evicted")
+	}
+
+	// Oversized entries are not cached
+	large := []byte(fmt.Sprintf(`{"pad":%q}`, strings.Repeat("b", 4*entrySize)))
+	if _, err := c.payload("root/large", large); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := c.entries["root/large"]; ok || c.lru.Len() != 3 {
+		t.Error("oversized entry should not be cached")
+	}
+
+	// Invalidation resets the usage
+	c.Invalidate()
+	if c.size != 0 {
+		t.Errorf("unexpected cache size %d after invalidation", c.size)
+	}
+}
+
+type reloadManager struct {
+	staticManager
+	hooks []func(string)
+}
+
+func (m *reloadManager) OnReload(fn func(string)) {
+	m.hooks = append(m.hooks, fn)
+}
+
+func TestKVHandler_ReloadInvalidatesPayloads(t *testing.T) {
+	bm := &reloadManager{staticManager: staticManager{"root/app/secret": `{"v":1}`}}
+	ctrl := newVaultKVHandler(bm)
+	if len(bm.hooks) != 1 {
+		t.Fatalf("expected 1 reload hook, got %d", len(bm.hooks))
+	}
+
+	rec := httptest.NewRecorder()
+	ctrl.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/secret/data/app/secret", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d", rec.Code)
+	}
+	if ctrl.payloads.lru.Len() != 1 {
+		t.Fatalf("expected 1 cached payload, got %d", ctrl.payloads.lru.Len())
+	}
+
+	// Reload drops cached payloads
+	bm.hooks[0]("root")
+	if ctrl.payloads.lru.Len() != 0 || ctrl.payloads.size != 0 {
+		t.Error("cache should be empty after reload")
+	}
+}
+
+func TestGetSecret_PooledIsolation(t *testing.T) {
+	bm := staticManager{}
+	for i := 0; i < 64; i++ {
+		// Vary sizes to exercise buffer reuse
+		bm[fmt.Sprintf("root/app/%d", i)] = fmt.Sprintf(`{"id":%d,"pad":%q}`, i, padding(i))
+	}
+	h := payloadHandler(bm, 16)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for n := 0; n < 500; n++ {
+				i := (w*31 + n*7) % 64
+
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/v1/secret/data/app/%d", i), nil))
+
+				var resp struct {
+					Data struct {
+						Data struct {
+							ID  int    `json:"id"`
+							Pad string `json:"pad"`
+						} `json:"data"`
+					} `json:"data"`
+				}
+				if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+					t.Errorf("unable to decode response: %v", err)
+					return
+				}
+				if resp.Data.Data.ID != i || resp.Data.Data.Pad != padding(i) {
+					t.Errorf("response of %d leaked data of %d", i, resp.Data.Data.ID)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+}
+
+func padding(i int) string {
+	return strings.Repeat(string(rune('a'+i%26)), i*37)
+}
+
+func BenchmarkGetSecret(b *testing.B) {
+	const paths = 10000
+
+	bm := staticManager{}
+	for i := 0; i < paths; i++ {
+		bm[fmt.Sprintf("root/app/production/customer/%d/database", i)] = fmt.Sprintf(`{"host":"db-%d.internal","port":5432,"user":"app","password":"secret-%d","options":{"sslmode":"verify-full","pool":[1,2,4]}}`, i, i)
+	}
+
+	for _, bc := range []struct {
+		name       string
+		maxEntries int
+	}{
+		{name: "uncached", maxEntries: 0},
+		{name: "cached", maxEntries: DefaultMaxCachedPayloads},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			h := payloadHandler(bm, bc.maxEntries)
+			zipf := rand.NewZipf(rand.New(rand.NewSource(1)), 1.1, 1, paths-1)
+			reqs := make([]*http.Request, 1024)
+			for i := range reqs {
+				reqs[i] = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/v1/secret/data/app/production/customer/%d/database", zipf.Uint64()), nil)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, reqs[i%len(reqs)])
+				if rec.Code != http.StatusOK {
+					b.Fatalf("unexpected status %d", rec.Code)
+				}
+			}
+		})
+	}
+}
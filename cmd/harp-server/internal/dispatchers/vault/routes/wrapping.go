@@ -0,0 +1,337 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dchest/uniuri"
+)
+
+const (
+	// WrapTTLHeader is the header used by Vault clients to request response
+	// wrapping.
+	WrapTTLHeader = "X-Vault-Wrap-TTL"
+
+	// DefaultMaxWrapTTL is the maximum lifetime of a wrapping token.
+	DefaultMaxWrapTTL = 24 * time.Hour
+	// DefaultMaxWrappedEntries is the maximum number of pending wrapping tokens.
+	DefaultMaxWrappedEntries = 1024
+	// DefaultMaxWrappedBytes is the maximum size of all pending wrapped responses.
+	DefaultMaxWrappedBytes = 16 << 20
+
+	wrapSweepInterval = time.Minute
+)
+
+var (
+	// ErrWrappingTokenInvalid is raised when the wrapping token doesn't exist,
+	// has already been unwrapped or is expired.
+	ErrWrappingTokenInvalid = errors.New("wrapping token is not valid or does not exist")
+	// ErrWrappingStoreFull is raised when the wrapping store limits are reached.
+	ErrWrappingStoreFull = errors.New("wrapping token store is full")
+)
+
+// -----------------------------------------------------------------------------
+
+type wrappedResponse struct {
+	accessor     string
+	contentType  string
+	body         []byte
+	creationPath string
+	creationTime time.Time
+	ttl          time.Duration
+}
+
+func (r *wrappedResponse) expired(now time.Time) bool {
+	return !now.Before(r.creationTime.Add(r.ttl))
+}
+
+// wrappingStore is an in-memory single-use token store for wrapped responses.
+type wrappingStore struct {
+	sync.Mutex
+
+	now        func() time.Time
+	maxEntries int
+	maxBytes   int
+	size       int
+	lastSweep  time.Time
+	entries    map[string]*wrappedResponse
+}
+
+func newWrappingStore(maxEntries, maxBytes int) *wrappingStore {
+	return &wrappingStore{
+		now:        time.Now,
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		entries:    map[string]*wrappedResponse{},
+	}
+}
+
+// put stores the response and returns the associated wrapping token.
+func (s *wrappingStore) put(resp *wrappedResponse) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	now := s.now()
+	resp.creationTime = now
+
+	// Sweep periodically or when limits are reached
+	full := len(s.entries) >= s.maxEntries || s.size+len(resp.body) > s.maxBytes
+	if full || now.Sub(s.lastSweep) >= wrapSweepInterval {
+		s.sweep(now)
+	}
+	if len(s.entries) >= s.maxEntries || s.size+len(resp.body) > s.maxBytes {
+		return "", ErrWrappingStoreFull
+	}
+
+	token := fmt.Sprintf("s.%s", uniuri.NewLen(24))
+	resp.accessor = uniuri.NewLen(24)
+	s.entries[token] = resp
+	s.size += len(resp.body)
+
+	return token, nil
+}
+
+// get returns the wrapped response, and removes it from the store when
+// consume is set.
+func (s *wrappingStore) get(token string, consume bool) (*wrappedResponse, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	resp, ok := s.entries[token]
+	if !ok {
+		return nil, ErrWrappingTokenInvalid
+	}
+	if resp.expired(s.now()) {
+		s.delete(token)
+		return nil, ErrWrappingTokenInvalid
+	}
+	if consume {
+		s.delete(token)
+	}
+
+	return resp, nil
+}
+
+func (s *wrappingStore) sweep(now time.Time) {
+	for token, resp := range s.entries {
+		if resp.expired(now) {
+			s.delete(token)
+		}
+	}
+	s.lastSweep = now
+}
+
+func (s *wrappingStore) delete(token string) {
+	if resp, ok := s.entries[token]; ok {
+		s.size -= len(resp.body)
+		delete(s.entries, token)
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// parseWrapTTL decodes the wrap TTL header value, expressed in seconds or as
+// a duration string.
+func parseWrapTTL(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	var ttl time.Duration
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+		ttl = time.Duration(secs) * time.Second
+	} else {
+		ttl, err = time.ParseDuration(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid wrap ttl %q", value)
+		}
+	}
+
+	switch {
+	case ttl < 0:
+		return 0, fmt.Errorf("wrap ttl %q must not be negative", value)
+	case ttl > DefaultMaxWrapTTL:
+		return 0, fmt.Errorf("wrap ttl %q exceeds the maximum of %s", value, DefaultMaxWrapTTL)
+	}
+
+	return ttl, nil
+}
+
+// responseBuffer captures a handler response before wrapping.
+type responseBuffer struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (b *responseBuffer) Header() http.Header         { return b.header }
+func (b *responseBuffer) Write(p []byte) (int, error) { return b.body.Write(p) }
+func (b *responseBuffer) WriteHeader(code int)        { b.code = code }
+
+func (b *responseBuffer) flush(w http.ResponseWriter) {
+	for k, v := range b.header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(b.code)
+	_, _ = w.Write(b.body.Bytes())
+}
+
+// wrap is a middleware which stores the response in the wrapping store when
+// the client requests response wrapping.
+func (h *vaultKVHandler) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ttl, err := parseWrapTTL(r.Header.Get(WrapTTLHeader))
+		if err != nil {
+			withVaultError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		if ttl == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Capture response
+		buf := &responseBuffer{header: http.Header{}, code: http.StatusOK}
+		next.ServeHTTP(buf, r)
+
+		// Don't wrap errors
+		if buf.code != http.StatusOK {
+			buf.flush(w)
+			return
+		}
+
+		resp := &wrappedResponse{
+			contentType:  buf.header.Get("Content-Type"),
+			body:         buf.body.Bytes(),
+			creationPath: strings.TrimPrefix(r.URL.Path, "/v1/"),
+			ttl:          ttl,
+		}
+		token, err := h.wraps.put(resp)
+		if err != nil {
+			withVaultError(w, r, http.StatusServiceUnavailable, err)
+			return
+		}
+
+		with(w, r, http.StatusOK, &KV{
+			"request_id":     uniuri.NewLen(16),
+			"lease_id":       "",
+			"renewable":      false,
+			"lease_duration": 0,
+			"data":           nil,
+			"wrap_info": &KV{
+				"token":            token,
+				"accessor":         resp.accessor,
+				"ttl":              int64(ttl / time.Second),
+				"creation_time":    resp.creationTime.Format(time.RFC3339Nano),
+				"creation_path":    resp.creationPath,
+				"wrapped_accessor": "",
+			},
+			"warnings": nil,
+			"auth":     nil,
+		})
+	})
+}
+
+func (h *vaultKVHandler) unwrap() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, err := wrappingToken(r)
+		if err != nil {
+			withVaultError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		resp, err := h.wraps.get(token, true)
+		if err != nil {
+			withVaultError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		// Send original response
+		w.Header().Set("Content-Type", resp.contentType)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(resp.body)
+	}
+}
+
+func (h *vaultKVHandler) lookup() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, err := wrappingToken(r)
+		if err != nil {
+			withVaultError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		resp, err := h.wraps.get(token, false)
+		if err != nil {
+			withVaultError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		with(w, r, http.StatusOK, &KV{
+			"data": &KV{
+				"creation_path": resp.creationPath,
+				"creation_time": resp.creationTime.Format(time.RFC3339Nano),
+				"creation_ttl":  int64(resp.ttl / time.Second),
+			},
+		})
+	}
+}
+
+// wrappingToken extracts the wrapping token from the request body, or from
+// the client token header when the body is empty.
+func wrappingToken(r *http.Request) (string, error) {
+	var req struct {
+		Token string `json:"token"`
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(nil, r.Body, 4096))
+	if err != nil {
+		return "", fmt.Errorf("unable to read request body: %w", err)
+	}
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			return "", fmt.Errorf("unable to decode request body: %w", err)
+		}
+	}
+	if req.Token == "" {
+		req.Token = r.Header.Get("X-Vault-Token")
+	}
+	if req.Token == "" {
+		return "", ErrWrappingTokenInvalid
+	}
+
+	return req.Token, nil
+}
+
+// withVaultError serializes an error using the Vault error envelope.
+func withVaultError(w http.ResponseWriter, r *http.Request, code int, err error) {
+	with(w, r, code, &KV{
+		"errors": []string{err.Error()},
+	})
+}
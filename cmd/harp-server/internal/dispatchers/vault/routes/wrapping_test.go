@@ -0,0 +1,227 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elastic/harp/pkg/server/storage"
+)
+
+type staticManager map[string]string
+
+func (m staticManager) GetSecret(_ context.Context, ns, id string) ([]byte, error) {
+	v, ok := m[ns+id]
+	if !ok {
+		return nil, storage.ErrSecretNotFound
+	}
+	return []byte(v), nil
+}
+
+func (m staticManager) Register(context.Context, string, string, ...func(storage.Engine) storage.Engine) error {
+	return nil
+}
+
+func (m staticManager) GetNameSpace(context.Context, string) (storage.Engine, error) {
+	return nil, nil
+}
+
+type wrapFixture struct {
+	t     *testing.T
+	h     http.Handler
+	store *wrappingStore
+	now   time.Time
+}
+
+func newWrapFixture(t *testing.T) *wrapFixture {
+	f := &wrapFixture{
+		t:   t,
+		now: time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC),
+	}
+
+	ctrl := &vaultKVHandler{
+		bm:       staticManager{"root/app/database": `{"user":"harp"}`},
+		wraps:    newWrappingStore(2, 1024),
+		payloads: newPayloadCache(DefaultMaxCachedPayloads, DefaultMaxCachedPayloadBytes),
+	}
+	ctrl.wraps.now = func() time.Time { return f.now }
+	f.store = ctrl.wraps
+	f.h = ctrl.routes()
+
+	return f
+}
+
+func (f *wrapFixture) do(method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	f.h.ServeHTTP(rec, req)
+	return rec
+}
+
+func (f *wrapFixture) wrap(ttl string) string {
+	rec := f.do(http.MethodGet, "/v1/secret/data/app/database", "", map[string]string{WrapTTLHeader: ttl})
+	if rec.Code != http.StatusOK {
+		f.t.Fatalf("unable to wrap response: %d %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Data     interface{} `json:"data"`
+		WrapInfo struct {
+			Token        string `json:"token"`
+			TTL          int    `json:"ttl"`
+			CreationPath string `json:"creation_path"`
+		} `json:"wrap_info"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		f.t.Fatalf("unable to decode wrap info: %v", err)
+	}
+	if resp.Data != nil {
+		f.t.Errorf("wrapped response must not expose data, got %v", resp.Data)
+	}
+	if resp.WrapInfo.Token == "" {
+		f.t.Fatal("empty wrapping token")
+	}
+	if resp.WrapInfo.CreationPath != "secret/data/app/database" {
+		f.t.Errorf("unexpected creation path %q", resp.WrapInfo.CreationPath)
+	}
+
+	return resp.WrapInfo.Token
+}
+
+func TestWrapping_SingleUse(t *testing.T) {
+	f := newWrapFixture(t)
+	token := f.wrap("300")
+
+	rec := f.do(http.MethodPut, "/v1/sys/wrapping/unwrap", `{"token":"`+token+`"}`, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `"user":"harp"`) {
+		t.Errorf("unexpected unwrapped response %s", rec.Body.String())
+	}
+
+	// Second redemption must fail
+	rec = f.do(http.MethodPut, "/v1/sys/wrapping/unwrap", "", map[string]string{"X-Vault-Token": token})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+	if len(f.store.entries) != 0 || f.store.size != 0 {
+		t.Errorf("store must be empty, got %d entries (%d bytes)", len(f.store.entries), f.store.size)
+	}
+}
+
+func TestWrapping_Expiry(t *testing.T) {
+	f := newWrapFixture(t)
+	token := f.wrap("1m")
+
+	f.now = f.now.Add(time.Minute)
+	rec := f.do(http.MethodPost, "/v1/sys/wrapping/lookup", `{"token":"`+token+`"}`, nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("lookup: expected status 400, got %d", rec.Code)
+	}
+	rec = f.do(http.MethodPost, "/v1/sys/wrapping/unwrap", `{"token":"`+token+`"}`, nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("unwrap: expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestWrapping_Lookup(t *testing.T) {
+	f := newWrapFixture(t)
+	token := f.wrap("120")
+
+	for i := 0; i < 2; i++ {
+		rec := f.do(http.MethodPost, "/v1/sys/wrapping/lookup", `{"token":"`+token+`"}`, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rec.Code)
+		}
+		if strings.Contains(rec.Body.String(), "harp") {
+			t.Errorf("lookup must not expose wrapped data, got %s", rec.Body.String())
+		}
+		if !strings.Contains(rec.Body.String(), `"creation_ttl":120`) {
+			t.Errorf("unexpected lookup response %s", rec.Body.String())
+		}
+	}
+
+	// Lookup doesn't consume the token
+	rec := f.do(http.MethodPut, "/v1/sys/wrapping/unwrap", `{"token":"`+token+`"}`, nil)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+}
+
+func TestWrapping_Limits(t *testing.T) {
+	f := newWrapFixture(t)
+	f.wrap("60")
+	f.wrap("60")
+
+	// Store is full
+	rec := f.do(http.MethodGet, "/v1/secret/data/app/database", "", map[string]string{WrapTTLHeader: "60"})
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", rec.Code)
+	}
+
+	// Expired entries are swept
+	f.now = f.now.Add(time.Hour)
+	f.wrap("60")
+	if len(f.store.entries) != 1 {
+		t.Errorf("expected 1 entry after sweep, got %d", len(f.store.entries))
+	}
+}
+
+func TestWrapping_Passthrough(t *testing.T) {
+	f := newWrapFixture(t)
+
+	testCases := []struct {
+		desc       string
+		path       string
+		ttl        string
+		wantStatus int
+	}{
+		{desc: "no header", path: "/v1/secret/data/app/database", wantStatus: http.StatusOK},
+		{desc: "not found", path: "/v1/secret/data/app/missing", ttl: "60", wantStatus: http.StatusNotFound},
+		{desc: "invalid ttl", path: "/v1/secret/data/app/database", ttl: "soon", wantStatus: http.StatusBadRequest},
+		{desc: "ttl too long", path: "/v1/secret/data/app/database", ttl: "720h", wantStatus: http.StatusBadRequest},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			headers := map[string]string{}
+			if tC.ttl != "" {
+				headers[WrapTTLHeader] = tC.ttl
+			}
+			rec := f.do(http.MethodGet, tC.path, "", headers)
+			if rec.Code != tC.wantStatus {
+				t.Errorf("expected status %d, got %d", tC.wantStatus, rec.Code)
+			}
+			if strings.Contains(rec.Body.String(), "wrap_info") {
+				t.Errorf("response must not be wrapped, got %s", rec.Body.String())
+			}
+		})
+	}
+	if len(f.store.entries) != 0 {
+		t.Errorf("expected empty store, got %d entries", len(f.store.entries))
+	}
+}
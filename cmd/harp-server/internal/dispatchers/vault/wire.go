@@ -32,23 +32,61 @@ import (
 
 	"github.com/elastic/harp/cmd/harp-server/internal/config"
 	"github.com/elastic/harp/cmd/harp-server/internal/dispatchers/vault/routes"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/decryptor"
 	"github.com/elastic/harp/pkg/sdk/log"
 	"github.com/elastic/harp/pkg/sdk/tlsconfig"
+	"github.com/elastic/harp/pkg/server/spiffe"
 	"github.com/elastic/harp/pkg/server/manager"
 	"github.com/elastic/harp/pkg/server/storage/backends/container"
 	vpath "github.com/elastic/harp/pkg/vault/path"
 )
 
 func backendManager(ctx context.Context, cfg *config.Configuration) (manager.Backend, error) {
+	// Apply expired package policy before loading containers, settings are
+	// already validated
+	expiredPolicy, _ := bundle.ParseExpiredPackagePolicy(cfg.Bundle.ExpiredPackages)
+	container.SetExpiredPackagePolicy(expiredPolicy)
+
+	// Apply memory-mapped loading settings
+	container.SetMmap(cfg.Bundle.Mmap, cfg.Bundle.SharedCacheDir)
+
+	// Apply value decryption settings
+	decryptPolicy, _ := bundle.ParseDecryptFailurePolicy(cfg.Bundle.ValueDecryptionFailure)
+	if cfg.Bundle.ValueDecryptionKey != "" {
+		d, err := decryptor.AESGCM(cfg.Bundle.ValueDecryptionKey)
+		if err != nil {
+			return nil, err
+		}
+		container.SetValueDecryptor(d, decryptPolicy)
+	}
+
 	// Initialize default manager
 	bm := manager.Default()
 
 	// Backends
 	for _, b := range cfg.Backends {
+		// Build response transformations
+		decorators, err := b.Decorators()
+		if err != nil {
+			return nil, err
+		}
+
 		// Register namespace engine
-		if err := bm.Register(ctx, vpath.SanitizePath(b.NS), b.URL); err != nil {
+		if err := bm.Register(ctx, vpath.SanitizePath(b.NS), b.URL, decorators...); err != nil {
 			return nil, err
 		}
+
+		// Reload namespace periodically to serve updated containers
+		if b.ReloadInterval != "" {
+			interval, err := time.ParseDuration(b.ReloadInterval)
+			if err != nil {
+				return nil, err
+			}
+			if w, ok := bm.(manager.Watcher); ok {
+				go manager.ReloadEvery(ctx, w, vpath.SanitizePath(b.NS), interval)
+			}
+		}
 	}
 
 	// No error
@@ -98,6 +136,16 @@ func httpServer(ctx context.Context, cfg *config.Configuration, bm manager.Backe
 			return nil, err
 		}
 
+		// Fetch rotated client trust bundles from SPIRE Workload API
+		if cfg.Vault.TLS.WorkloadAPISocket != "" {
+			source, err := spiffe.WatchBundles(ctx, cfg.Vault.TLS.WorkloadAPISocket)
+			if err != nil {
+				log.For(ctx).Error("Unable to fetch trust bundles from SPIRE Workload API", zap.Error(err))
+				return nil, err
+			}
+			tlsConfig = spiffe.ServerConfig(tlsConfig, source)
+		}
+
 		// Create the TLS credentials
 		server.TLSConfig = tlsConfig
 	} else {
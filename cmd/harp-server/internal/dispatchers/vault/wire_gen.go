@@ -10,8 +10,11 @@ import (
 	"crypto/tls"
 	"github.com/elastic/harp/cmd/harp-server/internal/config"
 	"github.com/elastic/harp/cmd/harp-server/internal/dispatchers/vault/routes"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/decryptor"
 	"github.com/elastic/harp/pkg/sdk/log"
 	"github.com/elastic/harp/pkg/sdk/tlsconfig"
+	"github.com/elastic/harp/pkg/server/spiffe"
 	"github.com/elastic/harp/pkg/server/manager"
 	"github.com/elastic/harp/pkg/server/storage/backends/container"
 	"github.com/elastic/harp/pkg/vault/path"
@@ -39,14 +42,39 @@ func setup(ctx context.Context, cfg *config.Configuration) (*http.Server, error)
 // wire.go:
 
 func backendManager(ctx context.Context, cfg *config.Configuration) (manager.Backend, error) {
+	expiredPolicy, _ := bundle.ParseExpiredPackagePolicy(cfg.Bundle.ExpiredPackages)
+	container.SetExpiredPackagePolicy(expiredPolicy)
+	container.SetMmap(cfg.Bundle.Mmap, cfg.Bundle.SharedCacheDir)
+
+	decryptPolicy, _ := bundle.ParseDecryptFailurePolicy(cfg.Bundle.ValueDecryptionFailure)
+	if cfg.Bundle.ValueDecryptionKey != "" {
+		d, err := decryptor.AESGCM(cfg.Bundle.ValueDecryptionKey)
+		if err != nil {
+			return nil, err
+		}
+		container.SetValueDecryptor(d, decryptPolicy)
+	}
 
 	bm := manager.Default()
 
 	for _, b := range cfg.Backends {
 
-		if err := bm.Register(ctx, path.SanitizePath(b.NS), b.URL); err != nil {
+		decorators, err := b.Decorators()
+		if err != nil {
 			return nil, err
 		}
+		if err := bm.Register(ctx, path.SanitizePath(b.NS), b.URL, decorators...); err != nil {
+			return nil, err
+		}
+		if b.ReloadInterval != "" {
+			interval, err := time.ParseDuration(b.ReloadInterval)
+			if err != nil {
+				return nil, err
+			}
+			if w, ok := bm.(manager.Watcher); ok {
+				go manager.ReloadEvery(ctx, w, path.SanitizePath(b.NS), interval)
+			}
+		}
 	}
 
 	return bm, nil
@@ -88,6 +116,16 @@ func httpServer(ctx context.Context, cfg *config.Configuration, bm manager.Backe
 			return nil, err
 		}
 
+		// Fetch rotated client trust bundles from SPIRE Workload API
+		if cfg.Vault.TLS.WorkloadAPISocket != "" {
+			source, err := spiffe.WatchBundles(ctx, cfg.Vault.TLS.WorkloadAPISocket)
+			if err != nil {
+				log.For(ctx).Error("Unable to fetch trust bundles from SPIRE Workload API", zap.Error(err))
+				return nil, err
+			}
+			tlsConfig = spiffe.ServerConfig(tlsConfig, source)
+		}
+
 		server.TLSConfig = tlsConfig
 	} else {
 		log.For(ctx).Info("No transport encryption enabled for fake Vault server")
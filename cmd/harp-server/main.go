@@ -28,6 +28,7 @@ import (
 	_ "github.com/elastic/harp/pkg/server/storage/backends/container"
 	_ "github.com/elastic/harp/pkg/server/storage/backends/file"
 	_ "github.com/elastic/harp/pkg/server/storage/backends/gcs"
+	_ "github.com/elastic/harp/pkg/server/storage/backends/hybrid"
 	_ "github.com/elastic/harp/pkg/server/storage/backends/s3"
 	_ "github.com/elastic/harp/pkg/server/storage/backends/vault"
 )
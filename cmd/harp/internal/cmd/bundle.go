@@ -38,6 +38,29 @@ var bundleCmd = func() *cobra.Command {
 	cmd.AddCommand(bundleDiffCmd())
 	cmd.AddCommand(bundlePatchCmd())
 	cmd.AddCommand(bundleFilterCmd())
+	cmd.AddCommand(bundlePromoteCmd())
+	cmd.AddCommand(bundleMergeCmd())
+	cmd.AddCommand(bundleLintCmd())
+	cmd.AddCommand(bundleNormalizePathsCmd())
+	cmd.AddCommand(bundleBudgetCmd())
+	cmd.AddCommand(bundleCompareAnomaliesCmd())
+	cmd.AddCommand(bundleOverlayCmd())
+	cmd.AddCommand(bundleArchiveCmd())
+	cmd.AddCommand(bundleUnarchiveCmd())
+	cmd.AddCommand(bundleCheckRefsCmd())
+	cmd.AddCommand(bundleSearchCmd())
+	cmd.AddCommand(bundleAtCmd())
+	cmd.AddCommand(bundleHistoryCmd())
+	cmd.AddCommand(bundleScaffoldCmd())
+	cmd.AddCommand(bundleQuarantineCmd())
+	cmd.AddCommand(bundleDocsCmd())
+	cmd.AddCommand(bundleRecoverCmd())
+	cmd.AddCommand(bundleSetCmd())
+	cmd.AddCommand(bundleDistributeCmd())
+	cmd.AddCommand(bundlePathsCmd())
+	cmd.AddCommand(bundleCSOSuggestCmd())
+	cmd.AddCommand(bundleEscrowCmd())
+	cmd.AddCommand(bundleMaterializeAnnotationsCmd())
 
 	return cmd
 }
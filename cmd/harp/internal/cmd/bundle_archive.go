@@ -0,0 +1,76 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks/bundle"
+)
+
+// -----------------------------------------------------------------------------
+
+var bundleArchiveCmd = func() *cobra.Command {
+	return bundleArchivalCmd("archive", "Archive packages, hiding them without deleting them", false)
+}
+
+var bundleUnarchiveCmd = func() *cobra.Command {
+	return bundleArchivalCmd("unarchive", "Restore archived packages", true)
+}
+
+func bundleArchivalCmd(use, short string, restore bool) *cobra.Command {
+	var (
+		inputPath  string
+		outputPath string
+		paths      []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   use,
+		Short: short,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-bundle-"+use, conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Prepare task
+			t := &bundle.ArchiveTask{
+				ContainerReader: cmdutil.FileReader(inputPath),
+				OutputWriter:    cmdutil.FileWriter(outputPath),
+				Paths:           paths,
+				Restore:         restore,
+			}
+
+			// Run the task
+			if err := cmdutil.RunTask(ctx, t); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&inputPath, "in", "", "Container input ('-' for stdin or filename)")
+	cmd.Flags().StringVar(&outputPath, "out", "", "Container output ('-' for stdout or filename)")
+	cmd.Flags().StringArrayVar(&paths, "path", []string{}, "Package path")
+	log.CheckErr("unable to mark 'path' flag as required.", cmd.MarkFlagRequired("path"))
+
+	return cmd
+}
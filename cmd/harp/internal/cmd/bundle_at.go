@@ -0,0 +1,83 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks/bundle"
+)
+
+// -----------------------------------------------------------------------------
+
+var bundleAtCmd = func() *cobra.Command {
+	var (
+		inputPath  string
+		outputPath string
+		cutoff     string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "at",
+		Short: "Materialize the bundle state at a given time",
+		Long: `Materialize the bundle state at a given time.
+
+For each package, the newest secret version created at or before the given time
+is selected. Packages created after the given time are dropped.`,
+		Example: `harp bundle at --in c.bundle --time 2021-06-01T00:00:00Z --out forensics.bundle`,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-bundle-at", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Parse cutoff time
+			ts, err := time.Parse(time.RFC3339, cutoff)
+			if err != nil {
+				log.For(ctx).Fatal("unable to parse time", zap.Error(err), zap.String("time", cutoff))
+			}
+
+			// Prepare task
+			t := &bundle.AtTask{
+				ContainerReader: cmdutil.FileReader(inputPath),
+				OutputWriter:    cmdutil.FileWriter(outputPath),
+				Time:            ts,
+			}
+
+			// Run the task
+			if err := cmdutil.RunTask(ctx, t); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&inputPath, "in", "", "Container input ('-' for stdin or filename)")
+	cmd.Flags().StringVar(&outputPath, "out", "", "Container output ('-' for stdout or filename)")
+	cmd.Flags().StringVar(&cutoff, "time", "", "Cutoff time (RFC3339)")
+
+	cmdutil.ValidateFlags(cmd,
+		cmdutil.Prompted("time", "Cutoff time (RFC3339)", false),
+	)
+
+	return cmd
+}
@@ -0,0 +1,74 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks/bundle"
+)
+
+// -----------------------------------------------------------------------------
+
+var bundleBudgetCmd = func() *cobra.Command {
+	var (
+		inputPath  string
+		policyPath string
+		outputPath string
+		jsonOutput bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "budget",
+		Short: "Check bundle package count and size against prefix budgets",
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-bundle-budget", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Check mandatory flags
+			if policyPath == "" {
+				log.For(ctx).Fatal("policy flag must be defined")
+			}
+
+			// Prepare task
+			t := &bundle.BudgetTask{
+				ContainerReader: cmdutil.FileReader(inputPath),
+				PolicyReader:    cmdutil.FileReader(policyPath),
+				OutputWriter:    cmdutil.FileWriter(outputPath),
+				JSONOutput:      jsonOutput,
+			}
+
+			// Run the task
+			if err := cmdutil.RunTask(ctx, t); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&inputPath, "in", "", "Container input ('-' for stdin or filename)")
+	cmd.Flags().StringVar(&policyPath, "policy", "", "Budget policy path")
+	cmd.Flags().StringVar(&outputPath, "out", "-", "Report output ('-' for stdout or filename)")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Display report as JSON")
+
+	return cmd
+}
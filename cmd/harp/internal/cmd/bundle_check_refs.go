@@ -0,0 +1,84 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/bundle/refs"
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks"
+	"github.com/elastic/harp/pkg/tasks/bundle"
+)
+
+// -----------------------------------------------------------------------------
+
+var bundleCheckRefsCmd = func() *cobra.Command {
+	var (
+		inputPath          string
+		companionPaths     []string
+		outputPath         string
+		extractors         []string
+		disabledExtractors []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "check-refs",
+		Short: "Check that secret path references point to existing packages",
+		Long:  fmt.Sprintf("Check that secret path references point to existing packages.\n\nAvailable extractors: %s", strings.Join(refs.Names(), ", ")),
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-bundle-check-refs", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Prepare companion readers
+			companions := []tasks.ReaderProvider{}
+			for _, p := range companionPaths {
+				companions = append(companions, cmdutil.FileReader(p))
+			}
+
+			// Prepare task
+			t := &bundle.CheckRefsTask{
+				ContainerReader:    cmdutil.FileReader(inputPath),
+				CompanionReaders:   companions,
+				OutputWriter:       cmdutil.FileWriter(outputPath),
+				Extractors:         extractors,
+				DisabledExtractors: disabledExtractors,
+			}
+
+			// Run the task
+			if err := cmdutil.RunTask(ctx, t); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&inputPath, "in", "", "Container input ('-' for stdin or filename)")
+	cmd.Flags().StringArrayVar(&companionPaths, "also", []string{}, "Companion container used to resolve references")
+	cmd.Flags().StringVar(&outputPath, "out", "", "Report output ('-' for stdout or filename)")
+	cmd.Flags().StringSliceVar(&extractors, "extractor", []string{}, "Enabled reference extractors (all by default)")
+	cmd.Flags().StringSliceVar(&disabledExtractors, "disable-extractor", []string{}, "Disabled reference extractors")
+
+	return cmd
+}
@@ -0,0 +1,77 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/bundle/lint"
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks/bundle"
+)
+
+// -----------------------------------------------------------------------------
+
+var bundleCompareAnomaliesCmd = func() *cobra.Command {
+	var (
+		oldPath    string
+		newPath    string
+		outputPath string
+		thresholds = lint.DefaultAnomalyThresholds()
+	)
+
+	cmd := &cobra.Command{
+		Use:   "compare-anomalies",
+		Short: "Detect suspicious value changes between container versions",
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-bundle-compare-anomalies", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Prepare task
+			t := &bundle.CompareAnomaliesTask{
+				OldReader:    cmdutil.FileReader(oldPath),
+				NewReader:    cmdutil.FileReader(newPath),
+				OutputWriter: cmdutil.FileWriter(outputPath),
+				Thresholds:   thresholds,
+			}
+
+			// Run the task
+			if err := cmdutil.RunTask(ctx, t); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&oldPath, "old", "", "Previous container path ('-' for stdin or filename)")
+	cmd.Flags().StringVar(&newPath, "new", "", "New container path")
+	log.CheckErr("unable to mark 'new' flag as required.", cmd.MarkFlagRequired("new"))
+	cmd.Flags().StringVar(&outputPath, "out", "", "Report output ('-' for stdout or filename)")
+	anomalyFlags(cmd, &thresholds)
+
+	return cmd
+}
+
+func anomalyFlags(cmd *cobra.Command, thresholds *lint.AnomalyThresholds) {
+	cmd.Flags().Float64Var(&thresholds.LengthChangeRatio, "length-change", thresholds.LengthChangeRatio, "Maximum relative value length change (0.5 for 50%)")
+	cmd.Flags().Float64Var(&thresholds.HighEntropy, "high-entropy", thresholds.HighEntropy, "Entropy (bits per byte) above which a value is considered random")
+	cmd.Flags().Float64Var(&thresholds.LowEntropy, "low-entropy", thresholds.LowEntropy, "Entropy (bits per byte) below which a previously random value is reported")
+}
@@ -0,0 +1,89 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks/bundle"
+)
+
+// -----------------------------------------------------------------------------
+
+var bundleCSOSuggestCmd = func() *cobra.Command {
+	var (
+		inputPath     string
+		hintsPath     string
+		rulesPath     string
+		reviewPath    string
+		minConfidence float64
+		reportPath    string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "cso-suggest",
+		Short: "Suggest CSO compliant paths for legacy packages",
+		Long: `Suggest CSO compliant paths for legacy packages.
+
+Non-compliant package paths are split on common separators, tokens are matched
+against stages (prd, stg, ...), cloud regions (us-east-1, use1, ...),
+versions and the product, component, platform and service names of the hints
+file. Each suggestion has a confidence score, suggestions above the threshold
+are written as BundlePatch rewrite rules, the others are listed for review.`,
+		Example: `  # Suggest paths
+  harp bundle cso-suggest --in legacy.bundle --hints hints.yaml --rules rewrite.yaml
+
+  # Apply rewrite rules
+  harp bundle patch --in legacy.bundle --spec rewrite.yaml --out cso.bundle`,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-bundle-cso-suggest", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Prepare task
+			t := &bundle.CSOSuggestTask{
+				ContainerReader: cmdutil.FileReader(inputPath),
+				RulesWriter:     cmdutil.FileWriter(rulesPath),
+				ReviewWriter:    cmdutil.FileWriter(reviewPath),
+				MinConfidence:   minConfidence,
+			}
+			if hintsPath != "" {
+				t.HintsReader = cmdutil.FileReader(hintsPath)
+			}
+
+			// Run the task
+			if err := cmdutil.RunReportedTask(ctx, "bundle-cso-suggest", t, cmdutil.ReportWriter(reportPath)); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&inputPath, "in", "", "Container input ('-' for stdin or filename)")
+	cmd.Flags().StringVar(&hintsPath, "hints", "", "Product, component, platform and service dictionary (YAML)")
+	cmd.Flags().StringVar(&rulesPath, "rules", "", "BundlePatch rewrite rules output of high confidence suggestions")
+	log.CheckErr("unable to mark 'rules' flag as required.", cmd.MarkFlagRequired("rules"))
+	cmd.Flags().StringVar(&reviewPath, "review", "-", "Review list output of other suggestions ('-' for stdout or filename)")
+	cmd.Flags().Float64Var(&minConfidence, "min-confidence", bundle.DefaultMinConfidence, "Minimum confidence of suggestions written as rewrite rules")
+	cmd.Flags().StringVar(&reportPath, "report-file", "", "Task execution report output (JSON)")
+
+	return cmd
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/elastic/harp/pkg/sdk/cmdutil"
 	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/sdk/value"
 	"github.com/elastic/harp/pkg/sdk/value/encryption"
 	"github.com/elastic/harp/pkg/tasks/bundle"
 )
@@ -44,21 +45,21 @@ var bundleDecryptCmd = func() *cobra.Command {
 			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-bundle-decrypt", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
 			defer cancel()
 
-			// Create transformer according to used encryption key
-			transformer, err := encryption.FromKey(key)
-			if err != nil {
-				log.For(ctx).Fatal("unable to initialize transformer", zap.Error(err))
-			}
-
 			// Prepare task
 			t := &bundle.DecryptTask{
 				ContainerReader: cmdutil.FileReader(inputPath),
 				OutputWriter:    cmdutil.FileWriter(outputPath),
-				Transformer:     transformer,
+				// Select the transformer according to the recorded algorithm
+				TransformerResolver: func(algorithm string) (value.Transformer, error) {
+					if algorithm == "" {
+						return encryption.FromKey(key)
+					}
+					return encryption.ForAlgorithm(algorithm, key)
+				},
 			}
 
 			// Run the task
-			if err := t.Run(ctx); err != nil {
+			if err := cmdutil.RunTask(ctx, t); err != nil {
 				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
 			}
 		},
@@ -68,7 +69,10 @@ var bundleDecryptCmd = func() *cobra.Command {
 	cmd.Flags().StringVar(&inputPath, "in", "", "Container input ('-' for stdin or filename)")
 	cmd.Flags().StringVar(&outputPath, "out", "", "Container output ('-' for stdout or filename)")
 	cmd.Flags().StringVar(&key, "key", "", "Secret value decryption key")
-	log.CheckErr("unable to mark 'key' flag as required.", cmd.MarkFlagRequired("key"))
+
+	cmdutil.ValidateFlags(cmd,
+		cmdutil.Prompted("key", "Secret value decryption key", true),
+	)
 
 	return cmd
 }
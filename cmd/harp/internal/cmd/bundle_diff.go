@@ -21,6 +21,7 @@ import (
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 
+	"github.com/elastic/harp/pkg/bundle/lint"
 	"github.com/elastic/harp/pkg/sdk/cmdutil"
 	"github.com/elastic/harp/pkg/sdk/log"
 	"github.com/elastic/harp/pkg/tasks/bundle"
@@ -32,6 +33,10 @@ var bundleDiffCmd = func() *cobra.Command {
 	var (
 		sourcePath      string
 		destinationPath string
+		anomalies       bool
+		includeArchived bool
+		thresholds      = lint.DefaultAnomalyThresholds()
+		reportPath      string
 	)
 
 	cmd := &cobra.Command{
@@ -47,10 +52,13 @@ var bundleDiffCmd = func() *cobra.Command {
 				SourceReader:      cmdutil.FileReader(sourcePath),
 				DestinationReader: cmdutil.FileReader(destinationPath),
 				OutputWriter:      cmdutil.StdoutWriter(),
+				DetectAnomalies:   anomalies,
+				AnomalyThresholds: thresholds,
+				IncludeArchived:   includeArchived,
 			}
 
 			// Run the task
-			if err := t.Run(ctx); err != nil {
+			if err := cmdutil.RunReportedTask(ctx, "bundle-diff", t, cmdutil.ReportWriter(reportPath)); err != nil {
 				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
 			}
 		},
@@ -60,6 +68,10 @@ var bundleDiffCmd = func() *cobra.Command {
 	cmd.Flags().StringVar(&sourcePath, "src", "", "Container path ('-' for stdin or filename)")
 	cmd.Flags().StringVar(&destinationPath, "dst", "", "Container path")
 	log.CheckErr("unable to mark 'dst' flag as required.", cmd.MarkFlagRequired("dst"))
+	cmd.Flags().BoolVar(&anomalies, "anomalies", false, "Report suspicious value changes as warnings")
+	cmd.Flags().BoolVar(&includeArchived, "include-archived", false, "Include archived packages")
+	anomalyFlags(cmd, &thresholds)
+	cmd.Flags().StringVar(&reportPath, "report-file", "", "Task execution report output (JSON)")
 
 	return cmd
 }
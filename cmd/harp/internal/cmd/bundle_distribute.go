@@ -0,0 +1,98 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/bundle/distribution"
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks"
+	"github.com/elastic/harp/pkg/tasks/bundle"
+)
+
+// -----------------------------------------------------------------------------
+
+var bundleDistributeCmd = func() *cobra.Command {
+	var (
+		inputPath     string
+		specPath      string
+		manifestPath  string
+		failOnOrphans bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "distribute",
+		Short: "Produce sealed consumer specific sub-bundles from a distribution specification",
+		Example: `# Build all distributions declared in dist.yaml
+harp bundle distribute -f dist.yaml --in master.bundle
+
+# Fail when a package is selected by no distribution
+harp bundle distribute -f dist.yaml --in master.bundle --fail-on-orphans --manifest manifest.json`,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-bundle-distribute", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Check mandatory flags
+			if specPath == "" {
+				log.For(ctx).Fatal("spec flag must be defined")
+			}
+
+			// Output paths are declared by the specification
+			reader, err := cmdutil.Reader(specPath)
+			if err != nil {
+				log.For(ctx).Fatal("unable to open distribution specification", zap.Error(err), zap.String("path", specPath))
+			}
+			spec, err := distribution.ParseSpec(reader)
+			if err != nil {
+				log.For(ctx).Fatal("unable to parse distribution specification", zap.Error(err), zap.String("path", specPath))
+			}
+			writers := map[string]tasks.WriterProvider{}
+			for _, d := range spec.Spec.Distributions {
+				writers[d.Output] = cmdutil.FileWriter(d.Output)
+			}
+
+			// Prepare task
+			t := &bundle.DistributeTask{
+				ContainerReader: cmdutil.FileReader(inputPath),
+				Spec:            spec,
+				BundleWriter: func(path string) tasks.WriterProvider {
+					return writers[path]
+				},
+				OutputWriter:  cmdutil.FileWriter(manifestPath),
+				FailOnOrphans: failOnOrphans,
+			}
+
+			// Run the task
+			if err := cmdutil.RunTask(ctx, t); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&inputPath, "in", "", "Container input ('-' for stdin or filename)")
+	cmd.Flags().StringVarP(&specPath, "spec", "f", "", "Distribution specification path")
+	cmd.Flags().StringVar(&manifestPath, "manifest", "-", "Distribution manifest output ('-' for stdout or filename)")
+	cmd.Flags().BoolVar(&failOnOrphans, "fail-on-orphans", false, "Fail when a package is selected by no distribution")
+
+	return cmd
+}
@@ -0,0 +1,75 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks/bundle"
+)
+
+// -----------------------------------------------------------------------------
+
+var bundleDocsCmd = func() *cobra.Command {
+	var (
+		inputPath  string
+		outputPath string
+		themePath  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "docs",
+		Short: "Generate a documentation catalog of bundle packages",
+		Long: `Generate a static documentation catalog of bundle packages.
+
+The catalog is organized by CSO ring, platform and product. It exposes
+package descriptions, owners, rotation periods and key schema, secret values
+are never rendered. A documentation completeness score is computed per team.
+
+Use --theme to render the catalog using your own Go templates, each
+'<name>.tmpl' file of the theme directory is rendered as '<name>'.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-bundle-docs", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Prepare task
+			t := &bundle.DocsTask{
+				ContainerReader: cmdutil.FileReader(inputPath),
+				OutputPath:      outputPath,
+				ThemePath:       themePath,
+			}
+
+			// Run the task
+			if err := cmdutil.RunTask(ctx, t); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&inputPath, "in", "", "Container input ('-' for stdin or filename)")
+	cmd.Flags().StringVar(&outputPath, "out", "", "Output directory")
+	log.CheckErr("unable to mark 'out' flag as required.", cmd.MarkFlagRequired("out"))
+	cmd.Flags().StringVar(&themePath, "theme", "", "Template directory used to render the catalog")
+
+	return cmd
+}
@@ -30,11 +30,15 @@ import (
 
 var bundleDumpCmd = func() *cobra.Command {
 	var (
-		inputPath      string
-		dataOnly       bool
-		metadataOnly   bool
-		pathOnly       bool
-		jmesPathFilter string
+		inputPath       string
+		dataOnly        bool
+		metadataOnly    bool
+		pathOnly        bool
+		pathSort        string
+		codecOnly       bool
+		jmesPathFilter  string
+		includeArchived bool
+		decryption      valueDecryptionFlags
 	)
 
 	cmd := &cobra.Command{
@@ -45,18 +49,29 @@ var bundleDumpCmd = func() *cobra.Command {
 			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-bundle-dump", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
 			defer cancel()
 
+			// Prepare value decryption
+			valueDecryptor, decryptPolicy, err := decryption.build()
+			if err != nil {
+				log.For(ctx).Fatal("unable to prepare value decryption", zap.Error(err))
+			}
+
 			// Prepare task
 			t := &bundle.DumpTask{
-				ContainerReader: cmdutil.FileReader(inputPath),
-				OutputWriter:    cmdutil.StdoutWriter(),
-				DataOnly:        dataOnly,
-				MetadataOnly:    metadataOnly,
-				PathOnly:        pathOnly,
-				JMESPathFilter:  jmesPathFilter,
+				ContainerReader:      cmdutil.FileReader(inputPath),
+				OutputWriter:         cmdutil.StdoutWriter(),
+				DataOnly:             dataOnly,
+				MetadataOnly:         metadataOnly,
+				PathOnly:             pathOnly,
+				PathSort:             pathSort,
+				CodecOnly:            codecOnly,
+				JMESPathFilter:       jmesPathFilter,
+				IncludeArchived:      includeArchived,
+				ValueDecryptor:       valueDecryptor,
+				DecryptFailurePolicy: decryptPolicy,
 			}
 
 			// Run the task
-			if err := t.Run(ctx); err != nil {
+			if err := cmdutil.RunTask(ctx, t); err != nil {
 				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
 			}
 		},
@@ -68,7 +83,16 @@ var bundleDumpCmd = func() *cobra.Command {
 	cmd.Flags().BoolVar(&dataOnly, "data-only", false, "Display data only")
 	cmd.Flags().BoolVar(&metadataOnly, "metadata-only", false, "Display metadata only")
 	cmd.Flags().BoolVar(&pathOnly, "path-only", false, "Display path only")
+	cmd.Flags().StringVar(&pathSort, "sort", "", "Displayed path order (lexical, cso), bundle order is used when blank")
+	cmd.Flags().BoolVar(&codecOnly, "codec-only", false, "Display secret value codec information only")
 	cmd.Flags().StringVar(&jmesPathFilter, "jmespath", "", "Specify a JMESPath query to format output")
+	cmd.Flags().BoolVar(&includeArchived, "include-archived", false, "Include archived packages")
+	decryption.register(cmd)
+
+	cmdutil.ValidateFlags(cmd,
+		cmdutil.MutuallyExclusive("data-only", "content-only", "metadata-only", "path-only", "codec-only", "jmespath"),
+		cmdutil.When("sort", "", cmdutil.Required("path-only")),
+	)
 
 	return cmd
 }
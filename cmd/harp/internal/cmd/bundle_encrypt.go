@@ -34,6 +34,7 @@ var bundleEncryptCmd = func() *cobra.Command {
 		inputPath  string
 		outputPath string
 		key        string
+		algorithm  string
 	)
 
 	cmd := &cobra.Command{
@@ -44,8 +45,17 @@ var bundleEncryptCmd = func() *cobra.Command {
 			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-bundle-encrypt", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
 			defer cancel()
 
+			// Resolve encryption algorithm from the key if not specified
+			if algorithm == "" {
+				var err error
+				algorithm, err = encryption.Algorithm(key)
+				if err != nil {
+					log.For(ctx).Fatal("unable to resolve encryption algorithm", zap.Error(err))
+				}
+			}
+
 			// Create transformer according to used encryption key
-			transformer, err := encryption.FromKey(key)
+			transformer, err := encryption.ForAlgorithm(algorithm, key)
 			if err != nil {
 				log.For(ctx).Fatal("unable to initialize transformer", zap.Error(err))
 			}
@@ -55,10 +65,11 @@ var bundleEncryptCmd = func() *cobra.Command {
 				ContainerReader: cmdutil.FileReader(inputPath),
 				OutputWriter:    cmdutil.FileWriter(outputPath),
 				Transformer:     transformer,
+				Algorithm:       algorithm,
 			}
 
 			// Run the task
-			if err := t.Run(ctx); err != nil {
+			if err := cmdutil.RunTask(ctx, t); err != nil {
 				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
 			}
 		},
@@ -68,7 +79,11 @@ var bundleEncryptCmd = func() *cobra.Command {
 	cmd.Flags().StringVar(&inputPath, "in", "", "Container input ('-' for stdin or filename)")
 	cmd.Flags().StringVar(&outputPath, "out", "", "Container output ('-' for stdout or filename)")
 	cmd.Flags().StringVar(&key, "key", "", "Secret value encryption key")
-	log.CheckErr("unable to mark 'key' flag as required.", cmd.MarkFlagRequired("key"))
+	cmd.Flags().StringVar(&algorithm, "algorithm", "", "Encryption algorithm overriding the key one (aes-gcm, aes-siv, fernet, secretbox, xchacha)")
+
+	cmdutil.ValidateFlags(cmd,
+		cmdutil.Prompted("key", "Secret value encryption key", true),
+	)
 
 	return cmd
 }
@@ -0,0 +1,180 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"github.com/awnumar/memguard"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks/bundle"
+)
+
+// -----------------------------------------------------------------------------
+
+type bundleEscrowParams struct {
+	inputPath      string
+	selector       string
+	recipientPath  string
+	keyPath        string
+	signer         string
+	outputPath     string
+	manifestPath   string
+	reportPath     string
+	ignoreKeyUsage bool
+}
+
+var bundleEscrowCmd = func() *cobra.Command {
+	params := bundleEscrowParams{}
+
+	cmd := &cobra.Command{
+		Use:   "escrow",
+		Short: "Export selected secret values sealed for an escrow recipient",
+		Long: `Export the values of the packages matching the JMESPath selector, each
+value being individually sealed for the escrow recipient so that a single entry
+can be recovered. The escrow manifest (path, key id and ciphertext digest of each
+entry) is signed with the operator key and attached to the escrow bundle.`,
+		Example: `  # Escrow packages annotated for escrow
+  harp bundle escrow --in secrets.bundle --selector "annotations.escrow == 'required'" --recipient escrow.pub --key operator.pem --out escrow.bundle`,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-bundle-escrow", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Resolve signer
+			signer, err := signerProvider(params.signer, params.keyPath, params.ignoreKeyUsage)
+			if err != nil {
+				log.For(ctx).Fatal("unable to initialize signer", zap.Error(err))
+			}
+
+			// Prepare task
+			t := &bundle.EscrowTask{
+				ContainerReader: cmdutil.FileReader(params.inputPath),
+				RecipientReader: cmdutil.FileReader(params.recipientPath),
+				Signer:          signer,
+				OutputWriter:    cmdutil.FileWriter(params.outputPath),
+				Selector:        params.selector,
+			}
+			if params.manifestPath != "" {
+				t.ManifestWriter = cmdutil.FileWriter(params.manifestPath)
+			}
+
+			// Run the task
+			if err := cmdutil.RunReportedTask(ctx, "bundle-escrow", t, cmdutil.ReportWriter(params.reportPath)); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&params.inputPath, "in", "", "Container input ('-' for stdin or filename)")
+	cmd.Flags().StringVar(&params.selector, "selector", "", "JMESPath expression selecting escrowed packages")
+	log.CheckErr("unable to mark 'selector' flag as required.", cmd.MarkFlagRequired("selector"))
+	cmd.Flags().StringVar(&params.recipientPath, "recipient", "", "Escrow public key path (identity or base64url x25519 public key)")
+	log.CheckErr("unable to mark 'recipient' flag as required.", cmd.MarkFlagRequired("recipient"))
+	cmd.Flags().StringVar(&params.keyPath, "key", "", "Operator signing private key path (PEM or JWK), shortcut for '--signer local:<path>'")
+	cmd.Flags().StringVar(&params.signer, "signer", "", "Operator signer (local:<key path> or vault-transit:[<mount>/]<key name>)")
+	cmd.Flags().BoolVar(&params.ignoreKeyUsage, "ignore-key-usage", false, "Don't enforce local key usage policy")
+	cmd.Flags().StringVar(&params.outputPath, "out", "", "Escrow container output ('-' for stdout or filename)")
+	log.CheckErr("unable to mark 'out' flag as required.", cmd.MarkFlagRequired("out"))
+	cmd.Flags().StringVar(&params.manifestPath, "manifest", "", "Signed escrow manifest copy output (filename)")
+	cmd.Flags().StringVar(&params.reportPath, "report-file", "", "Write a JSON execution report to the given path ('-' for stdout)")
+
+	cmd.AddCommand(bundleEscrowRecoverCmd())
+
+	return cmd
+}
+
+// -----------------------------------------------------------------------------
+
+type bundleEscrowRecoverParams struct {
+	inputPath      string
+	escrowKeyRaw   string
+	keyPath        string
+	signer         string
+	outputPath     string
+	secretPath     string
+	fieldName      string
+	ignoreKeyUsage bool
+}
+
+var bundleEscrowRecoverCmd = func() *cobra.Command {
+	params := bundleEscrowRecoverParams{}
+
+	cmd := &cobra.Command{
+		Use:   "recover",
+		Short: "Recover an escrowed secret value",
+		Long: `Recover escrowed values of a package using the escrow private key. The
+escrow manifest signature is verified with the operator key, and the value is
+checked against its manifest digest before decryption.`,
+		Example: `  # Recover a single escrowed value
+  harp bundle escrow recover --in escrow.bundle --key operator.pub.pem --path infra/pki/root-ca --field key`,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-bundle-escrow-recover", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Resolve verification keys
+			resolver, err := keyResolverProvider(params.signer, params.keyPath, params.ignoreKeyUsage)
+			if err != nil {
+				log.For(ctx).Fatal("unable to initialize verification keys", zap.Error(err))
+			}
+
+			// Prepare escrow key
+			escrowKey := memguard.NewBufferFromBytes([]byte(params.escrowKeyRaw))
+			if params.escrowKeyRaw == "" {
+				// Read escrow key from stdin
+				escrowKey, err = cmdutil.ReadSecret("Enter escrow key", false)
+				if err != nil {
+					log.For(ctx).Fatal("unable to read escrow key", zap.Error(err))
+				}
+			}
+			defer escrowKey.Destroy()
+
+			// Prepare task
+			t := &bundle.EscrowRecoverTask{
+				ContainerReader: cmdutil.FileReader(params.inputPath),
+				KeyResolver:     resolver,
+				EscrowKey:       escrowKey,
+				OutputWriter:    cmdutil.FileWriter(params.outputPath),
+				Path:            params.secretPath,
+				SecretKey:       params.fieldName,
+			}
+
+			// Run the task
+			if err := cmdutil.RunTask(ctx, t); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&params.inputPath, "in", "", "Escrow container input ('-' for stdin or filename)")
+	cmd.Flags().StringVar(&params.escrowKeyRaw, "escrow-key", "", "Escrow private key (prompted when not defined)")
+	cmd.Flags().StringVar(&params.keyPath, "key", "", "Operator public key path (PEM, certificate or JWK), shortcut for '--signer local:<path>'")
+	cmd.Flags().StringVar(&params.signer, "signer", "", "Operator signer (local:<key path> or vault-transit:[<mount>/]<key name>)")
+	cmd.Flags().BoolVar(&params.ignoreKeyUsage, "ignore-key-usage", false, "Don't enforce local key usage policy")
+	cmd.Flags().StringVar(&params.outputPath, "out", "-", "Secret output ('-' for stdout or filename)")
+	cmd.Flags().StringVar(&params.secretPath, "path", "", "Escrowed package path")
+	log.CheckErr("unable to mark 'path' flag as required.", cmd.MarkFlagRequired("path"))
+	cmd.Flags().StringVar(&params.fieldName, "field", "", "Secret field (all escrowed fields as JSON when not defined)")
+
+	return cmd
+}
@@ -35,6 +35,7 @@ var bundleFilterCmd = func() *cobra.Command {
 		excludePaths []string
 		keepPaths    []string
 		jmesPath     string
+		reportPath   string
 	)
 
 	cmd := &cobra.Command{
@@ -56,7 +57,7 @@ var bundleFilterCmd = func() *cobra.Command {
 			}
 
 			// Run the task
-			if err := t.Run(ctx); err != nil {
+			if err := cmdutil.RunReportedTask(ctx, "bundle-filter", t, cmdutil.ReportWriter(reportPath)); err != nil {
 				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
 			}
 		},
@@ -68,6 +69,11 @@ var bundleFilterCmd = func() *cobra.Command {
 	cmd.Flags().StringArrayVar(&excludePaths, "exclude", []string{}, "Exclude path")
 	cmd.Flags().StringArrayVar(&keepPaths, "keep", []string{}, "Keep path")
 	cmd.Flags().StringVar(&jmesPath, "jmespath", "", "JMESPath query used as filter")
+	cmd.Flags().StringVar(&reportPath, "report-file", "", "Task execution report output (JSON)")
+
+	cmdutil.ValidateFlags(cmd,
+		cmdutil.RequiredOneOf("keep", "exclude", "jmespath"),
+	)
 
 	return cmd
 }
@@ -0,0 +1,85 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks/bundle"
+)
+
+// -----------------------------------------------------------------------------
+
+var bundleHistoryCmd = func() *cobra.Command {
+	var (
+		inputPath  string
+		outputPath string
+		path       string
+		field      string
+		jsonOutput bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Display secret key change history",
+		Long: `Display secret key change history.
+
+Mutating commands (patch, promote) record the actor, timestamp, operation and
+previous value digest of each changed secret key. The digest identifies the
+previous value without exposing it.`,
+		Example: `harp bundle history --in c.bundle --path app/production/db --field password`,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-bundle-history", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Prepare task
+			t := &bundle.HistoryTask{
+				ContainerReader: cmdutil.FileReader(inputPath),
+				OutputWriter:    cmdutil.StdoutWriter(),
+				Path:            path,
+				Field:           field,
+				JSONOutput:      jsonOutput,
+			}
+			if outputPath != "" {
+				t.OutputWriter = cmdutil.FileWriter(outputPath)
+			}
+
+			// Run the task
+			if err := cmdutil.RunTask(ctx, t); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&inputPath, "in", "", "Container input ('-' for stdin or filename)")
+	cmd.Flags().StringVar(&outputPath, "out", "", "Output path (stdout by default)")
+	cmd.Flags().StringVar(&path, "path", "", "Package path")
+	cmd.Flags().StringVar(&field, "field", "", "Secret key (all recorded keys by default)")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Display history as JSON")
+
+	cmdutil.ValidateFlags(cmd,
+		cmdutil.Prompted("path", "Secret path", false),
+	)
+
+	return cmd
+}
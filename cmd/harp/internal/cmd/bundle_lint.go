@@ -0,0 +1,83 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	pkgbundle "github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/tasks"
+	"github.com/elastic/harp/pkg/tasks/bundle"
+)
+
+// -----------------------------------------------------------------------------
+
+var bundleLintCmd = func() *cobra.Command {
+	var (
+		inputPath          string
+		policyPath         string
+		outputPath         string
+		quarantineFindings bool
+		bundleOutputPath   string
+		actor              string
+		reportPath         string
+		failureMode        func() tasks.FailureMode
+	)
+
+	cmd := &cobra.Command{
+		Use:          "lint",
+		Short:        "Check bundle secrets against a lint policy",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-bundle-lint", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Prepare task
+			t := &bundle.LintTask{
+				ContainerReader:    cmdutil.FileReader(inputPath),
+				OutputWriter:       cmdutil.FileWriter(outputPath),
+				QuarantineFindings: quarantineFindings,
+				Actor:              actor,
+				FailureMode:        failureMode(),
+			}
+			if quarantineFindings {
+				t.BundleWriter = cmdutil.FileWriter(bundleOutputPath)
+			}
+			if policyPath != "" {
+				t.PolicyReader = cmdutil.FileReader(policyPath)
+			}
+
+			// Run the task
+			return cmdutil.RunReportedTask(ctx, "bundle-lint", t, cmdutil.ReportWriter(reportPath))
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&inputPath, "in", "", "Container input ('-' for stdin or filename)")
+	cmd.Flags().StringVar(&policyPath, "policy", "", "Lint policy path (built-in weak credential policy by default)")
+	cmd.Flags().StringVar(&outputPath, "out", "", "Report output ('-' for stdout or filename)")
+	cmd.Flags().BoolVar(&quarantineFindings, "quarantine-findings", false, "Quarantine packages with unwaived findings")
+	cmd.Flags().StringVar(&bundleOutputPath, "bundle-out", "", "Container output when quarantining findings ('-' for stdout or filename)")
+	cmd.Flags().StringVar(&actor, "actor", pkgbundle.DefaultActor(), "Actor recorded in package history")
+	cmd.Flags().StringVar(&reportPath, "report-file", "", "Task execution report output (JSON)")
+	failureMode = cmdutil.FailureModeFlags(cmd)
+
+	return cmd
+}
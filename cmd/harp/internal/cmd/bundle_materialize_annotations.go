@@ -0,0 +1,88 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks/bundle"
+)
+
+// -----------------------------------------------------------------------------
+
+var bundleMaterializeAnnotationsCmd = func() *cobra.Command {
+	var (
+		inputPath    string
+		defaultsPath string
+		outputPath   string
+		attachOnly   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "materialize-annotations",
+		Short: "Bake inherited annotations into container packages",
+		Long: `Write the effective annotations of all packages and remove the annotation defaults.
+
+Annotation defaults declare annotations per path prefix, they are stored in the
+'meta/annotations/defaults' package or provided as a sidecar manifest. Package
+annotations override inherited ones, and the longest matching prefix wins.
+
+  apiVersion: harp.elastic.co/v1
+  kind: AnnotationDefaults
+  spec:
+    prefixes:
+    - prefix: app/production
+      annotations:
+        harp.elastic.co/v1/package#owner: platform-team
+        harp.elastic.co/v1/package#rotation: 90d
+
+Use '--attach' to store the sidecar manifest in the container without baking
+the annotations.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-bundle-materialize-annotations", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Prepare task
+			t := &bundle.MaterializeAnnotationsTask{
+				ContainerReader: cmdutil.FileReader(inputPath),
+				OutputWriter:    cmdutil.FileWriter(outputPath),
+				AttachOnly:      attachOnly,
+			}
+			if defaultsPath != "" {
+				t.DefaultsReader = cmdutil.FileReader(defaultsPath)
+			}
+
+			// Run the task
+			if err := cmdutil.RunTask(ctx, t); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&inputPath, "in", "", "Container input ('-' for stdin or filename)")
+	cmd.Flags().StringVar(&defaultsPath, "defaults", "", "Annotation defaults manifest path, replaces the container ones")
+	cmd.Flags().StringVar(&outputPath, "out", "", "Container output ('-' for stdout or filename)")
+	cmd.Flags().BoolVar(&attachOnly, "attach", false, "Only attach the defaults manifest to the container")
+
+	return cmd
+}
@@ -0,0 +1,85 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	pkgbundle "github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks"
+	"github.com/elastic/harp/pkg/tasks/bundle"
+)
+
+// -----------------------------------------------------------------------------
+
+var bundleMergeCmd = func() *cobra.Command {
+	var (
+		inputPaths    []string
+		outputPath    string
+		mergeStrategy string
+		reportPath    string
+		actor         string
+		historyLimit  int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "merge",
+		Short: "Merge secret containers",
+		Long: `Merge secret containers in order into the first one.
+
+Packages are merged at secret key level, conflicting keys are resolved
+according to the merge strategy.`,
+		Example: `  # Merge overrides into base
+  harp bundle merge --in base.bundle --in overrides.bundle --out merged.bundle`,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-bundle-merge", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Prepare task
+			t := &bundle.MergeTask{
+				ContainerReaders: []tasks.ReaderProvider{},
+				OutputWriter:     cmdutil.FileWriter(outputPath),
+				MergeStrategy:    pkgbundle.MergeStrategy(mergeStrategy),
+				Actor:            actor,
+				HistoryLimit:     historyLimit,
+			}
+			for _, p := range inputPaths {
+				t.ContainerReaders = append(t.ContainerReaders, cmdutil.FileReader(p))
+			}
+
+			// Run the task
+			if err := cmdutil.RunReportedTask(ctx, "bundle-merge", t, cmdutil.ReportWriter(reportPath)); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringArrayVar(&inputPaths, "in", []string{}, "Container input paths, merged in order")
+	cmd.Flags().StringVar(&outputPath, "out", "", "Container output ('-' for stdout or filename)")
+	cmd.Flags().StringVar(&mergeStrategy, "merge-strategy", string(pkgbundle.MergeStrategyOverwrite), "Conflict resolution strategy (keep, overwrite, fail)")
+	cmd.Flags().StringVar(&reportPath, "report-file", "", "Task execution report output (JSON)")
+	cmd.Flags().StringVar(&actor, "actor", pkgbundle.DefaultActor(), "Actor recorded in secret key history")
+	cmd.Flags().IntVar(&historyLimit, "history-limit", pkgbundle.DefaultHistoryLimit, "Maximum history entry count kept per secret key")
+
+	return cmd
+}
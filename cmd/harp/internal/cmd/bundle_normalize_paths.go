@@ -0,0 +1,96 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	pkgbundle "github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks/bundle"
+)
+
+// -----------------------------------------------------------------------------
+
+var bundleNormalizePathsCmd = func() *cobra.Command {
+	var (
+		inputPath     string
+		outputPath    string
+		planPath      string
+		apply         bool
+		mergeStrategy string
+		reportPath    string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "normalize-paths",
+		Short: "Rewrite package paths to their canonical form",
+		Long: `Rewrite package paths to their canonical form.
+
+Package paths differing only by case, Unicode normalization form (NFC vs NFD)
+or confusable characters are colliding on case-insensitive targets. Colliding
+packages are merged into the suggested survivor, rewritten to its NFC form.
+Other package paths are rewritten to their NFC form.
+
+The rewrite plan is displayed without modifying the bundle unless --apply is
+given.`,
+		Example: `  # Display the rewrite plan
+  harp bundle normalize-paths --in secrets.bundle
+
+  # Rewrite package paths
+  harp bundle normalize-paths --in secrets.bundle --apply --out normalized.bundle`,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-bundle-normalize-paths", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Prepare task
+			t := &bundle.NormalizePathsTask{
+				ContainerReader: cmdutil.FileReader(inputPath),
+				OutputWriter:    cmdutil.FileWriter(planPath),
+				Apply:           apply,
+				MergeStrategy:   pkgbundle.MergeStrategy(mergeStrategy),
+			}
+			if apply {
+				t.BundleWriter = cmdutil.FileWriter(outputPath)
+			}
+
+			// Run the task
+			if err := cmdutil.RunReportedTask(ctx, "bundle-normalize-paths", t, cmdutil.ReportWriter(reportPath)); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&inputPath, "in", "", "Container input ('-' for stdin or filename)")
+	cmd.Flags().StringVar(&outputPath, "out", "", "Container output when applying rewrites ('-' for stdout or filename)")
+	cmd.Flags().StringVar(&planPath, "plan", "-", "Rewrite plan output ('-' for stdout or filename)")
+	cmd.Flags().BoolVar(&apply, "apply", false, "Rewrite package paths")
+	cmd.Flags().StringVar(&mergeStrategy, "merge-strategy", string(pkgbundle.MergeStrategyFail), "Colliding secret key resolution strategy (keep, overwrite, fail)")
+	cmd.Flags().StringVar(&reportPath, "report-file", "", "Task execution report output (JSON)")
+
+	cmdutil.ValidateFlags(cmd,
+		cmdutil.When("out", "", cmdutil.Required("apply")),
+		cmdutil.When("merge-strategy", "", cmdutil.Required("apply")),
+	)
+
+	return cmd
+}
@@ -0,0 +1,72 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks/bundle"
+)
+
+// -----------------------------------------------------------------------------
+
+var bundleOverlayCmd = func() *cobra.Command {
+	var (
+		basePath    string
+		overlayPath string
+		outputPath  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "overlay",
+		Short: "Flatten an overlay container on a base container",
+		Long: `Apply overlay container packages on a base container and produce a regular container.
+
+Overlay packages replace base packages with the same path. An overlay package
+annotated with 'harp.elastic.co/v1/overlay#tombstone' hides the base package.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-bundle-overlay", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Prepare task
+			t := &bundle.OverlayTask{
+				BaseReader:    cmdutil.FileReader(basePath),
+				OverlayReader: cmdutil.FileReader(overlayPath),
+				OutputWriter:  cmdutil.FileWriter(outputPath),
+			}
+
+			// Run the task
+			if err := cmdutil.RunTask(ctx, t); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&basePath, "base", "", "Base container path ('-' for stdin or filename)")
+	log.CheckErr("unable to mark 'base' flag as required.", cmd.MarkFlagRequired("base"))
+	cmd.Flags().StringVar(&overlayPath, "overlay", "", "Overlay container path")
+	log.CheckErr("unable to mark 'overlay' flag as required.", cmd.MarkFlagRequired("overlay"))
+	cmd.Flags().StringVar(&outputPath, "out", "", "Container output ('-' for stdout or filename)")
+
+	return cmd
+}
@@ -18,11 +18,17 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 
+	pkgbundle "github.com/elastic/harp/pkg/bundle"
 	"github.com/elastic/harp/pkg/sdk/cmdutil"
 	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks"
 	"github.com/elastic/harp/pkg/tasks/bundle"
 	tplcmdutil "github.com/elastic/harp/pkg/template/cmdutil"
 )
@@ -38,6 +44,11 @@ var bundlePatchCmd = func() *cobra.Command {
 		values       []string
 		stringValues []string
 		fileValues   []string
+		inPlace      bool
+		lockTimeout  time.Duration
+		noLock       bool
+		actor        string
+		historyLimit int
 	)
 
 	cmd := &cobra.Command{
@@ -66,10 +77,23 @@ var bundlePatchCmd = func() *cobra.Command {
 				PatchReader:     cmdutil.FileReader(patchPath),
 				OutputWriter:    cmdutil.FileWriter(outputPath),
 				Values:          values,
+				Actor:           actor,
+				HistoryLimit:    historyLimit,
+			}
+
+			// Replace the input container
+			if inPlace {
+				if err := runInPlace(ctx, inputPath, lockTimeout, noLock, t, func(ip *cmdutil.InPlace) {
+					t.ContainerReader = ip.Reader()
+					t.OutputWriter = ip.Writer()
+				}); err != nil {
+					log.For(ctx).Fatal("unable to update container in place", zap.Error(err))
+				}
+				return
 			}
 
 			// Run the task
-			if err := t.Run(ctx); err != nil {
+			if err := cmdutil.RunTask(ctx, t); err != nil {
 				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
 			}
 		},
@@ -79,11 +103,47 @@ var bundlePatchCmd = func() *cobra.Command {
 	cmd.Flags().StringVar(&inputPath, "in", "-", "Container input ('-' for stdin or filename)")
 	cmd.Flags().StringVar(&outputPath, "out", "", "Container output ('-' for stdout or a filename)")
 	cmd.Flags().StringVar(&patchPath, "spec", "", "Patch specification path ('-' for stdin or filename)")
-	log.CheckErr("unable to mark 'spec' flag as required.", cmd.MarkFlagRequired("spec"))
 	cmd.Flags().StringArrayVar(&valueFiles, "values", []string{}, "Specifies value files to load")
 	cmd.Flags().StringArrayVar(&values, "set", []string{}, "Specifies value (k=v)")
 	cmd.Flags().StringArrayVar(&stringValues, "set-string", []string{}, "Specifies value (k=string)")
 	cmd.Flags().StringArrayVar(&fileValues, "set-file", []string{}, "Specifies value (k=filepath)")
+	cmd.Flags().BoolVar(&inPlace, "in-place", false, "Replace the input container with the patched one")
+	cmd.Flags().DurationVar(&lockTimeout, "lock-timeout", cmdutil.DefaultLockTimeout, "Maximum wait time to acquire the in-place update lock")
+	cmd.Flags().BoolVar(&noLock, "no-lock", false, "Disable in-place update locking (read-only filesystems)")
+	cmd.Flags().StringVar(&actor, "actor", pkgbundle.DefaultActor(), "Actor recorded in secret key history")
+	cmd.Flags().IntVar(&historyLimit, "history-limit", pkgbundle.DefaultHistoryLimit, "Maximum history entry count kept per secret key")
+
+	cmdutil.ValidateFlags(cmd,
+		cmdutil.Prompted("spec", "Patch specification path", false),
+		cmdutil.MutuallyExclusive("in-place", "out"),
+		cmdutil.When("no-lock", "", cmdutil.Required("in-place")),
+		cmdutil.When("lock-timeout", "", cmdutil.Required("in-place")),
+	)
 
 	return cmd
 }
+
+// -----------------------------------------------------------------------------
+
+// runInPlace runs the task with its input and output bound to an in-place
+// update of the given file. The lock is released before returning.
+func runInPlace(ctx context.Context, name string, lockTimeout time.Duration, noLock bool, t tasks.Task, bind func(*cmdutil.InPlace)) error {
+	var (
+		ip  *cmdutil.InPlace
+		err error
+	)
+	if noLock {
+		ip, err = cmdutil.NewUnlockedInPlace(name)
+	} else {
+		ip, err = cmdutil.NewInPlace(name, lockTimeout)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to prepare in-place update: %w", err)
+	}
+
+	// Bind task input and output
+	bind(ip)
+
+	// Delegate to in-place update
+	return ip.RunTask(ctx, t)
+}
@@ -0,0 +1,96 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	pkgbundle "github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks/bundle"
+)
+
+// -----------------------------------------------------------------------------
+
+var bundlePromoteCmd = func() *cobra.Command {
+	var (
+		inputPath     string
+		targetPath    string
+		outputPath    string
+		reportPath    string
+		fromStage     string
+		toStage       string
+		platform      string
+		product       string
+		mergeStrategy string
+		actor         string
+		historyLimit  int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "promote",
+		Short: "Promote application and platform secrets between stages",
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-bundle-promote", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Prepare task
+			t := &bundle.PromoteTask{
+				ContainerReader: cmdutil.FileReader(inputPath),
+				OutputWriter:    cmdutil.FileWriter(outputPath),
+				ReportWriter:    cmdutil.StderrWriter(),
+				FromStage:       fromStage,
+				ToStage:         toStage,
+				Platform:        platform,
+				Product:         product,
+				MergeStrategy:   pkgbundle.MergeStrategy(mergeStrategy),
+				Actor:           actor,
+				HistoryLimit:    historyLimit,
+			}
+			if targetPath != "" {
+				t.TargetReader = cmdutil.FileReader(targetPath)
+			}
+			if reportPath != "" {
+				t.ReportWriter = cmdutil.FileWriter(reportPath)
+			}
+
+			// Run the task
+			if err := cmdutil.RunTask(ctx, t); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&inputPath, "in", "", "Container input ('-' for stdin or filename)")
+	cmd.Flags().StringVar(&targetPath, "target", "", "Target container to merge promoted secrets into")
+	cmd.Flags().StringVar(&outputPath, "out", "", "Container path ('-' for stdout or filename)")
+	cmd.Flags().StringVar(&reportPath, "report", "", "Promotion report output path (stderr by default)")
+	cmd.Flags().StringVar(&fromStage, "from-stage", "", "Source stage")
+	cmd.Flags().StringVar(&toStage, "to-stage", "", "Target stage")
+	cmd.Flags().StringVar(&platform, "platform", "", "Platform name")
+	cmd.Flags().StringVar(&product, "product", "", "Product name (application secrets only)")
+	cmd.Flags().StringVar(&mergeStrategy, "merge-strategy", string(pkgbundle.MergeStrategyKeep), "Conflict resolution strategy (keep, overwrite, fail)")
+	cmd.Flags().StringVar(&actor, "actor", pkgbundle.DefaultActor(), "Actor recorded in secret key history")
+	cmd.Flags().IntVar(&historyLimit, "history-limit", pkgbundle.DefaultHistoryLimit, "Maximum history entry count kept per secret key")
+
+	return cmd
+}
@@ -0,0 +1,77 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	pkgbundle "github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks/bundle"
+)
+
+// -----------------------------------------------------------------------------
+
+var bundleQuarantineCmd = func() *cobra.Command {
+	var (
+		inputPath  string
+		outputPath string
+		paths      []string
+		reason     string
+		release    bool
+		actor      string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "quarantine",
+		Short: "Quarantine packages, blocking their distribution without deleting them",
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-bundle-quarantine", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Prepare task
+			t := &bundle.QuarantineTask{
+				ContainerReader: cmdutil.FileReader(inputPath),
+				OutputWriter:    cmdutil.FileWriter(outputPath),
+				Paths:           paths,
+				Reason:          reason,
+				Release:         release,
+				Actor:           actor,
+			}
+
+			// Run the task
+			if err := cmdutil.RunTask(ctx, t); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&inputPath, "in", "", "Container input ('-' for stdin or filename)")
+	cmd.Flags().StringVar(&outputPath, "out", "", "Container output ('-' for stdout or filename)")
+	cmd.Flags().StringArrayVar(&paths, "path", []string{}, "Package path")
+	log.CheckErr("unable to mark 'path' flag as required.", cmd.MarkFlagRequired("path"))
+	cmd.Flags().StringVar(&reason, "reason", "", "Quarantine reason")
+	cmd.Flags().BoolVar(&release, "release", false, "Release quarantined packages")
+	cmd.Flags().StringVar(&actor, "actor", pkgbundle.DefaultActor(), "Actor recorded in package history")
+
+	return cmd
+}
@@ -30,9 +30,10 @@ import (
 
 var bundleReadCmd = func() *cobra.Command {
 	var (
-		inputPath   string
-		packageName string
-		secretKey   string
+		inputPath       string
+		packageName     string
+		secretKey       string
+		includeArchived bool
 	)
 
 	cmd := &cobra.Command{
@@ -49,10 +50,11 @@ var bundleReadCmd = func() *cobra.Command {
 				OutputWriter:    cmdutil.StdoutWriter(),
 				PackageName:     packageName,
 				SecretKey:       secretKey,
+				IncludeArchived: includeArchived,
 			}
 
 			// Run the task
-			if err := t.Run(ctx); err != nil {
+			if err := cmdutil.RunTask(ctx, t); err != nil {
 				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
 			}
 		},
@@ -61,8 +63,12 @@ var bundleReadCmd = func() *cobra.Command {
 	// Parameters
 	cmd.Flags().StringVar(&inputPath, "in", "", "Container input ('-' for stdin or filename)")
 	cmd.Flags().StringVar(&packageName, "path", "", "Secret path")
-	log.CheckErr("unable to mark 'path' flag as required.", cmd.MarkFlagRequired("path"))
 	cmd.Flags().StringVar(&secretKey, "field", "", "Secret field")
+	cmd.Flags().BoolVar(&includeArchived, "include-archived", false, "Include archived packages")
+
+	cmdutil.ValidateFlags(cmd,
+		cmdutil.Prompted("path", "Secret path", false),
+	)
 
 	return cmd
 }
@@ -0,0 +1,107 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks/bundle"
+	tplcmdutil "github.com/elastic/harp/pkg/template/cmdutil"
+	"github.com/elastic/harp/pkg/template/engine"
+)
+
+// -----------------------------------------------------------------------------
+
+var bundleScaffoldCmd = func() *cobra.Command {
+	var (
+		inputPath      string
+		outputPath     string
+		packPath       string
+		archetypeName  string
+		packagePath    string
+		valueFiles     []string
+		values         []string
+		stringValues   []string
+		listArchetypes bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "scaffold",
+		Short: "Generate a package from a secret archetype",
+		Example: `  # List available archetypes
+  harp bundle scaffold --list-archetypes
+
+  # Scaffold a database package in a new container
+  harp bundle scaffold --archetype database --path app/production/billing/orders/v1.0.0/server/database --set host=db.internal --out secrets.bundle`,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-bundle-scaffold", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Load values
+			valueOpts := tplcmdutil.ValueOptions{
+				ValueFiles:   valueFiles,
+				Values:       values,
+				StringValues: stringValues,
+			}
+			values, err := valueOpts.MergeValues()
+			if err != nil {
+				log.For(ctx).Fatal("unable to process values", zap.Error(err))
+			}
+
+			// Prepare task
+			t := &bundle.ScaffoldTask{
+				OutputWriter: cmdutil.FileWriter(outputPath),
+				TemplateContext: engine.NewContext(
+					engine.WithName(archetypeName),
+					engine.WithValues(values),
+				),
+				Archetype:      archetypeName,
+				Path:           packagePath,
+				ListArchetypes: listArchetypes,
+			}
+			if inputPath != "" {
+				t.ContainerReader = cmdutil.FileReader(inputPath)
+			}
+			if packPath != "" {
+				t.PackReader = cmdutil.FileReader(packPath)
+			}
+
+			// Run the task
+			if err := cmdutil.RunTask(ctx, t); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&inputPath, "in", "", "Container input to add the package to ('-' for stdin or filename, new container by default)")
+	cmd.Flags().StringVar(&outputPath, "out", "", "Container output ('-' for stdout or a filename)")
+	cmd.Flags().StringVar(&packPath, "pack", "", "Organization archetype pack path overriding built-in archetypes")
+	cmd.Flags().StringVar(&archetypeName, "archetype", "", "Archetype name")
+	cmd.Flags().StringVar(&packagePath, "path", "", "Generated package path")
+	cmd.Flags().StringArrayVar(&valueFiles, "values", []string{}, "Specifies value files to load")
+	cmd.Flags().StringArrayVar(&values, "set", []string{}, "Specifies value (k=v)")
+	cmd.Flags().StringArrayVar(&stringValues, "set-string", []string{}, "Specifies value (k=string)")
+	cmd.Flags().BoolVar(&listArchetypes, "list-archetypes", false, "List available archetypes")
+
+	return cmd
+}
@@ -0,0 +1,99 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/bundle/search"
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks/bundle"
+)
+
+// -----------------------------------------------------------------------------
+
+var bundleSearchCmd = func() *cobra.Command {
+	var (
+		inputPath       string
+		outputPath      string
+		pathPrefix      string
+		keyGlob         string
+		valueRegex      string
+		valueHex        string
+		includeValues   bool
+		includeArchived bool
+		contextSize     int
+		maxResults      int
+		jsonOutput      bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "search",
+		Short: "Search package paths, secret keys and values",
+		Long: `Search package paths, secret keys and values.
+
+The pattern is matched against package paths and secret key names, and against
+secret values when --include-values is set. Matched values are never displayed,
+only a context excerpt with the matched part masked.
+
+Binary values are searched as bytes, use --value-hex to search a byte sequence.`,
+		Example: `harp bundle search --in c.bundle --value-regex 'AKIA[0-9A-Z]{16}' --key-glob '*token*' --path-prefix app/ --include-values`,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-bundle-search", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Prepare task
+			t := &bundle.SearchTask{
+				ContainerReader: cmdutil.FileReader(inputPath),
+				OutputWriter:    cmdutil.FileWriter(outputPath),
+				PathPrefix:      pathPrefix,
+				KeyGlob:         keyGlob,
+				ValueRegex:      valueRegex,
+				ValueHex:        valueHex,
+				IncludeValues:   includeValues,
+				IncludeArchived: includeArchived,
+				ContextSize:     contextSize,
+				MaxResults:      maxResults,
+				JSONOutput:      jsonOutput,
+			}
+
+			// Run the task
+			if err := cmdutil.RunTask(ctx, t); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&inputPath, "in", "", "Container input ('-' for stdin or filename)")
+	cmd.Flags().StringVar(&outputPath, "out", "", "Output ('-' for stdout or filename)")
+	cmd.Flags().StringVar(&pathPrefix, "path-prefix", "", "Restrict search to packages with the given path prefix")
+	cmd.Flags().StringVar(&keyGlob, "key-glob", "", "Restrict search to secret keys matching the glob pattern")
+	cmd.Flags().StringVar(&valueRegex, "value-regex", "", "Regular expression to search")
+	cmd.Flags().StringVar(&valueHex, "value-hex", "", "Hex encoded byte sequence to search")
+	cmd.Flags().BoolVar(&includeValues, "include-values", false, "Search unpacked secret values")
+	cmd.Flags().BoolVar(&includeArchived, "include-archived", false, "Include archived packages")
+	cmd.Flags().IntVar(&contextSize, "context", search.DefaultContextSize, "Count of characters displayed around value matches")
+	cmd.Flags().IntVar(&maxResults, "max-results", 100, "Stop after the given match count (0 for unlimited)")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Display matches as JSON lines")
+
+	return cmd
+}
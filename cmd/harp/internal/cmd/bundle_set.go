@@ -0,0 +1,121 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	pkgbundle "github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks/bundle"
+)
+
+// -----------------------------------------------------------------------------
+
+var bundleSetCmd = func() *cobra.Command {
+	var (
+		inputPath    string
+		outputPath   string
+		path         string
+		field        string
+		jsonPatch    string
+		mergePatch   string
+		inPlace      bool
+		lockTimeout  time.Duration
+		noLock       bool
+		actor        string
+		historyLimit int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "set",
+		Short: "Edit a JSON secret value using a JSON patch or a merge patch",
+		Example: `  # Apply a JSON patch (RFC 6902) to a secret value
+  harp bundle set --in bundle.bin --out bundle.bin --path app/production/api --field config \
+    --json-patch '[{"op":"replace","path":"/db/port","value":5433}]'
+
+  # Apply a JSON merge patch (RFC 7386) to a secret value
+  harp bundle set --in bundle.bin --out bundle.bin --path app/production/api --field config \
+    --merge-patch '{"db":{"host":"db.internal"},"debug":null}'
+
+  # Edit the container in place, concurrent updates are serialized
+  harp bundle set --in bundle.bin --in-place --path app/production/api --field config \
+    --merge-patch '{"debug":false}'`,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-bundle-set", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Prepare task
+			t := &bundle.SetTask{
+				ContainerReader: cmdutil.FileReader(inputPath),
+				OutputWriter:    cmdutil.FileWriter(outputPath),
+				Path:            path,
+				Field:           field,
+				JSONPatch:       jsonPatch,
+				MergePatch:      mergePatch,
+				Actor:           actor,
+				HistoryLimit:    historyLimit,
+			}
+
+			// Replace the input container
+			if inPlace {
+				if err := runInPlace(ctx, inputPath, lockTimeout, noLock, t, func(ip *cmdutil.InPlace) {
+					t.ContainerReader = ip.Reader()
+					t.OutputWriter = ip.Writer()
+				}); err != nil {
+					log.For(ctx).Fatal("unable to update container in place", zap.Error(err))
+				}
+				return
+			}
+
+			// Run the task
+			if err := cmdutil.RunTask(ctx, t); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&inputPath, "in", "", "Container input ('-' for stdin or filename)")
+	cmd.Flags().StringVar(&outputPath, "out", "", "Container output ('-' for stdout or filename)")
+	cmd.Flags().StringVar(&path, "path", "", "Package path")
+	cmd.Flags().StringVar(&field, "field", "", "Secret field holding a JSON document")
+	cmd.Flags().StringVar(&jsonPatch, "json-patch", "", "JSON patch (RFC 6902) to apply")
+	cmd.Flags().StringVar(&mergePatch, "merge-patch", "", "JSON merge patch (RFC 7386) to apply")
+	cmd.Flags().BoolVar(&inPlace, "in-place", false, "Replace the input container with the edited one")
+	cmd.Flags().DurationVar(&lockTimeout, "lock-timeout", cmdutil.DefaultLockTimeout, "Maximum wait time to acquire the in-place update lock")
+	cmd.Flags().BoolVar(&noLock, "no-lock", false, "Disable in-place update locking (read-only filesystems)")
+	cmd.Flags().StringVar(&actor, "actor", pkgbundle.DefaultActor(), "Actor recorded in secret key history")
+	cmd.Flags().IntVar(&historyLimit, "history-limit", pkgbundle.DefaultHistoryLimit, "Maximum history entry count kept per secret key")
+
+	cmdutil.ValidateFlags(cmd,
+		cmdutil.Required("path", "field"),
+		cmdutil.RequiredOneOf("json-patch", "merge-patch"),
+		cmdutil.MutuallyExclusive("json-patch", "merge-patch"),
+		cmdutil.MutuallyExclusive("in-place", "out"),
+		cmdutil.When("no-lock", "", cmdutil.Required("in-place")),
+		cmdutil.When("lock-timeout", "", cmdutil.Required("in-place")),
+	)
+
+	return cmd
+}
@@ -0,0 +1,104 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/bundle/cache"
+	"github.com/elastic/harp/pkg/sdk/keyring"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/sdk/sandbox"
+)
+
+// -----------------------------------------------------------------------------
+
+var cacheCmd = func() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Local encrypted secret cache commands",
+		Long: `Local encrypted secret cache commands.
+
+The cache holds an allowed subset of container secrets, so that they can be
+read without the container identity. It is encrypted with a key stored in the
+operating system keyring, cached secrets can't be read after their expiration.`,
+	}
+
+	// Sub-commands
+	cmd.AddCommand(cacheSyncCmd())
+	cmd.AddCommand(cacheReadCmd())
+	cmd.AddCommand(cachePurgeCmd())
+
+	return cmd
+}
+
+// -----------------------------------------------------------------------------
+
+type cacheParams struct {
+	cacheDir            string
+	insecureFileKeyring bool
+}
+
+func (p *cacheParams) bind(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&p.cacheDir, "cache-dir", "", "Cache directory (defaults to the user cache directory)")
+	cmd.Flags().BoolVar(&p.insecureFileKeyring, "insecure-file-keyring", false, "Store the cache key in a plain text file, for headless systems without keyring")
+}
+
+// open returns the local cache, or exits on error.
+func (p *cacheParams) open(ctx context.Context) *cache.Cache {
+	// Resolve cache directory
+	dir := p.cacheDir
+	if dir == "" {
+		var err error
+		dir, err = cache.DefaultDir()
+		if err != nil {
+			log.For(ctx).Fatal("unable to resolve cache directory", zap.Error(err))
+		}
+	}
+
+	// Declare the cache directory
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		log.For(ctx).Fatal("unable to create cache directory", zap.Error(err), zap.String("path", dir))
+	}
+	sandbox.AllowWriteDir(dir)
+
+	// Select keyring
+	var ring keyring.Keyring
+	if p.insecureFileKeyring {
+		log.For(ctx).Warn("cache key is stored in plain text", zap.String("path", dir))
+		ring = keyring.File(filepath.Join(dir, "keyring"))
+	} else {
+		var err error
+		ring, err = keyring.System()
+		if err != nil {
+			log.For(ctx).Fatal("unable to open system keyring, use --insecure-file-keyring on headless systems", zap.Error(err))
+		}
+	}
+
+	c, err := cache.Open(dir, ring)
+	if err != nil {
+		log.For(ctx).Fatal("unable to open cache", zap.Error(err))
+	}
+
+	return c
+}
@@ -0,0 +1,69 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks/cache"
+)
+
+// -----------------------------------------------------------------------------
+
+var cachePurgeCmd = func() *cobra.Command {
+	var (
+		params      cacheParams
+		expiredOnly bool
+		reportPath  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "purge",
+		Short: "Remove cached secrets",
+		Long: `Remove cached secrets.
+
+The cache file and its key are removed, unless --expired is given to only
+remove expired secrets.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-cache-purge", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Prepare task
+			t := &cache.PurgeTask{
+				Cache:       params.open(ctx),
+				ExpiredOnly: expiredOnly,
+			}
+
+			// Run the task
+			if err := cmdutil.RunReportedTask(ctx, "cache-purge", t, cmdutil.ReportWriter(reportPath)); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	params.bind(cmd)
+	cmd.Flags().BoolVar(&expiredOnly, "expired", false, "Only remove expired secrets")
+	cmd.Flags().StringVar(&reportPath, "report-file", "", "Task execution report output (JSON)")
+
+	return cmd
+}
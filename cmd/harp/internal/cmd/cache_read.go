@@ -0,0 +1,72 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks/cache"
+)
+
+// -----------------------------------------------------------------------------
+
+var cacheReadCmd = func() *cobra.Command {
+	var (
+		params     cacheParams
+		outputPath string
+		secretPath string
+		fieldName  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "read",
+		Short: "Read a cached secret",
+		Example: `  # Read a secret field
+  harp cache read --path app/production/payments/api/database --field password`,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-cache-read", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Prepare task
+			t := &cache.ReadTask{
+				Cache:        params.open(ctx),
+				OutputWriter: cmdutil.FileWriter(outputPath),
+				Path:         secretPath,
+				SecretKey:    fieldName,
+			}
+
+			// Run the task
+			if err := cmdutil.RunTask(ctx, t); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	params.bind(cmd)
+	cmd.Flags().StringVar(&outputPath, "out", "-", "Secret output ('-' for stdout or filename)")
+	cmd.Flags().StringVar(&secretPath, "path", "", "Secret path")
+	cmd.Flags().StringVar(&fieldName, "field", "", "Secret field (all fields as JSON when not defined)")
+	log.CheckErr("unable to mark 'path' flag as required.", cmd.MarkFlagRequired("path"))
+
+	return cmd
+}
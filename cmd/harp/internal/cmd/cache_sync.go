@@ -0,0 +1,97 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/awnumar/memguard"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks/cache"
+)
+
+// -----------------------------------------------------------------------------
+
+var cacheSyncCmd = func() *cobra.Command {
+	var (
+		params      cacheParams
+		inputPath   string
+		identityRaw string
+		pathsPath   string
+		ttl         time.Duration
+		reportPath  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Cache allowed secrets of a sealed container",
+		Long: `Cache allowed secrets of a sealed container.
+
+Only packages matching at least one path pattern of the paths file are
+cached, one pattern per line. Blank lines and lines starting with '#' are
+ignored. The previous cache content is replaced.`,
+		Example: `  # Cache payments secrets for 8 hours
+  harp cache sync --in sealed.container --identity key --paths-file allow.txt --ttl 8h`,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-cache-sync", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Prepare container key
+			containerKey := memguard.NewBufferFromBytes([]byte(identityRaw))
+			if identityRaw == "" {
+				var err error
+				// Read container key from stdin
+				containerKey, err = cmdutil.ReadSecret("Enter container key", false)
+				if err != nil {
+					log.For(ctx).Fatal("unable to read container key", zap.Error(err))
+				}
+			}
+			defer containerKey.Destroy()
+
+			// Prepare task
+			t := &cache.SyncTask{
+				ContainerReader: cmdutil.FileReader(inputPath),
+				PathsReader:     cmdutil.FileReader(pathsPath),
+				ContainerKey:    containerKey,
+				Cache:           params.open(ctx),
+				TTL:             ttl,
+			}
+
+			// Run the task
+			if err := cmdutil.RunReportedTask(ctx, "cache-sync", t, cmdutil.ReportWriter(reportPath)); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	params.bind(cmd)
+	cmd.Flags().StringVar(&inputPath, "in", "", "Sealed container input ('-' for stdin or filename)")
+	cmd.Flags().StringVar(&identityRaw, "identity", "", "Container key (prompted when not defined)")
+	cmd.Flags().StringVar(&pathsPath, "paths-file", "", "Allowed path patterns file")
+	cmd.Flags().DurationVar(&ttl, "ttl", 24*time.Hour, "Cached secrets lifetime")
+	cmd.Flags().StringVar(&reportPath, "report-file", "", "Task execution report output (JSON)")
+	log.CheckErr("unable to mark 'paths-file' flag as required.", cmd.MarkFlagRequired("paths-file"))
+
+	return cmd
+}
@@ -0,0 +1,131 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	pkgbundle "github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/compose"
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// -----------------------------------------------------------------------------
+
+var composeCmd = func() *cobra.Command {
+	var (
+		harpfilePath string
+		printGraph   bool
+		targets      []string
+		actor        string
+		historyLimit int
+		reportPath   string
+		parallelism  int
+		maxInflight  int64
+		failFast     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "compose",
+		Short: "Run a bundle composition pipeline",
+		Long: `Run the bundle composition pipeline described by a harpfile.
+
+Inputs, steps and outputs are executed in dependency order. Intermediate
+bundles are kept in memory, only outputs and steps declaring a 'save' path are
+written. Relative paths are resolved from the harpfile directory.
+
+When a node fails, nodes depending on it are skipped while independent nodes
+are still executed; all failures are reported at once. Use '--fail-fast' to
+skip all pending nodes on the first failure.
+
+Independent nodes are executed concurrently up to the '--parallelism' limit,
+node logs are buffered and displayed in graph order. '--max-inflight-bytes'
+prevents nodes from running concurrently when their memory estimate, computed
+from their input package counts, exceeds the budget.`,
+		Example: `  # Run all outputs
+  harp compose -f harpfile.yaml
+
+  # Display the execution graph
+  harp compose -f harpfile.yaml --graph
+
+  # Produce only the 'production' output and its dependencies
+  harp compose -f harpfile.yaml --only production
+
+  # Build distributions concurrently within a 512MiB memory budget
+  harp compose -f harpfile.yaml --parallelism 4 --max-inflight-bytes 536870912`,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-compose", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Load harpfile
+			hf, err := compose.Load(harpfilePath)
+			if err != nil {
+				log.For(ctx).Fatal("unable to load harpfile", zap.Error(err))
+			}
+
+			// Prepare plan
+			plan, err := compose.NewPlan(hf, compose.Files{
+				Reader: func(path string) tasks.ReaderProvider { return cmdutil.FileReader(path) },
+				Writer: func(path string) tasks.WriterProvider { return cmdutil.FileWriter(path) },
+			}, compose.Options{
+				Targets:          targets,
+				Actor:            actor,
+				HistoryLimit:     historyLimit,
+				Parallelism:      parallelism,
+				MaxInflightBytes: maxInflight,
+				FailFast:         failFast,
+			})
+			if err != nil {
+				log.For(ctx).Fatal("unable to prepare composition plan", zap.Error(err))
+			}
+
+			// Display graph only
+			if printGraph {
+				if err := plan.Graph().Print(os.Stdout); err != nil {
+					log.For(ctx).Fatal("unable to display graph", zap.Error(err))
+				}
+				return
+			}
+
+			// Run the task
+			if err := cmdutil.RunReportedTask(ctx, "compose", plan, cmdutil.ReportWriter(reportPath)); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVarP(&harpfilePath, "file", "f", "", "Harpfile path")
+	log.CheckErr("unable to mark 'file' flag as required.", cmd.MarkFlagRequired("file"))
+	cmd.Flags().BoolVar(&printGraph, "graph", false, "Display the execution graph without running it")
+	cmd.Flags().StringArrayVar(&targets, "only", []string{}, "Restrict execution to the given node and its dependencies")
+	cmd.Flags().StringVar(&actor, "actor", pkgbundle.DefaultActor(), "Actor recorded in secret key history")
+	cmd.Flags().IntVar(&historyLimit, "history-limit", pkgbundle.DefaultHistoryLimit, "Maximum history entry count kept per secret key")
+	cmd.Flags().StringVar(&reportPath, "report-file", "", "Task execution report output (JSON)")
+	cmd.Flags().IntVar(&parallelism, "parallelism", 1, "Maximum count of nodes executed concurrently")
+	cmd.Flags().Int64Var(&maxInflight, "max-inflight-bytes", 0, "Estimated memory budget of concurrently executed nodes (0 for unlimited)")
+	cmd.Flags().BoolVar(&failFast, "fail-fast", false, "Skip all pending nodes on the first failure")
+
+	return cmd
+}
@@ -0,0 +1,119 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+)
+
+// -----------------------------------------------------------------------------
+
+var configEffectiveCmd = func() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "effective <command> [flags]",
+		Short: "Display the effective flag values of a command with their provenance",
+		Example: `  # Flags of 'harp to vault' using the 'work' user profile
+  harp config effective to vault --config-profile work
+
+  # Explicit flags are reported as given
+  harp config effective bundle filter --query-values=false`,
+		// Flags belong to the inspected command
+		DisableFlagParsing: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Find the inspected command
+			target, rest, err := cmd.Root().Find(args)
+			if err != nil || target == cmd.Root() {
+				log.Bg().Fatal("unable to find the command to inspect", zap.Strings("args", args))
+			}
+			if err := target.ParseFlags(rest); err != nil {
+				log.Bg().Fatal("unable to parse command flags", zap.Error(err))
+			}
+
+			// Resolve flags
+			name, path, flags, warnings, err := applyUserProfile(target)
+			if err != nil {
+				log.Bg().Fatal("unable to apply user profile", zap.Error(err))
+			}
+
+			// Display
+			w := cmd.OutOrStdout()
+			fmt.Fprintf(w, "Command: %s\n", target.CommandPath())
+			if name != "" {
+				fmt.Fprintf(w, "Profile: %s (%s)\n", name, path)
+			} else {
+				fmt.Fprintln(w, "Profile: none")
+			}
+			for _, warning := range warnings {
+				fmt.Fprintf(w, "Warning: %s\n", warning)
+			}
+			fmt.Fprintln(w)
+			if err := cmdutil.WriteEffectiveFlags(w, flags); err != nil {
+				log.Bg().Fatal("unable to write effective flags", zap.Error(err))
+			}
+		},
+	}
+
+	return cmd
+}
+
+// -----------------------------------------------------------------------------
+
+// applyUserProfile applies the selected user profile and environment flag
+// values to the given command. It returns the profile name and file path,
+// the effective flags and the user config warnings.
+func applyUserProfile(cmd *cobra.Command) (name, path string, flags []cmdutil.EffectiveFlag, warnings []string, err error) {
+	// Select profile
+	name = configProfile
+	if name == "" {
+		name = os.Getenv(cmdutil.ProfileEnvVar)
+	}
+
+	// Load user config
+	path, err = cmdutil.UserConfigPath()
+	if err != nil {
+		if name == "" {
+			// Environment still applies without user config
+			flags, warnings, err = cmdutil.ApplyFlagDefaults(cmd, "", cmdutil.FlagProfile{}, nil)
+			return name, "", flags, warnings, err
+		}
+		return name, "", nil, nil, err
+	}
+	cfg, err := cmdutil.LoadUserConfig(path)
+	if err != nil {
+		return name, path, nil, nil, err
+	}
+	profile, err := cfg.Profile(name)
+	if err != nil {
+		return name, path, nil, nil, fmt.Errorf("%w in '%s'", err, path)
+	}
+
+	// Apply flag values
+	flags, warnings, err = cmdutil.ApplyFlagDefaults(cmd, name, profile, nil)
+	if err != nil {
+		return name, path, nil, warnings, err
+	}
+
+	return name, path, flags, append(profile.Check(cmd.Root()), warnings...), nil
+}
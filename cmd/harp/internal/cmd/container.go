@@ -35,6 +35,12 @@ var containerCmd = func() *cobra.Command {
 	cmd.AddCommand(containerRecoveryCmd())
 	cmd.AddCommand(containerSealCmd())
 	cmd.AddCommand(containerUnsealCmd())
+	cmd.AddCommand(containerDeltaCmd())
+	cmd.AddCommand(containerApplyDeltaCmd())
+	cmd.AddCommand(containerAttestCmd())
+	cmd.AddCommand(containerVerifyAttestationCmd())
+	cmd.AddCommand(containerMigrateCmd())
+	cmd.AddCommand(containerFeaturesCmd())
 
 	return cmd
 }
@@ -0,0 +1,170 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks/container"
+)
+
+// -----------------------------------------------------------------------------
+
+type containerAttestParams struct {
+	inputPath       string
+	keyPath         string
+	signer          string
+	outputPath      string
+	name            string
+	builderID       string
+	validity        time.Duration
+	consumptionPath string
+	ignoreKeyUsage  bool
+}
+
+var containerAttestCmd = func() *cobra.Command {
+	params := containerAttestParams{}
+
+	cmd := &cobra.Command{
+		Use:   "attest",
+		Short: "Produce a signed in-toto attestation of an unsealed container",
+		Example: `  # Sign with a local private key
+  harp container attest --in secrets.harp --signer local:signing.pem --out secrets.att.json
+
+  # Sign with a Vault transit key (VAULT_ADDR / VAULT_TOKEN)
+  harp container attest --in secrets.harp --signer vault-transit:harp-signing --out secrets.att.json`,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-container-attest", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Default subject name
+			name := params.name
+			if name == "" {
+				name = filepath.Base(params.inputPath)
+			}
+
+			// Resolve signer
+			signer, err := signerProvider(params.signer, params.keyPath, params.ignoreKeyUsage)
+			if err != nil {
+				log.For(ctx).Fatal("unable to initialize signer", zap.Error(err))
+			}
+
+			// Prepare task
+			t := &container.AttestTask{
+				ContainerReader: cmdutil.FileReader(params.inputPath),
+				Signer:          signer,
+				OutputWriter:    cmdutil.FileWriter(params.outputPath),
+				SubjectName:     name,
+				BuilderID:       params.builderID,
+				Validity:        params.validity,
+			}
+			if params.consumptionPath != "" {
+				t.ConsumptionReader = cmdutil.FileReader(params.consumptionPath)
+			}
+
+			// Run the task
+			if err := cmdutil.RunTask(ctx, t); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&params.inputPath, "in", "", "Unsealed container path")
+	log.CheckErr("unable to mark 'in' flag as required.", cmd.MarkFlagRequired("in"))
+	cmd.Flags().StringVar(&params.keyPath, "key", "", "Signing private key path (PEM or JWK), shortcut for '--signer local:<path>'")
+	cmd.Flags().StringVar(&params.signer, "signer", "", "Signer (local:<key path> or vault-transit:[<mount>/]<key name>)")
+	cmd.Flags().BoolVar(&params.ignoreKeyUsage, "ignore-key-usage", false, "Don't enforce local key usage policy")
+	cmd.Flags().StringVar(&params.outputPath, "out", "-", "Attestation output ('-' for stdout or filename)")
+	cmd.Flags().StringVar(&params.name, "name", "", "Attested subject name (container file name by default)")
+	cmd.Flags().StringVar(&params.builderID, "builder", bundle.DefaultActor(), "Builder identity")
+	cmd.Flags().DurationVar(&params.validity, "validity", 0, "Attestation validity duration (0 for no expiration)")
+	cmd.Flags().StringVar(&params.consumptionPath, "consumption-manifest", "", "Consumption manifest of the render which produced the container, embedded in the attestation")
+
+	return cmd
+}
+
+// -----------------------------------------------------------------------------
+
+type containerVerifyAttestationParams struct {
+	containerPath  string
+	keyPath        string
+	signer         string
+	outputPath     string
+	jsonOutput     bool
+	ignoreKeyUsage bool
+}
+
+var containerVerifyAttestationCmd = func() *cobra.Command {
+	params := containerVerifyAttestationParams{}
+
+	cmd := &cobra.Command{
+		Use:   "verify-attestation <attestation>",
+		Short: "Verify a container attestation",
+		Example: `  # Verify with an exported public key
+  harp container verify-attestation --key signing.pub.pem --container secrets.harp secrets.att.json
+
+  # Verify with the public key of the recorded Vault transit key version
+  harp container verify-attestation --signer vault-transit:harp-signing --container secrets.harp secrets.att.json`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-container-verify-attestation", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Resolve verification keys
+			resolver, err := keyResolverProvider(params.signer, params.keyPath, params.ignoreKeyUsage)
+			if err != nil {
+				log.For(ctx).Fatal("unable to initialize verification keys", zap.Error(err))
+			}
+
+			// Prepare task
+			t := &container.VerifyAttestationTask{
+				ContainerReader:   cmdutil.FileReader(params.containerPath),
+				AttestationReader: cmdutil.FileReader(args[0]),
+				KeyResolver:       resolver,
+				OutputWriter:      cmdutil.FileWriter(params.outputPath),
+				JSONOutput:        params.jsonOutput,
+			}
+
+			// Run the task
+			if err := cmdutil.RunTask(ctx, t); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&params.containerPath, "container", "", "Attested container path")
+	log.CheckErr("unable to mark 'container' flag as required.", cmd.MarkFlagRequired("container"))
+	cmd.Flags().StringVar(&params.keyPath, "key", "", "Verification public key path (PEM, certificate or JWK), shortcut for '--signer local:<path>'")
+	cmd.Flags().StringVar(&params.signer, "signer", "", "Signer public key source (local:<key path> or vault-transit:[<mount>/]<key name>)")
+	cmd.Flags().BoolVar(&params.ignoreKeyUsage, "ignore-key-usage", false, "Don't enforce local key usage policy")
+	cmd.Flags().StringVar(&params.outputPath, "out", "-", "Verification report output ('-' for stdout or filename)")
+	cmd.Flags().BoolVar(&params.jsonOutput, "json", false, "Display the verified statement as JSON")
+
+	return cmd
+}
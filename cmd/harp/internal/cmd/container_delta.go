@@ -0,0 +1,122 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/container/delta"
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks/container"
+)
+
+// -----------------------------------------------------------------------------
+
+type containerDeltaParams struct {
+	basePath     string
+	targetPath   string
+	outputPath   string
+	chunkOptions delta.ChunkOptions
+}
+
+var containerDeltaCmd = func() *cobra.Command {
+	params := containerDeltaParams{
+		chunkOptions: delta.DefaultChunkOptions(),
+	}
+
+	cmd := &cobra.Command{
+		Use:   "delta",
+		Short: "Compute a binary delta between two containers",
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-container-delta", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Prepare task
+			t := &container.DeltaTask{
+				BaseReader:   cmdutil.FileReader(params.basePath),
+				TargetReader: cmdutil.FileReader(params.targetPath),
+				OutputWriter: cmdutil.FileWriter(params.outputPath),
+				StatsWriter:  cmdutil.StderrWriter(),
+				ChunkOptions: params.chunkOptions,
+			}
+
+			// Run the task
+			if err := cmdutil.RunTask(ctx, t); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&params.basePath, "base", "", "Base container path")
+	log.CheckErr("unable to mark 'base' flag as required.", cmd.MarkFlagRequired("base"))
+	cmd.Flags().StringVar(&params.targetPath, "target", "", "Target container path ('-' for stdin or filename)")
+	log.CheckErr("unable to mark 'target' flag as required.", cmd.MarkFlagRequired("target"))
+	cmd.Flags().StringVar(&params.outputPath, "out", "", "Delta output ('-' for stdout or filename)")
+	cmd.Flags().IntVar(&params.chunkOptions.MinSize, "min-chunk-size", params.chunkOptions.MinSize, "Minimum chunk size in bytes")
+	cmd.Flags().IntVar(&params.chunkOptions.AvgSize, "avg-chunk-size", params.chunkOptions.AvgSize, "Average chunk size in bytes (power of 2)")
+	cmd.Flags().IntVar(&params.chunkOptions.MaxSize, "max-chunk-size", params.chunkOptions.MaxSize, "Maximum chunk size in bytes")
+
+	return cmd
+}
+
+// -----------------------------------------------------------------------------
+
+type containerApplyDeltaParams struct {
+	basePath   string
+	deltaPath  string
+	outputPath string
+}
+
+var containerApplyDeltaCmd = func() *cobra.Command {
+	params := containerApplyDeltaParams{}
+
+	cmd := &cobra.Command{
+		Use:   "apply-delta",
+		Short: "Reconstruct a container from a base container and a delta",
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-container-apply-delta", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Prepare task
+			t := &container.ApplyDeltaTask{
+				BaseReader:   cmdutil.FileReader(params.basePath),
+				DeltaReader:  cmdutil.FileReader(params.deltaPath),
+				OutputWriter: cmdutil.FileWriter(params.outputPath),
+			}
+
+			// Run the task
+			if err := cmdutil.RunTask(ctx, t); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&params.basePath, "base", "", "Base container path")
+	log.CheckErr("unable to mark 'base' flag as required.", cmd.MarkFlagRequired("base"))
+	cmd.Flags().StringVar(&params.deltaPath, "delta", "", "Delta path ('-' for stdin or filename)")
+	log.CheckErr("unable to mark 'delta' flag as required.", cmd.MarkFlagRequired("delta"))
+	cmd.Flags().StringVar(&params.outputPath, "out", "", "Container output ('-' for stdout or filename)")
+
+	return cmd
+}
@@ -22,8 +22,10 @@ import (
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 
+	"github.com/elastic/harp/pkg/container/identity"
 	"github.com/elastic/harp/pkg/sdk/cmdutil"
 	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/sdk/security/piv"
 	"github.com/elastic/harp/pkg/tasks/container"
 )
 
@@ -34,6 +36,10 @@ type containerIdentityParams struct {
 	passPhrase       string
 	vaultTransitPath string
 	vaultTransitKey  string
+	usePIV           bool
+	pivSlot          string
+	store            string
+	name             string
 }
 
 var containerIdentityCmd = func() *cobra.Command {
@@ -48,12 +54,38 @@ var containerIdentityCmd = func() *cobra.Command {
 			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-identity", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
 			defer cancel()
 
-			// Check mandatory flags
-			if params.passPhrase == "" && params.vaultTransitKey == "" {
-				log.For(ctx).Fatal("passphrase or vault-transit-path flag must be defined")
+			// Select identity storage
+			var store *identity.Store
+			switch params.store {
+			case identityStoreFile:
+			case identityStoreKeychain:
+				store = identityStore(ctx)
+			default:
+				log.For(ctx).Fatal("unsupported identity store", zap.String("store", params.store))
 			}
-			if params.passPhrase != "" && params.vaultTransitKey != "" {
-				log.For(ctx).Fatal("passphrase and vault-transit-path flags are mutually exclusive")
+
+			// Token backed identity
+			if params.usePIV {
+				slot, err := piv.ParseSlot(params.pivSlot)
+				if err != nil {
+					log.For(ctx).Fatal("unable to parse slot flag", zap.Error(err))
+				}
+
+				// Prepare task
+				t := &container.IdentityTask{
+					OutputWriter: cmdutil.FileWriter(params.outputPath),
+					Description:  params.description,
+					PIVProvider:  piv.HardwareProvider(),
+					PIVSlot:      slot,
+					Store:        store,
+					StoreName:    params.name,
+				}
+
+				// Run the task
+				if err := cmdutil.RunTask(ctx, t); err != nil {
+					log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+				}
+				return
 			}
 
 			// Prepare task
@@ -63,22 +95,42 @@ var containerIdentityCmd = func() *cobra.Command {
 				VaultTransitPath: params.vaultTransitPath,
 				VaultTransitKey:  params.vaultTransitKey,
 				Description:      params.description,
+				Store:            store,
+				StoreName:        params.name,
 			}
 
 			// Run the task
-			if err := t.Run(ctx); err != nil {
+			if err := cmdutil.RunTask(ctx, t); err != nil {
 				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
 			}
 		},
 	}
 
+	// Subcommands
+	cmd.AddCommand(containerIdentityPassphraseCmd())
+	cmd.AddCommand(containerIdentityListCmd())
+	cmd.AddCommand(containerIdentityDeleteCmd())
+
 	// Flags
 	cmd.Flags().StringVar(&params.outputPath, "out", "", "Identity information output ('-' for stdout or filename)")
 	cmd.Flags().StringVar(&params.passPhrase, "passphrase", "", "Identity private key passphrase")
 	cmd.Flags().StringVar(&params.vaultTransitPath, "vault-transit-path", "transit", "Vault transit backend mount path")
 	cmd.Flags().StringVar(&params.vaultTransitKey, "vault-transit-key", "", "Use Vault transit encryption to protect identity private key")
 	cmd.Flags().StringVar(&params.description, "description", "", "Identity description")
-	log.CheckErr("unable to mark 'description' flag as required.", cmd.MarkFlagRequired("description"))
+	cmd.Flags().BoolVar(&params.usePIV, "piv", false, "Use the X25519 key stored on a PIV token (YubiKey) as identity")
+	cmd.Flags().StringVar(&params.pivSlot, "slot", "9a", "PIV token slot holding the identity key")
+	cmd.Flags().StringVar(&params.store, "store", identityStoreFile, "Identity storage (file, keychain)")
+	cmd.Flags().StringVar(&params.name, "name", "", "Identity name in the keychain store, referenced as 'keychain:<name>'")
+
+	cmdutil.ValidateFlags(cmd,
+		cmdutil.Prompted("description", "Identity description", false),
+		cmdutil.RequiredOneOf("piv", "passphrase", "vault-transit-key"),
+		cmdutil.MutuallyExclusive("piv", "passphrase", "vault-transit-key"),
+		cmdutil.When("store", identityStoreKeychain,
+			cmdutil.Forbidden("out"),
+			cmdutil.Prompted("name", "Identity name", false),
+		),
+	)
 
 	return cmd
 }
@@ -0,0 +1,109 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/awnumar/memguard"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks/container"
+)
+
+// -----------------------------------------------------------------------------
+
+type containerIdentityPassphraseParams struct {
+	inputPath     string
+	outputPath    string
+	oldPassPhrase string
+	newPassPhrase string
+	lockTimeout   time.Duration
+}
+
+var containerIdentityPassphraseCmd = func() *cobra.Command {
+	params := containerIdentityPassphraseParams{}
+
+	cmd := &cobra.Command{
+		Use:   "passphrase",
+		Short: "Change the passphrase protecting an identity private key",
+		Run: func(cmd *cobra.Command, _ []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-identity-passphrase", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Retrieve passphrases
+			oldPassPhrase := passphraseBuffer(ctx, params.oldPassPhrase, "Current passphrase", false)
+			defer oldPassPhrase.Destroy()
+			newPassPhrase := passphraseBuffer(ctx, params.newPassPhrase, "New passphrase", true)
+			defer newPassPhrase.Destroy()
+
+			// Prepare task
+			t := &container.PassphraseTask{
+				IdentityReader: cmdutil.FileReader(params.inputPath),
+				OutputWriter:   cmdutil.FileWriter(params.outputPath),
+				OldPassPhrase:  oldPassPhrase,
+				NewPassPhrase:  newPassPhrase,
+			}
+
+			// Replace the identity atomically when updated in place
+			if params.outputPath == params.inputPath && params.inputPath != "-" {
+				if err := runInPlace(ctx, params.inputPath, params.lockTimeout, false, t, func(ip *cmdutil.InPlace) {
+					t.IdentityReader = ip.Reader()
+					t.OutputWriter = ip.Writer()
+				}); err != nil {
+					log.For(ctx).Fatal("unable to update identity in place", zap.Error(err))
+				}
+				return
+			}
+
+			// Run the task
+			if err := cmdutil.RunTask(ctx, t); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Flags
+	cmd.Flags().StringVar(&params.inputPath, "in", "", "Identity input ('-' for stdin or filename)")
+	log.CheckErr("unable to mark 'in' flag as required.", cmd.MarkFlagRequired("in"))
+	cmd.Flags().StringVar(&params.outputPath, "out", "", "Identity output ('-' for stdout or filename)")
+	cmd.Flags().StringVar(&params.oldPassPhrase, "old-passphrase", "", "Current identity private key passphrase (prompted if not set)")
+	cmd.Flags().StringVar(&params.newPassPhrase, "new-passphrase", "", "New identity private key passphrase (prompted if not set)")
+	cmd.Flags().DurationVar(&params.lockTimeout, "lock-timeout", cmdutil.DefaultLockTimeout, "Maximum wait time to acquire the in-place update lock")
+
+	return cmd
+}
+
+// passphraseBuffer wraps the given passphrase or prompts for it when blank.
+func passphraseBuffer(ctx context.Context, value, prompt string, confirmation bool) *memguard.LockedBuffer {
+	if value != "" {
+		return memguard.NewBufferFromBytes([]byte(value))
+	}
+
+	buf, err := cmdutil.ReadSecret(prompt, confirmation)
+	if err != nil {
+		log.For(ctx).Fatal("unable to read passphrase", zap.Error(err))
+	}
+
+	return buf
+}
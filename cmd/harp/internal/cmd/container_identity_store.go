@@ -0,0 +1,152 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/container/identity"
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/keyring"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/sdk/sandbox"
+	"github.com/elastic/harp/pkg/tasks"
+	"github.com/elastic/harp/pkg/tasks/container"
+)
+
+const (
+	identityStoreFile     = "file"
+	identityStoreKeychain = "keychain"
+)
+
+// -----------------------------------------------------------------------------
+
+var containerIdentityListCmd = func() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List identities stored in the keychain",
+		Run: func(cmd *cobra.Command, _ []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-identity-list", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Prepare task
+			t := &container.IdentityListTask{
+				Store:        identityStore(ctx),
+				OutputWriter: cmdutil.StdoutWriter(),
+			}
+
+			// Run the task
+			if err := cmdutil.RunTask(ctx, t); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	return cmd
+}
+
+var containerIdentityDeleteCmd = func() *cobra.Command {
+	var name string
+
+	cmd := &cobra.Command{
+		Use:   "delete",
+		Short: "Delete an identity stored in the keychain",
+		Run: func(cmd *cobra.Command, _ []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-identity-delete", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Prepare task
+			t := &container.IdentityDeleteTask{
+				Store: identityStore(ctx),
+				Name:  name,
+			}
+
+			// Run the task
+			if err := cmdutil.RunTask(ctx, t); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Flags
+	cmd.Flags().StringVar(&name, "name", "", "Stored identity name")
+	log.CheckErr("unable to mark 'name' flag as required.", cmd.MarkFlagRequired("name"))
+
+	return cmd
+}
+
+// -----------------------------------------------------------------------------
+
+// identityStore returns the keychain identity store, or exits when the
+// system keychain is not available. Identities are never stored as files
+// implicitly.
+func identityStore(ctx context.Context) *identity.Store {
+	// Keychain helpers are external commands
+	if sandbox.Enabled() {
+		log.For(ctx).Fatal("keychain identity storage relies on system commands and can't be used with the sandbox enabled")
+	}
+
+	ring, err := keyring.System()
+	if err != nil {
+		log.For(ctx).Fatal("unable to open system keychain, identities must be stored as files on this system", zap.Error(err))
+	}
+
+	return identity.NewStore(ring)
+}
+
+// identityReader returns the identity reader of a file path or of a
+// 'keychain:<name>' reference. Keychain identities are retrieved before the
+// task runs, so that they are available in the sandbox.
+func identityReader(ctx context.Context, ref string) tasks.ReaderProvider {
+	reader, err := resolveIdentityReader(ref)
+	if err != nil {
+		log.For(ctx).Fatal("unable to retrieve identity", zap.Error(err), zap.String("identity", ref))
+	}
+
+	return reader
+}
+
+// resolveIdentityReader returns the identity reader of the given reference as
+// identityReader does, keychain errors are returned.
+func resolveIdentityReader(ref string) (tasks.ReaderProvider, error) {
+	name, ok := identity.KeychainName(ref)
+	if !ok {
+		return cmdutil.FileReader(ref), nil
+	}
+
+	ring, err := keyring.System()
+	if err != nil {
+		return nil, fmt.Errorf("unable to open system keychain: %w", err)
+	}
+	content, err := identity.NewStore(ring).Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve identity from keychain: %w", err)
+	}
+
+	return func(context.Context) (io.Reader, error) {
+		return bytes.NewReader(content), nil
+	}, nil
+}
@@ -34,6 +34,7 @@ type containerRecoveryParams struct {
 	jsonOutput       bool
 	vaultTransitPath string
 	vaultTransitKey  string
+	ignoreKeyUsage   bool
 }
 
 var containerRecoveryCmd = func() *cobra.Command {
@@ -47,37 +48,36 @@ var containerRecoveryCmd = func() *cobra.Command {
 			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-container-recover", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
 			defer cancel()
 
-			// Check mandatory flags
-			if params.passPhrase == "" && params.vaultTransitKey == "" {
-				log.For(ctx).Fatal("passphrase or vault-transit-path flag must be defined")
-			}
-			if params.passPhrase != "" && params.vaultTransitKey != "" {
-				log.For(ctx).Fatal("passphrase and vault-transit-path flags are mutually exclusive")
-			}
-
 			// Prepare task
 			t := &container.RecoverTask{
-				JSONReader:       cmdutil.FileReader(params.identityPath),
+				JSONReader:       identityReader(ctx, params.identityPath),
 				OutputWriter:     cmdutil.StdoutWriter(),
 				PassPhrase:       memguard.NewBufferFromBytes([]byte(params.passPhrase)),
 				VaultTransitPath: params.vaultTransitPath,
 				VaultTransitKey:  params.vaultTransitKey,
 				JSONOutput:       params.jsonOutput,
+				IgnoreKeyUsage:   params.ignoreKeyUsage,
 			}
 
 			// Run the task
-			if err := t.Run(ctx); err != nil {
+			if err := cmdutil.RunTask(ctx, t); err != nil {
 				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
 			}
 		},
 	}
 
 	// Flags
-	cmd.Flags().StringVar(&params.identityPath, "identity", "", "Identity input  ('-' for stdout or filename)")
+	cmd.Flags().StringVar(&params.identityPath, "identity", "", "Identity input ('-' for stdin, filename or 'keychain:<name>')")
 	cmd.Flags().StringVar(&params.passPhrase, "passphrase", "", "Identity private key passphrase")
 	cmd.Flags().StringVar(&params.vaultTransitPath, "vault-transit-path", "transit", "Vault transit backend mount path")
 	cmd.Flags().StringVar(&params.vaultTransitKey, "vault-transit-key", "", "Use Vault transit encryption to protect identity private key")
 	cmd.Flags().BoolVar(&params.jsonOutput, "json", false, "Display container key as json")
+	cmd.Flags().BoolVar(&params.ignoreKeyUsage, "ignore-key-usage", false, "Don't enforce identity key usage policy")
+
+	cmdutil.ValidateFlags(cmd,
+		cmdutil.RequiredOneOf("passphrase", "vault-transit-key"),
+		cmdutil.MutuallyExclusive("passphrase", "vault-transit-key"),
+	)
 
 	return cmd
 }
@@ -18,13 +18,14 @@
 package cmd
 
 import (
+	"context"
 	"encoding/base64"
+	"io"
 
 	"github.com/awnumar/memguard"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 
-	"github.com/elastic/harp/pkg/container/identity"
 	"github.com/elastic/harp/pkg/sdk/cmdutil"
 	"github.com/elastic/harp/pkg/sdk/log"
 	"github.com/elastic/harp/pkg/tasks/container"
@@ -41,6 +42,9 @@ type containerSealParams struct {
 	target              string
 	noContainerIdentity bool
 	jsonOutput          bool
+	dryRun              bool
+	ignoreKeyUsage      bool
+	reportPath          string
 }
 
 var containerSealCmd = func() *cobra.Command {
@@ -54,29 +58,22 @@ var containerSealCmd = func() *cobra.Command {
 			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-container-seal", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
 			defer cancel()
 
-			// Load idenity from files
-			if len(params.identityFilePaths) > 0 {
-				for _, f := range params.identityFilePaths {
-					if f == "" {
-						// Ignore empty
-						continue
-					}
-
-					// Open for reading
-					r, err := cmdutil.Reader(f)
-					if err != nil {
-						log.For(ctx).Fatal("unable to read identity file", zap.Error(err), zap.String("identity", f))
-					}
+			// Resolve identity files, keychain errors are reported by the task
+			identityFiles := []container.IdentityFile{}
+			for _, f := range params.identityFilePaths {
+				if f == "" {
+					// Ignore empty
+					continue
+				}
 
-					// Decode identity
-					id, err := identity.FromReader(r)
-					if err != nil {
-						log.For(ctx).Fatal("unable to decode identity from file", zap.Error(err), zap.String("identity", f))
+				reader, err := resolveIdentityReader(f)
+				if err != nil {
+					errResolve := err
+					reader = func(context.Context) (io.Reader, error) {
+						return nil, errResolve
 					}
-
-					// Append to identity list
-					params.identities = append(params.identities, id.Public)
 				}
+				identityFiles = append(identityFiles, container.IdentityFile{Ref: f, Reader: reader})
 			}
 
 			// Prepare task
@@ -86,16 +83,14 @@ var containerSealCmd = func() *cobra.Command {
 				OutputWriter:             cmdutil.StdoutWriter(),
 				JSONOutput:               params.jsonOutput,
 				Identities:               params.identities,
+				IdentityFiles:            identityFiles,
 				DisableContainerIdentity: params.noContainerIdentity,
+				DryRun:                   params.dryRun,
+				IgnoreKeyUsage:           params.ignoreKeyUsage,
 			}
 
 			// Check container sealing master key usage
 			if params.masterKey != "" {
-				// Process target
-				if params.target == "" {
-					log.For(ctx).Fatal("target flag (string) is mandatory for key derivation")
-				}
-
 				// Assign target parameter
 				t.DCKDTarget = params.target
 
@@ -114,8 +109,14 @@ var containerSealCmd = func() *cobra.Command {
 				t.DCKDMasterKey = memguard.NewBufferFromBytes(masterKeyRaw)
 			}
 
+			// Dry run plan is the task report
+			reportWriter := cmdutil.ReportWriter(params.reportPath)
+			if params.dryRun && params.jsonOutput {
+				reportWriter = cmdutil.StdoutWriter()
+			}
+
 			// Run the task
-			if err := t.Run(ctx); err != nil {
+			if err := cmdutil.RunReportedTask(ctx, "container-seal", t, reportWriter); err != nil {
 				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
 			}
 		},
@@ -124,13 +125,22 @@ var containerSealCmd = func() *cobra.Command {
 	// Parameters
 	cmd.Flags().StringVar(&params.inputPath, "in", "", "Unsealed container input ('-' for stdin or filename)")
 	cmd.Flags().StringVar(&params.outputPath, "out", "", "Sealed container output ('-' for stdout or filename)")
-	log.CheckErr("unable to mark 'out' flag as required.", cmd.MarkFlagRequired("out"))
 	cmd.Flags().BoolVar(&params.jsonOutput, "json", false, "Display seal info as json")
 	cmd.Flags().StringArrayVar(&params.identities, "identity", []string{}, "Identity allowed to unseal")
-	cmd.Flags().StringArrayVar(&params.identityFilePaths, "identity-file", []string{}, "Files with identity allowed to unseal")
+	cmd.Flags().StringArrayVar(&params.identityFilePaths, "identity-file", []string{}, "Files with identity allowed to unseal (or 'keychain:<name>')")
 	cmd.Flags().BoolVar(&params.noContainerIdentity, "no-container-identity", false, "Disable container identity")
 	cmd.Flags().StringVar(&params.masterKey, "dckd-master-key", "", "Master key used for deterministic container key derivation")
 	cmd.Flags().StringVar(&params.target, "dckd-target", "", "Target parameter for deterministic container key derivation")
+	cmd.Flags().BoolVar(&params.dryRun, "dry-run", false, "Check recipients and estimate the sealed container size without sealing")
+	cmd.Flags().BoolVar(&params.ignoreKeyUsage, "ignore-key-usage", false, "Don't enforce identity key usage policy")
+	cmd.Flags().StringVar(&params.reportPath, "report-file", "", "Task execution report output (JSON)")
+
+	cmdutil.ValidateFlags(cmd,
+		cmdutil.RequiredOneOf("out", "dry-run"),
+		cmdutil.When("dry-run", "true", cmdutil.Forbidden("out")),
+		cmdutil.When("dckd-master-key", "", cmdutil.Required("dckd-target")),
+		cmdutil.When("dckd-target", "", cmdutil.Required("dckd-master-key")),
+	)
 
 	return cmd
 }
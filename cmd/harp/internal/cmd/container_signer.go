@@ -0,0 +1,99 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	hcrypto "github.com/elastic/harp/pkg/sdk/security/crypto"
+	"github.com/elastic/harp/pkg/tasks/container"
+	"github.com/elastic/harp/pkg/vault"
+	"github.com/elastic/harp/pkg/vault/transit"
+)
+
+// signerSpec resolves the '--signer' value, '--key' is a shortcut for a local
+// key.
+func signerSpec(spec, keyPath string) (*container.SignerSpec, error) {
+	switch {
+	case spec != "" && keyPath != "":
+		return nil, errors.New("'--key' and '--signer' are mutually exclusive")
+	case keyPath != "":
+		return &container.SignerSpec{Type: container.SignerLocal, Path: keyPath}, nil
+	case spec == "":
+		return nil, errors.New("'--key' or '--signer' must be specified")
+	default:
+	}
+
+	return container.ParseSignerSpec(spec)
+}
+
+// transitService builds a transit service with the Vault client settings
+// from the environment.
+func transitService(s *container.SignerSpec) (transit.Service, error) {
+	v, err := vault.DefaultClient()
+	if err != nil {
+		return nil, err
+	}
+	svc, err := v.Transit(s.MountPath, s.KeyName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize transit service: %w", err)
+	}
+	return svc, nil
+}
+
+// keyParseOptions returns the local key parsing options.
+func keyParseOptions(ignoreKeyUsage bool) []hcrypto.ParseOption {
+	if ignoreKeyUsage {
+		return []hcrypto.ParseOption{hcrypto.IgnoreKeyUsage()}
+	}
+	return nil
+}
+
+func signerProvider(spec, keyPath string, ignoreKeyUsage bool) (container.SignerProvider, error) {
+	s, err := signerSpec(spec, keyPath)
+	if err != nil {
+		return nil, err
+	}
+	if s.Type == container.SignerLocal {
+		return container.LocalSigner(cmdutil.FileReader(s.Path), keyParseOptions(ignoreKeyUsage)...), nil
+	}
+
+	svc, err := transitService(s)
+	if err != nil {
+		return nil, err
+	}
+	return container.VaultTransitSigner(svc, s.KeyName), nil
+}
+
+func keyResolverProvider(spec, keyPath string, ignoreKeyUsage bool) (container.KeyResolverProvider, error) {
+	s, err := signerSpec(spec, keyPath)
+	if err != nil {
+		return nil, err
+	}
+	if s.Type == container.SignerLocal {
+		return container.LocalKeyResolver(cmdutil.FileReader(s.Path), keyParseOptions(ignoreKeyUsage)...), nil
+	}
+
+	svc, err := transitService(s)
+	if err != nil {
+		return nil, err
+	}
+	return container.VaultTransitKeyResolver(svc, s.KeyName), nil
+}
@@ -18,12 +18,15 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/awnumar/memguard"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 
 	"github.com/elastic/harp/pkg/sdk/cmdutil"
 	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/sdk/security/piv"
 	"github.com/elastic/harp/pkg/tasks/container"
 )
 
@@ -32,6 +35,12 @@ type containerUnsealParams struct {
 	inputPath       string
 	outputPath      string
 	containerKeyRaw string
+	usePIV          bool
+	pivSlot         string
+	pivPIN          string
+	identity        string
+	passPhrase      string
+	ignoreKeyUsage  bool
 }
 
 var containerUnsealCmd = func() *cobra.Command {
@@ -45,6 +54,60 @@ var containerUnsealCmd = func() *cobra.Command {
 			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-container-unseal", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
 			defer cancel()
 
+			// Token backed identity
+			if params.usePIV {
+				slot, err := piv.ParseSlot(params.pivSlot)
+				if err != nil {
+					log.For(ctx).Fatal("unable to parse slot flag", zap.Error(err))
+				}
+
+				// Prepare PIN prompt, never retry a PIN given as flag.
+				pinPrompt, attempts := piv.StaticPIN([]byte(params.pivPIN)), 1
+				if params.pivPIN == "" {
+					pinPrompt = func(retries int) (*memguard.LockedBuffer, error) {
+						return cmdutil.ReadSecret(fmt.Sprintf("Enter PIV PIN (%d attempt(s) remaining)", retries), false)
+					}
+					attempts = piv.DefaultPINRetries
+				}
+
+				// Prepare task
+				t := &container.UnsealTask{
+					ContainerReader: cmdutil.FileReader(params.inputPath),
+					OutputWriter:    cmdutil.StdoutWriter(),
+					PIVProvider:     piv.HardwareProvider(),
+					PIVSlot:         slot,
+					PIVPIN:          pinPrompt,
+					PIVPINAttempts:  attempts,
+				}
+
+				// Run the task
+				if err := cmdutil.RunTask(ctx, t); err != nil {
+					log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+				}
+				return
+			}
+
+			// Identity backed unsealing
+			if params.identity != "" {
+				passPhrase := passphraseBuffer(ctx, params.passPhrase, "Enter identity passphrase", false)
+				defer passPhrase.Destroy()
+
+				// Prepare task
+				t := &container.UnsealTask{
+					ContainerReader:    cmdutil.FileReader(params.inputPath),
+					OutputWriter:       cmdutil.StdoutWriter(),
+					IdentityReader:     identityReader(ctx, params.identity),
+					IdentityPassPhrase: passPhrase,
+					IgnoreKeyUsage:     params.ignoreKeyUsage,
+				}
+
+				// Run the task
+				if err := cmdutil.RunTask(ctx, t); err != nil {
+					log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+				}
+				return
+			}
+
 			// Prepare passphrase
 			containerKey := memguard.NewBufferFromBytes([]byte(params.containerKeyRaw))
 			if params.containerKeyRaw == "" {
@@ -65,7 +128,7 @@ var containerUnsealCmd = func() *cobra.Command {
 			}
 
 			// Run the task
-			if err := t.Run(ctx); err != nil {
+			if err := cmdutil.RunTask(ctx, t); err != nil {
 				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
 			}
 		},
@@ -75,7 +138,18 @@ var containerUnsealCmd = func() *cobra.Command {
 	cmd.Flags().StringVar(&params.inputPath, "in", "", "Sealed container input ('-' for stdin or filename)")
 	cmd.Flags().StringVar(&params.outputPath, "out", "", "Unsealed container output ('-' for stdout or filename)")
 	cmd.Flags().StringVar(&params.containerKeyRaw, "key", "", "Container key")
-	log.CheckErr("unable to mark 'key' flag as required.", cmd.MarkFlagRequired("key"))
+	cmd.Flags().BoolVar(&params.usePIV, "piv", false, "Unseal using the identity key stored on a PIV token (YubiKey)")
+	cmd.Flags().StringVar(&params.pivSlot, "slot", "9a", "PIV token slot holding the identity key")
+	cmd.Flags().StringVar(&params.pivPIN, "pin", "", "PIV token PIN (prompted when not defined)")
+	cmd.Flags().StringVar(&params.identity, "identity", "", "Unseal using the identity private key (filename or 'keychain:<name>')")
+	cmd.Flags().StringVar(&params.passPhrase, "passphrase", "", "Identity private key passphrase (prompted when not defined)")
+	cmd.Flags().BoolVar(&params.ignoreKeyUsage, "ignore-key-usage", false, "Don't enforce identity key usage policy")
+
+	cmdutil.ValidateFlags(cmd,
+		cmdutil.MutuallyExclusive("piv", "key", "identity"),
+		cmdutil.When("pin", "", cmdutil.Required("piv")),
+		cmdutil.When("passphrase", "", cmdutil.Required("identity")),
+	)
 
 	return cmd
 }
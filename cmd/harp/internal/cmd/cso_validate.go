@@ -19,6 +19,7 @@ package cmd
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 
@@ -28,6 +29,7 @@ import (
 	csov1 "github.com/elastic/harp/pkg/cso/v1"
 	"github.com/elastic/harp/pkg/sdk/cmdutil"
 	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks"
 )
 
 var (
@@ -36,16 +38,21 @@ var (
 	csoValidateDropCompliant    bool
 	csoValidateDropNonCompliant bool
 	csoValidatePathOnly         bool
+	csoValidateVersionRange     bool
+	csoValidateArtifactTypes    []string
+	csoValidateExplicitRings    []string
+	csoValidateFailureMode      func() tasks.FailureMode
 )
 
 // -----------------------------------------------------------------------------
 
 var csoValidateCmd = func() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     "validate",
-		Aliases: []string{"v"},
-		Short:   "Validate given paths with CSO Specification",
-		Run:     runCSOValidate,
+		Use:          "validate",
+		Aliases:      []string{"v"},
+		Short:        "Validate given paths with CSO Specification",
+		SilenceUsage: true,
+		RunE:         runCSOValidate,
 	}
 
 	// Parameters
@@ -54,6 +61,10 @@ var csoValidateCmd = func() *cobra.Command {
 	cmd.Flags().BoolVar(&csoValidateDropCompliant, "drop-compliant", false, "Drop compliant path(s) from result")
 	cmd.Flags().BoolVar(&csoValidateDropNonCompliant, "drop-non-compliant", false, "Drop non compliant path(s) from result")
 	cmd.Flags().BoolVar(&csoValidatePathOnly, "path-only", false, "Display path only as result")
+	cmd.Flags().BoolVar(&csoValidateVersionRange, "allow-version-range", false, "Accept version ranges (~1.2, 1.x) as product version")
+	cmd.Flags().StringSliceVar(&csoValidateArtifactTypes, "artifact-type", csov1.DefaultArtifactTypes, "Accepted artifact types")
+	cmd.Flags().StringSliceVar(&csoValidateExplicitRings, "explicit-partition-ring", []string{}, "Rings requiring partition explicit cloud providers (aws-cn, azure-china, ...) for regions outside of the default partition")
+	csoValidateFailureMode = cmdutil.FailureModeFlags(cmd)
 
 	return cmd
 }
@@ -63,7 +74,7 @@ type csoValidationResponse struct {
 	Error     string `json:"error,omitempty"`
 }
 
-func runCSOValidate(cmd *cobra.Command, args []string) {
+func runCSOValidate(cmd *cobra.Command, args []string) error {
 	ctx, cancel := cmdutil.Context(cmd.Context(), "harp-cso-validate", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
 	defer cancel()
 
@@ -72,7 +83,7 @@ func runCSOValidate(cmd *cobra.Command, args []string) {
 		// Force read from stdin
 		paths, errReader := cmdutil.LineReader(csoValidatePathFrom)
 		if errReader != nil {
-			log.For(ctx).Fatal("unable to read paths from stdin", zap.Error(errReader))
+			return tasks.Usage(fmt.Errorf("unable to read paths: %w", errReader))
 		}
 
 		// Add to paths
@@ -81,14 +92,41 @@ func runCSOValidate(cmd *cobra.Command, args []string) {
 
 	// Check path length
 	if len(csoValidatePaths) == 0 {
-		log.For(ctx).Fatal("unable to validate empty paths")
+		return tasks.Usage(errors.New("unable to validate empty paths"))
 	}
 
-	res := map[string]csoValidationResponse{}
+	// Prepare validation policy
+	opts := []csov1.ValidationOption{}
+	if csoValidateVersionRange {
+		opts = append(opts, csov1.AllowVersionRange())
+	}
+	opts = append(opts, csov1.ArtifactTypes(csoValidateArtifactTypes...))
+	if len(csoValidateExplicitRings) > 0 {
+		opts = append(opts, csov1.RequireExplicitPartition(csoValidateExplicitRings...))
+	}
+
+	var (
+		res      = map[string]csoValidationResponse{}
+		result   = &tasks.Result{}
+		failFast = csoValidateFailureMode() == tasks.FailFast
+		invalid  = 0
+	)
 
 	// Validate each path
 	for _, p := range csoValidatePaths {
-		err := csov1.Validate(p)
+		// Stop at the first non compliant path
+		if failFast && invalid > 0 {
+			result.Skip(p)
+			continue
+		}
+
+		err := csov1.Validate(p, opts...)
+		if err != nil {
+			invalid++
+			result.FailItem(p, err)
+		} else {
+			result.Succeed(p)
+		}
 
 		// Error format
 		var errMessage string
@@ -114,11 +152,20 @@ func runCSOValidate(cmd *cobra.Command, args []string) {
 	if !csoValidatePathOnly {
 		// Dump as json
 		if err := json.NewEncoder(os.Stdout).Encode(res); err != nil {
-			log.For(ctx).Fatal("unable to encode validation response", zap.Error(err))
+			return fmt.Errorf("unable to encode validation response: %w", err)
 		}
 	} else {
 		for k := range res {
 			fmt.Fprintf(os.Stdout, "%s\n", k)
 		}
 	}
+
+	// Non compliant paths are item failures
+	if invalid > 0 {
+		log.For(ctx).Debug("Non compliant paths found", zap.Int("count", invalid))
+		return result.ItemsError(errors.New("non compliant path(s) found"))
+	}
+
+	// No error
+	return nil
 }
@@ -0,0 +1,57 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/decryptor"
+)
+
+// valueDecryptionFlags holds externally encrypted value decryption settings
+// shared by exporters.
+type valueDecryptionFlags struct {
+	key     string
+	failure string
+}
+
+func (f *valueDecryptionFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.key, "value-decryption-key", "", "AES-GCM key used to export externally encrypted values ('enc:aes-gcm:...') as cleartext")
+	cmd.Flags().StringVar(&f.failure, "value-decryption-failure", "error", "Value decryption failure policy (error, hide)")
+}
+
+// build returns the value decryptor, nil when no key is given.
+func (f *valueDecryptionFlags) build() (bundle.ValueDecryptor, bundle.DecryptFailurePolicy, error) {
+	policy, err := bundle.ParseDecryptFailurePolicy(f.failure)
+	if err != nil {
+		return nil, policy, err
+	}
+	if f.key == "" {
+		return nil, policy, nil
+	}
+
+	d, err := decryptor.AESGCM(f.key)
+	if err != nil {
+		return nil, policy, fmt.Errorf("unable to initialize value decryptor: %w", err)
+	}
+
+	return d, policy, nil
+}
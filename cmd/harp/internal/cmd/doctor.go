@@ -0,0 +1,91 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/doctor"
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+)
+
+// -----------------------------------------------------------------------------
+
+type doctorParams struct {
+	output       string
+	urls         []string
+	identities   []string
+	timeURL      string
+	maxClockSkew time.Duration
+}
+
+func doctorCmd() *cobra.Command {
+	params := doctorParams{}
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose environment and configuration problems",
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-doctor", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Prepare environment
+			env := doctor.DefaultEnvironment()
+			env.URLs = params.urls
+			env.Identities = params.identities
+			env.TimeURL = params.timeURL
+			env.MaxClockSkew = params.maxClockSkew
+
+			// Run all checks
+			report := doctor.Run(ctx, env, doctor.Checks())
+
+			// Display report
+			var err error
+			switch params.output {
+			case "json":
+				err = report.WriteJSON(os.Stdout)
+			case "text":
+				err = report.WriteText(os.Stdout)
+			default:
+				log.For(ctx).Fatal("unsupported output format", zap.String("output", params.output))
+			}
+			if err != nil {
+				log.For(ctx).Fatal("unable to display report", zap.Error(err))
+			}
+
+			// Exit with error on failure
+			if report.HasFailures() {
+				os.Exit(1)
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&params.output, "output", "text", "Output format (text, json)")
+	cmd.Flags().StringSliceVar(&params.urls, "url", nil, "Backend URL to check")
+	cmd.Flags().StringSliceVar(&params.identities, "identity", nil, "Identity file to check")
+	cmd.Flags().StringVar(&params.timeURL, "time-url", doctor.DefaultTimeURL, "HTTPS URL used as clock reference")
+	cmd.Flags().DurationVar(&params.maxClockSkew, "max-clock-skew", doctor.DefaultMaxClockSkew, "Tolerated clock difference")
+
+	return cmd
+}
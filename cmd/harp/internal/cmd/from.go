@@ -31,6 +31,8 @@ var fromCmd = func() *cobra.Command {
 
 	// Add subcommands
 	cmd.AddCommand(fromVaultCmd())
+	cmd.AddCommand(fromGCPSecretManagerCmd())
+	cmd.AddCommand(fromACMECmd())
 	cmd.AddCommand(fromJSONCmd())
 	cmd.AddCommand(fromTemplateCmd())
 	cmd.AddCommand(fromDumpCmd())
@@ -0,0 +1,166 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"github.com/awnumar/memguard"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/bundle/acme"
+	"github.com/elastic/harp/pkg/cloud/aws/session"
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks/from"
+)
+
+// -----------------------------------------------------------------------------
+
+type fromACMEParams struct {
+	outputPath       string
+	packagePath      string
+	domains          []string
+	accountPath      string
+	passPhrase       string
+	email            string
+	stagingEndpoint  bool
+	directoryURL     string
+	dnsProvider      string
+	route53ZoneID    string
+	http01           bool
+	httpAddress      string
+	keyType          string
+	maxRateLimitWait time.Duration
+	budgetPath       string
+}
+
+var fromACMECmd = func() *cobra.Command {
+	params := &fromACMEParams{}
+
+	cmd := &cobra.Command{
+		Use:   "acme",
+		Short: "Issue a certificate from an ACME server as a secret container",
+		Long: `Issue a certificate from an ACME server (Let's Encrypt) as a secret container.
+
+The certificate private key, leaf certificate and issuer chain are stored as
+'key', 'cert' and 'chain' secrets of the given CSO compliant package. The
+order URL and the certificate expiration date are recorded as package
+annotations to drive renewals.
+
+Domain control is proven using dns-01 challenges (manual or route53) by
+default, or http-01 challenges served by harp with --http-01.
+
+The account key is stored as a passphrase sealed identity, it is created when
+the account file doesn't exist.
+
+The Let's Encrypt staging endpoint is used unless --staging-endpoint=false is
+given, to prevent reaching production rate limits while testing.`,
+		Example: `  # Issue a staging certificate with route53 dns-01 challenges
+  harp from acme --account account.json --passphrase ... \
+      --domain app.example.com --dns-provider route53 \
+      --path infra/aws/security/eu-central-1/elb/app/tls --out app.bundle
+
+  # Issue a production certificate using http-01 challenges
+  harp from acme --staging-endpoint=false --http-01 --account account.json --passphrase ... \
+      --domain app.example.com --path infra/aws/security/eu-central-1/elb/app/tls --out app.bundle`,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-from-acme", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Resolve directory
+			directoryURL := params.directoryURL
+			if directoryURL == "" {
+				directoryURL = acme.LetsEncryptStagingURL
+				if !params.stagingEndpoint {
+					directoryURL = acme.LetsEncryptURL
+				}
+			}
+
+			// Prepare task
+			t := &from.ACMETask{
+				OutputWriter:     cmdutil.FileWriter(params.outputPath),
+				PassPhrase:       memguard.NewBufferFromBytes([]byte(params.passPhrase)),
+				DirectoryURL:     directoryURL,
+				Email:            params.email,
+				Domains:          params.domains,
+				PackagePath:      params.packagePath,
+				KeyType:          params.keyType,
+				MaxRateLimitWait: params.maxRateLimitWait,
+			}
+			if _, err := os.Stat(params.accountPath); err == nil {
+				t.AccountReader = cmdutil.FileReader(params.accountPath)
+			} else {
+				t.AccountWriter = cmdutil.FileWriter(params.accountPath)
+			}
+			if params.budgetPath != "" {
+				t.BudgetReader = cmdutil.FileReader(params.budgetPath)
+			}
+
+			// Prepare solver
+			switch {
+			case params.http01:
+				t.Solver = &acme.HTTPSolver{Address: params.httpAddress}
+			case params.dnsProvider == "route53":
+				sess, err := session.NewSession(&session.Options{})
+				if err != nil {
+					log.For(ctx).Fatal("unable to initialize AWS session", zap.Error(err))
+				}
+				t.Solver = &acme.Route53Solver{
+					Client:       route53.New(sess),
+					HostedZoneID: params.route53ZoneID,
+				}
+			case params.dnsProvider == "manual":
+				t.Solver = &acme.ManualSolver{Out: os.Stderr, In: os.Stdin}
+			default:
+				log.For(ctx).Fatal("unsupported dns provider", zap.String("provider", params.dnsProvider))
+			}
+
+			// Run the task
+			if err := cmdutil.RunTask(ctx, t); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&params.outputPath, "out", "", "Container output ('-' for stdout or filename)")
+	cmd.Flags().StringVar(&params.packagePath, "path", "", "CSO compliant package path")
+	log.CheckErr("unable to mark 'path' flag as required.", cmd.MarkFlagRequired("path"))
+	cmd.Flags().StringArrayVar(&params.domains, "domain", []string{}, "Certificate domain (first one is the subject common name)")
+	log.CheckErr("unable to mark 'domain' flag as required.", cmd.MarkFlagRequired("domain"))
+	cmd.Flags().StringVar(&params.accountPath, "account", "", "ACME account key identity path (created when missing)")
+	log.CheckErr("unable to mark 'account' flag as required.", cmd.MarkFlagRequired("account"))
+	cmd.Flags().StringVar(&params.passPhrase, "passphrase", "", "Account key passphrase")
+	cmd.Flags().StringVar(&params.email, "email", "", "Account contact email")
+	cmd.Flags().BoolVar(&params.stagingEndpoint, "staging-endpoint", true, "Use Let's Encrypt staging endpoint")
+	cmd.Flags().StringVar(&params.directoryURL, "directory-url", "", "Custom ACME directory URL (overrides staging-endpoint)")
+	cmd.Flags().StringVar(&params.dnsProvider, "dns-provider", "manual", "dns-01 challenge provider (manual, route53)")
+	cmd.Flags().StringVar(&params.route53ZoneID, "route53-zone-id", "", "Route53 hosted zone identifier (resolved from domain when blank)")
+	cmd.Flags().BoolVar(&params.http01, "http-01", false, "Use http-01 challenges instead of dns-01")
+	cmd.Flags().StringVar(&params.httpAddress, "http-address", ":80", "http-01 challenge server listen address")
+	cmd.Flags().StringVar(&params.keyType, "key-type", acme.KeyTypeEC256, "Certificate key type (ec256, ec384, rsa2048, rsa4096)")
+	cmd.Flags().DurationVar(&params.maxRateLimitWait, "max-rate-limit-wait", time.Minute, "Maximum delay to wait when rate limited before failing")
+	cmd.Flags().StringVar(&params.budgetPath, "enforce-budget", "", "Size budget policy path (fails when a budget is exceeded)")
+
+	return cmd
+}
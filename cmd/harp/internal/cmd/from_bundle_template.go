@@ -36,10 +36,18 @@ var fromTemplateCmd = func() *cobra.Command {
 		inputPath    string
 		outputPath   string
 		rootPath     string
+		budgetPath   string
+		manifestPath string
+		secretsFrom  []string
 		valueFiles   []string
 		values       []string
 		stringValues []string
 		fileValues   []string
+		dryRun       bool
+		jsonOutput   bool
+		lenient      bool
+		limits       = engine.DefaultLimits()
+		network      engine.Network
 	)
 
 	cmd := &cobra.Command{
@@ -73,19 +81,35 @@ var fromTemplateCmd = func() *cobra.Command {
 				}
 			}
 
+			// Process secret readers
+			opts, remoteSecrets := templateSecretSources(ctx, secretsFrom)
+
 			// Prepare task
+			allowTemplateNetwork(network)
 			t := &from.BundleTemplateTask{
 				TemplateReader: cmdutil.FileReader(inputPath),
 				OutputWriter:   cmdutil.FileWriter(outputPath),
-				TemplateContext: engine.NewContext(
+				TemplateContext: engine.NewContext(append(opts,
 					engine.WithName(inputPath),
 					engine.WithValues(values),
 					engine.WithFiles(files),
-				),
+					engine.WithLimits(limits),
+					engine.WithStrictMode(!lenient),
+					engine.WithNetwork(network),
+				)...),
+				RemoteSecrets: remoteSecrets,
+				DryRun:        dryRun,
+				JSONOutput:    jsonOutput,
+			}
+			if budgetPath != "" {
+				t.BudgetReader = cmdutil.FileReader(budgetPath)
+			}
+			if manifestPath != "" {
+				t.ConsumptionWriter = cmdutil.FileWriter(manifestPath)
 			}
 
 			// Run the task
-			if err := t.Run(ctx); err != nil {
+			if err := cmdutil.RunTask(ctx, t); err != nil {
 				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
 			}
 		},
@@ -95,10 +119,19 @@ var fromTemplateCmd = func() *cobra.Command {
 	cmd.Flags().StringVar(&inputPath, "in", "-", "Template input path ('-' for stdin or filename)")
 	cmd.Flags().StringVar(&outputPath, "out", "", "Container output ('-' for stdout or a filename)")
 	cmd.Flags().StringVar(&rootPath, "root", "", "Defines file loader root base path")
+	cmd.Flags().StringArrayVarP(&secretsFrom, "secrets-from", "s", []string{}, "Specifies secret containers to load ('vault' for Vault loader or '-' for stdin or filename)")
 	cmd.Flags().StringArrayVarP(&valueFiles, "values", "f", []string{}, "Specifies value files to load")
 	cmd.Flags().StringArrayVar(&values, "set", []string{}, "Specifies value (k=v)")
 	cmd.Flags().StringArrayVar(&stringValues, "set-string", []string{}, "Specifies value (k=string)")
 	cmd.Flags().StringArrayVar(&fileValues, "set-file", []string{}, "Specifies value (k=filepath)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Report packages and value sources without generating secrets")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Display dry-run report as JSON")
+	cmd.Flags().BoolVar(&lenient, "lenient", false, "Render missing values as '<no value>' instead of failing")
+	templateLimitFlags(cmd, &limits)
+	templateNetworkFlags(cmd, &network)
+	cmd.Flags().IntVar(&limits.MaxPackages, "max-packages", limits.MaxPackages, "Maximum count of generated packages (0 to disable)")
+	cmd.Flags().StringVar(&budgetPath, "enforce-budget", "", "Size budget policy path (fails when a budget is exceeded)")
+	cmd.Flags().StringVar(&manifestPath, "consumption-manifest", "", "Write the manifest of consumed secret values to the given path")
 
 	return cmd
 }
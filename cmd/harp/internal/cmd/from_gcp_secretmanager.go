@@ -0,0 +1,104 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/bundle/secretmanager"
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks/from"
+)
+
+// -----------------------------------------------------------------------------
+
+var fromGCPSecretManagerCmd = func() *cobra.Command {
+	var (
+		outputPath   string
+		project      string
+		filter       string
+		pathTemplate string
+		allVersions  bool
+		mappingPath  string
+		budgetPath   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "gcp-secretmanager",
+		Short: "Pull secrets from GCP Secret Manager as a secret container",
+		Long: `Pull secrets from GCP Secret Manager as a secret container.
+
+Secrets are mapped to packages using a path template evaluated with the secret
+.ID, .Project and .Labels. The default template follows the CSO convention :
+
+  ` + secretmanager.DefaultPathTemplate + `
+
+Secrets missing a referenced label, or not accessible, are skipped with a
+warning. JSON object payloads are imported as secret maps, other payloads are
+imported as a 'value' key.
+
+Filter expression is evaluated locally, terms are combined with AND :
+
+  labels.<key>=<value>  label value equality
+  labels.<key>:*        label presence
+  name:<value>          secret identifier contains value`,
+		Example: `  harp from gcp-secretmanager --project my-proj --filter 'labels.team=payments' --out payments.bundle`,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-from-gcp-secretmanager", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Prepare task
+			t := &from.GCPSecretManagerTask{
+				OutputWriter: cmdutil.FileWriter(outputPath),
+				Project:      project,
+				Filter:       filter,
+				PathTemplate: pathTemplate,
+				AllVersions:  allVersions,
+			}
+			if mappingPath != "" {
+				if pathTemplate != "" {
+					log.For(ctx).Fatal("'mapping' and 'path-template' flags are exclusive")
+				}
+				t.MappingReader = cmdutil.FileReader(mappingPath)
+			}
+			if budgetPath != "" {
+				t.BudgetReader = cmdutil.FileReader(budgetPath)
+			}
+
+			// Run the task
+			if err := cmdutil.RunTask(ctx, t); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&outputPath, "out", "", "Container output ('-' for stdout or filename)")
+	cmd.Flags().StringVar(&project, "project", "", "GCP project identifier")
+	log.CheckErr("unable to mark 'project' flag as required.", cmd.MarkFlagRequired("project"))
+	cmd.Flags().StringVar(&filter, "filter", "", "Secret filter expression (labels.<key>=<value>, labels.<key>:*, name:<value>)")
+	cmd.Flags().StringVar(&pathTemplate, "path-template", "", "Package path template (defaults to CSO convention)")
+	cmd.Flags().BoolVar(&allVersions, "all-versions", false, "Import all enabled versions instead of the latest one")
+	cmd.Flags().StringVar(&mappingPath, "mapping", "", "Path mapping profile path (secret identifier and labels as metadata)")
+	cmd.Flags().StringVar(&budgetPath, "enforce-budget", "", "Size budget policy path (fails when a budget is exceeded)")
+
+	return cmd
+}
@@ -30,8 +30,10 @@ import (
 
 var fromJSONCmd = func() *cobra.Command {
 	var (
-		inputPath  string
-		outputPath string
+		inputPath   string
+		outputPath  string
+		mappingPath string
+		budgetPath  string
 	)
 	cmd := &cobra.Command{
 		Use:   "jsonmap",
@@ -46,9 +48,15 @@ var fromJSONCmd = func() *cobra.Command {
 				JSONReader:   cmdutil.FileReader(inputPath),
 				OutputWriter: cmdutil.FileWriter(outputPath),
 			}
+			if mappingPath != "" {
+				t.MappingReader = cmdutil.FileReader(mappingPath)
+			}
+			if budgetPath != "" {
+				t.BudgetReader = cmdutil.FileReader(budgetPath)
+			}
 
 			// Run the task
-			if err := t.Run(ctx); err != nil {
+			if err := cmdutil.RunTask(ctx, t); err != nil {
 				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
 			}
 		},
@@ -57,6 +65,8 @@ var fromJSONCmd = func() *cobra.Command {
 	// Parameters
 	cmd.Flags().StringVar(&inputPath, "in", "-", "JSON Map object ('-' for stdin or filename)")
 	cmd.Flags().StringVar(&outputPath, "out", "-", "Container output ('-' for stdout or filename)")
+	cmd.Flags().StringVar(&mappingPath, "mapping", "", "Path mapping profile path")
+	cmd.Flags().StringVar(&budgetPath, "enforce-budget", "", "Size budget policy path (fails when a budget is exceeded)")
 
 	return cmd
 }
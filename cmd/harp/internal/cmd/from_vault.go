@@ -33,8 +33,12 @@ var fromVaultCmd = func() *cobra.Command {
 		pathsFrom    string
 		secretPaths  []string
 		outputPath   string
+		incremental  string
 		namespace    string
 		withMetadata bool
+		mappingPath  string
+		budgetPath   string
+		reportPath   string
 	)
 
 	cmd := &cobra.Command{
@@ -64,9 +68,18 @@ var fromVaultCmd = func() *cobra.Command {
 				VaultNamespace: namespace,
 				WithMetadata:   withMetadata,
 			}
+			if mappingPath != "" {
+				t.MappingReader = cmdutil.FileReader(mappingPath)
+			}
+			if budgetPath != "" {
+				t.BudgetReader = cmdutil.FileReader(budgetPath)
+			}
+			if incremental != "" {
+				t.IncrementalWriter = cmdutil.FileWriter(incremental)
+			}
 
 			// Run the task
-			if err := t.Run(ctx); err != nil {
+			if err := cmdutil.RunReportedTask(ctx, "from-vault", t, cmdutil.ReportWriter(reportPath)); err != nil {
 				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
 			}
 		},
@@ -76,8 +89,12 @@ var fromVaultCmd = func() *cobra.Command {
 	cmd.Flags().StringVar(&pathsFrom, "paths-from", "", "Path to read path from ('-' for stdin or filename)")
 	cmd.Flags().StringArrayVar(&secretPaths, "path", []string{}, "Vault backend path (and recursive)")
 	cmd.Flags().StringVar(&outputPath, "out", "", "Container output ('-' for stdout or filename)")
+	cmd.Flags().StringVar(&incremental, "incremental-out", "", "Stream pulled packages to an incremental bundle file, recoverable with 'bundle recover' after a failure")
 	cmd.Flags().StringVar(&namespace, "namespace", "", "Vault namespace")
 	cmd.Flags().BoolVar(&withMetadata, "with-metadata", true, "Pull bundle metadata from Vault")
+	cmd.Flags().StringVar(&mappingPath, "mapping", "", "Path mapping profile path")
+	cmd.Flags().StringVar(&budgetPath, "enforce-budget", "", "Size budget policy path (fails when a budget is exceeded)")
+	cmd.Flags().StringVar(&reportPath, "report-file", "", "Task execution report output (JSON)")
 
 	return cmd
 }
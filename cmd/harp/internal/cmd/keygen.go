@@ -34,6 +34,8 @@ var keygenCmd = func() *cobra.Command {
 	cmd.AddCommand(keygenFernetCmd())
 	cmd.AddCommand(keygenSecretBoxCmd())
 	cmd.AddCommand(keygenAES256Cmd())
+	cmd.AddCommand(keygenXChaChaCmd())
+	cmd.AddCommand(keygenAESSIVCmd())
 	cmd.AddCommand(keygenMasterKeyCmd())
 
 	return cmd
@@ -0,0 +1,137 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks/mapping"
+)
+
+// -----------------------------------------------------------------------------
+
+var mappingTestCmd = func() *cobra.Command {
+	var (
+		profilePath string
+		outputPath  string
+		sourceName  string
+		metadata    []string
+		reverse     bool
+		jsonOutput  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "test",
+		Short: "Evaluate a mapping profile for a source name",
+		Long: `Evaluate a mapping profile for a source name and display the resulting path,
+the matching rule, its captures and the evaluation trace.
+
+Profile example :
+
+  apiVersion: harp.elastic.co/v1
+  kind: MappingProfile
+  spec:
+    strict: false
+    default: "legacy/{{ .Name }}"
+    rules:
+      - name: database
+        match:
+          regex: "^db-(?P<env>[a-z]+)-(?P<name>.+)$"
+          metadata:
+            team: payments
+        target: "app/{{ .Captures.env }}/payments/database/{{ .Captures.name }}"
+      - name: services
+        match:
+          glob: "services/*/**"
+        target: "app/{{ .Metadata.env }}/{{ index .Captures \"1\" }}/{{ index .Captures \"2\" }}"
+
+Rules are evaluated in order, the first matching rule wins. Without matching
+rule, the default target is used, or the source name is kept unless strict
+mode is enabled.
+
+Bidirectional profiles declare pairs instead of rules, '{name}' captures match
+a single path segment and '{name*}' one or more segments :
+
+  apiVersion: harp.elastic.co/v1
+  kind: MappingProfile
+  spec:
+    strict: true
+    pairs:
+      - name: teams
+        source: "secret/teams/{team}/{app}/{env}/{name}"
+        target: "app/{env}/{team}/{app}/v1.0.0/{name}"
+
+Pairs are checked at load time, mapping a generated sample path forward then
+back must give the original path. Use --reverse to map a secret path back to
+its source name.`,
+		Example: `  harp mapping test --profile profile.yaml --source-name db-production-orders --metadata team=payments
+
+  # Map a secret path back to its source name
+  harp mapping test --profile pairs.yaml --reverse --source-name app/production/payments/billing/v1.0.0/database`,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-mapping-test", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Parse metadata
+			md := map[string]string{}
+			for _, kv := range metadata {
+				parts := strings.SplitN(kv, "=", 2)
+				if len(parts) != 2 || parts[0] == "" {
+					log.For(ctx).Fatal("invalid metadata, expected k=v", zap.String("value", kv))
+				}
+				md[parts[0]] = parts[1]
+			}
+
+			// Prepare task
+			t := &mapping.TestTask{
+				ProfileReader: cmdutil.FileReader(profilePath),
+				OutputWriter:  cmdutil.StdoutWriter(),
+				SourceName:    sourceName,
+				Metadata:      md,
+				Reverse:       reverse,
+				JSONOutput:    jsonOutput,
+			}
+			if outputPath != "" {
+				t.OutputWriter = cmdutil.FileWriter(outputPath)
+			}
+
+			// Run the task
+			if err := cmdutil.RunTask(ctx, t); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&profilePath, "profile", "", "Mapping profile path")
+	log.CheckErr("unable to mark 'profile' flag as required.", cmd.MarkFlagRequired("profile"))
+	cmd.Flags().StringVar(&outputPath, "out", "", "Output path ('-' for stdout or filename)")
+	cmd.Flags().StringVar(&sourceName, "source-name", "", "Source name to map")
+	log.CheckErr("unable to mark 'source-name' flag as required.", cmd.MarkFlagRequired("source-name"))
+	cmd.Flags().StringArrayVar(&metadata, "metadata", []string{}, "Source metadata (k=v)")
+	cmd.Flags().BoolVar(&reverse, "reverse", false, "Map a secret path back to its source name (bidirectional profiles)")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Display result as JSON")
+
+	return cmd
+}
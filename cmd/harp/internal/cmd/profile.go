@@ -0,0 +1,63 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/sdk/sandbox"
+)
+
+// -----------------------------------------------------------------------------
+
+var profiler *cmdutil.Profiler
+
+// startProfile starts the command profiling when an output directory is
+// given.
+func startProfile(cmd *cobra.Command) {
+	if profileDir == "" {
+		return
+	}
+
+	p, err := cmdutil.StartProfile(profileDir, cmd.CommandPath())
+	if err != nil {
+		log.Bg().Fatal("unable to start profiling", zap.Error(err))
+	}
+
+	// Keep writing profiles under sandbox
+	sandbox.AllowWriteDir(profileDir)
+
+	profiler = p
+	log.OnFatal(func() {
+		_ = profiler.Stop()
+	})
+}
+
+// stopProfile writes the command profiles.
+func stopProfile() {
+	if err := profiler.Stop(); err != nil {
+		log.Bg().Error("unable to write profiles", zap.Error(err))
+		return
+	}
+	if profiler != nil {
+		log.Bg().Debug("Profiles written", zap.Strings("paths", profiler.Paths()))
+	}
+}
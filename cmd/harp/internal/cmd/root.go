@@ -29,14 +29,29 @@ import (
 	"github.com/elastic/harp/pkg/sdk/cmdutil"
 	"github.com/elastic/harp/pkg/sdk/config"
 	configcmd "github.com/elastic/harp/pkg/sdk/config/cmd"
+	"github.com/elastic/harp/pkg/sdk/httpclient"
 	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/sdk/sandbox"
+	"github.com/elastic/harp/pkg/tasks"
 )
 
 // -----------------------------------------------------------------------------
 
 var (
-	cfgFile string
-	conf    = &iconfig.Configuration{}
+	cfgFile         string
+	maxRate         int64
+	fileMode        string
+	fileUID         int
+	fileGID         int
+	noOverwrite     bool
+	sandboxMode     bool
+	profileDir      string
+	configProfile   string
+	interactiveMode bool
+	conf            = &iconfig.Configuration{}
+
+	// commandStarted is set once flags and arguments are parsed.
+	commandStarted bool
 )
 
 // -----------------------------------------------------------------------------
@@ -46,21 +61,75 @@ var mainCmd = func() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "harp",
 		Short: "Extensible secret management tool",
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			commandStarted = true
+
+			// Apply user profile and environment flag values
+			if _, _, _, warnings, err := applyUserProfile(cmd); err != nil {
+				log.Bg().Fatal("unable to apply user profile", zap.Error(err))
+			} else {
+				for _, warning := range warnings {
+					log.Bg().Warn("user config: " + warning)
+				}
+			}
+
+			// Apply remote content download settings
+			cmdutil.SetDownloadOptions(httpclient.WithMaxRate(maxRate))
+
+			// Apply output file settings
+			mode, err := cmdutil.ParseFileMode(fileMode)
+			if err != nil {
+				log.Bg().Fatal("unable to parse output file mode", zap.Error(err))
+			}
+			cmdutil.SetWriterOptions(
+				cmdutil.WithFileMode(mode),
+				cmdutil.WithOwner(fileUID, fileGID),
+				cmdutil.WithNoOverwrite(noOverwrite),
+			)
+
+			// Prompt missing required flags if requested
+			cmdutil.SetInteractive(interactiveMode)
+
+			// Restrict local commands if requested
+			if sandboxMode {
+				sandbox.Enable()
+			}
+
+			// Profile command execution if requested
+			startProfile(cmd)
+
+			// Record command usage if enabled
+			startTelemetry(cmd)
+		},
 	}
 
 	// Register falgs
 	cmd.Flags().StringVar(&cfgFile, "config", "", "config file")
+	cmd.PersistentFlags().Int64Var(&maxRate, "max-rate", 0, "Bandwidth limit in bytes per second for remote input download (0 for unlimited)")
+	cmd.PersistentFlags().StringVar(&fileMode, "mode", "0600", "Permissions of created output files (octal)")
+	cmd.PersistentFlags().IntVar(&fileUID, "uid", -1, "Owner user id of created output files (root only, -1 to keep)")
+	cmd.PersistentFlags().IntVar(&fileGID, "gid", -1, "Owner group id of created output files (root only, -1 to keep)")
+	cmd.PersistentFlags().BoolVar(&noOverwrite, "no-overwrite", false, "Fail instead of overwriting existing output files")
+	cmd.PersistentFlags().BoolVar(&sandboxMode, "sandbox", false, "Restrict filesystem, process and network access to declared needs (Linux only, or set HARP_SANDBOX=1)")
+	cmd.PersistentFlags().BoolVar(&interactiveMode, "interactive", false, "Prompt for missing required flag values when stdin is a terminal")
+	cmd.PersistentFlags().StringVar(&profileDir, "profile", "", "Write CPU and heap profiles (pprof) of the command execution to the given directory")
+	cmd.PersistentFlags().StringVar(&configProfile, "config-profile", "", "User config profile providing default flag values (or set HARP_PROFILE)")
 
 	// Register sub commands
 	cmd.AddCommand(version.Command())
-	cmd.AddCommand(configcmd.NewConfigCommand(conf, "HARP"))
+	configCmd := configcmd.NewConfigCommand(conf, "HARP")
+	configCmd.AddCommand(configEffectiveCmd())
+	cmd.AddCommand(configCmd)
 
 	cmd.AddCommand(bundleCmd())
 	cmd.AddCommand(containerCmd())
+	cmd.AddCommand(cacheCmd())
 	cmd.AddCommand(keygenCmd())
 	cmd.AddCommand(passphraseCmd())
 	cmd.AddCommand(docCmd())
 	cmd.AddCommand(bugCmd())
+	cmd.AddCommand(doctorCmd())
+	cmd.AddCommand(telemetryCmd())
 
 	cmd.AddCommand(pluginCmd())
 	cmd.AddCommand(csoCmd())
@@ -70,6 +139,9 @@ var mainCmd = func() *cobra.Command {
 
 	cmd.AddCommand(fromCmd())
 	cmd.AddCommand(toCmd())
+	cmd.AddCommand(mappingCmd())
+	cmd.AddCommand(vaultCmd())
+	cmd.AddCommand(composeCmd())
 
 	cmd.AddCommand(transformCmd())
 
@@ -106,7 +178,17 @@ func Execute() error {
 		}
 	}
 
-	return cmd.Execute()
+	err := cmd.Execute()
+
+	// Errors raised before the command starts are usage errors
+	if err != nil && !commandStarted {
+		err = tasks.Usage(err)
+	}
+
+	stopProfile()
+	endTelemetry(err)
+
+	return err
 }
 
 // -----------------------------------------------------------------------------
@@ -0,0 +1,77 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/sdk/sandbox"
+	"github.com/elastic/harp/pkg/sdk/telemetry"
+)
+
+// -----------------------------------------------------------------------------
+
+var telemetryCmd = func() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "telemetry",
+		Short: "Local usage telemetry commands",
+		Long:  "Local usage telemetry is recorded when HARP_TELEMETRY=local is set, nothing is sent anywhere.",
+	}
+
+	// Sub-commands
+	cmd.AddCommand(telemetryReportCmd())
+
+	return cmd
+}
+
+// -----------------------------------------------------------------------------
+
+var telemetrySession *telemetry.Session
+
+// startTelemetry starts recording the command invocation when the local
+// telemetry is enabled.
+func startTelemetry(cmd *cobra.Command) {
+	if !telemetry.Enabled() {
+		return
+	}
+
+	path, err := telemetry.DefaultPath()
+	if err != nil {
+		return
+	}
+
+	// Keep recording under sandbox
+	sandbox.AllowWriteDir(filepath.Dir(path))
+
+	telemetrySession = telemetry.Start(telemetry.NewRecorder(path), cmd)
+	log.OnFatal(func() {
+		telemetrySession.End(1)
+	})
+}
+
+// endTelemetry records the command invocation result.
+func endTelemetry(err error) {
+	status := 0
+	if err != nil {
+		status = 1
+	}
+	telemetrySession.End(status)
+}
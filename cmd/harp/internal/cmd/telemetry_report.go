@@ -0,0 +1,74 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/sdk/telemetry"
+)
+
+// -----------------------------------------------------------------------------
+
+type telemetryReportParams struct {
+	path string
+}
+
+var telemetryReportCmd = func() *cobra.Command {
+	params := &telemetryReportParams{}
+
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Summarize locally recorded command usage",
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-telemetry-report", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Resolve telemetry file
+			path := params.path
+			if path == "" {
+				var err error
+				path, err = telemetry.DefaultPath()
+				if err != nil {
+					log.For(ctx).Fatal("unable to resolve telemetry file path", zap.Error(err))
+				}
+			}
+
+			// Load events
+			events, err := telemetry.NewRecorder(path).Load()
+			if err != nil {
+				log.For(ctx).Fatal("unable to load telemetry events", zap.Error(err))
+			}
+
+			// Display summary
+			if err := telemetry.WriteTable(os.Stdout, telemetry.Aggregate(events)); err != nil {
+				log.For(ctx).Fatal("unable to display telemetry report", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&params.path, "path", "", "Telemetry file path (defaults to the user configuration directory)")
+
+	return cmd
+}
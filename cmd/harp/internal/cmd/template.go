@@ -18,6 +18,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -30,23 +31,29 @@ import (
 	"github.com/elastic/harp/pkg/bundle"
 	"github.com/elastic/harp/pkg/sdk/cmdutil"
 	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/sdk/sandbox"
+	"github.com/elastic/harp/pkg/tasks"
 	tplcmdutil "github.com/elastic/harp/pkg/template/cmdutil"
+	"github.com/elastic/harp/pkg/template/consumption"
 	"github.com/elastic/harp/pkg/template/engine"
 	"github.com/elastic/harp/pkg/vault/kv"
 )
 
 var (
-	templateInputPath     string
-	templateOutputPath    string
-	templateValueFiles    []string
-	templateSecretLoaders []string
-	templateValues        []string
-	templateStringValues  []string
-	templateFileValues    []string
-	templateLeftDelims    string
-	templateRightDelims   string
-	templateAltDelims     bool
-	templateRootPath      string
+	templateInputPath       string
+	templateOutputPath      string
+	templateValueFiles      []string
+	templateSecretLoaders   []string
+	templateValues          []string
+	templateStringValues    []string
+	templateFileValues      []string
+	templateLeftDelims      string
+	templateRightDelims     string
+	templateAltDelims       bool
+	templateRootPath        string
+	templateLimits          = engine.DefaultLimits()
+	templateNetwork         engine.Network
+	templateConsumptionPath string
 )
 
 // -----------------------------------------------------------------------------
@@ -71,10 +78,43 @@ var templateCmd = func() *cobra.Command {
 	cmd.Flags().StringVar(&templateLeftDelims, "left-delimiter", "{{", "Template left delimiter (default to '{{')")
 	cmd.Flags().StringVar(&templateRightDelims, "right-delimiter", "}}", "Template right delimiter (default to '}}')")
 	cmd.Flags().BoolVar(&templateAltDelims, "alt-delims", false, "Define '[[' and ']]' as template delimiters.")
+	cmd.Flags().StringVar(&templateConsumptionPath, "consumption-manifest", "", "Write the manifest of consumed secret values to the given path")
+	templateLimitFlags(cmd, &templateLimits)
+	templateNetworkFlags(cmd, &templateNetwork)
 
 	return cmd
 }
 
+// templateLimitFlags registers template rendering resource limit flags.
+func templateLimitFlags(cmd *cobra.Command, limits *engine.Limits) {
+	cmd.Flags().Int64Var(&limits.MaxOutputSize, "max-output-size", limits.MaxOutputSize, "Maximum rendered output size in bytes (0 to disable)")
+	cmd.Flags().IntVar(&limits.MaxDepth, "max-depth", limits.MaxDepth, "Maximum template / include nesting depth (0 to disable)")
+	cmd.Flags().DurationVar(&limits.Timeout, "render-timeout", limits.Timeout, "Template rendering timeout (0 to disable)")
+}
+
+// templateNetworkFlags registers network template function flags.
+func templateNetworkFlags(cmd *cobra.Command, network *engine.Network) {
+	cmd.Flags().BoolVar(&network.Enabled, "allow-network-functions", false, "Allow template functions fetching remote documents (remoteJWKS)")
+	cmd.Flags().BoolVar(&network.Offline, "offline", false, "Read remote documents from snapshots instead of fetching them")
+	cmd.Flags().StringVar(&network.SnapshotDir, "network-snapshot-dir", "", "Directory holding remote document snapshots, written when fetching")
+	cmd.Flags().Int64Var(&network.MaxSize, "max-remote-size", engine.DefaultMaxRemoteSize, "Maximum remote document size in bytes")
+}
+
+// allowTemplateNetwork declares network template function requirements to
+// the sandbox.
+func allowTemplateNetwork(network engine.Network) {
+	if network.RequiresNetwork() {
+		sandbox.RequireNetwork()
+	}
+	switch {
+	case network.SnapshotDir == "":
+	case network.Offline:
+		sandbox.AllowRead(network.SnapshotDir)
+	default:
+		sandbox.AllowWriteDir(network.SnapshotDir)
+	}
+}
+
 func runTemplate(cmd *cobra.Command, args []string) {
 	ctx, cancel := cmdutil.Context(cmd.Context(), "harp-template", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
 	defer cancel()
@@ -125,16 +165,73 @@ func runTemplate(cmd *cobra.Command, args []string) {
 	}
 
 	// Process secret readers
-	secretReaders := []engine.SecretReaderFunc{}
-	for _, sr := range templateSecretLoaders {
+	opts, remoteSecrets := templateSecretSources(ctx, templateSecretLoaders)
+
+	// Track consumed secrets
+	tracker := &consumption.Tracker{}
+	if templateConsumptionPath != "" {
+		sandbox.AllowWrite(templateConsumptionPath)
+		opts = append(opts, engine.WithConsumptionTracker(tracker))
+	}
+
+	// Restrict the process before rendering
+	sandbox.AllowWrite(templateOutputPath)
+	allowTemplateNetwork(templateNetwork)
+	if err := cmdutil.Sandbox(ctx, &tasks.Capabilities{Network: remoteSecrets}); err != nil {
+		log.For(ctx).Fatal("unable to restrict the process", zap.Error(err))
+	}
+
+	// Compile and execute template
+	out, err := engine.RenderContext(engine.NewContext(append(opts,
+		engine.WithName(templateInputPath),
+		engine.WithDelims(templateLeftDelims, templateRightDelims),
+		engine.WithValues(values),
+		engine.WithFiles(files),
+		engine.WithLimits(templateLimits),
+		engine.WithNetwork(templateNetwork),
+	)...), string(body))
+	if err != nil {
+		log.For(ctx).Fatal("unable to produce output content", zap.Error(err), zap.String("path", templateInputPath))
+	}
+
+	// Create output writer
+	writer, err := cmdutil.Writer(templateOutputPath)
+	if err != nil {
+		log.For(ctx).Fatal("unable to create output writer", zap.Error(err), zap.String("path", templateOutputPath))
+	}
+
+	// Write rendered content
+	fmt.Fprintf(writer, "%s", out)
+
+	// Write consumption manifest
+	if templateConsumptionPath != "" {
+		consumptionWriter, err := cmdutil.Writer(templateConsumptionPath)
+		if err != nil {
+			log.For(ctx).Fatal("unable to create consumption manifest writer", zap.Error(err), zap.String("path", templateConsumptionPath))
+		}
+		if err := tracker.Manifest().WriteJSON(consumptionWriter); err != nil {
+			log.For(ctx).Fatal("unable to write consumption manifest", zap.Error(err), zap.String("path", templateConsumptionPath))
+		}
+	}
+}
+
+// templateSecretSources returns context options declaring secret readers of
+// the given secret containers ('vault' for Vault loader), and whether Vault
+// is used.
+func templateSecretSources(ctx context.Context, loaders []string) ([]engine.ContextOption, bool) {
+	opts := []engine.ContextOption{}
+	remoteSecrets := false
+	for _, sr := range loaders {
 		if sr == "vault" {
+			remoteSecrets = true
+
 			// Initialize Vault connection
 			vaultClient, errVault := api.NewClient(api.DefaultConfig())
 			if errVault != nil {
 				log.For(ctx).Fatal("unable to initialize vault secret loader", zap.Error(errVault), zap.String("container-path", sr))
 			}
 
-			secretReaders = append(secretReaders, kv.SecretGetter(vaultClient))
+			opts = append(opts, engine.WithSecretSource(consumption.SourceVault, kv.SecretGetter(vaultClient)))
 			continue
 		}
 
@@ -151,27 +248,8 @@ func runTemplate(cmd *cobra.Command, args []string) {
 		}
 
 		// Append secret loader
-		secretReaders = append(secretReaders, bundle.SecretReader(b))
+		opts = append(opts, engine.WithSecretSource(consumption.ContainerSource(sr), bundle.SecretReader(bundle.WithoutArchived(b))))
 	}
 
-	// Compile and execute template
-	out, err := engine.RenderContext(engine.NewContext(
-		engine.WithName(templateInputPath),
-		engine.WithDelims(templateLeftDelims, templateRightDelims),
-		engine.WithValues(values),
-		engine.WithFiles(files),
-		engine.WithSecretReaders(secretReaders...),
-	), string(body))
-	if err != nil {
-		log.For(ctx).Fatal("unable to produce output content", zap.Error(err), zap.String("path", templateInputPath))
-	}
-
-	// Create output writer
-	writer, err := cmdutil.Writer(templateOutputPath)
-	if err != nil {
-		log.For(ctx).Fatal("unable to create output writer", zap.Error(err), zap.String("path", templateOutputPath))
-	}
-
-	// Write rendered content
-	fmt.Fprintf(writer, "%s", out)
+	return opts, remoteSecrets
 }
@@ -31,6 +31,9 @@ var toCmd = func() *cobra.Command {
 
 	// Add sub commands
 	cmd.AddCommand(toVaultCmd())
+	cmd.AddCommand(toSystemdCmd())
+	cmd.AddCommand(toKeystoreCmd())
+	cmd.AddCommand(toVaultPolicyCmd())
 
 	return cmd
 }
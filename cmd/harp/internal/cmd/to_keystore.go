@@ -0,0 +1,100 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks/to"
+)
+
+// -----------------------------------------------------------------------------
+
+var toKeystoreCmd = func() *cobra.Command {
+	var (
+		inputPath          string
+		mappingPath        string
+		outputPath         string
+		passphrase         string
+		format             string
+		includeQuarantined bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "keystore",
+		Short: "Export secrets as a Java keystore (PKCS#12 or JKS)",
+		Long: `Export selected certificates and private keys as a password protected
+Java keystore.
+
+Entries with a private key are exported as private key entries, entries
+without key are exported as trusted certificates. PKCS#12 keystores hold a
+single private key entry or trusted certificates only and don't store aliases,
+use the JKS format to combine several aliased entries.
+
+Mapping file example :
+
+  entries:
+    - alias: web
+      package: app/production/customer1/ece/v1.0.0/web/tls
+      cert: certificate
+      key: private_key
+      chain:
+        - intermediate
+    - alias: root-ca
+      package: infra/pki/root
+      cert: certificate
+
+Certificate keys may contain a PEM bundle, the first certificate is the leaf
+and the following ones are appended to the chain.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-to-keystore", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Prepare task
+			t := &to.KeystoreTask{
+				ContainerReader:    cmdutil.FileReader(inputPath),
+				MappingReader:      cmdutil.FileReader(mappingPath),
+				OutputWriter:       cmdutil.FileWriter(outputPath),
+				Passphrase:         passphraseBuffer(ctx, passphrase, "Keystore passphrase", true),
+				Format:             format,
+				IncludeQuarantined: includeQuarantined,
+			}
+
+			// Run the task
+			if err := cmdutil.RunTask(ctx, t); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&inputPath, "in", "-", "Container path ('-' for stdin or filename)")
+	cmd.Flags().StringVar(&mappingPath, "mapping", "", "Mapping configuration path (alias to package certificate and key)")
+	log.CheckErr("unable to mark 'mapping' flag as required.", cmd.MarkFlagRequired("mapping"))
+	cmd.Flags().StringVar(&outputPath, "out", "", "Keystore output path ('-' for stdout or filename)")
+	log.CheckErr("unable to mark 'out' flag as required.", cmd.MarkFlagRequired("out"))
+	cmd.Flags().StringVar(&passphrase, "passphrase", "", "Keystore passphrase (prompted when empty)")
+	cmd.Flags().StringVar(&format, "format", to.KeystoreFormatPKCS12, "Keystore format (pkcs12, jks)")
+	cmd.Flags().BoolVar(&includeQuarantined, "include-quarantined", false, "Export quarantined packages")
+
+	return cmd
+}
@@ -0,0 +1,102 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/sdk/sandbox"
+	"github.com/elastic/harp/pkg/tasks/to"
+)
+
+// -----------------------------------------------------------------------------
+
+var toSystemdCmd = func() *cobra.Command {
+	var (
+		inputPath          string
+		mappingPath        string
+		outputPath         string
+		tmpfsCheck         bool
+		includeQuarantined bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "systemd",
+		Short: "Export secrets as systemd credentials with unit drop-in snippets",
+		Long: `Export selected package secrets as systemd LoadCredential files.
+
+Each key is written as an individual file (0600) in <out>/<service>/, and a
+drop-in snippet <out>/<service>.service.d/harp-credentials.conf is generated
+for each service.
+
+Mapping file example :
+
+  mappings:
+    - package: app/production/customer1/ece/v1.0.0/adminconsole/database/usage_credentials
+      service: adminconsole
+      credentials:
+        password: db_password
+        user: db_user
+
+Credentials are optional, all package keys are exported with their sanitized
+name when omitted.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-to-systemd", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Declare the output directory
+			if sandbox.Enabled() {
+				if err := os.MkdirAll(outputPath, 0o700); err != nil {
+					log.For(ctx).Fatal("unable to create output directory", zap.Error(err), zap.String("path", outputPath))
+				}
+				sandbox.AllowWriteDir(outputPath)
+			}
+
+			// Prepare task
+			t := &to.SystemdTask{
+				ContainerReader:    cmdutil.FileReader(inputPath),
+				MappingReader:      cmdutil.FileReader(mappingPath),
+				OutputPath:         outputPath,
+				TmpfsCheck:         tmpfsCheck,
+				IncludeQuarantined: includeQuarantined,
+			}
+
+			// Run the task
+			if err := cmdutil.RunTask(ctx, t); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&inputPath, "in", "-", "Container path ('-' for stdin or filename)")
+	cmd.Flags().StringVar(&mappingPath, "mapping", "", "Mapping configuration path (package to service, key to credential name)")
+	log.CheckErr("unable to mark 'mapping' flag as required.", cmd.MarkFlagRequired("mapping"))
+	cmd.Flags().StringVar(&outputPath, "out", "", "Output directory")
+	log.CheckErr("unable to mark 'out' flag as required.", cmd.MarkFlagRequired("out"))
+	cmd.Flags().BoolVar(&tmpfsCheck, "tmpfs-check", false, "Warn if the output directory is not on a ramdisk")
+	cmd.Flags().BoolVar(&includeQuarantined, "include-quarantined", false, "Export quarantined packages")
+
+	return cmd
+}
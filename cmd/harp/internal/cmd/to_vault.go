@@ -18,11 +18,13 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/spf13/cobra"
-	"go.uber.org/zap"
 
+	"github.com/elastic/harp/pkg/bundle"
 	"github.com/elastic/harp/pkg/sdk/cmdutil"
-	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks"
 	"github.com/elastic/harp/pkg/tasks/to"
 )
 
@@ -30,32 +32,61 @@ import (
 
 var toVaultCmd = func() *cobra.Command {
 	var (
-		inputPath     string
-		backendPrefix string
-		namespace     string
-		withMetadata  bool
+		inputPath          string
+		backendPrefix      string
+		namespace          string
+		withMetadata       bool
+		mappingPath        string
+		includeQuarantined bool
+		reportPath         string
+		checkAndSet        bool
+		metadataPrefixes   []string
+		expiredPackages    string
+		decryption         valueDecryptionFlags
+		failureMode        func() tasks.FailureMode
 	)
 
 	cmd := &cobra.Command{
-		Use:   "vault",
-		Short: "Push a secret container in Hashicorp Vault",
-		Run: func(cmd *cobra.Command, args []string) {
+		Use:          "vault",
+		Short:        "Push a secret container in Hashicorp Vault",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
 			// Initialize logger and context
 			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-to-vault", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
 			defer cancel()
 
+			// Check expired package policy
+			expiredPolicy, err := bundle.ParseExpiredPackagePolicy(expiredPackages)
+			if err != nil {
+				return tasks.Usage(fmt.Errorf("unable to parse expired package policy: %w", err))
+			}
+
+			// Prepare value decryption
+			valueDecryptor, decryptPolicy, err := decryption.build()
+			if err != nil {
+				return tasks.Usage(fmt.Errorf("unable to prepare value decryption: %w", err))
+			}
+
 			// Prepare task
 			t := &to.VaultTask{
-				ContainerReader: cmdutil.FileReader(inputPath),
-				BackendPrefix:   backendPrefix,
-				PushMetadata:    withMetadata,
-				VaultNamespace:  namespace,
+				ContainerReader:        cmdutil.FileReader(inputPath),
+				BackendPrefix:          backendPrefix,
+				PushMetadata:           withMetadata,
+				VaultNamespace:         namespace,
+				IncludeQuarantined:     includeQuarantined,
+				CheckAndSet:            checkAndSet,
+				CustomMetadataPrefixes: metadataPrefixes,
+				ExpiredPackagePolicy:   expiredPolicy,
+				ValueDecryptor:         valueDecryptor,
+				DecryptFailurePolicy:   decryptPolicy,
+				FailureMode:            failureMode(),
+			}
+			if mappingPath != "" {
+				t.MappingReader = cmdutil.FileReader(mappingPath)
 			}
 
 			// Run the task
-			if err := t.Run(ctx); err != nil {
-				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
-			}
+			return cmdutil.RunReportedTask(ctx, "to-vault", t, cmdutil.ReportWriter(reportPath))
 		},
 	}
 
@@ -64,6 +95,15 @@ var toVaultCmd = func() *cobra.Command {
 	cmd.Flags().StringVar(&backendPrefix, "prefix", "", "Vault backend prefix")
 	cmd.Flags().StringVar(&namespace, "namespace", "", "Vault namespace")
 	cmd.Flags().BoolVar(&withMetadata, "with-metadata", false, "Push container metadata")
+	cmd.Flags().StringVar(&mappingPath, "mapping", "", "Path mapping profile path (package labels as metadata)")
+	cmd.Flags().BoolVar(&includeQuarantined, "include-quarantined", false, "Export quarantined packages")
+	cmd.Flags().StringVar(&reportPath, "report-file", "", "Task execution report output (JSON)")
+	cmd.Flags().BoolVar(&checkAndSet, "check-and-set", false, "Refuse to overwrite KV v2 secrets modified since the publication started")
+	cmd.Flags().StringArrayVar(&metadataPrefixes, "custom-metadata-prefix", []string{}, "Package annotation prefix to publish as KV v2 custom metadata (repeatable)")
+
+	cmd.Flags().StringVar(&expiredPackages, "expired-packages", "keep", "Expired package policy (keep, drop, error)")
+	decryption.register(cmd)
+	failureMode = cmdutil.FailureModeFlags(cmd)
 
 	return cmd
 }
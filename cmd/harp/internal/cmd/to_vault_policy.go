@@ -0,0 +1,99 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/sdk/sandbox"
+	"github.com/elastic/harp/pkg/tasks/to"
+)
+
+// -----------------------------------------------------------------------------
+
+var toVaultPolicyCmd = func() *cobra.Command {
+	var (
+		inputPath          string
+		outputPath         string
+		mount              string
+		kvVersion          int
+		withList           bool
+		includeQuarantined bool
+		reportPath         string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "vault-policy",
+		Short: "Generate Vault policies from package ownership annotations",
+		Long: `Generate one Vault HCL policy per package owner, granting read access to
+exactly the packages it owns under the target mount.
+
+Owners are declared with the 'harp.elastic.co/v1/package#owner' annotation,
+and 'meta/acl/<policy>' references of the 'harp.elastic.co/v1/package#acl'
+annotation.
+
+Sibling paths are collapsed as a '<folder>/*' rule only when no package of
+another owner, or without owner, lives under the folder. Packages without
+owner are reported.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-to-vault-policy", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Declare the output directory
+			if sandbox.Enabled() {
+				if err := os.MkdirAll(outputPath, 0o755); err != nil {
+					log.For(ctx).Fatal("unable to create output directory", zap.Error(err), zap.String("path", outputPath))
+				}
+				sandbox.AllowWriteDir(outputPath)
+			}
+
+			// Prepare task
+			t := &to.VaultPolicyTask{
+				ContainerReader:    cmdutil.FileReader(inputPath),
+				OutputPath:         outputPath,
+				Mount:              mount,
+				KVVersion:          kvVersion,
+				WithList:           withList,
+				IncludeQuarantined: includeQuarantined,
+			}
+
+			// Run the task
+			if err := cmdutil.RunReportedTask(ctx, "to-vault-policy", t, cmdutil.ReportWriter(reportPath)); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&inputPath, "in", "-", "Container path ('-' for stdin or filename)")
+	cmd.Flags().StringVar(&outputPath, "out", "", "Policy output directory")
+	log.CheckErr("unable to mark 'out' flag as required.", cmd.MarkFlagRequired("out"))
+	cmd.Flags().StringVar(&mount, "mount", "secret/", "Vault KV mount path")
+	cmd.Flags().IntVar(&kvVersion, "kv-version", 2, "Vault KV engine version (1, 2)")
+	cmd.Flags().BoolVar(&withList, "with-list", false, "Grant list capability on parent folders")
+	cmd.Flags().BoolVar(&includeQuarantined, "include-quarantined", false, "Grant quarantined packages")
+	cmd.Flags().StringVar(&reportPath, "report-file", "", "Task execution report output (JSON)")
+
+	return cmd
+}
@@ -62,8 +62,8 @@ var transformEncryptionCmd = func() *cobra.Command {
 				log.For(ctx).Fatal("unable to initialize input reader", zap.Error(err))
 			}
 
-			// Read input
-			writer, err := cmdutil.Writer(inputPath)
+			// Prepare output
+			writer, err := cmdutil.Writer(outputPath)
 			if err != nil {
 				log.For(ctx).Fatal("unable to initialize output writer", zap.Error(err))
 			}
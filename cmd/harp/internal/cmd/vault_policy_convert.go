@@ -0,0 +1,90 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks/vault"
+)
+
+// -----------------------------------------------------------------------------
+
+var vaultPolicyConvertCmd = func() *cobra.Command {
+	var (
+		policyDir  string
+		mount      string
+		kvVersion  int
+		outputPath string
+		inputPath  string
+		bundleOut  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "convert",
+		Short: "Convert Vault policies to harp access control rules",
+		Long: `Convert Vault HCL policies to harp access control rules.
+
+Each '*.hcl' file of the policy directory is converted as an ACL entry named
+after the file. Only read, list and deny capabilities targeting the given KV
+mount are converted, Vault '*' and '+' wildcards are translated with Vault
+matching and priority semantics.
+
+Constructs without harp equivalent (templated paths, sudo, parameter
+constraints, cross-policy deny) are reported in the warnings section.
+
+Use --in and --bundle-out to store converted rules as 'meta/acl/<policy>'
+packages and reference them from readable packages.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Initialize logger and context
+			ctx, cancel := cmdutil.Context(cmd.Context(), "harp-vault-policy-convert", conf.Debug.Enable, conf.Instrumentation.Logs.Level)
+			defer cancel()
+
+			// Prepare task
+			t := &vault.PolicyConvertTask{
+				PolicyDir:    policyDir,
+				Mount:        mount,
+				KVVersion:    kvVersion,
+				OutputWriter: cmdutil.FileWriter(outputPath),
+			}
+			if inputPath != "" || bundleOut != "" {
+				t.ContainerReader = cmdutil.FileReader(inputPath)
+				t.BundleWriter = cmdutil.FileWriter(bundleOut)
+			}
+
+			// Run the task
+			if err := cmdutil.RunTask(ctx, t); err != nil {
+				log.For(ctx).Fatal("unable to execute task", zap.Error(err))
+			}
+		},
+	}
+
+	// Parameters
+	cmd.Flags().StringVar(&policyDir, "policy-dir", "", "Vault policy directory")
+	log.CheckErr("unable to mark 'policy-dir' flag as required.", cmd.MarkFlagRequired("policy-dir"))
+	cmd.Flags().StringVar(&mount, "mount", "secret/", "Vault KV mount path")
+	cmd.Flags().IntVar(&kvVersion, "kv-version", 2, "Vault KV engine version")
+	cmd.Flags().StringVar(&outputPath, "out", "", "Converted rules output ('-' for stdout or filename)")
+	cmd.Flags().StringVar(&inputPath, "in", "", "Container input to apply rules to ('-' for stdin or filename)")
+	cmd.Flags().StringVar(&bundleOut, "bundle-out", "", "Container output with applied rules ('-' for stdout or filename)")
+
+	return cmd
+}
@@ -19,10 +19,13 @@ package main
 
 import (
 	"math/rand"
+	"os"
 	"time"
 
 	"github.com/elastic/harp/cmd/harp/internal/cmd"
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
 	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/sdk/security"
 )
 
 func init() {
@@ -34,7 +37,17 @@ func init() {
 }
 
 func main() {
-	if err := cmd.Execute(); err != nil {
-		log.CheckErr("Unable to complete command execution", err)
-	}
+	// Keep secrets out of core dumps
+	log.CheckErr("Unable to disable core dumps", security.DisableCoreDumps())
+
+	// Wipe sensitive memory on crash
+	code := cmdutil.ExitOK
+	security.Protect(func() {
+		if err := cmd.Execute(); err != nil {
+			log.CheckErr("Unable to complete command execution", err)
+			code = cmdutil.ExitCode(err)
+		}
+	})
+
+	os.Exit(code)
 }
@@ -0,0 +1,148 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package acme
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dchest/uniuri"
+	"gopkg.in/square/go-jose.v2"
+
+	"github.com/elastic/harp/pkg/container/identity"
+	"github.com/elastic/harp/pkg/sdk/security"
+)
+
+const (
+	// AccountKind is the identity kind of ACME account keys.
+	AccountKind = "ACMEAccountKey"
+
+	// Account keys are sealed with the same PBES2 settings as container
+	// identities.
+	pbes2SaltSize   = 16
+	pbes2Iterations = 500001
+)
+
+// NewAccountKey generates an ACME account key wrapped as an identity. The
+// private key is sealed with the given passphrase.
+func NewAccountKey(description string, passphrase []byte) (*identity.Identity, *ecdsa.PrivateKey, error) {
+	// Check arguments
+	if description == "" {
+		return nil, nil, errors.New("unable to create account key with a blank description")
+	}
+	if len(passphrase) == 0 {
+		return nil, nil, errors.New("unable to seal account key with a blank passphrase")
+	}
+
+	// Generate account key
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to generate account key: %w", err)
+	}
+
+	// Encode as JWK
+	payload, err := jose.JSONWebKey{Key: key, Algorithm: string(jose.ES256)}.MarshalJSON()
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to encode account key: %w", err)
+	}
+
+	// Encrypt JWK using PBES2
+	encrypter, err := jose.NewEncrypter(jose.A256GCM, jose.Recipient{
+		Algorithm:  jose.PBES2_HS512_A256KW,
+		Key:        passphrase,
+		PBES2Count: pbes2Iterations,
+		PBES2Salt:  []byte(uniuri.NewLen(pbes2SaltSize)),
+	}, (&jose.EncrypterOptions{}).WithContentType("jwk+json"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to initialize account key encrypter: %w", err)
+	}
+	jwe, err := encrypter.Encrypt(payload)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to seal account key: %w", err)
+	}
+	content, err := jwe.CompactSerialize()
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to serialize sealed account key: %w", err)
+	}
+
+	// No error
+	return &identity.Identity{
+		APIVersion:  identity.APIVersion,
+		Kind:        AccountKind,
+		Timestamp:   time.Now().UTC(),
+		Description: description,
+		Public:      publicKey(&key.PublicKey),
+		Private: &identity.PrivateKey{
+			Encoding: "jwe",
+			Content:  content,
+		},
+	}, key, nil
+}
+
+// AccountKey unseals the ACME account key from the given identity.
+func AccountKey(id *identity.Identity, passphrase []byte) (*ecdsa.PrivateKey, error) {
+	// Check arguments
+	if id == nil {
+		return nil, errors.New("unable to extract account key from a nil identity")
+	}
+	if id.Kind != AccountKind {
+		return nil, fmt.Errorf("identity kind '%s' is not an ACME account key", id.Kind)
+	}
+	if !id.HasPrivateKey() || id.Private.Encoding != "jwe" {
+		return nil, errors.New("account key must be sealed with a passphrase")
+	}
+
+	// Decrypt JWK
+	jwe, err := jose.ParseEncrypted(id.Private.Content)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse sealed account key: %w", err)
+	}
+	payload, err := jwe.Decrypt(passphrase)
+	if err != nil {
+		return nil, errors.New("unable to unseal account key")
+	}
+
+	// Decode key
+	var jwk jose.JSONWebKey
+	if err := jwk.UnmarshalJSON(payload); err != nil {
+		return nil, fmt.Errorf("unable to decode account key: %w", err)
+	}
+	key, ok := jwk.Key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("unsupported account key type %T", jwk.Key)
+	}
+
+	// Check validity
+	if !security.SecureCompareString(id.Public, publicKey(&key.PublicKey)) {
+		return nil, errors.New("invalid account key, key mismatch detected")
+	}
+
+	// No error
+	return key, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func publicKey(pub *ecdsa.PublicKey) string {
+	return base64.RawURLEncoding.EncodeToString(elliptic.Marshal(pub.Curve, pub.X, pub.Y))
+}
@@ -0,0 +1,60 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package acme
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/elastic/harp/pkg/container/identity"
+)
+
+func TestAccountKey(t *testing.T) {
+	id, key, err := NewAccountKey("staging account", []byte("passphrase"))
+	if err != nil {
+		t.Fatalf("unable to create account key: %v", err)
+	}
+
+	// Store and load through the identity codec
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(id); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := identity.FromReader(&buf)
+	if err != nil {
+		t.Fatalf("unable to load account identity: %v", err)
+	}
+
+	got, err := AccountKey(loaded, []byte("passphrase"))
+	if err != nil {
+		t.Fatalf("unable to unseal account key: %v", err)
+	}
+	if !got.Equal(key) {
+		t.Error("unsealed account key mismatch")
+	}
+
+	if _, err := AccountKey(loaded, []byte("invalid")); err == nil {
+		t.Error("invalid passphrase should raise an error")
+	}
+
+	loaded.Kind = "ContainerIdentity"
+	if _, err := AccountKey(loaded, []byte("passphrase")); err == nil {
+		t.Error("container identities should be rejected")
+	}
+}
@@ -0,0 +1,416 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package acme provides ACME (RFC 8555) certificate issuance as bundle
+// packages.
+package acme
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	xacme "golang.org/x/crypto/acme"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle/secret"
+	csov1 "github.com/elastic/harp/pkg/cso/v1"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/sdk/security/crypto"
+)
+
+const (
+	// LetsEncryptStagingURL is the Let's Encrypt staging directory.
+	LetsEncryptStagingURL = "https://acme-staging-v02.api.letsencrypt.org/directory"
+	// LetsEncryptURL is the Let's Encrypt production directory.
+	LetsEncryptURL = xacme.LetsEncryptURL
+
+	// MaxDomains is the maximum domain count of a certificate order.
+	MaxDomains = 100
+
+	// AnnotationPrefix prefixes package annotations describing the ACME
+	// order.
+	AnnotationPrefix = "harp.elastic.co/v1/acme#"
+	// DirectoryAnnotation holds the ACME directory URL.
+	DirectoryAnnotation = AnnotationPrefix + "directory"
+	// OrderAnnotation holds the ACME order URL.
+	OrderAnnotation = AnnotationPrefix + "order"
+	// CertificateAnnotation holds the ACME certificate URL.
+	CertificateAnnotation = AnnotationPrefix + "certificate"
+	// DomainsAnnotation holds the comma separated certificate domains.
+	DomainsAnnotation = AnnotationPrefix + "domains"
+	// ExpiryAnnotation holds the certificate expiration date (RFC3339).
+	ExpiryAnnotation = AnnotationPrefix + "expiry"
+
+	// KeySecret is the package key holding the PEM encoded private key.
+	KeySecret = "key"
+	// CertificateSecret is the package key holding the PEM encoded leaf
+	// certificate.
+	CertificateSecret = "cert"
+	// ChainSecret is the package key holding the PEM encoded issuer chain.
+	ChainSecret = "chain"
+
+	maxRetries = 5
+)
+
+// ErrRateLimited is raised when the ACME server rate limit is reached.
+var ErrRateLimited = errors.New("acme: rate limit reached")
+
+// Client issues certificates using an ACME server. Rate limited requests are
+// retried only when the server asks to wait less than the given delay.
+type Client struct {
+	api     *xacme.Client
+	maxWait time.Duration
+
+	mu         sync.Mutex
+	retryAfter time.Duration
+}
+
+// NewClient wraps the given ACME client.
+func NewClient(api *xacme.Client, maxRateLimitWait time.Duration) (*Client, error) {
+	// Check arguments
+	if api == nil {
+		return nil, errors.New("unable to initialize client with a nil ACME client")
+	}
+	if api.Key == nil {
+		return nil, errors.New("unable to initialize client without account key")
+	}
+
+	c := &Client{
+		api:     api,
+		maxWait: maxRateLimitWait,
+	}
+	api.RetryBackoff = c.backoff
+
+	// No error
+	return c, nil
+}
+
+// Register creates the ACME account bound to the client key. Existing
+// accounts are reused.
+func (c *Client) Register(ctx context.Context, email string) error {
+	acct := &xacme.Account{}
+	if email != "" {
+		acct.Contact = []string{fmt.Sprintf("mailto:%s", email)}
+	}
+
+	_, err := c.api.Register(ctx, acct, xacme.AcceptTOS)
+	switch {
+	case errors.Is(err, xacme.ErrAccountAlreadyExists):
+		return nil
+	case err != nil:
+		return fmt.Errorf("unable to register ACME account: %w", c.wrap(err))
+	default:
+	}
+
+	// No error
+	return nil
+}
+
+// Issue orders a certificate for the given domains and returns it as a
+// package named after the given CSO path.
+func (c *Client) Issue(ctx context.Context, name string, domains []string, solver Solver, opts ...Option) (*bundlev1.Package, error) {
+	// Check arguments
+	if solver == nil {
+		return nil, errors.New("unable to issue certificate with a nil solver")
+	}
+	if err := csov1.Validate(name); err != nil {
+		return nil, fmt.Errorf("package path '%s' is not CSO compliant: %w", name, err)
+	}
+	if err := validateDomains(domains); err != nil {
+		return nil, err
+	}
+
+	// Apply option functions
+	dopts := &options{keyType: KeyTypeEC256}
+	for _, o := range opts {
+		if err := o(dopts); err != nil {
+			return nil, err
+		}
+	}
+
+	// Create order
+	order, err := c.api.AuthorizeOrder(ctx, xacme.DomainIDs(domains...))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create order: %w", c.wrap(err))
+	}
+
+	// Complete authorizations
+	for _, u := range order.AuthzURLs {
+		if err := c.authorize(ctx, u, solver); err != nil {
+			return nil, err
+		}
+	}
+	ready, err := c.api.WaitOrder(ctx, order.URI)
+	if err != nil {
+		return nil, fmt.Errorf("unable to complete order '%s': %w", order.URI, c.wrap(err))
+	}
+
+	// Prepare certificate request
+	key, err := generateKey(dopts.keyType)
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate certificate key: %w", err)
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: domains[0]},
+		DNSNames: domains,
+	}, key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create certificate request: %w", err)
+	}
+
+	// Finalize order
+	der, certURL, err := c.api.CreateOrderCert(ctx, ready.FinalizeURL, csr, true)
+	if err != nil {
+		return nil, fmt.Errorf("unable to finalize order '%s': %w", order.URI, c.wrap(err))
+	}
+	if len(der) == 0 {
+		return nil, fmt.Errorf("order '%s' returned no certificate", order.URI)
+	}
+	leaf, err := x509.ParseCertificate(der[0])
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse issued certificate: %w", err)
+	}
+
+	// Encode private key
+	keyPEM, err := crypto.ToPEM(key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode certificate key: %w", err)
+	}
+
+	// Assemble package
+	return newPackage(name, map[string]string{
+		KeySecret:         keyPEM,
+		CertificateSecret: encodeCertificates(der[:1]),
+		ChainSecret:       encodeCertificates(der[1:]),
+	}, map[string]string{
+		DirectoryAnnotation:   c.api.DirectoryURL,
+		OrderAnnotation:       order.URI,
+		CertificateAnnotation: certURL,
+		DomainsAnnotation:     strings.Join(domains, ","),
+		ExpiryAnnotation:      leaf.NotAfter.UTC().Format(time.RFC3339),
+	})
+}
+
+// Expiry returns the certificate expiration date recorded on the given
+// package.
+func Expiry(p *bundlev1.Package) (time.Time, bool, error) {
+	if p == nil {
+		return time.Time{}, false, nil
+	}
+	raw, ok := p.Annotations[ExpiryAnnotation]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, true, fmt.Errorf("invalid expiry annotation '%s': %w", raw, err)
+	}
+
+	return t, true, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (c *Client) authorize(ctx context.Context, u string, solver Solver) error {
+	authz, err := c.api.GetAuthorization(ctx, u)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve authorization '%s': %w", u, c.wrap(err))
+	}
+	if authz.Status == xacme.StatusValid {
+		return nil
+	}
+
+	// Select the solver challenge
+	var chal *xacme.Challenge
+	for _, candidate := range authz.Challenges {
+		if candidate.Type == solver.Type() {
+			chal = candidate
+			break
+		}
+	}
+	if chal == nil {
+		return fmt.Errorf("no %s challenge offered for '%s'", solver.Type(), authz.Identifier.Value)
+	}
+
+	// Prepare challenge
+	ch := &Challenge{
+		Type:   chal.Type,
+		Domain: authz.Identifier.Value,
+		Token:  chal.Token,
+	}
+	switch chal.Type {
+	case ChallengeDNS01:
+		ch.Name = recordName(ch.Domain)
+		ch.Value, err = c.api.DNS01ChallengeRecord(chal.Token)
+	case ChallengeHTTP01:
+		ch.Name = c.api.HTTP01ChallengePath(chal.Token)
+		ch.Value, err = c.api.HTTP01ChallengeResponse(chal.Token)
+	default:
+		err = fmt.Errorf("unsupported challenge type '%s'", chal.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to prepare challenge for '%s': %w", ch.Domain, err)
+	}
+
+	// Provision challenge resources
+	if err := solver.Present(ctx, ch); err != nil {
+		return fmt.Errorf("unable to present %s challenge for '%s': %w", ch.Type, ch.Domain, err)
+	}
+	defer func() {
+		if errCleanUp := solver.CleanUp(ctx, ch); errCleanUp != nil {
+			log.For(ctx).Warn("unable to clean up challenge", zap.String("domain", ch.Domain), zap.Error(errCleanUp))
+		}
+	}()
+
+	// Ask for validation
+	if _, err := c.api.Accept(ctx, chal); err != nil {
+		return fmt.Errorf("unable to accept challenge for '%s': %w", ch.Domain, c.wrap(err))
+	}
+	if _, err := c.api.WaitAuthorization(ctx, authz.URI); err != nil {
+		return fmt.Errorf("unable to validate '%s': %w", ch.Domain, c.wrap(err))
+	}
+
+	// No error
+	return nil
+}
+
+// backoff retries failed requests, rate limited requests are only retried
+// when the announced delay is acceptable.
+func (c *Client) backoff(n int, _ *http.Request, res *http.Response) time.Duration {
+	if res != nil && res.StatusCode == http.StatusTooManyRequests {
+		d := parseRetryAfter(res.Header.Get("Retry-After"))
+		if d > c.maxWait || n > maxRetries {
+			c.mu.Lock()
+			c.retryAfter = d
+			c.mu.Unlock()
+			return -1
+		}
+		if d > 0 {
+			return d
+		}
+	}
+	if n > maxRetries {
+		return -1
+	}
+
+	// Exponential backoff capped to 10s
+	d := time.Duration(1<<uint(n-1)) * time.Second
+	if d > 10*time.Second {
+		d = 10 * time.Second
+	}
+	return d
+}
+
+// wrap decorates rate limit errors.
+func (c *Client) wrap(err error) error {
+	if d, ok := xacme.RateLimit(err); ok {
+		return fmt.Errorf("%w, retry after %s: %v", ErrRateLimited, d, err)
+	}
+
+	c.mu.Lock()
+	d := c.retryAfter
+	c.retryAfter = 0
+	c.mu.Unlock()
+	if d > 0 {
+		return fmt.Errorf("%w, retry after %s: %v", ErrRateLimited, d, err)
+	}
+
+	return err
+}
+
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if i, err := strconv.Atoi(v); err == nil {
+		return time.Duration(i) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+func validateDomains(domains []string) error {
+	if len(domains) == 0 {
+		return errors.New("at least one domain must be given")
+	}
+	if len(domains) > MaxDomains {
+		return fmt.Errorf("an order can't contain more than %d domains", MaxDomains)
+	}
+
+	seen := map[string]struct{}{}
+	for _, d := range domains {
+		if d == "" || d != strings.ToLower(strings.TrimSpace(d)) {
+			return fmt.Errorf("domain '%s' must be a lowercase name", d)
+		}
+		if _, ok := seen[d]; ok {
+			return fmt.Errorf("domain '%s' is duplicated", d)
+		}
+		seen[d] = struct{}{}
+	}
+
+	return nil
+}
+
+func encodeCertificates(der [][]byte) string {
+	var sb strings.Builder
+	for _, c := range der {
+		sb.Write(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c}))
+	}
+	return sb.String()
+}
+
+func newPackage(name string, secrets, annotations map[string]string) (*bundlev1.Package, error) {
+	chain := &bundlev1.SecretChain{
+		Version: 0,
+		Data:    []*bundlev1.KV{},
+	}
+	for _, k := range []string{KeySecret, CertificateSecret, ChainSecret} {
+		// Pack secret value
+		packed, err := secret.Pack(secrets[k])
+		if err != nil {
+			return nil, fmt.Errorf("unable to pack secret value for key '%s': %w", k, err)
+		}
+
+		chain.Data = append(chain.Data, &bundlev1.KV{
+			Key:   k,
+			Type:  "string",
+			Value: packed,
+		})
+	}
+
+	// No error
+	return &bundlev1.Package{
+		Name:        name,
+		Labels:      map[string]string{},
+		Annotations: annotations,
+		Secrets:     chain,
+	}, nil
+}
@@ -0,0 +1,140 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package acme
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	xacme "golang.org/x/crypto/acme"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+)
+
+const certPath = "infra/aws/security/eu-central-1/ec2/ssh/default/tls"
+
+func testClient(t *testing.T, directoryURL string) *Client {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(&xacme.Client{Key: key, DirectoryURL: directoryURL}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestClient_Issue_Validation(t *testing.T) {
+	c := testClient(t, "http://127.0.0.1:1/directory")
+	solver := &ManualSolver{}
+
+	many := []string{}
+	for i := 0; i <= MaxDomains; i++ {
+		many = append(many, fmt.Sprintf("d%d.example.com", i))
+	}
+
+	testCases := []struct {
+		desc    string
+		name    string
+		domains []string
+		solver  Solver
+		opts    []Option
+	}{
+		{desc: "nil solver", name: certPath, domains: []string{"example.com"}},
+		{desc: "invalid path", name: "tls/example.com", domains: []string{"example.com"}, solver: solver},
+		{desc: "no domain", name: certPath, solver: solver},
+		{desc: "too many domains", name: certPath, domains: many, solver: solver},
+		{desc: "duplicate domain", name: certPath, domains: []string{"example.com", "example.com"}, solver: solver},
+		{desc: "uppercase domain", name: certPath, domains: []string{"Example.com"}, solver: solver},
+		{desc: "invalid key type", name: certPath, domains: []string{"example.com"}, solver: solver, opts: []Option{WithKeyType("dsa")}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if _, err := c.Issue(context.Background(), tC.name, tC.domains, tC.solver, tC.opts...); err == nil {
+				t.Error("error should be raised")
+			}
+		})
+	}
+}
+
+func TestClient_RateLimit(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"type":"urn:ietf:params:acme:error:rateLimited","detail":"too many certificates already issued"}`)
+	}))
+	defer srv.Close()
+
+	c := testClient(t, srv.URL)
+	err := c.Register(context.Background(), "ops@example.com")
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("rate limit error should be raised, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("rate limited request should not be retried, got %d calls", calls)
+	}
+}
+
+func TestClient_Backoff(t *testing.T) {
+	c := testClient(t, "")
+	limited := func(retryAfter string) *http.Response {
+		return &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": []string{retryAfter}}}
+	}
+
+	if d := c.backoff(1, nil, limited("5")); d != 5*time.Second {
+		t.Errorf("short rate limit delay should be honored, got %s", d)
+	}
+	if d := c.backoff(1, nil, limited("3600")); d >= 0 {
+		t.Errorf("long rate limit delay should stop retries, got %s", d)
+	}
+	if d := c.backoff(maxRetries+1, nil, &http.Response{StatusCode: http.StatusInternalServerError}); d >= 0 {
+		t.Errorf("retries should be bounded, got %s", d)
+	}
+	if d := c.backoff(10, nil, &http.Response{StatusCode: http.StatusInternalServerError}); d > 10*time.Second {
+		t.Errorf("backoff should be capped, got %s", d)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	p := &bundlev1.Package{Annotations: map[string]string{ExpiryAnnotation: "2021-01-31T10:00:00Z"}}
+	got, ok, err := Expiry(p)
+	if err != nil || !ok || !got.Equal(time.Date(2021, 1, 31, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected expiry %s %v %v", got, ok, err)
+	}
+
+	if _, ok, _ := Expiry(&bundlev1.Package{}); ok {
+		t.Error("missing annotation should not be reported")
+	}
+
+	p.Annotations[ExpiryAnnotation] = "tomorrow"
+	if _, _, err := Expiry(p); err == nil {
+		t.Error("invalid annotation should raise an error")
+	}
+}
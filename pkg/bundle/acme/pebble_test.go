@@ -0,0 +1,132 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build integration
+// +build integration
+
+package acme
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	xacme "golang.org/x/crypto/acme"
+
+	"github.com/elastic/harp/pkg/bundle/secret"
+)
+
+// Integration tests require a pebble ACME server resolving challenge domains
+// to this host (i.e. using pebble-challtestsrv default A record):
+//
+//	pebble-challtestsrv -defaultIPv4 127.0.0.1 &
+//	pebble -config test/config/pebble-config.json -dnsserver 127.0.0.1:8053 &
+//	HARP_PEBBLE_DIRECTORY=https://127.0.0.1:14000/dir go test -tags integration ./pkg/bundle/acme/...
+func pebbleClient(t *testing.T) *Client {
+	directory := os.Getenv("HARP_PEBBLE_DIRECTORY")
+	if directory == "" {
+		t.Skip("HARP_PEBBLE_DIRECTORY is not set")
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewClient(&xacme.Client{
+		Key:          key,
+		DirectoryURL: directory,
+		HTTPClient: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				// Pebble uses a self-signed certificate
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, //nolint:gosec // test server
+			},
+		},
+	}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func TestPebble_Issue(t *testing.T) {
+	c := pebbleClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	if err := c.Register(ctx, "ops@example.com"); err != nil {
+		t.Fatalf("unable to register account: %v", err)
+	}
+	// Registration is idempotent
+	if err := c.Register(ctx, "ops@example.com"); err != nil {
+		t.Fatalf("unable to reuse account: %v", err)
+	}
+
+	address := os.Getenv("HARP_PEBBLE_HTTP_ADDRESS")
+	if address == "" {
+		address = ":5002"
+	}
+
+	domains := []string{"harp.example.com", "www.harp.example.com"}
+	p, err := c.Issue(ctx, certPath, domains, &HTTPSolver{Address: address}, WithKeyType(KeyTypeRSA2048))
+	if err != nil {
+		t.Fatalf("unable to issue certificate: %v", err)
+	}
+
+	// Check package content
+	values := map[string]string{}
+	for _, kv := range p.Secrets.Data {
+		var v string
+		if err := secret.Unpack(kv.Value, &v); err != nil {
+			t.Fatal(err)
+		}
+		values[kv.Key] = v
+	}
+	block, _ := pem.Decode([]byte(values[CertificateSecret]))
+	if block == nil {
+		t.Fatal("certificate should be PEM encoded")
+	}
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := leaf.VerifyHostname("www.harp.example.com"); err != nil {
+		t.Errorf("certificate should cover all domains: %v", err)
+	}
+	if values[ChainSecret] == "" || values[KeySecret] == "" {
+		t.Error("key and chain should be stored")
+	}
+
+	// Check annotations
+	expiry, ok, err := Expiry(p)
+	if err != nil || !ok || !expiry.Equal(leaf.NotAfter.UTC().Truncate(time.Second)) {
+		t.Errorf("unexpected expiry annotation %s %v %v", expiry, ok, err)
+	}
+	if p.Annotations[OrderAnnotation] == "" {
+		t.Error("order URL should be recorded")
+	}
+}
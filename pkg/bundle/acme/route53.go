@@ -0,0 +1,149 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package acme
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53/route53iface"
+)
+
+// DefaultRoute53TTL is the TTL of challenge records.
+const DefaultRoute53TTL = 60
+
+// Route53Solver provisions dns-01 challenge records in AWS Route53.
+type Route53Solver struct {
+	Client route53iface.Route53API
+	// HostedZoneID is resolved from the domain name when blank.
+	HostedZoneID string
+	TTL          int64
+	// SkipWait disables waiting for the record change propagation.
+	SkipWait bool
+}
+
+// Type returns the handled challenge type.
+func (s *Route53Solver) Type() string { return ChallengeDNS01 }
+
+// Present creates the challenge TXT record.
+func (s *Route53Solver) Present(ctx context.Context, ch *Challenge) error {
+	return s.change(ctx, route53.ChangeActionUpsert, ch)
+}
+
+// CleanUp deletes the challenge TXT record.
+func (s *Route53Solver) CleanUp(ctx context.Context, ch *Challenge) error {
+	return s.change(ctx, route53.ChangeActionDelete, ch)
+}
+
+// -----------------------------------------------------------------------------
+
+func (s *Route53Solver) change(ctx context.Context, action string, ch *Challenge) error {
+	// Check arguments
+	if s.Client == nil {
+		return errors.New("unable to use route53 solver with a nil client")
+	}
+
+	// Resolve hosted zone
+	zoneID := s.HostedZoneID
+	if zoneID == "" {
+		var err error
+		if zoneID, err = s.hostedZone(ctx, ch.Name); err != nil {
+			return err
+		}
+	}
+
+	ttl := s.TTL
+	if ttl <= 0 {
+		ttl = DefaultRoute53TTL
+	}
+
+	// Apply change
+	out, err := s.Client.ChangeResourceRecordSetsWithContext(ctx, &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String(zoneID),
+		ChangeBatch: &route53.ChangeBatch{
+			Comment: aws.String(fmt.Sprintf("harp acme challenge for %s", ch.Domain)),
+			Changes: []*route53.Change{
+				{
+					Action: aws.String(action),
+					ResourceRecordSet: &route53.ResourceRecordSet{
+						Name: aws.String(ch.Name + "."),
+						Type: aws.String(route53.RRTypeTxt),
+						TTL:  aws.Int64(ttl),
+						ResourceRecords: []*route53.ResourceRecord{
+							{Value: aws.String(strconv.Quote(ch.Value))},
+						},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("unable to %s record '%s': %w", strings.ToLower(action), ch.Name, err)
+	}
+
+	// Wait for propagation
+	if s.SkipWait || action == route53.ChangeActionDelete {
+		return nil
+	}
+	if err := s.Client.WaitUntilResourceRecordSetsChangedWithContext(ctx, &route53.GetChangeInput{
+		Id: out.ChangeInfo.Id,
+	}); err != nil {
+		return fmt.Errorf("unable to wait for record '%s' propagation: %w", ch.Name, err)
+	}
+
+	// No error
+	return nil
+}
+
+// hostedZone returns the identifier of the most specific public hosted zone
+// containing the given name.
+func (s *Route53Solver) hostedZone(ctx context.Context, name string) (string, error) {
+	var (
+		fqdn    = strings.TrimSuffix(name, ".") + "."
+		zoneID  string
+		longest int
+	)
+
+	if err := s.Client.ListHostedZonesPagesWithContext(ctx, &route53.ListHostedZonesInput{}, func(page *route53.ListHostedZonesOutput, _ bool) bool {
+		for _, z := range page.HostedZones {
+			if z.Config != nil && aws.BoolValue(z.Config.PrivateZone) {
+				continue
+			}
+			zone := aws.StringValue(z.Name)
+			if fqdn != zone && !strings.HasSuffix(fqdn, "."+zone) {
+				continue
+			}
+			if len(zone) > longest {
+				zoneID, longest = aws.StringValue(z.Id), len(zone)
+			}
+		}
+		return true
+	}); err != nil {
+		return "", fmt.Errorf("unable to list hosted zones: %w", err)
+	}
+	if zoneID == "" {
+		return "", fmt.Errorf("no hosted zone found for '%s'", name)
+	}
+
+	return strings.TrimPrefix(zoneID, "/hostedzone/"), nil
+}
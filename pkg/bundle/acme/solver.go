@@ -0,0 +1,205 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package acme
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ChallengeDNS01 is the DNS TXT record challenge type.
+	ChallengeDNS01 = "dns-01"
+	// ChallengeHTTP01 is the HTTP well-known resource challenge type.
+	ChallengeHTTP01 = "http-01"
+)
+
+// Challenge describes a domain validation challenge to fulfill.
+type Challenge struct {
+	Type   string
+	Domain string
+	Token  string
+	// Name is the TXT record name for dns-01 challenges, or the resource path
+	// for http-01 challenges.
+	Name string
+	// Value is the TXT record value for dns-01 challenges, or the resource
+	// content for http-01 challenges.
+	Value string
+}
+
+// Solver provisions the resources proving the domain control to the ACME
+// server.
+type Solver interface {
+	// Type returns the handled challenge type.
+	Type() string
+	// Present provisions the challenge resource.
+	Present(ctx context.Context, ch *Challenge) error
+	// CleanUp removes the challenge resource.
+	CleanUp(ctx context.Context, ch *Challenge) error
+}
+
+// -----------------------------------------------------------------------------
+
+// ManualSolver displays the DNS records to create and waits for the operator
+// confirmation.
+type ManualSolver struct {
+	Out io.Writer
+	In  io.Reader
+
+	once   sync.Once
+	reader *bufio.Reader
+}
+
+// Type returns the handled challenge type.
+func (s *ManualSolver) Type() string { return ChallengeDNS01 }
+
+// Present displays the record to create and waits for confirmation.
+func (s *ManualSolver) Present(ctx context.Context, ch *Challenge) error {
+	// Check arguments
+	if s.Out == nil || s.In == nil {
+		return errors.New("unable to use manual solver without input and output")
+	}
+	s.once.Do(func() {
+		s.reader = bufio.NewReader(s.In)
+	})
+
+	fmt.Fprintf(s.Out, "Create the following DNS record for '%s':\n\n  %s. IN TXT %q\n\nPress Enter when the record is propagated...", ch.Domain, ch.Name, ch.Value)
+
+	// Wait for confirmation
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.reader.ReadString('\n')
+		done <- err
+	}()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-done:
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("unable to read confirmation: %w", err)
+		}
+	}
+
+	// No error
+	return nil
+}
+
+// CleanUp displays the record to remove.
+func (s *ManualSolver) CleanUp(_ context.Context, ch *Challenge) error {
+	if s.Out != nil {
+		fmt.Fprintf(s.Out, "The DNS record '%s.' can be removed.\n", ch.Name)
+	}
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+// HTTPSolver serves challenge responses on the given address. The listener is
+// started with the first challenge and stopped once all challenges are
+// cleaned.
+type HTTPSolver struct {
+	Address string
+
+	mu        sync.Mutex
+	responses map[string]string
+	server    *http.Server
+}
+
+// Type returns the handled challenge type.
+func (s *HTTPSolver) Type() string { return ChallengeHTTP01 }
+
+// Present registers the challenge response and starts the listener.
+func (s *HTTPSolver) Present(_ context.Context, ch *Challenge) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.responses == nil {
+		s.responses = map[string]string{}
+	}
+	s.responses[ch.Name] = ch.Value
+
+	// Already started
+	if s.server != nil {
+		return nil
+	}
+
+	ln, err := net.Listen("tcp", s.Address)
+	if err != nil {
+		return fmt.Errorf("unable to listen on '%s': %w", s.Address, err)
+	}
+	s.server = &http.Server{
+		Handler:           http.HandlerFunc(s.serveHTTP),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func(srv *http.Server) {
+		_ = srv.Serve(ln)
+	}(s.server)
+
+	// No error
+	return nil
+}
+
+// CleanUp unregisters the challenge response.
+func (s *HTTPSolver) CleanUp(ctx context.Context, ch *Challenge) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.responses, ch.Name)
+	if len(s.responses) > 0 || s.server == nil {
+		return nil
+	}
+
+	// Stop the listener
+	srv := s.server
+	s.server = nil
+	if err := srv.Shutdown(ctx); err != nil {
+		return fmt.Errorf("unable to stop challenge server: %w", err)
+	}
+
+	// No error
+	return nil
+}
+
+func (s *HTTPSolver) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	value, ok := s.responses[r.URL.Path]
+	s.mu.Unlock()
+
+	if !ok || r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	_, _ = io.WriteString(w, value)
+}
+
+// -----------------------------------------------------------------------------
+
+// recordName returns the dns-01 TXT record name of the given domain.
+func recordName(domain string) string {
+	return "_acme-challenge." + strings.TrimPrefix(strings.TrimSuffix(domain, "."), "*.")
+}
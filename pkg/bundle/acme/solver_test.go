@@ -0,0 +1,197 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package acme
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53/route53iface"
+	"github.com/google/go-cmp/cmp"
+)
+
+var (
+	_ Solver = (*ManualSolver)(nil)
+	_ Solver = (*HTTPSolver)(nil)
+	_ Solver = (*Route53Solver)(nil)
+)
+
+func TestRecordName(t *testing.T) {
+	testCases := []struct {
+		domain string
+		want   string
+	}{
+		{domain: "example.com", want: "_acme-challenge.example.com"},
+		{domain: "example.com.", want: "_acme-challenge.example.com"},
+		{domain: "*.example.com", want: "_acme-challenge.example.com"},
+	}
+	for _, tC := range testCases {
+		if got := recordName(tC.domain); got != tC.want {
+			t.Errorf("recordName(%q) = %q, want %q", tC.domain, got, tC.want)
+		}
+	}
+}
+
+func TestManualSolver(t *testing.T) {
+	var out bytes.Buffer
+	s := &ManualSolver{Out: &out, In: strings.NewReader("\n")}
+	ch := &Challenge{Type: ChallengeDNS01, Domain: "example.com", Name: "_acme-challenge.example.com", Value: "abc"}
+
+	if err := s.Present(context.Background(), ch); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), `_acme-challenge.example.com. IN TXT "abc"`) {
+		t.Errorf("record should be displayed, got %q", out.String())
+	}
+	if err := s.CleanUp(context.Background(), ch); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Cancelled confirmation
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s = &ManualSolver{Out: ioutil.Discard, In: pr}
+	if err := s.Present(ctx, ch); !errors.Is(err, context.Canceled) {
+		t.Errorf("context cancellation should be raised, got %v", err)
+	}
+}
+
+func TestHTTPSolver(t *testing.T) {
+	// Reserve a free port
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	s := &HTTPSolver{Address: addr}
+	ch := &Challenge{Type: ChallengeHTTP01, Domain: "example.com", Name: "/.well-known/acme-challenge/token", Value: "token.thumbprint"}
+	if err := s.Present(context.Background(), ch); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get("http://" + addr + path)
+		if err != nil {
+			t.Fatalf("unable to query challenge server: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+	if code, body := get(ch.Name); code != http.StatusOK || body != ch.Value {
+		t.Errorf("unexpected challenge response %d %q", code, body)
+	}
+	if code, _ := get("/.well-known/acme-challenge/unknown"); code != http.StatusNotFound {
+		t.Errorf("unknown token should not be found, got %d", code)
+	}
+
+	// Listener is stopped with the last challenge
+	if err := s.CleanUp(context.Background(), ch); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := http.Get("http://" + addr + ch.Name); err == nil {
+		t.Error("challenge server should be stopped")
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+type fakeRoute53 struct {
+	route53iface.Route53API
+
+	zones   []*route53.HostedZone
+	changes []string
+	waited  int
+}
+
+func (f *fakeRoute53) ListHostedZonesPagesWithContext(_ aws.Context, _ *route53.ListHostedZonesInput, fn func(*route53.ListHostedZonesOutput, bool) bool, _ ...request.Option) error {
+	fn(&route53.ListHostedZonesOutput{HostedZones: f.zones}, true)
+	return nil
+}
+
+func (f *fakeRoute53) ChangeResourceRecordSetsWithContext(_ aws.Context, in *route53.ChangeResourceRecordSetsInput, _ ...request.Option) (*route53.ChangeResourceRecordSetsOutput, error) {
+	for _, c := range in.ChangeBatch.Changes {
+		rrs := c.ResourceRecordSet
+		f.changes = append(f.changes, strings.Join([]string{
+			aws.StringValue(in.HostedZoneId),
+			aws.StringValue(c.Action),
+			aws.StringValue(rrs.Name),
+			aws.StringValue(rrs.Type),
+			aws.StringValue(rrs.ResourceRecords[0].Value),
+		}, " "))
+	}
+	return &route53.ChangeResourceRecordSetsOutput{
+		ChangeInfo: &route53.ChangeInfo{Id: aws.String("/change/1")},
+	}, nil
+}
+
+func (f *fakeRoute53) WaitUntilResourceRecordSetsChangedWithContext(_ aws.Context, _ *route53.GetChangeInput, _ ...request.WaiterOption) error {
+	f.waited++
+	return nil
+}
+
+func TestRoute53Solver(t *testing.T) {
+	client := &fakeRoute53{
+		zones: []*route53.HostedZone{
+			{Id: aws.String("/hostedzone/Z1"), Name: aws.String("example.com.")},
+			{Id: aws.String("/hostedzone/Z2"), Name: aws.String("dev.example.com.")},
+			{Id: aws.String("/hostedzone/Z3"), Name: aws.String("app.dev.example.com."), Config: &route53.HostedZoneConfig{PrivateZone: aws.Bool(true)}},
+			{Id: aws.String("/hostedzone/Z4"), Name: aws.String("ample.com.")},
+		},
+	}
+	s := &Route53Solver{Client: client}
+	ch := &Challenge{Type: ChallengeDNS01, Domain: "app.dev.example.com", Name: "_acme-challenge.app.dev.example.com", Value: "abc"}
+
+	if err := s.Present(context.Background(), ch); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.CleanUp(context.Background(), ch); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{
+		`Z2 UPSERT _acme-challenge.app.dev.example.com. TXT "abc"`,
+		`Z2 DELETE _acme-challenge.app.dev.example.com. TXT "abc"`,
+	}
+	if diff := cmp.Diff(want, client.changes); diff != "" {
+		t.Errorf("unexpected changes\n-want/+got\ndiff %s", diff)
+	}
+	if client.waited != 1 {
+		t.Errorf("record creation should be awaited once, got %d", client.waited)
+	}
+
+	// Unknown zone
+	ch.Name = "_acme-challenge.example.org"
+	if err := s.Present(context.Background(), ch); err == nil {
+		t.Error("missing hosted zone should raise an error")
+	}
+}
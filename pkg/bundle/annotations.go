@@ -0,0 +1,105 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrLegacyAnnotationAlreadyRegistered is raised when a legacy annotation
+// name is registered twice.
+var ErrLegacyAnnotationAlreadyRegistered = errors.New("bundle: legacy annotation already registered")
+
+var (
+	legacyAnnotationsMu sync.RWMutex
+	legacyAnnotations   = map[string]string{}
+)
+
+// Annotation names used by secret-service, the harp predecessor.
+var secretServiceAnnotations = []string{
+	"bundleImportDate",
+	"vaultPathPrefix",
+	"vaultBackendPath",
+	"bundleName",
+	"bundleEncryptionKey",
+	"packageFileName",
+	"packageEncryptionKey",
+	"importFilePath",
+	"contentKeyRef",
+}
+
+func init() {
+	for _, name := range secretServiceAnnotations {
+		// Both domains have been used by secret-service releases
+		MustRegisterLegacyAnnotation("secret-service.elstc.co/"+name, LegacyAnnotationPrefix+name)
+		MustRegisterLegacyAnnotation("secret-service.elstc.io/"+name, LegacyAnnotationPrefix+name)
+	}
+}
+
+// RegisterLegacyAnnotation registers the current name of a legacy annotation
+// or label used during bundle migration.
+func RegisterLegacyAnnotation(legacy, current string) error {
+	legacyAnnotationsMu.Lock()
+	defer legacyAnnotationsMu.Unlock()
+
+	// Check arguments
+	if legacy == "" || current == "" {
+		return fmt.Errorf("legacy and current annotation names must not be blank")
+	}
+	if _, ok := legacyAnnotations[legacy]; ok {
+		return fmt.Errorf("unable to register '%s': %w", legacy, ErrLegacyAnnotationAlreadyRegistered)
+	}
+	legacyAnnotations[legacy] = current
+
+	// No error
+	return nil
+}
+
+// MustRegisterLegacyAnnotation registers a legacy annotation and panics on
+// error.
+func MustRegisterLegacyAnnotation(legacy, current string) {
+	if err := RegisterLegacyAnnotation(legacy, current); err != nil {
+		panic(err)
+	}
+}
+
+// LegacyAnnotations returns sorted registered legacy annotation names.
+func LegacyAnnotations() []string {
+	legacyAnnotationsMu.RLock()
+	defer legacyAnnotationsMu.RUnlock()
+
+	names := make([]string, 0, len(legacyAnnotations))
+	for name := range legacyAnnotations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// currentAnnotation returns the current name of the given legacy annotation.
+func currentAnnotation(name string) (string, bool) {
+	legacyAnnotationsMu.RLock()
+	defer legacyAnnotationsMu.RUnlock()
+
+	current, ok := legacyAnnotations[name]
+	return current, ok
+}
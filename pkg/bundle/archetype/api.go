@@ -0,0 +1,138 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package archetype provides package archetypes describing the expected shape
+// of common secrets (database, api-key, tls-cert, ...).
+package archetype
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/elastic/harp/pkg/sdk/convert"
+	"github.com/elastic/harp/pkg/sdk/types"
+)
+
+const (
+	// PackAPIVersion is the supported archetype pack api version.
+	PackAPIVersion = "harp.elastic.co/v1"
+	// PackKind is the supported archetype pack kind.
+	PackKind = "ArchetypePack"
+	// Annotation records the archetype used to scaffold a package.
+	Annotation = "harp.elastic.co/v1/package#archetype"
+)
+
+// ErrNotFound is raised when the requested archetype is not registered.
+var ErrNotFound = errors.New("archetype not found")
+
+var nameRegexp = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Pack describes an archetype collection used to override built-in ones.
+type Pack struct {
+	APIVersion string   `json:"apiVersion"`
+	Kind       string   `json:"kind"`
+	Spec       PackSpec `json:"spec"`
+}
+
+// PackSpec describes archetype pack content.
+type PackSpec struct {
+	Archetypes []*Archetype `json:"archetypes"`
+}
+
+// Archetype describes a package shape.
+type Archetype struct {
+	// Name is the archetype identifier (ex: database).
+	Name string `json:"name"`
+	// Description is a human readable summary.
+	Description string `json:"description,omitempty"`
+	// Template is rendered as a JSON object holding package secrets.
+	Template string `json:"template"`
+	// Schema describes the required package keys.
+	Schema *Schema `json:"schema"`
+}
+
+// Validate the archetype definition.
+func (a *Archetype) Validate() error {
+	// Check arguments
+	if a == nil {
+		return errors.New("unable to validate nil archetype")
+	}
+
+	if !nameRegexp.MatchString(a.Name) {
+		return fmt.Errorf("invalid archetype name '%s'", a.Name)
+	}
+	if a.Template == "" {
+		return fmt.Errorf("archetype '%s' must have a template", a.Name)
+	}
+	if a.Schema == nil {
+		return fmt.Errorf("archetype '%s' must have a schema", a.Name)
+	}
+	if err := a.Schema.compile(); err != nil {
+		return fmt.Errorf("archetype '%s' has an invalid schema: %w", a.Name, err)
+	}
+
+	// No error
+	return nil
+}
+
+// ParsePack reads a YAML or JSON archetype pack from the given reader.
+func ParsePack(r io.Reader) (*Pack, error) {
+	// Check arguments
+	if types.IsNil(r) {
+		return nil, fmt.Errorf("reader is nil")
+	}
+
+	// Convert to JSON
+	jsonReader, err := convert.YAMLtoJSON(r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse input as ArchetypePack: %w", err)
+	}
+
+	// Decode pack
+	var p Pack
+	dec := json.NewDecoder(jsonReader)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("unable to decode archetype pack: %w", err)
+	}
+
+	// Check pack header
+	if p.APIVersion != PackAPIVersion {
+		return nil, fmt.Errorf("unsupported archetype pack api version '%s'", p.APIVersion)
+	}
+	if p.Kind != PackKind {
+		return nil, fmt.Errorf("unsupported archetype pack kind '%s'", p.Kind)
+	}
+
+	// Validate archetypes
+	seen := map[string]struct{}{}
+	for i, a := range p.Spec.Archetypes {
+		if err := a.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid archetype #%d: %w", i, err)
+		}
+		if _, ok := seen[a.Name]; ok {
+			return nil, fmt.Errorf("duplicate archetype '%s'", a.Name)
+		}
+		seen[a.Name] = struct{}{}
+	}
+
+	// No error
+	return &p, nil
+}
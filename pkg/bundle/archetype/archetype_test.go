@@ -0,0 +1,269 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package archetype
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/template/engine"
+)
+
+func TestBuiltin_Render(t *testing.T) {
+	values := map[string]engine.Values{
+		"api-key":      {"endpoint": "https://api.example.com"},
+		"oauth-client": {"issuer": "https://login.example.com"},
+	}
+
+	r := Builtin()
+	expected := []string{"api-key", "cache", "database", "oauth-client", "ssh-keypair", "tls-cert"}
+	if got := strings.Join(r.Names(), ","); got != strings.Join(expected, ",") {
+		t.Fatalf("unexpected built-in archetypes %q", got)
+	}
+
+	for _, a := range r.List() {
+		a := a
+		t.Run(a.Name, func(t *testing.T) {
+			p, err := a.Render(engine.NewContext(engine.WithValues(values[a.Name])), "/app/production/security/harp/v1.0.0/server/"+a.Name)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if p.Name != "app/production/security/harp/v1.0.0/server/"+a.Name {
+				t.Errorf("unexpected package name %q", p.Name)
+			}
+			if got := p.Annotations[Annotation]; got != a.Name {
+				t.Errorf("unexpected archetype annotation %q", got)
+			}
+
+			// Packaged secrets validate by construction
+			secrets, err := bundle.AsSecretMap(p)
+			if err != nil {
+				t.Fatalf("unable to unpack secrets: %v", err)
+			}
+			if err := a.Check(secrets); err != nil {
+				t.Errorf("scaffolded package must match its schema: %v", err)
+			}
+		})
+	}
+}
+
+func TestBuiltin_RenderValues(t *testing.T) {
+	a, err := Builtin().Get("database")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	p, err := a.Render(engine.NewContext(engine.WithValues(engine.Values{
+		"engine": "mysql",
+		"host":   "db.internal",
+		"port":   3306,
+	})), "app/production/billing/orders/database")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	secrets, err := bundle.AsSecretMap(p)
+	if err != nil {
+		t.Fatalf("unable to unpack secrets: %v", err)
+	}
+	if secrets["engine"] != "mysql" || secrets["host"] != "db.internal" || secrets["username"] != "database" {
+		t.Errorf("unexpected secrets %v", secrets)
+	}
+	if p.Secrets.Annotations["harp.elastic.co/v1/generator#provenance"] == "" {
+		t.Error("generator provenance must be recorded")
+	}
+}
+
+func TestRender_SchemaViolation(t *testing.T) {
+	r := Builtin()
+
+	// Missing required value
+	a, _ := r.Get("oauth-client")
+	_, err := a.Render(engine.NewContext(), "app/production/security/harp/v1.0.0/server/oauth")
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	if len(verr.Violations) != 1 || verr.Violations[0].Key != "issuer" {
+		t.Errorf("unexpected violations %v", verr.Violations)
+	}
+
+	// Invalid value
+	a, _ = r.Get("database")
+	_, err = a.Render(engine.NewContext(engine.WithValues(engine.Values{"engine": "mongodb", "port": "http"})), "app/db")
+	if !errors.As(err, &verr) || len(verr.Violations) != 2 {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestRegistry_Override(t *testing.T) {
+	p, err := ParsePack(strings.NewReader(`
+apiVersion: harp.elastic.co/v1
+kind: ArchetypePack
+spec:
+  archetypes:
+    - name: database
+      template: '{"dsn": "postgres://{{ .Data.name }}"}'
+      schema:
+        type: object
+        required: [dsn]
+        additionalProperties: false
+        properties:
+          dsn:
+            type: string
+            pattern: "^postgres://"
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r := Builtin()
+	if err := r.Override(p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	a, err := r.Get("database")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := a.Check(map[string]interface{}{"dsn": "postgres://db", "password": "x"}); err == nil || !strings.Contains(err.Error(), "password: unexpected key") {
+		t.Errorf("organization schema must be used, got %v", err)
+	}
+
+	// Built-in registry is left untouched
+	if a, _ := Builtin().Get("database"); a == p.Spec.Archetypes[0] {
+		t.Error("built-in archetypes must not be overridden globally")
+	}
+
+	// Unknown archetype
+	if _, err := r.Get("unknown"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestParsePack_Invalid(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		content string
+		want    string
+	}{
+		{
+			desc:    "invalid kind",
+			content: "apiVersion: harp.elastic.co/v1\nkind: Foo\n",
+			want:    "unsupported archetype pack kind 'Foo'",
+		},
+		{
+			desc:    "invalid name",
+			content: "apiVersion: harp.elastic.co/v1\nkind: ArchetypePack\nspec:\n  archetypes:\n    - name: Database\n      template: '{}'\n      schema: {}\n",
+			want:    "invalid archetype name 'Database'",
+		},
+		{
+			desc:    "missing schema",
+			content: "apiVersion: harp.elastic.co/v1\nkind: ArchetypePack\nspec:\n  archetypes:\n    - name: db\n      template: '{}'\n",
+			want:    "archetype 'db' must have a schema",
+		},
+		{
+			desc:    "invalid pattern",
+			content: "apiVersion: harp.elastic.co/v1\nkind: ArchetypePack\nspec:\n  archetypes:\n    - name: db\n      template: '{}'\n      schema:\n        properties:\n          a:\n            pattern: '('\n",
+			want:    "property 'a': invalid pattern",
+		},
+		{
+			desc:    "duplicate",
+			content: "apiVersion: harp.elastic.co/v1\nkind: ArchetypePack\nspec:\n  archetypes:\n    - name: db\n      template: '{}'\n      schema: {}\n    - name: db\n      template: '{}'\n      schema: {}\n",
+			want:    "duplicate archetype 'db'",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			_, err := ParsePack(strings.NewReader(tC.content))
+			if err == nil || !strings.Contains(err.Error(), tC.want) {
+				t.Errorf("expected error containing %q, got %v", tC.want, err)
+			}
+		})
+	}
+}
+
+func TestSchema_Validate(t *testing.T) {
+	min, max := 1.0, 10.0
+	s := &Schema{
+		Type:     "object",
+		Required: []string{"count"},
+		Properties: map[string]*Schema{
+			"count": {Type: "integer", Minimum: &min, Maximum: &max},
+			"ratio": {Type: "number"},
+			"tags":  {Type: "array"},
+			"mode":  {Enum: []interface{}{"a", "b"}},
+			"nested": {
+				Type:     "object",
+				Required: []string{"id"},
+			},
+		},
+	}
+	if err := s.compile(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		desc  string
+		value map[string]interface{}
+		want  []string
+	}{
+		{
+			desc:  "valid",
+			value: map[string]interface{}{"count": int64(2), "ratio": 0.5, "tags": []string{"x"}, "mode": "a", "nested": map[string]interface{}{"id": "1"}},
+		},
+		{
+			desc:  "integer as float",
+			value: map[string]interface{}{"count": 3.0},
+		},
+		{
+			desc:  "violations",
+			value: map[string]interface{}{"count": 1.5, "ratio": "half", "tags": "x", "mode": "c", "nested": map[string]interface{}{}},
+			want: []string{
+				"count: expected integer, got number",
+				"mode: value must be one of [a b]",
+				"nested.id: required key is missing",
+				"ratio: expected number, got string",
+				"tags: expected array, got string",
+			},
+		},
+		{
+			desc:  "out of range",
+			value: map[string]interface{}{"count": uint8(11)},
+			want:  []string{"count: value must be less than or equal to 10"},
+		},
+		{
+			desc:  "missing",
+			value: map[string]interface{}{},
+			want:  []string{"count: required key is missing"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			got := []string{}
+			for _, v := range s.Validate(tC.value) {
+				got = append(got, v.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tC.want, "\n") {
+				t.Errorf("unexpected violations\ngot:  %q\nwant: %q", got, tC.want)
+			}
+		})
+	}
+}
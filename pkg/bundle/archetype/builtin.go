@@ -0,0 +1,186 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package archetype
+
+import (
+	"strings"
+)
+
+// builtinPack holds built-in archetypes, values are provided by template
+// values (--set host=db.internal).
+var builtinPack = mustParsePack(`
+apiVersion: harp.elastic.co/v1
+kind: ArchetypePack
+spec:
+  archetypes:
+    - name: database
+      description: Relational database credentials
+      template: |
+        {
+          "engine": {{ default "postgresql" (get .Values "engine") | toJson }},
+          "host": {{ default "localhost" (get .Values "host") | toJson }},
+          "port": {{ default "5432" (get .Values "port") | toString | toJson }},
+          "database": {{ default .Data.name (get .Values "database") | toJson }},
+          "username": {{ default .Data.name (get .Values "username") | toJson }},
+          "password": {{ generate "harp.password" | toJson }}
+        }
+      schema:
+        type: object
+        required: [engine, host, port, database, username, password]
+        properties:
+          engine:
+            type: string
+            enum: [postgresql, mysql, mariadb, sqlserver, oracle]
+          host:
+            type: string
+            minLength: 1
+          port:
+            type: string
+            pattern: "^[0-9]{1,5}$"
+          database:
+            type: string
+            minLength: 1
+          username:
+            type: string
+            minLength: 1
+          password:
+            type: string
+            minLength: 16
+
+    - name: cache
+      description: Key/value cache server credentials
+      template: |
+        {
+          "engine": {{ default "redis" (get .Values "engine") | toJson }},
+          "host": {{ default "localhost" (get .Values "host") | toJson }},
+          "port": {{ default "6379" (get .Values "port") | toString | toJson }},
+          "password": {{ generate "harp.password" (dict "symbols" 0) | toJson }},
+          "tls": {{ default false (get .Values "tls") | toJson }}
+        }
+      schema:
+        type: object
+        required: [engine, host, port, password]
+        properties:
+          engine:
+            type: string
+            enum: [redis, memcached]
+          host:
+            type: string
+            minLength: 1
+          port:
+            type: string
+            pattern: "^[0-9]{1,5}$"
+          password:
+            type: string
+            minLength: 16
+          tls:
+            type: boolean
+
+    - name: api-key
+      description: Third-party service API key
+      template: |
+        {
+          {{- with (get .Values "endpoint") }}
+          "endpoint": {{ toJson . }},
+          {{- end }}
+          "key": {{ generate "harp.bytes" (dict "size" 32 "encoding" "hex") | toJson }}
+        }
+      schema:
+        type: object
+        required: [key]
+        properties:
+          endpoint:
+            type: string
+            pattern: "^https?://"
+          key:
+            type: string
+            minLength: 32
+
+    - name: oauth-client
+      description: OAuth2 / OIDC client credentials
+      template: |
+        {
+          {{- with (get .Values "issuer") }}
+          "issuer": {{ toJson . }},
+          {{- end }}
+          "client_id": {{ default .Data.name (get .Values "clientId") | toJson }},
+          "client_secret": {{ generate "harp.bytes" (dict "size" 32 "encoding" "base64url") | toJson }}
+        }
+      schema:
+        type: object
+        required: [issuer, client_id, client_secret]
+        properties:
+          issuer:
+            type: string
+            pattern: "^https://"
+          client_id:
+            type: string
+            minLength: 1
+          client_secret:
+            type: string
+            minLength: 32
+
+    - name: tls-cert
+      description: Self-signed TLS certificate and private key
+      template: |
+        {{- $cert := genSelfSignedCert (default "localhost" (get .Values "commonName")) nil nil (int (default 365 (get .Values "days"))) -}}
+        {
+          "tls.crt": {{ $cert.Cert | toJson }},
+          "tls.key": {{ $cert.Key | toJson }}
+        }
+      schema:
+        type: object
+        required: [tls.crt, tls.key]
+        properties:
+          tls.crt:
+            type: string
+            pattern: "^-----BEGIN CERTIFICATE-----"
+          tls.key:
+            type: string
+            pattern: "^-----BEGIN [A-Z ]*PRIVATE KEY-----"
+          ca.crt:
+            type: string
+            pattern: "^-----BEGIN CERTIFICATE-----"
+
+    - name: ssh-keypair
+      description: Ed25519 SSH key pair
+      template: |
+        {{- $pair := cryptoPair "ssh" -}}
+        {
+          "private_key": {{ toSSH $pair.Private | toJson }},
+          "public_key": {{ toSSH $pair.Public | trim | toJson }}
+        }
+      schema:
+        type: object
+        required: [private_key, public_key]
+        properties:
+          private_key:
+            type: string
+            pattern: "^-----BEGIN [A-Z ]*PRIVATE KEY-----"
+          public_key:
+            type: string
+            pattern: "^ssh-"
+`)
+
+func mustParsePack(content string) *Pack {
+	p, err := ParsePack(strings.NewReader(content))
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
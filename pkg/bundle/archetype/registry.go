@@ -0,0 +1,91 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package archetype
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Registry holds archetypes by name.
+type Registry struct {
+	archetypes map[string]*Archetype
+}
+
+// Builtin returns a registry initialized with built-in archetypes.
+func Builtin() *Registry {
+	r := &Registry{
+		archetypes: map[string]*Archetype{},
+	}
+	for _, a := range builtinPack.Spec.Archetypes {
+		r.archetypes[a.Name] = a
+	}
+
+	return r
+}
+
+// Override registers all pack archetypes, replacing built-in ones sharing
+// the same name.
+func (r *Registry) Override(p *Pack) error {
+	// Check arguments
+	if p == nil {
+		return fmt.Errorf("unable to override with nil pack")
+	}
+
+	for _, a := range p.Spec.Archetypes {
+		if err := a.Validate(); err != nil {
+			return err
+		}
+		r.archetypes[a.Name] = a
+	}
+
+	// No error
+	return nil
+}
+
+// Get returns the archetype matching the given name.
+func (r *Registry) Get(name string) (*Archetype, error) {
+	a, ok := r.archetypes[name]
+	if !ok {
+		return nil, fmt.Errorf("unable to resolve '%s', expected one of %s: %w", name, strings.Join(r.Names(), ", "), ErrNotFound)
+	}
+
+	return a, nil
+}
+
+// Names returns sorted registered archetype names.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.archetypes))
+	for name := range r.archetypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// List returns registered archetypes sorted by name.
+func (r *Registry) List() []*Archetype {
+	res := make([]*Archetype, 0, len(r.archetypes))
+	for _, name := range r.Names() {
+		res = append(res, r.archetypes[name])
+	}
+
+	return res
+}
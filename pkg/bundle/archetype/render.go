@@ -0,0 +1,120 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package archetype
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/sdk/types"
+	"github.com/elastic/harp/pkg/template/engine"
+	"github.com/elastic/harp/pkg/template/generators"
+)
+
+// Render the archetype as a package stored at the given secret path. Rendered
+// secrets are validated against the archetype schema.
+func (a *Archetype) Render(templateContext engine.Context, secretPath string) (*bundlev1.Package, error) {
+	// Check arguments
+	if types.IsNil(templateContext) {
+		return nil, errors.New("unable to process with nil context")
+	}
+	secretPath = strings.Trim(secretPath, "/")
+	if secretPath == "" {
+		return nil, errors.New("unable to process with blank secret path")
+	}
+
+	// Render secrets
+	rec := &generators.Recorder{}
+	payload, err := engine.RenderContextWithData(engine.Recording(templateContext, rec), a.Template, map[string]interface{}{
+		"path": secretPath,
+		"name": path.Base(secretPath),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to render '%s' archetype: %w", a.Name, err)
+	}
+
+	// Decode rendered secrets
+	kv := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(payload), &kv); err != nil {
+		return nil, fmt.Errorf("unable to decode rendered '%s' archetype as a JSON object: %w", a.Name, err)
+	}
+
+	// Validate secrets
+	if err := a.Check(kv); err != nil {
+		return nil, err
+	}
+
+	// Pack secrets
+	data, err := bundle.FromSecretMap(kv)
+	if err != nil {
+		return nil, fmt.Errorf("unable to pack '%s' archetype secrets: %w", a.Name, err)
+	}
+	sort.Slice(data, func(i, j int) bool {
+		return data[i].Key < data[j].Key
+	})
+
+	// Prepare package
+	p := &bundlev1.Package{
+		Name: secretPath,
+		Labels: map[string]string{
+			"generated": "true",
+		},
+		Annotations: map[string]string{
+			Annotation: a.Name,
+		},
+		Secrets: &bundlev1.SecretChain{
+			Version: uint32(0),
+			Labels: map[string]string{
+				"generated": "true",
+			},
+			Annotations: map[string]string{
+				"creationDate": fmt.Sprintf("%d", time.Now().UTC().Unix()),
+				"description":  a.Description,
+			},
+			Data: data,
+		},
+	}
+
+	// Add value generator provenance
+	if provenance := rec.Annotation(); provenance != "" {
+		p.Secrets.Annotations[generators.ProvenanceAnnotation] = provenance
+	}
+
+	// No error
+	return p, nil
+}
+
+// Check the given secrets against the archetype schema.
+func (a *Archetype) Check(secrets map[string]interface{}) error {
+	if violations := a.Schema.Validate(secrets); len(violations) > 0 {
+		return &ValidationError{
+			Archetype:  a.Name,
+			Violations: violations,
+		}
+	}
+
+	// No error
+	return nil
+}
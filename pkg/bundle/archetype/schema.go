@@ -0,0 +1,267 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package archetype
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Schema describes the supported JSON Schema subset used to validate package
+// secrets.
+type Schema struct {
+	Type                 string             `json:"type,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// Violation describes a schema validation failure.
+type Violation struct {
+	Key     string `json:"key,omitempty"`
+	Message string `json:"message"`
+}
+
+func (v Violation) String() string {
+	if v.Key == "" {
+		return v.Message
+	}
+	return fmt.Sprintf("%s: %s", v.Key, v.Message)
+}
+
+// ValidationError is raised when package secrets don't match the schema.
+type ValidationError struct {
+	Archetype  string
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.String()
+	}
+	return fmt.Sprintf("secrets don't match '%s' archetype schema: %s", e.Archetype, strings.Join(msgs, "; "))
+}
+
+// Validate the given value and returns all violations.
+func (s *Schema) Validate(value interface{}) []Violation {
+	violations := []Violation{}
+	s.validate("", value, &violations)
+	return violations
+}
+
+// -----------------------------------------------------------------------------
+
+var schemaTypes = map[string]struct{}{
+	"":        {},
+	"object":  {},
+	"string":  {},
+	"integer": {},
+	"number":  {},
+	"boolean": {},
+	"array":   {},
+}
+
+func (s *Schema) compile() error {
+	if _, ok := schemaTypes[s.Type]; !ok {
+		return fmt.Errorf("unsupported type '%s'", s.Type)
+	}
+
+	if s.Pattern != "" {
+		p, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern '%s': %w", s.Pattern, err)
+		}
+		s.pattern = p
+	}
+
+	for name, prop := range s.Properties {
+		if prop == nil {
+			return fmt.Errorf("property '%s' must not be null", name)
+		}
+		if err := prop.compile(); err != nil {
+			return fmt.Errorf("property '%s': %w", name, err)
+		}
+	}
+
+	// No error
+	return nil
+}
+
+func (s *Schema) validate(key string, value interface{}, violations *[]Violation) {
+	add := func(format string, args ...interface{}) {
+		*violations = append(*violations, Violation{Key: key, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// Check type
+	if s.Type != "" && !matchType(s.Type, value) {
+		add("expected %s, got %s", s.Type, typeName(value))
+		return
+	}
+
+	// Check enumeration
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if fmt.Sprint(e) == fmt.Sprint(value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			add("value must be one of %v", s.Enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		if s.MinLength != nil && len(v) < *s.MinLength {
+			add("length must be at least %d", *s.MinLength)
+		}
+		if s.MaxLength != nil && len(v) > *s.MaxLength {
+			add("length must be at most %d", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			add("value must match '%s'", s.Pattern)
+		}
+	case map[string]interface{}:
+		s.validateObject(key, v, violations)
+	default:
+		if f, ok := toFloat(value); ok {
+			if s.Minimum != nil && f < *s.Minimum {
+				add("value must be greater than or equal to %v", *s.Minimum)
+			}
+			if s.Maximum != nil && f > *s.Maximum {
+				add("value must be less than or equal to %v", *s.Maximum)
+			}
+		}
+	}
+}
+
+func (s *Schema) validateObject(key string, obj map[string]interface{}, violations *[]Violation) {
+	child := func(name string) string {
+		if key == "" {
+			return name
+		}
+		return key + "." + name
+	}
+
+	// Required properties
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			*violations = append(*violations, Violation{Key: child(name), Message: "required key is missing"})
+		}
+	}
+
+	// Sort keys for stable reports
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		prop, ok := s.Properties[name]
+		if !ok {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				*violations = append(*violations, Violation{Key: child(name), Message: "unexpected key"})
+			}
+			continue
+		}
+		prop.validate(child(name), obj[name], violations)
+	}
+}
+
+func matchType(typ string, value interface{}) bool {
+	switch typ {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "array":
+		if value == nil {
+			return false
+		}
+		k := reflect.TypeOf(value).Kind()
+		return k == reflect.Slice || k == reflect.Array
+	case "number":
+		_, ok := toFloat(value)
+		return ok
+	case "integer":
+		f, ok := toFloat(value)
+		return ok && f == math.Trunc(f)
+	default:
+	}
+
+	return true
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	if value == nil {
+		return 0, false
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	default:
+	}
+
+	return 0, false
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	default:
+	}
+	if _, ok := toFloat(value); ok {
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,131 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+)
+
+// ArchivedAnnotation marks an archived package. The value is the archival
+// timestamp. Archived packages are kept in the container but hidden from
+// default consumers.
+const ArchivedAnnotation = "harp.elastic.co/v1/package#archived"
+
+var (
+	// ErrPackageArchived is raised when trying to mutate an archived package.
+	ErrPackageArchived = errors.New("bundle: package is archived")
+	// ErrPackageNotArchived is raised when trying to restore an active package.
+	ErrPackageNotArchived = errors.New("bundle: package is not archived")
+)
+
+// IsArchived returns true if the given package is archived.
+func IsArchived(p *bundlev1.Package) bool {
+	if p == nil {
+		return false
+	}
+	_, ok := p.Annotations[ArchivedAnnotation]
+	return ok
+}
+
+// CheckMutable returns an error if the given package is archived.
+func CheckMutable(p *bundlev1.Package) error {
+	if IsArchived(p) {
+		return fmt.Errorf("package '%s' must be unarchived first: %w", p.Name, ErrPackageArchived)
+	}
+	return nil
+}
+
+// Archive marks the package matching the given path as archived.
+func Archive(b *bundlev1.Bundle, path string, now time.Time) error {
+	p, err := lookup(b, path)
+	if err != nil {
+		return err
+	}
+	if IsArchived(p) {
+		return fmt.Errorf("unable to archive '%s': %w", path, ErrPackageArchived)
+	}
+
+	Annotate(p, ArchivedAnnotation, now.UTC().Format(time.RFC3339))
+
+	// No error
+	return nil
+}
+
+// Unarchive restores the archived package matching the given path.
+func Unarchive(b *bundlev1.Bundle, path string) error {
+	p, err := lookup(b, path)
+	if err != nil {
+		return err
+	}
+	if !IsArchived(p) {
+		return fmt.Errorf("unable to unarchive '%s': %w", path, ErrPackageNotArchived)
+	}
+
+	delete(p.Annotations, ArchivedAnnotation)
+
+	// No error
+	return nil
+}
+
+// WithoutArchived returns a bundle view without archived packages. Packages
+// are shared with the given bundle.
+func WithoutArchived(b *bundlev1.Bundle) *bundlev1.Bundle {
+	if b == nil {
+		return nil
+	}
+
+	res := &bundlev1.Bundle{
+		Labels:      b.Labels,
+		Annotations: b.Annotations,
+		Version:     b.Version,
+		Template:    b.Template,
+		Values:      b.Values,
+		Packages:    []*bundlev1.Package{},
+	}
+	for _, p := range b.Packages {
+		if IsArchived(p) {
+			continue
+		}
+		res.Packages = append(res.Packages, p)
+	}
+
+	return res
+}
+
+// -----------------------------------------------------------------------------
+
+func lookup(b *bundlev1.Bundle, path string) (*bundlev1.Package, error) {
+	// Check arguments
+	if b == nil {
+		return nil, fmt.Errorf("unable to process nil bundle")
+	}
+
+	path = strings.TrimPrefix(path, "/")
+	for _, p := range b.Packages {
+		if p != nil && p.Name == path {
+			return p, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unable to retrieve '%s': %w", path, ErrPackageNotFound)
+}
@@ -0,0 +1,122 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+)
+
+func archiveFixture() *bundlev1.Bundle {
+	return &bundlev1.Bundle{
+		Packages: []*bundlev1.Package{
+			{Name: "app/production/security/harp/v1.0.0/server/database", Secrets: &bundlev1.SecretChain{}},
+			{Name: "app/production/security/harp/v1.0.0/server/legacy", Secrets: &bundlev1.SecretChain{}},
+		},
+	}
+}
+
+func TestArchive(t *testing.T) {
+	b := archiveFixture()
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Archive
+	if err := Archive(b, "/app/production/security/harp/v1.0.0/server/legacy", now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !IsArchived(b.Packages[1]) {
+		t.Fatal("package must be archived")
+	}
+	if got := b.Packages[1].Annotations[ArchivedAnnotation]; got != "2021-01-01T00:00:00Z" {
+		t.Errorf("unexpected archival timestamp %q", got)
+	}
+
+	// Archived package is hidden, container still holds it
+	view := WithoutArchived(b)
+	if len(view.Packages) != 1 || view.Packages[0].Name != "app/production/security/harp/v1.0.0/server/database" {
+		t.Errorf("archived package must be hidden, got %v", view.Packages)
+	}
+	if len(b.Packages) != 2 {
+		t.Errorf("archived package must be kept")
+	}
+
+	// Archive twice
+	if err := Archive(b, "app/production/security/harp/v1.0.0/server/legacy", now); !errors.Is(err, ErrPackageArchived) {
+		t.Errorf("expected ErrPackageArchived, got %v", err)
+	}
+	if err := CheckMutable(b.Packages[1]); !errors.Is(err, ErrPackageArchived) {
+		t.Errorf("expected ErrPackageArchived, got %v", err)
+	}
+
+	// Restore
+	if err := Unarchive(b, "app/production/security/harp/v1.0.0/server/legacy"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if IsArchived(b.Packages[1]) {
+		t.Error("package must be restored")
+	}
+	if len(WithoutArchived(b).Packages) != 2 {
+		t.Error("restored package must be visible")
+	}
+
+	// Restore twice
+	if err := Unarchive(b, "app/production/security/harp/v1.0.0/server/legacy"); !errors.Is(err, ErrPackageNotArchived) {
+		t.Errorf("expected ErrPackageNotArchived, got %v", err)
+	}
+
+	// Unknown package
+	if err := Archive(b, "app/production/security/harp/v1.0.0/server/missing", now); !errors.Is(err, ErrPackageNotFound) {
+		t.Errorf("expected ErrPackageNotFound, got %v", err)
+	}
+	if err := Unarchive(nil, "app"); err == nil {
+		t.Error("error should be raised for nil bundle")
+	}
+}
+
+func Test_Merge_Archived(t *testing.T) {
+	archived := func() *bundlev1.Bundle {
+		b := mustFromMap(t, map[string]KV{
+			"app/production/a": {"user": "admin"},
+		})
+		Annotate(b.Packages[0], ArchivedAnnotation, "2021-01-01T00:00:00Z")
+		return b
+	}
+	active := func() *bundlev1.Bundle {
+		return mustFromMap(t, map[string]KV{
+			"app/production/a": {"password": "foo"},
+		})
+	}
+
+	// Archived source packages are skipped
+	dst := &bundlev1.Bundle{}
+	report, err := Merge(dst, archived(), MergeStrategyOverwrite)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(dst.Packages) != 0 || len(report.Skipped) != 1 || report.Skipped[0].Reason != "archived" {
+		t.Errorf("archived source package must be skipped, got %+v", report)
+	}
+
+	// Archived destination packages are immutable
+	if _, err := Merge(archived(), active(), MergeStrategyOverwrite); !errors.Is(err, ErrPackageArchived) {
+		t.Errorf("expected ErrPackageArchived, got %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+)
+
+// VersionCreatedAnnotation sets the creation timestamp (RFC3339) of a
+// secret version.
+const VersionCreatedAnnotation = "harp.elastic.co/v1/secret#created"
+
+// AsOf returns a bundle representing the state at the given time. For each
+// package, the newest secret version created at or before the cutoff is
+// selected, packages without such version are dropped.
+//
+// Versions without creation timestamp are assumed created right after the
+// previous timestamped version (by version number), or before any cutoff if
+// none; the names of packages using this ordinal fallback are returned.
+func AsOf(b *bundlev1.Bundle, cutoff time.Time) (*bundlev1.Bundle, []string, error) {
+	// Check arguments
+	if b == nil {
+		return nil, nil, fmt.Errorf("unable to process nil bundle")
+	}
+
+	res := &bundlev1.Bundle{
+		Labels:      b.Labels,
+		Annotations: b.Annotations,
+		Version:     b.Version,
+		Template:    b.Template,
+		Values:      b.Values,
+		Packages:    []*bundlev1.Package{},
+	}
+	fallbacks := []string{}
+
+	for _, p := range b.Packages {
+		if p == nil {
+			continue
+		}
+
+		chains, ordinal, err := versionHistory(p)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ordinal {
+			fallbacks = append(fallbacks, p.Name)
+		}
+
+		// Select the newest version before the cutoff
+		selected := -1
+		for i, c := range chains {
+			if c.created.After(cutoff) {
+				break
+			}
+			selected = i
+		}
+		if selected < 0 {
+			continue
+		}
+
+		// Rebuild historical package
+		hp := &bundlev1.Package{
+			Labels:      p.Labels,
+			Annotations: p.Annotations,
+			Name:        p.Name,
+			Secrets:     chains[selected].chain,
+			Versions:    map[uint32]*bundlev1.SecretChain{},
+		}
+		for _, c := range chains[:selected] {
+			hp.Versions[c.chain.Version] = c.chain
+		}
+
+		// Detach from newer versions
+		if hp.Secrets.NextVersion != nil {
+			current, ok := proto.Clone(hp.Secrets).(*bundlev1.SecretChain)
+			if !ok {
+				return nil, nil, fmt.Errorf("unable to copy secret chain of '%s'", p.Name)
+			}
+			current.NextVersion = nil
+			hp.Secrets = current
+		}
+
+		res.Packages = append(res.Packages, hp)
+	}
+
+	// No error
+	return res, fallbacks, nil
+}
+
+// -----------------------------------------------------------------------------
+
+type datedChain struct {
+	chain   *bundlev1.SecretChain
+	created time.Time
+}
+
+// versionHistory returns package versions in version number order with their
+// effective creation time.
+func versionHistory(p *bundlev1.Package) ([]datedChain, bool, error) {
+	// Collect versions, the current one has precedence
+	byVersion := map[uint32]*bundlev1.SecretChain{}
+	for v, c := range p.Versions {
+		if c != nil {
+			byVersion[v] = c
+		}
+	}
+	if p.Secrets != nil {
+		byVersion[p.Secrets.Version] = p.Secrets
+	}
+
+	versions := make([]uint32, 0, len(byVersion))
+	for v := range byVersion {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+
+	res := make([]datedChain, 0, len(versions))
+	ordinal := false
+	previous := time.Time{}
+	for _, v := range versions {
+		c := byVersion[v]
+
+		created := previous
+		raw, ok := c.Annotations[VersionCreatedAnnotation]
+		if ok {
+			ts, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				return nil, false, fmt.Errorf("invalid creation timestamp for version %d of '%s': %w", v, p.Name, err)
+			}
+			created = ts
+		} else {
+			ordinal = true
+		}
+
+		// Timestamps can't go backward in version order
+		if created.Before(previous) {
+			created = previous
+		}
+		previous = created
+
+		res = append(res, datedChain{chain: c, created: created})
+	}
+
+	return res, ordinal, nil
+}
@@ -0,0 +1,174 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+)
+
+func chain(version uint32, created string) *bundlev1.SecretChain {
+	c := &bundlev1.SecretChain{
+		Annotations: map[string]string{},
+		Version:     version,
+	}
+	if created != "" {
+		c.Annotations[VersionCreatedAnnotation] = created
+	}
+	if version > 0 {
+		c.PreviousVersion = wrapperspb.UInt32(version - 1)
+	}
+	return c
+}
+
+func versioned(name string, chains ...*bundlev1.SecretChain) *bundlev1.Package {
+	p := &bundlev1.Package{
+		Name:     name,
+		Versions: map[uint32]*bundlev1.SecretChain{},
+	}
+	for i, c := range chains {
+		if i < len(chains)-1 {
+			c.NextVersion = wrapperspb.UInt32(c.Version + 1)
+			p.Versions[c.Version] = c
+			continue
+		}
+		p.Secrets = c
+	}
+	return p
+}
+
+func TestAsOf(t *testing.T) {
+	b := &bundlev1.Bundle{
+		Packages: []*bundlev1.Package{
+			versioned("app/database",
+				chain(0, "2021-01-01T00:00:00Z"),
+				chain(1, "2021-03-01T00:00:00Z"),
+				chain(2, "2021-06-01T00:00:00Z"),
+			),
+			versioned("app/new",
+				chain(0, "2021-05-01T00:00:00Z"),
+			),
+			versioned("app/legacy",
+				chain(0, ""),
+				chain(1, "2021-02-01T00:00:00Z"),
+				chain(2, ""),
+			),
+		},
+	}
+
+	testCases := []struct {
+		desc          string
+		cutoff        string
+		want          map[string]uint32
+		wantHistory   map[string][]uint32
+		wantFallbacks []string
+	}{
+		{
+			desc:          "before first version",
+			cutoff:        "2020-12-31T23:59:59Z",
+			want:          map[string]uint32{"app/legacy": 0},
+			wantHistory:   map[string][]uint32{"app/legacy": {}},
+			wantFallbacks: []string{"app/legacy"},
+		},
+		{
+			desc:          "exactly equal timestamp",
+			cutoff:        "2021-03-01T00:00:00Z",
+			want:          map[string]uint32{"app/database": 1, "app/legacy": 2},
+			wantHistory:   map[string][]uint32{"app/database": {0}, "app/legacy": {0, 1}},
+			wantFallbacks: []string{"app/legacy"},
+		},
+		{
+			desc:          "between versions",
+			cutoff:        "2021-05-31T23:59:59Z",
+			want:          map[string]uint32{"app/database": 1, "app/new": 0, "app/legacy": 2},
+			wantHistory:   map[string][]uint32{"app/database": {0}, "app/new": {}, "app/legacy": {0, 1}},
+			wantFallbacks: []string{"app/legacy"},
+		},
+		{
+			desc:          "after last version",
+			cutoff:        "2022-01-01T00:00:00Z",
+			want:          map[string]uint32{"app/database": 2, "app/new": 0, "app/legacy": 2},
+			wantHistory:   map[string][]uint32{"app/database": {0, 1}, "app/new": {}, "app/legacy": {0, 1}},
+			wantFallbacks: []string{"app/legacy"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			cutoff, err := time.Parse(time.RFC3339, tC.cutoff)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res, fallbacks, err := AsOf(b, cutoff)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := map[string]uint32{}
+			gotHistory := map[string][]uint32{}
+			for _, p := range res.Packages {
+				got[p.Name] = p.Secrets.Version
+				if p.Secrets.NextVersion != nil {
+					t.Errorf("package '%s' must not reference a newer version", p.Name)
+				}
+				gotHistory[p.Name] = []uint32{}
+				for v := uint32(0); v < p.Secrets.Version; v++ {
+					if _, ok := p.Versions[v]; ok {
+						gotHistory[p.Name] = append(gotHistory[p.Name], v)
+					}
+				}
+				if len(p.Versions) != len(gotHistory[p.Name]) {
+					t.Errorf("package '%s' has newer versions in history", p.Name)
+				}
+			}
+			if diff := cmp.Diff(got, tC.want); diff != "" {
+				t.Errorf("%q. AsOf():\n-got/+want\ndiff %s", tC.desc, diff)
+			}
+			if diff := cmp.Diff(gotHistory, tC.wantHistory); diff != "" {
+				t.Errorf("%q. AsOf() history:\n-got/+want\ndiff %s", tC.desc, diff)
+			}
+			if diff := cmp.Diff(fallbacks, tC.wantFallbacks); diff != "" {
+				t.Errorf("%q. AsOf() fallbacks:\n-got/+want\ndiff %s", tC.desc, diff)
+			}
+		})
+	}
+
+	// Source bundle must not be modified
+	if b.Packages[0].Secrets.Version != 2 || b.Packages[0].Versions[1].NextVersion == nil {
+		t.Error("source bundle has been modified")
+	}
+}
+
+func TestAsOf_InvalidTimestamp(t *testing.T) {
+	b := &bundlev1.Bundle{
+		Packages: []*bundlev1.Package{
+			versioned("app/database", chain(0, "yesterday")),
+		},
+	}
+	if _, _, err := AsOf(b, time.Now()); err == nil {
+		t.Error("error expected")
+	}
+	if _, _, err := AsOf(nil, time.Now()); err == nil {
+		t.Error("error expected")
+	}
+}
@@ -0,0 +1,199 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package budget
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+)
+
+// ErrExceeded is raised when at least one budget is exceeded.
+var ErrExceeded = errors.New("bundle size budget exceeded")
+
+// Offender describes a package contributing to a budget usage.
+type Offender struct {
+	Name  string `json:"name"`
+	Bytes int64  `json:"bytes"`
+}
+
+// Usage describes the current usage of a budget.
+type Usage struct {
+	Prefix       string     `json:"prefix"`
+	Packages     int        `json:"packages"`
+	Bytes        int64      `json:"bytes"`
+	MaxPackages  int        `json:"maxPackages,omitempty"`
+	MaxBytes     int64      `json:"maxBytes,omitempty"`
+	Exceeded     bool       `json:"exceeded"`
+	TopOffenders []Offender `json:"topOffenders,omitempty"`
+}
+
+// Report describes budget evaluation result.
+type Report struct {
+	Budgets []Usage `json:"budgets"`
+	// Unbudgeted counts packages not attributed to any budget.
+	Unbudgeted int `json:"unbudgeted"`
+}
+
+// Exceeded returns true if at least one budget is exceeded.
+func (r *Report) Exceeded() bool {
+	for i := range r.Budgets {
+		if r.Budgets[i].Exceeded {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate computes budget usages of the given bundle.
+//
+// Each package is attributed to the budget with the longest matching prefix,
+// so that nested prefixes don't count the same package twice. Package size is
+// its packed (protobuf encoded) size.
+func Evaluate(b *bundlev1.Bundle, p *Policy) (*Report, error) {
+	// Check arguments
+	if b == nil {
+		return nil, fmt.Errorf("unable to evaluate a nil bundle")
+	}
+	if p == nil {
+		return nil, fmt.Errorf("unable to evaluate a nil policy")
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid budget policy: %w", err)
+	}
+
+	topCount := p.Spec.TopOffenders
+	if topCount == 0 {
+		topCount = DefaultTopOffenders
+	}
+
+	// Prepare usages
+	report := &Report{
+		Budgets: make([]Usage, len(p.Spec.Budgets)),
+	}
+	prefixes := make([]string, len(p.Spec.Budgets))
+	offenders := make([][]Offender, len(p.Spec.Budgets))
+	for i, budget := range p.Spec.Budgets {
+		prefixes[i] = normalizePrefix(budget.Prefix)
+		report.Budgets[i] = Usage{
+			Prefix:      budget.Prefix,
+			MaxPackages: budget.MaxPackages,
+			MaxBytes:    budget.MaxBytes,
+		}
+	}
+
+	// Attribute packages
+	for _, pkg := range b.Packages {
+		if pkg == nil {
+			continue
+		}
+
+		idx := attribute(prefixes, pkg.Name)
+		if idx < 0 {
+			report.Unbudgeted++
+			continue
+		}
+
+		size := int64(proto.Size(pkg))
+		report.Budgets[idx].Packages++
+		report.Budgets[idx].Bytes += size
+		offenders[idx] = append(offenders[idx], Offender{Name: pkg.Name, Bytes: size})
+	}
+
+	// Check limits
+	for i := range report.Budgets {
+		u := &report.Budgets[i]
+		u.Exceeded = (u.MaxPackages > 0 && u.Packages > u.MaxPackages) ||
+			(u.MaxBytes > 0 && u.Bytes > u.MaxBytes)
+
+		sort.SliceStable(offenders[i], func(a, b int) bool {
+			if offenders[i][a].Bytes != offenders[i][b].Bytes {
+				return offenders[i][a].Bytes > offenders[i][b].Bytes
+			}
+			return offenders[i][a].Name < offenders[i][b].Name
+		})
+		if len(offenders[i]) > topCount {
+			offenders[i] = offenders[i][:topCount]
+		}
+		u.TopOffenders = offenders[i]
+	}
+
+	// No error
+	return report, nil
+}
+
+// Enforce evaluates the policy against the given bundle and returns an error
+// wrapping ErrExceeded describing each exceeded budget.
+func Enforce(b *bundlev1.Bundle, p *Policy) error {
+	// Evaluate budgets
+	report, err := Evaluate(b, p)
+	if err != nil {
+		return err
+	}
+
+	// Collect exceeded budgets
+	violations := []string{}
+	for _, u := range report.Budgets {
+		if !u.Exceeded {
+			continue
+		}
+		violations = append(violations, fmt.Sprintf("'%s' (%s)", u.Prefix, u.describe()))
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("%w: %s", ErrExceeded, strings.Join(violations, ", "))
+	}
+
+	// No error
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (u *Usage) describe() string {
+	parts := []string{}
+	if u.MaxPackages > 0 {
+		parts = append(parts, fmt.Sprintf("packages %d/%d", u.Packages, u.MaxPackages))
+	}
+	if u.MaxBytes > 0 {
+		parts = append(parts, fmt.Sprintf("bytes %d/%d", u.Bytes, u.MaxBytes))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// attribute returns the index of the longest prefix matching the given
+// package name, or -1.
+func attribute(prefixes []string, name string) int {
+	name = strings.Trim(name, "/")
+
+	found, length := -1, -1
+	for i, prefix := range prefixes {
+		if prefix != "" && name != prefix && !strings.HasPrefix(name, prefix+"/") {
+			continue
+		}
+		if len(prefix) > length {
+			found, length = i, len(prefix)
+		}
+	}
+
+	return found
+}
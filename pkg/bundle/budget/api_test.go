@@ -0,0 +1,222 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package budget
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+)
+
+func testBundle() *bundlev1.Bundle {
+	return &bundlev1.Bundle{
+		Packages: []*bundlev1.Package{
+			{Name: "app/production/customer1/billing/database"},
+			{Name: "app/production/customer1/billing/cache"},
+			{Name: "app/production/customer2/billing/database-with-a-longer-name"},
+			{Name: "app/staging/customer1/billing/database"},
+			{Name: "infra/aws/account/iam/root"},
+			{Name: "app/productionx/leak"},
+		},
+	}
+}
+
+func packageSize(b *bundlev1.Bundle, names ...string) int64 {
+	var total int64
+	for _, p := range b.Packages {
+		for _, n := range names {
+			if p.Name == n {
+				total += int64(proto.Size(p))
+			}
+		}
+	}
+	return total
+}
+
+func TestEvaluate_NestedPrefixes(t *testing.T) {
+	b := testBundle()
+
+	report, err := Evaluate(b, &Policy{
+		Spec: PolicySpec{
+			TopOffenders: 1,
+			Budgets: []Budget{
+				{Prefix: "app", MaxPackages: 10},
+				{Prefix: "app/production", MaxPackages: 10},
+				{Prefix: "app/production/customer1/", MaxPackages: 10},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []struct {
+		packages int
+		bytes    int64
+		top      string
+	}{
+		{packages: 2, bytes: packageSize(b, "app/staging/customer1/billing/database", "app/productionx/leak"), top: "app/staging/customer1/billing/database"},
+		{packages: 1, bytes: packageSize(b, "app/production/customer2/billing/database-with-a-longer-name"), top: "app/production/customer2/billing/database-with-a-longer-name"},
+		{packages: 2, bytes: packageSize(b, "app/production/customer1/billing/database", "app/production/customer1/billing/cache"), top: "app/production/customer1/billing/database"},
+	}
+	for i, w := range want {
+		u := report.Budgets[i]
+		if u.Packages != w.packages || u.Bytes != w.bytes {
+			t.Errorf("%s: expected %d packages / %d bytes, got %d / %d", u.Prefix, w.packages, w.bytes, u.Packages, u.Bytes)
+		}
+		if len(u.TopOffenders) != 1 || u.TopOffenders[0].Name != w.top {
+			t.Errorf("%s: unexpected top offenders %+v", u.Prefix, u.TopOffenders)
+		}
+	}
+	if report.Unbudgeted != 1 {
+		t.Errorf("expected 1 unbudgeted package, got %d", report.Unbudgeted)
+	}
+	if report.Exceeded() {
+		t.Error("expected no exceeded budget")
+	}
+}
+
+func TestEvaluate_Limits(t *testing.T) {
+	b := testBundle()
+	infraSize := packageSize(b, "infra/aws/account/iam/root")
+
+	testCases := []struct {
+		desc   string
+		budget Budget
+		want   bool
+	}{
+		{desc: "packages at limit", budget: Budget{Prefix: "infra", MaxPackages: 1}},
+		{desc: "bytes at limit", budget: Budget{Prefix: "infra", MaxBytes: infraSize}},
+		{desc: "bytes exceeded", budget: Budget{Prefix: "infra", MaxBytes: infraSize - 1}, want: true},
+		{desc: "packages exceeded", budget: Budget{Prefix: "app/production", MaxPackages: 2}, want: true},
+		{desc: "root fallback", budget: Budget{Prefix: "", MaxPackages: 6}},
+		{desc: "root fallback exceeded", budget: Budget{Prefix: "/", MaxPackages: 5}, want: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			report, err := Evaluate(b, &Policy{
+				Spec: PolicySpec{Budgets: []Budget{tC.budget}},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := report.Exceeded(); got != tC.want {
+				t.Errorf("Exceeded() = %v, want %v (%+v)", got, tC.want, report.Budgets[0])
+			}
+		})
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		input   string
+		wantErr bool
+	}{
+		{
+			desc: "valid",
+			input: `apiVersion: harp.elastic.co/v1
+kind: BudgetPolicy
+spec:
+  budgets:
+  - prefix: app/production
+    maxPackages: 100
+    maxBytes: 1048576
+`,
+		},
+		{
+			desc: "invalid kind",
+			input: `apiVersion: harp.elastic.co/v1
+kind: LintPolicy
+spec:
+  budgets:
+  - prefix: app
+    maxPackages: 1
+`,
+			wantErr: true,
+		},
+		{
+			desc: "unknown field",
+			input: `apiVersion: harp.elastic.co/v1
+kind: BudgetPolicy
+spec:
+  budgets:
+  - prefix: app
+    maxSize: 1
+`,
+			wantErr: true,
+		},
+		{
+			desc: "no limit",
+			input: `apiVersion: harp.elastic.co/v1
+kind: BudgetPolicy
+spec:
+  budgets:
+  - prefix: app
+`,
+			wantErr: true,
+		},
+		{
+			desc: "duplicate prefix",
+			input: `apiVersion: harp.elastic.co/v1
+kind: BudgetPolicy
+spec:
+  budgets:
+  - prefix: app
+    maxPackages: 1
+  - prefix: app/
+    maxPackages: 2
+`,
+			wantErr: true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			_, err := ParsePolicy(strings.NewReader(tC.input))
+			if (err != nil) != tC.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tC.wantErr)
+			}
+		})
+	}
+}
+
+func TestEnforce(t *testing.T) {
+	b := testBundle()
+
+	err := Enforce(b, &Policy{
+		Spec: PolicySpec{
+			Budgets: []Budget{
+				{Prefix: "app/production", MaxPackages: 1},
+				{Prefix: "app/production/customer1", MaxPackages: 1},
+			},
+		},
+	})
+	if !errors.Is(err, ErrExceeded) {
+		t.Fatalf("expected ErrExceeded, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "'app/production/customer1' (packages 2/1)") {
+		t.Errorf("unexpected error message %q", err)
+	}
+	if strings.Contains(err.Error(), "'app/production' ") {
+		t.Errorf("nested packages must not be attributed to parent prefix: %q", err)
+	}
+}
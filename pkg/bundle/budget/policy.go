@@ -0,0 +1,134 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package budget
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/elastic/harp/pkg/sdk/convert"
+	"github.com/elastic/harp/pkg/sdk/types"
+)
+
+const (
+	// PolicyAPIVersion is the supported policy api version.
+	PolicyAPIVersion = "harp.elastic.co/v1"
+	// PolicyKind is the supported policy kind.
+	PolicyKind = "BudgetPolicy"
+
+	// DefaultTopOffenders is the default count of largest packages reported
+	// per budget.
+	DefaultTopOffenders = 5
+)
+
+// Policy describes bundle size budget configuration.
+type Policy struct {
+	APIVersion string     `json:"apiVersion"`
+	Kind       string     `json:"kind"`
+	Spec       PolicySpec `json:"spec"`
+}
+
+// PolicySpec describes bundle size budget settings.
+type PolicySpec struct {
+	TopOffenders int      `json:"topOffenders,omitempty"`
+	Budgets      []Budget `json:"budgets"`
+}
+
+// Budget describes limits applied to packages attributed to a path prefix.
+// An empty prefix matches all packages not attributed to a longer prefix.
+// A zero limit is not enforced.
+type Budget struct {
+	Prefix      string `json:"prefix"`
+	MaxPackages int    `json:"maxPackages,omitempty"`
+	MaxBytes    int64  `json:"maxBytes,omitempty"`
+}
+
+// ParsePolicy reads a YAML or JSON policy from the given reader.
+func ParsePolicy(r io.Reader) (*Policy, error) {
+	// Check arguments
+	if types.IsNil(r) {
+		return nil, fmt.Errorf("reader is nil")
+	}
+
+	// Convert to JSON
+	jsonReader, err := convert.YAMLtoJSON(r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse input as BudgetPolicy: %w", err)
+	}
+
+	// Decode policy
+	var p Policy
+	dec := json.NewDecoder(jsonReader)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("unable to decode policy: %w", err)
+	}
+
+	// Check policy header
+	if p.APIVersion != PolicyAPIVersion {
+		return nil, fmt.Errorf("unsupported policy api version '%s'", p.APIVersion)
+	}
+	if p.Kind != PolicyKind {
+		return nil, fmt.Errorf("unsupported policy kind '%s'", p.Kind)
+	}
+
+	// Validate budgets
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	// No error
+	return &p, nil
+}
+
+// Validate checks policy consistency.
+func (p *Policy) Validate() error {
+	if len(p.Spec.Budgets) == 0 {
+		return fmt.Errorf("policy must declare at least one budget")
+	}
+	if p.Spec.TopOffenders < 0 {
+		return fmt.Errorf("topOffenders must be positive")
+	}
+
+	seen := map[string]struct{}{}
+	for i, b := range p.Spec.Budgets {
+		prefix := normalizePrefix(b.Prefix)
+		if _, ok := seen[prefix]; ok {
+			return fmt.Errorf("budget #%d: duplicate prefix '%s'", i, b.Prefix)
+		}
+		seen[prefix] = struct{}{}
+
+		if b.MaxPackages < 0 || b.MaxBytes < 0 {
+			return fmt.Errorf("budget #%d: limits must be positive", i)
+		}
+		if b.MaxPackages == 0 && b.MaxBytes == 0 {
+			return fmt.Errorf("budget #%d: at least one of maxPackages or maxBytes must be set", i)
+		}
+	}
+
+	// No error
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func normalizePrefix(prefix string) string {
+	return strings.Trim(prefix, "/")
+}
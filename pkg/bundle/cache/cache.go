@@ -0,0 +1,456 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package cache provides a local encrypted secret cache, used to read a
+// subset of bundle secrets without access to the sealed container.
+//
+// Cache file is encrypted using XChaCha20-Poly1305 with a key derived from a
+// master key stored in the keyring. Cached entries carry an expiration date
+// after which they can't be read anymore.
+package cache
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gobwas/glob"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/sdk/keyring"
+)
+
+const (
+	// KeyringService is the keyring service name holding the master key.
+	KeyringService = "harp"
+	// KeyringAccount is the keyring account name holding the master key.
+	KeyringAccount = "cache"
+
+	fileName    = "secrets.cache"
+	fileVersion = 1
+	keyInfo     = "harp-cache-v1"
+	masterSize  = 32
+	saltSize    = 32
+)
+
+var (
+	// ErrNotFound is raised when the requested path is not cached.
+	ErrNotFound = errors.New("cache: secret path not found")
+	// ErrExpired is raised when the requested path is expired.
+	ErrExpired = errors.New("cache: secret path expired")
+)
+
+// Cache represents a local encrypted secret cache.
+type Cache struct {
+	dir  string
+	ring keyring.Keyring
+	now  func() time.Time
+}
+
+// Entry describes a cached package.
+type Entry struct {
+	ExpiresAt time.Time `json:"expires_at"`
+	Secrets   bundle.KV `json:"secrets"`
+}
+
+// Option defines cache option function.
+type Option func(*Cache)
+
+// WithClock overrides the clock used to check entry expiration.
+func WithClock(now func() time.Time) Option {
+	return func(c *Cache) {
+		c.now = now
+	}
+}
+
+// DefaultDir returns the default cache directory.
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve user cache directory: %w", err)
+	}
+	return filepath.Join(dir, "harp", "cache"), nil
+}
+
+// Open returns a cache stored in the given directory, protected by a master
+// key stored in the given keyring.
+func Open(dir string, ring keyring.Keyring, opts ...Option) (*Cache, error) {
+	// Check arguments
+	if dir == "" {
+		return nil, errors.New("unable to open cache with a blank directory")
+	}
+	if ring == nil {
+		return nil, errors.New("unable to open cache with a nil keyring")
+	}
+
+	c := &Cache{
+		dir:  dir,
+		ring: ring,
+		now:  time.Now,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	// No error
+	return c, nil
+}
+
+// Sync replaces cache content by the bundle packages matching at least one
+// of the given path patterns. It returns the count of cached packages.
+func (c *Cache) Sync(b *bundlev1.Bundle, patterns []string, ttl time.Duration) (int, error) {
+	// Check arguments
+	if b == nil {
+		return 0, errors.New("unable to sync a nil bundle")
+	}
+	if len(patterns) == 0 {
+		return 0, errors.New("at least one path pattern must be given")
+	}
+	if ttl <= 0 {
+		return 0, errors.New("cache entry ttl must be positive")
+	}
+
+	// Compile allow-list
+	filters := make([]glob.Glob, 0, len(patterns))
+	for _, pattern := range patterns {
+		g, err := glob.Compile(pattern, '/')
+		if err != nil {
+			return 0, fmt.Errorf("unable to compile path pattern '%s': %w", pattern, err)
+		}
+		filters = append(filters, g)
+	}
+
+	// Extract allowed packages
+	expiresAt := c.now().Add(ttl).UTC()
+	entries := map[string]Entry{}
+	for _, p := range b.Packages {
+		if !matchAny(filters, p.Name) {
+			continue
+		}
+		if p.Secrets == nil || p.Secrets.Locked != nil {
+			return 0, fmt.Errorf("unable to cache locked package '%s'", p.Name)
+		}
+
+		secrets, err := bundle.AsSecretMap(p)
+		if err != nil {
+			return 0, err
+		}
+		entries[p.Name] = Entry{
+			ExpiresAt: expiresAt,
+			Secrets:   secrets,
+		}
+	}
+
+	// Write cache
+	if err := c.write(entries); err != nil {
+		return 0, err
+	}
+
+	// No error
+	return len(entries), nil
+}
+
+// Read returns secrets of the given cached path. Expired entries are never
+// returned.
+func (c *Cache) Read(path string) (bundle.KV, error) {
+	entries, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+
+	e, ok := entries[path]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if !c.now().Before(e.ExpiresAt) {
+		return nil, ErrExpired
+	}
+
+	// No error
+	return e.Secrets, nil
+}
+
+// Paths returns all cached paths, expired ones included.
+func (c *Cache) Paths() ([]string, error) {
+	entries, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]string, 0, len(entries))
+	for path := range entries {
+		res = append(res, path)
+	}
+	sort.Strings(res)
+
+	return res, nil
+}
+
+// Purge removes cached entries. When expiredOnly is false, the cache file and
+// its master key are deleted. It returns the count of removed entries.
+func (c *Cache) Purge(expiredOnly bool) (int, error) {
+	entries, err := c.read()
+	switch {
+	case errors.Is(err, ErrNotFound):
+		entries = map[string]Entry{}
+	case err != nil && expiredOnly:
+		return 0, err
+	default:
+	}
+
+	if !expiredOnly {
+		// Remove cache file
+		if err := os.Remove(c.path()); err != nil && !os.IsNotExist(err) {
+			return 0, fmt.Errorf("unable to remove cache file: %w", err)
+		}
+
+		// Remove master key
+		if err := c.ring.Delete(KeyringService, KeyringAccount); err != nil && !errors.Is(err, keyring.ErrNotFound) {
+			return 0, fmt.Errorf("unable to remove cache key: %w", err)
+		}
+
+		return len(entries), nil
+	}
+
+	// Remove expired entries
+	removed := 0
+	for path, e := range entries {
+		if !c.now().Before(e.ExpiresAt) {
+			delete(entries, path)
+			removed++
+		}
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+
+	// Write cache
+	if err := c.write(entries); err != nil {
+		return 0, err
+	}
+
+	// No error
+	return removed, nil
+}
+
+// ReadPatterns reads path patterns from the given reader, one per line. Blank
+// lines and lines starting with '#' are ignored.
+func ReadPatterns(r io.Reader) ([]string, error) {
+	res := []string{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		res = append(res, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read path patterns: %w", err)
+	}
+
+	return res, nil
+}
+
+// -----------------------------------------------------------------------------
+
+type envelope struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Payload []byte `json:"payload"`
+}
+
+func (c *Cache) path() string {
+	return filepath.Join(c.dir, fileName)
+}
+
+func (c *Cache) read() (map[string]Entry, error) {
+	// Read cache file
+	raw, err := ioutil.ReadFile(c.path())
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read cache file: %w", err)
+	}
+
+	// Decode envelope
+	var env envelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return nil, fmt.Errorf("unable to decode cache file: %w", err)
+	}
+	if env.Version != fileVersion {
+		return nil, fmt.Errorf("unsupported cache file version %d", env.Version)
+	}
+
+	// Retrieve master key, without creating it
+	master, err := c.masterKey(false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decrypt payload
+	aead, err := fileCipher(master, env.Salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(env.Nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid cache file nonce")
+	}
+	payload, err := aead.Open(nil, env.Nonce, env.Payload, []byte(keyInfo))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt cache file: %w", err)
+	}
+
+	// Decode entries
+	entries := map[string]Entry{}
+	if err := json.Unmarshal(payload, &entries); err != nil {
+		return nil, fmt.Errorf("unable to decode cache entries: %w", err)
+	}
+
+	// No error
+	return entries, nil
+}
+
+func (c *Cache) write(entries map[string]Entry) error {
+	// Encode entries
+	payload, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("unable to encode cache entries: %w", err)
+	}
+
+	// Retrieve or create master key
+	master, err := c.masterKey(true)
+	if err != nil {
+		return err
+	}
+
+	// Encrypt payload with a fresh file key
+	env := envelope{
+		Version: fileVersion,
+		Salt:    make([]byte, saltSize),
+		Nonce:   make([]byte, chacha20poly1305.NonceSizeX),
+	}
+	if _, err := io.ReadFull(rand.Reader, env.Salt); err != nil {
+		return fmt.Errorf("unable to generate cache salt: %w", err)
+	}
+	if _, err := io.ReadFull(rand.Reader, env.Nonce); err != nil {
+		return fmt.Errorf("unable to generate cache nonce: %w", err)
+	}
+	aead, err := fileCipher(master, env.Salt)
+	if err != nil {
+		return err
+	}
+	env.Payload = aead.Seal(nil, env.Nonce, payload, []byte(keyInfo))
+
+	raw, err := json.Marshal(&env)
+	if err != nil {
+		return fmt.Errorf("unable to encode cache file: %w", err)
+	}
+
+	// Write atomically
+	if err := os.MkdirAll(c.dir, 0o700); err != nil {
+		return fmt.Errorf("unable to create cache directory: %w", err)
+	}
+	tmp, err := ioutil.TempFile(c.dir, fileName+".*")
+	if err != nil {
+		return fmt.Errorf("unable to create cache file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return fmt.Errorf("unable to write cache file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("unable to write cache file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path()); err != nil {
+		return fmt.Errorf("unable to write cache file: %w", err)
+	}
+
+	// No error
+	return nil
+}
+
+func (c *Cache) masterKey(create bool) ([]byte, error) {
+	encoded, err := c.ring.Get(KeyringService, KeyringAccount)
+	switch {
+	case err == nil:
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != masterSize {
+			return nil, errors.New("invalid cache key in keyring")
+		}
+		return key, nil
+	case errors.Is(err, keyring.ErrNotFound) && !create:
+		return nil, errors.New("cache key not found in keyring")
+	case errors.Is(err, keyring.ErrNotFound):
+	default:
+		return nil, fmt.Errorf("unable to retrieve cache key: %w", err)
+	}
+
+	// Generate a new master key
+	key := make([]byte, masterSize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("unable to generate cache key: %w", err)
+	}
+	if err := c.ring.Set(KeyringService, KeyringAccount, base64.StdEncoding.EncodeToString(key)); err != nil {
+		return nil, fmt.Errorf("unable to store cache key: %w", err)
+	}
+
+	return key, nil
+}
+
+func fileCipher(master, salt []byte) (cipher.AEAD, error) {
+	key := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, master, salt, []byte(keyInfo)), key); err != nil {
+		return nil, fmt.Errorf("unable to derive cache file key: %w", err)
+	}
+
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize cache cipher: %w", err)
+	}
+
+	return aead, nil
+}
+
+func matchAny(filters []glob.Glob, path string) bool {
+	for _, g := range filters {
+		if g.Match(path) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,196 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cache
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/sdk/keyring"
+)
+
+func fixture(t *testing.T) *bundlev1.Bundle {
+	t.Helper()
+
+	b, err := bundle.FromMap(map[string]bundle.KV{
+		"app/production/payments/api/database": {"user": "payments", "password": "foo"},
+		"app/production/payments/api/tls":      {"key": "bar"},
+		"app/production/billing/api/database":  {"password": "baz"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func TestCache_SyncRead(t *testing.T) {
+	dir := t.TempDir()
+	ring := keyring.File(filepath.Join(dir, "keyring"))
+	clk := &clock{now: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	c, err := Open(filepath.Join(dir, "cache"), ring, WithClock(clk.Now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Empty cache
+	if _, err := c.Read("app/production/payments/api/database"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// Only allowed paths are cached
+	count, err := c.Sync(fixture(t), []string{"app/production/payments/**"}, time.Hour)
+	if err != nil {
+		t.Fatalf("unable to sync cache: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 cached paths, got %d", count)
+	}
+
+	secrets, err := c.Read("app/production/payments/api/database")
+	if err != nil {
+		t.Fatalf("unable to read cache: %v", err)
+	}
+	if secrets["password"] != "foo" {
+		t.Errorf("unexpected secrets %v", secrets)
+	}
+	if _, err := c.Read("app/production/billing/api/database"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("denied path must not be cached, got %v", err)
+	}
+
+	// Cache file is encrypted
+	raw, err := ioutil.ReadFile(filepath.Join(dir, "cache", fileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), "payments") {
+		t.Error("cache file must not contain clear text secrets")
+	}
+
+	// Reads fail closed after expiration
+	clk.now = clk.now.Add(time.Hour)
+	if _, err := c.Read("app/production/payments/api/database"); !errors.Is(err, ErrExpired) {
+		t.Errorf("expected ErrExpired, got %v", err)
+	}
+}
+
+func TestCache_Tampered(t *testing.T) {
+	dir := t.TempDir()
+
+	c, err := Open(dir, keyring.File(filepath.Join(dir, "keyring")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Sync(fixture(t), []string{"**"}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another master key can't decrypt the cache
+	other, err := Open(dir, keyring.File(filepath.Join(dir, "other")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Read("app/production/billing/api/database"); err == nil {
+		t.Error("cache must not be readable without its master key")
+	}
+}
+
+func TestCache_Purge(t *testing.T) {
+	dir := t.TempDir()
+	ring := keyring.File(filepath.Join(dir, "keyring"))
+	clk := &clock{now: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	c, err := Open(filepath.Join(dir, "cache"), ring, WithClock(clk.Now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Purge empty cache
+	if count, err := c.Purge(true); err != nil || count != 0 {
+		t.Fatalf("unexpected purge result %d (%v)", count, err)
+	}
+
+	// Sync twice with different ttls, the second sync replaces the content
+	if _, err := c.Sync(fixture(t), []string{"**"}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Sync(fixture(t), []string{"**/database"}, 2*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	paths, err := c.Paths()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 2 {
+		t.Fatalf("unexpected cached paths %v", paths)
+	}
+
+	// Nothing is expired
+	clk.now = clk.now.Add(time.Hour)
+	if count, err := c.Purge(true); err != nil || count != 0 {
+		t.Fatalf("unexpected purge result %d (%v)", count, err)
+	}
+
+	// Everything is expired
+	clk.now = clk.now.Add(time.Hour)
+	if count, err := c.Purge(true); err != nil || count != 2 {
+		t.Fatalf("unexpected purge result %d (%v)", count, err)
+	}
+	if paths, err := c.Paths(); err != nil || len(paths) != 0 {
+		t.Errorf("expired entries must be removed, got %v (%v)", paths, err)
+	}
+
+	// Full purge removes the file and the master key
+	if _, err := c.Purge(false); err != nil {
+		t.Fatalf("unable to purge cache: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "cache", fileName)); !os.IsNotExist(err) {
+		t.Errorf("cache file must be removed, got %v", err)
+	}
+	if _, err := ring.Get(KeyringService, KeyringAccount); !errors.Is(err, keyring.ErrNotFound) {
+		t.Errorf("cache key must be removed, got %v", err)
+	}
+	if _, err := c.Read("app/production/payments/api/database"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestReadPatterns(t *testing.T) {
+	patterns, err := ReadPatterns(strings.NewReader("# Payments team\napp/production/payments/**\n\n  app/production/billing/api/*  \n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patterns) != 2 || patterns[1] != "app/production/billing/api/*" {
+		t.Errorf("unexpected patterns %q", patterns)
+	}
+}
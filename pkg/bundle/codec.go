@@ -35,6 +35,7 @@ import (
 	"google.golang.org/protobuf/proto"
 
 	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle/internal/bufpool"
 	"github.com/elastic/harp/pkg/bundle/secret"
 	csov1 "github.com/elastic/harp/pkg/cso/v1"
 	"github.com/elastic/harp/pkg/sdk/security"
@@ -68,13 +69,15 @@ func Tree(b *bundlev1.Bundle) (*merkletree.Tree, *Statistic, error) {
 		CSOCompliantPackageNameCount: 0,
 	}
 
-	// Ensure packages order
-	sort.SliceStable(b.Packages, func(i, j int) bool {
-		return b.Packages[i].Name < b.Packages[j].Name
+	// Merkle leaves are pushed in package name order, whatever the bundle
+	// package order is.
+	packages := append([]*bundlev1.Package(nil), b.Packages...)
+	sort.SliceStable(packages, func(i, j int) bool {
+		return packages[i].Name < packages[j].Name
 	})
 
 	// All packages
-	for _, p := range b.Packages {
+	for _, p := range packages {
 		// Increment package count
 		stats.PackageCount++
 
@@ -153,6 +156,9 @@ func Dump(w io.Writer, b *bundlev1.Bundle) error {
 		return fmt.Errorf("unable to process nil bundle")
 	}
 
+	// Ensure packages order
+	SortPackages(b.Packages)
+
 	// Compute merkle tree
 	tree, _, err := Tree(b)
 	if err != nil {
@@ -162,8 +168,12 @@ func Dump(w io.Writer, b *bundlev1.Bundle) error {
 	// Assign to bundle
 	b.MerkleTreeRoot = tree.Root()
 
-	// Serialize protobuf payload
-	payload, err := proto.Marshal(b)
+	// Serialize protobuf payload in a reused buffer, sized first so that the
+	// payload never escapes to a reallocated array.
+	buf := bufpool.Get()
+	defer bufpool.Put(buf)
+	buf.Grow(proto.Size(b))
+	payload, err := proto.MarshalOptions{UseCachedSize: true}.MarshalAppend(buf.Bytes(), b)
 	if err != nil {
 		return fmt.Errorf("unable to encode bundle content: %w", err)
 	}
@@ -216,6 +226,10 @@ func Read(b *bundlev1.Bundle, secretPath string) (map[string]interface{}, error)
 	return result, nil
 }
 
+// LockedAlgorithmAnnotation records the encryption algorithm of locked secret
+// values, used to select the decryption algorithm.
+const LockedAlgorithmAnnotation = "harp.elastic.co/v1/secret#algorithm"
+
 // Lock apply transformer function to all secret values and set as locked.
 func Lock(ctx context.Context, b *bundlev1.Bundle, transformer value.Transformer) error {
 	// Check bundle
@@ -151,3 +151,52 @@ func Test_Bundle_DumpLoad(t *testing.T) {
 		})
 	}
 }
+
+func Test_Bundle_DumpOrder(t *testing.T) {
+	names := []string{
+		"legacy/secrets",
+		"app/production/customer1/ece/v1.10.0/adminconsole/database/credentials",
+		"app/production/customer1/ece/v1.2.0/adminconsole/database/credentials",
+		"infra/aws/security/eu-central-1/ec2/ssh/default/encryption_key",
+	}
+	b := &bundlev1.Bundle{}
+	for _, name := range names {
+		b.Packages = append(b.Packages, &bundlev1.Package{
+			Name: name,
+			Secrets: &bundlev1.SecretChain{
+				Data: []*bundlev1.KV{{Key: "key", Type: "string", Value: []byte(name)}},
+			},
+		})
+	}
+
+	// Merkle root doesn't depend on package order
+	tree, _, err := Tree(b)
+	if err != nil {
+		t.Fatalf("unable to compute merkle tree: %v", err)
+	}
+	root := tree.Root()
+
+	var out bytes.Buffer
+	if err := Dump(&out, b); err != nil {
+		t.Fatalf("unable to dump bundle: %v", err)
+	}
+	if !bytes.Equal(b.MerkleTreeRoot, root) {
+		t.Error("merkle tree root must not depend on package order")
+	}
+
+	// Packages are dumped in canonical CSO order
+	got, err := Load(&out)
+	if err != nil {
+		t.Fatalf("unable to load bundle: %v", err)
+	}
+	gotNames, _ := Paths(got)
+	want := []string{
+		"infra/aws/security/eu-central-1/ec2/ssh/default/encryption_key",
+		"app/production/customer1/ece/v1.2.0/adminconsole/database/credentials",
+		"app/production/customer1/ece/v1.10.0/adminconsole/database/credentials",
+		"legacy/secrets",
+	}
+	if diff := cmp.Diff(want, gotNames); diff != "" {
+		t.Errorf("unexpected package order (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,189 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+)
+
+// CollisionKind describes why package paths are colliding.
+type CollisionKind string
+
+const (
+	// CollisionCase is raised for paths differing only by case.
+	CollisionCase CollisionKind = "case"
+	// CollisionNormalization is raised for paths differing only by their
+	// Unicode normalization form (NFC vs NFD).
+	CollisionNormalization CollisionKind = "normalization"
+	// CollisionConfusable is raised for paths differing by visually
+	// confusable characters (homoglyphs, compatibility forms).
+	CollisionConfusable CollisionKind = "confusable"
+)
+
+// PathCollision describes a group of package paths designating the same
+// secret path for a human or a case-insensitive target.
+type PathCollision struct {
+	// Canonical is the path all colliding packages should be rewritten to.
+	Canonical string `json:"canonical"`
+	// Survivor is the suggested package to keep.
+	Survivor string          `json:"survivor"`
+	Paths    []string        `json:"paths"`
+	Kinds    []CollisionKind `json:"kinds"`
+}
+
+// CanonicalPath returns the canonical form of the given package path (NFC).
+func CanonicalPath(path string) string {
+	return norm.NFC.String(path)
+}
+
+// PathCollisionKey returns the key shared by all paths colliding with the
+// given one.
+func PathCollisionKey(path string) string {
+	// Fold compatibility forms (fullwidth, ligatures, etc.) and case
+	folded := strings.ToLower(norm.NFKC.String(path))
+
+	// Replace confusable characters by their ASCII skeleton
+	return strings.Map(func(r rune) rune {
+		if s, ok := confusables[r]; ok {
+			return s
+		}
+		return r
+	}, folded)
+}
+
+// CollisionKindOf returns the collision kind between the two given paths.
+func CollisionKindOf(path, other string) CollisionKind {
+	a, b := CanonicalPath(path), CanonicalPath(other)
+	switch {
+	case a == b:
+		return CollisionNormalization
+	case strings.ToLower(a) == strings.ToLower(b):
+		return CollisionCase
+	default:
+	}
+
+	return CollisionConfusable
+}
+
+// PathCollisions returns all colliding package path groups of the given
+// bundle, sorted by canonical path.
+func PathCollisions(b *bundlev1.Bundle) []PathCollision {
+	res := []PathCollision{}
+	if b == nil {
+		return res
+	}
+
+	// Group distinct paths by collision key
+	groups := map[string][]string{}
+	seen := map[string]struct{}{}
+	for _, p := range b.Packages {
+		if _, ok := seen[p.Name]; ok {
+			continue
+		}
+		seen[p.Name] = struct{}{}
+
+		key := PathCollisionKey(p.Name)
+		groups[key] = append(groups[key], p.Name)
+	}
+
+	for _, paths := range groups {
+		if len(paths) < 2 {
+			continue
+		}
+
+		// Elect a survivor
+		sort.Slice(paths, func(i, j int) bool {
+			return survivorLess(paths[i], paths[j])
+		})
+		survivor := paths[0]
+
+		// Collect collision kinds
+		kinds := map[CollisionKind]struct{}{}
+		for _, path := range paths[1:] {
+			kinds[CollisionKindOf(path, survivor)] = struct{}{}
+		}
+
+		c := PathCollision{
+			Canonical: CanonicalPath(survivor),
+			Survivor:  survivor,
+			Paths:     append([]string{}, paths...),
+			Kinds:     []CollisionKind{},
+		}
+		sort.Strings(c.Paths)
+		for _, k := range []CollisionKind{CollisionCase, CollisionNormalization, CollisionConfusable} {
+			if _, ok := kinds[k]; ok {
+				c.Kinds = append(c.Kinds, k)
+			}
+		}
+		res = append(res, c)
+	}
+
+	// Ensure stable order
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Canonical < res[j].Canonical
+	})
+
+	return res
+}
+
+// -----------------------------------------------------------------------------
+
+// survivorLess prefers ASCII, lowercase and NFC encoded paths, in this order.
+func survivorLess(a, b string) bool {
+	for _, pref := range []func(string) bool{isASCII, isLower, norm.NFC.IsNormalString} {
+		pa, pb := pref(a), pref(b)
+		if pa != pb {
+			return pa
+		}
+	}
+	return a < b
+}
+
+func isASCII(s string) bool {
+	for _, r := range s {
+		if r > unicode.MaxASCII {
+			return false
+		}
+	}
+	return true
+}
+
+func isLower(s string) bool {
+	return strings.ToLower(s) == s
+}
+
+// confusables maps common homoglyphs, not folded by NFKC, to their ASCII
+// skeleton.
+var confusables = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'с': 'c', 'ԁ': 'd', 'е': 'e', 'һ': 'h', 'і': 'i', 'ј': 'j',
+	'ӏ': 'l', 'о': 'o', 'р': 'p', 'ԛ': 'q', 'ѕ': 's', 'ԝ': 'w', 'х': 'x',
+	'у': 'y',
+	// Greek
+	'α': 'a', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p', 'τ': 't',
+	'υ': 'u', 'χ': 'x',
+	// Punctuation
+	'‐': '-', '‑': '-', '‒': '-', '–': '-', '—': '-', '−': '-',
+	'∕': '/', '⁄': '/', '∖': '\\', '․': '.', '‧': '.',
+}
@@ -0,0 +1,123 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"reflect"
+	"testing"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+)
+
+const (
+	// "cafe" with an acute accent, encoded using NFC and NFD forms
+	cafeNFC = "app/production/caf\u00e9/harp/v1.0.0/server/database"
+	cafeNFD = "app/production/cafe\u0301/harp/v1.0.0/server/database"
+)
+
+func TestPathCollisions(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		paths []string
+		want  []PathCollision
+	}{
+		{
+			desc:  "no collision",
+			paths: []string{"app/production/security/harp/v1.0.0/server/database", "app/staging/security/harp/v1.0.0/server/database"},
+			want:  []PathCollision{},
+		},
+		{
+			desc:  "case",
+			paths: []string{"app/Prod/security/harp/v1.0.0/server/database", "app/prod/security/harp/v1.0.0/server/database", "app/PROD/security/harp/v1.0.0/server/database"},
+			want: []PathCollision{
+				{
+					Canonical: "app/prod/security/harp/v1.0.0/server/database",
+					Survivor:  "app/prod/security/harp/v1.0.0/server/database",
+					Paths:     []string{"app/PROD/security/harp/v1.0.0/server/database", "app/Prod/security/harp/v1.0.0/server/database", "app/prod/security/harp/v1.0.0/server/database"},
+					Kinds:     []CollisionKind{CollisionCase},
+				},
+			},
+		},
+		{
+			desc:  "normalization",
+			paths: []string{cafeNFD, cafeNFC},
+			want: []PathCollision{
+				{
+					Canonical: cafeNFC,
+					Survivor:  cafeNFC,
+					Paths:     []string{cafeNFD, cafeNFC},
+					Kinds:     []CollisionKind{CollisionNormalization},
+				},
+			},
+		},
+		{
+			desc:  "normalization without NFC survivor",
+			paths: []string{cafeNFD, "app/production/CAF\u00c9/harp/v1.0.0/server/database"},
+			want: []PathCollision{
+				{
+					Canonical: cafeNFC,
+					Survivor:  cafeNFD,
+					Paths:     []string{"app/production/CAF\u00c9/harp/v1.0.0/server/database", cafeNFD},
+					Kinds:     []CollisionKind{CollisionCase},
+				},
+			},
+		},
+		{
+			desc: "confusable",
+			// Cyrillic 'a' (U+0430) and fullwidth 's' (U+FF53)
+			paths: []string{"\u0430pp/production/security/harp/v1.0.0/server/database", "app/production/\uff53ecurity/harp/v1.0.0/server/database", "app/production/security/harp/v1.0.0/server/database"},
+			want: []PathCollision{
+				{
+					Canonical: "app/production/security/harp/v1.0.0/server/database",
+					Survivor:  "app/production/security/harp/v1.0.0/server/database",
+					Paths:     []string{"app/production/security/harp/v1.0.0/server/database", "app/production/\uff53ecurity/harp/v1.0.0/server/database", "\u0430pp/production/security/harp/v1.0.0/server/database"},
+					Kinds:     []CollisionKind{CollisionConfusable},
+				},
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			b := &bundlev1.Bundle{}
+			for _, path := range tC.paths {
+				b.Packages = append(b.Packages, &bundlev1.Package{Name: path})
+			}
+
+			got := PathCollisions(b)
+			if !reflect.DeepEqual(got, tC.want) {
+				t.Errorf("PathCollisions() = %+v, want %+v", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestCollisionKindOf(t *testing.T) {
+	testCases := []struct {
+		path, other string
+		want        CollisionKind
+	}{
+		{path: cafeNFD, other: cafeNFC, want: CollisionNormalization},
+		{path: "app/Prod", other: "app/prod", want: CollisionCase},
+		{path: "app/pr\u043ed", other: "app/prod", want: CollisionConfusable},
+	}
+	for _, tC := range testCases {
+		if got := CollisionKindOf(tC.path, tC.other); got != tC.want {
+			t.Errorf("CollisionKindOf(%q, %q) = %s, want %s", tC.path, tC.other, got, tC.want)
+		}
+	}
+}
@@ -42,7 +42,7 @@ const (
 )
 
 // FromContainerReader returns a Bundle extracted from a secret container.
-func FromContainerReader(r io.Reader) (*bundlev1.Bundle, error) {
+func FromContainerReader(r io.Reader, opts ...LoadOption) (*bundlev1.Bundle, error) {
 	// Check parameters
 	if types.IsNil(r) {
 		return nil, fmt.Errorf("unable to process nil reader")
@@ -51,11 +51,11 @@ func FromContainerReader(r io.Reader) (*bundlev1.Bundle, error) {
 	// Load secret container
 	c, err := container.Load(r)
 	if err != nil {
-		return nil, fmt.Errorf("unable to load Bundle: %v", err)
+		return nil, fmt.Errorf("unable to load Bundle: %w", err)
 	}
 
 	// Delegate to bundle loader
-	return FromContainer(c)
+	return FromContainer(c, opts...)
 }
 
 // ToContainerWriter returns a Bundle packaged as a secret container.
@@ -78,8 +78,9 @@ func ToContainerWriter(w io.Writer, b *bundlev1.Bundle) error {
 	return container.Dump(w, c)
 }
 
-// FromContainer unwraps a Bundle from a secret container.
-func FromContainer(c *containerv1.Container) (*bundlev1.Bundle, error) {
+// FromContainer unwraps a Bundle from a secret container. Expired packages are
+// kept unless another policy is given.
+func FromContainer(c *containerv1.Container, opts ...LoadOption) (*bundlev1.Bundle, error) {
 	// Check parameters
 	if types.IsNil(c) {
 		return nil, fmt.Errorf("unable to process nil container")
@@ -89,7 +90,10 @@ func FromContainer(c *containerv1.Container) (*bundlev1.Bundle, error) {
 	if types.IsNil(c.Headers) {
 		return nil, fmt.Errorf("unable to process nil container headers")
 	}
-	if c.Headers.ContentType != bundleContentType {
+	if err := container.CheckFeatures(c.Headers); err != nil {
+		return nil, err
+	}
+	if c.Headers.ContentType != bundleContentType && !IsLegacy(c) {
 		return nil, fmt.Errorf("invalid content type for Bundle loader")
 	}
 	if c.Headers.ContentEncoding != "gzip" {
@@ -102,8 +106,19 @@ func FromContainer(c *containerv1.Container) (*bundlev1.Bundle, error) {
 		return nil, fmt.Errorf("unable to initialize compression reader")
 	}
 
-	// Delegate to bundle loader
-	return Load(zr)
+	// Upgrade legacy bundle
+	var b *bundlev1.Bundle
+	if IsLegacy(c) {
+		b, err = migrateLegacy(zr)
+	} else {
+		b, err = Load(zr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply load options
+	return applyLoadOptions(b, opts...)
 }
 
 // ToContainer wrpas a Bundle as a container object.
@@ -0,0 +1,62 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package decryptor provides value decryptors for secret values encrypted
+// outside harp.
+package decryptor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/sdk/value"
+	"github.com/elastic/harp/pkg/sdk/value/encryption"
+)
+
+// SchemeAESGCM is the encryption scheme of values encrypted with a local
+// AES-GCM key.
+const SchemeAESGCM = encryption.AlgorithmAESGCM
+
+// AESGCM returns a value decryptor for values encrypted with the given local
+// AES-GCM key ("aes-gcm:<key>" or raw key).
+func AESGCM(key string) (bundle.ValueDecryptor, error) {
+	t, err := encryption.ForAlgorithm(encryption.AlgorithmAESGCM, key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize aes-gcm decryptor: %w", err)
+	}
+
+	return &transformerDecryptor{
+		scheme:      SchemeAESGCM,
+		transformer: t,
+	}, nil
+}
+
+// -----------------------------------------------------------------------------
+
+type transformerDecryptor struct {
+	scheme      string
+	transformer value.Transformer
+}
+
+func (d *transformerDecryptor) Decrypt(ctx context.Context, scheme string, ciphertext []byte) ([]byte, error) {
+	if scheme != d.scheme {
+		return nil, bundle.ErrUnsupportedEncryptionScheme
+	}
+
+	return d.transformer.From(ctx, ciphertext)
+}
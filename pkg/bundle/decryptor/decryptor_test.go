@@ -0,0 +1,243 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package decryptor
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/secret"
+	"github.com/elastic/harp/pkg/sdk/value/encryption/aes"
+	"github.com/elastic/harp/pkg/sdk/value/encryption/envelope"
+)
+
+var errKMSDenied = errors.New("kms: access denied")
+
+// fakeKMS wraps data keys by encoding them, and counts unwrap calls.
+type fakeKMS struct {
+	calls  int
+	denied bool
+}
+
+func (s *fakeKMS) Encrypt(_ context.Context, data []byte) ([]byte, error) {
+	return []byte(base64.URLEncoding.EncodeToString(data)), nil
+}
+
+func (s *fakeKMS) Decrypt(_ context.Context, data []byte) ([]byte, error) {
+	s.calls++
+	if s.denied {
+		return nil, errKMSDenied
+	}
+	return base64.URLEncoding.DecodeString(string(data))
+}
+
+func kmsEncrypt(t *testing.T, service envelope.Service, cleartext string) string {
+	t.Helper()
+
+	et, err := envelope.Transformer(service, aes.Transformer)
+	if err != nil {
+		t.Fatalf("unable to initialize envelope transformer: %v", err)
+	}
+	ciphertext, err := et.To(context.Background(), []byte(cleartext))
+	if err != nil {
+		t.Fatalf("unable to encrypt value: %v", err)
+	}
+
+	return bundle.EncryptedValue(SchemeKMS, ciphertext)
+}
+
+func mustPack(t *testing.T, value interface{}) []byte {
+	t.Helper()
+
+	packed, err := secret.Pack(value)
+	if err != nil {
+		t.Fatalf("unable to pack value: %v", err)
+	}
+
+	return packed
+}
+
+func unpackString(t *testing.T, kv *bundlev1.KV) string {
+	t.Helper()
+
+	var out string
+	if err := secret.Unpack(kv.Value, &out); err != nil {
+		t.Fatalf("unable to unpack value: %v", err)
+	}
+
+	return out
+}
+
+func TestKMS_Caching(t *testing.T) {
+	kms := &fakeKMS{}
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	d, err := KMS(kms, WithCacheTTL(time.Minute), WithClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, ciphertext, _, err := bundle.ParseEncryptedValue([]byte(kmsEncrypt(t, kms, "s3cr3t")))
+	if err != nil {
+		t.Fatalf("unable to parse encrypted value: %v", err)
+	}
+
+	// Unwrapped data key is reused
+	for i := 0; i < 3; i++ {
+		out, err := d.Decrypt(context.Background(), SchemeKMS, ciphertext)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(out) != "s3cr3t" {
+			t.Fatalf("unexpected cleartext %q", out)
+		}
+	}
+	if kms.calls != 1 {
+		t.Errorf("expected 1 kms call, got %d", kms.calls)
+	}
+
+	// Expired cache entry
+	now = now.Add(2 * time.Minute)
+	if _, err := d.Decrypt(context.Background(), SchemeKMS, ciphertext); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if kms.calls != 2 {
+		t.Errorf("expected 2 kms calls, got %d", kms.calls)
+	}
+
+	// Unsupported scheme
+	if _, err := d.Decrypt(context.Background(), "vault-transit", ciphertext); !errors.Is(err, bundle.ErrUnsupportedEncryptionScheme) {
+		t.Errorf("expected ErrUnsupportedEncryptionScheme, got %v", err)
+	}
+}
+
+func TestKMS_CacheDisabled(t *testing.T) {
+	kms := &fakeKMS{}
+	d, err := KMS(kms, WithCacheTTL(0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, ciphertext, _, _ := bundle.ParseEncryptedValue([]byte(kmsEncrypt(t, kms, "s3cr3t")))
+	for i := 0; i < 2; i++ {
+		if _, err := d.Decrypt(context.Background(), SchemeKMS, ciphertext); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if kms.calls != 2 {
+		t.Errorf("expected 2 kms calls, got %d", kms.calls)
+	}
+}
+
+func TestFromContainerReader_ValueDecryptor(t *testing.T) {
+	kms := &fakeKMS{}
+	local, err := AESGCM("aes-gcm:y8Jk4Lk9a8JZ0f8ELzWnUQu7Yr_f9-VFZbFPVWgzpJ4=")
+	if err != nil {
+		t.Fatalf("unable to initialize aes-gcm decryptor: %v", err)
+	}
+	at, _ := aes.Transformer("y8Jk4Lk9a8JZ0f8ELzWnUQu7Yr_f9-VFZbFPVWgzpJ4=")
+	localCiphertext, err := at.To(context.Background(), []byte("local-secret"))
+	if err != nil {
+		t.Fatalf("unable to encrypt value: %v", err)
+	}
+
+	b := &bundlev1.Bundle{
+		Packages: []*bundlev1.Package{
+			{
+				Name: "app/production/billing/api",
+				Secrets: &bundlev1.SecretChain{
+					Data: []*bundlev1.KV{
+						{Key: "token", Value: mustPack(t, kmsEncrypt(t, kms, "kms-secret"))},
+						{Key: "key", Value: mustPack(t, bundle.EncryptedValue(SchemeAESGCM, localCiphertext))},
+						{Key: "user", Value: mustPack(t, "admin")},
+					},
+				},
+			},
+			{
+				Name: "app/production/billing/legacy",
+				Secrets: &bundlev1.SecretChain{
+					Data: []*bundlev1.KV{
+						{Key: "token", Value: mustPack(t, bundle.EncryptedValue("vault-transit", []byte("opaque")))},
+					},
+				},
+			},
+		},
+	}
+	var container bytes.Buffer
+	if err := bundle.ToContainerWriter(&container, b); err != nil {
+		t.Fatalf("unable to write container: %v", err)
+	}
+
+	kmsDecryptor, err := KMS(kms)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decryptors := bundle.ValueDecryptors{local, kmsDecryptor}
+
+	t.Run("error", func(t *testing.T) {
+		_, err := bundle.FromContainerReader(bytes.NewReader(container.Bytes()), bundle.WithValueDecryptor(decryptors))
+		if !errors.Is(err, bundle.ErrValueDecryption) {
+			t.Fatalf("expected ErrValueDecryption, got %v", err)
+		}
+	})
+
+	t.Run("fail-closed", func(t *testing.T) {
+		hidden := 0
+		got, err := bundle.FromContainerReader(bytes.NewReader(container.Bytes()),
+			bundle.WithValueDecryptor(decryptors),
+			bundle.WithDecryptFailurePolicy(bundle.DecryptFailureHide),
+			bundle.WithHiddenPackageCount(&hidden),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if hidden != 1 || len(got.Packages) != 1 || got.Packages[0].Name != "app/production/billing/api" {
+			t.Fatalf("legacy package must be hidden, got %d hidden", hidden)
+		}
+
+		want := []string{"kms-secret", "local-secret", "admin"}
+		for i, kv := range got.Packages[0].Secrets.Data {
+			if v := unpackString(t, kv); v != want[i] {
+				t.Errorf("expected %q for '%s', got %q", want[i], kv.Key, v)
+			}
+		}
+	})
+
+	t.Run("kms denied", func(t *testing.T) {
+		denied := &fakeKMS{denied: true}
+		d, err := KMS(denied)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got, err := bundle.FromContainerReader(bytes.NewReader(container.Bytes()),
+			bundle.WithValueDecryptor(bundle.ValueDecryptors{local, d}),
+			bundle.WithDecryptFailurePolicy(bundle.DecryptFailureHide),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(got.Packages) != 0 {
+			t.Errorf("all packages must be hidden, got %d", len(got.Packages))
+		}
+	})
+}
@@ -0,0 +1,215 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package decryptor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/awnumar/memguard"
+	"golang.org/x/crypto/cryptobyte"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/sdk/value/encryption/aes"
+	"github.com/elastic/harp/pkg/sdk/value/encryption/envelope"
+)
+
+// SchemeKMS is the default encryption scheme of values encrypted with a data
+// key wrapped by a remote KMS.
+const SchemeKMS = "kms"
+
+type kmsOptions struct {
+	scheme    string
+	cacheTTL  time.Duration
+	cacheSize int
+	now       func() time.Time
+}
+
+// KMSOption defines the functional pattern for KMS decryptor settings.
+type KMSOption func(*kmsOptions)
+
+// WithScheme overrides the handled encryption scheme.
+func WithScheme(scheme string) KMSOption {
+	return func(opts *kmsOptions) {
+		opts.scheme = scheme
+	}
+}
+
+// WithCacheTTL sets the unwrapped data key cache duration, 0 disables the
+// cache.
+func WithCacheTTL(ttl time.Duration) KMSOption {
+	return func(opts *kmsOptions) {
+		opts.cacheTTL = ttl
+	}
+}
+
+// WithCacheSize sets the maximum count of cached data keys.
+func WithCacheSize(size int) KMSOption {
+	return func(opts *kmsOptions) {
+		opts.cacheSize = size
+	}
+}
+
+// WithClock overrides the clock used for cache expiration.
+func WithClock(now func() time.Time) KMSOption {
+	return func(opts *kmsOptions) {
+		opts.now = now
+	}
+}
+
+// KMS returns a value decryptor for envelope encrypted values. The ciphertext
+// holds the KMS wrapped data key (uint16 length prefixed) followed by the
+// value encrypted with the data key using AES-GCM, as produced by the
+// envelope value transformer. Unwrapped data keys are cached to limit KMS
+// calls.
+func KMS(service envelope.Service, opts ...KMSOption) (bundle.ValueDecryptor, error) {
+	const (
+		defaultCacheTTL  = 5 * time.Minute
+		defaultCacheSize = 1024
+	)
+
+	// Check arguments
+	if service == nil {
+		return nil, errors.New("unable to initialize kms decryptor with nil service")
+	}
+
+	// Apply options
+	dopts := &kmsOptions{
+		scheme:    SchemeKMS,
+		cacheTTL:  defaultCacheTTL,
+		cacheSize: defaultCacheSize,
+		now:       time.Now,
+	}
+	for _, o := range opts {
+		o(dopts)
+	}
+
+	return &kmsDecryptor{
+		service: service,
+		opts:    dopts,
+		cache:   map[[sha256.Size]byte]*dataKey{},
+	}, nil
+}
+
+// -----------------------------------------------------------------------------
+
+type dataKey struct {
+	key     []byte
+	expires time.Time
+}
+
+type kmsDecryptor struct {
+	service envelope.Service
+	opts    *kmsOptions
+
+	mu    sync.Mutex
+	cache map[[sha256.Size]byte]*dataKey
+}
+
+func (d *kmsDecryptor) Decrypt(ctx context.Context, scheme string, ciphertext []byte) ([]byte, error) {
+	if scheme != d.opts.scheme {
+		return nil, bundle.ErrUnsupportedEncryptionScheme
+	}
+
+	// Extract the wrapped data key
+	var wrapped cryptobyte.String
+	s := cryptobyte.String(ciphertext)
+	if ok := s.ReadUint16LengthPrefixed(&wrapped); !ok || len(wrapped) == 0 {
+		return nil, errors.New("kms: unable to read wrapped data key")
+	}
+
+	// Unwrap the data key
+	key, err := d.dataKey(ctx, wrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decrypt payload with data key
+	t, err := aes.Transformer(base64.URLEncoding.EncodeToString(key))
+	if err != nil {
+		return nil, fmt.Errorf("kms: unable to initialize payload transformer: %w", err)
+	}
+
+	return t.From(ctx, s)
+}
+
+func (d *kmsDecryptor) dataKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	id := sha256.Sum256(wrapped)
+	now := d.opts.now()
+
+	// Check cache
+	if d.opts.cacheTTL > 0 {
+		d.mu.Lock()
+		if entry, ok := d.cache[id]; ok && now.Before(entry.expires) {
+			key := append([]byte{}, entry.key...)
+			d.mu.Unlock()
+			return key, nil
+		}
+		d.mu.Unlock()
+	}
+
+	// Call the KMS
+	key, err := d.service.Decrypt(ctx, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("kms: unable to unwrap data key: %w", err)
+	}
+	if d.opts.cacheTTL <= 0 {
+		return key, nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Evict entries to respect the cache size
+	if len(d.cache) >= d.opts.cacheSize {
+		d.evict(now)
+	}
+	d.cache[id] = &dataKey{
+		key:     append([]byte{}, key...),
+		expires: now.Add(d.opts.cacheTTL),
+	}
+
+	return key, nil
+}
+
+// evict removes expired entries, or the entry expiring first if none expired.
+func (d *kmsDecryptor) evict(now time.Time) {
+	var (
+		oldestID [sha256.Size]byte
+		oldest   *dataKey
+	)
+	for id, entry := range d.cache {
+		if !now.Before(entry.expires) {
+			memguard.WipeBytes(entry.key)
+			delete(d.cache, id)
+			continue
+		}
+		if oldest == nil || entry.expires.Before(oldest.expires) {
+			oldestID, oldest = id, entry
+		}
+	}
+	if len(d.cache) >= d.opts.cacheSize && oldest != nil {
+		memguard.WipeBytes(oldest.key)
+		delete(d.cache, oldestID)
+	}
+}
@@ -0,0 +1,72 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"golang.org/x/crypto/blake2b"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+)
+
+// PackageDigest returns the digest of the active secret chain content of the
+// given package. It changes whenever a key or a value is added, updated or
+// removed, and is stable for the same content whatever the key order.
+func PackageDigest(p *bundlev1.Package) (string, error) {
+	// Check arguments
+	if p == nil {
+		return "", fmt.Errorf("unable to process nil package")
+	}
+
+	h, err := blake2b.New256(nil)
+	if err != nil {
+		return "", fmt.Errorf("unable to initialize hash function: %w", err)
+	}
+
+	// Length prefixed fields to prevent ambiguous concatenations
+	write := func(tag byte, data []byte) {
+		var size [8]byte
+		binary.BigEndian.PutUint64(size[:], uint64(len(data)))
+		h.Write([]byte{tag})
+		h.Write(size[:])
+		h.Write(data)
+	}
+
+	write('n', []byte(p.Name))
+	if p.Secrets != nil {
+		if p.Secrets.Locked != nil {
+			write('l', p.Secrets.Locked.Value)
+		}
+
+		// Sort keys for stable output
+		data := append([]*bundlev1.KV{}, p.Secrets.Data...)
+		sort.SliceStable(data, func(i, j int) bool {
+			return data[i].Key < data[j].Key
+		})
+		for _, kv := range data {
+			write('k', []byte(kv.Key))
+			write('v', kv.Value)
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
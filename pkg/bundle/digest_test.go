@@ -0,0 +1,80 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+)
+
+func TestPackageDigest(t *testing.T) {
+	pkg := func(kv ...string) *bundlev1.Package {
+		p := &bundlev1.Package{
+			Name:    "app/production/database",
+			Secrets: &bundlev1.SecretChain{Version: 1},
+		}
+		for i := 0; i < len(kv); i += 2 {
+			p.Secrets.Data = append(p.Secrets.Data, &bundlev1.KV{Key: kv[i], Value: []byte(kv[i+1])})
+		}
+		return p
+	}
+
+	base, err := PackageDigest(pkg("user", "harp", "password", "foo"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		desc     string
+		p        *bundlev1.Package
+		wantSame bool
+	}{
+		{desc: "same content", p: pkg("user", "harp", "password", "foo"), wantSame: true},
+		{desc: "key order", p: pkg("password", "foo", "user", "harp"), wantSame: true},
+		{desc: "value updated", p: pkg("user", "harp", "password", "bar")},
+		{desc: "key added", p: pkg("user", "harp", "password", "foo", "host", "db")},
+		{desc: "key removed", p: pkg("user", "harp")},
+		{desc: "key renamed", p: pkg("user", "harp", "passwor", "dfoo")},
+		{
+			desc: "locked",
+			p: func() *bundlev1.Package {
+				p := pkg()
+				p.Secrets.Locked = wrapperspb.Bytes([]byte("locked"))
+				return p
+			}(),
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			got, err := PackageDigest(tC.p)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (got == base) != tC.wantSame {
+				t.Errorf("digest equality = %v, want %v", got == base, tC.wantSame)
+			}
+		})
+	}
+
+	if _, err := PackageDigest(nil); err == nil {
+		t.Error("expected error for nil package")
+	}
+}
@@ -0,0 +1,305 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package distribution builds consumer specific sub-bundles from one master
+// bundle, according to a distribution specification.
+package distribution
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gobwas/glob"
+	"google.golang.org/protobuf/proto"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/selector"
+)
+
+// Annotation holds the distribution name of a sub-bundle.
+const Annotation = "harp.elastic.co/v1/bundle#distribution"
+
+// Result describes sub-bundles built from a bundle.
+type Result struct {
+	// Outputs lists sub-bundles in specification order.
+	Outputs []*Output
+	// Orphans lists active package names selected by no distribution.
+	Orphans []string
+}
+
+// Output describes a distribution sub-bundle.
+type Output struct {
+	Distribution Distribution
+	Bundle       *bundlev1.Bundle
+	// RemovedKeys is the count of secret keys removed by key filters.
+	RemovedKeys int
+}
+
+// Split builds the sub-bundle of each distribution. A package can be part of
+// several distributions. Archived and quarantined packages are never
+// distributed.
+func Split(b *bundlev1.Bundle, s *Spec) (*Result, error) {
+	// Check arguments
+	if b == nil {
+		return nil, fmt.Errorf("unable to split a nil bundle")
+	}
+	if s == nil {
+		return nil, fmt.Errorf("unable to split using a nil specification")
+	}
+	if err := s.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid distribution specification: %w", err)
+	}
+
+	// Prefix selectors are resolved from the package tree
+	tree, err := bundle.NewPackageTree(b)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build package tree: %w", err)
+	}
+
+	// Compile distributions
+	matchers := make([]*matcher, len(s.Spec.Distributions))
+	for i := range s.Spec.Distributions {
+		m, err := compile(&s.Spec.Distributions[i], tree)
+		if err != nil {
+			return nil, err
+		}
+		matchers[i] = m
+	}
+
+	res := &Result{
+		Outputs: make([]*Output, len(matchers)),
+		Orphans: []string{},
+	}
+	for i, m := range matchers {
+		res.Outputs[i] = &Output{
+			Distribution: *m.distribution,
+			Bundle:       header(b, m.distribution.Name),
+		}
+	}
+
+	// Dispatch packages
+	for _, p := range b.Packages {
+		if p == nil || bundle.IsArchived(p) || bundle.IsQuarantined(p) {
+			continue
+		}
+
+		selected := false
+		for i, m := range matchers {
+			if !m.selects(p) {
+				continue
+			}
+			selected = true
+
+			// Restrict package keys
+			out, removed, err := m.filterKeys(p)
+			if err != nil {
+				return nil, err
+			}
+			res.Outputs[i].RemovedKeys += removed
+			if out != nil {
+				res.Outputs[i].Bundle.Packages = append(res.Outputs[i].Bundle.Packages, out)
+			}
+		}
+		if !selected {
+			res.Orphans = append(res.Orphans, p.Name)
+		}
+	}
+	sort.Strings(res.Orphans)
+
+	// No error
+	return res, nil
+}
+
+// -----------------------------------------------------------------------------
+
+type keyMatcher struct {
+	packages glob.Glob
+	allow    []glob.Glob
+}
+
+type matcher struct {
+	distribution *Distribution
+	selectors    []selector.Specification
+	keys         []keyMatcher
+}
+
+func compile(d *Distribution, tree *bundle.PackageTree) (*matcher, error) {
+	m := &matcher{
+		distribution: d,
+	}
+
+	// Compile selectors
+	for _, sel := range d.Selectors {
+		if sel.CSO != "" {
+			s, err := selector.MatchCSO(sel.CSO)
+			if err != nil {
+				return nil, fmt.Errorf("distribution '%s': invalid cso selector '%s': %w", d.Name, sel.CSO, err)
+			}
+			m.selectors = append(m.selectors, s)
+			continue
+		}
+		if sel.Prefix != "" {
+			m.selectors = append(m.selectors, subtreeSelector(tree, sel.Prefix))
+			continue
+		}
+
+		g, err := glob.Compile(sel.Glob, '/')
+		if err != nil {
+			return nil, fmt.Errorf("distribution '%s': invalid glob selector '%s': %w", d.Name, sel.Glob, err)
+		}
+		m.selectors = append(m.selectors, globSelector{g})
+	}
+
+	// Compile key filters
+	for _, kf := range d.Keys {
+		km := keyMatcher{}
+		g, err := glob.Compile(kf.Packages, '/')
+		if err != nil {
+			return nil, fmt.Errorf("distribution '%s': invalid key filter packages '%s': %w", d.Name, kf.Packages, err)
+		}
+		km.packages = g
+		for _, a := range kf.Allow {
+			kg, err := glob.Compile(a)
+			if err != nil {
+				return nil, fmt.Errorf("distribution '%s': invalid allowed key '%s': %w", d.Name, a, err)
+			}
+			km.allow = append(km.allow, kg)
+		}
+		m.keys = append(m.keys, km)
+	}
+
+	return m, nil
+}
+
+func (m *matcher) selects(p *bundlev1.Package) bool {
+	for _, s := range m.selectors {
+		if s.IsSatisfiedBy(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterKeys returns a package copy holding only allowed keys, nil when no
+// key remains, and the count of removed keys. Allowed keys of all matching
+// filters are kept.
+func (m *matcher) filterKeys(p *bundlev1.Package) (*bundlev1.Package, int, error) {
+	allow := []glob.Glob{}
+	for _, km := range m.keys {
+		if km.packages.Match(p.Name) {
+			allow = append(allow, km.allow...)
+		}
+	}
+
+	out, ok := proto.Clone(p).(*bundlev1.Package)
+	if !ok {
+		return nil, 0, fmt.Errorf("unable to copy package '%s'", p.Name)
+	}
+	if len(allow) == 0 {
+		return out, 0, nil
+	}
+
+	// Locked values can't be filtered
+	if out.Secrets == nil {
+		return nil, 0, nil
+	}
+	if out.Secrets.Locked != nil {
+		return nil, 0, fmt.Errorf("distribution '%s': unable to filter keys of locked package '%s'", m.distribution.Name, p.Name)
+	}
+
+	kept := []*bundlev1.KV{}
+	for _, kv := range out.Secrets.Data {
+		if matchAny(allow, kv.Key) {
+			kept = append(kept, kv)
+			continue
+		}
+		// Don't disclose the removed key history
+		delete(out.Annotations, bundle.HistoryAnnotationPrefix+kv.Key)
+	}
+	removed := len(out.Secrets.Data) - len(kept)
+	if len(kept) == 0 {
+		return nil, removed, nil
+	}
+	out.Secrets.Data = kept
+
+	return out, removed, nil
+}
+
+func matchAny(globs []glob.Glob, value string) bool {
+	for _, g := range globs {
+		if g.Match(value) {
+			return true
+		}
+	}
+	return false
+}
+
+type globSelector struct {
+	g glob.Glob
+}
+
+func (s globSelector) IsSatisfiedBy(object interface{}) bool {
+	p, ok := object.(*bundlev1.Package)
+	if !ok {
+		return false
+	}
+	return s.g.Match(p.Name)
+}
+
+// packageSetSelector matches the packages of a set.
+type packageSetSelector map[*bundlev1.Package]struct{}
+
+// subtreeSelector returns a selector matching packages of the given subtree,
+// an unknown prefix selects nothing.
+func subtreeSelector(tree *bundle.PackageTree, prefix string) packageSetSelector {
+	s := packageSetSelector{}
+	if n := tree.Find(prefix); n != nil {
+		for _, p := range n.Packages() {
+			s[p] = struct{}{}
+		}
+	}
+	return s
+}
+
+func (s packageSetSelector) IsSatisfiedBy(object interface{}) bool {
+	p, ok := object.(*bundlev1.Package)
+	if !ok {
+		return false
+	}
+	_, ok = s[p]
+	return ok
+}
+
+// header returns an empty sub-bundle sharing the bundle metadata.
+func header(b *bundlev1.Bundle, name string) *bundlev1.Bundle {
+	out := &bundlev1.Bundle{
+		Version:     b.Version,
+		Labels:      map[string]string{},
+		Annotations: map[string]string{},
+		Packages:    []*bundlev1.Package{},
+	}
+	for k, v := range b.Labels {
+		out.Labels[k] = v
+	}
+	for k, v := range b.Annotations {
+		out.Annotations[k] = v
+	}
+	out.Annotations[Annotation] = name
+
+	return out
+}
@@ -0,0 +1,361 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package distribution
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	wrappers "github.com/golang/protobuf/ptypes/wrappers"
+	"golang.org/x/crypto/nacl/box"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+)
+
+func testRecipient(t *testing.T) string {
+	t.Helper()
+
+	pub, _, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(pub[:])
+}
+
+func testSpec(t *testing.T, body string) *Spec {
+	t.Helper()
+
+	s, err := ParseSpec(strings.NewReader(fmt.Sprintf("apiVersion: harp.elastic.co/v1\nkind: DistributionSpec\nspec:\n%s", body)))
+	if err != nil {
+		t.Fatalf("unable to parse specification: %v", err)
+	}
+	return s
+}
+
+func packageNames(b *bundlev1.Bundle) string {
+	names := []string{}
+	for _, p := range b.Packages {
+		names = append(names, p.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestParseSpec(t *testing.T) {
+	recipient := testRecipient(t)
+
+	testCases := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			body: fmt.Sprintf(`  distributions:
+  - name: frontend
+    selectors:
+    - cso: app/production/*/frontend
+    - glob: infra/**/cdn
+    keys:
+    - packages: "**"
+      allow: ["api_*"]
+    recipients: [%q]
+    output: frontend.bundle
+`, recipient),
+		},
+		{
+			name:    "no distribution",
+			body:    "  distributions: []\n",
+			wantErr: "at least one distribution",
+		},
+		{
+			name: "duplicate name",
+			body: fmt.Sprintf(`  distributions:
+  - {name: a, selectors: [{glob: "**"}], recipients: [%q], output: a.bundle}
+  - {name: a, selectors: [{glob: "**"}], recipients: [%q], output: b.bundle}
+`, recipient, recipient),
+			wantErr: "duplicate name",
+		},
+		{
+			name: "shared output",
+			body: fmt.Sprintf(`  distributions:
+  - {name: a, selectors: [{glob: "**"}], recipients: [%q], output: a.bundle}
+  - {name: b, selectors: [{glob: "**"}], recipients: [%q], output: a.bundle}
+`, recipient, recipient),
+			wantErr: "already used by 'a'",
+		},
+		{
+			name: "stdout output",
+			body: fmt.Sprintf(`  distributions:
+  - {name: a, selectors: [{glob: "**"}], recipients: [%q], output: "-"}
+`, recipient),
+			wantErr: "must be a file path",
+		},
+		{
+			name: "ambiguous selector",
+			body: fmt.Sprintf(`  distributions:
+  - {name: a, selectors: [{glob: "**", cso: "app/**"}], recipients: [%q], output: a.bundle}
+`, recipient),
+			wantErr: "exactly one of cso, glob or prefix",
+		},
+		{
+			name: "incomplete key filter",
+			body: fmt.Sprintf(`  distributions:
+  - {name: a, selectors: [{glob: "**"}], keys: [{packages: "**"}], recipients: [%q], output: a.bundle}
+`, recipient),
+			wantErr: "packages and allowed keys",
+		},
+		{
+			name: "no recipient",
+			body: `  distributions:
+  - {name: a, selectors: [{glob: "**"}], output: a.bundle}
+`,
+			wantErr: "at least one recipient",
+		},
+		{
+			name: "invalid recipient",
+			body: `  distributions:
+  - {name: a, selectors: [{glob: "**"}], recipients: ["foo"], output: a.bundle}
+`,
+			wantErr: "invalid 'foo' as public identity",
+		},
+		{
+			name: "unknown field",
+			body: fmt.Sprintf(`  distributions:
+  - {name: a, selectors: [{glob: "**"}], recipients: [%q], output: a.bundle, sign: true}
+`, recipient),
+			wantErr: "unknown field",
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := ParseSpec(strings.NewReader("apiVersion: harp.elastic.co/v1\nkind: DistributionSpec\nspec:\n" + tc.body))
+		if tc.wantErr == "" {
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", tc.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Fatalf("%s: expected error containing %q, got %v", tc.name, tc.wantErr, err)
+		}
+	}
+
+	// Header
+	if _, err := ParseSpec(strings.NewReader("apiVersion: harp.elastic.co/v1\nkind: BudgetPolicy\nspec: {}\n")); err == nil {
+		t.Fatalf("expected unsupported kind error")
+	}
+	if _, err := ParseSpec(bytes.NewReader(nil)); err == nil {
+		t.Fatalf("expected error with an empty specification")
+	}
+}
+
+func TestSplit(t *testing.T) {
+	b := testbundle.New().
+		Annotation("owner", "release").
+		Package("app/production/shop/frontend/1.0.0/web/config").
+		Secret("api_url", "https://shop.example.com").
+		Secret("session_key", "s3cr3t").
+		Annotation(bundle.HistoryAnnotationPrefix+"session_key", "[]").
+		Package("app/production/shop/backend/1.0.0/api/database").
+		Secret("user", "shop").
+		Secret("password", "p4ssw0rd").
+		Package("app/production/shop/backend/1.0.0/api/stripe").
+		Secret("api_key", "sk_live").
+		Package("app/production/shop/batch/1.0.0/job/database").
+		Secret("password", "b4tch").
+		Package("app/production/shop/legacy/1.0.0/web/config").
+		Secret("api_url", "https://legacy.example.com").
+		Annotation(bundle.ArchivedAnnotation, "2021-01-01T00:00:00Z").
+		Package("app/production/shop/backend/1.0.0/api/leaked").
+		Secret("token", "leaked").
+		Annotation(bundle.QuarantineAnnotation, "leaked in logs").
+		Package("infra/production/monitoring/agent").
+		Secret("token", "m0n1t0r").
+		Build()
+
+	recipient := testRecipient(t)
+	s := testSpec(t, fmt.Sprintf(`  distributions:
+  - name: frontend
+    selectors:
+    - glob: app/production/shop/frontend/**
+    - glob: app/production/shop/backend/**/stripe
+    keys:
+    - packages: "**/web/config"
+      allow: ["api_*"]
+    - packages: "**/stripe"
+      allow: ["public_*"]
+    recipients: [%[1]q]
+    output: frontend.bundle
+  - name: backend
+    selectors:
+    - cso: app/production/shop/backend/1.x
+    recipients: [%[1]q]
+    output: backend.bundle
+  - name: batch
+    selectors:
+    - glob: app/production/shop/*/1.0.0/*/database
+    keys:
+    - packages: "**/backend/**"
+      allow: [user]
+    recipients: [%[1]q]
+    output: batch.bundle
+`, recipient))
+
+	res, err := Split(b, s)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Orphans
+	if got := strings.Join(res.Orphans, ","); got != "infra/production/monitoring/agent" {
+		t.Fatalf("unexpected orphans: %s", got)
+	}
+
+	testCases := []struct {
+		name     string
+		packages string
+		removed  int
+	}{
+		{
+			name:     "frontend",
+			packages: "app/production/shop/frontend/1.0.0/web/config",
+			removed:  2,
+		},
+		{
+			name:     "backend",
+			packages: "app/production/shop/backend/1.0.0/api/database,app/production/shop/backend/1.0.0/api/stripe",
+		},
+		{
+			name:     "batch",
+			packages: "app/production/shop/backend/1.0.0/api/database,app/production/shop/batch/1.0.0/job/database",
+			removed:  1,
+		},
+	}
+	if len(res.Outputs) != len(testCases) {
+		t.Fatalf("expected %d outputs, got %d", len(testCases), len(res.Outputs))
+	}
+	for i, tc := range testCases {
+		out := res.Outputs[i]
+		if out.Distribution.Name != tc.name {
+			t.Fatalf("expected distribution %q at #%d, got %q", tc.name, i, out.Distribution.Name)
+		}
+		if got := packageNames(out.Bundle); got != tc.packages {
+			t.Fatalf("%s: unexpected packages %s", tc.name, got)
+		}
+		if out.RemovedKeys != tc.removed {
+			t.Fatalf("%s: expected %d removed keys, got %d", tc.name, tc.removed, out.RemovedKeys)
+		}
+		if out.Bundle.Annotations[Annotation] != tc.name || out.Bundle.Annotations["owner"] != "release" {
+			t.Fatalf("%s: unexpected bundle annotations %v", tc.name, out.Bundle.Annotations)
+		}
+	}
+
+	// Key filtering
+	frontend := res.Outputs[0].Bundle.Packages[0]
+	if len(frontend.Secrets.Data) != 1 || frontend.Secrets.Data[0].Key != "api_url" {
+		t.Fatalf("expected only the api_url key, got %v", frontend.Secrets.Data)
+	}
+	if _, ok := frontend.Annotations[bundle.HistoryAnnotationPrefix+"session_key"]; ok {
+		t.Fatalf("removed key history must not be distributed")
+	}
+	batch := res.Outputs[2].Bundle.Packages[0]
+	if len(batch.Secrets.Data) != 1 || batch.Secrets.Data[0].Key != "user" {
+		t.Fatalf("expected only the user key, got %v", batch.Secrets.Data)
+	}
+
+	// The master bundle is left untouched
+	if len(b.Packages[0].Secrets.Data) != 2 || len(b.Packages[1].Secrets.Data) != 2 {
+		t.Fatalf("master bundle must not be modified")
+	}
+	if res.Outputs[1].Bundle.Packages[0] == b.Packages[1] || len(res.Outputs[1].Bundle.Packages[0].Secrets.Data) != 2 {
+		t.Fatalf("distributed packages must be copies")
+	}
+}
+
+func TestSplit_Locked(t *testing.T) {
+	b := testbundle.New().
+		Package("app/production/shop/frontend/1.0.0/web/config").
+		Secret("api_url", "https://shop.example.com").
+		Build()
+	b.Packages[0].Secrets.Locked = &wrappers.BytesValue{Value: []byte("locked")}
+
+	recipient := testRecipient(t)
+	unfiltered := testSpec(t, fmt.Sprintf(`  distributions:
+  - {name: a, selectors: [{glob: "**"}], recipients: [%q], output: a.bundle}
+`, recipient))
+	if _, err := Split(b, unfiltered); err != nil {
+		t.Fatalf("locked packages must be distributed when not filtered: %v", err)
+	}
+
+	filtered := testSpec(t, fmt.Sprintf(`  distributions:
+  - {name: a, selectors: [{glob: "**"}], keys: [{packages: "**", allow: [api_url]}], recipients: [%q], output: a.bundle}
+`, recipient))
+	if _, err := Split(b, filtered); err == nil || !strings.Contains(err.Error(), "locked package") {
+		t.Fatalf("expected locked package error, got %v", err)
+	}
+}
+
+func TestSplit_InvalidPattern(t *testing.T) {
+	s := testSpec(t, fmt.Sprintf(`  distributions:
+  - {name: a, selectors: [{glob: "app/[production"}], recipients: [%q], output: a.bundle}
+`, testRecipient(t)))
+	if _, err := Split(testbundle.New().Build(), s); err == nil || !strings.Contains(err.Error(), "invalid glob selector") {
+		t.Fatalf("expected invalid glob error, got %v", err)
+	}
+}
+
+func TestSplit_Prefix(t *testing.T) {
+	b := testbundle.New().
+		Package("infra/production/monitoring").
+		Secret("token", "r00t").
+		Package("infra/production/monitoring/agent").
+		Secret("token", "m0n1t0r").
+		Package("infra/production/monitoring/leaked").
+		Secret("token", "leaked").
+		Annotation(bundle.QuarantineAnnotation, "leaked in logs").
+		Package("infra/production-eu/monitoring/agent").
+		Secret("token", "eu").
+		Build()
+
+	s := testSpec(t, fmt.Sprintf(`  distributions:
+  - {name: monitoring, selectors: [{prefix: infra/production/monitoring/}], recipients: [%[1]q], output: monitoring.bundle}
+  - {name: unknown, selectors: [{prefix: infra/staging}], recipients: [%[1]q], output: unknown.bundle}
+`, testRecipient(t)))
+
+	res, err := Split(b, s)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Subtree selection stops at segment boundaries
+	if got := packageNames(res.Outputs[0].Bundle); got != "infra/production/monitoring,infra/production/monitoring/agent" {
+		t.Fatalf("unexpected packages %s", got)
+	}
+	if got := packageNames(res.Outputs[1].Bundle); got != "" {
+		t.Fatalf("unknown prefix must select nothing, got %s", got)
+	}
+	if got := strings.Join(res.Orphans, ","); got != "infra/production-eu/monitoring/agent" {
+		t.Fatalf("unexpected orphans: %s", got)
+	}
+}
@@ -0,0 +1,208 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package distribution
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/elastic/harp/pkg/sdk/convert"
+	"github.com/elastic/harp/pkg/sdk/security/crypto/x25519"
+	"github.com/elastic/harp/pkg/sdk/types"
+)
+
+const (
+	// SpecAPIVersion is the supported specification api version.
+	SpecAPIVersion = "harp.elastic.co/v1"
+	// SpecKind is the supported specification kind.
+	SpecKind = "DistributionSpec"
+)
+
+// Spec describes consumer specific sub-bundles built from one bundle.
+type Spec struct {
+	APIVersion string   `json:"apiVersion"`
+	Kind       string   `json:"kind"`
+	Spec       SpecBody `json:"spec"`
+}
+
+// SpecBody describes distribution settings.
+type SpecBody struct {
+	Distributions []Distribution `json:"distributions"`
+}
+
+// Distribution describes a sealed sub-bundle delivered to a consumer.
+type Distribution struct {
+	// Name identifies the distribution.
+	Name string `json:"name"`
+	// Selectors select distributed packages, a package is selected when it
+	// matches at least one selector.
+	Selectors []Selector `json:"selectors"`
+	// Keys restricts secret keys of selected packages.
+	Keys []KeyFilter `json:"keys,omitempty"`
+	// Recipients lists public identities allowed to unseal the sub-bundle.
+	Recipients []string `json:"recipients"`
+	// Output is the sealed sub-bundle path.
+	Output string `json:"output"`
+}
+
+// Selector matches package names using a CSO path pattern (segment
+// wildcards and version ranges), a glob pattern (`*` within a segment, `**`
+// across segments) or a path prefix selecting a complete subtree. Exactly one
+// of them must be set.
+type Selector struct {
+	CSO    string `json:"cso,omitempty"`
+	Glob   string `json:"glob,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// KeyFilter keeps only the allowed secret keys of packages matching the
+// package glob pattern. Packages matching no filter keep all their keys.
+type KeyFilter struct {
+	Packages string   `json:"packages"`
+	Allow    []string `json:"allow"`
+}
+
+// ParseSpec reads a YAML or JSON specification from the given reader.
+func ParseSpec(r io.Reader) (*Spec, error) {
+	// Check arguments
+	if types.IsNil(r) {
+		return nil, fmt.Errorf("reader is nil")
+	}
+
+	// Convert to JSON
+	jsonReader, err := convert.YAMLtoJSON(r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse input as DistributionSpec: %w", err)
+	}
+
+	// Decode specification
+	var s Spec
+	dec := json.NewDecoder(jsonReader)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&s); err != nil {
+		return nil, fmt.Errorf("unable to decode specification: %w", err)
+	}
+
+	// Check specification header
+	if s.APIVersion != SpecAPIVersion {
+		return nil, fmt.Errorf("unsupported specification api version '%s'", s.APIVersion)
+	}
+	if s.Kind != SpecKind {
+		return nil, fmt.Errorf("unsupported specification kind '%s'", s.Kind)
+	}
+
+	// Validate distributions
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	// No error
+	return &s, nil
+}
+
+// Validate checks specification consistency. Patterns are validated when
+// compiled.
+func (s *Spec) Validate() error {
+	if len(s.Spec.Distributions) == 0 {
+		return fmt.Errorf("specification must declare at least one distribution")
+	}
+
+	names := map[string]struct{}{}
+	outputs := map[string]string{}
+	for i, d := range s.Spec.Distributions {
+		// Check identity
+		if strings.TrimSpace(d.Name) == "" {
+			return fmt.Errorf("distribution #%d: name must not be blank", i)
+		}
+		if _, ok := names[d.Name]; ok {
+			return fmt.Errorf("distribution #%d: duplicate name '%s'", i, d.Name)
+		}
+		names[d.Name] = struct{}{}
+
+		// Check output
+		if strings.TrimSpace(d.Output) == "" || d.Output == "-" {
+			return fmt.Errorf("distribution '%s': output must be a file path", d.Name)
+		}
+		if other, ok := outputs[d.Output]; ok {
+			return fmt.Errorf("distribution '%s': output '%s' already used by '%s'", d.Name, d.Output, other)
+		}
+		outputs[d.Output] = d.Name
+
+		// Check selectors
+		if len(d.Selectors) == 0 {
+			return fmt.Errorf("distribution '%s': at least one selector must be declared", d.Name)
+		}
+		for j, sel := range d.Selectors {
+			if sel.count() != 1 {
+				return fmt.Errorf("distribution '%s': selector #%d must declare exactly one of cso, glob or prefix", d.Name, j)
+			}
+		}
+
+		// Check key filters
+		for j, kf := range d.Keys {
+			if kf.Packages == "" || len(kf.Allow) == 0 {
+				return fmt.Errorf("distribution '%s': key filter #%d must declare packages and allowed keys", d.Name, j)
+			}
+		}
+
+		// Check recipients
+		if len(d.Recipients) == 0 {
+			return fmt.Errorf("distribution '%s': at least one recipient must be declared", d.Name)
+		}
+		for _, r := range d.Recipients {
+			if _, err := PublicKey(r); err != nil {
+				return fmt.Errorf("distribution '%s': %w", d.Name, err)
+			}
+		}
+	}
+
+	// No error
+	return nil
+}
+
+// PublicKey decodes the given public identity as a sealing public key.
+func PublicKey(recipient string) (*[32]byte, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(recipient)
+	if err != nil {
+		return nil, fmt.Errorf("invalid '%s' as public identity: %w", recipient, err)
+	}
+	if !x25519.IsValidPublicKey(raw) {
+		return nil, fmt.Errorf("invalid '%s' as public identity: not a valid public key", recipient)
+	}
+
+	var publicKey [32]byte
+	copy(publicKey[:], raw[:32])
+
+	return &publicKey, nil
+}
+
+// -----------------------------------------------------------------------------
+
+// count returns the declared pattern count.
+func (s *Selector) count() int {
+	count := 0
+	for _, v := range []string{s.CSO, s.Glob, s.Prefix} {
+		if v != "" {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,295 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package docs generates a documentation catalog of bundle packages.
+//
+// The catalog only exposes package metadata (descriptions, owners, rotation
+// periods and key schema), secret values are never read.
+package docs
+
+import (
+	"sort"
+
+	csov1 "github.com/elastic/harp/api/gen/go/cso/v1"
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/archetype"
+	csov1pkg "github.com/elastic/harp/pkg/cso/v1"
+)
+
+const (
+	// Unowned is the team name used for packages without owner.
+	Unowned = "unowned"
+	// OtherRing is the ring name used for packages not compliant with CSO.
+	OtherRing = "other"
+)
+
+// Catalog describes documented bundle packages.
+type Catalog struct {
+	Summary Score
+	Teams   []*Score
+	Rings   []*Ring
+}
+
+// Score describes documentation completeness of a package set.
+type Score struct {
+	Team         string
+	Packages     int
+	Documented   int
+	Score        int
+	Undocumented []string
+}
+
+// Ring groups packages of a CSO ring.
+type Ring struct {
+	Name      string
+	Platforms []*Platform
+}
+
+// Platform groups packages of a platform. Name is blank for rings without
+// platform notion.
+type Platform struct {
+	Name     string
+	Products []*Product
+}
+
+// Product groups packages of a product. Name is blank for rings without
+// product notion.
+type Product struct {
+	Name     string
+	Packages []*Package
+}
+
+// Package describes a documented package.
+type Package struct {
+	Path        string
+	Description string
+	Owner       string
+	Rotation    string
+	Archetype   string
+	Quarantine  string
+	Locked      bool
+	Keys        []*Key
+}
+
+// Key describes a package secret key.
+type Key struct {
+	Name        string
+	Type        string
+	Description string
+}
+
+// Documented returns true if the package has a description.
+func (p *Package) Documented() bool {
+	return p.Description != ""
+}
+
+// -----------------------------------------------------------------------------
+
+// Build the documentation catalog of the given bundle. Archived packages are
+// ignored.
+func Build(b *bundlev1.Bundle) *Catalog {
+	c := &Catalog{
+		Teams: []*Score{},
+		Rings: []*Ring{},
+	}
+	if b == nil {
+		return c
+	}
+
+	// Visit packages in path order, malformed bundles are visited as is
+	active := bundle.WithoutArchived(b)
+	packages := active.Packages
+	if tree, err := bundle.NewPackageTree(active); err == nil {
+		packages = tree.Root().Packages()
+	}
+
+	// Resolve inherited annotations, unreadable defaults are ignored
+	annotations, err := bundle.InheritedAnnotations(b)
+	if err != nil {
+		annotations = nil
+	}
+
+	rings := map[string]*Ring{}
+	teams := map[string]*Score{}
+	for _, p := range packages {
+		if p == nil || p.Name == bundle.AnnotationDefaultsPackage {
+			continue
+		}
+
+		// Describe package
+		pkg := describe(annotations, p)
+
+		// Attach to catalog tree
+		ringName, platformName, productName := locate(p.Name)
+		r, ok := rings[ringName]
+		if !ok {
+			r = &Ring{Name: ringName}
+			rings[ringName] = r
+			c.Rings = append(c.Rings, r)
+		}
+		product := r.product(platformName, productName)
+		product.Packages = append(product.Packages, pkg)
+
+		// Update team score
+		team := pkg.Owner
+		if team == "" {
+			team = Unowned
+		}
+		s, ok := teams[team]
+		if !ok {
+			s = &Score{Team: team}
+			teams[team] = s
+			c.Teams = append(c.Teams, s)
+		}
+		s.add(pkg)
+		c.Summary.add(pkg)
+	}
+
+	// Sort catalog
+	sort.SliceStable(c.Rings, func(i, j int) bool {
+		return ringOrder(c.Rings[i].Name) < ringOrder(c.Rings[j].Name)
+	})
+	for _, r := range c.Rings {
+		r.sort()
+	}
+	sort.Slice(c.Teams, func(i, j int) bool {
+		return c.Teams[i].Team < c.Teams[j].Team
+	})
+	for _, s := range c.Teams {
+		sort.Strings(s.Undocumented)
+	}
+	sort.Strings(c.Summary.Undocumented)
+
+	return c
+}
+
+// -----------------------------------------------------------------------------
+
+func describe(annotations *bundle.AnnotationResolver, p *bundlev1.Package) *Package {
+	archetypeName, _ := annotations.Lookup(p, archetype.Annotation)
+	pkg := &Package{
+		Path:        p.Name,
+		Description: annotations.Description(p),
+		Owner:       annotations.Owner(p),
+		Rotation:    annotations.RotationPeriod(p),
+		Archetype:   archetypeName,
+		Locked:      p.Secrets != nil && p.Secrets.Locked != nil,
+		Keys:        []*Key{},
+	}
+	if reason, ok := bundle.QuarantineReason(p); ok {
+		pkg.Quarantine = reason
+	}
+
+	// Describe keys, values are never read
+	if p.Secrets != nil {
+		for _, kv := range p.Secrets.Data {
+			if kv == nil {
+				continue
+			}
+			pkg.Keys = append(pkg.Keys, &Key{
+				Name:        kv.Key,
+				Type:        kv.Type,
+				Description: annotations.KeyDescription(p, kv.Key),
+			})
+		}
+	}
+	sort.Slice(pkg.Keys, func(i, j int) bool {
+		return pkg.Keys[i].Name < pkg.Keys[j].Name
+	})
+
+	return pkg
+}
+
+// locate returns ring, platform and product names of the given package path.
+func locate(path string) (ring, platform, product string) {
+	s, err := csov1pkg.Pack(path, nil)
+	if err != nil {
+		return OtherRing, "", ""
+	}
+
+	ring = csov1pkg.ToRingName(s.RingLevel)
+	switch p := s.Path.(type) {
+	case *csov1.Secret_Platform:
+		platform = p.Platform.Name
+	case *csov1.Secret_Product:
+		product = p.Product.Name
+	case *csov1.Secret_Application:
+		platform = p.Application.PlatformName
+		product = p.Application.ProductName
+	}
+
+	return ring, platform, product
+}
+
+// ringOrder returns the sort order of the given ring name.
+func ringOrder(name string) int {
+	if name == OtherRing {
+		return int(csov1.RingLevel_RING_LEVEL_ARTIFACT) + 1
+	}
+	return int(csov1pkg.FromRingName(name))
+}
+
+func (r *Ring) product(platformName, productName string) *Product {
+	var platform *Platform
+	for _, p := range r.Platforms {
+		if p.Name == platformName {
+			platform = p
+			break
+		}
+	}
+	if platform == nil {
+		platform = &Platform{Name: platformName}
+		r.Platforms = append(r.Platforms, platform)
+	}
+
+	for _, p := range platform.Products {
+		if p.Name == productName {
+			return p
+		}
+	}
+	product := &Product{Name: productName}
+	platform.Products = append(platform.Products, product)
+
+	return product
+}
+
+func (r *Ring) sort() {
+	sort.Slice(r.Platforms, func(i, j int) bool {
+		return r.Platforms[i].Name < r.Platforms[j].Name
+	})
+	for _, platform := range r.Platforms {
+		sort.Slice(platform.Products, func(i, j int) bool {
+			return platform.Products[i].Name < platform.Products[j].Name
+		})
+		for _, product := range platform.Products {
+			sort.Slice(product.Packages, func(i, j int) bool {
+				return product.Packages[i].Path < product.Packages[j].Path
+			})
+		}
+	}
+}
+
+func (s *Score) add(p *Package) {
+	s.Packages++
+	if p.Documented() {
+		s.Documented++
+	} else {
+		s.Undocumented = append(s.Undocumented, p.Path)
+	}
+	s.Score = s.Documented * 100 / s.Packages
+}
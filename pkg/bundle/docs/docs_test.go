@@ -0,0 +1,207 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package docs
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/archetype"
+)
+
+func fixture() *bundlev1.Bundle {
+	return &bundlev1.Bundle{
+		Packages: []*bundlev1.Package{
+			{
+				Name: "app/production/security/harp/v1.0.0/server/database",
+				Annotations: map[string]string{
+					bundle.OwnerAnnotation:                             "security",
+					bundle.RotationAnnotation:                          "90d",
+					archetype.Annotation:                               "database",
+					bundle.KeyDescriptionAnnotationPrefix + "PASSWORD": "Database password | rotated by vault",
+				},
+				Secrets: &bundlev1.SecretChain{
+					Annotations: map[string]string{
+						"description": "Harp server database credentials",
+					},
+					Data: []*bundlev1.KV{
+						{Key: "USER", Type: "string", Value: []byte("never-rendered-user")},
+						{Key: "PASSWORD", Type: "string", Value: []byte("never-rendered-password")},
+					},
+				},
+			},
+			{
+				Name: "app/production/security/harp/v1.0.0/server/http/session",
+				Annotations: map[string]string{
+					bundle.OwnerAnnotation: "security",
+				},
+				Secrets: &bundlev1.SecretChain{
+					Data: []*bundlev1.KV{
+						{Key: "key", Type: "string", Value: []byte("never-rendered-key")},
+					},
+				},
+			},
+			{
+				Name: "platform/production/customer1/us-east-1/postgresql/admin_credentials",
+				Annotations: map[string]string{
+					bundle.DescriptionAnnotation: "Postgresql administrator account",
+					bundle.OwnerAnnotation:       "database",
+					bundle.QuarantineAnnotation:  "leaked in CI logs",
+				},
+				Secrets: &bundlev1.SecretChain{
+					Locked: wrapperspb.Bytes([]byte("never-rendered-locked")),
+				},
+			},
+			{
+				Name: "product/ece/v1.0.0/adminconsole/authentication/otp/okta_api_key",
+				Annotations: map[string]string{
+					bundle.DescriptionAnnotation: "Okta API key",
+				},
+				Secrets: &bundlev1.SecretChain{
+					Data: []*bundlev1.KV{
+						{Key: "API_KEY", Type: "string", Value: []byte("never-rendered-api-key")},
+					},
+				},
+			},
+			{
+				Name: "infra/aws/essp-dev/us-east-1/rds/adminconsole/root_creds",
+				Secrets: &bundlev1.SecretChain{
+					Data: []*bundlev1.KV{},
+				},
+			},
+			{
+				Name: "legacy/token",
+				Annotations: map[string]string{
+					bundle.ArchivedAnnotation: "true",
+				},
+			},
+		},
+	}
+}
+
+func TestBuild(t *testing.T) {
+	c := Build(fixture())
+
+	// Summary
+	if c.Summary.Packages != 5 || c.Summary.Documented != 3 || c.Summary.Score != 60 {
+		t.Errorf("unexpected summary %+v", c.Summary)
+	}
+
+	// Teams
+	got := []string{}
+	for _, s := range c.Teams {
+		got = append(got, s.Team)
+	}
+	if strings.Join(got, ",") != "database,security,unowned" {
+		t.Errorf("unexpected teams %v", got)
+	}
+	if s := c.Teams[1]; s.Score != 50 || len(s.Undocumented) != 1 {
+		t.Errorf("unexpected security score %+v", s)
+	}
+
+	// Rings
+	got = []string{}
+	for _, r := range c.Rings {
+		got = append(got, r.Name)
+	}
+	if strings.Join(got, ",") != "infra,platform,product,app" {
+		t.Errorf("unexpected rings %v", got)
+	}
+
+	// Inherited annotations
+	b := fixture()
+	if err := bundle.AttachAnnotationDefaults(b, &bundle.AnnotationDefaults{
+		Spec: bundle.AnnotationDefaultsSpec{
+			Prefixes: []bundle.PrefixAnnotations{
+				{Prefix: "infra/aws", Annotations: map[string]string{
+					bundle.OwnerAnnotation:       "cloud",
+					bundle.DescriptionAnnotation: "AWS credentials",
+				}},
+			},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	c = Build(b)
+	if c.Summary.Packages != 5 || c.Summary.Documented != 4 {
+		t.Errorf("unexpected summary with inherited annotations %+v", c.Summary)
+	}
+	if c.Teams[0].Team != "cloud" || c.Teams[0].Score != 100 {
+		t.Errorf("unexpected inherited owner score %+v", c.Teams[0])
+	}
+
+	// Nil bundle
+	if c := Build(nil); c.Summary.Packages != 0 {
+		t.Errorf("unexpected catalog for nil bundle")
+	}
+}
+
+func TestRender_Markdown(t *testing.T) {
+	files, err := Render(Build(fixture()), DefaultTheme())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	golden, err := ioutil.ReadFile(filepath.Join("testdata", "index.md.golden"))
+	if err != nil {
+		t.Fatalf("unable to read golden file: %v", err)
+	}
+	if got := string(files["index.md"]); got != string(golden) {
+		t.Errorf("unexpected markdown, got:\n%s", got)
+	}
+
+	// Values must never be rendered
+	for name, body := range files {
+		if strings.Contains(string(body), "never-rendered") {
+			t.Errorf("secret value rendered in '%s'", name)
+		}
+	}
+}
+
+func TestLoadTheme(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := LoadTheme(dir); err == nil {
+		t.Fatal("error should be raised for empty theme")
+	}
+
+	body := "{{ range .Teams }}{{ .Team }}={{ .Score }}\n{{ end }}"
+	if err := ioutil.WriteFile(filepath.Join(dir, "teams.txt.tmpl"), []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "README"), []byte("ignored"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	theme, err := LoadTheme(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files, err := Render(Build(fixture()), theme)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(files) != 1 || string(files["teams.txt"]) != "database=100\nsecurity=50\nunowned=50\n" {
+		t.Errorf("unexpected rendered files %q", files)
+	}
+}
@@ -0,0 +1,153 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package docs
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// TemplateExtension is the file extension of theme templates.
+const TemplateExtension = ".tmpl"
+
+// Theme maps generated file names to their Go template. Files with a ".html"
+// extension are rendered using html/template.
+type Theme map[string]string
+
+// DefaultTheme returns the built-in theme generating a Markdown catalog and its
+// HTML counterpart.
+func DefaultTheme() Theme {
+	return Theme{
+		"index.md":   markdownTemplate,
+		"index.html": htmlTemplate,
+	}
+}
+
+// LoadTheme loads a theme from the given directory. Each "<name>.tmpl" file is
+// rendered as "<name>".
+func LoadTheme(dir string) (Theme, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list theme templates: %w", err)
+	}
+
+	theme := Theme{}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), TemplateExtension) {
+			continue
+		}
+
+		body, err := ioutil.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read theme template '%s': %w", e.Name(), err)
+		}
+		theme[strings.TrimSuffix(e.Name(), TemplateExtension)] = string(body)
+	}
+	if len(theme) == 0 {
+		return nil, fmt.Errorf("no '*%s' template found in '%s'", TemplateExtension, dir)
+	}
+
+	// No error
+	return theme, nil
+}
+
+// Render the catalog using the given theme. It returns generated file contents
+// indexed by file name.
+func Render(c *Catalog, theme Theme) (map[string][]byte, error) {
+	// Check arguments
+	if c == nil {
+		return nil, fmt.Errorf("unable to render a nil catalog")
+	}
+	if len(theme) == 0 {
+		return nil, fmt.Errorf("unable to render catalog with an empty theme")
+	}
+
+	names := make([]string, 0, len(theme))
+	for name := range theme {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	files := map[string][]byte{}
+	for _, name := range names {
+		var buf bytes.Buffer
+		if err := execute(&buf, name, theme[name], c); err != nil {
+			return nil, fmt.Errorf("unable to render '%s': %w", name, err)
+		}
+		files[name] = buf.Bytes()
+	}
+
+	// No error
+	return files, nil
+}
+
+// Write generated files to the given output directory.
+func Write(outputDir string, files map[string][]byte) error {
+	for name, body := range files {
+		path := filepath.Join(outputDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return fmt.Errorf("unable to create output directory for '%s': %w", name, err)
+		}
+		if err := ioutil.WriteFile(path, body, 0o644); err != nil {
+			return fmt.Errorf("unable to write '%s': %w", name, err)
+		}
+	}
+
+	// No error
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func execute(buf *bytes.Buffer, name, body string, c *Catalog) error {
+	if strings.EqualFold(filepath.Ext(name), ".html") {
+		t, err := htmltemplate.New(name).Option("missingkey=error").Funcs(htmltemplate.FuncMap(funcMap)).Parse(body)
+		if err != nil {
+			return err
+		}
+		return t.Execute(buf, c)
+	}
+
+	t, err := template.New(name).Option("missingkey=error").Funcs(funcMap).Parse(body)
+	if err != nil {
+		return err
+	}
+	return t.Execute(buf, c)
+}
+
+var funcMap = template.FuncMap{
+	// cell escapes a value used in a Markdown table cell.
+	"cell": func(value string) string {
+		value = strings.ReplaceAll(value, "|", `\|`)
+		return strings.Join(strings.Fields(value), " ")
+	},
+	// default returns the fallback when the value is blank.
+	"default": func(fallback, value string) string {
+		if strings.TrimSpace(value) == "" {
+			return fallback
+		}
+		return value
+	},
+}
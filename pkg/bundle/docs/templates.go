@@ -0,0 +1,141 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package docs
+
+const markdownTemplate = `# Secret catalog
+
+{{ .Summary.Packages }} packages, {{ .Summary.Documented }} documented ({{ .Summary.Score }}%).
+
+## Documentation completeness
+
+| Team | Packages | Documented | Score |
+| ---- | -------- | ---------- | ----- |
+{{- range .Teams }}
+| {{ cell .Team }} | {{ .Packages }} | {{ .Documented }} | {{ .Score }}% |
+{{- end }}
+{{- range .Teams }}{{ if .Undocumented }}
+
+### Undocumented packages of {{ .Team }}
+{{ range .Undocumented }}
+- ` + "`{{ . }}`" + `
+{{- end }}
+{{- end }}{{ end }}
+{{- range .Rings }}
+
+## Ring {{ .Name }}
+{{- range .Platforms }}{{ if .Name }}
+
+### Platform {{ .Name }}
+{{- end }}
+{{- range .Products }}{{ if .Name }}
+
+#### Product {{ .Name }}
+{{- end }}
+{{- range .Packages }}
+
+##### ` + "`{{ .Path }}`" + `
+
+{{ default "_No description._" .Description }}
+
+- Owner: {{ default "unowned" .Owner }}
+- Rotation: {{ default "unspecified" .Rotation }}
+{{- if .Archetype }}
+- Archetype: {{ .Archetype }}
+{{- end }}
+{{- if .Quarantine }}
+- Quarantined: {{ .Quarantine }}
+{{- end }}
+{{- if .Locked }}
+
+_Package is locked, keys are not listed._
+{{- else if .Keys }}
+
+| Key | Type | Description |
+| --- | ---- | ----------- |
+{{- range .Keys }}
+| ` + "`{{ cell .Name }}`" + ` | {{ cell .Type }} | {{ cell .Description }} |
+{{- end }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- end }}
+`
+
+const htmlTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Secret catalog</title>
+</head>
+<body>
+<h1>Secret catalog</h1>
+<p>{{ .Summary.Packages }} packages, {{ .Summary.Documented }} documented ({{ .Summary.Score }}%).</p>
+<h2>Documentation completeness</h2>
+<table>
+<tr><th>Team</th><th>Packages</th><th>Documented</th><th>Score</th></tr>
+{{- range .Teams }}
+<tr><td>{{ .Team }}</td><td>{{ .Packages }}</td><td>{{ .Documented }}</td><td>{{ .Score }}%</td></tr>
+{{- end }}
+</table>
+{{- range .Teams }}{{ if .Undocumented }}
+<h3>Undocumented packages of {{ .Team }}</h3>
+<ul>
+{{- range .Undocumented }}
+<li><code>{{ . }}</code></li>
+{{- end }}
+</ul>
+{{- end }}{{ end }}
+{{- range .Rings }}
+<h2>Ring {{ .Name }}</h2>
+{{- range .Platforms }}{{ if .Name }}
+<h3>Platform {{ .Name }}</h3>
+{{- end }}
+{{- range .Products }}{{ if .Name }}
+<h4>Product {{ .Name }}</h4>
+{{- end }}
+{{- range .Packages }}
+<h5><code>{{ .Path }}</code></h5>
+<p>{{ default "No description." .Description }}</p>
+<ul>
+<li>Owner: {{ default "unowned" .Owner }}</li>
+<li>Rotation: {{ default "unspecified" .Rotation }}</li>
+{{- if .Archetype }}
+<li>Archetype: {{ .Archetype }}</li>
+{{- end }}
+{{- if .Quarantine }}
+<li>Quarantined: {{ .Quarantine }}</li>
+{{- end }}
+</ul>
+{{- if .Locked }}
+<p><em>Package is locked, keys are not listed.</em></p>
+{{- else if .Keys }}
+<table>
+<tr><th>Key</th><th>Type</th><th>Description</th></tr>
+{{- range .Keys }}
+<tr><td><code>{{ .Name }}</code></td><td>{{ .Type }}</td><td>{{ .Description }}</td></tr>
+{{- end }}
+</table>
+{{- end }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- end }}
+</body>
+</html>
+`
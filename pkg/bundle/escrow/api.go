@@ -0,0 +1,378 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package escrow provides secret escrow export and recovery.
+//
+// Escrowed values are individually sealed for the escrow recipient, so that a
+// single entry can be recovered without exposing the others. The escrow
+// manifest lists all exported entries with their ciphertext digest and is
+// signed by the operator.
+package escrow
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/nacl/box"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle/secret"
+	"github.com/elastic/harp/pkg/bundle/selector"
+	"github.com/elastic/harp/pkg/container/attestation"
+	"github.com/elastic/harp/pkg/container/identity"
+)
+
+const (
+	// ManifestAnnotation is the escrow bundle annotation holding the signed
+	// escrow manifest envelope.
+	ManifestAnnotation = "harp.elastic.co/v1/escrow#manifest"
+	// ManifestPayloadType is the DSSE payload type of escrow manifests.
+	ManifestPayloadType = "application/vnd.harp.v1.EscrowManifest+json"
+	// DigestPrefix is the prefix of the entry ciphertext digests.
+	DigestPrefix = "sha256:"
+)
+
+var (
+	// ErrEntryNotFound is raised when the requested entry is not escrowed.
+	ErrEntryNotFound = errors.New("escrow: entry not found")
+	// ErrRecipientMismatch is raised when the escrow key doesn't match the
+	// manifest recipient.
+	ErrRecipientMismatch = errors.New("escrow: key doesn't match manifest recipient")
+	// ErrDigestMismatch is raised when an escrowed value doesn't match the
+	// manifest digest.
+	ErrDigestMismatch = errors.New("escrow: value doesn't match manifest digest")
+)
+
+// Entry describes an escrowed secret value.
+type Entry struct {
+	Path   string `json:"path"`
+	Key    string `json:"key"`
+	KeyID  string `json:"keyId"`
+	Digest string `json:"digest"`
+}
+
+// Manifest describes the content of an escrow bundle.
+type Manifest struct {
+	// Recipient is the escrow public key (base64url encoded x25519 key).
+	Recipient string  `json:"recipient"`
+	Entries   []Entry `json:"entries"`
+}
+
+// Lookup returns the manifest entry matching the given path and key.
+func (m *Manifest) Lookup(path, key string) (*Entry, bool) {
+	for i := range m.Entries {
+		if m.Entries[i].Path == path && m.Entries[i].Key == key {
+			return &m.Entries[i], true
+		}
+	}
+	return nil, false
+}
+
+// KeyID returns the hex encoded SHA-256 digest of the given escrow public key.
+func KeyID(pub *[32]byte) string {
+	h := sha256.Sum256(pub[:])
+	return hex.EncodeToString(h[:])
+}
+
+// ParseRecipient decodes an escrow public key from an identity document or a
+// base64url encoded x25519 public key.
+func ParseRecipient(raw []byte) (*[32]byte, error) {
+	encoded := strings.TrimSpace(string(raw))
+
+	// Identity document
+	if strings.HasPrefix(encoded, "{") {
+		id, err := identity.FromReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode recipient identity: %w", err)
+		}
+		encoded = id.Public
+	}
+
+	return decodeKey(encoded, "recipient public key")
+}
+
+// ParsePrivateKey decodes a base64url encoded x25519 escrow private key.
+func ParsePrivateKey(encoded string) (*[32]byte, error) {
+	return decodeKey(strings.TrimSpace(encoded), "escrow private key")
+}
+
+// Export seals all values of the packages matching the given specification
+// for the escrow recipient. It returns the escrow bundle and its manifest.
+//
+// Locked packages can't be escrowed and raise an error.
+func Export(b *bundlev1.Bundle, spec selector.Specification, recipient *[32]byte) (*bundlev1.Bundle, *Manifest, error) {
+	// Check arguments
+	if b == nil {
+		return nil, nil, fmt.Errorf("unable to export a nil bundle")
+	}
+	if spec == nil {
+		return nil, nil, fmt.Errorf("unable to export without package selector")
+	}
+	if recipient == nil {
+		return nil, nil, fmt.Errorf("unable to export without escrow recipient")
+	}
+
+	keyID := KeyID(recipient)
+	out := &bundlev1.Bundle{
+		Packages: []*bundlev1.Package{},
+	}
+	m := &Manifest{
+		Recipient: base64.RawURLEncoding.EncodeToString(recipient[:]),
+		Entries:   []Entry{},
+	}
+
+	for _, p := range b.Packages {
+		if p == nil || !spec.IsSatisfiedBy(p) {
+			continue
+		}
+		if p.Secrets == nil {
+			continue
+		}
+		if p.Secrets.Locked != nil {
+			return nil, nil, fmt.Errorf("unable to escrow locked package '%s'", p.Name)
+		}
+
+		// Seal each value individually
+		ep := &bundlev1.Package{
+			Name:        p.Name,
+			Labels:      copyMap(p.Labels),
+			Annotations: copyMap(p.Annotations),
+			Secrets: &bundlev1.SecretChain{
+				Version: p.Secrets.Version,
+				Data:    make([]*bundlev1.KV, 0, len(p.Secrets.Data)),
+			},
+		}
+		for _, kv := range p.Secrets.Data {
+			ciphertext, err := box.SealAnonymous(nil, kv.Value, recipient, rand.Reader)
+			if err != nil {
+				return nil, nil, fmt.Errorf("unable to seal '%s#%s': %w", p.Name, kv.Key, err)
+			}
+			ep.Secrets.Data = append(ep.Secrets.Data, &bundlev1.KV{
+				Key:   kv.Key,
+				Type:  kv.Type,
+				Value: ciphertext,
+			})
+			m.Entries = append(m.Entries, Entry{
+				Path:   p.Name,
+				Key:    kv.Key,
+				KeyID:  keyID,
+				Digest: digest(ciphertext),
+			})
+		}
+		out.Packages = append(out.Packages, ep)
+	}
+
+	// Sort entries for stable manifests
+	sort.SliceStable(m.Entries, func(i, j int) bool {
+		if m.Entries[i].Path == m.Entries[j].Path {
+			return m.Entries[i].Key < m.Entries[j].Key
+		}
+		return m.Entries[i].Path < m.Entries[j].Path
+	})
+
+	// No error
+	return out, m, nil
+}
+
+// Sign wraps the manifest in a DSSE envelope signed with the operator key.
+func Sign(m *Manifest, signer crypto.Signer) (*attestation.Envelope, error) {
+	// Check arguments
+	if m == nil {
+		return nil, fmt.Errorf("unable to sign a nil manifest")
+	}
+
+	// Encode manifest
+	payload, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode manifest: %w", err)
+	}
+
+	return attestation.SignPayload(ManifestPayloadType, payload, signer)
+}
+
+// Verify checks the envelope signatures with the operator keys returned by
+// the given resolver and returns the enclosed manifest.
+func Verify(env *attestation.Envelope, resolve attestation.KeyResolver) (*Manifest, error) {
+	payload, err := attestation.VerifyPayload(env, ManifestPayloadType, resolve)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode manifest
+	var m Manifest
+	if err := json.Unmarshal(payload, &m); err != nil {
+		return nil, fmt.Errorf("unable to decode manifest: %w", err)
+	}
+
+	// No error
+	return &m, nil
+}
+
+// Attach stores the signed manifest envelope in the escrow bundle.
+func Attach(b *bundlev1.Bundle, env *attestation.Envelope) error {
+	// Check arguments
+	if b == nil {
+		return fmt.Errorf("unable to attach manifest to a nil bundle")
+	}
+
+	raw, err := json.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("unable to encode manifest envelope: %w", err)
+	}
+
+	if b.Annotations == nil {
+		b.Annotations = map[string]string{}
+	}
+	b.Annotations[ManifestAnnotation] = string(raw)
+
+	// No error
+	return nil
+}
+
+// Envelope returns the signed manifest envelope stored in the escrow bundle.
+func Envelope(b *bundlev1.Bundle) (*attestation.Envelope, error) {
+	// Check arguments
+	if b == nil {
+		return nil, fmt.Errorf("unable to read manifest from a nil bundle")
+	}
+
+	raw, ok := b.Annotations[ManifestAnnotation]
+	if !ok {
+		return nil, fmt.Errorf("bundle has no escrow manifest")
+	}
+
+	var env attestation.Envelope
+	if err := json.Unmarshal([]byte(raw), &env); err != nil {
+		return nil, fmt.Errorf("unable to decode manifest envelope: %w", err)
+	}
+
+	// No error
+	return &env, nil
+}
+
+// Recover opens the escrowed value of the given package path and key using
+// the escrow private key. The value is checked against the manifest before
+// decryption.
+func Recover(b *bundlev1.Bundle, m *Manifest, path, key string, priv *[32]byte) (interface{}, error) {
+	// Check arguments
+	if b == nil {
+		return nil, fmt.Errorf("unable to recover from a nil bundle")
+	}
+	if m == nil {
+		return nil, fmt.Errorf("unable to recover without manifest")
+	}
+	if priv == nil {
+		return nil, fmt.Errorf("unable to recover without escrow private key")
+	}
+
+	// Check recipient
+	pubRaw, err := curve25519.X25519(priv[:], curve25519.Basepoint)
+	if err != nil {
+		return nil, fmt.Errorf("unable to derive escrow public key: %w", err)
+	}
+	var pub [32]byte
+	copy(pub[:], pubRaw)
+	if base64.RawURLEncoding.EncodeToString(pub[:]) != m.Recipient {
+		return nil, ErrRecipientMismatch
+	}
+
+	// Lookup entry
+	entry, ok := m.Lookup(path, key)
+	if !ok {
+		return nil, fmt.Errorf("unable to recover '%s#%s': %w", path, key, ErrEntryNotFound)
+	}
+	if entry.KeyID != KeyID(&pub) {
+		return nil, fmt.Errorf("unable to recover '%s#%s': %w", path, key, ErrRecipientMismatch)
+	}
+	ciphertext, ok := lookupValue(b, path, key)
+	if !ok {
+		return nil, fmt.Errorf("unable to recover '%s#%s': %w", path, key, ErrEntryNotFound)
+	}
+	if digest(ciphertext) != entry.Digest {
+		return nil, fmt.Errorf("unable to recover '%s#%s': %w", path, key, ErrDigestMismatch)
+	}
+
+	// Open the value
+	packed, ok := box.OpenAnonymous(nil, ciphertext, &pub, priv)
+	if !ok {
+		return nil, fmt.Errorf("unable to open '%s#%s' with the given escrow key", path, key)
+	}
+
+	var value interface{}
+	if err := secret.Unpack(packed, &value); err != nil {
+		return nil, fmt.Errorf("unable to unpack '%s#%s': %w", path, key, err)
+	}
+
+	// No error
+	return value, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func decodeKey(encoded, name string) (*[32]byte, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode %s: %w", name, err)
+	}
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("invalid %s length", name)
+	}
+
+	var key [32]byte
+	copy(key[:], raw)
+	return &key, nil
+}
+
+func digest(ciphertext []byte) string {
+	h := sha256.Sum256(ciphertext)
+	return DigestPrefix + hex.EncodeToString(h[:])
+}
+
+func lookupValue(b *bundlev1.Bundle, path, key string) ([]byte, bool) {
+	for _, p := range b.Packages {
+		if p == nil || p.Name != path || p.Secrets == nil {
+			continue
+		}
+		for _, kv := range p.Secrets.Data {
+			if kv.Key == key {
+				return kv.Value, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func copyMap(in map[string]string) map[string]string {
+	if in == nil {
+		return nil
+	}
+	out := make(map[string]string, len(in))
+	for k, v := range in {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,251 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package escrow
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/jmespath/go-jmespath"
+	"golang.org/x/crypto/nacl/box"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle/secret"
+	"github.com/elastic/harp/pkg/bundle/selector"
+	"github.com/elastic/harp/pkg/container/attestation"
+)
+
+func testBundle(t *testing.T) *bundlev1.Bundle {
+	t.Helper()
+
+	pkg := func(name string, escrowed bool, data map[string]string) *bundlev1.Package {
+		p := &bundlev1.Package{
+			Name:    name,
+			Secrets: &bundlev1.SecretChain{},
+		}
+		if escrowed {
+			p.Annotations = map[string]string{"escrow": "required"}
+		}
+		for k, v := range data {
+			packed, err := secret.Pack(v)
+			if err != nil {
+				t.Fatalf("unable to pack secret: %v", err)
+			}
+			p.Secrets.Data = append(p.Secrets.Data, &bundlev1.KV{Key: k, Type: "string", Value: packed})
+		}
+		return p
+	}
+
+	return &bundlev1.Bundle{
+		Packages: []*bundlev1.Package{
+			pkg("infra/pki/root-ca", true, map[string]string{"key": "root-ca-key", "cert": "root-ca-cert"}),
+			pkg("app/production/codesign", true, map[string]string{"key": "codesign-key"}),
+			pkg("app/production/database", false, map[string]string{"password": "db-password"}),
+		},
+	}
+}
+
+func escrowSelector(t *testing.T) selector.Specification {
+	t.Helper()
+
+	exp, err := jmespath.Compile("annotations.escrow == 'required'")
+	if err != nil {
+		t.Fatalf("unable to compile selector: %v", err)
+	}
+	return selector.MatchJMESPath(exp)
+}
+
+func staticResolver(pub crypto.PublicKey) attestation.KeyResolver {
+	return func(attestation.Signature) (crypto.PublicKey, error) {
+		return pub, nil
+	}
+}
+
+func TestExport(t *testing.T) {
+	pub, _, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, m, err := Export(testBundle(t), escrowSelector(t), pub)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Only escrowed packages are exported
+	if len(out.Packages) != 2 {
+		t.Fatalf("expected 2 packages, got %d", len(out.Packages))
+	}
+	for _, p := range out.Packages {
+		if p.Name == "app/production/database" {
+			t.Errorf("unexpected package '%s'", p.Name)
+		}
+	}
+
+	// Manifest lists all escrowed values
+	expected := []string{"app/production/codesign#key", "infra/pki/root-ca#cert", "infra/pki/root-ca#key"}
+	if len(m.Entries) != len(expected) {
+		t.Fatalf("expected %d entries, got %d", len(expected), len(m.Entries))
+	}
+	for i, e := range m.Entries {
+		if got := e.Path + "#" + e.Key; got != expected[i] {
+			t.Errorf("entry %d: expected '%s', got '%s'", i, expected[i], got)
+		}
+		if e.KeyID != KeyID(pub) {
+			t.Errorf("entry %d: unexpected key id '%s'", i, e.KeyID)
+		}
+	}
+	if m.Recipient != base64.RawURLEncoding.EncodeToString(pub[:]) {
+		t.Errorf("unexpected recipient '%s'", m.Recipient)
+	}
+
+	// Values are sealed
+	for _, p := range out.Packages {
+		for _, kv := range p.Secrets.Data {
+			var v interface{}
+			if err := secret.Unpack(kv.Value, &v); err == nil {
+				t.Errorf("value of '%s#%s' is not sealed", p.Name, kv.Key)
+			}
+		}
+	}
+}
+
+func TestExport_Locked(t *testing.T) {
+	pub, _, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := testBundle(t)
+	b.Packages[0].Secrets.Locked = &wrappers.BytesValue{Value: []byte("locked")}
+	if _, _, err := Export(b, escrowSelector(t), pub); err == nil {
+		t.Fatal("expected error for locked package")
+	}
+}
+
+func TestRecover(t *testing.T) {
+	pub, priv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherPriv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, m, err := Export(testBundle(t), escrowSelector(t), pub)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Single entry recovery
+	v, err := Recover(out, m, "infra/pki/root-ca", "key", priv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v != "root-ca-key" {
+		t.Errorf("expected 'root-ca-key', got '%v'", v)
+	}
+
+	// Unknown entry
+	if _, err := Recover(out, m, "app/production/database", "password", priv); !errors.Is(err, ErrEntryNotFound) {
+		t.Errorf("expected entry not found error, got %v", err)
+	}
+
+	// Wrong escrow key
+	if _, err := Recover(out, m, "infra/pki/root-ca", "key", otherPriv); !errors.Is(err, ErrRecipientMismatch) {
+		t.Errorf("expected recipient mismatch error, got %v", err)
+	}
+
+	// Tampered value
+	out.Packages[0].Secrets.Data[0].Value, _ = box.SealAnonymous(nil, []byte("tampered"), pub, rand.Reader)
+	tampered := out.Packages[0]
+	if _, err := Recover(out, m, tampered.Name, tampered.Secrets.Data[0].Key, priv); !errors.Is(err, ErrDigestMismatch) {
+		t.Errorf("expected digest mismatch error, got %v", err)
+	}
+}
+
+func TestManifestSignature(t *testing.T) {
+	pub, _, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	operatorPub, operatorPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, m, err := Export(testBundle(t), escrowSelector(t), pub)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	env, err := Sign(m, operatorPriv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := Attach(out, env); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Roundtrip through the bundle
+	attached, err := Envelope(out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	verified, err := Verify(attached, staticResolver(operatorPub))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(verified.Entries) != len(m.Entries) {
+		t.Errorf("expected %d entries, got %d", len(m.Entries), len(verified.Entries))
+	}
+
+	// Wrong operator key
+	if _, err := Verify(attached, staticResolver(otherPub)); !errors.Is(err, attestation.ErrInvalidSignature) {
+		t.Errorf("expected invalid signature error, got %v", err)
+	}
+
+	// Tampered manifest
+	m.Entries = m.Entries[1:]
+	tampered, err := Sign(m, operatorPriv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	attached.Payload = tampered.Payload
+	if _, err := Verify(attached, staticResolver(operatorPub)); !errors.Is(err, attestation.ErrInvalidSignature) {
+		t.Errorf("expected invalid signature error, got %v", err)
+	}
+
+	// Attestation envelopes are rejected
+	statement, err := attestation.Sign(&attestation.Statement{}, operatorPriv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := Verify(statement, staticResolver(operatorPub)); err == nil {
+		t.Error("expected error for attestation envelope")
+	}
+}
@@ -0,0 +1,255 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/sdk/log"
+)
+
+// ExpiresAnnotation holds the package expiration date (RFC3339).
+const ExpiresAnnotation = "harp.elastic.co/v1/package#expires"
+
+// ErrPackageExpired is raised when an expired package is rejected.
+var ErrPackageExpired = errors.New("bundle: package is expired")
+
+// ExpiredPackagePolicy describes how expired packages are handled when a
+// bundle is loaded.
+type ExpiredPackagePolicy int
+
+const (
+	// ExpiredPackageKeep loads expired packages as is.
+	ExpiredPackageKeep ExpiredPackagePolicy = iota
+	// ExpiredPackageDrop removes expired packages from the loaded bundle.
+	ExpiredPackageDrop
+	// ExpiredPackageError refuses to load a bundle with expired packages.
+	ExpiredPackageError
+)
+
+var expiredPackagePolicyNames = map[ExpiredPackagePolicy]string{
+	ExpiredPackageKeep:  "keep",
+	ExpiredPackageDrop:  "drop",
+	ExpiredPackageError: "error",
+}
+
+func (p ExpiredPackagePolicy) String() string {
+	if name, ok := expiredPackagePolicyNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("ExpiredPackagePolicy(%d)", int(p))
+}
+
+// ParseExpiredPackagePolicy returns the policy matching the given name
+// (keep, drop, error). A blank name returns the default keep policy.
+func ParseExpiredPackagePolicy(name string) (ExpiredPackagePolicy, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return ExpiredPackageKeep, nil
+	}
+	for p, n := range expiredPackagePolicyNames {
+		if n == name {
+			return p, nil
+		}
+	}
+
+	return ExpiredPackageKeep, fmt.Errorf("invalid expired package policy '%s', expected keep, drop or error", name)
+}
+
+// ExpiresAt returns the expiration date of the given package. The boolean is
+// false when the package is not annotated.
+func ExpiresAt(p *bundlev1.Package) (time.Time, bool, error) {
+	return (*AnnotationResolver)(nil).ExpiresAt(p)
+}
+
+// IsExpired returns true if the given package is expired at the given time.
+// Packages with an invalid expiration date are considered as expired.
+func IsExpired(p *bundlev1.Package, now time.Time) bool {
+	return (*AnnotationResolver)(nil).IsExpired(p, now)
+}
+
+// ApplyExpiredPackagePolicy handles expired packages of the given bundle
+// according to the policy, and returns the dropped package count. The error
+// policy reports all expired packages at once. Expiration dates inherited
+// from the bundle annotation defaults are honored.
+func ApplyExpiredPackagePolicy(b *bundlev1.Bundle, policy ExpiredPackagePolicy, now time.Time) (int, error) {
+	// Check arguments
+	if b == nil {
+		return 0, fmt.Errorf("unable to process nil bundle")
+	}
+	if policy == ExpiredPackageKeep {
+		return 0, nil
+	}
+
+	// Resolve inherited annotations
+	annotations, err := InheritedAnnotations(b)
+	if err != nil {
+		return 0, fmt.Errorf("unable to resolve inherited annotations: %w", err)
+	}
+
+	kept := make([]*bundlev1.Package, 0, len(b.Packages))
+	expired := []string{}
+	for _, p := range b.Packages {
+		if !annotations.IsExpired(p, now) {
+			kept = append(kept, p)
+			continue
+		}
+		expired = append(expired, p.Name)
+	}
+	if len(expired) == 0 {
+		return 0, nil
+	}
+
+	switch policy {
+	case ExpiredPackageError:
+		return 0, fmt.Errorf("%d expired package(s) found (%s): %w", len(expired), strings.Join(expired, ", "), ErrPackageExpired)
+	case ExpiredPackageDrop:
+		for _, name := range expired {
+			log.Bg().Info("Expired package dropped", zap.String("path", name))
+		}
+		b.Packages = kept
+	default:
+		return 0, fmt.Errorf("unsupported expired package policy '%s'", policy)
+	}
+
+	// No error
+	return len(expired), nil
+}
+
+// -----------------------------------------------------------------------------
+
+type loadOptions struct {
+	ctx           context.Context
+	expiredPolicy ExpiredPackagePolicy
+	now           func() time.Time
+	dropped       *int
+	decryptor     ValueDecryptor
+	decryptPolicy DecryptFailurePolicy
+	hidden        *int
+	mmap          bool
+	cacheDir      string
+	inherited     *AnnotationDefaults
+}
+
+// LoadOption defines the functional pattern for container loading settings.
+type LoadOption func(*loadOptions)
+
+// WithLoadContext sets the context used by load time hooks.
+func WithLoadContext(ctx context.Context) LoadOption {
+	return func(opts *loadOptions) {
+		opts.ctx = ctx
+	}
+}
+
+// WithExpiredPackagePolicy sets how expired packages are handled.
+func WithExpiredPackagePolicy(policy ExpiredPackagePolicy) LoadOption {
+	return func(opts *loadOptions) {
+		opts.expiredPolicy = policy
+	}
+}
+
+// WithLoadClock overrides the clock used to check package expiration.
+func WithLoadClock(now func() time.Time) LoadOption {
+	return func(opts *loadOptions) {
+		opts.now = now
+	}
+}
+
+// WithDroppedPackageCount sets the counter receiving the count of packages
+// dropped by the expired package policy.
+func WithDroppedPackageCount(count *int) LoadOption {
+	return func(opts *loadOptions) {
+		opts.dropped = count
+	}
+}
+
+// WithInheritedAnnotations attaches the given annotation defaults to the
+// loaded bundle, replacing the ones it holds.
+func WithInheritedAnnotations(d *AnnotationDefaults) LoadOption {
+	return func(opts *loadOptions) {
+		opts.inherited = d
+	}
+}
+
+func applyLoadOptions(b *bundlev1.Bundle, opts ...LoadOption) (*bundlev1.Bundle, error) {
+	// Apply options
+	dopts := newLoadOptions(opts...)
+
+	// Apply load policies
+	dropped, hidden, err := dopts.apply(b)
+	if err != nil {
+		return nil, err
+	}
+	if dopts.dropped != nil {
+		*dopts.dropped = dropped
+	}
+	if dopts.hidden != nil {
+		*dopts.hidden = hidden
+	}
+
+	// No error
+	return b, nil
+}
+
+func newLoadOptions(opts ...LoadOption) *loadOptions {
+	dopts := &loadOptions{
+		ctx:           context.Background(),
+		expiredPolicy: ExpiredPackageKeep,
+		now:           time.Now,
+	}
+	for _, o := range opts {
+		o(dopts)
+	}
+
+	return dopts
+}
+
+// apply handles annotation defaults, expired packages and externally
+// encrypted values, it returns dropped and hidden package counts.
+func (opts *loadOptions) apply(b *bundlev1.Bundle) (dropped, hidden int, err error) {
+	// Attach annotation defaults
+	if opts.inherited != nil {
+		if err = AttachAnnotationDefaults(b, opts.inherited); err != nil {
+			return 0, 0, fmt.Errorf("unable to attach annotation defaults: %w", err)
+		}
+	}
+
+	// Handle expired packages
+	dropped, err = ApplyExpiredPackagePolicy(b, opts.expiredPolicy, opts.now())
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// Decrypt externally encrypted values
+	if opts.decryptor != nil {
+		hidden, err = DecryptValues(opts.ctx, b, opts.decryptor, opts.decryptPolicy)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	// No error
+	return dropped, hidden, nil
+}
@@ -0,0 +1,145 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var expiryClock = func() time.Time {
+	return time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+}
+
+// expiryContainer returns a container with mixed expired and valid packages.
+func expiryContainer(t *testing.T) []byte {
+	t.Helper()
+
+	b := mustFromMap(t, map[string]KV{
+		"app/production/customer1/billing/database": {"password": "expired"},
+		"app/production/customer1/billing/api":      {"token": "valid"},
+		"app/production/customer1/billing/smtp":     {"password": "forever"},
+		"app/production/customer1/billing/broken":   {"password": "invalid"},
+		"app/production/customer1/billing/boundary": {"password": "now"},
+		"app/production/customer1/billing/archived": {"password": "expired"},
+	})
+	for _, p := range b.Packages {
+		switch p.Name {
+		case "app/production/customer1/billing/database", "app/production/customer1/billing/archived":
+			Annotate(p, ExpiresAnnotation, "2021-05-31T00:00:00Z")
+		case "app/production/customer1/billing/api":
+			Annotate(p, ExpiresAnnotation, "2021-06-02T00:00:00Z")
+		case "app/production/customer1/billing/broken":
+			Annotate(p, ExpiresAnnotation, "tomorrow")
+		case "app/production/customer1/billing/boundary":
+			Annotate(p, ExpiresAnnotation, "2021-06-01T12:00:00Z")
+		default:
+		}
+	}
+
+	var out bytes.Buffer
+	if err := ToContainerWriter(&out, b); err != nil {
+		t.Fatalf("unable to write container: %v", err)
+	}
+
+	return out.Bytes()
+}
+
+func TestFromContainerReader_ExpiredPackagePolicy(t *testing.T) {
+	raw := expiryContainer(t)
+
+	t.Run("keep", func(t *testing.T) {
+		b, err := FromContainerReader(bytes.NewReader(raw), WithLoadClock(expiryClock))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(b.Packages) != 6 {
+			t.Errorf("expected all packages to be kept, got %d", len(b.Packages))
+		}
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		dropped := 0
+		b, err := FromContainerReader(bytes.NewReader(raw),
+			WithExpiredPackagePolicy(ExpiredPackageDrop),
+			WithLoadClock(expiryClock),
+			WithDroppedPackageCount(&dropped),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if dropped != 4 {
+			t.Errorf("expected 4 dropped packages, got %d", dropped)
+		}
+		names := []string{}
+		for _, p := range b.Packages {
+			names = append(names, p.Name)
+		}
+		if got := strings.Join(names, ","); got != "app/production/customer1/billing/api,app/production/customer1/billing/smtp" {
+			t.Errorf("unexpected remaining packages: %s", got)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, err := FromContainerReader(bytes.NewReader(raw),
+			WithExpiredPackagePolicy(ExpiredPackageError),
+			WithLoadClock(expiryClock),
+		)
+		if !errors.Is(err, ErrPackageExpired) {
+			t.Fatalf("expected expired package error, got %v", err)
+		}
+		for _, name := range []string{"database", "archived", "broken", "boundary"} {
+			if !strings.Contains(err.Error(), "app/production/customer1/billing/"+name) {
+				t.Errorf("expected '%s' to be reported in %q", name, err.Error())
+			}
+		}
+		if strings.Contains(err.Error(), "billing/api") || strings.Contains(err.Error(), "billing/smtp") {
+			t.Errorf("valid packages must not be reported: %q", err.Error())
+		}
+	})
+
+	t.Run("error without expired package", func(t *testing.T) {
+		_, err := FromContainerReader(bytes.NewReader(raw),
+			WithExpiredPackagePolicy(ExpiredPackageError),
+			WithLoadClock(func() time.Time { return time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC) }),
+		)
+		if err == nil || !strings.Contains(err.Error(), "1 expired package(s)") {
+			t.Fatalf("expected only the invalid date to be reported, got %v", err)
+		}
+	})
+}
+
+func TestParseExpiredPackagePolicy(t *testing.T) {
+	for name, want := range map[string]ExpiredPackagePolicy{
+		"":      ExpiredPackageKeep,
+		"keep":  ExpiredPackageKeep,
+		"Drop":  ExpiredPackageDrop,
+		"error": ExpiredPackageError,
+	} {
+		got, err := ParseExpiredPackagePolicy(name)
+		if err != nil || got != want {
+			t.Errorf("ParseExpiredPackagePolicy(%q) = %v, %v; want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseExpiredPackagePolicy("purge"); err == nil {
+		t.Error("expected an error for unknown policy")
+	}
+}
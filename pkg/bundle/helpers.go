@@ -26,6 +26,7 @@ import (
 
 	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
 	"github.com/elastic/harp/pkg/bundle/secret"
+	csov1 "github.com/elastic/harp/pkg/cso/v1"
 )
 
 // KV describes map[string]interface{} alias
@@ -234,3 +235,27 @@ func FromSecretMap(secretKv KV) ([]*bundlev1.KV, error) {
 	// No error
 	return secrets, nil
 }
+
+// SortPackages sorts the given packages in place using the canonical CSO path
+// order of their names.
+func SortPackages(packages []*bundlev1.Package) {
+	// Sort names once
+	names := make([]string, 0, len(packages))
+	byName := map[string][]*bundlev1.Package{}
+	for _, p := range packages {
+		if _, ok := byName[p.Name]; !ok {
+			names = append(names, p.Name)
+		}
+		byName[p.Name] = append(byName[p.Name], p)
+	}
+	csov1.SortPaths(names)
+
+	// Rebuild package list, keeping duplicates order
+	i := 0
+	for _, name := range names {
+		for _, p := range byName[name] {
+			packages[i] = p
+			i++
+		}
+	}
+}
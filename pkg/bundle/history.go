@@ -0,0 +1,231 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/blake2b"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+)
+
+// HistoryAnnotationPrefix prefixes the package annotation holding the change
+// history of a secret key. The value is a JSON array of HistoryEntry, oldest
+// first.
+const HistoryAnnotationPrefix = "harp.elastic.co/v1/history#"
+
+// DefaultHistoryLimit defines the default history entry count kept per
+// secret key.
+const DefaultHistoryLimit = 10
+
+// HistoryEntry describes a secret key change.
+type HistoryEntry struct {
+	Actor     string `json:"actor"`
+	Timestamp string `json:"ts"`
+	Operation string `json:"op"`
+	OldDigest string `json:"old,omitempty"`
+}
+
+// Change describes the mutation to record in secret key histories.
+type Change struct {
+	Actor     string
+	Operation string
+	Time      time.Time
+	Limit     int
+}
+
+// DefaultActor returns the current `user@hostname` identity.
+func DefaultActor() string {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		name = u.Username
+	}
+	if name == "" {
+		name = "unknown"
+	}
+
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return name
+	}
+
+	return fmt.Sprintf("%s@%s", name, host)
+}
+
+// ValueDigest returns the digest of a packed secret value. It identifies a
+// value without exposing it.
+func ValueDigest(value []byte) string {
+	h := blake2b.Sum256(value)
+	return hex.EncodeToString(h[:16])
+}
+
+// History returns the change history of the given package secret key.
+func History(p *bundlev1.Package, key string) ([]HistoryEntry, error) {
+	// Check arguments
+	if p == nil {
+		return nil, fmt.Errorf("unable to process nil package")
+	}
+
+	raw, ok := p.Annotations[HistoryAnnotationPrefix+key]
+	if !ok {
+		return []HistoryEntry{}, nil
+	}
+
+	entries := []HistoryEntry{}
+	if err := json.Unmarshal([]byte(raw), &entries); err != nil {
+		return nil, fmt.Errorf("unable to decode '%s' history of '%s': %w", key, p.Name, err)
+	}
+
+	// No error
+	return entries, nil
+}
+
+// HistoryKeys returns the sorted secret keys having a history in the given
+// package.
+func HistoryKeys(p *bundlev1.Package) []string {
+	keys := []string{}
+	if p == nil {
+		return keys
+	}
+
+	for k := range p.Annotations {
+		if strings.HasPrefix(k, HistoryAnnotationPrefix) {
+			keys = append(keys, strings.TrimPrefix(k, HistoryAnnotationPrefix))
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// RecordChange appends a history entry to the given package secret key. The
+// old value may be nil for created keys. Oldest entries are pruned to respect
+// the change limit.
+func RecordChange(p *bundlev1.Package, key string, oldValue []byte, c Change) error {
+	entries, err := History(p, key)
+	if err != nil {
+		return err
+	}
+
+	// Prepare entry
+	e := HistoryEntry{
+		Actor:     c.Actor,
+		Timestamp: c.Time.UTC().Format(time.RFC3339),
+		Operation: c.Operation,
+	}
+	if oldValue != nil {
+		e.OldDigest = ValueDigest(oldValue)
+	}
+	entries = append(entries, e)
+
+	// Prune oldest entries
+	limit := c.Limit
+	if limit <= 0 {
+		limit = DefaultHistoryLimit
+	}
+	if len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+
+	// Encode history
+	out, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("unable to encode '%s' history of '%s': %w", key, p.Name, err)
+	}
+
+	if p.Annotations == nil {
+		p.Annotations = map[string]string{}
+	}
+	p.Annotations[HistoryAnnotationPrefix+key] = string(out)
+
+	// No error
+	return nil
+}
+
+// RecordChanges compares secret values of the before and after bundles and
+// records a history entry in the after bundle for each created, updated or
+// removed secret key. Packages are matched by name.
+func RecordChanges(before, after *bundlev1.Bundle, c Change) error {
+	// Check arguments
+	if after == nil {
+		return fmt.Errorf("unable to record changes in nil bundle")
+	}
+
+	// Index previous values
+	previous := map[string]map[string][]byte{}
+	if before != nil {
+		for _, p := range before.Packages {
+			if p == nil || p.Secrets == nil {
+				continue
+			}
+			values := map[string][]byte{}
+			for _, kv := range p.Secrets.Data {
+				values[kv.Key] = kv.Value
+			}
+			previous[p.Name] = values
+		}
+	}
+
+	for _, p := range after.Packages {
+		if p == nil || p.Secrets == nil {
+			continue
+		}
+
+		old := previous[p.Name]
+		seen := map[string]struct{}{}
+
+		// Created and updated keys
+		for _, kv := range p.Secrets.Data {
+			seen[kv.Key] = struct{}{}
+
+			oldValue, ok := old[kv.Key]
+			if ok && bytes.Equal(oldValue, kv.Value) {
+				continue
+			}
+			if err := RecordChange(p, kv.Key, oldValue, c); err != nil {
+				return err
+			}
+		}
+
+		// Removed keys
+		removed := []string{}
+		for k := range old {
+			if _, ok := seen[k]; !ok {
+				removed = append(removed, k)
+			}
+		}
+		sort.Strings(removed)
+		for _, k := range removed {
+			if err := RecordChange(p, k, old[k], c); err != nil {
+				return err
+			}
+		}
+	}
+
+	// No error
+	return nil
+}
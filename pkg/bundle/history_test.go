@@ -0,0 +1,161 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+)
+
+func historyBundle(values map[string]string) *bundlev1.Bundle {
+	p := &bundlev1.Package{
+		Name:    "app/production/database",
+		Secrets: &bundlev1.SecretChain{},
+	}
+	for _, k := range []string{"user", "password", "host"} {
+		if v, ok := values[k]; ok {
+			p.Secrets.Data = append(p.Secrets.Data, &bundlev1.KV{Key: k, Value: []byte(v)})
+		}
+	}
+	return &bundlev1.Bundle{Packages: []*bundlev1.Package{p}}
+}
+
+func TestRecordChange_Pruning(t *testing.T) {
+	p := &bundlev1.Package{Name: "app/production/database"}
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		err := RecordChange(p, "password", []byte{byte(i)}, Change{
+			Actor:     "alice@host",
+			Operation: "patch",
+			Time:      start.Add(time.Duration(i) * time.Hour),
+			Limit:     3,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	got, err := History(p, "password")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []HistoryEntry{
+		{Actor: "alice@host", Timestamp: "2021-01-01T02:00:00Z", Operation: "patch", OldDigest: ValueDigest([]byte{2})},
+		{Actor: "alice@host", Timestamp: "2021-01-01T03:00:00Z", Operation: "patch", OldDigest: ValueDigest([]byte{3})},
+		{Actor: "alice@host", Timestamp: "2021-01-01T04:00:00Z", Operation: "patch", OldDigest: ValueDigest([]byte{4})},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("History():\n-got/+want\ndiff %s", diff)
+	}
+}
+
+func TestRecordChange_DefaultLimit(t *testing.T) {
+	p := &bundlev1.Package{Name: "app/production/database"}
+	for i := 0; i < DefaultHistoryLimit+5; i++ {
+		if err := RecordChange(p, "password", nil, Change{Actor: "alice@host", Operation: "patch"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	got, err := History(p, "password")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != DefaultHistoryLimit {
+		t.Errorf("expected %d entries, got %d", DefaultHistoryLimit, len(got))
+	}
+}
+
+func TestRecordChanges(t *testing.T) {
+	ts := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Create, update then remove
+	v1 := historyBundle(map[string]string{"user": "admin", "password": "v1"})
+	if err := RecordChanges(nil, v1, Change{Actor: "alice@host", Operation: "promote", Time: ts}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	v2 := historyBundle(map[string]string{"user": "admin", "password": "v2", "host": "db"})
+	v2.Packages[0].Annotations = v1.Packages[0].Annotations
+	if err := RecordChanges(v1, v2, Change{Actor: "bob@host", Operation: "patch", Time: ts.Add(time.Hour)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	v3 := historyBundle(map[string]string{"password": "v2", "host": "db"})
+	v3.Packages[0].Annotations = v2.Packages[0].Annotations
+	if err := RecordChanges(v2, v3, Change{Actor: "carol@host", Operation: "patch", Time: ts.Add(2 * time.Hour)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	p := v3.Packages[0]
+	if diff := cmp.Diff(HistoryKeys(p), []string{"host", "password", "user"}); diff != "" {
+		t.Errorf("HistoryKeys():\n-got/+want\ndiff %s", diff)
+	}
+
+	testCases := []struct {
+		field string
+		want  []HistoryEntry
+	}{
+		{
+			field: "user",
+			want: []HistoryEntry{
+				{Actor: "alice@host", Timestamp: "2021-01-01T00:00:00Z", Operation: "promote"},
+				{Actor: "carol@host", Timestamp: "2021-01-01T02:00:00Z", Operation: "patch", OldDigest: ValueDigest([]byte("admin"))},
+			},
+		},
+		{
+			field: "password",
+			want: []HistoryEntry{
+				{Actor: "alice@host", Timestamp: "2021-01-01T00:00:00Z", Operation: "promote"},
+				{Actor: "bob@host", Timestamp: "2021-01-01T01:00:00Z", Operation: "patch", OldDigest: ValueDigest([]byte("v1"))},
+			},
+		},
+		{
+			field: "host",
+			want: []HistoryEntry{
+				{Actor: "bob@host", Timestamp: "2021-01-01T01:00:00Z", Operation: "patch"},
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.field, func(t *testing.T) {
+			got, err := History(p, tC.field)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(got, tC.want); diff != "" {
+				t.Errorf("%q. History():\n-got/+want\ndiff %s", tC.field, diff)
+			}
+		})
+	}
+}
+
+func TestHistory_Invalid(t *testing.T) {
+	p := &bundlev1.Package{
+		Name:        "app/production/database",
+		Annotations: map[string]string{HistoryAnnotationPrefix + "password": "{"},
+	}
+	if _, err := History(p, "password"); err == nil {
+		t.Error("error expected")
+	}
+}
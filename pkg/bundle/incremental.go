@@ -0,0 +1,415 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+
+	"google.golang.org/protobuf/proto"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/sdk/types"
+)
+
+// Incremental bundle file layout (big endian):
+//
+//	header  magic (uint32) | version (uint16)
+//	frame   length (uint32, > 0) | package (protobuf) | crc32c (uint32)
+//	footer  0 (uint32) | count (uint32) | frame offsets (count x uint64) | crc32c (uint32) | footer magic (uint32)
+//
+// Frames are self-delimited so that all complete frames of a truncated file
+// can be recovered, the footer is only written on Close.
+const (
+	incrementalMagic         = uint32(0x53CB3781)
+	incrementalFooterMagic   = uint32(0x53CB37FF)
+	incrementalVersion       = uint16(0x0001)
+	incrementalHeaderSize    = 6
+	incrementalMaxFrameSize  = 64 << 20
+	incrementalMaxIndexCount = incrementalMaxFrameSize / 8
+
+	// DefaultCheckpointInterval is the default appended package count between
+	// two checkpoints.
+	DefaultCheckpointInterval = 100
+)
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// ErrNotIncremental is raised when the input is not an incremental bundle
+// file.
+var ErrNotIncremental = errors.New("not an incremental bundle file")
+
+type incrementalOptions struct {
+	checkpointInterval int
+}
+
+// IncrementalOption defines the functional pattern for incremental writer
+// settings.
+type IncrementalOption func(*incrementalOptions)
+
+// WithCheckpointInterval sets the appended package count between two
+// checkpoints, 0 disables automatic checkpoints.
+func WithCheckpointInterval(value int) IncrementalOption {
+	return func(opts *incrementalOptions) {
+		opts.checkpointInterval = value
+	}
+}
+
+// IncrementalWriter streams bundle packages to an appendable file. Packages
+// are written as length-prefixed frames, buffered frames are flushed (and
+// synced when the writer is a file) on each checkpoint. An index footer is
+// written on Close.
+type IncrementalWriter struct {
+	sink     io.Writer
+	w        *bufio.Writer
+	opts     *incrementalOptions
+	offset   uint64
+	index    []uint64
+	pending  int
+	closed   bool
+	writeErr error
+}
+
+// NewIncrementalWriter writes the incremental file header to the given writer
+// and returns a writer ready to append packages.
+func NewIncrementalWriter(w io.Writer, opts ...IncrementalOption) (*IncrementalWriter, error) {
+	// Check arguments
+	if types.IsNil(w) {
+		return nil, fmt.Errorf("unable to process nil writer")
+	}
+
+	// Apply options
+	dopts := &incrementalOptions{
+		checkpointInterval: DefaultCheckpointInterval,
+	}
+	for _, o := range opts {
+		o(dopts)
+	}
+	if dopts.checkpointInterval < 0 {
+		return nil, fmt.Errorf("checkpoint interval must not be negative")
+	}
+
+	iw := &IncrementalWriter{
+		sink:  w,
+		w:     bufio.NewWriter(w),
+		opts:  dopts,
+		index: []uint64{},
+	}
+
+	// Write header
+	header := make([]byte, incrementalHeaderSize)
+	binary.BigEndian.PutUint32(header[0:], incrementalMagic)
+	binary.BigEndian.PutUint16(header[4:], incrementalVersion)
+	if err := iw.write(header); err != nil {
+		return nil, fmt.Errorf("unable to write incremental bundle header: %w", err)
+	}
+	if err := iw.Checkpoint(); err != nil {
+		return nil, err
+	}
+
+	// No error
+	return iw, nil
+}
+
+// Append writes the given package as a new frame.
+func (iw *IncrementalWriter) Append(p *bundlev1.Package) error {
+	// Check arguments
+	if p == nil {
+		return fmt.Errorf("unable to append nil package")
+	}
+	if iw.closed {
+		return fmt.Errorf("unable to append to a closed incremental writer")
+	}
+
+	// Serialize package
+	payload, err := proto.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("unable to encode package '%s': %w", p.Name, err)
+	}
+	if len(payload) == 0 || len(payload) > incrementalMaxFrameSize {
+		return fmt.Errorf("unable to append package '%s': invalid encoded size %d", p.Name, len(payload))
+	}
+
+	// Prepare frame
+	frame := make([]byte, 4, len(payload)+8)
+	binary.BigEndian.PutUint32(frame, uint32(len(payload)))
+	frame = append(frame, payload...)
+	frame = appendUint32(frame, crc32.Checksum(payload, crc32c))
+
+	// Write frame
+	start := iw.offset
+	if err := iw.write(frame); err != nil {
+		return fmt.Errorf("unable to write package '%s': %w", p.Name, err)
+	}
+	iw.index = append(iw.index, start)
+	iw.pending++
+
+	// Periodic checkpoint
+	if iw.opts.checkpointInterval > 0 && iw.pending >= iw.opts.checkpointInterval {
+		return iw.Checkpoint()
+	}
+
+	// No error
+	return nil
+}
+
+// Count returns the appended package count.
+func (iw *IncrementalWriter) Count() int {
+	return len(iw.index)
+}
+
+// Checkpoint flushes buffered frames to the underlying writer, and syncs it
+// to stable storage when supported.
+func (iw *IncrementalWriter) Checkpoint() error {
+	if iw.writeErr != nil {
+		return iw.writeErr
+	}
+
+	// Flush buffer
+	if err := iw.w.Flush(); err != nil {
+		iw.writeErr = fmt.Errorf("unable to flush incremental bundle: %w", err)
+		return iw.writeErr
+	}
+
+	// Sync file
+	if s, ok := iw.sink.(interface{ Sync() error }); ok {
+		if err := s.Sync(); err != nil {
+			iw.writeErr = fmt.Errorf("unable to sync incremental bundle: %w", err)
+			return iw.writeErr
+		}
+	}
+	iw.pending = 0
+
+	// No error
+	return nil
+}
+
+// Close writes the index footer and flushes the writer. The underlying writer
+// is not closed.
+func (iw *IncrementalWriter) Close() error {
+	if iw.closed {
+		return nil
+	}
+	iw.closed = true
+
+	// Prepare footer
+	footer := make([]byte, 8, 16+8*len(iw.index))
+	binary.BigEndian.PutUint32(footer[4:], uint32(len(iw.index)))
+	for _, off := range iw.index {
+		var buf [8]byte
+		binary.BigEndian.PutUint64(buf[:], off)
+		footer = append(footer, buf[:]...)
+	}
+	footer = appendUint32(footer, crc32.Checksum(footer[4:], crc32c))
+	footer = appendUint32(footer, incrementalFooterMagic)
+
+	// Write footer
+	if err := iw.write(footer); err != nil {
+		return fmt.Errorf("unable to write incremental bundle index: %w", err)
+	}
+
+	return iw.Checkpoint()
+}
+
+// RecoveryReport describes an incremental bundle recovery.
+type RecoveryReport struct {
+	// Packages is the recovered package count.
+	Packages int `json:"packages"`
+	// Complete is true when the file has been closed, its index matches all
+	// recovered frames.
+	Complete bool `json:"complete"`
+	// DiscardedBytes is the trailing byte count ignored after the last
+	// complete frame.
+	DiscardedBytes int64 `json:"discarded_bytes"`
+}
+
+// Recover reads all complete package frames of an incremental bundle file.
+// The file can be truncated (interrupted writer), packages of incomplete or
+// corrupted trailing frames are discarded and reported.
+func Recover(r io.Reader) (*bundlev1.Bundle, *RecoveryReport, error) {
+	// Check arguments
+	if types.IsNil(r) {
+		return nil, nil, fmt.Errorf("unable to process nil reader")
+	}
+
+	cr := &countingReader{r: bufio.NewReader(r)}
+
+	// Check header
+	header := make([]byte, incrementalHeaderSize)
+	if _, err := io.ReadFull(cr, header); err != nil {
+		return nil, nil, fmt.Errorf("unable to read incremental bundle header: %w", ErrNotIncremental)
+	}
+	if binary.BigEndian.Uint32(header[0:]) != incrementalMagic {
+		return nil, nil, ErrNotIncremental
+	}
+	if version := binary.BigEndian.Uint16(header[4:]); version != incrementalVersion {
+		return nil, nil, fmt.Errorf("unsupported incremental bundle version %d", version)
+	}
+
+	b := &bundlev1.Bundle{}
+	report := &RecoveryReport{}
+	offsets := []uint64{}
+	valid := cr.n
+
+	// Read frames
+	for {
+		start := cr.n
+		p, err := readFrame(cr)
+		if err != nil {
+			break
+		}
+
+		// Footer reached
+		if p == nil {
+			index, errIndex := readIndex(cr)
+			if errIndex == nil {
+				valid = cr.n
+				report.Complete = equalOffsets(index, offsets)
+			}
+			break
+		}
+
+		b.Packages = append(b.Packages, p)
+		offsets = append(offsets, uint64(start))
+		valid = cr.n
+	}
+
+	// Count discarded bytes
+	if _, err := io.Copy(ioutil.Discard, cr); err != nil {
+		return nil, nil, fmt.Errorf("unable to read incremental bundle: %w", err)
+	}
+	report.Packages = len(b.Packages)
+	report.DiscardedBytes = cr.n - valid
+
+	// No error
+	return b, report, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (iw *IncrementalWriter) write(data []byte) error {
+	if iw.writeErr != nil {
+		return iw.writeErr
+	}
+	n, err := iw.w.Write(data)
+	iw.offset += uint64(n)
+	if err != nil {
+		iw.writeErr = err
+	}
+	return err
+}
+
+// readFrame returns the next package, or nil when the footer marker is read.
+func readFrame(r io.Reader) (*bundlev1.Package, error) {
+	var length uint32
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+	if length == 0 {
+		return nil, nil
+	}
+	if length > incrementalMaxFrameSize {
+		return nil, fmt.Errorf("invalid frame length %d", length)
+	}
+
+	// Read payload and checksum
+	buf := make([]byte, int(length)+4)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	payload := buf[:length]
+	if crc32.Checksum(payload, crc32c) != binary.BigEndian.Uint32(buf[length:]) {
+		return nil, fmt.Errorf("frame checksum mismatch")
+	}
+
+	// Decode package
+	p := &bundlev1.Package{}
+	if err := proto.Unmarshal(payload, p); err != nil {
+		return nil, fmt.Errorf("unable to decode package frame: %w", err)
+	}
+
+	return p, nil
+}
+
+func readIndex(r io.Reader) ([]uint64, error) {
+	var count uint32
+	if err := binary.Read(r, binary.BigEndian, &count); err != nil {
+		return nil, err
+	}
+	if count > incrementalMaxIndexCount {
+		return nil, fmt.Errorf("invalid index size %d", count)
+	}
+
+	// Read offsets and trailer
+	buf := make([]byte, 8*int(count)+8)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	body := buf[:8*count]
+	trailer := buf[8*count:]
+
+	// Check integrity
+	if binary.BigEndian.Uint32(trailer[4:]) != incrementalFooterMagic {
+		return nil, fmt.Errorf("invalid footer magic")
+	}
+	sum := crc32.Update(crc32.Checksum(appendUint32(nil, count), crc32c), crc32c, body)
+	if sum != binary.BigEndian.Uint32(trailer) {
+		return nil, fmt.Errorf("index checksum mismatch")
+	}
+
+	index := make([]uint64, count)
+	for i := range index {
+		index[i] = binary.BigEndian.Uint64(body[8*i:])
+	}
+
+	return index, nil
+}
+
+func equalOffsets(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func appendUint32(buf []byte, v uint32) []byte {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	return append(buf, b[:]...)
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
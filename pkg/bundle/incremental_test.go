@@ -0,0 +1,236 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+)
+
+func incrementalPackages(t *testing.T, count int) []*bundlev1.Package {
+	t.Helper()
+
+	input := map[string]KV{}
+	for i := 0; i < count; i++ {
+		input[fmt.Sprintf("app/production/customer1/ece/v1.0.0/svc%03d/credentials", i)] = KV{
+			"user":     fmt.Sprintf("user-%d", i),
+			"password": fmt.Sprintf("secret-%d", i),
+		}
+	}
+
+	return mustFromMap(t, input).Packages
+}
+
+// failingWriter simulates a writer killed after a given byte count.
+type failingWriter struct {
+	buf   bytes.Buffer
+	limit int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if room := w.limit - w.buf.Len(); len(p) > room {
+		w.buf.Write(p[:room])
+		return room, errors.New("killed")
+	}
+	return w.buf.Write(p)
+}
+
+func TestIncremental_RoundTrip(t *testing.T) {
+	pkgs := incrementalPackages(t, 25)
+
+	var out bytes.Buffer
+	iw, err := NewIncrementalWriter(&out, WithCheckpointInterval(10))
+	if err != nil {
+		t.Fatalf("unable to create writer: %v", err)
+	}
+	for _, p := range pkgs {
+		if err := iw.Append(p); err != nil {
+			t.Fatalf("unable to append package: %v", err)
+		}
+	}
+	if err := iw.Close(); err != nil {
+		t.Fatalf("unable to close writer: %v", err)
+	}
+
+	b, report, err := Recover(&out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !report.Complete || report.Packages != len(pkgs) || report.DiscardedBytes != 0 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	for i, p := range b.Packages {
+		if !proto.Equal(p, pkgs[i]) {
+			t.Fatalf("package %d mismatch", i)
+		}
+	}
+}
+
+func TestIncremental_Checkpoints(t *testing.T) {
+	pkgs := incrementalPackages(t, 25)
+
+	var out bytes.Buffer
+	iw, err := NewIncrementalWriter(&out, WithCheckpointInterval(10))
+	if err != nil {
+		t.Fatalf("unable to create writer: %v", err)
+	}
+
+	// Packages are buffered until the next checkpoint
+	for _, p := range pkgs {
+		if err := iw.Append(p); err != nil {
+			t.Fatalf("unable to append package: %v", err)
+		}
+	}
+
+	// Simulate a crash, only checkpointed frames reached the file
+	_, report, err := Recover(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Complete || report.Packages != 20 {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	// Explicit checkpoint
+	if err := iw.Checkpoint(); err != nil {
+		t.Fatalf("unable to checkpoint: %v", err)
+	}
+	_, report, err = Recover(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Complete || report.Packages != 25 || iw.Count() != 25 {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
+
+func TestIncremental_KilledWriter(t *testing.T) {
+	pkgs := incrementalPackages(t, 12)
+
+	// Record frame boundaries of a complete file
+	var full bytes.Buffer
+	iw, err := NewIncrementalWriter(&full, WithCheckpointInterval(1))
+	if err != nil {
+		t.Fatalf("unable to create writer: %v", err)
+	}
+	ends := []int{}
+	for _, p := range pkgs {
+		if err := iw.Append(p); err != nil {
+			t.Fatalf("unable to append package: %v", err)
+		}
+		ends = append(ends, full.Len())
+	}
+	if err := iw.Close(); err != nil {
+		t.Fatalf("unable to close writer: %v", err)
+	}
+
+	// Kill the writer at every byte offset
+	for limit := incrementalHeaderSize; limit < full.Len(); limit++ {
+		fw := &failingWriter{limit: limit}
+		iw, err := NewIncrementalWriter(fw, WithCheckpointInterval(1))
+		if err != nil {
+			t.Fatalf("unable to create writer: %v", err)
+		}
+		for _, p := range pkgs {
+			if err := iw.Append(p); err != nil {
+				break
+			}
+		}
+		if err := iw.Close(); err == nil {
+			t.Fatalf("limit %d: expected a write error", limit)
+		}
+
+		// Expected salvaged frames
+		want := 0
+		for want < len(ends) && ends[want] <= limit {
+			want++
+		}
+
+		b, report, err := Recover(bytes.NewReader(fw.buf.Bytes()))
+		if err != nil {
+			t.Fatalf("limit %d: unexpected error: %v", limit, err)
+		}
+		if report.Complete || report.Packages != want {
+			t.Fatalf("limit %d: expected %d packages, got %+v", limit, want, report)
+		}
+		wantDiscarded := int64(limit - incrementalHeaderSize)
+		if want > 0 {
+			wantDiscarded = int64(limit - ends[want-1])
+		}
+		if report.DiscardedBytes != wantDiscarded {
+			t.Fatalf("limit %d: expected %d discarded bytes, got %d", limit, wantDiscarded, report.DiscardedBytes)
+		}
+		for i, p := range b.Packages {
+			if !proto.Equal(p, pkgs[i]) {
+				t.Fatalf("limit %d: salvaged package %d is corrupted", limit, i)
+			}
+		}
+	}
+}
+
+func TestIncremental_CorruptedFrame(t *testing.T) {
+	pkgs := incrementalPackages(t, 3)
+
+	var out bytes.Buffer
+	iw, err := NewIncrementalWriter(&out)
+	if err != nil {
+		t.Fatalf("unable to create writer: %v", err)
+	}
+	ends := []int{}
+	for _, p := range pkgs {
+		if err := iw.Append(p); err != nil {
+			t.Fatalf("unable to append package: %v", err)
+		}
+		if err := iw.Checkpoint(); err != nil {
+			t.Fatalf("unable to checkpoint: %v", err)
+		}
+		ends = append(ends, out.Len())
+	}
+	if err := iw.Close(); err != nil {
+		t.Fatalf("unable to close writer: %v", err)
+	}
+
+	// Flip a payload byte of the second frame
+	raw := out.Bytes()
+	raw[ends[0]+8] ^= 0xFF
+
+	b, report, err := Recover(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Complete || report.Packages != 1 || report.DiscardedBytes != int64(len(raw)-ends[0]) {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if !proto.Equal(b.Packages[0], pkgs[0]) {
+		t.Error("salvaged package is corrupted")
+	}
+}
+
+func TestRecover_NotIncremental(t *testing.T) {
+	for _, input := range [][]byte{nil, {0x53, 0xCB}, {0x53, 0xCB, 0x37, 0x01, 0x00, 0x02}} {
+		if _, _, err := Recover(bytes.NewReader(input)); !errors.Is(err, ErrNotIncremental) {
+			t.Errorf("expected not incremental error for %x, got %v", input, err)
+		}
+	}
+}
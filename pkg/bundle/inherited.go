@@ -0,0 +1,434 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle/secret"
+	"github.com/elastic/harp/pkg/sdk/convert"
+	"github.com/elastic/harp/pkg/sdk/types"
+)
+
+const (
+	// AnnotationDefaultsPackage is the reserved meta package holding the
+	// annotation defaults of a bundle.
+	AnnotationDefaultsPackage = "meta/annotations/defaults"
+	// AnnotationDefaultsAPIVersion is the supported defaults manifest api
+	// version.
+	AnnotationDefaultsAPIVersion = "harp.elastic.co/v1"
+	// AnnotationDefaultsKind is the supported defaults manifest kind.
+	AnnotationDefaultsKind = "AnnotationDefaults"
+
+	// annotationDefaultsKey is the reserved package key holding the manifest.
+	annotationDefaultsKey = "manifest"
+)
+
+// AnnotationDefaults describes annotations inherited by packages from their
+// path prefix.
+type AnnotationDefaults struct {
+	APIVersion string                 `json:"apiVersion"`
+	Kind       string                 `json:"kind"`
+	Spec       AnnotationDefaultsSpec `json:"spec"`
+}
+
+// AnnotationDefaultsSpec describes annotation defaults settings.
+type AnnotationDefaultsSpec struct {
+	Prefixes []PrefixAnnotations `json:"prefixes"`
+}
+
+// PrefixAnnotations describes annotations inherited by packages under the
+// given path prefix. An empty prefix matches all packages.
+type PrefixAnnotations struct {
+	Prefix      string            `json:"prefix"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// ParseAnnotationDefaults reads a YAML or JSON defaults manifest from the
+// given reader.
+func ParseAnnotationDefaults(r io.Reader) (*AnnotationDefaults, error) {
+	// Check arguments
+	if types.IsNil(r) {
+		return nil, fmt.Errorf("reader is nil")
+	}
+
+	// Convert to JSON
+	jsonReader, err := convert.YAMLtoJSON(r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse input as AnnotationDefaults: %w", err)
+	}
+
+	// Decode manifest
+	var d AnnotationDefaults
+	dec := json.NewDecoder(jsonReader)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&d); err != nil {
+		return nil, fmt.Errorf("unable to decode annotation defaults: %w", err)
+	}
+
+	// Check manifest header
+	if d.APIVersion != AnnotationDefaultsAPIVersion {
+		return nil, fmt.Errorf("unsupported annotation defaults api version '%s'", d.APIVersion)
+	}
+	if d.Kind != AnnotationDefaultsKind {
+		return nil, fmt.Errorf("unsupported annotation defaults kind '%s'", d.Kind)
+	}
+
+	// Validate prefixes
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+
+	// No error
+	return &d, nil
+}
+
+// Validate checks defaults consistency.
+func (d *AnnotationDefaults) Validate() error {
+	seen := map[string]struct{}{}
+	for i, pa := range d.Spec.Prefixes {
+		prefix := strings.Trim(pa.Prefix, "/")
+		if _, ok := seen[prefix]; ok {
+			return fmt.Errorf("prefix #%d: duplicate prefix '%s'", i, pa.Prefix)
+		}
+		seen[prefix] = struct{}{}
+
+		if len(pa.Annotations) == 0 {
+			return fmt.Errorf("prefix #%d: at least one annotation must be declared", i)
+		}
+		for k := range pa.Annotations {
+			if strings.TrimSpace(k) == "" {
+				return fmt.Errorf("prefix #%d: annotation name must not be blank", i)
+			}
+		}
+	}
+
+	// No error
+	return nil
+}
+
+// AttachAnnotationDefaults stores the given defaults in the reserved meta
+// package of the bundle, replacing existing ones.
+func AttachAnnotationDefaults(b *bundlev1.Bundle, d *AnnotationDefaults) error {
+	// Check arguments
+	if b == nil {
+		return fmt.Errorf("unable to process nil bundle")
+	}
+	if d == nil {
+		return fmt.Errorf("unable to attach nil annotation defaults")
+	}
+	if err := d.Validate(); err != nil {
+		return err
+	}
+
+	// Pack manifest
+	raw, err := json.Marshal(&AnnotationDefaults{
+		APIVersion: AnnotationDefaultsAPIVersion,
+		Kind:       AnnotationDefaultsKind,
+		Spec:       d.Spec,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to encode annotation defaults: %w", err)
+	}
+	value, err := secret.Pack(string(raw))
+	if err != nil {
+		return fmt.Errorf("unable to pack annotation defaults: %w", err)
+	}
+	data := []*bundlev1.KV{{Key: annotationDefaultsKey, Type: "string", Value: value}}
+
+	// Replace existing package content
+	for _, p := range b.Packages {
+		if p != nil && p.Name == AnnotationDefaultsPackage {
+			if p.Secrets != nil && p.Secrets.Locked != nil {
+				return fmt.Errorf("unable to update '%s': %w", p.Name, ErrPackageLocked)
+			}
+			p.Secrets = &bundlev1.SecretChain{Data: data}
+			return nil
+		}
+	}
+
+	b.Packages = append(b.Packages, &bundlev1.Package{
+		Name: AnnotationDefaultsPackage,
+		Annotations: map[string]string{
+			DescriptionAnnotation: "Annotations inherited by packages from their path prefix",
+		},
+		Secrets: &bundlev1.SecretChain{Data: data},
+	})
+
+	// No error
+	return nil
+}
+
+// AnnotationDefaultsOf returns the defaults stored in the reserved meta
+// package of the given bundle, or nil when the bundle has none.
+func AnnotationDefaultsOf(b *bundlev1.Bundle) (*AnnotationDefaults, error) {
+	if b == nil {
+		return nil, nil
+	}
+
+	for _, p := range b.Packages {
+		if p == nil || p.Name != AnnotationDefaultsPackage {
+			continue
+		}
+
+		// Check package state
+		if p.Secrets == nil || p.Secrets.Locked != nil {
+			return nil, fmt.Errorf("unable to read '%s': %w", p.Name, ErrPackageLocked)
+		}
+
+		// Unpack manifest
+		for _, kv := range p.Secrets.Data {
+			if kv.Key != annotationDefaultsKey {
+				continue
+			}
+			var raw string
+			if err := secret.Unpack(kv.Value, &raw); err != nil {
+				return nil, fmt.Errorf("unable to unpack annotation defaults: %w", err)
+			}
+			return ParseAnnotationDefaults(strings.NewReader(raw))
+		}
+
+		return nil, fmt.Errorf("unable to lookup '%s' in '%s': %w", annotationDefaultsKey, p.Name, ErrSecretNotFound)
+	}
+
+	// No defaults
+	return nil, nil
+}
+
+// InheritedAnnotations returns the effective annotation resolver of the
+// given bundle using its annotation defaults.
+func InheritedAnnotations(b *bundlev1.Bundle) (*AnnotationResolver, error) {
+	d, err := AnnotationDefaultsOf(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAnnotationResolver(d), nil
+}
+
+// MaterializeAnnotations writes the effective annotations of all packages
+// and removes the annotation defaults from the bundle, so that consumers
+// not aware of inheritance see the same annotations. It returns the count
+// of updated packages.
+func MaterializeAnnotations(b *bundlev1.Bundle) (int, error) {
+	r, err := InheritedAnnotations(b)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	packages := make([]*bundlev1.Package, 0, len(b.Packages))
+	for _, p := range b.Packages {
+		if p == nil || p.Name == AnnotationDefaultsPackage {
+			continue
+		}
+		packages = append(packages, p)
+
+		// Bake inherited annotations
+		updated := false
+		for k, v := range r.Effective(p) {
+			if _, ok := p.Annotations[k]; ok {
+				continue
+			}
+			if p.Annotations == nil {
+				p.Annotations = map[string]string{}
+			}
+			p.Annotations[k] = v
+			updated = true
+		}
+		if updated {
+			count++
+		}
+	}
+	b.Packages = packages
+
+	// No error
+	return count, nil
+}
+
+// -----------------------------------------------------------------------------
+
+// AnnotationResolver exposes effective package annotations. Package
+// annotations override the inherited ones, and the longest matching prefix
+// wins for each inherited annotation. A nil resolver exposes package
+// annotations only.
+type AnnotationResolver struct {
+	// prefixes sorted from the longest to the shortest one.
+	prefixes []PrefixAnnotations
+}
+
+// NewAnnotationResolver returns a resolver using the given defaults, which
+// may be nil.
+func NewAnnotationResolver(d *AnnotationDefaults) *AnnotationResolver {
+	r := &AnnotationResolver{
+		prefixes: []PrefixAnnotations{},
+	}
+	if d == nil {
+		return r
+	}
+
+	for _, pa := range d.Spec.Prefixes {
+		r.prefixes = append(r.prefixes, PrefixAnnotations{
+			Prefix:      strings.Trim(pa.Prefix, "/"),
+			Annotations: pa.Annotations,
+		})
+	}
+	sort.SliceStable(r.prefixes, func(i, j int) bool {
+		return len(r.prefixes[i].Prefix) > len(r.prefixes[j].Prefix)
+	})
+
+	return r
+}
+
+// Lookup returns the effective value of the given package annotation.
+func (r *AnnotationResolver) Lookup(p *bundlev1.Package, key string) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	if v, ok := p.Annotations[key]; ok {
+		return v, true
+	}
+	if r == nil {
+		return "", false
+	}
+
+	// Longest matching prefix first
+	for _, pa := range r.matching(p.Name) {
+		if v, ok := pa.Annotations[key]; ok {
+			return v, true
+		}
+	}
+
+	return "", false
+}
+
+// Effective returns all effective annotations of the given package.
+func (r *AnnotationResolver) Effective(p *bundlev1.Package) map[string]string {
+	res := map[string]string{}
+	if p == nil {
+		return res
+	}
+
+	// Shortest matching prefix first, so that longer ones override
+	if r != nil {
+		matching := r.matching(p.Name)
+		for i := len(matching) - 1; i >= 0; i-- {
+			for k, v := range matching[i].Annotations {
+				res[k] = v
+			}
+		}
+	}
+	for k, v := range p.Annotations {
+		res[k] = v
+	}
+
+	return res
+}
+
+// Description returns the effective package description. The secret chain
+// description set by the template engine is used when the package is not
+// annotated.
+func (r *AnnotationResolver) Description(p *bundlev1.Package) string {
+	if p == nil {
+		return ""
+	}
+	if desc, _ := r.Lookup(p, DescriptionAnnotation); strings.TrimSpace(desc) != "" {
+		return strings.TrimSpace(desc)
+	}
+	if p.Secrets != nil {
+		return strings.TrimSpace(p.Secrets.Annotations[chainDescriptionAnnotation])
+	}
+
+	return ""
+}
+
+// KeyDescription returns the effective description of the given package
+// secret key.
+func (r *AnnotationResolver) KeyDescription(p *bundlev1.Package, key string) string {
+	desc, _ := r.Lookup(p, KeyDescriptionAnnotationPrefix+key)
+	return strings.TrimSpace(desc)
+}
+
+// Owner returns the effective team owning the given package.
+func (r *AnnotationResolver) Owner(p *bundlev1.Package) string {
+	owner, _ := r.Lookup(p, OwnerAnnotation)
+	return strings.TrimSpace(owner)
+}
+
+// RotationPeriod returns the effective expected rotation period of the given
+// package.
+func (r *AnnotationResolver) RotationPeriod(p *bundlev1.Package) string {
+	period, _ := r.Lookup(p, RotationAnnotation)
+	return strings.TrimSpace(period)
+}
+
+// ExpiresAt returns the effective expiration date of the given package. The
+// boolean is false when the package is not annotated.
+func (r *AnnotationResolver) ExpiresAt(p *bundlev1.Package) (time.Time, bool, error) {
+	raw, ok := r.Lookup(p, ExpiresAnnotation)
+	if !ok {
+		return time.Time{}, false, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(raw))
+	if err != nil {
+		return time.Time{}, true, fmt.Errorf("invalid expiration date '%s' for package '%s': %w", raw, p.Name, err)
+	}
+
+	return t, true, nil
+}
+
+// IsExpired returns true if the given package is expired at the given time
+// according to its effective expiration date. Packages with an invalid
+// expiration date are considered as expired.
+func (r *AnnotationResolver) IsExpired(p *bundlev1.Package, now time.Time) bool {
+	t, ok, err := r.ExpiresAt(p)
+	switch {
+	case !ok:
+		return false
+	case err != nil:
+		return true
+	default:
+	}
+
+	return !now.Before(t)
+}
+
+func (r *AnnotationResolver) matching(name string) []PrefixAnnotations {
+	name = strings.Trim(name, "/")
+
+	res := []PrefixAnnotations{}
+	if name == AnnotationDefaultsPackage {
+		// Defaults are not applied to themselves
+		return res
+	}
+	for _, pa := range r.prefixes {
+		if pa.Prefix != "" && name != pa.Prefix && !strings.HasPrefix(name, pa.Prefix+"/") {
+			continue
+		}
+		res = append(res, pa)
+	}
+
+	return res
+}
@@ -0,0 +1,273 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+)
+
+const annotationDefaultsManifest = `apiVersion: harp.elastic.co/v1
+kind: AnnotationDefaults
+spec:
+  prefixes:
+  - prefix: ""
+    annotations:
+      harp.elastic.co/v1/package#rotation: 365d
+  - prefix: app/production
+    annotations:
+      harp.elastic.co/v1/package#owner: platform
+      harp.elastic.co/v1/package#rotation: 90d
+  - prefix: /app/production/billing/
+    annotations:
+      harp.elastic.co/v1/package#owner: billing
+`
+
+func annotationDefaults(t *testing.T) *AnnotationDefaults {
+	t.Helper()
+
+	d, err := ParseAnnotationDefaults(strings.NewReader(annotationDefaultsManifest))
+	if err != nil {
+		t.Fatalf("unable to parse annotation defaults: %v", err)
+	}
+
+	return d
+}
+
+func TestAnnotationResolver(t *testing.T) {
+	r := NewAnnotationResolver(annotationDefaults(t))
+
+	testCases := []struct {
+		desc         string
+		pkg          *bundlev1.Package
+		wantOwner    string
+		wantRotation string
+	}{
+		{
+			desc:         "root prefix",
+			pkg:          &bundlev1.Package{Name: "infra/dns"},
+			wantOwner:    "",
+			wantRotation: "365d",
+		},
+		{
+			desc:         "prefix",
+			pkg:          &bundlev1.Package{Name: "app/production/search/database"},
+			wantOwner:    "platform",
+			wantRotation: "90d",
+		},
+		{
+			desc:         "longest prefix wins",
+			pkg:          &bundlev1.Package{Name: "app/production/billing/database"},
+			wantOwner:    "billing",
+			wantRotation: "90d",
+		},
+		{
+			desc:         "exact prefix path",
+			pkg:          &bundlev1.Package{Name: "app/production/billing"},
+			wantOwner:    "billing",
+			wantRotation: "90d",
+		},
+		{
+			desc:         "sibling path",
+			pkg:          &bundlev1.Package{Name: "app/production/billing-v2/database"},
+			wantOwner:    "platform",
+			wantRotation: "90d",
+		},
+		{
+			desc: "package overrides prefix",
+			pkg: &bundlev1.Package{
+				Name: "app/production/billing/database",
+				Annotations: map[string]string{
+					OwnerAnnotation: "dba",
+				},
+			},
+			wantOwner:    "dba",
+			wantRotation: "90d",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if got := r.Owner(tC.pkg); got != tC.wantOwner {
+				t.Errorf("expected owner '%s', got '%s'", tC.wantOwner, got)
+			}
+			if got := r.RotationPeriod(tC.pkg); got != tC.wantRotation {
+				t.Errorf("expected rotation '%s', got '%s'", tC.wantRotation, got)
+			}
+
+			// Plain accessors ignore inherited annotations
+			if got := Owner(tC.pkg); got != tC.pkg.Annotations[OwnerAnnotation] {
+				t.Errorf("unexpected plain owner '%s'", got)
+			}
+		})
+	}
+}
+
+func TestParseAnnotationDefaults_Invalid(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		manifest string
+	}{
+		{
+			desc:     "kind",
+			manifest: "apiVersion: harp.elastic.co/v1\nkind: BundlePatch\nspec: {}\n",
+		},
+		{
+			desc:     "unknown field",
+			manifest: "apiVersion: harp.elastic.co/v1\nkind: AnnotationDefaults\nspec:\n  paths: []\n",
+		},
+		{
+			desc:     "duplicate prefix",
+			manifest: "apiVersion: harp.elastic.co/v1\nkind: AnnotationDefaults\nspec:\n  prefixes:\n  - prefix: app\n    annotations: {a: b}\n  - prefix: /app/\n    annotations: {c: d}\n",
+		},
+		{
+			desc:     "no annotation",
+			manifest: "apiVersion: harp.elastic.co/v1\nkind: AnnotationDefaults\nspec:\n  prefixes:\n  - prefix: app\n",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if _, err := ParseAnnotationDefaults(strings.NewReader(tC.manifest)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestMaterializeAnnotations(t *testing.T) {
+	b := mustFromMap(t, map[string]KV{
+		"app/production/billing/database": {"password": "billing"},
+		"app/production/search/database":  {"password": "search"},
+		"app/staging/billing/database":    {"password": "staging"},
+	})
+	for _, p := range b.Packages {
+		if p.Name == "app/production/search/database" {
+			Annotate(p, OwnerAnnotation, "search")
+		}
+	}
+	if err := AttachAnnotationDefaults(b, annotationDefaults(t)); err != nil {
+		t.Fatalf("unable to attach annotation defaults: %v", err)
+	}
+
+	// Effective annotations before materialization
+	r, err := InheritedAnnotations(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]map[string]string{}
+	for _, p := range b.Packages {
+		if p.Name != AnnotationDefaultsPackage {
+			want[p.Name] = r.Effective(p)
+		}
+	}
+
+	count, err := MaterializeAnnotations(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 updated packages, got %d", count)
+	}
+
+	// Plain annotations after materialization
+	got := map[string]map[string]string{}
+	for _, p := range b.Packages {
+		got[p.Name] = p.Annotations
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected annotations (-want +got):\n%s", diff)
+	}
+	if got["app/production/search/database"][OwnerAnnotation] != "search" {
+		t.Error("package annotation must not be overridden")
+	}
+
+	// Defaults are removed
+	if d, err := AnnotationDefaultsOf(b); err != nil || d != nil {
+		t.Errorf("expected no more annotation defaults, got %v / %v", d, err)
+	}
+}
+
+func TestFromContainerReader_InheritedAnnotations(t *testing.T) {
+	b := mustFromMap(t, map[string]KV{
+		"app/production/billing/database": {"password": "expired"},
+		"app/production/billing/api":      {"token": "valid"},
+		"app/production/search/database":  {"password": "valid"},
+	})
+	for _, p := range b.Packages {
+		if p.Name == "app/production/billing/api" {
+			Annotate(p, ExpiresAnnotation, "2021-06-02T00:00:00Z")
+		}
+	}
+	var out bytes.Buffer
+	if err := ToContainerWriter(&out, b); err != nil {
+		t.Fatalf("unable to write container: %v", err)
+	}
+
+	// Expiration inherited from the billing prefix
+	d := annotationDefaults(t)
+	d.Spec.Prefixes[2].Annotations[ExpiresAnnotation] = "2021-05-31T00:00:00Z"
+
+	dropped := 0
+	loaded, err := FromContainerReader(bytes.NewReader(out.Bytes()),
+		WithInheritedAnnotations(d),
+		WithExpiredPackagePolicy(ExpiredPackageDrop),
+		WithLoadClock(expiryClock),
+		WithDroppedPackageCount(&dropped),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dropped != 1 {
+		t.Errorf("expected 1 dropped package, got %d", dropped)
+	}
+
+	// Annotations are not materialized
+	names := []string{}
+	for _, p := range loaded.Packages {
+		names = append(names, p.Name)
+		if p.Name != AnnotationDefaultsPackage && Owner(p) != "" {
+			t.Errorf("package '%s' must not be annotated", p.Name)
+		}
+	}
+	if diff := cmp.Diff([]string{"app/production/billing/api", "app/production/search/database", AnnotationDefaultsPackage}, names); diff != "" {
+		t.Errorf("unexpected packages (-want +got):\n%s", diff)
+	}
+
+	// Defaults survive container roundtrip
+	out.Reset()
+	if err := ToContainerWriter(&out, loaded); err != nil {
+		t.Fatalf("unable to write container: %v", err)
+	}
+	reloaded, err := FromContainerReader(&out)
+	if err != nil {
+		t.Fatalf("unable to read container: %v", err)
+	}
+	r, err := InheritedAnnotations(reloaded)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, p := range reloaded.Packages {
+		if p.Name == "app/production/billing/api" && r.Owner(p) != "billing" {
+			t.Errorf("expected inherited owner, got '%s'", r.Owner(p))
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package bufpool provides reusable buffers for secret serialization.
+package bufpool
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/awnumar/memguard"
+)
+
+// maxRetainedSize is the capacity above which buffers are dropped instead of
+// being retained by the pool.
+const maxRetainedSize = 4 << 20
+
+var pool = sync.Pool{
+	New: func() interface{} {
+		return &bytes.Buffer{}
+	},
+}
+
+// Get returns an empty buffer from the pool.
+func Get() *bytes.Buffer {
+	return pool.Get().(*bytes.Buffer)
+}
+
+// Put wipes the buffer memory and returns it to the pool. The buffer content
+// must not be referenced after this call, values read from it must be copied.
+func Put(buf *bytes.Buffer) {
+	if buf == nil {
+		return
+	}
+
+	// Wipe the whole underlying array, not only the unread portion
+	buf.Reset()
+	memguard.WipeBytes(buf.Bytes()[:buf.Cap()])
+
+	// Don't retain large buffers
+	if buf.Cap() > maxRetainedSize {
+		return
+	}
+
+	pool.Put(buf)
+}
@@ -0,0 +1,87 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+)
+
+type jsonOptions struct {
+	allowUnknown bool
+}
+
+// JSONOption defines the functional pattern for JSON decoding settings.
+type JSONOption func(*jsonOptions)
+
+// WithAllowUnknownFields ignores unknown fields instead of rejecting them.
+func WithAllowUnknownFields(value bool) JSONOption {
+	return func(opts *jsonOptions) {
+		opts.allowUnknown = value
+	}
+}
+
+// MarshalJSON encodes the given bundle as JSON with enums as names and proto
+// field names.
+func MarshalJSON(b *bundlev1.Bundle) ([]byte, error) {
+	// Check arguments
+	if b == nil {
+		return nil, fmt.Errorf("unable to marshal nil bundle")
+	}
+
+	m := protojson.MarshalOptions{
+		UseProtoNames:  true,
+		UseEnumNumbers: false,
+	}
+
+	out, err := m.Marshal(b)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal bundle as JSON: %w", err)
+	}
+
+	// No error
+	return out, nil
+}
+
+// UnmarshalJSON decodes the given JSON as a bundle. Enums are accepted as
+// names or numbers, and field names as proto or JSON names.
+func UnmarshalJSON(data []byte, b *bundlev1.Bundle, opts ...JSONOption) error {
+	// Check arguments
+	if b == nil {
+		return fmt.Errorf("unable to unmarshal to nil bundle")
+	}
+
+	// Apply options
+	dopts := &jsonOptions{}
+	for _, o := range opts {
+		o(dopts)
+	}
+
+	u := protojson.UnmarshalOptions{
+		DiscardUnknown: dopts.allowUnknown,
+	}
+	if err := u.Unmarshal(data, b); err != nil {
+		return fmt.Errorf("unable to unmarshal bundle from JSON: %w", err)
+	}
+
+	// No error
+	return nil
+}
@@ -0,0 +1,58 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+)
+
+func Test_JSON_RoundTrip(t *testing.T) {
+	in := mustFromMap(t, map[string]KV{
+		"app/production/security/harp/v1.0.0/server/database": {
+			"user": "foo",
+		},
+	})
+	in.Packages[0].Annotations = map[string]string{"owner": "security"}
+
+	out, err := MarshalJSON(in)
+	if err != nil {
+		t.Fatalf("unable to marshal bundle: %v", err)
+	}
+
+	var got bundlev1.Bundle
+	if err := UnmarshalJSON(out, &got); err != nil {
+		t.Fatalf("unable to unmarshal bundle: %v", err)
+	}
+	if !proto.Equal(in, &got) {
+		t.Errorf("round-trip mismatch, got %v, want %v", &got, in)
+	}
+
+	// Unknown fields
+	raw := strings.Replace(string(out), "{", `{"foo":"bar",`, 1)
+	if err := UnmarshalJSON([]byte(raw), &bundlev1.Bundle{}); err == nil {
+		t.Error("error expected for unknown field")
+	}
+	if err := UnmarshalJSON([]byte(raw), &bundlev1.Bundle{}, WithAllowUnknownFields(true)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
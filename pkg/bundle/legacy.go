@@ -0,0 +1,181 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	containerv1 "github.com/elastic/harp/api/gen/go/harp/container/v1"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/sdk/types"
+)
+
+const (
+	// LegacyBundleContentType is the content type of bundles produced by
+	// secret-service, the harp predecessor.
+	LegacyBundleContentType = "application/vnd.secrethub.v1.Bundle"
+	// LegacyAnnotationPrefix is used to preserve legacy annotations without
+	// current equivalent.
+	LegacyAnnotationPrefix = "harp.elastic.co/v1/legacy#"
+	// LegacyFormatAnnotation records the content type of a migrated bundle.
+	LegacyFormatAnnotation = LegacyAnnotationPrefix + "format"
+	// LegacyUnknownFieldsAnnotation preserves unknown legacy fields of the
+	// annotated object as base64 encoded protobuf wire content.
+	LegacyUnknownFieldsAnnotation = LegacyAnnotationPrefix + "unknown"
+)
+
+// IsLegacy returns true when the container holds a bundle using a legacy
+// format.
+func IsLegacy(c *containerv1.Container) bool {
+	if c == nil || c.Headers == nil {
+		return false
+	}
+
+	return c.Headers.ContentType == LegacyBundleContentType
+}
+
+// migrateLegacy decodes a legacy bundle payload and upgrades it to the
+// current model.
+func migrateLegacy(r io.Reader) (*bundlev1.Bundle, error) {
+	// Check parameters
+	if types.IsNil(r) {
+		return nil, fmt.Errorf("unable to process nil reader")
+	}
+
+	log.Bg().Warn("Legacy bundle format is deprecated, use 'harp container migrate' to upgrade the container", zap.String("content_type", LegacyBundleContentType))
+
+	decoded, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decompress legacy bundle content")
+	}
+
+	// Legacy messages share field numbers with current ones, secret chains
+	// are not versioned and there is no merkle tree.
+	b := &bundlev1.Bundle{}
+	if err = proto.Unmarshal(decoded, b); err != nil {
+		return nil, fmt.Errorf("unable to decode legacy bundle content: %w", err)
+	}
+
+	// Upgrade all objects
+	migrateLegacyObject(b, b.Labels, b.Annotations)
+	for _, p := range b.Packages {
+		migrateLegacyObject(p, p.Labels, p.Annotations)
+
+		chains := []*bundlev1.SecretChain{p.Secrets}
+		for _, v := range sortedVersions(p) {
+			chains = append(chains, p.Versions[v])
+		}
+		for _, chain := range chains {
+			if chain == nil {
+				continue
+			}
+			migrateLegacyObject(chain, chain.Labels, chain.Annotations)
+
+			// Secret entries have no annotations, preserve them on the chain
+			for _, kv := range chain.Data {
+				if raw := stashUnknown(kv); raw != "" {
+					Annotate(chain, fmt.Sprintf("%s/data/%s", LegacyUnknownFieldsAnnotation, kv.Key), raw)
+				}
+			}
+		}
+	}
+
+	// Record migration
+	Annotate(b, LegacyFormatAnnotation, LegacyBundleContentType)
+
+	// Compute merkle tree root
+	tree, _, err := Tree(b)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compute merkle tree of migrated bundle content: %w", err)
+	}
+	b.MerkleTreeRoot = tree.Root()
+
+	// No error
+	return b, nil
+}
+
+type legacyObject interface {
+	proto.Message
+	AnnotationOwner
+}
+
+func migrateLegacyObject(obj legacyObject, labels, annotations map[string]string) {
+	// Map legacy names
+	renameLegacyKeys(labels)
+	renameLegacyKeys(annotations)
+
+	// Preserve unknown fields
+	if raw := stashUnknown(obj); raw != "" {
+		Annotate(obj, LegacyUnknownFieldsAnnotation, raw)
+	}
+}
+
+func renameLegacyKeys(m map[string]string) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		current, ok := currentAnnotation(k)
+		if !ok {
+			continue
+		}
+
+		// Keep the legacy name on conflict to stay lossless
+		if _, exists := m[current]; exists {
+			continue
+		}
+
+		m[current] = m[k]
+		delete(m, k)
+	}
+}
+
+// stashUnknown returns unknown message fields as base64 and removes them.
+func stashUnknown(msg proto.Message) string {
+	m := msg.ProtoReflect()
+
+	raw := m.GetUnknown()
+	if len(raw) == 0 {
+		return ""
+	}
+	m.SetUnknown(protoreflect.RawFields(nil))
+
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+func sortedVersions(p *bundlev1.Package) []uint32 {
+	versions := make([]uint32, 0, len(p.Versions))
+	for v := range p.Versions {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+
+	return versions
+}
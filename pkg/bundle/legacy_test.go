@@ -0,0 +1,226 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	containerv1 "github.com/elastic/harp/api/gen/go/harp/container/v1"
+	"github.com/elastic/harp/pkg/container"
+)
+
+const legacyFixtures = "../../test/fixtures/bundles"
+
+func loadLegacyFixture(t *testing.T, name string) *containerv1.Container {
+	t.Helper()
+
+	f, err := os.Open(filepath.Join(legacyFixtures, name))
+	if err != nil {
+		t.Fatalf("unable to open fixture: %v", err)
+	}
+	defer f.Close()
+
+	c, err := container.Load(f)
+	if err != nil {
+		t.Fatalf("unable to load legacy container: %v", err)
+	}
+	if !IsLegacy(c) {
+		t.Fatalf("fixture '%s' must be detected as legacy", name)
+	}
+
+	return c
+}
+
+func TestFromContainer_Legacy(t *testing.T) {
+	for _, name := range []string{"legacy.bundle", "legacy-aes256.bundle", "legacy-fernet.bundle", "legacy-secretbox.bundle"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			b, err := FromContainer(loadLegacyFixture(t, name))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// Known fields are preserved
+			if len(b.Packages) != 5 {
+				t.Fatalf("expected 5 packages, got %d", len(b.Packages))
+			}
+			if b.Version != 1 {
+				t.Errorf("unexpected bundle version %d", b.Version)
+			}
+			if got := b.Annotations[LegacyFormatAnnotation]; got != LegacyBundleContentType {
+				t.Errorf("unexpected legacy format annotation %q", got)
+			}
+
+			// Legacy annotations are renamed
+			if got := b.Annotations[LegacyAnnotationPrefix+"vaultBackendPath"]; got != "secrets" {
+				t.Errorf("unexpected vaultBackendPath annotation %q", got)
+			}
+			for _, obj := range append([]AnnotationOwner{b}, packageOwners(b)...) {
+				for k := range obj.GetAnnotations() {
+					if _, ok := currentAnnotation(k); ok {
+						t.Errorf("legacy annotation '%s' must be renamed", k)
+					}
+				}
+			}
+
+			// Round-trip through the current format
+			c, err := ToContainer(b)
+			if err != nil {
+				t.Fatalf("unable to write migrated bundle: %v", err)
+			}
+			var buf bytes.Buffer
+			if err := container.Dump(&buf, c); err != nil {
+				t.Fatalf("unable to dump container: %v", err)
+			}
+			loaded, err := FromContainerReader(&buf)
+			if err != nil {
+				t.Fatalf("unable to read migrated bundle: %v", err)
+			}
+			if !proto.Equal(b, loaded) {
+				t.Error("migrated bundle must round-trip")
+			}
+		})
+	}
+}
+
+func TestFromContainer_LegacySecrets(t *testing.T) {
+	b, err := FromContainer(loadLegacyFixture(t, "legacy.bundle"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	secrets, err := Read(b, "secrets/dist/env.yaml")
+	if err != nil {
+		t.Fatalf("unable to read secret: %v", err)
+	}
+	if secrets["DIST_ENV"] != "dist_env_value_awesome!!!" {
+		t.Errorf("unexpected secret value %v", secrets["DIST_ENV"])
+	}
+}
+
+func TestFromContainer_LegacyUnknownFields(t *testing.T) {
+	c := loadLegacyFixture(t, "legacy.bundle")
+
+	// Decode the legacy payload
+	zr, err := gzip.NewReader(bytes.NewReader(c.Raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := &bundlev1.Bundle{}
+	if err := proto.Unmarshal(payload, legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	// Add fields unknown to the current model
+	bundleField := protowire.AppendString(protowire.AppendTag(nil, 99, protowire.BytesType), "bundle-extra")
+	packageField := protowire.AppendVarint(protowire.AppendTag(nil, 42, protowire.VarintType), 7)
+	kvField := protowire.AppendString(protowire.AppendTag(nil, 15, protowire.BytesType), "kv-extra")
+	legacy.ProtoReflect().SetUnknown(protoreflect.RawFields(bundleField))
+	legacy.Packages[0].ProtoReflect().SetUnknown(protoreflect.RawFields(packageField))
+	legacy.Packages[0].Secrets.Data[0].ProtoReflect().SetUnknown(protoreflect.RawFields(kvField))
+
+	// Repack as a legacy container
+	raw, err := proto.Marshal(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(raw); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	c.Raw = buf.Bytes()
+
+	// Migrate
+	b, err := FromContainer(c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expect := func(owner AnnotationOwner, key string, want []byte) {
+		t.Helper()
+		if got := owner.GetAnnotations()[key]; got != base64.StdEncoding.EncodeToString(want) {
+			t.Errorf("unexpected '%s' annotation %q", key, got)
+		}
+	}
+	p := b.Packages[0]
+	expect(b, LegacyUnknownFieldsAnnotation, bundleField)
+	expect(p, LegacyUnknownFieldsAnnotation, packageField)
+	expect(p.Secrets, LegacyUnknownFieldsAnnotation+"/data/"+p.Secrets.Data[0].Key, kvField)
+
+	// Unknown fields are not serialized anymore
+	if len(b.ProtoReflect().GetUnknown()) != 0 || len(p.ProtoReflect().GetUnknown()) != 0 {
+		t.Error("unknown fields must be removed from migrated objects")
+	}
+}
+
+func TestRegisterLegacyAnnotation(t *testing.T) {
+	err := RegisterLegacyAnnotation("secret-service.elstc.co/bundleName", "foo")
+	if !errors.Is(err, ErrLegacyAnnotationAlreadyRegistered) {
+		t.Errorf("expected duplicate registration error, got %v", err)
+	}
+	if err := RegisterLegacyAnnotation("", "foo"); err == nil {
+		t.Error("blank legacy name must be rejected")
+	}
+
+	// Conflicting current key keeps the legacy one
+	m := map[string]string{
+		"secret-service.elstc.co/bundleName": "legacy",
+		"secret-service.elstc.io/bundleName": "typo",
+	}
+	renameLegacyKeys(m)
+	want := map[string]string{
+		LegacyAnnotationPrefix + "bundleName": "legacy",
+		"secret-service.elstc.io/bundleName":  "typo",
+	}
+	if len(m) != len(want) {
+		t.Fatalf("unexpected annotations %v", m)
+	}
+	for k, v := range want {
+		if m[k] != v {
+			t.Errorf("unexpected annotations %v", m)
+		}
+	}
+}
+
+func packageOwners(b *bundlev1.Bundle) []AnnotationOwner {
+	res := []AnnotationOwner{}
+	for _, p := range b.Packages {
+		res = append(res, p, p.Secrets)
+	}
+	return res
+}
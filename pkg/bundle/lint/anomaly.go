@@ -0,0 +1,212 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package lint
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+)
+
+const (
+	// RuleLengthChange is raised when a value length changed significantly.
+	RuleLengthChange = "HARP-AN-001"
+	// RuleEmptyValue is raised when a value became empty.
+	RuleEmptyValue = "HARP-AN-002"
+	// RuleEntropyDrop is raised when a high entropy value became a low entropy one.
+	RuleEntropyDrop = "HARP-AN-003"
+	// RuleTypeChange is raised when a value type changed.
+	RuleTypeChange = "HARP-AN-004"
+)
+
+// AnomalyThresholds describes value anomaly detection settings.
+type AnomalyThresholds struct {
+	// LengthChangeRatio is the maximum relative length change (0.5 = 50%).
+	LengthChangeRatio float64
+	// HighEntropy is the entropy (bits per byte) above which a value is
+	// considered random.
+	HighEntropy float64
+	// LowEntropy is the entropy (bits per byte) below which a previously
+	// random value is reported.
+	LowEntropy float64
+}
+
+// DefaultAnomalyThresholds returns default anomaly detection settings.
+func DefaultAnomalyThresholds() AnomalyThresholds {
+	return AnomalyThresholds{
+		LengthChangeRatio: 0.5,
+		HighEntropy:       3.5,
+		LowEntropy:        3.0,
+	}
+}
+
+// CompareAnomalies detects suspicious value changes between two bundle
+// versions. Findings only contain value metrics, never values.
+func CompareAnomalies(oldBundle, newBundle *bundlev1.Bundle, th AnomalyThresholds) ([]Finding, error) {
+	// Check arguments
+	if oldBundle == nil || newBundle == nil {
+		return nil, fmt.Errorf("unable to compare nil bundles")
+	}
+
+	// Convert bundles
+	oldMap, err := bundle.AsMap(oldBundle)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unpack old bundle: %w", err)
+	}
+	newMap, err := bundle.AsMap(newBundle)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unpack new bundle: %w", err)
+	}
+
+	res := []Finding{}
+	for path, newValue := range newMap {
+		oldSecrets, ok := oldMap[path].(bundle.KV)
+		if !ok {
+			continue
+		}
+		newSecrets, ok := newValue.(bundle.KV)
+		if !ok {
+			continue
+		}
+
+		for key, nv := range newSecrets {
+			ov, ok := oldSecrets[key]
+			if !ok {
+				continue
+			}
+			res = append(res, compareValue(path, key, ov, nv, th)...)
+		}
+	}
+
+	// Ensure stable order
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].Path != res[j].Path {
+			return res[i].Path < res[j].Path
+		}
+		if res[i].Key != res[j].Key {
+			return res[i].Key < res[j].Key
+		}
+		return res[i].RuleID < res[j].RuleID
+	})
+
+	// No error
+	return res, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func compareValue(path, key string, ov, nv interface{}, th AnomalyThresholds) []Finding {
+	res := []Finding{}
+
+	finding := func(ruleID, message string, metrics map[string]float64) {
+		res = append(res, Finding{
+			RuleID:  ruleID,
+			Path:    path,
+			Key:     key,
+			Message: message,
+			Metrics: metrics,
+		})
+	}
+
+	// Type change
+	oldRaw, oldOk := valueBytes(ov)
+	newRaw, newOk := valueBytes(nv)
+	if fmt.Sprintf("%T", ov) != fmt.Sprintf("%T", nv) || oldOk != newOk {
+		finding(RuleTypeChange, "value type changed", nil)
+		return res
+	}
+	if !oldOk {
+		return res
+	}
+	if isPEM(oldRaw) && !isPEM(newRaw) {
+		finding(RuleTypeChange, "value is no longer PEM encoded", nil)
+	}
+
+	oldLen, newLen := float64(len(oldRaw)), float64(len(newRaw))
+
+	// Empty value
+	if newLen == 0 {
+		if oldLen > 0 {
+			finding(RuleEmptyValue, "value became empty", map[string]float64{"old_length": oldLen, "new_length": newLen})
+		}
+		return res
+	}
+
+	// Length change
+	if oldLen > 0 {
+		ratio := math.Abs(newLen-oldLen) / oldLen
+		if ratio > th.LengthChangeRatio {
+			finding(RuleLengthChange, "value length changed significantly", map[string]float64{"old_length": oldLen, "new_length": newLen, "change_ratio": round(ratio)})
+		}
+	}
+
+	// Entropy drop
+	oldEntropy, newEntropy := entropy(oldRaw), entropy(newRaw)
+	if oldEntropy >= th.HighEntropy && newEntropy < th.LowEntropy {
+		finding(RuleEntropyDrop, "value entropy dropped", map[string]float64{"old_entropy": round(oldEntropy), "new_entropy": round(newEntropy)})
+	}
+
+	return res
+}
+
+func valueBytes(v interface{}) ([]byte, bool) {
+	switch vv := v.(type) {
+	case string:
+		return []byte(vv), true
+	case []byte:
+		return vv, true
+	default:
+	}
+	return nil, false
+}
+
+func isPEM(data []byte) bool {
+	return bytes.Contains(data, []byte("-----BEGIN ")) && len(pemBlocks(data)) > 0
+}
+
+// entropy computes Shannon entropy in bits per byte.
+func entropy(data []byte) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+
+	var freq [256]float64
+	for _, b := range data {
+		freq[b]++
+	}
+
+	res := 0.0
+	total := float64(len(data))
+	for _, f := range freq {
+		if f == 0 {
+			continue
+		}
+		p := f / total
+		res -= p * math.Log2(p)
+	}
+
+	return res
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,112 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package lint
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/elastic/harp/pkg/bundle"
+)
+
+func Test_CompareAnomalies(t *testing.T) {
+	certificate := certificatePEM(t, time.Now().Add(24*time.Hour))
+
+	oldBundle, err := bundle.FromMap(map[string]bundle.KV{
+		"app/production/security/harp/v1.0.0/server/database": {
+			"unchanged": "Zx8kQ2mN7pL4vR9tB3wY6cF1hJ5sD0gA",
+			"token":     "Zx8kQ2mN7pL4vR9tB3wY6cF1hJ5sD0gA",
+			"password":  "abc",
+			"comment":   "abcdefgh",
+			"cert":      certificate,
+			"port":      "5432",
+		},
+		"app/production/security/harp/v1.0.0/server/removed": {
+			"password": "abc",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newBundle, err := bundle.FromMap(map[string]bundle.KV{
+		"app/production/security/harp/v1.0.0/server/database": {
+			"unchanged": "Zx8kQ2mN7pL4vR9tB3wY6cF1hJ5sD0gA",
+			"token":     "aaaaaaaabbbbbbbbaaaaaaaabbbbbbbb",
+			"password":  "",
+			"comment":   "abcdefghijklmnopqrst",
+			"cert":      "not a certificate",
+			"port":      5432,
+			"added":     "",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	findings, err := CompareAnomalies(oldBundle, newBundle, DefaultAnomalyThresholds())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := []string{}
+	for _, f := range findings {
+		got = append(got, f.Key+":"+f.RuleID)
+
+		// Values must never be exposed
+		for _, v := range []string{"Zx8k", "aaaa", "abc", "certificate", "5432"} {
+			if strings.Contains(f.Message, v) {
+				t.Errorf("finding message must not contain values, got %q", f.Message)
+			}
+		}
+	}
+
+	want := []string{
+		"cert:HARP-AN-001",
+		"cert:HARP-AN-004",
+		"comment:HARP-AN-001",
+		"password:HARP-AN-002",
+		"port:HARP-AN-004",
+		"token:HARP-AN-003",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("%q. CompareAnomalies():\n-got/+want\ndiff %s", "anomalies", diff)
+	}
+}
+
+func Test_entropy(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		value string
+		want  float64
+	}{
+		{desc: "empty", value: "", want: 0},
+		{desc: "constant", value: "aaaa", want: 0},
+		{desc: "two symbols", value: "abab", want: 1},
+		{desc: "four symbols", value: "abcd", want: 2},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if got := entropy([]byte(tC.value)); got != tC.want {
+				t.Errorf("expected %f, got %f", tC.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package lint
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/gobwas/glob"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+)
+
+// Input describes a package rule evaluation input.
+type Input struct {
+	Package     *bundlev1.Package
+	Secrets     bundle.KV
+	Annotations *bundle.AnnotationResolver
+}
+
+// Annotation returns the effective value of the given package annotation,
+// including the ones inherited from the bundle annotation defaults.
+func (in *Input) Annotation(key string) (string, bool) {
+	return in.Annotations.Lookup(in.Package, key)
+}
+
+// Rule describes package lint rule contract.
+type Rule interface {
+	ID() string
+	Evaluate(ctx context.Context, in *Input) ([]Finding, error)
+}
+
+// Finding describes a rule violation.
+type Finding struct {
+	RuleID  string `json:"rule_id"`
+	Path    string `json:"path"`
+	Key     string `json:"key,omitempty"`
+	Message string `json:"message"`
+	Waived  bool   `json:"waived,omitempty"`
+	Reason  string `json:"waiver_reason,omitempty"`
+
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+}
+
+// Waiver describes an accepted rule violation.
+type Waiver struct {
+	RuleID string `json:"ruleId"`
+	Path   string `json:"path"`
+	Key    string `json:"key,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// Report describes lint results.
+type Report struct {
+	Findings   []Finding              `json:"findings"`
+	Collisions []bundle.PathCollision `json:"collisions,omitempty"`
+}
+
+// HasViolations returns true if the report contains unwaived findings.
+func (r *Report) HasViolations() bool {
+	for _, f := range r.Findings {
+		if !f.Waived {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate all rules over the given bundle packages.
+func Evaluate(ctx context.Context, b *bundlev1.Bundle, rules []Rule, waivers []Waiver) (*Report, error) {
+	// Check arguments
+	if b == nil {
+		return nil, fmt.Errorf("unable to lint nil bundle")
+	}
+
+	// Compile waivers
+	matchers, err := compileWaivers(waivers)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		Findings:   Quarantined(b),
+		Collisions: bundle.PathCollisions(b),
+	}
+	report.Findings = append(report.Findings, PathCollisions(report.Collisions)...)
+
+	// Resolve inherited annotations
+	annotations, err := bundle.InheritedAnnotations(b)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve inherited annotations: %w", err)
+	}
+
+	for _, p := range b.Packages {
+		// Skip locked packages
+		if p.Secrets == nil || p.Secrets.Locked != nil {
+			continue
+		}
+
+		// Unpack secrets once for all rules
+		secrets, err := bundle.AsSecretMap(p)
+		if err != nil {
+			return nil, fmt.Errorf("unable to unpack package '%s': %w", p.Name, err)
+		}
+
+		in := &Input{
+			Package:     p,
+			Secrets:     secrets,
+			Annotations: annotations,
+		}
+		for _, r := range rules {
+			findings, err := r.Evaluate(ctx, in)
+			if err != nil {
+				return nil, fmt.Errorf("unable to evaluate rule '%s' on package '%s': %w", r.ID(), p.Name, err)
+			}
+			report.Findings = append(report.Findings, findings...)
+		}
+	}
+
+	// Apply waivers
+	for i := range report.Findings {
+		for _, m := range matchers {
+			if m.match(&report.Findings[i]) {
+				report.Findings[i].Waived = true
+				report.Findings[i].Reason = m.reason
+				break
+			}
+		}
+	}
+
+	// Ensure stable order
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		fi, fj := report.Findings[i], report.Findings[j]
+		if fi.Path != fj.Path {
+			return fi.Path < fj.Path
+		}
+		if fi.Key != fj.Key {
+			return fi.Key < fj.Key
+		}
+		return fi.RuleID < fj.RuleID
+	})
+
+	// No error
+	return report, nil
+}
+
+// -----------------------------------------------------------------------------
+
+type waiverMatcher struct {
+	ruleID string
+	path   glob.Glob
+	key    glob.Glob
+	reason string
+}
+
+func (m *waiverMatcher) match(f *Finding) bool {
+	if m.ruleID != f.RuleID {
+		return false
+	}
+	if !m.path.Match(f.Path) {
+		return false
+	}
+	return m.key == nil || m.key.Match(f.Key)
+}
+
+func compileWaivers(waivers []Waiver) ([]*waiverMatcher, error) {
+	res := []*waiverMatcher{}
+	for _, w := range waivers {
+		// Check waiver
+		if w.RuleID == "" {
+			return nil, fmt.Errorf("waiver rule id must not be blank")
+		}
+		if w.Reason == "" {
+			return nil, fmt.Errorf("waiver for rule '%s' must have a reason", w.RuleID)
+		}
+
+		m := &waiverMatcher{
+			ruleID: w.RuleID,
+			reason: w.Reason,
+		}
+
+		// Compile path glob
+		pathPattern := w.Path
+		if pathPattern == "" {
+			pathPattern = "**"
+		}
+		g, err := glob.Compile(pathPattern, '/')
+		if err != nil {
+			return nil, fmt.Errorf("unable to compile waiver path '%s': %w", w.Path, err)
+		}
+		m.path = g
+
+		// Compile key glob
+		if w.Key != "" {
+			kg, err := glob.Compile(w.Key)
+			if err != nil {
+				return nil, fmt.Errorf("unable to compile waiver key '%s': %w", w.Key, err)
+			}
+			m.key = kg
+		}
+
+		res = append(res, m)
+	}
+
+	return res, nil
+}
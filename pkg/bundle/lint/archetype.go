@@ -0,0 +1,96 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package lint
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/elastic/harp/pkg/bundle/archetype"
+)
+
+// RuleArchetypeSchema is raised when a package doesn't match its archetype
+// schema.
+const RuleArchetypeSchema = "HARP-AR-001"
+
+func archetypeRule(a *ArchetypesPolicy) (Rule, error) {
+	registry := archetype.Builtin()
+
+	// Load organization archetypes
+	if a.Pack != "" {
+		f, err := os.Open(a.Pack)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open archetype pack '%s': %w", a.Pack, err)
+		}
+		defer f.Close()
+
+		pack, err := archetype.ParsePack(f)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load archetype pack '%s': %w", a.Pack, err)
+		}
+		if err := registry.Override(pack); err != nil {
+			return nil, fmt.Errorf("unable to register archetype pack '%s': %w", a.Pack, err)
+		}
+	}
+
+	return ArchetypeSchema(registry), nil
+}
+
+// ArchetypeSchema returns a rule checking packages scaffolded from an
+// archetype against the archetype schema.
+func ArchetypeSchema(registry *archetype.Registry) Rule {
+	return &archetypeSchemaRule{registry: registry}
+}
+
+type archetypeSchemaRule struct {
+	registry *archetype.Registry
+}
+
+func (r *archetypeSchemaRule) ID() string { return RuleArchetypeSchema }
+
+func (r *archetypeSchemaRule) Evaluate(_ context.Context, in *Input) ([]Finding, error) {
+	res := []Finding{}
+
+	// Only packages declaring an archetype are checked
+	name, ok := in.Annotation(archetype.Annotation)
+	if !ok {
+		return res, nil
+	}
+
+	a, err := r.registry.Get(name)
+	if err != nil {
+		res = append(res, Finding{
+			RuleID:  RuleArchetypeSchema,
+			Path:    in.Package.Name,
+			Message: fmt.Sprintf("unknown archetype '%s'", name),
+		})
+		return res, nil
+	}
+
+	for _, v := range a.Schema.Validate(map[string]interface{}(in.Secrets)) {
+		res = append(res, Finding{
+			RuleID:  RuleArchetypeSchema,
+			Path:    in.Package.Name,
+			Key:     v.Key,
+			Message: fmt.Sprintf("'%s' archetype: %s", name, v.Message),
+		})
+	}
+
+	return res, nil
+}
@@ -0,0 +1,133 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package lint
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/archetype"
+	"github.com/elastic/harp/pkg/template/engine"
+)
+
+func Test_ArchetypeSchema(t *testing.T) {
+	registry := archetype.Builtin()
+
+	// Scaffolded package
+	a, err := registry.Get("database")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scaffolded, err := a.Render(engine.NewContext(), "app/production/security/harp/v1.0.0/server/database")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Hand-edited packages
+	b, err := bundle.FromMap(map[string]bundle.KV{
+		"app/production/security/harp/v1.0.0/server/cache": {
+			"host": "localhost",
+			"port": "http",
+		},
+		"app/production/security/harp/v1.0.0/server/custom": {
+			"token": "x",
+		},
+		"app/production/security/harp/v1.0.0/server/plain": {
+			"token": "x",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range b.Packages {
+		switch p.Name {
+		case "app/production/security/harp/v1.0.0/server/cache":
+			bundle.Annotate(p, archetype.Annotation, "cache")
+		case "app/production/security/harp/v1.0.0/server/custom":
+			bundle.Annotate(p, archetype.Annotation, "custom")
+		default:
+		}
+	}
+	b.Packages = append(b.Packages, scaffolded)
+
+	report, err := Evaluate(context.Background(), b, []Rule{ArchetypeSchema(registry)}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, f := range report.Findings {
+		got = append(got, f.Path+":"+f.Key+":"+f.Message)
+	}
+	want := []string{
+		"app/production/security/harp/v1.0.0/server/cache:engine:'cache' archetype: required key is missing",
+		"app/production/security/harp/v1.0.0/server/cache:password:'cache' archetype: required key is missing",
+		"app/production/security/harp/v1.0.0/server/cache:port:'cache' archetype: value must match '^[0-9]{1,5}$'",
+		"app/production/security/harp/v1.0.0/server/custom::unknown archetype 'custom'",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected findings:\n%s", diff)
+	}
+}
+
+func Test_ArchetypeSchema_Inherited(t *testing.T) {
+	b, err := bundle.FromMap(map[string]bundle.KV{
+		"app/production/security/harp/v1.0.0/cache/redis": {
+			"host": "localhost",
+			"port": "http",
+		},
+		"app/production/security/harp/v1.0.0/server/plain": {
+			"token": "x",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Archetype declared by prefix
+	if err := bundle.AttachAnnotationDefaults(b, &bundle.AnnotationDefaults{
+		Spec: bundle.AnnotationDefaultsSpec{
+			Prefixes: []bundle.PrefixAnnotations{
+				{Prefix: "app/production/security/harp/v1.0.0/cache", Annotations: map[string]string{archetype.Annotation: "cache"}},
+			},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Evaluate(context.Background(), b, []Rule{ArchetypeSchema(archetype.Builtin())}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, f := range report.Findings {
+		got = append(got, f.Path+":"+f.Key+":"+f.Message)
+	}
+	want := []string{
+		"app/production/security/harp/v1.0.0/cache/redis:engine:'cache' archetype: required key is missing",
+		"app/production/security/harp/v1.0.0/cache/redis:password:'cache' archetype: required key is missing",
+		"app/production/security/harp/v1.0.0/cache/redis:port:'cache' archetype: value must match '^[0-9]{1,5}$'",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected findings:\n%s", diff)
+	}
+}
@@ -0,0 +1,49 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package lint
+
+import (
+	"fmt"
+
+	"github.com/elastic/harp/pkg/bundle"
+)
+
+// RulePathCollision is raised for each package path colliding with another one
+// by case, Unicode normalization form or confusable characters.
+const RulePathCollision = "HARP-PC-001"
+
+// PathCollisions returns a finding for each non-survivor path of the given
+// collision groups.
+func PathCollisions(collisions []bundle.PathCollision) []Finding {
+	res := []Finding{}
+
+	for _, c := range collisions {
+		for _, path := range c.Paths {
+			if path == c.Survivor {
+				continue
+			}
+			res = append(res, Finding{
+				RuleID:  RulePathCollision,
+				Path:    path,
+				Message: fmt.Sprintf("package path collides with '%s' (%s), canonical path is '%s'", c.Survivor, bundle.CollisionKindOf(path, c.Survivor), c.Canonical),
+			})
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,72 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build ignore
+// +build ignore
+
+// This program generates passwords_gen.go from the common password list.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+)
+
+func main() {
+	f, err := os.Open("data/common-passwords.txt")
+	if err != nil {
+		log.Fatalf("unable to open password list: %v", err)
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "// Code generated by gen_passwords.go. DO NOT EDIT.")
+	fmt.Fprintln(&buf)
+	fmt.Fprintln(&buf, "package lint")
+	fmt.Fprintln(&buf)
+	fmt.Fprintln(&buf, "// commonPasswords is generated from data/common-passwords.txt.")
+	fmt.Fprintln(&buf, "var commonPasswords = []string{")
+
+	// Passwords are matched case-insensitively
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fmt.Fprintf(&buf, "%q,\n", strings.ToLower(line))
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatalf("unable to read password list: %v", err)
+	}
+	fmt.Fprintln(&buf, "}")
+
+	// Format generated code
+	out, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatalf("unable to format generated code: %v", err)
+	}
+	if err := ioutil.WriteFile("passwords_gen.go", out, 0o644); err != nil {
+		log.Fatalf("unable to write generated code: %v", err)
+	}
+}
@@ -0,0 +1,203 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package lint
+
+import (
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"time"
+)
+
+var (
+	oidPublicKeyRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidPublicKeyECDSA = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+)
+
+// Minimal ASN.1 structures used to inspect keys without depending on Go
+// crypto support of the algorithm parameters (deprecated curves are not
+// supported by crypto/x509).
+type algorithmIdentifier struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters asn1.RawValue `asn1:"optional"`
+}
+
+type subjectPublicKeyInfo struct {
+	Algorithm algorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+type pkcs8PrivateKey struct {
+	Version    int
+	Algorithm  algorithmIdentifier
+	PrivateKey []byte
+}
+
+type ecPrivateKey struct {
+	Version       int
+	PrivateKey    []byte
+	NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	PublicKey     asn1.BitString        `asn1:"optional,explicit,tag:1"`
+}
+
+type pkcs1PrivateKey struct {
+	Version          int
+	N                *big.Int
+	E                int
+	D                *big.Int
+	P                *big.Int
+	Q                *big.Int
+	Dp               *big.Int      `asn1:"optional"`
+	Dq               *big.Int      `asn1:"optional"`
+	Qinv             *big.Int      `asn1:"optional"`
+	AdditionalPrimes asn1.RawValue `asn1:"optional"`
+}
+
+type pkcs1PublicKey struct {
+	N *big.Int
+	E int
+}
+
+type certificate struct {
+	TBSCertificate     tbsCertificate
+	SignatureAlgorithm algorithmIdentifier
+	SignatureValue     asn1.BitString
+}
+
+type tbsCertificate struct {
+	Version            int `asn1:"optional,explicit,default:0,tag:0"`
+	SerialNumber       *big.Int
+	SignatureAlgorithm algorithmIdentifier
+	Issuer             asn1.RawValue
+	Validity           validity
+	Subject            asn1.RawValue
+	PublicKey          subjectPublicKeyInfo
+	IssuerUniqueID     asn1.BitString `asn1:"optional,tag:1"`
+	SubjectUniqueID    asn1.BitString `asn1:"optional,tag:2"`
+	Extensions         asn1.RawValue  `asn1:"optional,explicit,tag:3"`
+}
+
+type validity struct {
+	NotBefore, NotAfter time.Time
+}
+
+// keyInfo describes extracted key properties.
+type keyInfo struct {
+	rsaBits  int
+	curveOID asn1.ObjectIdentifier
+	notAfter *time.Time
+}
+
+// pemBlocks returns all PEM blocks found in the given value.
+func pemBlocks(data []byte) []*pem.Block {
+	res := []*pem.Block{}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		res = append(res, block)
+	}
+	return res
+}
+
+// inspectBlock extracts key properties from a PEM block, best-effort only.
+func inspectBlock(block *pem.Block) (*keyInfo, bool) {
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		var k pkcs1PrivateKey
+		if _, err := asn1.Unmarshal(block.Bytes, &k); err != nil || k.N == nil {
+			return nil, false
+		}
+		return &keyInfo{rsaBits: k.N.BitLen()}, true
+	case "RSA PUBLIC KEY":
+		var k pkcs1PublicKey
+		if _, err := asn1.Unmarshal(block.Bytes, &k); err != nil || k.N == nil {
+			return nil, false
+		}
+		return &keyInfo{rsaBits: k.N.BitLen()}, true
+	case "EC PRIVATE KEY":
+		var k ecPrivateKey
+		if _, err := asn1.Unmarshal(block.Bytes, &k); err != nil {
+			return nil, false
+		}
+		return &keyInfo{curveOID: k.NamedCurveOID}, true
+	case "PRIVATE KEY":
+		var k pkcs8PrivateKey
+		if _, err := asn1.Unmarshal(block.Bytes, &k); err != nil {
+			return nil, false
+		}
+		switch {
+		case k.Algorithm.Algorithm.Equal(oidPublicKeyRSA):
+			var rk pkcs1PrivateKey
+			if _, err := asn1.Unmarshal(k.PrivateKey, &rk); err != nil || rk.N == nil {
+				return nil, false
+			}
+			return &keyInfo{rsaBits: rk.N.BitLen()}, true
+		case k.Algorithm.Algorithm.Equal(oidPublicKeyECDSA):
+			return &keyInfo{curveOID: namedCurve(k.Algorithm.Parameters)}, true
+		default:
+		}
+	case "PUBLIC KEY":
+		var k subjectPublicKeyInfo
+		if _, err := asn1.Unmarshal(block.Bytes, &k); err != nil {
+			return nil, false
+		}
+		return inspectPublicKey(&k)
+	case "CERTIFICATE":
+		var c certificate
+		if _, err := asn1.Unmarshal(block.Bytes, &c); err != nil {
+			return nil, false
+		}
+		info, ok := inspectPublicKey(&c.TBSCertificate.PublicKey)
+		if !ok {
+			info = &keyInfo{}
+		}
+		notAfter := c.TBSCertificate.Validity.NotAfter
+		info.notAfter = &notAfter
+		return info, true
+	default:
+	}
+
+	return nil, false
+}
+
+func inspectPublicKey(k *subjectPublicKeyInfo) (*keyInfo, bool) {
+	switch {
+	case k.Algorithm.Algorithm.Equal(oidPublicKeyRSA):
+		var pk pkcs1PublicKey
+		if _, err := asn1.Unmarshal(k.PublicKey.RightAlign(), &pk); err != nil || pk.N == nil {
+			return nil, false
+		}
+		return &keyInfo{rsaBits: pk.N.BitLen()}, true
+	case k.Algorithm.Algorithm.Equal(oidPublicKeyECDSA):
+		return &keyInfo{curveOID: namedCurve(k.Algorithm.Parameters)}, true
+	default:
+	}
+
+	return nil, false
+}
+
+func namedCurve(params asn1.RawValue) asn1.ObjectIdentifier {
+	var oid asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(params.FullBytes, &oid); err != nil {
+		return nil
+	}
+	return oid
+}
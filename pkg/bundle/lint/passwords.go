@@ -0,0 +1,37 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package lint
+
+import "strings"
+
+//go:generate go run gen_passwords.go
+
+func defaultCommonPasswords() map[string]struct{} {
+	res := make(map[string]struct{}, len(commonPasswords))
+	for _, p := range commonPasswords {
+		res[p] = struct{}{}
+	}
+	return res
+}
+
+// isCommonPassword returns true when the value is found in the list, the
+// comparison is case-insensitive.
+func isCommonPassword(list map[string]struct{}, value string) bool {
+	_, ok := list[strings.ToLower(value)]
+	return ok
+}
@@ -0,0 +1,282 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package lint
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/elastic/harp/pkg/sdk/convert"
+	"github.com/elastic/harp/pkg/sdk/types"
+)
+
+const (
+	// PolicyAPIVersion is the supported policy api version.
+	PolicyAPIVersion = "harp.elastic.co/v1"
+	// PolicyKind is the supported policy kind.
+	PolicyKind = "LintPolicy"
+)
+
+// Policy describes lint policy configuration.
+type Policy struct {
+	APIVersion string     `json:"apiVersion"`
+	Kind       string     `json:"kind"`
+	Spec       PolicySpec `json:"spec"`
+}
+
+// PolicySpec describes lint policy settings.
+type PolicySpec struct {
+	WeakCredentials *WeakCredentialsPolicy `json:"weakCredentials,omitempty"`
+	Archetypes      *ArchetypesPolicy      `json:"archetypes,omitempty"`
+	Waivers         []Waiver               `json:"waivers,omitempty"`
+}
+
+// ArchetypesPolicy describes archetype schema checks settings.
+type ArchetypesPolicy struct {
+	Disabled bool   `json:"disabled,omitempty"`
+	Pack     string `json:"pack,omitempty"`
+}
+
+// WeakCredentialsPolicy describes weak credential checks settings.
+type WeakCredentialsPolicy struct {
+	CommonPasswords    *CommonPasswordsCheck    `json:"commonPasswords,omitempty"`
+	DefaultCredentials *DefaultCredentialsCheck `json:"defaultCredentials,omitempty"`
+	RSAKeySize         *RSAKeySizeCheck         `json:"rsaKeySize,omitempty"`
+	CertificateExpiry  *CertificateExpiryCheck  `json:"certificateExpiry,omitempty"`
+	ECCurves           *ECCurvesCheck           `json:"ecCurves,omitempty"`
+}
+
+// CommonPasswordsCheck describes common password detection settings.
+type CommonPasswordsCheck struct {
+	Disabled   bool   `json:"disabled,omitempty"`
+	KeyPattern string `json:"keyPattern,omitempty"`
+	File       string `json:"file,omitempty"`
+}
+
+// DefaultCredentialsCheck describes default credential detection settings.
+type DefaultCredentialsCheck struct {
+	Disabled bool   `json:"disabled,omitempty"`
+	Pattern  string `json:"pattern,omitempty"`
+}
+
+// RSAKeySizeCheck describes RSA key size check settings.
+type RSAKeySizeCheck struct {
+	Disabled bool `json:"disabled,omitempty"`
+	MinBits  int  `json:"minBits,omitempty"`
+}
+
+// CertificateExpiryCheck describes certificate expiration check settings.
+type CertificateExpiryCheck struct {
+	Disabled bool   `json:"disabled,omitempty"`
+	Window   string `json:"window,omitempty"`
+}
+
+// ECCurvesCheck describes EC curve check settings.
+type ECCurvesCheck struct {
+	Disabled   bool     `json:"disabled,omitempty"`
+	Deprecated []string `json:"deprecated,omitempty"`
+}
+
+const (
+	defaultPasswordKeyPattern   = `(?i)(pass|pwd|secret|token|credential)`
+	defaultCredentialPattern    = `(?i)^(changeme|password|admin)$`
+	defaultRSAMinBits           = 2048
+	defaultCertificateExpiry    = 30 * 24 * time.Hour
+	defaultDeprecatedCurveNames = "P-192"
+)
+
+// DefaultPolicy returns a policy with all weak credential checks enabled.
+func DefaultPolicy() *Policy {
+	return &Policy{
+		APIVersion: PolicyAPIVersion,
+		Kind:       PolicyKind,
+		Spec: PolicySpec{
+			WeakCredentials: &WeakCredentialsPolicy{},
+			Archetypes:      &ArchetypesPolicy{},
+		},
+	}
+}
+
+// ParsePolicy reads a YAML or JSON policy from the given reader.
+func ParsePolicy(r io.Reader) (*Policy, error) {
+	// Check arguments
+	if types.IsNil(r) {
+		return nil, fmt.Errorf("reader is nil")
+	}
+
+	// Convert to JSON
+	jsonReader, err := convert.YAMLtoJSON(r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse input as LintPolicy: %w", err)
+	}
+
+	// Decode policy
+	var p Policy
+	dec := json.NewDecoder(jsonReader)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("unable to decode policy: %w", err)
+	}
+
+	// Check policy header
+	if p.APIVersion != PolicyAPIVersion {
+		return nil, fmt.Errorf("unsupported policy api version '%s'", p.APIVersion)
+	}
+	if p.Kind != PolicyKind {
+		return nil, fmt.Errorf("unsupported policy kind '%s'", p.Kind)
+	}
+
+	// No error
+	return &p, nil
+}
+
+// Rules builds the rule set described by the policy.
+func (p *Policy) Rules() ([]Rule, error) {
+	rules := []Rule{}
+
+	if wc := p.Spec.WeakCredentials; wc != nil {
+		wcRules, err := weakCredentialRules(wc, time.Now)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, wcRules...)
+	}
+
+	if a := p.Spec.Archetypes; a != nil && !a.Disabled {
+		r, err := archetypeRule(a)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+
+	return rules, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func weakCredentialRules(wc *WeakCredentialsPolicy, now func() time.Time) ([]Rule, error) {
+	rules := []Rule{}
+
+	// Common passwords
+	if c := wc.CommonPasswords; c == nil || !c.Disabled {
+		if c == nil {
+			c = &CommonPasswordsCheck{}
+		}
+		keyPattern, err := compilePattern(c.KeyPattern, defaultPasswordKeyPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid common passwords key pattern: %w", err)
+		}
+		list := defaultCommonPasswords()
+		if c.File != "" {
+			if err := loadPasswordFile(c.File, list); err != nil {
+				return nil, err
+			}
+		}
+		rules = append(rules, &commonPasswordRule{keyPattern: keyPattern, passwords: list})
+	}
+
+	// Default credentials
+	if c := wc.DefaultCredentials; c == nil || !c.Disabled {
+		pattern := ""
+		if c != nil {
+			pattern = c.Pattern
+		}
+		valuePattern, err := compilePattern(pattern, defaultCredentialPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid default credentials pattern: %w", err)
+		}
+		rules = append(rules, &defaultCredentialRule{pattern: valuePattern})
+	}
+
+	// RSA key size
+	if c := wc.RSAKeySize; c == nil || !c.Disabled {
+		minBits := defaultRSAMinBits
+		if c != nil && c.MinBits > 0 {
+			minBits = c.MinBits
+		}
+		rules = append(rules, &rsaKeySizeRule{minBits: minBits})
+	}
+
+	// Certificate expiry
+	if c := wc.CertificateExpiry; c == nil || !c.Disabled {
+		window := defaultCertificateExpiry
+		if c != nil && c.Window != "" {
+			d, err := time.ParseDuration(c.Window)
+			if err != nil {
+				return nil, fmt.Errorf("invalid certificate expiry window: %w", err)
+			}
+			window = d
+		}
+		rules = append(rules, &certificateExpiryRule{window: window, now: now})
+	}
+
+	// Deprecated EC curves
+	if c := wc.ECCurves; c == nil || !c.Disabled {
+		names := []string{defaultDeprecatedCurveNames}
+		if c != nil && len(c.Deprecated) > 0 {
+			names = c.Deprecated
+		}
+		curves, err := curveOIDs(names)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, &ecCurveRule{deprecated: curves})
+	}
+
+	return rules, nil
+}
+
+func compilePattern(value, defaultValue string) (*regexp.Regexp, error) {
+	if value == "" {
+		value = defaultValue
+	}
+	return regexp.Compile(value)
+}
+
+func loadPasswordFile(path string, list map[string]struct{}) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("unable to open password list '%s': %w", path, err)
+	}
+	defer f.Close()
+
+	return readPasswordList(f, list)
+}
+
+func readPasswordList(r io.Reader, list map[string]struct{}) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("unable to read password list: %w", err)
+	}
+
+	return nil
+}
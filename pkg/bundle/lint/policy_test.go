@@ -0,0 +1,165 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package lint
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/elastic/harp/pkg/bundle"
+)
+
+func Test_ParsePolicy(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		input     string
+		wantRules []string
+		wantErr   bool
+	}{
+		{
+			desc:    "invalid kind",
+			input:   "apiVersion: harp.elastic.co/v1\nkind: Foo\n",
+			wantErr: true,
+		},
+		{
+			desc:    "unknown field",
+			input:   "apiVersion: harp.elastic.co/v1\nkind: LintPolicy\nspec:\n  foo: bar\n",
+			wantErr: true,
+		},
+		{
+			desc:      "no checks",
+			input:     "apiVersion: harp.elastic.co/v1\nkind: LintPolicy\n",
+			wantRules: []string{},
+		},
+		{
+			desc:      "all checks",
+			input:     "apiVersion: harp.elastic.co/v1\nkind: LintPolicy\nspec:\n  weakCredentials: {}\n",
+			wantRules: []string{RuleCommonPassword, RuleDefaultCredential, RuleRSAKeySize, RuleCertificateExpiry, RuleDeprecatedCurve},
+		},
+		{
+			desc:      "archetype checks",
+			input:     "apiVersion: harp.elastic.co/v1\nkind: LintPolicy\nspec:\n  archetypes: {}\n",
+			wantRules: []string{RuleArchetypeSchema},
+		},
+		{
+			desc:    "missing archetype pack",
+			input:   "apiVersion: harp.elastic.co/v1\nkind: LintPolicy\nspec:\n  archetypes:\n    pack: /non-existent/pack.yaml\n",
+			wantErr: true,
+		},
+		{
+			desc: "disabled checks",
+			input: `apiVersion: harp.elastic.co/v1
+kind: LintPolicy
+spec:
+  weakCredentials:
+    commonPasswords:
+      disabled: true
+    rsaKeySize:
+      minBits: 4096
+    ecCurves:
+      disabled: true
+`,
+			wantRules: []string{RuleDefaultCredential, RuleRSAKeySize, RuleCertificateExpiry},
+		},
+		{
+			desc: "invalid window",
+			input: `apiVersion: harp.elastic.co/v1
+kind: LintPolicy
+spec:
+  weakCredentials:
+    certificateExpiry:
+      window: 30d
+`,
+			wantErr: true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			p, err := ParsePolicy(strings.NewReader(tC.input))
+			if err == nil {
+				var rules []Rule
+				rules, err = p.Rules()
+				if err == nil {
+					got := []string{}
+					for _, r := range rules {
+						got = append(got, r.ID())
+					}
+					if diff := cmp.Diff(tC.wantRules, got); diff != "" {
+						t.Errorf("%q. Policy.Rules():\n-got/+want\ndiff %s", tC.desc, diff)
+					}
+				}
+			}
+			if (err != nil) != tC.wantErr {
+				t.Errorf("error during the call, error = %v, wantErr %v", err, tC.wantErr)
+			}
+		})
+	}
+}
+
+func Test_Evaluate_Waivers(t *testing.T) {
+	b, err := bundle.FromMap(map[string]bundle.KV{
+		"app/production/security/harp/v1.0.0/server/database": {
+			"user":     "admin",
+			"password": "letmein",
+		},
+		"app/staging/security/harp/v1.0.0/server/database": {
+			"user":     "admin",
+			"password": "letmein",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := DefaultPolicy().Rules()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Evaluate(context.Background(), b, rules, []Waiver{
+		{RuleID: RuleDefaultCredential, Path: "app/staging/**", Key: "user", Reason: "staging test account"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, f := range report.Findings {
+		got = append(got, f.Path+":"+f.Key+":"+f.RuleID+":"+f.Reason)
+	}
+	want := []string{
+		"app/production/security/harp/v1.0.0/server/database:password:HARP-WC-001:",
+		"app/production/security/harp/v1.0.0/server/database:user:HARP-WC-002:",
+		"app/staging/security/harp/v1.0.0/server/database:password:HARP-WC-001:",
+		"app/staging/security/harp/v1.0.0/server/database:user:HARP-WC-002:staging test account",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("%q. Evaluate():\n-got/+want\ndiff %s", "waivers", diff)
+	}
+	if !report.HasViolations() {
+		t.Error("report must have violations")
+	}
+
+	// Waiver without reason is rejected
+	if _, err := Evaluate(context.Background(), b, rules, []Waiver{{RuleID: RuleCommonPassword}}); err == nil {
+		t.Error("error expected for waiver without reason")
+	}
+}
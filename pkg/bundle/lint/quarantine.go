@@ -0,0 +1,51 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package lint
+
+import (
+	"fmt"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+)
+
+// RuleQuarantined is raised for each quarantined package.
+const RuleQuarantined = "HARP-QU-001"
+
+// Quarantined returns a finding for each quarantined package of the given
+// bundle. Locked packages are reported too.
+func Quarantined(b *bundlev1.Bundle) []Finding {
+	res := []Finding{}
+	if b == nil {
+		return res
+	}
+
+	for _, p := range b.Packages {
+		reason, ok := bundle.QuarantineReason(p)
+		if !ok {
+			continue
+		}
+		res = append(res, Finding{
+			RuleID:  RuleQuarantined,
+			Path:    p.Name,
+			Message: fmt.Sprintf("package is quarantined: %s", reason),
+		})
+	}
+
+	return res
+}
@@ -0,0 +1,65 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package lint
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/elastic/harp/pkg/bundle"
+)
+
+func Test_Evaluate_Quarantined(t *testing.T) {
+	b, err := bundle.FromMap(map[string]bundle.KV{
+		"app/production/security/harp/v1.0.0/server/database": {
+			"user": "harp",
+		},
+		"app/production/security/harp/v1.0.0/server/leaked": {
+			"token": "ZmVhdHVyZS10b2tlbi12YWx1ZQ",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range b.Packages {
+		if p.Name == "app/production/security/harp/v1.0.0/server/leaked" {
+			bundle.Annotate(p, bundle.QuarantineAnnotation, "leaked in CI logs")
+		}
+	}
+
+	report, err := Evaluate(context.Background(), b, []Rule{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Finding{
+		{
+			RuleID:  RuleQuarantined,
+			Path:    "app/production/security/harp/v1.0.0/server/leaked",
+			Message: "package is quarantined: leaked in CI logs",
+		},
+	}
+	if diff := cmp.Diff(want, report.Findings); diff != "" {
+		t.Errorf("%q. Evaluate():\n-got/+want\ndiff %s", "quarantined", diff)
+	}
+	if !report.HasViolations() {
+		t.Error("report must have violations")
+	}
+}
@@ -0,0 +1,219 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package lint
+
+import (
+	"context"
+	"encoding/asn1"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// RuleCommonPassword is raised when a password is found in the common password list.
+	RuleCommonPassword = "HARP-WC-001"
+	// RuleDefaultCredential is raised when a value is a well-known default credential.
+	RuleDefaultCredential = "HARP-WC-002"
+	// RuleRSAKeySize is raised when a RSA key is shorter than the policy minimum.
+	RuleRSAKeySize = "HARP-WC-003"
+	// RuleCertificateExpiry is raised when a certificate expires within the policy window.
+	RuleCertificateExpiry = "HARP-WC-004"
+	// RuleDeprecatedCurve is raised when an EC key uses a deprecated curve.
+	RuleDeprecatedCurve = "HARP-WC-005"
+)
+
+var namedCurveOIDs = map[string]asn1.ObjectIdentifier{
+	"P-192": {1, 2, 840, 10045, 3, 1, 1},
+	"P-224": {1, 3, 132, 0, 33},
+	"P-256": {1, 2, 840, 10045, 3, 1, 7},
+	"P-384": {1, 3, 132, 0, 34},
+	"P-521": {1, 3, 132, 0, 35},
+}
+
+func curveOIDs(names []string) (map[string]asn1.ObjectIdentifier, error) {
+	res := map[string]asn1.ObjectIdentifier{}
+	for _, name := range names {
+		oid, ok := namedCurveOIDs[strings.ToUpper(name)]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve name '%s'", name)
+		}
+		res[strings.ToUpper(name)] = oid
+	}
+	return res, nil
+}
+
+// forEachValue calls the given function with all string values of the input
+// in stable key order.
+func forEachValue(in *Input, fn func(key, value string)) {
+	keys := make([]string, 0, len(in.Secrets))
+	for k := range in.Secrets {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		switch v := in.Secrets[k].(type) {
+		case string:
+			fn(k, v)
+		case []byte:
+			fn(k, string(v))
+		default:
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+type commonPasswordRule struct {
+	keyPattern *regexp.Regexp
+	passwords  map[string]struct{}
+}
+
+func (r *commonPasswordRule) ID() string { return RuleCommonPassword }
+
+func (r *commonPasswordRule) Evaluate(_ context.Context, in *Input) ([]Finding, error) {
+	res := []Finding{}
+	forEachValue(in, func(key, value string) {
+		if !r.keyPattern.MatchString(key) {
+			return
+		}
+		if isCommonPassword(r.passwords, value) {
+			res = append(res, Finding{
+				RuleID:  RuleCommonPassword,
+				Path:    in.Package.Name,
+				Key:     key,
+				Message: "value is a common password",
+			})
+		}
+	})
+	return res, nil
+}
+
+// -----------------------------------------------------------------------------
+
+type defaultCredentialRule struct {
+	pattern *regexp.Regexp
+}
+
+func (r *defaultCredentialRule) ID() string { return RuleDefaultCredential }
+
+func (r *defaultCredentialRule) Evaluate(_ context.Context, in *Input) ([]Finding, error) {
+	res := []Finding{}
+	forEachValue(in, func(key, value string) {
+		if r.pattern.MatchString(value) {
+			res = append(res, Finding{
+				RuleID:  RuleDefaultCredential,
+				Path:    in.Package.Name,
+				Key:     key,
+				Message: "value is a well-known default credential",
+			})
+		}
+	})
+	return res, nil
+}
+
+// -----------------------------------------------------------------------------
+
+type rsaKeySizeRule struct {
+	minBits int
+}
+
+func (r *rsaKeySizeRule) ID() string { return RuleRSAKeySize }
+
+func (r *rsaKeySizeRule) Evaluate(_ context.Context, in *Input) ([]Finding, error) {
+	res := []Finding{}
+	forEachValue(in, func(key, value string) {
+		for _, block := range pemBlocks([]byte(value)) {
+			info, ok := inspectBlock(block)
+			if !ok || info.rsaBits == 0 || info.rsaBits >= r.minBits {
+				continue
+			}
+			res = append(res, Finding{
+				RuleID:  RuleRSAKeySize,
+				Path:    in.Package.Name,
+				Key:     key,
+				Message: fmt.Sprintf("RSA key size is %d bits, expected at least %d bits", info.rsaBits, r.minBits),
+			})
+		}
+	})
+	return res, nil
+}
+
+// -----------------------------------------------------------------------------
+
+type certificateExpiryRule struct {
+	window time.Duration
+	now    func() time.Time
+}
+
+func (r *certificateExpiryRule) ID() string { return RuleCertificateExpiry }
+
+func (r *certificateExpiryRule) Evaluate(_ context.Context, in *Input) ([]Finding, error) {
+	res := []Finding{}
+	limit := r.now().Add(r.window)
+	forEachValue(in, func(key, value string) {
+		for _, block := range pemBlocks([]byte(value)) {
+			info, ok := inspectBlock(block)
+			if !ok || info.notAfter == nil || info.notAfter.After(limit) {
+				continue
+			}
+			res = append(res, Finding{
+				RuleID:  RuleCertificateExpiry,
+				Path:    in.Package.Name,
+				Key:     key,
+				Message: fmt.Sprintf("certificate expires at %s", info.notAfter.UTC().Format(time.RFC3339)),
+			})
+		}
+	})
+	return res, nil
+}
+
+// -----------------------------------------------------------------------------
+
+type ecCurveRule struct {
+	deprecated map[string]asn1.ObjectIdentifier
+}
+
+func (r *ecCurveRule) ID() string { return RuleDeprecatedCurve }
+
+func (r *ecCurveRule) Evaluate(_ context.Context, in *Input) ([]Finding, error) {
+	res := []Finding{}
+	forEachValue(in, func(key, value string) {
+		for _, block := range pemBlocks([]byte(value)) {
+			info, ok := inspectBlock(block)
+			if !ok || info.curveOID == nil {
+				continue
+			}
+			for name, oid := range r.deprecated {
+				if !info.curveOID.Equal(oid) {
+					continue
+				}
+				res = append(res, Finding{
+					RuleID:  RuleDeprecatedCurve,
+					Path:    in.Package.Name,
+					Key:     key,
+					Message: fmt.Sprintf("EC key uses deprecated curve %s", name),
+				})
+			}
+		}
+	})
+	return res, nil
+}
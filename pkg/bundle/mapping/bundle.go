@@ -0,0 +1,131 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package mapping
+
+import (
+	"fmt"
+	"time"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/secret"
+	"github.com/elastic/harp/pkg/template/engine"
+)
+
+// valueTemplateTimeout is the maximum rendering duration of a value template.
+const valueTemplateTimeout = 5 * time.Second
+
+// Apply renames bundle packages in place and renders the matching rule value
+// templates. Package labels are used as mapping metadata.
+func (e *Engine) Apply(b *bundlev1.Bundle) error {
+	// Check arguments
+	if b == nil {
+		return fmt.Errorf("unable to process nil bundle")
+	}
+
+	// Compute all names before renaming
+	names := make([]string, len(b.Packages))
+	rules := make([]*compiledRule, len(b.Packages))
+	sources := map[string]string{}
+	for i, p := range b.Packages {
+		res, r, err := e.evaluate(p.Name, p.Labels)
+		if err != nil {
+			return err
+		}
+		if previous, ok := sources[res.Path]; ok {
+			return fmt.Errorf("'%s' and '%s' are both mapped to '%s'", previous, p.Name, res.Path)
+		}
+		sources[res.Path] = p.Name
+		names[i], rules[i] = res.Path, r
+	}
+
+	// Render all values before any modification
+	values := make([][]*bundlev1.KV, len(b.Packages))
+	for i, p := range b.Packages {
+		kv, err := renderValueTemplates(p, rules[i])
+		if err != nil {
+			return err
+		}
+		values[i] = kv
+	}
+
+	for i, p := range b.Packages {
+		p.Name = names[i]
+		setSecrets(p, values[i])
+	}
+
+	// No error
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func renderValueTemplates(p *bundlev1.Package, rule *compiledRule) ([]*bundlev1.KV, error) {
+	if rule == nil || len(rule.values) == 0 {
+		return nil, nil
+	}
+
+	// Extract package secrets
+	if p.Secrets == nil {
+		return nil, fmt.Errorf("package '%s', rule '%s': unable to render value templates without secrets", p.Name, rule.name)
+	}
+	kv, err := bundle.AsSecretMap(p)
+	if err != nil {
+		return nil, fmt.Errorf("package '%s', rule '%s': %w", p.Name, rule.name, err)
+	}
+
+	// Render all values against original secrets
+	rendered := make([]*bundlev1.KV, 0, len(rule.values))
+	for _, v := range rule.values {
+		out, err := engine.RenderSafe(v.key, v.source, nil, map[string]interface{}(kv), valueTemplateTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("package '%s', rule '%s': unable to render value '%s': %w", p.Name, rule.name, v.key, err)
+		}
+
+		packed, err := secret.Pack(out)
+		if err != nil {
+			return nil, fmt.Errorf("package '%s', rule '%s': unable to pack value '%s': %w", p.Name, rule.name, v.key, err)
+		}
+
+		rendered = append(rendered, &bundlev1.KV{
+			Key:   v.key,
+			Type:  fmt.Sprintf("%T", out),
+			Value: packed,
+		})
+	}
+
+	// No error
+	return rendered, nil
+}
+
+// setSecrets overrides or appends the given secrets.
+func setSecrets(p *bundlev1.Package, values []*bundlev1.KV) {
+	for _, r := range values {
+		replaced := false
+		for i, s := range p.Secrets.Data {
+			if s.Key == r.Key {
+				p.Secrets.Data[i] = r
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			p.Secrets.Data = append(p.Secrets.Data, r)
+		}
+	}
+}
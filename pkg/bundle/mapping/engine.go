@@ -0,0 +1,336 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package mapping
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/elastic/harp/pkg/template/engine"
+)
+
+// ErrUnmapped is raised in strict mode when no rule matches the source name.
+var ErrUnmapped = errors.New("mapping: no rule matches")
+
+const (
+	// DefaultRule is the rule name reported when the default target is used.
+	DefaultRule = "<default>"
+	// IdentityRule is the rule name reported when the source name is kept.
+	IdentityRule = "<identity>"
+)
+
+// Result describes a mapping evaluation.
+type Result struct {
+	Path     string            `json:"path"`
+	Rule     string            `json:"rule"`
+	Captures map[string]string `json:"captures,omitempty"`
+	Trace    []Trace           `json:"trace"`
+}
+
+// Trace describes a rule evaluation.
+type Trace struct {
+	Rule    string `json:"rule"`
+	Matched bool   `json:"matched"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// Engine evaluates compiled mapping rules.
+type Engine struct {
+	strict bool
+	def    *template.Template
+	rules  []*compiledRule
+}
+
+type compiledRule struct {
+	name     string
+	pattern  *regexp.Regexp
+	metadata map[string]string
+	target   *template.Template
+	values   []valueTemplate
+}
+
+type valueTemplate struct {
+	key    string
+	source string
+}
+
+// Engine compiles the profile rules, or the pairs from source names to secret
+// paths.
+func (p *Profile) Engine() (*Engine, error) {
+	// Bidirectional profile
+	if p.Bidirectional() {
+		forward, _, err := p.pairEngines()
+		if err != nil {
+			return nil, err
+		}
+		return forward, nil
+	}
+
+	e := &Engine{
+		strict: p.Spec.Strict,
+		rules:  []*compiledRule{},
+	}
+
+	// Default target
+	if p.Spec.Default != "" {
+		t, err := parseTarget(DefaultRule, p.Spec.Default)
+		if err != nil {
+			return nil, err
+		}
+		e.def = t
+	}
+
+	for i, r := range p.Spec.Rules {
+		name := r.Name
+		if name == "" {
+			name = fmt.Sprintf("rule-%d", i)
+		}
+
+		cr := &compiledRule{
+			name:     name,
+			metadata: r.Match.Metadata,
+		}
+
+		// Compile source name pattern
+		var err error
+		switch {
+		case r.Match.Regex != "" && r.Match.Glob != "":
+			return nil, fmt.Errorf("rule '%s': regex and glob are exclusive", name)
+		case r.Match.Regex != "":
+			cr.pattern, err = regexp.Compile(r.Match.Regex)
+		case r.Match.Glob != "":
+			cr.pattern, err = regexp.Compile(globToRegexp(r.Match.Glob))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("rule '%s': unable to compile pattern: %w", name, err)
+		}
+		if cr.pattern == nil && len(cr.metadata) == 0 {
+			return nil, fmt.Errorf("rule '%s': at least one match condition is required", name)
+		}
+
+		// Compile target
+		if r.Target == "" {
+			return nil, fmt.Errorf("rule '%s': target must not be blank", name)
+		}
+		if cr.target, err = parseTarget(name, r.Target); err != nil {
+			return nil, err
+		}
+
+		// Compile value templates
+		if cr.values, err = parseValueTemplates(name, r.ValueTemplate); err != nil {
+			return nil, err
+		}
+
+		e.rules = append(e.rules, cr)
+	}
+
+	// No error
+	return e, nil
+}
+
+// Map evaluates the rules against the given source name and metadata.
+func (e *Engine) Map(name string, metadata map[string]string) (*Result, error) {
+	res, _, err := e.evaluate(name, metadata)
+	return res, err
+}
+
+// evaluate returns the mapping result and the matching rule, nil when no
+// rule matches.
+func (e *Engine) evaluate(name string, metadata map[string]string) (*Result, *compiledRule, error) {
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+
+	res := &Result{
+		Trace: []Trace{},
+	}
+
+	for _, r := range e.rules {
+		captures, reason := r.match(name, metadata)
+		res.Trace = append(res.Trace, Trace{Rule: r.name, Matched: reason == "", Reason: reason})
+		if reason != "" {
+			continue
+		}
+
+		// First matching rule wins
+		p, err := render(r.target, name, captures, metadata)
+		if err != nil {
+			return nil, nil, fmt.Errorf("rule '%s': %w", r.name, err)
+		}
+		res.Path, res.Rule, res.Captures = p, r.name, captures
+
+		return res, r, nil
+	}
+
+	switch {
+	case e.def != nil:
+		p, err := render(e.def, name, map[string]string{}, metadata)
+		if err != nil {
+			return nil, nil, fmt.Errorf("default: %w", err)
+		}
+		res.Path, res.Rule = p, DefaultRule
+	case e.strict:
+		return nil, nil, fmt.Errorf("unable to map '%s': %w", name, ErrUnmapped)
+	default:
+		res.Path, res.Rule = name, IdentityRule
+	}
+
+	return res, nil, nil
+}
+
+// Path returns only the mapped path.
+func (e *Engine) Path(name string, metadata map[string]string) (string, error) {
+	res, err := e.Map(name, metadata)
+	if err != nil {
+		return "", err
+	}
+
+	return res.Path, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (r *compiledRule) match(name string, metadata map[string]string) (map[string]string, string) {
+	captures := map[string]string{}
+
+	// Source name
+	if r.pattern != nil {
+		m := r.pattern.FindStringSubmatch(name)
+		if m == nil {
+			return nil, fmt.Sprintf("name doesn't match '%s'", r.pattern)
+		}
+		for i, v := range m {
+			captures[strconv.Itoa(i)] = v
+		}
+		for i, n := range r.pattern.SubexpNames() {
+			if n != "" {
+				captures[n] = m[i]
+			}
+		}
+	}
+
+	// Metadata conditions, sorted for stable reasons
+	keys := make([]string, 0, len(r.metadata))
+	for k := range r.metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v, ok := metadata[k]
+		if !ok {
+			return nil, fmt.Sprintf("metadata '%s' is missing", k)
+		}
+		if expected := r.metadata[k]; expected != "*" && v != expected {
+			return nil, fmt.Sprintf("metadata '%s' is '%s', expected '%s'", k, v, expected)
+		}
+	}
+
+	return captures, ""
+}
+
+func parseTarget(name, value string) (*template.Template, error) {
+	t, err := template.New(name).Option("missingkey=error").Funcs(template.FuncMap{
+		"lower":      strings.ToLower,
+		"upper":      strings.ToUpper,
+		"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	}).Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("rule '%s': unable to parse target template: %w", name, err)
+	}
+
+	return t, nil
+}
+
+func parseValueTemplates(name string, values map[string]string) ([]valueTemplate, error) {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := make([]valueTemplate, 0, len(keys))
+	for _, k := range keys {
+		if strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("rule '%s': value template key must not be blank", name)
+		}
+
+		// Validate template syntax with the restricted function set
+		if _, err := template.New(k).Funcs(engine.SafeFuncMap(nil)).Parse(values[k]); err != nil {
+			return nil, fmt.Errorf("rule '%s': unable to parse value template '%s': %w", name, k, err)
+		}
+
+		out = append(out, valueTemplate{key: k, source: values[k]})
+	}
+
+	return out, nil
+}
+
+func render(t *template.Template, name string, captures, metadata map[string]string) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, map[string]interface{}{
+		"Name":     name,
+		"Captures": captures,
+		"Metadata": metadata,
+	}); err != nil {
+		return "", fmt.Errorf("unable to render target: %w", err)
+	}
+
+	// Reject incomplete paths
+	p := strings.Trim(strings.TrimSpace(buf.String()), "/")
+	for _, part := range strings.Split(p, "/") {
+		if strings.TrimSpace(part) == "" {
+			return "", fmt.Errorf("target '%s' has empty segments", buf.String())
+		}
+	}
+
+	return p, nil
+}
+
+// globToRegexp converts a glob pattern to an anchored regexp. `**` matches
+// any characters, `*` any characters except `/`, `?` a single character
+// except `/`. Each wildcard is a numbered capture.
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				sb.WriteString("(.*)")
+				i++
+			} else {
+				sb.WriteString("([^/]*)")
+			}
+		case '?':
+			sb.WriteString("([^/])")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+
+	return sb.String()
+}
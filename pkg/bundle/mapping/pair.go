@@ -0,0 +1,338 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package mapping
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var captureNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Bidirectional returns true when the profile declares pairs.
+func (p *Profile) Bidirectional() bool {
+	return len(p.Spec.Pairs) > 0
+}
+
+// ReverseEngine compiles the profile pairs to map secret paths back to
+// source names.
+func (p *Profile) ReverseEngine() (*Engine, error) {
+	if !p.Bidirectional() {
+		return nil, fmt.Errorf("profile is not bidirectional, it declares no pairs")
+	}
+
+	_, reverse, err := p.pairEngines()
+	if err != nil {
+		return nil, err
+	}
+
+	return reverse, nil
+}
+
+// -----------------------------------------------------------------------------
+
+// pathTemplate is a path with named captures, `{name}` matches a single path
+// segment and `{name*}` one or more segments.
+type pathTemplate struct {
+	parts []templatePart
+}
+
+type templatePart struct {
+	literal string
+	capture string
+	multi   bool
+}
+
+func parsePathTemplate(raw string) (*pathTemplate, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, fmt.Errorf("template must not be blank")
+	}
+
+	t := &pathTemplate{}
+	seen := map[string]bool{}
+	for rest := raw; rest != ""; {
+		open := strings.IndexAny(rest, "{}")
+		switch {
+		case open < 0:
+			t.parts = append(t.parts, templatePart{literal: rest})
+			rest = ""
+			continue
+		case rest[open] == '}':
+			return nil, fmt.Errorf("template '%s' has an unexpected '}'", raw)
+		case open > 0:
+			t.parts = append(t.parts, templatePart{literal: rest[:open]})
+			rest = rest[open:]
+			continue
+		default:
+		}
+
+		// Capture
+		end := strings.IndexAny(rest[1:], "{}")
+		if end < 0 || rest[1+end] != '}' {
+			return nil, fmt.Errorf("template '%s' has an unterminated capture", raw)
+		}
+		name := rest[1 : 1+end]
+		part := templatePart{capture: strings.TrimSuffix(name, "*"), multi: strings.HasSuffix(name, "*")}
+		if !captureNameRegexp.MatchString(part.capture) {
+			return nil, fmt.Errorf("template '%s' has an invalid capture name '%s'", raw, name)
+		}
+		if seen[part.capture] {
+			return nil, fmt.Errorf("template '%s' declares capture '%s' more than once", raw, part.capture)
+		}
+		if n := len(t.parts); n > 0 && t.parts[n-1].capture != "" {
+			return nil, fmt.Errorf("template '%s' captures must be separated by a literal", raw)
+		}
+		seen[part.capture] = true
+		t.parts = append(t.parts, part)
+		rest = rest[2+end:]
+	}
+
+	return t, nil
+}
+
+// captures returns sorted capture names.
+func (t *pathTemplate) captures() []string {
+	res := []string{}
+	for _, p := range t.parts {
+		if p.capture != "" {
+			res = append(res, p.capture)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// pattern returns the anchored regexp matching the template.
+func (t *pathTemplate) pattern() *regexp.Regexp {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for _, p := range t.parts {
+		switch {
+		case p.capture == "":
+			sb.WriteString(regexp.QuoteMeta(p.literal))
+		case p.multi:
+			fmt.Fprintf(&sb, "(?P<%s>[^/]+(?:/[^/]+)*)", p.capture)
+		default:
+			fmt.Fprintf(&sb, "(?P<%s>[^/]+)", p.capture)
+		}
+	}
+	sb.WriteString("$")
+
+	return regexp.MustCompile(sb.String())
+}
+
+// target returns the template source rendering the template from captures.
+func (t *pathTemplate) target() string {
+	var sb strings.Builder
+	for _, p := range t.parts {
+		if p.capture == "" {
+			sb.WriteString(p.literal)
+			continue
+		}
+		fmt.Fprintf(&sb, "{{ index .Captures %q }}", p.capture)
+	}
+
+	return sb.String()
+}
+
+func (t *pathTemplate) expand(values map[string]string) string {
+	var sb strings.Builder
+	for _, p := range t.parts {
+		if p.capture == "" {
+			sb.WriteString(p.literal)
+			continue
+		}
+		sb.WriteString(values[p.capture])
+	}
+
+	return sb.String()
+}
+
+type compiledPair struct {
+	name   string
+	source *pathTemplate
+	target *pathTemplate
+}
+
+// pairEngines compiles forward (source to target) and reverse engines, and
+// checks their round-trip consistency.
+func (p *Profile) pairEngines() (*Engine, *Engine, error) {
+	if len(p.Spec.Rules) > 0 || p.Spec.Default != "" {
+		return nil, nil, fmt.Errorf("pairs can't be combined with rules or default target")
+	}
+
+	forward := &Engine{strict: p.Spec.Strict, rules: []*compiledRule{}}
+	reverse := &Engine{strict: p.Spec.Strict, rules: []*compiledRule{}}
+	pairs := make([]*compiledPair, 0, len(p.Spec.Pairs))
+	names := map[string]bool{}
+
+	for i, pair := range p.Spec.Pairs {
+		name := pair.Name
+		if name == "" {
+			name = fmt.Sprintf("pair-%d", i)
+		}
+		if names[name] {
+			return nil, nil, fmt.Errorf("pair '%s' is declared more than once", name)
+		}
+		names[name] = true
+
+		// Parse templates
+		src, err := parsePathTemplate(pair.Source)
+		if err != nil {
+			return nil, nil, fmt.Errorf("pair '%s': invalid source: %w", name, err)
+		}
+		dst, err := parsePathTemplate(pair.Target)
+		if err != nil {
+			return nil, nil, fmt.Errorf("pair '%s': invalid target: %w", name, err)
+		}
+		if sc, tc := src.captures(), dst.captures(); strings.Join(sc, ",") != strings.Join(tc, ",") {
+			return nil, nil, fmt.Errorf("pair '%s': source captures [%s] and target captures [%s] must be the same", name, strings.Join(sc, ", "), strings.Join(tc, ", "))
+		}
+
+		// Compile both directions
+		for _, d := range []struct {
+			e        *Engine
+			from, to *pathTemplate
+		}{{forward, src, dst}, {reverse, dst, src}} {
+			target, err := parseTarget(name, d.to.target())
+			if err != nil {
+				return nil, nil, err
+			}
+			d.e.rules = append(d.e.rules, &compiledRule{
+				name:    name,
+				pattern: d.from.pattern(),
+				target:  target,
+			})
+		}
+
+		pairs = append(pairs, &compiledPair{name: name, source: src, target: dst})
+	}
+
+	// Check round-trip consistency
+	if problems := checkRoundTrip(pairs, forward, reverse); len(problems) > 0 {
+		return nil, nil, fmt.Errorf("inconsistent bidirectional mapping: %s", strings.Join(problems, "; "))
+	}
+
+	// No error
+	return forward, reverse, nil
+}
+
+// checkRoundTrip maps generated samples of each pair through both engines,
+// in both orders, and returns the first problem found for each pair.
+func checkRoundTrip(pairs []*compiledPair, forward, reverse *Engine) []string {
+	// Use literal segments of all templates, alone or surrounded, as sample
+	// values to detect overlapping pairs and ambiguous captures.
+	literals := map[string]bool{}
+	for _, p := range pairs {
+		for _, t := range []*pathTemplate{p.source, p.target} {
+			for _, part := range t.parts {
+				for _, s := range strings.Split(part.literal, "/") {
+					if s != "" {
+						literals[s] = true
+						literals["x"+s+"y"] = true
+					}
+				}
+			}
+		}
+	}
+	values := make([]string, 0, len(literals))
+	for l := range literals {
+		values = append(values, l)
+	}
+	sort.Strings(values)
+
+	problems := []string{}
+	for _, p := range pairs {
+		for _, sample := range pairSamples(p.source, values) {
+			problem := roundTrip(p.name, p.source.expand(sample), p.target.expand(sample), forward, reverse, "mapping")
+			if problem == "" {
+				problem = roundTrip(p.name, p.target.expand(sample), p.source.expand(sample), reverse, forward, "reverse mapping")
+			}
+			if problem != "" {
+				problems = append(problems, fmt.Sprintf("pair '%s': %s", p.name, problem))
+				break
+			}
+		}
+	}
+
+	return problems
+}
+
+func pairSamples(t *pathTemplate, values []string) []map[string]string {
+	// Base sample
+	base := map[string]string{}
+	for i, p := range t.parts {
+		switch {
+		case p.capture == "":
+		case p.multi:
+			base[p.capture] = fmt.Sprintf("s%d/m%d", i, i)
+		default:
+			base[p.capture] = fmt.Sprintf("s%d", i)
+		}
+	}
+	samples := []map[string]string{base}
+
+	// Vary one capture at a time
+	for _, c := range t.captures() {
+		for _, v := range values {
+			s := map[string]string{}
+			for k, bv := range base {
+				s[k] = bv
+			}
+			s[c] = v
+			samples = append(samples, s)
+		}
+	}
+
+	return samples
+}
+
+// roundTrip maps the given name of a pair with the first engine, then back
+// with the second one. The first engine can select another pair, the round
+// trip must be the identity.
+func roundTrip(pair, from, to string, there, back *Engine, direction string) string {
+	// There
+	res, rule, err := there.evaluate(from, nil)
+	switch {
+	case err != nil:
+		return fmt.Sprintf("unable to map '%s': %v", from, err)
+	case rule == nil:
+		return fmt.Sprintf("'%s' is not mapped", from)
+	case rule.name == pair && res.Path != to:
+		return fmt.Sprintf("'%s' is mapped to '%s', expected '%s'", from, res.Path, to)
+	default:
+	}
+
+	// And back
+	res2, rule2, err := back.evaluate(res.Path, nil)
+	switch {
+	case err != nil:
+		return fmt.Sprintf("unable to map back '%s': %v", res.Path, err)
+	case rule2 == nil:
+		return fmt.Sprintf("'%s' is not mapped back", res.Path)
+	case res2.Path == from:
+		return ""
+	case rule2.name != rule.name:
+		return fmt.Sprintf("ambiguous %s, '%s' is mapped to '%s' by pair '%s' and back to '%s' by pair '%s'", direction, from, res.Path, rule.name, res2.Path, rule2.name)
+	default:
+	}
+
+	return fmt.Sprintf("round trip of '%s' gives '%s'", from, res2.Path)
+}
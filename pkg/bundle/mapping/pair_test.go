@@ -0,0 +1,268 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package mapping
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+)
+
+const testPairProfile = `apiVersion: harp.elastic.co/v1
+kind: MappingProfile
+spec:
+  strict: true
+  pairs:
+    - name: billing
+      source: "secret/billing/{app}/{env}/{name}"
+      target: "app/{env}/billing/{app}/v1.0.0/{name}"
+    - name: teams
+      source: "secret/teams/{team}/{app}/{env}/{name}"
+      target: "app/{env}/{team}/{app}/v0.0.0/{name}"
+    - name: infra
+      source: "secret/infra/{provider}/{path*}"
+      target: "infra/{provider}/legacy/{path*}/secrets"
+`
+
+func mustParseProfile(t *testing.T, raw string) *Profile {
+	t.Helper()
+
+	p, err := ParseProfile(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("unable to parse profile: %v", err)
+	}
+	return p
+}
+
+func TestPairs_RoundTrip(t *testing.T) {
+	p := mustParseProfile(t, testPairProfile)
+
+	forward, err := p.Engine()
+	if err != nil {
+		t.Fatalf("unable to compile forward engine: %v", err)
+	}
+	reverse, err := p.ReverseEngine()
+	if err != nil {
+		t.Fatalf("unable to compile reverse engine: %v", err)
+	}
+
+	testCases := []struct {
+		source, target, rule string
+	}{
+		{"secret/billing/invoices/production/database", "app/production/billing/invoices/v1.0.0/database", "billing"},
+		{"secret/teams/identity/sso/staging/ldap", "app/staging/identity/sso/v0.0.0/ldap", "teams"},
+		{"secret/infra/aws/eu-central-1/ec2/ssh", "infra/aws/legacy/eu-central-1/ec2/ssh/secrets", "infra"},
+	}
+	for _, tC := range testCases {
+		res, err := forward.Map(tC.source, nil)
+		if err != nil {
+			t.Fatalf("unable to map '%s': %v", tC.source, err)
+		}
+		if res.Path != tC.target || res.Rule != tC.rule {
+			t.Errorf("got %s (%s), want %s (%s)", res.Path, res.Rule, tC.target, tC.rule)
+		}
+
+		back, err := reverse.Map(tC.target, nil)
+		if err != nil {
+			t.Fatalf("unable to map back '%s': %v", tC.target, err)
+		}
+		if back.Path != tC.source || back.Rule != tC.rule {
+			t.Errorf("got %s (%s), want %s (%s)", back.Path, back.Rule, tC.source, tC.rule)
+		}
+	}
+
+	// Strict mode applies to both directions
+	if _, err := reverse.Map("product/ece/v1.0.0/server/key", nil); err == nil {
+		t.Error("unmapped path must be rejected in strict mode")
+	}
+}
+
+func TestPairs_Apply(t *testing.T) {
+	b := &bundlev1.Bundle{
+		Packages: []*bundlev1.Package{
+			{Name: "secret/teams/identity/sso/staging/ldap"},
+			{Name: "secret/infra/gcp/gke/token"},
+		},
+	}
+
+	forward, err := Load(strings.NewReader(testPairProfile))
+	if err != nil {
+		t.Fatalf("unable to load profile: %v", err)
+	}
+	if err := forward.Apply(b); err != nil {
+		t.Fatalf("unable to apply forward mapping: %v", err)
+	}
+	if diff := cmp.Diff([]string{"app/staging/identity/sso/v0.0.0/ldap", "infra/gcp/legacy/gke/token/secrets"}, packageNames(b)); diff != "" {
+		t.Errorf("unexpected forward names (-want +got):\n%s", diff)
+	}
+
+	reverse, err := LoadReverse(strings.NewReader(testPairProfile))
+	if err != nil {
+		t.Fatalf("unable to load profile: %v", err)
+	}
+	if err := reverse.Apply(b); err != nil {
+		t.Fatalf("unable to apply reverse mapping: %v", err)
+	}
+	if diff := cmp.Diff([]string{"secret/teams/identity/sso/staging/ldap", "secret/infra/gcp/gke/token"}, packageNames(b)); diff != "" {
+		t.Errorf("unexpected reverse names (-want +got):\n%s", diff)
+	}
+}
+
+func TestPairs_Invalid(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		pairs   string
+		wantErr string
+	}{
+		{
+			desc: "overlapping targets",
+			pairs: `
+    - name: teams
+      source: "secret/teams/{team}/{name}"
+      target: "app/production/{team}/{name}"
+    - name: shared
+      source: "secret/shared/{name}"
+      target: "app/production/shared/{name}"`,
+			wantErr: "pair 'shared': ambiguous mapping, 'secret/shared/s1' is mapped to 'app/production/shared/s1' by pair 'shared' and back to 'secret/teams/shared/s1' by pair 'teams'",
+		},
+		{
+			desc: "shadowed targets",
+			pairs: `
+    - name: shared
+      source: "secret/shared/{name}"
+      target: "app/production/shared/{name}"
+    - name: teams
+      source: "secret/teams/{team}/{name}"
+      target: "app/production/{team}/{name}"`,
+			wantErr: "pair 'teams': ambiguous mapping, 'secret/teams/shared/s3' is mapped to 'app/production/shared/s3' by pair 'teams' and back to 'secret/shared/s3' by pair 'shared'",
+		},
+		{
+			desc: "overlapping sources",
+			pairs: `
+    - name: any
+      source: "secret/{team}/{name}"
+      target: "app/production/{team}/{name}"
+    - name: infra
+      source: "secret/infra/{name}"
+      target: "infra/aws/{name}"`,
+			wantErr: "pair 'infra': ambiguous reverse mapping, 'infra/aws/s1' is mapped to 'secret/infra/s1' by pair 'infra' and back to 'app/production/infra/s1' by pair 'any'",
+		},
+		{
+			desc: "ambiguous captures",
+			pairs: `
+    - name: split
+      source: "secret/{app}-{env}"
+      target: "app/{env}/{app}"`,
+			wantErr: "pair 'split': 'secret/s1-x-y' is mapped to 'app/y/s1-x', expected 'app/x-y/s1'",
+		},
+		{
+			desc: "ambiguous multi segment captures",
+			pairs: `
+    - name: multi
+      source: "secret/{a*}/{b*}"
+      target: "app/{a*}/x/{b*}"`,
+			wantErr: "pair 'multi': 'secret/s1/m1/s3/m3' is mapped to 'app/s1/m1/s3/x/m3', expected 'app/s1/m1/x/s3/m3'",
+		},
+		{
+			desc: "capture mismatch",
+			pairs: `
+    - source: "secret/{team}/{name}"
+      target: "app/production/{name}"`,
+			wantErr: "pair 'pair-0': source captures [name, team] and target captures [name] must be the same",
+		},
+		{
+			desc: "adjacent captures",
+			pairs: `
+    - source: "secret/{team}{name}"
+      target: "app/{team}/{name}"`,
+			wantErr: "captures must be separated by a literal",
+		},
+		{
+			desc: "duplicated capture",
+			pairs: `
+    - source: "secret/{name}/{name}"
+      target: "app/{name}"`,
+			wantErr: "declares capture 'name' more than once",
+		},
+		{
+			desc: "unterminated capture",
+			pairs: `
+    - source: "secret/{name"
+      target: "app/{name}"`,
+			wantErr: "unterminated capture",
+		},
+		{
+			desc: "duplicated pair",
+			pairs: `
+    - name: a
+      source: "secret/{name}"
+      target: "app/{name}"
+    - name: a
+      source: "legacy/{name}"
+      target: "infra/{name}"`,
+			wantErr: "pair 'a' is declared more than once",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			p := mustParseProfile(t, "apiVersion: harp.elastic.co/v1\nkind: MappingProfile\nspec:\n  pairs:"+tC.pairs+"\n")
+
+			_, err := p.Engine()
+			if err == nil || !strings.Contains(err.Error(), tC.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tC.wantErr, err)
+			}
+			if _, errReverse := p.ReverseEngine(); errReverse == nil || errReverse.Error() != err.Error() {
+				t.Errorf("reverse engine must report the same error, got %v", errReverse)
+			}
+		})
+	}
+}
+
+func TestPairs_WithRules(t *testing.T) {
+	p := mustParseProfile(t, `apiVersion: harp.elastic.co/v1
+kind: MappingProfile
+spec:
+  default: "legacy/{{ .Name }}"
+  pairs:
+    - source: "secret/{name}"
+      target: "app/{name}"
+`)
+	if _, err := p.Engine(); err == nil {
+		t.Error("pairs combined with default target must be rejected")
+	}
+
+	// Profiles without pairs
+	p = mustParseProfile(t, testProfile)
+	if _, err := p.ReverseEngine(); err == nil {
+		t.Error("reverse engine requires pairs")
+	}
+	if _, err := LoadReverse(strings.NewReader(testProfile)); err != nil {
+		t.Errorf("rules must be compiled as is, got %v", err)
+	}
+}
+
+func packageNames(b *bundlev1.Bundle) []string {
+	res := []string{}
+	for _, p := range b.Packages {
+		res = append(res, p.Name)
+	}
+	return res
+}
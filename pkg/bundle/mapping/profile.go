@@ -0,0 +1,144 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package mapping provides the path mapping engine shared by importers and
+// exporters to translate external names to secret paths.
+package mapping
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/elastic/harp/pkg/sdk/convert"
+	"github.com/elastic/harp/pkg/sdk/types"
+)
+
+const (
+	// ProfileAPIVersion is the supported profile api version.
+	ProfileAPIVersion = "harp.elastic.co/v1"
+	// ProfileKind is the supported profile kind.
+	ProfileKind = "MappingProfile"
+)
+
+// Profile describes a mapping profile.
+type Profile struct {
+	APIVersion string      `json:"apiVersion"`
+	Kind       string      `json:"kind"`
+	Spec       ProfileSpec `json:"spec"`
+}
+
+// ProfileSpec describes mapping profile settings.
+type ProfileSpec struct {
+	// Strict fails unmapped entries instead of keeping their source name.
+	Strict bool `json:"strict,omitempty"`
+	// Default is the fallback target template used when no rule matches.
+	Default string `json:"default,omitempty"`
+	// Rules are evaluated in order, the first matching rule wins.
+	Rules []Rule `json:"rules,omitempty"`
+	// Pairs declare bidirectional mappings, they are exclusive with Rules and
+	// Default.
+	Pairs []Pair `json:"pairs,omitempty"`
+}
+
+// Rule describes a mapping rule.
+type Rule struct {
+	Name   string `json:"name,omitempty"`
+	Match  Match  `json:"match"`
+	Target string `json:"target"`
+	// ValueTemplate maps secret keys to templates rendered against the package
+	// secrets with the restricted function set. Rendered values override
+	// existing keys.
+	ValueTemplate map[string]string `json:"valueTemplate,omitempty"`
+}
+
+// Pair describes a bidirectional mapping between source names and secret
+// paths. Templates use `{name}` captures matching a single path segment, or
+// `{name*}` captures matching one or more segments. Both templates must
+// declare the same captures.
+type Pair struct {
+	Name   string `json:"name,omitempty"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// Match describes rule conditions. Regex and Glob are exclusive, metadata
+// conditions are exact values or `*` for key presence.
+type Match struct {
+	Regex    string            `json:"regex,omitempty"`
+	Glob     string            `json:"glob,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// ParseProfile reads a YAML or JSON mapping profile from the given reader.
+func ParseProfile(r io.Reader) (*Profile, error) {
+	// Check arguments
+	if types.IsNil(r) {
+		return nil, fmt.Errorf("reader is nil")
+	}
+
+	// Convert to JSON
+	jsonReader, err := convert.YAMLtoJSON(r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse input as MappingProfile: %w", err)
+	}
+
+	// Decode profile
+	var p Profile
+	dec := json.NewDecoder(jsonReader)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("unable to decode profile: %w", err)
+	}
+
+	// Check profile header
+	if p.APIVersion != ProfileAPIVersion {
+		return nil, fmt.Errorf("unsupported profile api version '%s'", p.APIVersion)
+	}
+	if p.Kind != ProfileKind {
+		return nil, fmt.Errorf("unsupported profile kind '%s'", p.Kind)
+	}
+
+	// No error
+	return &p, nil
+}
+
+// Load parses and compiles the mapping profile from the given reader.
+func Load(r io.Reader) (*Engine, error) {
+	p, err := ParseProfile(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.Engine()
+}
+
+// LoadReverse parses the mapping profile from the given reader and compiles
+// the engine mapping secret paths back to source names. Profiles without
+// pairs are compiled as is.
+func LoadReverse(r io.Reader) (*Engine, error) {
+	p, err := ParseProfile(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Bidirectional() {
+		return p.ReverseEngine()
+	}
+
+	return p.Engine()
+}
@@ -0,0 +1,181 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	csov1 "github.com/elastic/harp/pkg/cso/v1"
+)
+
+// MergeStrategy defines how secret key conflicts are resolved during merge.
+type MergeStrategy string
+
+const (
+	// MergeStrategyKeep keeps the destination value on conflict.
+	MergeStrategyKeep MergeStrategy = "keep"
+	// MergeStrategyOverwrite replaces the destination value on conflict.
+	MergeStrategyOverwrite MergeStrategy = "overwrite"
+	// MergeStrategyFail raises an error on conflict.
+	MergeStrategyFail MergeStrategy = "fail"
+)
+
+// MergeEntry describes a merged secret key.
+type MergeEntry struct {
+	Path   string `json:"path"`
+	Key    string `json:"key,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// MergeReport describes merge operation results.
+type MergeReport struct {
+	Copied     []MergeEntry `json:"copied"`
+	Skipped    []MergeEntry `json:"skipped"`
+	Conflicted []MergeEntry `json:"conflicted"`
+}
+
+// ParseMergeStrategy returns the merge strategy matching the given name.
+func ParseMergeStrategy(name string) (MergeStrategy, error) {
+	switch s := MergeStrategy(name); s {
+	case MergeStrategyKeep, MergeStrategyOverwrite, MergeStrategyFail:
+		return s, nil
+	default:
+	}
+
+	return "", fmt.Errorf("unsupported merge strategy '%s'", name)
+}
+
+// Merge src bundle packages into dst bundle. Packages are merged at secret
+// key level, conflicting keys are resolved according to the given strategy.
+func Merge(dst, src *bundlev1.Bundle, strategy MergeStrategy) (*MergeReport, error) {
+	// Check arguments
+	if dst == nil {
+		return nil, fmt.Errorf("unable to merge into nil bundle")
+	}
+	if src == nil {
+		return nil, fmt.Errorf("unable to merge nil bundle")
+	}
+	if _, err := ParseMergeStrategy(string(strategy)); err != nil {
+		return nil, err
+	}
+
+	report := &MergeReport{
+		Copied:     []MergeEntry{},
+		Skipped:    []MergeEntry{},
+		Conflicted: []MergeEntry{},
+	}
+
+	// Index destination packages by normalized path, so that equivalent
+	// versions (v1.2.0 and 1.2.0) designate the same package.
+	index := map[string]*bundlev1.Package{}
+	for _, p := range dst.Packages {
+		index[csov1.NormalizePath(p.Name)] = p
+	}
+
+	for _, sp := range src.Packages {
+		// Skip locked packages
+		if sp.Secrets == nil || sp.Secrets.Locked != nil {
+			report.Skipped = append(report.Skipped, MergeEntry{Path: sp.Name, Reason: "locked"})
+			continue
+		}
+
+		// Skip archived packages
+		if IsArchived(sp) {
+			report.Skipped = append(report.Skipped, MergeEntry{Path: sp.Name, Reason: "archived"})
+			continue
+		}
+
+		dp, ok := index[csov1.NormalizePath(sp.Name)]
+		if !ok {
+			// Copy the complete package
+			np, _ := proto.Clone(sp).(*bundlev1.Package)
+			dst.Packages = append(dst.Packages, np)
+			index[csov1.NormalizePath(np.Name)] = np
+			for _, kv := range np.Secrets.Data {
+				report.Copied = append(report.Copied, MergeEntry{Path: np.Name, Key: kv.Key})
+			}
+			continue
+		}
+
+		// Archived packages are immutable
+		if err := CheckMutable(dp); err != nil {
+			return report, err
+		}
+
+		// Merge secret keys
+		if err := mergePackage(dp, sp, strategy, report); err != nil {
+			return report, err
+		}
+	}
+
+	// Ensure deterministic order
+	SortPackages(dst.Packages)
+
+	// No error
+	return report, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func mergePackage(dp, sp *bundlev1.Package, strategy MergeStrategy, report *MergeReport) error {
+	// Check destination state
+	if dp.Secrets == nil {
+		dp.Secrets = &bundlev1.SecretChain{}
+	}
+	if dp.Secrets.Locked != nil {
+		report.Skipped = append(report.Skipped, MergeEntry{Path: dp.Name, Reason: "locked destination"})
+		return nil
+	}
+
+	// Index destination keys
+	keys := map[string]*bundlev1.KV{}
+	for _, kv := range dp.Secrets.Data {
+		keys[kv.Key] = kv
+	}
+
+	for _, skv := range sp.Secrets.Data {
+		dkv, ok := keys[skv.Key]
+		switch {
+		case !ok:
+			nkv, _ := proto.Clone(skv).(*bundlev1.KV)
+			dp.Secrets.Data = append(dp.Secrets.Data, nkv)
+			report.Copied = append(report.Copied, MergeEntry{Path: dp.Name, Key: skv.Key})
+		case bytes.Equal(dkv.Value, skv.Value):
+			report.Skipped = append(report.Skipped, MergeEntry{Path: dp.Name, Key: skv.Key, Reason: "identical"})
+		default:
+			switch strategy {
+			case MergeStrategyFail:
+				report.Conflicted = append(report.Conflicted, MergeEntry{Path: dp.Name, Key: skv.Key, Reason: "failed"})
+				return fmt.Errorf("secret key '%s' of package '%s' is conflicting", skv.Key, dp.Name)
+			case MergeStrategyOverwrite:
+				dkv.Type = skv.Type
+				dkv.Value = append([]byte{}, skv.Value...)
+				report.Conflicted = append(report.Conflicted, MergeEntry{Path: dp.Name, Key: skv.Key, Reason: "overwritten"})
+			case MergeStrategyKeep:
+				report.Conflicted = append(report.Conflicted, MergeEntry{Path: dp.Name, Key: skv.Key, Reason: "kept"})
+			}
+		}
+	}
+
+	// No error
+	return nil
+}
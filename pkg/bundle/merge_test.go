@@ -0,0 +1,138 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"testing"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+)
+
+func mustFromMap(t *testing.T, input map[string]KV) *bundlev1.Bundle {
+	b, err := FromMap(input)
+	if err != nil {
+		t.Fatalf("unable to build bundle: %v", err)
+	}
+	return b
+}
+
+func Test_Merge(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		strategy       MergeStrategy
+		wantErr        bool
+		wantValue      string
+		wantCopied     int
+		wantSkipped    int
+		wantConflicted int
+	}{
+		{
+			desc:     "invalid strategy",
+			strategy: MergeStrategy("foo"),
+			wantErr:  true,
+		},
+		{
+			desc:           "keep",
+			strategy:       MergeStrategyKeep,
+			wantValue:      "dst",
+			wantCopied:     2,
+			wantSkipped:    1,
+			wantConflicted: 1,
+		},
+		{
+			desc:           "overwrite",
+			strategy:       MergeStrategyOverwrite,
+			wantValue:      "src",
+			wantCopied:     2,
+			wantSkipped:    1,
+			wantConflicted: 1,
+		},
+		{
+			desc:     "fail",
+			strategy: MergeStrategyFail,
+			wantErr:  true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			dst := mustFromMap(t, map[string]KV{
+				"app/production/a": {"user": "admin", "password": "dst"},
+				"app/production/c": {"only": "dst"},
+			})
+			src := mustFromMap(t, map[string]KV{
+				"app/production/a": {"user": "admin", "password": "src", "host": "localhost"},
+				"app/production/b": {"token": "foo"},
+			})
+
+			report, err := Merge(dst, src, tC.strategy)
+			if (err != nil) != tC.wantErr {
+				t.Fatalf("error during the call, error = %v, wantErr %v", err, tC.wantErr)
+			}
+			if tC.wantErr {
+				return
+			}
+
+			if len(dst.Packages) != 3 {
+				t.Errorf("expected 3 packages, got %d", len(dst.Packages))
+			}
+			if len(report.Copied) != tC.wantCopied {
+				t.Errorf("expected %d copied, got %v", tC.wantCopied, report.Copied)
+			}
+			if len(report.Skipped) != tC.wantSkipped {
+				t.Errorf("expected %d skipped, got %v", tC.wantSkipped, report.Skipped)
+			}
+			if len(report.Conflicted) != tC.wantConflicted {
+				t.Errorf("expected %d conflicted, got %v", tC.wantConflicted, report.Conflicted)
+			}
+
+			secrets, err := Read(dst, "app/production/a")
+			if err != nil {
+				t.Fatalf("unable to read merged package: %v", err)
+			}
+			if secrets["password"] != tC.wantValue {
+				t.Errorf("expected %q, got %q", tC.wantValue, secrets["password"])
+			}
+			if secrets["host"] != "localhost" {
+				t.Errorf("expected new key to be merged")
+			}
+		})
+	}
+}
+
+func Test_Merge_NormalizedVersion(t *testing.T) {
+	dst := mustFromMap(t, map[string]KV{
+		"app/production/customer1/ece/v1.2.0/server/database": {"user": "admin"},
+	})
+	src := mustFromMap(t, map[string]KV{
+		"app/production/customer1/ece/1.2.0/server/database": {"password": "secret"},
+	})
+
+	report, err := Merge(dst, src, MergeStrategyFail)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(dst.Packages) != 1 {
+		t.Fatalf("equivalent versions must designate the same package, got %d packages", len(dst.Packages))
+	}
+	if dst.Packages[0].Name != "app/production/customer1/ece/v1.2.0/server/database" {
+		t.Errorf("destination package name must be preserved, got %q", dst.Packages[0].Name)
+	}
+	if len(dst.Packages[0].Secrets.Data) != 2 || len(report.Copied) != 1 {
+		t.Errorf("source keys must be merged in destination package")
+	}
+}
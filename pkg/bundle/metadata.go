@@ -0,0 +1,58 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+
+const (
+	// DescriptionAnnotation holds the human readable description of a package.
+	DescriptionAnnotation = "harp.elastic.co/v1/package#description"
+	// KeyDescriptionAnnotationPrefix prefixes the annotation holding the
+	// description of a package secret key (i.e. "...#description/<key>").
+	KeyDescriptionAnnotationPrefix = "harp.elastic.co/v1/package#description/"
+	// OwnerAnnotation holds the team owning a package.
+	OwnerAnnotation = "harp.elastic.co/v1/package#owner"
+	// RotationAnnotation holds the expected rotation period of a package
+	// (i.e. "90d").
+	RotationAnnotation = "harp.elastic.co/v1/package#rotation"
+)
+
+// chainDescriptionAnnotation is set on secret chains by the template engine.
+const chainDescriptionAnnotation = "description"
+
+// Description returns the package description. The secret chain description
+// set by the template engine is used when the package is not annotated.
+// Inherited annotations are not resolved, use AnnotationResolver for them.
+func Description(p *bundlev1.Package) string {
+	return (*AnnotationResolver)(nil).Description(p)
+}
+
+// KeyDescription returns the description of the given package secret key.
+func KeyDescription(p *bundlev1.Package, key string) string {
+	return (*AnnotationResolver)(nil).KeyDescription(p, key)
+}
+
+// Owner returns the team owning the given package.
+func Owner(p *bundlev1.Package) string {
+	return (*AnnotationResolver)(nil).Owner(p)
+}
+
+// RotationPeriod returns the expected rotation period of the given package.
+func RotationPeriod(p *bundlev1.Package) string {
+	return (*AnnotationResolver)(nil).RotationPeriod(p)
+}
@@ -0,0 +1,67 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"testing"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+)
+
+func TestDescription(t *testing.T) {
+	p := &bundlev1.Package{
+		Name: "app/production/security/harp/v1.0.0/server/database",
+		Annotations: map[string]string{
+			OwnerAnnotation:    " security ",
+			RotationAnnotation: "90d",
+			KeyDescriptionAnnotationPrefix + "PASSWORD": "Database password",
+		},
+		Secrets: &bundlev1.SecretChain{
+			Annotations: map[string]string{
+				"description": "Database credentials",
+			},
+		},
+	}
+
+	// Chain description fallback
+	if got := Description(p); got != "Database credentials" {
+		t.Errorf("unexpected description %q", got)
+	}
+
+	// Package annotation wins
+	p.Annotations[DescriptionAnnotation] = "Harp server database credentials"
+	if got := Description(p); got != "Harp server database credentials" {
+		t.Errorf("unexpected description %q", got)
+	}
+
+	if got := KeyDescription(p, "PASSWORD"); got != "Database password" {
+		t.Errorf("unexpected key description %q", got)
+	}
+	if got := KeyDescription(p, "USER"); got != "" {
+		t.Errorf("unexpected key description %q", got)
+	}
+	if got := Owner(p); got != "security" {
+		t.Errorf("unexpected owner %q", got)
+	}
+	if got := RotationPeriod(p); got != "90d" {
+		t.Errorf("unexpected rotation period %q", got)
+	}
+	if Description(nil) != "" || Owner(nil) != "" {
+		t.Error("nil package must not be described")
+	}
+}
@@ -0,0 +1,518 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	containerv1 "github.com/elastic/harp/api/gen/go/harp/container/v1"
+	"github.com/elastic/harp/pkg/container"
+	"github.com/elastic/harp/pkg/sdk/fsutil"
+	"github.com/elastic/harp/pkg/sdk/security"
+	"github.com/elastic/harp/pkg/sdk/types"
+)
+
+// ErrMappedPackageNotFound is raised when the requested package is not part
+// of a mapped bundle.
+var ErrMappedPackageNotFound = errors.New("package not found in mapped bundle")
+
+// SharedCacheLockTimeout is the maximum duration to wait for the shared cache
+// lock held by another process.
+var SharedCacheLockTimeout = 30 * time.Second
+
+const (
+	// sharedCacheMagic prefixes shared cache files, it's followed by the
+	// SHA256 digest of the bundle payload.
+	sharedCacheMagic        = "HARPMAP1"
+	sharedCacheHeaderLength = len(sharedCacheMagic) + sha256.Size
+
+	// Protobuf field numbers used to index packages without decoding them.
+	bundlePackagesField = 4
+	packageNameField    = 3
+)
+
+// WithMmap loads the bundle payload from a memory-mapped shared cache file,
+// so that processes loading the same container share the OS page cache.
+func WithMmap() LoadOption {
+	return func(opts *loadOptions) {
+		opts.mmap = true
+	}
+}
+
+// WithSharedCacheDir sets the shared cache directory used by the memory-mapped
+// loader. It defaults to /dev/shm when it is a tmpfs mount, mapping fails
+// otherwise so that plaintext bundles are never written to disk implicitly.
+func WithSharedCacheDir(dir string) LoadOption {
+	return func(opts *loadOptions) {
+		opts.cacheDir = dir
+	}
+}
+
+// MappedBundle is a read-only bundle which decodes packages on demand.
+type MappedBundle struct {
+	payload []byte
+	release func() error
+	spans   map[string]span
+	names   []string
+	opts    *loadOptions
+}
+
+type span struct {
+	start, end int
+}
+
+// OpenMapped indexes the bundle of an unsealed container without decoding
+// its packages. The bundle payload is memory-mapped from a shared cache file
+// when WithMmap is given, and kept in process memory otherwise. Load policies
+// are applied when a package is decoded.
+func OpenMapped(c *containerv1.Container, opts ...LoadOption) (*MappedBundle, error) {
+	// Check parameters
+	if types.IsNil(c) {
+		return nil, fmt.Errorf("unable to process nil container")
+	}
+	if types.IsNil(c.Headers) {
+		return nil, fmt.Errorf("unable to process nil container headers")
+	}
+	if err := container.CheckFeatures(c.Headers); err != nil {
+		return nil, err
+	}
+	if IsLegacy(c) {
+		return nil, fmt.Errorf("legacy containers can't be mapped")
+	}
+	if c.Headers.ContentType != bundleContentType {
+		return nil, fmt.Errorf("invalid content type for Bundle loader")
+	}
+	if c.Headers.ContentEncoding != "gzip" {
+		return nil, fmt.Errorf("invalid content encoding for Bundle loader")
+	}
+
+	// Apply options
+	dopts := newLoadOptions(opts...)
+
+	var (
+		payload []byte
+		release = func() error { return nil }
+		err     error
+	)
+	if dopts.mmap {
+		payload, release, err = mapSharedCache(c, dopts.cacheDir)
+	} else {
+		payload, err = decompressPayload(c)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Index packages
+	spans, err := indexPackages(payload)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	names := make([]string, 0, len(spans))
+	for name := range spans {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// No error
+	return &MappedBundle{
+		payload: payload,
+		release: release,
+		spans:   spans,
+		names:   names,
+		opts:    dopts,
+	}, nil
+}
+
+// Names returns the sorted package names.
+func (m *MappedBundle) Names() []string {
+	return append([]string{}, m.names...)
+}
+
+// Package decodes the named package. It returns nil when the package is
+// dropped or hidden by load policies.
+func (m *MappedBundle) Package(name string) (*bundlev1.Package, error) {
+	s, ok := m.spans[name]
+	if !ok {
+		return nil, fmt.Errorf("unable to decode '%s': %w", name, ErrMappedPackageNotFound)
+	}
+
+	// Decode package, values are copied out of the mapping
+	p := &bundlev1.Package{}
+	if err := proto.Unmarshal(m.payload[s.start:s.end], p); err != nil {
+		return nil, fmt.Errorf("unable to decode '%s' package: %w", name, err)
+	}
+
+	// Apply load policies
+	b := &bundlev1.Bundle{Packages: []*bundlev1.Package{p}}
+	if _, _, err := m.opts.apply(b); err != nil {
+		return nil, err
+	}
+	if len(b.Packages) == 0 {
+		return nil, nil
+	}
+
+	// No error
+	return b.Packages[0], nil
+}
+
+// Bundle decodes the complete bundle and checks its merkle tree root.
+func (m *MappedBundle) Bundle() (*bundlev1.Bundle, error) {
+	b, err := decodePayload(m.payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply load policies
+	dropped, hidden, err := m.opts.apply(b)
+	if err != nil {
+		return nil, err
+	}
+	if m.opts.dropped != nil {
+		*m.opts.dropped = dropped
+	}
+	if m.opts.hidden != nil {
+		*m.opts.hidden = hidden
+	}
+
+	// No error
+	return b, nil
+}
+
+// Close releases the mapping, decoded packages remain usable. The shared cache
+// file is removed when no other process maps it.
+func (m *MappedBundle) Close() error {
+	release := m.release
+	m.payload, m.spans = nil, nil
+	m.release = func() error { return nil }
+	return release()
+}
+
+// -----------------------------------------------------------------------------
+
+// mapSharedCache maps the bundle payload from the shared cache, the cache file
+// is created on miss and replaced when its digest doesn't match its content.
+func mapSharedCache(c *containerv1.Container, dir string) ([]byte, func() error, error) {
+	if dir == "" {
+		var err error
+		if dir, err = defaultSharedCacheDir(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Container digest is the cache key
+	key := sha256.Sum256(c.Raw)
+	path := filepath.Join(dir, fmt.Sprintf("harp-%s.bundle", hex.EncodeToString(key[:])))
+
+	// Serialize cache access between processes
+	unlock, err := fsutil.Lock(path, SharedCacheLockTimeout)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to lock shared cache: %w", err)
+	}
+	defer unlock()
+
+	// Reuse existing cache file
+	data, release, err := openSharedCache(path)
+	switch {
+	case err == nil:
+		return data[sharedCacheHeaderLength:], release, nil
+	case os.IsNotExist(err):
+	default:
+		// Corrupted cache is rebuilt
+		if errRemove := os.Remove(path); errRemove != nil && !os.IsNotExist(errRemove) {
+			return nil, nil, fmt.Errorf("unable to remove invalid shared cache: %w", errRemove)
+		}
+	}
+
+	// Extract and check payload before sharing it
+	payload, err := decompressPayload(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := decodePayload(payload); err != nil {
+		return nil, nil, err
+	}
+
+	// Write cache file
+	if err := writeSharedCache(path, payload); err != nil {
+		return nil, nil, err
+	}
+	data, release, err = openSharedCache(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to open shared cache: %w", err)
+	}
+
+	// Remove cache files left by previous container versions
+	sweepSharedCache(dir, path)
+
+	// No error
+	return data[sharedCacheHeaderLength:], release, nil
+}
+
+// openSharedCache maps the cache file and holds a shared lock on it until the
+// mapping is released, so that the last user removes the file. It must be
+// called with the cache file lock held.
+func openSharedCache(path string) ([]byte, func() error, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, unmap, err := mapSharedFile(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+
+	return data, func() error {
+		errUnmap := unmap()
+		if err := releaseSharedCache(path, f); err != nil {
+			return err
+		}
+		return errUnmap
+	}, nil
+}
+
+func mapSharedFile(f *os.File) ([]byte, func() error, error) {
+	// Register as a cache file user
+	if err := lockShared(f); err != nil {
+		return nil, nil, fmt.Errorf("unable to lock shared cache file: %w", err)
+	}
+
+	// Only owner-only cache files are trusted
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to stat shared cache: %w", err)
+	}
+	if fi.Mode().Perm() != 0o600 {
+		return nil, nil, fmt.Errorf("shared cache file must have 0600 permissions, got %04o", fi.Mode().Perm())
+	}
+	if err := checkOwner(fi); err != nil {
+		return nil, nil, err
+	}
+	if fi.Size() < int64(sharedCacheHeaderLength) {
+		return nil, nil, fmt.Errorf("shared cache file is truncated")
+	}
+
+	// Map file content
+	data, release, err := mapFile(f, int(fi.Size()))
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to map shared cache: %w", err)
+	}
+
+	// Check content digest
+	digest := sha256.Sum256(data[sharedCacheHeaderLength:])
+	if string(data[:len(sharedCacheMagic)]) != sharedCacheMagic || !security.SecureCompare(data[len(sharedCacheMagic):sharedCacheHeaderLength], digest[:]) {
+		release()
+		return nil, nil, fmt.Errorf("shared cache digest mismatch")
+	}
+
+	// No error
+	return data, release, nil
+}
+
+// releaseSharedCache closes the cache file and removes it when no other
+// process uses it.
+func releaseSharedCache(path string, f *os.File) error {
+	unlock, err := fsutil.Lock(path, SharedCacheLockTimeout)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("unable to lock shared cache: %w", err)
+	}
+	defer unlock()
+
+	// Other users hold a shared lock
+	unused := tryLockExclusive(f)
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("unable to close shared cache: %w", err)
+	}
+	if !unused {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to remove shared cache: %w", err)
+	}
+
+	// No error
+	return nil
+}
+
+// sweepSharedCache removes the unused cache files of the directory, except
+// the given one. Cache files left by crashed processes are not locked anymore.
+func sweepSharedCache(dir, keep string) {
+	paths, err := filepath.Glob(filepath.Join(dir, "harp-*.bundle"))
+	if err != nil {
+		return
+	}
+
+	for _, path := range paths {
+		if path == keep {
+			continue
+		}
+
+		// Skip cache files being opened
+		unlock, err := fsutil.Lock(path, 0)
+		if err != nil {
+			continue
+		}
+		if f, err := os.Open(path); err == nil {
+			unused := tryLockExclusive(f)
+			f.Close()
+			if unused {
+				os.Remove(path)
+			}
+		}
+		unlock()
+	}
+}
+
+func writeSharedCache(path string, payload []byte) error {
+	f, err := fsutil.CreateAtomic(path, 0o600)
+	if err != nil {
+		return fmt.Errorf("unable to create shared cache: %w", err)
+	}
+	defer f.Close()
+
+	// Write header and payload
+	digest := sha256.Sum256(payload)
+	for _, chunk := range [][]byte{[]byte(sharedCacheMagic), digest[:], payload} {
+		if _, err := f.Write(chunk); err != nil {
+			return fmt.Errorf("unable to write shared cache: %w", err)
+		}
+	}
+
+	// Publish the cache file
+	if err := f.Commit(); err != nil {
+		return fmt.Errorf("unable to commit shared cache: %w", err)
+	}
+
+	// No error
+	return nil
+}
+
+// defaultSharedCacheDir returns /dev/shm when it is a tmpfs mount, plaintext
+// bundles must not be written to a disk-backed directory unless configured.
+func defaultSharedCacheDir() (string, error) {
+	const dir = "/dev/shm"
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() || !isTmpfs(dir) {
+		return "", fmt.Errorf("no tmpfs-backed shared cache directory found, a shared cache directory must be configured")
+	}
+	return dir, nil
+}
+
+func decompressPayload(c *containerv1.Container) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(c.Raw))
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize compression reader")
+	}
+	payload, err := ioutil.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decompress bundle content")
+	}
+
+	return payload, nil
+}
+
+func decodePayload(payload []byte) (*bundlev1.Bundle, error) {
+	return Load(bytes.NewReader(payload))
+}
+
+// indexPackages returns package message locations by name.
+func indexPackages(payload []byte) (map[string]span, error) {
+	spans := map[string]span{}
+
+	for offset := 0; offset < len(payload); {
+		num, typ, n := protowire.ConsumeTag(payload[offset:])
+		if n < 0 {
+			return nil, fmt.Errorf("unable to index bundle content: %w", protowire.ParseError(n))
+		}
+		offset += n
+
+		// Skip bundle level fields
+		if num != bundlePackagesField || typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, payload[offset:])
+			if n < 0 {
+				return nil, fmt.Errorf("unable to index bundle content: %w", protowire.ParseError(n))
+			}
+			offset += n
+			continue
+		}
+
+		// Locate package message
+		msg, n := protowire.ConsumeBytes(payload[offset:])
+		if n < 0 {
+			return nil, fmt.Errorf("unable to index bundle content: %w", protowire.ParseError(n))
+		}
+		end := offset + n
+		name, err := packageName(msg)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := spans[name]; ok {
+			return nil, fmt.Errorf("duplicate package '%s' in bundle content", name)
+		}
+		spans[name] = span{start: end - len(msg), end: end}
+		offset = end
+	}
+
+	// No error
+	return spans, nil
+}
+
+func packageName(msg []byte) (string, error) {
+	name := ""
+	for len(msg) > 0 {
+		num, typ, n := protowire.ConsumeTag(msg)
+		if n < 0 {
+			return "", fmt.Errorf("unable to index package: %w", protowire.ParseError(n))
+		}
+		msg = msg[n:]
+
+		if num == packageNameField && typ == protowire.BytesType {
+			v, n := protowire.ConsumeString(msg)
+			if n < 0 {
+				return "", fmt.Errorf("unable to index package: %w", protowire.ParseError(n))
+			}
+			name = v
+			msg = msg[n:]
+			continue
+		}
+
+		n = protowire.ConsumeFieldValue(num, typ, msg)
+		if n < 0 {
+			return "", fmt.Errorf("unable to index package: %w", protowire.ParseError(n))
+		}
+		msg = msg[n:]
+	}
+
+	return name, nil
+}
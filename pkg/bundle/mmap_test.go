@@ -0,0 +1,406 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	containerv1 "github.com/elastic/harp/api/gen/go/harp/container/v1"
+	"github.com/elastic/harp/pkg/container"
+)
+
+func mustContainer(t testing.TB, raw []byte) *containerv1.Container {
+	t.Helper()
+
+	c, err := container.Load(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("unable to load container: %v", err)
+	}
+	return c
+}
+
+func cacheFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join(dir, "harp-*.bundle"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestOpenMapped_LazyDecode(t *testing.T) {
+	raw := expiryContainer(t)
+	want, err := FromContainerReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("unable to load reference bundle: %v", err)
+	}
+
+	for _, mmap := range []bool{false, true} {
+		t.Run(fmt.Sprintf("mmap=%v", mmap), func(t *testing.T) {
+			opts := []LoadOption{WithSharedCacheDir(t.TempDir())}
+			if mmap {
+				opts = append(opts, WithMmap())
+			}
+
+			m, err := OpenMapped(mustContainer(t, raw), opts...)
+			if err != nil {
+				t.Fatalf("unable to open mapped bundle: %v", err)
+			}
+			defer m.Close()
+
+			// Index is complete and sorted
+			names := m.Names()
+			if len(names) != len(want.Packages) {
+				t.Fatalf("expected %d packages, got %d", len(want.Packages), len(names))
+			}
+			for i, p := range want.Packages {
+				if names[i] != p.Name {
+					t.Fatalf("expected '%s' at %d, got '%s'", p.Name, i, names[i])
+				}
+			}
+
+			// Decoded packages match the eager loader
+			for _, p := range want.Packages {
+				got, err := m.Package(p.Name)
+				if err != nil {
+					t.Fatalf("unable to decode '%s': %v", p.Name, err)
+				}
+				if !proto.Equal(got, p) {
+					t.Fatalf("decoded package '%s' doesn't match", p.Name)
+				}
+			}
+
+			// Complete bundle is checked
+			b, err := m.Bundle()
+			if err != nil {
+				t.Fatalf("unable to decode bundle: %v", err)
+			}
+			if !proto.Equal(b, want) {
+				t.Fatal("decoded bundle doesn't match")
+			}
+
+			// Unknown package
+			if _, err := m.Package("app/production/customer1/billing/unknown"); !errors.Is(err, ErrMappedPackageNotFound) {
+				t.Fatalf("expected ErrMappedPackageNotFound, got %v", err)
+			}
+		})
+	}
+}
+
+func TestOpenMapped_LoadPolicies(t *testing.T) {
+	dropped := -1
+	m, err := OpenMapped(mustContainer(t, expiryContainer(t)),
+		WithMmap(),
+		WithSharedCacheDir(t.TempDir()),
+		WithLoadClock(expiryClock),
+		WithExpiredPackagePolicy(ExpiredPackageDrop),
+		WithDroppedPackageCount(&dropped),
+	)
+	if err != nil {
+		t.Fatalf("unable to open mapped bundle: %v", err)
+	}
+	defer m.Close()
+
+	// Expired package is dropped on decode
+	p, err := m.Package("app/production/customer1/billing/database")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p != nil {
+		t.Fatal("expected expired package to be dropped")
+	}
+	p, err = m.Package("app/production/customer1/billing/api")
+	if err != nil || p == nil {
+		t.Fatalf("expected valid package, got %v (%v)", p, err)
+	}
+
+	// Lazy decode doesn't update the counter
+	if dropped != -1 {
+		t.Fatalf("unexpected dropped count %d", dropped)
+	}
+	if _, err := m.Bundle(); err != nil {
+		t.Fatalf("unable to decode bundle: %v", err)
+	}
+	if dropped != 4 {
+		t.Fatalf("expected 4 dropped packages, got %d", dropped)
+	}
+}
+
+func TestOpenMapped_SharedCache(t *testing.T) {
+	dir := t.TempDir()
+	c := mustContainer(t, expiryContainer(t))
+	name := "app/production/customer1/billing/smtp"
+
+	open := func() *MappedBundle {
+		t.Helper()
+		m, err := OpenMapped(c, WithMmap(), WithSharedCacheDir(dir))
+		if err != nil {
+			t.Fatalf("unable to open mapped bundle: %v", err)
+		}
+		if p, err := m.Package(name); err != nil || p == nil {
+			t.Fatalf("unable to decode '%s': %v", name, err)
+		}
+		return m
+	}
+
+	// First consumer creates the cache file
+	first := open()
+	defer first.Close()
+	files := cacheFiles(t, dir)
+	if len(files) != 1 {
+		t.Fatalf("expected one cache file, got %v", files)
+	}
+	fi, err := os.Stat(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && fi.Mode().Perm() != 0o600 {
+		t.Fatalf("expected owner-only cache file, got %04o", fi.Mode().Perm())
+	}
+
+	// Second consumer reuses it
+	second := open()
+	defer second.Close()
+	reused, err := os.Stat(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(fi, reused) {
+		t.Fatal("expected cache file to be reused")
+	}
+
+	// Tampered cache is rebuilt
+	content, err := ioutil.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	content[len(content)-1] ^= 0xff
+	if err := ioutil.WriteFile(files[0], content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	third := open()
+	defer third.Close()
+	rebuilt, err := os.Stat(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if os.SameFile(fi, rebuilt) {
+		t.Fatal("expected tampered cache file to be replaced")
+	}
+
+	// Cache with loose permissions is rebuilt
+	if runtime.GOOS != "windows" {
+		if err := os.Chmod(files[0], 0o644); err != nil {
+			t.Fatal(err)
+		}
+		fourth := open()
+		defer fourth.Close()
+		fi, err := os.Stat(files[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode().Perm() != 0o600 {
+			t.Fatalf("expected owner-only cache file, got %04o", fi.Mode().Perm())
+		}
+	}
+}
+
+func TestOpenMapped_SharedCacheCleanup(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("cache file users are tracked with advisory locks")
+	}
+
+	dir := t.TempDir()
+	current := mustContainer(t, expiryContainer(t))
+	open := func(c *containerv1.Container) *MappedBundle {
+		t.Helper()
+		m, err := OpenMapped(c, WithMmap(), WithSharedCacheDir(dir))
+		if err != nil {
+			t.Fatalf("unable to open mapped bundle: %v", err)
+		}
+		return m
+	}
+
+	// Cache file is kept while used
+	first, second := open(current), open(current)
+	if err := first.Close(); err != nil {
+		t.Fatalf("unable to close mapped bundle: %v", err)
+	}
+	if files := cacheFiles(t, dir); len(files) != 1 {
+		t.Fatalf("expected cache file to be kept, got %v", files)
+	}
+
+	// Last user removes it
+	if err := second.Close(); err != nil {
+		t.Fatalf("unable to close mapped bundle: %v", err)
+	}
+	if err := second.Close(); err != nil {
+		t.Fatalf("unable to close mapped bundle twice: %v", err)
+	}
+	if files := cacheFiles(t, dir); len(files) != 0 {
+		t.Fatalf("expected cache file to be removed, got %v", files)
+	}
+
+	// Unused cache files are removed when a new one is created
+	used := open(current)
+	defer used.Close()
+	stale := filepath.Join(dir, "harp-stale.bundle")
+	if err := ioutil.WriteFile(stale, []byte("plaintext"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	next, err := FromMap(map[string]KV{"app/production/next": {"key": "value"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := ToContainerWriter(&buf, next); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := open(mustContainer(t, buf.Bytes()))
+	defer reloaded.Close()
+
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("expected stale cache file to be removed, got %v", err)
+	}
+	if files := cacheFiles(t, dir); len(files) != 2 {
+		t.Errorf("expected used cache files to be kept, got %v", files)
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// BenchmarkConsumers measures memory held by 10 simulated consumers loading
+// the same container. Consumers share a process here, so each mapping is
+// accounted in shared memory, separate processes share the same pages.
+func BenchmarkConsumers(b *testing.B) {
+	if runtime.GOOS != "linux" {
+		b.Skip("resident memory is measured from /proc")
+	}
+
+	// Prepare a large container
+	input := map[string]KV{}
+	value := strings.Repeat("x", 1024)
+	for i := 0; i < 4096; i++ {
+		input[fmt.Sprintf("app/production/customer%d/billing/database", i)] = KV{"password": value}
+	}
+	fixture, err := FromMap(input)
+	if err != nil {
+		b.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := ToContainerWriter(&buf, fixture); err != nil {
+		b.Fatal(err)
+	}
+	c := mustContainer(b, buf.Bytes())
+	dir, err := ioutil.TempDir("", "harp-bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const consumers = 10
+	run := func(b *testing.B, load func() (interface{}, func())) {
+		for i := 0; i < b.N; i++ {
+			runtime.GC()
+			heap, shared := residentMemory(b)
+
+			loaded := []interface{}{}
+			closers := []func(){}
+			for j := 0; j < consumers; j++ {
+				l, closer := load()
+				loaded = append(loaded, l)
+				closers = append(closers, closer)
+			}
+
+			runtime.GC()
+			heapAfter, sharedAfter := residentMemory(b)
+			b.ReportMetric(float64(heapAfter-heap), "heap-KiB")
+			b.ReportMetric(float64(sharedAfter-shared), "shared-KiB")
+
+			runtime.KeepAlive(loaded)
+			for _, closer := range closers {
+				closer()
+			}
+		}
+	}
+
+	b.Run("eager", func(b *testing.B) {
+		run(b, func() (interface{}, func()) {
+			out, err := FromContainer(c)
+			if err != nil {
+				b.Fatal(err)
+			}
+			return out, func() {}
+		})
+	})
+	b.Run("mmap", func(b *testing.B) {
+		run(b, func() (interface{}, func()) {
+			m, err := OpenMapped(c, WithMmap(), WithSharedCacheDir(dir))
+			if err != nil {
+				b.Fatal(err)
+			}
+			// Touch every package as a serving consumer would
+			for _, name := range m.Names() {
+				if _, err := m.Package(name); err != nil {
+					b.Fatal(err)
+				}
+			}
+			return m, func() { m.Close() }
+		})
+	})
+}
+
+// residentMemory returns in use heap and shared resident memory in KiB.
+func residentMemory(b *testing.B) (heap, shared int64) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	heap = int64(ms.HeapInuse / 1024)
+
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		v, _ := strconv.ParseInt(fields[1], 10, 64)
+		if fields[0] == "RssFile:" || fields[0] == "RssShmem:" {
+			shared += v
+		}
+	}
+
+	return heap, shared
+}
@@ -0,0 +1,167 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+)
+
+// TombstoneAnnotation marks an overlay package hiding the base package
+// using the same path.
+const TombstoneAnnotation = "harp.elastic.co/v1/overlay#tombstone"
+
+// ErrPackageNotFound is raised when the requested package doesn't exist.
+var ErrPackageNotFound = errors.New("bundle: package not found")
+
+// Layered exposes a read-only view of an overlay bundle applied to a base
+// bundle. Packages are not copied, the base bundle is shared.
+type Layered struct {
+	base     *bundlev1.Bundle
+	overlay  *bundlev1.Bundle
+	packages map[string]*bundlev1.Package
+}
+
+// Overlay combines the base and overlay bundles. Overlay packages win per
+// path, and tombstone packages hide the base package with the same path.
+func Overlay(base, overlay *bundlev1.Bundle) (*Layered, error) {
+	// Check arguments
+	if base == nil {
+		return nil, fmt.Errorf("unable to overlay a nil base bundle")
+	}
+	if overlay == nil {
+		return nil, fmt.Errorf("unable to overlay a nil overlay bundle")
+	}
+
+	packages := map[string]*bundlev1.Package{}
+
+	// Index base packages
+	for _, p := range base.Packages {
+		if p == nil {
+			continue
+		}
+		packages[p.Name] = p
+	}
+
+	// Apply overlay packages
+	for _, p := range overlay.Packages {
+		if p == nil {
+			continue
+		}
+		if IsTombstone(p) {
+			delete(packages, p.Name)
+			continue
+		}
+		packages[p.Name] = p
+	}
+
+	// No error
+	return &Layered{
+		base:     base,
+		overlay:  overlay,
+		packages: packages,
+	}, nil
+}
+
+// IsTombstone returns true if the given package is an overlay tombstone.
+func IsTombstone(p *bundlev1.Package) bool {
+	if p == nil {
+		return false
+	}
+	_, ok := p.Annotations[TombstoneAnnotation]
+	return ok
+}
+
+// Get returns the package matching the given path.
+func (l *Layered) Get(path string) (*bundlev1.Package, error) {
+	p, ok := l.packages[strings.TrimPrefix(path, "/")]
+	if !ok {
+		return nil, fmt.Errorf("unable to retrieve '%s': %w", path, ErrPackageNotFound)
+	}
+
+	// No error
+	return p, nil
+}
+
+// List returns the sorted children of the given path prefix from both
+// layers. Intermediate directories are suffixed by a '/'.
+func (l *Layered) List(prefix string) []string {
+	// Normalize prefix
+	prefix = strings.TrimPrefix(prefix, "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	children := map[string]struct{}{}
+	for name := range l.packages {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		// Keep only the direct child
+		child := strings.TrimPrefix(name, prefix)
+		if idx := strings.Index(child, "/"); idx >= 0 {
+			child = child[:idx+1]
+		}
+		children[child] = struct{}{}
+	}
+
+	res := make([]string, 0, len(children))
+	for child := range children {
+		res = append(res, child)
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+// Bundle materializes the layered view as a regular bundle. Base packages
+// order is preserved, new overlay packages are appended.
+func (l *Layered) Bundle() *bundlev1.Bundle {
+	res := &bundlev1.Bundle{
+		Labels:      l.base.Labels,
+		Annotations: l.base.Annotations,
+		Version:     l.base.Version,
+		Template:    l.base.Template,
+		Values:      l.base.Values,
+		Packages:    []*bundlev1.Package{},
+	}
+
+	// Add packages by layer order
+	seen := map[string]struct{}{}
+	for _, layer := range []*bundlev1.Bundle{l.base, l.overlay} {
+		for _, p := range layer.Packages {
+			if p == nil {
+				continue
+			}
+			if _, ok := seen[p.Name]; ok {
+				continue
+			}
+			if lp, ok := l.packages[p.Name]; ok {
+				res.Packages = append(res.Packages, lp)
+				seen[p.Name] = struct{}{}
+			}
+		}
+	}
+
+	return res
+}
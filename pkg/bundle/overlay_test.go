@@ -0,0 +1,167 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+)
+
+func overlayFixtures(t *testing.T) (base, overlay *bundlev1.Bundle) {
+	base = mustFromMap(t, map[string]KV{
+		"app/production/a/database": {"user": "base"},
+		"app/production/a/cache":    {"password": "base"},
+		"app/production/b/queue":    {"token": "base"},
+		"infra/aws/account/iam":     {"key": "base"},
+	})
+	overlay = mustFromMap(t, map[string]KV{
+		"app/production/a/database": {"user": "overlay"},
+		"app/production/b/queue":    {},
+		"app/production/c/search":   {"token": "overlay"},
+	})
+
+	// Mark tombstone
+	for _, p := range overlay.Packages {
+		if p.Name == "app/production/b/queue" {
+			Annotate(p, TombstoneAnnotation, "true")
+		}
+	}
+
+	return base, overlay
+}
+
+func Test_Overlay_Get(t *testing.T) {
+	base, overlay := overlayFixtures(t)
+
+	l, err := Overlay(base, overlay)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		desc    string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{desc: "shadowed by overlay", path: "app/production/a/database", want: "overlay"},
+		{desc: "base only", path: "app/production/a/cache", want: "base"},
+		{desc: "overlay only", path: "/app/production/c/search", want: "overlay"},
+		{desc: "tombstone", path: "app/production/b/queue", wantErr: true},
+		{desc: "unknown", path: "app/production/z", wantErr: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			p, err := l.Get(tC.path)
+			if tC.wantErr {
+				if !errors.Is(err, ErrPackageNotFound) {
+					t.Fatalf("expected not found error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			secrets, err := AsSecretMap(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, v := range secrets {
+				if v != tC.want {
+					t.Errorf("expected %q, got %q", tC.want, v)
+				}
+			}
+		})
+	}
+}
+
+func Test_Overlay_List(t *testing.T) {
+	base, overlay := overlayFixtures(t)
+
+	l, err := Overlay(base, overlay)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		desc   string
+		prefix string
+		want   []string
+	}{
+		{desc: "root", prefix: "", want: []string{"app/", "infra/"}},
+		{desc: "merged children", prefix: "app/production", want: []string{"a/", "c/"}},
+		{desc: "leaves", prefix: "/app/production/a/", want: []string{"cache", "database"}},
+		{desc: "hidden by tombstone", prefix: "app/production/b", want: []string{}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			got := l.List(tC.prefix)
+			if diff := cmp.Diff(got, tC.want); diff != "" {
+				t.Errorf("%q. List():\n-got/+want\ndiff %s", tC.desc, diff)
+			}
+		})
+	}
+}
+
+func Test_Overlay_Bundle(t *testing.T) {
+	base, overlay := overlayFixtures(t)
+
+	l, err := Overlay(base, overlay)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	paths, err := Paths(l.Bundle())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]bool{
+		"app/production/a/database": true,
+		"app/production/a/cache":    true,
+		"app/production/c/search":   true,
+		"infra/aws/account/iam":     true,
+	}
+	if len(paths) != len(want) {
+		t.Fatalf("unexpected package count, got %v", paths)
+	}
+	for _, p := range paths {
+		if !want[p] {
+			t.Errorf("unexpected package %q", p)
+		}
+	}
+
+	// Base must not be modified
+	if len(base.Packages) != 4 {
+		t.Errorf("base bundle must not be modified")
+	}
+}
+
+func Test_Overlay_Nil(t *testing.T) {
+	if _, err := Overlay(nil, &bundlev1.Bundle{}); err == nil {
+		t.Error("error expected for nil base")
+	}
+	if _, err := Overlay(&bundlev1.Bundle{}, nil); err == nil {
+		t.Error("error expected for nil overlay")
+	}
+}
@@ -55,6 +55,11 @@ func executeRule(patchName string, r *bundlev1.PatchRule, b *bundlev1.Bundle, va
 	for _, p := range b.Packages {
 		// Package match selector specification
 		if s.IsSatisfiedBy(p) {
+			// Archived packages are immutable
+			if err := bundle.CheckMutable(p); err != nil {
+				return err
+			}
+
 			// Apply patch
 			if err := applyPackagePatch(p, r.Package, values); err != nil {
 				return fmt.Errorf("unable to apply patch to package `%s`: %w", p.Name, err)
@@ -0,0 +1,307 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+)
+
+// DefaultPathDelimiter is the package path segment delimiter.
+const DefaultPathDelimiter = "/"
+
+// PackageTree is a hierarchical view of bundle packages. Nodes are package
+// path segments, a package is attached to the node of its path so that
+// internal nodes can hold a package too.
+//
+// Subtree operations update the underlying bundle and keep the tree
+// consistent with it.
+type PackageTree struct {
+	bundle    *bundlev1.Bundle
+	delimiter string
+	root      *TreeNode
+}
+
+// TreeNode is a package tree node.
+type TreeNode struct {
+	// Name is the path segment of the node, blank for the root node.
+	Name string
+	// Path is the full path of the node, blank for the root node.
+	Path string
+	// Package is the package stored at the node path, if any.
+	Package *bundlev1.Package
+	// Children are sorted by name.
+	Children []*TreeNode
+
+	tree   *PackageTree
+	parent *TreeNode
+	index  map[string]*TreeNode
+}
+
+// PackageTreeOption defines the functional pattern for package tree settings.
+type PackageTreeOption func(*PackageTree)
+
+// WithPathDelimiter sets the path segment delimiter, defaults to '/'.
+func WithPathDelimiter(delimiter string) PackageTreeOption {
+	return func(t *PackageTree) {
+		t.delimiter = delimiter
+	}
+}
+
+// NewPackageTree builds the package tree of the given bundle.
+func NewPackageTree(b *bundlev1.Bundle, opts ...PackageTreeOption) (*PackageTree, error) {
+	// Check arguments
+	if b == nil {
+		return nil, fmt.Errorf("unable to build the package tree of a nil bundle")
+	}
+
+	// Apply options
+	t := &PackageTree{
+		bundle:    b,
+		delimiter: DefaultPathDelimiter,
+	}
+	for _, o := range opts {
+		o(t)
+	}
+	if t.delimiter == "" {
+		return nil, fmt.Errorf("package tree delimiter must not be blank")
+	}
+	t.root = &TreeNode{tree: t}
+
+	// Attach packages
+	for _, p := range b.Packages {
+		if p == nil {
+			continue
+		}
+
+		n := t.root
+		for _, segment := range t.split(p.Name) {
+			n = n.child(segment)
+		}
+		if n == t.root {
+			return nil, fmt.Errorf("unable to attach package with a blank name")
+		}
+		if n.Package != nil {
+			return nil, fmt.Errorf("duplicate package '%s'", p.Name)
+		}
+		n.Package = p
+	}
+
+	// Sort children once all nodes are attached
+	if err := t.root.Walk(func(n *TreeNode) error {
+		sort.Slice(n.Children, func(i, j int) bool {
+			return n.Children[i].Name < n.Children[j].Name
+		})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	// No error
+	return t, nil
+}
+
+// Root returns the root node.
+func (t *PackageTree) Root() *TreeNode {
+	return t.root
+}
+
+// Find returns the node of the given path, or nil. A blank path returns the
+// root node.
+func (t *PackageTree) Find(path string) *TreeNode {
+	n := t.root
+	for _, segment := range t.split(path) {
+		next, ok := n.index[segment]
+		if !ok {
+			return nil
+		}
+		n = next
+	}
+
+	return n
+}
+
+// -----------------------------------------------------------------------------
+
+// IsLeaf returns true if the node has no children.
+func (n *TreeNode) IsLeaf() bool {
+	return len(n.Children) == 0
+}
+
+// Walk visits the subtree nodes depth first, in path order. Walking stops at
+// the first error.
+func (n *TreeNode) Walk(fn func(*TreeNode) error) error {
+	if err := fn(n); err != nil {
+		return err
+	}
+	for _, c := range n.Children {
+		if err := c.Walk(fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Packages returns the subtree packages in path order.
+func (n *TreeNode) Packages() []*bundlev1.Package {
+	res := []*bundlev1.Package{}
+	// Walk callback never fails
+	_ = n.Walk(func(c *TreeNode) error {
+		if c.Package != nil {
+			res = append(res, c.Package)
+		}
+		return nil
+	})
+
+	return res
+}
+
+// Count returns the subtree package count.
+func (n *TreeNode) Count() int {
+	return len(n.Packages())
+}
+
+// TotalSize returns the packed (protobuf encoded) size of subtree packages.
+func (n *TreeNode) TotalSize() int64 {
+	size := int64(0)
+	for _, p := range n.Packages() {
+		size += int64(proto.Size(p))
+	}
+
+	return size
+}
+
+// Annotate all subtree packages, existing annotations are kept.
+func (n *TreeNode) Annotate(key, value string) {
+	for _, p := range n.Packages() {
+		Annotate(p, key, value)
+	}
+}
+
+// Extract returns a new bundle holding a copy of subtree packages. The bundle
+// metadata is copied from the tree bundle.
+func (n *TreeNode) Extract() (*bundlev1.Bundle, error) {
+	src := n.tree.bundle
+	out := &bundlev1.Bundle{
+		Version:     src.Version,
+		Labels:      map[string]string{},
+		Annotations: map[string]string{},
+		Packages:    []*bundlev1.Package{},
+	}
+	for k, v := range src.Labels {
+		out.Labels[k] = v
+	}
+	for k, v := range src.Annotations {
+		out.Annotations[k] = v
+	}
+
+	for _, p := range n.Packages() {
+		cp, ok := proto.Clone(p).(*bundlev1.Package)
+		if !ok {
+			return nil, fmt.Errorf("unable to copy package '%s'", p.Name)
+		}
+		out.Packages = append(out.Packages, cp)
+	}
+
+	// No error
+	return out, nil
+}
+
+// Delete removes subtree packages from the bundle and the tree, and returns
+// the removed package count. Deleting the root node removes all packages.
+func (n *TreeNode) Delete() int {
+	removed := map[*bundlev1.Package]struct{}{}
+	for _, p := range n.Packages() {
+		removed[p] = struct{}{}
+	}
+	if len(removed) == 0 {
+		return 0
+	}
+
+	// Update the bundle
+	b := n.tree.bundle
+	kept := []*bundlev1.Package{}
+	for _, p := range b.Packages {
+		if _, ok := removed[p]; !ok {
+			kept = append(kept, p)
+		}
+	}
+	b.Packages = kept
+
+	// Detach the subtree and prune empty ancestors
+	n.Package = nil
+	n.Children = nil
+	n.index = nil
+	for c := n; c.parent != nil && c.Package == nil && c.IsLeaf(); c = c.parent {
+		c.parent.remove(c)
+	}
+
+	return len(removed)
+}
+
+// -----------------------------------------------------------------------------
+
+func (t *PackageTree) split(path string) []string {
+	res := []string{}
+	for _, segment := range strings.Split(path, t.delimiter) {
+		if segment != "" {
+			res = append(res, segment)
+		}
+	}
+	return res
+}
+
+func (n *TreeNode) child(name string) *TreeNode {
+	if c, ok := n.index[name]; ok {
+		return c
+	}
+	if n.index == nil {
+		n.index = map[string]*TreeNode{}
+	}
+
+	path := name
+	if n.parent != nil {
+		path = n.Path + n.tree.delimiter + name
+	}
+	c := &TreeNode{
+		Name:   name,
+		Path:   path,
+		tree:   n.tree,
+		parent: n,
+	}
+	n.Children = append(n.Children, c)
+	n.index[name] = c
+
+	return c
+}
+
+func (n *TreeNode) remove(child *TreeNode) {
+	delete(n.index, child.Name)
+	for i, c := range n.Children {
+		if c == child {
+			n.Children = append(n.Children[:i], n.Children[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,224 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+)
+
+func treeFixture(t *testing.T) *bundlev1.Bundle {
+	t.Helper()
+
+	b := mustFromMap(t, map[string]KV{
+		"app/production/billing":                 {"owner": "billing"},
+		"app/production/billing/payments/api":    {"password": "foo"},
+		"app/production/billing/payments/stripe": {"api_key": "sk_live"},
+		"app/production/billing/invoices":        {"token": "bar"},
+		"app/production/billing-legacy/api":      {"password": "old"},
+		"app/staging/billing/payments/api":       {"password": "baz"},
+		"/infra/production//monitoring/agent/":   {"token": "m0n1t0r"},
+	})
+	b.Labels = map[string]string{"env": "all"}
+	return b
+}
+
+func packageNames(packages []*bundlev1.Package) string {
+	names := []string{}
+	for _, p := range packages {
+		names = append(names, p.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestPackageTree(t *testing.T) {
+	tree, err := NewPackageTree(treeFixture(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Package at an internal node
+	n := tree.Find("/app/production/billing/")
+	if n == nil {
+		t.Fatal("expected node to be found")
+	}
+	if n.Package == nil || n.Package.Name != "app/production/billing" || n.IsLeaf() {
+		t.Fatalf("expected an internal node holding a package, got %+v", n)
+	}
+	if n.Name != "billing" || n.Path != "app/production/billing" {
+		t.Fatalf("unexpected node identity %s (%s)", n.Name, n.Path)
+	}
+
+	// Subtree doesn't cross segment boundaries
+	if got := packageNames(n.Packages()); got != "app/production/billing,app/production/billing/invoices,app/production/billing/payments/api,app/production/billing/payments/stripe" {
+		t.Fatalf("unexpected subtree packages %s", got)
+	}
+	if n.Count() != 4 {
+		t.Fatalf("expected 4 packages, got %d", n.Count())
+	}
+	size := int64(0)
+	for _, p := range n.Packages() {
+		size += int64(proto.Size(p))
+	}
+	if n.TotalSize() != size || size == 0 {
+		t.Fatalf("expected %d bytes, got %d", size, n.TotalSize())
+	}
+
+	// Blank segments are ignored
+	if n := tree.Find("infra/production/monitoring/agent"); n == nil || n.Package == nil {
+		t.Fatal("expected normalized package path to be found")
+	}
+
+	// Unknown and partial paths
+	if tree.Find("app/production/bill") != nil || tree.Find("app/qa") != nil {
+		t.Fatal("unexpected node")
+	}
+	if tree.Find("") != tree.Root() || tree.Root().Count() != 7 {
+		t.Fatal("blank path must return the root node")
+	}
+
+	// Children are sorted
+	root := tree.Root()
+	if len(root.Children) != 2 || root.Children[0].Name != "app" || root.Children[1].Name != "infra" {
+		t.Fatalf("unexpected root children %v", root.Children)
+	}
+}
+
+func TestPackageTree_Extract(t *testing.T) {
+	b := treeFixture(t)
+	tree, err := NewPackageTree(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, prefix := range []string{"app/production/billing", "app/production", "app/staging/billing/payments/api", "app"} {
+		out, err := tree.Find(prefix).Extract()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// Equivalent to a prefix filter
+		want := []*bundlev1.Package{}
+		for _, p := range b.Packages {
+			if p.Name == prefix || strings.HasPrefix(p.Name, prefix+"/") {
+				want = append(want, p)
+			}
+		}
+		// Tree order is hierarchical, compare in name order
+		SortPackages(want)
+		SortPackages(out.Packages)
+		if len(out.Packages) != len(want) {
+			t.Fatalf("%s: expected %d packages, got %d", prefix, len(want), len(out.Packages))
+		}
+		for i := range want {
+			if !proto.Equal(out.Packages[i], want[i]) {
+				t.Fatalf("%s: package #%d doesn't match", prefix, i)
+			}
+			if out.Packages[i] == want[i] {
+				t.Fatalf("%s: extracted packages must be copies", prefix)
+			}
+		}
+		if out.Labels["env"] != "all" {
+			t.Fatalf("%s: expected bundle metadata to be copied", prefix)
+		}
+	}
+}
+
+func TestPackageTree_Annotate(t *testing.T) {
+	b := treeFixture(t)
+	tree, err := NewPackageTree(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tree.Find("app/production/billing/payments").Annotate("owner", "payments")
+	for _, p := range b.Packages {
+		_, ok := p.Annotations["owner"]
+		if ok != strings.HasPrefix(p.Name, "app/production/billing/payments/") {
+			t.Fatalf("unexpected annotation state for %s", p.Name)
+		}
+	}
+}
+
+func TestPackageTree_Delete(t *testing.T) {
+	b := treeFixture(t)
+	tree, err := NewPackageTree(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Delete a subtree holding a package at its root
+	if removed := tree.Find("app/production/billing").Delete(); removed != 4 {
+		t.Fatalf("expected 4 removed packages, got %d", removed)
+	}
+	if len(b.Packages) != 3 {
+		t.Fatalf("expected 3 remaining packages, got %d", len(b.Packages))
+	}
+	if tree.Find("app/production/billing") != nil {
+		t.Fatal("deleted node must be detached")
+	}
+	if tree.Find("app/production/billing-legacy/api") == nil {
+		t.Fatal("sibling subtree must be kept")
+	}
+
+	// Empty ancestors are pruned
+	tree.Find("infra/production/monitoring/agent").Delete()
+	if tree.Find("infra") != nil {
+		t.Fatal("empty ancestors must be pruned")
+	}
+	if tree.Root().Count() != len(b.Packages) {
+		t.Fatalf("tree and bundle are inconsistent")
+	}
+
+	// Delete everything
+	tree.Root().Delete()
+	if len(b.Packages) != 0 || !tree.Root().IsLeaf() {
+		t.Fatal("expected all packages to be removed")
+	}
+}
+
+func TestPackageTree_Errors(t *testing.T) {
+	if _, err := NewPackageTree(nil); err == nil {
+		t.Fatal("expected error with a nil bundle")
+	}
+	if _, err := NewPackageTree(&bundlev1.Bundle{}, WithPathDelimiter("")); err == nil {
+		t.Fatal("expected error with a blank delimiter")
+	}
+	b := &bundlev1.Bundle{Packages: []*bundlev1.Package{{Name: "app/a"}, {Name: "app//a/"}}}
+	if _, err := NewPackageTree(b); err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Fatalf("expected duplicate package error, got %v", err)
+	}
+	b = &bundlev1.Bundle{Packages: []*bundlev1.Package{{Name: "//"}}}
+	if _, err := NewPackageTree(b); err == nil {
+		t.Fatal("expected error with a blank package name")
+	}
+
+	// Custom delimiter
+	b = &bundlev1.Bundle{Packages: []*bundlev1.Package{{Name: "app.production.db"}, {Name: "app.staging.db"}}}
+	tree, err := NewPackageTree(b, WithPathDelimiter("."))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := tree.Find("app.production"); n == nil || n.Count() != 1 || n.Children[0].Path != "app.production.db" {
+		t.Fatalf("unexpected node %+v", n)
+	}
+}
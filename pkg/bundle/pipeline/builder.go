@@ -17,6 +17,8 @@
 
 package pipeline
 
+import "go.uber.org/zap"
+
 // Options defines default options.
 type Options struct {
 	disableOutput bool
@@ -24,6 +26,7 @@ type Options struct {
 	ppf           PackageProcessorFunc
 	cpf           ChainProcessorFunc
 	kpf           KVProcessorFunc
+	logger        *zap.Logger
 }
 
 // Option represents option function
@@ -63,3 +66,11 @@ func KVProcessor(f KVProcessorFunc) Option {
 		opts.kpf = f
 	}
 }
+
+// Logger assign the logger used by processors instead of the process default
+// logger.
+func Logger(l *zap.Logger) Option {
+	return func(opts *Options) {
+		opts.logger = l
+	}
+}
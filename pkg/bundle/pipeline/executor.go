@@ -32,20 +32,37 @@ import (
 
 // Run a processor.
 func Run(ctx context.Context, name string, opts ...Option) error {
+	const (
+		defaultDisableOutput = false
+	)
+
 	// Initialize a running context to attach all goroutines
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	// Apply options
+	options := &Options{
+		disableOutput: defaultDisableOutput,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
 	// Initialize logger
-	log.Setup(ctx,
-		&log.Options{
-			Debug:    true,
-			AppName:  slug.Make(name),
-			AppID:    version.ID(),
-			Version:  version.Version,
-			Revision: version.Revision,
-		},
-	)
+	if options.logger != nil {
+		ctx = log.WithLogger(ctx, options.logger)
+	} else if _, ok := log.FromContext(ctx); !ok {
+		// Standalone processor process
+		log.Setup(ctx,
+			&log.Options{
+				Debug:    true,
+				AppName:  slug.Make(name),
+				AppID:    version.ID(),
+				Version:  version.Version,
+				Revision: version.Revision,
+			},
+		)
+	}
 
 	// Read bundle from Stdin
 	b, err := bundle.FromContainerReader(os.Stdin)
@@ -53,23 +70,12 @@ func Run(ctx context.Context, name string, opts ...Option) error {
 		return fmt.Errorf("unable to read bundle from stdin: %w", err)
 	}
 
-	const (
-		defaultDisableOutput = false
-	)
-
 	v := &bundleVisitor{
-		ctx: ctx,
-		opts: &Options{
-			disableOutput: defaultDisableOutput,
-		},
+		ctx:      ctx,
+		opts:     options,
 		position: &defaultContext{},
 	}
 
-	// Loop through each option
-	for _, opt := range opts {
-		opt(v.opts)
-	}
-
 	// Apply remapping strategy
 	v.VisitForFile(b)
 
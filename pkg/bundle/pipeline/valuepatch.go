@@ -0,0 +1,86 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+)
+
+// ValuePatchRule describes a JSON patch applied to matching secret values.
+type ValuePatchRule struct {
+	// Path is the package path glob pattern (path.Match syntax).
+	Path string
+	// Field is the secret key to patch.
+	Field string
+	// JSONPatch holds a JSON Patch (RFC 6902) document.
+	JSONPatch []byte
+	// MergePatch holds a JSON Merge Patch (RFC 7386) document.
+	MergePatch []byte
+}
+
+// ValuePatchProcessor returns a KV processor applying the given rules in
+// order to matching secret values.
+func ValuePatchProcessor(rules ...ValuePatchRule) (KVProcessorFunc, error) {
+	// Check rules
+	for i, r := range rules {
+		if r.Field == "" {
+			return nil, fmt.Errorf("rule %d: secret field must be specified", i)
+		}
+		if (len(r.JSONPatch) == 0) == (len(r.MergePatch) == 0) {
+			return nil, fmt.Errorf("rule %d: exactly one of json patch or merge patch must be specified", i)
+		}
+		if _, err := path.Match(strings.TrimPrefix(r.Path, "/"), ""); err != nil {
+			return nil, fmt.Errorf("rule %d: invalid path pattern '%s': %w", i, r.Path, err)
+		}
+	}
+
+	return func(ctx Context, kv *bundlev1.KV) error {
+		p := ctx.GetPackage()
+		if p == nil || kv == nil {
+			return nil
+		}
+
+		for _, r := range rules {
+			if r.Field != kv.Key {
+				continue
+			}
+			if matched, _ := path.Match(strings.TrimPrefix(r.Path, "/"), p.Name); !matched {
+				continue
+			}
+			if err := bundle.CheckMutable(p); err != nil {
+				return err
+			}
+
+			fn := bundle.MergePatch(r.MergePatch)
+			if len(r.JSONPatch) > 0 {
+				fn = bundle.JSONPatch(r.JSONPatch)
+			}
+			if err := bundle.PatchKV(kv, fn); err != nil {
+				return fmt.Errorf("unable to patch '%s' of '%s': %w", kv.Key, p.Name, err)
+			}
+		}
+
+		// No error
+		return nil
+	}, nil
+}
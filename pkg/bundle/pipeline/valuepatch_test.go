@@ -0,0 +1,78 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"testing"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle/secret"
+)
+
+func TestValuePatchProcessor(t *testing.T) {
+	// Invalid rules
+	if _, err := ValuePatchProcessor(ValuePatchRule{Path: "app/*", Field: "config"}); err == nil {
+		t.Fatal("error should be raised without patch")
+	}
+	if _, err := ValuePatchProcessor(ValuePatchRule{Path: "[", Field: "config", MergePatch: []byte(`{}`)}); err == nil {
+		t.Fatal("error should be raised for invalid pattern")
+	}
+
+	fn, err := ValuePatchProcessor(
+		ValuePatchRule{Path: "app/production/*", Field: "config", JSONPatch: []byte(`[{"op":"add","path":"/replicas","value":3}]`)},
+		ValuePatchRule{Path: "app/*/api", Field: "config", MergePatch: []byte(`{"debug":null}`)},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		name string
+		path string
+		key  string
+		want string
+	}{
+		{name: "both rules", path: "app/production/api", key: "config", want: `{"replicas":3}`},
+		{name: "merge rule only", path: "app/staging/api", key: "config", want: `{}`},
+		{name: "other field", path: "app/production/api", key: "password", want: `{"debug":true}`},
+		{name: "no match", path: "infra/production/db", key: "config", want: `{"debug":true}`},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			packed, err := secret.Pack(`{"debug":true}`)
+			if err != nil {
+				t.Fatalf("unable to pack value: %v", err)
+			}
+			kv := &bundlev1.KV{Key: tc.key, Value: packed}
+			ctx := &defaultContext{Package: &bundlev1.Package{Name: tc.path}, KV: kv}
+
+			if err := fn(ctx, kv); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got string
+			if err := secret.Unpack(kv.Value, &got); err != nil {
+				t.Fatalf("unable to unpack value: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+)
+
+// QuarantineAnnotation marks a quarantined package. The value is the
+// quarantine reason. Quarantined packages are kept in the container but must
+// not be distributed.
+const QuarantineAnnotation = "harp.elastic.co/v1/package#quarantine"
+
+// QuarantineHistoryKey is the reserved history key used to record quarantine
+// and release operations of a package.
+const QuarantineHistoryKey = "@quarantine"
+
+var (
+	// ErrPackageQuarantined is raised when trying to distribute a quarantined
+	// package.
+	ErrPackageQuarantined = errors.New("bundle: package is quarantined")
+	// ErrPackageNotQuarantined is raised when trying to release a package not
+	// in quarantine.
+	ErrPackageNotQuarantined = errors.New("bundle: package is not quarantined")
+)
+
+// QuarantineReason returns the quarantine reason of the given package and
+// true if the package is quarantined.
+func QuarantineReason(p *bundlev1.Package) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	reason, ok := p.Annotations[QuarantineAnnotation]
+	return reason, ok
+}
+
+// IsQuarantined returns true if the given package is quarantined.
+func IsQuarantined(p *bundlev1.Package) bool {
+	_, ok := QuarantineReason(p)
+	return ok
+}
+
+// Quarantine marks the package matching the given path as quarantined and
+// records the operation in the package history.
+func Quarantine(b *bundlev1.Bundle, path, reason string, c Change) error {
+	// Check arguments
+	if strings.TrimSpace(reason) == "" {
+		return fmt.Errorf("quarantine reason must not be blank")
+	}
+
+	p, err := lookup(b, path)
+	if err != nil {
+		return err
+	}
+	if IsQuarantined(p) {
+		return fmt.Errorf("unable to quarantine '%s': %w", path, ErrPackageQuarantined)
+	}
+
+	Annotate(p, QuarantineAnnotation, reason)
+
+	// Record operation
+	c.Operation = "quarantine"
+	return RecordChange(p, QuarantineHistoryKey, nil, c)
+}
+
+// Release clears the quarantine of the package matching the given path and
+// records the operation in the package history.
+func Release(b *bundlev1.Bundle, path string, c Change) error {
+	p, err := lookup(b, path)
+	if err != nil {
+		return err
+	}
+	if !IsQuarantined(p) {
+		return fmt.Errorf("unable to release '%s': %w", path, ErrPackageNotQuarantined)
+	}
+
+	delete(p.Annotations, QuarantineAnnotation)
+
+	// Record operation
+	c.Operation = "release"
+	return RecordChange(p, QuarantineHistoryKey, nil, c)
+}
+
+// WithoutQuarantined returns a bundle view without quarantined packages.
+// Packages are shared with the given bundle.
+func WithoutQuarantined(b *bundlev1.Bundle) *bundlev1.Bundle {
+	if b == nil {
+		return nil
+	}
+
+	res := &bundlev1.Bundle{
+		Labels:      b.Labels,
+		Annotations: b.Annotations,
+		Version:     b.Version,
+		Template:    b.Template,
+		Values:      b.Values,
+		Packages:    []*bundlev1.Package{},
+	}
+	for _, p := range b.Packages {
+		if IsQuarantined(p) {
+			continue
+		}
+		res.Packages = append(res.Packages, p)
+	}
+
+	return res
+}
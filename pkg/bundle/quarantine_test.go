@@ -0,0 +1,92 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQuarantine(t *testing.T) {
+	b := archiveFixture()
+	path := "app/production/security/harp/v1.0.0/server/legacy"
+	c := Change{
+		Actor: "alice@host",
+		Time:  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	// Blank reason
+	if err := Quarantine(b, path, " ", c); err == nil {
+		t.Fatal("error should be raised for blank reason")
+	}
+
+	// Quarantine
+	if err := Quarantine(b, "/"+path, "leaked in CI logs", c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reason, ok := QuarantineReason(b.Packages[1]); !ok || reason != "leaked in CI logs" {
+		t.Errorf("unexpected quarantine state %v %q", ok, reason)
+	}
+
+	// Quarantined package is hidden, container still holds it
+	view := WithoutQuarantined(b)
+	if len(view.Packages) != 1 || IsQuarantined(view.Packages[0]) {
+		t.Errorf("quarantined package must be hidden, got %v", view.Packages)
+	}
+	if len(b.Packages) != 2 {
+		t.Errorf("quarantined package must be kept")
+	}
+
+	// Quarantine twice
+	if err := Quarantine(b, path, "again", c); !errors.Is(err, ErrPackageQuarantined) {
+		t.Errorf("expected ErrPackageQuarantined, got %v", err)
+	}
+
+	// Release
+	c.Actor = "bob@host"
+	if err := Release(b, path, c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if IsQuarantined(b.Packages[1]) {
+		t.Error("package must be released")
+	}
+	if err := Release(b, path, c); !errors.Is(err, ErrPackageNotQuarantined) {
+		t.Errorf("expected ErrPackageNotQuarantined, got %v", err)
+	}
+
+	// Operations are recorded
+	entries, err := History(b.Packages[1], QuarantineHistoryKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 history entries, got %d", len(entries))
+	}
+	if entries[0].Operation != "quarantine" || entries[0].Actor != "alice@host" {
+		t.Errorf("unexpected quarantine entry %+v", entries[0])
+	}
+	if entries[1].Operation != "release" || entries[1].Actor != "bob@host" {
+		t.Errorf("unexpected release entry %+v", entries[1])
+	}
+
+	// Unknown package
+	if err := Quarantine(b, "app/missing", "reason", c); !errors.Is(err, ErrPackageNotFound) {
+		t.Errorf("expected ErrPackageNotFound, got %v", err)
+	}
+}
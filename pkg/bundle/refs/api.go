@@ -0,0 +1,61 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package refs validates that secret path references embedded in a bundle
+// point to existing packages.
+package refs
+
+import (
+	"errors"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+)
+
+var (
+	// ErrExtractorAlreadyRegistered is raised when trying to register an
+	// extractor with an existing name.
+	ErrExtractorAlreadyRegistered = errors.New("refs: extractor already registered")
+	// ErrExtractorNotFound is raised when the requested extractor is not
+	// registered.
+	ErrExtractorNotFound = errors.New("refs: extractor not found")
+)
+
+// Reference describes a path reference found in a package.
+type Reference struct {
+	Extractor string `json:"extractor"`
+	Source    string `json:"source"`
+	Location  string `json:"location"`
+	Target    string `json:"target"`
+}
+
+// Extractor describes path reference extractor contract.
+type Extractor interface {
+	Name() string
+	Extract(p *bundlev1.Package, secrets bundle.KV) ([]Reference, error)
+}
+
+// Report describes referential integrity check results.
+type Report struct {
+	Checked  int         `json:"checked"`
+	Dangling []Reference `json:"dangling"`
+}
+
+// HasDangling returns true if at least one reference is dangling.
+func (r *Report) HasDangling() bool {
+	return len(r.Dangling) > 0
+}
@@ -0,0 +1,86 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package refs
+
+import (
+	"fmt"
+	"strings"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+)
+
+// Check extracts path references from the given bundle and verifies that
+// each target exists in the bundle or in one of the companion bundles.
+// Archived packages are neither scanned nor valid targets.
+func Check(b *bundlev1.Bundle, companions []*bundlev1.Bundle, extractors []Extractor) (*Report, error) {
+	// Check arguments
+	if b == nil {
+		return nil, fmt.Errorf("unable to check nil bundle")
+	}
+
+	// Index existing paths
+	index := map[string]struct{}{}
+	for _, layer := range append([]*bundlev1.Bundle{b}, companions...) {
+		if layer == nil {
+			continue
+		}
+		for _, p := range bundle.WithoutArchived(layer).Packages {
+			index[clean(p.Name)] = struct{}{}
+		}
+	}
+
+	report := &Report{
+		Dangling: []Reference{},
+	}
+
+	for _, p := range bundle.WithoutArchived(b).Packages {
+		// Locked packages only expose annotations
+		secrets := bundle.KV{}
+		if p.Secrets != nil && p.Secrets.Locked == nil {
+			var err error
+			secrets, err = bundle.AsSecretMap(p)
+			if err != nil {
+				return nil, fmt.Errorf("unable to unpack package '%s': %w", p.Name, err)
+			}
+		}
+
+		for _, e := range extractors {
+			refs, err := e.Extract(p, secrets)
+			if err != nil {
+				return nil, fmt.Errorf("unable to extract references with '%s' from package '%s': %w", e.Name(), p.Name, err)
+			}
+
+			for _, ref := range refs {
+				report.Checked++
+				if _, ok := index[clean(ref.Target)]; !ok {
+					report.Dangling = append(report.Dangling, ref)
+				}
+			}
+		}
+	}
+
+	// No error
+	return report, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func clean(path string) string {
+	return strings.Trim(strings.TrimSpace(path), "/")
+}
@@ -0,0 +1,144 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package refs
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+)
+
+const (
+	// AliasAnnotation declares the package as an alias of the given path.
+	AliasAnnotation = "harp.elastic.co/v1/package#alias"
+	// ACLAnnotation lists comma separated meta package paths holding the
+	// package access control rules.
+	ACLAnnotation = "harp.elastic.co/v1/package#acl"
+)
+
+func init() {
+	MustRegister(&secretTemplateExtractor{})
+	MustRegister(&annotationExtractor{name: "alias", key: AliasAnnotation})
+	MustRegister(&annotationExtractor{name: "acl", key: ACLAnnotation, separator: ","})
+}
+
+// -----------------------------------------------------------------------------
+
+// secretRefRegexp matches the `{{secret:<path>}}` convention. An optional
+// `#<key>` suffix designates a secret key and is ignored.
+var secretRefRegexp = regexp.MustCompile(`\{\{\s*secret:([^}#\s]+)(?:#[^}\s]*)?\s*\}\}`)
+
+type secretTemplateExtractor struct{}
+
+func (e *secretTemplateExtractor) Name() string { return "secret-template" }
+
+func (e *secretTemplateExtractor) Extract(p *bundlev1.Package, secrets bundle.KV) ([]Reference, error) {
+	refs := []Reference{}
+
+	// Scan annotations
+	for _, key := range sortedKeys(p.Annotations) {
+		refs = append(refs, e.scan(p.Name, fmt.Sprintf("annotation:%s", key), p.Annotations[key])...)
+	}
+
+	// Scan secret values
+	keys := make([]string, 0, len(secrets))
+	for k := range secrets {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var value string
+		switch v := secrets[k].(type) {
+		case string:
+			value = v
+		case []byte:
+			value = string(v)
+		default:
+			continue
+		}
+		refs = append(refs, e.scan(p.Name, fmt.Sprintf("secret:%s", k), value)...)
+	}
+
+	return refs, nil
+}
+
+func (e *secretTemplateExtractor) scan(source, location, value string) []Reference {
+	refs := []Reference{}
+	for _, m := range secretRefRegexp.FindAllStringSubmatch(value, -1) {
+		refs = append(refs, Reference{
+			Extractor: e.Name(),
+			Source:    source,
+			Location:  location,
+			Target:    m[1],
+		})
+	}
+	return refs
+}
+
+// -----------------------------------------------------------------------------
+
+type annotationExtractor struct {
+	name      string
+	key       string
+	separator string
+}
+
+func (e *annotationExtractor) Name() string { return e.name }
+
+func (e *annotationExtractor) Extract(p *bundlev1.Package, _ bundle.KV) ([]Reference, error) {
+	value, ok := p.Annotations[e.key]
+	if !ok {
+		return nil, nil
+	}
+
+	targets := []string{value}
+	if e.separator != "" {
+		targets = strings.Split(value, e.separator)
+	}
+
+	refs := []Reference{}
+	for _, target := range targets {
+		target = strings.TrimSpace(target)
+		if target == "" {
+			continue
+		}
+		refs = append(refs, Reference{
+			Extractor: e.name,
+			Source:    p.Name,
+			Location:  fmt.Sprintf("annotation:%s", e.key),
+			Target:    target,
+		})
+	}
+
+	return refs, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
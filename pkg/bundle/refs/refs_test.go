@@ -0,0 +1,230 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package refs
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+)
+
+const (
+	dbPath   = "app/production/security/harp/v1.0.0/server/database"
+	webPath  = "app/production/security/harp/v1.0.0/server/web"
+	aclPath  = "meta/production/security/harp/v1.0.0/server/acl"
+	infraDB  = "infra/aws/security/eu-central-1/rds/adminconsole"
+	missing1 = "app/production/security/harp/v1.0.0/server/missing"
+)
+
+func mustSelect(t *testing.T, enabled ...string) []Extractor {
+	t.Helper()
+	e, err := Select(enabled, nil)
+	if err != nil {
+		t.Fatalf("unable to select extractors: %v", err)
+	}
+	return e
+}
+
+func TestExtractors(t *testing.T) {
+	b := testbundle.New()
+	b.Package(dbPath).Secret("password", "foo")
+	b.Package(webPath).
+		Secret("dsn", "postgres://{{secret:"+dbPath+"#password}}@db/{{ secret:/"+missing1+" }}").
+		Secret("port", "5432").
+		Annotation("harp.elastic.co/v1/package#description", "uses {{secret:"+infraDB+"}}").
+		Annotation(AliasAnnotation, dbPath).
+		Annotation(ACLAnnotation, aclPath+", "+missing1)
+	fixture := b.Build()
+
+	testCases := []struct {
+		desc      string
+		extractor string
+		want      []Reference
+	}{
+		{
+			desc:      "secret-template",
+			extractor: "secret-template",
+			want: []Reference{
+				{Extractor: "secret-template", Source: webPath, Location: "annotation:harp.elastic.co/v1/package#description", Target: infraDB},
+				{Extractor: "secret-template", Source: webPath, Location: "secret:dsn", Target: dbPath},
+				{Extractor: "secret-template", Source: webPath, Location: "secret:dsn", Target: "/" + missing1},
+			},
+		},
+		{
+			desc:      "alias",
+			extractor: "alias",
+			want: []Reference{
+				{Extractor: "alias", Source: webPath, Location: "annotation:" + AliasAnnotation, Target: dbPath},
+			},
+		},
+		{
+			desc:      "acl",
+			extractor: "acl",
+			want: []Reference{
+				{Extractor: "acl", Source: webPath, Location: "annotation:" + ACLAnnotation, Target: aclPath},
+				{Extractor: "acl", Source: webPath, Location: "annotation:" + ACLAnnotation, Target: missing1},
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			e := mustSelect(t, tC.extractor)[0]
+
+			p := fixture.Packages[1]
+			secrets, err := bundle.AsSecretMap(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := e.Extract(p, secrets)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(got, tC.want); diff != "" {
+				t.Errorf("%q. Extract():\n-got/+want\ndiff %s", tC.desc, diff)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	main := testbundle.New()
+	main.Package(dbPath).Secret("password", "foo")
+	main.Package(webPath).
+		Secret("dsn", "{{secret:"+dbPath+"}} {{secret:"+infraDB+"}}").
+		Annotation(ACLAnnotation, aclPath)
+	main.Package(missing1).
+		Secret("token", "{{secret:"+webPath+"}}").
+		Annotation(bundle.ArchivedAnnotation, "2021-01-01T00:00:00Z")
+
+	companion := testbundle.New()
+	companion.Package(infraDB).Secret("password", "bar")
+	companion.Package(aclPath).Secret("rules", "[]")
+
+	testCases := []struct {
+		desc         string
+		companions   []*bundlev1.Bundle
+		extractors   []string
+		wantChecked  int
+		wantDangling []string
+	}{
+		{
+			desc:         "without companion",
+			wantChecked:  3,
+			wantDangling: []string{aclPath, infraDB},
+		},
+		{
+			desc:        "cross bundle resolution",
+			companions:  []*bundlev1.Bundle{companion.Build()},
+			wantChecked: 3,
+		},
+		{
+			desc:         "acl only",
+			extractors:   []string{"acl"},
+			wantChecked:  1,
+			wantDangling: []string{aclPath},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			report, err := Check(main.Build(), tC.companions, mustSelect(t, tC.extractors...))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if report.Checked != tC.wantChecked {
+				t.Errorf("checked = %d, want %d", report.Checked, tC.wantChecked)
+			}
+
+			got := []string{}
+			for _, ref := range report.Dangling {
+				got = append(got, ref.Target)
+			}
+			if len(tC.wantDangling) == 0 {
+				tC.wantDangling = []string{}
+			}
+			if diff := cmp.Diff(got, tC.wantDangling); diff != "" {
+				t.Errorf("%q. Check():\n-got/+want\ndiff %s", tC.desc, diff)
+			}
+		})
+	}
+
+	// Archived packages are not valid targets
+	archived := testbundle.New()
+	archived.Package(webPath).Annotation(AliasAnnotation, dbPath)
+	archived.Package(dbPath).Secret("password", "foo").Annotation(bundle.ArchivedAnnotation, "2021-01-01T00:00:00Z")
+	report, err := Check(archived.Build(), nil, mustSelect(t, "alias"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !report.HasDangling() {
+		t.Error("reference to an archived package must be dangling")
+	}
+}
+
+func TestSelect(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		enabled  []string
+		disabled []string
+		want     []string
+		wantErr  error
+	}{
+		{desc: "all", want: []string{"acl", "alias", "secret-template"}},
+		{desc: "enabled", enabled: []string{"secret-template", "acl"}, want: []string{"acl", "secret-template"}},
+		{desc: "disabled", disabled: []string{"alias"}, want: []string{"acl", "secret-template"}},
+		{desc: "unknown", enabled: []string{"foo"}, wantErr: ErrExtractorNotFound},
+		{desc: "unknown disabled", disabled: []string{"foo"}, wantErr: ErrExtractorNotFound},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			res, err := Select(tC.enabled, tC.disabled)
+			if !errors.Is(err, tC.wantErr) {
+				t.Fatalf("expected error %v, got %v", tC.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			got := []string{}
+			for _, e := range res {
+				got = append(got, e.Name())
+			}
+			if diff := cmp.Diff(got, tC.want); diff != "" {
+				t.Errorf("%q. Select():\n-got/+want\ndiff %s", tC.desc, diff)
+			}
+		})
+	}
+}
+
+type nopExtractor struct{}
+
+func (nopExtractor) Name() string { return "alias" }
+func (nopExtractor) Extract(*bundlev1.Package, bundle.KV) ([]Reference, error) {
+	return nil, nil
+}
+
+func TestRegister(t *testing.T) {
+	if err := Register(nopExtractor{}); !errors.Is(err, ErrExtractorAlreadyRegistered) {
+		t.Errorf("expected ErrExtractorAlreadyRegistered, got %v", err)
+	}
+}
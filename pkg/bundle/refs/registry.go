@@ -0,0 +1,102 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package refs
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	extractorsMu sync.RWMutex
+	extractors   = map[string]Extractor{}
+)
+
+// Register an extractor.
+func Register(e Extractor) error {
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+
+	if _, ok := extractors[e.Name()]; ok {
+		return fmt.Errorf("unable to register '%s': %w", e.Name(), ErrExtractorAlreadyRegistered)
+	}
+	extractors[e.Name()] = e
+
+	// No error
+	return nil
+}
+
+// MustRegister registers an extractor and panics on error.
+func MustRegister(e Extractor) {
+	if err := Register(e); err != nil {
+		panic(err)
+	}
+}
+
+// Names returns sorted registered extractor names.
+func Names() []string {
+	extractorsMu.RLock()
+	defer extractorsMu.RUnlock()
+
+	names := make([]string, 0, len(extractors))
+	for name := range extractors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Select returns the extractors to use. All registered extractors are
+// enabled when the enabled list is empty, disabled ones are removed.
+func Select(enabled, disabled []string) ([]Extractor, error) {
+	extractorsMu.RLock()
+	defer extractorsMu.RUnlock()
+
+	// Check requested names
+	for _, name := range append(append([]string{}, enabled...), disabled...) {
+		if _, ok := extractors[name]; !ok {
+			return nil, fmt.Errorf("unable to select '%s': %w", name, ErrExtractorNotFound)
+		}
+	}
+
+	names := enabled
+	if len(names) == 0 {
+		for name := range extractors {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	skip := map[string]struct{}{}
+	for _, name := range disabled {
+		skip[name] = struct{}{}
+	}
+
+	res := []Extractor{}
+	for _, name := range names {
+		if _, ok := skip[name]; ok {
+			continue
+		}
+		res = append(res, extractors[name])
+	}
+
+	// No error
+	return res, nil
+}
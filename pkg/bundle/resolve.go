@@ -0,0 +1,250 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle/secret"
+	"github.com/elastic/harp/pkg/template/consumption"
+)
+
+var (
+	// ErrSecretNotFound is raised when the requested secret key doesn't exist
+	// in the package.
+	ErrSecretNotFound = errors.New("bundle: secret not found")
+	// ErrPackageLocked is raised when the requested package secrets are
+	// encrypted.
+	ErrPackageLocked = errors.New("bundle: package is locked")
+	// ErrInvalidRequest is raised when a secret request is malformed.
+	ErrInvalidRequest = errors.New("bundle: invalid secret request")
+)
+
+// Decoding describes how a resolved secret value must be decoded.
+type Decoding string
+
+const (
+	// DecodeNone returns the unpacked value as is.
+	DecodeNone Decoding = ""
+	// DecodeString returns the value as a string.
+	DecodeString Decoding = "string"
+	// DecodeBase64 decodes a base64 encoded string value as bytes.
+	DecodeBase64 Decoding = "base64"
+	// DecodeJSON decodes a JSON encoded string value.
+	DecodeJSON Decoding = "json"
+)
+
+// SecretRequest describes a secret value to resolve.
+type SecretRequest struct {
+	Path   string
+	Key    string
+	Decode Decoding
+}
+
+// SecretResult holds a resolved secret value or the resolution error of the
+// request.
+type SecretResult struct {
+	Request SecretRequest
+	Value   interface{}
+	Err     error
+}
+
+// ResolveAll resolves all requested secret values. Each package is looked up
+// and unpacked once whatever the count of requests referencing it. Request
+// errors are reported in the matching result, results preserve the request
+// order. Resolved values are recorded by the context consumption tracker if
+// any.
+func ResolveAll(ctx context.Context, b *bundlev1.Bundle, requests []SecretRequest) ([]SecretResult, error) {
+	// Check arguments
+	if b == nil {
+		return nil, fmt.Errorf("unable to process nil bundle")
+	}
+
+	results := make([]SecretResult, len(requests))
+
+	// Index packages by path, first match wins as with Read
+	index := make(map[string]*bundlev1.Package, len(b.Packages))
+	for _, p := range b.Packages {
+		name := strings.ToLower(p.Name)
+		if _, ok := index[name]; !ok {
+			index[name] = p
+		}
+	}
+
+	// Group requests by package
+	groups := []*resolution{}
+	byPackage := map[*bundlev1.Package]*resolution{}
+	for i, req := range requests {
+		results[i].Request = req
+
+		// Check request
+		if req.Path == "" || req.Key == "" {
+			results[i].Err = fmt.Errorf("path and key must not be blank: %w", ErrInvalidRequest)
+			continue
+		}
+
+		p, ok := index[strings.ToLower(req.Path)]
+		if !ok {
+			results[i].Err = fmt.Errorf("unable to lookup secret with path '%s': %w", req.Path, ErrPackageNotFound)
+			continue
+		}
+
+		g, ok := byPackage[p]
+		if !ok {
+			g = &resolution{pkg: p}
+			byPackage[p] = g
+			groups = append(groups, g)
+		}
+		g.requests = append(g.requests, i)
+	}
+
+	// Resolve packages with bounded concurrency
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(groups) {
+		workers = len(groups)
+	}
+
+	tracker := consumption.TrackerFrom(ctx)
+	jobs := make(chan *resolution)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for g := range jobs {
+				g.resolve(results, tracker)
+			}
+		}()
+	}
+
+	var err error
+	for _, g := range groups {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		jobs <- g
+	}
+	close(jobs)
+	wg.Wait()
+
+	// Check cancellation
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve all secrets: %w", err)
+	}
+
+	// No error
+	return results, nil
+}
+
+// -----------------------------------------------------------------------------
+
+type resolution struct {
+	pkg      *bundlev1.Package
+	requests []int
+}
+
+type unpacked struct {
+	value interface{}
+	err   error
+}
+
+func (r *resolution) resolve(results []SecretResult, tracker *consumption.Tracker) {
+	// Check package state
+	if r.pkg.Secrets == nil || r.pkg.Secrets.Locked != nil {
+		for _, i := range r.requests {
+			results[i].Err = fmt.Errorf("unable to read secrets of '%s': %w", r.pkg.Name, ErrPackageLocked)
+		}
+		return
+	}
+
+	// Index secret entries
+	entries := make(map[string]*bundlev1.KV, len(r.pkg.Secrets.Data))
+	for _, kv := range r.pkg.Secrets.Data {
+		entries[kv.Key] = kv
+	}
+
+	// Unpack each requested key once
+	cache := map[string]*unpacked{}
+	for _, i := range r.requests {
+		req := results[i].Request
+
+		u, ok := cache[req.Key]
+		if !ok {
+			u = &unpacked{}
+			if kv, found := entries[req.Key]; !found {
+				u.err = fmt.Errorf("unable to lookup '%s' in '%s': %w", req.Key, r.pkg.Name, ErrSecretNotFound)
+			} else if err := secret.Unpack(kv.Value, &u.value); err != nil {
+				u.err = fmt.Errorf("unable to unpack '%s' secret value of '%s': %w", req.Key, r.pkg.Name, err)
+			} else if tracker != nil {
+				u.err = tracker.Track(consumption.SourceReference, r.pkg.Name, req.Key, u.value)
+			}
+			cache[req.Key] = u
+		}
+		if u.err != nil {
+			results[i].Err = u.err
+			continue
+		}
+
+		results[i].Value, results[i].Err = decodeValue(u.value, req.Decode)
+	}
+}
+
+func decodeValue(value interface{}, decoding Decoding) (interface{}, error) {
+	if decoding == DecodeNone {
+		return value, nil
+	}
+
+	// All decodings expect a textual value
+	var raw string
+	switch v := value.(type) {
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	default:
+		return nil, fmt.Errorf("unable to decode %T value as %s", value, decoding)
+	}
+
+	switch decoding {
+	case DecodeString:
+		return raw, nil
+	case DecodeBase64:
+		out, err := base64.StdEncoding.DecodeString(raw)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode value as base64: %w", err)
+		}
+		return out, nil
+	case DecodeJSON:
+		var out interface{}
+		if err := json.Unmarshal([]byte(raw), &out); err != nil {
+			return nil, fmt.Errorf("unable to decode value as json: %w", err)
+		}
+		return out, nil
+	default:
+	}
+
+	return nil, fmt.Errorf("unsupported decoding '%s': %w", decoding, ErrInvalidRequest)
+}
@@ -0,0 +1,292 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle/secret"
+)
+
+func resolveBundle(t testing.TB, packages, keys int) *bundlev1.Bundle {
+	b := &bundlev1.Bundle{}
+	for i := 0; i < packages; i++ {
+		p := &bundlev1.Package{
+			Name:    fmt.Sprintf("app/production/service-%d", i),
+			Secrets: &bundlev1.SecretChain{},
+		}
+		for k := 0; k < keys; k++ {
+			value, err := secret.Pack(fmt.Sprintf("value-%d-%d", i, k))
+			if err != nil {
+				t.Fatalf("unable to pack value: %v", err)
+			}
+			p.Secrets.Data = append(p.Secrets.Data, &bundlev1.KV{Key: fmt.Sprintf("key-%d", k), Value: value})
+		}
+		b.Packages = append(b.Packages, p)
+	}
+	return b
+}
+
+func addSecret(t testing.TB, b *bundlev1.Bundle, path, key string, value interface{}) {
+	packed, err := secret.Pack(value)
+	if err != nil {
+		t.Fatalf("unable to pack value: %v", err)
+	}
+	for _, p := range b.Packages {
+		if p.Name == path {
+			p.Secrets.Data = append(p.Secrets.Data, &bundlev1.KV{Key: key, Value: packed})
+			return
+		}
+	}
+	b.Packages = append(b.Packages, &bundlev1.Package{
+		Name: path,
+		Secrets: &bundlev1.SecretChain{
+			Data: []*bundlev1.KV{{Key: key, Value: packed}},
+		},
+	})
+}
+
+func TestResolveAll_NilBundle(t *testing.T) {
+	if _, err := ResolveAll(context.Background(), nil, nil); err == nil {
+		t.Fatal("error should be raised")
+	}
+}
+
+func TestResolveAll_Order(t *testing.T) {
+	b := resolveBundle(t, 10, 3)
+
+	requests := []SecretRequest{}
+	for i := 9; i >= 0; i-- {
+		for k := 2; k >= 0; k-- {
+			requests = append(requests, SecretRequest{
+				Path: fmt.Sprintf("app/production/service-%d", i),
+				Key:  fmt.Sprintf("key-%d", k),
+			})
+		}
+	}
+
+	results, err := ResolveAll(context.Background(), b, requests)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != len(requests) {
+		t.Fatalf("expected %d results, got %d", len(requests), len(results))
+	}
+	for i, res := range results {
+		if res.Err != nil {
+			t.Fatalf("unexpected error for request %d: %v", i, res.Err)
+		}
+		if res.Request != requests[i] {
+			t.Errorf("result %d doesn't match request order", i)
+		}
+		var pkg, key int
+		if _, err := fmt.Sscanf(requests[i].Path, "app/production/service-%d", &pkg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := fmt.Sscanf(requests[i].Key, "key-%d", &key); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if expected := fmt.Sprintf("value-%d-%d", pkg, key); res.Value != expected {
+			t.Errorf("result %d: expected %q, got %v", i, expected, res.Value)
+		}
+	}
+}
+
+func TestResolveAll_PartialFailures(t *testing.T) {
+	b := resolveBundle(t, 2, 1)
+	b.Packages[1].Secrets.Data = append(b.Packages[1].Secrets.Data, &bundlev1.KV{Key: "corrupted", Value: []byte{0xFF, 0x00}})
+	b.Packages = append(b.Packages, &bundlev1.Package{
+		Name:    "app/production/locked",
+		Secrets: &bundlev1.SecretChain{Locked: wrapperspb.Bytes([]byte("encrypted"))},
+	})
+
+	requests := []SecretRequest{
+		{Path: "app/production/service-0", Key: "key-0"},
+		{Path: "app/production/missing", Key: "key-0"},
+		{Path: "app/production/service-0", Key: "missing"},
+		{Path: "app/production/locked", Key: "key-0"},
+		{Path: "app/production/service-1", Key: "corrupted"},
+		{Path: "", Key: "key-0"},
+		{Path: "app/production/service-1", Key: "key-0", Decode: "yaml"},
+		{Path: "app/production/service-1", Key: "key-0"},
+	}
+
+	results, err := ResolveAll(context.Background(), b, requests)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if results[0].Err != nil || results[0].Value != "value-0-0" {
+		t.Errorf("unexpected first result: %+v", results[0])
+	}
+	if !errors.Is(results[1].Err, ErrPackageNotFound) {
+		t.Errorf("expected package not found error, got %v", results[1].Err)
+	}
+	if !errors.Is(results[2].Err, ErrSecretNotFound) {
+		t.Errorf("expected secret not found error, got %v", results[2].Err)
+	}
+	if !errors.Is(results[3].Err, ErrPackageLocked) {
+		t.Errorf("expected package locked error, got %v", results[3].Err)
+	}
+	if results[4].Err == nil {
+		t.Error("expected unpack error for corrupted value")
+	}
+	if !errors.Is(results[5].Err, ErrInvalidRequest) {
+		t.Errorf("expected invalid request error, got %v", results[5].Err)
+	}
+	if !errors.Is(results[6].Err, ErrInvalidRequest) {
+		t.Errorf("expected invalid request error, got %v", results[6].Err)
+	}
+	if results[7].Err != nil || results[7].Value != "value-1-0" {
+		t.Errorf("unexpected last result: %+v", results[7])
+	}
+}
+
+func TestResolveAll_DuplicatePaths(t *testing.T) {
+	b := resolveBundle(t, 1, 2)
+	// Duplicate package name, the first one must win as with Read.
+	b.Packages = append(b.Packages, &bundlev1.Package{
+		Name:    "APP/production/service-0",
+		Secrets: &bundlev1.SecretChain{},
+	})
+
+	requests := []SecretRequest{
+		{Path: "app/production/service-0", Key: "key-0"},
+		{Path: "APP/PRODUCTION/SERVICE-0", Key: "key-0"},
+		{Path: "app/production/service-0", Key: "key-1"},
+		{Path: "app/production/service-0", Key: "key-0", Decode: DecodeString},
+	}
+
+	results, err := ResolveAll(context.Background(), b, requests)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := []interface{}{}
+	for _, res := range results {
+		if res.Err != nil {
+			t.Fatalf("unexpected error: %v", res.Err)
+		}
+		got = append(got, res.Value)
+	}
+	expected := []interface{}{"value-0-0", "value-0-0", "value-0-1", "value-0-0"}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("%s: mismatch (-want +got):\n%s", t.Name(), diff)
+	}
+}
+
+func TestResolveAll_Decoding(t *testing.T) {
+	b := &bundlev1.Bundle{}
+	addSecret(t, b, "app/production/encoded", "b64", "c2VjcmV0")
+	addSecret(t, b, "app/production/encoded", "json", `{"user":"admin"}`)
+	addSecret(t, b, "app/production/encoded", "bytes", []byte("raw"))
+	addSecret(t, b, "app/production/encoded", "invalid", "%%%")
+
+	results, err := ResolveAll(context.Background(), b, []SecretRequest{
+		{Path: "app/production/encoded", Key: "b64", Decode: DecodeBase64},
+		{Path: "app/production/encoded", Key: "json", Decode: DecodeJSON},
+		{Path: "app/production/encoded", Key: "bytes", Decode: DecodeString},
+		{Path: "app/production/encoded", Key: "invalid", Decode: DecodeBase64},
+		{Path: "app/production/encoded", Key: "b64"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if diff := cmp.Diff([]byte("secret"), results[0].Value); diff != "" {
+		t.Errorf("base64 mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[string]interface{}{"user": "admin"}, results[1].Value); diff != "" {
+		t.Errorf("json mismatch (-want +got):\n%s", diff)
+	}
+	if results[2].Value != "raw" {
+		t.Errorf("expected string value, got %v", results[2].Value)
+	}
+	if results[3].Err == nil {
+		t.Error("expected base64 decoding error")
+	}
+	if results[4].Value != "c2VjcmV0" {
+		t.Errorf("expected raw value, got %v", results[4].Value)
+	}
+}
+
+func TestResolveAll_Cancelled(t *testing.T) {
+	b := resolveBundle(t, 2, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := ResolveAll(ctx, b, []SecretRequest{{Path: "app/production/service-0", Key: "key-0"}})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context cancellation error, got %v", err)
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+func benchmarkRequests(packages, keys int) []SecretRequest {
+	requests := []SecretRequest{}
+	for i := 0; i < packages; i++ {
+		for k := 0; k < keys; k++ {
+			requests = append(requests, SecretRequest{
+				Path: fmt.Sprintf("app/production/service-%d", i),
+				Key:  fmt.Sprintf("key-%d", k),
+			})
+		}
+	}
+	return requests
+}
+
+func BenchmarkResolveAll(b *testing.B) {
+	bundle := resolveBundle(b, 200, 10)
+	requests := benchmarkRequests(200, 10)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, err := ResolveAll(context.Background(), bundle, requests); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSequentialRead(b *testing.B) {
+	bundle := resolveBundle(b, 200, 10)
+	requests := benchmarkRequests(200, 10)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, req := range requests {
+			secrets, err := Read(bundle, req.Path)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, ok := secrets[req.Key]; !ok {
+				b.Fatal("secret not found")
+			}
+		}
+	}
+}
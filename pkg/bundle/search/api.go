@@ -0,0 +1,124 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package search finds bundle packages, secret keys and secret values
+// matching a pattern without disclosing matched values.
+package search
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+)
+
+var (
+	// ErrInvalidQuery is raised when query settings are inconsistent.
+	ErrInvalidQuery = errors.New("search: invalid query")
+	// errStop is used to interrupt the bundle walk.
+	errStop = errors.New("search: stop")
+)
+
+// Field describes the matched bundle element.
+type Field string
+
+const (
+	// FieldPath is used when the package path matches.
+	FieldPath Field = "path"
+	// FieldKey is used when the secret key name matches.
+	FieldKey Field = "key"
+	// FieldValue is used when the secret value matches.
+	FieldValue Field = "value"
+)
+
+// DefaultContextSize is the default count of characters displayed around a
+// value match.
+const DefaultContextSize = 8
+
+// Query describes search settings.
+type Query struct {
+	// PathPrefix restricts the search to packages with the given path prefix.
+	PathPrefix string
+	// KeyGlob restricts the search to secret keys matching the glob pattern.
+	KeyGlob string
+	// ValueRegex is the pattern searched in paths, keys and values.
+	ValueRegex *regexp.Regexp
+	// ValueBytes is the byte sequence searched in paths, keys and values.
+	ValueBytes []byte
+	// IncludeValues enables secret values unpacking and matching.
+	IncludeValues bool
+	// IncludeArchived enables archived packages matching.
+	IncludeArchived bool
+	// ContextSize is the count of characters displayed around value matches.
+	ContextSize int
+	// MaxResults stops the search after the given match count, 0 for
+	// unlimited.
+	MaxResults int
+}
+
+// Match describes a search result.
+type Match struct {
+	Path    string `json:"path"`
+	Key     string `json:"key,omitempty"`
+	Field   Field  `json:"field"`
+	Context string `json:"context,omitempty"`
+}
+
+// Validate the query.
+func (q *Query) Validate() error {
+	if q.ValueRegex != nil && len(q.ValueBytes) > 0 {
+		return fmt.Errorf("%w: value regex and bytes patterns are exclusive", ErrInvalidQuery)
+	}
+	if q.IncludeValues && q.ValueRegex == nil && len(q.ValueBytes) == 0 {
+		return fmt.Errorf("%w: value search requires a pattern", ErrInvalidQuery)
+	}
+	if q.ContextSize < 0 {
+		return fmt.Errorf("%w: context size must be positive", ErrInvalidQuery)
+	}
+	if q.MaxResults < 0 {
+		return fmt.Errorf("%w: max results must be positive", ErrInvalidQuery)
+	}
+	if _, err := path.Match(q.KeyGlob, ""); err != nil {
+		return fmt.Errorf("%w: invalid key glob: %v", ErrInvalidQuery, err)
+	}
+
+	// No error
+	return nil
+}
+
+// hasPattern returns true if the query has a content pattern.
+func (q *Query) hasPattern() bool {
+	return q.ValueRegex != nil || len(q.ValueBytes) > 0
+}
+
+// find returns the first pattern match location.
+func (q *Query) find(data []byte) (start, end int, ok bool) {
+	if q.ValueRegex != nil {
+		loc := q.ValueRegex.FindIndex(data)
+		if loc == nil {
+			return 0, 0, false
+		}
+		return loc[0], loc[1], true
+	}
+
+	idx := bytes.Index(data, q.ValueBytes)
+	if idx < 0 {
+		return 0, 0, false
+	}
+	return idx, idx + len(q.ValueBytes), true
+}
@@ -0,0 +1,120 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package search
+
+import (
+	"encoding/hex"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	maskChar       = "*"
+	truncationMark = "..."
+	// maxRevealed is the maximum count of leading matched characters kept
+	// visible to identify the match kind (AKIA, ghp_, etc.)
+	maxRevealed = 4
+)
+
+// Excerpt returns the [start, end) match surrounded by up to size characters
+// of context. Only the leading quarter of the match, up to 4 characters, is
+// kept, the rest of the match is masked. Binary data is rendered as
+// hexadecimal bytes.
+func Excerpt(data []byte, start, end, size int) string {
+	if start < 0 || end > len(data) || start > end {
+		return ""
+	}
+	if !isText(data) || !utf8.Valid(data[start:end]) {
+		return binaryExcerpt(data, start, end, size)
+	}
+
+	before := []rune(string(data[:start]))
+	match := []rune(string(data[start:end]))
+	after := []rune(string(data[end:]))
+
+	var sb strings.Builder
+	if len(before) > size {
+		sb.WriteString(truncationMark)
+		before = before[len(before)-size:]
+	}
+	sb.WriteString(string(before))
+
+	revealed := revealedCount(len(match))
+	sb.WriteString(string(match[:revealed]))
+	sb.WriteString(strings.Repeat(maskChar, len(match)-revealed))
+
+	if len(after) > size {
+		sb.WriteString(string(after[:size]))
+		sb.WriteString(truncationMark)
+	} else {
+		sb.WriteString(string(after))
+	}
+
+	return sb.String()
+}
+
+// -----------------------------------------------------------------------------
+
+func binaryExcerpt(data []byte, start, end, size int) string {
+	from := start - size
+	if from < 0 {
+		from = 0
+	}
+	to := end + size
+	if to > len(data) {
+		to = len(data)
+	}
+
+	var sb strings.Builder
+	if from > 0 {
+		sb.WriteString(truncationMark)
+	}
+	sb.WriteString(hex.EncodeToString(data[from:start]))
+
+	revealed := revealedCount(end - start)
+	sb.WriteString(hex.EncodeToString(data[start : start+revealed]))
+	sb.WriteString(strings.Repeat(maskChar+maskChar, end-start-revealed))
+
+	sb.WriteString(hex.EncodeToString(data[end:to]))
+	if to < len(data) {
+		sb.WriteString(truncationMark)
+	}
+
+	return sb.String()
+}
+
+func revealedCount(length int) int {
+	n := length / 4
+	if n > maxRevealed {
+		n = maxRevealed
+	}
+	return n
+}
+
+func isText(data []byte) bool {
+	if !utf8.Valid(data) {
+		return false
+	}
+	for _, r := range string(data) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
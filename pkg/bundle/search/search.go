@@ -0,0 +1,153 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package search
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/secret"
+)
+
+// Search walks the bundle and calls fn for each match as soon as it is
+// found. Secret values are unpacked one at a time. It returns the match count.
+func Search(b *bundlev1.Bundle, q *Query, fn func(*Match) error) (int, error) {
+	// Check arguments
+	if b == nil {
+		return 0, fmt.Errorf("unable to search a nil bundle")
+	}
+	if q == nil {
+		return 0, fmt.Errorf("unable to search with a nil query")
+	}
+	if err := q.Validate(); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	emit := func(m *Match) error {
+		if err := fn(m); err != nil {
+			return err
+		}
+		count++
+		if q.MaxResults > 0 && count >= q.MaxResults {
+			return errStop
+		}
+		return nil
+	}
+
+	for _, p := range b.Packages {
+		if err := searchPackage(p, q, emit); err != nil {
+			if errors.Is(err, errStop) {
+				break
+			}
+			return count, err
+		}
+	}
+
+	// No error
+	return count, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func searchPackage(p *bundlev1.Package, q *Query, emit func(*Match) error) error {
+	if p == nil {
+		return nil
+	}
+	if !q.IncludeArchived && bundle.IsArchived(p) {
+		return nil
+	}
+	if !strings.HasPrefix(p.Name, q.PathPrefix) {
+		return nil
+	}
+
+	// Package path is only reported when keys are not filtered
+	if q.KeyGlob == "" {
+		if !q.hasPattern() {
+			return emit(&Match{Path: p.Name, Field: FieldPath})
+		}
+		if _, _, ok := q.find([]byte(p.Name)); ok {
+			if err := emit(&Match{Path: p.Name, Field: FieldPath, Context: p.Name}); err != nil {
+				return err
+			}
+		}
+	}
+	if p.Secrets == nil {
+		return nil
+	}
+
+	for _, kv := range p.Secrets.Data {
+		if q.KeyGlob != "" {
+			if ok, _ := path.Match(q.KeyGlob, kv.Key); !ok {
+				continue
+			}
+		}
+
+		// Match key name
+		if !q.hasPattern() {
+			if err := emit(&Match{Path: p.Name, Key: kv.Key, Field: FieldKey}); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, _, ok := q.find([]byte(kv.Key)); ok {
+			if err := emit(&Match{Path: p.Name, Key: kv.Key, Field: FieldKey, Context: kv.Key}); err != nil {
+				return err
+			}
+		}
+
+		// Locked values can't be searched
+		if !q.IncludeValues || p.Secrets.Locked != nil {
+			continue
+		}
+
+		// Match value
+		data, err := valueBytes(kv)
+		if err != nil {
+			return fmt.Errorf("unable to unpack '%s' value of '%s': %w", kv.Key, p.Name, err)
+		}
+		if start, end, ok := q.find(data); ok {
+			if err := emit(&Match{Path: p.Name, Key: kv.Key, Field: FieldValue, Context: Excerpt(data, start, end, q.ContextSize)}); err != nil {
+				return err
+			}
+		}
+	}
+
+	// No error
+	return nil
+}
+
+func valueBytes(kv *bundlev1.KV) ([]byte, error) {
+	var value interface{}
+	if err := secret.Unpack(kv.Value, &value); err != nil {
+		return nil, err
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return []byte(fmt.Sprintf("%v", v)), nil
+	}
+}
@@ -0,0 +1,153 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package secret
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+)
+
+const (
+	// CodecLegacyASN1 is the codec name of ASN.1 DER packed values.
+	CodecLegacyASN1 = "legacy-asn1"
+	// CodecUnknown is used when the value codec can't be identified.
+	CodecUnknown = "unknown"
+)
+
+var (
+	// ErrTrailingData is raised when packed value has data after the encoded value.
+	ErrTrailingData = errors.New("trailing data after secret value")
+	// ErrUnsupportedCodec is raised when the packed value codec is not supported.
+	ErrUnsupportedCodec = errors.New("unsupported secret value codec")
+)
+
+// codecMagics references known value encodings which are not supported by
+// this version but could be found in bundles produced by other tools.
+var codecMagics = []struct {
+	name  string
+	magic []byte
+}{
+	{name: "gzip", magic: []byte{0x1f, 0x8b}},
+	{name: "zstd", magic: []byte{0x28, 0xb5, 0x2f, 0xfd}},
+	{name: "json", magic: []byte("{")},
+	{name: "json", magic: []byte("[")},
+}
+
+// UnpackError describes a secret value decoding error.
+type UnpackError struct {
+	Codec         string
+	ExpectedType  string
+	TrailingBytes int
+	Err           error
+}
+
+// Error returns the error message.
+func (e *UnpackError) Error() string {
+	if e.TrailingBytes > 0 {
+		return fmt.Sprintf("unable to unpack secret value (codec: %s, type: %s): %v (%d bytes)", e.Codec, e.ExpectedType, e.Err, e.TrailingBytes)
+	}
+	return fmt.Sprintf("unable to unpack secret value (codec: %s, type: %s): %v", e.Codec, e.ExpectedType, e.Err)
+}
+
+// Unwrap returns the wrapped error.
+func (e *UnpackError) Unwrap() error {
+	return e.Err
+}
+
+// CodecInfo describes a packed secret value without decoding it.
+type CodecInfo struct {
+	Codec         string `json:"codec"`
+	Tag           int    `json:"tag,omitempty"`
+	Size          int    `json:"size"`
+	PayloadSize   int    `json:"payload_size"`
+	TrailingBytes int    `json:"trailing_bytes,omitempty"`
+}
+
+// Inspect returns codec information of the given packed secret value.
+func Inspect(in []byte) (CodecInfo, error) {
+	info := CodecInfo{
+		Codec: detectCodec(in),
+		Size:  len(in),
+	}
+
+	// Only legacy ASN.1 values can be inspected further
+	if info.Codec != CodecLegacyASN1 {
+		return info, &UnpackError{
+			Codec:        info.Codec,
+			ExpectedType: "asn1.RawValue",
+			Err:          ErrUnsupportedCodec,
+		}
+	}
+
+	// Decode only the outer ASN.1 envelope
+	var raw asn1.RawValue
+	rest, err := asn1.Unmarshal(in, &raw)
+	if err != nil {
+		return info, &UnpackError{
+			Codec:        info.Codec,
+			ExpectedType: "asn1.RawValue",
+			Err:          err,
+		}
+	}
+
+	// Assign envelope properties
+	info.Tag = raw.Tag
+	info.PayloadSize = len(raw.Bytes)
+	info.TrailingBytes = len(rest)
+
+	// No error
+	return info, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func detectCodec(in []byte) string {
+	if len(in) == 0 {
+		return CodecUnknown
+	}
+
+	// Check known foreign encodings
+	for _, c := range codecMagics {
+		if bytes.HasPrefix(in, c.magic) {
+			return c.name
+		}
+	}
+
+	// ASN.1 universal class identifiers produced by asn1.Marshal
+	switch in[0] {
+	case 0x01, // BOOLEAN
+		0x02, // INTEGER
+		0x03, // BIT STRING
+		0x04, // OCTET STRING
+		0x05, // NULL
+		0x06, // OBJECT IDENTIFIER
+		0x0a, // ENUMERATED
+		0x0c, // UTF8String
+		0x13, // PrintableString
+		0x16, // IA5String
+		0x17, // UTCTime
+		0x18, // GeneralizedTime
+		0x30, // SEQUENCE
+		0x31: // SET
+		return CodecLegacyASN1
+	}
+
+	return CodecUnknown
+}
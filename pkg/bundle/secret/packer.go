@@ -18,12 +18,36 @@
 package secret
 
 import (
+	"bytes"
 	"encoding/asn1"
 	"fmt"
+	"unicode/utf8"
+)
+
+// ASN.1 DER identifiers of values encoded without reflection.
+const (
+	tagOctetString     = 0x04
+	tagUTF8String      = 0x0c
+	tagPrintableString = 0x13
+	// maxHeaderLength is the DER header length of the largest supported value.
+	maxHeaderLength = 6
 )
 
 // Pack a secret value.
 func Pack(value interface{}) ([]byte, error) {
+	// Encode common values without reflection
+	switch v := value.(type) {
+	case string:
+		if tag, ok := stringTag(v); ok {
+			out := appendHeader(make([]byte, 0, headerLength(len(v))+len(v)), tag, len(v))
+			return append(out, v...), nil
+		}
+	case []byte:
+		out := appendHeader(make([]byte, 0, headerLength(len(v))+len(v)), tagOctetString, len(v))
+		return append(out, v...), nil
+	default:
+	}
+
 	// Encode the payload
 	// JSON is not used to prevents Base64 double encoding.
 	payload, err := asn1.Marshal(value)
@@ -34,12 +58,289 @@ func Pack(value interface{}) ([]byte, error) {
 	return payload, nil
 }
 
+// PackTo appends the packed secret value to the given buffer. The encoding is
+// the same as Pack, string and byte slice values are written without
+// allocation when the buffer has enough capacity.
+func PackTo(buf *bytes.Buffer, value interface{}) error {
+	// Check arguments
+	if buf == nil {
+		return fmt.Errorf("unable to pack secret value to a nil buffer")
+	}
+
+	var header [maxHeaderLength]byte
+	switch v := value.(type) {
+	case string:
+		if tag, ok := stringTag(v); ok {
+			buf.Grow(headerLength(len(v)) + len(v))
+			buf.Write(appendHeader(header[:0], tag, len(v)))
+			buf.WriteString(v)
+			return nil
+		}
+	case []byte:
+		buf.Grow(headerLength(len(v)) + len(v))
+		buf.Write(appendHeader(header[:0], tagOctetString, len(v)))
+		buf.Write(v)
+		return nil
+	default:
+	}
+
+	// Delegate to reflection based encoder
+	payload, err := Pack(value)
+	if err != nil {
+		return err
+	}
+	buf.Write(payload)
+
+	// No error
+	return nil
+}
+
 // Unpack a secret value.
 func Unpack(in []byte, out interface{}) error {
-	// Decode the value
-	if _, err := asn1.Unmarshal(in, out); err != nil {
-		return fmt.Errorf("unable to upack secret value: %w", err)
+	// Detect value codec
+	codec := detectCodec(in)
+	if codec != CodecLegacyASN1 {
+		return &UnpackError{
+			Codec:        codec,
+			ExpectedType: fmt.Sprintf("%T", out),
+			Err:          ErrUnsupportedCodec,
+		}
+	}
+
+	// Decode common values without reflection
+	rest := []byte(nil)
+	if n, ok := unpackPrimitive(in, out, false); ok {
+		rest = in[n:]
+	} else {
+		// Decode the value
+		var err error
+		rest, err = asn1.Unmarshal(in, out)
+		if err != nil {
+			return &UnpackError{
+				Codec:        codec,
+				ExpectedType: fmt.Sprintf("%T", out),
+				Err:          err,
+			}
+		}
 	}
 
+	// Check trailing data
+	if len(rest) > 0 {
+		return &UnpackError{
+			Codec:         codec,
+			ExpectedType:  fmt.Sprintf("%T", out),
+			TrailingBytes: len(rest),
+			Err:           ErrTrailingData,
+		}
+	}
+
+	return nil
+}
+
+// UnpackFrom unpacks the next secret value of the given buffer and advances
+// the buffer past it, remaining data is left for the next call. Decoded values
+// never reference the buffer memory so that it can be reused, and a *[]byte
+// output reuses its own capacity.
+func UnpackFrom(buf *bytes.Buffer, out interface{}) error {
+	// Check arguments
+	if buf == nil {
+		return fmt.Errorf("unable to unpack secret value from a nil buffer")
+	}
+
+	// Detect value codec
+	in := buf.Bytes()
+	codec := detectCodec(in)
+	if codec != CodecLegacyASN1 {
+		return &UnpackError{
+			Codec:        codec,
+			ExpectedType: fmt.Sprintf("%T", out),
+			Err:          ErrUnsupportedCodec,
+		}
+	}
+
+	// Decode common values without reflection
+	if n, ok := unpackPrimitive(in, out, true); ok {
+		buf.Next(n)
+		return nil
+	}
+
+	// Isolate the encoded value
+	var raw asn1.RawValue
+	if _, err := asn1.Unmarshal(in, &raw); err != nil {
+		return &UnpackError{
+			Codec:        codec,
+			ExpectedType: fmt.Sprintf("%T", out),
+			Err:          err,
+		}
+	}
+	n := len(raw.FullBytes)
+
+	// Decode from a copy, decoded values may reference their input
+	if _, err := asn1.Unmarshal(append([]byte(nil), in[:n]...), out); err != nil {
+		return &UnpackError{
+			Codec:        codec,
+			ExpectedType: fmt.Sprintf("%T", out),
+			Err:          err,
+		}
+	}
+	buf.Next(n)
+
 	return nil
 }
+
+// -----------------------------------------------------------------------------
+
+// stringTag returns the string type selected by asn1.Marshal for the given
+// value, false when the value can't be encoded.
+func stringTag(s string) (byte, bool) {
+	for i := 0; i < len(s); i++ {
+		if !isPrintable(s[i], false) {
+			if !utf8.ValidString(s) {
+				return 0, false
+			}
+			return tagUTF8String, true
+		}
+	}
+
+	return tagPrintableString, true
+}
+
+// isPrintable returns true when the byte is part of the PrintableString
+// character set. Decoders tolerate '*' and '&' found in the wild.
+func isPrintable(b byte, lenient bool) bool {
+	return 'a' <= b && b <= 'z' ||
+		'A' <= b && b <= 'Z' ||
+		'0' <= b && b <= '9' ||
+		'\'' <= b && b <= ')' ||
+		'+' <= b && b <= '/' ||
+		b == ' ' ||
+		b == ':' ||
+		b == '=' ||
+		b == '?' ||
+		(lenient && (b == '*' || b == '&'))
+}
+
+// headerLength returns the DER header length of a value of the given length.
+func headerLength(n int) int {
+	if n < 0x80 {
+		return 2
+	}
+
+	l := 1
+	for n > 0xff {
+		l++
+		n >>= 8
+	}
+
+	return 2 + l
+}
+
+// appendHeader appends the DER tag and length of a primitive value.
+func appendHeader(dst []byte, tag byte, n int) []byte {
+	dst = append(dst, tag)
+	if n < 0x80 {
+		return append(dst, byte(n))
+	}
+
+	l := headerLength(n) - 2
+	dst = append(dst, 0x80|byte(l))
+	for ; l > 0; l-- {
+		dst = append(dst, byte(n>>uint((l-1)*8)))
+	}
+
+	return dst
+}
+
+// parseHeader decodes the DER header of a primitive value. It returns false
+// for any encoding which is not strict DER, so that the value is processed by
+// the reflection based decoder which reports the exact error.
+func parseHeader(in []byte) (tag byte, offset, length int, ok bool) {
+	if len(in) < 2 {
+		return 0, 0, 0, false
+	}
+
+	tag, offset = in[0], 2
+	switch {
+	case in[1] < 0x80:
+		length = int(in[1])
+	case in[1] == 0x80 || in[1] > 0x83 || len(in) < 2+int(in[1]&0x7f) || in[2] == 0x00:
+		// Indefinite, too large, truncated or non minimal length
+		return 0, 0, 0, false
+	default:
+		l := int(in[1] & 0x7f)
+		for _, b := range in[2 : 2+l] {
+			length = length<<8 | int(b)
+		}
+		if length < 0x80 {
+			return 0, 0, 0, false
+		}
+		offset += l
+	}
+
+	// Check truncation
+	if length > len(in)-offset {
+		return 0, 0, 0, false
+	}
+
+	return tag, offset, length, true
+}
+
+// unpackPrimitive decodes string and byte slice values without reflection. It
+// returns the consumed length, false when the value must be decoded by
+// encoding/asn1. Byte slices reference the input unless copied is set, as
+// encoding/asn1 does for interface outputs.
+func unpackPrimitive(in []byte, out interface{}, copied bool) (int, bool) {
+	tag, offset, length, ok := parseHeader(in)
+	if !ok {
+		return 0, false
+	}
+	content := in[offset : offset+length]
+
+	// Validate string content
+	switch tag {
+	case tagPrintableString:
+		for _, b := range content {
+			if !isPrintable(b, true) {
+				return 0, false
+			}
+		}
+	case tagUTF8String:
+		if !utf8.Valid(content) {
+			return 0, false
+		}
+	case tagOctetString:
+	default:
+		return 0, false
+	}
+
+	switch v := out.(type) {
+	case *string:
+		if tag == tagOctetString {
+			return 0, false
+		}
+		*v = string(content)
+	case *[]byte:
+		if tag != tagOctetString {
+			return 0, false
+		}
+		if copied && *v != nil && cap(*v) >= len(content) {
+			*v = (*v)[:len(content)]
+		} else {
+			*v = make([]byte, len(content))
+		}
+		copy(*v, content)
+	case *interface{}:
+		switch {
+		case tag != tagOctetString:
+			*v = string(content)
+		case copied:
+			*v = append([]byte{}, content...)
+		default:
+			*v = content
+		}
+	default:
+		return 0, false
+	}
+
+	return offset + length, true
+}
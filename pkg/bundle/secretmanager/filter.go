@@ -0,0 +1,95 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package secretmanager
+
+import (
+	"fmt"
+	"strings"
+)
+
+// filter is a conjunction of secret label and name conditions. Secret Manager
+// list API doesn't support server side filtering, it is evaluated locally.
+//
+// Supported terms, separated by spaces or `AND` :
+//
+//	labels.<key>=<value>  label value equality
+//	labels.<key>:<value>  label value equality
+//	labels.<key>:*        label presence
+//	name:<value>          secret identifier contains value
+type filter struct {
+	terms []filterTerm
+}
+
+type filterTerm struct {
+	label string
+	name  bool
+	value string
+}
+
+func parseFilter(expr string) (*filter, error) {
+	f := &filter{}
+
+	for _, token := range strings.Fields(expr) {
+		if token == "AND" {
+			continue
+		}
+
+		// Split condition
+		idx := strings.IndexAny(token, "=:")
+		if idx <= 0 || idx == len(token)-1 {
+			return nil, fmt.Errorf("invalid term '%s'", token)
+		}
+		field, value := token[:idx], token[idx+1:]
+
+		switch {
+		case field == "name":
+			f.terms = append(f.terms, filterTerm{name: true, value: value})
+		case strings.HasPrefix(field, "labels.") && len(field) > len("labels."):
+			if value == "*" && token[idx] != ':' {
+				return nil, fmt.Errorf("invalid term '%s', use 'labels.<key>:*' for presence", token)
+			}
+			f.terms = append(f.terms, filterTerm{label: strings.TrimPrefix(field, "labels."), value: value})
+		default:
+			return nil, fmt.Errorf("unsupported field '%s'", field)
+		}
+	}
+
+	return f, nil
+}
+
+func (f *filter) match(id string, labels map[string]string) bool {
+	if f == nil {
+		return true
+	}
+
+	for _, t := range f.terms {
+		if t.name {
+			if !strings.Contains(id, t.value) {
+				return false
+			}
+			continue
+		}
+
+		v, ok := labels[t.label]
+		if !ok || (t.value != "*" && v != t.value) {
+			return false
+		}
+	}
+
+	return true
+}
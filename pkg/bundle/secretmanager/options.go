@@ -0,0 +1,102 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package secretmanager
+
+import (
+	"fmt"
+	"text/template"
+
+	"github.com/elastic/harp/pkg/bundle/mapping"
+)
+
+// DefaultPathTemplate maps secrets to the CSO application path convention.
+const DefaultPathTemplate = "app/{{ .Labels.env }}/{{ .Labels.platform }}/{{ .Labels.product }}/{{ .Labels.version }}/{{ .Labels.component }}/{{ .ID }}"
+
+type options struct {
+	filter       *filter
+	pathTemplate *template.Template
+	mapping      *mapping.Engine
+	allVersions  bool
+	pageSize     int32
+}
+
+// Option defines the functional pattern for importer settings.
+type Option func(*options) error
+
+// WithFilter registers a label filter expression applied to listed secrets.
+func WithFilter(value string) Option {
+	return func(opts *options) error {
+		f, err := parseFilter(value)
+		if err != nil {
+			return fmt.Errorf("unable to parse `%s` as a valid filter: %w", value, err)
+		}
+
+		opts.filter = f
+
+		// No error
+		return nil
+	}
+}
+
+// WithPathTemplate sets the package path template. The template is evaluated
+// with the secret `.ID`, `.Project` and `.Labels`.
+func WithPathTemplate(value string) Option {
+	return func(opts *options) error {
+		t, err := template.New("path").Option("missingkey=error").Parse(value)
+		if err != nil {
+			return fmt.Errorf("unable to parse `%s` as a valid path template: %w", value, err)
+		}
+
+		opts.pathTemplate = t
+
+		// No error
+		return nil
+	}
+}
+
+// WithAllVersions imports all enabled secret versions instead of the latest
+// one.
+func WithAllVersions(value bool) Option {
+	return func(opts *options) error {
+		opts.allVersions = value
+		// No error
+		return nil
+	}
+}
+
+// WithPageSize sets the listing page size.
+func WithPageSize(value int32) Option {
+	return func(opts *options) error {
+		if value < 0 {
+			return fmt.Errorf("page size must be positive")
+		}
+		opts.pageSize = value
+		// No error
+		return nil
+	}
+}
+
+// WithMapping sets the mapping engine used to compute package paths from the
+// secret identifier and labels. It takes precedence over the path template.
+func WithMapping(value *mapping.Engine) Option {
+	return func(opts *options) error {
+		opts.mapping = value
+		// No error
+		return nil
+	}
+}
@@ -0,0 +1,352 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package secretmanager provides GCP Secret Manager import features.
+package secretmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	sm "cloud.google.com/go/secretmanager/apiv1"
+	"go.uber.org/zap"
+	"google.golang.org/api/iterator"
+	smpb "google.golang.org/genproto/googleapis/cloud/secretmanager/v1"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle/mapping"
+	"github.com/elastic/harp/pkg/bundle/secret"
+	"github.com/elastic/harp/pkg/sdk/log"
+	vpath "github.com/elastic/harp/pkg/vault/path"
+)
+
+const (
+	// AnnotationPrefix prefixes package annotations describing the source
+	// secret.
+	AnnotationPrefix = "harp.elastic.co/v1/gcp-secretmanager#"
+	// NameAnnotation holds the secret resource name.
+	NameAnnotation = AnnotationPrefix + "name"
+	// ReplicationAnnotation holds the secret replication policy.
+	ReplicationAnnotation = AnnotationPrefix + "replication"
+	// LabelsAnnotation holds the secret labels as a JSON object.
+	LabelsAnnotation = AnnotationPrefix + "labels"
+	// CreateTimeAnnotation holds the secret creation time.
+	CreateTimeAnnotation = AnnotationPrefix + "createTime"
+
+	// ValueKey is the package key used for non JSON object payloads.
+	ValueKey = "value"
+)
+
+// Summary describes the import operation results.
+type Summary struct {
+	Listed   int
+	Imported int
+	Skipped  int
+	Warnings []string
+}
+
+func (s *Summary) warn(ctx context.Context, id, format string, args ...interface{}) {
+	msg := fmt.Sprintf("%s: %s", id, fmt.Sprintf(format, args...))
+	log.For(ctx).Warn("secret ignored", zap.String("secret", id), zap.String("reason", fmt.Sprintf(format, args...)))
+	s.Warnings = append(s.Warnings, msg)
+	s.Skipped++
+}
+
+// Pull secrets from the given GCP project as a bundle.
+func Pull(ctx context.Context, client *sm.Client, project string, opts ...Option) (*bundlev1.Bundle, *Summary, error) {
+	// Check arguments
+	if client == nil {
+		return nil, nil, errors.New("unable to pull secrets with a nil client")
+	}
+	if project == "" {
+		return nil, nil, errors.New("project must not be blank")
+	}
+
+	// Default options
+	defaultOpts := &options{}
+	if err := WithPathTemplate(DefaultPathTemplate)(defaultOpts); err != nil {
+		return nil, nil, err
+	}
+
+	// Apply option functions
+	for _, o := range opts {
+		if err := o(defaultOpts); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return runPull(ctx, client, project, defaultOpts)
+}
+
+// -----------------------------------------------------------------------------
+
+func runPull(ctx context.Context, client *sm.Client, project string, opts *options) (*bundlev1.Bundle, *Summary, error) {
+	var (
+		b       = &bundlev1.Bundle{}
+		summary = &Summary{Warnings: []string{}}
+		paths   = map[string]string{}
+	)
+
+	// Iterate over all pages
+	it := client.ListSecrets(ctx, &smpb.ListSecretsRequest{
+		Parent:   fmt.Sprintf("projects/%s", project),
+		PageSize: opts.pageSize,
+	})
+	for {
+		s, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to list secrets of project '%s': %w", project, err)
+		}
+		summary.Listed++
+
+		// Apply filter
+		id := path.Base(s.Name)
+		if !opts.filter.match(id, s.Labels) {
+			continue
+		}
+
+		// Compute package path
+		name, err := packagePath(opts, project, id, s.Labels)
+		if errors.Is(err, mapping.ErrUnmapped) {
+			return nil, nil, fmt.Errorf("unable to map secret '%s': %w", id, err)
+		}
+		if err != nil {
+			summary.warn(ctx, id, "unable to map package path: %v", err)
+			continue
+		}
+		if previous, ok := paths[name]; ok {
+			summary.warn(ctx, id, "package path '%s' already used by '%s'", name, previous)
+			continue
+		}
+
+		// Retrieve payloads
+		p, err := pullSecret(ctx, client, s, opts.allVersions)
+		if err != nil {
+			summary.warn(ctx, id, "%v", err)
+			continue
+		}
+		p.Name = name
+		paths[name] = id
+
+		b.Packages = append(b.Packages, p)
+		summary.Imported++
+	}
+
+	// No error
+	return b, summary, nil
+}
+
+func packagePath(opts *options, project, id string, labels map[string]string) (string, error) {
+	if labels == nil {
+		labels = map[string]string{}
+	}
+
+	// Delegate to mapping engine
+	if opts.mapping != nil {
+		name, err := opts.mapping.Path(id, labels)
+		if err != nil {
+			return "", err
+		}
+		return vpath.SanitizePath(name), nil
+	}
+
+	var buf bytes.Buffer
+	if err := opts.pathTemplate.Execute(&buf, map[string]interface{}{
+		"ID":      id,
+		"Project": project,
+		"Labels":  labels,
+	}); err != nil {
+		return "", err
+	}
+
+	// Reject incomplete paths
+	name := vpath.SanitizePath(buf.String())
+	for _, part := range strings.Split(buf.String(), "/") {
+		if strings.TrimSpace(part) == "" {
+			return "", fmt.Errorf("path '%s' has empty segments", buf.String())
+		}
+	}
+
+	return name, nil
+}
+
+func pullSecret(ctx context.Context, client *sm.Client, s *smpb.Secret, allVersions bool) (*bundlev1.Package, error) {
+	// Select versions
+	versions := []string{fmt.Sprintf("%s/versions/latest", s.Name)}
+	if allVersions {
+		var err error
+		if versions, err = enabledVersions(ctx, client, s.Name); err != nil {
+			return nil, err
+		}
+		if len(versions) == 0 {
+			return nil, errors.New("no enabled version")
+		}
+	}
+
+	// Access payloads
+	chains := []*bundlev1.SecretChain{}
+	for _, v := range versions {
+		resp, err := client.AccessSecretVersion(ctx, &smpb.AccessSecretVersionRequest{
+			Name: v,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to access secret version '%s': %w", path.Base(v), err)
+		}
+
+		chain, err := secretChain(resp)
+		if err != nil {
+			return nil, err
+		}
+		chains = append(chains, chain)
+	}
+
+	// Link versions, the latest one is the active chain
+	sort.SliceStable(chains, func(i, j int) bool {
+		return chains[i].Version < chains[j].Version
+	})
+	p := &bundlev1.Package{
+		Labels:      map[string]string{},
+		Annotations: annotations(s),
+		Secrets:     chains[len(chains)-1],
+	}
+	if len(chains) > 1 {
+		p.Versions = map[uint32]*bundlev1.SecretChain{}
+		for i, c := range chains {
+			if i > 0 {
+				c.PreviousVersion = wrapperspb.UInt32(chains[i-1].Version)
+			}
+			if i < len(chains)-1 {
+				c.NextVersion = wrapperspb.UInt32(chains[i+1].Version)
+				p.Versions[c.Version] = c
+			}
+		}
+	}
+
+	return p, nil
+}
+
+func enabledVersions(ctx context.Context, client *sm.Client, name string) ([]string, error) {
+	res := []string{}
+
+	it := client.ListSecretVersions(ctx, &smpb.ListSecretVersionsRequest{
+		Parent: name,
+	})
+	for {
+		v, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to list secret versions: %w", err)
+		}
+		if v.State != smpb.SecretVersion_ENABLED {
+			continue
+		}
+		res = append(res, v.Name)
+	}
+
+	return res, nil
+}
+
+func secretChain(resp *smpb.AccessSecretVersionResponse) (*bundlev1.SecretChain, error) {
+	// Extract version number from resource name
+	version, err := strconv.ParseUint(path.Base(resp.Name), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("unable to extract version from '%s': %w", resp.Name, err)
+	}
+
+	chain := &bundlev1.SecretChain{
+		Version: uint32(version),
+		Data:    []*bundlev1.KV{},
+	}
+
+	// Decode JSON objects as secret maps
+	values := map[string]interface{}{}
+	payload := resp.GetPayload().GetData()
+	if err := json.Unmarshal(payload, &values); err != nil || len(values) == 0 {
+		values = map[string]interface{}{ValueKey: string(payload)}
+	}
+
+	// Sort keys for stable output
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := values[k]
+
+		// Pack secret value
+		packed, err := secret.Pack(v)
+		if err != nil {
+			return nil, fmt.Errorf("unable to pack secret value for key '%s': %w", k, err)
+		}
+
+		chain.Data = append(chain.Data, &bundlev1.KV{
+			Key:   k,
+			Type:  fmt.Sprintf("%T", v),
+			Value: packed,
+		})
+	}
+
+	return chain, nil
+}
+
+func annotations(s *smpb.Secret) map[string]string {
+	res := map[string]string{
+		NameAnnotation:        s.Name,
+		ReplicationAnnotation: replication(s.Replication),
+	}
+	if s.CreateTime != nil {
+		res[CreateTimeAnnotation] = s.CreateTime.AsTime().UTC().Format(time.RFC3339)
+	}
+	if len(s.Labels) > 0 {
+		if raw, err := json.Marshal(s.Labels); err == nil {
+			res[LabelsAnnotation] = string(raw)
+		}
+	}
+
+	return res
+}
+
+func replication(r *smpb.Replication) string {
+	switch {
+	case r.GetAutomatic() != nil:
+		return "automatic"
+	case r.GetUserManaged() != nil:
+		locations := []string{}
+		for _, replica := range r.GetUserManaged().GetReplicas() {
+			locations = append(locations, replica.Location)
+		}
+		return fmt.Sprintf("user-managed:%s", strings.Join(locations, ","))
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,403 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package secretmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	sm "cloud.google.com/go/secretmanager/apiv1"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+	smpb "google.golang.org/genproto/googleapis/cloud/secretmanager/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle/mapping"
+	"github.com/elastic/harp/pkg/bundle/secret"
+)
+
+type fakeVersion struct {
+	payload string
+	state   smpb.SecretVersion_State
+}
+
+type fakeSecret struct {
+	secret   *smpb.Secret
+	versions []fakeVersion
+	denied   bool
+}
+
+// fakeServer implements a paginated in-memory Secret Manager.
+type fakeServer struct {
+	smpb.UnimplementedSecretManagerServiceServer
+
+	secrets  []*fakeSecret
+	pageSize int
+	pages    int
+}
+
+func (s *fakeServer) find(name string) (*fakeSecret, error) {
+	for _, fs := range s.secrets {
+		if fs.secret.Name == name {
+			if fs.denied {
+				return nil, status.Error(codes.PermissionDenied, "permission denied")
+			}
+			return fs, nil
+		}
+	}
+	return nil, status.Error(codes.NotFound, "secret not found")
+}
+
+func (s *fakeServer) ListSecrets(_ context.Context, req *smpb.ListSecretsRequest) (*smpb.ListSecretsResponse, error) {
+	if req.Parent != "projects/my-proj" {
+		return nil, status.Error(codes.NotFound, "project not found")
+	}
+	s.pages++
+
+	start := 0
+	if req.PageToken != "" {
+		start, _ = strconv.Atoi(req.PageToken)
+	}
+	end := start + s.pageSize
+	if end > len(s.secrets) {
+		end = len(s.secrets)
+	}
+
+	resp := &smpb.ListSecretsResponse{TotalSize: int32(len(s.secrets))}
+	for _, fs := range s.secrets[start:end] {
+		resp.Secrets = append(resp.Secrets, fs.secret)
+	}
+	if end < len(s.secrets) {
+		resp.NextPageToken = strconv.Itoa(end)
+	}
+
+	return resp, nil
+}
+
+func (s *fakeServer) ListSecretVersions(_ context.Context, req *smpb.ListSecretVersionsRequest) (*smpb.ListSecretVersionsResponse, error) {
+	fs, err := s.find(req.Parent)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &smpb.ListSecretVersionsResponse{}
+	for i := len(fs.versions) - 1; i >= 0; i-- {
+		resp.Versions = append(resp.Versions, &smpb.SecretVersion{
+			Name:  fmt.Sprintf("%s/versions/%d", req.Parent, i+1),
+			State: fs.versions[i].state,
+		})
+	}
+
+	return resp, nil
+}
+
+func (s *fakeServer) AccessSecretVersion(_ context.Context, req *smpb.AccessSecretVersionRequest) (*smpb.AccessSecretVersionResponse, error) {
+	idx := strings.LastIndex(req.Name, "/versions/")
+	fs, err := s.find(req.Name[:idx])
+	if err != nil {
+		return nil, err
+	}
+
+	version := len(fs.versions)
+	if v := req.Name[idx+len("/versions/"):]; v != "latest" {
+		version, _ = strconv.Atoi(v)
+	}
+	if version < 1 || version > len(fs.versions) || fs.versions[version-1].state != smpb.SecretVersion_ENABLED {
+		return nil, status.Error(codes.FailedPrecondition, "version is not enabled")
+	}
+
+	return &smpb.AccessSecretVersionResponse{
+		Name:    fmt.Sprintf("%s/versions/%d", req.Name[:idx], version),
+		Payload: &smpb.SecretPayload{Data: []byte(fs.versions[version-1].payload)},
+	}, nil
+}
+
+func newFakeClient(t *testing.T, srv *fakeServer) *sm.Client {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	smpb.RegisterSecretManagerServiceServer(s, srv)
+	go func() {
+		_ = s.Serve(lis)
+	}()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithInsecure(),
+	)
+	if err != nil {
+		t.Fatalf("unable to connect to fake server: %v", err)
+	}
+
+	client, err := sm.NewClient(context.Background(), option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatalf("unable to initialize client: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func fixture() *fakeServer {
+	labels := func(team, component string) map[string]string {
+		return map[string]string{
+			"team": team, "env": "production", "platform": "gcp", "product": "billing", "version": "v1", "component": component,
+		}
+	}
+	created := timestamppb.New(time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC))
+	enabled := func(payloads ...string) []fakeVersion {
+		res := []fakeVersion{}
+		for _, p := range payloads {
+			res = append(res, fakeVersion{payload: p, state: smpb.SecretVersion_ENABLED})
+		}
+		return res
+	}
+
+	return &fakeServer{
+		pageSize: 2,
+		secrets: []*fakeSecret{
+			{
+				secret: &smpb.Secret{
+					Name:       "projects/my-proj/secrets/database",
+					Labels:     labels("payments", "api"),
+					CreateTime: created,
+					Replication: &smpb.Replication{Replication: &smpb.Replication_Automatic_{
+						Automatic: &smpb.Replication_Automatic{},
+					}},
+				},
+				versions: []fakeVersion{
+					{payload: `{"user":"v1"}`, state: smpb.SecretVersion_ENABLED},
+					{payload: "destroyed", state: smpb.SecretVersion_DESTROYED},
+					{payload: `{"user":"harp","password":"foo"}`, state: smpb.SecretVersion_ENABLED},
+				},
+			},
+			{
+				secret: &smpb.Secret{
+					Name:   "projects/my-proj/secrets/api-token",
+					Labels: labels("payments", "gateway"),
+					Replication: &smpb.Replication{Replication: &smpb.Replication_UserManaged_{
+						UserManaged: &smpb.Replication_UserManaged{Replicas: []*smpb.Replication_UserManaged_Replica{
+							{Location: "us-east1"}, {Location: "europe-west1"},
+						}},
+					}},
+				},
+				versions: enabled("raw-token"),
+			},
+			{
+				secret:   &smpb.Secret{Name: "projects/my-proj/secrets/forbidden", Labels: labels("payments", "worker")},
+				versions: enabled("secret"),
+				denied:   true,
+			},
+			{
+				secret:   &smpb.Secret{Name: "projects/my-proj/secrets/unlabeled", Labels: map[string]string{"team": "payments"}},
+				versions: enabled("secret"),
+			},
+			{
+				secret:   &smpb.Secret{Name: "projects/my-proj/secrets/other-team", Labels: labels("identity", "api")},
+				versions: enabled("secret"),
+			},
+		},
+	}
+}
+
+func unpack(t *testing.T, c *bundlev1.SecretChain) map[string]interface{} {
+	t.Helper()
+
+	res := map[string]interface{}{}
+	for _, kv := range c.Data {
+		var v interface{}
+		if err := secret.Unpack(kv.Value, &v); err != nil {
+			t.Fatalf("unable to unpack '%s': %v", kv.Key, err)
+		}
+		res[kv.Key] = v
+	}
+	return res
+}
+
+func TestPull(t *testing.T) {
+	srv := fixture()
+	client := newFakeClient(t, srv)
+
+	b, summary, err := Pull(context.Background(), client, "my-proj", WithFilter("labels.team=payments"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Pagination
+	if srv.pages != 3 {
+		t.Errorf("expected 3 pages to be listed, got %d", srv.pages)
+	}
+	if summary.Listed != 5 || summary.Imported != 2 || summary.Skipped != 2 || len(summary.Warnings) != 2 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+
+	names := []string{}
+	for _, p := range b.Packages {
+		names = append(names, p.Name)
+	}
+	sort.Strings(names)
+	if diff := cmp.Diff(names, []string{
+		"app/production/gcp/billing/v1/api/database",
+		"app/production/gcp/billing/v1/gateway/api-token",
+	}); diff != "" {
+		t.Errorf("%q. Pull():\n-got/+want\ndiff %s", "names", diff)
+	}
+
+	// JSON payload and annotations
+	db := b.Packages[0]
+	if diff := cmp.Diff(unpack(t, db.Secrets), map[string]interface{}{"user": "harp", "password": "foo"}); diff != "" {
+		t.Errorf("%q. Pull():\n-got/+want\ndiff %s", "database", diff)
+	}
+	if db.Secrets.Version != 3 || len(db.Versions) != 0 {
+		t.Errorf("expected latest version only, got %d (%d versions)", db.Secrets.Version, len(db.Versions))
+	}
+	if diff := cmp.Diff(db.Annotations, map[string]string{
+		NameAnnotation:        "projects/my-proj/secrets/database",
+		ReplicationAnnotation: "automatic",
+		CreateTimeAnnotation:  "2021-01-02T03:04:05Z",
+		LabelsAnnotation:      `{"component":"api","env":"production","platform":"gcp","product":"billing","team":"payments","version":"v1"}`,
+	}); diff != "" {
+		t.Errorf("%q. Pull():\n-got/+want\ndiff %s", "annotations", diff)
+	}
+
+	// Raw payload
+	token := b.Packages[1]
+	if diff := cmp.Diff(unpack(t, token.Secrets), map[string]interface{}{ValueKey: "raw-token"}); diff != "" {
+		t.Errorf("%q. Pull():\n-got/+want\ndiff %s", "api-token", diff)
+	}
+	if got := token.Annotations[ReplicationAnnotation]; got != "user-managed:us-east1,europe-west1" {
+		t.Errorf("unexpected replication annotation %q", got)
+	}
+}
+
+func TestPull_AllVersions(t *testing.T) {
+	client := newFakeClient(t, fixture())
+
+	b, _, err := Pull(context.Background(), client, "my-proj", WithFilter("name:database"), WithAllVersions(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(b.Packages) != 1 {
+		t.Fatalf("expected 1 package, got %d", len(b.Packages))
+	}
+
+	p := b.Packages[0]
+	if p.Secrets.Version != 3 || p.Secrets.GetPreviousVersion().GetValue() != 1 {
+		t.Errorf("unexpected active chain %v", p.Secrets)
+	}
+	old, ok := p.Versions[1]
+	if !ok {
+		t.Fatal("expected version 1 to be imported")
+	}
+	if old.GetNextVersion().GetValue() != 3 {
+		t.Errorf("unexpected next version %v", old.NextVersion)
+	}
+	if diff := cmp.Diff(unpack(t, old), map[string]interface{}{"user": "v1"}); diff != "" {
+		t.Errorf("%q. Pull():\n-got/+want\ndiff %s", "v1", diff)
+	}
+}
+
+func TestPull_PathTemplate(t *testing.T) {
+	client := newFakeClient(t, fixture())
+
+	b, summary, err := Pull(context.Background(), client, "my-proj",
+		WithFilter("labels.team:*"),
+		WithPathTemplate("infra/{{ .Project }}/{{ .Labels.team }}/{{ .ID }}"),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary.Imported != 4 || summary.Skipped != 1 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	if b.Packages[2].Name != "infra/my-proj/payments/unlabeled" {
+		t.Errorf("unexpected package name %q", b.Packages[2].Name)
+	}
+}
+
+func TestPull_Errors(t *testing.T) {
+	client := newFakeClient(t, fixture())
+
+	testCases := []struct {
+		desc    string
+		project string
+		opts    []Option
+	}{
+		{desc: "blank project"},
+		{desc: "unknown project", project: "unknown"},
+		{desc: "invalid filter", project: "my-proj", opts: []Option{WithFilter("team=payments")}},
+		{desc: "invalid presence filter", project: "my-proj", opts: []Option{WithFilter("labels.team=*")}},
+		{desc: "invalid template", project: "my-proj", opts: []Option{WithPathTemplate("{{ .ID ")}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if _, _, err := Pull(context.Background(), client, tC.project, tC.opts...); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestPull_Mapping(t *testing.T) {
+	client := newFakeClient(t, fixture())
+
+	load := func(spec string) *mapping.Engine {
+		e, err := mapping.Load(strings.NewReader("apiVersion: harp.elastic.co/v1\nkind: MappingProfile\nspec:\n" + spec))
+		if err != nil {
+			t.Fatalf("unable to load profile: %v", err)
+		}
+		return e
+	}
+
+	e := load(`  rules:
+    - match:
+        glob: "*"
+        metadata:
+          component: "*"
+      target: "services/{{ .Metadata.component }}/{{ .Name }}"
+`)
+	b, summary, err := Pull(context.Background(), client, "my-proj", WithFilter("labels.team=payments"), WithMapping(e))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary.Imported != 3 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	if b.Packages[0].Name != "services/api/database" || b.Packages[2].Name != "unlabeled" {
+		t.Errorf("unexpected package names %s, %s", b.Packages[0].Name, b.Packages[2].Name)
+	}
+
+	// Strict mode fails unmapped secrets
+	if _, _, err := Pull(context.Background(), client, "my-proj", WithFilter("labels.team=payments"), WithMapping(load("  strict: true\n  rules: []\n"))); !errors.Is(err, mapping.ErrUnmapped) {
+		t.Errorf("expected ErrUnmapped, got %v", err)
+	}
+}
@@ -0,0 +1,260 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package selector
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jmespath/go-jmespath"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// JMESPath AST node types, in go-jmespath declaration order.
+const (
+	astEmpty int64 = iota
+	astComparator
+	astCurrentNode
+	astExpRef
+	astFunctionExpression
+	astField
+	astFilterProjection
+	astFlatten
+	astIdentity
+	astIndex
+	astIndexExpression
+	astKeyValPair
+	astLiteral
+	astMultiSelectHash
+	astMultiSelectList
+	astOrExpression
+	astAndExpression
+	astNotExpression
+	astPipe
+	astProjection
+	astSubexpression
+	astSlice
+	astValueProjection
+)
+
+// projection describes the object fields accessed by a JMESPath expression.
+// A whole projection requires the complete field value.
+type projection struct {
+	whole  bool
+	fields map[string]*projection
+}
+
+// jmesPathProjection inspects the compiled expression to build the minimal
+// object projection required for its evaluation. It returns nil when the
+// complete object is required, or when the expression can't be inspected.
+func jmesPathProjection(exp *jmespath.JMESPath) (res *projection) {
+	// Check arguments
+	if exp == nil {
+		return nil
+	}
+
+	// The AST is not exposed by go-jmespath, any unexpected structure
+	// disables the optimization.
+	defer func() {
+		if r := recover(); r != nil {
+			res = nil
+		}
+	}()
+
+	ast := reflect.ValueOf(exp).Elem().FieldByName("ast")
+	if !isASTNode(ast) {
+		return nil
+	}
+
+	p := &projection{fields: map[string]*projection{}}
+	if !p.collect(ast) {
+		return nil
+	}
+
+	return p
+}
+
+// -----------------------------------------------------------------------------
+
+func isASTNode(n reflect.Value) bool {
+	if !n.IsValid() || n.Kind() != reflect.Struct {
+		return false
+	}
+
+	nodeType := n.FieldByName("nodeType")
+	value := n.FieldByName("value")
+	children := n.FieldByName("children")
+
+	return nodeType.IsValid() && nodeType.Kind() == reflect.Int &&
+		value.IsValid() && value.Kind() == reflect.Interface &&
+		children.IsValid() && children.Kind() == reflect.Slice && children.Type().Elem() == n.Type()
+}
+
+func nodeChildren(n reflect.Value) []reflect.Value {
+	children := n.FieldByName("children")
+
+	res := make([]reflect.Value, children.Len())
+	for i := range res {
+		res[i] = children.Index(i)
+	}
+
+	return res
+}
+
+// fieldPath returns the field path of a field or field chain node.
+func fieldPath(n reflect.Value) ([]string, bool) {
+	switch n.FieldByName("nodeType").Int() {
+	case astField:
+		value := n.FieldByName("value")
+		if value.IsNil() || value.Elem().Kind() != reflect.String {
+			return nil, false
+		}
+		return []string{value.Elem().String()}, true
+	case astSubexpression:
+		children := nodeChildren(n)
+		if len(children) != 2 {
+			return nil, false
+		}
+		left, ok := fieldPath(children[0])
+		if !ok {
+			return nil, false
+		}
+		right, ok := fieldPath(children[1])
+		if !ok {
+			return nil, false
+		}
+		return append(left, right...), true
+	default:
+	}
+
+	return nil, false
+}
+
+// collect registers fields accessed by the node evaluated against the
+// current object. It returns false if the complete object is required.
+func (p *projection) collect(n reflect.Value) bool {
+	// Field chains are resolved directly
+	if path, ok := fieldPath(n); ok {
+		p.add(path)
+		return true
+	}
+
+	children := nodeChildren(n)
+	switch n.FieldByName("nodeType").Int() {
+	case astEmpty, astLiteral, astExpRef:
+		// Expression references are evaluated against function arguments.
+		return true
+	case astSubexpression, astIndexExpression, astPipe, astProjection, astFilterProjection, astValueProjection, astFlatten:
+		// Right hand side is evaluated against the left hand side result.
+		if len(children) == 0 {
+			return false
+		}
+		return p.collect(children[0])
+	case astComparator, astOrExpression, astAndExpression, astNotExpression, astMultiSelectList, astMultiSelectHash, astKeyValPair, astFunctionExpression:
+		for _, c := range children {
+			if !p.collect(c) {
+				return false
+			}
+		}
+		return true
+	default:
+	}
+
+	// Current node, identity, index and slices use the complete object.
+	return false
+}
+
+func (p *projection) add(path []string) {
+	node := p
+	for _, name := range path {
+		if node.whole {
+			return
+		}
+		child, ok := node.fields[name]
+		if !ok {
+			child = &projection{fields: map[string]*projection{}}
+			node.fields[name] = child
+		}
+		node = child
+	}
+
+	// Complete value required
+	node.whole = true
+	node.fields = nil
+}
+
+// object returns the JSON object representation of the projected message
+// fields, as produced by protojson.
+func (p *projection) object(m protoreflect.Message) (map[string]interface{}, error) {
+	res := map[string]interface{}{}
+
+	fields := m.Descriptor().Fields()
+	for name, sub := range p.fields {
+		fd := fields.ByJSONName(name)
+		if fd == nil || !m.Has(fd) {
+			continue
+		}
+
+		v, err := sub.value(m, fd)
+		if err != nil {
+			return nil, err
+		}
+		res[name] = v
+	}
+
+	return res, nil
+}
+
+func (p *projection) value(m protoreflect.Message, fd protoreflect.FieldDescriptor) (interface{}, error) {
+	switch {
+	case fd.Kind() == protoreflect.StringKind && fd.Cardinality() != protoreflect.Repeated:
+		return m.Get(fd).String(), nil
+	case fd.IsMap() && fd.MapKey().Kind() == protoreflect.StringKind && fd.MapValue().Kind() == protoreflect.StringKind:
+		res := map[string]interface{}{}
+		m.Get(fd).Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+			res[k.String()] = v.String()
+			return true
+		})
+		return res, nil
+	case !p.whole && fd.Message() != nil && !fd.IsList() && !fd.IsMap() && !strings.HasPrefix(string(fd.Message().FullName()), "google.protobuf."):
+		return p.object(m.Get(fd).Message())
+	default:
+	}
+
+	// Encode the single field message
+	single := m.New()
+	single.Set(fd, m.Get(fd))
+	raw, err := protojson.Marshal(single.Interface())
+	if err != nil {
+		return nil, err
+	}
+
+	var obj map[string]interface{}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, err
+	}
+	v, ok := obj[fd.JSONName()]
+	if !ok {
+		return nil, fmt.Errorf("unable to encode field '%s'", fd.JSONName())
+	}
+
+	return v, nil
+}
@@ -0,0 +1,222 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package selector
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/jmespath/go-jmespath"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+)
+
+// Object wildcards and keys() return values in map iteration order, their
+// results are compared unordered.
+var projectionExpressions = []string{
+	"name",
+	"name=='app/production/db'",
+	"starts_with(name, 'app/')",
+	"contains(name, 'production') && labels.team=='security'",
+	"annotations.\"harp.elastic.co/v1/owner\"",
+	"annotations.patched=='true' || !labels.team",
+	"labels",
+	"keys(labels)",
+	"length(annotations) > `0`",
+	"{n: name, t: labels.team}",
+	"[name, labels.team]",
+	"secrets.version",
+	"secrets.labels.kind",
+	"secrets.previousVersion",
+	"secrets.locked",
+	"secrets.data[?key=='password'].type",
+	"length(secrets.data)",
+	"secrets.data[0].key",
+	"secrets.data[*].key | sort(@)",
+	"contains(secrets.data[*].key, 'password')",
+	"sort_by(secrets.data, &key)[0].key",
+	"max_by(secrets.data, &key).key",
+	"secrets.data.key",
+	"secrets",
+	"versions",
+	"@",
+	"keys(@)",
+	"length(@)",
+	"*",
+	"[name][0]",
+	"to_string(@)",
+	"type(secrets)",
+	"secrets.*",
+	"labels.*",
+	"not_null(labels.missing, name)",
+	"missing.field",
+	"`true`",
+}
+
+func projectionPackages() []*bundlev1.Package {
+	res := testbundle.New().
+		Package("app/production/db").
+		Label("team", "security").
+		Annotation("patched", "true").
+		Annotation("harp.elastic.co/v1/owner", "cloud-security").
+		Secret("user", "admin").
+		Secret("password", "secret").
+		Package("app/staging/cache").
+		Secret("token", 12).
+		Build().Packages
+
+	// Complete secret chain
+	res = append(res, &bundlev1.Package{
+		Name:   "infra/aws/account-1/vault",
+		Labels: map[string]string{"team": "platform"},
+		Secrets: &bundlev1.SecretChain{
+			Version:         3,
+			Labels:          map[string]string{"kind": "credentials"},
+			PreviousVersion: wrapperspb.UInt32(2),
+			Locked:          wrapperspb.Bytes([]byte("locked")),
+		},
+		Versions: map[uint32]*bundlev1.SecretChain{
+			2: {Version: 2, Data: []*bundlev1.KV{{Key: "k", Type: "string", Value: []byte("v")}}},
+		},
+	})
+
+	// Empty packages
+	res = append(res, &bundlev1.Package{}, &bundlev1.Package{Secrets: &bundlev1.SecretChain{}})
+
+	// Random packages
+	res = append(res, testbundle.RandomPackages(20, 1).Packages...)
+
+	return res
+}
+
+func TestJMESPathProjection_Differential(t *testing.T) {
+	packages := projectionPackages()
+
+	for _, expr := range projectionExpressions {
+		exp := jmespath.MustCompile(expr)
+		proj := jmesPathProjection(exp)
+
+		for i, p := range packages {
+			t.Run(fmt.Sprintf("%s/%d", expr, i), func(t *testing.T) {
+				full, err := fullObject(p)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				want, errWant := exp.Search(full)
+
+				object := full
+				if proj != nil {
+					object, err = proj.object(p.ProtoReflect())
+					if err != nil {
+						t.Fatalf("unexpected error: %v", err)
+					}
+				}
+				got, errGot := exp.Search(object)
+
+				if (errGot != nil) != (errWant != nil) {
+					t.Fatalf("error mismatch: got %v, want %v", errGot, errWant)
+				}
+				opts := []cmp.Option{}
+				if unorderedExpression(expr) {
+					opts = append(opts, cmpopts.SortSlices(func(a, b interface{}) bool {
+						return fmt.Sprint(a) < fmt.Sprint(b)
+					}))
+				}
+				if diff := cmp.Diff(got, want, opts...); diff != "" {
+					t.Errorf("%q. Search():\n-got/+want\ndiff %s", expr, diff)
+				}
+			})
+		}
+	}
+}
+
+func TestJMESPathProjection(t *testing.T) {
+	testCases := []struct {
+		expr string
+		want *projection
+	}{
+		{
+			expr: "name=='foo' && labels.team=='security'",
+			want: &projection{fields: map[string]*projection{
+				"name":   {whole: true},
+				"labels": {fields: map[string]*projection{"team": {whole: true}}},
+			}},
+		},
+		{
+			expr: "secrets.version > `1`",
+			want: &projection{fields: map[string]*projection{
+				"secrets": {fields: map[string]*projection{"version": {whole: true}}},
+			}},
+		},
+		{
+			expr: "secrets.data[?key=='password']",
+			want: &projection{fields: map[string]*projection{
+				"secrets": {fields: map[string]*projection{"data": {whole: true}}},
+			}},
+		},
+		{
+			expr: "labels.team || labels",
+			want: &projection{fields: map[string]*projection{
+				"labels": {whole: true},
+			}},
+		},
+		{expr: "@"},
+		{expr: "keys(@)"},
+		{expr: "*"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.expr, func(t *testing.T) {
+			got := jmesPathProjection(jmespath.MustCompile(tC.expr))
+			if diff := cmp.Diff(got, tC.want, cmp.AllowUnexported(projection{})); diff != "" {
+				t.Errorf("%q. jmesPathProjection():\n-got/+want\ndiff %s", tC.expr, diff)
+			}
+		})
+	}
+}
+
+func unorderedExpression(expr string) bool {
+	return expr == "*" || strings.Contains(expr, ".*") || strings.Contains(expr, "keys(")
+}
+
+func BenchmarkJMESPathMatcher(b *testing.B) {
+	packages := testbundle.RandomPackages(100000, 1).Packages
+	exp := jmespath.MustCompile("starts_with(name, 'app/production/')")
+
+	benchmarks := []struct {
+		name string
+		spec Specification
+	}{
+		{name: "full", spec: &jmesPathMatcher{exp: exp}},
+		{name: "projection", spec: MatchJMESPath(exp)},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				for _, p := range packages {
+					bm.spec.IsSatisfiedBy(p)
+				}
+			}
+		})
+	}
+}
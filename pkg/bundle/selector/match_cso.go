@@ -0,0 +1,124 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package selector
+
+import (
+	"fmt"
+	"strings"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	csov1 "github.com/elastic/harp/pkg/cso/v1"
+)
+
+// MatchCSO returns a CSO path matcher specification. Pattern segments are
+// matched against package path prefix segments, `*` matches any segment and
+// the version segment accepts version ranges (`~1.2`, `1.x`).
+//
+//	MatchCSO("app/production/*/ece/~1.2")
+func MatchCSO(pattern string) (Specification, error) {
+	// Check arguments
+	parts := strings.Split(csov1.Clean(pattern), "/")
+	if len(parts) == 0 || parts[0] == "" {
+		return nil, fmt.Errorf("unable to build a matcher from a blank pattern")
+	}
+
+	s := &matchCSO{
+		parts:        parts,
+		versionIndex: -1,
+	}
+
+	// Prepare version matcher
+	if idx, ok := versionIndex(parts); ok && parts[idx] != "*" {
+		s.versionIndex = idx
+		if csov1.IsVersionRange(parts[idx]) {
+			r, err := csov1.ParseVersionRange(parts[idx])
+			if err != nil {
+				return nil, err
+			}
+			s.versionRange = r
+		} else {
+			v, err := csov1.NormalizeVersion(parts[idx])
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern version '%s': %w", parts[idx], err)
+			}
+			s.version = v
+		}
+	}
+
+	// No error
+	return s, nil
+}
+
+type matchCSO struct {
+	parts        []string
+	versionIndex int
+	version      string
+	versionRange *csov1.VersionRange
+}
+
+// IsSatisfiedBy returns specification satisfaction status
+func (s *matchCSO) IsSatisfiedBy(object interface{}) bool {
+	// If object is a package
+	p, ok := object.(*bundlev1.Package)
+	if !ok {
+		return false
+	}
+
+	parts := strings.Split(csov1.Clean(p.Name), "/")
+	if len(parts) < len(s.parts) {
+		return false
+	}
+
+	for i, expected := range s.parts {
+		switch {
+		case i == s.versionIndex:
+			if !s.matchVersion(parts[i]) {
+				return false
+			}
+		case expected == "*":
+		case expected != parts[i]:
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *matchCSO) matchVersion(value string) bool {
+	if s.versionRange != nil {
+		return s.versionRange.Contains(value)
+	}
+
+	v, err := csov1.NormalizeVersion(value)
+	if err != nil {
+		return false
+	}
+
+	return v == s.version
+}
+
+// versionIndex returns the version segment position according to the ring.
+func versionIndex(parts []string) (int, bool) {
+	switch {
+	case len(parts) > 2 && parts[0] == "product":
+		return 2, true
+	case len(parts) > 4 && parts[0] == "app":
+		return 4, true
+	}
+	return 0, false
+}
@@ -0,0 +1,63 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package selector
+
+import (
+	"testing"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+)
+
+func Test_matchCSO_IsSatisfiedBy(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		pattern string
+		name    string
+		want    bool
+	}{
+		{desc: "exact", pattern: "app/production/customer1/ece/v1.0.0/adminconsole", name: "app/production/customer1/ece/v1.0.0/adminconsole/database", want: true},
+		{desc: "normalized version", pattern: "app/production/customer1/ece/1.0.0", name: "app/production/customer1/ece/v1.0.0/adminconsole/database", want: true},
+		{desc: "tilde range", pattern: "app/production/customer1/ece/~1.2", name: "app/production/customer1/ece/v1.2.7/adminconsole/database", want: true},
+		{desc: "tilde range mismatch", pattern: "app/production/customer1/ece/~1.2", name: "app/production/customer1/ece/v1.3.0/adminconsole/database", want: false},
+		{desc: "wildcard range", pattern: "app/production/*/ece/1.x", name: "app/production/customer2/ece/v1.9.0+build.3/adminconsole/database", want: true},
+		{desc: "wildcard segment", pattern: "app/*/customer1", name: "app/staging/customer1/ece/v1.0.0/adminconsole/database", want: true},
+		{desc: "segment mismatch", pattern: "app/staging/customer1", name: "app/production/customer1/ece/v1.0.0/adminconsole/database", want: false},
+		{desc: "product range", pattern: "product/ece/~2.0", name: "product/ece/v2.0.5/server/key", want: true},
+		{desc: "invalid path version", pattern: "product/ece/~2.0", name: "product/ece/latest/server/key", want: false},
+		{desc: "too short", pattern: "app/production/customer1/ece/~1.2/adminconsole", name: "app/production/customer1/ece/v1.2.0", want: false},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			s, err := MatchCSO(tC.pattern)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := s.IsSatisfiedBy(&bundlev1.Package{Name: tC.name}); got != tC.want {
+				t.Errorf("IsSatisfiedBy() = %v, want %v", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestMatchCSO_Invalid(t *testing.T) {
+	for _, pattern := range []string{"", "app/production/customer1/ece/~1", "product/ece/latest"} {
+		if _, err := MatchCSO(pattern); err == nil {
+			t.Errorf("MatchCSO(%q) error expected", pattern)
+		}
+	}
+}
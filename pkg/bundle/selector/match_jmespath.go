@@ -22,19 +22,25 @@ import (
 
 	"github.com/jmespath/go-jmespath"
 	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 
 	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
 )
 
 // MatchJMESPath returns a JMESPatch package matcher specification.
+//
+// Packages are evaluated using a projection limited to the fields referenced
+// by the expression, so that secret data is only encoded when accessed.
 func MatchJMESPath(exp *jmespath.JMESPath) Specification {
 	return &jmesPathMatcher{
-		exp: exp,
+		exp:  exp,
+		proj: jmesPathProjection(exp),
 	}
 }
 
 type jmesPathMatcher struct {
-	exp *jmespath.JMESPath
+	exp  *jmespath.JMESPath
+	proj *projection
 }
 
 // IsSatisfiedBy returns specification satisfaction status
@@ -46,17 +52,12 @@ func (s *jmesPathMatcher) IsSatisfiedBy(object interface{}) bool {
 			return false
 		}
 
-		// Rencode as json
-		jsonRaw, err := protojson.Marshal(p)
+		// Convert the package
+		object, err := s.object(p)
 		if err != nil {
 			return false
 		}
 
-		var object map[string]interface{}
-		if errJSON := json.Unmarshal(jsonRaw, &object); errJSON != nil {
-			return false
-		}
-
 		// Check if query match results
 		res, err := s.exp.Search(object)
 		if err != nil {
@@ -71,3 +72,29 @@ func (s *jmesPathMatcher) IsSatisfiedBy(object interface{}) bool {
 
 	return false
 }
+
+// -----------------------------------------------------------------------------
+
+func (s *jmesPathMatcher) object(p *bundlev1.Package) (map[string]interface{}, error) {
+	// Use field projection if possible
+	if s.proj != nil {
+		return s.proj.object(p.ProtoReflect())
+	}
+
+	return fullObject(p)
+}
+
+func fullObject(m proto.Message) (map[string]interface{}, error) {
+	// Rencode as json
+	jsonRaw, err := protojson.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	var object map[string]interface{}
+	if err := json.Unmarshal(jsonRaw, &object); err != nil {
+		return nil, err
+	}
+
+	return object, nil
+}
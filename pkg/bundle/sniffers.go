@@ -0,0 +1,132 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/url"
+	"strings"
+
+	"github.com/elastic/harp/pkg/sdk/security/crypto"
+)
+
+// minBase64Length prevents short words to be detected as base64 blobs.
+const minBase64Length = 16
+
+func sniffPEMCertificate(raw []byte) *TypedValue {
+	rest := bytes.TrimSpace(raw)
+	certs := []*x509.Certificate{}
+	invalid := false
+	for len(rest) > 0 {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil || block.Type != "CERTIFICATE" {
+			return nil
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			invalid = true
+			continue
+		}
+		certs = append(certs, cert)
+		rest = bytes.TrimSpace(rest)
+	}
+	if len(certs) == 0 && !invalid {
+		return nil
+	}
+
+	// PEM armor without a parseable certificate
+	confidence := 1.0
+	if invalid {
+		confidence = 0.7
+	}
+
+	return &TypedValue{Kind: KindPEMCertificate, Confidence: confidence, Certificates: certs}
+}
+
+func sniffPEMPrivateKey(raw []byte) *TypedValue {
+	block, rest := pem.Decode(bytes.TrimSpace(raw))
+	if block == nil || !strings.HasSuffix(block.Type, "PRIVATE KEY") || len(bytes.TrimSpace(rest)) > 0 {
+		return nil
+	}
+
+	// Encrypted or unsupported keys are still private keys
+	key, err := crypto.ParsePrivateKey(pem.EncodeToMemory(block))
+	if err != nil {
+		return &TypedValue{Kind: KindPEMPrivateKey, Confidence: 0.8}
+	}
+
+	return &TypedValue{Kind: KindPEMPrivateKey, Confidence: 1, PrivateKey: key}
+}
+
+func sniffJSON(raw []byte) *TypedValue {
+	// Only objects and arrays, scalars are ambiguous
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) < 2 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(trimmed))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil || dec.More() {
+		return nil
+	}
+
+	return &TypedValue{Kind: KindJSON, Confidence: 0.95, JSON: doc}
+}
+
+func sniffURL(raw []byte) *TypedValue {
+	value := string(raw)
+	if value == "" || strings.ContainsAny(value, " \t\r\n") {
+		return nil
+	}
+
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil
+	}
+
+	return &TypedValue{Kind: KindURL, Confidence: 0.9, URL: u}
+}
+
+func sniffBase64(raw []byte) *TypedValue {
+	if len(raw) < minBase64Length || len(raw)%4 != 0 {
+		return nil
+	}
+
+	blob, err := base64.StdEncoding.DecodeString(string(raw))
+	if err != nil {
+		return nil
+	}
+
+	// Alphabetic words are valid base64, require base64 specific features
+	confidence := 0.5
+	if bytes.ContainsAny(raw, "+/=") {
+		confidence += 0.2
+	}
+	if bytes.ContainsAny(raw, "0123456789") && bytes.ContainsAny(raw, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") && bytes.ContainsAny(raw, "abcdefghijklmnopqrstuvwxyz") {
+		confidence += 0.2
+	}
+
+	return &TypedValue{Kind: KindBase64Blob, Confidence: confidence, Blob: blob}
+}
@@ -0,0 +1,258 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package dryrun reports what a BundleTemplate would produce without
+// generating any secret value.
+package dryrun
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle/secret"
+	"github.com/elastic/harp/pkg/bundle/template"
+	"github.com/elastic/harp/pkg/bundle/template/visitor/secretbuilder"
+	"github.com/elastic/harp/pkg/template/analysis"
+	"github.com/elastic/harp/pkg/template/engine"
+)
+
+const (
+	// SourceLiteral is used for values written in the template.
+	SourceLiteral = "literal"
+	// SourceValue is used for values resolved from template values.
+	SourceValue = "value"
+	// SourceGenerator is used for values minted by a generator function.
+	SourceGenerator = "generator"
+)
+
+// Report holds the dry-run result.
+type Report struct {
+	Packages []Package `json:"packages"`
+	Problems []string  `json:"problems,omitempty"`
+}
+
+// Package describes a package that would be produced.
+type Package struct {
+	Path         string      `json:"path"`
+	Secrets      []Secret    `json:"secrets"`
+	Unattributed []Generator `json:"unattributed,omitempty"`
+}
+
+// Secret describes a secret key and its value source.
+type Secret struct {
+	Key        string      `json:"key"`
+	Source     string      `json:"source"`
+	Generators []Generator `json:"generators,omitempty"`
+	Values     []string    `json:"values,omitempty"`
+}
+
+// Generator describes a generator function invocation.
+type Generator struct {
+	Name   string   `json:"name"`
+	Params []string `json:"params,omitempty"`
+}
+
+// String returns the generator invocation in template syntax.
+func (g Generator) String() string {
+	return strings.TrimSpace(fmt.Sprintf("%s %s", g.Name, strings.Join(g.Params, " ")))
+}
+
+// Analyze executes the given template with generator functions replaced by
+// placeholders, and classifies the source of each produced secret value.
+// Execution problems, such as CSO path violations, are reported instead of
+// being raised.
+func Analyze(spec *bundlev1.Template, templateContext engine.Context) (*Report, error) {
+	// Check arguments
+	if err := template.Validate(spec); err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		Packages: []Package{},
+	}
+
+	// Execute the template without generating values
+	dr := &engine.DryRun{}
+	b := &bundlev1.Bundle{
+		Template: spec,
+	}
+	v := secretbuilder.New(b, engine.DryRunning(templateContext, dr))
+	if err := template.Execute(spec, v); err != nil {
+		report.Problems = append(report.Problems, err.Error())
+	}
+
+	// Index invocations by package
+	calls := dr.Calls()
+	byScope := map[string][]int{}
+	for i, c := range calls {
+		byScope[c.Scope] = append(byScope[c.Scope], i)
+	}
+
+	for _, p := range b.Packages {
+		pkg, err := analyzePackage(templateContext, dr, calls, byScope[p.Name], p)
+		if err != nil {
+			report.Problems = append(report.Problems, fmt.Sprintf("%s: %v", p.Name, err))
+		}
+		report.Packages = append(report.Packages, pkg)
+	}
+
+	// No error
+	return report, nil
+}
+
+// WriteText writes the report as a human readable table.
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PATH\tKEY\tSOURCE\tDETAILS")
+	for _, p := range r.Packages {
+		for _, s := range p.Secrets {
+			details := []string{}
+			for _, g := range s.Generators {
+				details = append(details, g.String())
+			}
+			details = append(details, s.Values...)
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", p.Path, s.Key, s.Source, strings.Join(details, ", "))
+		}
+		for _, g := range p.Unattributed {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", p.Path, "-", SourceGenerator, g.String())
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, problem := range r.Problems {
+		fmt.Fprintf(w, "PROBLEM: %s\n", problem)
+	}
+
+	return nil
+}
+
+// WriteJSON writes the report as JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// -----------------------------------------------------------------------------
+
+func analyzePackage(templateContext engine.Context, dr *engine.DryRun, calls []engine.GeneratorCall, scoped []int, p *bundlev1.Package) (Package, error) {
+	res := Package{
+		Path:    p.Name,
+		Secrets: []Secret{},
+	}
+	if p.Secrets == nil {
+		return res, nil
+	}
+
+	// Inspect the secret template
+	static := &analysis.Report{}
+	if tmpl := p.Secrets.Annotations["template"]; tmpl != "" {
+		var err error
+		static, err = analysis.Inspect(templateContext, tmpl)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	attributed := map[int]bool{}
+	staticGenerators := map[string]bool{}
+	for _, kv := range p.Secrets.Data {
+		// Unpack rendered value
+		var out interface{}
+		if err := secret.Unpack(kv.Value, &out); err != nil {
+			return res, fmt.Errorf("unable to unpack '%s' value: %w", kv.Key, err)
+		}
+		value, ok := out.(string)
+		if !ok {
+			raw, err := json.Marshal(out)
+			if err != nil {
+				return res, fmt.Errorf("unable to encode '%s' value: %w", kv.Key, err)
+			}
+			value = string(raw)
+		}
+
+		s := Secret{
+			Key:    kv.Key,
+			Source: SourceLiteral,
+		}
+
+		// Invocations whose placeholder has been rendered as is
+		for _, idx := range dr.Placeholders(value) {
+			if idx >= len(calls) || attributed[idx] {
+				continue
+			}
+			attributed[idx] = true
+			s.Generators = append(s.Generators, fromCall(calls[idx]))
+		}
+
+		// Invocations found in the template actions rendering the key
+		for _, a := range static.ForKey(kv.Key) {
+			for _, c := range a.Calls {
+				if engine.IsGenerator(c.Name) && len(s.Generators) == 0 {
+					s.Generators = append(s.Generators, Generator{Name: c.Name, Params: c.Args})
+					staticGenerators[c.Name] = true
+				}
+			}
+			s.Values = append(s.Values, a.Values...)
+		}
+
+		// Classify the source
+		switch {
+		case len(s.Generators) > 0:
+			s.Source = SourceGenerator
+		case len(s.Values) > 0:
+			s.Source = SourceValue
+		default:
+		}
+
+		res.Secrets = append(res.Secrets, s)
+	}
+
+	// Keep stable output
+	sort.SliceStable(res.Secrets, func(i, j int) bool {
+		return res.Secrets[i].Key < res.Secrets[j].Key
+	})
+
+	// Report invocations not attributed to a key
+	for _, idx := range scoped {
+		if attributed[idx] || staticGenerators[calls[idx].Name] {
+			continue
+		}
+		res.Unattributed = append(res.Unattributed, fromCall(calls[idx]))
+	}
+
+	return res, nil
+}
+
+func fromCall(c engine.GeneratorCall) Generator {
+	g := Generator{Name: c.Name}
+	for _, arg := range c.Args {
+		raw, err := json.Marshal(arg)
+		if err != nil {
+			raw = []byte(fmt.Sprintf("%v", arg))
+		}
+		g.Params = append(g.Params, string(raw))
+	}
+	return g
+}
@@ -0,0 +1,155 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package dryrun
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/elastic/harp/pkg/bundle/template"
+	"github.com/elastic/harp/pkg/template/engine"
+)
+
+const specHeader = `apiVersion: harp.elastic.co/v1
+kind: BundleTemplate
+meta:
+  name: "dry-run"
+  owner: security@elastic.co
+  description: "Dry-run test"
+spec:
+  namespaces:
+    infrastructure:
+    - provider: "aws"
+      account: "{{ .Values.account }}"
+      description: "AWS Account"
+      regions:
+      - name: "us-east-1"
+        services:
+        - type: "rds"
+          name: "adminconsole"
+          description: "Database"
+          secrets:
+`
+
+func TestAnalyze(t *testing.T) {
+	values := engine.Values{
+		"account":  "123456789",
+		"user":     "dbroot",
+		"replicas": []interface{}{"a", "b"},
+	}
+
+	testCases := []struct {
+		desc        string
+		secrets     string
+		want        *Report
+		wantProblem string
+	}{
+		{
+			desc: "loops and includes",
+			secrets: `          - suffix: "accounts/root_credentials"
+            description: "Root account"
+            template: |-
+              {{- define "password" }}{{ paranoidPassword | b64enc }}{{ end -}}
+              {
+                "user": "{{ .Values.user }}",
+                "password": "{{ template "password" }}",
+                "port": "5432",
+                {{- range $name := .Values.replicas }}
+                "replica_{{ $name }}": "{{ randAlphaNum 8 }}",
+                {{- end }}
+                "token": "{{ generate "harp.bytes" (dict "size" 16) }}"
+              }
+`,
+			want: &Report{
+				Packages: []Package{
+					{
+						Path: "infra/aws/123456789/us-east-1/rds/adminconsole/accounts/root_credentials",
+						Secrets: []Secret{
+							{Key: "password", Source: SourceGenerator, Generators: []Generator{{Name: "paranoidPassword"}}},
+							{Key: "port", Source: SourceLiteral},
+							{Key: "replica_a", Source: SourceGenerator, Generators: []Generator{{Name: "randAlphaNum", Params: []string{"8"}}}},
+							{Key: "replica_b", Source: SourceGenerator, Generators: []Generator{{Name: "randAlphaNum", Params: []string{"8"}}}},
+							{Key: "token", Source: SourceGenerator, Generators: []Generator{{Name: "generate", Params: []string{`"harp.bytes"`, `{"size":16}`}}}},
+							{Key: "user", Source: SourceValue, Values: []string{".Values.user"}},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "cso violation",
+			secrets: `          - suffix: "accounts/root_credentials"
+            template: |-
+              {"user": "{{ .Values.user }}"}
+      - name: "mars-north-1"
+        services:
+        - type: "rds"
+          name: "adminconsole"
+          secrets:
+          - suffix: "accounts/root_credentials"
+            template: |-
+              {"password": "{{ strongPassword }}"}
+`,
+			want: &Report{
+				Packages: []Package{
+					{
+						Path: "infra/aws/123456789/us-east-1/rds/adminconsole/accounts/root_credentials",
+						Secrets: []Secret{
+							{Key: "user", Source: SourceValue, Values: []string{".Values.user"}},
+						},
+					},
+				},
+			},
+			wantProblem: "invalid region (mars-north-1)",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			spec, err := template.YAML(strings.NewReader(specHeader + tC.secrets))
+			if err != nil {
+				t.Fatalf("unable to parse spec: %v", err)
+			}
+
+			got, err := Analyze(spec, engine.NewContext(engine.WithValues(values)))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tC.wantProblem != "" {
+				if len(got.Problems) != 1 || !strings.Contains(got.Problems[0], tC.wantProblem) {
+					t.Fatalf("expected problem %q, got %v", tC.wantProblem, got.Problems)
+				}
+				got.Problems = nil
+			}
+			if diff := cmp.Diff(got, tC.want); diff != "" {
+				t.Errorf("%q. Analyze():\n-got/+want\ndiff %s", tC.desc, diff)
+			}
+
+			// Rendered output must not contain placeholders
+			var out bytes.Buffer
+			if err := got.WriteText(&out); err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(out.String(), "@generated") {
+				t.Errorf("placeholders leaked in output:\n%s", out.String())
+			}
+		})
+	}
+}
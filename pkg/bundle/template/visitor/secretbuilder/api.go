@@ -63,6 +63,12 @@ func (sb *secretBuilder) Visit(t *bundlev1.Template) {
 	go func() {
 		defer close(results)
 
+		// Collect errors of all packages
+		var errs Errors
+		defer func() {
+			sb.err = errs.err()
+		}()
+
 		// Infrastructure secrets
 		if t.Spec.Namespaces.Infrastructure != nil {
 			for _, obj := range t.Spec.Namespaces.Infrastructure {
@@ -72,11 +78,8 @@ func (sb *secretBuilder) Visit(t *bundlev1.Template) {
 				// Traverse the object-tree
 				visitor.InfrastructureDecorator(obj).Accept(v)
 
-				// Get result
-				if err := v.Error(); err != nil {
-					sb.err = err
-					return
-				}
+				// Collect errors
+				errs.add(v.Error())
 			}
 		}
 
@@ -85,25 +88,22 @@ func (sb *secretBuilder) Visit(t *bundlev1.Template) {
 			for _, obj := range t.Spec.Namespaces.Platform {
 				// Check selector
 				if t.Spec.Selector == nil {
-					sb.err = fmt.Errorf("selector is mandatory for platform secrets")
+					errs.add(fmt.Errorf("selector is mandatory for platform secrets"))
 					return
 				}
 
 				// Initialize a infrastructure visitor
 				v, err := platform(results, sb.templateContext, t.Spec.Selector.Quality, t.Spec.Selector.Platform)
 				if err != nil {
-					sb.err = err
+					errs.add(err)
 					return
 				}
 
 				// Traverse the object-tree
 				visitor.PlatformDecorator(obj).Accept(v)
 
-				// Get result
-				if err := v.Error(); err != nil {
-					sb.err = err
-					return
-				}
+				// Collect errors
+				errs.add(v.Error())
 			}
 		}
 
@@ -112,25 +112,22 @@ func (sb *secretBuilder) Visit(t *bundlev1.Template) {
 			for _, obj := range t.Spec.Namespaces.Product {
 				// Check selector
 				if t.Spec.Selector == nil {
-					sb.err = fmt.Errorf("selector is mandatory for product secrets")
+					errs.add(fmt.Errorf("selector is mandatory for product secrets"))
 					return
 				}
 
 				// Initialize a infrastructure visitor
 				v, err := product(results, sb.templateContext, t.Spec.Selector.Product, t.Spec.Selector.Version)
 				if err != nil {
-					sb.err = err
+					errs.add(err)
 					return
 				}
 
 				// Traverse the object-tree
 				visitor.ProductDecorator(obj).Accept(v)
 
-				// Get result
-				if err := v.Error(); err != nil {
-					sb.err = err
-					return
-				}
+				// Collect errors
+				errs.add(v.Error())
 			}
 		}
 
@@ -139,33 +136,50 @@ func (sb *secretBuilder) Visit(t *bundlev1.Template) {
 			for _, obj := range t.Spec.Namespaces.Application {
 				// Check selector
 				if t.Spec.Selector == nil {
-					sb.err = fmt.Errorf("selector is mandatory for application secrets")
+					errs.add(fmt.Errorf("selector is mandatory for application secrets"))
 					return
 				}
 
 				// Initialize a infrastructure visitor
 				v, err := application(results, sb.templateContext, t.Spec.Selector.Quality, t.Spec.Selector.Platform, t.Spec.Selector.Product, t.Spec.Selector.Version)
 				if err != nil {
-					sb.err = err
+					errs.add(err)
 					return
 				}
 
 				// Traverse the object-tree
 				visitor.ApplicationDecorator(obj).Accept(v)
 
-				// Get result
-				if err := v.Error(); err != nil {
-					sb.err = err
-					return
-				}
+				// Collect errors
+				errs.add(v.Error())
 			}
 		}
 	}()
 
 	// Pull all packages
+	var limitErr error
+	maxPackages := sb.templateContext.Limits().MaxPackages
 	for p := range results {
+		// Drain remaining packages once the limit is exceeded
+		if limitErr != nil {
+			continue
+		}
+		if maxPackages > 0 && len(sb.bundle.Packages) >= maxPackages {
+			limitErr = &engine.LimitError{
+				Limit:    engine.LimitPackages,
+				Value:    maxPackages,
+				Template: sb.templateContext.Name(),
+				Position: p.Name,
+			}
+			continue
+		}
 		sb.bundle.Packages = append(sb.bundle.Packages, p)
 	}
+
+	// Report limit violation if the generation succeeded
+	if sb.err == nil {
+		sb.err = limitErr
+	}
 }
 
 func (sb *secretBuilder) Error() error {
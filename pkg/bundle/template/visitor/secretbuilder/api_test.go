@@ -0,0 +1,180 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package secretbuilder
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/template/engine"
+)
+
+func TestVisit_MaxPackages(t *testing.T) {
+	// Prepare a template generating 5 packages
+	secrets := []*bundlev1.SecretSuffix{}
+	for i := 0; i < 5; i++ {
+		secrets = append(secrets, &bundlev1.SecretSuffix{
+			Suffix:   fmt.Sprintf("secret-%d", i),
+			Template: `{"foo":"bar"}`,
+		})
+	}
+	spec := &bundlev1.Template{
+		Spec: &bundlev1.TemplateSpec{
+			Namespaces: &bundlev1.Namespaces{
+				Infrastructure: []*bundlev1.InfrastructureNS{
+					{
+						Provider: "aws",
+						Account:  "foo",
+						Regions: []*bundlev1.InfrastructureRegionNS{
+							{
+								Name: "us-east-1",
+								Services: []*bundlev1.InfrastructureServiceNS{
+									{Type: "rds", Name: "database", Secrets: secrets},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	testCases := []struct {
+		desc      string
+		limits    engine.Limits
+		wantCount int
+		wantErr   bool
+	}{
+		{
+			desc:      "unlimited",
+			limits:    engine.Limits{},
+			wantCount: 5,
+		},
+		{
+			desc:      "within limit",
+			limits:    engine.Limits{MaxPackages: 5},
+			wantCount: 5,
+		},
+		{
+			desc:      "exceeded",
+			limits:    engine.Limits{MaxPackages: 3},
+			wantCount: 3,
+			wantErr:   true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			b := &bundlev1.Bundle{}
+			v := New(b, engine.NewContext(engine.WithLimits(tC.limits)))
+			v.Visit(spec)
+
+			err := v.Error()
+			if (err != nil) != tC.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tC.wantErr)
+			}
+			if tC.wantErr {
+				var limitErr *engine.LimitError
+				if !errors.As(err, &limitErr) || limitErr.Limit != engine.LimitPackages {
+					t.Errorf("expected a package limit error, got %v", err)
+				}
+				if limitErr.Position != "infra/aws/foo/us-east-1/rds/database/secret-3" {
+					t.Errorf("unexpected limit position %q", limitErr.Position)
+				}
+			}
+			if len(b.Packages) != tC.wantCount {
+				t.Errorf("expected %d packages, got %d", tC.wantCount, len(b.Packages))
+			}
+		})
+	}
+}
+
+func TestVisit_AggregatedErrors(t *testing.T) {
+	spec := &bundlev1.Template{
+		Spec: &bundlev1.TemplateSpec{
+			Namespaces: &bundlev1.Namespaces{
+				Infrastructure: []*bundlev1.InfrastructureNS{
+					{
+						Provider: "aws",
+						Account:  "foo",
+						Regions: []*bundlev1.InfrastructureRegionNS{
+							{
+								Name: "us-east-1",
+								Services: []*bundlev1.InfrastructureServiceNS{
+									{
+										Type: "rds",
+										Name: "database",
+										Secrets: []*bundlev1.SecretSuffix{
+											{Suffix: "missing", Template: `{"password":"{{ .Values.password }}"}`},
+											{Suffix: "valid", Template: `{"user":"{{ .Values.user }}"}`},
+											{Suffix: "nil", Template: `{"token":"{{ .Values.token }}"}`},
+										},
+									},
+									{
+										Type:    "ec2",
+										Name:    "{{ .Values.service }}",
+										Secrets: []*bundlev1.SecretSuffix{{Suffix: "skipped", Template: `{}`}},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	b := &bundlev1.Bundle{}
+	v := New(b, engine.NewContext(engine.WithValues(engine.Values{
+		"user":  "harp",
+		"token": nil,
+	})))
+	v.Visit(spec)
+
+	// All problems are reported
+	var errs Errors
+	if !errors.As(v.Error(), &errs) {
+		t.Fatalf("expected aggregated errors, got %v", v.Error())
+	}
+	if len(errs) != 3 {
+		t.Fatalf("expected 3 errors, got %d: %v", len(errs), errs)
+	}
+	for i, want := range []string{
+		"unable to generate package 'infra/aws/foo/us-east-1/rds/database/missing'",
+		"unable to generate package 'infra/aws/foo/us-east-1/rds/database/nil'",
+		`map has no entry for key "service"`,
+	} {
+		if !strings.Contains(errs[i].Error(), want) {
+			t.Errorf("error %d: expected %q, got %q", i, want, errs[i].Error())
+		}
+	}
+	var noValue *engine.NoValueError
+	if !errors.As(errs[1], &noValue) {
+		t.Errorf("expected a missing value error, got %v", errs[1])
+	}
+	if !strings.HasPrefix(errs.Error(), "3 error(s) occurred during bundle generation:\n- ") {
+		t.Errorf("unexpected error message %q", errs.Error())
+	}
+
+	// Valid packages are still generated
+	if len(b.Packages) != 1 || b.Packages[0].Name != "infra/aws/foo/us-east-1/rds/database/valid" {
+		t.Errorf("unexpected packages %v", b.Packages)
+	}
+}
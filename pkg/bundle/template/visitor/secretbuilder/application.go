@@ -37,7 +37,7 @@ type applicationSecretBuilder struct {
 	product   string
 	version   string
 	component string
-	err       error
+	errs      Errors
 }
 
 // -----------------------------------------------------------------------------
@@ -91,7 +91,7 @@ func application(results chan *bundlev1.Package, templateContext engine.Context,
 // -----------------------------------------------------------------------------
 
 func (b *applicationSecretBuilder) Error() error {
-	return b.err
+	return b.errs.err()
 }
 
 func (b *applicationSecretBuilder) VisitForComponent(obj *bundlev1.ApplicationComponentNS) {
@@ -100,9 +100,11 @@ func (b *applicationSecretBuilder) VisitForComponent(obj *bundlev1.ApplicationCo
 		return
 	}
 
+	var err error
+
 	// Set context values
-	b.component, b.err = engine.RenderContext(b.templateContext, obj.Name)
-	if b.err != nil {
+	if b.component, err = engine.RenderContext(b.templateContext, obj.Name); err != nil {
+		b.errs.add(err)
 		return
 	}
 
@@ -115,15 +117,15 @@ func (b *applicationSecretBuilder) VisitForComponent(obj *bundlev1.ApplicationCo
 		// Parse suffix with template engine
 		suffix, err := engine.RenderContext(b.templateContext, item.Suffix)
 		if err != nil {
-			b.err = fmt.Errorf("unable to merge template is suffix '%s'", item.Suffix)
-			return
+			b.errs.add(fmt.Errorf("unable to merge template is suffix '%s': %w", item.Suffix, err))
+			continue
 		}
 
 		// Generate secret suffix
 		secretPath, err := csov1.RingApplication.Path(b.quality, b.platform, b.product, b.version, b.component, suffix)
 		if err != nil {
-			b.err = err
-			return
+			b.errs.add(err)
+			continue
 		}
 
 		// Prepare template model
@@ -146,8 +148,8 @@ func (b *applicationSecretBuilder) VisitForComponent(obj *bundlev1.ApplicationCo
 		// Compile template
 		p, err := parseSecretTemplate(b.templateContext, csov1.RingApplication, secretPath, item, tmplModel)
 		if err != nil {
-			b.err = err
-			return
+			b.errs.add(fmt.Errorf("unable to generate package '%s': %w", secretPath, err))
+			continue
 		}
 
 		// Add package to collection
@@ -0,0 +1,61 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package secretbuilder
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Errors aggregates package generation errors, so that all template problems
+// are reported in a single run.
+type Errors []error
+
+// Error returns all error messages, one per line.
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = "- " + err.Error()
+	}
+
+	return fmt.Sprintf("%d error(s) occurred during bundle generation:\n%s", len(e), strings.Join(msgs, "\n"))
+}
+
+// Unwrap returns aggregated errors.
+func (e Errors) Unwrap() []error {
+	return e
+}
+
+// -----------------------------------------------------------------------------
+
+func (e *Errors) add(err error) {
+	switch typed := err.(type) {
+	case nil:
+	case Errors:
+		*e = append(*e, typed...)
+	default:
+		*e = append(*e, err)
+	}
+}
+
+func (e Errors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
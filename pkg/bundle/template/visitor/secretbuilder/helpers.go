@@ -24,10 +24,12 @@ import (
 	"time"
 
 	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
 	"github.com/elastic/harp/pkg/bundle/secret"
 	csov1 "github.com/elastic/harp/pkg/cso/v1"
 	"github.com/elastic/harp/pkg/sdk/types"
 	"github.com/elastic/harp/pkg/template/engine"
+	"github.com/elastic/harp/pkg/template/generators"
 )
 
 func parseSecretTemplate(templateContext engine.Context, ring csov1.Ring, secretPath string, item *bundlev1.SecretSuffix, data interface{}) (*bundlev1.Package, error) {
@@ -53,8 +55,14 @@ func buildSecretChain(templateContext engine.Context, secretPath string, item *b
 		return nil, errors.New("unable to process with nil secret suffix")
 	}
 
+	// Attach value generator invocations to the secret path
+	if dr := templateContext.DryRun(); dr != nil {
+		dr.Scope(secretPath)
+	}
+
 	// Extract generated secret value
-	kv, err := renderSuffix(templateContext, secretPath, item, data)
+	rec := &generators.Recorder{}
+	kv, err := renderSuffix(engine.Recording(templateContext, rec), secretPath, item, data)
 	if err != nil {
 		return nil, err
 	}
@@ -75,6 +83,11 @@ func buildSecretChain(templateContext engine.Context, secretPath string, item *b
 		PreviousVersion: nil,
 	}
 
+	// Add value generator provenance
+	if provenance := rec.Annotation(); provenance != "" {
+		chain.Annotations[generators.ProvenanceAnnotation] = provenance
+	}
+
 	// Check vendor status
 	if item.Vendor {
 		chain.Labels["vendor"] = "true"
@@ -123,7 +136,7 @@ func renderSuffix(templateContext engine.Context, secretPath string, item *bundl
 	if item.Template != "" {
 		payload, err := engine.RenderContextWithData(templateContext, item.Template, data)
 		if err != nil {
-			return nil, fmt.Errorf("unable to render suffiox template: %v", err)
+			return nil, fmt.Errorf("unable to render suffiox template: %w", err)
 		}
 
 		// Parse generated JSON
@@ -142,13 +155,13 @@ func renderSuffix(templateContext engine.Context, secretPath string, item *bundl
 			// Render filename
 			renderedFilename, err := engine.RenderContextWithData(templateContext, filename, data)
 			if err != nil {
-				return nil, fmt.Errorf("unable to render filename template: %v", err)
+				return nil, fmt.Errorf("unable to render filename template: %w", err)
 			}
 
 			// Render content
 			payload, err := engine.RenderContextWithData(templateContext, content, data)
 			if err != nil {
-				return nil, fmt.Errorf("unable to render file content template: %v", err)
+				return nil, fmt.Errorf("unable to render file content template: %w", err)
 			}
 
 			// Assign result
@@ -201,6 +214,16 @@ func buildPackage(templateContext engine.Context, secretPath string, chain *bund
 		}
 	}
 
+	// Expose the secret description as package documentation
+	if item.Description != "" {
+		if item.Annotations == nil {
+			item.Annotations = map[string]string{}
+		}
+		if _, ok := item.Annotations[bundle.DescriptionAnnotation]; !ok {
+			item.Annotations[bundle.DescriptionAnnotation] = item.Description
+		}
+	}
+
 	// Assemble final secret package
 	return &bundlev1.Package{
 		Name:        secretPath,
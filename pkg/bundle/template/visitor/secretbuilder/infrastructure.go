@@ -36,7 +36,7 @@ type infrastructureSecretBuilder struct {
 	region      string
 	serviceType string
 	serviceName string
-	err         error
+	errs        Errors
 }
 
 // -----------------------------------------------------------------------------
@@ -53,7 +53,7 @@ func infrastructure(results chan *bundlev1.Package, templateContext engine.Conte
 // -----------------------------------------------------------------------------
 
 func (b *infrastructureSecretBuilder) Error() error {
-	return b.err
+	return b.errs.err()
 }
 
 func (b *infrastructureSecretBuilder) VisitForProvider(obj *bundlev1.InfrastructureNS) {
@@ -62,13 +62,15 @@ func (b *infrastructureSecretBuilder) VisitForProvider(obj *bundlev1.Infrastruct
 		return
 	}
 
+	var err error
+
 	// Set context values
-	b.provider, b.err = engine.RenderContext(b.templateContext, obj.Provider)
-	if b.err != nil {
+	if b.provider, err = engine.RenderContext(b.templateContext, obj.Provider); err != nil {
+		b.errs.add(err)
 		return
 	}
-	b.accountName, b.err = engine.RenderContext(b.templateContext, obj.Account)
-	if b.err != nil {
+	if b.accountName, err = engine.RenderContext(b.templateContext, obj.Account); err != nil {
+		b.errs.add(err)
 		return
 	}
 
@@ -84,9 +86,11 @@ func (b *infrastructureSecretBuilder) VisitForRegion(obj *bundlev1.Infrastructur
 		return
 	}
 
+	var err error
+
 	// Set context values
-	b.region, b.err = engine.RenderContext(b.templateContext, obj.Name)
-	if b.err != nil {
+	if b.region, err = engine.RenderContext(b.templateContext, obj.Name); err != nil {
+		b.errs.add(err)
 		return
 	}
 
@@ -102,13 +106,15 @@ func (b *infrastructureSecretBuilder) VisitForService(obj *bundlev1.Infrastructu
 		return
 	}
 
+	var err error
+
 	// Set context values
-	b.serviceType, b.err = engine.RenderContext(b.templateContext, obj.Type)
-	if b.err != nil {
+	if b.serviceType, err = engine.RenderContext(b.templateContext, obj.Type); err != nil {
+		b.errs.add(err)
 		return
 	}
-	b.serviceName, b.err = engine.RenderContext(b.templateContext, obj.Name)
-	if b.err != nil {
+	if b.serviceName, err = engine.RenderContext(b.templateContext, obj.Name); err != nil {
+		b.errs.add(err)
 		return
 	}
 
@@ -121,15 +127,15 @@ func (b *infrastructureSecretBuilder) VisitForService(obj *bundlev1.Infrastructu
 		// Parse suffix with template engine
 		suffix, err := engine.RenderContext(b.templateContext, item.Suffix)
 		if err != nil {
-			b.err = fmt.Errorf("unable to merge template is suffix '%s'", item.Suffix)
-			return
+			b.errs.add(fmt.Errorf("unable to merge template is suffix '%s': %w", item.Suffix, err))
+			continue
 		}
 
 		// Generate secret suffix
 		secretPath, err := csov1.RingInfra.Path(b.provider, b.accountName, b.region, b.serviceType, b.serviceName, suffix)
 		if err != nil {
-			b.err = err
-			return
+			b.errs.add(err)
+			continue
 		}
 
 		// Prepare template model
@@ -152,8 +158,8 @@ func (b *infrastructureSecretBuilder) VisitForService(obj *bundlev1.Infrastructu
 		// Compile template
 		p, err := parseSecretTemplate(b.templateContext, csov1.RingInfra, secretPath, item, tmplModel)
 		if err != nil {
-			b.err = err
-			return
+			b.errs.add(fmt.Errorf("unable to generate package '%s': %w", secretPath, err))
+			continue
 		}
 
 		// Add package to collection
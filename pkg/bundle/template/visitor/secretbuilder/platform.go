@@ -36,7 +36,7 @@ type platformSecretBuilder struct {
 	name      string
 	region    string
 	component string
-	err       error
+	errs      Errors
 }
 
 // -----------------------------------------------------------------------------
@@ -72,7 +72,7 @@ func platform(results chan *bundlev1.Package, templateContext engine.Context, qu
 // -----------------------------------------------------------------------------
 
 func (b *platformSecretBuilder) Error() error {
-	return b.err
+	return b.errs.err()
 }
 
 func (b *platformSecretBuilder) VisitForRegion(obj *bundlev1.PlatformRegionNS) {
@@ -81,9 +81,11 @@ func (b *platformSecretBuilder) VisitForRegion(obj *bundlev1.PlatformRegionNS) {
 		return
 	}
 
+	var err error
+
 	// Set context values
-	b.region, b.err = engine.RenderContext(b.templateContext, obj.Region)
-	if b.err != nil {
+	if b.region, err = engine.RenderContext(b.templateContext, obj.Region); err != nil {
+		b.errs.add(err)
 		return
 	}
 
@@ -99,9 +101,11 @@ func (b *platformSecretBuilder) VisitForComponent(obj *bundlev1.PlatformComponen
 		return
 	}
 
+	var err error
+
 	// Set context value
-	b.component, b.err = engine.RenderContext(b.templateContext, obj.Name)
-	if b.err != nil {
+	if b.component, err = engine.RenderContext(b.templateContext, obj.Name); err != nil {
+		b.errs.add(err)
 		return
 	}
 
@@ -114,15 +118,15 @@ func (b *platformSecretBuilder) VisitForComponent(obj *bundlev1.PlatformComponen
 		// Parse suffix with template engine
 		suffix, err := engine.RenderContext(b.templateContext, item.Suffix)
 		if err != nil {
-			b.err = fmt.Errorf("unable to merge template is suffix '%s'", item.Suffix)
-			return
+			b.errs.add(fmt.Errorf("unable to merge template is suffix '%s': %w", item.Suffix, err))
+			continue
 		}
 
 		// Generate secret suffix
 		secretPath, err := csov1.RingPlatform.Path(b.quality, b.name, b.region, b.component, suffix)
 		if err != nil {
-			b.err = err
-			return
+			b.errs.add(err)
+			continue
 		}
 
 		// Prepare template model
@@ -143,8 +147,8 @@ func (b *platformSecretBuilder) VisitForComponent(obj *bundlev1.PlatformComponen
 		// Compile template
 		p, err := parseSecretTemplate(b.templateContext, csov1.RingPlatform, secretPath, item, tmplModel)
 		if err != nil {
-			b.err = err
-			return
+			b.errs.add(fmt.Errorf("unable to generate package '%s': %w", secretPath, err))
+			continue
 		}
 
 		// Add package to collection
@@ -35,7 +35,7 @@ type productSecretBuilder struct {
 	name      string
 	version   string
 	component string
-	err       error
+	errs      Errors
 }
 
 // -----------------------------------------------------------------------------
@@ -71,7 +71,7 @@ func product(results chan *bundlev1.Package, templateContext engine.Context, nam
 // -----------------------------------------------------------------------------
 
 func (b *productSecretBuilder) Error() error {
-	return b.err
+	return b.errs.err()
 }
 
 func (b *productSecretBuilder) VisitForComponent(obj *bundlev1.ProductComponentNS) {
@@ -80,9 +80,11 @@ func (b *productSecretBuilder) VisitForComponent(obj *bundlev1.ProductComponentN
 		return
 	}
 
+	var err error
+
 	// Set context values
-	b.component, b.err = engine.RenderContext(b.templateContext, obj.Name)
-	if b.err != nil {
+	if b.component, err = engine.RenderContext(b.templateContext, obj.Name); err != nil {
+		b.errs.add(err)
 		return
 	}
 
@@ -95,15 +97,15 @@ func (b *productSecretBuilder) VisitForComponent(obj *bundlev1.ProductComponentN
 		// Parse suffix with template engine
 		suffix, err := engine.RenderContext(b.templateContext, item.Suffix)
 		if err != nil {
-			b.err = fmt.Errorf("unable to merge template is suffix '%s'", item.Suffix)
-			return
+			b.errs.add(fmt.Errorf("unable to merge template is suffix '%s': %w", item.Suffix, err))
+			continue
 		}
 
 		// Generate secret suffix
 		secretPath, err := csov1.RingProduct.Path(b.name, b.version, b.component, suffix)
 		if err != nil {
-			b.err = err
-			return
+			b.errs.add(err)
+			continue
 		}
 
 		// Prepare template model
@@ -122,8 +124,8 @@ func (b *productSecretBuilder) VisitForComponent(obj *bundlev1.ProductComponentN
 		// Compile template
 		p, err := parseSecretTemplate(b.templateContext, csov1.RingProduct, secretPath, item, tmplModel)
 		if err != nil {
-			b.err = err
-			return
+			b.errs.add(fmt.Errorf("unable to generate package '%s': %w", secretPath, err))
+			continue
 		}
 
 		// Add package to collection
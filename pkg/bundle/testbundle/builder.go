@@ -0,0 +1,135 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package testbundle provides an in-memory bundle builder for tests.
+//
+//	b := testbundle.New().
+//		Package("app/production/security/harp/v1.0.0/server/database").
+//			Secret("user", "admin").
+//			Secret("password", testbundle.Random(32)).
+//			Annotation("owner", "security").
+//		Build()
+package testbundle
+
+import (
+	"fmt"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle/secret"
+)
+
+// Builder builds a bundle.
+type Builder struct {
+	b   *bundlev1.Bundle
+	err error
+}
+
+// New returns an empty bundle builder.
+func New() *Builder {
+	return &Builder{
+		b: &bundlev1.Bundle{
+			Labels:      map[string]string{},
+			Annotations: map[string]string{},
+			Packages:    []*bundlev1.Package{},
+		},
+	}
+}
+
+// Label sets a bundle label.
+func (b *Builder) Label(key, value string) *Builder {
+	b.b.Labels[key] = value
+	return b
+}
+
+// Annotation sets a bundle annotation.
+func (b *Builder) Annotation(key, value string) *Builder {
+	b.b.Annotations[key] = value
+	return b
+}
+
+// Package adds a package and returns its builder.
+func (b *Builder) Package(name string) *PackageBuilder {
+	p := &bundlev1.Package{
+		Name:        name,
+		Labels:      map[string]string{},
+		Annotations: map[string]string{},
+		Secrets: &bundlev1.SecretChain{
+			Data: []*bundlev1.KV{},
+		},
+	}
+	b.b.Packages = append(b.b.Packages, p)
+
+	return &PackageBuilder{parent: b, p: p}
+}
+
+// Build returns the built bundle. It panics if a value can't be packed.
+func (b *Builder) Build() *bundlev1.Bundle {
+	if b.err != nil {
+		panic(b.err)
+	}
+	return b.b
+}
+
+// -----------------------------------------------------------------------------
+
+// PackageBuilder builds a bundle package.
+type PackageBuilder struct {
+	parent *Builder
+	p      *bundlev1.Package
+}
+
+// Secret adds a secret value to the package.
+func (pb *PackageBuilder) Secret(key string, value interface{}) *PackageBuilder {
+	// Pack secret value
+	packed, err := secret.Pack(value)
+	if err != nil {
+		if pb.parent.err == nil {
+			pb.parent.err = fmt.Errorf("unable to pack secret value for `%s.%s`: %w", pb.p.Name, key, err)
+		}
+		return pb
+	}
+
+	pb.p.Secrets.Data = append(pb.p.Secrets.Data, &bundlev1.KV{
+		Key:   key,
+		Type:  fmt.Sprintf("%T", value),
+		Value: packed,
+	})
+
+	return pb
+}
+
+// Label sets a package label.
+func (pb *PackageBuilder) Label(key, value string) *PackageBuilder {
+	pb.p.Labels[key] = value
+	return pb
+}
+
+// Annotation sets a package annotation.
+func (pb *PackageBuilder) Annotation(key, value string) *PackageBuilder {
+	pb.p.Annotations[key] = value
+	return pb
+}
+
+// Package adds another package to the bundle.
+func (pb *PackageBuilder) Package(name string) *PackageBuilder {
+	return pb.parent.Package(name)
+}
+
+// Build returns the built bundle.
+func (pb *PackageBuilder) Build() *bundlev1.Bundle {
+	return pb.parent.Build()
+}
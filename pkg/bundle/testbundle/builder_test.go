@@ -0,0 +1,87 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package testbundle
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/elastic/harp/pkg/bundle"
+	csov1 "github.com/elastic/harp/pkg/cso/v1"
+)
+
+func TestBuilder(t *testing.T) {
+	b := New().
+		Annotation("harp.elastic.co/v1/bundle#origin", "test").
+		Package("app/production/security/harp/v1.0.0/server/database").
+		Secret("user", "admin").
+		Secret("password", Random(32)).
+		Secret("port", 5432).
+		Annotation("owner", "security").
+		Label("tier", "1").
+		Package("app/production/security/harp/v1.0.0/server/tls").
+		Secret("cert", []byte("-----BEGIN CERTIFICATE-----")).
+		Build()
+
+	if len(b.Packages) != 2 {
+		t.Fatalf("2 packages expected, got %d", len(b.Packages))
+	}
+	if b.Packages[0].Annotations["owner"] != "security" || b.Packages[0].Labels["tier"] != "1" {
+		t.Error("package metadata expected")
+	}
+
+	// Round trip through container
+	kv, err := bundle.AsMap(Load(t, bytes.NewBuffer(Container(t, b))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	secrets := kv["app/production/security/harp/v1.0.0/server/database"].(bundle.KV)
+	if secrets["user"] != "admin" || len(secrets["password"].(string)) != 32 {
+		t.Errorf("unexpected secrets: %v", secrets)
+	}
+}
+
+func TestBuilder_PackError(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("panic expected for unpackable value")
+		}
+	}()
+
+	New().Package("app/production/a/b/v1.0.0/c/d").Secret("invalid", map[string]interface{}{"a": 1}).Build()
+}
+
+func TestRandomPackages(t *testing.T) {
+	b := RandomPackages(200, 42)
+	if len(b.Packages) != 200 {
+		t.Fatalf("200 packages expected, got %d", len(b.Packages))
+	}
+	for _, p := range b.Packages {
+		if err := csov1.Validate(p.Name); err != nil {
+			t.Errorf("path '%s' must be CSO compliant: %v", p.Name, err)
+		}
+	}
+
+	// Paths are reproducible
+	other := RandomPackages(200, 42)
+	for i := range b.Packages {
+		if b.Packages[i].Name != other.Packages[i].Name {
+			t.Fatal("same seed must produce the same paths")
+		}
+	}
+}
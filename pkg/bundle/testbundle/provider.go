@@ -0,0 +1,71 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package testbundle
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// Container serializes the given bundle as an unsealed container.
+func Container(t testing.TB, b *bundlev1.Bundle) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := bundle.ToContainerWriter(&buf, b); err != nil {
+		t.Fatalf("unable to prepare container: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+// Reader returns a reader provider serving the given bundle as container.
+// Each call returns a new reader.
+func Reader(t testing.TB, b *bundlev1.Bundle) tasks.ReaderProvider {
+	t.Helper()
+
+	content := Container(t, b)
+	return func(context.Context) (io.Reader, error) {
+		return bytes.NewReader(content), nil
+	}
+}
+
+// Writer returns a writer provider writing to the given buffer.
+func Writer(buf *bytes.Buffer) tasks.WriterProvider {
+	return func(context.Context) (io.Writer, error) {
+		return buf, nil
+	}
+}
+
+// Load decodes the container written in the given buffer.
+func Load(t testing.TB, buf *bytes.Buffer) *bundlev1.Bundle {
+	t.Helper()
+
+	b, err := bundle.FromContainerReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("unable to load container: %v", err)
+	}
+
+	return b
+}
@@ -0,0 +1,93 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package testbundle
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	mrand "math/rand"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+)
+
+const alphanum = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// Random returns a random alphanumeric string of the given length.
+func Random(length int) string {
+	out := make([]byte, length)
+	max := big.NewInt(int64(len(alphanum)))
+	for i := range out {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			panic(err)
+		}
+		out[i] = alphanum[n.Int64()]
+	}
+	return string(out)
+}
+
+var (
+	qualities  = []string{"production", "staging", "qa", "dev"}
+	regions    = []string{"us-east-1", "eu-central-1", "ap-southeast-1"}
+	products   = []string{"ece", "harp", "billing", "search"}
+	components = []string{"api", "worker", "server", "console"}
+	names      = []string{"database", "cache", "queue", "tls", "oauth"}
+)
+
+// RandomPath returns a random CSO compliant path using the given random
+// source.
+func RandomPath(r *mrand.Rand) string {
+	pick := func(values []string) string {
+		return values[r.Intn(len(values))]
+	}
+	id := r.Intn(1 << 16)
+
+	switch r.Intn(4) {
+	case 0:
+		return fmt.Sprintf("infra/aws/account-%d/%s/%s/%s-%d", id, pick(regions), pick(components), pick(names), id)
+	case 1:
+		return fmt.Sprintf("platform/%s/platform-%d/%s/%s/%s", pick(qualities), id, pick(regions), pick(components), pick(names))
+	case 2:
+		return fmt.Sprintf("product/%s/v%d.%d.%d/%s/%s-%d", pick(products), r.Intn(5), r.Intn(10), r.Intn(10), pick(components), pick(names), id)
+	default:
+		return fmt.Sprintf("app/%s/platform-%d/%s/v%d.%d.%d/%s/%s", pick(qualities), id, pick(products), r.Intn(5), r.Intn(10), r.Intn(10), pick(components), pick(names))
+	}
+}
+
+// RandomPackages returns a bundle with count random CSO compliant packages
+// with unique paths. The same seed produces the same paths.
+func RandomPackages(count int, seed int64) *bundlev1.Bundle {
+	r := mrand.New(mrand.NewSource(seed)) //nolint:gosec // test data only
+
+	b := New()
+	seen := map[string]struct{}{}
+	for len(seen) < count {
+		path := RandomPath(r)
+		if _, ok := seen[path]; ok {
+			continue
+		}
+		seen[path] = struct{}{}
+
+		b.Package(path).
+			Secret("user", fmt.Sprintf("user-%d", len(seen))).
+			Secret("password", Random(32))
+	}
+
+	return b.Build()
+}
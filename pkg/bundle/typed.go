@@ -0,0 +1,318 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/blake2b"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle/secret"
+)
+
+// ValueKind describes the inferred type of a secret value.
+type ValueKind string
+
+const (
+	// KindString is a printable text value.
+	KindString ValueKind = "string"
+	// KindBytes is an opaque binary value.
+	KindBytes ValueKind = "bytes"
+	// KindPEMCertificate is a PEM encoded certificate, optionally followed by
+	// its chain.
+	KindPEMCertificate ValueKind = "pem-certificate"
+	// KindPEMPrivateKey is a PEM encoded private key.
+	KindPEMPrivateKey ValueKind = "pem-private-key"
+	// KindJSON is a JSON object or array.
+	KindJSON ValueKind = "json"
+	// KindURL is an absolute URL.
+	KindURL ValueKind = "url"
+	// KindBase64Blob is a base64 encoded binary value.
+	KindBase64Blob ValueKind = "base64"
+)
+
+// TypeAnnotationPrefix prefixes the package annotation caching the inferred
+// type of a secret key (i.e. "...#type/<key>").
+const TypeAnnotationPrefix = "harp.elastic.co/v1/package#type/"
+
+// fallbackConfidence is the confidence of the string fallback, a sniffer must
+// be strictly more confident to win.
+const fallbackConfidence = 0.5
+
+var (
+	// ErrSnifferAlreadyRegistered is raised when a sniffer kind is registered
+	// twice.
+	ErrSnifferAlreadyRegistered = errors.New("bundle: sniffer already registered")
+	// ErrSecretKeyNotFound is raised when the requested secret key doesn't
+	// exist in the package.
+	ErrSecretKeyNotFound = errors.New("bundle: secret key not found")
+)
+
+// TypedValue is a secret value tagged with its inferred kind. Raw always
+// holds the value content, the kind specific field is set according to Kind.
+type TypedValue struct {
+	Kind       ValueKind
+	Confidence float64
+	Raw        []byte
+
+	// Certificates is set for KindPEMCertificate.
+	Certificates []*x509.Certificate
+	// PrivateKey is set for KindPEMPrivateKey, nil when the key can't be
+	// parsed (i.e. encrypted).
+	PrivateKey crypto.PrivateKey
+	// JSON is set for KindJSON.
+	JSON interface{}
+	// URL is set for KindURL.
+	URL *url.URL
+	// Blob is set for KindBase64Blob with decoded content.
+	Blob []byte
+}
+
+// String returns the value as a string.
+func (v *TypedValue) String() string {
+	return string(v.Raw)
+}
+
+// Sniffer inspects raw secret content and returns the typed value when the
+// content matches its kind, nil otherwise. Sniffers must tolerate any input.
+type Sniffer func(raw []byte) *TypedValue
+
+type snifferEntry struct {
+	kind    ValueKind
+	sniffer Sniffer
+}
+
+var (
+	sniffersMu sync.RWMutex
+	sniffers   = []snifferEntry{}
+)
+
+func init() {
+	MustRegisterSniffer(KindPEMCertificate, sniffPEMCertificate)
+	MustRegisterSniffer(KindPEMPrivateKey, sniffPEMPrivateKey)
+	MustRegisterSniffer(KindJSON, sniffJSON)
+	MustRegisterSniffer(KindURL, sniffURL)
+	MustRegisterSniffer(KindBase64Blob, sniffBase64)
+}
+
+// RegisterSniffer registers a sniffer for the given kind. Sniffers are
+// evaluated in registration order, the most confident one wins.
+func RegisterSniffer(kind ValueKind, s Sniffer) error {
+	sniffersMu.Lock()
+	defer sniffersMu.Unlock()
+
+	// Check arguments
+	if kind == "" || s == nil {
+		return fmt.Errorf("sniffer kind and function must not be blank")
+	}
+	if kind == KindString || kind == KindBytes {
+		return fmt.Errorf("unable to register '%s': %w", kind, ErrSnifferAlreadyRegistered)
+	}
+	for _, e := range sniffers {
+		if e.kind == kind {
+			return fmt.Errorf("unable to register '%s': %w", kind, ErrSnifferAlreadyRegistered)
+		}
+	}
+	sniffers = append(sniffers, snifferEntry{kind: kind, sniffer: s})
+
+	// No error
+	return nil
+}
+
+// MustRegisterSniffer registers a sniffer and panics on error.
+func MustRegisterSniffer(kind ValueKind, s Sniffer) {
+	if err := RegisterSniffer(kind, s); err != nil {
+		panic(err)
+	}
+}
+
+// Sniff infers the type of the given raw content.
+func Sniff(raw []byte) *TypedValue {
+	sniffersMu.RLock()
+	entries := append([]snifferEntry{}, sniffers...)
+	sniffersMu.RUnlock()
+
+	best := fallback(raw)
+	for _, e := range entries {
+		if v := safeSniff(e, raw); v != nil && v.Confidence > best.Confidence {
+			best = v
+		}
+	}
+
+	return best
+}
+
+// Typed returns the typed value of the given package secret key. The type
+// cached by InferTypes is used when it's still valid for the current value.
+func Typed(p *bundlev1.Package, key string) (*TypedValue, error) {
+	// Check arguments
+	if p == nil {
+		return nil, errors.New("unable to process nil package")
+	}
+	if p.Secrets == nil || p.Secrets.Locked != nil {
+		return nil, fmt.Errorf("unable to read '%s': %w", p.Name, ErrPackageLocked)
+	}
+
+	for _, kv := range p.Secrets.Data {
+		if kv == nil || kv.Key != key {
+			continue
+		}
+
+		return typedKV(p, kv)
+	}
+
+	return nil, fmt.Errorf("unable to read '%s' of '%s': %w", key, p.Name, ErrSecretKeyNotFound)
+}
+
+// InferTypes infers the type of all secret values and caches results as
+// package annotations. Locked packages are skipped.
+func InferTypes(b *bundlev1.Bundle) error {
+	// Check arguments
+	if b == nil {
+		return errors.New("unable to process nil bundle")
+	}
+
+	for _, p := range b.Packages {
+		if p == nil || p.Secrets == nil || p.Secrets.Locked != nil {
+			continue
+		}
+
+		for _, kv := range p.Secrets.Data {
+			if kv == nil {
+				continue
+			}
+
+			v, err := typedKV(p, kv)
+			if err != nil {
+				return err
+			}
+
+			// Overwrite stale cache entries
+			if p.Annotations == nil {
+				p.Annotations = map[string]string{}
+			}
+			p.Annotations[TypeAnnotationPrefix+kv.Key] = typeCache(v, kv.Value)
+		}
+	}
+
+	// No error
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func typedKV(p *bundlev1.Package, kv *bundlev1.KV) (*TypedValue, error) {
+	// Unpack secret value
+	var data interface{}
+	if err := secret.Unpack(kv.Value, &data); err != nil {
+		return nil, fmt.Errorf("unable to unpack '%s' of '%s': %w", kv.Key, p.Name, err)
+	}
+
+	var raw []byte
+	switch value := data.(type) {
+	case string:
+		raw = []byte(value)
+	case []byte:
+		raw = value
+	default:
+		raw = []byte(fmt.Sprintf("%v", value))
+	}
+
+	// Use cached type when valid
+	if v := cachedType(p.Annotations[TypeAnnotationPrefix+kv.Key], kv.Value, raw); v != nil {
+		return v, nil
+	}
+
+	return Sniff(raw), nil
+}
+
+// typeCache returns the annotation value caching the given typed value.
+func typeCache(v *TypedValue, packed []byte) string {
+	return fmt.Sprintf("%s;%s;%s", v.Kind, strconv.FormatFloat(v.Confidence, 'f', -1, 64), valueDigest(packed))
+}
+
+// cachedType decodes raw content using the cached kind. It returns nil when
+// the cache is missing, stale or doesn't match anymore.
+func cachedType(cache string, packed, raw []byte) *TypedValue {
+	parts := strings.Split(cache, ";")
+	if len(parts) != 3 || parts[2] != valueDigest(packed) {
+		return nil
+	}
+	kind := ValueKind(parts[0])
+
+	switch kind {
+	case KindString, KindBytes:
+		if v := fallback(raw); v.Kind == kind {
+			return v
+		}
+		return nil
+	default:
+	}
+
+	sniffersMu.RLock()
+	defer sniffersMu.RUnlock()
+	for _, e := range sniffers {
+		if e.kind == kind {
+			return safeSniff(e, raw)
+		}
+	}
+
+	return nil
+}
+
+func valueDigest(packed []byte) string {
+	h := blake2b.Sum256(packed)
+	return hex.EncodeToString(h[:8])
+}
+
+func fallback(raw []byte) *TypedValue {
+	if utf8.Valid(raw) {
+		return &TypedValue{Kind: KindString, Confidence: fallbackConfidence, Raw: raw}
+	}
+	return &TypedValue{Kind: KindBytes, Confidence: 1, Raw: raw}
+}
+
+// safeSniff calls the sniffer and ignores its result on panic or kind
+// mismatch.
+func safeSniff(e snifferEntry, raw []byte) (v *TypedValue) {
+	defer func() {
+		if r := recover(); r != nil {
+			v = nil
+		}
+	}()
+
+	v = e.sniffer(raw)
+	if v == nil || v.Kind != e.kind {
+		return nil
+	}
+	if v.Raw == nil {
+		v.Raw = raw
+	}
+
+	return v
+}
@@ -0,0 +1,261 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle/secret"
+	"github.com/elastic/harp/pkg/sdk/log"
+)
+
+// EncryptedValuePrefix prefixes secret values encrypted outside harp. These
+// values are encoded as "enc:<scheme>:<base64 ciphertext>".
+const EncryptedValuePrefix = "enc:"
+
+var (
+	// ErrUnsupportedEncryptionScheme is raised by a value decryptor when the
+	// value encryption scheme is not handled.
+	ErrUnsupportedEncryptionScheme = errors.New("bundle: unsupported value encryption scheme")
+	// ErrValueDecryption is raised when an encrypted value can't be decrypted.
+	ErrValueDecryption = errors.New("bundle: unable to decrypt secret value")
+)
+
+// ValueDecryptor decrypts secret values encrypted outside harp.
+type ValueDecryptor interface {
+	// Decrypt returns the cleartext of the given ciphertext, or
+	// ErrUnsupportedEncryptionScheme when the scheme is not handled.
+	Decrypt(ctx context.Context, scheme string, ciphertext []byte) ([]byte, error)
+}
+
+// ValueDecryptors chains value decryptors, the first one handling the value
+// encryption scheme is used.
+type ValueDecryptors []ValueDecryptor
+
+// Decrypt delegates the decryption to the first decryptor handling the scheme.
+func (vd ValueDecryptors) Decrypt(ctx context.Context, scheme string, ciphertext []byte) ([]byte, error) {
+	for _, d := range vd {
+		if d == nil {
+			continue
+		}
+		out, err := d.Decrypt(ctx, scheme, ciphertext)
+		if errors.Is(err, ErrUnsupportedEncryptionScheme) {
+			continue
+		}
+		return out, err
+	}
+
+	return nil, fmt.Errorf("no decryptor for '%s': %w", scheme, ErrUnsupportedEncryptionScheme)
+}
+
+// DecryptFailurePolicy describes how value decryption failures are handled.
+type DecryptFailurePolicy int
+
+const (
+	// DecryptFailureError refuses to load the bundle.
+	DecryptFailureError DecryptFailurePolicy = iota
+	// DecryptFailureHide removes the package holding the value (fail-closed).
+	DecryptFailureHide
+)
+
+var decryptFailurePolicyNames = map[DecryptFailurePolicy]string{
+	DecryptFailureError: "error",
+	DecryptFailureHide:  "hide",
+}
+
+func (p DecryptFailurePolicy) String() string {
+	if name, ok := decryptFailurePolicyNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("DecryptFailurePolicy(%d)", int(p))
+}
+
+// ParseDecryptFailurePolicy returns the policy matching the given name
+// (error, hide). A blank name returns the default error policy.
+func ParseDecryptFailurePolicy(name string) (DecryptFailurePolicy, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return DecryptFailureError, nil
+	}
+	for p, n := range decryptFailurePolicyNames {
+		if n == name {
+			return p, nil
+		}
+	}
+
+	return DecryptFailureError, fmt.Errorf("invalid decryption failure policy '%s', expected error or hide", name)
+}
+
+// EncryptedValue encodes the given ciphertext as an externally encrypted
+// secret value.
+func EncryptedValue(scheme string, ciphertext []byte) string {
+	return fmt.Sprintf("%s%s:%s", EncryptedValuePrefix, scheme, base64.StdEncoding.EncodeToString(ciphertext))
+}
+
+// ParseEncryptedValue decodes an externally encrypted secret value. The
+// boolean is false when the value doesn't carry the encryption prefix.
+func ParseEncryptedValue(raw []byte) (scheme string, ciphertext []byte, ok bool, err error) {
+	if !bytes.HasPrefix(raw, []byte(EncryptedValuePrefix)) {
+		return "", nil, false, nil
+	}
+
+	parts := strings.SplitN(string(raw[len(EncryptedValuePrefix):]), ":", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", nil, true, errors.New("encrypted value must be encoded as 'enc:<scheme>:<base64 ciphertext>'")
+	}
+	ciphertext, err = base64.StdEncoding.DecodeString(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return parts[0], nil, true, fmt.Errorf("unable to decode encrypted value: %w", err)
+	}
+
+	return parts[0], ciphertext, true, nil
+}
+
+// DecryptValues replaces externally encrypted secret values of the given
+// bundle by their cleartext, and returns the hidden package count. Locked
+// packages are skipped.
+func DecryptValues(ctx context.Context, b *bundlev1.Bundle, d ValueDecryptor, policy DecryptFailurePolicy) (int, error) {
+	// Check arguments
+	if b == nil {
+		return 0, errors.New("unable to process nil bundle")
+	}
+	if d == nil {
+		return 0, errors.New("unable to decrypt values with nil decryptor")
+	}
+
+	kept := make([]*bundlev1.Package, 0, len(b.Packages))
+	for _, p := range b.Packages {
+		err := decryptPackage(ctx, p, d)
+		if err == nil {
+			kept = append(kept, p)
+			continue
+		}
+
+		switch policy {
+		case DecryptFailureError:
+			return 0, err
+		case DecryptFailureHide:
+			log.For(ctx).Warn("Package hidden for value decryption failure", zap.String("path", p.Name), zap.Error(err))
+		default:
+			return 0, fmt.Errorf("unsupported decryption failure policy '%s'", policy)
+		}
+	}
+
+	hidden := len(b.Packages) - len(kept)
+	b.Packages = kept
+
+	// No error
+	return hidden, nil
+}
+
+// -----------------------------------------------------------------------------
+
+// decryptPackage decrypts all values of the package, values are updated only
+// when all of them are decrypted.
+func decryptPackage(ctx context.Context, p *bundlev1.Package, d ValueDecryptor) error {
+	if p == nil || p.Secrets == nil || p.Secrets.Locked != nil {
+		return nil
+	}
+
+	decrypted := make(map[*bundlev1.KV][]byte, len(p.Secrets.Data))
+	for _, kv := range p.Secrets.Data {
+		if kv == nil {
+			continue
+		}
+
+		// Unpack secret value
+		var data interface{}
+		if err := secret.Unpack(kv.Value, &data); err != nil {
+			continue
+		}
+		var raw []byte
+		switch value := data.(type) {
+		case string:
+			raw = []byte(value)
+		case []byte:
+			raw = value
+		default:
+			continue
+		}
+
+		// Decrypt value
+		scheme, ciphertext, ok, err := ParseEncryptedValue(raw)
+		if !ok {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("unable to decrypt '%s' of '%s': %v: %w", kv.Key, p.Name, err, ErrValueDecryption)
+		}
+		cleartext, err := d.Decrypt(ctx, scheme, ciphertext)
+		if err != nil {
+			return fmt.Errorf("unable to decrypt '%s' of '%s' (%s): %v: %w", kv.Key, p.Name, scheme, err, ErrValueDecryption)
+		}
+
+		// Pack with the original type
+		var packed []byte
+		if _, isString := data.(string); isString {
+			packed, err = secret.Pack(string(cleartext))
+		} else {
+			packed, err = secret.Pack(cleartext)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to pack decrypted value of '%s': %w", kv.Key, err)
+		}
+		decrypted[kv] = packed
+	}
+
+	for kv, packed := range decrypted {
+		kv.Value = packed
+	}
+
+	// No error
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+// WithValueDecryptor sets the decryptor used to decrypt externally encrypted
+// values at load time.
+func WithValueDecryptor(d ValueDecryptor) LoadOption {
+	return func(opts *loadOptions) {
+		opts.decryptor = d
+	}
+}
+
+// WithDecryptFailurePolicy sets how value decryption failures are handled.
+func WithDecryptFailurePolicy(policy DecryptFailurePolicy) LoadOption {
+	return func(opts *loadOptions) {
+		opts.decryptPolicy = policy
+	}
+}
+
+// WithHiddenPackageCount sets the counter receiving the count of packages
+// hidden for value decryption failure.
+func WithHiddenPackageCount(count *int) LoadOption {
+	return func(opts *loadOptions) {
+		opts.hidden = count
+	}
+}
@@ -0,0 +1,134 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/elastic/harp/pkg/bundle/secret"
+)
+
+// upperDecryptor "decrypts" values of the upper scheme by upper casing them.
+type upperDecryptor struct{}
+
+func (upperDecryptor) Decrypt(_ context.Context, scheme string, ciphertext []byte) ([]byte, error) {
+	if scheme != "upper" {
+		return nil, ErrUnsupportedEncryptionScheme
+	}
+	return []byte(strings.ToUpper(string(ciphertext))), nil
+}
+
+func TestParseEncryptedValue(t *testing.T) {
+	testCases := []struct {
+		name       string
+		raw        string
+		wantScheme string
+		wantOK     bool
+		wantErr    bool
+	}{
+		{name: "plain", raw: "s3cr3t"},
+		{name: "valid", raw: EncryptedValue("kms", []byte("payload")), wantScheme: "kms", wantOK: true},
+		{name: "missing scheme", raw: "enc::cGF5bG9hZA==", wantOK: true, wantErr: true},
+		{name: "missing payload separator", raw: "enc:kms", wantOK: true, wantErr: true},
+		{name: "invalid payload", raw: "enc:kms:not base64!", wantScheme: "kms", wantOK: true, wantErr: true},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			scheme, _, ok, err := ParseEncryptedValue([]byte(tc.raw))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if ok != tc.wantOK || scheme != tc.wantScheme {
+				t.Errorf("got (%q, %v), want (%q, %v)", scheme, ok, tc.wantScheme, tc.wantOK)
+			}
+		})
+	}
+}
+
+func TestDecryptValues(t *testing.T) {
+	b := mustFromMap(t, map[string]KV{
+		"app/production/billing/api": {
+			"user":  EncryptedValue("upper", []byte("admin")),
+			"token": "plain",
+		},
+		"app/production/billing/db": {
+			"user":     EncryptedValue("upper", []byte("dba")),
+			"password": EncryptedValue("unknown", []byte("opaque")),
+		},
+	})
+
+	// Error policy refuses the bundle
+	if _, err := DecryptValues(context.Background(), b, upperDecryptor{}, DecryptFailureError); !errors.Is(err, ErrValueDecryption) {
+		t.Fatalf("expected ErrValueDecryption, got %v", err)
+	}
+
+	hidden, err := DecryptValues(context.Background(), b, upperDecryptor{}, DecryptFailureHide)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hidden != 1 || len(b.Packages) != 1 || b.Packages[0].Name != "app/production/billing/api" {
+		t.Fatalf("db package must be hidden, got %d hidden", hidden)
+	}
+
+	m, err := AsSecretMap(b.Packages[0])
+	if err != nil {
+		t.Fatalf("unable to read package: %v", err)
+	}
+	if m["user"] != "ADMIN" || m["token"] != "plain" {
+		t.Errorf("unexpected values %v", m)
+	}
+}
+
+func TestDecryptValues_PackageAtomicity(t *testing.T) {
+	b := mustFromMap(t, map[string]KV{
+		"app/production/billing/db": {
+			"a": EncryptedValue("upper", []byte("dba")),
+			"b": EncryptedValue("unknown", []byte("opaque")),
+		},
+	})
+
+	if _, err := DecryptValues(context.Background(), b, upperDecryptor{}, DecryptFailureError); err == nil {
+		t.Fatal("error should be raised")
+	}
+
+	for _, kv := range b.Packages[0].Secrets.Data {
+		var v string
+		if err := secret.Unpack(kv.Value, &v); err != nil {
+			t.Fatalf("unable to unpack value: %v", err)
+		}
+		if !strings.HasPrefix(v, EncryptedValuePrefix) {
+			t.Errorf("value of '%s' must be left encrypted, got %q", kv.Key, v)
+		}
+	}
+}
+
+func TestParseDecryptFailurePolicy(t *testing.T) {
+	for name, want := range map[string]DecryptFailurePolicy{"": DecryptFailureError, "error": DecryptFailureError, "Hide": DecryptFailureHide} {
+		got, err := ParseDecryptFailurePolicy(name)
+		if err != nil || got != want {
+			t.Errorf("ParseDecryptFailurePolicy(%q) = %v, %v", name, got, err)
+		}
+	}
+	if _, err := ParseDecryptFailurePolicy("drop"); err == nil {
+		t.Error("error should be raised for unknown policy")
+	}
+}
@@ -0,0 +1,170 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle/secret"
+	"github.com/elastic/harp/pkg/sdk/jsonpatch"
+)
+
+// ErrValueNotJSON is raised when a JSON patch targets a secret value which is
+// not a JSON document.
+var ErrValueNotJSON = errors.New("bundle: secret value is not a JSON document")
+
+// ValuePatchFunc transforms a JSON document.
+type ValuePatchFunc func(doc []byte) ([]byte, error)
+
+// JSONPatch returns a value patch applying the given JSON Patch (RFC 6902).
+func JSONPatch(patch []byte) ValuePatchFunc {
+	return func(doc []byte) ([]byte, error) {
+		return jsonpatch.Apply(doc, patch)
+	}
+}
+
+// MergePatch returns a value patch applying the given JSON Merge Patch
+// (RFC 7386).
+func MergePatch(patch []byte) ValuePatchFunc {
+	return func(doc []byte) ([]byte, error) {
+		return jsonpatch.MergePatch(doc, patch)
+	}
+}
+
+// PatchValue applies the given patch to the secret field of the package
+// matching the given path.
+func PatchValue(b *bundlev1.Bundle, path, field string, fn ValuePatchFunc) error {
+	// Check arguments
+	if fn == nil {
+		return errors.New("unable to apply nil value patch")
+	}
+
+	p, err := lookup(b, path)
+	if err != nil {
+		return err
+	}
+	if err := CheckMutable(p); err != nil {
+		return err
+	}
+	if p.Secrets == nil || p.Secrets.Locked != nil {
+		return fmt.Errorf("unable to patch '%s': %w", p.Name, ErrPackageLocked)
+	}
+
+	for _, kv := range p.Secrets.Data {
+		if kv == nil || kv.Key != field {
+			continue
+		}
+		if err := PatchKV(kv, fn); err != nil {
+			return fmt.Errorf("unable to patch '%s' of '%s': %w", field, p.Name, err)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unable to patch '%s' of '%s': %w", field, p.Name, ErrSecretKeyNotFound)
+}
+
+// PatchKV applies the given patch to the JSON document held by the secret
+// value. The result is packed using the original value type, and keeps the
+// original document layout (compact or indented).
+func PatchKV(kv *bundlev1.KV, fn ValuePatchFunc) error {
+	// Check arguments
+	if kv == nil {
+		return errors.New("unable to patch nil secret")
+	}
+	if fn == nil {
+		return errors.New("unable to apply nil value patch")
+	}
+
+	// Unpack secret value
+	var data interface{}
+	if err := secret.Unpack(kv.Value, &data); err != nil {
+		return fmt.Errorf("unable to unpack secret value: %w", err)
+	}
+
+	var doc []byte
+	switch value := data.(type) {
+	case string:
+		doc = []byte(value)
+	case []byte:
+		doc = value
+	default:
+		return fmt.Errorf("value of type %T can't be patched: %w", data, ErrValueNotJSON)
+	}
+	if !json.Valid(doc) {
+		return ErrValueNotJSON
+	}
+
+	// Apply patch
+	out, err := fn(doc)
+	if err != nil {
+		return err
+	}
+	out, err = relayout(doc, out)
+	if err != nil {
+		return err
+	}
+
+	// Pack with the original type
+	var packed []byte
+	if _, ok := data.(string); ok {
+		packed, err = secret.Pack(string(out))
+	} else {
+		packed, err = secret.Pack(out)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to pack patched value: %w", err)
+	}
+	kv.Value = packed
+
+	// No error
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+// relayout formats the compact patched document like the original one.
+func relayout(original, patched []byte) ([]byte, error) {
+	trimmed := bytes.TrimSpace(original)
+	trailing := original[len(bytes.TrimRight(original, " \t\r\n")):]
+
+	// Compact document
+	if !bytes.Contains(trimmed, []byte("\n")) {
+		return append(patched, trailing...), nil
+	}
+
+	// Detect indentation from the first indented line
+	indent := "  "
+	for _, line := range bytes.Split(trimmed, []byte("\n"))[1:] {
+		content := bytes.TrimLeft(line, " \t")
+		if len(content) < len(line) {
+			indent = string(line[:len(line)-len(content)])
+			break
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, patched, "", indent); err != nil {
+		return nil, fmt.Errorf("unable to indent patched value: %w", err)
+	}
+
+	return append(buf.Bytes(), trailing...), nil
+}
@@ -0,0 +1,174 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle/secret"
+	"github.com/elastic/harp/pkg/sdk/jsonpatch"
+)
+
+const valuePatchPath = "app/production/customer1/billing/api"
+
+// valuePatchBundle returns a bundle holding the given value as "config".
+func valuePatchBundle(t *testing.T, value interface{}) *bundlev1.Bundle {
+	t.Helper()
+
+	packed, err := secret.Pack(value)
+	if err != nil {
+		t.Fatalf("unable to pack value: %v", err)
+	}
+
+	return &bundlev1.Bundle{
+		Packages: []*bundlev1.Package{
+			{
+				Name: valuePatchPath,
+				Secrets: &bundlev1.SecretChain{
+					Data: []*bundlev1.KV{
+						{Key: "config", Type: "string", Value: packed},
+					},
+				},
+			},
+		},
+	}
+}
+
+func unpackConfig(t *testing.T, b *bundlev1.Bundle) interface{} {
+	t.Helper()
+
+	var out interface{}
+	if err := secret.Unpack(b.Packages[0].Secrets.Data[0].Value, &out); err != nil {
+		t.Fatalf("unable to unpack value: %v", err)
+	}
+
+	return out
+}
+
+func TestPatchValue_CodecPreservation(t *testing.T) {
+	testCases := []struct {
+		name  string
+		value interface{}
+		want  interface{}
+	}{
+		{
+			name:  "compact string",
+			value: `{"db":{"port":5432}}`,
+			want:  `{"db":{"port":5433}}`,
+		},
+		{
+			name:  "compact bytes",
+			value: []byte(`{"db":{"port":5432}}`),
+			want:  []byte(`{"db":{"port":5433}}`),
+		},
+		{
+			name:  "indented string",
+			value: "{\n    \"db\": {\n        \"port\": 5432\n    }\n}\n",
+			want:  "{\n    \"db\": {\n        \"port\": 5433\n    }\n}\n",
+		},
+		{
+			name:  "tab indented bytes",
+			value: []byte("{\n\t\"db\": {\n\t\t\"port\": 5432\n\t}\n}"),
+			want:  []byte("{\n\t\"db\": {\n\t\t\"port\": 5433\n\t}\n}"),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			b := valuePatchBundle(t, tc.value)
+			err := PatchValue(b, valuePatchPath, "config", JSONPatch([]byte(`[{"op":"replace","path":"/db/port","value":5433}]`)))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := unpackConfig(t, b)
+			switch want := tc.want.(type) {
+			case string:
+				if s, ok := got.(string); !ok || s != want {
+					t.Errorf("expected string %q, got %T %q", want, got, got)
+				}
+			case []byte:
+				if raw, ok := got.([]byte); !ok || string(raw) != string(want) {
+					t.Errorf("expected bytes %q, got %T %q", want, got, got)
+				}
+			}
+		})
+	}
+}
+
+func TestPatchValue_MergePatch(t *testing.T) {
+	b := valuePatchBundle(t, `{"db":{"host":"localhost"},"debug":true}`)
+	if err := PatchValue(b, valuePatchPath, "config", MergePatch([]byte(`{"db":{"host":"db.internal"},"debug":null}`))); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := unpackConfig(t, b); got != `{"db":{"host":"db.internal"}}` {
+		t.Errorf("unexpected value %q", got)
+	}
+}
+
+func TestPatchValue_Errors(t *testing.T) {
+	patch := JSONPatch([]byte(`[{"op":"add","path":"/a","value":1}]`))
+
+	t.Run("not json", func(t *testing.T) {
+		b := valuePatchBundle(t, "s3cr3t")
+		if err := PatchValue(b, valuePatchPath, "config", patch); !errors.Is(err, ErrValueNotJSON) {
+			t.Fatalf("expected ErrValueNotJSON, got %v", err)
+		}
+		if got := unpackConfig(t, b); got != "s3cr3t" {
+			t.Errorf("value must be untouched, got %q", got)
+		}
+	})
+
+	t.Run("test failure leaves value untouched", func(t *testing.T) {
+		b := valuePatchBundle(t, `{"a":1}`)
+		err := PatchValue(b, valuePatchPath, "config", JSONPatch([]byte(`[{"op":"replace","path":"/a","value":2},{"op":"test","path":"/a","value":1}]`)))
+		if !errors.Is(err, jsonpatch.ErrTestFailed) {
+			t.Fatalf("expected ErrTestFailed, got %v", err)
+		}
+		if got := unpackConfig(t, b); got != `{"a":1}` {
+			t.Errorf("value must be untouched, got %q", got)
+		}
+	})
+
+	t.Run("missing field", func(t *testing.T) {
+		b := valuePatchBundle(t, `{}`)
+		if err := PatchValue(b, valuePatchPath, "unknown", patch); !errors.Is(err, ErrSecretKeyNotFound) {
+			t.Fatalf("expected ErrSecretKeyNotFound, got %v", err)
+		}
+	})
+
+	t.Run("missing package", func(t *testing.T) {
+		b := valuePatchBundle(t, `{}`)
+		if err := PatchValue(b, "app/unknown", "config", patch); !errors.Is(err, ErrPackageNotFound) {
+			t.Fatalf("expected ErrPackageNotFound, got %v", err)
+		}
+	})
+
+	t.Run("archived package", func(t *testing.T) {
+		b := valuePatchBundle(t, `{}`)
+		if err := Archive(b, valuePatchPath, time.Now()); err != nil {
+			t.Fatalf("unable to archive package: %v", err)
+		}
+		if err := PatchValue(b, valuePatchPath, "config", patch); !errors.Is(err, ErrPackageArchived) {
+			t.Fatalf("expected ErrPackageArchived, got %v", err)
+		}
+	})
+}
@@ -20,9 +20,11 @@ package operation
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/hashicorp/vault/api"
 	"go.uber.org/zap"
@@ -36,37 +38,84 @@ import (
 	vpath "github.com/elastic/harp/pkg/vault/path"
 )
 
+// WriteObserver is notified of each secret write result, it must be safe for
+// concurrent use.
+type WriteObserver func(secretPath string, err error)
+
+// ImporterOptions describes secret importer settings.
+type ImporterOptions struct {
+	Prefix       string
+	WithMetadata bool
+	// CheckAndSet enables KV v2 check-and-set writes, using the secret
+	// versions captured before the first write.
+	CheckAndSet bool
+	// CustomMetadataPrefixes lists the package annotation prefixes written as
+	// KV v2 custom metadata.
+	CustomMetadataPrefixes []string
+	OnWrite                WriteObserver
+	// KeepGoing writes all secrets instead of stopping at the first write
+	// failure.
+	KeepGoing bool
+}
+
 // Importer initialize a secret importer operation
-func Importer(client *api.Client, bundleFile *bundlev1.Bundle, prefix string, withMetadata bool) Operation {
+func Importer(client *api.Client, bundleFile *bundlev1.Bundle, opts ImporterOptions) Operation {
 	return &importer{
-		client:       client,
-		bundle:       bundleFile,
-		prefix:       prefix,
-		withMetadata: withMetadata,
-		backends:     map[string]kv.Service{},
+		client:                 client,
+		bundle:                 bundleFile,
+		prefix:                 opts.Prefix,
+		withMetadata:           opts.WithMetadata,
+		checkAndSet:            opts.CheckAndSet,
+		customMetadataPrefixes: opts.CustomMetadataPrefixes,
+		onWrite:                opts.OnWrite,
+		keepGoing:              opts.KeepGoing,
+		backends:               map[string]kv.Service{},
 	}
 }
 
 // -----------------------------------------------------------------------------
 
+const (
+	// Vault KV v2 custom metadata limits
+	maxCustomMetadataKeys        = 64
+	maxCustomMetadataKeyLength   = 128
+	maxCustomMetadataValueLength = 512
+)
+
 type importer struct {
-	client        *api.Client
-	bundle        *bundlev1.Bundle
-	prefix        string
-	withMetadata  bool
-	backends      map[string]kv.Service
-	backendsMutex sync.RWMutex
+	client                 *api.Client
+	bundle                 *bundlev1.Bundle
+	prefix                 string
+	withMetadata           bool
+	checkAndSet            bool
+	customMetadataPrefixes []string
+	onWrite                WriteObserver
+	keepGoing              bool
+	backends               map[string]kv.Service
+	backendsMutex          sync.RWMutex
 }
 
 // Run the implemented operation
 // nolint:gocognit,funlen,gocyclo // To refactor
 func (op *importer) Run(ctx context.Context) error {
+	// Capture secret versions before writing anything
+	var versions map[string]uint64
+	if op.checkAndSet {
+		var err error
+		if versions, err = op.plan(ctx); err != nil {
+			return err
+		}
+	}
+
 	// Initialize sub context
 	g, gctx := errgroup.WithContext(ctx)
 
 	// Prepare channels
 	packageChan := make(chan *bundlev1.Package)
 
+	// Check-and-set mismatches fail the path only
+	var drifted, failed int32
+
 	// consumers ---------------------------------------------------------------
 
 	// Secret writer
@@ -98,74 +147,26 @@ func (op *importer) Run(ctx context.Context) error {
 					return nil
 				}
 
-				data := map[string]interface{}{}
-				// Wrap secret k/v as a map
-				for _, s := range secretPackage.Secrets.Data {
-					// Unpack secret to original value
-					var value interface{}
-					if err := secret.Unpack(s.Value, &value); err != nil {
-						return fmt.Errorf("unable to unpack secret value for path '%s': %w", secretPackage.Name, err)
-					}
-
-					// Assign to map for vault storage
-					data[s.Key] = value
-				}
-
-				// Export metadata
-				if op.withMetadata {
-					// Has annotations
-					if len(secretPackage.Annotations) > 0 {
-						out, err := json.Marshal(secretPackage.Annotations)
-						if err != nil {
-							return fmt.Errorf("unable to encode annotations as JSON for path '%v': %v", secretPackage.Name, err)
-						}
-
-						// Assign json
-						data["harp.elastic.io/v1/bundle#annotations"] = string(out)
-					}
-
-					// Has labels
-					if len(secretPackage.Labels) > 0 {
-						out, err := json.Marshal(secretPackage.Labels)
-						if err != nil {
-							return fmt.Errorf("unable to encode labels as JSON for path '%v': %v", secretPackage.Name, err)
-						}
-
-						// Assign json
-						data["harp.elastic.io/v1/bundle#labels"] = string(out)
-					}
-				}
+				// Write the package
+				secretPath := op.secretPath(secretPackage)
+				err := op.write(gWriterCtx, secretPath, secretPackage, versions)
 
-				// Assemble secret path
-				secretPath := secretPackage.Name
-				if op.prefix != "" {
-					secretPath = fmt.Sprintf("%s/%s", op.prefix, secretPath)
+				// Notify write result
+				if op.onWrite != nil {
+					op.onWrite(secretPath, err)
 				}
-
-				// Extract root backend path
-				rootPath := strings.Split(vpath.SanitizePath(secretPath), "/")[0]
-
-				// Check backend initialization
-				if _, ok := op.backends[rootPath]; !ok {
-					// Initialize new service for backend
-					service, err := kv.New(op.client, rootPath)
-					if err != nil {
-						return fmt.Errorf("unable to initialize Vault service for '%s' KV backend: %w", op.prefix, err)
-					}
-
-					// All queries will be handled by same backend service
-					op.backendsMutex.Lock()
-					op.backends[rootPath] = service
-					op.backendsMutex.Unlock()
+				if errors.Is(err, kv.ErrCASMismatch) {
+					log.For(gWriterCtx).Error("Secret has been modified since plan", zap.String("path", secretPath), zap.Error(err))
+					atomic.AddInt32(&drifted, 1)
+					return nil
 				}
-
-				// Write secret to Vault
-				if err := op.backends[rootPath].Write(gWriterCtx, secretPath, data); err != nil {
-					return err
+				if err != nil && op.keepGoing {
+					log.For(gWriterCtx).Error("Unable to write secret", zap.String("path", secretPath), zap.Error(err))
+					atomic.AddInt32(&failed, 1)
+					return nil
 				}
 
-				// No error
-				return nil
+				return err
 			})
 		}
 
@@ -195,7 +196,228 @@ func (op *importer) Run(ctx context.Context) error {
 	if err := g.Wait(); err != nil {
 		return err
 	}
+	if failed > 0 {
+		return fmt.Errorf("unable to write %d secret path(s)", failed)
+	}
+	if drifted > 0 {
+		return fmt.Errorf("%d secret path(s) have been modified in Vault since plan: %w", drifted, kv.ErrCASMismatch)
+	}
 
 	// No error
 	return nil
 }
+
+// -----------------------------------------------------------------------------
+
+func (op *importer) secretPath(p *bundlev1.Package) string {
+	if op.prefix != "" {
+		return fmt.Sprintf("%s/%s", op.prefix, p.Name)
+	}
+	return p.Name
+}
+
+func (op *importer) backend(secretPath string) (kv.Service, error) {
+	// Extract root backend path
+	rootPath := strings.Split(vpath.SanitizePath(secretPath), "/")[0]
+
+	op.backendsMutex.RLock()
+	service, ok := op.backends[rootPath]
+	op.backendsMutex.RUnlock()
+	if ok {
+		return service, nil
+	}
+
+	op.backendsMutex.Lock()
+	defer op.backendsMutex.Unlock()
+
+	// Check backend initialization
+	if service, ok := op.backends[rootPath]; ok {
+		return service, nil
+	}
+
+	// Initialize new service for backend
+	service, err := kv.New(op.client, rootPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize Vault service for '%s' KV backend: %w", rootPath, err)
+	}
+
+	// All queries will be handled by same backend service
+	op.backends[rootPath] = service
+
+	// No error
+	return service, nil
+}
+
+// plan captures the current version of all secrets to write.
+func (op *importer) plan(ctx context.Context) (map[string]uint64, error) {
+	var (
+		mu       sync.Mutex
+		versions = map[string]uint64{}
+		sem      = semaphore.NewWeighted(int64(maxReaderWorker))
+	)
+
+	g, gctx := errgroup.WithContext(ctx)
+	for _, p := range op.bundle.Packages {
+		// No data to insert
+		if p.Secrets == nil {
+			continue
+		}
+
+		secretPath := op.secretPath(p)
+
+		// Acquire a token
+		if err := sem.Acquire(gctx, 1); err != nil {
+			g.Go(func() error { return err })
+			break
+		}
+
+		g.Go(func() error {
+			defer sem.Release(1)
+
+			service, err := op.backend(secretPath)
+			if err != nil {
+				return err
+			}
+
+			// Check-and-set is a KV v2 feature
+			reader, ok := service.(kv.SecretVersionReader)
+			if !ok {
+				log.For(gctx).Warn("Check-and-set is not supported by the KV backend, secret will be overwritten", zap.String("path", secretPath))
+				return nil
+			}
+
+			version, err := reader.ReadVersion(gctx, secretPath)
+			if err != nil {
+				return err
+			}
+
+			mu.Lock()
+			versions[secretPath] = version
+			mu.Unlock()
+
+			// No error
+			return nil
+		})
+	}
+
+	// Wait for all reads to complete
+	if err := g.Wait(); err != nil {
+		return nil, fmt.Errorf("unable to capture secret versions: %w", err)
+	}
+
+	// No error
+	return versions, nil
+}
+
+func (op *importer) write(ctx context.Context, secretPath string, secretPackage *bundlev1.Package, versions map[string]uint64) error {
+	data := map[string]interface{}{}
+	// Wrap secret k/v as a map
+	for _, s := range secretPackage.Secrets.Data {
+		// Unpack secret to original value
+		var value interface{}
+		if err := secret.Unpack(s.Value, &value); err != nil {
+			return fmt.Errorf("unable to unpack secret value for path '%s': %w", secretPackage.Name, err)
+		}
+
+		// Assign to map for vault storage
+		data[s.Key] = value
+	}
+
+	// Export metadata
+	if op.withMetadata {
+		// Has annotations
+		if len(secretPackage.Annotations) > 0 {
+			out, err := json.Marshal(secretPackage.Annotations)
+			if err != nil {
+				return fmt.Errorf("unable to encode annotations as JSON for path '%v': %v", secretPackage.Name, err)
+			}
+
+			// Assign json
+			data["harp.elastic.io/v1/bundle#annotations"] = string(out)
+		}
+
+		// Has labels
+		if len(secretPackage.Labels) > 0 {
+			out, err := json.Marshal(secretPackage.Labels)
+			if err != nil {
+				return fmt.Errorf("unable to encode labels as JSON for path '%v': %v", secretPackage.Name, err)
+			}
+
+			// Assign json
+			data["harp.elastic.io/v1/bundle#labels"] = string(out)
+		}
+	}
+
+	// Select custom metadata
+	metadata, err := customMetadata(secretPackage.Annotations, op.customMetadataPrefixes)
+	if err != nil {
+		return fmt.Errorf("unable to prepare custom metadata for path '%s': %w", secretPackage.Name, err)
+	}
+
+	// Retrieve backend service
+	service, err := op.backend(secretPath)
+	if err != nil {
+		return err
+	}
+
+	// Write secret to Vault
+	version, planned := versions[secretPath]
+	if writer, ok := service.(kv.SecretCASWriter); ok && planned {
+		if err := writer.WriteWithCAS(ctx, secretPath, data, version); err != nil {
+			if errors.Is(err, kv.ErrCASMismatch) {
+				return fmt.Errorf("secret '%s' has been modified in Vault since version %d was planned: %w", secretPath, version, kv.ErrCASMismatch)
+			}
+			return err
+		}
+	} else if err := service.Write(ctx, secretPath, data); err != nil {
+		return err
+	}
+
+	// Write custom metadata
+	if len(metadata) > 0 {
+		writer, ok := service.(kv.SecretMetadataWriter)
+		if !ok {
+			log.For(ctx).Warn("Custom metadata is not supported by the KV backend, metadata is ignored", zap.String("path", secretPath))
+			return nil
+		}
+		if err := writer.WriteCustomMetadata(ctx, secretPath, metadata); err != nil {
+			return err
+		}
+	}
+
+	// No error
+	return nil
+}
+
+// customMetadata returns the annotations matching one of the given prefixes.
+func customMetadata(annotations map[string]string, prefixes []string) (map[string]string, error) {
+	res := map[string]string{}
+	if len(prefixes) == 0 {
+		return res, nil
+	}
+
+	for k, v := range annotations {
+		for _, prefix := range prefixes {
+			if !strings.HasPrefix(k, prefix) {
+				continue
+			}
+
+			// Check Vault limits
+			if len(k) > maxCustomMetadataKeyLength {
+				return nil, fmt.Errorf("annotation key '%s' exceeds %d characters", k, maxCustomMetadataKeyLength)
+			}
+			if len(v) > maxCustomMetadataValueLength {
+				return nil, fmt.Errorf("annotation '%s' value exceeds %d characters", k, maxCustomMetadataValueLength)
+			}
+
+			res[k] = v
+			break
+		}
+	}
+	if len(res) > maxCustomMetadataKeys {
+		return nil, fmt.Errorf("%d annotations selected, exceeds %d custom metadata keys", len(res), maxCustomMetadataKeys)
+	}
+
+	// No error
+	return res, nil
+}
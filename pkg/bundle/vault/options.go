@@ -20,13 +20,20 @@ package vault
 import (
 	"fmt"
 	"regexp"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
 )
 
 type options struct {
-	prefix       string
-	withMetadata bool
-	exclusions   []*regexp.Regexp
-	includes     []*regexp.Regexp
+	prefix                 string
+	withMetadata           bool
+	exclusions             []*regexp.Regexp
+	includes               []*regexp.Regexp
+	onWrite                func(secretPath string, err error)
+	checkAndSet            bool
+	customMetadataPrefixes []string
+	onPackage              func(p *bundlev1.Package) error
+	keepGoing              bool
 }
 
 // Option defines the functional pattern for bundle operation settings.
@@ -83,3 +90,61 @@ func WithMetadata(value bool) Option {
 		return nil
 	}
 }
+
+// WithWriteObserver registers a function notified of each secret write
+// result during a push. It must be safe for concurrent use.
+func WithWriteObserver(fn func(secretPath string, err error)) Option {
+	return func(opts *options) error {
+		opts.onWrite = fn
+		// No error
+		return nil
+	}
+}
+
+// WithCheckAndSet enables KV v2 check-and-set writes during a push. Secret
+// versions are captured before the first write, a secret modified in the
+// meantime fails with kv.ErrCASMismatch instead of being overwritten.
+func WithCheckAndSet(value bool) Option {
+	return func(opts *options) error {
+		opts.checkAndSet = value
+		// No error
+		return nil
+	}
+}
+
+// WithKeepGoing writes all secrets during a push instead of stopping at the
+// first write failure. Failures are notified to the write observer and
+// counted in the returned error.
+func WithKeepGoing(value bool) Option {
+	return func(opts *options) error {
+		opts.keepGoing = value
+		// No error
+		return nil
+	}
+}
+
+// WithCustomMetadata writes package annotations matching one of the given
+// prefixes as KV v2 custom metadata during a push.
+func WithCustomMetadata(prefixes ...string) Option {
+	return func(opts *options) error {
+		for _, p := range prefixes {
+			if p == "" {
+				return fmt.Errorf("custom metadata annotation prefix must not be blank")
+			}
+		}
+		opts.customMetadataPrefixes = append(opts.customMetadataPrefixes, prefixes...)
+		// No error
+		return nil
+	}
+}
+
+// WithPackageObserver registers a function notified of each pulled package
+// as soon as it is received. Packages are notified sequentially, the first
+// error fails the pull.
+func WithPackageObserver(fn func(p *bundlev1.Package) error) Option {
+	return func(opts *options) error {
+		opts.onPackage = fn
+		// No error
+		return nil
+	}
+}
@@ -100,8 +100,20 @@ func runPull(ctx context.Context, client *api.Client, paths []string, opts *opti
 		b := &bundlev1.Bundle{}
 
 		// Wait for all packages
+		var errObserver error
 		for p := range packageChan {
 			b.Packages = append(b.Packages, p)
+
+			// Notify observer, keep draining the channel on error to
+			// release producers.
+			if opts.onPackage != nil && errObserver == nil {
+				if err := opts.onPackage(p); err != nil {
+					errObserver = fmt.Errorf("package observer failed for '%s': %w", p.Name, err)
+				}
+			}
+		}
+		if errObserver != nil {
+			return errObserver
 		}
 
 		// Assign result
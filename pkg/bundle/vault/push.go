@@ -87,7 +87,14 @@ func runPush(ctx context.Context, b *bundlev1.Bundle, client *api.Client, opts *
 	}
 
 	// Initialize operation
-	op := operation.Importer(client, b, opts.prefix, opts.withMetadata)
+	op := operation.Importer(client, b, operation.ImporterOptions{
+		Prefix:                 opts.prefix,
+		WithMetadata:           opts.withMetadata,
+		CheckAndSet:            opts.checkAndSet,
+		CustomMetadataPrefixes: opts.customMetadataPrefixes,
+		OnWrite:                opts.onWrite,
+		KeepGoing:              opts.keepGoing,
+	})
 
 	// Run the vault operation
 	if err := op.Run(ctx); err != nil {
@@ -0,0 +1,302 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/api"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/vault/kv"
+)
+
+// fakeKVv2 emulates a KV v2 backend mounted on 'secret/'.
+type fakeKVv2 struct {
+	mu       sync.Mutex
+	data     map[string]map[string]interface{}
+	versions map[string]uint64
+	metadata map[string]map[string]string
+	// onVersionRead is called after a version read, to emulate concurrent
+	// writers.
+	onVersionRead func(path string)
+}
+
+func newFakeKVv2(t *testing.T) (*fakeKVv2, *api.Client) {
+	f := &fakeKVv2{
+		data:     map[string]map[string]interface{}{},
+		versions: map[string]uint64{},
+		metadata: map[string]map[string]string{},
+	}
+
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+
+	// Initialize Vault client
+	client, err := api.NewClient(&api.Config{
+		Address:    server.URL,
+		Timeout:    time.Second * 1,
+		MaxRetries: 1,
+		HttpClient: &http.Client{Transport: cleanhttp.DefaultTransport(), Timeout: time.Second * 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return f, client
+}
+
+func (f *fakeKVv2) seed(path string, version uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.data[path] = map[string]interface{}{"seeded": "true"}
+	f.versions[path] = version
+}
+
+func (f *fakeKVv2) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/v1/sys/internal/ui/mounts/"):
+		fmt.Fprint(w, `{"data":{"type":"kv","path":"secret/","options":{"version":"2"}}}`)
+	case strings.HasPrefix(r.URL.Path, "/v1/secret/metadata/"):
+		f.serveMetadata(w, r, strings.TrimPrefix(r.URL.Path, "/v1/secret/metadata/"))
+	case strings.HasPrefix(r.URL.Path, "/v1/secret/data/"):
+		f.serveData(w, r, strings.TrimPrefix(r.URL.Path, "/v1/secret/data/"))
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func (f *fakeKVv2) serveMetadata(w http.ResponseWriter, r *http.Request, path string) {
+	switch r.Method {
+	case http.MethodGet:
+		f.mu.Lock()
+		version, ok := f.versions[path]
+		f.mu.Unlock()
+		if f.onVersionRead != nil {
+			defer f.onVersionRead(path)
+		}
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"errors":[]}`)
+			return
+		}
+		fmt.Fprintf(w, `{"data":{"current_version":%d}}`, version)
+	case http.MethodPut, http.MethodPost:
+		var body struct {
+			CustomMetadata map[string]string `json:"custom_metadata"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		f.metadata[path] = body.CustomMetadata
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeKVv2) serveData(w http.ResponseWriter, r *http.Request, path string) {
+	if r.Method != http.MethodPut && r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Data    map[string]interface{} `json:"data"`
+		Options struct {
+			CAS *uint64 `json:"cas"`
+		} `json:"options"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if body.Options.CAS != nil && *body.Options.CAS != f.versions[path] {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"errors":["check-and-set parameter did not match the current version"]}`)
+		return
+	}
+
+	f.versions[path]++
+	f.data[path] = body.Data
+	fmt.Fprintf(w, `{"data":{"version":%d}}`, f.versions[path])
+}
+
+// -----------------------------------------------------------------------------
+
+const (
+	pushedPath  = "app/production/security/harp/v1.0.0/server/database"
+	driftedPath = "app/production/security/harp/v1.0.0/server/cache"
+)
+
+func pushFixture(t *testing.T) (*fakeKVv2, *api.Client) {
+	f, client := newFakeKVv2(t)
+	f.seed(driftedPath, 3)
+
+	// Emulate a concurrent writer between plan and write
+	var once sync.Once
+	f.onVersionRead = func(path string) {
+		if path == driftedPath {
+			once.Do(func() { f.seed(driftedPath, 4) })
+		}
+	}
+
+	return f, client
+}
+
+func TestPush_CheckAndSet(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		checkAndSet bool
+		wantDrift   bool
+	}{
+		{desc: "disabled", checkAndSet: false},
+		{desc: "enabled", checkAndSet: true, wantDrift: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			f, client := pushFixture(t)
+
+			b, err := bundle.FromMap(map[string]bundle.KV{
+				pushedPath:  {"password": "foo"},
+				driftedPath: {"token": "bar"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var (
+				mu      sync.Mutex
+				results = map[string]error{}
+			)
+			err = Push(context.Background(), b, client,
+				WithPrefix("secret"),
+				WithCheckAndSet(tC.checkAndSet),
+				WithWriteObserver(func(secretPath string, err error) {
+					mu.Lock()
+					defer mu.Unlock()
+					results[secretPath] = err
+				}),
+			)
+			if tC.wantDrift != errors.Is(err, kv.ErrCASMismatch) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// Other paths are written
+			if results["secret/"+pushedPath] != nil || f.versions[pushedPath] != 1 {
+				t.Errorf("secret should be written, got %v", results["secret/"+pushedPath])
+			}
+
+			// Drifted path
+			driftErr := results["secret/"+driftedPath]
+			if tC.wantDrift {
+				if !errors.Is(driftErr, kv.ErrCASMismatch) || !strings.Contains(driftErr.Error(), "since version 3 was planned") {
+					t.Errorf("drift error should be reported, got %v", driftErr)
+				}
+				if f.data[driftedPath]["seeded"] != "true" {
+					t.Error("drifted secret should not be overwritten")
+				}
+			} else {
+				if driftErr != nil || f.data[driftedPath]["token"] != "bar" {
+					t.Errorf("secret should be overwritten, got %v", driftErr)
+				}
+			}
+		})
+	}
+}
+
+func TestPush_CustomMetadata(t *testing.T) {
+	f, client := newFakeKVv2(t)
+
+	b, err := bundle.FromMap(map[string]bundle.KV{
+		pushedPath: {"password": "foo"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Packages[0].Annotations = map[string]string{
+		"harp.elastic.co/v1/package#owner":    "security",
+		"harp.elastic.co/v1/package#rotation": "90d",
+		"infosec.elastic.co/v1/package#tier":  "1",
+		"patched":                             "true",
+	}
+
+	err = Push(context.Background(), b, client,
+		WithPrefix("secret"),
+		WithCustomMetadata("harp.elastic.co/v1/package#", "infosec.elastic.co/"),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]string{
+		"harp.elastic.co/v1/package#owner":    "security",
+		"harp.elastic.co/v1/package#rotation": "90d",
+		"infosec.elastic.co/v1/package#tier":  "1",
+	}
+	if got := f.metadata[pushedPath]; !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected custom metadata %v", got)
+	}
+	if _, ok := f.data[pushedPath]["harp.elastic.io/v1/bundle#annotations"]; ok {
+		t.Error("annotations should not be written as secret data")
+	}
+}
+
+func TestPush_CustomMetadata_Limits(t *testing.T) {
+	_, client := newFakeKVv2(t)
+
+	b, err := bundle.FromMap(map[string]bundle.KV{
+		pushedPath: {"password": "foo"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Packages[0].Annotations = map[string]string{
+		"harp.elastic.co/v1/package#description": strings.Repeat("a", 513),
+	}
+
+	err = Push(context.Background(), b, client,
+		WithPrefix("secret"),
+		WithCustomMetadata("harp.elastic.co/"),
+	)
+	if err == nil || !strings.Contains(err.Error(), "exceeds 512 characters") {
+		t.Errorf("metadata limit error should be raised, got %v", err)
+	}
+	if err := WithCustomMetadata("")(&options{}); err == nil {
+		t.Error("blank prefix should be rejected")
+	}
+}
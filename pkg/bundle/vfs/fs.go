@@ -18,6 +18,7 @@
 package vfs
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -27,6 +28,7 @@ import (
 	"github.com/spf13/afero"
 
 	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle/internal/bufpool"
 	"github.com/elastic/harp/pkg/bundle/secret"
 )
 
@@ -72,17 +74,18 @@ func FromBundle(b *bundlev1.Bundle) (afero.Fs, error) {
 				// Clear buffer
 				memguard.WipeBytes(content)
 			} else {
-				// Convert as json
-				content, err := json.Marshal(secrets)
-				if err != nil {
+				// Convert as json in a reused buffer
+				buf := bufpool.Get()
+				if err := json.NewEncoder(buf).Encode(secrets); err != nil {
+					bufpool.Put(buf)
 					return nil, fmt.Errorf("unable to extract secret map as json")
 				}
 
-				// Lock buffer
-				box = memguard.NewEnclave(content)
+				// Lock buffer without the encoder trailing newline
+				box = memguard.NewEnclave(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
 
 				// Clear buffer
-				memguard.WipeBytes(content)
+				bufpool.Put(buf)
 			}
 		}
 
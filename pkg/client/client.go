@@ -0,0 +1,367 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package client implements a harp-server HTTP API client.
+//
+// Responses are cached by entity tag, repeated reads are sent as conditional
+// requests and served from the cache when the server replies 304. Failed
+// requests are retried on transport errors and 429, 502, 503 and 504
+// responses, honoring the Retry-After header.
+package client
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/harp/pkg/server/spiffe"
+)
+
+// nextCursorHeader exposes the cursor of the next listing page.
+const nextCursorHeader = "X-Harp-Next-Cursor"
+
+// maxErrorMessageSize is the maximum size of a server error message.
+const maxErrorMessageSize = 4096
+
+// Interface describes the harp-server API consumed by clients.
+type Interface interface {
+	// GetSecret returns the secret content (JSON encoded package secrets).
+	GetSecret(ctx context.Context, namespace, path string) ([]byte, error)
+	// GetDigest returns the secret content digest.
+	GetDigest(ctx context.Context, namespace, path string) (*Digest, error)
+	// ListPaths returns the immediate children of the given prefix, children
+	// holding deeper paths are suffixed with "/".
+	ListPaths(ctx context.Context, namespace, prefix string) ([]string, error)
+}
+
+// Digest describes the content digest of a secret.
+type Digest struct {
+	Value   string `json:"digest"`
+	Version uint32 `json:"version,omitempty"`
+}
+
+// Client is a harp-server HTTP API client.
+type Client struct {
+	baseURL *url.URL
+	client  *http.Client
+	opts    *options
+	cache   *etagCache
+}
+
+// Ensure the client implements the interface
+var _ Interface = (*Client)(nil)
+
+// New returns a client for the server exposed at the given base URL
+// (ex: https://harp-server:8080).
+func New(baseURL string, opts ...Option) (*Client, error) {
+	// Check arguments
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse server url: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("server url '%s' must be an absolute http(s) url", baseURL)
+	}
+
+	// Default options
+	dopts := &options{
+		maxRetries:  DefaultMaxRetries,
+		backoffBase: 200 * time.Millisecond,
+		backoffMax:  5 * time.Second,
+		cacheSize:   DefaultCacheSize,
+	}
+
+	// Apply options
+	for _, o := range opts {
+		if err := o(dopts); err != nil {
+			return nil, err
+		}
+	}
+
+	// Prepare HTTP client
+	httpClient := dopts.client
+	switch {
+	case httpClient != nil && dopts.tlsConfig != nil:
+		return nil, errors.New("tls options can't be used with a custom http client")
+	case httpClient == nil:
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = dopts.tlsConfig
+		httpClient = &http.Client{
+			Timeout:   DefaultTimeout,
+			Transport: transport,
+		}
+	default:
+	}
+
+	c := &Client{
+		baseURL: u,
+		client:  httpClient,
+		opts:    dopts,
+	}
+	if dopts.cacheSize > 0 {
+		c.cache = newETagCache(dopts.cacheSize)
+	}
+
+	// No error
+	return c, nil
+}
+
+// GetSecret returns the secret content.
+func (c *Client) GetSecret(ctx context.Context, namespace, path string) ([]byte, error) {
+	body, _, err := c.get(ctx, c.endpoint(namespace, "", path, nil), true)
+	return body, err
+}
+
+// GetDigest returns the secret content digest.
+func (c *Client) GetDigest(ctx context.Context, namespace, path string) (*Digest, error) {
+	body, _, err := c.get(ctx, c.endpoint(namespace, "digest", path, nil), true)
+	if err != nil {
+		return nil, err
+	}
+
+	var d Digest
+	if err := json.Unmarshal(body, &d); err != nil {
+		return nil, fmt.Errorf("unable to decode secret digest: %w", err)
+	}
+
+	// No error
+	return &d, nil
+}
+
+// ListPaths returns the immediate children of the given prefix, all pages are
+// retrieved.
+func (c *Client) ListPaths(ctx context.Context, namespace, prefix string) ([]string, error) {
+	var (
+		out   = []string{}
+		after string
+	)
+	for {
+		// Prepare pagination parameters
+		q := url.Values{}
+		if c.opts.pageSize > 0 {
+			q.Set("limit", strconv.Itoa(c.opts.pageSize))
+		}
+		if after != "" {
+			q.Set("after", after)
+		}
+
+		// Retrieve the page
+		body, header, err := c.get(ctx, c.endpoint(namespace, "list", prefix, q), false)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Keys []string `json:"keys"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("unable to decode secret listing: %w", err)
+		}
+		out = append(out, page.Keys...)
+
+		// Check next page
+		after = header.Get(nextCursorHeader)
+		if after == "" {
+			break
+		}
+	}
+
+	// No error
+	return out, nil
+}
+
+// -----------------------------------------------------------------------------
+
+// endpoint returns the API URL of the given namespace resource.
+func (c *Client) endpoint(namespace, route, path string, q url.Values) string {
+	parts := []string{"api", "v1", strings.Trim(namespace, "/")}
+	if route != "" {
+		parts = append(parts, route)
+	}
+	if path = strings.Trim(path, "/"); path != "" {
+		parts = append(parts, path)
+	}
+
+	u := *c.baseURL
+	u.Path = fmt.Sprintf("%s/%s", strings.TrimSuffix(u.Path, "/"), strings.Join(parts, "/"))
+	u.RawQuery = q.Encode()
+
+	return u.String()
+}
+
+// get sends a GET request, retried on transient failures. Cacheable responses
+// are sent as conditional requests.
+func (c *Client) get(ctx context.Context, target string, cacheable bool) ([]byte, http.Header, error) {
+	for attempt := 0; ; attempt++ {
+		body, header, retryAfter, err := c.do(ctx, target, cacheable)
+		if err == nil || retryAfter < 0 || attempt >= c.opts.maxRetries {
+			return body, header, err
+		}
+
+		// Wait before retrying
+		if retryAfter == 0 {
+			retryAfter = c.backoff(attempt + 1)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-time.After(retryAfter):
+		}
+	}
+}
+
+// do sends a single request. The returned delay is negative when the error
+// is not retryable, 0 when the backoff delay must be used.
+func (c *Client) do(ctx context.Context, target string, cacheable bool) ([]byte, http.Header, time.Duration, error) {
+	// Prepare the request
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, nil, -1, fmt.Errorf("unable to prepare request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.opts.token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.opts.token))
+	}
+	var cached []byte
+	if cacheable {
+		var etag string
+		if etag, cached, cacheable = c.cache.get(target); cacheable {
+			req.Header.Set("If-None-Match", etag)
+		}
+	}
+
+	// Send the request
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, nil, -1, ctx.Err()
+		}
+		return nil, nil, 0, fmt.Errorf("unable to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cacheable:
+		return cached, resp.Header, -1, nil
+	case resp.StatusCode == http.StatusOK:
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, nil, 0, fmt.Errorf("unable to read response: %w", err)
+		}
+		if etag := resp.Header.Get("ETag"); etag != "" {
+			c.cache.set(target, etag, body)
+		}
+		return body, resp.Header, -1, nil
+	default:
+	}
+
+	// Build the typed error
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorMessageSize))
+	rerr := &ResponseError{
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(string(msg)),
+	}
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		c.cache.remove(target)
+	}
+
+	// Check retryable responses
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return nil, nil, retryAfter(resp.Header.Get("Retry-After"), time.Now()), rerr
+	default:
+	}
+
+	return nil, nil, -1, rerr
+}
+
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.opts.backoffBase << uint(attempt-1)
+	if d <= 0 || d > c.opts.backoffMax {
+		d = c.opts.backoffMax
+	}
+	return d
+}
+
+// retryAfter returns the delay requested by a Retry-After header value
+// (seconds or HTTP date), 0 when missing or invalid.
+func retryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+
+	return 0
+}
+
+// verifySPIFFE returns a peer certificate verifier checking the certificate
+// chain against the trust bundle and the SPIFFE ID against the pattern.
+func verifySPIFFE(roots *x509.CertPool, pattern *spiffe.Pattern) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("no server certificate")
+		}
+
+		// Decode the certificate chain
+		certs := make([]*x509.Certificate, 0, len(rawCerts))
+		for _, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return fmt.Errorf("unable to parse server certificate: %w", err)
+			}
+			certs = append(certs, cert)
+		}
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+
+		// Verify the chain
+		if _, err := certs[0].Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		}); err != nil {
+			return fmt.Errorf("unable to verify server certificate: %w", err)
+		}
+
+		// Check the SPIFFE ID
+		id, err := spiffe.IDFromCertificate(certs[0])
+		if err != nil {
+			return err
+		}
+		if !pattern.Matches(id) {
+			return fmt.Errorf("server spiffe id '%s' doesn't match '%s'", id, pattern)
+		}
+
+		// No error
+		return nil
+	}
+}
@@ -0,0 +1,248 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name    string
+		url     string
+		opts    []Option
+		wantErr bool
+	}{
+		{name: "valid", url: "https://harp-server:8080"},
+		{name: "relative", url: "/api/v1", wantErr: true},
+		{name: "unsupported scheme", url: "ftp://harp-server", wantErr: true},
+		{name: "blank token", url: "http://harp-server", opts: []Option{WithToken("")}, wantErr: true},
+		{name: "invalid backoff", url: "http://harp-server", opts: []Option{WithBackoff(time.Second, time.Millisecond)}, wantErr: true},
+		{name: "invalid spiffe id", url: "https://harp-server", opts: []Option{WithSPIFFE(nil, "spiffe://example.org")}, wantErr: true},
+	}
+	for _, tc := range testCases {
+		_, err := New(tc.url, tc.opts...)
+		if (err != nil) != tc.wantErr {
+			t.Fatalf("%s: error = %v, wantErr %v", tc.name, err, tc.wantErr)
+		}
+	}
+}
+
+func TestClient_GetSecret_ETag(t *testing.T) {
+	var requests, notModified int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Path != "/api/v1/app/production/db" {
+			http.Error(w, "secret not found", http.StatusNotFound)
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer s3cr3t" {
+			http.Error(w, "authentication required", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fmt.Fprint(w, `{"user":"foo"}`)
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL, WithToken("s3cr3t"))
+	if err != nil {
+		t.Fatalf("unable to create client: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		value, err := c.GetSecret(context.Background(), "app", "production/db")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(value) != `{"user":"foo"}` {
+			t.Fatalf("unexpected secret value: %s", value)
+		}
+	}
+	if requests != 3 || notModified != 2 {
+		t.Fatalf("expected 3 requests with 2 conditional hits, got %d/%d", requests, notModified)
+	}
+
+	// Unauthenticated client
+	anonymous, err := New(srv.URL)
+	if err != nil {
+		t.Fatalf("unable to create client: %v", err)
+	}
+	_, err = anonymous.GetSecret(context.Background(), "app", "production/db")
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected unauthorized error, got %v", err)
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	testCases := []struct {
+		status  int
+		wantErr error
+	}{
+		{status: http.StatusNotFound, wantErr: ErrNotFound},
+		{status: http.StatusForbidden, wantErr: ErrForbidden},
+		{status: http.StatusGone, wantErr: ErrQuarantined},
+		{status: http.StatusMethodNotAllowed, wantErr: ErrListNotSupported},
+	}
+	for _, tc := range testCases {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, http.StatusText(tc.status), tc.status)
+		}))
+
+		c, err := New(srv.URL)
+		if err != nil {
+			t.Fatalf("unable to create client: %v", err)
+		}
+		_, err = c.GetDigest(context.Background(), "app", "production/db")
+		srv.Close()
+
+		if !errors.Is(err, tc.wantErr) {
+			t.Fatalf("%d: expected %v, got %v", tc.status, tc.wantErr, err)
+		}
+		var rerr *ResponseError
+		if !errors.As(err, &rerr) || rerr.StatusCode != tc.status || rerr.Message != http.StatusText(tc.status) {
+			t.Fatalf("%d: unexpected response error %#v", tc.status, err)
+		}
+	}
+}
+
+func TestClient_Retry(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&requests, 1) {
+		case 1:
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+		case 2:
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		default:
+			fmt.Fprint(w, `{"digest":"abc","version":2}`)
+		}
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL, WithBackoff(time.Millisecond, 10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("unable to create client: %v", err)
+	}
+
+	start := time.Now()
+	d, err := c.GetDigest(context.Background(), "app", "production/db")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.Value != "abc" || d.Version != 2 {
+		t.Fatalf("unexpected digest: %#v", d)
+	}
+	if requests != 3 {
+		t.Fatalf("expected 3 requests, got %d", requests)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("Retry-After not honored, retried after %s", elapsed)
+	}
+
+	// Retries exhausted
+	atomic.StoreInt32(&requests, 1)
+	c, err = New(srv.URL, WithMaxRetries(0))
+	if err != nil {
+		t.Fatalf("unable to create client: %v", err)
+	}
+	if _, err := c.GetDigest(context.Background(), "app", "production/db"); err == nil {
+		t.Fatal("expected error when retries are disabled")
+	}
+
+	// Context deadline bounds retries
+	atomic.StoreInt32(&requests, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	c, err = New(srv.URL)
+	if err != nil {
+		t.Fatalf("unable to create client: %v", err)
+	}
+	if _, err := c.GetDigest(ctx, "app", "production/db"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error, got %v", err)
+	}
+}
+
+func TestClient_ListPaths(t *testing.T) {
+	pages := map[string]struct {
+		body string
+		next string
+	}{
+		"":      {body: `{"keys":["cache/","db"]}`, next: "db"},
+		"db":    {body: `{"keys":["queue"]}`, next: "queue"},
+		"queue": {body: `{"keys":[]}`},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/app/list/production" || r.URL.Query().Get("limit") != "2" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		page := pages[r.URL.Query().Get("after")]
+		if page.next != "" {
+			w.Header().Set(nextCursorHeader, page.next)
+		}
+		fmt.Fprint(w, page.body)
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL, WithPageSize(2))
+	if err != nil {
+		t.Fatalf("unable to create client: %v", err)
+	}
+	keys, err := c.ListPaths(context.Background(), "app", "/production/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(keys, []string{"cache/", "db", "queue"}) {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		value string
+		want  time.Duration
+	}{
+		{value: "", want: 0},
+		{value: "3", want: 3 * time.Second},
+		{value: "-1", want: 0},
+		{value: now.Add(10 * time.Second).Format(http.TimeFormat), want: 10 * time.Second},
+		{value: now.Add(-10 * time.Second).Format(http.TimeFormat), want: 0},
+		{value: "soon", want: 0},
+	}
+	for _, tc := range testCases {
+		if got := retryAfter(tc.value, now); got != tc.want {
+			t.Fatalf("retryAfter(%q) = %s, want %s", tc.value, got, tc.want)
+		}
+	}
+}
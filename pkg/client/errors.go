@@ -0,0 +1,74 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrNotFound is raised when the secret or the namespace doesn't exist.
+	ErrNotFound = errors.New("client: secret not found")
+	// ErrForbidden is raised when the client identity is not allowed to access
+	// the secret.
+	ErrForbidden = errors.New("client: access denied")
+	// ErrQuarantined is raised when the secret is quarantined by the server.
+	ErrQuarantined = errors.New("client: secret quarantined")
+	// ErrUnauthorized is raised when the client is not authenticated.
+	ErrUnauthorized = errors.New("client: authentication required")
+	// ErrListNotSupported is raised when the namespace backend doesn't support
+	// listing.
+	ErrListNotSupported = errors.New("client: listing not supported")
+)
+
+// ResponseError describes an unexpected server response.
+type ResponseError struct {
+	// StatusCode is the HTTP response status code.
+	StatusCode int
+	// Message is the server error message.
+	Message string
+}
+
+// Error returns the error message.
+func (e *ResponseError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("client: unexpected server response %d (%s)", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("client: unexpected server response %d (%s): %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Unwrap returns the typed error matching the response status code.
+func (e *ResponseError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusGone:
+		return ErrQuarantined
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusMethodNotAllowed:
+		return ErrListNotSupported
+	default:
+	}
+
+	return nil
+}
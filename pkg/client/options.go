@@ -0,0 +1,185 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package client
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/elastic/harp/pkg/sdk/tlsconfig"
+	"github.com/elastic/harp/pkg/server/spiffe"
+)
+
+// Default settings
+const (
+	// DefaultMaxRetries is the default retry count of a failed request.
+	DefaultMaxRetries = 3
+	// DefaultCacheSize is the default count of cached responses.
+	DefaultCacheSize = 1024
+	// DefaultTimeout is the default request timeout.
+	DefaultTimeout = 30 * time.Second
+)
+
+type options struct {
+	client      *http.Client
+	tlsConfig   *tls.Config
+	token       string
+	maxRetries  int
+	backoffBase time.Duration
+	backoffMax  time.Duration
+	cacheSize   int
+	pageSize    int
+}
+
+// Option defines the functional pattern for client settings.
+type Option func(*options) error
+
+// WithHTTPClient sets the HTTP client used for requests. It can't be combined
+// with TLS options.
+func WithHTTPClient(value *http.Client) Option {
+	return func(opts *options) error {
+		if value == nil {
+			return errors.New("unable to use nil http client")
+		}
+		opts.client = value
+		// No error
+		return nil
+	}
+}
+
+// WithToken sends the given bearer token with all requests.
+func WithToken(value string) Option {
+	return func(opts *options) error {
+		if value == "" {
+			return errors.New("token must not be blank")
+		}
+		opts.token = value
+		// No error
+		return nil
+	}
+}
+
+// WithTLS enables TLS using the given settings, mTLS is used when a client
+// certificate is given.
+func WithTLS(value *tlsconfig.Options) Option {
+	return func(opts *options) error {
+		if value == nil {
+			return errors.New("unable to use nil tls options")
+		}
+
+		tlsConfig, err := tlsconfig.Client(value)
+		if err != nil {
+			return fmt.Errorf("unable to build tls configuration: %w", err)
+		}
+		opts.tlsConfig = tlsConfig
+
+		// No error
+		return nil
+	}
+}
+
+// WithSPIFFE enables mTLS using the given X.509 SVID (certificate and key)
+// and trust bundle (CA file). The server certificate is verified against the
+// trust bundle and its SPIFFE ID must match the given pattern (exact,
+// '/*' suffixed prefix, or trust domain only), the host name is not checked.
+func WithSPIFFE(value *tlsconfig.Options, serverID string) Option {
+	return func(opts *options) error {
+		if value == nil {
+			return errors.New("unable to use nil tls options")
+		}
+		if value.CAFile == "" {
+			return errors.New("spiffe trust bundle (CA file) must be provided")
+		}
+		pattern, err := spiffe.ParsePattern(serverID)
+		if err != nil {
+			return fmt.Errorf("invalid server spiffe id: %w", err)
+		}
+
+		// Build the base configuration
+		tlsOpts := *value
+		tlsOpts.ExclusiveRootPools = true
+		tlsConfig, err := tlsconfig.Client(&tlsOpts)
+		if err != nil {
+			return fmt.Errorf("unable to build tls configuration: %w", err)
+		}
+
+		// Verify the server SPIFFE ID instead of its host name
+		roots := tlsConfig.RootCAs
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyPeerCertificate = verifySPIFFE(roots, pattern)
+		opts.tlsConfig = tlsConfig
+
+		// No error
+		return nil
+	}
+}
+
+// WithMaxRetries sets the maximum retry count of a failed request.
+func WithMaxRetries(value int) Option {
+	return func(opts *options) error {
+		if value < 0 {
+			return errors.New("max retries must be positive")
+		}
+		opts.maxRetries = value
+		// No error
+		return nil
+	}
+}
+
+// WithBackoff sets retry backoff bounds, used when the server doesn't send a
+// Retry-After header.
+func WithBackoff(base, max time.Duration) Option {
+	return func(opts *options) error {
+		if base <= 0 || max < base {
+			return fmt.Errorf("invalid backoff bounds (%s, %s)", base, max)
+		}
+		opts.backoffBase = base
+		opts.backoffMax = max
+		// No error
+		return nil
+	}
+}
+
+// WithCacheSize sets the maximum count of responses cached for conditional
+// requests, 0 disables the cache.
+func WithCacheSize(value int) Option {
+	return func(opts *options) error {
+		if value < 0 {
+			return errors.New("cache size must be positive")
+		}
+		opts.cacheSize = value
+		// No error
+		return nil
+	}
+}
+
+// WithPageSize sets the listing page size, the server default is used when
+// 0.
+func WithPageSize(value int) Option {
+	return func(opts *options) error {
+		if value < 0 {
+			return errors.New("page size must be positive")
+		}
+		opts.pageSize = value
+		// No error
+		return nil
+	}
+}
@@ -0,0 +1,191 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package testclient provides an in-memory harp-server client to test
+// consumers without a running server.
+package testclient
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/elastic/harp/pkg/client"
+	"github.com/elastic/harp/pkg/server/storage"
+)
+
+// Fake is an in-memory client.Interface implementation. Errors are returned
+// as the client would, using *client.ResponseError wrapping typed errors.
+type Fake struct {
+	mu          sync.RWMutex
+	secrets     map[string][]byte
+	quarantined map[string]bool
+	denied      map[string]bool
+	unlistable  map[string]bool
+}
+
+// Ensure the fake implements the client interface
+var _ client.Interface = (*Fake)(nil)
+
+// New returns an empty fake client.
+func New() *Fake {
+	return &Fake{
+		secrets:     map[string][]byte{},
+		quarantined: map[string]bool{},
+		denied:      map[string]bool{},
+		unlistable:  map[string]bool{},
+	}
+}
+
+// Set registers the secret content.
+func (f *Fake) Set(namespace, path string, value []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	content := make([]byte, len(value))
+	copy(content, value)
+	f.secrets[key(namespace, path)] = content
+}
+
+// Delete removes the secret.
+func (f *Fake) Delete(namespace, path string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.secrets, key(namespace, path))
+	delete(f.quarantined, key(namespace, path))
+}
+
+// Quarantine marks the secret as quarantined, it is restored by calling
+// Quarantine with false.
+func (f *Fake) Quarantine(namespace, path string, value bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.quarantined[key(namespace, path)] = value
+}
+
+// Deny makes all namespace requests fail as access denied when true.
+func (f *Fake) Deny(namespace string, value bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.denied[clean(namespace)] = value
+}
+
+// DisableList makes namespace listing requests fail as not supported when
+// true.
+func (f *Fake) DisableList(namespace string, value bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.unlistable[clean(namespace)] = value
+}
+
+// GetSecret returns the secret content.
+func (f *Fake) GetSecret(ctx context.Context, namespace, path string) ([]byte, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	value, err := f.lookup(namespace, path)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, len(value))
+	copy(out, value)
+
+	// No error
+	return out, nil
+}
+
+// GetDigest returns the secret content digest.
+func (f *Fake) GetDigest(ctx context.Context, namespace, path string) (*client.Digest, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	value, err := f.lookup(namespace, path)
+	if err != nil {
+		return nil, err
+	}
+
+	// No error
+	return &client.Digest{
+		Value: storage.ContentDigest(value).Value,
+	}, nil
+}
+
+// ListPaths returns the immediate children of the given prefix.
+func (f *Fake) ListPaths(ctx context.Context, namespace, prefix string) ([]string, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	ns := clean(namespace)
+	switch {
+	case f.denied[ns]:
+		return nil, &client.ResponseError{StatusCode: http.StatusForbidden, Message: "access denied"}
+	case f.unlistable[ns]:
+		return nil, &client.ResponseError{StatusCode: http.StatusMethodNotAllowed, Message: "listing not supported"}
+	default:
+	}
+
+	// Collect namespace identifiers
+	ids := []string{}
+	for k := range f.secrets {
+		if strings.HasPrefix(k, ns+"/") {
+			ids = append(ids, strings.TrimPrefix(k, ns+"/"))
+		}
+	}
+	sort.Strings(ids)
+
+	page, err := storage.Paginate(ids, strings.Trim(prefix, "/"), storage.PageRequest{})
+	if err != nil {
+		return nil, &client.ResponseError{StatusCode: http.StatusBadRequest, Message: err.Error()}
+	}
+
+	// No error
+	return page.Keys, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (f *Fake) lookup(namespace, path string) ([]byte, error) {
+	if f.denied[clean(namespace)] {
+		return nil, &client.ResponseError{StatusCode: http.StatusForbidden, Message: "access denied"}
+	}
+
+	k := key(namespace, path)
+	value, ok := f.secrets[k]
+	if !ok {
+		return nil, &client.ResponseError{StatusCode: http.StatusNotFound, Message: "secret not found"}
+	}
+	if f.quarantined[k] {
+		return nil, &client.ResponseError{StatusCode: http.StatusGone, Message: "secret unavailable"}
+	}
+
+	return value, nil
+}
+
+func clean(namespace string) string {
+	return strings.Trim(namespace, "/")
+}
+
+func key(namespace, path string) string {
+	return clean(namespace) + "/" + strings.Trim(path, "/")
+}
@@ -0,0 +1,113 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package testclient
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/elastic/harp/pkg/client"
+)
+
+func TestFake(t *testing.T) {
+	ctx := context.Background()
+
+	f := New()
+	f.Set("app", "production/db", []byte(`{"user":"foo"}`))
+	f.Set("app", "production/cache/redis", []byte(`{}`))
+	f.Set("app", "staging/db", []byte(`{}`))
+
+	// Secret
+	value, err := f.GetSecret(ctx, "app", "/production/db")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(value) != `{"user":"foo"}` {
+		t.Fatalf("unexpected secret value: %s", value)
+	}
+	d, err := f.GetDigest(ctx, "app", "production/db")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(d.Value) != 64 {
+		t.Fatalf("unexpected digest: %q", d.Value)
+	}
+
+	// Listing
+	keys, err := f.ListPaths(ctx, "app", "production")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(keys, []string{"cache/", "db"}) {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+
+	// Typed errors
+	testCases := []struct {
+		name    string
+		prepare func()
+		call    func() error
+		wantErr error
+	}{
+		{
+			name: "not found",
+			call: func() error {
+				_, err := f.GetSecret(ctx, "app", "missing")
+				return err
+			},
+			wantErr: client.ErrNotFound,
+		},
+		{
+			name:    "quarantined",
+			prepare: func() { f.Quarantine("app", "staging/db", true) },
+			call: func() error {
+				_, err := f.GetDigest(ctx, "app", "staging/db")
+				return err
+			},
+			wantErr: client.ErrQuarantined,
+		},
+		{
+			name:    "list not supported",
+			prepare: func() { f.DisableList("app", true) },
+			call: func() error {
+				_, err := f.ListPaths(ctx, "app", "")
+				return err
+			},
+			wantErr: client.ErrListNotSupported,
+		},
+		{
+			name:    "denied",
+			prepare: func() { f.Deny("app", true) },
+			call: func() error {
+				_, err := f.GetSecret(ctx, "app", "production/db")
+				return err
+			},
+			wantErr: client.ErrForbidden,
+		},
+	}
+	for _, tc := range testCases {
+		if tc.prepare != nil {
+			tc.prepare()
+		}
+		if err := tc.call(); !errors.Is(err, tc.wantErr) {
+			t.Fatalf("%s: expected error %v, got %v", tc.name, tc.wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package compose
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Node kinds.
+const (
+	KindInput  = "input"
+	KindStep   = "step"
+	KindOutput = "output"
+)
+
+// Node describes a pipeline graph node.
+type Node struct {
+	Name      string
+	Kind      string
+	DependsOn []string
+}
+
+// Graph is the pipeline dependency graph, nodes are sorted in execution
+// order.
+type Graph struct {
+	Nodes []*Node
+}
+
+// BuildGraph returns the harpfile dependency graph. Nodes are ordered by
+// dependency, then by declaration order.
+func BuildGraph(hf *Harpfile) (*Graph, error) {
+	// Declare nodes
+	declared := []*Node{}
+	for _, in := range hf.Spec.Inputs {
+		declared = append(declared, &Node{Name: in.Name, Kind: KindInput, DependsOn: []string{}})
+	}
+	for _, s := range hf.Spec.Steps {
+		declared = append(declared, &Node{Name: s.Name, Kind: KindStep, DependsOn: uniqueNames(s.dependencies())})
+	}
+	for _, o := range hf.Spec.Outputs {
+		declared = append(declared, &Node{Name: o.Name, Kind: KindOutput, DependsOn: []string{o.From}})
+	}
+
+	// Sort topologically, keeping declaration order when possible
+	done := map[string]bool{}
+	sorted := []*Node{}
+	for len(sorted) < len(declared) {
+		progress := false
+		for _, n := range declared {
+			if done[n.Name] || !allDone(done, n.DependsOn) {
+				continue
+			}
+			done[n.Name] = true
+			sorted = append(sorted, n)
+			progress = true
+			break
+		}
+		if !progress {
+			pending := []string{}
+			for _, n := range declared {
+				if !done[n.Name] {
+					pending = append(pending, n.Name)
+				}
+			}
+			return nil, fmt.Errorf("%w: dependency cycle between %s", ErrInvalidHarpfile, strings.Join(pending, ", "))
+		}
+	}
+
+	return &Graph{Nodes: sorted}, nil
+}
+
+// Select returns the subgraph required to produce the given targets.
+func (g *Graph) Select(targets ...string) (*Graph, error) {
+	if len(targets) == 0 {
+		return g, nil
+	}
+
+	index := map[string]*Node{}
+	for _, n := range g.Nodes {
+		index[n.Name] = n
+	}
+
+	// Collect targets and their ancestors
+	required := map[string]bool{}
+	var visit func(name string)
+	visit = func(name string) {
+		if required[name] {
+			return
+		}
+		required[name] = true
+		for _, dep := range index[name].DependsOn {
+			visit(dep)
+		}
+	}
+	for _, t := range targets {
+		if _, ok := index[t]; !ok {
+			return nil, fmt.Errorf("unknown target '%s'", t)
+		}
+		visit(t)
+	}
+
+	res := &Graph{Nodes: []*Node{}}
+	for _, n := range g.Nodes {
+		if required[n.Name] {
+			res.Nodes = append(res.Nodes, n)
+		}
+	}
+
+	return res, nil
+}
+
+// Print writes the graph in execution order, one node per line with its
+// dependencies.
+func (g *Graph) Print(w io.Writer) error {
+	for _, n := range g.Nodes {
+		line := fmt.Sprintf("%s/%s", n.Kind, n.Name)
+		if len(n.DependsOn) > 0 {
+			line += " <- " + strings.Join(n.DependsOn, ", ")
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return fmt.Errorf("unable to print graph: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func allDone(done map[string]bool, names []string) bool {
+	for _, n := range names {
+		if !done[n] {
+			return false
+		}
+	}
+	return true
+}
+
+func uniqueNames(names []string) []string {
+	seen := map[string]bool{}
+	res := []string{}
+	for _, n := range names {
+		if !seen[n] {
+			seen[n] = true
+			res = append(res, n)
+		}
+	}
+	return res
+}
@@ -0,0 +1,331 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package compose executes declarative bundle composition pipelines.
+package compose
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/elastic/harp/pkg/bundle"
+)
+
+const (
+	// APIVersion is the supported harpfile api version.
+	APIVersion = "harp.elastic.co/v1"
+	// Kind is the harpfile kind.
+	Kind = "Harpfile"
+)
+
+// ErrInvalidHarpfile is raised when the harpfile content is not valid.
+var ErrInvalidHarpfile = errors.New("compose: invalid harpfile")
+
+// Harpfile describes a bundle composition pipeline.
+type Harpfile struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Spec       Spec   `json:"spec"`
+
+	// baseDir is used to resolve relative paths.
+	baseDir string
+}
+
+// Spec describes pipeline nodes.
+type Spec struct {
+	Inputs  []*Input  `json:"inputs"`
+	Steps   []*Step   `json:"steps,omitempty"`
+	Outputs []*Output `json:"outputs"`
+}
+
+// Input describes a pipeline source bundle.
+type Input struct {
+	Name string `json:"name"`
+	// Container is an unsealed container path.
+	Container string `json:"container,omitempty"`
+	// Template generates the bundle from a BundleTemplate.
+	Template *TemplateInput `json:"template,omitempty"`
+}
+
+// TemplateInput describes a BundleTemplate rendering.
+type TemplateInput struct {
+	Path   string   `json:"path"`
+	Values []string `json:"values,omitempty"`
+	Set    []string `json:"set,omitempty"`
+}
+
+// Step describes a pipeline operation. Exactly one operation must be set.
+type Step struct {
+	Name     string      `json:"name"`
+	Filter   *FilterOp   `json:"filter,omitempty"`
+	Rewrite  *RewriteOp  `json:"rewrite,omitempty"`
+	Merge    *MergeOp    `json:"merge,omitempty"`
+	Annotate *AnnotateOp `json:"annotate,omitempty"`
+	Validate *ValidateOp `json:"validate,omitempty"`
+	// Save persists the step result as an unsealed container.
+	Save string `json:"save,omitempty"`
+}
+
+// FilterOp selects packages of the source bundle.
+type FilterOp struct {
+	From         string   `json:"from"`
+	KeepPaths    []string `json:"keepPaths,omitempty"`
+	ExcludePaths []string `json:"excludePaths,omitempty"`
+	JMESPath     string   `json:"jmesPath,omitempty"`
+}
+
+// RewriteOp applies a BundlePatch to the source bundle.
+type RewriteOp struct {
+	From  string   `json:"from"`
+	Patch string   `json:"patch"`
+	Set   []string `json:"set,omitempty"`
+}
+
+// MergeOp merges source bundles in order.
+type MergeOp struct {
+	From     []string `json:"from"`
+	Strategy string   `json:"strategy,omitempty"`
+}
+
+// AnnotateOp assigns annotations and labels to all packages.
+type AnnotateOp struct {
+	From        string            `json:"from"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+}
+
+// ValidateOp lints the source bundle, the bundle is passed through when the
+// policy is satisfied.
+type ValidateOp struct {
+	From   string `json:"from"`
+	Policy string `json:"policy,omitempty"`
+}
+
+// Output describes a pipeline artifact written to disk.
+type Output struct {
+	Name      string    `json:"name"`
+	From      string    `json:"from"`
+	Container string    `json:"container"`
+	Seal      *SealOp   `json:"seal,omitempty"`
+	Attest    *AttestOp `json:"attest,omitempty"`
+}
+
+// SealOp seals the output container for the given recipients.
+type SealOp struct {
+	Identities []string `json:"identities"`
+	// ContainerKey writes the generated container key, the container identity
+	// is disabled when blank.
+	ContainerKey string `json:"containerKey,omitempty"`
+}
+
+// AttestOp signs an attestation of the written container. Attestations
+// describe the bundle content and can't be combined with sealing.
+type AttestOp struct {
+	Key       string `json:"key"`
+	Out       string `json:"out"`
+	Subject   string `json:"subject,omitempty"`
+	BuilderID string `json:"builderId,omitempty"`
+	Validity  string `json:"validity,omitempty"`
+}
+
+// Load reads and validates the harpfile from the given path. Relative paths
+// are resolved from the harpfile directory.
+func Load(path string) (*Harpfile, error) {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read harpfile: %w", err)
+	}
+
+	return Parse(strings.NewReader(string(body)), filepath.Dir(path))
+}
+
+// Parse reads and validates a harpfile. Relative paths are resolved from the
+// given base directory.
+func Parse(r io.Reader, baseDir string) (*Harpfile, error) {
+	// Check arguments
+	if r == nil {
+		return nil, errors.New("unable to parse nil reader")
+	}
+
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read harpfile: %w", err)
+	}
+
+	var hf Harpfile
+	if err := yaml.UnmarshalStrict(body, &hf); err != nil {
+		return nil, fmt.Errorf("unable to decode harpfile: %w", err)
+	}
+	hf.baseDir = baseDir
+
+	// Validate content
+	if err := hf.Validate(); err != nil {
+		return nil, err
+	}
+
+	// No error
+	return &hf, nil
+}
+
+// Validate the harpfile, all issues are reported at once.
+//
+//nolint:gocyclo // flat validation
+func (hf *Harpfile) Validate() error {
+	errs := []string{}
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Sprintf(format, args...))
+	}
+
+	if hf.APIVersion != APIVersion {
+		fail("apiVersion must be '%s'", APIVersion)
+	}
+	if hf.Kind != Kind {
+		fail("kind must be '%s'", Kind)
+	}
+	if len(hf.Spec.Inputs) == 0 {
+		fail("at least one input must be declared")
+	}
+	if len(hf.Spec.Outputs) == 0 {
+		fail("at least one output must be declared")
+	}
+
+	// Node names are unique across kinds
+	names := map[string]string{}
+	declare := func(kind, name string) {
+		if strings.TrimSpace(name) == "" {
+			fail("%s name must not be blank", kind)
+			return
+		}
+		if other, ok := names[name]; ok {
+			fail("%s '%s' is already declared as %s", kind, name, other)
+			return
+		}
+		names[name] = kind
+	}
+	// References must target inputs or steps
+	reference := func(kind, name, ref string) {
+		switch names[ref] {
+		case "input", "step":
+		default:
+			fail("%s '%s' references unknown input or step '%s'", kind, name, ref)
+		}
+	}
+
+	for _, in := range hf.Spec.Inputs {
+		declare("input", in.Name)
+		if (in.Container == "") == (in.Template == nil) {
+			fail("input '%s' must declare either a container or a template", in.Name)
+		}
+		if in.Template != nil && in.Template.Path == "" {
+			fail("input '%s' template path must not be blank", in.Name)
+		}
+	}
+	for _, s := range hf.Spec.Steps {
+		declare("step", s.Name)
+	}
+	for _, s := range hf.Spec.Steps {
+		if ops := s.operations(); ops != 1 {
+			fail("step '%s' must declare exactly one operation, got %d", s.Name, ops)
+			continue
+		}
+		for _, ref := range s.dependencies() {
+			reference("step", s.Name, ref)
+		}
+		if s.Rewrite != nil && s.Rewrite.Patch == "" {
+			fail("step '%s' rewrite patch must not be blank", s.Name)
+		}
+		if s.Merge != nil {
+			if len(s.Merge.From) < 2 {
+				fail("step '%s' merge must declare at least 2 sources", s.Name)
+			}
+			if s.Merge.Strategy != "" {
+				if _, err := bundle.ParseMergeStrategy(s.Merge.Strategy); err != nil {
+					fail("step '%s' %v", s.Name, err)
+				}
+			}
+		}
+	}
+	for _, o := range hf.Spec.Outputs {
+		declare("output", o.Name)
+		reference("output", o.Name, o.From)
+		if o.Container == "" {
+			fail("output '%s' container path must not be blank", o.Name)
+		}
+		if o.Seal != nil && len(o.Seal.Identities) == 0 {
+			fail("output '%s' seal must declare at least one identity", o.Name)
+		}
+		if o.Attest != nil && (o.Attest.Key == "" || o.Attest.Out == "") {
+			fail("output '%s' attest key and out paths must not be blank", o.Name)
+		}
+		if o.Attest != nil && o.Seal != nil {
+			fail("output '%s' attestation requires an unsealed container", o.Name)
+		}
+	}
+
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return fmt.Errorf("%w:\n  - %s", ErrInvalidHarpfile, strings.Join(errs, "\n  - "))
+	}
+
+	// No error
+	return nil
+}
+
+// path resolves the given path from the harpfile directory.
+func (hf *Harpfile) path(p string) string {
+	if p == "" || p == "-" || filepath.IsAbs(p) || strings.Contains(p, "://") {
+		return p
+	}
+	return filepath.Join(hf.baseDir, p)
+}
+
+// -----------------------------------------------------------------------------
+
+func (s *Step) operations() int {
+	count := 0
+	for _, set := range []bool{s.Filter != nil, s.Rewrite != nil, s.Merge != nil, s.Annotate != nil, s.Validate != nil} {
+		if set {
+			count++
+		}
+	}
+	return count
+}
+
+// dependencies returns the step source names.
+func (s *Step) dependencies() []string {
+	switch {
+	case s.Filter != nil:
+		return []string{s.Filter.From}
+	case s.Rewrite != nil:
+		return []string{s.Rewrite.From}
+	case s.Merge != nil:
+		return s.Merge.From
+	case s.Annotate != nil:
+		return []string{s.Annotate.From}
+	case s.Validate != nil:
+		return []string{s.Validate.From}
+	default:
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package compose
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const header = "apiVersion: harp.elastic.co/v1\nkind: Harpfile\n"
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		wantErr  bool
+		wantMsgs []string
+	}{
+		{
+			desc:    "unknown field",
+			input:   header + "spec:\n  foo: bar\n",
+			wantErr: true,
+		},
+		{
+			desc:     "empty",
+			input:    header + "spec: {}\n",
+			wantErr:  true,
+			wantMsgs: []string{"at least one input", "at least one output"},
+		},
+		{
+			desc: "aggregated errors",
+			input: `apiVersion: harp.elastic.co/v1
+kind: Harpfile
+spec:
+  inputs:
+    - name: base
+      container: base.bundle
+      template:
+        path: template.yaml
+  steps:
+    - name: base
+      filter:
+        from: base
+    - name: both
+      filter:
+        from: base
+      annotate:
+        from: base
+    - name: merged
+      merge:
+        from: [base]
+        strategy: random
+  outputs:
+    - name: prod
+      from: missing
+      seal: {}
+      attest:
+        key: signing.pem
+        out: prod.att
+`,
+			wantErr: true,
+			wantMsgs: []string{
+				"input 'base' must declare either a container or a template",
+				"step 'base' is already declared as input",
+				"step 'both' must declare exactly one operation, got 2",
+				"step 'merged' merge must declare at least 2 sources",
+				"step 'merged' unsupported merge strategy 'random'",
+				"output 'prod' references unknown input or step 'missing'",
+				"output 'prod' container path must not be blank",
+				"output 'prod' seal must declare at least one identity",
+				"output 'prod' attestation requires an unsealed container",
+			},
+		},
+		{
+			desc: "output reference",
+			input: header + `spec:
+  inputs:
+    - name: base
+      container: base.bundle
+  outputs:
+    - name: first
+      from: base
+      container: first.bundle
+    - name: second
+      from: first
+      container: second.bundle
+`,
+			wantErr:  true,
+			wantMsgs: []string{"output 'second' references unknown input or step 'first'"},
+		},
+		{
+			desc: "valid",
+			input: header + `spec:
+  inputs:
+    - name: base
+      container: base.bundle
+  outputs:
+    - name: prod
+      from: base
+      container: prod.bundle
+`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tC.input), "")
+			if (err != nil) != tC.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tC.wantErr)
+			}
+			for _, msg := range tC.wantMsgs {
+				if !errors.Is(err, ErrInvalidHarpfile) || !strings.Contains(err.Error(), msg) {
+					t.Errorf("error should contain %q, got %v", msg, err)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildGraph(t *testing.T) {
+	hf, err := Parse(strings.NewReader(header+`spec:
+  inputs:
+    - name: base
+      container: base.bundle
+    - name: overrides
+      container: overrides.bundle
+  steps:
+    - name: validated
+      validate:
+        from: merged
+    - name: merged
+      merge:
+        from: [base, overrides, base]
+    - name: labeled
+      annotate:
+        from: validated
+        labels:
+          env: production
+  outputs:
+    - name: prod
+      from: labeled
+      container: prod.bundle
+    - name: raw
+      from: overrides
+      container: raw.bundle
+`), "/tmp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := BuildGraph(hf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := g.Print(&out); err != nil {
+		t.Fatal(err)
+	}
+	want := `input/base
+input/overrides
+step/merged <- base, overrides
+step/validated <- merged
+step/labeled <- validated
+output/prod <- labeled
+output/raw <- overrides
+`
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("Graph.Print()\n-want/+got\ndiff %s", diff)
+	}
+
+	// Select a subset
+	sub, err := g.Select("raw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, n := range sub.Nodes {
+		got = append(got, n.Name)
+	}
+	if diff := cmp.Diff([]string{"overrides", "raw"}, got); diff != "" {
+		t.Errorf("Graph.Select()\n-want/+got\ndiff %s", diff)
+	}
+
+	if _, err := g.Select("unknown"); err == nil {
+		t.Error("error should be raised for unknown target")
+	}
+}
+
+func TestBuildGraph_Cycle(t *testing.T) {
+	hf, err := Parse(strings.NewReader(header+`spec:
+  inputs:
+    - name: base
+      container: base.bundle
+  steps:
+    - name: a
+      filter:
+        from: b
+    - name: b
+      filter:
+        from: a
+  outputs:
+    - name: prod
+      from: base
+      container: prod.bundle
+`), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := BuildGraph(hf); !errors.Is(err, ErrInvalidHarpfile) || !strings.Contains(err.Error(), "cycle between a, b") {
+		t.Errorf("cycle error should be raised, got %v", err)
+	}
+}
@@ -0,0 +1,620 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package compose
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/lint"
+	"github.com/elastic/harp/pkg/tasks"
+	tasksbundle "github.com/elastic/harp/pkg/tasks/bundle"
+	"github.com/elastic/harp/pkg/tasks/container"
+	"github.com/elastic/harp/pkg/tasks/from"
+	tplcmdutil "github.com/elastic/harp/pkg/template/cmdutil"
+	"github.com/elastic/harp/pkg/template/engine"
+)
+
+// Files binds harpfile paths to data providers.
+type Files struct {
+	Reader func(path string) tasks.ReaderProvider
+	Writer func(path string) tasks.WriterProvider
+}
+
+// Options defines plan execution settings.
+type Options struct {
+	// Targets restricts the execution to the given nodes and their
+	// dependencies, all nodes are executed when empty.
+	Targets []string
+	// Actor is recorded in secret key history by rewrite and merge steps.
+	Actor string
+	// HistoryLimit is the maximum history entry count kept per secret key.
+	HistoryLimit int
+	// Parallelism is the maximum count of nodes executed concurrently, nodes
+	// are executed sequentially by default.
+	Parallelism int
+	// MaxInflightBytes limits the estimated memory used by concurrently
+	// executed nodes, no limit is applied when zero.
+	MaxInflightBytes int64
+	// FailFast skips all pending nodes on the first failure, only nodes
+	// depending on a failure are skipped otherwise.
+	FailFast bool
+}
+
+// NodeError describes a failed pipeline node.
+type NodeError struct {
+	Node string
+	Kind string
+	Err  error
+}
+
+func (e *NodeError) Error() string {
+	return fmt.Sprintf("%s '%s': %v", e.Kind, e.Node, e.Err)
+}
+
+func (e *NodeError) Unwrap() error {
+	return e.Err
+}
+
+// Error aggregates the failures of a pipeline execution. Nodes depending on
+// a failed node are skipped, independent nodes are still executed.
+type Error struct {
+	Failed  []*NodeError
+	Skipped []string
+}
+
+func (e *Error) Error() string {
+	msgs := []string{}
+	for _, f := range e.Failed {
+		msgs = append(msgs, f.Error())
+	}
+	res := fmt.Sprintf("%d node(s) failed:\n  - %s", len(e.Failed), strings.Join(msgs, "\n  - "))
+	if len(e.Skipped) > 0 {
+		res += fmt.Sprintf("\nskipped: %s", strings.Join(e.Skipped, ", "))
+	}
+	return res
+}
+
+// Result describes a plan execution with a report per node. Nodes running a
+// task reporting a structured result embed it.
+type Result struct {
+	tasks.Result
+	Nodes []*tasks.Report `json:"nodes"`
+}
+
+// Plan is a harpfile bound to its data providers.
+type Plan struct {
+	graph   *Graph
+	opts    Options
+	runners map[string]runner
+	result  *Result
+}
+
+// artifact is a node output container. The package count estimates the
+// memory used by dependent nodes, it's negative when unknown to the runner.
+type artifact struct {
+	data     []byte
+	packages int
+}
+
+// runner executes a node, artifacts returns the result of a previous node.
+// The node task result is returned when available.
+type runner func(ctx context.Context, artifacts map[string]*artifact) (*artifact, interface{}, error)
+
+// NewPlan prepares the execution of the given harpfile. All data providers
+// are bound before execution.
+func NewPlan(hf *Harpfile, files Files, opts Options) (*Plan, error) {
+	// Check arguments
+	if hf == nil {
+		return nil, errors.New("unable to prepare plan for nil harpfile")
+	}
+	if files.Reader == nil || files.Writer == nil {
+		return nil, errors.New("unable to prepare plan without file providers")
+	}
+	if opts.HistoryLimit == 0 {
+		opts.HistoryLimit = bundle.DefaultHistoryLimit
+	}
+	if opts.Parallelism < 1 {
+		opts.Parallelism = 1
+	}
+	if opts.MaxInflightBytes < 0 {
+		return nil, errors.New("unable to prepare plan with a negative in-flight bytes limit")
+	}
+
+	// Build dependency graph
+	g, err := BuildGraph(hf)
+	if err != nil {
+		return nil, err
+	}
+	g, err = g.Select(opts.Targets...)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &binder{hf: hf, files: files, opts: opts}
+	p := &Plan{
+		graph:   g,
+		opts:    opts,
+		runners: map[string]runner{},
+	}
+	for _, n := range g.Nodes {
+		r, err := b.bind(n)
+		if err != nil {
+			return nil, fmt.Errorf("unable to prepare %s '%s': %w", n.Kind, n.Name, err)
+		}
+		p.runners[n.Name] = r
+	}
+
+	// No error
+	return p, nil
+}
+
+// Graph returns the plan dependency graph.
+func (p *Plan) Graph() *Graph {
+	return p.graph
+}
+
+// Capabilities returns the task required capabilities.
+func (p *Plan) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Result returns the last execution result.
+func (p *Plan) Result() interface{} {
+	return p.result
+}
+
+// Run the plan. Intermediate artifacts are kept in memory, only outputs and
+// saved steps are written. Independent nodes are executed concurrently
+// according to the plan options, reports are returned in graph order.
+func (p *Plan) Run(ctx context.Context) error {
+	// Cancel running nodes on fail fast
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s := newScheduler(p)
+	for {
+		// Start ready nodes
+		s.schedule(runCtx)
+		if s.running == 0 {
+			break
+		}
+
+		// Wait for a node completion
+		s.complete(<-s.done)
+		if s.failed && p.opts.FailFast && !s.cancelled {
+			s.cancelled = true
+			cancel()
+		}
+		s.flush(ctx)
+	}
+	s.flush(ctx)
+
+	// Aggregate node reports in graph order
+	res := &Error{}
+	p.result = &Result{Nodes: []*tasks.Report{}}
+	for _, st := range s.nodes {
+		p.result.Nodes = append(p.result.Nodes, st.report)
+		switch st.report.Status {
+		case tasks.StatusFailed:
+			p.result.Fail(st.report.Task, st.err)
+			res.Failed = append(res.Failed, &NodeError{Node: st.node.Name, Kind: st.node.Kind, Err: st.err})
+		case tasks.StatusSkipped:
+			res.Skipped = append(res.Skipped, st.node.Name)
+		default:
+		}
+	}
+
+	if len(res.Failed) > 0 {
+		return res
+	}
+
+	// No error
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+type binder struct {
+	hf    *Harpfile
+	files Files
+	opts  Options
+}
+
+func (b *binder) bind(n *Node) (runner, error) {
+	switch n.Kind {
+	case KindInput:
+		for _, in := range b.hf.Spec.Inputs {
+			if in.Name == n.Name {
+				return b.input(in)
+			}
+		}
+	case KindStep:
+		for _, s := range b.hf.Spec.Steps {
+			if s.Name == n.Name {
+				return b.step(s)
+			}
+		}
+	case KindOutput:
+		for _, o := range b.hf.Spec.Outputs {
+			if o.Name == n.Name {
+				return b.output(o)
+			}
+		}
+	default:
+	}
+
+	return nil, fmt.Errorf("unknown node '%s'", n.Name)
+}
+
+func (b *binder) input(in *Input) (runner, error) {
+	// Container input
+	if in.Container != "" {
+		reader := b.files.Reader(b.hf.path(in.Container))
+		return func(ctx context.Context, _ map[string]*artifact) (*artifact, interface{}, error) {
+			out, err := readAll(ctx, reader)
+			if err != nil {
+				return nil, nil, err
+			}
+			return &artifact{data: out, packages: -1}, nil, nil
+		}, nil
+	}
+
+	// Template values are resolved before execution
+	valueFiles := []string{}
+	for _, vf := range in.Template.Values {
+		valueFiles = append(valueFiles, b.hf.path(vf))
+	}
+	values, err := (&tplcmdutil.ValueOptions{
+		ValueFiles: valueFiles,
+		Values:     in.Template.Set,
+	}).MergeValues()
+	if err != nil {
+		return nil, fmt.Errorf("unable to process template values: %w", err)
+	}
+
+	path := b.hf.path(in.Template.Path)
+	reader := b.files.Reader(path)
+	return func(ctx context.Context, _ map[string]*artifact) (*artifact, interface{}, error) {
+		return run(ctx, -1, func(w tasks.WriterProvider) tasks.Task {
+			return &from.BundleTemplateTask{
+				TemplateReader: reader,
+				OutputWriter:   w,
+				TemplateContext: engine.NewContext(
+					engine.WithName(path),
+					engine.WithValues(values),
+				),
+			}
+		})
+	}, nil
+}
+
+func (b *binder) step(s *Step) (runner, error) {
+	r, err := b.operation(s)
+	if err != nil || s.Save == "" {
+		return r, err
+	}
+
+	// Persist step result on request
+	writer := b.files.Writer(b.hf.path(s.Save))
+	return func(ctx context.Context, artifacts map[string]*artifact) (*artifact, interface{}, error) {
+		out, result, err := r(ctx, artifacts)
+		if err != nil {
+			return nil, result, err
+		}
+		if err := writeAll(ctx, writer, out.data); err != nil {
+			return nil, result, fmt.Errorf("unable to save step result: %w", err)
+		}
+		return out, result, nil
+	}, nil
+}
+
+//nolint:funlen // one case per operation
+func (b *binder) operation(s *Step) (runner, error) {
+	switch {
+	case s.Filter != nil:
+		op := s.Filter
+		return func(ctx context.Context, artifacts map[string]*artifact) (*artifact, interface{}, error) {
+			return run(ctx, artifacts[op.From].packages, func(w tasks.WriterProvider) tasks.Task {
+				return &tasksbundle.FilterTask{
+					ContainerReader: memReader(artifacts[op.From].data),
+					OutputWriter:    w,
+					KeepPaths:       op.KeepPaths,
+					ExcludePaths:    op.ExcludePaths,
+					JMESPath:        op.JMESPath,
+				}
+			})
+		}, nil
+
+	case s.Rewrite != nil:
+		op := s.Rewrite
+		values, err := (&tplcmdutil.ValueOptions{Values: op.Set}).MergeValues()
+		if err != nil {
+			return nil, fmt.Errorf("unable to process patch values: %w", err)
+		}
+		patch := b.files.Reader(b.hf.path(op.Patch))
+		return func(ctx context.Context, artifacts map[string]*artifact) (*artifact, interface{}, error) {
+			return run(ctx, artifacts[op.From].packages, func(w tasks.WriterProvider) tasks.Task {
+				return &tasksbundle.PatchTask{
+					PatchReader:     patch,
+					ContainerReader: memReader(artifacts[op.From].data),
+					OutputWriter:    w,
+					Values:          values,
+					Actor:           b.opts.Actor,
+					HistoryLimit:    b.opts.HistoryLimit,
+				}
+			})
+		}, nil
+
+	case s.Merge != nil:
+		op := s.Merge
+		strategy := bundle.MergeStrategyOverwrite
+		if op.Strategy != "" {
+			strategy = bundle.MergeStrategy(op.Strategy)
+		}
+		return func(ctx context.Context, artifacts map[string]*artifact) (*artifact, interface{}, error) {
+			return run(ctx, -1, func(w tasks.WriterProvider) tasks.Task {
+				readers := []tasks.ReaderProvider{}
+				for _, name := range op.From {
+					readers = append(readers, memReader(artifacts[name].data))
+				}
+				return &tasksbundle.MergeTask{
+					ContainerReaders: readers,
+					OutputWriter:     w,
+					MergeStrategy:    strategy,
+					Actor:            b.opts.Actor,
+					HistoryLimit:     b.opts.HistoryLimit,
+				}
+			})
+		}, nil
+
+	case s.Annotate != nil:
+		op := s.Annotate
+		return func(ctx context.Context, artifacts map[string]*artifact) (*artifact, interface{}, error) {
+			src, err := bundle.FromContainerReader(bytes.NewReader(artifacts[op.From].data))
+			if err != nil {
+				return nil, nil, fmt.Errorf("unable to load '%s': %w", op.From, err)
+			}
+			for _, p := range src.Packages {
+				if p.Annotations == nil && len(op.Annotations) > 0 {
+					p.Annotations = map[string]string{}
+				}
+				for k, v := range op.Annotations {
+					p.Annotations[k] = v
+				}
+				if p.Labels == nil && len(op.Labels) > 0 {
+					p.Labels = map[string]string{}
+				}
+				for k, v := range op.Labels {
+					p.Labels[k] = v
+				}
+			}
+			out, err := dump(src)
+			if err != nil {
+				return nil, nil, err
+			}
+			return &artifact{data: out, packages: len(src.Packages)}, nil, nil
+		}, nil
+
+	case s.Validate != nil:
+		op := s.Validate
+		var policy tasks.ReaderProvider
+		if op.Policy != "" {
+			policy = b.files.Reader(b.hf.path(op.Policy))
+		}
+		return func(ctx context.Context, artifacts map[string]*artifact) (*artifact, interface{}, error) {
+			var report bytes.Buffer
+			t := &tasksbundle.LintTask{
+				ContainerReader: memReader(artifacts[op.From].data),
+				PolicyReader:    policy,
+				OutputWriter:    memWriter(&report),
+			}
+			err := t.Run(ctx)
+			if errors.Is(err, tasksbundle.ErrLintViolations) {
+				return nil, t.Result(), fmt.Errorf("%w: %s", err, violations(report.Bytes()))
+			}
+			if err != nil {
+				return nil, t.Result(), err
+			}
+
+			// Pass through
+			return artifacts[op.From], t.Result(), nil
+		}, nil
+
+	default:
+	}
+
+	return nil, fmt.Errorf("step '%s' has no operation", s.Name)
+}
+
+func (b *binder) output(o *Output) (runner, error) {
+	var (
+		writer    = b.files.Writer(b.hf.path(o.Container))
+		keyWriter tasks.WriterProvider
+		attest    *container.AttestTask
+	)
+
+	if o.Seal != nil && o.Seal.ContainerKey != "" {
+		keyWriter = b.files.Writer(b.hf.path(o.Seal.ContainerKey))
+	}
+	if o.Attest != nil {
+		var validity time.Duration
+		if o.Attest.Validity != "" {
+			d, err := time.ParseDuration(o.Attest.Validity)
+			if err != nil {
+				return nil, fmt.Errorf("invalid attestation validity: %w", err)
+			}
+			validity = d
+		}
+		subject := o.Attest.Subject
+		if subject == "" {
+			subject = filepath.Base(o.Container)
+		}
+		attest = &container.AttestTask{
+			KeyReader:    b.files.Reader(b.hf.path(o.Attest.Key)),
+			OutputWriter: b.files.Writer(b.hf.path(o.Attest.Out)),
+			SubjectName:  subject,
+			BuilderID:    o.Attest.BuilderID,
+			Validity:     validity,
+		}
+	}
+
+	return func(ctx context.Context, artifacts map[string]*artifact) (*artifact, interface{}, error) {
+		in := artifacts[o.From]
+		out := in.data
+
+		// Seal container
+		if o.Seal != nil {
+			var sealed bytes.Buffer
+			t := &container.SealTask{
+				ContainerReader:          memReader(out),
+				SealedContainerWriter:    memWriter(&sealed),
+				OutputWriter:             memWriter(&bytes.Buffer{}),
+				Identities:               o.Seal.Identities,
+				JSONOutput:               true,
+				DisableContainerIdentity: keyWriter == nil,
+			}
+			if keyWriter != nil {
+				t.OutputWriter = keyWriter
+			}
+			if err := t.Run(ctx); err != nil {
+				return nil, nil, err
+			}
+			out = sealed.Bytes()
+		}
+
+		// Write container
+		if err := writeAll(ctx, writer, out); err != nil {
+			return nil, nil, fmt.Errorf("unable to write container: %w", err)
+		}
+
+		// Attest written container
+		if attest != nil {
+			t := *attest
+			t.ContainerReader = memReader(out)
+			if err := t.Run(ctx); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		return &artifact{data: out, packages: in.packages}, nil, nil
+	}, nil
+}
+
+// -----------------------------------------------------------------------------
+
+// run executes a task writing its output in memory, the task result is
+// returned when available. The output package count is taken from the task
+// result, packages is used for tasks not reporting it.
+func run(ctx context.Context, packages int, build func(w tasks.WriterProvider) tasks.Task) (*artifact, interface{}, error) {
+	var (
+		out    bytes.Buffer
+		result interface{}
+	)
+
+	t := build(memWriter(&out))
+	err := t.Run(ctx)
+	if r, ok := t.(tasks.Reporter); ok {
+		result = r.Result()
+	}
+	if err != nil {
+		return nil, result, err
+	}
+
+	switch res := result.(type) {
+	case *tasksbundle.FilterResult:
+		packages = res.Kept + res.Archived
+	case *tasksbundle.MergeResult:
+		packages = res.Packages
+	default:
+	}
+
+	return &artifact{data: out.Bytes(), packages: packages}, result, nil
+}
+
+func dump(b *bundlev1.Bundle) ([]byte, error) {
+	var out bytes.Buffer
+	if err := bundle.ToContainerWriter(&out, b); err != nil {
+		return nil, fmt.Errorf("unable to dump bundle content: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+func memReader(data []byte) tasks.ReaderProvider {
+	return func(context.Context) (io.Reader, error) {
+		return bytes.NewReader(data), nil
+	}
+}
+
+func memWriter(buf *bytes.Buffer) tasks.WriterProvider {
+	return func(context.Context) (io.Writer, error) {
+		return buf, nil
+	}
+}
+
+func readAll(ctx context.Context, rp tasks.ReaderProvider) ([]byte, error) {
+	reader, err := rp(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(reader)
+}
+
+func writeAll(ctx context.Context, wp tasks.WriterProvider, data []byte) error {
+	writer, err := wp(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(data)
+	return err
+}
+
+// violations summarizes unwaived lint findings.
+func violations(raw []byte) string {
+	var report lint.Report
+	if err := json.Unmarshal(raw, &report); err != nil {
+		return "unable to decode lint report"
+	}
+
+	res := []string{}
+	for _, f := range report.Findings {
+		if !f.Waived {
+			res = append(res, fmt.Sprintf("%s (%s)", f.RuleID, f.Path))
+		}
+	}
+	return strings.Join(res, ", ")
+}
+
+func anyOf(set map[string]bool, names []string) bool {
+	for _, n := range names {
+		if set[n] {
+			return true
+		}
+	}
+	return false
+}
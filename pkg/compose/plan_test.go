@@ -0,0 +1,410 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package compose
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+	"github.com/elastic/harp/pkg/container"
+	"github.com/elastic/harp/pkg/container/identity"
+	"github.com/elastic/harp/pkg/sdk/security/crypto"
+	"github.com/elastic/harp/pkg/tasks"
+	taskscontainer "github.com/elastic/harp/pkg/tasks/container"
+)
+
+const dbPath = "app/production/billing/payments/1.0.0/api/database"
+
+func osFiles(t *testing.T) Files {
+	return Files{
+		Reader: func(path string) tasks.ReaderProvider {
+			return func(context.Context) (io.Reader, error) {
+				return os.Open(path)
+			}
+		},
+		Writer: func(path string) tasks.WriterProvider {
+			return func(context.Context) (io.Writer, error) {
+				f, err := os.Create(path)
+				if err == nil {
+					t.Cleanup(func() { f.Close() })
+				}
+				return f, err
+			}
+		},
+	}
+}
+
+func writeFile(t *testing.T, path string, body []byte) {
+	if err := ioutil.WriteFile(path, body, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func loadFile(t *testing.T, path string) *bundlev1.Bundle {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	b, err := bundle.FromContainerReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func listDir(t *testing.T, dir string) []string {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := []string{}
+	for _, e := range entries {
+		res = append(res, e.Name())
+	}
+	sort.Strings(res)
+	return res
+}
+
+func secrets(t *testing.T, b *bundlev1.Bundle, path string) bundle.KV {
+	kv, err := bundle.Read(b, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kv
+}
+
+const pipeline = `apiVersion: harp.elastic.co/v1
+kind: Harpfile
+spec:
+  inputs:
+    - name: base
+      container: base.bundle
+    - name: overrides
+      container: overrides.bundle
+    - name: infra
+      template:
+        path: template.yaml
+        values: [values.yaml]
+        set: [account=123456789]
+  steps:
+    - name: merged
+      merge:
+        from: [base, overrides, infra]
+    - name: rotated
+      rewrite:
+        from: merged
+        patch: patch.yaml
+        set: [tag=v2]
+      save: rotated.bundle
+    - name: labeled
+      annotate:
+        from: rotated
+        labels:
+          env: production
+    - name: validated
+      validate:
+        from: labeled
+  outputs:
+    - name: prod
+      from: validated
+      container: prod.bundle
+      seal:
+        identities: [%IDENTITY%]
+    - name: raw
+      from: overrides
+      container: raw.bundle
+      attest:
+        key: signing.pem
+        out: raw.att
+        builderId: ci@runner
+`
+
+const template = `apiVersion: harp.elastic.co/v1
+kind: BundleTemplate
+meta:
+  name: "infra"
+  owner: security@elastic.co
+  description: "Infrastructure secrets"
+spec:
+  namespaces:
+    infrastructure:
+    - provider: "aws"
+      account: "{{ .Values.account }}"
+      description: "AWS Account"
+      regions:
+      - name: "us-east-1"
+        services:
+        - type: "rds"
+          name: "adminconsole"
+          description: "Database"
+          secrets:
+          - suffix: "accounts/root_credentials"
+            description: "Root account"
+            template: |-
+              {"user": "{{ .Values.user }}", "password": "{{ paranoidPassword | b64enc }}"}
+`
+
+const patch = `apiVersion: harp.elastic.co/v1
+kind: BundlePatch
+meta:
+  name: tag
+spec:
+  rules:
+    - selector:
+        matchPath:
+          strict: ` + dbPath + `
+      package:
+        data:
+          kv:
+            add:
+              tag: "{{ .Values.tag }}"
+`
+
+func prepare(t *testing.T, basePassword string) (dir, signingPub string) {
+	dir = t.TempDir()
+
+	base := testbundle.New().Package(dbPath).Secret("user", "payments").Secret("password", basePassword).Build()
+	overrides := testbundle.New().Package(dbPath).Secret("password", "Nj8!vQz2#pLw5Rt7^yXe").Build()
+	writeFile(t, filepath.Join(dir, "base.bundle"), testbundle.Container(t, base))
+	writeFile(t, filepath.Join(dir, "overrides.bundle"), testbundle.Container(t, overrides))
+	writeFile(t, filepath.Join(dir, "template.yaml"), []byte(template))
+	writeFile(t, filepath.Join(dir, "values.yaml"), []byte("user: dbroot\n"))
+	writeFile(t, filepath.Join(dir, "patch.yaml"), []byte(patch))
+
+	// Recipient identity
+	id, _, err := identity.New("security officer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, "harpfile.yaml"), bytes.ReplaceAll([]byte(pipeline), []byte("%IDENTITY%"), []byte(id.Public)))
+
+	// Attestation key
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privPEM, err := crypto.ToPEM(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubPEM, err := crypto.ToPEM(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, "signing.pem"), []byte(privPEM))
+
+	return dir, pubPEM
+}
+
+func TestPlan_Run(t *testing.T) {
+	dir, pubPEM := prepare(t, "Zr4#Wq9!kLp2@Vn6$Ty8")
+
+	hf, err := Load(filepath.Join(dir, "harpfile.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := NewPlan(hf, osFiles(t), Options{Actor: "ci@runner"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := plan.Run(context.Background()); err != nil {
+		t.Fatalf("unable to run plan: %v", err)
+	}
+
+	// Only outputs and saved steps are written
+	want := []string{
+		"base.bundle", "harpfile.yaml", "overrides.bundle", "patch.yaml",
+		"prod.bundle", "raw.att", "raw.bundle", "rotated.bundle",
+		"signing.pem", "template.yaml", "values.yaml",
+	}
+	if diff := cmp.Diff(want, listDir(t, dir)); diff != "" {
+		t.Errorf("unexpected files\n-want/+got\ndiff %s", diff)
+	}
+
+	// Saved step
+	rotated := loadFile(t, filepath.Join(dir, "rotated.bundle"))
+	if diff := cmp.Diff(bundle.KV{"user": "payments", "password": "Nj8!vQz2#pLw5Rt7^yXe", "tag": "v2"}, secrets(t, rotated, dbPath)); diff != "" {
+		t.Errorf("unexpected rotated secrets\n-want/+got\ndiff %s", diff)
+	}
+	for _, p := range rotated.Packages {
+		if p.Name != dbPath {
+			continue
+		}
+		for key, op := range map[string]string{"password": "merge", "tag": "patch"} {
+			entries, err := bundle.History(p, key)
+			if err != nil || len(entries) != 1 || entries[0].Operation != op || entries[0].Actor != "ci@runner" {
+				t.Errorf("unexpected '%s' history: %+v (%v)", key, entries, err)
+			}
+		}
+	}
+	infra := secrets(t, rotated, "infra/aws/123456789/us-east-1/rds/adminconsole/accounts/root_credentials")
+	if infra["user"] != "dbroot" || infra["password"] == "" {
+		t.Errorf("unexpected generated secrets %v", infra)
+	}
+
+	// Sealed output
+	f, err := os.Open(filepath.Join(dir, "prod.bundle"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	c, err := container.Load(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Headers.Recipients) != 1 {
+		t.Errorf("output should be sealed for 1 recipient, got %d", len(c.Headers.Recipients))
+	}
+
+	// Attestation matches the written container
+	if err := (&taskscontainer.VerifyAttestationTask{
+		ContainerReader:   osFiles(t).Reader(filepath.Join(dir, "raw.bundle")),
+		AttestationReader: osFiles(t).Reader(filepath.Join(dir, "raw.att")),
+		KeyReader:         func(context.Context) (io.Reader, error) { return bytes.NewReader([]byte(pubPEM)), nil },
+		OutputWriter:      func(context.Context) (io.Writer, error) { return ioutil.Discard, nil },
+	}).Run(context.Background()); err != nil {
+		t.Errorf("unable to verify attestation: %v", err)
+	}
+}
+
+func TestPlan_Run_Parallel(t *testing.T) {
+	dir, _ := prepare(t, "Zr4#Wq9!kLp2@Vn6$Ty8")
+
+	hf, err := Load(filepath.Join(dir, "harpfile.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := NewPlan(hf, osFiles(t), Options{Actor: "ci@runner", Parallelism: 4, MaxInflightBytes: 1 << 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := plan.Run(context.Background()); err != nil {
+		t.Fatalf("unable to run plan: %v", err)
+	}
+
+	for _, name := range []string{"prod.bundle", "rotated.bundle", "raw.bundle", "raw.att"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("'%s' should be written: %v", name, err)
+		}
+	}
+	rotated := loadFile(t, filepath.Join(dir, "rotated.bundle"))
+	if diff := cmp.Diff(bundle.KV{"user": "payments", "password": "Nj8!vQz2#pLw5Rt7^yXe", "tag": "v2"}, secrets(t, rotated, dbPath)); diff != "" {
+		t.Errorf("unexpected rotated secrets\n-want/+got\ndiff %s", diff)
+	}
+}
+
+func TestPlan_Run_Targets(t *testing.T) {
+	dir, _ := prepare(t, "Zr4#Wq9!kLp2@Vn6$Ty8")
+
+	hf, err := Load(filepath.Join(dir, "harpfile.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := NewPlan(hf, osFiles(t), Options{Targets: []string{"raw"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := plan.Run(context.Background()); err != nil {
+		t.Fatalf("unable to run plan: %v", err)
+	}
+
+	for _, name := range []string{"prod.bundle", "rotated.bundle"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("'%s' should not be written", name)
+		}
+	}
+	for _, name := range []string{"raw.bundle", "raw.att"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("'%s' should be written: %v", name, err)
+		}
+	}
+}
+
+func TestPlan_Run_Failure(t *testing.T) {
+	dir, _ := prepare(t, "changeme")
+
+	hf, err := Load(filepath.Join(dir, "harpfile.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Keep the weak base password
+	hf.Spec.Steps[0].Merge.Strategy = string(bundle.MergeStrategyKeep)
+
+	plan, err := NewPlan(hf, osFiles(t), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = plan.Run(context.Background())
+	var perr *Error
+	if !errors.As(err, &perr) {
+		t.Fatalf("pipeline error should be raised, got %v", err)
+	}
+	if len(perr.Failed) != 1 || perr.Failed[0].Node != "validated" {
+		t.Errorf("unexpected failures %v", err)
+	}
+	if diff := cmp.Diff([]string{"prod"}, perr.Skipped); diff != "" {
+		t.Errorf("unexpected skipped nodes\n-want/+got\ndiff %s", diff)
+	}
+
+	// Node reports are kept for failed and skipped nodes
+	res, ok := plan.Result().(*Result)
+	if !ok {
+		t.Fatalf("unexpected plan result %T", plan.Result())
+	}
+	statuses := map[string]string{}
+	for _, r := range res.Nodes {
+		statuses[r.Task] = r.Status
+	}
+	if got := statuses["step/validated"]; got != tasks.StatusFailed {
+		t.Errorf("'validated' step should be failed, got %q", got)
+	}
+	if got := statuses["output/prod"]; got != tasks.StatusSkipped {
+		t.Errorf("'prod' output should be skipped, got %q", got)
+	}
+	if got := statuses["output/raw"]; got != tasks.StatusSucceeded {
+		t.Errorf("'raw' output should be succeeded, got %q", got)
+	}
+	if len(res.Failures) != 1 || res.Failures[0].Item != "step/validated" {
+		t.Errorf("unexpected failures %+v", res.Failures)
+	}
+
+	// Independent output is still written
+	if _, err := os.Stat(filepath.Join(dir, "raw.bundle")); err != nil {
+		t.Errorf("'raw.bundle' should be written: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "prod.bundle")); !os.IsNotExist(err) {
+		t.Error("'prod.bundle' should not be written")
+	}
+}
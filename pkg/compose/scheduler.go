@@ -0,0 +1,329 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package compose
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// estimatedPackageSize is the in-memory size estimate of a decoded package,
+// used to account in-flight bytes of a node from its input package counts.
+const estimatedPackageSize = 16 << 10
+
+const (
+	statePending = iota
+	stateRunning
+	stateDone
+)
+
+type nodeState struct {
+	node       *Node
+	report     *tasks.Report
+	state      int
+	err        error
+	estimate   int64
+	dependents int
+	logs       *logBuffer
+}
+
+type nodeDone struct {
+	name     string
+	out      *artifact
+	result   interface{}
+	err      error
+	duration time.Duration
+}
+
+// scheduler executes plan nodes as soon as their dependencies succeeded. It
+// is only accessed from the Run goroutine, nodes receive a copy of their
+// input artifacts.
+type scheduler struct {
+	plan      *Plan
+	nodes     []*nodeState
+	index     map[string]*nodeState
+	artifacts map[string]*artifact
+	done      chan *nodeDone
+	running   int
+	inflight  int64
+	flushed   int
+	failed    bool
+	cancelled bool
+}
+
+func newScheduler(p *Plan) *scheduler {
+	s := &scheduler{
+		plan:      p,
+		nodes:     []*nodeState{},
+		index:     map[string]*nodeState{},
+		artifacts: map[string]*artifact{},
+		done:      make(chan *nodeDone, len(p.graph.Nodes)),
+	}
+	for _, n := range p.graph.Nodes {
+		st := &nodeState{
+			node: n,
+			report: &tasks.Report{
+				SchemaVersion: tasks.ReportSchemaVersion,
+				Task:          n.Kind + "/" + n.Name,
+				Status:        tasks.StatusSkipped,
+			},
+		}
+		s.nodes = append(s.nodes, st)
+		s.index[n.Name] = st
+	}
+	for _, st := range s.nodes {
+		for _, dep := range st.node.DependsOn {
+			s.index[dep].dependents++
+		}
+	}
+
+	return s
+}
+
+// schedule skips nodes depending on a failure and starts ready nodes within
+// the parallelism and in-flight bytes limits.
+func (s *scheduler) schedule(ctx context.Context) {
+	opts := s.plan.opts
+	for _, st := range s.nodes {
+		if st.state != statePending {
+			continue
+		}
+
+		// Skip nodes depending on a failure
+		if s.blocked(st) || (s.failed && opts.FailFast) {
+			s.finish(st, tasks.StatusSkipped, nil)
+			continue
+		}
+		if !s.ready(st) || s.running >= opts.Parallelism {
+			continue
+		}
+
+		// Check memory budget, a node exceeding it runs alone
+		st.estimate = s.estimate(st)
+		if opts.MaxInflightBytes > 0 && s.running > 0 && s.inflight+st.estimate > opts.MaxInflightBytes {
+			continue
+		}
+
+		s.start(ctx, st)
+	}
+}
+
+func (s *scheduler) start(ctx context.Context, st *nodeState) {
+	st.state = stateRunning
+	s.running++
+	s.inflight += st.estimate
+
+	// Give a copy of input artifacts
+	inputs := map[string]*artifact{}
+	for _, dep := range st.node.DependsOn {
+		inputs[dep] = s.artifacts[dep]
+	}
+
+	// Buffer node logs
+	st.logs = newLogBuffer()
+	nodeCtx := log.WithLogger(ctx, zap.New(st.logs))
+
+	run := s.plan.runners[st.node.Name]
+	go func(name string) {
+		start := time.Now()
+		out, result, err := run(nodeCtx, inputs)
+		s.done <- &nodeDone{
+			name:     name,
+			out:      out,
+			result:   result,
+			err:      err,
+			duration: time.Since(start),
+		}
+	}(st.node.Name)
+}
+
+func (s *scheduler) complete(d *nodeDone) {
+	st := s.index[d.name]
+	s.running--
+	s.inflight -= st.estimate
+	st.report.DurationMs = d.duration.Milliseconds()
+	st.report.Result = d.result
+
+	switch {
+	case d.err != nil && s.cancelled && errors.Is(d.err, context.Canceled):
+		s.finish(st, tasks.StatusSkipped, nil)
+	case d.err != nil:
+		s.failed = true
+		s.finish(st, tasks.StatusFailed, d.err)
+	case st.dependents == 0:
+		s.finish(st, tasks.StatusSucceeded, nil)
+	default:
+		s.artifacts[d.name] = d.out
+		s.finish(st, tasks.StatusSucceeded, nil)
+	}
+}
+
+func (s *scheduler) finish(st *nodeState, status string, err error) {
+	st.state = stateDone
+	st.report.Status = status
+	st.err = err
+	if err != nil {
+		st.report.Error = err.Error()
+	}
+
+	// Release consumed artifacts
+	for _, dep := range st.node.DependsOn {
+		parent := s.index[dep]
+		parent.dependents--
+		if parent.dependents == 0 {
+			delete(s.artifacts, dep)
+		}
+	}
+}
+
+// flush writes buffered node logs in graph order, up to the first unfinished
+// node.
+func (s *scheduler) flush(ctx context.Context) {
+	for ; s.flushed < len(s.nodes); s.flushed++ {
+		st := s.nodes[s.flushed]
+		if st.state != stateDone {
+			return
+		}
+		if st.logs == nil {
+			continue
+		}
+
+		logger := log.For(ctx).With(zap.String("node", st.report.Task))
+		for _, e := range st.logs.entries() {
+			switch e.Level {
+			case zapcore.DebugLevel:
+				logger.Debug(e.Message, e.Fields...)
+			case zapcore.InfoLevel:
+				logger.Info(e.Message, e.Fields...)
+			case zapcore.WarnLevel:
+				logger.Warn(e.Message, e.Fields...)
+			default:
+				logger.Error(e.Message, e.Fields...)
+			}
+		}
+	}
+}
+
+func (s *scheduler) blocked(st *nodeState) bool {
+	for _, dep := range st.node.DependsOn {
+		if d := s.index[dep]; d.state == stateDone && d.report.Status != tasks.StatusSucceeded {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *scheduler) ready(st *nodeState) bool {
+	for _, dep := range st.node.DependsOn {
+		if s.index[dep].report.Status != tasks.StatusSucceeded {
+			return false
+		}
+	}
+	return true
+}
+
+// estimate returns the in-flight bytes estimate of the given node. Input
+// nodes are not accounted, their size is unknown before loading. Artifacts
+// with an unknown package count are accounted by their encoded size.
+func (s *scheduler) estimate(st *nodeState) int64 {
+	var res int64
+	for _, dep := range st.node.DependsOn {
+		a, ok := s.artifacts[dep]
+		switch {
+		case !ok:
+		case a.packages < 0:
+			res += int64(len(a.data))
+		default:
+			res += int64(a.packages) * estimatedPackageSize
+		}
+	}
+	return res
+}
+
+// -----------------------------------------------------------------------------
+
+// logEntry is a buffered log entry.
+type logEntry struct {
+	Level   zapcore.Level
+	Message string
+	Fields  []zapcore.Field
+}
+
+// logBuffer is a zap core buffering node log entries, they are written in
+// graph order once the node is done.
+type logBuffer struct {
+	store  *logStore
+	fields []zapcore.Field
+}
+
+type logStore struct {
+	sync.Mutex
+	entries []logEntry
+}
+
+var _ zapcore.Core = (*logBuffer)(nil)
+
+func newLogBuffer() *logBuffer {
+	return &logBuffer{store: &logStore{}}
+}
+
+func (b *logBuffer) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (b *logBuffer) With(fields []zapcore.Field) zapcore.Core {
+	return &logBuffer{
+		store:  b.store,
+		fields: append(append([]zapcore.Field{}, b.fields...), fields...),
+	}
+}
+
+func (b *logBuffer) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(e, b)
+}
+
+func (b *logBuffer) Write(e zapcore.Entry, fields []zapcore.Field) error {
+	b.store.Lock()
+	defer b.store.Unlock()
+
+	b.store.entries = append(b.store.entries, logEntry{
+		Level:   e.Level,
+		Message: e.Message,
+		Fields:  append(append([]zapcore.Field{}, b.fields...), fields...),
+	})
+	return nil
+}
+
+func (b *logBuffer) Sync() error {
+	return nil
+}
+
+func (b *logBuffer) entries() []logEntry {
+	b.store.Lock()
+	defer b.store.Unlock()
+
+	return b.store.entries
+}
@@ -0,0 +1,404 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package compose
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// probe records node executions.
+type probe struct {
+	mu         sync.Mutex
+	running    map[string]bool
+	maxRunning int
+	events     []string
+}
+
+func newProbe() *probe {
+	return &probe{running: map[string]bool{}}
+}
+
+// runner returns a runner recording its execution, and running the given
+// function while registered as running.
+func (p *probe) runner(name string, fn func(ctx context.Context) error) runner {
+	return func(ctx context.Context, _ map[string]*artifact) (*artifact, interface{}, error) {
+		p.mu.Lock()
+		p.running[name] = true
+		if len(p.running) > p.maxRunning {
+			p.maxRunning = len(p.running)
+		}
+		p.events = append(p.events, "start:"+name)
+		p.mu.Unlock()
+
+		var err error
+		if fn != nil {
+			err = fn(ctx)
+		}
+
+		p.mu.Lock()
+		delete(p.running, name)
+		p.events = append(p.events, "end:"+name)
+		p.mu.Unlock()
+
+		return &artifact{data: []byte(name), packages: -1}, nil, err
+	}
+}
+
+// before returns true if the first event happened before the second one.
+func (p *probe) before(first, second string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, e := range p.events {
+		switch e {
+		case first:
+			return true
+		case second:
+			return false
+		default:
+		}
+	}
+	return false
+}
+
+// barrier returns a function blocking until count callers are waiting.
+func barrier(t *testing.T, count int) func(ctx context.Context) error {
+	var wg sync.WaitGroup
+	wg.Add(count)
+	return func(ctx context.Context) error {
+		wg.Done()
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+			return nil
+		case <-time.After(5 * time.Second):
+			t.Error("nodes are not executed concurrently")
+			return errors.New("barrier timeout")
+		}
+	}
+}
+
+// diamond returns 'in -> left, right -> join -> out' and an independent
+// 'other -> side' branch.
+func diamond() *Graph {
+	return &Graph{Nodes: []*Node{
+		{Name: "in", Kind: KindInput, DependsOn: []string{}},
+		{Name: "other", Kind: KindInput, DependsOn: []string{}},
+		{Name: "left", Kind: KindStep, DependsOn: []string{"in"}},
+		{Name: "right", Kind: KindStep, DependsOn: []string{"in"}},
+		{Name: "join", Kind: KindStep, DependsOn: []string{"left", "right"}},
+		{Name: "out", Kind: KindOutput, DependsOn: []string{"join"}},
+		{Name: "side", Kind: KindOutput, DependsOn: []string{"other"}},
+	}}
+}
+
+func testPlan(g *Graph, runners map[string]runner, opts Options) *Plan {
+	if opts.Parallelism < 1 {
+		opts.Parallelism = 1
+	}
+	return &Plan{graph: g, opts: opts, runners: runners}
+}
+
+func statuses(t *testing.T, p *Plan) map[string]string {
+	res, ok := p.Result().(*Result)
+	if !ok {
+		t.Fatalf("unexpected plan result %T", p.Result())
+	}
+	out := map[string]string{}
+	for _, r := range res.Nodes {
+		out[r.Task] = r.Status
+	}
+	return out
+}
+
+// -----------------------------------------------------------------------------
+
+func TestPlan_Run_Parallel_Diamond(t *testing.T) {
+	pr := newProbe()
+	together := barrier(t, 2)
+	plan := testPlan(diamond(), map[string]runner{
+		"in":    pr.runner("in", nil),
+		"other": pr.runner("other", nil),
+		"left":  pr.runner("left", together),
+		"right": pr.runner("right", together),
+		"join":  pr.runner("join", nil),
+		"out":   pr.runner("out", nil),
+		"side":  pr.runner("side", nil),
+	}, Options{Parallelism: 2})
+
+	if err := plan.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Dependencies are honored
+	for _, c := range [][2]string{
+		{"end:in", "start:left"},
+		{"end:in", "start:right"},
+		{"end:left", "start:join"},
+		{"end:right", "start:join"},
+		{"end:join", "start:out"},
+		{"end:other", "start:side"},
+	} {
+		if !pr.before(c[0], c[1]) {
+			t.Errorf("expected '%s' before '%s' in %v", c[0], c[1], pr.events)
+		}
+	}
+	if pr.maxRunning != 2 {
+		t.Errorf("expected 2 concurrent nodes, got %d", pr.maxRunning)
+	}
+
+	// Reports are in graph order
+	res := plan.Result().(*Result)
+	got := []string{}
+	for _, r := range res.Nodes {
+		got = append(got, r.Task+"="+r.Status)
+	}
+	want := []string{
+		"input/in=succeeded", "input/other=succeeded", "step/left=succeeded", "step/right=succeeded",
+		"step/join=succeeded", "output/out=succeeded", "output/side=succeeded",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected reports (-want +got):\n%s", diff)
+	}
+}
+
+func TestPlan_Run_Parallelism_Limit(t *testing.T) {
+	pr := newProbe()
+	g := &Graph{Nodes: []*Node{{Name: "in", Kind: KindInput, DependsOn: []string{}}}}
+	runners := map[string]runner{"in": pr.runner("in", nil)}
+	for i := 0; i < 8; i++ {
+		name := fmt.Sprintf("dist%d", i)
+		g.Nodes = append(g.Nodes, &Node{Name: name, Kind: KindOutput, DependsOn: []string{"in"}})
+		runners[name] = pr.runner(name, func(context.Context) error {
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		})
+	}
+
+	if err := testPlan(g, runners, Options{Parallelism: 3}).Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pr.maxRunning > 3 {
+		t.Errorf("expected at most 3 concurrent nodes, got %d", pr.maxRunning)
+	}
+}
+
+func TestPlan_Run_Parallel_Failure(t *testing.T) {
+	injected := errors.New("injected failure")
+
+	testCases := []struct {
+		desc     string
+		failFast bool
+		want     map[string]string
+		skipped  []string
+	}{
+		{
+			desc: "independent branches finish",
+			want: map[string]string{
+				"input/in": tasks.StatusSucceeded, "input/other": tasks.StatusSucceeded,
+				"step/left": tasks.StatusFailed, "step/right": tasks.StatusSucceeded,
+				"step/join": tasks.StatusSkipped, "output/out": tasks.StatusSkipped,
+				"output/side": tasks.StatusSucceeded,
+			},
+			skipped: []string{"join", "out"},
+		},
+		{
+			desc:     "fail fast",
+			failFast: true,
+			want: map[string]string{
+				"input/in": tasks.StatusSucceeded, "input/other": tasks.StatusSucceeded,
+				"step/left": tasks.StatusFailed, "step/right": tasks.StatusSkipped,
+				"step/join": tasks.StatusSkipped, "output/out": tasks.StatusSkipped,
+				"output/side": tasks.StatusSkipped,
+			},
+			skipped: []string{"right", "join", "out", "side"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			pr := newProbe()
+			gate := make(chan struct{})
+			started := make(chan struct{})
+			plan := testPlan(diamond(), map[string]runner{
+				"in": pr.runner("in", nil),
+				"other": pr.runner("other", func(context.Context) error {
+					// Side branch waits for the failure
+					<-gate
+					return nil
+				}),
+				"left": pr.runner("left", func(context.Context) error {
+					<-started
+					defer close(gate)
+					return injected
+				}),
+				"right": pr.runner("right", func(ctx context.Context) error {
+					close(started)
+					if !tC.failFast {
+						return nil
+					}
+					select {
+					case <-ctx.Done():
+						return ctx.Err()
+					case <-time.After(5 * time.Second):
+						return errors.New("running node not cancelled")
+					}
+				}),
+				"join": pr.runner("join", nil),
+				"out":  pr.runner("out", nil),
+				"side": pr.runner("side", nil),
+			}, Options{Parallelism: 4, FailFast: tC.failFast})
+
+			err := plan.Run(context.Background())
+			var perr *Error
+			if !errors.As(err, &perr) {
+				t.Fatalf("pipeline error should be raised, got %v", err)
+			}
+			if len(perr.Failed) != 1 || !errors.Is(perr.Failed[0], injected) {
+				t.Errorf("unexpected failures %v", err)
+			}
+			if diff := cmp.Diff(tC.skipped, perr.Skipped); diff != "" {
+				t.Errorf("unexpected skipped nodes (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tC.want, statuses(t, plan)); diff != "" {
+				t.Errorf("unexpected statuses (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPlan_Run_MaxInflightBytes(t *testing.T) {
+	// Two inputs of 10 packages
+	container := func(prefix string) []byte {
+		b := testbundle.New()
+		for i := 0; i < 10; i++ {
+			b.Package(fmt.Sprintf("app/production/%s/%d", prefix, i)).Secret("key", "value")
+		}
+		return testbundle.Container(t, b.Build())
+	}
+	input := func(data []byte) runner {
+		return func(context.Context, map[string]*artifact) (*artifact, interface{}, error) {
+			return &artifact{data: data, packages: 10}, nil, nil
+		}
+	}
+	g := &Graph{Nodes: []*Node{
+		{Name: "a", Kind: KindInput, DependsOn: []string{}},
+		{Name: "b", Kind: KindInput, DependsOn: []string{}},
+		{Name: "huge-a", Kind: KindOutput, DependsOn: []string{"a"}},
+		{Name: "huge-b", Kind: KindOutput, DependsOn: []string{"b"}},
+	}}
+	slow := func(context.Context) error {
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	}
+
+	testCases := []struct {
+		desc   string
+		budget int64
+		want   int
+	}{
+		{desc: "unlimited", budget: 0, want: 2},
+		{desc: "one branch fits", budget: 15 * estimatedPackageSize, want: 1},
+		{desc: "branch exceeds budget", budget: 1, want: 1},
+		{desc: "both branches fit", budget: 20 * estimatedPackageSize, want: 2},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			pr := newProbe()
+			runners := map[string]runner{
+				"a":      input(container("a")),
+				"b":      input(container("b")),
+				"huge-a": pr.runner("huge-a", slow),
+				"huge-b": pr.runner("huge-b", slow),
+			}
+			if tC.want == 2 {
+				together := barrier(t, 2)
+				runners["huge-a"] = pr.runner("huge-a", together)
+				runners["huge-b"] = pr.runner("huge-b", together)
+			}
+
+			if err := testPlan(g, runners, Options{Parallelism: 4, MaxInflightBytes: tC.budget}).Run(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if pr.maxRunning != tC.want {
+				t.Errorf("expected %d concurrent branches, got %d", tC.want, pr.maxRunning)
+			}
+		})
+	}
+}
+
+func TestPlan_Run_Logs(t *testing.T) {
+	// Right branch completes first
+	leftGate := make(chan struct{})
+	logged := func(name string, fn func()) runner {
+		return func(ctx context.Context, _ map[string]*artifact) (*artifact, interface{}, error) {
+			if fn != nil {
+				fn()
+			}
+			log.For(ctx).Info(name + " started")
+			log.For(ctx).With(zap.String("step", name)).Warn(name + " finished")
+			return &artifact{data: []byte(name), packages: -1}, nil, nil
+		}
+	}
+	plan := testPlan(diamond(), map[string]runner{
+		"in":    logged("in", nil),
+		"other": logged("other", nil),
+		"left":  logged("left", func() { <-leftGate }),
+		"right": logged("right", func() { close(leftGate) }),
+		"join":  logged("join", nil),
+		"out":   logged("out", nil),
+		"side":  logged("side", nil),
+	}, Options{Parallelism: 4})
+
+	core, observed := observer.New(zapcore.DebugLevel)
+	if err := plan.Run(log.WithLogger(context.Background(), zap.New(core))); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := []string{}
+	for _, e := range observed.AllUntimed() {
+		got = append(got, fmt.Sprintf("%s|%s|%s|%v", e.ContextMap()["node"], e.Level, e.Message, e.ContextMap()["step"]))
+	}
+	want := []string{}
+	for _, n := range diamond().Nodes {
+		want = append(want,
+			fmt.Sprintf("%s/%s|info|%s started|<nil>", n.Kind, n.Name, n.Name),
+			fmt.Sprintf("%s/%s|warn|%s finished|%s", n.Kind, n.Name, n.Name, n.Name),
+		)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected logs (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,197 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package attestation
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/template/consumption"
+)
+
+func testContainer(t *testing.T, password string) []byte {
+	t.Helper()
+
+	b, err := bundle.FromMap(map[string]bundle.KV{
+		"app/production/billing/payments/1.0.0/api/database": {"password": password},
+	})
+	if err != nil {
+		t.Fatalf("unable to prepare bundle: %v", err)
+	}
+	b.Template = &bundlev1.Template{ApiVersion: "harp.elastic.co/v1", Kind: "BundleTemplate"}
+
+	var buf bytes.Buffer
+	if err := bundle.ToContainerWriter(&buf, b); err != nil {
+		t.Fatalf("unable to prepare container: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func testKeys(t *testing.T) map[string]crypto.Signer {
+	t.Helper()
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string]crypto.Signer{"ed25519": edKey, "ecdsa": ecKey, "rsa": rsaKey}
+}
+
+func TestSignVerify(t *testing.T) {
+	raw := testContainer(t, "foo")
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	for name, key := range testKeys(t) {
+		t.Run(name, func(t *testing.T) {
+			s, err := NewStatement(raw, Options{Name: "secrets.harp", BuilderID: "ci@runner", Validity: time.Hour, Now: now})
+			if err != nil {
+				t.Fatalf("unable to build statement: %v", err)
+			}
+			if s.Predicate.Bundle.PackageCount != 1 || s.Predicate.Bundle.TemplateDigest[DigestSHA256] == "" {
+				t.Errorf("unexpected bundle content %+v", s.Predicate.Bundle)
+			}
+
+			env, err := Sign(s, key)
+			if err != nil {
+				t.Fatalf("unable to sign statement: %v", err)
+			}
+
+			got, err := Verify(env, key.Public())
+			if err != nil {
+				t.Fatalf("unable to verify envelope: %v", err)
+			}
+			if err := got.Check(raw, now.Add(time.Minute)); err != nil {
+				t.Errorf("unexpected check error: %v", err)
+			}
+		})
+	}
+}
+
+func TestStatement_Consumption(t *testing.T) {
+	raw := testContainer(t, "foo")
+	key := testKeys(t)["ed25519"]
+	manifest := &consumption.Manifest{
+		Records: []consumption.Record{
+			{Source: "vault", Path: "legacy/database", Key: "password", Digest: "sha256:0123"},
+		},
+	}
+
+	s, err := NewStatement(raw, Options{Name: "secrets.harp", BuilderID: "ci@runner", Now: time.Now(), Consumption: manifest})
+	if err != nil {
+		t.Fatalf("unable to build statement: %v", err)
+	}
+	env, err := Sign(s, key)
+	if err != nil {
+		t.Fatalf("unable to sign statement: %v", err)
+	}
+
+	// Manifest is embedded in the signed predicate
+	got, err := Verify(env, key.Public())
+	if err != nil {
+		t.Fatalf("unable to verify envelope: %v", err)
+	}
+	if got.Predicate.Consumption == nil || len(got.Predicate.Consumption.Records) != 1 || got.Predicate.Consumption.Records[0] != manifest.Records[0] {
+		t.Errorf("unexpected consumption manifest %+v", got.Predicate.Consumption)
+	}
+}
+
+func TestVerify_Tampered(t *testing.T) {
+	raw := testContainer(t, "foo")
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	key := testKeys(t)["ed25519"]
+	other := testKeys(t)["ecdsa"]
+
+	s, err := NewStatement(raw, Options{Name: "secrets.harp", BuilderID: "ci@runner", Validity: time.Hour, Now: now})
+	if err != nil {
+		t.Fatalf("unable to build statement: %v", err)
+	}
+	env, err := Sign(s, key)
+	if err != nil {
+		t.Fatalf("unable to sign statement: %v", err)
+	}
+
+	t.Run("tampered payload", func(t *testing.T) {
+		forged := *s
+		forged.Predicate.Builder.ID = "attacker"
+		payload, _ := json.Marshal(&forged)
+
+		tampered := *env
+		tampered.Payload = base64.StdEncoding.EncodeToString(payload)
+		if _, err := Verify(&tampered, key.Public()); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("expected ErrInvalidSignature, got %v", err)
+		}
+	})
+
+	t.Run("tampered signature", func(t *testing.T) {
+		sig, _ := base64.StdEncoding.DecodeString(env.Signatures[0].Sig)
+		sig[0] ^= 0xff
+
+		tampered := *env
+		tampered.Signatures = []Signature{{KeyID: env.Signatures[0].KeyID, Sig: base64.StdEncoding.EncodeToString(sig)}}
+		if _, err := Verify(&tampered, key.Public()); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("expected ErrInvalidSignature, got %v", err)
+		}
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		if _, err := Verify(env, other.Public()); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("expected ErrInvalidSignature, got %v", err)
+		}
+	})
+
+	t.Run("tampered container", func(t *testing.T) {
+		got, err := Verify(env, key.Public())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := got.Check(testContainer(t, "bar"), now); !errors.Is(err, ErrDigestMismatch) {
+			t.Errorf("expected ErrDigestMismatch, got %v", err)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		got, err := Verify(env, key.Public())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := got.Check(raw, now.Add(time.Hour)); !errors.Is(err, ErrExpired) {
+			t.Errorf("expected ErrExpired, got %v", err)
+		}
+	})
+}
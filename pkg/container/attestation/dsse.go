@@ -0,0 +1,283 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package attestation
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// PayloadType is the DSSE payload type of in-toto statements.
+const PayloadType = "application/vnd.in-toto+json"
+
+// ErrInvalidSignature is raised when no envelope signature can be verified.
+var ErrInvalidSignature = errors.New("invalid attestation signature")
+
+// Envelope describes a DSSE envelope.
+type Envelope struct {
+	PayloadType string      `json:"payloadType"`
+	Payload     string      `json:"payload"`
+	Signatures  []Signature `json:"signatures"`
+}
+
+// Signature describes a DSSE envelope signature.
+type Signature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+	// Signer describes the remote key which produced the signature, nil for
+	// local keys.
+	Signer *SignerInfo `json:"signer,omitempty"`
+}
+
+// SignerInfo describes a remote signing key.
+type SignerInfo struct {
+	// Type is the signer type (vault-transit).
+	Type string `json:"type"`
+	// KeyName is the remote key name.
+	KeyName string `json:"keyName"`
+	// KeyVersion is the remote key version used to sign.
+	KeyVersion int `json:"keyVersion,omitempty"`
+}
+
+// RemoteSigner is implemented by signers backed by a remote key, the signer
+// description is embedded in produced signatures.
+type RemoteSigner interface {
+	crypto.Signer
+	SignerInfo() *SignerInfo
+}
+
+// KeyResolver returns the public key used to verify the given signature.
+type KeyResolver func(sig Signature) (crypto.PublicKey, error)
+
+// Sign wraps the statement in a DSSE envelope signed with the given key.
+//
+// Ed25519 keys sign the pre-authentication encoding directly, ECDSA keys sign
+// its digest using the curve sized SHA-2 function (ASN.1 signature), RSA keys
+// sign its SHA-256 digest using PKCS#1 v1.5.
+func Sign(s *Statement, signer crypto.Signer) (*Envelope, error) {
+	// Check arguments
+	if s == nil {
+		return nil, fmt.Errorf("unable to sign a nil statement")
+	}
+
+	// Encode statement
+	payload, err := json.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode statement: %w", err)
+	}
+
+	return SignPayload(PayloadType, payload, signer)
+}
+
+// SignPayload wraps the given payload in a DSSE envelope signed with the
+// given key, as Sign does for statements.
+func SignPayload(payloadType string, payload []byte, signer crypto.Signer) (*Envelope, error) {
+	// Check arguments
+	if payloadType == "" {
+		return nil, fmt.Errorf("unable to sign a payload without type")
+	}
+	if signer == nil {
+		return nil, fmt.Errorf("unable to sign with a nil key")
+	}
+
+	// Compute key identifier
+	keyID, err := KeyID(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	// Sign the pre-authentication encoding
+	message, opts, err := signedMessage(signer.Public(), pae(payloadType, payload))
+	if err != nil {
+		return nil, err
+	}
+	sig, err := signer.Sign(rand.Reader, message, opts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to sign payload: %w", err)
+	}
+
+	// Describe remote key
+	var info *SignerInfo
+	if rs, ok := signer.(RemoteSigner); ok {
+		info = rs.SignerInfo()
+	}
+
+	// Assemble envelope
+	return &Envelope{
+		PayloadType: payloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures: []Signature{
+			{KeyID: keyID, Sig: base64.StdEncoding.EncodeToString(sig), Signer: info},
+		},
+	}, nil
+}
+
+// Verify checks the envelope signatures with the given public key and returns
+// the enclosed statement.
+func Verify(env *Envelope, pub crypto.PublicKey) (*Statement, error) {
+	// Check arguments
+	if pub == nil {
+		return nil, fmt.Errorf("unable to verify with a nil key")
+	}
+
+	return VerifyWith(env, func(Signature) (crypto.PublicKey, error) {
+		return pub, nil
+	})
+}
+
+// VerifyWith checks the envelope signatures with the public keys returned by
+// the given resolver and returns the enclosed statement. Signatures which
+// can't be resolved are skipped.
+func VerifyWith(env *Envelope, resolve KeyResolver) (*Statement, error) {
+	payload, err := VerifyPayload(env, PayloadType, resolve)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode statement
+	var s Statement
+	if err := json.Unmarshal(payload, &s); err != nil {
+		return nil, fmt.Errorf("unable to decode statement: %w", err)
+	}
+
+	// No error
+	return &s, nil
+}
+
+// VerifyPayload checks the envelope payload type and signatures with the
+// public keys returned by the given resolver, and returns the decoded
+// payload.
+func VerifyPayload(env *Envelope, payloadType string, resolve KeyResolver) ([]byte, error) {
+	// Check arguments
+	if env == nil {
+		return nil, fmt.Errorf("unable to verify a nil envelope")
+	}
+	if resolve == nil {
+		return nil, fmt.Errorf("unable to verify with a nil key resolver")
+	}
+	if env.PayloadType != payloadType {
+		return nil, fmt.Errorf("unsupported payload type '%s'", env.PayloadType)
+	}
+
+	// Decode payload
+	payload, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode envelope payload: %w", err)
+	}
+
+	// Look for a valid signature
+	verified := false
+	var lastErr error
+	for _, s := range env.Signatures {
+		pub, errResolve := resolve(s)
+		if errResolve != nil {
+			lastErr = errResolve
+			continue
+		}
+		message, opts, errMessage := signedMessage(pub, pae(env.PayloadType, payload))
+		if errMessage != nil {
+			lastErr = errMessage
+			continue
+		}
+		sig, errDecode := base64.StdEncoding.DecodeString(s.Sig)
+		if errDecode != nil {
+			continue
+		}
+		if verifySignature(pub, message, sig, opts) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		if lastErr != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, lastErr)
+		}
+		return nil, ErrInvalidSignature
+	}
+
+	// No error
+	return payload, nil
+}
+
+// KeyID returns the hex encoded SHA-256 digest of the PKIX encoded public key.
+func KeyID(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", fmt.Errorf("unable to encode public key: %w", err)
+	}
+	h := sha256.Sum256(der)
+	return hex.EncodeToString(h[:]), nil
+}
+
+// -----------------------------------------------------------------------------
+
+// pae returns the DSSE v1 pre-authentication encoding.
+func pae(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
+
+func signedMessage(pub crypto.PublicKey, message []byte) ([]byte, crypto.SignerOpts, error) {
+	var h crypto.Hash
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		return message, crypto.Hash(0), nil
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			h = crypto.SHA256
+		case elliptic.P384():
+			h = crypto.SHA384
+		case elliptic.P521():
+			h = crypto.SHA512
+		default:
+			return nil, nil, fmt.Errorf("unsupported ECDSA curve '%s'", k.Curve.Params().Name)
+		}
+	case *rsa.PublicKey:
+		h = crypto.SHA256
+	default:
+		return nil, nil, fmt.Errorf("unsupported key type %T", pub)
+	}
+
+	hasher := h.New()
+	hasher.Write(message)
+	return hasher.Sum(nil), h, nil
+}
+
+func verifySignature(pub crypto.PublicKey, message, sig []byte, opts crypto.SignerOpts) bool {
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, message, sig)
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(k, message, sig)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, opts.HashFunc(), message, sig) == nil
+	default:
+	}
+	return false
+}
@@ -0,0 +1,243 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package attestation
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/container"
+	"github.com/elastic/harp/pkg/template/consumption"
+)
+
+const (
+	// StatementType is the supported in-toto statement type.
+	StatementType = "https://in-toto.io/Statement/v0.1"
+	// PredicateType is the harp bundle content predicate type.
+	PredicateType = "https://harp.elastic.co/attestation/bundle/v1"
+
+	// DigestSHA256 is the subject digest algorithm name.
+	DigestSHA256 = "sha256"
+)
+
+var (
+	// ErrDigestMismatch is raised when the container doesn't match the
+	// attested subject.
+	ErrDigestMismatch = errors.New("container digest mismatch")
+	// ErrExpired is raised when the attestation validity is over.
+	ErrExpired = errors.New("attestation expired")
+)
+
+// Statement describes an in-toto statement about a secret container.
+type Statement struct {
+	Type          string    `json:"_type"`
+	Subject       []Subject `json:"subject"`
+	PredicateType string    `json:"predicateType"`
+	Predicate     Predicate `json:"predicate"`
+}
+
+// Subject describes an attested artifact.
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// Predicate describes the harp bundle content attestation.
+type Predicate struct {
+	Builder  Builder  `json:"builder"`
+	Bundle   Content  `json:"bundle"`
+	Metadata Metadata `json:"metadata"`
+	// Consumption lists the existing secret values read to generate the
+	// bundle, if known.
+	Consumption *consumption.Manifest `json:"consumption,omitempty"`
+}
+
+// Builder describes the identity which produced the attestation.
+type Builder struct {
+	ID string `json:"id"`
+}
+
+// Content describes the attested bundle content.
+type Content struct {
+	// MerkleRoot is the hex encoded Blake2b-512 merkle tree root of package
+	// secrets.
+	MerkleRoot   string `json:"merkleRoot"`
+	PackageCount int    `json:"packageCount"`
+	SecretCount  int    `json:"secretCount"`
+	// TemplateDigest is the digest of the bundle template specification used
+	// to generate the bundle, if any.
+	TemplateDigest map[string]string `json:"templateDigest,omitempty"`
+}
+
+// Metadata describes attestation timestamps.
+type Metadata struct {
+	IssuedOn  time.Time  `json:"issuedOn"`
+	ExpiresOn *time.Time `json:"expiresOn,omitempty"`
+}
+
+// Options describes statement generation settings.
+type Options struct {
+	// Name is the container subject name.
+	Name string
+	// BuilderID identifies the attestation issuer.
+	BuilderID string
+	// Validity sets the attestation expiration (0 for no expiration).
+	Validity time.Duration
+	// Now is the issuance time.
+	Now time.Time
+	// Consumption is the optional consumption manifest of the render which
+	// produced the bundle.
+	Consumption *consumption.Manifest
+}
+
+// NewStatement builds a statement describing the given raw unsealed
+// container.
+func NewStatement(raw []byte, opts Options) (*Statement, error) {
+	// Check arguments
+	if opts.Name == "" {
+		return nil, fmt.Errorf("subject name must not be blank")
+	}
+	if opts.BuilderID == "" {
+		return nil, fmt.Errorf("builder identity must not be blank")
+	}
+	if opts.Validity < 0 {
+		return nil, fmt.Errorf("validity must be positive")
+	}
+
+	// Load the bundle
+	content, err := bundleContent(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	// Prepare metadata
+	issuedOn := opts.Now.UTC().Truncate(time.Second)
+	metadata := Metadata{IssuedOn: issuedOn}
+	if opts.Validity > 0 {
+		exp := issuedOn.Add(opts.Validity)
+		metadata.ExpiresOn = &exp
+	}
+
+	// Assemble statement
+	return &Statement{
+		Type: StatementType,
+		Subject: []Subject{
+			{Name: opts.Name, Digest: map[string]string{DigestSHA256: digest(raw)}},
+		},
+		PredicateType: PredicateType,
+		Predicate: Predicate{
+			Builder:     Builder{ID: opts.BuilderID},
+			Bundle:      *content,
+			Metadata:    metadata,
+			Consumption: opts.Consumption,
+		},
+	}, nil
+}
+
+// Check validates that the given raw container matches the statement subject
+// and bundle content at the given time.
+func (s *Statement) Check(raw []byte, now time.Time) error {
+	// Check statement header
+	if s.Type != StatementType {
+		return fmt.Errorf("unsupported statement type '%s'", s.Type)
+	}
+	if s.PredicateType != PredicateType {
+		return fmt.Errorf("unsupported predicate type '%s'", s.PredicateType)
+	}
+
+	// Check validity
+	if exp := s.Predicate.Metadata.ExpiresOn; exp != nil && !now.Before(*exp) {
+		return fmt.Errorf("attestation expired at %s: %w", exp.Format(time.RFC3339), ErrExpired)
+	}
+
+	// Check container digest
+	if len(s.Subject) != 1 {
+		return fmt.Errorf("statement must have exactly one subject")
+	}
+	expected, ok := s.Subject[0].Digest[DigestSHA256]
+	if !ok {
+		return fmt.Errorf("statement subject doesn't have a %s digest", DigestSHA256)
+	}
+	if got := digest(raw); got != expected {
+		return fmt.Errorf("expected %s:%s, got %s:%s: %w", DigestSHA256, expected, DigestSHA256, got, ErrDigestMismatch)
+	}
+
+	// Check bundle content
+	content, err := bundleContent(raw)
+	if err != nil {
+		return err
+	}
+	if content.MerkleRoot != s.Predicate.Bundle.MerkleRoot {
+		return fmt.Errorf("bundle merkle root doesn't match the attested one: %w", ErrDigestMismatch)
+	}
+
+	// No error
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func digest(raw []byte) string {
+	h := sha256.Sum256(raw)
+	return hex.EncodeToString(h[:])
+}
+
+func bundleContent(raw []byte) (*Content, error) {
+	// Load container
+	c, err := container.Load(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("unable to load container: %w", err)
+	}
+
+	// Extract bundle
+	b, err := bundle.FromContainer(c)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load bundle from container (sealed containers are not supported): %w", err)
+	}
+
+	// Compute merkle tree
+	tree, stats, err := bundle.Tree(b)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compute bundle merkle tree: %w", err)
+	}
+
+	content := &Content{
+		MerkleRoot:   hex.EncodeToString(tree.Root()),
+		PackageCount: int(stats.PackageCount),
+		SecretCount:  int(stats.SecretCount),
+	}
+
+	// Template specification digest
+	if b.Template != nil {
+		spec, err := proto.MarshalOptions{Deterministic: true}.Marshal(b.Template)
+		if err != nil {
+			return nil, fmt.Errorf("unable to encode bundle template: %w", err)
+		}
+		content.TemplateDigest = map[string]string{DigestSHA256: digest(spec)}
+	}
+
+	// No error
+	return content, nil
+}
@@ -33,71 +33,60 @@ import (
 	"google.golang.org/protobuf/proto"
 
 	containerv1 "github.com/elastic/harp/api/gen/go/harp/container/v1"
+	"github.com/elastic/harp/pkg/sdk/security"
 	"github.com/elastic/harp/pkg/sdk/types"
 )
 
 const (
 	containerMagic             = uint32(0x53CB3701)
 	containerVersion           = uint16(0x0002)
+	legacyContainerVersion     = uint16(0x0001)
 	containerSealedContentType = "application/vnd.harp.v1.SealedContainer"
 	publicKeySize              = 32
 	privateKeySize             = 32
 	encryptionKeySize          = 32
 )
 
-// Load a reader to extract as a container.
-func Load(r io.Reader) (*containerv1.Container, error) {
-	// Check parameters
-	if types.IsNil(r) {
-		return nil, fmt.Errorf("unable to process nil reader")
-	}
-
-	// Read magic
-	var magic uint32
-	if err := binary.Read(r, binary.BigEndian, &magic); err != nil {
-		return nil, fmt.Errorf("unable to read magic code: %v", err)
-	}
-
-	// Check magic value
-	if magic != containerMagic {
-		return nil, fmt.Errorf("invalid magic signature")
-	}
-
-	// Read container version
-	var version uint16
-	if err := binary.Read(r, binary.BigEndian, &version); err != nil {
-		return nil, fmt.Errorf("unable to read container version: %v", err)
-	}
+// FormatVersion returns the supported container format version.
+func FormatVersion() uint16 {
+	return containerVersion
+}
 
-	// Check magic value
-	if version != containerVersion {
-		return nil, fmt.Errorf("invalid container version %d", version)
-	}
+// IsSealed returns true if the given container is sealed.
+func IsSealed(c *containerv1.Container) bool {
+	return c != nil && c.Headers != nil && c.Headers.ContentType == containerSealedContentType
+}
 
-	// Drain input reader
-	decoded, err := ioutil.ReadAll(r)
+// Load a reader to extract as a container. Containers requiring features not
+// supported by this version are refused.
+func Load(r io.Reader) (*containerv1.Container, error) {
+	// Decode container
+	container, err := decode(r)
 	if err != nil {
-		return nil, fmt.Errorf("unable to container content")
+		return nil, err
 	}
 
-	// Check content length
-	if len(decoded) == 0 {
-		return nil, fmt.Errorf("container is empty")
+	// Check required features
+	if err := CheckFeatures(container.Headers); err != nil {
+		return nil, err
 	}
 
-	// Deserialize protobuf payload
-	container := &containerv1.Container{}
-	if err = proto.Unmarshal(decoded, container); err != nil {
-		return nil, fmt.Errorf("unable to decode content as container")
-	}
+	// No error
+	return container, nil
+}
 
-	// Check headers
-	if container.Headers == nil {
-		container.Headers = &containerv1.Header{}
+// LoadHeaders returns the headers of the container read from the given
+// reader. Required features are not checked, so that containers written by
+// newer versions can be inspected.
+func LoadHeaders(r io.Reader) (*containerv1.Header, error) {
+	// Decode container
+	container, err := decode(r)
+	if err != nil {
+		return nil, err
 	}
 
 	// No error
-	return container, nil
+	return container.Headers, nil
 }
 
 // Dump the marshaled container instance to writer.
@@ -150,9 +139,11 @@ func Seal(container *containerv1.Container, peersPublicKey ...*[32]byte) (*conta
 	if err != nil {
 		return container, fmt.Errorf("unable to encode container content: %v", err)
 	}
+	defer security.RegisterSensitive(content)()
 
 	// Generate payload encryption key
 	var payloadKey [32]byte
+	defer security.RegisterSensitive(payloadKey[:])()
 	if _, err = io.ReadFull(rand.Reader, payloadKey[:]); err != nil {
 		return nil, fmt.Errorf("unable to generate payload key for encryption")
 	}
@@ -181,6 +172,9 @@ func Seal(container *containerv1.Container, peersPublicKey ...*[32]byte) (*conta
 		EncryptionPublicKey: encPub[:],
 		ContainerBox:        encryptedPubSig,
 		Recipients:          []*containerv1.Recipient{},
+		// Expose inner content requirements to readers without the key
+		RequiredFeatures: container.Headers.RequiredFeatures,
+		MinVersion:       container.Headers.MinVersion,
 	}
 
 	// Process recipients
@@ -228,9 +222,38 @@ func Seal(container *containerv1.Container, peersPublicKey ...*[32]byte) (*conta
 	}, nil
 }
 
+// KeyAgreement describes an identity private key able to compute X25519
+// shared secrets without exposing the key material, such as hardware tokens.
+type KeyAgreement interface {
+	// ECDH returns the X25519 shared secret between the identity private key
+	// and the given public key.
+	ECDH(peerPublicKey *[32]byte) (*[32]byte, error)
+}
+
 // Unseal a sealed container with the given identity
-//nolint:funlen,gocyclo,gocognit // To refactor
 func Unseal(container *containerv1.Container, identity *memguard.LockedBuffer) (*containerv1.Container, error) {
+	// Check parameters
+	if identity == nil {
+		return nil, fmt.Errorf("unable to process without container key")
+	}
+
+	// Check identity private encryption key
+	privRaw := identity.Bytes()
+	if len(privRaw) != privateKeySize {
+		return nil, fmt.Errorf("invalid identity private key length")
+	}
+	var pk [privateKeySize]byte
+	copy(pk[:], privRaw[:privateKeySize])
+	defer memguard.WipeBytes(pk[:])
+
+	// Delegate to key agreement
+	return UnsealWithKeyAgreement(container, &privateKeyAgreement{privateKey: &pk})
+}
+
+// UnsealWithKeyAgreement unseals a sealed container using the given identity
+// key agreement.
+//nolint:funlen,gocyclo,gocognit // To refactor
+func UnsealWithKeyAgreement(container *containerv1.Container, identity KeyAgreement) (*containerv1.Container, error) {
 	// Check parameters
 	if types.IsNil(container) {
 		return nil, fmt.Errorf("unable to process nil container")
@@ -238,7 +261,7 @@ func Unseal(container *containerv1.Container, identity *memguard.LockedBuffer) (
 	if types.IsNil(container.Headers) {
 		return nil, fmt.Errorf("unable to process nil container headers")
 	}
-	if identity == nil {
+	if types.IsNil(identity) {
 		return nil, fmt.Errorf("unable to process without container key")
 	}
 
@@ -254,28 +277,29 @@ func Unseal(container *containerv1.Container, identity *memguard.LockedBuffer) (
 	var publicKey [publicKeySize]byte
 	copy(publicKey[:], container.Headers.EncryptionPublicKey[:publicKeySize])
 
-	// Check identity private encryption key
-	privRaw := identity.Bytes()
-	if len(privRaw) != privateKeySize {
-		return nil, fmt.Errorf("invalid identity private key length")
+	// Compute shared secret with identity
+	sharedSecret, err := identity.ECDH(&publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compute identity shared secret: %w", err)
 	}
-	var pk [privateKeySize]byte
-	copy(pk[:], privRaw[:privateKeySize])
 
 	// Precompute identifier
-	derivedKey := deriveSharedKeyFromRecipient(&publicKey, &pk)
+	derivedKey := deriveSharedKeyFromSecret(sharedSecret)
+	memguard.WipeBytes(sharedSecret[:])
 
 	// Try recipients
 	payloadKey, err := tryRecipientKeys(&derivedKey, container.Headers.Recipients)
 	if err != nil {
 		return nil, err
 	}
+	defer security.RegisterSensitive(payloadKey)()
 
 	// Check private key
 	if len(payloadKey) != encryptionKeySize {
 		return nil, fmt.Errorf("unable to unseal container: invalid encryption key size")
 	}
 	var encryptionKey [encryptionKeySize]byte
+	defer security.RegisterSensitive(encryptionKey[:])()
 	copy(encryptionKey[:], payloadKey[:encryptionKeySize])
 
 	// Prepare sig nonce
@@ -306,6 +330,7 @@ func Unseal(container *containerv1.Container, identity *memguard.LockedBuffer) (
 	if !ok || len(payloadRaw) < ed25519.SignatureSize {
 		return nil, fmt.Errorf("invalid ciphered content")
 	}
+	defer security.RegisterSensitive(payloadRaw)()
 
 	// Prepare protected content
 	protected := bytes.Buffer{}
@@ -330,6 +355,69 @@ func Unseal(container *containerv1.Container, identity *memguard.LockedBuffer) (
 		return nil, fmt.Errorf("unable to unpack inner content: %v", err)
 	}
 
+	// Check required features
+	if err := CheckFeatures(out.Headers); err != nil {
+		return nil, err
+	}
+
 	// No error
 	return out, nil
 }
+
+// -----------------------------------------------------------------------------
+
+// decode reads a container from the given reader.
+func decode(r io.Reader) (*containerv1.Container, error) {
+	// Check parameters
+	if types.IsNil(r) {
+		return nil, fmt.Errorf("unable to process nil reader")
+	}
+
+	// Read magic
+	var magic uint32
+	if err := binary.Read(r, binary.BigEndian, &magic); err != nil {
+		return nil, fmt.Errorf("unable to read magic code: %v", err)
+	}
+
+	// Check magic value
+	if magic != containerMagic {
+		return nil, fmt.Errorf("invalid magic signature")
+	}
+
+	// Read container version
+	var version uint16
+	if err := binary.Read(r, binary.BigEndian, &version); err != nil {
+		return nil, fmt.Errorf("unable to read container version: %v", err)
+	}
+
+	// Check container version, legacy containers share the same envelope and
+	// are migrated according to their content type.
+	if version != containerVersion && version != legacyContainerVersion {
+		return nil, fmt.Errorf("invalid container version %d", version)
+	}
+
+	// Drain input reader
+	decoded, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to container content")
+	}
+
+	// Check content length
+	if len(decoded) == 0 {
+		return nil, fmt.Errorf("container is empty")
+	}
+
+	// Deserialize protobuf payload
+	container := &containerv1.Container{}
+	if err = proto.Unmarshal(decoded, container); err != nil {
+		return nil, fmt.Errorf("unable to decode content as container")
+	}
+
+	// Check headers
+	if container.Headers == nil {
+		container.Headers = &containerv1.Header{}
+	}
+
+	// No error
+	return container, nil
+}
@@ -19,9 +19,12 @@ package container
 
 import (
 	"bytes"
+	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"io"
 	"io/ioutil"
+	"strings"
 	"testing"
 
 	"github.com/awnumar/memguard"
@@ -296,6 +299,120 @@ func Test_Seal_Unseal(t *testing.T) {
 	}
 }
 
+type failingKeyAgreement struct{}
+
+func (failingKeyAgreement) ECDH(_ *[32]byte) (*[32]byte, error) {
+	return nil, errors.New("token removed")
+}
+
+func Test_UnsealWithKeyAgreement(t *testing.T) {
+	publicKey1, privateKey1, err := box.GenerateKey(bytes.NewReader([]byte("deterministic-generation-for-tests-0003")))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	input := &containerv1.Container{
+		Headers: &containerv1.Header{
+			ContentEncoding: "gzip",
+			ContentType:     "application/vnd.harp.v1.Bundle",
+		},
+		Raw: []byte{0x00, 0x00},
+	}
+
+	sealed, err := Seal(input, nil, publicKey1)
+	if err != nil {
+		t.Fatalf("unable to seal container: %v", err)
+	}
+
+	t.Run("software", func(t *testing.T) {
+		unsealed, err := UnsealWithKeyAgreement(sealed, &privateKeyAgreement{privateKey: privateKey1})
+		if err != nil {
+			t.Fatalf("unable to unseal container: %v", err)
+		}
+		if diff := cmp.Diff(unsealed, input, ignoreOpts...); diff != "" {
+			t.Errorf("UnsealWithKeyAgreement()\n-got/+want\ndiff %s", diff)
+		}
+	})
+
+	t.Run("nil agreement", func(t *testing.T) {
+		if _, err := UnsealWithKeyAgreement(sealed, nil); err == nil {
+			t.Error("error should be raised")
+		}
+	})
+
+	t.Run("failing agreement", func(t *testing.T) {
+		_, err := UnsealWithKeyAgreement(sealed, failingKeyAgreement{})
+		if err == nil || !strings.Contains(err.Error(), "token removed") {
+			t.Errorf("expected agreement error, got %v", err)
+		}
+	})
+}
+
+func Test_SealedSize(t *testing.T) {
+	for _, recipients := range []int{1, 2, 20} {
+		for _, size := range []int{0, 100, 200000} {
+			input := &containerv1.Container{
+				Headers: &containerv1.Header{
+					ContentEncoding: "gzip",
+					ContentType:     "application/vnd.harp.v1.Bundle",
+				},
+				Raw: make([]byte, size),
+			}
+
+			keys := []*[32]byte{}
+			for i := 0; i < recipients; i++ {
+				pub, _, err := box.GenerateKey(rand.Reader)
+				if err != nil {
+					t.Fatalf("%v", err)
+				}
+				keys = append(keys, pub)
+			}
+
+			estimated, err := SealedSize(input, recipients)
+			if err != nil {
+				t.Fatalf("unable to estimate sealed size: %v", err)
+			}
+
+			sealed, err := Seal(input, keys...)
+			if err != nil {
+				t.Fatalf("unable to seal container: %v", err)
+			}
+			var out bytes.Buffer
+			if err := Dump(&out, sealed); err != nil {
+				t.Fatalf("unable to dump container: %v", err)
+			}
+			if estimated != int64(out.Len()) {
+				t.Errorf("recipients=%d size=%d: estimated %d, got %d", recipients, size, estimated, out.Len())
+			}
+		}
+	}
+
+	if _, err := SealedSize(&containerv1.Container{}, 0); err == nil {
+		t.Error("error should be raised without recipient")
+	}
+}
+
+func Test_deriveSharedKeyFromSecret(t *testing.T) {
+	publicKey1, _, err := box.GenerateKey(bytes.NewReader([]byte("deterministic-generation-for-tests-0004")))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	_, privateKey2, err := box.GenerateKey(bytes.NewReader([]byte("deterministic-generation-for-tests-0005")))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	expected := deriveSharedKeyFromRecipient(publicKey1, privateKey2)
+
+	secret, err := (&privateKeyAgreement{privateKey: privateKey2}).ECDH(publicKey1)
+	if err != nil {
+		t.Fatalf("unable to compute shared secret: %v", err)
+	}
+	if got := deriveSharedKeyFromSecret(secret); got != expected {
+		t.Errorf("derived key mismatch")
+	}
+}
+
 // -----------------------------------------------------------------------------
 
 func Test_Load_Fuzz(t *testing.T) {
@@ -0,0 +1,137 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package delta
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// gear is the FastCDC rolling hash table, generated from a fixed seed so that
+// chunk boundaries are stable across releases.
+var gear = func() [256]uint64 {
+	var (
+		table [256]uint64
+		state = uint64(0x6861727064656c74) // "harpdelt"
+	)
+	for i := range table {
+		// splitmix64
+		state += 0x9e3779b97f4a7c15
+		z := state
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		table[i] = z ^ (z >> 31)
+	}
+	return table
+}()
+
+// ChunkOptions defines content defined chunking bounds.
+type ChunkOptions struct {
+	MinSize int
+	AvgSize int
+	MaxSize int
+}
+
+// DefaultChunkOptions returns the default chunk size bounds.
+func DefaultChunkOptions() ChunkOptions {
+	return ChunkOptions{
+		MinSize: 2 * 1024,
+		AvgSize: 8 * 1024,
+		MaxSize: 64 * 1024,
+	}
+}
+
+// Validate chunk options.
+func (o ChunkOptions) Validate() error {
+	if o.MinSize <= 0 {
+		return fmt.Errorf("minimum chunk size must be positive")
+	}
+	if o.AvgSize&(o.AvgSize-1) != 0 || o.AvgSize < 64 {
+		return fmt.Errorf("average chunk size must be a power of 2 greater than 64")
+	}
+	if o.MinSize > o.AvgSize || o.AvgSize > o.MaxSize {
+		return fmt.Errorf("chunk sizes must verify min <= avg <= max")
+	}
+	return nil
+}
+
+// Chunks splits the given content using FastCDC normalized chunking and
+// returns chunk boundaries.
+func Chunks(data []byte, opts ChunkOptions) ([]int, error) {
+	// Check arguments
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Normalized chunking masks (level 2)
+	avgBits := bits.TrailingZeros(uint(opts.AvgSize))
+	maskS := spreadMask(avgBits + 2)
+	maskL := spreadMask(avgBits - 2)
+
+	boundaries := []int{}
+	for offset := 0; offset < len(data); {
+		n := cut(data[offset:], opts, maskS, maskL)
+		offset += n
+		boundaries = append(boundaries, offset)
+	}
+
+	return boundaries, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func cut(data []byte, opts ChunkOptions, maskS, maskL uint64) int {
+	n := len(data)
+	if n <= opts.MinSize {
+		return n
+	}
+	if n > opts.MaxSize {
+		n = opts.MaxSize
+	}
+	normal := opts.AvgSize
+	if n < normal {
+		normal = n
+	}
+
+	var fp uint64
+	i := opts.MinSize
+	for ; i < normal; i++ {
+		fp = (fp << 1) + gear[data[i]]
+		if fp&maskS == 0 {
+			return i + 1
+		}
+	}
+	for ; i < n; i++ {
+		fp = (fp << 1) + gear[data[i]]
+		if fp&maskL == 0 {
+			return i + 1
+		}
+	}
+
+	return n
+}
+
+// spreadMask returns a mask with the given count of bits set, spread over the
+// upper part of the fingerprint.
+func spreadMask(count int) uint64 {
+	var mask uint64
+	for i := 0; i < count; i++ {
+		mask |= 1 << uint(63-2*i)
+	}
+	return mask
+}
@@ -0,0 +1,420 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package delta provides content defined chunking based binary delta between
+// container versions.
+package delta
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/elastic/harp/pkg/sdk/security"
+)
+
+const (
+	deltaMagic   = uint32(0x53CBDE17)
+	deltaVersion = uint16(0x0001)
+
+	opEnd    = byte(0x00)
+	opCopy   = byte(0x01)
+	opInsert = byte(0x02)
+
+	modeRaw     = byte(0x00)
+	modePayload = byte(0x01)
+
+	maxPreallocSize        = 64 * 1024 * 1024
+	maxContainerHeaderSize = 64 * 1024
+)
+
+var modeNames = map[byte]string{
+	modeRaw:     "raw",
+	modePayload: "payload",
+}
+
+var (
+	// ErrBaseMismatch is raised when the delta is applied to another base.
+	ErrBaseMismatch = errors.New("delta: base content digest mismatch")
+	// ErrTargetMismatch is raised when the reconstructed content doesn't match
+	// the expected target.
+	ErrTargetMismatch = errors.New("delta: target content digest mismatch")
+)
+
+// Stats describes a delta content.
+type Stats struct {
+	Mode        string `json:"mode"`
+	TargetSize  int    `json:"target_size"`
+	CopiedSize  int    `json:"copied_size"`
+	InsertSize  int    `json:"insert_size"`
+	CopyCount   int    `json:"copy_count"`
+	InsertCount int    `json:"insert_count"`
+}
+
+type chunkRef struct {
+	offset int
+	size   int
+}
+
+type header struct {
+	baseDigest      []byte
+	targetDigest    []byte
+	mode            byte
+	containerHeader []byte
+	streamSize      uint64
+}
+
+// Create computes the delta to reconstruct target from base and writes it to
+// the given writer.
+//
+// Unsealed compressed containers are compared using their uncompressed
+// payload, other contents are compared as raw bytes.
+func Create(w io.Writer, base, target []byte, opts ChunkOptions) (*Stats, error) {
+	h := &header{mode: modeRaw}
+	baseDigest := sha256.Sum256(base)
+	targetDigest := sha256.Sum256(target)
+	h.baseDigest, h.targetDigest = baseDigest[:], targetDigest[:]
+
+	// Select delta mode
+	src, dst := base, target
+	if basePayload, _, okBase := decodePayload(base); okBase {
+		if targetPayload, containerHeader, okTarget := decodePayload(target); okTarget {
+			h.mode, h.containerHeader = modePayload, containerHeader
+			src, dst = basePayload, targetPayload
+		}
+	}
+	h.streamSize = uint64(len(dst))
+
+	// Split base content
+	baseBoundaries, err := Chunks(src, opts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to chunk base content: %w", err)
+	}
+
+	// Index base chunks
+	index := map[[sha256.Size]byte]chunkRef{}
+	start := 0
+	for _, end := range baseBoundaries {
+		digest := sha256.Sum256(src[start:end])
+		if _, ok := index[digest]; !ok {
+			index[digest] = chunkRef{offset: start, size: end - start}
+		}
+		start = end
+	}
+
+	// Split target content
+	targetBoundaries, err := Chunks(dst, opts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to chunk target content: %w", err)
+	}
+
+	// Write header
+	bw := bufio.NewWriter(w)
+	if err = writeHeader(bw, h); err != nil {
+		return nil, err
+	}
+
+	// Prepare operation encoder
+	enc := &encoder{w: bw, target: dst, stats: &Stats{Mode: modeNames[h.mode], TargetSize: len(target)}}
+
+	// Emit operations
+	start = 0
+	for _, end := range targetBoundaries {
+		digest := sha256.Sum256(dst[start:end])
+		if ref, ok := index[digest]; ok && bytes.Equal(src[ref.offset:ref.offset+ref.size], dst[start:end]) {
+			err = enc.copy(ref.offset, ref.size)
+		} else {
+			err = enc.insert(start, end-start)
+		}
+		if err != nil {
+			return nil, err
+		}
+		start = end
+	}
+
+	// Terminate stream
+	if err = enc.flush(); err != nil {
+		return nil, err
+	}
+	if err = bw.WriteByte(opEnd); err != nil {
+		return nil, fmt.Errorf("unable to write delta trailer: %w", err)
+	}
+	if err = bw.Flush(); err != nil {
+		return nil, fmt.Errorf("unable to flush delta: %w", err)
+	}
+
+	// No error
+	return enc.stats, nil
+}
+
+// Apply reconstructs the target content from base and delta. The delta is
+// rejected if base content is not the one used to create it, and the result
+// is verified against the expected target digest before being returned.
+func Apply(base []byte, r io.Reader) ([]byte, error) {
+	br := bufio.NewReader(r)
+
+	// Read header
+	h, err := readHeader(br)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check base content
+	actualBase := sha256.Sum256(base)
+	if !security.SecureCompare(actualBase[:], h.baseDigest) {
+		return nil, ErrBaseMismatch
+	}
+
+	// Prepare copy source
+	src := base
+	if h.mode == modePayload {
+		payload, _, ok := decodePayload(base)
+		if !ok {
+			return nil, fmt.Errorf("unable to decode base container payload")
+		}
+		src = payload
+	}
+
+	// Reconstruct target stream
+	stream, err := applyOperations(br, src, h.streamSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// Encode target container
+	target := stream
+	if h.mode == modePayload {
+		target, err = encodePayload(stream, h.containerHeader)
+		if err != nil {
+			return nil, fmt.Errorf("unable to encode target container: %w", err)
+		}
+	}
+
+	// Check reconstructed content
+	actualTarget := sha256.Sum256(target)
+	if !security.SecureCompare(actualTarget[:], h.targetDigest) {
+		return nil, ErrTargetMismatch
+	}
+
+	// No error
+	return target, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func applyOperations(br *bufio.Reader, src []byte, streamSize uint64) ([]byte, error) {
+	// Preallocate output buffer (bounded to not trust the header)
+	capacity := streamSize
+	if capacity > maxPreallocSize {
+		capacity = maxPreallocSize
+	}
+
+	// Apply operations
+	out := bytes.NewBuffer(make([]byte, 0, capacity))
+	for {
+		op, errOp := br.ReadByte()
+		if errOp != nil {
+			return nil, fmt.Errorf("unable to read delta operation: %w", errOp)
+		}
+
+		switch op {
+		case opEnd:
+			if uint64(out.Len()) != streamSize {
+				return nil, ErrTargetMismatch
+			}
+
+			// No error
+			return out.Bytes(), nil
+		case opCopy:
+			offset, errOffset := binary.ReadUvarint(br)
+			if errOffset != nil {
+				return nil, fmt.Errorf("unable to read copy offset: %w", errOffset)
+			}
+			size, errSize := binary.ReadUvarint(br)
+			if errSize != nil {
+				return nil, fmt.Errorf("unable to read copy size: %w", errSize)
+			}
+			if offset > uint64(len(src)) || size > uint64(len(src))-offset {
+				return nil, fmt.Errorf("invalid copy operation out of base bounds")
+			}
+			out.Write(src[offset : offset+size])
+		case opInsert:
+			size, errSize := binary.ReadUvarint(br)
+			if errSize != nil {
+				return nil, fmt.Errorf("unable to read insert size: %w", errSize)
+			}
+			if size > streamSize-uint64(out.Len()) {
+				return nil, fmt.Errorf("invalid insert operation larger than target")
+			}
+			if _, errCopy := io.CopyN(out, br, int64(size)); errCopy != nil {
+				return nil, fmt.Errorf("unable to read inserted data: %w", errCopy)
+			}
+		default:
+			return nil, fmt.Errorf("invalid delta operation code %d", op)
+		}
+
+		// Check target size overflow
+		if uint64(out.Len()) > streamSize {
+			return nil, ErrTargetMismatch
+		}
+	}
+}
+
+func writeHeader(w *bufio.Writer, h *header) error {
+	for _, v := range []interface{}{deltaMagic, deltaVersion, h.baseDigest, h.targetDigest, h.mode} {
+		if err := binary.Write(w, binary.BigEndian, v); err != nil {
+			return fmt.Errorf("unable to write delta header: %w", err)
+		}
+	}
+
+	// Container header is only used by payload mode
+	var buf [binary.MaxVarintLen64]byte
+	w.Write(buf[:binary.PutUvarint(buf[:], uint64(len(h.containerHeader)))])
+	w.Write(h.containerHeader)
+	w.Write(buf[:binary.PutUvarint(buf[:], h.streamSize)])
+
+	return nil
+}
+
+func readHeader(r *bufio.Reader) (*header, error) {
+	// Read magic
+	var magic uint32
+	if err := binary.Read(r, binary.BigEndian, &magic); err != nil {
+		return nil, fmt.Errorf("unable to read magic code: %w", err)
+	}
+	if magic != deltaMagic {
+		return nil, fmt.Errorf("invalid delta magic signature")
+	}
+
+	// Read version
+	var version uint16
+	if err := binary.Read(r, binary.BigEndian, &version); err != nil {
+		return nil, fmt.Errorf("unable to read delta version: %w", err)
+	}
+	if version != deltaVersion {
+		return nil, fmt.Errorf("invalid delta version %d", version)
+	}
+
+	// Read digests
+	h := &header{
+		baseDigest:   make([]byte, sha256.Size),
+		targetDigest: make([]byte, sha256.Size),
+	}
+	if _, err := io.ReadFull(r, h.baseDigest); err != nil {
+		return nil, fmt.Errorf("unable to read base digest: %w", err)
+	}
+	if _, err := io.ReadFull(r, h.targetDigest); err != nil {
+		return nil, fmt.Errorf("unable to read target digest: %w", err)
+	}
+
+	// Read mode
+	mode, err := r.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read delta mode: %w", err)
+	}
+	if _, ok := modeNames[mode]; !ok {
+		return nil, fmt.Errorf("invalid delta mode %d", mode)
+	}
+	h.mode = mode
+
+	// Read container header
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read container header size: %w", err)
+	}
+	if size > maxContainerHeaderSize {
+		return nil, fmt.Errorf("invalid container header size")
+	}
+	h.containerHeader = make([]byte, size)
+	if _, err = io.ReadFull(r, h.containerHeader); err != nil {
+		return nil, fmt.Errorf("unable to read container header: %w", err)
+	}
+
+	// Read target stream size
+	if h.streamSize, err = binary.ReadUvarint(r); err != nil {
+		return nil, fmt.Errorf("unable to read target size: %w", err)
+	}
+
+	return h, nil
+}
+
+// -----------------------------------------------------------------------------
+
+// encoder merges contiguous operations of the same kind.
+type encoder struct {
+	w      *bufio.Writer
+	target []byte
+	stats  *Stats
+
+	pending byte
+	offset  int
+	size    int
+}
+
+func (e *encoder) copy(offset, size int) error {
+	if e.pending == opCopy && e.offset+e.size == offset {
+		e.size += size
+		return nil
+	}
+	if err := e.flush(); err != nil {
+		return err
+	}
+	e.pending, e.offset, e.size = opCopy, offset, size
+	return nil
+}
+
+func (e *encoder) insert(offset, size int) error {
+	if e.pending == opInsert && e.offset+e.size == offset {
+		e.size += size
+		return nil
+	}
+	if err := e.flush(); err != nil {
+		return err
+	}
+	e.pending, e.offset, e.size = opInsert, offset, size
+	return nil
+}
+
+func (e *encoder) flush() error {
+	var buf [binary.MaxVarintLen64]byte
+
+	switch e.pending {
+	case opCopy:
+		e.w.WriteByte(opCopy)
+		e.w.Write(buf[:binary.PutUvarint(buf[:], uint64(e.offset))])
+		e.w.Write(buf[:binary.PutUvarint(buf[:], uint64(e.size))])
+		e.stats.CopyCount++
+		e.stats.CopiedSize += e.size
+	case opInsert:
+		e.w.WriteByte(opInsert)
+		e.w.Write(buf[:binary.PutUvarint(buf[:], uint64(e.size))])
+		if _, err := e.w.Write(e.target[e.offset : e.offset+e.size]); err != nil {
+			return fmt.Errorf("unable to write inserted data: %w", err)
+		}
+		e.stats.InsertCount++
+		e.stats.InsertSize += e.size
+	default:
+		return nil
+	}
+
+	e.pending, e.offset, e.size = opEnd, 0, 0
+	return nil
+}
@@ -0,0 +1,214 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package delta
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func randomBytes(t *testing.T, size int) []byte {
+	out := make([]byte, size)
+	if _, err := rand.Read(out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func concat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func TestChunks_Bounds(t *testing.T) {
+	opts := DefaultChunkOptions()
+	data := randomBytes(t, 1024*1024)
+
+	boundaries, err := Chunks(data, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := 0
+	for i, end := range boundaries {
+		size := end - start
+		if size > opts.MaxSize {
+			t.Errorf("chunk %d exceeds max size (%d)", i, size)
+		}
+		if size < opts.MinSize && i != len(boundaries)-1 {
+			t.Errorf("chunk %d is smaller than min size (%d)", i, size)
+		}
+		start = end
+	}
+	if start != len(data) {
+		t.Errorf("chunks must cover the whole content")
+	}
+}
+
+func TestChunks_Stability(t *testing.T) {
+	opts := DefaultChunkOptions()
+	data := randomBytes(t, 512*1024)
+
+	before, err := Chunks(data, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Insert a few bytes at the beginning
+	shifted := concat([]byte("prefix"), data)
+	after, err := Chunks(shifted, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Most boundaries must be preserved (shifted by the prefix)
+	known := map[int]bool{}
+	for _, b := range before {
+		known[b+len("prefix")] = true
+	}
+	shared := 0
+	for _, b := range after {
+		if known[b] {
+			shared++
+		}
+	}
+	if shared < len(before)-2 {
+		t.Errorf("expected boundaries to resynchronize, %d/%d shared", shared, len(before))
+	}
+}
+
+func TestChunkOptions_Validate(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		opts    ChunkOptions
+		wantErr bool
+	}{
+		{desc: "default", opts: DefaultChunkOptions()},
+		{desc: "zero min", opts: ChunkOptions{MinSize: 0, AvgSize: 1024, MaxSize: 4096}, wantErr: true},
+		{desc: "avg not power of 2", opts: ChunkOptions{MinSize: 256, AvgSize: 1000, MaxSize: 4096}, wantErr: true},
+		{desc: "min greater than avg", opts: ChunkOptions{MinSize: 2048, AvgSize: 1024, MaxSize: 4096}, wantErr: true},
+		{desc: "avg greater than max", opts: ChunkOptions{MinSize: 256, AvgSize: 8192, MaxSize: 4096}, wantErr: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			err := tC.opts.Validate()
+			if (err != nil) != tC.wantErr {
+				t.Errorf("error during the call, error = %v, wantErr %v", err, tC.wantErr)
+			}
+		})
+	}
+}
+
+func TestDelta_RoundTrip(t *testing.T) {
+	head := randomBytes(t, 256*1024)
+	middle := randomBytes(t, 1024)
+	tail := randomBytes(t, 256*1024)
+
+	base := concat(head, middle, tail)
+
+	// A change can affect at most the chunks surrounding it
+	maxChange := 2*DefaultChunkOptions().MaxSize + 1024
+
+	testCases := []struct {
+		desc       string
+		target     []byte
+		maxInserts int
+	}{
+		{desc: "identical", target: base, maxInserts: 0},
+		{desc: "middle change", target: concat(head, randomBytes(t, 1024), tail), maxInserts: maxChange},
+		{desc: "append", target: concat(base, randomBytes(t, 100)), maxInserts: maxChange},
+		{desc: "truncate", target: head, maxInserts: maxChange},
+		{desc: "empty target", target: []byte{}, maxInserts: 0},
+		{desc: "unrelated", target: randomBytes(t, 10*1024), maxInserts: 10 * 1024},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var buf bytes.Buffer
+			stats, err := Create(&buf, base, tC.target, DefaultChunkOptions())
+			if err != nil {
+				t.Fatalf("unable to create delta: %v", err)
+			}
+			if stats.InsertSize > tC.maxInserts {
+				t.Errorf("delta is too large, %d bytes inserted", stats.InsertSize)
+			}
+			if stats.InsertSize+stats.CopiedSize != len(tC.target) {
+				t.Errorf("delta operations must cover the target")
+			}
+
+			got, err := Apply(base, &buf)
+			if err != nil {
+				t.Fatalf("unable to apply delta: %v", err)
+			}
+			if !bytes.Equal(got, tC.target) {
+				t.Error("reconstructed content doesn't match target")
+			}
+		})
+	}
+}
+
+func TestDelta_WrongBase(t *testing.T) {
+	base := randomBytes(t, 64*1024)
+	target := concat(base, []byte("new"))
+
+	var buf bytes.Buffer
+	if _, err := Create(&buf, base, target, DefaultChunkOptions()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Same size, different content
+	other := append([]byte{}, base...)
+	other[0] ^= 0xFF
+
+	if _, err := Apply(other, bytes.NewReader(buf.Bytes())); !errors.Is(err, ErrBaseMismatch) {
+		t.Errorf("expected base mismatch error, got %v", err)
+	}
+}
+
+func TestDelta_Corrupted(t *testing.T) {
+	base := randomBytes(t, 64*1024)
+	target := concat([]byte("head"), base)
+
+	var buf bytes.Buffer
+	if _, err := Create(&buf, base, target, DefaultChunkOptions()); err != nil {
+		t.Fatal(err)
+	}
+	raw := buf.Bytes()
+
+	testCases := []struct {
+		desc    string
+		delta   []byte
+		wantErr error
+	}{
+		{desc: "empty", delta: []byte{}},
+		{desc: "invalid magic", delta: concat([]byte{0, 0, 0, 0}, raw[4:])},
+		{desc: "truncated", delta: raw[:len(raw)-10]},
+		{desc: "altered target digest", delta: concat(raw[:40], []byte{raw[40] ^ 0xFF}, raw[41:]), wantErr: ErrTargetMismatch},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			_, err := Apply(base, bytes.NewReader(tC.delta))
+			if err == nil {
+				t.Fatal("error expected")
+			}
+			if tC.wantErr != nil && !errors.Is(err, tC.wantErr) {
+				t.Errorf("expected %v, got %v", tC.wantErr, err)
+			}
+		})
+	}
+}
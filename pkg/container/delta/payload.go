@@ -0,0 +1,107 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package delta
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+
+	"google.golang.org/protobuf/proto"
+
+	containerv1 "github.com/elastic/harp/api/gen/go/harp/container/v1"
+	"github.com/elastic/harp/pkg/container"
+)
+
+// gzipCompressionLevel matches the bundle container compression level.
+const gzipCompressionLevel = 9
+
+// decodePayload extracts the uncompressed payload and the serialized headers
+// of an unsealed gzip container. It reports false when the content is not
+// such a container or when encoding the payload doesn't reproduce the exact
+// same content.
+func decodePayload(content []byte) (payload, header []byte, ok bool) {
+	// Load container
+	c, err := container.Load(bytes.NewReader(content))
+	if err != nil || c.Headers == nil {
+		return nil, nil, false
+	}
+
+	// Only unsealed compressed containers are supported
+	if c.Headers.ContentEncoding != "gzip" || len(c.Headers.ContainerBox) > 0 {
+		return nil, nil, false
+	}
+
+	// Decompress payload
+	zr, err := gzip.NewReader(bytes.NewReader(c.Raw))
+	if err != nil {
+		return nil, nil, false
+	}
+	payload, err = ioutil.ReadAll(zr)
+	if err != nil {
+		return nil, nil, false
+	}
+
+	// Serialize headers
+	header, err = proto.MarshalOptions{Deterministic: true}.Marshal(c.Headers)
+	if err != nil {
+		return nil, nil, false
+	}
+
+	// Check encoding reproducibility
+	encoded, err := encodePayload(payload, header)
+	if err != nil || !bytes.Equal(encoded, content) {
+		return nil, nil, false
+	}
+
+	return payload, header, true
+}
+
+// encodePayload builds a container from the given uncompressed payload and
+// serialized headers.
+func encodePayload(payload, header []byte) ([]byte, error) {
+	// Decode headers
+	var h containerv1.Header
+	if err := proto.Unmarshal(header, &h); err != nil {
+		return nil, err
+	}
+
+	// Compress payload
+	var raw bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&raw, gzipCompressionLevel)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = zw.Write(payload); err != nil {
+		return nil, err
+	}
+	if err = zw.Close(); err != nil {
+		return nil, err
+	}
+
+	// Serialize container
+	var out bytes.Buffer
+	if err = container.Dump(&out, &containerv1.Container{
+		Headers: &h,
+		Raw:     raw.Bytes(),
+	}); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
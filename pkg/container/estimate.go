@@ -0,0 +1,79 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package container
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/nacl/secretbox"
+	"google.golang.org/protobuf/proto"
+
+	containerv1 "github.com/elastic/harp/api/gen/go/harp/container/v1"
+	"github.com/elastic/harp/pkg/sdk/types"
+)
+
+// SealCipherSuite describes the algorithms used to seal a container:
+// X25519 recipient key agreement, XSalsa20-Poly1305 payload and key
+// encryption, Ed25519 content signature and Blake2b-512 hashing.
+const SealCipherSuite = "X25519-XSalsa20-Poly1305-Ed25519-Blake2b512"
+
+const (
+	// envelopeSize is the magic and version prefix written by Dump.
+	envelopeSize = 6
+	// recipientIdentifierSize is the truncated Blake2b recipient identifier.
+	recipientIdentifierSize = 32
+	// nonceSize is the XSalsa20 nonce size.
+	nonceSize = 24
+	// signatureSize is the Ed25519 content signature size.
+	signatureSize = 64
+)
+
+// SealedSize returns the size of the given container once sealed for the
+// given recipient count and dumped, without sealing it. The container content
+// is sealed as is, the estimation honors the content encoding as the payload
+// is not compressed again.
+func SealedSize(c *containerv1.Container, recipients int) (int64, error) {
+	// Check parameters
+	if types.IsNil(c) {
+		return 0, fmt.Errorf("unable to process nil container")
+	}
+	if recipients <= 0 {
+		return 0, fmt.Errorf("unable to estimate sealed size without recipient")
+	}
+
+	// Placeholder sealed container with sealed field sizes
+	headers := &containerv1.Header{
+		ContentType:         containerSealedContentType,
+		EncryptionPublicKey: make([]byte, publicKeySize),
+		ContainerBox:        make([]byte, publicKeySize+secretbox.Overhead),
+		Recipients:          make([]*containerv1.Recipient, recipients),
+	}
+	for i := range headers.Recipients {
+		headers.Recipients[i] = &containerv1.Recipient{
+			Identifier: make([]byte, recipientIdentifierSize),
+			Key:        make([]byte, nonceSize+encryptionKeySize+secretbox.Overhead),
+		}
+	}
+	sealed := &containerv1.Container{
+		Headers: headers,
+		Raw:     make([]byte, signatureSize+proto.Size(c)+secretbox.Overhead),
+	}
+
+	// No error
+	return int64(envelopeSize + proto.Size(sealed)), nil
+}
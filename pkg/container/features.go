@@ -0,0 +1,202 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package container
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/blang/semver/v4"
+
+	containerv1 "github.com/elastic/harp/api/gen/go/harp/container/v1"
+)
+
+var (
+	// ErrFeatureAlreadyRegistered is raised when a feature is registered twice.
+	ErrFeatureAlreadyRegistered = errors.New("container: feature already registered")
+	// ErrUnknownFeature is raised when a writer requires a feature which is not
+	// registered.
+	ErrUnknownFeature = errors.New("container: unknown feature")
+	// ErrUnsupportedFeatures is raised when a container requires features not
+	// supported by this version.
+	ErrUnsupportedFeatures = errors.New("container: unsupported features")
+)
+
+var (
+	featuresMu sync.RWMutex
+	features   = map[string]semver.Version{}
+)
+
+// RegisterFeature declares a container format feature supported by this
+// version. Since is the first harp version able to read containers using the
+// feature.
+func RegisterFeature(name, since string) error {
+	featuresMu.Lock()
+	defer featuresMu.Unlock()
+
+	// Check arguments
+	if name == "" || strings.ContainsAny(name, " \t\n") {
+		return fmt.Errorf("feature name '%s' is invalid", name)
+	}
+	v, err := semver.ParseTolerant(since)
+	if err != nil {
+		return fmt.Errorf("unable to parse '%s' feature version: %w", name, err)
+	}
+	if _, ok := features[name]; ok {
+		return fmt.Errorf("unable to register '%s': %w", name, ErrFeatureAlreadyRegistered)
+	}
+	features[name] = v
+
+	// No error
+	return nil
+}
+
+// MustRegisterFeature registers a container feature and panics on error.
+func MustRegisterFeature(name, since string) {
+	if err := RegisterFeature(name, since); err != nil {
+		panic(err)
+	}
+}
+
+// SupportedFeatures returns the sorted list of features supported by this
+// version.
+func SupportedFeatures() []string {
+	featuresMu.RLock()
+	defer featuresMu.RUnlock()
+
+	res := make([]string, 0, len(features))
+	for name := range features {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+// RequireFeatures records the given features in the container headers and
+// raises the minimum version accordingly. Writers must call it when they
+// produce content using a format feature.
+func RequireFeatures(h *containerv1.Header, names ...string) error {
+	// Check arguments
+	if h == nil {
+		return fmt.Errorf("unable to process nil container headers")
+	}
+
+	featuresMu.RLock()
+	defer featuresMu.RUnlock()
+
+	// Compute minimum version
+	var minVersion semver.Version
+	if h.MinVersion != "" {
+		v, err := semver.ParseTolerant(h.MinVersion)
+		if err != nil {
+			return fmt.Errorf("unable to parse container minimum version: %w", err)
+		}
+		minVersion = v
+	}
+	for _, name := range names {
+		since, ok := features[name]
+		if !ok {
+			return fmt.Errorf("unable to require '%s': %w", name, ErrUnknownFeature)
+		}
+		if since.GT(minVersion) {
+			minVersion = since
+		}
+	}
+
+	// Update headers
+	h.RequiredFeatures = normalizeFeatures(append(h.RequiredFeatures, names...))
+	if len(h.RequiredFeatures) > 0 {
+		h.MinVersion = "v" + minVersion.String()
+	}
+
+	// No error
+	return nil
+}
+
+// MissingFeatures returns the sorted list of features required by the
+// container and not supported by this version.
+func MissingFeatures(h *containerv1.Header) []string {
+	featuresMu.RLock()
+	defer featuresMu.RUnlock()
+
+	res := []string{}
+	for _, name := range normalizeFeatures(h.GetRequiredFeatures()) {
+		if _, ok := features[name]; !ok {
+			res = append(res, name)
+		}
+	}
+
+	return res
+}
+
+// CheckFeatures returns an error when the container requires features not
+// supported by this version. Containers without requirements are always
+// accepted.
+func CheckFeatures(h *containerv1.Header) error {
+	missing := MissingFeatures(h)
+	if len(missing) == 0 {
+		return nil
+	}
+
+	return &UnsupportedFeaturesError{
+		Features:   missing,
+		MinVersion: h.GetMinVersion(),
+	}
+}
+
+// UnsupportedFeaturesError describes features required by a container and not
+// supported by this version. MinVersion is empty when the container doesn't
+// declare it.
+type UnsupportedFeaturesError struct {
+	Features   []string
+	MinVersion string
+}
+
+func (e *UnsupportedFeaturesError) Error() string {
+	if e.MinVersion == "" {
+		return fmt.Sprintf("this container requires features [%s]; upgrade harp to a version supporting them", strings.Join(e.Features, " "))
+	}
+	return fmt.Sprintf("this container requires features [%s]; upgrade harp to >= %s", strings.Join(e.Features, " "), e.MinVersion)
+}
+
+// Unwrap returns ErrUnsupportedFeatures.
+func (e *UnsupportedFeaturesError) Unwrap() error {
+	return ErrUnsupportedFeatures
+}
+
+// -----------------------------------------------------------------------------
+
+// normalizeFeatures returns the sorted list of unique feature names.
+func normalizeFeatures(names []string) []string {
+	seen := map[string]struct{}{}
+	res := []string{}
+	for _, name := range names {
+		if _, ok := seen[name]; ok || name == "" {
+			continue
+		}
+		seen[name] = struct{}{}
+		res = append(res, name)
+	}
+	sort.Strings(res)
+
+	return res
+}
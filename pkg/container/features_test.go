@@ -0,0 +1,197 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package container
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/awnumar/memguard"
+	"golang.org/x/crypto/nacl/box"
+
+	containerv1 "github.com/elastic/harp/api/gen/go/harp/container/v1"
+)
+
+func init() {
+	MustRegisterFeature("test-partial-index", "v0.1.20")
+	MustRegisterFeature("test-aliases", "0.1.21")
+}
+
+func Test_RegisterFeature(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		name    string
+		since   string
+		wantErr error
+	}{
+		{desc: "blank name", name: "", since: "v0.1.0"},
+		{desc: "invalid name", name: "test feature", since: "v0.1.0"},
+		{desc: "invalid version", name: "test-invalid-version", since: "latest"},
+		{desc: "duplicate", name: "test-aliases", since: "v0.1.0", wantErr: ErrFeatureAlreadyRegistered},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			err := RegisterFeature(tC.name, tC.since)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if tC.wantErr != nil && !errors.Is(err, tC.wantErr) {
+				t.Errorf("expected %v, got %v", tC.wantErr, err)
+			}
+		})
+	}
+
+	supported := SupportedFeatures()
+	for _, name := range []string{"test-aliases", "test-partial-index"} {
+		found := false
+		for _, s := range supported {
+			found = found || s == name
+		}
+		if !found {
+			t.Errorf("expected '%s' to be supported, got %v", name, supported)
+		}
+	}
+}
+
+func Test_RequireFeatures(t *testing.T) {
+	h := &containerv1.Header{}
+
+	// Unknown features can't be written
+	if err := RequireFeatures(h, "test-unknown"); !errors.Is(err, ErrUnknownFeature) {
+		t.Fatalf("expected unknown feature error, got %v", err)
+	}
+	if len(h.RequiredFeatures) != 0 || h.MinVersion != "" {
+		t.Fatalf("headers must not be updated on error, got %v", h)
+	}
+
+	// Features are deduplicated and sorted, the highest version is kept
+	if err := RequireFeatures(h, "test-partial-index", "test-aliases"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := RequireFeatures(h, "test-partial-index"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(h.RequiredFeatures, []string{"test-aliases", "test-partial-index"}) {
+		t.Errorf("unexpected features %v", h.RequiredFeatures)
+	}
+	if h.MinVersion != "v0.1.21" {
+		t.Errorf("unexpected minimum version %q", h.MinVersion)
+	}
+	if err := CheckFeatures(h); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func Test_Load_RequiredFeatures(t *testing.T) {
+	dump := func(h *containerv1.Header) []byte {
+		var buf bytes.Buffer
+		if err := Dump(&buf, &containerv1.Container{Headers: h, Raw: []byte{0x00}}); err != nil {
+			t.Fatalf("unable to dump container: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	testCases := []struct {
+		desc    string
+		headers *containerv1.Header
+		wantErr string
+	}{
+		{
+			desc:    "no requirement",
+			headers: &containerv1.Header{ContentType: "application/vnd.harp.v1.Bundle"},
+		},
+		{
+			desc:    "supported",
+			headers: &containerv1.Header{RequiredFeatures: []string{"test-aliases"}, MinVersion: "v0.1.21"},
+		},
+		{
+			desc:    "future features",
+			headers: &containerv1.Header{RequiredFeatures: []string{"test-aliases", "quantum-dedup", "hyper-zstd"}, MinVersion: "v9.0.0"},
+			wantErr: "this container requires features [hyper-zstd quantum-dedup]; upgrade harp to >= v9.0.0",
+		},
+		{
+			desc:    "future features without version",
+			headers: &containerv1.Header{RequiredFeatures: []string{"quantum-dedup"}},
+			wantErr: "this container requires features [quantum-dedup]; upgrade harp to a version supporting them",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			raw := dump(tC.headers)
+
+			_, err := Load(bytes.NewReader(raw))
+			switch {
+			case tC.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tC.wantErr != "" && err == nil:
+				t.Fatal("expected error")
+			case tC.wantErr != "":
+				if err.Error() != tC.wantErr {
+					t.Errorf("expected %q, got %q", tC.wantErr, err.Error())
+				}
+				if !errors.Is(err, ErrUnsupportedFeatures) {
+					t.Errorf("expected unsupported features error, got %v", err)
+				}
+			}
+
+			// Headers are always readable
+			h, err := LoadHeaders(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(h.RequiredFeatures, tC.headers.RequiredFeatures) {
+				t.Errorf("unexpected features %v", h.RequiredFeatures)
+			}
+		})
+	}
+}
+
+func Test_Seal_RequiredFeatures(t *testing.T) {
+	pub, priv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := &containerv1.Container{
+		Headers: &containerv1.Header{
+			ContentEncoding:  "gzip",
+			ContentType:      "application/vnd.harp.v1.Bundle",
+			RequiredFeatures: []string{"quantum-dedup"},
+			MinVersion:       "v9.0.0",
+		},
+		Raw: []byte{0x00, 0x00},
+	}
+
+	sealed, err := Seal(input, pub)
+	if err != nil {
+		t.Fatalf("unable to seal container: %v", err)
+	}
+
+	// Requirements are visible without the key
+	if !reflect.DeepEqual(sealed.Headers.RequiredFeatures, []string{"quantum-dedup"}) || sealed.Headers.MinVersion != "v9.0.0" {
+		t.Errorf("unexpected sealed headers %v", sealed.Headers)
+	}
+
+	// Inner requirements are checked
+	if _, err := Unseal(sealed, memguard.NewBufferFromBytes(priv[:])); !errors.Is(err, ErrUnsupportedFeatures) {
+		t.Errorf("expected unsupported features error, got %v", err)
+	}
+}
@@ -24,8 +24,10 @@ import (
 
 	"github.com/awnumar/memguard"
 	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/curve25519"
 	"golang.org/x/crypto/nacl/box"
 	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/salsa20/salsa"
 	"google.golang.org/protobuf/proto"
 
 	containerv1 "github.com/elastic/harp/api/gen/go/harp/container/v1"
@@ -101,6 +103,45 @@ func deriveSharedKeyFromRecipient(publicKey, privateKey *[32]byte) [32]byte {
 	return sharedKey
 }
 
+// deriveSharedKeyFromSecret computes the same key than
+// deriveSharedKeyFromRecipient from a raw X25519 shared secret.
+func deriveSharedKeyFromSecret(secret *[32]byte) [32]byte {
+	// Apply box key derivation
+	var boxKey [32]byte
+	salsa.HSalsa20(&boxKey, &[16]byte{}, secret, &salsa.Sigma)
+	defer memguard.WipeBytes(boxKey[:])
+
+	// Prepare nonce
+	var nonce [24]byte
+	copy(nonce[:], "harp_derived_id_sboxkey0")
+
+	// Use secretbox as a key agreement function
+	var sharedKey [32]byte
+	derivedKey := secretbox.Seal(nil, make([]byte, 32), &nonce, &boxKey)
+	copy(sharedKey[:], derivedKey[len(derivedKey)-32:])
+
+	// No error
+	return sharedKey
+}
+
+// privateKeyAgreement implements KeyAgreement with an in-memory private key.
+type privateKeyAgreement struct {
+	privateKey *[32]byte
+}
+
+func (k *privateKeyAgreement) ECDH(peerPublicKey *[32]byte) (*[32]byte, error) {
+	secret, err := curve25519.X25519(k.privateKey[:], peerPublicKey[:])
+	if err != nil {
+		return nil, fmt.Errorf("unable to compute X25519 shared secret: %w", err)
+	}
+
+	var out [32]byte
+	copy(out[:], secret)
+	memguard.WipeBytes(secret)
+
+	return &out, nil
+}
+
 func keyIdentifierFromDerivedKey(derivedKey *[32]byte) ([]byte, error) {
 	// Hash the derived key
 	h, err := blake2b.New512([]byte("harp signcryption box key identifier"))
@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/elastic/harp/pkg/sdk/security/crypto"
 	"github.com/elastic/harp/pkg/sdk/types"
 	"github.com/elastic/harp/pkg/sdk/value"
 )
@@ -37,6 +38,9 @@ type Identity struct {
 	Description string      `json:"@description"`
 	Public      string      `json:"public"`
 	Private     *PrivateKey `json:"private"`
+	// Usage declares the intended usage of the identity key, identities
+	// created before usage declarations have none.
+	Usage *crypto.KeyUsage `json:"usage,omitempty"`
 }
 
 // HasPrivateKey returns true if identity as a wrapped private.
@@ -44,6 +48,33 @@ func (i *Identity) HasPrivateKey() bool {
 	return i.Private != nil
 }
 
+// PublicJWK returns the identity public key as a JWK carrying the declared
+// key usage, to be checked with crypto.ValidateKeyUsage.
+func (i *Identity) PublicJWK() ([]byte, error) {
+	key := JSONWebKey{
+		Kty:   "OKP",
+		Crv:   "X25519",
+		X:     i.Public,
+		Usage: i.Usage,
+	}
+	if i.Usage != nil {
+		ops, err := crypto.KeyOps(i.Usage.Use)
+		if err != nil {
+			return nil, fmt.Errorf("invalid identity key usage: %w", err)
+		}
+		key.KeyOps = ops
+	}
+
+	// Encode JWK
+	out, err := json.Marshal(&key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode identity public key: %w", err)
+	}
+
+	// No error
+	return out, nil
+}
+
 // Decrypt private key with given transformer.
 func (i *Identity) Decrypt(ctx context.Context, t value.Transformer) (*JSONWebKey, error) {
 	// Check arguments
@@ -88,4 +119,7 @@ type JSONWebKey struct {
 	Crv string `json:"crv"`
 	X   string `json:"x"`
 	D   string `json:"d"`
+
+	KeyOps []string         `json:"key_ops,omitempty"`
+	Usage  *crypto.KeyUsage `json:"harp.elastic.co/usage,omitempty"`
 }
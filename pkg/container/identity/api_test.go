@@ -0,0 +1,55 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package identity
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/elastic/harp/pkg/sdk/security/crypto"
+)
+
+func TestIdentity_PublicJWK_KeyUsage(t *testing.T) {
+	// Generated identity declares sealing usage
+	generated, _, err := New("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		desc     string
+		identity *Identity
+		wantErr  error
+	}{
+		{desc: "generated identity", identity: generated},
+		{desc: "legacy identity without usage", identity: &Identity{Public: "a"}},
+		{desc: "signing key", identity: &Identity{Public: "a", Usage: &crypto.KeyUsage{Use: crypto.KeyUsageSign}}, wantErr: crypto.ErrKeyUsageMismatch},
+		{desc: "expired key", identity: &Identity{Public: "a", Usage: &crypto.KeyUsage{Use: crypto.KeyUsageSeal, ExpiresAt: 1}}, wantErr: crypto.ErrKeyExpired},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			jwk, err := tC.identity.PublicJWK()
+			if err != nil {
+				t.Fatalf("PublicJWK() error = %v", err)
+			}
+			if err := crypto.ValidateKeyUsage(jwk, crypto.KeyUsageSeal); !errors.Is(err, tC.wantErr) {
+				t.Fatalf("ValidateKeyUsage() error = %v, want %v", err, tC.wantErr)
+			}
+		})
+	}
+}
@@ -29,11 +29,13 @@ import (
 	"github.com/go-ozzo/ozzo-validation/v4/is"
 	"golang.org/x/crypto/nacl/box"
 
+	"github.com/elastic/harp/pkg/sdk/security/crypto"
 	"github.com/elastic/harp/pkg/sdk/types"
 )
 
 const (
-	apiVersion = "harp.elastic.co/v1"
+	// APIVersion is the identity format version.
+	APIVersion = "harp.elastic.co/v1"
 	kind       = "ContainerIdentity"
 )
 
@@ -52,12 +54,20 @@ func New(description string) (*Identity, []byte, error) {
 		return nil, nil, fmt.Errorf("unable to generate identity keypair: %v", err)
 	}
 
+	// Declare key usage
+	ops, err := crypto.KeyOps(crypto.KeyUsageSeal)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to prepare identity key usage: %v", err)
+	}
+
 	// Wrap as JWK
 	jwk := JSONWebKey{
-		Kty: "OKP",
-		Crv: "X25519",
-		X:   base64.RawURLEncoding.EncodeToString(pub[:]),
-		D:   base64.RawURLEncoding.EncodeToString(priv[:]),
+		Kty:    "OKP",
+		Crv:    "X25519",
+		X:      base64.RawURLEncoding.EncodeToString(pub[:]),
+		D:      base64.RawURLEncoding.EncodeToString(priv[:]),
+		KeyOps: ops,
+		Usage:  &crypto.KeyUsage{Use: crypto.KeyUsageSeal},
 	}
 
 	// Encode JWK as json
@@ -68,14 +78,41 @@ func New(description string) (*Identity, []byte, error) {
 
 	// Return unsealed identity
 	return &Identity{
-		APIVersion:  apiVersion,
+		APIVersion:  APIVersion,
 		Kind:        kind,
 		Timestamp:   time.Now().UTC(),
 		Description: description,
 		Public:      base64.RawURLEncoding.EncodeToString(pub[:]),
+		Usage:       &crypto.KeyUsage{Use: crypto.KeyUsageSeal},
 	}, payload, nil
 }
 
+// FromPublicKey creates an identity for a private key held outside of the
+// identity, such as a hardware token.
+func FromPublicKey(description string, publicKey *[32]byte, private *PrivateKey) (*Identity, error) {
+	// Check arguments
+	if err := validation.Validate(description, validation.Required, is.ASCII); err != nil {
+		return nil, fmt.Errorf("unable to create identity with invalid description: %v", err)
+	}
+	if publicKey == nil {
+		return nil, fmt.Errorf("unable to create identity without public key")
+	}
+	if private == nil {
+		return nil, fmt.Errorf("unable to create identity without private key reference")
+	}
+
+	// No error
+	return &Identity{
+		APIVersion:  APIVersion,
+		Kind:        kind,
+		Timestamp:   time.Now().UTC(),
+		Description: description,
+		Public:      base64.RawURLEncoding.EncodeToString(publicKey[:]),
+		Private:     private,
+		Usage:       &crypto.KeyUsage{Use: crypto.KeyUsageSeal},
+	}, nil
+}
+
 // FromReader extract identity instance from reader.
 func FromReader(r io.Reader) (*Identity, error) {
 	// Check arguments
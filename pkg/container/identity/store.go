@@ -0,0 +1,221 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package identity
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/elastic/harp/pkg/sdk/keyring"
+)
+
+const (
+	// KeychainPrefix prefixes identity references resolved from the keychain
+	// store (i.e. "keychain:prod-unsealer").
+	KeychainPrefix = "keychain:"
+	// KeyringService is the keyring service name holding stored identities.
+	KeyringService = "harp-identity"
+	// KeyringIndexService is the keyring service name holding the stored
+	// identity name index, keyrings can't be listed portably.
+	KeyringIndexService = "harp"
+	// KeyringIndexAccount is the keyring account name holding the stored
+	// identity name index.
+	KeyringIndexAccount = "identities"
+)
+
+var (
+	// ErrNotFound is raised when the requested identity is not stored.
+	ErrNotFound = errors.New("identity not found")
+	// ErrAlreadyExists is raised when storing an identity with a name
+	// already used.
+	ErrAlreadyExists = errors.New("identity already exists")
+)
+
+// KeychainName returns the identity name of a keychain reference.
+func KeychainName(ref string) (string, bool) {
+	if !strings.HasPrefix(ref, KeychainPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(ref, KeychainPrefix), true
+}
+
+// Store persists encoded identities, including their wrapped private key,
+// in a keyring.
+type Store struct {
+	ring keyring.Keyring
+}
+
+// NewStore returns an identity store backed by the given keyring.
+func NewStore(ring keyring.Keyring) *Store {
+	return &Store{
+		ring: ring,
+	}
+}
+
+// Put stores the encoded identity with the given name. Existing identities
+// are never overwritten.
+func (s *Store) Put(name string, content []byte) error {
+	// Check arguments
+	if err := checkName(name); err != nil {
+		return err
+	}
+	if _, err := FromReader(bytes.NewReader(content)); err != nil {
+		return fmt.Errorf("unable to store an invalid identity: %w", err)
+	}
+
+	// Check name collision
+	names, err := s.List()
+	if err != nil {
+		return err
+	}
+	if contains(names, name) {
+		return fmt.Errorf("unable to store identity '%s': %w", name, ErrAlreadyExists)
+	}
+	if _, err := s.ring.Get(KeyringService, name); !errors.Is(err, keyring.ErrNotFound) {
+		if err != nil {
+			return fmt.Errorf("unable to check identity '%s': %w", name, err)
+		}
+		return fmt.Errorf("unable to store identity '%s': %w", name, ErrAlreadyExists)
+	}
+
+	// Store identity then index it
+	if err := s.ring.Set(KeyringService, name, string(content)); err != nil {
+		return fmt.Errorf("unable to store identity '%s': %w", name, err)
+	}
+	if err := s.saveIndex(append(names, name)); err != nil {
+		return err
+	}
+
+	// No error
+	return nil
+}
+
+// Get returns the encoded identity stored with the given name.
+func (s *Store) Get(name string) ([]byte, error) {
+	// Check arguments
+	if err := checkName(name); err != nil {
+		return nil, err
+	}
+
+	content, err := s.ring.Get(KeyringService, name)
+	switch {
+	case errors.Is(err, keyring.ErrNotFound):
+		return nil, fmt.Errorf("unable to retrieve identity '%s': %w", name, ErrNotFound)
+	case err != nil:
+		return nil, fmt.Errorf("unable to retrieve identity '%s': %w", name, err)
+	default:
+	}
+
+	// No error
+	return []byte(content), nil
+}
+
+// Delete removes the identity stored with the given name.
+func (s *Store) Delete(name string) error {
+	// Check arguments
+	if err := checkName(name); err != nil {
+		return err
+	}
+
+	names, err := s.List()
+	if err != nil {
+		return err
+	}
+
+	err = s.ring.Delete(KeyringService, name)
+	switch {
+	case errors.Is(err, keyring.ErrNotFound):
+		if !contains(names, name) {
+			return fmt.Errorf("unable to delete identity '%s': %w", name, ErrNotFound)
+		}
+		// Repair the index of an externally deleted identity
+	case err != nil:
+		return fmt.Errorf("unable to delete identity '%s': %w", name, err)
+	default:
+	}
+
+	// Remove from index
+	remaining := []string{}
+	for _, n := range names {
+		if n != name {
+			remaining = append(remaining, n)
+		}
+	}
+
+	return s.saveIndex(remaining)
+}
+
+// List returns sorted stored identity names.
+func (s *Store) List() ([]string, error) {
+	content, err := s.ring.Get(KeyringIndexService, KeyringIndexAccount)
+	switch {
+	case errors.Is(err, keyring.ErrNotFound):
+		return []string{}, nil
+	case err != nil:
+		return nil, fmt.Errorf("unable to retrieve identity index: %w", err)
+	default:
+	}
+
+	var names []string
+	if err := json.Unmarshal([]byte(content), &names); err != nil {
+		return nil, fmt.Errorf("unable to decode identity index: %w", err)
+	}
+	sort.Strings(names)
+
+	// No error
+	return names, nil
+}
+
+// -----------------------------------------------------------------------------
+
+var nameRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+func checkName(name string) error {
+	if !nameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid identity name '%s', only letters, digits, '.', '_' and '-' are allowed", name)
+	}
+	return nil
+}
+
+func (s *Store) saveIndex(names []string) error {
+	sort.Strings(names)
+
+	content, err := json.Marshal(names)
+	if err != nil {
+		return fmt.Errorf("unable to encode identity index: %w", err)
+	}
+	if err := s.ring.Set(KeyringIndexService, KeyringIndexAccount, string(content)); err != nil {
+		return fmt.Errorf("unable to store identity index: %w", err)
+	}
+
+	return nil
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package identity
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/elastic/harp/pkg/sdk/keyring"
+)
+
+func encodedIdentity(t *testing.T, description string) []byte {
+	t.Helper()
+
+	id, _, err := New(description)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id.Private = &PrivateKey{Encoding: "jwe", Content: "test"}
+
+	content, err := json.Marshal(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+func TestStore(t *testing.T) {
+	ring := keyring.File(filepath.Join(t.TempDir(), "keyring"))
+	s := NewStore(ring)
+
+	// Empty store
+	if names, err := s.List(); err != nil || len(names) != 0 {
+		t.Fatalf("unexpected identities %v (%v)", names, err)
+	}
+	if _, err := s.Get("prod"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err := s.Delete("prod"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// Store identities
+	prod, staging := encodedIdentity(t, "prod"), encodedIdentity(t, "staging")
+	if err := s.Put("staging", staging); err != nil {
+		t.Fatalf("unable to store identity: %v", err)
+	}
+	if err := s.Put("prod", prod); err != nil {
+		t.Fatalf("unable to store identity: %v", err)
+	}
+	if names, err := s.List(); err != nil || !reflect.DeepEqual(names, []string{"prod", "staging"}) {
+		t.Fatalf("unexpected identities %v (%v)", names, err)
+	}
+	if got, err := s.Get("prod"); err != nil || string(got) != string(prod) {
+		t.Fatalf("unexpected identity %s (%v)", got, err)
+	}
+
+	// Existing identities are never overwritten
+	if err := s.Put("prod", staging); !errors.Is(err, ErrAlreadyExists) {
+		t.Fatalf("expected ErrAlreadyExists, got %v", err)
+	}
+	if got, _ := s.Get("prod"); string(got) != string(prod) {
+		t.Fatal("identity must not be overwritten")
+	}
+
+	// Unindexed keyring entries are also protected
+	if err := ring.Set(KeyringService, "legacy", string(prod)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put("legacy", staging); !errors.Is(err, ErrAlreadyExists) {
+		t.Fatalf("expected ErrAlreadyExists, got %v", err)
+	}
+
+	// Externally deleted identities are removed from the index
+	if err := ring.Delete(KeyringService, "staging"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("staging"); err != nil {
+		t.Fatalf("unable to delete identity: %v", err)
+	}
+	if err := s.Delete("prod"); err != nil {
+		t.Fatalf("unable to delete identity: %v", err)
+	}
+	if names, err := s.List(); err != nil || len(names) != 0 {
+		t.Fatalf("unexpected identities %v (%v)", names, err)
+	}
+}
+
+func TestStore_Invalid(t *testing.T) {
+	s := NewStore(keyring.File(filepath.Join(t.TempDir(), "keyring")))
+
+	for _, name := range []string{"", "../prod", "prod/unsealer", "-prod"} {
+		if err := s.Put(name, encodedIdentity(t, "prod")); err == nil {
+			t.Errorf("name %q should be rejected", name)
+		}
+	}
+	if err := s.Put("prod", []byte("{}")); err == nil {
+		t.Error("invalid identity should be rejected")
+	}
+}
+
+func TestKeychainName(t *testing.T) {
+	if name, ok := KeychainName("keychain:prod-unsealer"); !ok || name != "prod-unsealer" {
+		t.Errorf("unexpected name %q (%v)", name, ok)
+	}
+	if _, ok := KeychainName("identity.json"); ok {
+		t.Error("file path must not be parsed as keychain reference")
+	}
+}
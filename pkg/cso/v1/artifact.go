@@ -0,0 +1,136 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	csov1 "github.com/elastic/harp/api/gen/go/cso/v1"
+	"github.com/elastic/harp/pkg/sdk/types"
+)
+
+// DefaultArtifactTypes lists artifact types accepted when the validation
+// policy doesn't declare its own.
+var DefaultArtifactTypes = []string{"docker", "oci", "npm", "maven", "generic"}
+
+// artifactDigestSizes maps supported digest algorithms to their hexadecimal
+// length.
+var artifactDigestSizes = map[string]int{
+	"sha256": 64,
+	"sha384": 96,
+	"sha512": 128,
+}
+
+var hexRegexp = regexp.MustCompile(`^[0-9a-f]+$`)
+
+// ArtifactTypes declares accepted artifact types, replacing the default ones.
+func ArtifactTypes(types ...string) ValidationOption {
+	return func(opts *Policy) {
+		opts.ArtifactTypes = types
+	}
+}
+
+// ParseArtifact validates the given artifact secret path and returns its
+// components.
+func ParseArtifact(path string, opts ...ValidationOption) (*csov1.Artifact, error) {
+	// Validate secret path first
+	if err := Validate(path, opts...); err != nil {
+		return nil, err
+	}
+
+	// Split path using '/'
+	parts := strings.Split(Clean(path), "/")
+	if parts[0] != ringArtifact {
+		return nil, fmt.Errorf("'%s' is not an artifact secret path", path)
+	}
+
+	return packArtifact(parts).GetArtifact(), nil
+}
+
+// ArtifactPath builds and validates the secret path of the given artifact.
+func ArtifactPath(a *csov1.Artifact) (string, error) {
+	// Check arguments
+	if a == nil {
+		return "", errors.New("unable to build path of a nil artifact")
+	}
+
+	return RingArtifact.Path(a.Type, a.Id, a.Key)
+}
+
+// -----------------------------------------------------------------------------
+
+func validateArtifact(parts []string, opts *Policy) error {
+	// Validate parts count
+	if len(parts) < 3 {
+		return fmt.Errorf("invalid part count for artifact secret path")
+	}
+
+	// Validate type
+	allowed := opts.ArtifactTypes
+	if allowed == nil {
+		allowed = DefaultArtifactTypes
+	}
+	switch {
+	case parts[0] == "":
+		return &ValidationError{Ring: ringArtifact, Component: "type", Reason: "must not be blank"}
+	case !types.StringArray(allowed).Contains(parts[0]):
+		return &ValidationError{Ring: ringArtifact, Component: "type", Value: parts[0], Reason: fmt.Sprintf("must be one of %s", strings.Join(allowed, ", "))}
+	default:
+	}
+
+	// Validate identifier
+	if err := validateArtifactID(parts[1]); err != nil {
+		return err
+	}
+
+	// Validate key
+	if strings.Join(parts[2:], "") == "" {
+		return &ValidationError{Ring: ringArtifact, Component: "key", Reason: "must not be blank"}
+	}
+
+	// Artifact has no more constraints
+	return nil
+}
+
+func validateArtifactID(id string) error {
+	if id == "" {
+		return &ValidationError{Ring: ringArtifact, Component: "id", Reason: "must not be blank"}
+	}
+
+	// Digest identifiers
+	idx := strings.Index(id, ":")
+	if idx < 0 {
+		return nil
+	}
+
+	algo, digest := id[:idx], id[idx+1:]
+	size, ok := artifactDigestSizes[algo]
+	switch {
+	case !ok:
+		return &ValidationError{Ring: ringArtifact, Component: "id", Value: id, Reason: fmt.Sprintf("unsupported digest algorithm '%s'", algo)}
+	case len(digest) != size || !hexRegexp.MatchString(digest):
+		return &ValidationError{Ring: ringArtifact, Component: "id", Value: id, Reason: fmt.Sprintf("%s digest must be %d hexadecimal characters", algo, size)}
+	default:
+	}
+
+	// No error
+	return nil
+}
@@ -0,0 +1,108 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package v1
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	csov1 "github.com/elastic/harp/api/gen/go/cso/v1"
+)
+
+const testDigest = "sha256:fab2dded59dd0c2894dd9dbae71418f565be5bd0d8fd82365c16aec41c7e367f"
+
+func TestValidate_ArtifactComponents(t *testing.T) {
+	testCases := []struct {
+		path      string
+		component string
+	}{
+		// Previously accepted, the blank type was never checked
+		{path: "artifact//" + testDigest + "/key", component: "type"},
+		// Previously reported as an invalid type
+		{path: "artifact/docker//key", component: "id"},
+		{path: "artifact/helm/" + testDigest + "/key", component: "type"},
+		{path: "artifact/docker/sha1:da39a3ee5e6b4b0d3255bfef95601890afd80709/key", component: "id"},
+		{path: "artifact/docker/sha256:fab2/key", component: "id"},
+		{path: "artifact/docker/sha256:zz2dded59dd0c2894dd9dbae71418f565be5bd0d8fd82365c16aec41c7e367f/key", component: "id"},
+		{path: "artifact/docker/" + testDigest + "//", component: "key"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.path, func(t *testing.T) {
+			err := Validate(tC.path)
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("expected a validation error, got %v", err)
+			}
+			if verr.Ring != "artifact" || verr.Component != tC.component {
+				t.Errorf("expected invalid artifact %s, got %v", tC.component, verr)
+			}
+		})
+	}
+}
+
+func TestValidate_ArtifactTypes(t *testing.T) {
+	path := "artifact/helm/" + testDigest + "/key"
+	if err := Validate(path); err == nil {
+		t.Fatal("helm type must not be accepted by default")
+	}
+	if err := Validate(path, ArtifactTypes("helm")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := Validate("artifact/docker/"+testDigest+"/key", ArtifactTypes("helm")); err == nil {
+		t.Fatal("configured types must replace default ones")
+	}
+}
+
+func TestParseArtifact(t *testing.T) {
+	want := &csov1.Artifact{
+		Type: "docker",
+		Id:   testDigest,
+		Key:  "attestations/snyk_report",
+	}
+
+	a, err := ParseArtifact("artifact/docker/" + testDigest + "/attestations/snyk_report")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !proto.Equal(a, want) {
+		t.Errorf("unexpected artifact %v", a)
+	}
+
+	// Round trip
+	path, err := ArtifactPath(a)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != "artifact/docker/"+testDigest+"/attestations/snyk_report" {
+		t.Errorf("unexpected path %q", path)
+	}
+
+	// Invalid inputs
+	if _, err := ParseArtifact("product/foo/v1.0.0/key"); err == nil {
+		t.Error("non artifact path must be rejected")
+	}
+	if _, err := ArtifactPath(nil); err == nil {
+		t.Error("nil artifact must be rejected")
+	}
+	if _, err := ArtifactPath(&csov1.Artifact{Type: "docker", Key: "key"}); err == nil {
+		t.Error("artifact without id must be rejected")
+	}
+}
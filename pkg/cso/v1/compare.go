@@ -0,0 +1,143 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package v1
+
+import (
+	"sort"
+	"strings"
+)
+
+// Compare returns an integer comparing two secret paths in canonical CSO
+// order. The result is 0 if a == b, -1 if a < b, and +1 if a > b.
+//
+// CSO paths are ordered by ring (meta, infra, platform, product, app,
+// artifact), then by stage severity (production, staging, qa, dev), then
+// component by component. Version components are compared using semantic
+// versioning precedence. Paths that are not CSO compliant are ordered
+// lexicographically after all CSO paths.
+func Compare(a, b string) int {
+	return compareKeys(newPathKey(a), newPathKey(b))
+}
+
+// SortPaths sorts the given secret paths in place using the canonical CSO
+// order.
+func SortPaths(paths []string) {
+	// Split paths once
+	keys := make([]*pathKey, len(paths))
+	for i, p := range paths {
+		keys[i] = newPathKey(p)
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool {
+		return compareKeys(keys[i], keys[j]) < 0
+	})
+	for i, k := range keys {
+		paths[i] = k.raw
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// pathKey holds the components of a CSO compliant path, parts is nil for
+// other paths.
+type pathKey struct {
+	raw   string
+	parts []string
+}
+
+func newPathKey(path string) *pathKey {
+	k := &pathKey{raw: path}
+	if Validate(path) == nil {
+		k.parts = strings.Split(Clean(path), "/")
+	}
+
+	return k
+}
+
+func compareKeys(a, b *pathKey) int {
+	pa, pb := a.parts, b.parts
+
+	switch {
+	case pa != nil && pb == nil:
+		return -1
+	case pa == nil && pb != nil:
+		return 1
+	case pa == nil && pb == nil:
+		return strings.Compare(a.raw, b.raw)
+	default:
+	}
+
+	// Ring order
+	if c := compareInt(int(FromRingName(pa[0])), int(FromRingName(pb[0]))); c != 0 {
+		return c
+	}
+
+	// Components
+	vIdx, hasVersion := versionIndex(pa)
+	stage := pa[0] == ringPlatform || pa[0] == ringApp
+	for i := 1; i < len(pa) && i < len(pb); i++ {
+		var c int
+		switch {
+		case i == 1 && stage:
+			c = compareInt(int(FromStageName(pa[i])), int(FromStageName(pb[i])))
+		case i == vIdx && hasVersion:
+			c = compareVersion(pa[i], pb[i])
+		default:
+			c = strings.Compare(pa[i], pb[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	if c := compareInt(len(pa), len(pb)); c != 0 {
+		return c
+	}
+
+	// Equivalent versions, keep a total order
+	return strings.Compare(a.raw, b.raw)
+}
+
+func compareVersion(a, b string) int {
+	va, errA := parseVersion(a)
+	vb, errB := parseVersion(b)
+
+	switch {
+	case errA == nil && errB == nil:
+		return va.Compare(vb)
+	case errA == nil:
+		// Valid versions first
+		return -1
+	case errB == nil:
+		return 1
+	default:
+	}
+
+	return strings.Compare(a, b)
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+	}
+
+	return 0
+}
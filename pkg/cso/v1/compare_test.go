@@ -0,0 +1,103 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package v1
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// canonicalOrder lists paths in expected canonical order.
+var canonicalOrder = []string{
+	"meta/cso/owner",
+	"meta/harp/team/lead",
+	"infra/aws/security/eu-central-1/ec2/ssh/default/encryption_key",
+	"infra/gcp/security/europe-west1/gke/token",
+	"platform/production/customer1/eu-central-1/postgresql/admin_credentials",
+	"platform/production/customer1/us-east-1/postgresql/admin_credentials",
+	"platform/staging/customer1/eu-central-1/postgresql/admin_credentials",
+	"platform/qa/customer1/eu-central-1/postgresql/admin_credentials",
+	"platform/dev/customer1/eu-central-1/postgresql/admin_credentials",
+	"product/ece/v1.0.0-rc.1/server/private_key",
+	"product/ece/v1.0.0/server/private_key",
+	"product/ece/v1.2.0/server/private_key",
+	"product/ece/v1.10.0/adminconsole/private_key",
+	"product/ece/v1.10.0/server/private_key",
+	"product/ece/v1.10.0/server/tls/private_key",
+	"product/kibana/v7.10.0/server/cookie_encryption_key",
+	"app/production/customer1/ece/v1.2.0/adminconsole/database/usage_credentials",
+	"app/production/customer1/ece/v1.10.0/adminconsole/database/usage_credentials",
+	"app/production/customer2/ece/v1.0.0/adminconsole/database/usage_credentials",
+	"app/staging/customer1/ece/v1.0.0/adminconsole/database/usage_credentials",
+	"app/qa/customer1/ece/v1.0.0/adminconsole/database/usage_credentials",
+	"app/dev/customer1/ece/v1.0.0/adminconsole/database/usage_credentials",
+	"artifact/docker/sha256:fab2dded59dd0c2894dd9dbae71418f565be5bd0d8fd82365c16aec41c7e367f/attestations/snyk_report",
+	"artifact/npm/left-pad/publish_token",
+	"app/foo",
+	"legacy/secrets",
+	"services/production/database",
+	"zzz",
+}
+
+func TestCompare(t *testing.T) {
+	// Exhaustive pairs
+	for i, a := range canonicalOrder {
+		for j, b := range canonicalOrder {
+			want := compareInt(i, j)
+			if got := Compare(a, b); got != want {
+				t.Errorf("Compare(%q, %q) = %d, want %d", a, b, got, want)
+			}
+		}
+	}
+}
+
+func TestCompare_EquivalentVersions(t *testing.T) {
+	a := "product/ece/v1.0.0/server/private_key"
+	b := "product/ece/1.0.0/server/private_key"
+
+	// Total order, equivalent paths are ordered lexicographically
+	if Compare(a, b) <= 0 || Compare(b, a) >= 0 {
+		t.Errorf("expected %q before %q", b, a)
+	}
+	if Compare(a, a) != 0 {
+		t.Errorf("expected %q to be equal to itself", a)
+	}
+}
+
+func TestSortPaths(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 32; i++ {
+		paths := append([]string(nil), canonicalOrder...)
+		r.Shuffle(len(paths), func(i, j int) { paths[i], paths[j] = paths[j], paths[i] })
+
+		SortPaths(paths)
+		if diff := cmp.Diff(canonicalOrder, paths); diff != "" {
+			t.Fatalf("unexpected order (-want +got):\n%s", diff)
+		}
+	}
+
+	// Mixed CSO and non-CSO paths
+	paths := []string{"zzz", "app/foo", "product/ece/v1.10.0/server/key", "aaa", "product/ece/v1.9.0/server/key"}
+	SortPaths(paths)
+	if diff := cmp.Diff([]string{"product/ece/v1.9.0/server/key", "product/ece/v1.10.0/server/key", "aaa", "app/foo", "zzz"}, paths); diff != "" {
+		t.Errorf("unexpected order (-want +got):\n%s", diff)
+	}
+}
@@ -18,6 +18,7 @@
 package v1
 
 import (
+	"fmt"
 	"strings"
 
 	csov1 "github.com/elastic/harp/api/gen/go/cso/v1"
@@ -77,3 +78,49 @@ func FromStageName(name string) csov1.QualityLevel {
 
 	return csov1.QualityLevel(i)
 }
+
+// -----------------------------------------------------------------------------
+
+var ringAliases = map[string]csov1.RingLevel{
+	"infrastructure": csov1.RingLevel_RING_LEVEL_INFRASTRUCTURE,
+	"application":    csov1.RingLevel_RING_LEVEL_APPLICATION,
+}
+
+// RingLevelFromString returns the ring level matching the given name. It
+// accepts short ring names used in paths (`platform`, `app`), long names
+// (`application`) and enum constant names (`RING_LEVEL_PLATFORM`).
+func RingLevelFromString(name string) (csov1.RingLevel, error) {
+	// Enum constant name
+	if v, ok := csov1.RingLevel_value[strings.ToUpper(name)]; ok && v > 0 {
+		return csov1.RingLevel(v), nil
+	}
+
+	// Long name
+	if lvl, ok := ringAliases[strings.ToLower(name)]; ok {
+		return lvl, nil
+	}
+
+	// Short name
+	if lvl := FromRingName(name); lvl > csov1.RingLevel_RING_LEVEL_UNKNOWN {
+		return lvl, nil
+	}
+
+	return csov1.RingLevel_RING_LEVEL_INVALID, fmt.Errorf("unknown ring level '%s'", name)
+}
+
+// QualityLevelFromString returns the quality level matching the given name.
+// It accepts stage names used in paths (`production`) and enum constant names
+// (`QUALITY_LEVEL_PRODUCTION`).
+func QualityLevelFromString(name string) (csov1.QualityLevel, error) {
+	// Enum constant name
+	if v, ok := csov1.QualityLevel_value[strings.ToUpper(name)]; ok && v > 0 {
+		return csov1.QualityLevel(v), nil
+	}
+
+	// Stage name
+	if lvl := FromStageName(name); lvl > csov1.QualityLevel_QUALITY_LEVEL_UNKNOWN {
+		return lvl, nil
+	}
+
+	return csov1.QualityLevel_QUALITY_LEVEL_INVALID, fmt.Errorf("unknown quality level '%s'", name)
+}
@@ -0,0 +1,87 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package v1
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	csov1 "github.com/elastic/harp/api/gen/go/cso/v1"
+)
+
+type jsonOptions struct {
+	allowUnknown bool
+}
+
+// JSONOption defines the functional pattern for JSON decoding settings.
+type JSONOption func(*jsonOptions)
+
+// WithAllowUnknownFields ignores unknown fields instead of rejecting them.
+func WithAllowUnknownFields(value bool) JSONOption {
+	return func(opts *jsonOptions) {
+		opts.allowUnknown = value
+	}
+}
+
+// MarshalJSON encodes the given secret as JSON with enums as names and proto
+// field names.
+func MarshalJSON(s *csov1.Secret) ([]byte, error) {
+	// Check arguments
+	if s == nil {
+		return nil, fmt.Errorf("unable to marshal nil secret")
+	}
+
+	m := protojson.MarshalOptions{
+		UseProtoNames:  true,
+		UseEnumNumbers: false,
+	}
+
+	out, err := m.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal secret as JSON: %w", err)
+	}
+
+	// No error
+	return out, nil
+}
+
+// UnmarshalJSON decodes the given JSON as a secret. Enums are accepted as
+// names or numbers, and field names as proto or JSON names.
+func UnmarshalJSON(data []byte, s *csov1.Secret, opts ...JSONOption) error {
+	// Check arguments
+	if s == nil {
+		return fmt.Errorf("unable to unmarshal to nil secret")
+	}
+
+	// Apply options
+	dopts := &jsonOptions{}
+	for _, o := range opts {
+		o(dopts)
+	}
+
+	u := protojson.UnmarshalOptions{
+		DiscardUnknown: dopts.allowUnknown,
+	}
+	if err := u.Unmarshal(data, s); err != nil {
+		return fmt.Errorf("unable to unmarshal secret from JSON: %w", err)
+	}
+
+	// No error
+	return nil
+}
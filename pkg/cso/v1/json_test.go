@@ -0,0 +1,179 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package v1
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	csov1 "github.com/elastic/harp/api/gen/go/cso/v1"
+)
+
+func Test_JSON_RoundTrip(t *testing.T) {
+	for ring := range csov1.RingLevel_name {
+		for quality := range csov1.QualityLevel_name {
+			in := &csov1.Secret{
+				RingLevel: csov1.RingLevel(ring),
+				Path: &csov1.Secret_Platform{
+					Platform: &csov1.Platform{
+						Stage:       csov1.QualityLevel(quality),
+						Name:        "security",
+						Region:      "eu-central-1",
+						ServiceName: "vault",
+						Key:         "database",
+					},
+				},
+			}
+
+			out, err := MarshalJSON(in)
+			if err != nil {
+				t.Fatalf("unable to marshal secret: %v", err)
+			}
+
+			// Enums must be encoded as names
+			if ring > 0 && !strings.Contains(string(out), csov1.RingLevel_name[ring]) {
+				t.Errorf("ring level must be encoded as name, got %s", out)
+			}
+			if quality > 0 && !strings.Contains(string(out), csov1.QualityLevel_name[quality]) {
+				t.Errorf("quality level must be encoded as name, got %s", out)
+			}
+			if strings.Contains(string(out), "serviceName") {
+				t.Errorf("proto field names must be used, got %s", out)
+			}
+
+			var got csov1.Secret
+			if err := UnmarshalJSON(out, &got); err != nil {
+				t.Fatalf("unable to unmarshal secret: %v", err)
+			}
+			if !proto.Equal(in, &got) {
+				t.Errorf("round-trip mismatch, got %v, want %v", &got, in)
+			}
+		}
+	}
+}
+
+func Test_UnmarshalJSON(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		input   string
+		opts    []JSONOption
+		want    *csov1.Secret
+		wantErr bool
+	}{
+		{
+			desc:  "numeric enums",
+			input: `{"ringLevel": 4, "platform": {"stage": 2, "name": "security"}}`,
+			want: &csov1.Secret{
+				RingLevel: csov1.RingLevel_RING_LEVEL_PLATFORM,
+				Path: &csov1.Secret_Platform{
+					Platform: &csov1.Platform{Stage: csov1.QualityLevel_QUALITY_LEVEL_PRODUCTION, Name: "security"},
+				},
+			},
+		},
+		{
+			desc:    "unknown field rejected",
+			input:   `{"ring_level": "RING_LEVEL_META", "meta": {"key": "cso"}, "owner": "foo"}`,
+			wantErr: true,
+		},
+		{
+			desc:  "unknown field allowed",
+			input: `{"ring_level": "RING_LEVEL_META", "meta": {"key": "cso"}, "owner": "foo"}`,
+			opts:  []JSONOption{WithAllowUnknownFields(true)},
+			want: &csov1.Secret{
+				RingLevel: csov1.RingLevel_RING_LEVEL_META,
+				Path:      &csov1.Secret_Meta{Meta: &csov1.Meta{Key: "cso"}},
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var got csov1.Secret
+			err := UnmarshalJSON([]byte(tC.input), &got, tC.opts...)
+			if (err != nil) != tC.wantErr {
+				t.Fatalf("error during the call, error = %v, wantErr %v", err, tC.wantErr)
+			}
+			if tC.wantErr {
+				return
+			}
+			if !proto.Equal(tC.want, &got) {
+				t.Errorf("got %v, want %v", &got, tC.want)
+			}
+		})
+	}
+}
+
+func Test_RingLevelFromString(t *testing.T) {
+	testCases := []struct {
+		name    string
+		want    csov1.RingLevel
+		wantErr bool
+	}{
+		{name: "meta", want: csov1.RingLevel_RING_LEVEL_META},
+		{name: "infra", want: csov1.RingLevel_RING_LEVEL_INFRASTRUCTURE},
+		{name: "Infrastructure", want: csov1.RingLevel_RING_LEVEL_INFRASTRUCTURE},
+		{name: "platform", want: csov1.RingLevel_RING_LEVEL_PLATFORM},
+		{name: "product", want: csov1.RingLevel_RING_LEVEL_PRODUCT},
+		{name: "app", want: csov1.RingLevel_RING_LEVEL_APPLICATION},
+		{name: "application", want: csov1.RingLevel_RING_LEVEL_APPLICATION},
+		{name: "artifact", want: csov1.RingLevel_RING_LEVEL_ARTIFACT},
+		{name: "RING_LEVEL_PLATFORM", want: csov1.RingLevel_RING_LEVEL_PLATFORM},
+		{name: "RING_LEVEL_INVALID", wantErr: true},
+		{name: "foo", wantErr: true},
+		{name: "", wantErr: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			got, err := RingLevelFromString(tC.name)
+			if (err != nil) != tC.wantErr {
+				t.Errorf("error during the call, error = %v, wantErr %v", err, tC.wantErr)
+			}
+			if got != tC.want {
+				t.Errorf("got %v, want %v", got, tC.want)
+			}
+		})
+	}
+}
+
+func Test_QualityLevelFromString(t *testing.T) {
+	testCases := []struct {
+		name    string
+		want    csov1.QualityLevel
+		wantErr bool
+	}{
+		{name: "production", want: csov1.QualityLevel_QUALITY_LEVEL_PRODUCTION},
+		{name: "staging", want: csov1.QualityLevel_QUALITY_LEVEL_STAGING},
+		{name: "QA", want: csov1.QualityLevel_QUALITY_LEVEL_QA},
+		{name: "dev", want: csov1.QualityLevel_QUALITY_LEVEL_DEV},
+		{name: "quality_level_dev", want: csov1.QualityLevel_QUALITY_LEVEL_DEV},
+		{name: "unknown", wantErr: true},
+		{name: "prod", wantErr: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			got, err := QualityLevelFromString(tC.name)
+			if (err != nil) != tC.wantErr {
+				t.Errorf("error during the call, error = %v, wantErr %v", err, tC.wantErr)
+			}
+			if got != tC.want {
+				t.Errorf("got %v, want %v", got, tC.want)
+			}
+		})
+	}
+}
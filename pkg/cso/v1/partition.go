@@ -0,0 +1,259 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package v1
+
+import (
+	"fmt"
+
+	"github.com/elastic/harp/pkg/sdk/types"
+)
+
+// Partition describes an isolated cloud provider partition with its own
+// region set.
+type Partition struct {
+	// Provider is the cloud provider name (aws, gcp, azure).
+	Provider string
+	// Name is the partition name, the default partition is named "standard"
+	// (or "public" for azure).
+	Name string
+	// Regions lists the partition regions.
+	Regions types.StringArray
+}
+
+// Default reports whether the partition is the default provider partition.
+func (p *Partition) Default() bool {
+	return p.Name == "standard" || p.Name == "public"
+}
+
+// ExplicitProvider returns the provider name designating the partition in
+// secret paths (i.e. aws-cn).
+func (p *Partition) ExplicitProvider() string {
+	if p.Default() {
+		return p.Provider
+	}
+	return fmt.Sprintf("%s-%s", p.Provider, p.Name)
+}
+
+var cloudPartitions = []*Partition{
+	{
+		Provider: "aws",
+		Name:     "standard",
+		Regions: types.StringArray{
+			"global",
+			"us-east-1",
+			"us-east-2",
+			"us-west-1",
+			"us-west-2",
+			"ap-east-1",
+			"ap-south-1",
+			"ap-northeast-3",
+			"ap-northeast-2",
+			"ap-southeast-1",
+			"ap-southeast-2",
+			"ap-northeast-1",
+			"ca-central-1",
+			"eu-central-1",
+			"eu-west-1",
+			"eu-west-2",
+			"eu-west-3",
+			"eu-north-1",
+			"me-south-1",
+			"sa-east-1",
+		},
+	},
+	{
+		Provider: "aws",
+		Name:     "cn",
+		Regions: types.StringArray{
+			"cn-north-1",
+			"cn-northwest-1",
+		},
+	},
+	{
+		Provider: "aws",
+		Name:     "us-gov",
+		Regions: types.StringArray{
+			"us-gov-east-1",
+			"us-gov-west-1",
+		},
+	},
+	{
+		Provider: "gcp",
+		Name:     "standard",
+		Regions: types.StringArray{
+			"global",
+			"asia-east1",
+			"asia-east2",
+			"asia-northeast1",
+			"asia-northeast2",
+			"asia-south1",
+			"asia-southeast1",
+			"australia-southeast1",
+			"europe-north1",
+			"europe-west1",
+			"europe-west2",
+			"europe-west3",
+			"europe-west4",
+			"europe-west6",
+			"northamerica-northeast1",
+			"southamerica-east1",
+			"us-central1",
+			"us-east1",
+			"us-east4",
+			"us-west1",
+			"us-west2",
+		},
+	},
+	{
+		Provider: "azure",
+		Name:     "public",
+		Regions: types.StringArray{
+			"global",
+			"eastasia",
+			"southeastasia",
+			"centralus",
+			"eastus",
+			"eastus2",
+			"westus",
+			"northcentralus",
+			"southcentralus",
+			"northeurope",
+			"westeurope",
+			"japanwest",
+			"japaneast",
+			"brazilsouth",
+			"australiaeast",
+			"australiasoutheast",
+			"southindia",
+			"centralindia",
+			"westindia",
+			"canadacentral",
+			"canadaeast",
+			"uksouth",
+			"ukwest",
+			"westcentralus",
+			"westus2",
+			"koreacentral",
+			"koreasouth",
+			"francecentral",
+			"francesouth",
+			"australiacentral",
+			"australiacentral2",
+			"uaecentral",
+			"uaenorth",
+			"southafricanorth",
+			"southafricawest",
+			"switzerlandnorth",
+			"switzerlandwest",
+			"germanynorth",
+			"germanywestcentral",
+			"norwaywest",
+			"norwayeast",
+			"brazilsoutheast",
+		},
+	},
+	{
+		Provider: "azure",
+		Name:     "us-gov",
+		Regions: types.StringArray{
+			"usgovvirginia",
+			"usgoviowa",
+			"usgovarizona",
+			"usgovtexas",
+		},
+	},
+	{
+		Provider: "azure",
+		Name:     "china",
+		Regions: types.StringArray{
+			"chinaeast",
+			"chinaeast2",
+			"chinaeast3",
+			"chinanorth",
+			"chinanorth2",
+			"chinanorth3",
+		},
+	},
+	{
+		Provider: "azure",
+		Name:     "germany",
+		Regions: types.StringArray{
+			"germanycentral",
+			"germanynortheast",
+		},
+	},
+}
+
+// RequireExplicitPartition rejects base providers resolving regions outside
+// of their default partition for the given rings (all rings when none is
+// given), i.e. infra/aws-cn/... must be used instead of infra/aws/... for
+// cn-north-1.
+func RequireExplicitPartition(rings ...string) ValidationOption {
+	return func(opts *Policy) {
+		opts.ExplicitPartition = true
+		opts.ExplicitPartitionRings = rings
+	}
+}
+
+// ExplicitPartitionRequired reports whether the policy requires partition
+// explicit providers for the given ring.
+func (p *Policy) ExplicitPartitionRequired(ring string) bool {
+	if !p.ExplicitPartition {
+		return false
+	}
+	if len(p.ExplicitPartitionRings) == 0 {
+		return true
+	}
+
+	return types.StringArray(p.ExplicitPartitionRings).Contains(ring)
+}
+
+// ResolvePartition returns the partition of the given region for the cloud
+// provider. The provider is either a base provider (aws), which resolves the
+// region in all its partitions for compatibility, or a partition explicit
+// provider (aws-cn).
+func ResolvePartition(provider, region string) (*Partition, error) {
+	known := false
+	for _, p := range cloudPartitions {
+		if provider != p.Provider && provider != p.ExplicitProvider() {
+			continue
+		}
+		known = true
+
+		if p.Regions.Contains(region) {
+			return p, nil
+		}
+	}
+	if !known {
+		return nil, fmt.Errorf("cloud provider (%s) not supported", provider)
+	}
+
+	return nil, fmt.Errorf("invalid region (%s) on cloud provider (%s)", region, provider)
+}
+
+// -----------------------------------------------------------------------------
+
+// isCloudRegion reports whether the region belongs to a known partition.
+func isCloudRegion(region string) bool {
+	for _, p := range cloudPartitions {
+		if p.Regions.Contains(region) {
+			return true
+		}
+	}
+	return false
+}
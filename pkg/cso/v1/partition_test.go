@@ -0,0 +1,117 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package v1
+
+import (
+	"testing"
+)
+
+func TestValidate_Partitions(t *testing.T) {
+	testCases := []struct {
+		path       string
+		wantErr    bool
+		wantStrict bool
+	}{
+		// AWS standard
+		{path: "infra/aws/security/us-east-1/iam", wantErr: false, wantStrict: false},
+		{path: "infra/aws/security/global/iam", wantErr: false, wantStrict: false},
+		// AWS China
+		{path: "infra/aws-cn/security/cn-north-1/iam", wantErr: false, wantStrict: false},
+		{path: "infra/aws-cn/security/cn-northwest-1/iam", wantErr: false, wantStrict: false},
+		{path: "infra/aws/security/cn-north-1/iam", wantErr: false, wantStrict: true},
+		{path: "infra/aws-cn/security/us-east-1/iam", wantErr: true, wantStrict: true},
+		// AWS GovCloud
+		{path: "infra/aws-us-gov/security/us-gov-west-1/iam", wantErr: false, wantStrict: false},
+		{path: "infra/aws/security/us-gov-east-1/iam", wantErr: false, wantStrict: true},
+		{path: "infra/aws-us-gov/security/cn-north-1/iam", wantErr: true, wantStrict: true},
+		// GCP
+		{path: "infra/gcp/security/us-east1/db", wantErr: false, wantStrict: false},
+		{path: "infra/gcp-cn/security/us-east1/db", wantErr: true, wantStrict: true},
+		// Azure public
+		{path: "infra/azure/security/westeurope/keyvault", wantErr: false, wantStrict: false},
+		// Azure Government
+		{path: "infra/azure-us-gov/security/usgovvirginia/keyvault", wantErr: false, wantStrict: false},
+		{path: "infra/azure/security/usgovtexas/keyvault", wantErr: false, wantStrict: true},
+		// Azure China
+		{path: "infra/azure-china/security/chinaeast2/keyvault", wantErr: false, wantStrict: false},
+		{path: "infra/azure/security/chinanorth/keyvault", wantErr: false, wantStrict: true},
+		{path: "infra/azure-china/security/westeurope/keyvault", wantErr: true, wantStrict: true},
+		// Azure Germany
+		{path: "infra/azure-germany/security/germanycentral/keyvault", wantErr: false, wantStrict: false},
+		{path: "infra/azure/security/germanynortheast/keyvault", wantErr: false, wantStrict: true},
+		{path: "infra/azure-germany/security/germanywestcentral/keyvault", wantErr: true, wantStrict: true},
+		// Platform regions
+		{path: "platform/production/customer1/cn-north-1/rds/adminconsole", wantErr: false, wantStrict: false},
+		{path: "platform/production/customer1/chinaeast/sql/adminconsole", wantErr: false, wantStrict: false},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.path, func(t *testing.T) {
+			if err := Validate(tc.path); (err != nil) != tc.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err := Validate(tc.path, RequireExplicitPartition()); (err != nil) != tc.wantStrict {
+				t.Errorf("Validate(strict) error = %v, wantErr %v", err, tc.wantStrict)
+			}
+		})
+	}
+}
+
+func TestValidate_ExplicitPartitionRings(t *testing.T) {
+	path := "infra/aws/security/cn-north-1/iam"
+
+	if err := Validate(path, RequireExplicitPartition("infra")); err == nil {
+		t.Error("error should be raised when infra ring is regulated")
+	}
+	if err := Validate(path, RequireExplicitPartition("platform")); err != nil {
+		t.Errorf("unexpected error when infra ring is not regulated: %v", err)
+	}
+}
+
+func TestResolvePartition(t *testing.T) {
+	testCases := []struct {
+		provider, region string
+		wantPartition    string
+		wantProvider     string
+		wantErr          bool
+	}{
+		{provider: "aws", region: "eu-west-1", wantPartition: "standard", wantProvider: "aws"},
+		{provider: "aws", region: "cn-north-1", wantPartition: "cn", wantProvider: "aws-cn"},
+		{provider: "aws-cn", region: "cn-north-1", wantPartition: "cn", wantProvider: "aws-cn"},
+		{provider: "aws", region: "us-gov-west-1", wantPartition: "us-gov", wantProvider: "aws-us-gov"},
+		{provider: "gcp", region: "europe-west1", wantPartition: "standard", wantProvider: "gcp"},
+		{provider: "azure", region: "eastus", wantPartition: "public", wantProvider: "azure"},
+		{provider: "azure", region: "usgoviowa", wantPartition: "us-gov", wantProvider: "azure-us-gov"},
+		{provider: "azure", region: "chinanorth2", wantPartition: "china", wantProvider: "azure-china"},
+		{provider: "azure", region: "germanycentral", wantPartition: "germany", wantProvider: "azure-germany"},
+		{provider: "aws-cn", region: "eu-west-1", wantErr: true},
+		{provider: "oci", region: "us-ashburn-1", wantErr: true},
+	}
+	for _, tc := range testCases {
+		p, err := ResolvePartition(tc.provider, tc.region)
+		if (err != nil) != tc.wantErr {
+			t.Fatalf("ResolvePartition(%s, %s) error = %v, wantErr %v", tc.provider, tc.region, err, tc.wantErr)
+		}
+		if err != nil {
+			continue
+		}
+		if p.Name != tc.wantPartition || p.ExplicitProvider() != tc.wantProvider {
+			t.Errorf("ResolvePartition(%s, %s) = %s (%s), want %s (%s)", tc.provider, tc.region, p.Name, p.ExplicitProvider(), tc.wantPartition, tc.wantProvider)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// ErrRegistryFrozen is raised when a ring validator is registered after
+	// the first path validation.
+	ErrRegistryFrozen = errors.New("cso: ring validator registry is frozen, call Reset first")
+	// ErrInvalidRingValidator is raised when registering a blank ring or a nil
+	// validator.
+	ErrInvalidRingValidator = errors.New("cso: invalid ring validator registration")
+)
+
+// RingValidator validates the components of a secret path, the ring prefix
+// excluded. The validation policy is retrieved from the context using
+// PolicyFromContext.
+type RingValidator interface {
+	Validate(ctx context.Context, components []string) error
+}
+
+// RingValidatorFunc adapts a function as a RingValidator.
+type RingValidatorFunc func(ctx context.Context, components []string) error
+
+// Validate calls the function.
+func (f RingValidatorFunc) Validate(ctx context.Context, components []string) error {
+	return f(ctx, components)
+}
+
+// RegisterRingValidator registers the validator of the given ring, replacing
+// the current one. Use RingValidatorFor to retrieve the current validator and
+// call it through from the new one.
+//
+// Registrations are refused once a path has been validated, to prevent
+// concurrent validations from observing partial registrations.
+func RegisterRingValidator(ring string, v RingValidator) error {
+	return registry.register(ring, v)
+}
+
+// RingValidatorFor returns the validator registered for the given ring.
+func RingValidatorFor(ring string) (RingValidator, bool) {
+	return registry.get(ring)
+}
+
+// DefaultRingValidator returns the built-in validator of the given ring.
+func DefaultRingValidator(ring string) (RingValidator, bool) {
+	fn, ok := defaultValidators[ring]
+	if !ok {
+		return nil, false
+	}
+	return builtin(fn), true
+}
+
+// Reset restores the built-in ring validators and allows registrations again.
+func Reset() {
+	registry.reset()
+}
+
+// -----------------------------------------------------------------------------
+
+type policyContextKey struct{}
+
+// WithPolicy returns a context carrying the given validation policy.
+func WithPolicy(ctx context.Context, p *Policy) context.Context {
+	return context.WithValue(ctx, policyContextKey{}, p)
+}
+
+// PolicyFromContext returns the validation policy carried by the context, the
+// default policy otherwise.
+func PolicyFromContext(ctx context.Context) *Policy {
+	if p, ok := ctx.Value(policyContextKey{}).(*Policy); ok && p != nil {
+		return p
+	}
+	return &Policy{}
+}
+
+// -----------------------------------------------------------------------------
+
+var defaultValidators = map[string]func([]string, *Policy) error{
+	"meta":     validateMeta,
+	"infra":    validateInfra,
+	"platform": validatePlatform,
+	"product":  validateProduct,
+	"app":      validateApplication,
+	"artifact": validateArtifact,
+}
+
+func builtin(fn func([]string, *Policy) error) RingValidator {
+	return RingValidatorFunc(func(ctx context.Context, components []string) error {
+		return fn(components, PolicyFromContext(ctx))
+	})
+}
+
+type ringRegistry struct {
+	mu         sync.RWMutex
+	validators map[string]RingValidator
+	frozen     int32
+}
+
+var registry = newRingRegistry()
+
+func newRingRegistry() *ringRegistry {
+	r := &ringRegistry{}
+	r.reset()
+	return r
+}
+
+func (r *ringRegistry) register(ring string, v RingValidator) error {
+	// Check arguments
+	if ring == "" || v == nil {
+		return ErrInvalidRingValidator
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if atomic.LoadInt32(&r.frozen) == 1 {
+		return fmt.Errorf("unable to register '%s' ring validator: %w", ring, ErrRegistryFrozen)
+	}
+	r.validators[ring] = v
+
+	// No error
+	return nil
+}
+
+// lookup returns the validator used to validate a path, registrations are
+// frozen from now on.
+func (r *ringRegistry) lookup(ring string) (RingValidator, bool) {
+	atomic.StoreInt32(&r.frozen, 1)
+	return r.get(ring)
+}
+
+func (r *ringRegistry) get(ring string) (RingValidator, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	v, ok := r.validators[ring]
+	return v, ok
+}
+
+func (r *ringRegistry) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.validators = map[string]RingValidator{}
+	for ring, fn := range defaultValidators {
+		r.validators[ring] = builtin(fn)
+	}
+	atomic.StoreInt32(&r.frozen, 0)
+}
@@ -0,0 +1,156 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// teamWhitelist requires application secret keys to be prefixed by an allowed
+// team name, after the built-in application checks.
+func teamWhitelist(next RingValidator, teams ...string) RingValidator {
+	return RingValidatorFunc(func(ctx context.Context, components []string) error {
+		// Call through
+		if err := next.Validate(ctx, components); err != nil {
+			return err
+		}
+
+		key := components[5]
+		for _, team := range teams {
+			if strings.HasPrefix(key, team+"-") {
+				return nil
+			}
+		}
+		return fmt.Errorf("secret key (%s) must be prefixed by an allowed team name", key)
+	})
+}
+
+func TestRegisterRingValidator_TeamWhitelist(t *testing.T) {
+	Reset()
+	t.Cleanup(Reset)
+
+	current, ok := RingValidatorFor("app")
+	if !ok {
+		t.Fatal("app ring validator must be registered")
+	}
+	if err := RegisterRingValidator("app", teamWhitelist(current, "payments", "security")); err != nil {
+		t.Fatalf("unable to register validator: %v", err)
+	}
+
+	testCases := []struct {
+		path    string
+		opts    []ValidationOption
+		wantErr bool
+	}{
+		{path: "app/production/name/foo/v1.0.0/component/payments-database", wantErr: false},
+		{path: "app/production/name/foo/v1.0.0/component/security-tls/server", wantErr: false},
+		{path: "app/production/name/foo/v1.0.0/component/database", wantErr: true},
+		{path: "app/production/name/foo/v1.0.0/component/marketing-database", wantErr: true},
+		// Built-in checks are still applied
+		{path: "app/essp/name/foo/v1.0.0/component/payments-database", wantErr: true},
+		// Validation policy is propagated
+		{path: "app/production/name/foo/~1.2/component/payments-database", wantErr: true},
+		{path: "app/production/name/foo/~1.2/component/payments-database", opts: []ValidationOption{AllowVersionRange()}, wantErr: false},
+		// Other rings are not affected
+		{path: "meta/cso/revision", wantErr: false},
+	}
+	for _, tC := range testCases {
+		if err := Validate(tC.path, tC.opts...); (err != nil) != tC.wantErr {
+			t.Errorf("Validate(%q) = %v, wantErr %v", tC.path, err, tC.wantErr)
+		}
+	}
+}
+
+func TestRegisterRingValidator_CustomRing(t *testing.T) {
+	Reset()
+	t.Cleanup(Reset)
+
+	if err := Validate("ops/oncall/pager"); err == nil {
+		t.Fatal("unknown ring should be rejected")
+	}
+
+	// Registry is frozen after validation
+	ops := RingValidatorFunc(func(_ context.Context, components []string) error {
+		if len(components) < 2 {
+			return errors.New("invalid part count for ops secret path")
+		}
+		return nil
+	})
+	if err := RegisterRingValidator("ops", ops); !errors.Is(err, ErrRegistryFrozen) {
+		t.Fatalf("frozen registry error should be raised, got %v", err)
+	}
+
+	Reset()
+	if err := RegisterRingValidator("ops", ops); err != nil {
+		t.Fatalf("unable to register validator: %v", err)
+	}
+	if err := Validate("ops/oncall/pager"); err != nil {
+		t.Errorf("custom ring should be accepted, got %v", err)
+	}
+	if err := Validate("ops/oncall"); err == nil {
+		t.Error("custom ring validator should be applied")
+	}
+
+	// Built-ins are restored
+	Reset()
+	if err := Validate("ops/oncall/pager"); err == nil {
+		t.Error("custom ring should be removed by reset")
+	}
+	if _, ok := DefaultRingValidator("ops"); ok {
+		t.Error("custom ring should not be a default validator")
+	}
+}
+
+func TestRegisterRingValidator_Invalid(t *testing.T) {
+	Reset()
+	t.Cleanup(Reset)
+
+	if err := RegisterRingValidator("", RingValidatorFunc(func(context.Context, []string) error { return nil })); !errors.Is(err, ErrInvalidRingValidator) {
+		t.Errorf("blank ring should be rejected, got %v", err)
+	}
+	if err := RegisterRingValidator("app", nil); !errors.Is(err, ErrInvalidRingValidator) {
+		t.Errorf("nil validator should be rejected, got %v", err)
+	}
+}
+
+func TestRegistry_Concurrency(t *testing.T) {
+	Reset()
+	t.Cleanup(Reset)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_ = Validate("meta/cso/revision")
+		}()
+		go func(i int) {
+			defer wg.Done()
+			err := RegisterRingValidator(fmt.Sprintf("ring%d", i), RingValidatorFunc(func(context.Context, []string) error { return nil }))
+			if err != nil && !errors.Is(err, ErrRegistryFrozen) {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,711 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package v1
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Suggestion confidence weights of path component sources.
+const (
+	// WeightMatched is used for components matching a known name.
+	WeightMatched = 1.0
+	// WeightAlias is used for components matching an alias or an
+	// abbreviation.
+	WeightAlias = 0.95
+	// WeightDefault is used for components set from hint defaults.
+	WeightDefault = 0.9
+	// WeightGuessed is used for components guessed from unknown tokens.
+	WeightGuessed = 0.5
+	// WeightAmbiguous is applied when a path contains conflicting values for
+	// the same component.
+	WeightAmbiguous = 0.5
+)
+
+// DefaultSecretName is the path suffix used when all path tokens are
+// consumed by the inferred path components.
+const DefaultSecretName = "secrets"
+
+// SuggestHints describes the dictionary used to infer CSO paths from legacy
+// paths. Names and aliases are matched case-insensitively.
+type SuggestHints struct {
+	// Platforms maps platform names to their aliases.
+	Platforms map[string][]string `json:"platforms,omitempty"`
+	// Services maps platform service names to their aliases.
+	Services map[string][]string `json:"services,omitempty"`
+	// Products maps product names to their hints.
+	Products map[string]ProductHint `json:"products,omitempty"`
+	// Aliases maps path tokens to known names (i.e. live: production).
+	Aliases map[string]string `json:"aliases,omitempty"`
+	// Defaults sets missing path components.
+	Defaults SuggestDefaults `json:"defaults,omitempty"`
+}
+
+// ProductHint describes a product.
+type ProductHint struct {
+	Aliases []string `json:"aliases,omitempty"`
+	// Version is used when the legacy path has no version.
+	Version string `json:"version,omitempty"`
+	// Components maps component names to their aliases.
+	Components map[string][]string `json:"components,omitempty"`
+}
+
+// SuggestDefaults describes path components used when they can't be inferred.
+type SuggestDefaults struct {
+	Platform string `json:"platform,omitempty"`
+	Region   string `json:"region,omitempty"`
+	Version  string `json:"version,omitempty"`
+	// Secret is the path suffix used when no path token is left, defaults to
+	// DefaultSecretName.
+	Secret string `json:"secret,omitempty"`
+}
+
+// Suggestion describes an inferred CSO path.
+type Suggestion struct {
+	// Path is the legacy path.
+	Path string `json:"path"`
+	// Suggested is the inferred CSO path, blank when no ring matches.
+	Suggested string `json:"suggested,omitempty"`
+	// Ring is the suggested path ring.
+	Ring string `json:"ring,omitempty"`
+	// Confidence is the product of component source weights, between 0 and
+	// 1, rounded to 3 decimals.
+	Confidence float64 `json:"confidence"`
+	// Reasons explains the confidence decrease.
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// Suggester infers CSO compliant paths from legacy paths.
+type Suggester struct {
+	hints    *SuggestHints
+	index    map[string][]tokenMatch
+	products map[string]ProductHint
+}
+
+// NewSuggester returns a path suggester using the given hints.
+func NewSuggester(hints *SuggestHints) (*Suggester, error) {
+	if hints == nil {
+		hints = &SuggestHints{}
+	}
+
+	s := &Suggester{
+		hints:    hints,
+		index:    map[string][]tokenMatch{},
+		products: map[string]ProductHint{},
+	}
+
+	// Stages
+	for _, stage := range platformQualityLevels {
+		s.add(stage, tokenMatch{kind: kindStage, value: stage, weight: WeightMatched})
+	}
+	for alias, stage := range stageAbbreviations {
+		s.add(alias, tokenMatch{kind: kindStage, value: stage, weight: WeightAlias})
+	}
+
+	// Dictionary
+	for name, aliases := range hints.Platforms {
+		if err := s.addNamed(kindPlatform, name, aliases, ""); err != nil {
+			return nil, err
+		}
+	}
+	for name, aliases := range hints.Services {
+		if err := s.addNamed(kindService, name, aliases, ""); err != nil {
+			return nil, err
+		}
+	}
+	for name, p := range hints.Products {
+		name = strings.ToLower(name)
+		if p.Version != "" {
+			if _, err := parseVersion(p.Version); err != nil {
+				return nil, fmt.Errorf("invalid product '%s' version '%s': %w", name, p.Version, err)
+			}
+		}
+		s.products[name] = p
+		if err := s.addNamed(kindProduct, name, p.Aliases, ""); err != nil {
+			return nil, err
+		}
+		for component, aliases := range p.Components {
+			if err := s.addNamed(kindComponent, component, aliases, name); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// User aliases resolve to already indexed names
+	for alias, name := range hints.Aliases {
+		matches := s.index[strings.ToLower(name)]
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("alias '%s' refers to unknown name '%s'", alias, name)
+		}
+		for _, m := range matches {
+			m.weight = WeightAlias
+			s.add(alias, m)
+		}
+	}
+
+	// Check defaults
+	if v := hints.Defaults.Version; v != "" {
+		if _, err := parseVersion(v); err != nil {
+			return nil, fmt.Errorf("invalid default version '%s': %w", v, err)
+		}
+	}
+	if r := hints.Defaults.Region; r != "" && !isCloudRegion(r) {
+		return nil, fmt.Errorf("invalid default region '%s'", r)
+	}
+
+	// No error
+	return s, nil
+}
+
+// Suggest infers a CSO path from the given legacy path. Path segments are
+// split on common separators, tokens are matched against stages, regions,
+// versions and the hint dictionary. Unmatched tokens are kept as the secret
+// path suffix.
+func (s *Suggester) Suggest(path string) *Suggestion {
+	res := &Suggestion{Path: path}
+
+	// Classify tokens
+	segments := s.tokenize(path)
+	slots := map[tokenKind]*slot{}
+	for _, tokens := range segments {
+		for _, t := range tokens {
+			if t.match == nil {
+				continue
+			}
+			sl, ok := slots[t.match.kind]
+			if !ok {
+				sl = &slot{}
+				slots[t.match.kind] = sl
+			}
+			sl.add(t.match)
+		}
+	}
+
+	// Components belong to the matched product, a component of a single
+	// product implies the product unless the path designates a platform
+	// service.
+	product := slots[kindProduct].value()
+	if c, ok := slots[kindComponent]; ok {
+		c.keepOwner(product)
+		if product == "" && len(c.owners()) == 1 && slots[kindService] == nil {
+			slots[kindProduct] = &slot{values: []tokenMatch{{kind: kindProduct, value: c.owners()[0], weight: WeightDefault}}}
+			res.reason("product '%s' inferred from component", c.owners()[0])
+		}
+		if len(c.values) == 0 {
+			delete(slots, kindComponent)
+		}
+	}
+
+	// Select the ring
+	b := &pathBuilder{res: res, slots: slots, confidence: 1}
+	var build func(*pathBuilder)
+	switch {
+	case slots[kindProduct] != nil && slots[kindStage] != nil:
+		b.res.Ring, build = ringApp, s.appPath
+	case slots[kindProduct] != nil:
+		b.res.Ring, build = ringProduct, s.productPath
+	case slots[kindPlatform] != nil || slots[kindService] != nil:
+		b.res.Ring, build = ringPlatform, s.platformPath
+	default:
+		res.reason("no product, platform or service found")
+		return res
+	}
+
+	// Ambiguous components
+	for _, kind := range ringKinds[b.res.Ring] {
+		if sl, ok := slots[kind]; ok && sl.ambiguous() {
+			res.reason("conflicting %s values %s", kind, strings.Join(sl.distinct(), ", "))
+		}
+	}
+
+	// Tokens not used by the ring are kept in the secret path suffix
+	b.leftover = leftoverSegments(segments, ringKinds[b.res.Ring], slots[kindProduct].value())
+	b.parts = []string{b.res.Ring}
+	build(b)
+	if b.failed {
+		res.Ring, res.Confidence = "", 0
+		return res
+	}
+
+	// Build and check the path
+	secret := s.hints.Defaults.Secret
+	if secret == "" {
+		secret = DefaultSecretName
+	}
+	if len(b.leftover) > 0 {
+		secret = strings.Join(b.leftover, "/")
+	}
+	suggested := strings.Join(append(b.parts, secret), "/")
+	if err := Validate(suggested); err != nil {
+		res.reason("suggested path '%s' is not compliant: %v", suggested, err)
+		res.Ring = ""
+		return res
+	}
+
+	res.Suggested = suggested
+	res.Confidence = math.Round(b.confidence*1000) / 1000
+
+	return res
+}
+
+// -----------------------------------------------------------------------------
+
+type tokenKind string
+
+const (
+	kindStage     tokenKind = "stage"
+	kindRegion    tokenKind = "region"
+	kindVersion   tokenKind = "version"
+	kindProduct   tokenKind = "product"
+	kindComponent tokenKind = "component"
+	kindPlatform  tokenKind = "platform"
+	kindService   tokenKind = "service"
+)
+
+// ringKinds lists path components used by each ring.
+var ringKinds = map[string][]tokenKind{
+	ringApp:      {kindStage, kindPlatform, kindProduct, kindVersion, kindComponent},
+	ringProduct:  {kindProduct, kindVersion, kindComponent},
+	ringPlatform: {kindStage, kindPlatform, kindRegion, kindService},
+}
+
+// kindPriority orders matches of a token known with several kinds.
+var kindPriority = map[tokenKind]int{
+	kindStage:     0,
+	kindRegion:    1,
+	kindVersion:   2,
+	kindProduct:   3,
+	kindComponent: 4,
+	kindPlatform:  5,
+	kindService:   6,
+}
+
+var stageAbbreviations = map[string]string{
+	"prod":        "production",
+	"prd":         "production",
+	"pro":         "production",
+	"live":        "production",
+	"stage":       "staging",
+	"stg":         "staging",
+	"stag":        "staging",
+	"preprod":     "staging",
+	"uat":         "staging",
+	"test":        "qa",
+	"tst":         "qa",
+	"testing":     "qa",
+	"develop":     "dev",
+	"development": "dev",
+	"devel":       "dev",
+	"dv":          "dev",
+}
+
+var regionDirections = map[string]string{
+	"e":  "east",
+	"w":  "west",
+	"n":  "north",
+	"s":  "south",
+	"c":  "central",
+	"ne": "northeast",
+	"nw": "northwest",
+	"se": "southeast",
+	"sw": "southwest",
+}
+
+var (
+	tokenSeparators = regexp.MustCompile(`[-_:.\s]+`)
+	versionPattern  = regexp.MustCompile(`^v?(\d+)(\.\d+)?(\.\d+)?$`)
+	compactRegion   = regexp.MustCompile(`^([a-z]{2})(e|w|n|s|c|ne|nw|se|sw)(\d)$`)
+)
+
+type tokenMatch struct {
+	kind   tokenKind
+	value  string
+	weight float64
+	// owner is the product of a component.
+	owner string
+}
+
+type token struct {
+	raw   string
+	match *tokenMatch
+}
+
+func (s *Suggester) add(name string, m tokenMatch) {
+	name = strings.ToLower(name)
+	for _, existing := range s.index[name] {
+		if existing.kind == m.kind && existing.value == m.value && existing.owner == m.owner {
+			return
+		}
+	}
+	s.index[name] = append(s.index[name], m)
+}
+
+func (s *Suggester) addNamed(kind tokenKind, name string, aliases []string, owner string) error {
+	name = strings.ToLower(name)
+	if name == "" || strings.ContainsAny(name, "/ ") {
+		return fmt.Errorf("invalid %s name '%s'", kind, name)
+	}
+	s.add(name, tokenMatch{kind: kind, value: name, weight: WeightMatched, owner: owner})
+	for _, alias := range aliases {
+		s.add(alias, tokenMatch{kind: kind, value: name, weight: WeightAlias, owner: owner})
+	}
+
+	return nil
+}
+
+// tokenize splits the path in segments of classified tokens.
+func (s *Suggester) tokenize(path string) [][]token {
+	res := [][]token{}
+	for _, segment := range strings.Split(strings.ToLower(strings.TrimSpace(path)), "/") {
+		if segment == "" {
+			continue
+		}
+
+		// Split on separators, versions keep their dots
+		words := []string{}
+		for _, w := range tokenSeparators.Split(segment, -1) {
+			if w != "" {
+				words = append(words, w)
+			}
+		}
+		if versionPattern.MatchString(strings.Trim(segment, "-_:")) {
+			words = []string{strings.Trim(segment, "-_:")}
+		}
+
+		tokens := []token{}
+		for i := 0; i < len(words); i++ {
+			// Regions span several words
+			if n, region := matchRegion(words[i:]); n > 0 {
+				tokens = append(tokens, token{
+					raw:   strings.Join(words[i:i+n], "-"),
+					match: &tokenMatch{kind: kindRegion, value: region, weight: WeightMatched},
+				})
+				i += n - 1
+				continue
+			}
+
+			tokens = append(tokens, s.classify(words[i]))
+		}
+		res = append(res, tokens)
+	}
+
+	return res
+}
+
+func (s *Suggester) classify(word string) token {
+	t := token{raw: word}
+
+	// Versions
+	if m := versionPattern.FindStringSubmatch(word); m != nil {
+		version := "v" + m[1] + orDefault(m[2], ".0") + orDefault(m[3], ".0")
+		weight := WeightMatched
+		if m[2] == "" || m[3] == "" {
+			weight = WeightAlias
+		}
+		t.match = &tokenMatch{kind: kindVersion, value: version, weight: weight}
+		return t
+	}
+
+	// Compact regions (use1, euw2)
+	if m := compactRegion.FindStringSubmatch(word); m != nil {
+		region := fmt.Sprintf("%s-%s-%s", m[1], regionDirections[m[2]], m[3])
+		if isCloudRegion(region) {
+			t.match = &tokenMatch{kind: kindRegion, value: region, weight: WeightAlias}
+			return t
+		}
+	}
+
+	// Dictionary
+	matches := s.index[word]
+	if len(matches) > 0 {
+		sorted := append([]tokenMatch(nil), matches...)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return kindPriority[sorted[i].kind] < kindPriority[sorted[j].kind]
+		})
+		t.match = &sorted[0]
+		// Keep components of all products, filtered once the product is known
+		if t.match.kind == kindComponent {
+			t.match = &tokenMatch{kind: kindComponent, value: sorted[0].value, weight: sorted[0].weight}
+			owners := []string{}
+			for _, m := range sorted {
+				if m.kind == kindComponent {
+					owners = append(owners, m.owner)
+				}
+			}
+			t.match.owner = strings.Join(owners, ",")
+		}
+	}
+
+	return t
+}
+
+func matchRegion(words []string) (int, string) {
+	for n := 3; n >= 1; n-- {
+		if len(words) < n {
+			continue
+		}
+		candidate := strings.Join(words[:n], "-")
+		if isCloudRegion(candidate) {
+			return n, candidate
+		}
+	}
+	return 0, ""
+}
+
+// leftoverSegments returns tokens not matching one of the given kinds,
+// grouped by segment. Components of other products are not matching.
+func leftoverSegments(segments [][]token, kinds []tokenKind, product string) []string {
+	used := map[tokenKind]bool{}
+	for _, k := range kinds {
+		used[k] = true
+	}
+
+	res := []string{}
+	for _, tokens := range segments {
+		words := []string{}
+		for _, t := range tokens {
+			if t.match != nil && used[t.match.kind] && (t.match.kind != kindComponent || hasOwner(t.match.owner, product)) {
+				continue
+			}
+			words = append(words, t.raw)
+		}
+		if len(words) > 0 {
+			res = append(res, strings.Join(words, "_"))
+		}
+	}
+	return res
+}
+
+func hasOwner(owners, product string) bool {
+	if product == "" {
+		return true
+	}
+	for _, o := range strings.Split(owners, ",") {
+		if o == product {
+			return true
+		}
+	}
+	return false
+}
+
+func orDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+// -----------------------------------------------------------------------------
+
+type slot struct {
+	values []tokenMatch
+}
+
+func (sl *slot) add(m *tokenMatch) {
+	sl.values = append(sl.values, *m)
+}
+
+func (sl *slot) value() string {
+	if sl == nil || len(sl.values) == 0 {
+		return ""
+	}
+	return sl.values[0].value
+}
+
+func (sl *slot) distinct() []string {
+	res := []string{}
+	seen := map[string]bool{}
+	for _, v := range sl.values {
+		if !seen[v.value] {
+			seen[v.value] = true
+			res = append(res, v.value)
+		}
+	}
+	return res
+}
+
+func (sl *slot) ambiguous() bool {
+	return len(sl.distinct()) > 1
+}
+
+// weight returns the lowest weight of matches of the retained value.
+func (sl *slot) weight() float64 {
+	w := WeightMatched
+	for _, v := range sl.values {
+		if v.value == sl.values[0].value && v.weight < w {
+			w = v.weight
+		}
+	}
+	if sl.ambiguous() {
+		w *= WeightAmbiguous
+	}
+	return w
+}
+
+func (sl *slot) owners() []string {
+	res := []string{}
+	seen := map[string]bool{}
+	for _, v := range sl.values {
+		for _, o := range strings.Split(v.owner, ",") {
+			if o != "" && !seen[o] {
+				seen[o] = true
+				res = append(res, o)
+			}
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// keepOwner drops components not belonging to the given product, all
+// components are kept when the product is blank.
+func (sl *slot) keepOwner(product string) {
+	if product == "" {
+		return
+	}
+	kept := []tokenMatch{}
+	for _, v := range sl.values {
+		if hasOwner(v.owner, product) {
+			kept = append(kept, v)
+		}
+	}
+	sl.values = kept
+}
+
+// -----------------------------------------------------------------------------
+
+type pathBuilder struct {
+	res        *Suggestion
+	slots      map[tokenKind]*slot
+	leftover   []string
+	parts      []string
+	confidence float64
+	failed     bool
+}
+
+func (b *pathBuilder) matched(kind tokenKind) bool {
+	sl, ok := b.slots[kind]
+	if !ok || len(sl.values) == 0 {
+		return false
+	}
+	b.parts = append(b.parts, sl.value())
+	b.confidence *= sl.weight()
+	return true
+}
+
+func (b *pathBuilder) fallback(kind tokenKind, value string, weight float64, origin string) bool {
+	if value == "" {
+		return false
+	}
+	b.parts = append(b.parts, value)
+	b.confidence *= weight
+	if origin != "" {
+		b.res.reason("%s '%s' %s", kind, value, origin)
+	}
+	return true
+}
+
+// guess uses the first unmatched segment as the given component.
+func (b *pathBuilder) guess(kind tokenKind) bool {
+	if len(b.leftover) < 2 {
+		return false
+	}
+	value := b.leftover[0]
+	b.leftover = b.leftover[1:]
+	return b.fallback(kind, value, WeightGuessed, "guessed from unknown tokens")
+}
+
+func (b *pathBuilder) fail(kind tokenKind) {
+	b.res.reason("%s can't be inferred", kind)
+	b.failed = true
+}
+
+func (s *Suggester) version(b *pathBuilder) {
+	if b.matched(kindVersion) {
+		return
+	}
+	if p, ok := s.products[b.slots[kindProduct].value()]; ok && p.Version != "" {
+		b.fallback(kindVersion, p.Version, WeightMatched, "")
+		return
+	}
+	if b.fallback(kindVersion, s.hints.Defaults.Version, WeightDefault, "set from defaults") {
+		return
+	}
+	b.fallback(kindVersion, "v1.0.0", WeightGuessed, "assumed")
+}
+
+func (s *Suggester) component(b *pathBuilder) {
+	if b.matched(kindComponent) {
+		return
+	}
+	if p, ok := s.products[b.slots[kindProduct].value()]; ok && len(p.Components) == 1 {
+		for name := range p.Components {
+			b.fallback(kindComponent, strings.ToLower(name), WeightDefault, "is the only product component")
+		}
+		return
+	}
+	if !b.guess(kindComponent) {
+		b.fail(kindComponent)
+	}
+}
+
+// app/<stage>/<platform>/<product>/<version>/<component>/<secret>
+func (s *Suggester) appPath(b *pathBuilder) {
+	b.matched(kindStage)
+	if !b.matched(kindPlatform) && !b.fallback(kindPlatform, s.hints.Defaults.Platform, WeightDefault, "set from defaults") {
+		b.fail(kindPlatform)
+		return
+	}
+	b.matched(kindProduct)
+	s.version(b)
+	s.component(b)
+}
+
+// product/<product>/<version>/<component>/<secret>
+func (s *Suggester) productPath(b *pathBuilder) {
+	b.matched(kindProduct)
+	s.version(b)
+	s.component(b)
+}
+
+// platform/<stage>/<platform>/<region>/<service>/<secret>
+func (s *Suggester) platformPath(b *pathBuilder) {
+	if !b.matched(kindStage) {
+		b.fail(kindStage)
+		return
+	}
+	if !b.matched(kindPlatform) && !b.fallback(kindPlatform, s.hints.Defaults.Platform, WeightDefault, "set from defaults") {
+		b.fail(kindPlatform)
+		return
+	}
+	if !b.matched(kindRegion) && !b.fallback(kindRegion, s.hints.Defaults.Region, WeightDefault, "set from defaults") {
+		b.fail(kindRegion)
+		return
+	}
+	if !b.matched(kindService) && !b.guess(kindService) {
+		b.fail(kindService)
+	}
+}
+
+func (r *Suggestion) reason(format string, args ...interface{}) {
+	r.Reasons = append(r.Reasons, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,175 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package v1
+
+import (
+	"testing"
+)
+
+func corpusHints() *SuggestHints {
+	return &SuggestHints{
+		Platforms: map[string][]string{
+			"customer1": {"cust1", "c1"},
+			"security":  {"sec"},
+		},
+		Services: map[string][]string{
+			"vault":         {},
+			"elasticsearch": {"es"},
+			"kafka":         {},
+		},
+		Products: map[string]ProductHint{
+			"billing": {
+				Aliases: []string{"bill", "invoicing"},
+				Version: "v1.0.0",
+				Components: map[string][]string{
+					"database": {"db", "pg", "postgres"},
+					"api":      {"backend"},
+					"smtp":     {"mail", "mailer"},
+				},
+			},
+			"ece": {
+				Aliases: []string{"cloud"},
+				Components: map[string][]string{
+					"adminconsole": {"admin", "console"},
+				},
+			},
+			"search": {
+				Components: map[string][]string{
+					"indexer": {"idx"},
+				},
+			},
+		},
+		Aliases: map[string]string{
+			"pre": "staging",
+		},
+		Defaults: SuggestDefaults{
+			Platform: "customer1",
+			Region:   "us-east-1",
+			Version:  "v1.0.0",
+		},
+	}
+}
+
+// suggestCorpus lists realistic legacy paths and their known CSO mapping.
+var suggestCorpus = []struct {
+	path string
+	want string
+}{
+	{path: "prod/billing/db", want: "app/production/customer1/billing/v1.0.0/database/secrets"},
+	{path: "prd/billing/database/password", want: "app/production/customer1/billing/v1.0.0/database/password"},
+	{path: "billing-prod-db", want: "app/production/customer1/billing/v1.0.0/database/secrets"},
+	{path: "billing_prd_pg/credentials", want: "app/production/customer1/billing/v1.0.0/database/credentials"},
+	{path: "secret/stg/billing/api/stripe_key", want: "app/staging/customer1/billing/v1.0.0/api/secret/stripe_key"},
+	{path: "staging/cust1/bill/backend", want: "app/staging/customer1/billing/v1.0.0/api/secrets"},
+	{path: "dev/billing/v2.1/mailer/sendgrid", want: "app/dev/customer1/billing/v2.1.0/smtp/sendgrid"},
+	{path: "qa/c1/billing/smtp", want: "app/qa/customer1/billing/v1.0.0/smtp/secrets"},
+	{path: "test.billing.db", want: "app/qa/customer1/billing/v1.0.0/database/secrets"},
+	{path: "PROD/Billing/DB", want: "app/production/customer1/billing/v1.0.0/database/secrets"},
+	{path: "pre/billing/db", want: "app/staging/customer1/billing/v1.0.0/database/secrets"},
+	{path: "production/ece/1.2.3/admin/okta", want: "app/production/customer1/ece/v1.2.3/adminconsole/okta"},
+	{path: "live-cloud-console/otp", want: "app/production/customer1/ece/v1.0.0/adminconsole/otp"},
+	{path: "prod/security/search/idx/token", want: "app/production/security/search/v1.0.0/indexer/token"},
+	{path: "search/idx", want: "product/search/v1.0.0/indexer/secrets"},
+	{path: "billing/v3/db/root", want: "product/billing/v3.0.0/database/root"},
+	{path: "prod/sec/us-east-1/vault/unseal", want: "platform/production/security/us-east-1/vault/unseal"},
+	{path: "prd-security-euw1-es/admin", want: "platform/production/security/eu-west-1/elasticsearch/admin"},
+	{path: "staging/kafka/broker_password", want: "platform/staging/customer1/us-east-1/kafka/broker_password"},
+	{path: "dev/customer1/ap-southeast-2/vault", want: "platform/dev/customer1/ap-southeast-2/vault/secrets"},
+}
+
+func TestSuggester_Corpus(t *testing.T) {
+	s, err := NewSuggester(corpusHints())
+	if err != nil {
+		t.Fatalf("unable to create suggester: %v", err)
+	}
+
+	recovered := 0
+	for _, c := range suggestCorpus {
+		got := s.Suggest(c.path)
+		if got.Confidence >= 0.8 && got.Suggested != c.want {
+			t.Errorf("'%s': high confidence suggestion '%s' is wrong", c.path, got.Suggested)
+		}
+		if got.Suggested == c.want {
+			recovered++
+		} else {
+			t.Logf("'%s': expected '%s', got '%s' (%v)", c.path, c.want, got.Suggested, got.Reasons)
+		}
+	}
+
+	// Known mappings are recovered
+	if rate := float64(recovered) / float64(len(suggestCorpus)); rate < 0.9 {
+		t.Errorf("recovered %d/%d known mappings", recovered, len(suggestCorpus))
+	}
+}
+
+func TestSuggester_Review(t *testing.T) {
+	s, err := NewSuggester(corpusHints())
+	if err != nil {
+		t.Fatalf("unable to create suggester: %v", err)
+	}
+
+	testCases := []struct {
+		desc          string
+		path          string
+		wantSuggested bool
+		maxConfidence float64
+	}{
+		{desc: "unknown tokens", path: "legacy/foo/bar", maxConfidence: 0},
+		{desc: "conflicting stages", path: "prod/staging/billing/db", wantSuggested: true, maxConfidence: 0.5},
+		{desc: "platform without stage", path: "security/vault/token", maxConfidence: 0},
+		{desc: "guessed component", path: "prod/ece/billing_admin/password", wantSuggested: true, maxConfidence: 0.5},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			got := s.Suggest(tC.path)
+			if (got.Suggested != "") != tC.wantSuggested {
+				t.Fatalf("unexpected suggestion '%s' (%v)", got.Suggested, got.Reasons)
+			}
+			if got.Confidence > tC.maxConfidence {
+				t.Errorf("expected confidence <= %.2f, got %.2f", tC.maxConfidence, got.Confidence)
+			}
+			if len(got.Reasons) == 0 {
+				t.Error("expected reasons")
+			}
+			if got.Suggested != "" {
+				if err := Validate(got.Suggested); err != nil {
+					t.Errorf("suggested path is not compliant: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestNewSuggester_InvalidHints(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		hints *SuggestHints
+	}{
+		{desc: "unknown alias target", hints: &SuggestHints{Aliases: map[string]string{"x": "unknown"}}},
+		{desc: "invalid product version", hints: &SuggestHints{Products: map[string]ProductHint{"billing": {Version: "latest"}}}},
+		{desc: "invalid default region", hints: &SuggestHints{Defaults: SuggestDefaults{Region: "mars-1"}}},
+		{desc: "invalid name", hints: &SuggestHints{Platforms: map[string][]string{"a/b": nil}}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if _, err := NewSuggester(tC.hints); err == nil {
+				t.Error("error should be raised")
+			}
+		})
+	}
+}
@@ -18,27 +18,70 @@
 package v1
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
-	semver "github.com/blang/semver/v4"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/go-ozzo/ozzo-validation/v4/is"
 
 	"github.com/elastic/harp/pkg/sdk/types"
 )
 
-var validators = map[string]func([]string) error{
-	"meta":     validateMeta,
-	"infra":    validateInfra,
-	"platform": validatePlatform,
-	"product":  validateProduct,
-	"app":      validateApplication,
-	"artifact": validateArtifact,
+// ValidationOption defines path validation policy options.
+type ValidationOption func(*Policy)
+
+// Policy describes the path validation policy. It is carried by the context
+// given to ring validators.
+type Policy struct {
+	// AllowVersionRange accepts version ranges as product version.
+	AllowVersionRange bool
+	// ArtifactTypes lists accepted artifact types, DefaultArtifactTypes when
+	// nil.
+	ArtifactTypes []string
+	// ExplicitPartition requires partition explicit cloud providers (aws-cn)
+	// for regions outside of the provider default partition.
+	ExplicitPartition bool
+	// ExplicitPartitionRings restricts ExplicitPartition to the given rings,
+	// all rings when empty.
+	ExplicitPartitionRings []string
+}
+
+// ValidationError describes an invalid secret path component.
+type ValidationError struct {
+	Ring      string
+	Component string
+	Value     string
+	Reason    string
+}
+
+// Error returns the error message.
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s %s (%s): %s", e.Ring, e.Component, e.Value, e.Reason)
+}
+
+// AllowVersionRange accepts version ranges (`~1.2`, `1.x`) as product
+// version.
+func AllowVersionRange() ValidationOption {
+	return func(opts *Policy) {
+		opts.AllowVersionRange = true
+	}
 }
 
 // Validate path according to to CSO model
-func Validate(path string) error {
+func Validate(path string, opts ...ValidationOption) error {
+	return ValidateContext(context.Background(), path, opts...)
+}
+
+// ValidateContext validates the path according to the CSO model using the
+// registered ring validators.
+func ValidateContext(ctx context.Context, path string, opts ...ValidationOption) error {
+	// Apply options
+	policy := &Policy{}
+	for _, o := range opts {
+		o(policy)
+	}
+
 	// Validate path
 	if err := validation.Validate(path,
 		validation.Required,
@@ -59,18 +102,18 @@ func Validate(path string) error {
 	}
 
 	// Check validator according to given ring value
-	v, ok := validators[parts[0]]
+	v, ok := registry.lookup(parts[0])
 	if !ok {
 		return fmt.Errorf("invalid ring value (%s)", parts[0])
 	}
 
 	// Delegate to ring validator
-	return v(parts[1:])
+	return v.Validate(WithPolicy(ctx, policy), parts[1:])
 }
 
 // -----------------------------------------------------------------------------
 
-func validateMeta(parts []string) error {
+func validateMeta(parts []string, _ *Policy) error {
 	// Validate parts count
 	if len(parts) < 2 {
 		return fmt.Errorf("invalid part count for meta secret path")
@@ -90,122 +133,12 @@ func validateMeta(parts []string) error {
 
 // -----------------------------------------------------------------------------
 
-var cloudProviderRegions = map[string]types.StringArray{
-	"aws": {
-		"global",
-		"us-east-1",
-		"us-east-2",
-		"us-west-1",
-		"us-west-2",
-		"ap-east-1",
-		"ap-south-1",
-		"ap-northeast-3",
-		"ap-northeast-2",
-		"ap-southeast-1",
-		"ap-southeast-2",
-		"ap-northeast-1",
-		"ca-central-1",
-		"cn-north-1",
-		"cn-northwest-1",
-		"eu-central-1",
-		"eu-west-1",
-		"eu-west-2",
-		"eu-west-3",
-		"eu-north-1",
-		"me-south-1",
-		"sa-east-1",
-	},
-	"aws-us-gov": {
-		"us-gov-east-1",
-		"us-gov-west-1",
-	},
-	"gcp": {
-		"global",
-		"asia-east1",
-		"asia-east2",
-		"asia-northeast1",
-		"asia-northeast2",
-		"asia-south1",
-		"asia-southeast1",
-		"australia-southeast1",
-		"europe-north1",
-		"europe-west1",
-		"europe-west2",
-		"europe-west3",
-		"europe-west4",
-		"europe-west6",
-		"northamerica-northeast1",
-		"southamerica-east1",
-		"us-central1",
-		"us-east1",
-		"us-east4",
-		"us-west1",
-		"us-west2",
-	},
-	"azure": {
-		"global",
-		"eastasia",
-		"southeastasia",
-		"centralus",
-		"eastus",
-		"eastus2",
-		"westus",
-		"northcentralus",
-		"southcentralus",
-		"northeurope",
-		"westeurope",
-		"japanwest",
-		"japaneast",
-		"brazilsouth",
-		"australiaeast",
-		"australiasoutheast",
-		"southindia",
-		"centralindia",
-		"westindia",
-		"canadacentral",
-		"canadaeast",
-		"uksouth",
-		"ukwest",
-		"westcentralus",
-		"westus2",
-		"koreacentral",
-		"koreasouth",
-		"francecentral",
-		"francesouth",
-		"australiacentral",
-		"australiacentral2",
-		"uaecentral",
-		"uaenorth",
-		"southafricanorth",
-		"southafricawest",
-		"switzerlandnorth",
-		"switzerlandwest",
-		"germanynorth",
-		"germanywestcentral",
-		"norwaywest",
-		"norwayeast",
-		"brazilsoutheast",
-	},
-	"azure-us-gov": {
-		"usgovvirginia",
-		"usgoviowa",
-		"usgovarizona",
-		"usgovtexas",
-	},
-}
-
-func validateInfra(parts []string) error {
+func validateInfra(parts []string, opts *Policy) error {
 	// Validate parts count
 	if len(parts) < 4 {
 		return fmt.Errorf("invalid part count for infrastructure secret path")
 	}
 
-	// Validate cloud provider
-	r, ok := cloudProviderRegions[parts[0]]
-	if !ok {
-		return fmt.Errorf("cloud provider (%s) not supported", parts[0])
-	}
-
 	// Validate accounts
 	if err := validation.Validate(parts[1],
 		validation.Required,
@@ -214,9 +147,13 @@ func validateInfra(parts []string) error {
 		return fmt.Errorf("unable to validate infrastructure cloud provider account (%s): %v", parts[1], err)
 	}
 
-	// Validate region
-	if !r.Contains(parts[2]) {
-		return fmt.Errorf("invalid region (%s) for account (%s) on cloud provider (%s)", parts[2], parts[1], parts[0])
+	// Validate region according to provider partitions
+	p, err := ResolvePartition(parts[0], parts[2])
+	if err != nil {
+		return fmt.Errorf("invalid infrastructure region for account (%s): %w", parts[1], err)
+	}
+	if opts.ExplicitPartitionRequired("infra") && parts[0] != p.ExplicitProvider() {
+		return fmt.Errorf("region (%s) belongs to the %s %s partition, the partition explicit provider (%s) must be used", parts[2], p.Provider, p.Name, p.ExplicitProvider())
 	}
 
 	// Infra has no more constraints
@@ -227,7 +164,7 @@ func validateInfra(parts []string) error {
 
 var platformQualityLevels = types.StringArray{"production", "staging", "qa", "dev"}
 
-func validatePlatform(parts []string) error {
+func validatePlatform(parts []string, _ *Policy) error {
 	// Validate parts count
 	if len(parts) < 5 {
 		return fmt.Errorf("invalid part count for platform secret path")
@@ -247,16 +184,8 @@ func validatePlatform(parts []string) error {
 	}
 
 	// Validate platform region
-	r := parts[2]
-	regionFound := false
-	for _, regions := range cloudProviderRegions {
-		if regions.Contains(r) {
-			regionFound = true
-			break
-		}
-	}
-	if !regionFound {
-		return fmt.Errorf("unable to find a region matching (%s)", r)
+	if !isCloudRegion(parts[2]) {
+		return fmt.Errorf("unable to find a region matching (%s)", parts[2])
 	}
 
 	// Validate accounts
@@ -273,7 +202,7 @@ func validatePlatform(parts []string) error {
 
 // -----------------------------------------------------------------------------
 
-func validateProduct(parts []string) error {
+func validateProduct(parts []string, opts *Policy) error {
 	// Validate parts count
 	if len(parts) < 3 {
 		return fmt.Errorf("invalid part count for product secret path")
@@ -288,7 +217,7 @@ func validateProduct(parts []string) error {
 	}
 
 	// check version as a semver compliant version
-	if err := validateSemVer(parts[1]); err != nil {
+	if err := validateSemVer(parts[1], opts); err != nil {
 		return fmt.Errorf("invalid product (%s) version (%s), semver not compliant: %w", parts[0], parts[1], err)
 	}
 
@@ -298,7 +227,7 @@ func validateProduct(parts []string) error {
 
 // -----------------------------------------------------------------------------
 
-func validateApplication(parts []string) error {
+func validateApplication(parts []string, opts *Policy) error {
 	// Validate parts count
 	if len(parts) < 6 {
 		return fmt.Errorf("invalid part count for application secret path")
@@ -326,7 +255,7 @@ func validateApplication(parts []string) error {
 	}
 
 	// check version as a semver compliant version
-	if err := validateSemVer(parts[3]); err != nil {
+	if err := validateSemVer(parts[3], opts); err != nil {
 		return fmt.Errorf("invalid product (%s) version (%s), semver not compliant: %w", parts[2], parts[3], err)
 	}
 
@@ -344,32 +273,15 @@ func validateApplication(parts []string) error {
 
 // -----------------------------------------------------------------------------
 
-func validateArtifact(parts []string) error {
-	// Validate parts count
-	if len(parts) < 2 {
-		return fmt.Errorf("invalid part count for artifact secret path")
-	}
-
-	// Validate type
-	if err := validation.Validate(parts[1],
-		validation.Required,
-		is.PrintableASCII,
-	); err != nil {
-		return fmt.Errorf("unable to validate artifact type (%s): %v", parts[1], err)
+func validateSemVer(version string, opts *Policy) error {
+	// Check version range
+	if opts.AllowVersionRange && IsVersionRange(version) {
+		_, err := ParseVersionRange(version)
+		return err
 	}
 
-	// Artifact has no more constraints
-	return nil
-}
-
-// -----------------------------------------------------------------------------
-
-func validateSemVer(version string) error {
-	// Clean input
-	version = strings.TrimPrefix(strings.TrimSpace(strings.ToLower(version)), "v")
-
 	// check version as a semver compliant version
-	_, err := semver.Make(version)
+	_, err := parseVersion(version)
 	if err != nil {
 		return err
 	}
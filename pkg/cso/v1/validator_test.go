@@ -17,7 +17,10 @@
 
 package v1
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 var tests = []struct {
 	in      string
@@ -78,16 +81,30 @@ var tests = []struct {
 	{"product/foo/v1.0.0/foo/bar", false},
 	{"product/foo/1.0.0/foo", false},
 	{"product/foo/1.0.0/foo/bar", false},
+	{"product/foo/1.2.3+build.5/foo", false},
+	{"product/foo/v1.2.3-rc.1+Build.5/foo", false},
+	{"product/foo/~1.2/foo", true},
+	{"product/foo/1.x/foo", true},
+	{"product/foo/01.2.3/foo", true},
 	// Application
 	{"app", true},
 	{"app/production/name/foo/v1.0.0//foo", true},
 	{"app/production/name/foo/v1.0.0/component/foo", false},
 	{"app/production/name/foo/v1.0.0/component/foo/bar", false},
+	{"app/production/name/foo/v1.2.3+build.5/component/foo", false},
 	{"app/essp/name/foo/v1.0.0/component/foo/bar", true},
 	// Artifact
 	{"artifact", true},
 	{"artifact/docker", true},
 	{"artifact/docker/sha256:fab2dded59dd0c2894dd9dbae71418f565be5bd0d8fd82365c16aec41c7e367f/attestations/snyk_report", false},
+	{"artifact/oci/sha512:" + strings.Repeat("ab", 64) + "/signature", false},
+	{"artifact/npm/left-pad/publish_token", false},
+	{"artifact//sha256:abc/key", true},
+	{"artifact/docker//key", true},
+	{"artifact/docker/sha256:abc/key", true},
+	{"artifact/docker/md5:d41d8cd98f00b204e9800998ecf8427e/key", true},
+	{"artifact/rpm/foo/key", true},
+	{"artifact/docker/foo", true},
 }
 
 func Test_Validate(t *testing.T) {
@@ -98,3 +115,26 @@ func Test_Validate(t *testing.T) {
 		}
 	}
 }
+
+func Test_Validate_VersionRange(t *testing.T) {
+	testCases := []struct {
+		in      string
+		wantErr bool
+	}{
+		{"product/foo/~1.2/foo", false},
+		{"product/foo/1.x/foo", false},
+		{"product/foo/v1.2.*/foo", false},
+		{"product/foo/1.2.3+build.5/foo", false},
+		{"product/foo/~1/foo", true},
+		{"product/foo/~1.x/foo", true},
+		{"product/foo/x.1/foo", true},
+		{"app/production/name/foo/1.2.x/component/foo", false},
+		{"app/production/name/foo/1.2.x.4/component/foo", true},
+	}
+	for _, tt := range testCases {
+		err := Validate(tt.in, AllowVersionRange())
+		if tt.wantErr != (err != nil) {
+			t.Errorf("Validate(%q, AllowVersionRange()) = %v, want %v", tt.in, err, tt.wantErr)
+		}
+	}
+}
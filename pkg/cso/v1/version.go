@@ -0,0 +1,215 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package v1
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	semver "github.com/blang/semver/v4"
+)
+
+// NormalizeVersion returns the canonical form of the given semver version
+// used for path comparison. The form is lowercased and prefixed by 'v',
+// build metadata is preserved.
+//
+//	NormalizeVersion("1.2.0")          // v1.2.0
+//	NormalizeVersion("V1.2.3+Build.5") // v1.2.3+build.5
+func NormalizeVersion(version string) (string, error) {
+	v, err := parseVersion(version)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("v%s", v.String()), nil
+}
+
+// VersionRange describes a constrained version range.
+//
+// Supported syntaxes are tilde ranges (`~1.2` or `~1.2.3` for >=1.2.x <1.3.0)
+// and wildcard ranges (`1.x`, `1.2.x`, `*` and `X` are also accepted as
+// wildcard).
+type VersionRange struct {
+	raw   string
+	lower semver.Version
+	upper *semver.Version
+}
+
+// IsVersionRange returns true if the given value uses a range syntax.
+func IsVersionRange(value string) bool {
+	value = strings.TrimSpace(strings.ToLower(value))
+	if strings.HasPrefix(value, "~") {
+		return true
+	}
+	for _, part := range strings.Split(strings.TrimPrefix(value, "v"), ".") {
+		if isWildcard(part) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseVersionRange parses the given range expression.
+func ParseVersionRange(value string) (*VersionRange, error) {
+	raw := strings.TrimSpace(strings.ToLower(value))
+	expr := raw
+
+	// Tilde range
+	tilde := strings.HasPrefix(expr, "~")
+	expr = strings.TrimPrefix(strings.TrimPrefix(expr, "~"), "v")
+
+	parts := strings.Split(expr, ".")
+	if len(parts) == 0 || len(parts) > 3 {
+		return nil, fmt.Errorf("invalid version range '%s'", value)
+	}
+
+	// Parse numeric components until the first wildcard
+	numbers := []uint64{}
+	for i, part := range parts {
+		if isWildcard(part) {
+			if tilde {
+				return nil, fmt.Errorf("invalid version range '%s', wildcard is not allowed in tilde range", value)
+			}
+			if i != len(parts)-1 {
+				return nil, fmt.Errorf("invalid version range '%s', wildcard must be the last component", value)
+			}
+			break
+		}
+
+		n, err := parseComponent(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version range '%s': %w", value, err)
+		}
+		numbers = append(numbers, n)
+	}
+
+	// Check range
+	switch {
+	case tilde && len(numbers) < 2:
+		return nil, fmt.Errorf("invalid version range '%s', tilde range requires major and minor", value)
+	case !tilde && len(numbers) == len(parts):
+		return nil, fmt.Errorf("invalid version range '%s', wildcard or tilde expected", value)
+	}
+
+	r := &VersionRange{raw: raw}
+
+	// Compute bounds
+	for i, n := range numbers {
+		switch i {
+		case 0:
+			r.lower.Major = n
+		case 1:
+			r.lower.Minor = n
+		case 2:
+			r.lower.Patch = n
+		}
+	}
+	switch {
+	case len(numbers) == 0:
+		// Any version
+	case len(numbers) == 1:
+		r.upper = &semver.Version{Major: r.lower.Major + 1}
+	default:
+		r.upper = &semver.Version{Major: r.lower.Major, Minor: r.lower.Minor + 1}
+	}
+
+	return r, nil
+}
+
+// Contains returns true if the given version is in the range. Pre-release
+// versions are never part of a range.
+func (r *VersionRange) Contains(version string) bool {
+	v, err := parseVersion(version)
+	if err != nil {
+		return false
+	}
+	if len(v.Pre) > 0 {
+		return false
+	}
+
+	// Build metadata doesn't participate to precedence
+	if v.Compare(r.lower) < 0 {
+		return false
+	}
+	if r.upper != nil && v.Compare(*r.upper) >= 0 {
+		return false
+	}
+
+	return true
+}
+
+// String returns the range expression.
+func (r *VersionRange) String() string {
+	return r.raw
+}
+
+// NormalizePath returns the cleaned path with a normalized version component
+// for product and application paths. Other paths are only cleaned.
+func NormalizePath(path string) string {
+	cleanPath := Clean(path)
+
+	parts := strings.Split(cleanPath, "/")
+	idx, ok := versionIndex(parts)
+	if !ok {
+		return cleanPath
+	}
+
+	// Normalize version if valid
+	if v, err := NormalizeVersion(parts[idx]); err == nil {
+		parts[idx] = v
+	}
+
+	return strings.Join(parts, "/")
+}
+
+// -----------------------------------------------------------------------------
+
+// versionIndex returns the position of the version component according to
+// the path ring.
+func versionIndex(parts []string) (int, bool) {
+	switch {
+	case len(parts) > 2 && parts[0] == "product":
+		return 2, true
+	case len(parts) > 4 && parts[0] == "app":
+		return 4, true
+	}
+	return 0, false
+}
+
+func parseVersion(version string) (semver.Version, error) {
+	// Clean input
+	version = strings.TrimPrefix(strings.TrimSpace(strings.ToLower(version)), "v")
+
+	// Build metadata is accepted
+	return semver.Parse(version)
+}
+
+func parseComponent(part string) (uint64, error) {
+	if part == "" {
+		return 0, fmt.Errorf("empty version component")
+	}
+	if len(part) > 1 && part[0] == '0' {
+		return 0, fmt.Errorf("version component '%s' must not contain leading zeroes", part)
+	}
+	return strconv.ParseUint(part, 10, 64)
+}
+
+func isWildcard(part string) bool {
+	return part == "x" || part == "*"
+}
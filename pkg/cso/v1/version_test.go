@@ -0,0 +1,110 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package v1
+
+import (
+	"testing"
+)
+
+func TestNormalizeVersion(t *testing.T) {
+	classes := []struct {
+		want     string
+		versions []string
+	}{
+		{want: "v1.2.0", versions: []string{"1.2.0", "v1.2.0", "V1.2.0", " v1.2.0 "}},
+		{want: "v1.2.3+build.5", versions: []string{"1.2.3+build.5", "v1.2.3+BUILD.5"}},
+		{want: "v1.2.3-rc.1", versions: []string{"1.2.3-rc.1", "v1.2.3-RC.1"}},
+	}
+	for _, c := range classes {
+		for _, v := range c.versions {
+			got, err := NormalizeVersion(v)
+			if err != nil {
+				t.Errorf("NormalizeVersion(%q) unexpected error: %v", v, err)
+				continue
+			}
+			if got != c.want {
+				t.Errorf("NormalizeVersion(%q) = %q, want %q", v, got, c.want)
+			}
+		}
+	}
+
+	for _, v := range []string{"", "1.2", "01.2.3", "abc", "1.2.3.4"} {
+		if _, err := NormalizeVersion(v); err == nil {
+			t.Errorf("NormalizeVersion(%q) error expected", v)
+		}
+	}
+}
+
+func TestVersionRange(t *testing.T) {
+	testCases := []struct {
+		expr    string
+		match   []string
+		noMatch []string
+	}{
+		{expr: "~1.2", match: []string{"1.2.0", "v1.2.9", "1.2.3+build.5"}, noMatch: []string{"1.3.0", "1.1.9", "1.2.1-rc.1", "invalid"}},
+		{expr: "~1.2.3", match: []string{"1.2.3", "1.2.10"}, noMatch: []string{"1.2.2", "1.3.0"}},
+		{expr: "1.x", match: []string{"1.0.0", "1.99.3"}, noMatch: []string{"0.9.0", "2.0.0"}},
+		{expr: "v1.2.X", match: []string{"1.2.0", "1.2.7"}, noMatch: []string{"1.3.0"}},
+		{expr: "*", match: []string{"0.0.1", "9.0.0"}, noMatch: []string{"1.0.0-alpha"}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.expr, func(t *testing.T) {
+			if !IsVersionRange(tC.expr) {
+				t.Errorf("IsVersionRange(%q) must be true", tC.expr)
+			}
+			r, err := ParseVersionRange(tC.expr)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, v := range tC.match {
+				if !r.Contains(v) {
+					t.Errorf("%q must contain %q", tC.expr, v)
+				}
+			}
+			for _, v := range tC.noMatch {
+				if r.Contains(v) {
+					t.Errorf("%q must not contain %q", tC.expr, v)
+				}
+			}
+		})
+	}
+
+	for _, expr := range []string{"1.2.3", "~1", "~1.x", "x.1", "1.x.x", "1.2.3.x", "~01.2"} {
+		if _, err := ParseVersionRange(expr); err == nil {
+			t.Errorf("ParseVersionRange(%q) error expected", expr)
+		}
+	}
+}
+
+func TestNormalizePath(t *testing.T) {
+	testCases := []struct {
+		in   string
+		want string
+	}{
+		{in: "app/production/name/foo/1.2.0/component/db", want: "app/production/name/foo/v1.2.0/component/db"},
+		{in: "/APP/production/name/foo/V1.2.0/component/db", want: "app/production/name/foo/v1.2.0/component/db"},
+		{in: "product/foo/1.0.0+build.1/foo", want: "product/foo/v1.0.0+build.1/foo"},
+		{in: "product/foo/invalid/foo", want: "product/foo/invalid/foo"},
+		{in: "platform/production/foo/eu-central-1/db/admin_account", want: "platform/production/foo/eu-central-1/db/admin_account"},
+	}
+	for _, tC := range testCases {
+		if got := NormalizePath(tC.in); got != tC.want {
+			t.Errorf("NormalizePath(%q) = %q, want %q", tC.in, got, tC.want)
+		}
+	}
+}
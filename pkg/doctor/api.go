@@ -0,0 +1,77 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package doctor
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Status describes a check result status.
+type Status string
+
+const (
+	// StatusPass is used when the check succeeded.
+	StatusPass Status = "pass"
+	// StatusWarn is used when the check detected a non blocking problem.
+	StatusWarn Status = "warn"
+	// StatusFail is used when the check detected a blocking problem.
+	StatusFail Status = "fail"
+)
+
+// Result describes a check execution result.
+type Result struct {
+	ID      string `json:"id"`
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+}
+
+// Check describes a diagnostic check contract.
+type Check interface {
+	// ID returns the stable check identifier.
+	ID() string
+	// Run the check against the given environment.
+	Run(ctx context.Context, env *Environment) Result
+}
+
+// Environment holds the execution environment inspected by checks.
+type Environment struct {
+	// IsTerminal returns true if stdin is an interactive terminal.
+	IsTerminal func() bool
+	// Unavailable lists platform features not available to commands.
+	Unavailable []string
+	// TempDir is the temporary directory path.
+	TempDir string
+	// ConfigDir is the harp configuration directory path.
+	ConfigDir string
+	// VaultAddr is the configured Vault address.
+	VaultAddr string
+	// URLs is the list of backend URLs to check.
+	URLs []string
+	// Identities is the list of identity file paths to check.
+	Identities []string
+	// TimeURL is the HTTPS URL used to compare the local clock.
+	TimeURL string
+	// MaxClockSkew is the tolerated clock difference.
+	MaxClockSkew time.Duration
+	// Client is the HTTP client used by network checks.
+	Client *http.Client
+	// Now returns the local time.
+	Now func() time.Time
+}
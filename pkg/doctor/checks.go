@@ -0,0 +1,278 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package doctor
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/container"
+	"github.com/elastic/harp/pkg/container/identity"
+)
+
+func init() {
+	MustRegister(CheckFunc("terminal.password-prompt", checkTerminal))
+	MustRegister(CheckFunc("platform.capabilities", checkPlatform))
+	MustRegister(CheckFunc("fs.temp-dir", checkTempDir))
+	MustRegister(CheckFunc("fs.config-dir", checkConfigDir))
+	MustRegister(CheckFunc("net.vault", checkVault))
+	MustRegister(CheckFunc("net.backends", checkBackends))
+	MustRegister(CheckFunc("identity.files", checkIdentities))
+	MustRegister(CheckFunc("time.clock-skew", checkClockSkew))
+	MustRegister(CheckFunc("formats.versions", checkFormats))
+}
+
+// CheckFunc wraps a function as a named check.
+func CheckFunc(id string, fn func(context.Context, *Environment) Result) Check {
+	return &funcCheck{id: id, fn: fn}
+}
+
+type funcCheck struct {
+	id string
+	fn func(context.Context, *Environment) Result
+}
+
+func (c *funcCheck) ID() string { return c.id }
+
+func (c *funcCheck) Run(ctx context.Context, env *Environment) Result {
+	return c.fn(ctx, env)
+}
+
+// -----------------------------------------------------------------------------
+
+func pass(format string, args ...interface{}) Result {
+	return Result{Status: StatusPass, Message: fmt.Sprintf(format, args...)}
+}
+
+func warn(format string, args ...interface{}) Result {
+	return Result{Status: StatusWarn, Message: fmt.Sprintf(format, args...)}
+}
+
+func fail(format string, args ...interface{}) Result {
+	return Result{Status: StatusFail, Message: fmt.Sprintf(format, args...)}
+}
+
+// -----------------------------------------------------------------------------
+
+func checkTerminal(_ context.Context, env *Environment) Result {
+	if env.IsTerminal == nil || !env.IsTerminal() {
+		return warn("stdin is not a terminal, passphrase prompts are unavailable")
+	}
+	return pass("stdin is a terminal, passphrase prompts are available")
+}
+
+func checkPlatform(_ context.Context, env *Environment) Result {
+	if len(env.Unavailable) > 0 {
+		return warn("features unavailable on %s/%s: %s", runtime.GOOS, runtime.GOARCH, strings.Join(env.Unavailable, ", "))
+	}
+	return pass("all platform features are available on %s/%s", runtime.GOOS, runtime.GOARCH)
+}
+
+func checkTempDir(_ context.Context, env *Environment) Result {
+	return checkWritableDir(env.TempDir, "temporary", true)
+}
+
+func checkConfigDir(_ context.Context, env *Environment) Result {
+	if env.ConfigDir == "" {
+		return warn("configuration directory can't be resolved")
+	}
+
+	// Missing configuration directory is not an error
+	if _, err := os.Stat(env.ConfigDir); os.IsNotExist(err) {
+		return pass("configuration directory '%s' doesn't exist", env.ConfigDir)
+	}
+
+	return checkWritableDir(env.ConfigDir, "configuration", false)
+}
+
+func checkWritableDir(path, name string, shared bool) Result {
+	// Check directory
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fail("unable to access %s directory '%s': %v", name, path, err)
+	}
+	if !fi.IsDir() {
+		return fail("%s path '%s' is not a directory", name, path)
+	}
+
+	// Check writability
+	f, err := ioutil.TempFile(path, ".harp-doctor-*")
+	if err != nil {
+		return fail("%s directory '%s' is not writable: %v", name, path, err)
+	}
+	f.Close()
+	os.Remove(f.Name())
+
+	// Check permissions
+	mode := fi.Mode()
+	switch {
+	case shared && mode.Perm()&0o002 != 0 && mode&os.ModeSticky == 0:
+		return warn("%s directory '%s' is world writable without sticky bit (%s)", name, path, mode)
+	case !shared && mode.Perm()&0o022 != 0:
+		return warn("%s directory '%s' is writable by other users (%s)", name, path, mode)
+	}
+
+	return pass("%s directory '%s' is writable (%s)", name, path, mode)
+}
+
+func checkVault(ctx context.Context, env *Environment) Result {
+	if env.VaultAddr == "" {
+		return pass("no Vault address configured")
+	}
+
+	// Query the health endpoint
+	u := strings.TrimSuffix(env.VaultAddr, "/") + "/v1/sys/health"
+	if err := reach(ctx, env, u); err != nil {
+		return fail("Vault '%s' is not reachable: %v", env.VaultAddr, err)
+	}
+
+	return pass("Vault '%s' is reachable", env.VaultAddr)
+}
+
+func checkBackends(ctx context.Context, env *Environment) Result {
+	if len(env.URLs) == 0 {
+		return pass("no backend URL configured")
+	}
+
+	for _, raw := range env.URLs {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return fail("invalid backend URL '%s': %v", raw, err)
+		}
+
+		// Only remote backends are checked
+		scheme := strings.TrimPrefix(u.Scheme, "bundle+")
+		if scheme != "http" && scheme != "https" {
+			continue
+		}
+		u.Scheme = scheme
+
+		if err := reach(ctx, env, u.String()); err != nil {
+			return fail("backend '%s' is not reachable: %v", u.Redacted(), err)
+		}
+	}
+
+	return pass("%d backend URL(s) checked", len(env.URLs))
+}
+
+func checkIdentities(_ context.Context, env *Environment) Result {
+	if len(env.Identities) == 0 {
+		return pass("no identity referenced")
+	}
+
+	for _, path := range env.Identities {
+		f, err := os.Open(path)
+		if err != nil {
+			return fail("unable to open identity '%s': %v", path, err)
+		}
+		_, err = identity.FromReader(f)
+		f.Close()
+		if err != nil {
+			return fail("invalid identity '%s': %v", path, err)
+		}
+	}
+
+	return pass("%d identity file(s) found", len(env.Identities))
+}
+
+func checkClockSkew(ctx context.Context, env *Environment) Result {
+	if env.TimeURL == "" {
+		return warn("no time reference URL configured")
+	}
+
+	// Query the reference server
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, env.TimeURL, nil)
+	if err != nil {
+		return fail("unable to prepare time reference query: %v", err)
+	}
+	resp, err := env.Client.Do(req)
+	if err != nil {
+		return warn("unable to query time reference '%s': %v", env.TimeURL, describe(err))
+	}
+	resp.Body.Close()
+
+	// Parse remote date
+	remote, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return warn("time reference '%s' returned an invalid Date header", env.TimeURL)
+	}
+
+	// Compare with local clock (Date header has a second precision)
+	skew := env.Now().Sub(remote).Truncate(time.Second)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > env.MaxClockSkew {
+		return fail("local clock differs from '%s' by %s", env.TimeURL, skew)
+	}
+
+	return pass("local clock differs from '%s' by %s", env.TimeURL, skew)
+}
+
+func checkFormats(_ context.Context, _ *Environment) Result {
+	return pass("container v%d, bundle %s, identity %s",
+		container.FormatVersion(),
+		string((&bundlev1.Bundle{}).ProtoReflect().Descriptor().FullName().Parent()),
+		identity.APIVersion,
+	)
+}
+
+// -----------------------------------------------------------------------------
+
+func reach(ctx context.Context, env *Environment, u string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := env.Client.Do(req)
+	if err != nil {
+		return describe(err)
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// describe makes TLS validation errors explicit.
+func describe(err error) error {
+	var (
+		unknownAuthority x509.UnknownAuthorityError
+		hostname         x509.HostnameError
+		invalid          x509.CertificateInvalidError
+	)
+	switch {
+	case errors.As(err, &unknownAuthority):
+		return fmt.Errorf("TLS validation failed, certificate signed by unknown authority (check CA settings): %w", err)
+	case errors.As(err, &hostname):
+		return fmt.Errorf("TLS validation failed, hostname mismatch: %w", err)
+	case errors.As(err, &invalid):
+		return fmt.Errorf("TLS validation failed, invalid certificate: %w", err)
+	}
+	return err
+}
@@ -0,0 +1,329 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func fakeEnvironment(t *testing.T) *Environment {
+	t.Helper()
+
+	tmpDir, err := ioutil.TempDir("", "harp-doctor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+
+	return &Environment{
+		IsTerminal:   func() bool { return true },
+		TempDir:      tmpDir,
+		ConfigDir:    filepath.Join(tmpDir, "config"),
+		MaxClockSkew: DefaultMaxClockSkew,
+		Client:       http.DefaultClient,
+		Now:          time.Now,
+	}
+}
+
+func runCheck(t *testing.T, id string, env *Environment) Result {
+	t.Helper()
+
+	for _, c := range Checks() {
+		if c.ID() == id {
+			return Run(context.Background(), env, []Check{c}).Results[0]
+		}
+	}
+
+	t.Fatalf("check %q not registered", id)
+	return Result{}
+}
+
+func dateServer(date time.Time) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", date.UTC().Format(http.TimeFormat))
+	}))
+}
+
+// -----------------------------------------------------------------------------
+
+func TestChecks_IDs(t *testing.T) {
+	ids := []string{}
+	for _, c := range Checks() {
+		ids = append(ids, c.ID())
+	}
+
+	want := []string{
+		"formats.versions",
+		"fs.config-dir",
+		"fs.temp-dir",
+		"identity.files",
+		"net.backends",
+		"net.vault",
+		"platform.capabilities",
+		"terminal.password-prompt",
+		"time.clock-skew",
+	}
+	if diff := cmp.Diff(ids, want); diff != "" {
+		t.Errorf("%q. Checks():\n-got/+want\ndiff %s", "ids", diff)
+	}
+}
+
+func TestChecks_FailureModes(t *testing.T) {
+	okServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer okServer.Close()
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsServer.Close()
+	skewedServer := dateServer(time.Now().Add(-time.Hour))
+	defer skewedServer.Close()
+	syncServer := dateServer(time.Now())
+	defer syncServer.Close()
+
+	testCases := []struct {
+		desc        string
+		id          string
+		prepare     func(t *testing.T, env *Environment)
+		wantStatus  Status
+		wantMessage string
+	}{
+		{
+			desc:       "terminal available",
+			id:         "terminal.password-prompt",
+			wantStatus: StatusPass,
+		},
+		{
+			desc:       "no terminal",
+			id:         "terminal.password-prompt",
+			prepare:    func(_ *testing.T, env *Environment) { env.IsTerminal = func() bool { return false } },
+			wantStatus: StatusWarn,
+		},
+		{
+			desc:       "platform features available",
+			id:         "platform.capabilities",
+			wantStatus: StatusPass,
+		},
+		{
+			desc:        "platform features unavailable",
+			id:          "platform.capabilities",
+			prepare:     func(_ *testing.T, env *Environment) { env.Unavailable = []string{"file-locking", "sandbox"} },
+			wantStatus:  StatusWarn,
+			wantMessage: "file-locking, sandbox",
+		},
+		{
+			desc:       "temp dir writable",
+			id:         "fs.temp-dir",
+			wantStatus: StatusPass,
+		},
+		{
+			desc:        "temp dir missing",
+			id:          "fs.temp-dir",
+			prepare:     func(_ *testing.T, env *Environment) { env.TempDir = filepath.Join(env.TempDir, "missing") },
+			wantStatus:  StatusFail,
+			wantMessage: "unable to access",
+		},
+		{
+			desc: "temp dir world writable",
+			id:   "fs.temp-dir",
+			prepare: func(t *testing.T, env *Environment) {
+				if err := os.Chmod(env.TempDir, 0o777); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantStatus:  StatusWarn,
+			wantMessage: "without sticky bit",
+		},
+		{
+			desc:       "config dir missing",
+			id:         "fs.config-dir",
+			wantStatus: StatusPass,
+		},
+		{
+			desc: "config dir is a file",
+			id:   "fs.config-dir",
+			prepare: func(t *testing.T, env *Environment) {
+				if err := ioutil.WriteFile(env.ConfigDir, []byte{}, 0o600); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantStatus:  StatusFail,
+			wantMessage: "is not a directory",
+		},
+		{
+			desc: "config dir group writable",
+			id:   "fs.config-dir",
+			prepare: func(t *testing.T, env *Environment) {
+				if err := os.Mkdir(env.ConfigDir, 0o700); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chmod(env.ConfigDir, 0o770); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantStatus:  StatusWarn,
+			wantMessage: "writable by other users",
+		},
+		{
+			desc:       "vault not configured",
+			id:         "net.vault",
+			wantStatus: StatusPass,
+		},
+		{
+			desc:       "vault reachable",
+			id:         "net.vault",
+			prepare:    func(_ *testing.T, env *Environment) { env.VaultAddr = okServer.URL },
+			wantStatus: StatusPass,
+		},
+		{
+			desc:        "vault unknown authority",
+			id:          "net.vault",
+			prepare:     func(_ *testing.T, env *Environment) { env.VaultAddr = tlsServer.URL },
+			wantStatus:  StatusFail,
+			wantMessage: "unknown authority",
+		},
+		{
+			desc:        "backend unreachable",
+			id:          "net.backends",
+			prepare:     func(_ *testing.T, env *Environment) { env.URLs = []string{"bundle+http://127.0.0.1:1/prod.bundle"} },
+			wantStatus:  StatusFail,
+			wantMessage: "is not reachable",
+		},
+		{
+			desc: "backend reachable",
+			id:   "net.backends",
+			prepare: func(_ *testing.T, env *Environment) {
+				env.URLs = []string{"bundle:///prod.bundle", strings.Replace(okServer.URL, "http://", "bundle+http://", 1)}
+			},
+			wantStatus: StatusPass,
+		},
+		{
+			desc:        "identity missing",
+			id:          "identity.files",
+			prepare:     func(_ *testing.T, env *Environment) { env.Identities = []string{filepath.Join(env.TempDir, "id.json")} },
+			wantStatus:  StatusFail,
+			wantMessage: "unable to open identity",
+		},
+		{
+			desc: "identity invalid",
+			id:   "identity.files",
+			prepare: func(t *testing.T, env *Environment) {
+				path := filepath.Join(env.TempDir, "id.json")
+				if err := ioutil.WriteFile(path, []byte(`{"public":"AAAA"}`), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				env.Identities = []string{path}
+			},
+			wantStatus:  StatusFail,
+			wantMessage: "invalid identity",
+		},
+		{
+			desc:       "clock synchronized",
+			id:         "time.clock-skew",
+			prepare:    func(_ *testing.T, env *Environment) { env.TimeURL = syncServer.URL },
+			wantStatus: StatusPass,
+		},
+		{
+			desc:        "clock skewed",
+			id:          "time.clock-skew",
+			prepare:     func(_ *testing.T, env *Environment) { env.TimeURL = skewedServer.URL },
+			wantStatus:  StatusFail,
+			wantMessage: "differs",
+		},
+		{
+			desc:        "clock reference unreachable",
+			id:          "time.clock-skew",
+			prepare:     func(_ *testing.T, env *Environment) { env.TimeURL = "http://127.0.0.1:1" },
+			wantStatus:  StatusWarn,
+			wantMessage: "unable to query time reference",
+		},
+		{
+			desc:        "formats",
+			id:          "formats.versions",
+			wantStatus:  StatusPass,
+			wantMessage: "container v2",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			env := fakeEnvironment(t)
+			if tC.prepare != nil {
+				tC.prepare(t, env)
+			}
+
+			res := runCheck(t, tC.id, env)
+			if res.ID != tC.id {
+				t.Errorf("expected id %q, got %q", tC.id, res.ID)
+			}
+			if res.Status != tC.wantStatus {
+				t.Errorf("expected status %q, got %q (%s)", tC.wantStatus, res.Status, res.Message)
+			}
+			if !strings.Contains(res.Message, tC.wantMessage) {
+				t.Errorf("expected message to contain %q, got %q", tC.wantMessage, res.Message)
+			}
+		})
+	}
+}
+
+func TestRegister_Conflict(t *testing.T) {
+	if err := Register(CheckFunc("fs.temp-dir", checkTempDir)); err != ErrCheckAlreadyRegistered {
+		t.Errorf("expected registration conflict, got %v", err)
+	}
+}
+
+func TestReport_Output(t *testing.T) {
+	report := &Report{
+		Results: []Result{
+			{ID: "a.check", Status: StatusPass, Message: "ok"},
+			{ID: "b.check", Status: StatusFail, Message: "ko"},
+		},
+	}
+
+	if !report.HasFailures() {
+		t.Error("report must have failures")
+	}
+
+	var text bytes.Buffer
+	if err := report.WriteText(&text); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(text.String(), "PASS  a.check  ok\nFAIL  b.check  ko\n"); diff != "" {
+		t.Errorf("%q. WriteText():\n-got/+want\ndiff %s", "text", diff)
+	}
+
+	var decoded Report
+	var js bytes.Buffer
+	if err := report.WriteJSON(&js); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(js.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&decoded, report); diff != "" {
+		t.Errorf("%q. WriteJSON():\n-got/+want\ndiff %s", "json", diff)
+	}
+}
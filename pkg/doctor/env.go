@@ -0,0 +1,63 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package doctor
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/tty"
+)
+
+const (
+	// DefaultTimeURL is the default HTTPS clock reference.
+	DefaultTimeURL = "https://www.elastic.co"
+	// DefaultMaxClockSkew is the default tolerated clock difference.
+	DefaultMaxClockSkew = 30 * time.Second
+)
+
+// DefaultEnvironment returns the environment of the current process.
+func DefaultEnvironment() *Environment {
+	// Resolve configuration directory
+	configDir := ""
+	if dir, err := os.UserConfigDir(); err == nil {
+		configDir = filepath.Join(dir, "harp")
+	}
+
+	// Build HTTP client
+	client := cleanhttp.DefaultClient()
+	client.Timeout = 10 * time.Second
+
+	return &Environment{
+		IsTerminal: func() bool {
+			return tty.IsTerminal(os.Stdin)
+		},
+		Unavailable:  cmdutil.Capabilities().Unavailable(),
+		TempDir:      os.TempDir(),
+		ConfigDir:    configDir,
+		VaultAddr:    os.Getenv("VAULT_ADDR"),
+		TimeURL:      DefaultTimeURL,
+		MaxClockSkew: DefaultMaxClockSkew,
+		Client:       client,
+		Now:          time.Now,
+	}
+}
@@ -0,0 +1,85 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package doctor
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+)
+
+var (
+	mu     sync.RWMutex
+	checks = map[string]Check{}
+
+	// ErrCheckAlreadyRegistered is raised when trying to register an existent check.
+	ErrCheckAlreadyRegistered = errors.New("doctor: check already registered")
+)
+
+// Register a new diagnostic check.
+func Register(c Check) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, ok := checks[c.ID()]; ok {
+		return ErrCheckAlreadyRegistered
+	}
+	checks[c.ID()] = c
+
+	// No error
+	return nil
+}
+
+// MustRegister try to register the check and panic on error.
+func MustRegister(c Check) {
+	if err := Register(c); err != nil {
+		panic(err)
+	}
+}
+
+// Checks returns registered checks sorted by identifier.
+func Checks() []Check {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	res := make([]Check, 0, len(checks))
+	for _, c := range checks {
+		res = append(res, c)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID() < res[j].ID()
+	})
+
+	return res
+}
+
+// Run all given checks in order.
+func Run(ctx context.Context, env *Environment, cs []Check) *Report {
+	report := &Report{
+		Results: make([]Result, 0, len(cs)),
+	}
+
+	for _, c := range cs {
+		res := c.Run(ctx, env)
+		res.ID = c.ID()
+		report.Results = append(report.Results, res)
+	}
+
+	return report
+}
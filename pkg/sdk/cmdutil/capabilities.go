@@ -0,0 +1,104 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmdutil
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/fsutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/sdk/sandbox"
+	"github.com/elastic/harp/pkg/sdk/tty"
+)
+
+const (
+	// FeatureFileLocking identifies advisory file locking.
+	FeatureFileLocking = "file-locking"
+	// FeatureSandbox identifies process self-sandboxing.
+	FeatureSandbox = "sandbox"
+	// FeatureTTYPrompt identifies secret prompting with echo disabled.
+	FeatureTTYPrompt = "tty-prompt"
+)
+
+// PlatformCapabilities describes the platform dependent features available
+// to commands.
+type PlatformCapabilities struct {
+	// FileLocking is true when advisory file locks are enforced.
+	FileLocking bool `json:"fileLocking"`
+	// Sandbox is true when process restrictions can be applied.
+	Sandbox bool `json:"sandbox"`
+	// TTYPrompt is true when secrets can be prompted with echo disabled.
+	TTYPrompt bool `json:"ttyPrompt"`
+}
+
+// Available returns true if the given feature is available.
+func (c PlatformCapabilities) Available(feature string) bool {
+	switch feature {
+	case FeatureFileLocking:
+		return c.FileLocking
+	case FeatureSandbox:
+		return c.Sandbox
+	case FeatureTTYPrompt:
+		return c.TTYPrompt
+	default:
+	}
+
+	return false
+}
+
+// Unavailable returns the unavailable feature names.
+func (c PlatformCapabilities) Unavailable() []string {
+	res := []string{}
+	for _, f := range []string{FeatureFileLocking, FeatureSandbox, FeatureTTYPrompt} {
+		if !c.Available(f) {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+var (
+	capabilitiesOnce sync.Once
+	capabilities     PlatformCapabilities
+)
+
+// Capabilities returns the platform capabilities, evaluated once.
+func Capabilities() PlatformCapabilities {
+	capabilitiesOnce.Do(func() {
+		capabilities = PlatformCapabilities{
+			FileLocking: fsutil.LockSupported(),
+			Sandbox:     sandbox.Supported(),
+			TTYPrompt:   tty.Supported(),
+		}
+	})
+	return capabilities
+}
+
+// WarnUnavailable logs a warning when the given feature is not available on
+// this platform, and returns the feature availability.
+func WarnUnavailable(ctx context.Context, feature, consequence string) bool {
+	if Capabilities().Available(feature) {
+		return true
+	}
+
+	log.For(ctx).Warn("feature is not available on this platform", zap.String("feature", feature), zap.String("consequence", consequence))
+	return false
+}
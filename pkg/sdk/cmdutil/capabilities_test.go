@@ -0,0 +1,81 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmdutil
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/elastic/harp/pkg/sdk/fsutil"
+	"github.com/elastic/harp/pkg/sdk/sandbox"
+	"github.com/elastic/harp/pkg/sdk/tty"
+)
+
+func TestPlatformCapabilities(t *testing.T) {
+	testCases := []struct {
+		name        string
+		caps        PlatformCapabilities
+		unavailable []string
+	}{
+		{
+			name:        "all available",
+			caps:        PlatformCapabilities{FileLocking: true, Sandbox: true, TTYPrompt: true},
+			unavailable: []string{},
+		},
+		{
+			name:        "wasm",
+			caps:        PlatformCapabilities{},
+			unavailable: []string{FeatureFileLocking, FeatureSandbox, FeatureTTYPrompt},
+		},
+		{
+			name:        "darwin",
+			caps:        PlatformCapabilities{FileLocking: true, TTYPrompt: true},
+			unavailable: []string{FeatureSandbox},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.caps.Unavailable(); !reflect.DeepEqual(got, tc.unavailable) {
+				t.Errorf("expected %v, got %v", tc.unavailable, got)
+			}
+		})
+	}
+
+	if (PlatformCapabilities{FileLocking: true}).Available("unknown") {
+		t.Error("unknown feature must not be available")
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	expected := PlatformCapabilities{
+		FileLocking: fsutil.LockSupported(),
+		Sandbox:     sandbox.Supported(),
+		TTYPrompt:   tty.Supported(),
+	}
+	if got := Capabilities(); got != expected {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+
+	// Availability is reported by warnings
+	for _, f := range []string{FeatureFileLocking, FeatureSandbox, FeatureTTYPrompt} {
+		if got := WarnUnavailable(context.Background(), f, "test"); got != expected.Available(f) {
+			t.Errorf("%s: expected %v, got %v", f, expected.Available(f), got)
+		}
+	}
+}
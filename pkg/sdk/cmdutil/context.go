@@ -31,6 +31,11 @@ func Context(ctx context.Context, name string, debug bool, logLevel string) (con
 	// Context to attach all goroutines
 	ctx, cancel := context.WithCancel(ctx)
 
+	// Keep the embedding application logger
+	if _, ok := log.FromContext(ctx); ok {
+		return ctx, cancel
+	}
+
 	// Initialize logger
 	log.Setup(ctx,
 		&log.Options{
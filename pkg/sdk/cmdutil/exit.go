@@ -0,0 +1,88 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmdutil
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// Command exit codes.
+const (
+	// ExitOK is returned when the command succeeds.
+	ExitOK = 0
+	// ExitUsage is returned for invalid flags, arguments or configuration.
+	ExitUsage = 1
+	// ExitPartialFailure is returned when some items failed while others
+	// succeeded.
+	ExitPartialFailure = 2
+	// ExitFailure is returned when the command failed as a whole.
+	ExitFailure = 3
+	// ExitPolicyViolation is returned when policy violations are detected.
+	ExitPolicyViolation = 4
+)
+
+// ExitCode returns the process exit code matching the given command error.
+// Unclassified errors are total failures.
+func ExitCode(err error) int {
+	var (
+		flagErr   *FlagError
+		usageErr  *tasks.UsageError
+		policyErr *tasks.PolicyError
+		itemsErr  *tasks.ItemsError
+	)
+
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &flagErr), errors.As(err, &usageErr):
+		return ExitUsage
+	case errors.As(err, &policyErr):
+		return ExitPolicyViolation
+	case errors.As(err, &itemsErr):
+		if itemsErr.Partial() {
+			return ExitPartialFailure
+		}
+		return ExitFailure
+	default:
+	}
+
+	return ExitFailure
+}
+
+// FailureModeFlags registers the mutually exclusive --fail-fast and
+// --keep-going flags. The returned function resolves the selected mode,
+// items are processed until completion by default.
+func FailureModeFlags(cmd *cobra.Command) func() tasks.FailureMode {
+	var failFast bool
+
+	cmd.Flags().BoolVar(&failFast, "fail-fast", false, "Stop processing items at the first failure")
+	cmd.Flags().Bool("keep-going", false, "Process all items and report aggregated failures (default)")
+
+	ValidateFlags(cmd, MutuallyExclusive("fail-fast", "keep-going"))
+
+	return func() tasks.FailureMode {
+		if failFast {
+			return tasks.FailFast
+		}
+		return tasks.KeepGoing
+	}
+}
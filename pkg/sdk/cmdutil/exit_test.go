@@ -0,0 +1,87 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmdutil
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+func TestExitCode(t *testing.T) {
+	testCases := []struct {
+		desc string
+		err  error
+		want int
+	}{
+		{desc: "success", want: ExitOK},
+		{desc: "flag error", err: &FlagError{Violations: []string{"--in is required"}}, want: ExitUsage},
+		{desc: "usage error", err: fmt.Errorf("wrapped: %w", tasks.Usage(errors.New("invalid policy"))), want: ExitUsage},
+		{desc: "policy violation", err: tasks.PolicyViolation(errors.New("violations")), want: ExitPolicyViolation},
+		{desc: "partial failure", err: &tasks.ItemsError{Succeeded: 1, Failed: 1}, want: ExitPartialFailure},
+		{desc: "total failure", err: &tasks.ItemsError{Failed: 2, Skipped: 1}, want: ExitFailure},
+		{desc: "unclassified", err: errors.New("unable to open input"), want: ExitFailure},
+	}
+	for _, tC := range testCases {
+		if got := ExitCode(tC.err); got != tC.want {
+			t.Errorf("%s: expected %d, got %d", tC.desc, tC.want, got)
+		}
+	}
+}
+
+func TestFailureModeFlags(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		args    []string
+		want    tasks.FailureMode
+		wantErr bool
+	}{
+		{desc: "default", want: tasks.KeepGoing},
+		{desc: "keep going", args: []string{"--keep-going"}, want: tasks.KeepGoing},
+		{desc: "fail fast", args: []string{"--fail-fast"}, want: tasks.FailFast},
+		{desc: "both", args: []string{"--fail-fast", "--keep-going"}, wantErr: true},
+	}
+	for _, tC := range testCases {
+		var got tasks.FailureMode
+		cmd := &cobra.Command{
+			Use:           "test",
+			SilenceErrors: true,
+			SilenceUsage:  true,
+		}
+		mode := FailureModeFlags(cmd)
+		cmd.Run = func(*cobra.Command, []string) {
+			got = mode()
+		}
+		cmd.SetArgs(tC.args)
+
+		err := cmd.Execute()
+		if tC.wantErr {
+			if ExitCode(err) != ExitUsage {
+				t.Errorf("%s: expected usage error, got %v", tC.desc, err)
+			}
+			continue
+		}
+		if err != nil || got != tC.want {
+			t.Errorf("%s: expected %v, got %v (%v)", tC.desc, tC.want, got, err)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmdutil
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/elastic/harp/pkg/sdk/tty"
+)
+
+var (
+	interactiveMu sync.RWMutex
+	interactive   bool
+
+	// stdinIsTerminal returns true when prompts can be displayed.
+	stdinIsTerminal = func() bool {
+		return tty.IsTerminal(os.Stdin)
+	}
+
+	// promptValue asks the user for a flag value.
+	promptValue = func(prompt string, secret bool) (string, error) {
+		if secret {
+			buf, err := ReadSecret(prompt, false)
+			if err != nil {
+				return "", err
+			}
+			defer buf.Destroy()
+			return buf.String(), nil
+		}
+
+		fmt.Printf("%s: ", prompt)
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			return "", fmt.Errorf("unable to read value: %w", err)
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+)
+
+// SetInteractive enables prompting for missing required flag values. Prompts
+// are only displayed when stdin is a terminal.
+func SetInteractive(value bool) {
+	interactiveMu.Lock()
+	interactive = value
+	interactiveMu.Unlock()
+}
+
+// FlagError describes all flag constraint violations of a command.
+type FlagError struct {
+	Violations []string
+}
+
+// Error returns the error message.
+func (e *FlagError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d invalid flag usage(s) found", len(e.Violations))
+	for _, v := range e.Violations {
+		fmt.Fprintf(&sb, "\n  - %s", v)
+	}
+	return sb.String()
+}
+
+// FlagRule describes a flag constraint, rules are built using RequiredOneOf,
+// MutuallyExclusive, Required, Forbidden, Prompted and When.
+type FlagRule func(c *flagCheck)
+
+// RequiredOneOf requires at least one of the given flags to be set.
+func RequiredOneOf(names ...string) FlagRule {
+	return func(c *flagCheck) {
+		if len(c.set(names)) == 0 {
+			c.add("one of %s must be set", strings.Join(flagNames(names), ", "))
+		}
+	}
+}
+
+// MutuallyExclusive forbids setting more than one of the given flags.
+func MutuallyExclusive(names ...string) FlagRule {
+	return func(c *flagCheck) {
+		if set := c.set(names); len(set) > 1 {
+			c.add("%s are mutually exclusive", flagList(set))
+		}
+	}
+}
+
+// Required requires all given flags to be set.
+func Required(names ...string) FlagRule {
+	return func(c *flagCheck) {
+		for _, name := range names {
+			if !c.flags.Changed(name) {
+				c.add("--%s is required", name)
+			}
+		}
+	}
+}
+
+// Forbidden forbids setting the given flags, it is meant to be used with
+// When.
+func Forbidden(names ...string) FlagRule {
+	return func(c *flagCheck) {
+		for _, name := range c.set(names) {
+			c.add("--%s is not allowed", name)
+		}
+	}
+}
+
+// Prompted requires the given flag to be set. In interactive mode, the
+// missing value is prompted once all other rules are satisfied.
+func Prompted(name, prompt string, secret bool) FlagRule {
+	return func(c *flagCheck) {
+		if c.flags.Changed(name) {
+			return
+		}
+		switch {
+		case c.interactive && c.terminal:
+			c.prompts = append(c.prompts, flagPrompt{name: name, prompt: prompt, secret: secret, condition: c.condition})
+		case c.interactive:
+			c.add("--%s is required (not prompted, stdin is not a terminal)", name)
+		default:
+			c.add("--%s is required", name)
+		}
+	}
+}
+
+// When applies the given rules only when the flag is set to the given value,
+// or is set at all when the value is blank.
+func When(name, value string, rules ...FlagRule) FlagRule {
+	return func(c *flagCheck) {
+		f := c.flags.Lookup(name)
+		if f == nil {
+			c.add("unknown flag --%s", name)
+			return
+		}
+
+		// Check condition
+		condition := fmt.Sprintf("when --%s is set", name)
+		if value == "" {
+			if !c.flags.Changed(name) {
+				return
+			}
+		} else {
+			if f.Value.String() != value {
+				return
+			}
+			condition = fmt.Sprintf("when --%s is '%s'", name, value)
+		}
+
+		// Apply nested rules
+		parent := c.condition
+		c.condition = condition
+		if parent != "" {
+			c.condition = parent + " and " + condition[len("when "):]
+		}
+		for _, r := range rules {
+			r(c)
+		}
+		c.condition = parent
+	}
+}
+
+// ValidateFlags registers the given rules to be checked before the command
+// runs. All violations are reported together, missing prompted values are
+// only asked when no violation is found.
+func ValidateFlags(cmd *cobra.Command, rules ...FlagRule) {
+	preRunE, preRun := cmd.PreRunE, cmd.PreRun
+	cmd.PreRun = nil
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := CheckFlags(cmd.Flags(), rules...); err != nil {
+			return err
+		}
+
+		// Delegate to original hooks
+		switch {
+		case preRunE != nil:
+			return preRunE(cmd, args)
+		case preRun != nil:
+			preRun(cmd, args)
+		default:
+		}
+
+		return nil
+	}
+}
+
+// CheckFlags checks the given rules against the flag set and prompts for
+// missing values in interactive mode. A *FlagError is returned when rules
+// are violated.
+func CheckFlags(flags *pflag.FlagSet, rules ...FlagRule) error {
+	interactiveMu.RLock()
+	c := &flagCheck{
+		flags:       flags,
+		interactive: interactive,
+		terminal:    stdinIsTerminal(),
+	}
+	interactiveMu.RUnlock()
+
+	// Check all rules
+	for _, r := range rules {
+		r(c)
+	}
+	if len(c.violations) > 0 {
+		return &FlagError{Violations: c.violations}
+	}
+
+	// Prompt missing values
+	for _, p := range c.prompts {
+		value, err := promptValue(p.prompt, p.secret)
+		if err != nil {
+			return fmt.Errorf("unable to read --%s value: %w", p.name, err)
+		}
+		if value == "" {
+			c.condition = p.condition
+			c.add("--%s is required", p.name)
+			continue
+		}
+		if err := flags.Set(p.name, value); err != nil {
+			c.condition = p.condition
+			c.add("invalid --%s value: %v", p.name, err)
+		}
+	}
+	if len(c.violations) > 0 {
+		return &FlagError{Violations: c.violations}
+	}
+
+	// No error
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+type flagPrompt struct {
+	name      string
+	prompt    string
+	secret    bool
+	condition string
+}
+
+type flagCheck struct {
+	flags       *pflag.FlagSet
+	interactive bool
+	terminal    bool
+	condition   string
+	violations  []string
+	prompts     []flagPrompt
+}
+
+func (c *flagCheck) add(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if c.condition != "" {
+		msg = fmt.Sprintf("%s %s", msg, c.condition)
+	}
+	c.violations = append(c.violations, msg)
+}
+
+func (c *flagCheck) set(names []string) []string {
+	res := []string{}
+	for _, name := range names {
+		if c.flags.Changed(name) {
+			res = append(res, name)
+		}
+	}
+	return res
+}
+
+func flagNames(names []string) []string {
+	res := make([]string, len(names))
+	for i, name := range names {
+		res[i] = "--" + name
+	}
+	return res
+}
+
+// flagList returns the given flag names as '--a, --b and --c'.
+func flagList(names []string) string {
+	res := flagNames(names)
+	if len(res) < 2 {
+		return strings.Join(res, "")
+	}
+	return fmt.Sprintf("%s and %s", strings.Join(res[:len(res)-1], ", "), res[len(res)-1])
+}
@@ -0,0 +1,245 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmdutil
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/cobra"
+)
+
+type testFlags struct {
+	in, out, spec, store, name, key string
+	inPlace, piv                    bool
+}
+
+func testCommand(values *testFlags, rules ...FlagRule) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:  "test",
+		Run:  func(*cobra.Command, []string) {},
+		Args: cobra.NoArgs,
+	}
+	cmd.SetOut(ioutil.Discard)
+	cmd.SetErr(ioutil.Discard)
+
+	cmd.Flags().StringVar(&values.in, "in", "-", "")
+	cmd.Flags().StringVar(&values.out, "out", "", "")
+	cmd.Flags().StringVar(&values.spec, "spec", "", "")
+	cmd.Flags().StringVar(&values.store, "store", "file", "")
+	cmd.Flags().StringVar(&values.name, "name", "", "")
+	cmd.Flags().StringVar(&values.key, "key", "", "")
+	cmd.Flags().BoolVar(&values.inPlace, "in-place", false, "")
+	cmd.Flags().BoolVar(&values.piv, "piv", false, "")
+
+	ValidateFlags(cmd, rules...)
+
+	return cmd
+}
+
+func withPrompt(t *testing.T, terminal bool, answers map[string]string) *[]string {
+	t.Helper()
+
+	asked := []string{}
+	origTerminal, origPrompt := stdinIsTerminal, promptValue
+	stdinIsTerminal = func() bool { return terminal }
+	promptValue = func(prompt string, _ bool) (string, error) {
+		asked = append(asked, prompt)
+		return answers[prompt], nil
+	}
+	SetInteractive(true)
+	t.Cleanup(func() {
+		stdinIsTerminal, promptValue = origTerminal, origPrompt
+		SetInteractive(false)
+	})
+
+	return &asked
+}
+
+func TestValidateFlags(t *testing.T) {
+	rules := []FlagRule{
+		Prompted("spec", "Patch specification", false),
+		MutuallyExclusive("in-place", "out"),
+		MutuallyExclusive("piv", "key", "name"),
+		RequiredOneOf("in-place", "out"),
+		When("store", "keychain",
+			Forbidden("out"),
+			Required("name"),
+		),
+		When("in-place", "", Forbidden("key")),
+	}
+
+	testCases := []struct {
+		desc string
+		args []string
+		want []string
+	}{
+		{
+			desc: "valid",
+			args: []string{"--spec", "patch.yaml", "--out", "out.bundle"},
+		},
+		{
+			desc: "conflicts",
+			args: []string{"--spec", "patch.yaml", "--in-place", "--out", "out.bundle", "--piv", "--key", "foo", "--name", "bar"},
+			want: []string{
+				"--in-place and --out are mutually exclusive",
+				"--piv, --key and --name are mutually exclusive",
+				"--key is not allowed when --in-place is set",
+			},
+		},
+		{
+			desc: "missing",
+			args: []string{},
+			want: []string{
+				"--spec is required",
+				"one of --in-place, --out must be set",
+			},
+		},
+		{
+			desc: "conditional",
+			args: []string{"--spec", "patch.yaml", "--store", "keychain", "--out", "id.json"},
+			want: []string{
+				"--out is not allowed when --store is 'keychain'",
+				"--name is required when --store is 'keychain'",
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			cmd := testCommand(&testFlags{}, rules...)
+			cmd.SetArgs(tC.args)
+			err := cmd.Execute()
+
+			if len(tC.want) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var ferr *FlagError
+			if !errors.As(err, &ferr) {
+				t.Fatalf("expected a flag error, got %v", err)
+			}
+			if diff := cmp.Diff(tC.want, ferr.Violations); diff != "" {
+				t.Errorf("unexpected violations (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateFlags_ErrorText(t *testing.T) {
+	cmd := testCommand(&testFlags{}, MutuallyExclusive("in-place", "out"), Required("spec"))
+	cmd.SetArgs([]string{"--in-place", "--out", "-"})
+
+	err := cmd.Execute()
+	want := "2 invalid flag usage(s) found\n  - --in-place and --out are mutually exclusive\n  - --spec is required"
+	if err == nil || err.Error() != want {
+		t.Errorf("expected %q, got %v", want, err)
+	}
+}
+
+func TestValidateFlags_Interactive(t *testing.T) {
+	asked := withPrompt(t, true, map[string]string{
+		"Patch specification": "patch.yaml",
+		"Identity name":       "prod",
+	})
+
+	values := &testFlags{}
+	cmd := testCommand(values,
+		Prompted("spec", "Patch specification", false),
+		When("store", "keychain", Prompted("name", "Identity name", false)),
+	)
+	cmd.SetArgs([]string{"--store", "keychain"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if values.spec != "patch.yaml" || values.name != "prod" {
+		t.Errorf("prompted values not assigned: %+v", values)
+	}
+	if diff := cmp.Diff([]string{"Patch specification", "Identity name"}, *asked); diff != "" {
+		t.Errorf("unexpected prompts (-want +got):\n%s", diff)
+	}
+}
+
+func TestValidateFlags_InteractiveViolations(t *testing.T) {
+	asked := withPrompt(t, true, map[string]string{})
+
+	cmd := testCommand(&testFlags{},
+		Prompted("spec", "Patch specification", false),
+		MutuallyExclusive("in-place", "out"),
+	)
+	cmd.SetArgs([]string{"--in-place", "--out", "-"})
+
+	// Violations are reported before prompting
+	var ferr *FlagError
+	if err := cmd.Execute(); !errors.As(err, &ferr) {
+		t.Fatalf("expected a flag error, got %v", err)
+	}
+	if len(*asked) != 0 {
+		t.Errorf("unexpected prompts %v", *asked)
+	}
+
+	// Blank answer
+	cmd = testCommand(&testFlags{}, Prompted("spec", "Patch specification", false))
+	cmd.SetArgs([]string{})
+	err := cmd.Execute()
+	if !errors.As(err, &ferr) || ferr.Violations[0] != "--spec is required" {
+		t.Errorf("expected a missing flag error, got %v", err)
+	}
+}
+
+func TestValidateFlags_NotTerminal(t *testing.T) {
+	asked := withPrompt(t, false, map[string]string{"Patch specification": "patch.yaml"})
+
+	cmd := testCommand(&testFlags{}, Prompted("spec", "Patch specification", false))
+	cmd.SetArgs([]string{})
+
+	err := cmd.Execute()
+	var ferr *FlagError
+	if !errors.As(err, &ferr) {
+		t.Fatalf("expected a flag error, got %v", err)
+	}
+	if diff := cmp.Diff([]string{"--spec is required (not prompted, stdin is not a terminal)"}, ferr.Violations); diff != "" {
+		t.Errorf("unexpected violations (-want +got):\n%s", diff)
+	}
+	if len(*asked) != 0 {
+		t.Errorf("unexpected prompts %v", *asked)
+	}
+}
+
+func TestValidateFlags_PreRun(t *testing.T) {
+	called := false
+	cmd := &cobra.Command{
+		Use:    "test",
+		PreRun: func(*cobra.Command, []string) { called = true },
+		Run:    func(*cobra.Command, []string) {},
+	}
+	cmd.Flags().String("spec", "", "")
+	ValidateFlags(cmd, Required("spec"))
+
+	cmd.SetArgs([]string{"--spec", "patch.yaml"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !called {
+		t.Error("original PreRun hook must be called")
+	}
+}
@@ -0,0 +1,155 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmdutil
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/elastic/harp/pkg/sdk/fsutil"
+	"github.com/elastic/harp/pkg/sdk/sandbox"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// DefaultLockTimeout is the default wait time to acquire an in-place update
+// lock.
+const DefaultLockTimeout = 30 * time.Second
+
+// InPlace describes an in-place file update. The file is locked from the
+// creation until commit or close, and written content replaces the file
+// atomically on commit.
+type InPlace struct {
+	name   string
+	opts   []WriterOption
+	unlock func() error
+	tmp    *fsutil.AtomicFile
+}
+
+// NewInPlace prepares a locked in-place update of the given file. The lock is
+// acquired once when lockTimeout is zero.
+func NewInPlace(name string, lockTimeout time.Duration, opts ...WriterOption) (*InPlace, error) {
+	p, err := NewUnlockedInPlace(name, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Acquire the lock
+	WarnUnavailable(context.Background(), FeatureFileLocking, "concurrent updates are not detected")
+	unlock, err := fsutil.Lock(name, lockTimeout)
+	if err != nil {
+		return nil, err
+	}
+	p.unlock = unlock
+
+	// No error
+	return p, nil
+}
+
+// NewUnlockedInPlace prepares an in-place update of the given file without
+// locking it (read-only filesystems for lock files).
+func NewUnlockedInPlace(name string, opts ...WriterOption) (*InPlace, error) {
+	// Check arguments
+	if name == "" || name == "-" {
+		return nil, fmt.Errorf("in-place update requires a file path")
+	}
+
+	// Allow the file and its lock to be replaced
+	sandbox.AllowWrite(name)
+
+	// No error
+	return &InPlace{
+		name: name,
+		opts: opts,
+	}, nil
+}
+
+// Reader returns lazy evaluated reader of the current file content.
+func (p *InPlace) Reader() func(context.Context) (io.Reader, error) {
+	return FileReader(p.name)
+}
+
+// Writer returns lazy evaluated writer of the new file content.
+func (p *InPlace) Writer() func(context.Context) (io.Writer, error) {
+	return func(_ context.Context) (io.Writer, error) {
+		if p.tmp != nil {
+			return p.tmp, nil
+		}
+
+		// Create temporary file
+		tmp, err := fsutil.CreateAtomic(p.name, resolveWriterOptions(p.opts...).mode)
+		if err != nil {
+			return nil, err
+		}
+		p.tmp = tmp
+
+		// No error
+		return tmp, nil
+	}
+}
+
+// RunTask runs the task and commits its output. The lock is released and
+// uncommitted content discarded when the task fails.
+func (p *InPlace) RunTask(ctx context.Context, t tasks.Task) error {
+	defer p.Close()
+
+	// Run the task
+	if err := RunTask(ctx, t); err != nil {
+		return fmt.Errorf("unable to execute task: %w", err)
+	}
+
+	// Replace the file
+	if err := p.Commit(); err != nil {
+		return fmt.Errorf("unable to commit in-place update: %w", err)
+	}
+
+	// No error
+	return nil
+}
+
+// Commit replaces the file with written content and releases the lock.
+func (p *InPlace) Commit() error {
+	if p.tmp == nil {
+		return p.Close()
+	}
+
+	errCommit := p.tmp.Commit()
+	if err := p.Close(); err != nil && errCommit == nil {
+		return err
+	}
+
+	return errCommit
+}
+
+// Close discards uncommitted content and releases the lock.
+func (p *InPlace) Close() error {
+	if p.tmp != nil {
+		if err := p.tmp.Abort(); err != nil {
+			return err
+		}
+	}
+	if p.unlock != nil {
+		unlock := p.unlock
+		p.unlock = nil
+		return unlock()
+	}
+
+	// No error
+	return nil
+}
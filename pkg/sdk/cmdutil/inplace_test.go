@@ -0,0 +1,220 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmdutil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/sdk/fsutil"
+)
+
+const (
+	stressWriters   = 8
+	stressValueSize = 256 * 1024
+)
+
+// Test_InPlace_Writer is executed by Test_InPlace_Stress in a subprocess to
+// add a package to the shared container.
+func Test_InPlace_Writer(t *testing.T) {
+	target := os.Getenv("HARP_INPLACE_TARGET")
+	if target == "" {
+		t.Skip("only used as stress test subprocess")
+	}
+	id := os.Getenv("HARP_INPLACE_ID")
+
+	ip, err := NewInPlace(target, time.Minute)
+	if err != nil {
+		t.Fatalf("unable to prepare in-place update: %v", err)
+	}
+	defer ip.Close()
+
+	// Read current content
+	reader, err := ip.Reader()(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := bundle.FromContainerReader(reader)
+	if err != nil {
+		t.Fatalf("unable to load container: %v", err)
+	}
+	secrets, err := bundle.AsMap(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Add writer package
+	input := map[string]bundle.KV{}
+	for k, v := range secrets {
+		input[k] = v.(bundle.KV)
+	}
+	input[fmt.Sprintf("app/production/security/harp/v1.0.0/writer/%s", id)] = bundle.KV{
+		"value": string(bytes.Repeat([]byte(id), stressValueSize)),
+	}
+	out, err := bundle.FromMap(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Write and commit
+	writer, err := ip.Writer()(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bundle.ToContainerWriter(writer, out); err != nil {
+		t.Fatal(err)
+	}
+	if err := ip.Commit(); err != nil {
+		t.Fatalf("unable to commit: %v", err)
+	}
+}
+
+func Test_InPlace_Stress(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping stress test in short mode")
+	}
+
+	tmpDir, err := ioutil.TempDir("", "harp-inplace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Initialize an empty container
+	target := filepath.Join(tmpDir, "shared.container")
+	initial, err := bundle.FromMap(map[string]bundle.KV{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := bundle.ToContainerWriter(&buf, initial); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(target, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Spawn concurrent writers
+	cmds := []*exec.Cmd{}
+	outputs := []*bytes.Buffer{}
+	for i := 0; i < stressWriters; i++ {
+		var out bytes.Buffer
+		cmd := exec.Command(os.Args[0], "-test.run=^Test_InPlace_Writer$")
+		cmd.Env = append(os.Environ(), "HARP_INPLACE_TARGET="+target, "HARP_INPLACE_ID="+strconv.Itoa(i))
+		cmd.Stdout = &out
+		cmd.Stderr = &out
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		cmds = append(cmds, cmd)
+		outputs = append(outputs, &out)
+	}
+	for i, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Fatalf("writer %d failed: %v\n%s", i, err, outputs[i].String())
+		}
+	}
+
+	// Final container must parse and contain all writer results
+	content, err := ioutil.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := bundle.FromContainerReader(bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("unable to parse final container: %v", err)
+	}
+	secrets, err := bundle.AsMap(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secrets) != stressWriters {
+		t.Fatalf("expected %d packages, got %d", stressWriters, len(secrets))
+	}
+	for i := 0; i < stressWriters; i++ {
+		kv, ok := secrets[fmt.Sprintf("app/production/security/harp/v1.0.0/writer/%d", i)].(bundle.KV)
+		if !ok {
+			t.Fatalf("writer %d result not found", i)
+		}
+		if kv["value"] != string(bytes.Repeat([]byte(strconv.Itoa(i)), stressValueSize)) {
+			t.Errorf("writer %d value corrupted", i)
+		}
+	}
+}
+
+type failingTask struct{}
+
+func (failingTask) Run(context.Context) error {
+	return errors.New("task failed")
+}
+
+func Test_InPlace_Locking(t *testing.T) {
+	if !fsutil.LockSupported() {
+		t.Skip("file locking is not supported")
+	}
+
+	target := filepath.Join(t.TempDir(), "shared.container")
+	if err := ioutil.WriteFile(target, []byte("content"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Zero timeout tries once
+	ip, err := NewInPlace(target, 0)
+	if err != nil {
+		t.Fatalf("unable to acquire a free lock: %v", err)
+	}
+	if _, err := NewInPlace(target, 0); !errors.Is(err, fsutil.ErrLockTimeout) {
+		t.Fatalf("expected lock timeout, got %v", err)
+	}
+
+	// Unlocked update ignores the lock
+	unlocked, err := NewUnlockedInPlace(target)
+	if err != nil {
+		t.Fatalf("unable to prepare unlocked update: %v", err)
+	}
+	if err := unlocked.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Failed task releases the lock and keeps the file
+	if err := ip.RunTask(context.Background(), failingTask{}); err == nil {
+		t.Fatal("expected task error")
+	}
+	next, err := NewInPlace(target, 0)
+	if err != nil {
+		t.Fatalf("expected lock to be released: %v", err)
+	}
+	defer next.Close()
+	content, err := ioutil.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "content" {
+		t.Errorf("unexpected content %q", content)
+	}
+}
@@ -24,7 +24,11 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"time"
+
+	"github.com/elastic/harp/pkg/sdk/httpclient"
+	"github.com/elastic/harp/pkg/sdk/sandbox"
 )
 
 const (
@@ -34,8 +38,16 @@ const (
 	ReaderTimeout = 1 * time.Minute
 )
 
+var downloadOptions []httpclient.Option
+
+// SetDownloadOptions assigns the options used by readers to download remote
+// content.
+func SetDownloadOptions(opts ...httpclient.Option) {
+	downloadOptions = opts
+}
+
 // Reader creates a reader instance according to the given name value
-// Use "" or "-" for Stdin reader, else use a filename.
+// Use "" or "-" for Stdin reader, an URL for remote content, else use a filename.
 func Reader(name string) (io.Reader, error) {
 	var (
 		reader io.Reader
@@ -43,8 +55,15 @@ func Reader(name string) (io.Reader, error) {
 	)
 
 	// Create input reader
-	switch name {
-	case "", "-":
+	switch {
+	case strings.HasPrefix(name, "http://"), strings.HasPrefix(name, "https://"):
+		// Remote content is fully downloaded, it is not limited
+		reader, err = urlReader(name)
+		if err != nil {
+			return nil, fmt.Errorf("unable to download '%s': %w", name, err)
+		}
+		return reader, nil
+	case name == "", name == "-":
 		// Check stdin
 		info, errStat := os.Stdin.Stat()
 		if errStat != nil {
@@ -72,6 +91,41 @@ func Reader(name string) (io.Reader, error) {
 	return limitedReader, nil
 }
 
+func urlReader(url string) (io.Reader, error) {
+	// Download to temporary file
+	r, err := httpclient.Download(context.Background(), url, downloadOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Stream the temporary file, it is removed once consumed
+	return &autoCloseReader{rc: r}, nil
+}
+
+// autoCloseReader closes the wrapped reader when it returns an error or EOF,
+// readers are not closed by their consumers.
+type autoCloseReader struct {
+	rc     io.ReadCloser
+	closed bool
+}
+
+// Read implements io.Reader interface.
+func (r *autoCloseReader) Read(buf []byte) (int, error) {
+	if r.closed {
+		return 0, io.EOF
+	}
+
+	n, err := r.rc.Read(buf)
+	if err != nil {
+		r.closed = true
+		if errClose := r.rc.Close(); errClose != nil && errors.Is(err, io.EOF) {
+			return n, fmt.Errorf("unable to release downloaded content: %w", errClose)
+		}
+	}
+
+	return n, err
+}
+
 // LineReader creates a reder and returns content read line by line.
 func LineReader(name string) ([]string, error) {
 	out := []string{}
@@ -99,7 +153,7 @@ func LineReader(name string) ([]string, error) {
 
 // Writer creates a writer according to the given name value
 // Use "" or "-" for Stdout writer, else use a filename.
-func Writer(name string) (io.Writer, error) {
+func Writer(name string, opts ...WriterOption) (io.Writer, error) {
 	var (
 		writer io.Writer
 		err    error
@@ -112,9 +166,9 @@ func Writer(name string) (io.Writer, error) {
 		writer = os.Stdout
 	default:
 		// Open output file
-		writer, err = os.OpenFile(name, os.O_CREATE|os.O_WRONLY, 0o400)
+		writer, err = openFile(name, opts...)
 		if err != nil {
-			return nil, fmt.Errorf("unable to open '%s' for write: %w", name, err)
+			return nil, err
 		}
 	}
 
@@ -159,6 +213,8 @@ func (r *TimeoutReader) Read(buf []byte) (n int, err error) {
 
 // FileReader returns lazy evaluated reader.
 func FileReader(filename string) func(context.Context) (io.Reader, error) {
+	allowRead(filename)
+
 	return func(_ context.Context) (io.Reader, error) {
 		reader, err := Reader(filename)
 		if err != nil {
@@ -171,9 +227,11 @@ func FileReader(filename string) func(context.Context) (io.Reader, error) {
 }
 
 // FileWriter returns lazy evaluated writer.
-func FileWriter(filename string) func(context.Context) (io.Writer, error) {
+func FileWriter(filename string, opts ...WriterOption) func(context.Context) (io.Writer, error) {
+	sandbox.AllowWrite(filename)
+
 	return func(_ context.Context) (io.Writer, error) {
-		writer, err := Writer(filename)
+		writer, err := Writer(filename, opts...)
 		if err != nil {
 			return nil, fmt.Errorf("unable to open file '%s' for writing: %w", filename, err)
 		}
@@ -190,3 +248,11 @@ func StdoutWriter() func(context.Context) (io.Writer, error) {
 		return os.Stdout, nil
 	}
 }
+
+// StderrWriter returns lazy evaluated writer.
+func StderrWriter() func(context.Context) (io.Writer, error) {
+	return func(_ context.Context) (io.Writer, error) {
+		// No error
+		return os.Stderr, nil
+	}
+}
@@ -0,0 +1,63 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmdutil
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/elastic/harp/pkg/sdk/httpclient"
+)
+
+func Test_Reader_URL_Unlimited(t *testing.T) {
+	// Content larger than the reader limit
+	content := bytes.Repeat([]byte("x"), MaxReaderLimitSize+1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "container.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+
+	tmpDir := t.TempDir()
+	SetDownloadOptions(httpclient.WithTempDir(tmpDir))
+	defer SetDownloadOptions()
+
+	reader, err := Reader(srv.URL + "/container.bin")
+	if err != nil {
+		t.Fatalf("unable to open URL reader: %v", err)
+	}
+	got, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("unable to read downloaded content: %v", err)
+	}
+	if len(got) != len(content) {
+		t.Fatalf("expected %d bytes, got %d", len(content), len(got))
+	}
+
+	// Temporary file is removed once consumed
+	entries, err := ioutil.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected temporary file to be removed, found %d entries", len(entries))
+	}
+}
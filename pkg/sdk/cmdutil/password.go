@@ -18,16 +18,19 @@
 package cmdutil
 
 import (
+	"context"
 	"fmt"
-	"syscall"
+	"os"
 
 	"github.com/awnumar/memguard"
-	"golang.org/x/crypto/ssh/terminal"
 
 	"github.com/elastic/harp/pkg/sdk/security"
+	"github.com/elastic/harp/pkg/sdk/tty"
 )
 
-// ReadSecret reads password from Stdin and returns a lockedbuffer.
+// ReadSecret reads password from Stdin and returns a lockedbuffer. Input echo
+// can't be disabled on platforms without terminal support, a warning is
+// logged before the prompt.
 func ReadSecret(prompt string, confirmation bool) (*memguard.LockedBuffer, error) {
 	var (
 		err             error
@@ -37,10 +40,11 @@ func ReadSecret(prompt string, confirmation bool) (*memguard.LockedBuffer, error
 	defer memguard.WipeBytes(password)
 	defer memguard.WipeBytes(passwordConfirm)
 
+	WarnUnavailable(context.Background(), FeatureTTYPrompt, "typed secret will be visible")
+
 	// Ask to password
 	fmt.Printf("%s: ", prompt)
-	//nolint:unconvert // stdin doesn't share same type on each platform
-	password, err = terminal.ReadPassword(int(syscall.Stdin))
+	password, err = tty.ReadPassword(os.Stdin)
 	if err != nil {
 		return nil, fmt.Errorf("unable to read secret")
 	}
@@ -54,8 +58,7 @@ func ReadSecret(prompt string, confirmation bool) (*memguard.LockedBuffer, error
 	}
 
 	fmt.Printf("%s (confirmation): ", prompt)
-	//nolint:unconvert // stdin doesn't share same type on each platform
-	passwordConfirm, err = terminal.ReadPassword(int(syscall.Stdin))
+	passwordConfirm, err = tty.ReadPassword(os.Stdin)
 	if err != nil {
 		return nil, fmt.Errorf("unable to read secret confirmation")
 	}
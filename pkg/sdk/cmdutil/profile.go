@@ -0,0 +1,124 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmdutil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+	"time"
+
+	"github.com/gosimple/slug"
+)
+
+// Profiler records CPU and heap profiles of a command execution.
+type Profiler struct {
+	cpu      *os.File
+	cpuPath  string
+	heapPath string
+
+	once sync.Once
+	err  error
+}
+
+// StartProfile starts the CPU profiling of the named command. Profiles are
+// written in the given directory, named by command and start time.
+func StartProfile(dir, name string) (*Profiler, error) {
+	// Check arguments
+	if dir == "" {
+		return nil, errors.New("unable to start profiling with a blank output directory")
+	}
+
+	// Create output directory
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("unable to create profile directory: %w", err)
+	}
+
+	base := filepath.Join(dir, fmt.Sprintf("%s-%s", slug.Make(name), time.Now().UTC().Format("20060102T150405.000Z")))
+	p := &Profiler{
+		cpuPath:  base + ".cpu.pprof",
+		heapPath: base + ".heap.pprof",
+	}
+
+	// Start CPU profiling
+	f, err := os.OpenFile(p.cpuPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create CPU profile: %w", err)
+	}
+	if err := pprof.StartCPUProfile(f); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("unable to start CPU profiling: %w", err)
+	}
+	p.cpu = f
+
+	// No error
+	return p, nil
+}
+
+// Paths returns the CPU and heap profile paths.
+func (p *Profiler) Paths() []string {
+	if p == nil {
+		return nil
+	}
+	return []string{p.cpuPath, p.heapPath}
+}
+
+// Stop stops the CPU profiling and writes the heap profile. Only the first
+// call has an effect.
+func (p *Profiler) Stop() error {
+	if p == nil {
+		return nil
+	}
+
+	p.once.Do(func() {
+		p.err = p.stop()
+	})
+
+	return p.err
+}
+
+// -----------------------------------------------------------------------------
+
+func (p *Profiler) stop() error {
+	// Flush CPU profile
+	pprof.StopCPUProfile()
+	if err := p.cpu.Close(); err != nil {
+		return fmt.Errorf("unable to close CPU profile: %w", err)
+	}
+
+	// Write heap profile with up-to-date statistics
+	f, err := os.OpenFile(p.heapPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("unable to create heap profile: %w", err)
+	}
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("unable to write heap profile: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("unable to close heap profile: %w", err)
+	}
+
+	// No error
+	return nil
+}
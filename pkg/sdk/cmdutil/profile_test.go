@@ -0,0 +1,71 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmdutil
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStartProfile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "profiles")
+
+	p, err := StartProfile(dir, "harp bundle filter")
+	if err != nil {
+		t.Fatalf("unable to start profiling: %v", err)
+	}
+
+	// Burn some CPU
+	sum := 0
+	for i := 0; i < 1000000; i++ {
+		sum += i % 7
+	}
+	_ = sum
+
+	if err := p.Stop(); err != nil {
+		t.Fatalf("unable to stop profiling: %v", err)
+	}
+	if err := p.Stop(); err != nil {
+		t.Fatalf("second stop must be a no-op: %v", err)
+	}
+
+	paths := p.Paths()
+	if len(paths) != 2 {
+		t.Fatalf("expected 2 profiles, got %v", paths)
+	}
+	for _, path := range paths {
+		if filepath.Dir(path) != dir || !strings.HasPrefix(filepath.Base(path), "harp-bundle-filter-") {
+			t.Errorf("unexpected profile path '%s'", path)
+		}
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("profile must exist: %v", err)
+		}
+		if fi.Size() == 0 {
+			t.Errorf("profile '%s' must not be empty", path)
+		}
+	}
+
+	// Not started profiler
+	var none *Profiler
+	if err := none.Stop(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
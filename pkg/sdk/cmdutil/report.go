@@ -0,0 +1,57 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmdutil
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// ReportWriter returns the task report writer for the given path, nil when
+// the path is blank.
+func ReportWriter(path string) tasks.WriterProvider {
+	if path == "" {
+		return nil
+	}
+	return FileWriter(path)
+}
+
+// RunReportedTask runs the given task as RunTask does, then writes its
+// execution report when a report writer is given. The report is written even
+// when the task fails.
+func RunReportedTask(ctx context.Context, name string, t tasks.Task, reportWriter tasks.WriterProvider) error {
+	start := time.Now()
+	err := RunTask(ctx, t)
+
+	// Write execution report
+	if reportWriter != nil {
+		if errReport := tasks.WriteReport(ctx, reportWriter, tasks.NewReport(name, t, err, time.Since(start))); errReport != nil {
+			if err == nil {
+				return errReport
+			}
+			log.For(ctx).Error("unable to write task report", zap.Error(errReport))
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,96 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmdutil
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/sandbox"
+	"github.com/elastic/harp/pkg/sdk/security"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// RunTask runs the given task, restricted by the sandbox when enabled. Phase
+// durations traced by the task are logged at debug level. Sensitive memory is
+// wiped when the task panics, the panic is raised again with a scrubbed
+// message.
+func RunTask(ctx context.Context, t tasks.Task) (err error) {
+	if sandbox.Enabled() {
+		var caps *tasks.Capabilities
+		if d, ok := t.(tasks.CapabilitiesDeclarer); ok {
+			c := d.Capabilities()
+			caps = &c
+		}
+
+		if err := Sandbox(ctx, caps); err != nil {
+			return err
+		}
+	}
+
+	// Run the task
+	ctx, tracer := WithTracer(ctx)
+	security.Protect(func() {
+		err = t.Run(ctx)
+	})
+	tracer.Log(ctx, "Task timing", zap.String("task", fmt.Sprintf("%T", t)))
+
+	return err
+}
+
+// Sandbox restricts the process to declared paths. Process execution and
+// network are also restricted when capabilities are declared. Restrictions
+// are skipped with a warning when the platform doesn't support them.
+func Sandbox(ctx context.Context, caps *tasks.Capabilities) error {
+	if !sandbox.Enabled() {
+		return nil
+	}
+	if !WarnUnavailable(ctx, FeatureSandbox, "restrictions are not applied") {
+		return nil
+	}
+
+	local, network := false, false
+	if caps != nil {
+		local, network = true, caps.Network
+	}
+
+	// Apply restrictions
+	if err := sandbox.Apply(ctx, sandbox.Current(local, network)); err != nil {
+		return fmt.Errorf("unable to apply sandbox: %w", err)
+	}
+
+	// No error
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func allowRead(name string) {
+	switch {
+	case strings.HasPrefix(name, "http://"), strings.HasPrefix(name, "https://"):
+		// Remote content is downloaded to a temporary file
+		sandbox.RequireNetwork()
+		sandbox.AllowWriteDir(os.TempDir())
+	default:
+		sandbox.AllowRead(name)
+	}
+}
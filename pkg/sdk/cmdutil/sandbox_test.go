@@ -0,0 +1,60 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmdutil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/elastic/harp/pkg/sdk/security"
+)
+
+type panickingTask struct {
+	key []byte
+}
+
+func (pt *panickingTask) Run(ctx context.Context) error {
+	defer security.RegisterSensitive(pt.key)()
+	panic(errors.New("unable to open vault with token=" + string(pt.key)))
+}
+
+func TestRunTask_Panic(t *testing.T) {
+	task := &panickingTask{key: []byte("s.0123456789abcdefghijklmn")}
+
+	var recovered interface{}
+	func() {
+		defer func() {
+			recovered = recover()
+		}()
+		_ = RunTask(context.Background(), task)
+	}()
+
+	p, ok := recovered.(*security.SanitizedPanic)
+	if !ok {
+		t.Fatalf("expected a sanitized panic, got %T (%v)", recovered, recovered)
+	}
+	if !bytes.Equal(task.key, make([]byte, len(task.key))) {
+		t.Errorf("registered buffer is not zeroed: %q", task.key)
+	}
+	if strings.Contains(p.Error(), "0123456789") || !strings.Contains(p.Error(), "token=[REDACTED]") {
+		t.Errorf("panic message is not scrubbed: %q", p.Error())
+	}
+}
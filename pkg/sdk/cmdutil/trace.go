@@ -0,0 +1,100 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmdutil
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/log"
+)
+
+// Phase describes a timed execution phase.
+type Phase struct {
+	Name     string
+	Duration time.Duration
+}
+
+// Tracer records execution phase durations.
+type Tracer struct {
+	start time.Time
+
+	mu     sync.Mutex
+	phases []Phase
+}
+
+type tracerKey struct{}
+
+// WithTracer attaches a new phase tracer to the context.
+func WithTracer(ctx context.Context) (context.Context, *Tracer) {
+	t := &Tracer{start: time.Now()}
+	return context.WithValue(ctx, tracerKey{}, t), t
+}
+
+// TracePhase marks the start of the named phase and returns the function
+// marking its end. Durations of phases with the same name are summed. It
+// does nothing when the context has no tracer.
+func TracePhase(ctx context.Context, name string) func() {
+	t, ok := ctx.Value(tracerKey{}).(*Tracer)
+	if !ok {
+		return noopPhase
+	}
+
+	start := time.Now()
+	return func() {
+		t.add(name, time.Since(start))
+	}
+}
+
+// Phases returns recorded phases in their starting order.
+func (t *Tracer) Phases() []Phase {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]Phase(nil), t.phases...)
+}
+
+// Log writes the timing summary at debug level.
+func (t *Tracer) Log(ctx context.Context, msg string, fields ...zap.Field) {
+	fields = append(fields, zap.Duration("total", time.Since(t.start)))
+	for _, p := range t.Phases() {
+		fields = append(fields, zap.Duration("phase."+p.Name, p.Duration))
+	}
+
+	log.For(ctx).Debug(msg, fields...)
+}
+
+// -----------------------------------------------------------------------------
+
+func noopPhase() {}
+
+func (t *Tracer) add(name string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i := range t.phases {
+		if t.phases[i].Name == name {
+			t.phases[i].Duration += d
+			return
+		}
+	}
+	t.phases = append(t.phases, Phase{Name: name, Duration: d})
+}
@@ -0,0 +1,97 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmdutil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/elastic/harp/pkg/sdk/log"
+)
+
+type phasedTask struct{}
+
+func (phasedTask) Run(ctx context.Context) error {
+	for _, name := range []string{"load", "filter", "write", "filter"} {
+		done := TracePhase(ctx, name)
+		time.Sleep(time.Millisecond)
+		done()
+	}
+	return nil
+}
+
+func TestRunTask_Timing(t *testing.T) {
+	core, observed := observer.New(zapcore.DebugLevel)
+	ctx := log.WithLogger(context.Background(), zap.New(core))
+
+	if err := RunTask(ctx, phasedTask{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entries := observed.FilterMessage("Task timing").All()
+	if len(entries) != 1 {
+		t.Fatalf("expected a timing entry, got %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if fields["task"] != "cmdutil.phasedTask" {
+		t.Errorf("unexpected task name %v", fields["task"])
+	}
+	for _, name := range []string{"total", "phase.load", "phase.filter", "phase.write"} {
+		d, ok := fields[name].(time.Duration)
+		if !ok || d < time.Millisecond {
+			t.Errorf("expected '%s' duration, got %v", name, fields[name])
+		}
+	}
+	if d := fields["phase.filter"].(time.Duration); d < 2*time.Millisecond {
+		t.Errorf("repeated phase durations must be summed, got %v", d)
+	}
+}
+
+func TestTracePhase_Disabled(t *testing.T) {
+	ctx := context.Background()
+
+	// No tracer must not allocate
+	allocs := testing.AllocsPerRun(100, func() {
+		TracePhase(ctx, "load")()
+	})
+	if allocs != 0 {
+		t.Errorf("disabled tracing must not allocate, got %v allocations", allocs)
+	}
+}
+
+func BenchmarkTracePhase(b *testing.B) {
+	b.Run("disabled", func(b *testing.B) {
+		ctx := context.Background()
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			TracePhase(ctx, "load")()
+		}
+	})
+	b.Run("enabled", func(b *testing.B) {
+		ctx, _ := WithTracer(context.Background())
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			TracePhase(ctx, "load")()
+		}
+	})
+}
@@ -0,0 +1,268 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmdutil
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// UserConfigEnvVar overrides the user configuration file path.
+	UserConfigEnvVar = "HARP_USER_CONFIG"
+	// ProfileEnvVar selects the user configuration profile.
+	ProfileEnvVar = "HARP_PROFILE"
+)
+
+// FlagSource describes where an effective flag value comes from.
+type FlagSource string
+
+const (
+	// FlagSourceDefault is the flag default value.
+	FlagSourceDefault FlagSource = "default"
+	// FlagSourceProfile is a value from the selected user profile.
+	FlagSourceProfile FlagSource = "profile"
+	// FlagSourceEnv is a value from the environment.
+	FlagSourceEnv FlagSource = "env"
+	// FlagSourceFlag is a value given on the command line.
+	FlagSourceFlag FlagSource = "flag"
+)
+
+// UserConfig describes the user configuration file.
+type UserConfig struct {
+	Profiles map[string]FlagProfile `json:"profiles"`
+}
+
+// FlagProfile holds default flag values by command path ('bundle filter').
+type FlagProfile map[string]map[string]interface{}
+
+// EffectiveFlag describes a flag value and its provenance.
+type EffectiveFlag struct {
+	Name   string
+	Value  string
+	Source FlagSource
+	// Origin is the environment variable name or the profile name the value
+	// comes from.
+	Origin string
+}
+
+// UserConfigPath returns the user configuration file path,
+// '<user config dir>/harp/config.yaml' unless overridden by HARP_USER_CONFIG.
+func UserConfigPath() (string, error) {
+	if path := os.Getenv(UserConfigEnvVar); path != "" {
+		return path, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to locate user config directory: %w", err)
+	}
+	return filepath.Join(dir, "harp", "config.yaml"), nil
+}
+
+// LoadUserConfig reads the user configuration file, a missing file is an
+// empty configuration.
+func LoadUserConfig(path string) (*UserConfig, error) {
+	raw, err := ioutil.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		return &UserConfig{}, nil
+	case err != nil:
+		return nil, fmt.Errorf("unable to read user config '%s': %w", path, err)
+	default:
+	}
+
+	var cfg UserConfig
+	if err := yaml.UnmarshalStrict(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("unable to decode user config '%s': %w", path, err)
+	}
+
+	return &cfg, nil
+}
+
+// Profile returns the named profile, an empty name returns an empty profile.
+func (c *UserConfig) Profile(name string) (FlagProfile, error) {
+	if name == "" {
+		return FlagProfile{}, nil
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("user config profile '%s' not found", name)
+	}
+	if p == nil {
+		p = FlagProfile{}
+	}
+	return p, nil
+}
+
+// Check returns warnings for profile command paths which don't match a
+// command of the given tree.
+func (p FlagProfile) Check(root *cobra.Command) []string {
+	paths := make([]string, 0, len(p))
+	for path := range p {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	warnings := []string{}
+	for _, path := range paths {
+		target, _, err := root.Find(strings.Fields(path))
+		if err != nil || target == nil || CommandPath(target) != strings.Join(strings.Fields(path), " ") {
+			warnings = append(warnings, fmt.Sprintf("unknown command '%s' in profile", path))
+		}
+	}
+	return warnings
+}
+
+// CommandPath returns the command path without the root command name.
+func CommandPath(cmd *cobra.Command) string {
+	return strings.TrimSpace(strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()))
+}
+
+// FlagEnvVar returns the environment variable name providing the given flag
+// value of the command ('HARP_BUNDLE_FILTER_QUERY_VALUES').
+func FlagEnvVar(cmd *cobra.Command, name string) string {
+	parts := append([]string{cmd.Root().Name()}, strings.Fields(CommandPath(cmd))...)
+	parts = append(parts, name)
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(strings.Join(parts, "_")))
+}
+
+// ApplyFlagDefaults sets flags not given on the command line from the
+// environment, then from the given profile. The precedence is flag > env >
+// profile > default. It returns the effective flags sorted by name, and
+// warnings for profile keys which don't match a flag of the command.
+func ApplyFlagDefaults(cmd *cobra.Command, profileName string, profile FlagProfile, lookupEnv func(string) (string, bool)) ([]EffectiveFlag, []string, error) {
+	// Check arguments
+	if lookupEnv == nil {
+		lookupEnv = os.LookupEnv
+	}
+	flags := cmd.Flags()
+
+	// Check profile keys
+	path := CommandPath(cmd)
+	values := profile[path]
+	warnings := []string{}
+	for _, name := range sortedKeys(values) {
+		if flags.Lookup(name) == nil {
+			warnings = append(warnings, fmt.Sprintf("unknown flag '%s' for command '%s' in profile", name, path))
+		}
+	}
+
+	// Resolve all flags
+	res := []EffectiveFlag{}
+	var errs []string
+	flags.VisitAll(func(f *pflag.Flag) {
+		ef := EffectiveFlag{Name: f.Name, Source: FlagSourceDefault}
+		if f.Changed {
+			ef.Source = FlagSourceFlag
+		} else if env := FlagEnvVar(cmd, f.Name); env != "" {
+			if v, ok := lookupEnv(env); ok {
+				if err := flags.Set(f.Name, v); err != nil {
+					errs = append(errs, fmt.Sprintf("invalid %s value for --%s: %v", env, f.Name, err))
+				}
+				ef.Source, ef.Origin = FlagSourceEnv, env
+			} else if v, ok := values[f.Name]; ok {
+				if err := setFlagValue(flags, f.Name, v); err != nil {
+					errs = append(errs, fmt.Sprintf("invalid profile value for --%s: %v", f.Name, err))
+				}
+				ef.Source, ef.Origin = FlagSourceProfile, profileName
+			}
+		}
+		ef.Value = f.Value.String()
+		res = append(res, ef)
+	})
+	if len(errs) > 0 {
+		return nil, warnings, fmt.Errorf("unable to apply flag defaults: %s", strings.Join(errs, ", "))
+	}
+
+	// No error
+	return res, warnings, nil
+}
+
+// WriteEffectiveFlags writes the effective flags as a table with the
+// provenance of each value.
+func WriteEffectiveFlags(w io.Writer, flags []EffectiveFlag) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FLAG\tVALUE\tSOURCE")
+	for _, f := range flags {
+		source := string(f.Source)
+		if f.Origin != "" {
+			source = fmt.Sprintf("%s (%s)", f.Source, f.Origin)
+		}
+		value := f.Value
+		if value == "" {
+			value = "-"
+		}
+		fmt.Fprintf(tw, "--%s\t%s\t%s\n", f.Name, value, source)
+	}
+	return tw.Flush()
+}
+
+// -----------------------------------------------------------------------------
+
+func setFlagValue(flags *pflag.FlagSet, name string, value interface{}) error {
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if err := flags.Set(name, scalarString(item)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		for _, k := range sortedKeys(v) {
+			if err := flags.Set(name, fmt.Sprintf("%s=%s", k, scalarString(v[k]))); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return flags.Set(name, scalarString(v))
+	}
+}
+
+func scalarString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
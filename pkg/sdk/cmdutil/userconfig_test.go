@@ -0,0 +1,186 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmdutil
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/cobra"
+)
+
+type profileFlags struct {
+	queryValues bool
+	concurrency int
+	keep        []string
+	out         string
+}
+
+// profileCommands returns a 'harp to vault' command tree.
+func profileCommands(values *profileFlags) (root, leaf *cobra.Command) {
+	root = &cobra.Command{Use: "harp"}
+	root.PersistentFlags().String("mode", "0600", "")
+	to := &cobra.Command{Use: "to"}
+	leaf = &cobra.Command{Use: "vault", Run: func(*cobra.Command, []string) {}}
+	leaf.Flags().BoolVar(&values.queryValues, "query-values", false, "")
+	leaf.Flags().IntVar(&values.concurrency, "concurrency", 1, "")
+	leaf.Flags().StringSliceVar(&values.keep, "keep", nil, "")
+	leaf.Flags().StringVar(&values.out, "out", "-", "")
+	to.AddCommand(leaf)
+	root.AddCommand(to)
+	return root, leaf
+}
+
+func loadTestProfile(t *testing.T, content string) FlagProfile {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := ioutil.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadUserConfig(path)
+	if err != nil {
+		t.Fatalf("unable to load user config: %v", err)
+	}
+	p, err := cfg.Profile("work")
+	if err != nil {
+		t.Fatalf("unable to select profile: %v", err)
+	}
+	return p
+}
+
+const testUserConfig = `
+profiles:
+  work:
+    to vault:
+      query-values: true
+      concurrency: 8
+      keep: [app/production, infra]
+      out: profile.out
+      unknown: 1
+    to nope:
+      x: 1
+`
+
+func TestApplyFlagDefaults_Precedence(t *testing.T) {
+	profile := loadTestProfile(t, testUserConfig)
+
+	var values profileFlags
+	root, leaf := profileCommands(&values)
+	if err := leaf.ParseFlags([]string{"--out", "flag.out"}); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{
+		"HARP_TO_VAULT_CONCURRENCY": "4",
+		"HARP_TO_VAULT_OUT":         "env.out",
+	}
+	lookupEnv := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+
+	flags, warnings, err := ApplyFlagDefaults(leaf, "work", profile, lookupEnv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Flag > env > profile > default
+	want := []EffectiveFlag{
+		{Name: "concurrency", Value: "4", Source: FlagSourceEnv, Origin: "HARP_TO_VAULT_CONCURRENCY"},
+		{Name: "keep", Value: "[app/production,infra]", Source: FlagSourceProfile, Origin: "work"},
+		{Name: "mode", Value: "0600", Source: FlagSourceDefault},
+		{Name: "out", Value: "flag.out", Source: FlagSourceFlag},
+		{Name: "query-values", Value: "true", Source: FlagSourceProfile, Origin: "work"},
+	}
+	if diff := cmp.Diff(want, flags); diff != "" {
+		t.Errorf("unexpected effective flags (-want +got):\n%s", diff)
+	}
+	if values.concurrency != 4 || !values.queryValues || values.out != "flag.out" || len(values.keep) != 2 {
+		t.Errorf("flag values not applied: %+v", values)
+	}
+
+	// Unknown keys are reported
+	warnings = append(profile.Check(root), warnings...)
+	wantWarnings := []string{
+		"unknown command 'to nope' in profile",
+		"unknown flag 'unknown' for command 'to vault' in profile",
+	}
+	if diff := cmp.Diff(wantWarnings, warnings); diff != "" {
+		t.Errorf("unexpected warnings (-want +got):\n%s", diff)
+	}
+}
+
+func TestApplyFlagDefaults_InvalidValue(t *testing.T) {
+	profile := loadTestProfile(t, `
+profiles:
+  work:
+    to vault:
+      concurrency: many
+`)
+
+	var values profileFlags
+	_, leaf := profileCommands(&values)
+	if _, _, err := ApplyFlagDefaults(leaf, "work", profile, func(string) (string, bool) { return "", false }); err == nil || !strings.Contains(err.Error(), "--concurrency") {
+		t.Fatalf("expected invalid value error, got %v", err)
+	}
+}
+
+func TestUserConfig_Profile(t *testing.T) {
+	cfg, err := LoadUserConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatalf("missing user config must be empty: %v", err)
+	}
+	if p, err := cfg.Profile(""); err != nil || len(p) != 0 {
+		t.Errorf("expected empty profile, got %v (%v)", p, err)
+	}
+	if _, err := cfg.Profile("work"); err == nil {
+		t.Error("expected unknown profile error")
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := ioutil.WriteFile(path, []byte("profile:\n  work: {}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadUserConfig(path); err == nil {
+		t.Error("expected unknown top level key error")
+	}
+}
+
+func TestWriteEffectiveFlags(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteEffectiveFlags(&out, []EffectiveFlag{
+		{Name: "concurrency", Value: "8", Source: FlagSourceProfile, Origin: "work"},
+		{Name: "in", Value: "", Source: FlagSourceDefault},
+		{Name: "out", Value: "env.out", Source: FlagSourceEnv, Origin: "HARP_TO_VAULT_OUT"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `FLAG           VALUE    SOURCE
+--concurrency  8        profile (work)
+--in           -        default
+--out          env.out  env (HARP_TO_VAULT_OUT)
+`
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("unexpected output (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,159 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmdutil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// DefaultFileMode is the permission set used for created files. Files
+// produced by harp are likely to contain secrets, so they are only readable
+// by the owner.
+const DefaultFileMode os.FileMode = 0o600
+
+// ErrFileExists is raised when the output file already exists and overwrite
+// is disabled.
+var ErrFileExists = errors.New("output file already exists")
+
+type writerOptions struct {
+	mode        os.FileMode
+	uid         int
+	gid         int
+	noOverwrite bool
+}
+
+// WriterOption defines the functional pattern for file writer settings.
+type WriterOption func(*writerOptions)
+
+// WithFileMode sets the permissions of the created file.
+func WithFileMode(value os.FileMode) WriterOption {
+	return func(opts *writerOptions) {
+		opts.mode = value
+	}
+}
+
+// WithOwner changes the owner of the created file. It is only applied when
+// running as root, use -1 to keep the current value.
+func WithOwner(uid, gid int) WriterOption {
+	return func(opts *writerOptions) {
+		opts.uid = uid
+		opts.gid = gid
+	}
+}
+
+// WithNoOverwrite prevents existing files from being overwritten.
+func WithNoOverwrite(value bool) WriterOption {
+	return func(opts *writerOptions) {
+		opts.noOverwrite = value
+	}
+}
+
+var defaultWriterOptions []WriterOption
+
+// SetWriterOptions assigns the options used by default by file writers.
+func SetWriterOptions(opts ...WriterOption) {
+	defaultWriterOptions = opts
+}
+
+// ParseFileMode parses an octal file mode string (`0600`, `640`).
+func ParseFileMode(value string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid file mode '%s': %w", value, err)
+	}
+	if mode&^uint64(os.ModePerm) != 0 {
+		return 0, fmt.Errorf("invalid file mode '%s': only permission bits are supported", value)
+	}
+
+	return os.FileMode(mode), nil
+}
+
+// -----------------------------------------------------------------------------
+
+func resolveWriterOptions(opts ...WriterOption) *writerOptions {
+	// Default options
+	dopts := &writerOptions{
+		mode: DefaultFileMode,
+		uid:  -1,
+		gid:  -1,
+	}
+
+	// Apply options
+	for _, o := range defaultWriterOptions {
+		o(dopts)
+	}
+	for _, o := range opts {
+		o(dopts)
+	}
+
+	return dopts
+}
+
+// openFile creates the output file according to writer options.
+//
+// On Windows, the file mode only controls the read-only attribute and the
+// created file inherits the ACL of its parent directory; ownership change is
+// not supported.
+func openFile(name string, opts ...WriterOption) (*os.File, error) {
+	dopts := resolveWriterOptions(opts...)
+
+	// Prepare open flags
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if dopts.noOverwrite {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_EXCL
+	}
+
+	// Open output file
+	f, err := os.OpenFile(name, flags, dopts.mode)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("unable to open '%s' for write: %w", name, ErrFileExists)
+		}
+		return nil, fmt.Errorf("unable to open '%s' for write: %w", name, err)
+	}
+
+	// Devices, pipes and sockets are written as-is
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("unable to retrieve '%s' file information: %w", name, err)
+	}
+	if !info.Mode().IsRegular() {
+		return f, nil
+	}
+
+	// Enforce mode, umask and existing files could have altered it
+	if err := f.Chmod(dopts.mode); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("unable to set '%s' file mode: %w", name, err)
+	}
+
+	// Change ownership only when running as root
+	if (dopts.uid >= 0 || dopts.gid >= 0) && os.Geteuid() == 0 {
+		if err := f.Chown(dopts.uid, dopts.gid); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("unable to change '%s' file owner: %w", name, err)
+		}
+	}
+
+	// No error
+	return f, nil
+}
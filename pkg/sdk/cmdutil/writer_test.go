@@ -0,0 +1,154 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmdutil
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func Test_Writer_FileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not supported on windows")
+	}
+
+	tmpDir, err := ioutil.TempDir("", "harp-writer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	testCases := []struct {
+		desc string
+		opts []WriterOption
+		want os.FileMode
+	}{
+		{desc: "default", want: 0o600},
+		{desc: "custom", opts: []WriterOption{WithFileMode(0o640)}, want: 0o640},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			name := filepath.Join(tmpDir, tC.desc)
+
+			w, err := Writer(name, tC.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			w.(*os.File).Close()
+
+			fi, err := os.Stat(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fi.Mode().Perm() != tC.want {
+				t.Errorf("expected %o file mode, got %o", tC.want, fi.Mode().Perm())
+			}
+		})
+	}
+}
+
+func Test_Writer_Overwrite(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "harp-writer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	name := filepath.Join(tmpDir, "output")
+	if err := ioutil.WriteFile(name, []byte("previous content"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// No clobber
+	if _, err := Writer(name, WithNoOverwrite(true)); !errors.Is(err, ErrFileExists) {
+		t.Fatalf("expected file exists error, got %v", err)
+	}
+
+	// Overwrite must truncate previous content
+	w, err := Writer(name)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := w.Write([]byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	w.(*os.File).Close()
+
+	content, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "new" {
+		t.Errorf("unexpected content %q", content)
+	}
+}
+
+func Test_Writer_NonRegularFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not supported on windows")
+	}
+
+	before, err := os.Stat(os.DevNull)
+	if err != nil {
+		t.Skip("null device is not available")
+	}
+
+	w, err := Writer(os.DevNull, WithFileMode(0o600))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w.(*os.File).Close()
+
+	// Device mode must be preserved
+	after, err := os.Stat(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Mode() != before.Mode() {
+		t.Errorf("expected %v device mode, got %v", before.Mode(), after.Mode())
+	}
+}
+
+func Test_ParseFileMode(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		value   string
+		want    os.FileMode
+		wantErr bool
+	}{
+		{desc: "leading zero", value: "0600", want: 0o600},
+		{desc: "short", value: "640", want: 0o640},
+		{desc: "not octal", value: "0900", wantErr: true},
+		{desc: "special bits", value: "4755", wantErr: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			got, err := ParseFileMode(tC.value)
+			if (err != nil) != tC.wantErr {
+				t.Errorf("error during the call, error = %v, wantErr %v", err, tC.wantErr)
+			}
+			if got != tC.want {
+				t.Errorf("expected %o, got %o", tC.want, got)
+			}
+		})
+	}
+}
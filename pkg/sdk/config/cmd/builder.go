@@ -27,6 +27,7 @@ import (
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 
+	"github.com/elastic/harp/pkg/sdk/escape"
 	"github.com/elastic/harp/pkg/sdk/flags"
 	"github.com/elastic/harp/pkg/sdk/log"
 )
@@ -67,7 +68,11 @@ func NewConfigCommand(conf interface{}, envPrefix string) *cobra.Command {
 
 				sort.Strings(keys)
 				for _, k := range keys {
-					fmt.Printf("export %s=\"%s\"\n", k, m[k])
+					v, err := escape.ShellDoubleQuote(m[k])
+					if err != nil {
+						log.For(cmd.Context()).Fatal("Error during configuration export", zap.String("key", k), zap.Error(err))
+					}
+					fmt.Printf("export %s=%s\n", k, v)
 				}
 			}
 		},
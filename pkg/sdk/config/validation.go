@@ -0,0 +1,365 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	defaults "github.com/mcuadros/go-defaults"
+	toml "github.com/pelletier/go-toml"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// Problem describes a configuration validation error.
+type Problem struct {
+	Path    string
+	Line    int
+	Message string
+}
+
+// String returns the problem description with its location.
+func (p Problem) String() string {
+	switch {
+	case p.Line > 0 && p.Path != "":
+		return fmt.Sprintf("line %d: %s: %s", p.Line, p.Path, p.Message)
+	case p.Path != "":
+		return fmt.Sprintf("%s: %s", p.Path, p.Message)
+	default:
+		return p.Message
+	}
+}
+
+// ValidationError aggregates all configuration problems.
+type ValidationError struct {
+	File     string
+	Problems []Problem
+}
+
+// Error returns all problems as a single report.
+func (e *ValidationError) Error() string {
+	var sb strings.Builder
+	if e.File != "" {
+		fmt.Fprintf(&sb, "config: %d problem(s) found in '%s'", len(e.Problems), e.File)
+	} else {
+		fmt.Fprintf(&sb, "config: %d problem(s) found", len(e.Problems))
+	}
+	for _, p := range e.Problems {
+		fmt.Fprintf(&sb, "\n  - %s", p.String())
+	}
+	return sb.String()
+}
+
+// Report collects configuration problems.
+type Report struct {
+	doc      *Document
+	file     string
+	problems []Problem
+}
+
+// NewReport returns a problem report resolving line numbers from the given
+// document, which may be nil.
+func NewReport(file string, doc *Document) *Report {
+	return &Report{
+		doc:  doc,
+		file: file,
+	}
+}
+
+// Add a problem for the given configuration path. Missing keys are located
+// at their closest declared parent.
+func (r *Report) Add(path, format string, args ...interface{}) {
+	line := 0
+	for p := path; p != "" && line == 0; {
+		line = r.doc.Line(p)
+		if idx := strings.LastIndexAny(p, ".["); idx > 0 {
+			p = p[:idx]
+		} else {
+			p = ""
+		}
+	}
+
+	r.problems = append(r.problems, Problem{
+		Path:    path,
+		Line:    line,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// Merge problems into the report.
+func (r *Report) Merge(problems ...Problem) {
+	r.problems = append(r.problems, problems...)
+}
+
+// Err returns a ValidationError if problems have been reported.
+func (r *Report) Err() error {
+	if len(r.problems) == 0 {
+		return nil
+	}
+
+	// Sort by location
+	problems := append([]Problem(nil), r.problems...)
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Line < problems[j].Line
+	})
+
+	return &ValidationError{
+		File:     r.file,
+		Problems: problems,
+	}
+}
+
+// Decode applies defaults and the given file settings to conf, without
+// environment overrides, and returns the parsed document.
+func Decode(conf interface{}, cfgFile string) (*Document, error) {
+	// Parse document first to get syntax errors
+	doc, err := ParseFile(cfgFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply defaults
+	defaults.SetDefaults(conf)
+
+	// Apply file settings
+	v := viper.New()
+	v.SetConfigFile(cfgFile)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("config: unable to decode config file '%s': %w", cfgFile, err)
+	}
+	if err := v.Unmarshal(conf); err != nil {
+		return nil, fmt.Errorf("config: unable to apply config '%s': %w", cfgFile, err)
+	}
+
+	// No error
+	return doc, nil
+}
+
+// -----------------------------------------------------------------------------
+
+// Document holds the key tree of a configuration file with line numbers.
+type Document struct {
+	root *docNode
+}
+
+type docNode struct {
+	line   int
+	keys   []string
+	fields map[string]*docNode
+	items  []*docNode
+}
+
+// ParseFile parses the given YAML, JSON or TOML configuration file.
+func ParseFile(cfgFile string) (*Document, error) {
+	switch strings.ToLower(filepath.Ext(cfgFile)) {
+	case ".toml":
+		tree, err := toml.LoadFile(cfgFile)
+		if err != nil {
+			return nil, fmt.Errorf("config: unable to parse '%s': %w", cfgFile, err)
+		}
+		return &Document{root: fromTOML(tree)}, nil
+	case ".yaml", ".yml", ".json":
+		raw, err := ioutil.ReadFile(cfgFile)
+		if err != nil {
+			return nil, fmt.Errorf("config: unable to read '%s': %w", cfgFile, err)
+		}
+		var root yaml.Node
+		if err := yaml.Unmarshal(raw, &root); err != nil {
+			return nil, fmt.Errorf("config: unable to parse '%s': %w", cfgFile, err)
+		}
+		if len(root.Content) == 0 {
+			return &Document{root: &docNode{fields: map[string]*docNode{}}}, nil
+		}
+		return &Document{root: fromYAML(root.Content[0])}, nil
+	default:
+	}
+
+	return nil, fmt.Errorf("config: unsupported configuration file format '%s'", cfgFile)
+}
+
+// Line returns the line of the given configuration path (`A.b[1].c`), or 0
+// if unknown. Keys are matched case-insensitively.
+func (d *Document) Line(path string) int {
+	if d == nil || d.root == nil || path == "" {
+		return 0
+	}
+
+	node := d.root
+	for _, part := range strings.Split(path, ".") {
+		// Extract index suffixes
+		name := part
+		indexes := []int{}
+		if i := strings.Index(part, "["); i >= 0 {
+			name = part[:i]
+			for _, idx := range strings.Split(strings.TrimSuffix(part[i+1:], "]"), "][") {
+				var n int
+				if _, err := fmt.Sscanf(idx, "%d", &n); err != nil {
+					return 0
+				}
+				indexes = append(indexes, n)
+			}
+		}
+
+		node = node.field(name)
+		for _, idx := range indexes {
+			if node == nil || idx >= len(node.items) {
+				return 0
+			}
+			node = node.items[idx]
+		}
+		if node == nil {
+			return 0
+		}
+	}
+
+	return node.line
+}
+
+// UnknownFields reports document keys not declared by the given
+// configuration struct. Declared names are read from `toml` tags.
+func (d *Document) UnknownFields(conf interface{}) []Problem {
+	if d == nil || d.root == nil {
+		return nil
+	}
+
+	res := []Problem{}
+	checkFields(reflect.TypeOf(conf), d.root, "", &res)
+	return res
+}
+
+// -----------------------------------------------------------------------------
+
+func (n *docNode) field(name string) *docNode {
+	if n == nil {
+		return nil
+	}
+	for _, k := range n.keys {
+		if strings.EqualFold(k, name) {
+			return n.fields[k]
+		}
+	}
+	return nil
+}
+
+func checkFields(t reflect.Type, node *docNode, path string, res *[]Problem) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		for _, key := range node.keys {
+			child := node.fields[key]
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+
+			f, ok := lookupField(t, key)
+			if !ok {
+				*res = append(*res, Problem{
+					Path:    childPath,
+					Line:    child.line,
+					Message: "unknown field",
+				})
+				continue
+			}
+			checkFields(f.Type, child, childPath, res)
+		}
+	case reflect.Slice, reflect.Array:
+		for i, item := range node.items {
+			checkFields(t.Elem(), item, fmt.Sprintf("%s[%d]", path, i), res)
+		}
+	default:
+	}
+}
+
+func lookupField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("toml"), ",")[0]
+		if name == "" {
+			name = f.Name
+		}
+		if strings.EqualFold(name, key) || strings.EqualFold(f.Name, key) {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func fromYAML(n *yaml.Node) *docNode {
+	res := &docNode{
+		line:   n.Line,
+		fields: map[string]*docNode{},
+	}
+
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i]
+			child := fromYAML(n.Content[i+1])
+			child.line = key.Line
+			res.keys = append(res.keys, key.Value)
+			res.fields[key.Value] = child
+		}
+	case yaml.SequenceNode:
+		for _, item := range n.Content {
+			res.items = append(res.items, fromYAML(item))
+		}
+	case yaml.AliasNode:
+		if n.Alias != nil {
+			return fromYAML(n.Alias)
+		}
+	default:
+	}
+
+	return res
+}
+
+func fromTOML(tree *toml.Tree) *docNode {
+	res := &docNode{
+		line:   tree.Position().Line,
+		fields: map[string]*docNode{},
+	}
+
+	for _, key := range tree.Keys() {
+		var child *docNode
+		switch v := tree.GetPath([]string{key}).(type) {
+		case *toml.Tree:
+			child = fromTOML(v)
+		case []*toml.Tree:
+			child = &docNode{fields: map[string]*docNode{}}
+			for _, item := range v {
+				child.items = append(child.items, fromTOML(item))
+			}
+		default:
+			child = &docNode{fields: map[string]*docNode{}}
+		}
+		child.line = tree.GetPositionPath([]string{key}).Line
+		res.keys = append(res.keys, key)
+		res.fields[key] = child
+	}
+
+	return res
+}
@@ -0,0 +1,95 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type testConfig struct {
+	Debug struct {
+		Enable bool `toml:"enable"`
+	}
+	Items []struct {
+		Name string `toml:"name"`
+	} `toml:"items"`
+}
+
+func TestDocument(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		filename string
+		content  string
+		path     string
+		wantLine int
+		want     []Problem
+	}{
+		{
+			desc:     "yaml",
+			filename: "config.yaml",
+			content:  "debug:\n  enable: true\nitems:\n  - name: a\n  - name: b\n    label: c\n",
+			path:     "Items[1].name",
+			wantLine: 5,
+			want: []Problem{
+				{Path: "items[1].label", Line: 6, Message: "unknown field"},
+			},
+		},
+		{
+			desc:     "toml",
+			filename: "config.toml",
+			content:  "[Debug]\nenable = true\nverbose = true\n\n[[items]]\nname = \"a\"\n",
+			path:     "Debug.enable",
+			wantLine: 2,
+			want: []Problem{
+				{Path: "Debug.verbose", Line: 3, Message: "unknown field"},
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "harp-config")
+			if err != nil {
+				t.Fatalf("unable to create temporary directory: %v", err)
+			}
+			defer os.RemoveAll(dir)
+
+			path := filepath.Join(dir, tC.filename)
+			if err := ioutil.WriteFile(path, []byte(tC.content), 0o600); err != nil {
+				t.Fatalf("unable to write file: %v", err)
+			}
+
+			doc, err := ParseFile(path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := doc.Line(tC.path); got != tC.wantLine {
+				t.Errorf("Line(%q) = %d, want %d", tC.path, got, tC.wantLine)
+			}
+
+			got := doc.UnknownFields(&testConfig{})
+			if diff := cmp.Diff(got, tC.want); diff != "" {
+				t.Errorf("%q. UnknownFields():\n-got/+want\ndiff %s", tC.desc, diff)
+			}
+		})
+	}
+}
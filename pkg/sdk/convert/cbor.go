@@ -0,0 +1,118 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/ugorji/go/codec"
+)
+
+// CBORContentType is the CBOR media type.
+const CBORContentType = "application/cbor"
+
+// cborHandle returns a canonical CBOR handle, map keys are sorted so that the
+// same value always produces the same encoding. Maps are decoded with string
+// keys.
+func cborHandle() *codec.CborHandle {
+	h := &codec.CborHandle{}
+	h.Canonical = true
+	h.MapType = reflect.TypeOf(map[string]interface{}(nil))
+	return h
+}
+
+// ToCBOR encodes the given value as canonical CBOR.
+func ToCBOR(v interface{}) ([]byte, error) {
+	var out []byte
+	if err := codec.NewEncoderBytes(&out, cborHandle()).Encode(v); err != nil {
+		return nil, fmt.Errorf("unable to encode value as CBOR: %w", err)
+	}
+
+	// No error
+	return out, nil
+}
+
+// FromCBOR decodes the given canonical CBOR payload.
+func FromCBOR(in []byte, v interface{}) error {
+	if err := codec.NewDecoderBytes(in, cborHandle()).Decode(v); err != nil {
+		return fmt.Errorf("unable to decode CBOR payload: %w", err)
+	}
+	return nil
+}
+
+// JSONtoCBOR transcodes a JSON document to canonical CBOR. Integral numbers
+// are encoded as CBOR integers.
+func JSONtoCBOR(in []byte) ([]byte, error) {
+	// Decode JSON document
+	dec := json.NewDecoder(bytes.NewReader(in))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("unable to decode JSON document: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unable to decode JSON document: unexpected trailing content")
+	}
+
+	// Delegate to encoder
+	return ToCBOR(normalizeNumbers(v))
+}
+
+// ContentToCBOR encodes secret content as canonical CBOR. JSON documents are
+// transcoded with the same logical structure, other content is encoded as a
+// CBOR byte string.
+func ContentToCBOR(content []byte) ([]byte, error) {
+	if out, err := JSONtoCBOR(content); err == nil {
+		return out, nil
+	}
+
+	return ToCBOR(content)
+}
+
+// -----------------------------------------------------------------------------
+
+func normalizeNumbers(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		for k, item := range vv {
+			vv[k] = normalizeNumbers(item)
+		}
+		return vv
+	case []interface{}:
+		for i, item := range vv {
+			vv[i] = normalizeNumbers(item)
+		}
+		return vv
+	case json.Number:
+		if i, err := vv.Int64(); err == nil {
+			return i
+		}
+		if f, err := vv.Float64(); err == nil {
+			return f
+		}
+		return vv.String()
+	default:
+	}
+
+	return v
+}
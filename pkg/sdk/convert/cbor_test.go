@@ -0,0 +1,102 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestJSONtoCBOR(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		in      string
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			desc: "secret map",
+			in:   `{"user":"harp","port":5432,"ratio":0.5,"tags":["a","b"],"tls":{"enabled":true}}`,
+			want: map[string]interface{}{
+				"user":  "harp",
+				"port":  uint64(5432),
+				"ratio": 0.5,
+				"tags":  []interface{}{"a", "b"},
+				"tls":   map[string]interface{}{"enabled": true},
+			},
+		},
+		{desc: "negative integer", in: `-12`, want: int64(-12)},
+		{desc: "invalid", in: `{"user":`, wantErr: true},
+		{desc: "trailing content", in: `{} {}`, wantErr: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			out, err := JSONtoCBOR([]byte(tC.in))
+			if (err != nil) != tC.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tC.wantErr)
+			}
+			if tC.wantErr {
+				return
+			}
+
+			var got interface{}
+			if err := FromCBOR(out, &got); err != nil {
+				t.Fatalf("unable to decode CBOR: %v", err)
+			}
+			if diff := cmp.Diff(tC.want, got); diff != "" {
+				t.Errorf("%q. JSONtoCBOR():\n-want/+got\ndiff %s", tC.desc, diff)
+			}
+		})
+	}
+}
+
+func TestJSONtoCBOR_Deterministic(t *testing.T) {
+	a, err := JSONtoCBOR([]byte(`{"user":"harp","password":"foo","host":{"name":"db","port":5432}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := JSONtoCBOR([]byte(`{"host":{"port":5432,"name":"db"},"password":"foo","user":"harp"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a, b) {
+		t.Error("encoding must not depend on JSON key order")
+	}
+
+	// Same logical value as JSON
+	var fromCBOR, fromJSON interface{}
+	if err := FromCBOR(a, &fromCBOR); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := json.Marshal(fromCBOR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(raw, &fromCBOR); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`{"user":"harp","password":"foo","host":{"name":"db","port":5432}}`), &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(fromJSON, fromCBOR); diff != "" {
+		t.Errorf("logical value mismatch:\n-json/+cbor\ndiff %s", diff)
+	}
+}
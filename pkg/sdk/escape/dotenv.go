@@ -0,0 +1,95 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package escape
+
+import (
+	"strings"
+)
+
+// Dotenv returns the value as a double-quoted dotenv value.
+//
+// '\', '"', '$' and '`' are backslash escaped, newline, carriage return and
+// tab use their C escape (\n, \r, \t). Other control characters, NUL bytes and
+// invalid UTF-8 sequences are refused.
+//
+// The encoded value is inert when the file is sourced by a POSIX shell: no
+// expansion nor command substitution happens, and values without escaped
+// control characters are read unchanged.
+func Dotenv(value string) (string, error) {
+	if err := checkNUL(value); err != nil {
+		return "", err
+	}
+	if err := checkUTF8(value); err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(value) + 2)
+	sb.WriteByte('"')
+	for _, r := range value {
+		switch r {
+		case '\\', '"', '$', '`':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			if isControl(r) {
+				return "", ErrControlCharacter
+			}
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+
+	return sb.String(), nil
+}
+
+// DotenvKey checks the given variable name is a valid dotenv and shell
+// variable name, and returns it.
+func DotenvKey(key string) (string, error) {
+	if !isShellName(key) {
+		return "", errInvalidName(key)
+	}
+	return key, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func isControl(r rune) bool {
+	return r < 0x20 || r == 0x7f || (r >= 0x80 && r < 0xa0)
+}
+
+func isShellName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,138 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package escape
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+func TestDotenv(t *testing.T) {
+	testCases := []struct {
+		value   string
+		want    string
+		wantErr error
+	}{
+		{value: "", want: `""`},
+		{value: "line1\nline2", want: `"line1\nline2"`},
+		{value: "`id`", want: "\"\\`id\\`\""},
+		{value: "$(touch pwned)", want: `"\$(touch pwned)"`},
+		{value: `a\b"c`, want: `"a\\b\"c"`},
+		{value: "caf\xc3\xa9", want: "\"caf\xc3\xa9\""},
+		{value: "bell\a", wantErr: ErrControlCharacter},
+		{value: "a\x00b", wantErr: ErrNULByte},
+		{value: "non\xffutf8", wantErr: ErrInvalidUTF8},
+	}
+	for _, tC := range testCases {
+		got, err := Dotenv(tC.value)
+		if !errors.Is(err, tC.wantErr) {
+			t.Errorf("Dotenv(%q) error = %v, want %v", tC.value, err, tC.wantErr)
+			continue
+		}
+		if got != tC.want {
+			t.Errorf("Dotenv(%q) = %s, want %s", tC.value, got, tC.want)
+		}
+	}
+}
+
+func TestDotenvKey(t *testing.T) {
+	for _, key := range []string{"A", "_a1", "DB_PASSWORD"} {
+		if _, err := DotenvKey(key); err != nil {
+			t.Errorf("DotenvKey(%q) unexpected error: %v", key, err)
+		}
+	}
+	for _, key := range []string{"", "1A", "A-B", "A B", "A=B", "$(id)"} {
+		if _, err := DotenvKey(key); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("DotenvKey(%q) expected ErrInvalidName, got %v", key, err)
+		}
+	}
+}
+
+// sourceDotenv sources the given dotenv content with a POSIX shell and
+// returns the VALUE variable.
+func sourceDotenv(t *testing.T, shell, dir, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, "test.env")
+	if err := ioutil.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(shell, "-c", `. ./test.env && printf '%s' "$VALUE"`)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("unable to source %q: %v", content, err)
+	}
+	return string(out)
+}
+
+func TestDotenv_ShellSource(t *testing.T) {
+	shell := sh(t)
+	dir := t.TempDir()
+
+	for _, v := range knownBad {
+		encoded, err := Dotenv(v)
+		if err != nil {
+			t.Fatalf("unable to encode %q: %v", v, err)
+		}
+
+		got := sourceDotenv(t, shell, dir, fmt.Sprintf("VALUE=%s\n", encoded))
+
+		// Escaped control characters are kept as is by the shell
+		want := strings.NewReplacer("\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(v)
+		if got != want {
+			t.Errorf("encoded %q sourced as %q", v, got)
+		}
+	}
+
+	// Nothing must have been executed
+	if _, err := os.Stat(filepath.Join(dir, "pwned")); !os.IsNotExist(err) {
+		t.Fatal("command substitution has been executed")
+	}
+}
+
+func TestDotenv_RoundTrip(t *testing.T) {
+	shell := sh(t)
+	dir := t.TempDir()
+
+	f := func(v string) bool {
+		// Control characters are either refused or escaped
+		v = strings.Map(func(r rune) rune {
+			if isControl(r) {
+				return -1
+			}
+			return r
+		}, v)
+
+		encoded, err := Dotenv(v)
+		if err != nil {
+			return false
+		}
+		return sourceDotenv(t, shell, dir, fmt.Sprintf("VALUE=%s\n", encoded)) == v
+	}
+	if err := quick.Check(f, &quick.Config{MaxCount: 50}); err != nil {
+		t.Error(err)
+	}
+}
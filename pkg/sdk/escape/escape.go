@@ -0,0 +1,66 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package escape provides value encoders for exported file formats.
+//
+// Encoded values are safe to embed in their target context: they are decoded
+// as the original value by format parsers, and never trigger expansion or
+// command execution when the file is interpreted by a shell.
+package escape
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+var (
+	// ErrNULByte is raised when the value contains a NUL byte which can't be
+	// represented in the target format.
+	ErrNULByte = errors.New("escape: value contains a NUL byte")
+	// ErrInvalidUTF8 is raised when the target format requires a valid UTF-8
+	// value.
+	ErrInvalidUTF8 = errors.New("escape: value is not valid UTF-8")
+	// ErrControlCharacter is raised when the value contains a control
+	// character which can't be represented in the target format.
+	ErrControlCharacter = errors.New("escape: value contains a control character")
+	// ErrInvalidName is raised when a key can't be used as a name in the
+	// target format.
+	ErrInvalidName = errors.New("escape: invalid name")
+)
+
+// -----------------------------------------------------------------------------
+
+func checkNUL(value string) error {
+	for i := 0; i < len(value); i++ {
+		if value[i] == 0 {
+			return fmt.Errorf("%w at offset %d", ErrNULByte, i)
+		}
+	}
+	return nil
+}
+
+func errInvalidName(name string) error {
+	return fmt.Errorf("%w '%s'", ErrInvalidName, name)
+}
+
+func checkUTF8(value string) error {
+	if !utf8.ValidString(value) {
+		return ErrInvalidUTF8
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package escape
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// PropertiesValue returns the value encoded as a Java properties value.
+//
+// The encoding matches java.util.Properties.store: the output is ASCII only,
+// characters outside of the printable ASCII range use unicode escapes
+// (\uXXXX, as UTF-16 surrogate pairs when needed), and separators, comment
+// markers and a leading space are escaped. Invalid UTF-8 is refused.
+func PropertiesValue(value string) (string, error) {
+	return encodeProperties(value, false)
+}
+
+// PropertiesKey returns the key encoded as a Java properties key, spaces are
+// escaped everywhere.
+func PropertiesKey(key string) (string, error) {
+	if key == "" {
+		return "", errInvalidName(key)
+	}
+	return encodeProperties(key, true)
+}
+
+// -----------------------------------------------------------------------------
+
+func encodeProperties(value string, key bool) (string, error) {
+	if err := checkUTF8(value); err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(value))
+	for i, r := range value {
+		switch r {
+		case ' ':
+			if key || i == 0 {
+				sb.WriteByte('\\')
+			}
+			sb.WriteByte(' ')
+		case '\\', '=', ':', '#', '!':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\f':
+			sb.WriteString(`\f`)
+		default:
+			if r >= 0x20 && r <= 0x7e {
+				sb.WriteRune(r)
+				continue
+			}
+			if r1, r2 := utf16.EncodeRune(r); r1 != unicode.ReplacementChar {
+				fmt.Fprintf(&sb, `\u%04X\u%04X`, r1, r2)
+				continue
+			}
+			fmt.Fprintf(&sb, `\u%04X`, r)
+		}
+	}
+
+	return sb.String(), nil
+}
@@ -0,0 +1,132 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package escape
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/magiconair/properties"
+)
+
+// loadProperties parses the given key and value with a reference parser.
+func loadProperties(t *testing.T, key, value string) (string, string) {
+	t.Helper()
+
+	l := &properties.Loader{Encoding: properties.UTF8, DisableExpansion: true}
+	p, err := l.LoadBytes([]byte(fmt.Sprintf("%s=%s\n", key, value)))
+	if err != nil {
+		t.Fatalf("unable to parse properties: %v", err)
+	}
+	keys := p.Keys()
+	if len(keys) != 1 {
+		t.Fatalf("expected one property, got %v", keys)
+	}
+	v, _ := p.Get(keys[0])
+	return keys[0], v
+}
+
+func TestProperties(t *testing.T) {
+	testCases := []struct {
+		value   string
+		want    string
+		wantErr error
+	}{
+		{value: "", want: ``},
+		{value: " leading", want: `\ leading`},
+		{value: "inner space", want: `inner space`},
+		{value: "a=b:c", want: `a\=b\:c`},
+		{value: "#!", want: `\#\!`},
+		{value: "line1\nline2", want: `line1\nline2`},
+		{value: `C:\path`, want: `C\:\\path`},
+		{value: "caf\xc3\xa9", want: `caf\u00E9`},
+		{value: "\xf0\x9f\x94\x91", want: `\uD83D\uDD11`},
+		{value: "a\x00b", want: `a\u0000b`},
+		{value: "non\xffutf8", wantErr: ErrInvalidUTF8},
+	}
+	for _, tC := range testCases {
+		got, err := PropertiesValue(tC.value)
+		if !errors.Is(err, tC.wantErr) {
+			t.Errorf("PropertiesValue(%q) error = %v, want %v", tC.value, err, tC.wantErr)
+			continue
+		}
+		if got != tC.want {
+			t.Errorf("PropertiesValue(%q) = %s, want %s", tC.value, got, tC.want)
+		}
+	}
+
+	if got, _ := PropertiesKey("db url"); got != `db\ url` {
+		t.Errorf("unexpected key %s", got)
+	}
+	if _, err := PropertiesKey(""); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("expected ErrInvalidName, got %v", err)
+	}
+}
+
+func TestProperties_Fixtures(t *testing.T) {
+	for _, v := range knownBad {
+		// The reference parser doesn't decode surrogate pairs
+		if v == "\xf0\x9f\x94\x91" {
+			continue
+		}
+
+		key, err := PropertiesKey("key " + v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		value, err := PropertiesValue(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotKey, gotValue := loadProperties(t, key, value)
+		if gotKey != "key "+v || gotValue != v {
+			t.Errorf("encoded %q parsed as %q=%q", v, gotKey, gotValue)
+		}
+	}
+}
+
+func TestProperties_RoundTrip(t *testing.T) {
+	f := func(k, v string) bool {
+		// The reference parser doesn't decode surrogate pairs
+		bmp := func(r rune) rune {
+			if r > 0xffff {
+				return -1
+			}
+			return r
+		}
+		k = "k" + strings.Map(bmp, k)
+		v = strings.Map(bmp, v)
+
+		key, err := PropertiesKey(k)
+		if err != nil {
+			return false
+		}
+		value, err := PropertiesValue(v)
+		if err != nil {
+			return false
+		}
+		gotKey, gotValue := loadProperties(t, key, value)
+		return gotKey == k && gotValue == v
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,58 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package escape
+
+import (
+	"strings"
+)
+
+// ShellSingleQuote returns the value as a POSIX shell single-quoted word.
+// Single quotes are closed, escaped and reopened, no other character is
+// interpreted. Any byte except NUL is supported.
+func ShellSingleQuote(value string) (string, error) {
+	if err := checkNUL(value); err != nil {
+		return "", err
+	}
+
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'", nil
+}
+
+// ShellDoubleQuote returns the value as a POSIX shell double-quoted word.
+// Characters keeping a special meaning within double quotes ('$', '`', '"'
+// and '\') are escaped. Any byte except NUL is supported.
+func ShellDoubleQuote(value string) (string, error) {
+	if err := checkNUL(value); err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(value) + 2)
+	sb.WriteByte('"')
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '$', '`', '"', '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	sb.WriteByte('"')
+
+	return sb.String(), nil
+}
@@ -0,0 +1,118 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package escape
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"testing"
+	"testing/quick"
+)
+
+// knownBad are values known to break naive exporters.
+var knownBad = []string{
+	"",
+	"simple",
+	"with space",
+	"line1\nline2",
+	"carriage\r\nreturn",
+	"tab\tseparated",
+	"`id`",
+	"$(touch pwned)",
+	"${HOME}",
+	"$HOME",
+	`back\slash\`,
+	`\n`,
+	`it's`,
+	`say "hello"`,
+	"'\"'\"'",
+	"#comment",
+	"!bang",
+	"key=value",
+	"key:value",
+	" leading space",
+	"trailing space ",
+	"%i specifier",
+	"caf\xc3\xa9",
+	"\xf0\x9f\x94\x91",
+}
+
+func sh(t *testing.T) string {
+	t.Helper()
+
+	path, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no POSIX shell available")
+	}
+	return path
+}
+
+// shellEval returns the value of the given shell word as seen by the shell.
+func shellEval(t *testing.T, shell, word string) string {
+	t.Helper()
+
+	out, err := exec.Command(shell, "-c", "printf '%s' "+word).Output()
+	if err != nil {
+		t.Fatalf("unable to evaluate %q: %v", word, err)
+	}
+	return string(out)
+}
+
+func TestShellQuote_Fixtures(t *testing.T) {
+	shell := sh(t)
+	values := append([]string{"non\xffutf8\xc3"}, knownBad...)
+
+	for _, encode := range []func(string) (string, error){ShellSingleQuote, ShellDoubleQuote} {
+		for _, v := range values {
+			word, err := encode(v)
+			if err != nil {
+				t.Fatalf("unable to quote %q: %v", v, err)
+			}
+			if got := shellEval(t, shell, word); got != v {
+				t.Errorf("quoted %q evaluates to %q", v, got)
+			}
+		}
+	}
+}
+
+func TestShellQuote_RoundTrip(t *testing.T) {
+	shell := sh(t)
+
+	for _, encode := range []func(string) (string, error){ShellSingleQuote, ShellDoubleQuote} {
+		f := func(raw []byte) bool {
+			v := string(bytes.ReplaceAll(raw, []byte{0}, nil))
+			word, err := encode(v)
+			if err != nil {
+				return false
+			}
+			return shellEval(t, shell, word) == v
+		}
+		if err := quick.Check(f, &quick.Config{MaxCount: 50}); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestShellQuote_NUL(t *testing.T) {
+	for _, encode := range []func(string) (string, error){ShellSingleQuote, ShellDoubleQuote} {
+		if _, err := encode("a\x00b"); !errors.Is(err, ErrNULByte) {
+			t.Errorf("expected ErrNULByte, got %v", err)
+		}
+	}
+}
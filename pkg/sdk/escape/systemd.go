@@ -0,0 +1,98 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package escape
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SystemdNameMax is the maximum credential name length (NAME_MAX).
+const SystemdNameMax = 255
+
+// ValidateSystemdCredentialName checks systemd credential name restrictions:
+// a valid file name, made of printable ASCII characters without ':'.
+func ValidateSystemdCredentialName(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("credential name must not be blank")
+	case name == "." || name == "..":
+		return fmt.Errorf("credential name '%s' is reserved", name)
+	case len(name) > SystemdNameMax:
+		return fmt.Errorf("credential name must not exceed %d characters", SystemdNameMax)
+	}
+	for _, r := range name {
+		if r < ' ' || r > '~' || r == ':' || r == '/' {
+			return fmt.Errorf("credential name '%s' contains invalid character %q", name, r)
+		}
+	}
+	return nil
+}
+
+// SystemdCredentialName converts the given key to a valid credential name.
+// Characters other than letters, digits, '.', '-' and '_' are replaced by '_'.
+func SystemdCredentialName(key string) string {
+	var sb strings.Builder
+	for _, r := range key {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_' {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteRune('_')
+		}
+	}
+
+	name := sb.String()
+	if len(name) > SystemdNameMax {
+		name = name[:SystemdNameMax]
+	}
+	if name == "" || name == "." || name == ".." {
+		name = strings.Repeat("_", len(name)+1)
+	}
+
+	return name
+}
+
+// SystemdCredentialID returns the credential name escaped for the ID part of
+// a LoadCredential= setting. The name is validated, '%' specifier markers and
+// '\' word escapes are doubled.
+func SystemdCredentialID(name string) (string, error) {
+	if err := ValidateSystemdCredentialName(name); err != nil {
+		return "", err
+	}
+
+	return strings.NewReplacer(`%`, `%%`, `\`, `\\`).Replace(name), nil
+}
+
+// SystemdUnitValue returns the value escaped for a unit file setting value
+// subject to specifier expansion: '%' is doubled. Control characters,
+// invalid UTF-8 and a trailing '\' (line continuation) are refused.
+func SystemdUnitValue(value string) (string, error) {
+	if err := checkUTF8(value); err != nil {
+		return "", err
+	}
+	for _, r := range value {
+		if isControl(r) {
+			return "", ErrControlCharacter
+		}
+	}
+	if strings.HasSuffix(value, `\`) {
+		return "", fmt.Errorf("escape: value must not end with a line continuation")
+	}
+
+	return strings.ReplaceAll(value, "%", "%%"), nil
+}
@@ -0,0 +1,88 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package escape
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSystemdCredentialID(t *testing.T) {
+	testCases := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "db_password", want: "db_password"},
+		{name: "100%", want: "100%%"},
+		{name: `a\b`, want: `a\\b`},
+		{name: "", wantErr: true},
+		{name: "..", wantErr: true},
+		{name: "a:b", wantErr: true},
+		{name: "a/b", wantErr: true},
+		{name: "a\nb", wantErr: true},
+		{name: strings.Repeat("a", SystemdNameMax+1), wantErr: true},
+	}
+	for _, tC := range testCases {
+		got, err := SystemdCredentialID(tC.name)
+		if (err != nil) != tC.wantErr {
+			t.Errorf("SystemdCredentialID(%q) error = %v, wantErr %v", tC.name, err, tC.wantErr)
+			continue
+		}
+		if got != tC.want {
+			t.Errorf("SystemdCredentialID(%q) = %q, want %q", tC.name, got, tC.want)
+		}
+	}
+}
+
+func TestSystemdCredentialName(t *testing.T) {
+	for _, v := range append([]string{"non\xffutf8", ".", strings.Repeat("é", SystemdNameMax)}, knownBad...) {
+		if err := ValidateSystemdCredentialName(SystemdCredentialName(v)); err != nil {
+			t.Errorf("sanitized %q is invalid: %v", v, err)
+		}
+	}
+}
+
+func TestSystemdUnitValue(t *testing.T) {
+	testCases := []struct {
+		value   string
+		want    string
+		wantErr error
+	}{
+		{value: "/run/creds/%i/token", want: "/run/creds/%%i/token"},
+		{value: "/with space/$(id)", want: "/with space/$(id)"},
+		{value: "line1\nline2", wantErr: ErrControlCharacter},
+		{value: "a\x00b", wantErr: ErrControlCharacter},
+		{value: "non\xffutf8", wantErr: ErrInvalidUTF8},
+	}
+	for _, tC := range testCases {
+		got, err := SystemdUnitValue(tC.value)
+		if !errors.Is(err, tC.wantErr) {
+			t.Errorf("SystemdUnitValue(%q) error = %v, want %v", tC.value, err, tC.wantErr)
+			continue
+		}
+		if got != tC.want {
+			t.Errorf("SystemdUnitValue(%q) = %q, want %q", tC.value, got, tC.want)
+		}
+	}
+
+	if _, err := SystemdUnitValue(`continued\`); err == nil {
+		t.Error("expected line continuation to be refused")
+	}
+}
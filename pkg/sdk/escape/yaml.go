@@ -0,0 +1,76 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package escape
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// YAMLScalar returns the value as a YAML double-quoted scalar. Non printable
+// characters use YAML escapes, so that the scalar fits on a single line.
+// Invalid UTF-8 is refused.
+func YAMLScalar(value string) (string, error) {
+	if err := checkUTF8(value); err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(value) + 2)
+	sb.WriteByte('"')
+	for _, r := range value {
+		switch r {
+		case '\\', '"':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case 0:
+			sb.WriteString(`\0`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\u0085':
+			sb.WriteString(`\N`)
+		case '\u00A0':
+			sb.WriteString(`\_`)
+		case '\u2028':
+			sb.WriteString(`\L`)
+		case '\u2029':
+			sb.WriteString(`\P`)
+		default:
+			switch {
+			case r < 0x80 && !isControl(r):
+				sb.WriteRune(r)
+			case r >= 0x80 && unicode.IsPrint(r) && r != '\uFEFF':
+				sb.WriteRune(r)
+			case r <= 0xff:
+				fmt.Fprintf(&sb, `\x%02X`, r)
+			case r <= 0xffff:
+				fmt.Fprintf(&sb, `\u%04X`, r)
+			default:
+				fmt.Fprintf(&sb, `\U%08X`, r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+
+	return sb.String(), nil
+}
@@ -0,0 +1,88 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package escape
+
+import (
+	"errors"
+	"testing"
+	"testing/quick"
+
+	"gopkg.in/yaml.v3"
+)
+
+func yamlDecode(t *testing.T, scalar string) string {
+	t.Helper()
+
+	var out struct {
+		Value string `yaml:"value"`
+	}
+	if err := yaml.Unmarshal([]byte("value: "+scalar+"\n"), &out); err != nil {
+		t.Fatalf("unable to decode %s: %v", scalar, err)
+	}
+	return out.Value
+}
+
+func TestYAMLScalar(t *testing.T) {
+	testCases := []struct {
+		value   string
+		want    string
+		wantErr error
+	}{
+		{value: "", want: `""`},
+		{value: "yes", want: `"yes"`},
+		{value: "line1\nline2", want: `"line1\nline2"`},
+		{value: `a\b"c`, want: `"a\\b\"c"`},
+		{value: "a\x00b\x1b", want: `"a\0b\x1B"`},
+		{value: "caf\xc3\xa9", want: "\"caf\xc3\xa9\""},
+		{value: "\u2028", want: `"\L"`},
+		{value: "non\xffutf8", wantErr: ErrInvalidUTF8},
+	}
+	for _, tC := range testCases {
+		got, err := YAMLScalar(tC.value)
+		if !errors.Is(err, tC.wantErr) {
+			t.Errorf("YAMLScalar(%q) error = %v, want %v", tC.value, err, tC.wantErr)
+			continue
+		}
+		if got != tC.want {
+			t.Errorf("YAMLScalar(%q) = %s, want %s", tC.value, got, tC.want)
+		}
+	}
+}
+
+func TestYAMLScalar_RoundTrip(t *testing.T) {
+	for _, v := range knownBad {
+		scalar, err := YAMLScalar(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := yamlDecode(t, scalar); got != v {
+			t.Errorf("encoded %q decoded as %q", v, got)
+		}
+	}
+
+	f := func(v string) bool {
+		scalar, err := YAMLScalar(v)
+		if err != nil {
+			return false
+		}
+		return yamlDecode(t, scalar) == v
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,116 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package fsutil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// AtomicFile is a temporary file which replaces the target file on commit.
+type AtomicFile struct {
+	*os.File
+	target string
+	done   bool
+}
+
+// CreateAtomic creates a temporary file in the target directory, so that the
+// final rename stays on the same filesystem.
+func CreateAtomic(target string, mode os.FileMode) (*AtomicFile, error) {
+	dir, base := filepath.Split(target)
+	if dir == "" {
+		dir = "."
+	}
+
+	// Create the temporary file
+	f, err := ioutil.TempFile(dir, fmt.Sprintf(".%s.tmp-*", base))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create temporary file for '%s': %w", target, err)
+	}
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, fmt.Errorf("unable to set temporary file mode: %w", err)
+	}
+
+	// No error
+	return &AtomicFile{
+		File:   f,
+		target: target,
+	}, nil
+}
+
+// Commit flushes the written content and replaces the target file.
+func (f *AtomicFile) Commit() error {
+	if f.done {
+		return fmt.Errorf("atomic file already closed")
+	}
+	f.done = true
+
+	// Flush content to disk
+	if err := f.File.Sync(); err != nil {
+		f.File.Close()
+		os.Remove(f.File.Name())
+		return fmt.Errorf("unable to sync temporary file: %w", err)
+	}
+	if err := f.File.Close(); err != nil {
+		os.Remove(f.File.Name())
+		return fmt.Errorf("unable to close temporary file: %w", err)
+	}
+
+	// Replace target
+	if err := os.Rename(f.File.Name(), f.target); err != nil {
+		os.Remove(f.File.Name())
+		return fmt.Errorf("unable to replace '%s': %w", f.target, err)
+	}
+
+	// Persist the directory entry, best effort only
+	syncDir(filepath.Dir(f.target))
+
+	// No error
+	return nil
+}
+
+// Abort discards the written content.
+func (f *AtomicFile) Abort() error {
+	if f.done {
+		return nil
+	}
+	f.done = true
+
+	f.File.Close()
+	if err := os.Remove(f.File.Name()); err != nil {
+		return fmt.Errorf("unable to remove temporary file: %w", err)
+	}
+
+	// No error
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	_ = d.Close()
+}
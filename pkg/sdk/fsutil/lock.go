@@ -0,0 +1,84 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package fsutil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// LockSuffix is appended to the locked file name to build the lock file name.
+const LockSuffix = ".lock"
+
+// ErrLockTimeout is raised when the lock can't be acquired in time.
+var ErrLockTimeout = errors.New("fsutil: lock acquisition timeout")
+
+// lockRetryInterval is the delay between lock acquisition attempts.
+var lockRetryInterval = 50 * time.Millisecond
+
+// LockSupported returns true if advisory file locks are enforced on this
+// platform. Otherwise Lock only creates the sidecar file and always succeeds.
+func LockSupported() bool {
+	return lockSupported
+}
+
+// Lock acquires an exclusive advisory lock on the `<path>.lock` sidecar file.
+// It retries until the timeout is reached, and returns the unlock function.
+//
+// The sidecar file is never removed, removing it while another process waits
+// for the lock would let two processes hold a lock on different files.
+func Lock(path string, timeout time.Duration) (func() error, error) {
+	lockPath := path + LockSuffix
+
+	// Open or create the lock file
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open lock file '%s': %w", lockPath, err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		// Try to acquire the lock
+		acquired, err := tryLockFile(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("unable to lock '%s': %w", lockPath, err)
+		}
+		if acquired {
+			break
+		}
+
+		// Check timeout
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("unable to lock '%s' in %s: %w", lockPath, timeout, ErrLockTimeout)
+		}
+
+		time.Sleep(lockRetryInterval)
+	}
+
+	return func() error {
+		errUnlock := unlockFile(f)
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("unable to close lock file '%s': %w", lockPath, err)
+		}
+		return errUnlock
+	}, nil
+}
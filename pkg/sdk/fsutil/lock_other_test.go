@@ -0,0 +1,58 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly && !windows
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly,!windows
+
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_Lock_Unsupported(t *testing.T) {
+	if LockSupported() {
+		t.Fatal("file locking must not be reported as supported")
+	}
+
+	target := filepath.Join(t.TempDir(), "bundle.container")
+
+	// Both locks are acquired without waiting
+	unlock, err := Lock(target, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	other, err := Lock(target, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The sidecar file is still created
+	if _, err := os.Stat(target + LockSuffix); err != nil {
+		t.Errorf("expected lock file: %v", err)
+	}
+
+	if err := other(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := unlock(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,395 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/sdk/security"
+)
+
+// ErrDigestMismatch is raised when downloaded content doesn't match the
+// expected digest.
+var ErrDigestMismatch = errors.New("downloaded content digest mismatch")
+
+// Download fetches the given URL content to a temporary file and returns a
+// reader on it. Interrupted transfers are resumed using HTTP Range requests
+// conditioned by the content entity tag or modification date, the transfer is
+// restarted when the remote content has changed or has no validator. The
+// temporary file is removed when the reader is closed.
+func Download(ctx context.Context, url string, opts ...Option) (io.ReadCloser, error) {
+	// Default options
+	dopts := &options{
+		client:      cleanhttp.DefaultClient(),
+		maxRetries:  5,
+		backoffBase: 500 * time.Millisecond,
+		backoffMax:  30 * time.Second,
+		tempDir:     "",
+	}
+
+	// Apply options
+	for _, o := range opts {
+		if err := o(dopts); err != nil {
+			return nil, fmt.Errorf("unable to apply downloader option: %w", err)
+		}
+	}
+
+	// Create the temporary file
+	f, err := ioutil.TempFile(dopts.tempDir, "harp-download-*")
+	if err != nil {
+		return nil, fmt.Errorf("unable to create temporary file: %w", err)
+	}
+	if err = f.Chmod(0o600); err != nil {
+		cleanup(f)
+		return nil, fmt.Errorf("unable to restrict temporary file permissions: %w", err)
+	}
+
+	// Download with retries
+	if err = download(ctx, url, f, dopts); err != nil {
+		cleanup(f)
+		return nil, err
+	}
+
+	// Verify content integrity
+	if err = verify(f, dopts); err != nil {
+		cleanup(f)
+		return nil, err
+	}
+
+	// Rewind for reading
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		cleanup(f)
+		return nil, fmt.Errorf("unable to rewind temporary file: %w", err)
+	}
+
+	// No error
+	return &tempFile{File: f}, nil
+}
+
+// -----------------------------------------------------------------------------
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// transfer holds the resumable download state.
+type transfer struct {
+	offset int64
+	// validator is the entity tag or the modification date of the partially
+	// downloaded content.
+	validator string
+}
+
+func download(ctx context.Context, url string, f *os.File, opts *options) error {
+	var (
+		st      transfer
+		attempt int
+	)
+
+	for {
+		err := fetch(ctx, url, f, &st, opts)
+		if err == nil {
+			return nil
+		}
+
+		// Don't retry permanent errors
+		var perr *permanentError
+		if errors.As(err, &perr) {
+			return fmt.Errorf("unable to download '%s': %w", url, perr.err)
+		}
+
+		// Check retry count
+		attempt++
+		if attempt > opts.maxRetries {
+			return fmt.Errorf("unable to download '%s' after %d attempts: %w", url, attempt, err)
+		}
+
+		log.For(ctx).Warn("Download interrupted, retrying", zap.String("url", url), zap.Int64("offset", st.offset), zap.Int("attempt", attempt), zap.Error(err))
+
+		// Wait before retrying
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("unable to download '%s': %w", url, ctx.Err())
+		case <-time.After(backoff(attempt, opts)):
+		}
+	}
+}
+
+func fetch(ctx context.Context, url string, f *os.File, st *transfer, opts *options) error {
+	// Partial content without validator can't be safely resumed
+	if st.offset > 0 && st.validator == "" {
+		if err := reset(f, st); err != nil {
+			return &permanentError{err: err}
+		}
+	}
+
+	// Prepare query
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return &permanentError{err: fmt.Errorf("unable to prepare http query: %w", err)}
+	}
+	if st.offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", st.offset))
+		req.Header.Set("If-Range", st.validator)
+	}
+
+	// Query
+	resp, err := opts.client.Do(req)
+	if err != nil {
+		// Context cancellation is not retryable
+		if ctx.Err() != nil {
+			return &permanentError{err: ctx.Err()}
+		}
+		return fmt.Errorf("unable to query remote URL: %w", err)
+	}
+	defer resp.Body.Close()
+
+	total := int64(-1)
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		// Server doesn't support range or content has changed, restart from
+		// scratch
+		if err = reset(f, st); err != nil {
+			return &permanentError{err: err}
+		}
+		st.validator = validator(resp)
+		total = resp.ContentLength
+	case resp.StatusCode == http.StatusPartialContent:
+		if v := validator(resp); v != "" && v != st.validator {
+			// Content has changed, restart from scratch
+			if err = reset(f, st); err != nil {
+				return &permanentError{err: err}
+			}
+			return fmt.Errorf("remote content has changed during the transfer")
+		}
+		start, size, errRange := parseContentRange(resp.Header.Get("Content-Range"))
+		if errRange != nil || start != st.offset {
+			// Inconsistent range, restart from scratch
+			if err = reset(f, st); err != nil {
+				return &permanentError{err: err}
+			}
+			return fmt.Errorf("unexpected content range '%s'", resp.Header.Get("Content-Range"))
+		}
+		total = size
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// Local state is inconsistent with remote, restart from scratch
+		if err = reset(f, st); err != nil {
+			return &permanentError{err: err}
+		}
+		return fmt.Errorf("requested range not satisfiable")
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= http.StatusInternalServerError:
+		return fmt.Errorf("remote server error (%d)", resp.StatusCode)
+	default:
+		return &permanentError{err: fmt.Errorf("unexpected response status (%d)", resp.StatusCode)}
+	}
+
+	// Copy response body
+	n, err := io.Copy(f, newRateLimitedReader(ctx, resp.Body, opts.maxRate))
+	st.offset += n
+	if err != nil {
+		if ctx.Err() != nil {
+			return &permanentError{err: ctx.Err()}
+		}
+		return fmt.Errorf("transfer interrupted: %w", err)
+	}
+
+	// Check transfer completion
+	if total >= 0 && st.offset != total {
+		return fmt.Errorf("transfer interrupted at %d/%d bytes: %w", st.offset, total, io.ErrUnexpectedEOF)
+	}
+
+	// No error
+	return nil
+}
+
+func reset(f *os.File, st *transfer) error {
+	st.offset = 0
+	st.validator = ""
+	if err := f.Truncate(0); err != nil {
+		return fmt.Errorf("unable to truncate temporary file: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("unable to rewind temporary file: %w", err)
+	}
+	return nil
+}
+
+// validator returns the response strong entity tag, or its modification date
+// if any. Weak entity tags can't be used to resume a transfer.
+func validator(resp *http.Response) string {
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return resp.Header.Get("Last-Modified")
+}
+
+func verify(f *os.File, opts *options) error {
+	// Skip if no digest expected
+	if opts.digestHashFunc == nil {
+		return nil
+	}
+
+	// Rewind file
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("unable to rewind temporary file: %w", err)
+	}
+
+	// Compute digest
+	h := opts.digestHashFunc()
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("unable to compute downloaded content digest: %w", err)
+	}
+
+	// Compare with expected one
+	if !security.SecureCompare(h.Sum(nil), opts.digestValue) {
+		return ErrDigestMismatch
+	}
+
+	// No error
+	return nil
+}
+
+// parseContentRange extracts start offset and complete size from a
+// `bytes <start>-<end>/<size>` header value.
+func parseContentRange(value string) (start, size int64, err error) {
+	if !strings.HasPrefix(value, "bytes ") {
+		return 0, 0, fmt.Errorf("invalid content range unit")
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(value, "bytes "), "/", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid content range format")
+	}
+
+	bounds := strings.SplitN(parts[0], "-", 2)
+	if len(bounds) != 2 {
+		return 0, 0, fmt.Errorf("invalid content range bounds")
+	}
+
+	start, err = strconv.ParseInt(bounds[0], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid content range start: %w", err)
+	}
+
+	// Size could be unknown
+	if parts[1] == "*" {
+		return start, -1, nil
+	}
+
+	size, err = strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid content range size: %w", err)
+	}
+
+	return start, size, nil
+}
+
+func backoff(attempt int, opts *options) time.Duration {
+	// Exponential delay
+	d := opts.backoffBase << uint(attempt-1)
+	if d <= 0 || d > opts.backoffMax {
+		d = opts.backoffMax
+	}
+
+	// Apply jitter in [d/2, d]
+	half := int64(d / 2)
+	//nolint:gosec // no need for cryptographic randomness
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+func cleanup(f *os.File) {
+	f.Close()
+	os.Remove(f.Name())
+}
+
+// -----------------------------------------------------------------------------
+
+type tempFile struct {
+	*os.File
+}
+
+// Close the file and remove it from the filesystem.
+func (t *tempFile) Close() error {
+	errClose := t.File.Close()
+	if err := os.Remove(t.File.Name()); err != nil {
+		return fmt.Errorf("unable to remove temporary file: %w", err)
+	}
+	return errClose
+}
+
+// -----------------------------------------------------------------------------
+
+type rateLimitedReader struct {
+	ctx   context.Context
+	r     io.Reader
+	rate  int64
+	start time.Time
+	read  int64
+}
+
+func newRateLimitedReader(ctx context.Context, r io.Reader, rate int64) io.Reader {
+	if rate <= 0 {
+		return r
+	}
+	return &rateLimitedReader{
+		ctx:   ctx,
+		r:     r,
+		rate:  rate,
+		start: time.Now(),
+	}
+}
+
+// Read implements io.Reader interface.
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+	// Don't read more than one second of transfer at once
+	if int64(len(p)) > r.rate {
+		p = p[:r.rate]
+	}
+
+	n, err := r.r.Read(p)
+	r.read += int64(n)
+
+	// Wait until the transfer rate is below the limit
+	expected := time.Duration(float64(r.read) / float64(r.rate) * float64(time.Second))
+	if wait := expected - time.Since(r.start); wait > 0 {
+		select {
+		case <-r.ctx.Done():
+			return n, r.ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+
+	return n, err
+}
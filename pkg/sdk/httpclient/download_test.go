@@ -0,0 +1,323 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func fixture(t *testing.T, size int) []byte {
+	payload := make([]byte, size)
+	if _, err := rand.Read(payload); err != nil {
+		t.Fatalf("unable to generate fixture: %v", err)
+	}
+	return payload
+}
+
+// flakyServer serves the payload with range support, and drops the
+// connection in the middle of the transfer for the first requests.
+func flakyServer(t *testing.T, payload []byte, drops int32, ranges *[]string) *httptest.Server {
+	var calls int32
+	h := sha256.Sum256(payload)
+	etag := `"` + hex.EncodeToString(h[:]) + `"`
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*ranges = append(*ranges, r.Header.Get("Range"))
+		w.Header().Set("ETag", etag)
+
+		if atomic.AddInt32(&calls, 1) <= drops {
+			// Write the beginning of the requested content then drop
+			start := 0
+			if rh := r.Header.Get("Range"); rh != "" {
+				start, _ = strconv.Atoi(rh[len("bytes=") : len(rh)-1])
+				w.Header().Set("Content-Range", "bytes "+strconv.Itoa(start)+"-"+strconv.Itoa(len(payload)-1)+"/"+strconv.Itoa(len(payload)))
+				w.Header().Set("Content-Length", strconv.Itoa(len(payload)-start))
+				w.WriteHeader(http.StatusPartialContent)
+			} else {
+				w.Header().Set("Content-Length", strconv.Itoa(len(payload)))
+				w.WriteHeader(http.StatusOK)
+			}
+			end := start + (len(payload)-start)/2
+			_, _ = w.Write(payload[start:end])
+			w.(http.Flusher).Flush()
+
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Errorf("unable to hijack connection: %v", err)
+				return
+			}
+			conn.Close()
+			return
+		}
+
+		http.ServeContent(w, r, "container", time.Time{}, bytes.NewReader(payload))
+	}))
+}
+
+func Test_Download_Resume(t *testing.T) {
+	payload := fixture(t, 512*1024)
+	ranges := []string{}
+	srv := flakyServer(t, payload, 2, &ranges)
+	defer srv.Close()
+
+	digest := sha256.Sum256(payload)
+
+	tmpDir, err := ioutil.TempDir("", "harp-download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	r, err := Download(context.Background(), srv.URL,
+		WithBackoff(time.Millisecond, 10*time.Millisecond),
+		WithTempDir(tmpDir),
+		WithExpectedDigest("sha256:"+hex.EncodeToString(digest[:])),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Check file permissions
+	f, ok := r.(*tempFile)
+	if !ok {
+		t.Fatalf("unexpected reader type %T", r)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0o600 {
+		t.Errorf("expected 0600 file mode, got %o", fi.Mode().Perm())
+	}
+
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Error("downloaded content doesn't match")
+	}
+
+	// Check resumption
+	if len(ranges) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(ranges))
+	}
+	if ranges[0] != "" {
+		t.Errorf("first request must not use range, got %q", ranges[0])
+	}
+	if ranges[1] != "bytes=262144-" || ranges[2] != "bytes=393216-" {
+		t.Errorf("unexpected resume ranges %v", ranges[1:])
+	}
+
+	// Check cleanup
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := ioutil.ReadDir(tmpDir)
+	if len(entries) != 0 {
+		t.Errorf("temporary file must be removed on close")
+	}
+}
+
+func Test_Download_ContentChanged(t *testing.T) {
+	testCases := []struct {
+		desc string
+		// ignoreIfRange serves the requested range whatever the validator.
+		ignoreIfRange bool
+		noValidator   bool
+		wantRequests  int
+	}{
+		{desc: "conditional range", wantRequests: 2},
+		{desc: "if-range not supported", ignoreIfRange: true, wantRequests: 3},
+		{desc: "no validator", noValidator: true, wantRequests: 2},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			v1, v2 := fixture(t, 64*1024), fixture(t, 64*1024)
+
+			var (
+				calls    int32
+				ifRanges []string
+			)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ifRanges = append(ifRanges, r.Header.Get("If-Range"))
+
+				// First request serves half of the first version then drops
+				if atomic.AddInt32(&calls, 1) == 1 {
+					if !tC.noValidator {
+						w.Header().Set("ETag", `"v1"`)
+					}
+					w.Header().Set("Content-Length", strconv.Itoa(len(v1)))
+					w.WriteHeader(http.StatusOK)
+					_, _ = w.Write(v1[:len(v1)/2])
+					w.(http.Flusher).Flush()
+
+					conn, _, err := w.(http.Hijacker).Hijack()
+					if err != nil {
+						t.Errorf("unable to hijack connection: %v", err)
+						return
+					}
+					conn.Close()
+					return
+				}
+
+				// Content has been updated
+				if !tC.noValidator {
+					w.Header().Set("ETag", `"v2"`)
+				}
+				if tC.ignoreIfRange {
+					r.Header.Del("If-Range")
+				}
+				http.ServeContent(w, r, "container", time.Time{}, bytes.NewReader(v2))
+			}))
+			defer srv.Close()
+
+			r, err := Download(context.Background(), srv.URL, WithBackoff(time.Millisecond, 10*time.Millisecond))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer r.Close()
+
+			got, err := ioutil.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, v2) {
+				t.Error("downloaded content must be the updated version only")
+			}
+			if int(calls) != tC.wantRequests {
+				t.Errorf("expected %d requests, got %d", tC.wantRequests, calls)
+			}
+			wantIfRange := `"v1"`
+			if tC.noValidator {
+				wantIfRange = ""
+			}
+			if ifRanges[1] != wantIfRange {
+				t.Errorf("expected If-Range %q on resume request, got %q", wantIfRange, ifRanges[1])
+			}
+		})
+	}
+}
+
+func Test_Download_DigestMismatch(t *testing.T) {
+	payload := fixture(t, 1024)
+	ranges := []string{}
+	srv := flakyServer(t, payload, 0, &ranges)
+	defer srv.Close()
+
+	tmpDir, err := ioutil.TempDir("", "harp-download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	_, err = Download(context.Background(), srv.URL,
+		WithTempDir(tmpDir),
+		WithExpectedDigest("sha256:"+hex.EncodeToString(make([]byte, 32))),
+	)
+	if !errors.Is(err, ErrDigestMismatch) {
+		t.Fatalf("expected digest mismatch, got %v", err)
+	}
+
+	// Check cleanup on failure
+	entries, _ := ioutil.ReadDir(tmpDir)
+	if len(entries) != 0 {
+		t.Errorf("temporary file must be removed on failure")
+	}
+}
+
+func Test_Download_RetryExhausted(t *testing.T) {
+	payload := fixture(t, 1024)
+	ranges := []string{}
+	srv := flakyServer(t, payload, 10, &ranges)
+	defer srv.Close()
+
+	_, err := Download(context.Background(), srv.URL,
+		WithMaxRetries(2),
+		WithBackoff(time.Millisecond, time.Millisecond),
+	)
+	if err == nil {
+		t.Fatal("error expected")
+	}
+	if len(ranges) != 3 {
+		t.Errorf("expected 3 attempts, got %d", len(ranges))
+	}
+}
+
+func Test_Download_NotFound(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	_, err := Download(context.Background(), srv.URL, WithBackoff(time.Millisecond, time.Millisecond))
+	if err == nil {
+		t.Fatal("error expected")
+	}
+}
+
+func Test_Download_MaxRate(t *testing.T) {
+	payload := fixture(t, 64*1024)
+	ranges := []string{}
+	srv := flakyServer(t, payload, 0, &ranges)
+	defer srv.Close()
+
+	start := time.Now()
+	r, err := Download(context.Background(), srv.URL, WithMaxRate(128*1024))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer r.Close()
+
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("rate limit not applied, download took %s", elapsed)
+	}
+}
+
+func Test_WithExpectedDigest(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		value   string
+		wantErr bool
+	}{
+		{desc: "blank", value: "", wantErr: false},
+		{desc: "no algorithm", value: "abcd", wantErr: true},
+		{desc: "unsupported algorithm", value: "md5:d41d8cd98f00b204e9800998ecf8427e", wantErr: true},
+		{desc: "invalid hex", value: "sha256:zz", wantErr: true},
+		{desc: "invalid length", value: "sha256:abcd", wantErr: true},
+		{desc: "valid", value: "sha256:" + hex.EncodeToString(make([]byte, 32)), wantErr: false},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			err := WithExpectedDigest(tC.value)(&options{})
+			if (err != nil) != tC.wantErr {
+				t.Errorf("error during the call, error = %v, wantErr %v", err, tC.wantErr)
+			}
+		})
+	}
+}
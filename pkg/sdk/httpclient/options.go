@@ -0,0 +1,140 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package httpclient
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type options struct {
+	client         *http.Client
+	maxRate        int64
+	maxRetries     int
+	backoffBase    time.Duration
+	backoffMax     time.Duration
+	tempDir        string
+	digestHashFunc func() hash.Hash
+	digestValue    []byte
+}
+
+// Option defines the functional pattern for downloader settings.
+type Option func(*options) error
+
+// WithClient sets the HTTP client used for requests.
+func WithClient(value *http.Client) Option {
+	return func(opts *options) error {
+		if value == nil {
+			return fmt.Errorf("unable to use nil http client")
+		}
+		opts.client = value
+		// No error
+		return nil
+	}
+}
+
+// WithMaxRate limits the download bandwidth (bytes per second, 0 for unlimited).
+func WithMaxRate(value int64) Option {
+	return func(opts *options) error {
+		if value < 0 {
+			return fmt.Errorf("max rate must be positive")
+		}
+		opts.maxRate = value
+		// No error
+		return nil
+	}
+}
+
+// WithMaxRetries sets the maximum retry count after a transfer failure.
+func WithMaxRetries(value int) Option {
+	return func(opts *options) error {
+		if value < 0 {
+			return fmt.Errorf("max retries must be positive")
+		}
+		opts.maxRetries = value
+		// No error
+		return nil
+	}
+}
+
+// WithBackoff sets retry backoff bounds.
+func WithBackoff(base, max time.Duration) Option {
+	return func(opts *options) error {
+		if base <= 0 || max < base {
+			return fmt.Errorf("invalid backoff bounds (%s, %s)", base, max)
+		}
+		opts.backoffBase = base
+		opts.backoffMax = max
+		// No error
+		return nil
+	}
+}
+
+// WithTempDir sets the directory used to store the downloaded file.
+func WithTempDir(value string) Option {
+	return func(opts *options) error {
+		opts.tempDir = value
+		// No error
+		return nil
+	}
+}
+
+// WithExpectedDigest enables content verification with the given digest
+// expressed as `<algorithm>:<hex>` (sha256 and sha512 supported).
+func WithExpectedDigest(value string) Option {
+	return func(opts *options) error {
+		// Ignore blank digest
+		if value == "" {
+			return nil
+		}
+
+		parts := strings.SplitN(value, ":", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid digest format '%s', expected '<algorithm>:<hex>'", value)
+		}
+
+		// Select hash function
+		switch strings.ToLower(parts[0]) {
+		case "sha256":
+			opts.digestHashFunc = sha256.New
+		case "sha512":
+			opts.digestHashFunc = sha512.New
+		default:
+			return fmt.Errorf("unsupported digest algorithm '%s'", parts[0])
+		}
+
+		// Decode expected value
+		raw, err := hex.DecodeString(parts[1])
+		if err != nil {
+			return fmt.Errorf("unable to decode digest value: %w", err)
+		}
+		if len(raw) != opts.digestHashFunc().Size() {
+			return fmt.Errorf("invalid digest length for '%s'", parts[0])
+		}
+		opts.digestValue = raw
+
+		// No error
+		return nil
+	}
+}
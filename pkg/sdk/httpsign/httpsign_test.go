@@ -0,0 +1,283 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package httpsign
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func signedRequest(t *testing.T, method, target, body string, now time.Time) *http.Request {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if err := sign(req, testKey, now, rand.Read); err != nil {
+		t.Fatalf("unable to sign request: %v", err)
+	}
+
+	return req
+}
+
+func testVerifier(t *testing.T, now time.Time, opts ...Option) *Verifier {
+	t.Helper()
+
+	v, err := NewVerifier(testKey, opts...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v.now = func() time.Time { return now }
+
+	return v
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	v := testVerifier(t, now, WithSkew(time.Minute))
+
+	// Valid request, body is restored
+	req := signedRequest(t, http.MethodPost, "/admin/v1/reload?force=true", `{"ns":"root"}`, now)
+	if err := v.Verify(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if body, _ := ioutil.ReadAll(req.Body); string(body) != `{"ns":"root"}` {
+		t.Errorf("request body must be restored, got %q", body)
+	}
+
+	// Replayed request
+	replay := httptest.NewRequest(http.MethodPost, "/admin/v1/reload?force=true", strings.NewReader(`{"ns":"root"}`))
+	replay.Header = req.Header.Clone()
+	if err := v.Verify(replay); !errors.Is(err, ErrReplayedNonce) {
+		t.Errorf("expected ErrReplayedNonce, got %v", err)
+	}
+}
+
+func TestVerify_Skew(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+
+	testCases := []struct {
+		name    string
+		offset  time.Duration
+		wantErr error
+	}{
+		{name: "in sync", offset: 0},
+		{name: "client behind", offset: -59 * time.Second},
+		{name: "client ahead", offset: 59 * time.Second},
+		{name: "client too far behind", offset: -2 * time.Minute, wantErr: ErrTimestampSkew},
+		{name: "client too far ahead", offset: 2 * time.Minute, wantErr: ErrTimestampSkew},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := testVerifier(t, now, WithSkew(time.Minute))
+			err := v.Verify(signedRequest(t, http.MethodGet, "/admin/v1/status", "", now.Add(tc.offset)))
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("expected %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestVerify_Malformed(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+
+	testCases := []struct {
+		name    string
+		mutate  func(req *http.Request)
+		wantErr error
+	}{
+		{
+			name:    "unsigned",
+			mutate:  func(req *http.Request) { req.Header.Del(SignatureHeader) },
+			wantErr: ErrMissingSignature,
+		},
+		{
+			name:    "non hex signature",
+			mutate:  func(req *http.Request) { req.Header.Set(SignatureHeader, "zz") },
+			wantErr: ErrMalformedSignature,
+		},
+		{
+			name:    "truncated signature",
+			mutate:  func(req *http.Request) { req.Header.Set(SignatureHeader, req.Header.Get(SignatureHeader)[:32]) },
+			wantErr: ErrMalformedSignature,
+		},
+		{
+			name:    "invalid timestamp",
+			mutate:  func(req *http.Request) { req.Header.Set(TimestampHeader, "yesterday") },
+			wantErr: ErrMalformedSignature,
+		},
+		{
+			name:    "invalid nonce",
+			mutate:  func(req *http.Request) { req.Header.Set(NonceHeader, "../../etc") },
+			wantErr: ErrMalformedSignature,
+		},
+		{
+			name:    "tampered nonce",
+			mutate:  func(req *http.Request) { req.Header.Set(NonceHeader, strings.Repeat("0", 32)) },
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "tampered method",
+			mutate:  func(req *http.Request) { req.Method = http.MethodDelete },
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "tampered path",
+			mutate:  func(req *http.Request) { req.URL.Path = "/admin/v1/quarantine/release" },
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "tampered body",
+			mutate:  func(req *http.Request) { req.Body = ioutil.NopCloser(strings.NewReader(`{"ns":"all"}`)) },
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name: "wrong key",
+			mutate: func(req *http.Request) {
+				if err := sign(req, []byte("another-key"), now, rand.Read); err != nil {
+					panic(err)
+				}
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "body too large",
+			mutate:  func(req *http.Request) { req.Body = ioutil.NopCloser(strings.NewReader(strings.Repeat("a", 2048))) },
+			wantErr: ErrBodyTooLarge,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := testVerifier(t, now, WithMaxBodySize(1024))
+			req := signedRequest(t, http.MethodPost, "/admin/v1/reload", `{"ns":"root"}`, now)
+			tc.mutate(req)
+
+			if err := v.Verify(req); !errors.Is(err, tc.wantErr) {
+				t.Errorf("expected %v, got %v", tc.wantErr, err)
+			}
+			if v.nonces.Len() != 0 {
+				t.Error("rejected requests must not be tracked")
+			}
+		})
+	}
+}
+
+func TestNonceStore_Bounded(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	s := NewNonceStore(100, time.Minute)
+
+	// Concurrent load beyond capacity
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		accepted int
+		full     int
+	)
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				err := s.Add(fmt.Sprintf("%d-%d", w, i), now)
+				mu.Lock()
+				switch {
+				case err == nil:
+					accepted++
+				case errors.Is(err, ErrNonceStoreFull):
+					full++
+				default:
+					t.Errorf("unexpected error: %v", err)
+				}
+				mu.Unlock()
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if accepted != 100 || full != 7900 {
+		t.Errorf("unexpected accepted/full counts %d/%d", accepted, full)
+	}
+	if s.Len() != 100 {
+		t.Errorf("store must be bounded, got %d", s.Len())
+	}
+
+	// Tracked nonces are never evicted before expiration
+	var tracked string
+	for nonce := range s.seen {
+		tracked = nonce
+		break
+	}
+	if err := s.Add(tracked, now.Add(59*time.Second)); !errors.Is(err, ErrReplayedNonce) {
+		t.Errorf("expected ErrReplayedNonce, got %v", err)
+	}
+
+	// Expired nonces are forgotten, memory is reclaimed
+	later := now.Add(time.Minute)
+	for i := 0; i < 10000; i++ {
+		if err := s.Add(fmt.Sprintf("later-%d", i), later.Add(time.Duration(i)*time.Second)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if s.Len() > 100 || cap(s.queue) > 1000 {
+		t.Errorf("store must stay bounded, got %d entries, %d queue capacity", s.Len(), cap(s.queue))
+	}
+}
+
+func TestTransport(t *testing.T) {
+	v, err := NewVerifier(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(v.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		fmt.Fprintf(w, "ok %s", body)
+	})))
+	defer srv.Close()
+
+	// Signed client
+	client := &http.Client{Transport: Transport(testKey, nil)}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Post(srv.URL+"/admin/v1/reload", "application/json", strings.NewReader(`{"ns":"root"}`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || string(body) != `ok {"ns":"root"}` {
+			t.Errorf("unexpected response %d %q", resp.StatusCode, body)
+		}
+	}
+
+	// Unsigned client
+	resp, err := http.Get(srv.URL + "/admin/v1/status")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("unexpected status %d", resp.StatusCode)
+	}
+}
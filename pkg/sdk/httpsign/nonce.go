@@ -0,0 +1,100 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package httpsign
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrReplayedNonce is raised when a nonce has already been seen.
+	ErrReplayedNonce = errors.New("nonce has already been used")
+	// ErrNonceStoreFull is raised when the nonce store capacity is reached
+	// with unexpired nonces.
+	ErrNonceStoreFull = errors.New("nonce store is full")
+)
+
+// NonceStore tracks recently seen nonces. It holds at most capacity nonces,
+// each one is forgotten after the ttl. When full of unexpired nonces, new ones
+// are rejected instead of evicting nonces that could be replayed.
+type NonceStore struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	seen     map[string]struct{}
+	queue    []nonceEntry
+	head     int
+}
+
+type nonceEntry struct {
+	nonce  string
+	expiry time.Time
+}
+
+// NewNonceStore returns a bounded nonce store.
+func NewNonceStore(capacity int, ttl time.Duration) *NonceStore {
+	return &NonceStore{
+		capacity: capacity,
+		ttl:      ttl,
+		seen:     make(map[string]struct{}, capacity),
+		queue:    make([]nonceEntry, 0, capacity),
+	}
+}
+
+// Add records the given nonce. It returns ErrReplayedNonce when the nonce is
+// still tracked.
+func (s *NonceStore) Add(nonce string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Forget expired nonces, the queue is ordered by insertion time
+	for s.head < len(s.queue) && !now.Before(s.queue[s.head].expiry) {
+		delete(s.seen, s.queue[s.head].nonce)
+		s.queue[s.head] = nonceEntry{}
+		s.head++
+	}
+	if s.head > 0 && s.head >= len(s.queue)/2 {
+		s.queue = append(s.queue[:0], s.queue[s.head:]...)
+		s.head = 0
+	}
+
+	// Check nonce
+	if _, ok := s.seen[nonce]; ok {
+		return ErrReplayedNonce
+	}
+	if len(s.seen) >= s.capacity {
+		return ErrNonceStoreFull
+	}
+
+	// Track nonce
+	s.seen[nonce] = struct{}{}
+	s.queue = append(s.queue, nonceEntry{nonce: nonce, expiry: now.Add(s.ttl)})
+
+	// No error
+	return nil
+}
+
+// Len returns the count of tracked nonces.
+func (s *NonceStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.seen)
+}
@@ -0,0 +1,150 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package httpsign implements HMAC signed HTTP requests with replay
+// protection.
+//
+// The signature covers the request method, path and query, body, timestamp and
+// a random nonce. Servers reject timestamps outside of the allowed clock skew
+// and nonces seen during this window.
+package httpsign
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// TimestampHeader holds the request unix timestamp in seconds.
+	TimestampHeader = "X-Harp-Timestamp"
+	// NonceHeader holds the request random nonce.
+	NonceHeader = "X-Harp-Nonce"
+	// SignatureHeader holds the hex encoded request HMAC-SHA256 signature.
+	SignatureHeader = "X-Harp-Signature"
+)
+
+// nonceSize is the random nonce size in bytes.
+const nonceSize = 16
+
+// Sign the given request using the shared key. The request body is read and
+// restored.
+func Sign(req *http.Request, key []byte) error {
+	return sign(req, key, time.Now(), rand.Read)
+}
+
+// Transport returns an HTTP round tripper signing all requests using the
+// shared key. The default transport is used when base is nil.
+func Transport(key []byte, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{key: key, base: base}
+}
+
+// -----------------------------------------------------------------------------
+
+type transport struct {
+	key  []byte
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Round trippers must not modify the request
+	signed := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read request body: %w", err)
+		}
+		signed.Body = body
+	}
+
+	if err := Sign(signed, t.key); err != nil {
+		return nil, err
+	}
+
+	return t.base.RoundTrip(signed)
+}
+
+func sign(req *http.Request, key []byte, now time.Time, random func([]byte) (int, error)) error {
+	// Check arguments
+	if req == nil {
+		return errors.New("unable to sign nil request")
+	}
+	if len(key) == 0 {
+		return errors.New("unable to sign request with a blank key")
+	}
+
+	// Read body
+	body, err := readBody(req)
+	if err != nil {
+		return err
+	}
+
+	// Generate nonce
+	raw := make([]byte, nonceSize)
+	if _, err := random(raw); err != nil {
+		return fmt.Errorf("unable to generate request nonce: %w", err)
+	}
+	nonce := hex.EncodeToString(raw)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	// Assign headers
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(NonceHeader, nonce)
+	req.Header.Set(SignatureHeader, hex.EncodeToString(signature(key, req, body, timestamp, nonce)))
+
+	// No error
+	return nil
+}
+
+// signature computes the request HMAC.
+func signature(key []byte, req *http.Request, body []byte, timestamp, nonce string) []byte {
+	bodyHash := sha256.Sum256(body)
+
+	h := hmac.New(sha256.New, key)
+	fmt.Fprintf(h, "%s\n%s\n%s\n%s\n%s", req.Method, req.URL.RequestURI(), timestamp, nonce, hex.EncodeToString(bodyHash[:]))
+
+	return h.Sum(nil)
+}
+
+// readBody reads the request body and restores it for next readers.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return []byte{}, nil
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read request body: %w", err)
+	}
+	if err := req.Body.Close(); err != nil {
+		return nil, fmt.Errorf("unable to close request body: %w", err)
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
@@ -0,0 +1,209 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package httpsign
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/log"
+)
+
+var (
+	// ErrMissingSignature is raised when signature headers are missing.
+	ErrMissingSignature = errors.New("request is not signed")
+	// ErrMalformedSignature is raised when signature headers can't be decoded.
+	ErrMalformedSignature = errors.New("request signature is malformed")
+	// ErrInvalidSignature is raised when the signature doesn't match the
+	// request.
+	ErrInvalidSignature = errors.New("request signature is invalid")
+	// ErrTimestampSkew is raised when the request timestamp is outside of the
+	// allowed clock skew.
+	ErrTimestampSkew = errors.New("request timestamp is outside of the allowed clock skew")
+	// ErrBodyTooLarge is raised when the request body exceeds the verified
+	// size limit.
+	ErrBodyTooLarge = errors.New("request body is too large")
+)
+
+var nonceRegexp = regexp.MustCompile(`^[0-9a-f]{16,64}$`)
+
+type options struct {
+	skew          time.Duration
+	nonceCapacity int
+	maxBodySize   int64
+}
+
+// Option defines the functional pattern for verifier settings.
+type Option func(*options) error
+
+// WithSkew sets the maximum allowed clock difference between clients and
+// server.
+func WithSkew(value time.Duration) Option {
+	return func(opts *options) error {
+		if value <= 0 {
+			return fmt.Errorf("clock skew must be strictly positive")
+		}
+		opts.skew = value
+		// No error
+		return nil
+	}
+}
+
+// WithNonceCapacity sets the maximum count of tracked nonces.
+func WithNonceCapacity(value int) Option {
+	return func(opts *options) error {
+		if value <= 0 {
+			return fmt.Errorf("nonce capacity must be strictly positive")
+		}
+		opts.nonceCapacity = value
+		// No error
+		return nil
+	}
+}
+
+// WithMaxBodySize sets the maximum verified request body size.
+func WithMaxBodySize(value int64) Option {
+	return func(opts *options) error {
+		if value <= 0 {
+			return fmt.Errorf("max body size must be strictly positive")
+		}
+		opts.maxBodySize = value
+		// No error
+		return nil
+	}
+}
+
+// Verifier checks signed requests.
+type Verifier struct {
+	key         []byte
+	skew        time.Duration
+	maxBodySize int64
+	nonces      *NonceStore
+	now         func() time.Time
+}
+
+// NewVerifier returns a signed request verifier using the shared key.
+func NewVerifier(key []byte, opts ...Option) (*Verifier, error) {
+	// Check arguments
+	if len(key) == 0 {
+		return nil, errors.New("unable to verify requests with a blank key")
+	}
+
+	// Default options
+	dopts := &options{
+		skew:          5 * time.Minute,
+		nonceCapacity: 10000,
+		maxBodySize:   1 << 20,
+	}
+
+	// Apply options
+	for _, o := range opts {
+		if err := o(dopts); err != nil {
+			return nil, fmt.Errorf("unable to apply verifier option: %w", err)
+		}
+	}
+
+	return &Verifier{
+		key:         key,
+		skew:        dopts.skew,
+		maxBodySize: dopts.maxBodySize,
+		// A replayed request is accepted until its timestamp, which can be
+		// up to skew in the future, leaves the skew window.
+		nonces: NewNonceStore(dopts.nonceCapacity, 2*dopts.skew),
+		now:    time.Now,
+	}, nil
+}
+
+// Verify the given request signature, timestamp and nonce. The request body is
+// read and restored.
+func (v *Verifier) Verify(req *http.Request) error {
+	timestamp := req.Header.Get(TimestampHeader)
+	nonce := req.Header.Get(NonceHeader)
+	sig := req.Header.Get(SignatureHeader)
+
+	// Check headers
+	if timestamp == "" || nonce == "" || sig == "" {
+		return ErrMissingSignature
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp: %w", ErrMalformedSignature)
+	}
+	if !nonceRegexp.MatchString(nonce) {
+		return fmt.Errorf("invalid nonce: %w", ErrMalformedSignature)
+	}
+	expected, err := hex.DecodeString(sig)
+	if err != nil || len(expected) != 32 {
+		return fmt.Errorf("invalid signature encoding: %w", ErrMalformedSignature)
+	}
+
+	// Check timestamp
+	now := v.now()
+	if d := now.Sub(time.Unix(ts, 0)); d > v.skew || d < -v.skew {
+		return ErrTimestampSkew
+	}
+
+	// Read bounded body
+	body := []byte{}
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err = ioutil.ReadAll(io.LimitReader(req.Body, v.maxBodySize+1))
+		if err != nil {
+			return fmt.Errorf("unable to read request body: %w", err)
+		}
+		if int64(len(body)) > v.maxBodySize {
+			return ErrBodyTooLarge
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	// Check signature before tracking the nonce
+	if !hmac.Equal(expected, signature(v.key, req, body, timestamp, nonce)) {
+		return ErrInvalidSignature
+	}
+
+	return v.nonces.Add(nonce, now)
+}
+
+// Middleware rejects requests failing signature verification with 401.
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := v.Verify(r); err != nil {
+			log.For(r.Context()).Warn("Rejected signed request", zap.String("path", r.URL.Path), zap.Error(err))
+
+			status := http.StatusUnauthorized
+			if errors.Is(err, ErrNonceStoreFull) {
+				status = http.StatusServiceUnavailable
+			}
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,443 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package jsonpatch implements JSON Patch (RFC 6902) and JSON Merge Patch
+// (RFC 7386) document edition.
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidDocument is raised when the patched content is not a JSON document.
+	ErrInvalidDocument = errors.New("jsonpatch: invalid JSON document")
+	// ErrInvalidPatch is raised when the patch can't be decoded or is malformed.
+	ErrInvalidPatch = errors.New("jsonpatch: invalid patch")
+	// ErrPathNotFound is raised when an operation references a missing location.
+	ErrPathNotFound = errors.New("jsonpatch: path not found")
+	// ErrTestFailed is raised when a test operation doesn't match.
+	ErrTestFailed = errors.New("jsonpatch: test operation failed")
+)
+
+// Operation describes a JSON Patch operation.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Apply applies the given JSON Patch (RFC 6902) to the document. Operations
+// are applied in order, the document is left untouched when one fails.
+func Apply(doc, patch []byte) ([]byte, error) {
+	// Decode inputs
+	target, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+	var ops []Operation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("%w: patch must be an array of operations: %v", ErrInvalidPatch, err)
+	}
+
+	// Apply operations
+	for i, op := range ops {
+		target, err = apply(target, &op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s '%s'): %w", i, op.Op, op.Path, err)
+		}
+	}
+
+	return encode(target)
+}
+
+// MergePatch applies the given JSON Merge Patch (RFC 7386) to the document.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	// Decode inputs
+	target, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+	p, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	return encode(merge(target, p))
+}
+
+// -----------------------------------------------------------------------------
+
+func decode(raw []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+
+	var out interface{}
+	if err := dec.Decode(&out); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("trailing data after JSON value")
+	}
+
+	return out, nil
+}
+
+func encode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, fmt.Errorf("unable to encode patched document: %w", err)
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func apply(doc interface{}, op *Operation) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: missing value", ErrInvalidPatch)
+		}
+		value, err := decode(op.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid value: %v", ErrInvalidPatch, err)
+		}
+		switch op.Op {
+		case "add":
+			return add(doc, path, value)
+		case "replace":
+			return replace(doc, path, value)
+		default:
+			current, err := get(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !equal(current, value) {
+				return nil, ErrTestFailed
+			}
+			return doc, nil
+		}
+	case "remove":
+		doc, _, err = remove(doc, path)
+		return doc, err
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "copy" {
+			value, err := get(doc, from)
+			if err != nil {
+				return nil, fmt.Errorf("from: %w", err)
+			}
+			return add(doc, path, deepCopy(value))
+		}
+		if len(from) < len(path) && strings.Join(path[:len(from)], "/") == strings.Join(from, "/") {
+			return nil, fmt.Errorf("%w: a location can't be moved into one of its children", ErrInvalidPatch)
+		}
+		doc, value, err := remove(doc, from)
+		if err != nil {
+			return nil, fmt.Errorf("from: %w", err)
+		}
+		return add(doc, path, value)
+	default:
+	}
+
+	return nil, fmt.Errorf("%w: unsupported operation '%s'", ErrInvalidPatch, op.Op)
+}
+
+// parsePointer decodes a JSON pointer (RFC 6901) as reference tokens.
+func parsePointer(raw string) ([]string, error) {
+	if raw == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(raw, "/") {
+		return nil, fmt.Errorf("%w: pointer '%s' must start with '/'", ErrInvalidPatch, raw)
+	}
+
+	tokens := strings.Split(raw[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+// arrayIndex decodes an array index token, `-` references the end of the
+// array when allowed.
+func arrayIndex(token string, size int, allowEnd bool) (int, error) {
+	if token == "-" && allowEnd {
+		return size, nil
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: invalid array index '%s'", ErrPathNotFound, token)
+	}
+	idx, err := strconv.Atoi(token)
+	if err != nil || idx < 0 {
+		return 0, fmt.Errorf("%w: invalid array index '%s'", ErrPathNotFound, token)
+	}
+
+	limit := size - 1
+	if allowEnd {
+		limit = size
+	}
+	if idx > limit {
+		return 0, fmt.Errorf("%w: array index %d out of bounds", ErrPathNotFound, idx)
+	}
+
+	return idx, nil
+}
+
+func get(doc interface{}, path []string) (interface{}, error) {
+	node := doc
+	for _, token := range path {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			child, ok := n[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: member '%s' doesn't exist", ErrPathNotFound, token)
+			}
+			node = child
+		case []interface{}:
+			idx, err := arrayIndex(token, len(n), false)
+			if err != nil {
+				return nil, err
+			}
+			node = n[idx]
+		default:
+			return nil, fmt.Errorf("%w: '%s' can't be referenced in a scalar value", ErrPathNotFound, token)
+		}
+	}
+
+	return node, nil
+}
+
+// update replaces the container holding the last path token by the result of
+// the given function, and returns the updated document.
+func update(doc interface{}, path []string, fn func(container interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+
+	switch n := doc.(type) {
+	case map[string]interface{}:
+		child, ok := n[path[0]]
+		if !ok {
+			return nil, fmt.Errorf("%w: member '%s' doesn't exist", ErrPathNotFound, path[0])
+		}
+		updated, err := update(child, path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		n[path[0]] = updated
+		return n, nil
+	case []interface{}:
+		idx, err := arrayIndex(path[0], len(n), false)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := update(n[idx], path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		n[idx] = updated
+		return n, nil
+	default:
+	}
+
+	return nil, fmt.Errorf("%w: '%s' can't be referenced in a scalar value", ErrPathNotFound, path[0])
+}
+
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	// Replace the whole document
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return update(doc, path, func(container interface{}, token string) (interface{}, error) {
+		switch n := container.(type) {
+		case map[string]interface{}:
+			n[token] = value
+			return n, nil
+		case []interface{}:
+			idx, err := arrayIndex(token, len(n), true)
+			if err != nil {
+				return nil, err
+			}
+			n = append(n, nil)
+			copy(n[idx+1:], n[idx:])
+			n[idx] = value
+			return n, nil
+		default:
+		}
+		return nil, fmt.Errorf("%w: '%s' can't be added to a scalar value", ErrPathNotFound, token)
+	})
+}
+
+func remove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("%w: the whole document can't be removed", ErrInvalidPatch)
+	}
+
+	var removed interface{}
+	doc, err := update(doc, path, func(container interface{}, token string) (interface{}, error) {
+		switch n := container.(type) {
+		case map[string]interface{}:
+			value, ok := n[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: member '%s' doesn't exist", ErrPathNotFound, token)
+			}
+			removed = value
+			delete(n, token)
+			return n, nil
+		case []interface{}:
+			idx, err := arrayIndex(token, len(n), false)
+			if err != nil {
+				return nil, err
+			}
+			removed = n[idx]
+			return append(n[:idx], n[idx+1:]...), nil
+		default:
+		}
+		return nil, fmt.Errorf("%w: '%s' can't be removed from a scalar value", ErrPathNotFound, token)
+	})
+
+	return doc, removed, err
+}
+
+func replace(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	// Replace the whole document
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return update(doc, path, func(container interface{}, token string) (interface{}, error) {
+		switch n := container.(type) {
+		case map[string]interface{}:
+			if _, ok := n[token]; !ok {
+				return nil, fmt.Errorf("%w: member '%s' doesn't exist", ErrPathNotFound, token)
+			}
+			n[token] = value
+			return n, nil
+		case []interface{}:
+			idx, err := arrayIndex(token, len(n), false)
+			if err != nil {
+				return nil, err
+			}
+			n[idx] = value
+			return n, nil
+		default:
+		}
+		return nil, fmt.Errorf("%w: '%s' can't be replaced in a scalar value", ErrPathNotFound, token)
+	})
+}
+
+func merge(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = merge(t[k], v)
+	}
+
+	return t
+}
+
+func deepCopy(v interface{}) interface{} {
+	switch n := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(n))
+		for k, item := range n {
+			out[k] = deepCopy(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(n))
+		for i, item := range n {
+			out[i] = deepCopy(item)
+		}
+		return out
+	default:
+	}
+
+	return v
+}
+
+func equal(a, b interface{}) bool {
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, ok := y[k]
+			if !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		if x == y {
+			return true
+		}
+		fx, errX := x.Float64()
+		fy, errY := y.Float64()
+		return errX == nil && errY == nil && fx == fy
+	default:
+	}
+
+	return a == b
+}
@@ -0,0 +1,186 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package jsonpatch
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	testCases := []struct {
+		name    string
+		doc     string
+		patch   string
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "nested replace",
+			doc:   `{"db":{"host":"localhost","port":5432}}`,
+			patch: `[{"op":"replace","path":"/db/port","value":5433}]`,
+			want:  `{"db":{"host":"localhost","port":5433}}`,
+		},
+		{
+			name:  "nested add and remove",
+			doc:   `{"db":{"host":"localhost","debug":true}}`,
+			patch: `[{"op":"add","path":"/db/options","value":{"ssl":"require"}},{"op":"remove","path":"/db/debug"}]`,
+			want:  `{"db":{"host":"localhost","options":{"ssl":"require"}}}`,
+		},
+		{
+			name:  "escaped pointer",
+			doc:   `{"a/b":{"m~n":1}}`,
+			patch: `[{"op":"replace","path":"/a~1b/m~0n","value":2}]`,
+			want:  `{"a/b":{"m~n":2}}`,
+		},
+		{
+			name:  "array insert and append",
+			doc:   `{"hosts":["a","c"]}`,
+			patch: `[{"op":"add","path":"/hosts/1","value":"b"},{"op":"add","path":"/hosts/-","value":"d"}]`,
+			want:  `{"hosts":["a","b","c","d"]}`,
+		},
+		{
+			name:  "array remove",
+			doc:   `{"hosts":["a","b","c"]}`,
+			patch: `[{"op":"remove","path":"/hosts/0"}]`,
+			want:  `{"hosts":["b","c"]}`,
+		},
+		{
+			name:  "move and copy",
+			doc:   `{"old":{"user":"admin"},"hosts":["a"]}`,
+			patch: `[{"op":"move","from":"/old","path":"/new"},{"op":"copy","from":"/hosts/0","path":"/hosts/-"}]`,
+			want:  `{"hosts":["a","a"],"new":{"user":"admin"}}`,
+		},
+		{
+			name:  "test succeeds",
+			doc:   `{"version":1.0,"tags":["x"]}`,
+			patch: `[{"op":"test","path":"/version","value":1},{"op":"test","path":"/tags","value":["x"]},{"op":"replace","path":"/version","value":2}]`,
+			want:  `{"tags":["x"],"version":2}`,
+		},
+		{
+			name:    "test fails",
+			doc:     `{"version":1}`,
+			patch:   `[{"op":"replace","path":"/version","value":2},{"op":"test","path":"/version","value":1}]`,
+			wantErr: ErrTestFailed,
+		},
+		{
+			name:    "array index out of bounds",
+			doc:     `{"hosts":["a"]}`,
+			patch:   `[{"op":"replace","path":"/hosts/1","value":"b"}]`,
+			wantErr: ErrPathNotFound,
+		},
+		{
+			name:    "missing parent",
+			doc:     `{}`,
+			patch:   `[{"op":"add","path":"/db/port","value":1}]`,
+			wantErr: ErrPathNotFound,
+		},
+		{
+			name:    "move into child",
+			doc:     `{"a":{"b":{}}}`,
+			patch:   `[{"op":"move","from":"/a","path":"/a/b/c"}]`,
+			wantErr: ErrInvalidPatch,
+		},
+		{
+			name:    "unsupported operation",
+			doc:     `{}`,
+			patch:   `[{"op":"upsert","path":"/a","value":1}]`,
+			wantErr: ErrInvalidPatch,
+		},
+		{
+			name:    "missing value",
+			doc:     `{}`,
+			patch:   `[{"op":"add","path":"/a"}]`,
+			wantErr: ErrInvalidPatch,
+		},
+		{
+			name:    "invalid document",
+			doc:     `password`,
+			patch:   `[]`,
+			wantErr: ErrInvalidDocument,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Apply([]byte(tc.doc), []byte(tc.patch))
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("expected %v, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	testCases := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{
+			name:  "nested merge",
+			doc:   `{"db":{"host":"localhost","port":5432},"debug":true}`,
+			patch: `{"db":{"host":"db.internal"},"debug":null}`,
+			want:  `{"db":{"host":"db.internal","port":5432}}`,
+		},
+		{
+			name:  "arrays are replaced",
+			doc:   `{"hosts":["a","b"]}`,
+			patch: `{"hosts":["c"]}`,
+			want:  `{"hosts":["c"]}`,
+		},
+		{
+			name:  "scalar target",
+			doc:   `{"db":"inline"}`,
+			patch: `{"db":{"host":"x"}}`,
+			want:  `{"db":{"host":"x"}}`,
+		},
+		{
+			name:  "non object patch",
+			doc:   `{"a":1}`,
+			patch: `["b"]`,
+			want:  `["b"]`,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := MergePatch([]byte(tc.doc), []byte(tc.patch))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+
+	if _, err := MergePatch([]byte(`not json`), []byte(`{}`)); !errors.Is(err, ErrInvalidDocument) {
+		t.Errorf("expected ErrInvalidDocument, got %v", err)
+	}
+}
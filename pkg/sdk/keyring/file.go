@@ -0,0 +1,107 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package keyring
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// File returns an insecure keyring storing secrets in plain text files of
+// the given directory, readable by the current user only.
+func File(dir string) Keyring {
+	return &fileKeyring{
+		dir: dir,
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+type fileKeyring struct {
+	dir string
+}
+
+func (k *fileKeyring) Get(service, account string) (string, error) {
+	// Check arguments
+	if err := checkIdentifiers(service, account); err != nil {
+		return "", err
+	}
+
+	// Read secret file
+	content, err := ioutil.ReadFile(k.path(service, account))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return "", ErrNotFound
+	case err != nil:
+		return "", fmt.Errorf("keyring: unable to read secret: %w", err)
+	default:
+	}
+
+	// No error
+	return string(content), nil
+}
+
+func (k *fileKeyring) Set(service, account, secret string) error {
+	// Check arguments
+	if err := checkIdentifiers(service, account); err != nil {
+		return err
+	}
+
+	path := k.path(service, account)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("keyring: unable to create keyring directory: %w", err)
+	}
+
+	// Write to a temporary file first to prevent partial secrets
+	tmp := fmt.Sprintf("%s.tmp", path)
+	if err := ioutil.WriteFile(tmp, []byte(secret), 0o600); err != nil {
+		return fmt.Errorf("keyring: unable to write secret: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("keyring: unable to write secret: %w", err)
+	}
+
+	// No error
+	return nil
+}
+
+func (k *fileKeyring) Delete(service, account string) error {
+	// Check arguments
+	if err := checkIdentifiers(service, account); err != nil {
+		return err
+	}
+
+	err := os.Remove(k.path(service, account))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return ErrNotFound
+	case err != nil:
+		return fmt.Errorf("keyring: unable to delete secret: %w", err)
+	default:
+	}
+
+	// No error
+	return nil
+}
+
+func (k *fileKeyring) path(service, account string) string {
+	return filepath.Join(k.dir, service, account)
+}
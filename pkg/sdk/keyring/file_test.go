@@ -0,0 +1,76 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package keyring
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	k := File(dir)
+
+	// Missing secret
+	if _, err := k.Get("harp", "cache"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("missing secret should raise ErrNotFound, got %v", err)
+	}
+	if err := k.Delete("harp", "cache"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("missing secret should raise ErrNotFound, got %v", err)
+	}
+
+	// Store and update
+	for _, secret := range []string{"first", "second"} {
+		if err := k.Set("harp", "cache", secret); err != nil {
+			t.Fatalf("unable to set secret: %v", err)
+		}
+		got, err := k.Get("harp", "cache")
+		if err != nil || got != secret {
+			t.Fatalf("unexpected secret %q (%v), want %q", got, err, secret)
+		}
+	}
+
+	// Check permissions
+	fi, err := os.Stat(filepath.Join(dir, "harp", "cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0o600 {
+		t.Errorf("secret file should be readable by owner only, got %v", perm)
+	}
+
+	// Delete
+	if err := k.Delete("harp", "cache"); err != nil {
+		t.Fatalf("unable to delete secret: %v", err)
+	}
+	if _, err := k.Get("harp", "cache"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleted secret should raise ErrNotFound, got %v", err)
+	}
+}
+
+func TestFile_InvalidIdentifiers(t *testing.T) {
+	k := File(t.TempDir())
+
+	for _, id := range [][2]string{{"", "cache"}, {"harp", ""}, {"../harp", "cache"}, {"harp", "a/b"}} {
+		if err := k.Set(id[0], id[1], "secret"); err == nil {
+			t.Errorf("identifiers %q should be rejected", id)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package keyring stores small secrets in the operating system credential
+// store : macOS Keychain, Windows DPAPI or the Secret Service on Linux.
+//
+// A file based keyring is provided for headless systems, it stores secrets
+// in plain text and must only be used when explicitly requested.
+package keyring
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+var (
+	// ErrNotFound is raised when the requested secret doesn't exist.
+	ErrNotFound = errors.New("keyring: secret not found")
+	// ErrUnsupported is raised when no system keyring is available.
+	ErrUnsupported = errors.New("keyring: system keyring is not supported")
+)
+
+// Keyring describes secret store contract. Secrets are identified by a
+// service and an account name.
+type Keyring interface {
+	Get(service, account string) (string, error)
+	Set(service, account, secret string) error
+	Delete(service, account string) error
+}
+
+// System returns the operating system keyring.
+func System() (Keyring, error) {
+	return system()
+}
+
+// -----------------------------------------------------------------------------
+
+var identifierRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+func checkIdentifiers(service, account string) error {
+	if !identifierRegexp.MatchString(service) {
+		return fmt.Errorf("keyring: invalid service name '%s'", service)
+	}
+	if !identifierRegexp.MatchString(account) {
+		return fmt.Errorf("keyring: invalid account name '%s'", account)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build darwin
+// +build darwin
+
+package keyring
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// errItemNotFound is the security command exit code of missing items.
+const errItemNotFound = 44
+
+// system uses the macOS Keychain through the security command.
+func system() (Keyring, error) {
+	path, err := exec.LookPath("security")
+	if err != nil {
+		return nil, fmt.Errorf("%w: security command not found", ErrUnsupported)
+	}
+
+	return &keychain{
+		command: path,
+	}, nil
+}
+
+// -----------------------------------------------------------------------------
+
+type keychain struct {
+	command string
+}
+
+func (k *keychain) Get(service, account string) (string, error) {
+	// Check arguments
+	if err := checkIdentifiers(service, account); err != nil {
+		return "", err
+	}
+
+	out, err := k.run("find-generic-password", "-s", service, "-a", account, "-w")
+	if err != nil {
+		return "", err
+	}
+
+	// No error
+	return strings.TrimSuffix(out, "\n"), nil
+}
+
+func (k *keychain) Set(service, account, secret string) error {
+	// Check arguments
+	if err := checkIdentifiers(service, account); err != nil {
+		return err
+	}
+
+	// -U updates the existing item
+	_, err := k.run("add-generic-password", "-U", "-s", service, "-a", account, "-w", secret)
+	return err
+}
+
+func (k *keychain) Delete(service, account string) error {
+	// Check arguments
+	if err := checkIdentifiers(service, account); err != nil {
+		return err
+	}
+
+	_, err := k.run("delete-generic-password", "-s", service, "-a", account)
+	return err
+}
+
+func (k *keychain) run(args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	//nolint:gosec // command path is resolved at initialization
+	cmd := exec.Command(k.command, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == errItemNotFound {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("keyring: keychain operation failed: %s", strings.TrimSpace(stderr.String()))
+	}
+
+	// No error
+	return stdout.String(), nil
+}
@@ -0,0 +1,109 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+// +build linux
+
+package keyring
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// system uses the Secret Service (GNOME Keyring, KWallet) through the
+// libsecret secret-tool command.
+func system() (Keyring, error) {
+	path, err := exec.LookPath("secret-tool")
+	if err != nil {
+		return nil, fmt.Errorf("%w: secret-tool command not found", ErrUnsupported)
+	}
+
+	return &secretService{
+		command: path,
+	}, nil
+}
+
+// -----------------------------------------------------------------------------
+
+type secretService struct {
+	command string
+}
+
+func (k *secretService) Get(service, account string) (string, error) {
+	// Check arguments
+	if err := checkIdentifiers(service, account); err != nil {
+		return "", err
+	}
+
+	out, err := k.run("", "lookup", "service", service, "account", account)
+	if err != nil {
+		return "", err
+	}
+
+	// Missing items are not reported as errors
+	if out == "" {
+		return "", ErrNotFound
+	}
+
+	// No error
+	return out, nil
+}
+
+func (k *secretService) Set(service, account, secret string) error {
+	// Check arguments
+	if err := checkIdentifiers(service, account); err != nil {
+		return err
+	}
+
+	// The secret is read from stdin to keep it out of the process list
+	_, err := k.run(secret, "store", fmt.Sprintf("--label=%s (%s)", service, account), "service", service, "account", account)
+	return err
+}
+
+func (k *secretService) Delete(service, account string) error {
+	// Check arguments
+	if _, err := k.Get(service, account); err != nil {
+		return err
+	}
+
+	_, err := k.run("", "clear", "service", service, "account", account)
+	return err
+}
+
+func (k *secretService) run(stdin string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	//nolint:gosec // command path is resolved at initialization
+	cmd := exec.Command(k.command, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		// Lookup exits with 1 for missing items
+		if args[0] == "lookup" && stderr.Len() == 0 {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("keyring: secret service operation failed: %s", strings.TrimSpace(stderr.String()))
+	}
+
+	// No error
+	return stdout.String(), nil
+}
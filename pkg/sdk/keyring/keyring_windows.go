@@ -0,0 +1,142 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build windows
+// +build windows
+
+package keyring
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	crypt32                = syscall.NewLazyDLL("crypt32.dll")
+	kernel32               = syscall.NewLazyDLL("kernel32.dll")
+	procCryptProtectData   = crypt32.NewProc("CryptProtectData")
+	procCryptUnprotectData = crypt32.NewProc("CryptUnprotectData")
+	procLocalFree          = kernel32.NewProc("LocalFree")
+)
+
+// cryptProtectUIForbidden prevents DPAPI from prompting the user.
+const cryptProtectUIForbidden = 0x1
+
+// system uses DPAPI to protect secrets with the current user credentials,
+// protected secrets are stored in the user local application data directory.
+func system() (Keyring, error) {
+	if err := procCryptProtectData.Find(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+
+	dir := os.Getenv("LOCALAPPDATA")
+	if dir == "" {
+		return nil, fmt.Errorf("%w: LOCALAPPDATA is not defined", ErrUnsupported)
+	}
+
+	return &dpapi{
+		files: &fileKeyring{
+			dir: filepath.Join(dir, "harp", "keyring"),
+		},
+	}, nil
+}
+
+// -----------------------------------------------------------------------------
+
+type dpapi struct {
+	files *fileKeyring
+}
+
+func (k *dpapi) Get(service, account string) (string, error) {
+	blob, err := k.files.Get(service, account)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := cryptData(procCryptUnprotectData, []byte(blob))
+	if err != nil {
+		return "", fmt.Errorf("keyring: unable to unprotect secret: %w", err)
+	}
+
+	// No error
+	return string(out), nil
+}
+
+func (k *dpapi) Set(service, account, secret string) error {
+	// Check arguments
+	if err := checkIdentifiers(service, account); err != nil {
+		return err
+	}
+
+	blob, err := cryptData(procCryptProtectData, []byte(secret))
+	if err != nil {
+		return fmt.Errorf("keyring: unable to protect secret: %w", err)
+	}
+
+	return k.files.Set(service, account, string(blob))
+}
+
+func (k *dpapi) Delete(service, account string) error {
+	return k.files.Delete(service, account)
+}
+
+// -----------------------------------------------------------------------------
+
+type dataBlob struct {
+	size uint32
+	data *byte
+}
+
+func newBlob(d []byte) *dataBlob {
+	if len(d) == 0 {
+		return &dataBlob{}
+	}
+	return &dataBlob{
+		size: uint32(len(d)),
+		data: &d[0],
+	}
+}
+
+func (b *dataBlob) bytes() []byte {
+	out := make([]byte, b.size)
+	copy(out, (*[1 << 30]byte)(unsafe.Pointer(b.data))[:b.size:b.size])
+	return out
+}
+
+// cryptData calls CryptProtectData or CryptUnprotectData, both share the
+// same signature.
+func cryptData(proc *syscall.LazyProc, in []byte) ([]byte, error) {
+	var out dataBlob
+
+	r, _, err := proc.Call(
+		uintptr(unsafe.Pointer(newBlob(in))),
+		0, 0, 0, 0,
+		cryptProtectUIForbidden,
+		uintptr(unsafe.Pointer(&out)),
+	)
+	if r == 0 {
+		return nil, err
+	}
+	//nolint:errcheck // nothing to do on failure
+	defer procLocalFree.Call(uintptr(unsafe.Pointer(out.data)))
+
+	// No error
+	return out.bytes(), nil
+}
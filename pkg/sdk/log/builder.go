@@ -19,6 +19,7 @@ package log
 
 import (
 	"context"
+	"fmt"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -39,26 +40,40 @@ type Options struct {
 
 // -----------------------------------------------------------------------------
 
-// DefaultOptions defines default logger options
-var DefaultOptions = &Options{
-	Debug:     false,
-	LogLevel:  "info",
-	AppName:   "changeme",
-	AppID:     "changeme",
-	Version:   "0.0.1",
-	Revision:  "123456789",
-	SentryDSN: "",
+// DefaultOptions defines default logger options.
+//
+// Deprecated: use NewDefaultOptions to avoid sharing mutable options.
+var DefaultOptions = NewDefaultOptions()
+
+// NewDefaultOptions returns default logger options.
+func NewDefaultOptions() *Options {
+	return &Options{
+		Debug:     false,
+		LogLevel:  "info",
+		AppName:   "changeme",
+		AppID:     "changeme",
+		Version:   "0.0.1",
+		Revision:  "123456789",
+		SentryDSN: "",
+	}
 }
 
 // -----------------------------------------------------------------------------
 
-// Setup the logger
-func Setup(ctx context.Context, opts *Options) {
+// New builds a logger according to the given options, without altering the
+// process default logger. Attach it to a context with WithLogger.
+func New(opts *Options) (*zap.Logger, error) {
+	// Check arguments
+	if opts == nil {
+		opts = NewDefaultOptions()
+	}
+
 	// Initialize logs
 	var config zap.Config
 
+	logLevel := opts.LogLevel
 	if opts.Debug {
-		opts.LogLevel = "debug"
+		logLevel = "debug"
 		config = zap.NewDevelopmentConfig()
 		config.DisableCaller = true
 		config.DisableStacktrace = true
@@ -72,33 +87,40 @@ func Setup(ctx context.Context, opts *Options) {
 	}
 
 	// Parse log level
-	errLogLevel := config.Level.UnmarshalText([]byte(opts.LogLevel))
-	if errLogLevel != nil {
-		panic(errLogLevel)
+	if err := config.Level.UnmarshalText([]byte(logLevel)); err != nil {
+		return nil, fmt.Errorf("invalid log level '%s': %w", logLevel, err)
 	}
 
 	// Build real logger
 	logger, err := config.Build(
-		zap.AddCallerSkip(2),
+		zap.Hooks(runFatalHooks),
 	)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("unable to build logger: %w", err)
 	}
 
 	// Add prefix to logger
-	logger = logger.With(
+	return logger.With(
 		zap.String("@appName", opts.AppName),
 		zap.String("@version", opts.Version),
 		zap.String("@revision", opts.Revision),
 		zap.String("@appID", opts.AppID),
 		zap.Namespace("@fields"),
-	)
+	), nil
+}
 
-	// Prepare factory
-	logFactory := NewFactory(logger)
+// Setup builds a logger and defines it as the process default logger and
+// zap global logger. This is reserved to process entrypoints (CLI, servers),
+// libraries should use New and WithLogger instead.
+func Setup(ctx context.Context, opts *Options) {
+	// Build the logger
+	logger, err := New(opts)
+	if err != nil {
+		panic(err)
+	}
 
 	// Override the global factory
-	SetLoggerFactory(logFactory)
+	SetLoggerFactory(NewFactory(logger.WithOptions(zap.AddCallerSkip(2))))
 
 	// Override zap default logger
 	zap.ReplaceGlobals(logger)
@@ -0,0 +1,49 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package log
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type contextKey struct{}
+
+// WithLogger returns a context carrying the given logger. Loggers returned by
+// For for this context delegate to it instead of the process default logger
+// factory, so that embedding applications can route harp logs to their own
+// sinks.
+func WithLogger(ctx context.Context, l *zap.Logger) context.Context {
+	if l == nil {
+		return ctx
+	}
+
+	// Skip the logger wrapper frame
+	return context.WithValue(ctx, contextKey{}, l.WithOptions(zap.AddCallerSkip(1)))
+}
+
+// FromContext returns the logger attached to the given context.
+func FromContext(ctx context.Context) (*zap.Logger, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+
+	l, ok := ctx.Value(contextKey{}).(*zap.Logger)
+	return l, ok
+}
@@ -0,0 +1,95 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestWithLogger_ConcurrentInstances(t *testing.T) {
+	const (
+		instances = 2
+		entries   = 100
+	)
+
+	// Prepare one sink per instance
+	logs := make([]*observer.ObservedLogs, instances)
+	ctxs := make([]context.Context, instances)
+	for i := range logs {
+		core, observed := observer.New(zapcore.DebugLevel)
+		logs[i] = observed
+		ctxs[i] = WithLogger(context.Background(), zap.New(core).With(zap.Int("instance", i)))
+	}
+
+	// Log concurrently from each instance
+	var wg sync.WaitGroup
+	for i := range ctxs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < entries; j++ {
+				For(ctxs[i]).Info(fmt.Sprintf("instance-%d", i))
+				CheckErrCtx(ctxs[i], fmt.Sprintf("instance-%d", i), errors.New("failure"))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Check sinks isolation
+	for i, observed := range logs {
+		if got := observed.Len(); got != 2*entries {
+			t.Errorf("instance %d: expected %d entries, got %d", i, 2*entries, got)
+		}
+		want := fmt.Sprintf("instance-%d", i)
+		if n := observed.FilterMessage(want).Len(); n != 2*entries {
+			t.Errorf("instance %d: expected only its own entries, got %d/%d", i, n, observed.Len())
+		}
+	}
+}
+
+func TestFor_Fallback(t *testing.T) {
+	// Context without logger uses the default factory
+	core, observed := observer.New(zapcore.DebugLevel)
+	factory := NewFactory(zap.New(core))
+
+	factory.For(context.Background()).Info("default")
+	if observed.Len() != 1 {
+		t.Fatalf("expected default factory logger to be used, got %d entries", observed.Len())
+	}
+
+	// Context logger takes precedence
+	ctxCore, ctxObserved := observer.New(zapcore.DebugLevel)
+	ctx := WithLogger(context.Background(), zap.New(ctxCore))
+	factory.For(ctx).Info("context")
+	if observed.Len() != 1 || ctxObserved.Len() != 1 {
+		t.Errorf("expected context logger to be used, got %d/%d entries", observed.Len(), ctxObserved.Len())
+	}
+
+	// Nil logger is ignored
+	if _, ok := FromContext(WithLogger(context.Background(), nil)); ok {
+		t.Error("nil logger must not be attached")
+	}
+}
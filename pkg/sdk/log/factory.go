@@ -47,8 +47,12 @@ func (b factory) Bg() Logger {
 	return &logger{logger: b.logger}
 }
 
-// For returns a context-aware Logger.
+// For returns a context-aware Logger. The logger attached to the context
+// with WithLogger takes precedence over the factory one.
 func (b factory) For(ctx context.Context) Logger {
+	if l, ok := FromContext(ctx); ok {
+		return &logger{logger: l}
+	}
 	return b.Bg()
 }
 
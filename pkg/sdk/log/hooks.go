@@ -0,0 +1,53 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package log
+
+import (
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	fatalHooksMu sync.Mutex
+	fatalHooks   []func()
+)
+
+// OnFatal registers a function called before the process exits on a fatal
+// log entry.
+func OnFatal(fn func()) {
+	fatalHooksMu.Lock()
+	defer fatalHooksMu.Unlock()
+	fatalHooks = append(fatalHooks, fn)
+}
+
+// -----------------------------------------------------------------------------
+
+func runFatalHooks(e zapcore.Entry) error {
+	if e.Level != zapcore.FatalLevel {
+		return nil
+	}
+
+	fatalHooksMu.Lock()
+	defer fatalHooksMu.Unlock()
+	for _, fn := range fatalHooks {
+		fn()
+	}
+
+	return nil
+}
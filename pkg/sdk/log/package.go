@@ -20,45 +20,59 @@ package log
 import (
 	"context"
 	"io"
+	"sync/atomic"
 
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-var defaultFactory LoggerFactory
+// defaultFactory holds the process default logger factory, used when the
+// context doesn't carry a logger.
+var defaultFactory atomic.Value
 
-// -----------------------------------------------------------------------------
-
-func init() {
-	SetLoggerFactory(NewFactory(zap.L()))
+// factoryHolder keeps a consistent concrete type for atomic.Value.
+type factoryHolder struct {
+	factory LoggerFactory
 }
 
-// SetLoggerFactory defines the default package logger
+// -----------------------------------------------------------------------------
+
+// SetLoggerFactory defines the process default logger factory.
+//
+// This is kept for CLI compatibility, libraries and embedding applications
+// should attach their logger to the context using WithLogger instead.
 func SetLoggerFactory(instance LoggerFactory) {
-	if defaultFactory != nil {
-		defaultFactory.Bg().Debug("Replacing logger factory", zap.String("old", defaultFactory.Name()), zap.String("new", instance.Name()))
-	} else {
-		instance.Bg().Debug("Initializing logger factory", zap.String("factory", instance.Name()))
+	if instance == nil {
+		return
 	}
-	defaultFactory = instance
+	instance.Bg().Debug("Initializing logger factory", zap.String("factory", instance.Name()))
+	defaultFactory.Store(factoryHolder{factory: instance})
 }
 
 // -----------------------------------------------------------------------------
 
 // Bg delegates a no-context logger
 func Bg() Logger {
-	return checkFactory(defaultFactory).Bg()
+	return Default().Bg()
 }
 
-// For delegates a context logger
+// For delegates a context logger, the logger attached to the context with
+// WithLogger takes precedence over the default factory.
 func For(ctx context.Context) Logger {
-	return checkFactory(defaultFactory).For(ctx)
+	if l, ok := FromContext(ctx); ok {
+		return &logger{logger: l}
+	}
+	return Default().For(ctx)
 }
 
-// Default returns the logger factory
+// Default returns the process default logger factory. It discards all
+// entries until a factory is defined with SetLoggerFactory.
 func Default() LoggerFactory {
-	return checkFactory(defaultFactory)
+	if h, ok := defaultFactory.Load().(factoryHolder); ok {
+		return h.factory
+	}
+	return nopFactory
 }
 
 // CheckErr handles error correctly
@@ -73,7 +87,7 @@ func CheckErr(msg string, err error, fields ...zapcore.Field) {
 func CheckErrCtx(ctx context.Context, msg string, err error, fields ...zapcore.Field) {
 	if err != nil {
 		fields = append(fields, zap.Error(errors.WithStack(err)))
-		Default().For(ctx).Error(msg, fields...)
+		For(ctx).Error(msg, fields...)
 	}
 }
 
@@ -89,15 +103,10 @@ func SafeClose(c io.Closer, msg string, fields ...zapcore.Field) {
 func SafeCloseCtx(ctx context.Context, c io.Closer, msg string, fields ...zapcore.Field) {
 	if cerr := c.Close(); cerr != nil {
 		fields = append(fields, zap.Error(errors.WithStack(cerr)))
-		Default().For(ctx).Error(msg, fields...)
+		For(ctx).Error(msg, fields...)
 	}
 }
 
 // -----------------------------------------------------------------------------
 
-func checkFactory(defaultFactory LoggerFactory) LoggerFactory {
-	if defaultFactory == nil {
-		panic("Unable to create logger instance, you have to register an adapter first.")
-	}
-	return defaultFactory
-}
+var nopFactory = NewFactory(zap.NewNop())
@@ -19,6 +19,7 @@ package diagnostic
 
 import (
 	"context"
+	"expvar"
 	"net/http"
 	"net/http/pprof"
 
@@ -55,6 +56,7 @@ func Register(ctx context.Context, conf *Config, r *http.ServeMux) (func(), erro
 		r.Handle("/debug/heap", pprof.Handler("heap"))
 		r.Handle("/debug/threadcreate", pprof.Handler("threadcreate"))
 		r.Handle("/debug/block", pprof.Handler("block"))
+		r.Handle("/debug/vars", expvar.Handler())
 	}
 
 	// No error
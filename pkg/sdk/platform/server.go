@@ -48,6 +48,22 @@ type Server struct {
 	Network         string
 	Address         string
 	Builder         func(ln net.Listener, group *run.Group)
+	// Logger overrides the process default logger when set.
+	Logger *zap.Logger
+	// GracePeriod is the maximum duration allowed to drain in-flight
+	// requests, defaults to DefaultGracePeriod.
+	GracePeriod time.Duration
+	// Readiness is served on the instrumentation /readyz endpoint, it is
+	// marked as draining as soon as the shutdown starts.
+	Readiness *Readiness
+	// Cleanup is called once all components are stopped to release
+	// resources.
+	Cleanup func(context.Context)
+}
+
+// forceExit is called when a second signal is received during the shutdown.
+var forceExit = func() {
+	os.Exit(1)
 }
 
 // Serve starts the server listening process
@@ -57,14 +73,23 @@ func Serve(ctx context.Context, srv *Server) error {
 	appID := uniuri.NewLen(64)
 
 	// Prepare logger
-	log.Setup(ctx, &log.Options{
-		Debug:    srv.Debug,
-		AppName:  srv.Name,
-		AppID:    appID,
-		Version:  srv.Version,
-		Revision: srv.Revision,
-		LogLevel: srv.Instrumentation.Logs.Level,
-	})
+	if srv.Logger != nil {
+		ctx = log.WithLogger(ctx, srv.Logger)
+	} else if _, ok := log.FromContext(ctx); !ok {
+		log.Setup(ctx, &log.Options{
+			Debug:    srv.Debug,
+			AppName:  srv.Name,
+			AppID:    appID,
+			Version:  srv.Version,
+			Revision: srv.Revision,
+			LogLevel: srv.Instrumentation.Logs.Level,
+		})
+	}
+
+	// Prepare readiness probe
+	if srv.Readiness == nil {
+		srv.Readiness = &Readiness{}
+	}
 
 	// Preparing instrumentation
 	instrumentationRouter := instrumentServer(ctx, srv, appID)
@@ -93,11 +118,7 @@ func Serve(ctx context.Context, srv *Server) error {
 			func(e error) {
 				log.For(ctx).Info("Shutting instrumentation server down")
 
-				ctxShutdown, cancel := context.WithTimeout(ctx, 60*time.Second)
-				defer cancel()
-
-				log.CheckErrCtx(ctx, "Error raised while shutting down the server", server.Shutdown(ctxShutdown))
-				log.SafeClose(server, "Unable to close instrumentation server")
+				log.CheckErrCtx(ctx, "Error raised while shutting down the server", DrainHTTP(ctx, server, srv.GracePeriod))
 			},
 		)
 	}
@@ -115,25 +136,39 @@ func Serve(ctx context.Context, srv *Server) error {
 	{
 		var (
 			cancelInterrupt = make(chan struct{})
+			stopped         = make(chan struct{})
 			ch              = make(chan os.Signal, 2)
 		)
-		defer close(ch)
+		signal.Notify(ch, syscall.SIGINT, syscall.SIGTERM)
+		defer func() {
+			signal.Stop(ch)
+			close(stopped)
+		}()
 
 		group.Add(
 			func() error {
-				signal.Notify(ch, syscall.SIGINT, syscall.SIGTERM)
-
 				select {
-				case <-ch:
-					log.For(ctx).Info("Captured signal")
+				case sig := <-ch:
+					log.For(ctx).Info("Captured signal, draining connections", zap.Stringer("signal", sig))
+					srv.Readiness.Drain()
+
+					// Force exit on second signal
+					go func() {
+						select {
+						case <-ch:
+							log.For(ctx).Warn("Captured second signal, forcing exit")
+							forceExit()
+						case <-stopped:
+						}
+					}()
 				case <-cancelInterrupt:
 				}
 
 				return nil
 			},
 			func(e error) {
+				srv.Readiness.Drain()
 				close(cancelInterrupt)
-				signal.Stop(ch)
 			},
 		)
 	}
@@ -142,12 +177,26 @@ func Serve(ctx context.Context, srv *Server) error {
 	upg.SetupGracefulRestart(ctx, group)
 
 	// Run goroutine group
-	return group.Run()
+	err = group.Run()
+
+	// Release resources
+	if srv.Cleanup != nil {
+		srv.Cleanup(ctx)
+	}
+
+	return err
 }
 
 func instrumentServer(ctx context.Context, srv *Server, appID string) *http.ServeMux {
 	instrumentationRouter := http.NewServeMux()
 
+	// Probes
+	instrumentationRouter.Handle("/readyz", srv.Readiness)
+	instrumentationRouter.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "ok")
+	})
+
 	// Register common features
 	if srv.Instrumentation.Diagnostic.Enabled {
 		cancelFunc, err := diagnostic.Register(ctx, &srv.Instrumentation.Diagnostic.Config, instrumentationRouter)
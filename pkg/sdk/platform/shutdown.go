@@ -0,0 +1,99 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package platform
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultGracePeriod is the default duration allowed to drain in-flight
+// requests on shutdown.
+const DefaultGracePeriod = 30 * time.Second
+
+// Readiness reports whether the server accepts new traffic. It is flipped
+// as soon as the shutdown starts so that load balancers stop routing requests
+// while in-flight ones are drained.
+type Readiness struct {
+	draining int32
+}
+
+// Drain marks the server as not ready.
+func (r *Readiness) Drain() {
+	atomic.StoreInt32(&r.draining, 1)
+}
+
+// Ready returns true when the server accepts new traffic.
+func (r *Readiness) Ready() bool {
+	return atomic.LoadInt32(&r.draining) == 0
+}
+
+// ServeHTTP implements the readiness probe handler.
+func (r *Readiness) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	if !r.Ready() {
+		http.Error(w, "draining", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "ok")
+}
+
+// Drain stops a server gracefully and forces it to stop when the grace period
+// is exceeded. The graceful function must stop accepting new connections and
+// wait for in-flight requests.
+func Drain(ctx context.Context, gracePeriod time.Duration, graceful func(context.Context) error, force func() error) error {
+	if gracePeriod <= 0 {
+		gracePeriod = DefaultGracePeriod
+	}
+
+	ctxDrain, cancel := context.WithTimeout(ctx, gracePeriod)
+	defer cancel()
+
+	// Run graceful stop in background, some implementations ignore context.
+	done := make(chan error, 1)
+	go func() {
+		done <- graceful(ctxDrain)
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			return nil
+		}
+		if ctxDrain.Err() == nil {
+			return err
+		}
+	case <-ctxDrain.Done():
+	}
+
+	// Grace period exceeded
+	if err := force(); err != nil {
+		return fmt.Errorf("unable to force server stop: %w", err)
+	}
+
+	return fmt.Errorf("grace period of %s exceeded, remaining connections closed: %w", gracePeriod, context.DeadlineExceeded)
+}
+
+// DrainHTTP drains the given HTTP server.
+func DrainHTTP(ctx context.Context, server *http.Server, gracePeriod time.Duration) error {
+	return Drain(ctx, gracePeriod, server.Shutdown, server.Close)
+}
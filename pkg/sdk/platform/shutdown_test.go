@@ -0,0 +1,153 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package platform
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func startServer(t *testing.T, h http.Handler) (*http.Server, string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+
+	server := &http.Server{Handler: h}
+	go func() {
+		_ = server.Serve(ln)
+	}()
+
+	return server, "http://" + ln.Addr().String()
+}
+
+func TestDrainHTTP_InFlight(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	server, baseURL := startServer(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Issue a slow request
+	result := make(chan error, 1)
+	go func() {
+		resp, err := http.Get(baseURL + "/slow")
+		if err != nil {
+			result <- err
+			return
+		}
+		defer resp.Body.Close()
+		_, _ = ioutil.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK {
+			result <- errors.New(resp.Status)
+			return
+		}
+		result <- nil
+	}()
+	<-started
+
+	// Trigger shutdown
+	drained := make(chan error, 1)
+	go func() {
+		drained <- DrainHTTP(context.Background(), server, 5*time.Second)
+	}()
+
+	// Wait for listener to be closed
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		conn, err := net.DialTimeout("tcp", baseURL[len("http://"):], 100*time.Millisecond)
+		if err != nil {
+			break
+		}
+		conn.Close()
+		if time.Now().After(deadline) {
+			t.Fatal("new connections are still accepted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Complete the slow request
+	close(release)
+	if err := <-result; err != nil {
+		t.Fatalf("in-flight request should complete, got %v", err)
+	}
+	if err := <-drained; err != nil {
+		t.Fatalf("unexpected drain error: %v", err)
+	}
+}
+
+func TestDrainHTTP_GracePeriodExceeded(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	server, baseURL := startServer(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		close(started)
+		<-release
+	}))
+
+	result := make(chan error, 1)
+	go func() {
+		resp, err := http.Get(baseURL + "/stuck")
+		if err == nil {
+			resp.Body.Close()
+		}
+		result <- err
+	}()
+	<-started
+
+	err := DrainHTTP(context.Background(), server, 50*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded error, got %v", err)
+	}
+	if err := <-result; err == nil {
+		t.Fatal("stuck request should be interrupted")
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	r := &Readiness{}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected ready status, got %d", rec.Code)
+	}
+
+	r.Drain()
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected draining status, got %d", rec.Code)
+	}
+	if r.Ready() {
+		t.Fatal("readiness should be drained")
+	}
+}
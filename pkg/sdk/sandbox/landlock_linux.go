@@ -0,0 +1,162 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux && go1.16
+// +build linux,go1.16
+
+package sandbox
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	sysLandlockCreateRuleset = 444
+	sysLandlockAddRule       = 445
+	sysLandlockRestrictSelf  = 446
+
+	landlockCreateRulesetVersion = 1 << 0
+	landlockRulePathBeneath      = 1
+
+	oPath = 0x200000
+
+	accessExecute    = 1 << 0
+	accessWriteFile  = 1 << 1
+	accessReadFile   = 1 << 2
+	accessReadDir    = 1 << 3
+	accessRemoveDir  = 1 << 4
+	accessRemoveFile = 1 << 5
+	accessMakeChar   = 1 << 6
+	accessMakeDir    = 1 << 7
+	accessMakeReg    = 1 << 8
+	accessMakeSock   = 1 << 9
+	accessMakeFifo   = 1 << 10
+	accessMakeBlock  = 1 << 11
+	accessMakeSym    = 1 << 12
+	accessTruncate   = 1 << 14
+
+	// Rights applicable to regular files
+	accessFileMask = accessExecute | accessWriteFile | accessReadFile | accessTruncate
+	// Rights granted on readable paths
+	accessRead = accessReadFile | accessReadDir
+	// Rights granted on the parent directory of writable paths
+	accessWrite = accessRead | accessWriteFile | accessMakeReg | accessRemoveFile | accessTruncate
+	// Rights granted on writable directories
+	accessWriteDir = accessWrite | accessMakeDir
+)
+
+// networkReadPaths lists system paths read by network clients.
+var networkReadPaths = []string{"/etc", "/usr/share/ca-certificates", "/usr/share/pki"}
+
+type landlockRulesetAttr struct {
+	handledAccessFS uint64
+}
+
+type landlockPathBeneathAttr struct {
+	allowedAccess uint64
+	parentFd      int32
+}
+
+func applyLandlock(p *Policy) error {
+	// Retrieve supported ABI version
+	abi, _, errno := syscall.Syscall(sysLandlockCreateRuleset, 0, 0, landlockCreateRulesetVersion)
+	if errno != 0 {
+		return fmt.Errorf("landlock is not supported by the kernel: %w", errno)
+	}
+
+	// Handle all supported filesystem rights
+	handled := uint64(accessExecute | accessWriteFile | accessReadFile | accessReadDir |
+		accessRemoveDir | accessRemoveFile | accessMakeChar | accessMakeDir |
+		accessMakeReg | accessMakeSock | accessMakeFifo | accessMakeBlock | accessMakeSym)
+	if abi >= 3 {
+		handled |= accessTruncate
+	}
+
+	// Create ruleset
+	attr := landlockRulesetAttr{handledAccessFS: handled}
+	fd, _, errno := syscall.Syscall(sysLandlockCreateRuleset, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return fmt.Errorf("unable to create landlock ruleset: %w", errno)
+	}
+	defer syscall.Close(int(fd))
+
+	// Add rules
+	for _, path := range p.ReadPaths {
+		if err := addPathRule(int(fd), path, accessRead&handled); err != nil {
+			return err
+		}
+	}
+	for _, path := range p.WritePaths {
+		if err := addPathRule(int(fd), path, accessWrite&handled); err != nil {
+			return err
+		}
+		if err := addPathRule(int(fd), filepath.Dir(path), accessWrite&handled); err != nil {
+			return err
+		}
+	}
+	for _, path := range p.WriteDirs {
+		if err := addPathRule(int(fd), path, accessWriteDir&handled); err != nil {
+			return err
+		}
+	}
+	if p.Network {
+		// Resolver and certificate authorities configuration
+		for _, path := range networkReadPaths {
+			if err := addPathRule(int(fd), path, accessRead&handled); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Enforce ruleset
+	return allThreads(sysLandlockRestrictSelf, fd, 0, 0)
+}
+
+func addPathRule(rulesetFd int, path string, access uint64) error {
+	// Missing paths can't be granted
+	fi, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to inspect sandbox path '%s': %w", path, err)
+	}
+	if !fi.IsDir() {
+		access &= accessFileMask
+	}
+
+	f, err := os.OpenFile(path, os.O_RDONLY|oPath|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("unable to open sandbox path '%s': %w", path, err)
+	}
+	defer f.Close()
+
+	attr := landlockPathBeneathAttr{
+		allowedAccess: access,
+		parentFd:      int32(f.Fd()),
+	}
+	if _, _, errno := syscall.Syscall6(sysLandlockAddRule, uintptr(rulesetFd), landlockRulePathBeneath, uintptr(unsafe.Pointer(&attr)), 0, 0, 0); errno != 0 {
+		return fmt.Errorf("unable to allow sandbox path '%s': %w", path, errno)
+	}
+
+	// No error
+	return nil
+}
@@ -0,0 +1,153 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package sandbox provides an opt-in process self-sandboxing restricting
+// filesystem access to declared paths, and blocking process execution,
+// tracing and network sockets for purely local commands.
+//
+// Restrictions rely on Linux landlock and seccomp, other platforms ignore
+// them with a warning.
+package sandbox
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// EnvVar is the environment variable used to enable the sandbox.
+const EnvVar = "HARP_SANDBOX"
+
+var (
+	mu         sync.Mutex
+	enabled    bool
+	readPaths  = map[string]struct{}{}
+	writePaths = map[string]struct{}{}
+	writeDirs  = map[string]struct{}{}
+	network    bool
+)
+
+// Enable the sandbox.
+func Enable() {
+	mu.Lock()
+	defer mu.Unlock()
+	enabled = true
+}
+
+// Enabled returns true if the sandbox has been enabled by flag or
+// environment.
+func Enabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if enabled {
+		return true
+	}
+	v, err := strconv.ParseBool(strings.TrimSpace(os.Getenv(EnvVar)))
+	return err == nil && v
+}
+
+// AllowRead declares a path read by the command.
+func AllowRead(path string) {
+	declare(readPaths, path)
+}
+
+// AllowWrite declares a path written by the command.
+func AllowWrite(path string) {
+	declare(writePaths, path)
+}
+
+// AllowWriteDir declares a directory where the command creates files and
+// sub-directories. The directory must exist before the sandbox is applied.
+func AllowWriteDir(path string) {
+	declare(writeDirs, path)
+}
+
+// RequireNetwork declares that the command requires network access.
+func RequireNetwork() {
+	mu.Lock()
+	defer mu.Unlock()
+	network = true
+}
+
+// -----------------------------------------------------------------------------
+
+// Policy describes the sandbox restrictions.
+type Policy struct {
+	// ReadPaths lists readable files and directories.
+	ReadPaths []string
+	// WritePaths lists writable files, their parent directory is writable.
+	WritePaths []string
+	// WriteDirs lists directories where files can be created.
+	WriteDirs []string
+	// Local blocks process execution and tracing.
+	Local bool
+	// Network allows socket creation for local commands.
+	Network bool
+}
+
+// Current returns the policy built from declared paths and the harp
+// configuration directory.
+func Current(local, requireNetwork bool) *Policy {
+	mu.Lock()
+	defer mu.Unlock()
+
+	p := &Policy{
+		ReadPaths:  sortedKeys(readPaths),
+		WritePaths: sortedKeys(writePaths),
+		WriteDirs:  sortedKeys(writeDirs),
+		Local:      local,
+		Network:    requireNetwork || network,
+	}
+
+	// Add configuration directory
+	if dir, err := os.UserConfigDir(); err == nil {
+		p.ReadPaths = append(p.ReadPaths, filepath.Join(dir, "harp"))
+	}
+
+	return p
+}
+
+// -----------------------------------------------------------------------------
+
+func declare(m map[string]struct{}, path string) {
+	// Ignore standard streams
+	if path == "" || path == "-" {
+		return
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	m[abs] = struct{}{}
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
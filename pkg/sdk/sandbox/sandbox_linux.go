@@ -0,0 +1,84 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux && go1.16
+// +build linux,go1.16
+
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"syscall"
+)
+
+// Supported returns true if the kernel supports landlock filesystem
+// restrictions.
+func Supported() bool {
+	_, _, errno := syscall.Syscall(sysLandlockCreateRuleset, 0, 0, landlockCreateRulesetVersion)
+	return errno == 0
+}
+
+// Apply restricts the current process according to the given policy. All
+// process threads are restricted and restrictions can't be removed.
+func Apply(_ context.Context, p *Policy) error {
+	// Check arguments
+	if p == nil {
+		return fmt.Errorf("unable to apply a nil policy")
+	}
+
+	// Restrictions are applied from a single thread
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	// Required to apply restrictions without privileges
+	if err := allThreads(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0); err != nil {
+		return fmt.Errorf("unable to set no_new_privs: %w", err)
+	}
+
+	// Restrict filesystem access
+	if err := applyLandlock(p); err != nil {
+		return fmt.Errorf("unable to apply filesystem restrictions: %w", err)
+	}
+
+	// Restrict system calls
+	if p.Local {
+		if err := applySeccomp(p); err != nil {
+			return fmt.Errorf("unable to apply system call restrictions: %w", err)
+		}
+	}
+
+	// No error
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+const prSetNoNewPrivs = 38
+
+// allThreads invokes the syscall on all process threads.
+func allThreads(trap, a1, a2, a3 uintptr) error {
+	_, _, errno := syscall.AllThreadsSyscall(trap, a1, a2, a3)
+	switch {
+	case errno == syscall.ENOTSUP:
+		return fmt.Errorf("process-wide restrictions require a build without cgo: %w", errno)
+	case errno != 0:
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux && go1.16
+// +build linux,go1.16
+
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+const (
+	childEnvVar = "HARP_SANDBOX_TEST_CHILD"
+
+	exitAllowed     = 0
+	exitDenied      = 3
+	exitUnsupported = 4
+)
+
+// TestSandboxChild is executed in a subprocess to apply the sandbox.
+func TestSandboxChild(t *testing.T) {
+	check := os.Getenv(childEnvVar)
+	if check == "" {
+		return
+	}
+
+	p := &Policy{
+		ReadPaths: []string{os.Getenv("HARP_SANDBOX_TEST_ALLOWED")},
+		Local:     true,
+	}
+	if err := Apply(context.Background(), p); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if errors.Is(err, syscall.ENOTSUP) || errors.Is(err, syscall.ENOSYS) || errors.Is(err, syscall.EOPNOTSUPP) {
+			os.Exit(exitUnsupported)
+		}
+		os.Exit(1)
+	}
+
+	var err error
+	switch check {
+	case "read-allowed":
+		_, err = ioutil.ReadFile(os.Getenv("HARP_SANDBOX_TEST_ALLOWED"))
+	case "read-denied":
+		_, err = ioutil.ReadFile(os.Getenv("HARP_SANDBOX_TEST_DENIED"))
+	case "exec":
+		// Denied by the seccomp filter before the landlock check
+		err = syscall.Exec("/bin/true", []string{"true"}, nil)
+		if !errors.Is(err, syscall.EPERM) {
+			fmt.Fprintln(os.Stderr, "unexpected error:", err)
+			os.Exit(1)
+		}
+	case "socket":
+		var fd int
+		fd, err = syscall.Socket(syscall.AF_INET, syscall.SOCK_STREAM, 0)
+		if err == nil {
+			syscall.Close(fd)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitDenied)
+	}
+	os.Exit(exitAllowed)
+}
+
+func TestApply(t *testing.T) {
+	dir, err := ioutil.TempDir("", "harp-sandbox-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	allowed := filepath.Join(dir, "allowed.txt")
+	denied := filepath.Join(dir, "denied.txt")
+	for _, f := range []string{allowed, denied} {
+		if err := ioutil.WriteFile(f, []byte("secret"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		desc  string
+		check string
+		want  int
+	}{
+		{
+			desc:  "declared path",
+			check: "read-allowed",
+			want:  exitAllowed,
+		},
+		{
+			desc:  "undeclared path",
+			check: "read-denied",
+			want:  exitDenied,
+		},
+		{
+			desc:  "process execution",
+			check: "exec",
+			want:  exitDenied,
+		},
+		{
+			desc:  "socket creation",
+			check: "socket",
+			want:  exitDenied,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			cmd := exec.Command(os.Args[0], "-test.run=^TestSandboxChild$")
+			cmd.Env = append(os.Environ(),
+				childEnvVar+"="+tC.check,
+				"HARP_SANDBOX_TEST_ALLOWED="+allowed,
+				"HARP_SANDBOX_TEST_DENIED="+denied,
+			)
+			out, _ := cmd.CombinedOutput()
+
+			got := cmd.ProcessState.ExitCode()
+			if got == exitUnsupported {
+				t.Skipf("sandbox is not supported: %s", out)
+			}
+			if got != tC.want {
+				t.Errorf("%q. Apply():\ngot exit code %d, want %d\n%s", tC.desc, got, tC.want, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build !linux || !go1.16
+// +build !linux !go1.16
+
+package sandbox
+
+import (
+	"context"
+	"runtime"
+
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/log"
+)
+
+// Supported returns false, restrictions are not supported on this platform.
+func Supported() bool {
+	return false
+}
+
+// Apply is a no-op on this platform.
+func Apply(ctx context.Context, _ *Policy) error {
+	log.For(ctx).Warn("sandbox is not supported on this platform, restrictions are not applied", zap.String("os", runtime.GOOS))
+	return nil
+}
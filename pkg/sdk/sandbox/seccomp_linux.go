@@ -0,0 +1,92 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux && go1.16
+// +build linux,go1.16
+
+package sandbox
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	seccompSetModeFilter   = 1
+	seccompFilterFlagTsync = 1
+
+	seccompRetAllow = 0x7fff0000
+	seccompRetErrno = 0x00050000
+
+	// struct seccomp_data offsets
+	seccompDataNr   = 0
+	seccompDataArch = 4
+)
+
+// applySeccomp installs a filter on all threads denying process execution
+// and tracing, and socket creation when network is not required.
+func applySeccomp(p *Policy) error {
+	if auditArch == 0 {
+		return fmt.Errorf("unsupported architecture")
+	}
+
+	// Build denied syscall list
+	denied := append([]uint32{}, deniedLocal...)
+	if !p.Network {
+		denied = append(denied, deniedNetwork...)
+	}
+
+	prog := filter(denied)
+	fprog := syscall.SockFprog{
+		Len:    uint16(len(prog)),
+		Filter: &prog[0],
+	}
+
+	_, _, errno := syscall.Syscall(sysSeccomp, seccompSetModeFilter, seccompFilterFlagTsync, uintptr(unsafe.Pointer(&fprog)))
+	if errno != 0 {
+		return fmt.Errorf("unable to install seccomp filter: %w", errno)
+	}
+
+	// No error
+	return nil
+}
+
+// filter builds the BPF program returning EPERM for denied syscalls.
+func filter(denied []uint32) []syscall.SockFilter {
+	ret := func(v uint32) syscall.SockFilter {
+		return syscall.SockFilter{Code: syscall.BPF_RET | syscall.BPF_K, K: v}
+	}
+	deny := ret(seccompRetErrno | uint32(syscall.EPERM))
+
+	prog := []syscall.SockFilter{
+		// Deny on architecture mismatch
+		{Code: syscall.BPF_LD | syscall.BPF_W | syscall.BPF_ABS, K: seccompDataArch},
+		{Code: syscall.BPF_JMP | syscall.BPF_JEQ | syscall.BPF_K, Jt: 1, Jf: 0, K: auditArch},
+		deny,
+		// Load syscall number
+		{Code: syscall.BPF_LD | syscall.BPF_W | syscall.BPF_ABS, K: seccompDataNr},
+	}
+	for _, nr := range denied {
+		prog = append(prog,
+			syscall.SockFilter{Code: syscall.BPF_JMP | syscall.BPF_JEQ | syscall.BPF_K, Jt: 0, Jf: 1, K: nr},
+			deny,
+		)
+	}
+
+	return append(prog, ret(seccompRetAllow))
+}
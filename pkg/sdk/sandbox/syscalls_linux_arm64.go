@@ -0,0 +1,30 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package sandbox
+
+// AUDIT_ARCH_AARCH64
+const auditArch = 0xc00000b7
+
+const sysSeccomp = 277
+
+var (
+	// execve, execveat, ptrace, process_vm_readv, process_vm_writev
+	deniedLocal = []uint32{221, 281, 117, 270, 271}
+	// socket
+	deniedNetwork = []uint32{198}
+)
@@ -0,0 +1,63 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package security
+
+import (
+	"github.com/awnumar/memguard"
+)
+
+// SensitiveBuffer is an append-only buffer registered as sensitive. Previous
+// backing arrays are wiped when it grows.
+type SensitiveBuffer struct {
+	buf        []byte
+	unregister func()
+}
+
+// Write appends the given bytes to the buffer.
+func (b *SensitiveBuffer) Write(p []byte) (int, error) {
+	if len(b.buf)+len(p) > cap(b.buf) {
+		grown := make([]byte, len(b.buf), 2*cap(b.buf)+len(p))
+		copy(grown, b.buf)
+		b.Destroy()
+		b.buf = grown
+		b.unregister = RegisterSensitive(grown[:cap(grown)])
+	}
+	b.buf = append(b.buf, p...)
+
+	return len(p), nil
+}
+
+// Bytes returns the buffer content, it is wiped by Destroy.
+func (b *SensitiveBuffer) Bytes() []byte {
+	return b.buf
+}
+
+// String returns a copy of the buffer content.
+func (b *SensitiveBuffer) String() string {
+	return string(b.buf)
+}
+
+// Destroy wipes and unregisters the buffer.
+func (b *SensitiveBuffer) Destroy() {
+	if b.unregister != nil {
+		b.unregister()
+		b.unregister = nil
+	}
+	memguard.WipeBytes(b.buf[:cap(b.buf)])
+	b.buf = nil
+}
@@ -0,0 +1,38 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package security
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// AllowCoreDumpEnvVar is the environment variable used to keep core dumps
+// enabled.
+const AllowCoreDumpEnvVar = "HARP_ALLOW_COREDUMP"
+
+// DisableCoreDumps prevents the process memory from being written to a core
+// dump when it crashes, unless allowed by the environment.
+func DisableCoreDumps() error {
+	if v, err := strconv.ParseBool(strings.TrimSpace(os.Getenv(AllowCoreDumpEnvVar))); err == nil && v {
+		return nil
+	}
+
+	return disableCoreDumps()
+}
@@ -0,0 +1,175 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+const (
+	blockTypeCertificate = "CERTIFICATE"
+)
+
+type parseOptions struct {
+	ignoreKeyUsage bool
+}
+
+// ParseOption defines key parsing options.
+type ParseOption func(*parseOptions)
+
+// IgnoreKeyUsage disables the JWK usage declaration check, for existing keys
+// declaring an unexpected usage.
+func IgnoreKeyUsage() ParseOption {
+	return func(opts *parseOptions) {
+		opts.ignoreKeyUsage = true
+	}
+}
+
+// ParsePrivateKey decodes a PEM (PKCS#8, PKCS#1 or SEC1) or JWK encoded
+// private key.
+func ParsePrivateKey(raw []byte) (crypto.PrivateKey, error) {
+	// Decode as JWK
+	if isJSON(raw) {
+		var jwk jose.JSONWebKey
+		if err := jwk.UnmarshalJSON(raw); err != nil {
+			return nil, fmt.Errorf("unable to decode JWK: %w", err)
+		}
+		if jwk.IsPublic() {
+			return nil, errors.New("JWK is not a private key")
+		}
+		return jwk.Key, nil
+	}
+
+	// Decode as PEM
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case blockTypePrivateKey:
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	case blockTypeRsaPrivateKey:
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case blockTypeEcdsaPrivateKey:
+		return x509.ParseECPrivateKey(block.Bytes)
+	default:
+	}
+
+	return nil, fmt.Errorf("unsupported PEM block type '%s'", block.Type)
+}
+
+// ParseSigningKey decodes a private key used to produce signatures. JWK
+// usage declaration, when present, must allow signing unless the check is
+// disabled.
+func ParseSigningKey(raw []byte, opts ...ParseOption) (crypto.Signer, error) {
+	dopts := &parseOptions{}
+	for _, o := range opts {
+		o(dopts)
+	}
+
+	// Check JWK usage
+	if isJSON(raw) && !dopts.ignoreKeyUsage {
+		if err := ValidateKeyUsage(raw, KeyUsageSign); err != nil {
+			return nil, err
+		}
+	}
+
+	// Decode private key
+	pk, err := ParsePrivateKey(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check signing capability
+	signer, ok := pk.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("private key type %T can't be used to sign", pk)
+	}
+
+	// No error
+	return signer, nil
+}
+
+// ParseVerificationKey decodes a PEM (PKIX public key or certificate) or JWK
+// encoded public key used to verify signatures. A private key is accepted and
+// reduced to its public part. JWK usage declaration, when present, must allow
+// signature verification unless the check is disabled.
+func ParseVerificationKey(raw []byte, opts ...ParseOption) (crypto.PublicKey, error) {
+	dopts := &parseOptions{}
+	for _, o := range opts {
+		o(dopts)
+	}
+
+	// Decode as JWK
+	if isJSON(raw) {
+		if !dopts.ignoreKeyUsage {
+			if err := ValidateKeyUsage(raw, KeyUsageSign); err != nil {
+				return nil, err
+			}
+		}
+		var jwk jose.JSONWebKey
+		if err := jwk.UnmarshalJSON(raw); err != nil {
+			return nil, fmt.Errorf("unable to decode JWK: %w", err)
+		}
+		return jwk.Public().Key, nil
+	}
+
+	// Decode as PEM
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case blockTypePublicKey, blockTypeRsaPublicKey, blockTypeEcdsaPublicKey:
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case blockTypeCertificate:
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse certificate: %w", err)
+		}
+		return cert.PublicKey, nil
+	default:
+	}
+
+	// Try as private key
+	pk, err := ParsePrivateKey(raw)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := pk.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("private key type %T has no public key", pk)
+	}
+
+	return signer.Public(), nil
+}
+
+// -----------------------------------------------------------------------------
+
+func isJSON(raw []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{"))
+}
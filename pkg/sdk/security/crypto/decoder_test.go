@@ -0,0 +1,107 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package crypto
+
+import (
+	"crypto"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseSigningKey(t *testing.T) {
+	for _, keyType := range []string{"rsa", "ec:p256", "ssh"} {
+		t.Run(keyType, func(t *testing.T) {
+			pub, priv, err := generateKeyPair(keyType)
+			if err != nil {
+				t.Fatalf("unable to generate key: %v", err)
+			}
+
+			privPEM, err := ToPEM(priv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pubPEM, err := ToPEM(pub)
+			if err != nil {
+				t.Fatal(err)
+			}
+			privJWK, err := ToJWKWithUsage(priv, KeyUsageSign, time.Time{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, raw := range []string{privPEM, privJWK} {
+				signer, err := ParseSigningKey([]byte(raw))
+				if err != nil {
+					t.Fatalf("unable to parse signing key: %v", err)
+				}
+				if !publicEqual(signer.Public(), pub) {
+					t.Errorf("unexpected public key %T", signer.Public())
+				}
+			}
+			for _, raw := range []string{pubPEM, privPEM, privJWK} {
+				got, err := ParseVerificationKey([]byte(raw))
+				if err != nil {
+					t.Fatalf("unable to parse verification key: %v", err)
+				}
+				if !publicEqual(got, pub) {
+					t.Errorf("unexpected verification key %T", got)
+				}
+			}
+		})
+	}
+}
+
+func TestParseSigningKey_Usage(t *testing.T) {
+	_, priv, err := generateKeyPair("ec:p256")
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	jwk, err := ToJWKWithUsage(priv, KeyUsageEncrypt, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ParseSigningKey([]byte(jwk)); !errors.Is(err, ErrKeyUsageMismatch) {
+		t.Errorf("expected ErrKeyUsageMismatch, got %v", err)
+	}
+	if _, err := ParseVerificationKey([]byte(jwk)); !errors.Is(err, ErrKeyUsageMismatch) {
+		t.Errorf("expected ErrKeyUsageMismatch, got %v", err)
+	}
+
+	// Usage check is disabled
+	signer, err := ParseSigningKey([]byte(jwk), IgnoreKeyUsage())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pub, err := ParseVerificationKey([]byte(jwk), IgnoreKeyUsage())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !publicEqual(pub, signer.Public()) {
+		t.Errorf("unexpected verification key %T", pub)
+	}
+	if _, err := ParseSigningKey([]byte("not a key")); err == nil {
+		t.Error("expected an error for invalid key")
+	}
+}
+
+func publicEqual(a, b crypto.PublicKey) bool {
+	k, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && k.Equal(b)
+}
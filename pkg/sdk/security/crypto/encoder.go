@@ -111,7 +111,7 @@ func ToPEM(key interface{}) (string, error) {
 			},
 		)
 
-	// Private keys ------------------------------------------------------------
+	// Public keys -------------------------------------------------------------
 	case *rsa.PublicKey:
 		pubkeyBytes, err := x509.MarshalPKIXPublicKey(k)
 		if err != nil {
@@ -211,7 +211,10 @@ func ToSSH(key interface{}) (string, error) {
 			},
 		)
 	case ed25519.PrivateKey:
-		privkeyBytes := marshalED25519PrivateKey(k)
+		privkeyBytes, err := marshalED25519PrivateKey(k)
+		if err != nil {
+			return "", fmt.Errorf("unable to encode ed25519 private key: %w", err)
+		}
 		result = pem.EncodeToMemory(
 			&pem.Block{
 				Type:  blockTypeOpenSSHPrivateKey,
@@ -219,7 +222,7 @@ func ToSSH(key interface{}) (string, error) {
 			},
 		)
 
-	// Private keys ------------------------------------------------------------
+	// Public keys -------------------------------------------------------------
 	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
 		pubKey, err := ssh.NewPublicKey(k)
 		if err != nil {
@@ -235,20 +238,77 @@ func ToSSH(key interface{}) (string, error) {
 	return string(result), nil
 }
 
+// SSHFingerprint returns the OpenSSH fingerprint of the given key, as
+// displayed by `ssh-keygen -l`. Supported algorithms are "sha256" (default)
+// and "md5" (legacy format).
+func SSHFingerprint(key interface{}, algo string) (string, error) {
+	// Check key
+	if types.IsNil(key) {
+		return "", fmt.Errorf("unable to compute fingerprint of nil key")
+	}
+
+	var pubKey ssh.PublicKey
+	switch k := key.(type) {
+	case ssh.PublicKey:
+		pubKey = k
+	case string:
+		// Authorized key format
+		pk, _, _, _, err := ssh.ParseAuthorizedKey([]byte(k))
+		if err != nil {
+			return "", fmt.Errorf("unable to parse ssh public key: %w", err)
+		}
+		pubKey = pk
+	case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+		pk, err := ssh.NewPublicKey(k.(crypto.Signer).Public())
+		if err != nil {
+			return "", fmt.Errorf("unable to convert key as ssh public key: %w", err)
+		}
+		pubKey = pk
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		pk, err := ssh.NewPublicKey(k)
+		if err != nil {
+			return "", fmt.Errorf("unable to convert key as ssh public key: %w", err)
+		}
+		pubKey = pk
+	default:
+		return "", fmt.Errorf("given key type is not supported")
+	}
+
+	switch algo {
+	case "", "sha256":
+		return ssh.FingerprintSHA256(pubKey), nil
+	case "md5":
+		return "MD5:" + ssh.FingerprintLegacyMD5(pubKey), nil
+	default:
+	}
+
+	return "", fmt.Errorf("unsupported fingerprint algorithm '%s' [sha256, md5]", algo)
+}
+
 // -----------------------------------------------------------------------------
 
 // Writes ed25519 private keys into the new OpenSSH private key format.
-func marshalED25519PrivateKey(key ed25519.PrivateKey) []byte {
-	// Add our key header (followed by a null byte)
-	magic := append([]byte("openssh-key-v1"), 0)
+// https://github.com/openssh/openssh-portable/blob/master/PROTOCOL.key
+func marshalED25519PrivateKey(key ed25519.PrivateKey) ([]byte, error) {
+	// Check key
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid private key length %d", len(key))
+	}
 
-	var w struct {
-		CipherName   string
-		KdfName      string
-		KdfOpts      string
-		NumKeys      uint32
-		PubKey       []byte
-		PrivKeyBlock []byte
+	// Prepare public key blob
+	pub, ok := key.Public().(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("unable to extract public key")
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert public key: %w", err)
+	}
+
+	// Set our check ints
+	ci, err := randUInt32()
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate check value: %w", err)
 	}
 
 	// Fill out the private key fields
@@ -260,63 +320,44 @@ func marshalED25519PrivateKey(key ed25519.PrivateKey) []byte {
 		Priv    []byte
 		Comment string
 		Pad     []byte `ssh:"rest"`
-	}{}
-
-	// Set our check ints
-	ci, err := randUInt32()
-	if err != nil {
-		panic(err)
-	}
-
-	pk1.Check1 = ci
-	pk1.Check2 = ci
-
-	// Set our key type
-	pk1.Keytype = ssh.KeyAlgoED25519
-
-	// Add the pubkey to the optionally-encrypted block
-	pk, ok := key.Public().(ed25519.PublicKey)
-	if !ok {
-		return nil
+	}{
+		Check1:  ci,
+		Check2:  ci,
+		Keytype: ssh.KeyAlgoED25519,
+		Pub:     []byte(pub),
+		Priv:    []byte(key),
 	}
-	pubKey := []byte(pk)
-	pk1.Pub = pubKey
-
-	// Add our private key
-	pk1.Priv = []byte(key)
-
-	// Might be useful to put something in here at some point
-	pk1.Comment = ""
 
-	// Add some padding to match the encryption block size within PrivKeyBlock (without Pad field)
-	// 8 doesn't match the documentation, but that's what ssh-keygen uses for unencrypted keys. *shrug*
-	bs := 8
-	blockLen := len(ssh.Marshal(pk1))
-	padLen := (bs - (blockLen % bs)) % bs
+	// Pad the private section to the cipher block size, 8 for unencrypted
+	// keys as ssh-keygen does. Padding is a sequence of bytes like: 1, 2, 3...
+	const blockSize = 8
+	padLen := (blockSize - len(ssh.Marshal(pk1))%blockSize) % blockSize
 	pk1.Pad = make([]byte, padLen)
-
-	// Padding is a sequence of bytes like: 1, 2, 3...
-	for i := 0; i < padLen; i++ {
+	for i := range pk1.Pad {
 		pk1.Pad[i] = byte(i + 1)
 	}
 
-	// Generate the pubkey prefix "\0\0\0\nssh-ed25519\0\0\0 "
-	prefix := []byte{0x0, 0x0, 0x0, 0x0b}
-	prefix = append(prefix, []byte(ssh.KeyAlgoED25519)...)
-	prefix = append(prefix, []byte{0x0, 0x0, 0x0, 0x20}...)
-
 	// Only going to support unencrypted keys for now
-	w.CipherName = "none"
-	w.KdfName = "none"
-	w.KdfOpts = ""
-	w.NumKeys = 1
-	// nolint
-	w.PubKey = append(prefix, pubKey...)
-	w.PrivKeyBlock = ssh.Marshal(pk1)
+	w := struct {
+		CipherName   string
+		KdfName      string
+		KdfOpts      string
+		NumKeys      uint32
+		PubKey       []byte
+		PrivKeyBlock []byte
+	}{
+		CipherName:   "none",
+		KdfName:      "none",
+		NumKeys:      1,
+		PubKey:       sshPub.Marshal(),
+		PrivKeyBlock: ssh.Marshal(pk1),
+	}
 
-	magic = append(magic, ssh.Marshal(w)...)
+	// Add our key header (followed by a null byte)
+	magic := append([]byte("openssh-key-v1"), 0)
 
-	return magic
+	// No error
+	return append(magic, ssh.Marshal(w)...), nil
 }
 
 func randUInt32() (uint32, error) {
@@ -18,9 +18,15 @@
 package crypto
 
 import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"io/ioutil"
+	"path/filepath"
 	"testing"
 
 	_ "golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/ssh"
 )
 
 func TestToJWK(t *testing.T) {
@@ -278,3 +284,78 @@ func TestToSSH(t *testing.T) {
 		})
 	}
 }
+
+func TestToSSH_ED25519_RoundTrip(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("unable to generate key: %v", err)
+		}
+
+		out, err := ToSSH(priv)
+		if err != nil {
+			t.Fatalf("unable to encode key: %v", err)
+		}
+
+		raw, err := ssh.ParseRawPrivateKey([]byte(out))
+		if err != nil {
+			t.Fatalf("unable to parse generated key #%d: %v\n%s", i, err, out)
+		}
+		parsed, ok := raw.(*ed25519.PrivateKey)
+		if !ok {
+			t.Fatalf("unexpected key type %T", raw)
+		}
+		if !bytes.Equal(*parsed, priv) || !bytes.Equal(parsed.Public().(ed25519.PublicKey), pub) {
+			t.Fatalf("key #%d doesn't round-trip", i)
+		}
+	}
+}
+
+func TestSSHFingerprint(t *testing.T) {
+	readFixture := func(name string) []byte {
+		raw, err := ioutil.ReadFile(filepath.Join("testdata", "ssh", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+
+	// Fixture private key
+	edPriv, err := ssh.ParseRawPrivateKey(readFixture("id_ed25519"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Expected values from `ssh-keygen -lf` and `ssh-keygen -E md5 -lf`
+	tests := []struct {
+		name    string
+		key     interface{}
+		algo    string
+		want    string
+		wantErr bool
+	}{
+		{name: "nil", key: nil, wantErr: true},
+		{name: "unsupported key", key: []byte("foo"), wantErr: true},
+		{name: "invalid authorized key", key: "ssh-ed25519 foo", wantErr: true},
+		{name: "unsupported algorithm", key: string(readFixture("id_ed25519.pub")), algo: "sha1", wantErr: true},
+		{name: "ed25519", key: string(readFixture("id_ed25519.pub")), want: "SHA256:1gsmzmgwtiIyaGT060LgkoeB66L1TtWLjRHcFewV0C8"},
+		{name: "ed25519 md5", key: string(readFixture("id_ed25519.pub")), algo: "md5", want: "MD5:93:8f:24:7a:b9:96:89:36:21:db:67:fb:b3:52:21:01"},
+		{name: "ed25519 private", key: *edPriv.(*ed25519.PrivateKey), algo: "sha256", want: "SHA256:1gsmzmgwtiIyaGT060LgkoeB66L1TtWLjRHcFewV0C8"},
+		{name: "rsa", key: string(readFixture("id_rsa.pub")), want: "SHA256:5+b5HhtwBvLns8269UgNqRYX8Khc70GzycVUnY9nB/Q"},
+		{name: "rsa md5", key: string(readFixture("id_rsa.pub")), algo: "md5", want: "MD5:61:32:59:34:5f:14:2d:80:dd:3e:44:9a:6e:7c:56:d3"},
+		{name: "ecdsa", key: string(readFixture("id_ecdsa.pub")), want: "SHA256:pa5eCFbwAEGFc3+njUvj+X+xXikU5h+/VK55Zbs8gVE"},
+		{name: "ecdsa md5", key: string(readFixture("id_ecdsa.pub")), algo: "md5", want: "MD5:8d:5d:56:7e:c2:09:e9:06:4d:88:b0:76:af:21:02:df"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SSHFingerprint(tt.key, tt.algo)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SSHFingerprint() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("SSHFingerprint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
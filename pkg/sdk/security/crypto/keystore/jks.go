@@ -0,0 +1,231 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package keystore
+
+import (
+	"bytes"
+	"crypto/sha1" //nolint:gosec // required by the JKS format
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// JKS is the legacy proprietary JVM keystore format, prefer PKCS#12 when the
+// consumer supports it. There is no maintained pure Go JKS encoder, the format
+// is a flat big-endian record stream with a SHA-1 integrity digest and the Sun
+// KeyProtector key encryption, both implemented below.
+const (
+	jksMagic                = 0xFEEDFEED
+	jksVersion              = 2
+	jksPrivateKeyTag        = 1
+	jksTrustedCertTag       = 2
+	jksSaltSize             = 20
+	jksIntegrityWhitener    = "Mighty Aphrodite"
+	jksCertificateType      = "X.509"
+	jksMaxModifiedUTF8Bytes = 65535
+)
+
+var oidJKSKeyProtector = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 42, 2, 17, 1, 1}
+
+type encryptedPrivateKeyInfo struct {
+	AlgorithmIdentifier pkix.AlgorithmIdentifier
+	EncryptedData       []byte
+}
+
+// EncodeJKS returns a legacy JKS keystore containing the given entries
+// protected by the password. Aliases are lowercased as keytool does.
+func EncodeJKS(rand io.Reader, entries []Entry, password []byte, now time.Time) ([]byte, error) {
+	// Check arguments
+	if err := Validate(entries); err != nil {
+		return nil, err
+	}
+
+	passwd := bmpString(string(password))
+	timestamp := now.UnixNano() / int64(time.Millisecond)
+
+	var buf bytes.Buffer
+	w := &jksWriter{w: &buf}
+	w.uint32(jksMagic)
+	w.uint32(jksVersion)
+	w.uint32(uint32(len(entries)))
+
+	for i := range entries {
+		e := &entries[i]
+
+		// Trusted certificate entry
+		if e.IsTrustedCertificate() {
+			w.uint32(jksTrustedCertTag)
+			w.utf(strings.ToLower(e.Alias))
+			w.uint64(uint64(timestamp))
+			w.certificate(e.Certificate)
+			continue
+		}
+
+		// Private key entry
+		protected, err := jksProtectKey(rand, e, passwd)
+		if err != nil {
+			return nil, err
+		}
+
+		w.uint32(jksPrivateKeyTag)
+		w.utf(strings.ToLower(e.Alias))
+		w.uint64(uint64(timestamp))
+		w.bytes(protected)
+		w.uint32(uint32(len(e.Chain) + 1))
+		w.certificate(e.Certificate)
+		for _, c := range e.Chain {
+			w.certificate(c)
+		}
+	}
+	if w.err != nil {
+		return nil, w.err
+	}
+
+	// Append integrity digest
+	h := sha1.New() //nolint:gosec // required by the JKS format
+	h.Write(passwd)
+	h.Write([]byte(jksIntegrityWhitener))
+	h.Write(buf.Bytes())
+	buf.Write(h.Sum(nil))
+
+	// No error
+	return buf.Bytes(), nil
+}
+
+// -----------------------------------------------------------------------------
+
+// jksProtectKey encrypts the private key using the Sun KeyProtector
+// algorithm (SHA-1 based keystream).
+func jksProtectKey(rand io.Reader, e *Entry, passwd []byte) ([]byte, error) {
+	plain, err := x509.MarshalPKCS8PrivateKey(e.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("keystore: unable to encode '%s' private key: %w", e.Alias, err)
+	}
+
+	salt := make([]byte, jksSaltSize)
+	if _, err := io.ReadFull(rand, salt); err != nil {
+		return nil, fmt.Errorf("keystore: unable to generate salt: %w", err)
+	}
+
+	// Generate keystream
+	encrypted := make([]byte, len(plain))
+	digest := salt
+	for i := 0; i < len(plain); i += sha1.Size {
+		h := sha1.New() //nolint:gosec // required by the JKS format
+		h.Write(passwd)
+		h.Write(digest)
+		digest = h.Sum(nil)
+		for j := 0; j < sha1.Size && i+j < len(plain); j++ {
+			encrypted[i+j] = plain[i+j] ^ digest[j]
+		}
+	}
+
+	// Compute plaintext checksum
+	h := sha1.New() //nolint:gosec // required by the JKS format
+	h.Write(passwd)
+	h.Write(plain)
+
+	protected := append(append(append([]byte{}, salt...), encrypted...), h.Sum(nil)...)
+
+	out, err := asn1.Marshal(encryptedPrivateKeyInfo{
+		AlgorithmIdentifier: pkix.AlgorithmIdentifier{Algorithm: oidJKSKeyProtector, Parameters: asn1.NullRawValue},
+		EncryptedData:       protected,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keystore: unable to encode '%s' protected key: %w", e.Alias, err)
+	}
+
+	return out, nil
+}
+
+type jksWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (w *jksWriter) write(data []byte) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = w.w.Write(data)
+}
+
+func (w *jksWriter) uint32(v uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	w.write(b[:])
+}
+
+func (w *jksWriter) uint64(v uint64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	w.write(b[:])
+}
+
+func (w *jksWriter) bytes(data []byte) {
+	w.uint32(uint32(len(data)))
+	w.write(data)
+}
+
+func (w *jksWriter) certificate(c *x509.Certificate) {
+	w.utf(jksCertificateType)
+	w.bytes(c.Raw)
+}
+
+// utf writes the string using Java modified UTF-8 encoding.
+func (w *jksWriter) utf(s string) {
+	var out []byte
+	for _, c := range utf16.Encode([]rune(s)) {
+		switch {
+		case c != 0 && c < 0x80:
+			out = append(out, byte(c))
+		case c < 0x800:
+			out = append(out, byte(0xC0|c>>6), byte(0x80|c&0x3F))
+		default:
+			out = append(out, byte(0xE0|c>>12), byte(0x80|(c>>6)&0x3F), byte(0x80|c&0x3F))
+		}
+	}
+	if len(out) > jksMaxModifiedUTF8Bytes {
+		if w.err == nil {
+			w.err = fmt.Errorf("keystore: string '%s' is too long", s)
+		}
+		return
+	}
+
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], uint16(len(out)))
+	w.write(b[:])
+	w.write(out)
+}
+
+// bmpString returns the UTF-16BE encoding of the given string, as the JVM
+// encodes password characters.
+func bmpString(s string) []byte {
+	u := utf16.Encode([]rune(s))
+	out := make([]byte, 0, 2*len(u))
+	for _, c := range u {
+		out = append(out, byte(c>>8), byte(c))
+	}
+	return out
+}
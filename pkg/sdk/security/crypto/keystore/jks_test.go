@@ -0,0 +1,283 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package keystore
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec // required by the JKS format
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"io"
+	mathrand "math/rand"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+type jksEntry struct {
+	tag       uint32
+	alias     string
+	timestamp uint64
+	key       []byte
+	certs     [][]byte
+}
+
+// readJKS decodes a JKS keystore and recovers the private keys.
+func readJKS(t *testing.T, data, password []byte) []jksEntry {
+	t.Helper()
+
+	passwd := bmpString(string(password))
+
+	// Check integrity
+	if len(data) < sha1.Size {
+		t.Fatal("keystore too short")
+	}
+	body, digest := data[:len(data)-sha1.Size], data[len(data)-sha1.Size:]
+	h := sha1.New() //nolint:gosec // required by the JKS format
+	h.Write(passwd)
+	h.Write([]byte(jksIntegrityWhitener))
+	h.Write(body)
+	if !bytes.Equal(h.Sum(nil), digest) {
+		t.Fatal("keystore integrity check failed")
+	}
+
+	r := bytes.NewReader(body)
+	u32 := func() uint32 {
+		var v uint32
+		if err := binary.Read(r, binary.BigEndian, &v); err != nil {
+			t.Fatalf("unable to read uint32: %v", err)
+		}
+		return v
+	}
+	u64 := func() uint64 {
+		var v uint64
+		if err := binary.Read(r, binary.BigEndian, &v); err != nil {
+			t.Fatalf("unable to read uint64: %v", err)
+		}
+		return v
+	}
+	read := func(n int) []byte {
+		b := make([]byte, n)
+		if _, err := io.ReadFull(r, b); err != nil {
+			t.Fatalf("unable to read %d bytes: %v", n, err)
+		}
+		return b
+	}
+	utf := func() string {
+		var n uint16
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+			t.Fatalf("unable to read string length: %v", err)
+		}
+		return decodeModifiedUTF8(t, read(int(n)))
+	}
+	cert := func() []byte {
+		if typ := utf(); typ != jksCertificateType {
+			t.Fatalf("unexpected certificate type %q", typ)
+		}
+		return read(int(u32()))
+	}
+
+	if u32() != jksMagic || u32() != jksVersion {
+		t.Fatal("invalid keystore header")
+	}
+
+	entries := []jksEntry{}
+	for count := u32(); count > 0; count-- {
+		e := jksEntry{tag: u32(), alias: utf(), timestamp: u64()}
+		switch e.tag {
+		case jksPrivateKeyTag:
+			var info encryptedPrivateKeyInfo
+			if _, err := asn1.Unmarshal(read(int(u32())), &info); err != nil {
+				t.Fatalf("unable to decode protected key: %v", err)
+			}
+			if !info.AlgorithmIdentifier.Algorithm.Equal(oidJKSKeyProtector) {
+				t.Fatalf("unexpected key protection algorithm %v", info.AlgorithmIdentifier.Algorithm)
+			}
+
+			// Recover the key
+			protected := info.EncryptedData
+			salt := protected[:jksSaltSize]
+			encrypted := protected[jksSaltSize : len(protected)-sha1.Size]
+			plain := make([]byte, len(encrypted))
+			digest := salt
+			for i := 0; i < len(encrypted); i += sha1.Size {
+				h := sha1.New() //nolint:gosec // required by the JKS format
+				h.Write(passwd)
+				h.Write(digest)
+				digest = h.Sum(nil)
+				for j := 0; j < sha1.Size && i+j < len(encrypted); j++ {
+					plain[i+j] = encrypted[i+j] ^ digest[j]
+				}
+			}
+			h := sha1.New() //nolint:gosec // required by the JKS format
+			h.Write(passwd)
+			h.Write(plain)
+			if !bytes.Equal(h.Sum(nil), protected[len(protected)-sha1.Size:]) {
+				t.Fatal("key checksum mismatch")
+			}
+			e.key = plain
+
+			for n := u32(); n > 0; n-- {
+				e.certs = append(e.certs, cert())
+			}
+		case jksTrustedCertTag:
+			e.certs = append(e.certs, cert())
+		default:
+			t.Fatalf("unexpected entry tag %d", e.tag)
+		}
+		entries = append(entries, e)
+	}
+	if r.Len() != 0 {
+		t.Fatal("trailing data found")
+	}
+
+	return entries
+}
+
+func TestEncodeJKS(t *testing.T) {
+	pki := newTestPKI(t)
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	entries := []Entry{
+		{Alias: "Server", PrivateKey: pki.leafKey, Certificate: pki.leaf, Chain: []*x509.Certificate{pki.intermediate, pki.root}},
+		{Alias: "ca", Certificate: pki.root},
+	}
+
+	out, err := EncodeJKS(rand.Reader, entries, []byte("changeit"), now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := readJKS(t, out, []byte("changeit"))
+	if len(got) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(got))
+	}
+
+	// Private key entry
+	if got[0].tag != jksPrivateKeyTag || got[0].alias != "server" || got[0].timestamp != uint64(now.Unix()*1000) {
+		t.Errorf("unexpected key entry: %d %q %d", got[0].tag, got[0].alias, got[0].timestamp)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(got[0].key)
+	if err != nil {
+		t.Fatalf("unable to parse private key: %v", err)
+	}
+	if ecKey, ok := key.(*ecdsa.PrivateKey); !ok || !ecKey.Equal(pki.leafKey) {
+		t.Error("private key mismatch")
+	}
+	wantChain := [][]byte{pki.leaf.Raw, pki.intermediate.Raw, pki.root.Raw}
+	if len(got[0].certs) != len(wantChain) {
+		t.Fatalf("expected %d chain certificates, got %d", len(wantChain), len(got[0].certs))
+	}
+	for i := range wantChain {
+		if !bytes.Equal(got[0].certs[i], wantChain[i]) {
+			t.Errorf("chain certificate %d mismatch", i)
+		}
+	}
+
+	// Trusted certificate entry
+	if got[1].tag != jksTrustedCertTag || got[1].alias != "ca" || !bytes.Equal(got[1].certs[0], pki.root.Raw) {
+		t.Errorf("unexpected trusted certificate entry: %d %q", got[1].tag, got[1].alias)
+	}
+}
+
+func TestEncodeJKS_Randomized(t *testing.T) {
+	pki := newTestPKI(t)
+	runes := []rune("aZ09-_. éßЖ中文\u00ff\u0800\uffff\U0001F511\U00010000")
+	random := func(r *mathrand.Rand, min, max int) string {
+		out := make([]rune, min+r.Intn(max-min+1))
+		for i := range out {
+			out[i] = runes[r.Intn(len(runes))]
+		}
+		return string(out)
+	}
+
+	r := mathrand.New(mathrand.NewSource(1)) //nolint:gosec // reproducible inputs
+	for i := 0; i < 200; i++ {
+		password := []byte(random(r, 0, 32))
+		aliases := []string{"x" + random(r, 0, 64), "y" + random(r, 0, 64)}
+		entries := []Entry{
+			{Alias: aliases[0], PrivateKey: pki.leafKey, Certificate: pki.leaf, Chain: []*x509.Certificate{pki.intermediate}},
+			{Alias: aliases[1], Certificate: pki.root},
+		}
+
+		out, err := EncodeJKS(rand.Reader, entries, password, time.Now())
+		if err != nil {
+			t.Fatalf("iteration %d: unexpected error: %v", i, err)
+		}
+
+		got := readJKS(t, out, password)
+		if len(got) != 2 {
+			t.Fatalf("iteration %d: expected 2 entries, got %d", i, len(got))
+		}
+		for j, e := range got {
+			if e.alias != strings.ToLower(aliases[j]) {
+				t.Errorf("iteration %d: expected alias %q, got %q", i, strings.ToLower(aliases[j]), e.alias)
+			}
+		}
+		key, err := x509.ParsePKCS8PrivateKey(got[0].key)
+		if err != nil {
+			t.Fatalf("iteration %d: unable to parse private key: %v", i, err)
+		}
+		if ecKey, ok := key.(*ecdsa.PrivateKey); !ok || !ecKey.Equal(pki.leafKey) {
+			t.Errorf("iteration %d: private key mismatch", i)
+		}
+	}
+}
+
+func TestEncodeJKS_Invalid(t *testing.T) {
+	pki := newTestPKI(t)
+
+	_, err := EncodeJKS(rand.Reader, []Entry{
+		{Alias: "ca", Certificate: pki.root},
+		{Alias: "Ca", Certificate: pki.intermediate},
+	}, []byte("changeit"), time.Now())
+	if err == nil {
+		t.Fatal("error expected")
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// decodeModifiedUTF8 decodes a Java modified UTF-8 string, supplementary
+// characters are encoded as surrogate pairs.
+func decodeModifiedUTF8(t *testing.T, in []byte) string {
+	t.Helper()
+
+	units := []uint16{}
+	for i := 0; i < len(in); {
+		switch c := in[i]; {
+		case c < 0x80:
+			units = append(units, uint16(c))
+			i++
+		case c&0xE0 == 0xC0 && i+1 < len(in):
+			units = append(units, uint16(c&0x1F)<<6|uint16(in[i+1]&0x3F))
+			i += 2
+		case c&0xF0 == 0xE0 && i+2 < len(in):
+			units = append(units, uint16(c&0x0F)<<12|uint16(in[i+1]&0x3F)<<6|uint16(in[i+2]&0x3F))
+			i += 3
+		default:
+			t.Fatalf("invalid modified UTF-8 byte 0x%02x", c)
+		}
+	}
+
+	return string(utf16.Decode(units))
+}
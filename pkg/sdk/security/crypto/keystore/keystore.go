@@ -0,0 +1,126 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package keystore
+
+import (
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+var (
+	// ErrInvalidEntry is raised when a keystore entry is not consistent.
+	ErrInvalidEntry = errors.New("keystore: invalid entry")
+	// ErrDuplicateAlias is raised when an alias is used by several entries.
+	ErrDuplicateAlias = errors.New("keystore: duplicate alias")
+	// ErrUnsupportedPKCS12Layout is raised when entries can't be represented
+	// in a PKCS#12 keystore.
+	ErrUnsupportedPKCS12Layout = errors.New("keystore: pkcs12 holds a single private key entry or trusted certificates only")
+)
+
+// Entry describes a keystore entry. Entries without private key are exported
+// as trusted certificates.
+type Entry struct {
+	Alias       string
+	PrivateKey  crypto.PrivateKey
+	Certificate *x509.Certificate
+	Chain       []*x509.Certificate
+}
+
+// IsTrustedCertificate returns true if the entry is a trusted certificate
+// entry.
+func (e *Entry) IsTrustedCertificate() bool {
+	return e.PrivateKey == nil
+}
+
+// Validate the entry consistency. The private key must match the leaf
+// certificate, and each chain certificate must be the issuer of the previous
+// one.
+func (e *Entry) Validate() error {
+	// Check alias
+	if strings.TrimSpace(e.Alias) == "" {
+		return fmt.Errorf("alias must not be blank: %w", ErrInvalidEntry)
+	}
+	for _, r := range e.Alias {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("alias '%s' must not contain control characters: %w", e.Alias, ErrInvalidEntry)
+		}
+	}
+
+	// Check certificate
+	if e.Certificate == nil {
+		return fmt.Errorf("entry '%s' must have a certificate: %w", e.Alias, ErrInvalidEntry)
+	}
+
+	// Trusted certificate
+	if e.IsTrustedCertificate() {
+		if len(e.Chain) > 0 {
+			return fmt.Errorf("trusted certificate entry '%s' must not have a chain: %w", e.Alias, ErrInvalidEntry)
+		}
+		return nil
+	}
+
+	// Check key pair
+	signer, ok := e.PrivateKey.(crypto.Signer)
+	if !ok {
+		return fmt.Errorf("entry '%s' has an unsupported private key type %T: %w", e.Alias, e.PrivateKey, ErrInvalidEntry)
+	}
+	pub, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !pub.Equal(e.Certificate.PublicKey) {
+		return fmt.Errorf("entry '%s' private key doesn't match the certificate: %w", e.Alias, ErrInvalidEntry)
+	}
+
+	// Check chain order
+	child := e.Certificate
+	for i, parent := range e.Chain {
+		if parent == nil {
+			return fmt.Errorf("entry '%s' has a nil chain certificate at position %d: %w", e.Alias, i, ErrInvalidEntry)
+		}
+		if err := child.CheckSignatureFrom(parent); err != nil {
+			return fmt.Errorf("entry '%s' chain certificate at position %d ('%s') is not the issuer of '%s': %w", e.Alias, i, parent.Subject, child.Subject, ErrInvalidEntry)
+		}
+		child = parent
+	}
+
+	// No error
+	return nil
+}
+
+// Validate all entries and check alias uniqueness. Aliases are compared
+// case-insensitively as JVM keystores do.
+func Validate(entries []Entry) error {
+	seen := map[string]struct{}{}
+	for i := range entries {
+		e := &entries[i]
+		if err := e.Validate(); err != nil {
+			return err
+		}
+
+		key := strings.ToLower(e.Alias)
+		if _, ok := seen[key]; ok {
+			return fmt.Errorf("alias '%s' is already used: %w", e.Alias, ErrDuplicateAlias)
+		}
+		seen[key] = struct{}{}
+	}
+
+	// No error
+	return nil
+}
@@ -0,0 +1,150 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package keystore
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+type testPKI struct {
+	rootKey, intermediateKey, leafKey *ecdsa.PrivateKey
+	root, intermediate, leaf          *x509.Certificate
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+
+	pki := &testPKI{
+		rootKey:         generateKey(t),
+		intermediateKey: generateKey(t),
+		leafKey:         generateKey(t),
+	}
+	pki.root = issue(t, "root", true, pki.rootKey, nil, nil)
+	pki.intermediate = issue(t, "intermediate", true, pki.intermediateKey, pki.root, pki.rootKey)
+	pki.leaf = issue(t, "leaf", false, pki.leafKey, pki.intermediate, pki.intermediateKey)
+
+	return pki
+}
+
+func generateKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	return key
+}
+
+func issue(t *testing.T, cn string, ca bool, key *ecdsa.PrivateKey, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) *x509.Certificate {
+	t.Helper()
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  ca,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+
+	raw, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("unable to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		t.Fatalf("unable to parse certificate: %v", err)
+	}
+
+	return cert
+}
+
+func TestValidate(t *testing.T) {
+	pki := newTestPKI(t)
+
+	testCases := []struct {
+		desc    string
+		entries []Entry
+		wantErr error
+	}{
+		{
+			desc: "valid",
+			entries: []Entry{
+				{Alias: "server", PrivateKey: pki.leafKey, Certificate: pki.leaf, Chain: []*x509.Certificate{pki.intermediate, pki.root}},
+				{Alias: "ca", Certificate: pki.root},
+			},
+		},
+		{
+			desc:    "blank alias",
+			entries: []Entry{{Alias: " ", Certificate: pki.root}},
+			wantErr: ErrInvalidEntry,
+		},
+		{
+			desc:    "missing certificate",
+			entries: []Entry{{Alias: "server", PrivateKey: pki.leafKey}},
+			wantErr: ErrInvalidEntry,
+		},
+		{
+			desc:    "key mismatch",
+			entries: []Entry{{Alias: "server", PrivateKey: pki.rootKey, Certificate: pki.leaf}},
+			wantErr: ErrInvalidEntry,
+		},
+		{
+			desc:    "unordered chain",
+			entries: []Entry{{Alias: "server", PrivateKey: pki.leafKey, Certificate: pki.leaf, Chain: []*x509.Certificate{pki.root, pki.intermediate}}},
+			wantErr: ErrInvalidEntry,
+		},
+		{
+			desc:    "trusted certificate with chain",
+			entries: []Entry{{Alias: "ca", Certificate: pki.intermediate, Chain: []*x509.Certificate{pki.root}}},
+			wantErr: ErrInvalidEntry,
+		},
+		{
+			desc: "duplicate alias",
+			entries: []Entry{
+				{Alias: "ca", Certificate: pki.root},
+				{Alias: "CA", Certificate: pki.intermediate},
+			},
+			wantErr: ErrDuplicateAlias,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			err := Validate(tC.entries)
+			if tC.wantErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tC.wantErr != nil && !errors.Is(err, tC.wantErr) {
+				t.Fatalf("expected %v, got %v", tC.wantErr, err)
+			}
+		})
+	}
+}
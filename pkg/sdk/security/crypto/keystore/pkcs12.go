@@ -0,0 +1,76 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package keystore
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// EncodePKCS12 returns a PKCS#12 keystore containing the given entries
+// protected by the password. The keystore holds either a single private key
+// entry with its leaf certificate and chain, or trusted certificates only for
+// JVM truststores. Aliases are not stored, the JVM generates them; use the
+// JKS format to export several aliased entries.
+func EncodePKCS12(rand io.Reader, entries []Entry, password []byte) ([]byte, error) {
+	// Check arguments
+	if err := Validate(entries); err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("at least one entry must be given: %w", ErrInvalidEntry)
+	}
+
+	var (
+		out []byte
+		err error
+	)
+	switch {
+	case len(entries) == 1 && !entries[0].IsTrustedCertificate():
+		e := &entries[0]
+		out, err = pkcs12.Encode(rand, e.PrivateKey, e.Certificate, e.Chain, string(password))
+	case allTrustedCertificates(entries):
+		certs := make([]*x509.Certificate, 0, len(entries))
+		for i := range entries {
+			certs = append(certs, entries[i].Certificate)
+		}
+		out, err = pkcs12.EncodeTrustStore(rand, certs, string(password))
+	default:
+		return nil, ErrUnsupportedPKCS12Layout
+	}
+	if err != nil {
+		return nil, fmt.Errorf("keystore: unable to encode pkcs12: %w", err)
+	}
+
+	// No error
+	return out, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func allTrustedCertificates(entries []Entry) bool {
+	for i := range entries {
+		if !entries[i].IsTrustedCertificate() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,127 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package keystore
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"testing"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+func TestEncodePKCS12(t *testing.T) {
+	pki := newTestPKI(t)
+
+	entries := []Entry{
+		{Alias: "server", PrivateKey: pki.leafKey, Certificate: pki.leaf, Chain: []*x509.Certificate{pki.intermediate, pki.root}},
+	}
+
+	out, err := EncodePKCS12(rand.Reader, entries, []byte("changeit"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Wrong password
+	if _, _, _, err := pkcs12.DecodeChain(out, "wrong"); err == nil {
+		t.Fatal("error expected with an invalid password")
+	}
+
+	key, leaf, chain, err := pkcs12.DecodeChain(out, "changeit")
+	if err != nil {
+		t.Fatalf("unable to decode keystore: %v", err)
+	}
+	if k, ok := key.(*ecdsa.PrivateKey); !ok || !k.Equal(pki.leafKey) {
+		t.Error("private key mismatch")
+	}
+	if !leaf.Equal(pki.leaf) {
+		t.Error("leaf certificate mismatch")
+	}
+	if len(chain) != 2 || !chain[0].Equal(pki.intermediate) || !chain[1].Equal(pki.root) {
+		t.Errorf("unexpected chain: %d certificates", len(chain))
+	}
+}
+
+func TestEncodePKCS12_TrustStore(t *testing.T) {
+	pki := newTestPKI(t)
+
+	entries := []Entry{
+		{Alias: "root", Certificate: pki.root},
+		{Alias: "intermediate", Certificate: pki.intermediate},
+	}
+
+	out, err := EncodePKCS12(rand.Reader, entries, []byte("changeit"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	certs, err := pkcs12.DecodeTrustStore(out, "changeit")
+	if err != nil {
+		t.Fatalf("unable to decode truststore: %v", err)
+	}
+	if len(certs) != 2 || !certs[0].Equal(pki.root) || !certs[1].Equal(pki.intermediate) {
+		t.Errorf("unexpected trusted certificates: %d", len(certs))
+	}
+}
+
+func TestEncodePKCS12_Invalid(t *testing.T) {
+	pki := newTestPKI(t)
+
+	testCases := []struct {
+		name    string
+		entries []Entry
+		wantErr error
+	}{
+		{
+			name:    "empty",
+			wantErr: ErrInvalidEntry,
+		},
+		{
+			name:    "key mismatch",
+			entries: []Entry{{Alias: "server", PrivateKey: pki.rootKey, Certificate: pki.leaf}},
+			wantErr: ErrInvalidEntry,
+		},
+		{
+			name: "several private keys",
+			entries: []Entry{
+				{Alias: "server", PrivateKey: pki.leafKey, Certificate: pki.leaf},
+				{Alias: "root", PrivateKey: pki.rootKey, Certificate: pki.root},
+			},
+			wantErr: ErrUnsupportedPKCS12Layout,
+		},
+		{
+			name: "private key and trusted certificate",
+			entries: []Entry{
+				{Alias: "server", PrivateKey: pki.leafKey, Certificate: pki.leaf},
+				{Alias: "root", Certificate: pki.root},
+			},
+			wantErr: ErrUnsupportedPKCS12Layout,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := EncodePKCS12(rand.Reader, tc.entries, []byte("changeit"))
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("expected %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -39,6 +39,12 @@ func Key(keyType string) (string, error) {
 	case "secretbox":
 		key := memguard.NewBufferRandom(32).Bytes()
 		return base64.StdEncoding.EncodeToString(key), nil
+	case "xchacha":
+		key := memguard.NewBufferRandom(32).Bytes()
+		return base64.StdEncoding.EncodeToString(key), nil
+	case "aes-siv":
+		key := memguard.NewBufferRandom(64).Bytes()
+		return base64.StdEncoding.EncodeToString(key), nil
 	case "fernet":
 		// Generate a fernet key
 		k := &fernet.Key{}
@@ -47,6 +53,6 @@ func Key(keyType string) (string, error) {
 		}
 		return k.Encode(), nil
 	default:
-		return "", fmt.Errorf("invalid keytype (%s) [aes:128, aes:256, secretbox, fernet]", keyType)
+		return "", fmt.Errorf("invalid keytype (%s) [aes:128, aes:256, secretbox, xchacha, aes-siv, fernet]", keyType)
 	}
 }
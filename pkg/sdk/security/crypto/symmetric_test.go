@@ -51,6 +51,16 @@ func TestKey(t *testing.T) {
 			args:    "secretbox",
 			wantErr: false,
 		},
+		{
+			name:    "xchacha",
+			args:    "xchacha",
+			wantErr: false,
+		},
+		{
+			name:    "aes-siv",
+			args:    "aes-siv",
+			wantErr: false,
+		},
 		{
 			name:    "fernet",
 			args:    "fernet",
@@ -0,0 +1,182 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package crypto
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// KeyUsageMember is the custom JWK member name used to declare key usage.
+	KeyUsageMember = "harp.elastic.co/usage"
+
+	// KeyUsageSign declares a signing / verification key.
+	KeyUsageSign = "sign"
+	// KeyUsageEncrypt declares an encryption / decryption key.
+	KeyUsageEncrypt = "encrypt"
+	// KeyUsageSeal declares a container sealing key.
+	KeyUsageSeal = "seal"
+)
+
+var (
+	// ErrKeyUsageMismatch is raised when a key is used for a purpose it is not
+	// declared for.
+	ErrKeyUsageMismatch = errors.New("key usage mismatch")
+	// ErrKeyExpired is raised when the key usage expiration is reached.
+	ErrKeyExpired = errors.New("key usage expired")
+)
+
+// keyOps maps harp key usages to RFC7517 key_ops values.
+var keyOps = map[string][]string{
+	KeyUsageSign:    {"sign", "verify"},
+	KeyUsageEncrypt: {"encrypt", "decrypt", "wrapKey", "unwrapKey"},
+	KeyUsageSeal:    {"deriveKey", "deriveBits"},
+}
+
+// KeyUsage describes the custom JWK usage member.
+type KeyUsage struct {
+	Use       string `json:"use"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+}
+
+// KeyUsageError describes a key usage policy violation.
+type KeyUsageError struct {
+	Intended string
+	Declared string
+	Expired  time.Time
+	Err      error
+}
+
+// Error returns the error message.
+func (e *KeyUsageError) Error() string {
+	if !e.Expired.IsZero() {
+		return fmt.Sprintf("key declared for '%s' usage expired at %s: %v", e.Declared, e.Expired.UTC().Format(time.RFC3339), e.Err)
+	}
+	return fmt.Sprintf("key declared for '%s' usage can't be used to '%s': %v", e.Declared, e.Intended, e.Err)
+}
+
+// Unwrap returns the wrapped error.
+func (e *KeyUsageError) Unwrap() error {
+	return e.Err
+}
+
+// ToJWKWithUsage encodes given key using JWK with key_ops and harp usage
+// member. A zero expiration time disables the usage expiration.
+func ToJWKWithUsage(key interface{}, usage string, expiresAt time.Time) (string, error) {
+	// Check usage
+	ops, ok := keyOps[usage]
+	if !ok {
+		return "", fmt.Errorf("unsupported key usage '%s'", usage)
+	}
+
+	// Encode the key
+	payload, err := ToJWK(key)
+	if err != nil {
+		return "", err
+	}
+
+	// Decode as map to add members
+	var jwk map[string]interface{}
+	if err = json.Unmarshal([]byte(payload), &jwk); err != nil {
+		return "", fmt.Errorf("unable to decode JWK: %w", err)
+	}
+
+	// Assign usage
+	jwk["key_ops"] = ops
+	u := KeyUsage{Use: usage}
+	if !expiresAt.IsZero() {
+		u.ExpiresAt = expiresAt.Unix()
+	}
+	jwk[KeyUsageMember] = u
+
+	// Encode final JWK
+	out, err := json.Marshal(jwk)
+	if err != nil {
+		return "", fmt.Errorf("unable to encode JWK: %w", err)
+	}
+
+	// No error
+	return string(out), nil
+}
+
+// KeyOps returns the key_ops values associated to the given usage.
+func KeyOps(usage string) ([]string, error) {
+	ops, ok := keyOps[usage]
+	if !ok {
+		return nil, fmt.Errorf("unsupported key usage '%s'", usage)
+	}
+	return append([]string{}, ops...), nil
+}
+
+// ValidateKeyUsage checks that the given JWK can be used for the intended
+// usage. Keys without usage declaration are accepted.
+func ValidateKeyUsage(jwk []byte, intendedUse string) error {
+	return validateKeyUsage(jwk, intendedUse, time.Now())
+}
+
+// -----------------------------------------------------------------------------
+
+func validateKeyUsage(jwk []byte, intendedUse string, now time.Time) error {
+	// Check arguments
+	if _, ok := keyOps[intendedUse]; !ok {
+		return fmt.Errorf("unsupported key usage '%s'", intendedUse)
+	}
+
+	// Extract usage members
+	var key struct {
+		KeyOps []string  `json:"key_ops"`
+		Usage  *KeyUsage `json:"harp.elastic.co/usage"`
+	}
+	if err := json.Unmarshal(jwk, &key); err != nil {
+		return fmt.Errorf("unable to decode JWK usage: %w", err)
+	}
+
+	// Check harp usage member
+	if key.Usage != nil {
+		if key.Usage.Use != intendedUse {
+			return &KeyUsageError{Intended: intendedUse, Declared: key.Usage.Use, Err: ErrKeyUsageMismatch}
+		}
+		if key.Usage.ExpiresAt > 0 {
+			if exp := time.Unix(key.Usage.ExpiresAt, 0); !now.Before(exp) {
+				return &KeyUsageError{Intended: intendedUse, Declared: key.Usage.Use, Expired: exp, Err: ErrKeyExpired}
+			}
+		}
+	}
+
+	// Check key operations
+	if len(key.KeyOps) > 0 && !hasAnyOp(key.KeyOps, keyOps[intendedUse]) {
+		return &KeyUsageError{Intended: intendedUse, Declared: fmt.Sprintf("%v", key.KeyOps), Err: ErrKeyUsageMismatch}
+	}
+
+	// No error
+	return nil
+}
+
+func hasAnyOp(declared, expected []string) bool {
+	for _, d := range declared {
+		for _, e := range expected {
+			if d == e {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package crypto
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidateKeyUsage(t *testing.T) {
+	_, priv, err := generateKeyPair("ec:p256")
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	plain, err := ToJWK(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signing, err := ToJWKWithUsage(priv, KeyUsageSign, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := ToJWKWithUsage(priv, KeyUsageSign, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid, err := ToJWKWithUsage(priv, KeyUsageSign, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		desc    string
+		jwk     string
+		use     string
+		wantErr error
+	}{
+		{desc: "no usage declaration", jwk: plain, use: KeyUsageEncrypt},
+		{desc: "matching usage", jwk: signing, use: KeyUsageSign},
+		{desc: "usage mismatch", jwk: signing, use: KeyUsageEncrypt, wantErr: ErrKeyUsageMismatch},
+		{desc: "expired", jwk: expired, use: KeyUsageSign, wantErr: ErrKeyExpired},
+		{desc: "not expired", jwk: valid, use: KeyUsageSign},
+		{desc: "key_ops only match", jwk: `{"kty":"oct","key_ops":["encrypt","decrypt"]}`, use: KeyUsageEncrypt},
+		{desc: "key_ops only mismatch", jwk: `{"kty":"oct","key_ops":["sign"]}`, use: KeyUsageSeal, wantErr: ErrKeyUsageMismatch},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			err := validateKeyUsage([]byte(tC.jwk), tC.use, now)
+			if !errors.Is(err, tC.wantErr) {
+				t.Errorf("validateKeyUsage() error = %v, want %v", err, tC.wantErr)
+			}
+		})
+	}
+}
+
+func TestToJWKWithUsage_Invalid(t *testing.T) {
+	_, priv, err := generateKeyPair("ec:p256")
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+
+	if _, err := ToJWKWithUsage(priv, "launch", time.Time{}); err == nil {
+		t.Error("error expected for unsupported usage")
+	}
+	if err := ValidateKeyUsage([]byte("{}"), "launch"); err == nil {
+		t.Error("error expected for unsupported intended usage")
+	}
+}
@@ -0,0 +1,100 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package piv
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/awnumar/memguard"
+
+	"github.com/elastic/harp/pkg/sdk/types"
+)
+
+// PINPrompt returns the PIN used to unlock the token, retries is the count of
+// remaining attempts before the PIN is blocked.
+type PINPrompt func(retries int) (*memguard.LockedBuffer, error)
+
+// StaticPIN returns a prompt always returning the given PIN.
+func StaticPIN(pin []byte) PINPrompt {
+	return func(_ int) (*memguard.LockedBuffer, error) {
+		return memguard.NewBufferFromBytes(append([]byte{}, pin...)), nil
+	}
+}
+
+// Agreement implements a container key agreement delegated to a PIV token.
+type Agreement struct {
+	Token Token
+	Slot  Slot
+	PIN   PINPrompt
+	// Attempts defines the maximum count of PIN prompts, defaults to 1 so that
+	// a static PIN can't block the token.
+	Attempts int
+}
+
+// ECDH computes the X25519 shared secret using the token slot private key.
+func (a *Agreement) ECDH(peerPublicKey *[32]byte) (*[32]byte, error) {
+	// Check arguments
+	if types.IsNil(a.Token) {
+		return nil, ErrNoToken
+	}
+	if a.PIN == nil {
+		return nil, errors.New("unable to unlock PIV token without PIN prompt")
+	}
+	if peerPublicKey == nil {
+		return nil, errors.New("unable to compute shared key with nil public key")
+	}
+
+	attempts := a.Attempts
+	if attempts <= 0 {
+		attempts = 1
+	}
+
+	var lastErr error
+	for i := 0; i < attempts; i++ {
+		// Check PIN status before prompting
+		retries, err := a.Token.Retries()
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve PIV PIN retry counter: %w", err)
+		}
+		if retries <= 0 {
+			return nil, ErrPINBlocked
+		}
+
+		// Prompt for PIN
+		pin, err := a.PIN(retries)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read PIV PIN: %w", err)
+		}
+
+		// Delegate to token
+		secret, err := a.Token.SharedKey(a.Slot, pin, peerPublicKey)
+		pin.Destroy()
+		if err == nil {
+			return secret, nil
+		}
+		if !errors.Is(err, ErrWrongPIN) || errors.Is(err, ErrPINBlocked) {
+			return nil, err
+		}
+
+		lastErr = err
+	}
+
+	// Too many attempts
+	return nil, lastErr
+}
@@ -0,0 +1,208 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package piv
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/awnumar/memguard"
+	"golang.org/x/crypto/curve25519"
+)
+
+func TestParseSlot(t *testing.T) {
+	testCases := []struct {
+		value   string
+		want    Slot
+		wantErr bool
+	}{
+		{value: "9a", want: SlotAuthentication},
+		{value: "9D", want: SlotKeyManagement},
+		{value: "0x82", want: Slot(0x82)},
+		{value: "95", want: Slot(0x95)},
+		{value: "96", wantErr: true},
+		{value: "9b", wantErr: true},
+		{value: "zz", wantErr: true},
+		{value: "", wantErr: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.value, func(t *testing.T) {
+			got, err := ParseSlot(tC.value)
+			if (err != nil) != tC.wantErr {
+				t.Fatalf("ParseSlot() error = %v, wantErr %v", err, tC.wantErr)
+			}
+			if got != tC.want {
+				t.Errorf("ParseSlot() = %s, want %s", got, tC.want)
+			}
+		})
+	}
+}
+
+func TestParseReference(t *testing.T) {
+	ref, err := ParseReference(Reference{Serial: 12345678, Slot: SlotKeyManagement}.String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ref.Serial != 12345678 || ref.Slot != SlotKeyManagement {
+		t.Errorf("unexpected reference %+v", ref)
+	}
+
+	for _, invalid := range []string{"", "12345678", "abc/9a", "123/00"} {
+		if _, err := ParseReference(invalid); err == nil {
+			t.Errorf("error should be raised for %q", invalid)
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+func pinSequence(pins ...string) (PINPrompt, *[]int) {
+	seen := []int{}
+	return func(retries int) (*memguard.LockedBuffer, error) {
+		seen = append(seen, retries)
+		pin := pins[0]
+		if len(pins) > 1 {
+			pins = pins[1:]
+		}
+		return memguard.NewBufferFromBytes([]byte(pin)), nil
+	}, &seen
+}
+
+func simulatedAgreement(t *testing.T, attempts int, prompt PINPrompt) (*Simulator, *Agreement, *[32]byte) {
+	t.Helper()
+
+	sim := NewSimulator(42)
+	if _, err := sim.GenerateKey(nil, SlotKeyManagement); err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+
+	token, err := sim.Open()
+	if err != nil {
+		t.Fatalf("unable to open token: %v", err)
+	}
+
+	peerPriv := bytes.Repeat([]byte{0x01}, 32)
+	peerPub, err := curve25519.X25519(peerPriv, curve25519.Basepoint)
+	if err != nil {
+		t.Fatalf("unable to compute peer public key: %v", err)
+	}
+	var peer [32]byte
+	copy(peer[:], peerPub)
+
+	return sim, &Agreement{
+		Token:    token,
+		Slot:     SlotKeyManagement,
+		PIN:      prompt,
+		Attempts: attempts,
+	}, &peer
+}
+
+func TestAgreement_ECDH(t *testing.T) {
+	t.Run("valid pin", func(t *testing.T) {
+		sim, a, peer := simulatedAgreement(t, 1, StaticPIN([]byte(DefaultPIN)))
+
+		secret, err := a.ECDH(peer)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// Compare with software computation
+		tokenPub, err := sim.PublicKey(SlotKeyManagement)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected, err := curve25519.X25519(bytes.Repeat([]byte{0x01}, 32), tokenPub[:])
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !bytes.Equal(secret[:], expected) {
+			t.Error("shared secret mismatch")
+		}
+	})
+
+	t.Run("static wrong pin is not retried", func(t *testing.T) {
+		_, a, peer := simulatedAgreement(t, 0, StaticPIN([]byte("000000")))
+
+		_, err := a.ECDH(peer)
+		if !errors.Is(err, ErrWrongPIN) {
+			t.Fatalf("expected wrong pin error, got %v", err)
+		}
+		var pinErr *PINError
+		if !errors.As(err, &pinErr) || pinErr.Retries != DefaultPINRetries-1 {
+			t.Errorf("expected %d remaining retries, got %v", DefaultPINRetries-1, err)
+		}
+	})
+
+	t.Run("retry after wrong pin", func(t *testing.T) {
+		prompt, seen := pinSequence("000000", DefaultPIN)
+		_, a, peer := simulatedAgreement(t, 3, prompt)
+
+		if _, err := a.ECDH(peer); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(*seen) != 2 || (*seen)[0] != 3 || (*seen)[1] != 2 {
+			t.Errorf("unexpected prompted retry counters %v", *seen)
+		}
+
+		// Counter is reset after success
+		retries, err := a.Token.Retries()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if retries != DefaultPINRetries {
+			t.Errorf("expected retry counter reset, got %d", retries)
+		}
+	})
+
+	t.Run("blocked pin", func(t *testing.T) {
+		_, a, peer := simulatedAgreement(t, 5, StaticPIN([]byte("000000")))
+
+		_, err := a.ECDH(peer)
+		if !errors.Is(err, ErrPINBlocked) {
+			t.Fatalf("expected blocked pin error, got %v", err)
+		}
+
+		// Valid PIN can't be used anymore
+		a.PIN = StaticPIN([]byte(DefaultPIN))
+		if _, err := a.ECDH(peer); !errors.Is(err, ErrPINBlocked) {
+			t.Errorf("expected blocked pin error, got %v", err)
+		}
+	})
+
+	t.Run("empty slot", func(t *testing.T) {
+		_, a, peer := simulatedAgreement(t, 1, StaticPIN([]byte(DefaultPIN)))
+		a.Slot = SlotAuthentication
+
+		if _, err := a.ECDH(peer); !errors.Is(err, ErrEmptySlot) {
+			t.Errorf("expected empty slot error, got %v", err)
+		}
+	})
+
+	t.Run("token removed", func(t *testing.T) {
+		sim, a, peer := simulatedAgreement(t, 1, StaticPIN([]byte(DefaultPIN)))
+		sim.Detach()
+
+		if _, err := a.ECDH(peer); !errors.Is(err, ErrNoToken) {
+			t.Errorf("expected no token error, got %v", err)
+		}
+		if _, err := sim.Open(); !errors.Is(err, ErrNoToken) {
+			t.Errorf("expected no token error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,137 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package piv provides PIV token (YubiKey) backed X25519 identities used to
+// seal and unseal secret containers without exposing the private key.
+//
+// The hardware implementation relies on go-piv and PC/SC, it is only compiled
+// when the `piv` build tag is set. Without it, the simulated provider can be
+// used to exercise the same code paths.
+package piv
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/awnumar/memguard"
+)
+
+// Slot represents a PIV key reference.
+type Slot uint32
+
+const (
+	// SlotAuthentication is the PIV authentication slot (9a).
+	SlotAuthentication Slot = 0x9a
+	// SlotSignature is the digital signature slot (9c).
+	SlotSignature Slot = 0x9c
+	// SlotKeyManagement is the key management slot (9d).
+	SlotKeyManagement Slot = 0x9d
+	// SlotCardAuthentication is the card authentication slot (9e).
+	SlotCardAuthentication Slot = 0x9e
+
+	retiredSlotFirst Slot = 0x82
+	retiredSlotLast  Slot = 0x95
+)
+
+// ParseSlot decodes a slot from its hexadecimal key reference (9a, 9d, 82...).
+func ParseSlot(value string) (Slot, error) {
+	raw := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(value)), "0x")
+
+	ref, err := strconv.ParseUint(raw, 16, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid PIV slot '%s', expected an hexadecimal key reference (9a, 9c, 9d, 9e, 82-95)", value)
+	}
+
+	slot := Slot(ref)
+	if !slot.Valid() {
+		return 0, fmt.Errorf("unsupported PIV slot '%s', expected one of 9a, 9c, 9d, 9e or a retired slot 82-95", value)
+	}
+
+	// No error
+	return slot, nil
+}
+
+// Valid returns true if the slot is a known key slot.
+func (s Slot) Valid() bool {
+	switch s {
+	case SlotAuthentication, SlotSignature, SlotKeyManagement, SlotCardAuthentication:
+		return true
+	default:
+		return s >= retiredSlotFirst && s <= retiredSlotLast
+	}
+}
+
+// String returns the slot key reference.
+func (s Slot) String() string {
+	return fmt.Sprintf("%02x", uint32(s))
+}
+
+// Token describes a connected PIV token.
+type Token interface {
+	// Serial returns the token serial number.
+	Serial() (uint32, error)
+	// PublicKey returns the X25519 public key stored in the given slot.
+	PublicKey(slot Slot) (*[32]byte, error)
+	// SharedKey performs an X25519 key agreement on the token using the slot
+	// private key, the PIN is used to authenticate the operation.
+	SharedKey(slot Slot, pin *memguard.LockedBuffer, peerPublicKey *[32]byte) (*[32]byte, error)
+	// Retries returns the remaining PIN attempts before the PIN is blocked.
+	Retries() (int, error)
+	// Close releases the token connection.
+	Close() error
+}
+
+// Provider opens a connection to a PIV token.
+type Provider interface {
+	Open() (Token, error)
+}
+
+// Reference identifies a key stored on a token.
+type Reference struct {
+	Serial uint32
+	Slot   Slot
+}
+
+// String returns the reference as <serial>/<slot>.
+func (r Reference) String() string {
+	return fmt.Sprintf("%d/%s", r.Serial, r.Slot)
+}
+
+// ParseReference decodes a <serial>/<slot> token key reference.
+func ParseReference(value string) (*Reference, error) {
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid PIV key reference '%s', expected <serial>/<slot>", value)
+	}
+
+	serial, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PIV key reference serial '%s': %w", parts[0], err)
+	}
+
+	slot, err := ParseSlot(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	// No error
+	return &Reference{
+		Serial: uint32(serial),
+		Slot:   slot,
+	}, nil
+}
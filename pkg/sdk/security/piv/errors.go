@@ -0,0 +1,64 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package piv
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNoToken is raised when no PIV token could be found.
+	ErrNoToken = errors.New("no PIV token found, plug your security key and make sure the PC/SC daemon (pcscd) is running")
+	// ErrWrongPIN is raised when the token rejects the given PIN.
+	ErrWrongPIN = errors.New("wrong PIV PIN")
+	// ErrPINBlocked is raised when no PIN attempt remains.
+	ErrPINBlocked = errors.New("PIV PIN is blocked, reset it with the PUK using 'ykman piv access unblock-pin'")
+	// ErrEmptySlot is raised when the requested slot holds no key.
+	ErrEmptySlot = errors.New("PIV slot is empty, generate a key with 'ykman piv keys generate -a X25519 <slot> <public.pem>'")
+	// ErrUnsupportedKey is raised when the slot key is not an X25519 key.
+	ErrUnsupportedKey = errors.New("PIV slot key must be an X25519 key (YubiKey firmware 5.7+)")
+	// ErrHardwareUnsupported is raised when the binary has been built without
+	// PIV hardware support.
+	ErrHardwareUnsupported = errors.New("PIV hardware support is not enabled, rebuild with '-tags piv'")
+)
+
+// PINError is raised when the token rejects a PIN.
+type PINError struct {
+	Retries int
+}
+
+// Error returns the error message.
+func (e *PINError) Error() string {
+	if e.Retries <= 0 {
+		return ErrPINBlocked.Error()
+	}
+	return fmt.Sprintf("%s, %d attempt(s) remaining before the PIN is blocked", ErrWrongPIN.Error(), e.Retries)
+}
+
+// Is matches ErrWrongPIN, and ErrPINBlocked when no attempt remains.
+func (e *PINError) Is(target error) bool {
+	switch {
+	case target == ErrWrongPIN:
+		return true
+	case target == ErrPINBlocked:
+		return e.Retries <= 0
+	default:
+		return false
+	}
+}
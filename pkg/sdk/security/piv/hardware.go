@@ -0,0 +1,182 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build piv
+// +build piv
+
+package piv
+
+import (
+	"crypto/ecdh"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/awnumar/memguard"
+	"github.com/go-piv/piv-go/v2/piv"
+)
+
+// HardwareProvider returns a provider connecting to the first YubiKey found
+// via PC/SC.
+//
+// X25519 slot keys require YubiKey firmware 5.7+. The provider is only
+// available when built with the `piv` tag, it requires the go-piv module and
+// the PC/SC library (pcsclite on Linux) at build time.
+func HardwareProvider() Provider {
+	return &hardwareProvider{}
+}
+
+type hardwareProvider struct{}
+
+func (hardwareProvider) Open() (Token, error) {
+	// List connected cards
+	cards, err := piv.Cards()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNoToken, err)
+	}
+
+	for _, card := range cards {
+		if !strings.Contains(strings.ToLower(card), "yubikey") {
+			continue
+		}
+
+		// Connect to the token
+		yk, err := piv.Open(card)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open PIV token '%s': %w", card, err)
+		}
+
+		return &hardwareToken{yk: yk}, nil
+	}
+
+	return nil, ErrNoToken
+}
+
+// -----------------------------------------------------------------------------
+
+type hardwareToken struct {
+	yk *piv.YubiKey
+}
+
+func (t *hardwareToken) Serial() (uint32, error) {
+	return t.yk.Serial()
+}
+
+func (t *hardwareToken) Retries() (int, error) {
+	return t.yk.Retries()
+}
+
+func (t *hardwareToken) PublicKey(slot Slot) (*[32]byte, error) {
+	pub, err := t.publicKey(slot)
+	if err != nil {
+		return nil, err
+	}
+
+	var out [32]byte
+	copy(out[:], pub.Bytes())
+
+	return &out, nil
+}
+
+func (t *hardwareToken) SharedKey(slot Slot, pin *memguard.LockedBuffer, peerPublicKey *[32]byte) (*[32]byte, error) {
+	pub, err := t.publicKey(slot)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := pivSlot(slot)
+	if err != nil {
+		return nil, err
+	}
+
+	// Retrieve private key handle
+	priv, err := t.yk.PrivateKey(s, pub, piv.KeyAuth{PIN: pin.String()})
+	if err != nil {
+		return nil, fmt.Errorf("unable to access PIV slot %s private key: %w", slot, err)
+	}
+	key, ok := priv.(*piv.X25519PrivateKey)
+	if !ok {
+		return nil, ErrUnsupportedKey
+	}
+
+	// Decode peer public key
+	peer, err := ecdh.X25519().NewPublicKey(peerPublicKey[:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid peer public key: %w", err)
+	}
+
+	// Compute on token
+	secret, err := key.SharedKey(peer)
+	if err != nil {
+		var authErr piv.AuthErr
+		if errors.As(err, &authErr) {
+			return nil, &PINError{Retries: authErr.Retries}
+		}
+		return nil, fmt.Errorf("unable to compute shared key on PIV token: %w", err)
+	}
+
+	var out [32]byte
+	copy(out[:], secret)
+	memguard.WipeBytes(secret)
+
+	return &out, nil
+}
+
+func (t *hardwareToken) Close() error {
+	return t.yk.Close()
+}
+
+func (t *hardwareToken) publicKey(slot Slot) (*ecdh.PublicKey, error) {
+	s, err := pivSlot(slot)
+	if err != nil {
+		return nil, err
+	}
+
+	// Query slot metadata (firmware 5.3+)
+	info, err := t.yk.KeyInfo(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrEmptySlot, err)
+	}
+
+	pub, ok := info.PublicKey.(*ecdh.PublicKey)
+	if !ok || pub.Curve() != ecdh.X25519() {
+		return nil, ErrUnsupportedKey
+	}
+
+	return pub, nil
+}
+
+func pivSlot(slot Slot) (piv.Slot, error) {
+	switch slot {
+	case SlotAuthentication:
+		return piv.SlotAuthentication, nil
+	case SlotSignature:
+		return piv.SlotSignature, nil
+	case SlotKeyManagement:
+		return piv.SlotKeyManagement, nil
+	case SlotCardAuthentication:
+		return piv.SlotCardAuthentication, nil
+	default:
+	}
+
+	s, ok := piv.RetiredKeyManagementSlot(uint32(slot))
+	if !ok {
+		return piv.Slot{}, fmt.Errorf("unsupported PIV slot '%s'", slot)
+	}
+
+	return s, nil
+}
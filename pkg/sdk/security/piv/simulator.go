@@ -0,0 +1,204 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package piv
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/awnumar/memguard"
+	"golang.org/x/crypto/curve25519"
+)
+
+const (
+	// DefaultPIN is the factory PIN of PIV tokens.
+	DefaultPIN = "123456"
+	// DefaultPINRetries is the factory PIN retry counter.
+	DefaultPINRetries = 3
+)
+
+// Simulator is an in-memory PIV token used to test token backed identities
+// without hardware.
+type Simulator struct {
+	sync.Mutex
+
+	serial   uint32
+	pin      []byte
+	retries  int
+	keys     map[Slot]*[32]byte
+	detached bool
+}
+
+var _ Provider = (*Simulator)(nil)
+
+// NewSimulator returns a simulated token protected by the default PIN.
+func NewSimulator(serial uint32) *Simulator {
+	return &Simulator{
+		serial:  serial,
+		pin:     []byte(DefaultPIN),
+		retries: DefaultPINRetries,
+		keys:    map[Slot]*[32]byte{},
+	}
+}
+
+// GenerateKey creates a new X25519 key in the given slot and returns its
+// public key.
+func (s *Simulator) GenerateKey(random io.Reader, slot Slot) (*[32]byte, error) {
+	// Check arguments
+	if !slot.Valid() {
+		return nil, fmt.Errorf("invalid PIV slot '%s'", slot)
+	}
+	if random == nil {
+		random = rand.Reader
+	}
+
+	var priv [32]byte
+	if _, err := io.ReadFull(random, priv[:]); err != nil {
+		return nil, fmt.Errorf("unable to generate private key: %w", err)
+	}
+
+	s.Lock()
+	s.keys[slot] = &priv
+	s.Unlock()
+
+	return s.PublicKey(slot)
+}
+
+// Detach simulates the token removal.
+func (s *Simulator) Detach() {
+	s.Lock()
+	s.detached = true
+	s.Unlock()
+}
+
+// Open returns a token handle.
+func (s *Simulator) Open() (Token, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.detached {
+		return nil, ErrNoToken
+	}
+
+	return &simulatedToken{sim: s}, nil
+}
+
+// PublicKey returns the public key stored in the given slot.
+func (s *Simulator) PublicKey(slot Slot) (*[32]byte, error) {
+	s.Lock()
+	priv, ok := s.keys[slot]
+	s.Unlock()
+	if !ok {
+		return nil, ErrEmptySlot
+	}
+
+	pub, err := curve25519.X25519(priv[:], curve25519.Basepoint)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compute public key: %w", err)
+	}
+
+	var out [32]byte
+	copy(out[:], pub)
+
+	return &out, nil
+}
+
+// -----------------------------------------------------------------------------
+
+type simulatedToken struct {
+	sim *Simulator
+}
+
+func (t *simulatedToken) Serial() (uint32, error) {
+	if err := t.check(); err != nil {
+		return 0, err
+	}
+	return t.sim.serial, nil
+}
+
+func (t *simulatedToken) PublicKey(slot Slot) (*[32]byte, error) {
+	if err := t.check(); err != nil {
+		return nil, err
+	}
+	return t.sim.PublicKey(slot)
+}
+
+func (t *simulatedToken) Retries() (int, error) {
+	if err := t.check(); err != nil {
+		return 0, err
+	}
+
+	t.sim.Lock()
+	defer t.sim.Unlock()
+
+	return t.sim.retries, nil
+}
+
+func (t *simulatedToken) SharedKey(slot Slot, pin *memguard.LockedBuffer, peerPublicKey *[32]byte) (*[32]byte, error) {
+	if err := t.check(); err != nil {
+		return nil, err
+	}
+
+	t.sim.Lock()
+	defer t.sim.Unlock()
+
+	// Authenticate
+	if t.sim.retries <= 0 {
+		return nil, ErrPINBlocked
+	}
+	if pin == nil || subtle.ConstantTimeCompare(pin.Bytes(), t.sim.pin) != 1 {
+		t.sim.retries--
+		return nil, &PINError{Retries: t.sim.retries}
+	}
+	t.sim.retries = DefaultPINRetries
+
+	// Retrieve slot key
+	priv, ok := t.sim.keys[slot]
+	if !ok {
+		return nil, ErrEmptySlot
+	}
+
+	secret, err := curve25519.X25519(priv[:], peerPublicKey[:])
+	if err != nil {
+		return nil, fmt.Errorf("unable to compute shared key: %w", err)
+	}
+
+	var out [32]byte
+	copy(out[:], secret)
+	memguard.WipeBytes(secret)
+
+	return &out, nil
+}
+
+func (t *simulatedToken) Close() error {
+	return nil
+}
+
+func (t *simulatedToken) check() error {
+	t.sim.Lock()
+	defer t.sim.Unlock()
+
+	if t.sim.detached {
+		return ErrNoToken
+	}
+
+	return nil
+}
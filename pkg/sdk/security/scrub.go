@@ -0,0 +1,94 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package security
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Redacted replaces scrubbed values.
+const Redacted = "[REDACTED]"
+
+const sensitiveNames = `(?:password|passphrase|passwd|secret|token|api[_-]?key|private[_-]?key|credentials?|pin)`
+
+var (
+	// Private key blocks
+	pemRegexp = regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----[\s\S]*?(-----END [A-Z0-9 ]*PRIVATE KEY-----|$)`)
+	// Sensitive assignments as `password=value`, `"token": "value"` or `secret: value`
+	quotedAssignmentRegexp = regexp.MustCompile(`(?i)(` + sensitiveNames + `["']?\s*[:=]\s*)(["'])[^"']*["']`)
+	assignmentRegexp       = regexp.MustCompile(`(?i)(` + sensitiveNames + `["']?\s*[:=]\s*)[^\s"',;{}\[\]]+`)
+	// Authorization headers
+	bearerRegexp = regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9._~+/-]+=*`)
+	// JWT / JWE compact serializations
+	jwtRegexp = regexp.MustCompile(`\beyJ[A-Za-z0-9_-]*(?:\.[A-Za-z0-9_-]*){1,4}`)
+	// Vault tokens
+	vaultTokenRegexp = regexp.MustCompile(`\b(?:hv[sbr]|[sbr])\.[A-Za-z0-9_-]{20,}`)
+	// AWS access key identifiers
+	awsKeyRegexp = regexp.MustCompile(`\b(?:AKIA|ASIA)[A-Z0-9]{16}\b`)
+	// Long base64, base64url or hex encoded values
+	encodedRegexp = regexp.MustCompile(`[A-Za-z0-9+/_=-]{32,}`)
+)
+
+// Scrub removes secret-bearing values matching common patterns from the
+// given message.
+func Scrub(msg string) string {
+	msg = pemRegexp.ReplaceAllString(msg, Redacted)
+	msg = quotedAssignmentRegexp.ReplaceAllString(msg, "${1}${2}"+Redacted+"${2}")
+	msg = assignmentRegexp.ReplaceAllString(msg, "${1}"+Redacted)
+	msg = bearerRegexp.ReplaceAllString(msg, "${1} "+Redacted)
+	msg = jwtRegexp.ReplaceAllString(msg, Redacted)
+	msg = vaultTokenRegexp.ReplaceAllString(msg, Redacted)
+	msg = awsKeyRegexp.ReplaceAllString(msg, Redacted)
+	msg = encodedRegexp.ReplaceAllStringFunc(msg, func(s string) string {
+		if looksEncoded(s) {
+			return Redacted
+		}
+		return s
+	})
+
+	return msg
+}
+
+// -----------------------------------------------------------------------------
+
+// looksEncoded returns true for values looking like encoded key material,
+// paths and identifiers are kept.
+func looksEncoded(s string) bool {
+	// Hexadecimal
+	if strings.Trim(strings.ToLower(s), "0123456789abcdef") == "" {
+		return true
+	}
+
+	// Base64 mixes digits, lower and upper case letters
+	var digit, lower, upper bool
+	for _, r := range s {
+		switch {
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		default:
+		}
+	}
+
+	return digit && lower && upper
+}
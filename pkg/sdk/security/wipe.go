@@ -0,0 +1,110 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package security
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/awnumar/memguard"
+)
+
+var (
+	sensitiveMu   sync.Mutex
+	sensitiveID   uint64
+	sensitiveBufs = map[uint64][]byte{}
+)
+
+// RegisterSensitive registers a byte slice to be wiped when the process
+// crashes. The returned function wipes and unregisters it, it is meant to be
+// deferred so that the slice is also wiped while a panic unwinds the stack.
+func RegisterSensitive(buf []byte) (release func()) {
+	if len(buf) == 0 {
+		return func() {}
+	}
+
+	sensitiveMu.Lock()
+	defer sensitiveMu.Unlock()
+
+	sensitiveID++
+	id := sensitiveID
+	sensitiveBufs[id] = buf
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			sensitiveMu.Lock()
+			defer sensitiveMu.Unlock()
+			memguard.WipeBytes(buf)
+			delete(sensitiveBufs, id)
+		})
+	}
+}
+
+// WipeRegistered zeroes all registered sensitive byte slices.
+func WipeRegistered() {
+	sensitiveMu.Lock()
+	defer sensitiveMu.Unlock()
+
+	for id, buf := range sensitiveBufs {
+		memguard.WipeBytes(buf)
+		delete(sensitiveBufs, id)
+	}
+}
+
+// WipeAll destroys all memguard buffers and zeroes registered sensitive byte
+// slices.
+func WipeAll() {
+	memguard.Purge()
+	WipeRegistered()
+}
+
+// SanitizedPanic is the panic value raised again after a crash wiped the
+// sensitive memory.
+type SanitizedPanic struct {
+	Message string
+}
+
+func (p *SanitizedPanic) Error() string {
+	return p.Message
+}
+
+// Protect runs the given function, when it panics all sensitive memory is
+// wiped and the panic is raised again with a scrubbed message.
+func Protect(fn func()) {
+	// The panic is raised again once the original one is recovered, so that
+	// the runtime doesn't print its value.
+	if p := protect(fn); p != nil {
+		panic(p)
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+func protect(fn func()) (p *SanitizedPanic) {
+	defer func() {
+		if r := recover(); r != nil {
+			WipeAll()
+			p = &SanitizedPanic{Message: Scrub(fmt.Sprint(r))}
+		}
+	}()
+
+	fn()
+
+	return nil
+}
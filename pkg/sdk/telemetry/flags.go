@@ -0,0 +1,81 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package telemetry
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// SensitiveAnnotation marks a flag excluded from telemetry.
+const SensitiveAnnotation = "harp.elastic.co/v1/telemetry#sensitive"
+
+// sensitiveFlagTerms lists flag name fragments denoting secret-bearing flags.
+var sensitiveFlagTerms = []string{
+	"key",
+	"passphrase",
+	"password",
+	"psk",
+	"secret",
+	"token",
+	"credential",
+	"identity",
+}
+
+// MarkSensitive excludes the given flag from telemetry even if its name is
+// not matched by the denylist.
+func MarkSensitive(flags *pflag.FlagSet, name string) error {
+	return flags.SetAnnotation(name, SensitiveAnnotation, []string{"true"})
+}
+
+// IsSensitive returns true if the flag must not be recorded.
+func IsSensitive(f *pflag.Flag) bool {
+	if f == nil {
+		return true
+	}
+	if _, ok := f.Annotations[SensitiveAnnotation]; ok {
+		return true
+	}
+
+	name := strings.ToLower(f.Name)
+	for _, term := range sensitiveFlagTerms {
+		if strings.Contains(name, term) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FlagNames returns the sorted names of flags set on the command line,
+// sensitive flags excluded. Values are never collected.
+func FlagNames(cmd *cobra.Command) []string {
+	names := []string{}
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		if IsSensitive(f) {
+			return
+		}
+		names = append(names, f.Name)
+	})
+	sort.Strings(names)
+
+	return names
+}
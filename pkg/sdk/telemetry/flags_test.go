@@ -0,0 +1,86 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package telemetry
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/cobra"
+)
+
+func TestFlagNames(t *testing.T) {
+	cmd := &cobra.Command{Use: "test", Run: func(*cobra.Command, []string) {}}
+	cmd.Flags().String("in", "", "")
+	cmd.Flags().String("out", "", "")
+	cmd.Flags().String("key", "", "")
+	cmd.Flags().String("passphrase", "", "")
+	cmd.Flags().String("vault-token", "", "")
+	cmd.Flags().String("client-secret", "", "")
+	cmd.Flags().String("identity", "", "")
+	cmd.Flags().String("recipient", "", "")
+	cmd.Flags().String("unset", "", "")
+	if err := MarkSensitive(cmd.Flags(), "recipient"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cmd.SetArgs([]string{
+		"--out", "foo.bundle",
+		"--in", "bar.bundle",
+		"--key", "ZmVybmV0",
+		"--passphrase", "changeme",
+		"--vault-token", "s.xxx",
+		"--client-secret", "yyy",
+		"--identity", "id.json",
+		"--recipient", "v1.ipk.xxx",
+	})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := FlagNames(cmd)
+	want := []string{"in", "out"}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("FlagNames():\n-got/+want\ndiff %s", diff)
+	}
+}
+
+func TestIsSensitive(t *testing.T) {
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().String("prefix", "", "")
+	cmd.Flags().String("secret-path", "", "")
+	cmd.Flags().String("PSK", "", "")
+
+	testCases := []struct {
+		desc string
+		name string
+		want bool
+	}{
+		{desc: "nil flag", name: "missing", want: true},
+		{desc: "regular", name: "prefix", want: false},
+		{desc: "denylisted", name: "secret-path", want: true},
+		{desc: "case insensitive", name: "PSK", want: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if got := IsSensitive(cmd.Flags().Lookup(tC.name)); got != tC.want {
+				t.Errorf("IsSensitive(%q) = %v, want %v", tC.name, got, tC.want)
+			}
+		})
+	}
+}
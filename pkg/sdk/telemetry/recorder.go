@@ -0,0 +1,134 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// DefaultMaxSize is the file size triggering a rotation.
+	DefaultMaxSize = 1 << 20
+	// DefaultTimeout is the maximum delay allowed to record an event.
+	DefaultTimeout = 100 * time.Millisecond
+)
+
+// Recorder appends events as JSON lines to a file, rotated when it exceeds
+// the maximum size. Only one rotated file is kept.
+type Recorder struct {
+	path    string
+	maxSize int64
+	timeout time.Duration
+}
+
+// Option is used to customize the recorder.
+type Option func(*Recorder)
+
+// WithMaxSize sets the file size triggering a rotation.
+func WithMaxSize(size int64) Option {
+	return func(r *Recorder) {
+		r.maxSize = size
+	}
+}
+
+// WithTimeout sets the maximum delay allowed to record an event.
+func WithTimeout(timeout time.Duration) Option {
+	return func(r *Recorder) {
+		r.timeout = timeout
+	}
+}
+
+// NewRecorder returns a recorder writing to the given path.
+func NewRecorder(path string, opts ...Option) *Recorder {
+	r := &Recorder{
+		path:    path,
+		maxSize: DefaultMaxSize,
+		timeout: DefaultTimeout,
+	}
+	for _, o := range opts {
+		o(r)
+	}
+	return r
+}
+
+// Path returns the current telemetry file path.
+func (r *Recorder) Path() string {
+	return r.path
+}
+
+// RotatedPath returns the rotated telemetry file path.
+func (r *Recorder) RotatedPath() string {
+	return r.path + ".1"
+}
+
+// Record the event. Recording is best effort, errors are ignored and the
+// call returns after the recorder timeout.
+func (r *Recorder) Record(ev *Event) {
+	if r == nil || ev == nil {
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = r.write(ev)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(r.timeout):
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+func (r *Recorder) write(ev *Event) error {
+	line, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("unable to encode event: %w", err)
+	}
+	line = append(line, '\n')
+
+	// Ensure parent directory
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o700); err != nil {
+		return fmt.Errorf("unable to create telemetry directory: %w", err)
+	}
+
+	// Rotate if required
+	if fi, err := os.Stat(r.path); err == nil && fi.Size()+int64(len(line)) > r.maxSize {
+		if err := os.Rename(r.path, r.RotatedPath()); err != nil {
+			return fmt.Errorf("unable to rotate telemetry file: %w", err)
+		}
+	}
+
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("unable to open telemetry file: %w", err)
+	}
+
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return fmt.Errorf("unable to write telemetry event: %w", err)
+	}
+
+	return f.Close()
+}
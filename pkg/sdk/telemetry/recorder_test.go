@@ -0,0 +1,93 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package telemetry
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecorder_Rotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "harp", "telemetry.jsonl")
+	r := NewRecorder(path, WithMaxSize(300), WithTimeout(time.Second))
+
+	for i := 0; i < 10; i++ {
+		r.Record(&Event{Command: "harp bundle dump", Flags: []string{"in"}, DurationMs: int64(i)})
+	}
+
+	// Current file size is bounded
+	fi, err := os.Stat(r.Path())
+	if err != nil {
+		t.Fatalf("unable to stat telemetry file: %v", err)
+	}
+	if fi.Size() > 300 {
+		t.Errorf("telemetry file size %d exceeds limit", fi.Size())
+	}
+	if fi.Mode().Perm() != 0o600 {
+		t.Errorf("unexpected telemetry file mode %s", fi.Mode())
+	}
+
+	// Rotated file exists
+	if _, err := os.Stat(r.RotatedPath()); err != nil {
+		t.Fatalf("rotated file must exist: %v", err)
+	}
+
+	// Only the rotated and current files are kept, most recent events last
+	events, err := r.Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) == 0 || len(events) >= 10 {
+		t.Fatalf("unexpected event count %d", len(events))
+	}
+	if last := events[len(events)-1]; last.DurationMs != 9 {
+		t.Errorf("last event must be the most recent one, got %d", last.DurationMs)
+	}
+}
+
+func TestRecorder_BestEffort(t *testing.T) {
+	// Parent path is a file, the recorder can't create its directory
+	parent := filepath.Join(t.TempDir(), "file")
+	if err := ioutil.WriteFile(parent, []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRecorder(filepath.Join(parent, "telemetry.jsonl"))
+	r.Record(&Event{Command: "harp"})
+
+	// Nil recorder and session are no-op
+	var nr *Recorder
+	nr.Record(&Event{Command: "harp"})
+	var s *Session
+	s.End(0)
+}
+
+func TestRecorder_Load_Missing(t *testing.T) {
+	r := NewRecorder(filepath.Join(t.TempDir(), "telemetry.jsonl"))
+
+	events, err := r.Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("expected no event, got %d", len(events))
+	}
+}
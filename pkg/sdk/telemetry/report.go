@@ -0,0 +1,139 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package telemetry
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Load reads recorded events from the rotated and current files. Malformed
+// lines are skipped.
+func (r *Recorder) Load() ([]Event, error) {
+	events := []Event{}
+	for _, path := range []string{r.RotatedPath(), r.Path()} {
+		f, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to open telemetry file '%s': %w", path, err)
+		}
+
+		evs, err := decode(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read telemetry file '%s': %w", path, err)
+		}
+		events = append(events, evs...)
+	}
+
+	// No error
+	return events, nil
+}
+
+// Summary holds aggregated usage of a command.
+type Summary struct {
+	Command      string
+	Invocations  int
+	Failures     int
+	MeanDuration time.Duration
+	Flags        map[string]int
+}
+
+// Aggregate events per command, sorted by decreasing invocation count.
+func Aggregate(events []Event) []Summary {
+	index := map[string]*Summary{}
+	totals := map[string]int64{}
+	for i := range events {
+		ev := &events[i]
+		s, ok := index[ev.Command]
+		if !ok {
+			s = &Summary{Command: ev.Command, Flags: map[string]int{}}
+			index[ev.Command] = s
+		}
+		s.Invocations++
+		if ev.ExitStatus != 0 {
+			s.Failures++
+		}
+		totals[ev.Command] += ev.DurationMs
+		for _, f := range ev.Flags {
+			s.Flags[f]++
+		}
+	}
+
+	res := make([]Summary, 0, len(index))
+	for cmd, s := range index {
+		s.MeanDuration = time.Duration(totals[cmd]/int64(s.Invocations)) * time.Millisecond
+		res = append(res, *s)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Invocations != res[j].Invocations {
+			return res[i].Invocations > res[j].Invocations
+		}
+		return res[i].Command < res[j].Command
+	})
+
+	return res
+}
+
+// WriteTable writes summaries as a human readable table.
+func WriteTable(w io.Writer, summaries []Summary) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "COMMAND\tCALLS\tFAILURES\tMEAN DURATION\tFLAGS")
+	for _, s := range summaries {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\n", s.Command, s.Invocations, s.Failures, s.MeanDuration, formatFlags(s.Flags))
+	}
+	return tw.Flush()
+}
+
+// -----------------------------------------------------------------------------
+
+func decode(r io.Reader) ([]Event, error) {
+	events := []Event{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var ev Event
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			continue
+		}
+		events = append(events, ev)
+	}
+	return events, scanner.Err()
+}
+
+func formatFlags(flags map[string]int) string {
+	names := make([]string, 0, len(flags))
+	for name := range flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("--%s(%d)", name, flags[name]))
+	}
+	return strings.Join(parts, " ")
+}
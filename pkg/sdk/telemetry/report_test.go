@@ -0,0 +1,75 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package telemetry
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAggregate(t *testing.T) {
+	events := []Event{
+		{Command: "harp bundle dump", Flags: []string{"in"}, DurationMs: 10},
+		{Command: "harp bundle dump", Flags: []string{"in", "data-only"}, DurationMs: 30, ExitStatus: 1},
+		{Command: "harp keygen fernet", DurationMs: 5},
+		{Command: "harp bundle dump", Flags: []string{"in"}, DurationMs: 20},
+	}
+
+	got := Aggregate(events)
+	want := []Summary{
+		{
+			Command:      "harp bundle dump",
+			Invocations:  3,
+			Failures:     1,
+			MeanDuration: 20 * time.Millisecond,
+			Flags:        map[string]int{"in": 3, "data-only": 1},
+		},
+		{
+			Command:      "harp keygen fernet",
+			Invocations:  1,
+			MeanDuration: 5 * time.Millisecond,
+			Flags:        map[string]int{},
+		},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Aggregate():\n-got/+want\ndiff %s", diff)
+	}
+
+	var out bytes.Buffer
+	if err := WriteTable(&out, got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Contains(out.Bytes(), []byte("--data-only(1) --in(3)")) {
+		t.Errorf("unexpected table output:\n%s", out.String())
+	}
+}
+
+func TestDecode_SkipsMalformed(t *testing.T) {
+	in := bytes.NewBufferString("{\"command\":\"harp\"}\nnot-json\n{\"command\":\"harp version\"}\n")
+
+	events, err := decode(in)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 2 {
+		t.Errorf("expected 2 events, got %d", len(events))
+	}
+}
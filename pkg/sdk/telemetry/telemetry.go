@@ -0,0 +1,100 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package telemetry provides an opt-in, local only, command usage recorder.
+//
+// Nothing is recorded unless the HARP_TELEMETRY environment variable is set
+// to "local", and recorded events are never sent anywhere.
+package telemetry
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// EnvVar is the environment variable used to enable telemetry.
+	EnvVar = "HARP_TELEMETRY"
+	// ModeLocal enables the local only recorder.
+	ModeLocal = "local"
+)
+
+// Event describes a command invocation.
+type Event struct {
+	Time       time.Time `json:"time"`
+	Command    string    `json:"command"`
+	Flags      []string  `json:"flags,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	ExitStatus int       `json:"exit_status"`
+}
+
+// Enabled returns true if the local telemetry is enabled.
+func Enabled() bool {
+	return strings.EqualFold(strings.TrimSpace(os.Getenv(EnvVar)), ModeLocal)
+}
+
+// DefaultPath returns the telemetry file path located in the user
+// configuration directory.
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "harp", "telemetry.jsonl"), nil
+}
+
+// -----------------------------------------------------------------------------
+
+// Session tracks a command invocation until its completion.
+type Session struct {
+	recorder *Recorder
+	event    Event
+	start    time.Time
+	once     sync.Once
+}
+
+// Start a session for the given command. Only flag names are collected,
+// sensitive flags are excluded.
+func Start(r *Recorder, cmd *cobra.Command) *Session {
+	now := time.Now()
+	return &Session{
+		recorder: r,
+		start:    now,
+		event: Event{
+			Time:    now.UTC(),
+			Command: cmd.CommandPath(),
+			Flags:   FlagNames(cmd),
+		},
+	}
+}
+
+// End the session and record the event. Only the first call is recorded.
+func (s *Session) End(status int) {
+	if s == nil {
+		return
+	}
+	s.once.Do(func() {
+		s.event.DurationMs = time.Since(s.start).Milliseconds()
+		s.event.ExitStatus = status
+		s.recorder.Record(&s.event)
+	})
+}
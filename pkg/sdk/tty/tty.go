@@ -0,0 +1,90 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package tty provides terminal detection and secret prompting with a pure-Go
+// fallback on platforms without terminal support (js/wasm, plan9).
+package tty
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Supported returns true if secrets can be read from a terminal with echo
+// disabled on this platform.
+func Supported() bool {
+	return supported
+}
+
+// IsTerminal returns true if the given file is a terminal. It always returns
+// false when terminals are not supported.
+func IsTerminal(f *os.File) bool {
+	if f == nil {
+		return false
+	}
+	return isTerminal(f)
+}
+
+// ReadPassword reads a line from the given terminal with echo disabled. When
+// terminals are not supported, the line is read as is and may be echoed by
+// the host.
+func ReadPassword(f *os.File) ([]byte, error) {
+	// Check arguments
+	if f == nil {
+		return nil, fmt.Errorf("unable to read from a nil file")
+	}
+
+	return readPassword(f)
+}
+
+// -----------------------------------------------------------------------------
+
+// readLine reads a line byte per byte, so that the remaining input is left
+// unread for next readers. The line ending is not returned.
+func readLine(r io.Reader) ([]byte, error) {
+	var (
+		buf [1]byte
+		out []byte
+	)
+	for {
+		n, err := r.Read(buf[:])
+		if n > 0 {
+			if buf[0] == '\n' {
+				break
+			}
+			out = append(out, buf[0])
+		}
+		if err == io.EOF {
+			if len(out) == 0 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Trim windows line ending
+	if l := len(out); l > 0 && out[l-1] == '\r' {
+		out = out[:l-1]
+	}
+
+	// No error
+	return out, nil
+}
@@ -0,0 +1,86 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tty
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func Test_readLine(t *testing.T) {
+	testCases := []struct {
+		name      string
+		input     string
+		want      string
+		remaining string
+		wantErr   error
+	}{
+		{name: "line", input: "secret\nnext", want: "secret", remaining: "next"},
+		{name: "windows line ending", input: "secret\r\nnext", want: "secret", remaining: "next"},
+		{name: "no line ending", input: "secret", want: "secret"},
+		{name: "empty line", input: "\nnext", want: "", remaining: "next"},
+		{name: "empty input", input: "", wantErr: io.ErrUnexpectedEOF},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := strings.NewReader(tc.input)
+
+			got, err := readLine(iotest.OneByteReader(r))
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("expected '%s', got '%s'", tc.want, got)
+			}
+
+			// Remaining input is left unread
+			rest, _ := ioutil.ReadAll(r)
+			if string(rest) != tc.remaining {
+				t.Errorf("expected remaining '%s', got '%s'", tc.remaining, rest)
+			}
+		})
+	}
+}
+
+func Test_IsTerminal(t *testing.T) {
+	if IsTerminal(nil) {
+		t.Error("nil file must not be a terminal")
+	}
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "input"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if IsTerminal(f) {
+		t.Error("regular file must not be a terminal")
+	}
+}
+
+func Test_ReadPassword_Nil(t *testing.T) {
+	if _, err := ReadPassword(nil); err == nil {
+		t.Error("expected error for nil file")
+	}
+}
@@ -0,0 +1,84 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package siv
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+)
+
+// dbl multiplies the block by x in GF(2^128) (RFC 5297 section 2.3).
+func dbl(in []byte) []byte {
+	out := make([]byte, len(in))
+	carry := byte(0)
+	for i := len(in) - 1; i >= 0; i-- {
+		out[i] = in[i]<<1 | carry
+		carry = in[i] >> 7
+	}
+	// Constant-time reduction
+	out[len(out)-1] ^= byte(subtle.ConstantTimeByteEq(carry, 1)) * 0x87
+	return out
+}
+
+func xorBytes(dst, a, b []byte) {
+	for i := range dst {
+		dst[i] = a[i] ^ b[i]
+	}
+}
+
+// pad appends the 10* padding to the incomplete block.
+func pad(in []byte, size int) []byte {
+	out := make([]byte, size)
+	copy(out, in)
+	out[len(in)] = 0x80
+	return out
+}
+
+// cmac computes AES-CMAC of the message (RFC 4493).
+func cmac(block cipher.Block, msg []byte) []byte {
+	size := block.BlockSize()
+
+	// Derive subkeys
+	l := make([]byte, size)
+	block.Encrypt(l, l)
+	k1 := dbl(l)
+	k2 := dbl(k1)
+
+	// Prepare last block
+	n := (len(msg) + size - 1) / size
+	last := make([]byte, size)
+	if n > 0 && len(msg)%size == 0 {
+		xorBytes(last, msg[(n-1)*size:], k1)
+	} else {
+		if n == 0 {
+			n = 1
+		}
+		xorBytes(last, pad(msg[(n-1)*size:], size), k2)
+	}
+
+	// Chain blocks
+	x := make([]byte, size)
+	for i := 0; i < n-1; i++ {
+		xorBytes(x, x, msg[i*size:])
+		block.Encrypt(x, x)
+	}
+	xorBytes(x, x, last)
+	block.Encrypt(x, x)
+
+	return x
+}
@@ -0,0 +1,117 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package siv
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+)
+
+const ivSize = aes.BlockSize
+
+var errOpen = errors.New("siv: message authentication failed")
+
+// aead implements AES-SIV (RFC 5297) deterministic authenticated encryption.
+type aead struct {
+	mac cipher.Block
+	ctr cipher.Block
+}
+
+// newAEAD initializes AES-SIV with a 256, 384 or 512-bit key. The first half
+// is used for S2V, the second half for CTR encryption.
+func newAEAD(key []byte) (*aead, error) {
+	switch len(key) {
+	case 32, 48, 64:
+	default:
+		return nil, fmt.Errorf("siv: invalid key length (%d)", len(key))
+	}
+
+	mac, err := aes.NewCipher(key[:len(key)/2])
+	if err != nil {
+		return nil, fmt.Errorf("siv: unable to initialize block cipher: %w", err)
+	}
+	ctr, err := aes.NewCipher(key[len(key)/2:])
+	if err != nil {
+		return nil, fmt.Errorf("siv: unable to initialize block cipher: %w", err)
+	}
+
+	return &aead{mac: mac, ctr: ctr}, nil
+}
+
+// s2v computes the synthetic IV from associated data and plaintext.
+func (a *aead) s2v(ad [][]byte, plaintext []byte) []byte {
+	d := cmac(a.mac, make([]byte, aes.BlockSize))
+	for _, s := range ad {
+		xorBytes(d, dbl(d), cmac(a.mac, s))
+	}
+
+	var t []byte
+	if len(plaintext) >= aes.BlockSize {
+		t = append([]byte{}, plaintext...)
+		end := t[len(t)-aes.BlockSize:]
+		xorBytes(end, end, d)
+	} else {
+		t = pad(plaintext, aes.BlockSize)
+		xorBytes(t, t, dbl(d))
+	}
+
+	return cmac(a.mac, t)
+}
+
+// xorKeyStream applies the CTR keystream derived from the synthetic IV.
+func (a *aead) xorKeyStream(dst, src, v []byte) {
+	q := append([]byte{}, v...)
+	q[8] &= 0x7f
+	q[12] &= 0x7f
+	cipher.NewCTR(a.ctr, q).XORKeyStream(dst, src)
+}
+
+// seal returns the synthetic IV followed by the ciphertext.
+func (a *aead) seal(plaintext []byte, ad ...[]byte) []byte {
+	v := a.s2v(ad, plaintext)
+
+	out := make([]byte, ivSize+len(plaintext))
+	copy(out, v)
+	a.xorKeyStream(out[ivSize:], plaintext, v)
+
+	return out
+}
+
+// open decrypts and authenticates the sealed message.
+func (a *aead) open(sealed []byte, ad ...[]byte) ([]byte, error) {
+	if len(sealed) < ivSize {
+		return nil, errOpen
+	}
+
+	v := sealed[:ivSize]
+	out := make([]byte, len(sealed)-ivSize)
+	a.xorKeyStream(out, sealed[ivSize:], v)
+
+	// Check synthetic IV
+	if subtle.ConstantTimeCompare(a.s2v(ad, out), v) != 1 {
+		for i := range out {
+			out[i] = 0
+		}
+		return nil, errOpen
+	}
+
+	return out, nil
+}
@@ -0,0 +1,134 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package siv
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	out, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// RFC 4493 section 4 test vectors.
+func TestCMAC_RFC4493(t *testing.T) {
+	message := "6bc1bee22e409f96e93d7e117393172a ae2d8a571e03ac9c9eb76fac45af8e51 30c81c46a35ce411e5fbc1191a0a52ef f69f2445df4f9b17ad2b417be66c3710"
+
+	testCases := []struct {
+		desc   string
+		length int
+		want   string
+	}{
+		{desc: "empty", length: 0, want: "bb1d6929e95937287fa37d129b756746"},
+		{desc: "one block", length: 16, want: "070a16b46b4d4144f79bdd9dd04a287c"},
+		{desc: "incomplete block", length: 40, want: "dfa66747de9ae63030ca32611497c827"},
+		{desc: "four blocks", length: 64, want: "51f0bebf7e3b9d92fc49741779363cfe"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			block, err := aes.NewCipher(unhex(t, "2b7e151628aed2a6abf7158809cf4f3c"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := cmac(block, unhex(t, message)[:tC.length])
+			if want := unhex(t, tC.want); !bytes.Equal(got, want) {
+				t.Errorf("%q. cmac():\ngot  %x\nwant %x", tC.desc, got, want)
+			}
+		})
+	}
+}
+
+// RFC 5297 appendix A test vectors.
+func TestSIV_RFC5297(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		key       string
+		ad        []string
+		plaintext string
+		want      string
+	}{
+		{
+			desc:      "deterministic authenticated encryption",
+			key:       "fffefdfc fbfaf9f8 f7f6f5f4 f3f2f1f0 f0f1f2f3 f4f5f6f7 f8f9fafb fcfdfeff",
+			ad:        []string{"10111213 14151617 18191a1b 1c1d1e1f 20212223 24252627"},
+			plaintext: "11223344 55667788 99aabbcc ddee",
+			want:      "85632d07 c6e8f37f 950acd32 0a2ecc93 40c02b96 90c4dc04 daef7f6a fe5c",
+		},
+		{
+			desc: "nonce-based authenticated encryption",
+			key:  "7f7e7d7c 7b7a7978 77767574 73727170 40414243 44454647 48494a4b 4c4d4e4f",
+			ad: []string{
+				"00112233 44556677 8899aabb ccddeeff deaddada deaddada ffeeddcc bbaa9988 77665544 33221100",
+				"10203040 50607080 90a0",
+				"09f91102 9d74e35b d84156c5 635688c0",
+			},
+			plaintext: "74686973 20697320 736f6d65 20706c61 696e7465 78742074 6f20656e 63727970 74207573 696e6720 5349562d 414553",
+			want:      "7bdb6e3b 432667eb 06f4d14b ff2fbd0f cb900f2f ddbe4043 26601965 c889bf17 dba77ceb 094fa663 b7a3f748 ba8af829 ea64ad54 4a272e9c 485b62a3 fd5c0d",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			a, err := newAEAD(unhex(t, tC.key))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ad := [][]byte{}
+			for _, s := range tC.ad {
+				ad = append(ad, unhex(t, s))
+			}
+			plaintext := unhex(t, tC.plaintext)
+
+			got := a.seal(plaintext, ad...)
+			if want := unhex(t, tC.want); !bytes.Equal(got, want) {
+				t.Errorf("%q. seal():\ngot  %x\nwant %x", tC.desc, got, want)
+			}
+
+			opened, err := a.open(got, ad...)
+			if err != nil {
+				t.Fatalf("unable to open: %v", err)
+			}
+			if !bytes.Equal(opened, plaintext) {
+				t.Errorf("%q. open():\ngot  %x\nwant %x", tC.desc, opened, plaintext)
+			}
+
+			// Tampered message
+			got[len(got)-1] ^= 0x01
+			if _, err := a.open(got, ad...); err == nil {
+				t.Error("tampered message must not be opened")
+			}
+		})
+	}
+}
+
+func TestNewAEAD_InvalidKey(t *testing.T) {
+	for _, l := range []int{0, 16, 63} {
+		if _, err := newAEAD(make([]byte, l)); err == nil {
+			t.Errorf("key length %d must be rejected", l)
+		}
+	}
+}
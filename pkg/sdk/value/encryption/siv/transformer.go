@@ -0,0 +1,81 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package siv
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/elastic/harp/pkg/sdk/value"
+)
+
+const (
+	keyLength = 64
+)
+
+// Transformer returns an AES-256-SIV value transformer instance. Encryption
+// is deterministic, identical values produce identical ciphertexts.
+func Transformer(key string) (value.Transformer, error) {
+	// Decode key
+	k, err := base64.URLEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("siv: unable to decode key: %w", err)
+	}
+	if l := len(k); l != keyLength {
+		return nil, fmt.Errorf("siv: invalid secret key length (%d)", l)
+	}
+
+	// Initialize AES-SIV
+	a, err := newAEAD(k)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return transformer
+	return &sivTransformer{
+		aead: a,
+	}, nil
+}
+
+// -----------------------------------------------------------------------------
+
+type sivTransformer struct {
+	aead *aead
+}
+
+func (t *sivTransformer) To(_ context.Context, input []byte) ([]byte, error) {
+	// Check allocation limit
+	if len(input) > 100*1024*1024 { // Limit to 100MB
+		return nil, fmt.Errorf("siv: data too large")
+	}
+
+	// Encrypt using AES-SIV
+	return t.aead.seal(input), nil
+}
+
+func (t *sivTransformer) From(_ context.Context, input []byte) ([]byte, error) {
+	// Decrypt using AES-SIV
+	out, err := t.aead.open(input)
+	if err != nil {
+		return nil, fmt.Errorf("siv: unable to decrypt data: %w", err)
+	}
+
+	// No error
+	return out, nil
+}
@@ -0,0 +1,73 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package siv
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+)
+
+func Test_Transformer_SIV_InvalidKey(t *testing.T) {
+	keys := []string{
+		"",
+		"foo",
+		"zQyPnNa-jlQsLW3Ypd87cX88ROMkdgnqv0a3y8LiISg=",
+	}
+	for _, k := range keys {
+		key := k
+		t.Run(fmt.Sprintf("key `%s`", key), func(t *testing.T) {
+			underTest, err := Transformer(key)
+			if err == nil {
+				t.Fatalf("Transformer should raise an error with key `%s`", key)
+			}
+			if underTest != nil {
+				t.Fatalf("Transformer instance should be nil")
+			}
+		})
+	}
+}
+
+func Test_Transformer_SIV_RoundTrip(t *testing.T) {
+	underTest, err := Transformer("AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8gISIjJCUmJygpKissLS4vMDEyMzQ1Njc4OTo7PD0-Pw==")
+	if err != nil {
+		t.Fatalf("unable to initialize transformer: %v", err)
+	}
+
+	ctx := context.Background()
+	plainText := []byte("cool-protected-data")
+
+	encrypted, err := underTest.To(ctx, plainText)
+	if err != nil {
+		t.Fatalf("unable to encrypt: %v", err)
+	}
+
+	decrypted, err := underTest.From(ctx, encrypted)
+	if err != nil {
+		t.Fatalf("unable to decrypt: %v", err)
+	}
+	if !bytes.Equal(decrypted, plainText) {
+		t.Errorf("From():\ngot  %q\nwant %q", decrypted, plainText)
+	}
+
+	// Truncated input
+	if _, err := underTest.From(ctx, encrypted[:10]); err == nil {
+		t.Error("truncated input must be rejected")
+	}
+}
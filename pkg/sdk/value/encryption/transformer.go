@@ -18,6 +18,8 @@
 package encryption
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -25,39 +27,134 @@ import (
 	"github.com/elastic/harp/pkg/sdk/value/encryption/aes"
 	"github.com/elastic/harp/pkg/sdk/value/encryption/fernet"
 	"github.com/elastic/harp/pkg/sdk/value/encryption/secretbox"
+	"github.com/elastic/harp/pkg/sdk/value/encryption/siv"
+	"github.com/elastic/harp/pkg/sdk/value/encryption/xchacha"
 )
 
+// Supported value encryption algorithms, also used as key prefixes.
 const (
-	secretboxPrefix = "secretbox:"
-	aesgcmPrefix    = "aes-gcm:"
-	fernetPrefix    = "fernet:"
+	AlgorithmSecretBox = "secretbox"
+	AlgorithmAESGCM    = "aes-gcm"
+	AlgorithmFernet    = "fernet"
+	AlgorithmXChaCha   = "xchacha"
+	AlgorithmAESSIV    = "aes-siv"
 )
 
-// FromKey returns the value transformer that match the value format.
+// jwkAlgorithms maps JWK "alg" values to encryption algorithms.
+var jwkAlgorithms = map[string]string{
+	"A256GCM": AlgorithmAESGCM,
+	"XC20P":   AlgorithmXChaCha,
+	"A256SIV": AlgorithmAESSIV,
+}
+
+// FromKey returns the value transformer that match the value format. The key
+// is prefixed by the algorithm name ("aes-siv:<key>"), or is a symmetric JWK
+// with an "alg" attribute. Unprefixed keys are fernet keys.
 func FromKey(keyValue string) (value.Transformer, error) {
-	var (
-		transformer value.Transformer
-		err         error
-	)
+	// Check arguments
+	if keyValue == "" {
+		return nil, fmt.Errorf("unable to select a value transformer with blank value")
+	}
+
+	// Resolve algorithm
+	algorithm, key, err := parseKey(keyValue)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize value transformer: %w", err)
+	}
+
+	return build(algorithm, key)
+}
 
+// ForAlgorithm returns the value transformer of the given algorithm using the
+// key material, the algorithm selected by the key is ignored.
+func ForAlgorithm(algorithm, keyValue string) (value.Transformer, error) {
 	// Check arguments
 	if keyValue == "" {
 		return nil, fmt.Errorf("unable to select a value transformer with blank value")
 	}
 
-	// Build the value transformer according to used prefix.
-	if strings.HasPrefix(keyValue, secretboxPrefix) {
+	// Extract key material
+	_, key, err := parseKey(keyValue)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize value transformer: %w", err)
+	}
+
+	return build(algorithm, key)
+}
+
+// Algorithm returns the encryption algorithm selected by the key.
+func Algorithm(keyValue string) (string, error) {
+	algorithm, _, err := parseKey(keyValue)
+	return algorithm, err
+}
+
+// -----------------------------------------------------------------------------
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	K   string `json:"k"`
+}
+
+// parseKey returns the algorithm and the key material encoded as expected
+// by the transformer.
+func parseKey(keyValue string) (algorithm, key string, err error) {
+	// Symmetric JWK
+	if strings.HasPrefix(strings.TrimSpace(keyValue), "{") {
+		var jwk jsonWebKey
+		if errJSON := json.Unmarshal([]byte(keyValue), &jwk); errJSON != nil {
+			return "", "", fmt.Errorf("unable to decode JWK: %w", errJSON)
+		}
+		if jwk.Kty != "oct" {
+			return "", "", fmt.Errorf("unsupported JWK key type '%s'", jwk.Kty)
+		}
+		algorithm, ok := jwkAlgorithms[jwk.Alg]
+		if !ok {
+			return "", "", fmt.Errorf("unsupported JWK algorithm '%s'", jwk.Alg)
+		}
+		raw, errDecode := base64.RawURLEncoding.DecodeString(jwk.K)
+		if errDecode != nil {
+			return "", "", fmt.Errorf("unable to decode JWK key material: %w", errDecode)
+		}
+		return algorithm, base64.URLEncoding.EncodeToString(raw), nil
+	}
+
+	// Prefixed key
+	for _, algorithm := range []string{AlgorithmSecretBox, AlgorithmAESGCM, AlgorithmFernet, AlgorithmXChaCha, AlgorithmAESSIV} {
+		if strings.HasPrefix(keyValue, algorithm+":") {
+			return algorithm, strings.TrimPrefix(keyValue, algorithm+":"), nil
+		}
+	}
+
+	// Fallback to fernet
+	return AlgorithmFernet, keyValue, nil
+}
+
+func build(algorithm, key string) (value.Transformer, error) {
+	var (
+		transformer value.Transformer
+		err         error
+	)
+
+	// Build the value transformer according to the algorithm.
+	switch algorithm {
+	case AlgorithmSecretBox:
 		// Activate Nacl SecretBox transformer
-		transformer, err = secretbox.Transformer(strings.TrimPrefix(keyValue, secretboxPrefix))
-	} else if strings.HasPrefix(keyValue, aesgcmPrefix) {
+		transformer, err = secretbox.Transformer(key)
+	case AlgorithmAESGCM:
 		// Activate AES-GCM transformer
-		transformer, err = aes.Transformer(strings.TrimPrefix(keyValue, aesgcmPrefix))
-	} else if strings.HasPrefix(keyValue, fernetPrefix) {
+		transformer, err = aes.Transformer(key)
+	case AlgorithmFernet:
 		// Activate Fernet transformer
-		transformer, err = fernet.Transformer(strings.TrimPrefix(keyValue, fernetPrefix))
-	} else {
-		// Fallback to fernet
-		transformer, err = fernet.Transformer(keyValue)
+		transformer, err = fernet.Transformer(key)
+	case AlgorithmXChaCha:
+		// Activate XChaCha20-Poly1305 transformer
+		transformer, err = xchacha.Transformer(key)
+	case AlgorithmAESSIV:
+		// Activate AES-SIV transformer
+		transformer, err = siv.Transformer(key)
+	default:
+		return nil, fmt.Errorf("unsupported encryption algorithm '%s'", algorithm)
 	}
 
 	// Check transformer initialization error
@@ -18,13 +18,17 @@
 package encryption
 
 import (
+	"bytes"
 	"context"
+	"math/rand"
 	"reflect"
 	"testing"
 
 	"github.com/elastic/harp/pkg/sdk/value"
 )
 
+const sivKey = "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8gISIjJCUmJygpKissLS4vMDEyMzQ1Njc4OTo7PD0-Pw=="
+
 func TestFromKey(t *testing.T) {
 	type args struct {
 		keyValue string
@@ -91,6 +95,48 @@ func TestFromKey(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "xchacha",
+			args: args{
+				keyValue: "xchacha:zQyPnNa-jlQsLW3Ypd87cX88ROMkdgnqv0a3y8LiISg=",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid aes-siv",
+			args: args{
+				keyValue: "aes-siv:zQyPnNa-jlQsLW3Ypd87cX88ROMkdgnqv0a3y8LiISg=",
+			},
+			wantErr: true,
+		},
+		{
+			name: "aes-siv",
+			args: args{
+				keyValue: "aes-siv:" + sivKey,
+			},
+			wantErr: false,
+		},
+		{
+			name: "jwk",
+			args: args{
+				keyValue: `{"kty":"oct","alg":"XC20P","k":"AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8"}`,
+			},
+			wantErr: false,
+		},
+		{
+			name: "jwk unsupported algorithm",
+			args: args{
+				keyValue: `{"kty":"oct","alg":"HS256","k":"AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8"}`,
+			},
+			wantErr: true,
+		},
+		{
+			name: "jwk unsupported key type",
+			args: args{
+				keyValue: `{"kty":"OKP","alg":"XC20P","k":"AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8"}`,
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -126,3 +172,89 @@ func TestFromKey(t *testing.T) {
 		})
 	}
 }
+
+func TestAlgorithm(t *testing.T) {
+	testCases := []struct {
+		keyValue string
+		want     string
+	}{
+		{keyValue: "ZER8WwNyw5Dsd65bctxillSrRMX4ObaZsQjaNW1nBBI=", want: AlgorithmFernet},
+		{keyValue: "secretbox:gCUODuqhcktiM1USKOfkwVlKhoUyHxXZm6d64nztCp0=", want: AlgorithmSecretBox},
+		{keyValue: "aes-siv:" + sivKey, want: AlgorithmAESSIV},
+		{keyValue: `{"kty":"oct","alg":"A256GCM","k":"AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8"}`, want: AlgorithmAESGCM},
+	}
+	for _, tC := range testCases {
+		got, err := Algorithm(tC.keyValue)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != tC.want {
+			t.Errorf("Algorithm(%q) = %q, want %q", tC.keyValue, got, tC.want)
+		}
+	}
+}
+
+func TestForAlgorithm(t *testing.T) {
+	// Encrypt with an explicit algorithm
+	encrypter, err := ForAlgorithm(AlgorithmXChaCha, "aes-gcm:zQyPnNa-jlQsLW3Ypd87cX88ROMkdgnqv0a3y8LiISg=")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	encrypted, err := encrypter.To(context.Background(), []byte("msg"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Key prefix is not the encryption algorithm
+	decrypter, err := FromKey("aes-gcm:zQyPnNa-jlQsLW3Ypd87cX88ROMkdgnqv0a3y8LiISg=")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := decrypter.From(context.Background(), encrypted); err == nil {
+		t.Error("error expected with a different algorithm")
+	}
+
+	if _, err := ForAlgorithm("foo", sivKey); err == nil {
+		t.Error("error expected with an unsupported algorithm")
+	}
+}
+
+func TestDeterminism(t *testing.T) {
+	testCases := []struct {
+		keyValue      string
+		deterministic bool
+	}{
+		{keyValue: "aes-siv:" + sivKey, deterministic: true},
+		{keyValue: "aes-gcm:zQyPnNa-jlQsLW3Ypd87cX88ROMkdgnqv0a3y8LiISg=", deterministic: false},
+		{keyValue: "xchacha:zQyPnNa-jlQsLW3Ypd87cX88ROMkdgnqv0a3y8LiISg=", deterministic: false},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.keyValue, func(t *testing.T) {
+			underTest, err := FromKey(tC.keyValue)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// Property: for any plaintext, encrypting twice gives identical
+			// ciphertexts only with deterministic algorithms.
+			r := rand.New(rand.NewSource(1))
+			for i := 0; i < 100; i++ {
+				msg := make([]byte, r.Intn(64))
+				r.Read(msg)
+
+				first, err := underTest.To(context.Background(), msg)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				second, err := underTest.To(context.Background(), msg)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if got := bytes.Equal(first, second); got != tC.deterministic {
+					t.Fatalf("identical ciphertexts = %v for %x, want %v", got, msg, tC.deterministic)
+				}
+			}
+		})
+	}
+}
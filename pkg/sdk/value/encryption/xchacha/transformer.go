@@ -0,0 +1,95 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xchacha
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20poly1305"
+
+	"github.com/elastic/harp/pkg/sdk/value"
+)
+
+// Transformer returns an XChaCha20-Poly1305 value transformer instance.
+// Nonces are 192-bit random values, safe to generate without coordination.
+func Transformer(key string) (value.Transformer, error) {
+	// Decode key
+	k, err := base64.URLEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("xchacha: unable to decode key: %w", err)
+	}
+	if l := len(k); l != chacha20poly1305.KeySize {
+		return nil, fmt.Errorf("xchacha: invalid secret key length (%d)", l)
+	}
+
+	// Create AEAD cipher
+	aead, err := chacha20poly1305.NewX(k)
+	if err != nil {
+		return nil, fmt.Errorf("xchacha: unable to initialize aead: %w", err)
+	}
+
+	// Return transformer
+	return &xchachaTransformer{
+		aead: aead,
+	}, nil
+}
+
+// -----------------------------------------------------------------------------
+
+type xchachaTransformer struct {
+	aead cipher.AEAD
+}
+
+func (t *xchachaTransformer) To(_ context.Context, input []byte) ([]byte, error) {
+	// Calculate allocation limit
+	nonceSize := t.aead.NonceSize()
+	bufSize := nonceSize + t.aead.Overhead() + len(input)
+	if bufSize > 100*1024*1024 { // Limit to 100MB
+		return nil, fmt.Errorf("xchacha: data too large")
+	}
+
+	// Generate nonce
+	result := make([]byte, nonceSize, bufSize)
+	if _, err := rand.Read(result); err != nil {
+		return nil, fmt.Errorf("xchacha: unable to generate nonce: %w", err)
+	}
+
+	// Encrypt and seal
+	return t.aead.Seal(result, result[:nonceSize], input, nil), nil
+}
+
+func (t *xchachaTransformer) From(_ context.Context, input []byte) ([]byte, error) {
+	// Check nonce size
+	nonceSize := t.aead.NonceSize()
+	if len(input) < nonceSize+t.aead.Overhead() {
+		return nil, fmt.Errorf("xchacha: the stored data was shorter than the required size")
+	}
+
+	// Try to decrypt data
+	out, err := t.aead.Open(nil, input[:nonceSize], input[nonceSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("xchacha: unable to decrypt data: %w", err)
+	}
+
+	// No error
+	return out, nil
+}
@@ -0,0 +1,100 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package xchacha
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"testing"
+)
+
+func Test_Transformer_XChaCha_InvalidKey(t *testing.T) {
+	keys := []string{
+		"",
+		"foo",
+		"123456",
+		"0123456789012345678901",
+	}
+	for _, k := range keys {
+		key := k
+		t.Run(fmt.Sprintf("key `%s`", key), func(t *testing.T) {
+			underTest, err := Transformer(key)
+			if err == nil {
+				t.Fatalf("Transformer should raise an error with key `%s`", key)
+			}
+			if underTest != nil {
+				t.Fatalf("Transformer instance should be nil")
+			}
+		})
+	}
+}
+
+// draft-irtf-cfrg-xchacha-03 appendix A.3.1 test vector.
+func Test_Transformer_XChaCha_KnownAnswer(t *testing.T) {
+	key, _ := hex.DecodeString("808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9f")
+	nonce, _ := hex.DecodeString("404142434445464748494a4b4c4d4e4f5051525354555657")
+	aad, _ := hex.DecodeString("50515253c0c1c2c3c4c5c6c7")
+	plaintext := []byte("Ladies and Gentlemen of the class of '99: If I could offer you only one tip for the future, sunscreen would be it.")
+	want, _ := hex.DecodeString("bd6d179d3e83d43b9576579493c0e939572a1700252bfaccbed2902c21396cbb731c7f1b0b4aa6440bf3a82f4eda7e39ae64c6708c54c216cb96b72e1213b4522f8c9ba40db5d945b11b69b982c1bb9e3f3fac2bc369488f76b2383565d3fff921f9664c97637da9768812f615c68b13b52e" +
+		"c0875924c1c7987947deafd8780acf49")
+
+	underTest, err := Transformer(base64.URLEncoding.EncodeToString(key))
+	if err != nil {
+		t.Fatalf("unable to initialize transformer: %v", err)
+	}
+	aead := underTest.(*xchachaTransformer).aead
+
+	got := aead.Seal(nil, nonce, plaintext, aad)
+	if !bytes.Equal(got, want) {
+		t.Errorf("Seal():\ngot  %x\nwant %x", got, want)
+	}
+}
+
+func Test_Transformer_XChaCha_RoundTrip(t *testing.T) {
+	underTest, err := Transformer("zQyPnNa-jlQsLW3Ypd87cX88ROMkdgnqv0a3y8LiISg=")
+	if err != nil {
+		t.Fatalf("unable to initialize transformer: %v", err)
+	}
+
+	ctx := context.Background()
+	plainText := []byte("cool-protected-data")
+
+	encrypted, err := underTest.To(ctx, plainText)
+	if err != nil {
+		t.Fatalf("unable to encrypt: %v", err)
+	}
+	if len(encrypted) != 24+len(plainText)+16 {
+		t.Errorf("unexpected ciphertext length %d", len(encrypted))
+	}
+
+	decrypted, err := underTest.From(ctx, encrypted)
+	if err != nil {
+		t.Fatalf("unable to decrypt: %v", err)
+	}
+	if !bytes.Equal(decrypted, plainText) {
+		t.Errorf("From():\ngot  %q\nwant %q", decrypted, plainText)
+	}
+
+	// Truncated input
+	if _, err := underTest.From(ctx, encrypted[:20]); err == nil {
+		t.Error("truncated input must be rejected")
+	}
+}
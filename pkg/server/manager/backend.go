@@ -21,24 +21,46 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gosimple/slug"
+	"go.uber.org/zap"
 
+	"github.com/elastic/harp/pkg/sdk/log"
 	"github.com/elastic/harp/pkg/sdk/value/encryption"
 	"github.com/elastic/harp/pkg/server/storage"
 	valueDecorator "github.com/elastic/harp/pkg/server/storage/decorators/value"
+	"github.com/elastic/harp/pkg/server/watch"
 )
 
 // Backend declares backend manager contract.
 type Backend interface {
 	GetSecret(context.Context, string, string) ([]byte, error)
-	Register(context.Context, string, string) error
+	Register(context.Context, string, string, ...func(storage.Engine) storage.Engine) error
 	GetNameSpace(context.Context, string) (storage.Engine, error)
 }
 
+// Watcher is implemented by backend managers notifying secret changes.
+type Watcher interface {
+	// Watch subscribes to namespace secret changes under the given prefix,
+	// and returns the current secret digests. Subscribers not consuming
+	// their pending changes for the stall duration are disconnected.
+	Watch(ctx context.Context, namespace, prefix string, stall time.Duration) (*watch.Subscription, map[string]string, error)
+	// Reload rebuilds the namespace engine and notifies the secret changes.
+	Reload(ctx context.Context, namespace string) error
+}
+
+// ReloadNotifier is implemented by backend managers notifying engine reloads.
+type ReloadNotifier interface {
+	// OnReload registers a callback invoked with the namespace name once its
+	// engine has been replaced.
+	OnReload(func(namespace string))
+}
+
 var (
 	// ErrNamespaceNotFound is raised when namespace is not registered
 	ErrNamespaceNotFound = errors.New("manager: namespace not found")
@@ -49,15 +71,44 @@ var (
 // Default returns the default backend manager instance
 func Default() Backend {
 	return &backendManager{
-		backends: map[string]storage.Engine{},
+		backends: map[string]*namespace{},
+		hub:      watch.NewHub(),
+	}
+}
+
+// ReloadEvery reloads the namespace engine at the given interval until the
+// context is done.
+func ReloadEvery(ctx context.Context, w Watcher, ns string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.Reload(ctx, ns); err != nil {
+				log.For(ctx).Error("Unable to reload namespace", zap.Error(err), zap.String("namespace", ns))
+			}
+		}
 	}
 }
 
 // -----------------------------------------------------------------------------
 
+type namespace struct {
+	uri        string
+	decorators []func(storage.Engine) storage.Engine
+	raw        storage.Engine
+	engine     storage.Engine
+}
+
 type backendManager struct {
 	sync.RWMutex
-	backends map[string]storage.Engine
+	backends map[string]*namespace
+	hub      *watch.Hub
+	reloadMu sync.Mutex
+	onReload []func(string)
 }
 
 func (bm *backendManager) GetSecret(ctx context.Context, namespace, identifier string) ([]byte, error) {
@@ -71,7 +122,7 @@ func (bm *backendManager) GetSecret(ctx context.Context, namespace, identifier s
 	return engine.Get(ctx, identifier)
 }
 
-func (bm *backendManager) Register(ctx context.Context, namespace, uri string) error {
+func (bm *backendManager) Register(ctx context.Context, namespace, uri string, decorators ...func(storage.Engine) storage.Engine) error {
 	// Check backend registration
 	_, err := bm.GetNameSpace(ctx, namespace)
 	if err == nil {
@@ -79,39 +130,165 @@ func (bm *backendManager) Register(ctx context.Context, namespace, uri string) e
 	}
 
 	// Load backend settings
-	engine, err := storage.Build(uri)
+	ns, err := bm.build(namespace, uri, decorators)
 	if err != nil {
 		return err
 	}
 
-	// Add encryption backend
-	engine, err = wrapEncryptionEngine(uri, engine)
+	// Add to backend map
+	bm.Lock()
+	bm.backends[clean(namespace)] = ns
+	bm.Unlock()
+
+	// Return no error
+	return nil
+}
+
+func (bm *backendManager) Reload(ctx context.Context, name string) error {
+	// Serialize reloads to keep notified changes ordered
+	bm.reloadMu.Lock()
+	defer bm.reloadMu.Unlock()
+
+	// Check backend registration
+	current, err := bm.namespace(name)
 	if err != nil {
 		return err
 	}
 
-	// Add to backend map
+	// Rebuild the engine
+	ns, err := bm.build(name, current.uri, current.decorators)
+	if err != nil {
+		return fmt.Errorf("backend: unable to reload '%s' namespace: %w", name, err)
+	}
+
+	// Compute changes, engines without listing support are not watchable
+	before, errBefore := storage.Snapshot(ctx, current.raw, "")
+	after, errAfter := storage.Snapshot(ctx, ns.raw, "")
+
+	// Replace the engine
 	bm.Lock()
-	bm.backends[clean(namespace)] = engine
+	bm.backends[clean(name)] = ns
+	hooks := bm.onReload
 	bm.Unlock()
 
-	// Return no error
+	// Drop state derived from the previous engine
+	for _, fn := range hooks {
+		fn(clean(name))
+	}
+
+	// Notify watchers
+	if errBefore == nil && errAfter == nil {
+		bm.hub.Publish(clean(name), storage.Diff(before, after)...)
+	}
+
+	// Release previous engine resources
+	if c, ok := current.raw.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			log.For(ctx).Warn("Unable to release previous namespace engine", zap.Error(err), zap.String("namespace", name))
+		}
+	}
+
+	// No error
 	return nil
 }
 
+func (bm *backendManager) Watch(ctx context.Context, name, prefix string, stall time.Duration) (*watch.Subscription, map[string]string, error) {
+	// Check backend registration
+	ns, err := bm.namespace(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Check client authorization and listing support
+	if _, err := storage.List(ctx, ns.engine, prefix, storage.PageRequest{Limit: 1}); err != nil {
+		return nil, nil, err
+	}
+
+	// Subscribe before the snapshot, so that no change is lost
+	sub := bm.hub.Subscribe(clean(name), prefix, stall)
+
+	// Retrieve current digests
+	digests, err := storage.Snapshot(ctx, ns.raw, prefix)
+	if err != nil {
+		sub.Close()
+		return nil, nil, err
+	}
+
+	// No error
+	return sub, digests, nil
+}
+
+func (bm *backendManager) OnReload(fn func(string)) {
+	bm.Lock()
+	defer bm.Unlock()
+
+	bm.onReload = append(bm.onReload, fn)
+}
+
 func (bm *backendManager) GetNameSpace(ctx context.Context, namespace string) (storage.Engine, error) {
 	// Lock read
 	bm.RLock()
 	defer bm.RUnlock()
 
 	// Check backend registration
-	engine, ok := bm.backends[clean(namespace)]
+	ns, ok := bm.backends[clean(namespace)]
 	if !ok {
 		return nil, ErrNamespaceNotFound
 	}
 
 	// No error
-	return engine, nil
+	return ns.engine, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (bm *backendManager) namespace(name string) (*namespace, error) {
+	bm.RLock()
+	defer bm.RUnlock()
+
+	ns, ok := bm.backends[clean(name)]
+	if !ok {
+		return nil, ErrNamespaceNotFound
+	}
+
+	return ns, nil
+}
+
+func (bm *backendManager) build(name, uri string, decorators []func(storage.Engine) storage.Engine) (*namespace, error) {
+	// Load backend settings
+	raw, err := storage.Build(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add encryption backend
+	engine, err := wrapEncryptionEngine(uri, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply response decorators
+	for _, d := range decorators {
+		engine = d(engine)
+	}
+
+	ns := &namespace{
+		uri:        uri,
+		decorators: decorators,
+		raw:        raw,
+		engine:     engine,
+	}
+
+	// Forward changes observed by the engine, until it is reloaded
+	if n, ok := raw.(storage.ChangeNotifier); ok {
+		n.OnChange(func(c storage.Change) {
+			if current, err := bm.namespace(name); err == nil && current == ns {
+				bm.hub.Publish(clean(name), c)
+			}
+		})
+	}
+
+	return ns, nil
 }
 
 // -----------------------------------------------------------------------------
@@ -0,0 +1,417 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package replication
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/server/storage"
+)
+
+// Namespace replication states.
+const (
+	// StateInSync is set when the local container matches the primary one.
+	StateInSync = "in-sync"
+	// StateConflict is set when the local container is newer than the primary
+	// one, the local container is kept.
+	StateConflict = "conflict"
+	// StateFailed is set when the primary container can't be applied.
+	StateFailed = "failed"
+	// StateMissing is set when the primary doesn't replicate the namespace.
+	StateMissing = "missing"
+)
+
+// maxSnapshotSize is the maximum accepted snapshot body size.
+const maxSnapshotSize = 256 << 20
+
+// ReloadFunc rebuilds the namespace engine from its local container.
+type ReloadFunc func(ctx context.Context, namespace string) error
+
+// ReplicaOption defines replica optional settings.
+type ReplicaOption func(*Replica)
+
+// WithHTTPClient sets the HTTP client used to query the primary, usually with
+// a signing transport for the primary admin API.
+func WithHTTPClient(c *http.Client) ReplicaOption {
+	return func(r *Replica) {
+		r.client = c
+	}
+}
+
+// WithReplicaClock sets the clock used to compute the replication lag.
+func WithReplicaClock(now func() time.Time) ReplicaOption {
+	return func(r *Replica) {
+		r.now = now
+	}
+}
+
+// Status describes the replica state.
+type Status struct {
+	Primary    string                      `json:"primary"`
+	LastSync   time.Time                   `json:"lastSync"`
+	LastError  string                      `json:"lastError,omitempty"`
+	LagSeconds float64                     `json:"lagSeconds"`
+	Namespaces map[string]*NamespaceStatus `json:"namespaces"`
+}
+
+// NamespaceStatus describes a replicated namespace state.
+type NamespaceStatus struct {
+	State          string    `json:"state"`
+	Digest         string    `json:"digest,omitempty"`
+	PrimaryDigest  string    `json:"primaryDigest,omitempty"`
+	PrimaryUpdated time.Time `json:"primaryUpdated"`
+	Error          string    `json:"error,omitempty"`
+}
+
+// Replica keeps local namespace containers in sync with the primary.
+type Replica struct {
+	endpoint string
+	key      ed25519.PublicKey
+	targets  map[string]string
+	reload   ReloadFunc
+	client   *http.Client
+	now      func() time.Time
+
+	mu         sync.RWMutex
+	converged  time.Time
+	lastSync   time.Time
+	lastError  string
+	namespaces map[string]*NamespaceStatus
+}
+
+// NewReplica returns a replica polling the given primary snapshot endpoint.
+// Targets map replicated namespaces to their local container file, reloaded
+// using the given function once updated.
+func NewReplica(endpoint string, key ed25519.PublicKey, targets map[string]string, reload ReloadFunc, opts ...ReplicaOption) (*Replica, error) {
+	// Check arguments
+	if endpoint == "" {
+		return nil, errors.New("primary snapshot endpoint must not be blank")
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("snapshot verification key must be an Ed25519 public key")
+	}
+	if len(targets) == 0 {
+		return nil, errors.New("at least one namespace must be replicated")
+	}
+	if reload == nil {
+		return nil, errors.New("unable to replicate with a nil reload function")
+	}
+
+	r := &Replica{
+		endpoint:   endpoint,
+		key:        key,
+		targets:    targets,
+		reload:     reload,
+		client:     http.DefaultClient,
+		now:        time.Now,
+		namespaces: map[string]*NamespaceStatus{},
+	}
+
+	// Apply options
+	for _, o := range opts {
+		o(r)
+	}
+	r.converged = r.now()
+
+	// Expose the lag of the last created replica
+	metrics.Set("lag_seconds", expvar.Func(func() interface{} {
+		return r.lag().Seconds()
+	}))
+
+	// No error
+	return r, nil
+}
+
+// Run synchronizes the replica at the given interval until the context is
+// done.
+func (r *Replica) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Sync(ctx); err != nil {
+			log.For(ctx).Error("Unable to synchronize with primary", zap.Error(err), zap.String("primary", r.endpoint))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync retrieves the primary snapshot and applies updated namespaces.
+func (r *Replica) Sync(ctx context.Context) error {
+	metrics.Add("syncs", 1)
+
+	// Retrieve the primary state
+	snapshot, err := r.fetch(ctx)
+	if err != nil {
+		metrics.Add("sync_failures", 1)
+
+		r.mu.Lock()
+		r.lastError = err.Error()
+		r.mu.Unlock()
+
+		return err
+	}
+
+	// Index primary namespaces
+	primary := make(map[string]*Namespace, len(snapshot.Namespaces))
+	for _, ns := range snapshot.Namespaces {
+		if ns != nil {
+			primary[ns.Name] = ns
+		}
+	}
+
+	// Apply namespaces
+	names := make([]string, 0, len(r.targets))
+	for name := range r.targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	inSync := true
+	states := make(map[string]*NamespaceStatus, len(names))
+	for _, name := range names {
+		st := r.apply(ctx, name, r.targets[name], primary[name])
+		if st.State != StateInSync {
+			inSync = false
+		}
+		states[name] = st
+	}
+
+	// Update status
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastSync = r.now()
+	r.lastError = ""
+	r.namespaces = states
+	if inSync {
+		r.converged = r.lastSync
+	}
+
+	// No error
+	return nil
+}
+
+// Status returns the replica state.
+func (r *Replica) Status() *Status {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := &Status{
+		Primary:    r.endpoint,
+		LastSync:   r.lastSync,
+		LastError:  r.lastError,
+		LagSeconds: r.now().Sub(r.converged).Seconds(),
+		Namespaces: make(map[string]*NamespaceStatus, len(r.namespaces)),
+	}
+	for name, st := range r.namespaces {
+		st := *st
+		out.Namespaces[name] = &st
+	}
+
+	return out
+}
+
+// -----------------------------------------------------------------------------
+
+var metrics = expvar.NewMap("harp_server_replication")
+
+func (r *Replica) lag() time.Duration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.now().Sub(r.converged)
+}
+
+func (r *Replica) fetch(ctx context.Context) (*Snapshot, error) {
+	// Query the primary
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to prepare snapshot request: %w", err)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve primary snapshot: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to retrieve primary snapshot: unexpected status %d", resp.StatusCode)
+	}
+
+	// Read and verify the snapshot
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxSnapshotSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read primary snapshot: %w", err)
+	}
+	if len(body) > maxSnapshotSize {
+		return nil, fmt.Errorf("primary snapshot exceeds %d bytes", maxSnapshotSize)
+	}
+	if err := Verify(body, resp.Header.Get(SignatureHeader), r.key); err != nil {
+		return nil, err
+	}
+
+	// Decode the snapshot
+	var snapshot Snapshot
+	if err := json.Unmarshal(body, &snapshot); err != nil {
+		return nil, fmt.Errorf("unable to decode primary snapshot: %w", err)
+	}
+
+	// No error
+	return &snapshot, nil
+}
+
+func (r *Replica) apply(ctx context.Context, name, path string, ns *Namespace) *NamespaceStatus {
+	st := &NamespaceStatus{}
+
+	// Read the local container
+	local, modified, err := readLocal(path)
+	if err != nil {
+		st.State, st.Error = StateFailed, err.Error()
+		return st
+	}
+	if local != nil {
+		st.Digest = storage.ContentDigest(local).Value
+	}
+
+	// Check the primary state
+	if ns == nil {
+		log.For(ctx).Warn("Namespace is not replicated by primary", zap.String("namespace", name))
+		st.State = StateMissing
+		return st
+	}
+	st.PrimaryDigest, st.PrimaryUpdated = ns.Digest, ns.Updated
+	if st.Digest == ns.Digest {
+		st.State = StateInSync
+		return st
+	}
+	if storage.ContentDigest(ns.Container).Value != ns.Digest {
+		st.State, st.Error = StateFailed, "primary container doesn't match its digest"
+		return st
+	}
+
+	// Never override a newer local container
+	if local != nil && modified.After(ns.Updated) {
+		metrics.Add("conflicts", 1)
+		log.For(ctx).Error("Replica namespace is newer than primary one, local container kept",
+			zap.String("namespace", name),
+			zap.String("digest", st.Digest),
+			zap.Time("modified", modified),
+			zap.String("primary_digest", ns.Digest),
+			zap.Time("primary_updated", ns.Updated),
+		)
+		st.State = StateConflict
+		return st
+	}
+
+	// Swap the container and rebuild the namespace engine
+	if err := writeAtomic(path, ns.Container, ns.Updated); err != nil {
+		st.State, st.Error = StateFailed, err.Error()
+		return st
+	}
+	if err := r.reload(ctx, name); err != nil {
+		// Restore the previous container, the previous engine is still served
+		var errRestore error
+		if local != nil {
+			errRestore = writeAtomic(path, local, modified)
+		} else {
+			errRestore = os.Remove(path)
+		}
+		if errRestore != nil {
+			log.For(ctx).Error("Unable to restore local container", zap.String("namespace", name), zap.Error(errRestore))
+		}
+		st.State, st.Error = StateFailed, fmt.Sprintf("unable to reload namespace: %v", err)
+		return st
+	}
+
+	metrics.Add("updates", 1)
+	log.For(ctx).Info("Namespace updated from primary", zap.String("namespace", name), zap.String("digest", ns.Digest))
+	st.State, st.Digest = StateInSync, ns.Digest
+
+	return st
+}
+
+func readLocal(path string) ([]byte, time.Time, error) {
+	fi, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		return nil, time.Time{}, nil
+	case err != nil:
+		return nil, time.Time{}, fmt.Errorf("unable to access local container: %w", err)
+	default:
+	}
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("unable to read local container: %w", err)
+	}
+
+	return raw, fi.ModTime(), nil
+}
+
+// writeAtomic replaces the file content using a renamed temporary file. The
+// modification time is set to the given one to detect newer local content.
+func writeAtomic(path string, content []byte, modified time.Time) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), fmt.Sprintf(".%s-*", filepath.Base(path)))
+	if err != nil {
+		return fmt.Errorf("unable to create temporary container: %w", err)
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return fmt.Errorf("unable to write temporary container: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("unable to sync temporary container: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("unable to close temporary container: %w", err)
+	}
+	if err := os.Chtimes(tmp, modified, modified); err != nil {
+		return fmt.Errorf("unable to set container modification time: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("unable to replace local container: %w", err)
+	}
+
+	// No error
+	return nil
+}
@@ -0,0 +1,281 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package replication
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/nacl/box"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+	"github.com/elastic/harp/pkg/container"
+	"github.com/elastic/harp/pkg/server/manager"
+	containerBackend "github.com/elastic/harp/pkg/server/storage/backends/container"
+)
+
+const secretPath = "/app/production/security/harp/v1.0.0/server/database"
+
+// identity is a container unseal identity.
+type identity struct {
+	pub *[32]byte
+	cid string
+}
+
+func newIdentity(t *testing.T) *identity {
+	t.Helper()
+
+	pub, priv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &identity{pub: pub, cid: base64.RawURLEncoding.EncodeToString(priv[:])}
+}
+
+// sealed returns a container holding the given password, sealed for the
+// given identities.
+func sealed(t *testing.T, password string, recipients ...*identity) []byte {
+	t.Helper()
+
+	b := testbundle.New()
+	b.Package(secretPath[1:]).Secret("password", password)
+	c, err := bundle.ToContainer(b.Build())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := make([]*[32]byte, 0, len(recipients))
+	for _, r := range recipients {
+		keys = append(keys, r.pub)
+	}
+	s, err := container.Seal(c, keys...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := container.Dump(&buf, s); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// server is an in-process server serving a single namespace.
+type server struct {
+	bm   manager.Backend
+	path string
+	uri  string
+}
+
+func newServer(t *testing.T, id *identity, content []byte) *server {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "app.bundle")
+	if err := ioutil.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s := &server{
+		bm:   manager.Default(),
+		path: path,
+		uri:  fmt.Sprintf("bundle://%s?cid=%s", path, id.cid),
+	}
+	if err := s.bm.Register(context.Background(), "app", s.uri); err != nil {
+		t.Fatalf("unable to register namespace: %v", err)
+	}
+
+	return s
+}
+
+func (s *server) update(t *testing.T, content []byte) {
+	t.Helper()
+
+	if err := ioutil.WriteFile(s.path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.bm.(manager.Watcher).Reload(context.Background(), "app"); err != nil {
+		t.Fatalf("unable to reload namespace: %v", err)
+	}
+}
+
+func (s *server) assertPassword(t *testing.T, password string) {
+	t.Helper()
+
+	out, err := s.bm.GetSecret(context.Background(), "app", secretPath)
+	if err != nil {
+		t.Fatalf("unable to read secret: %v", err)
+	}
+	if !bytes.Contains(out, []byte(password)) {
+		t.Errorf("expected password %q, got %s", password, out)
+	}
+}
+
+func TestReplication(t *testing.T) {
+	ctx := context.Background()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Both servers hold their own unseal identity
+	primaryID, replicaID := newIdentity(t), newIdentity(t)
+	initial := sealed(t, "v1", primaryID, replicaID)
+	primary := newServer(t, primaryID, initial)
+	replica := newServer(t, replicaID, initial)
+
+	// Expose the primary snapshot
+	src, err := NewSource(priv, map[string]string{"app": primary.uri})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ts := httptest.NewServer(src)
+	defer ts.Close()
+
+	r, err := NewReplica(ts.URL, pub, map[string]string{"app": replica.path}, replica.bm.(manager.Watcher).Reload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Already converged
+	if err := r.Sync(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if st := r.Status().Namespaces["app"]; st.State != StateInSync {
+		t.Fatalf("expected in-sync namespace, got %+v", st)
+	}
+
+	// Primary updates the namespace
+	primary.update(t, sealed(t, "v2", primaryID, replicaID))
+	primary.assertPassword(t, "v2")
+	replica.assertPassword(t, "v1")
+
+	if err := r.Sync(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	replica.assertPassword(t, "v2")
+	status := r.Status()
+	if st := status.Namespaces["app"]; st.State != StateInSync || st.Digest != st.PrimaryDigest {
+		t.Errorf("expected in-sync namespace, got %+v", st)
+	}
+	if status.LagSeconds < 0 || status.LagSeconds > 60 {
+		t.Errorf("unexpected replication lag %f", status.LagSeconds)
+	}
+
+	// Primary container the replica can't unseal, previous one is kept
+	previous := mustRead(t, replica.path)
+	primary.update(t, sealed(t, "v3", primaryID))
+	if err := r.Sync(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if st := r.Status().Namespaces["app"]; st.State != StateFailed {
+		t.Errorf("expected failed namespace, got %+v", st)
+	}
+	replica.assertPassword(t, "v2")
+	if !bytes.Equal(mustRead(t, replica.path), previous) {
+		t.Error("local container must be restored")
+	}
+
+	// Replica has a newer container
+	primary.update(t, sealed(t, "v4", primaryID, replicaID))
+	if _, err := src.Snapshot(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	replica.update(t, sealed(t, "local", primaryID, replicaID))
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(replica.path, future, future); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Sync(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if st := r.Status().Namespaces["app"]; st.State != StateConflict {
+		t.Errorf("expected conflicting namespace, got %+v", st)
+	}
+	replica.assertPassword(t, "local")
+}
+
+func TestReplica_InvalidSignature(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	other, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	id := newIdentity(t)
+	primary := newServer(t, id, sealed(t, "v1", id))
+	src, err := NewSource(priv, map[string]string{"app": primary.uri})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ts := httptest.NewServer(src)
+	defer ts.Close()
+
+	r, err := NewReplica(ts.URL, other, map[string]string{"app": filepath.Join(t.TempDir(), "app.bundle")}, func(context.Context, string) error {
+		t.Error("namespace must not be reloaded")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.Sync(context.Background()); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature, got %v", err)
+	}
+	if r.Status().LastError == "" {
+		t.Error("last error must be reported")
+	}
+}
+
+func TestSource_UnsealedContainer(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+
+	path := filepath.Join(t.TempDir(), "app.bundle")
+	b := testbundle.New()
+	b.Package(secretPath[1:]).Secret("password", "v1")
+	if err := ioutil.WriteFile(path, testbundle.Container(t, b.Build()), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := NewSource(priv, map[string]string{"app": fmt.Sprintf("bundle://%s", path)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := src.Snapshot(context.Background()); !errors.Is(err, containerBackend.ErrUnsealedContainer) {
+		t.Errorf("expected ErrUnsealedContainer, got %v", err)
+	}
+}
+
+func mustRead(t *testing.T, path string) []byte {
+	t.Helper()
+
+	out, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return out
+}
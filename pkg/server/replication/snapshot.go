@@ -0,0 +1,83 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package replication keeps warm standby servers in sync with a primary.
+//
+// The primary exposes a signed snapshot of its namespaces, made of the sealed
+// container of each namespace and its digest. Replicas poll the snapshot,
+// verify its signature and swap updated namespaces in. Containers are only
+// transferred sealed, replicas unseal them with their own identities.
+package replication
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SignatureHeader holds the base64 encoded Ed25519 signature of the snapshot
+// response body.
+const SignatureHeader = "X-Harp-Snapshot-Signature"
+
+// ErrInvalidSignature is raised when the snapshot signature can't be verified.
+var ErrInvalidSignature = errors.New("replication: invalid snapshot signature")
+
+// Snapshot describes the primary namespaces state.
+type Snapshot struct {
+	Generated  time.Time    `json:"generated"`
+	Namespaces []*Namespace `json:"namespaces"`
+}
+
+// Namespace describes a replicated namespace.
+type Namespace struct {
+	Name string `json:"name"`
+	// Digest is the sealed container content digest.
+	Digest string `json:"digest"`
+	// Updated is the time the primary started serving this digest.
+	Updated time.Time `json:"updated"`
+	// Container is the sealed container content.
+	Container []byte `json:"container"`
+}
+
+// Sign returns the encoded signature of the given snapshot body.
+func Sign(body []byte, key ed25519.PrivateKey) (string, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return "", errors.New("invalid snapshot signing key")
+	}
+
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(key, body)), nil
+}
+
+// Verify checks the encoded signature of the given snapshot body.
+func Verify(body []byte, signature string, key ed25519.PublicKey) error {
+	if len(key) != ed25519.PublicKeySize {
+		return errors.New("invalid snapshot verification key")
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("unable to decode snapshot signature: %v: %w", err, ErrInvalidSignature)
+	}
+	if !ed25519.Verify(key, body, sig) {
+		return ErrInvalidSignature
+	}
+
+	// No error
+	return nil
+}
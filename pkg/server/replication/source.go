@@ -0,0 +1,171 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package replication
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/server/storage"
+	"github.com/elastic/harp/pkg/server/storage/backends/container"
+)
+
+// FetchFunc returns the sealed container content of a backend URL.
+type FetchFunc func(ctx context.Context, uri string) ([]byte, error)
+
+// SourceOption defines snapshot source optional settings.
+type SourceOption func(*Source)
+
+// WithFetcher sets the sealed container fetcher, containers are read from
+// their bundle backend by default.
+func WithFetcher(fetch FetchFunc) SourceOption {
+	return func(s *Source) {
+		s.fetch = fetch
+	}
+}
+
+// WithSourceClock sets the clock used to timestamp snapshots.
+func WithSourceClock(now func() time.Time) SourceOption {
+	return func(s *Source) {
+		s.now = now
+	}
+}
+
+// Source builds the primary snapshots.
+type Source struct {
+	key        ed25519.PrivateKey
+	namespaces map[string]string
+	fetch      FetchFunc
+	now        func() time.Time
+
+	mu   sync.Mutex
+	seen map[string]*Namespace
+}
+
+// NewSource returns a snapshot source for the given namespace backend URLs,
+// signed with the given key.
+func NewSource(key ed25519.PrivateKey, namespaces map[string]string, opts ...SourceOption) (*Source, error) {
+	// Check arguments
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, errors.New("snapshot signing key must be an Ed25519 private key")
+	}
+	if len(namespaces) == 0 {
+		return nil, errors.New("at least one namespace must be replicated")
+	}
+
+	s := &Source{
+		key:        key,
+		namespaces: namespaces,
+		fetch:      container.ReadSealed,
+		now:        time.Now,
+		seen:       map[string]*Namespace{},
+	}
+
+	// Apply options
+	for _, o := range opts {
+		o(s)
+	}
+
+	// No error
+	return s, nil
+}
+
+// Snapshot returns the current namespaces state.
+func (s *Source) Snapshot(ctx context.Context) (*Snapshot, error) {
+	names := make([]string, 0, len(s.namespaces))
+	for name := range s.namespaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	out := &Snapshot{
+		Generated:  now,
+		Namespaces: make([]*Namespace, 0, len(names)),
+	}
+	for _, name := range names {
+		// Read the sealed container
+		raw, err := s.fetch(ctx, s.namespaces[name])
+		if err != nil {
+			return nil, fmt.Errorf("unable to read '%s' namespace container: %w", name, err)
+		}
+
+		// Keep the time the primary started serving this content
+		digest := storage.ContentDigest(raw).Value
+		prev, ok := s.seen[name]
+		if !ok || prev.Digest != digest {
+			prev = &Namespace{Name: name, Digest: digest, Updated: now}
+			s.seen[name] = prev
+		}
+
+		out.Namespaces = append(out.Namespaces, &Namespace{
+			Name:      name,
+			Digest:    digest,
+			Updated:   prev.Updated,
+			Container: raw,
+		})
+	}
+
+	// No error
+	return out, nil
+}
+
+// ServeHTTP exposes the signed snapshot.
+func (s *Source) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Build the snapshot
+	snapshot, err := s.Snapshot(ctx)
+	if err != nil {
+		log.For(ctx).Error("Unable to build replication snapshot", zap.Error(err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	// Encode and sign
+	body, err := json.Marshal(snapshot)
+	if err != nil {
+		log.For(ctx).Error("Unable to encode replication snapshot", zap.Error(err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	sig, err := Sign(body, s.key)
+	if err != nil {
+		log.For(ctx).Error("Unable to sign replication snapshot", zap.Error(err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set(SignatureHeader, sig)
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,201 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package spiffe
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Scheme is the SPIFFE ID URI scheme.
+const Scheme = "spiffe"
+
+var (
+	// ErrInvalidID is raised when a SPIFFE ID is malformed.
+	ErrInvalidID = errors.New("spiffe: invalid SPIFFE ID")
+	// ErrNoID is raised when a certificate doesn't hold a SPIFFE ID.
+	ErrNoID = errors.New("spiffe: certificate has no SPIFFE ID")
+)
+
+// ParseID validates the given SPIFFE ID and returns its normalized form. Only
+// canonical paths are accepted: percent-encoded characters, empty, '.' and
+// '..' segments are rejected.
+func ParseID(id string) (string, error) {
+	u, err := url.Parse(id)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse '%s': %w", id, ErrInvalidID)
+	}
+
+	// Check SPIFFE ID constraints
+	switch {
+	case !strings.EqualFold(u.Scheme, Scheme):
+		return "", fmt.Errorf("'%s' scheme must be '%s': %w", id, Scheme, ErrInvalidID)
+	case u.Host == "":
+		return "", fmt.Errorf("'%s' must have a trust domain: %w", id, ErrInvalidID)
+	case u.Port() != "", u.User != nil:
+		return "", fmt.Errorf("'%s' trust domain must not have a port or user info: %w", id, ErrInvalidID)
+	case u.RawQuery != "", u.Fragment != "":
+		return "", fmt.Errorf("'%s' must not have a query or a fragment: %w", id, ErrInvalidID)
+	case u.Opaque != "":
+		return "", fmt.Errorf("'%s' must be a hierarchical URI: %w", id, ErrInvalidID)
+	case strings.Contains(id, "%"):
+		return "", fmt.Errorf("'%s' must not have percent-encoded characters: %w", id, ErrInvalidID)
+	}
+
+	// Check trust domain characters
+	host := strings.ToLower(u.Host)
+	if !validChars(host) {
+		return "", fmt.Errorf("'%s' trust domain must only contain letters, digits, '.', '-' and '_': %w", id, ErrInvalidID)
+	}
+
+	// Check path segments, only canonical paths are accepted
+	if u.Path != "" {
+		for _, segment := range strings.Split(strings.TrimPrefix(u.Path, "/"), "/") {
+			switch {
+			case segment == "":
+				return "", fmt.Errorf("'%s' must not have empty path segments: %w", id, ErrInvalidID)
+			case segment == "." || segment == "..":
+				return "", fmt.Errorf("'%s' must not have relative path segments: %w", id, ErrInvalidID)
+			case !validChars(segment):
+				return "", fmt.Errorf("'%s' path segments must only contain letters, digits, '.', '-' and '_': %w", id, ErrInvalidID)
+			}
+		}
+	}
+
+	return fmt.Sprintf("%s://%s%s", Scheme, host, u.Path), nil
+}
+
+// TrustDomain returns the trust domain ID ('spiffe://<trust domain>') of the
+// given SPIFFE ID.
+func TrustDomain(id string) (string, error) {
+	normalized, err := ParseID(id)
+	if err != nil {
+		return "", err
+	}
+
+	// Remove path
+	host := strings.TrimPrefix(normalized, Scheme+"://")
+	if idx := strings.Index(host, "/"); idx >= 0 {
+		host = host[:idx]
+	}
+
+	return Scheme + "://" + host, nil
+}
+
+// IDFromCertificate returns the SPIFFE ID held by the certificate URI SANs.
+// An X.509 SVID must have exactly one URI SAN.
+func IDFromCertificate(cert *x509.Certificate) (string, error) {
+	// Check arguments
+	if cert == nil {
+		return "", errors.New("unable to extract SPIFFE ID from a nil certificate")
+	}
+
+	var ids []string
+	for _, u := range cert.URIs {
+		if strings.EqualFold(u.Scheme, Scheme) {
+			ids = append(ids, u.String())
+		}
+	}
+
+	switch len(ids) {
+	case 0:
+		return "", ErrNoID
+	case 1:
+	default:
+		return "", fmt.Errorf("certificate must have exactly one SPIFFE ID, got %d: %w", len(ids), ErrInvalidID)
+	}
+
+	return ParseID(ids[0])
+}
+
+// validChars returns true if the value only contains SPIFFE ID characters.
+func validChars(value string) bool {
+	for _, r := range value {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '.', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// -----------------------------------------------------------------------------
+
+// Pattern is an allowed SPIFFE ID matcher. It can be:
+//
+//	spiffe://example.org            - any ID of the trust domain
+//	spiffe://example.org/ns/prod/*  - any ID under the given path prefix
+//	spiffe://example.org/ns/prod/db - the exact ID
+type Pattern struct {
+	trustDomain string
+	value       string
+	prefix      bool
+}
+
+// ParsePattern validates and compiles the given SPIFFE ID pattern.
+func ParsePattern(pattern string) (*Pattern, error) {
+	prefix := strings.HasSuffix(pattern, "/*")
+
+	// Validate ID part
+	id, err := ParseID(strings.TrimSuffix(pattern, "/*"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SPIFFE ID pattern '%s': %w", pattern, err)
+	}
+	td, err := TrustDomain(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Pattern{
+		trustDomain: td,
+		value:       id,
+		prefix:      prefix,
+	}, nil
+}
+
+// Matches returns true if the given SPIFFE ID is matched by the pattern.
+func (p *Pattern) Matches(id string) bool {
+	normalized, err := ParseID(id)
+	if err != nil {
+		return false
+	}
+
+	switch {
+	case p.value == p.trustDomain:
+		// Trust domain only
+		td, _ := TrustDomain(normalized)
+		return td == p.trustDomain
+	case p.prefix:
+		return strings.HasPrefix(normalized, p.value+"/")
+	default:
+		return normalized == p.value
+	}
+}
+
+// String returns the pattern as string.
+func (p *Pattern) String() string {
+	if p.prefix {
+		return p.value + "/*"
+	}
+	return p.value
+}
@@ -0,0 +1,208 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package spiffe
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// -----------------------------------------------------------------------------
+
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newAuthority generates a self-signed trust domain authority.
+func newAuthority(t *testing.T, name string) *authority {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate authority key: %v", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unable to create authority certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("unable to parse authority certificate: %v", err)
+	}
+
+	return &authority{cert: cert, key: key}
+}
+
+// issue generates a SVID-like client certificate with the given URI SANs.
+func (a *authority) issue(t *testing.T, commonName string, uris ...string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate leaf key: %v", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	for _, raw := range uris {
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatalf("unable to parse uri: %v", err)
+		}
+		tmpl.URIs = append(tmpl.URIs, u)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, a.cert, &key.PublicKey, a.key)
+	if err != nil {
+		t.Fatalf("unable to create leaf certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("unable to parse leaf certificate: %v", err)
+	}
+
+	return cert, key
+}
+
+// -----------------------------------------------------------------------------
+
+func TestParseID(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		id      string
+		want    string
+		wantErr bool
+	}{
+		{desc: "valid", id: "spiffe://example.org/ns/prod/sa/db", want: "spiffe://example.org/ns/prod/sa/db"},
+		{desc: "normalized", id: "SPIFFE://Example.ORG/ns/prod", want: "spiffe://example.org/ns/prod"},
+		{desc: "trust domain", id: "spiffe://example.org", want: "spiffe://example.org"},
+		{desc: "invalid scheme", id: "https://example.org/ns", wantErr: true},
+		{desc: "missing trust domain", id: "spiffe:///ns", wantErr: true},
+		{desc: "port", id: "spiffe://example.org:8443/ns", wantErr: true},
+		{desc: "user info", id: "spiffe://user@example.org/ns", wantErr: true},
+		{desc: "query", id: "spiffe://example.org/ns?a=b", wantErr: true},
+		{desc: "trailing slash", id: "spiffe://example.org/ns/prod/", wantErr: true},
+		{desc: "root path", id: "spiffe://example.org/", wantErr: true},
+		{desc: "empty segment", id: "spiffe://example.org/ns//prod", wantErr: true},
+		{desc: "dot segment", id: "spiffe://example.org/ns/./prod", wantErr: true},
+		{desc: "dot dot segment", id: "spiffe://example.org/ns/prod/../admin", wantErr: true},
+		{desc: "percent encoded path", id: "spiffe://example.org/ns/pr%6Fd", wantErr: true},
+		{desc: "percent encoded slash", id: "spiffe://example.org/ns%2Fprod", wantErr: true},
+		{desc: "percent encoded trust domain", id: "spiffe://ex%61mple.org/ns", wantErr: true},
+		{desc: "invalid path character", id: "spiffe://example.org/ns/prod:db", wantErr: true},
+		{desc: "invalid trust domain character", id: "spiffe://exa$mple.org/ns", wantErr: true},
+		{desc: "dotted segment", id: "spiffe://example.org/ns/prod.v1/sa_db-2", want: "spiffe://example.org/ns/prod.v1/sa_db-2"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			got, err := ParseID(tC.id)
+			if (err != nil) != tC.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tC.wantErr)
+			}
+			if err != nil {
+				if !errors.Is(err, ErrInvalidID) {
+					t.Errorf("expected ErrInvalidID, got %v", err)
+				}
+				return
+			}
+			if got != tC.want {
+				t.Errorf("expected %q, got %q", tC.want, got)
+			}
+		})
+	}
+}
+
+func TestIDFromCertificate(t *testing.T) {
+	ca := newAuthority(t, "example.org")
+
+	single, _ := ca.issue(t, "db", "spiffe://example.org/ns/prod/sa/db")
+	none, _ := ca.issue(t, "db", "https://example.org/db")
+	multiple, _ := ca.issue(t, "db", "spiffe://example.org/a", "spiffe://example.org/b")
+
+	got, err := IDFromCertificate(single)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "spiffe://example.org/ns/prod/sa/db" {
+		t.Errorf("unexpected SPIFFE ID %q", got)
+	}
+
+	if _, err := IDFromCertificate(none); !errors.Is(err, ErrNoID) {
+		t.Errorf("expected ErrNoID, got %v", err)
+	}
+	if _, err := IDFromCertificate(multiple); !errors.Is(err, ErrInvalidID) {
+		t.Errorf("expected ErrInvalidID, got %v", err)
+	}
+}
+
+func TestPattern_Matches(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		pattern string
+		id      string
+		want    bool
+	}{
+		{desc: "exact", pattern: "spiffe://example.org/ns/prod/sa/db", id: "spiffe://example.org/ns/prod/sa/db", want: true},
+		{desc: "exact mismatch", pattern: "spiffe://example.org/ns/prod/sa/db", id: "spiffe://example.org/ns/prod/sa/db2", want: false},
+		{desc: "prefix", pattern: "spiffe://example.org/ns/prod/*", id: "spiffe://example.org/ns/prod/sa/db", want: true},
+		{desc: "prefix boundary", pattern: "spiffe://example.org/ns/prod/*", id: "spiffe://example.org/ns/production", want: false},
+		{desc: "prefix self", pattern: "spiffe://example.org/ns/prod/*", id: "spiffe://example.org/ns/prod", want: false},
+		{desc: "trust domain", pattern: "spiffe://example.org", id: "spiffe://example.org/anything", want: true},
+		{desc: "trust domain wildcard", pattern: "spiffe://example.org/*", id: "spiffe://example.org/anything", want: true},
+		{desc: "other trust domain", pattern: "spiffe://example.org", id: "spiffe://evil.org/anything", want: false},
+		{desc: "common name", pattern: "spiffe://example.org", id: "db", want: false},
+		{desc: "anonymous", pattern: "spiffe://example.org", id: "", want: false},
+		{desc: "prefix traversal", pattern: "spiffe://example.org/ns/prod/*", id: "spiffe://example.org/ns/prod/../admin", want: false},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			p, err := ParsePattern(tC.pattern)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := p.Matches(tC.id); got != tC.want {
+				t.Errorf("%s.Matches(%q) = %v, want %v", p, tC.id, got, tC.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package spiffe
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// BundleSource provides X.509 trust bundles indexed by trust domain ID
+// ('spiffe://<trust domain>').
+type BundleSource interface {
+	Bundles() map[string][]*x509.Certificate
+}
+
+// Bundles is a thread-safe updatable bundle source.
+type Bundles struct {
+	mu      sync.RWMutex
+	bundles map[string][]*x509.Certificate
+}
+
+// Bundles returns the current trust bundles.
+func (b *Bundles) Bundles() map[string][]*x509.Certificate {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.bundles
+}
+
+// Set replaces all trust bundles.
+func (b *Bundles) Set(bundles map[string][]*x509.Certificate) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.bundles = bundles
+}
+
+// -----------------------------------------------------------------------------
+
+// ServerConfig returns a TLS server configuration derived from the given one,
+// verifying client certificates against the current trust bundles of the
+// source. Bundle rotation is applied on next handshake.
+func ServerConfig(base *tls.Config, source BundleSource) *tls.Config {
+	cfg := base.Clone()
+	cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		bundles := source.Bundles()
+
+		// Build client CA pool from current trust bundles
+		pool := x509.NewCertPool()
+		for _, authorities := range bundles {
+			for _, ca := range authorities {
+				pool.AddCert(ca)
+			}
+		}
+
+		c := base.Clone()
+		c.ClientCAs = pool
+		c.VerifyPeerCertificate = verifyTrustDomain(bundles)
+		return c, nil
+	}
+	return cfg
+}
+
+// verifyTrustDomain ensures that the client SVID chain is anchored in the
+// trust bundle of its own trust domain.
+func verifyTrustDomain(bundles map[string][]*x509.Certificate) func([][]byte, [][]*x509.Certificate) error {
+	return func(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
+		// No client certificate
+		if len(verifiedChains) == 0 {
+			return nil
+		}
+
+		// Extract SPIFFE ID
+		id, err := IDFromCertificate(verifiedChains[0][0])
+		if errors.Is(err, ErrNoID) {
+			// Not a SVID, chain is already verified
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		td, err := TrustDomain(id)
+		if err != nil {
+			return err
+		}
+
+		// Check chain anchors
+		for _, chain := range verifiedChains {
+			root := chain[len(chain)-1]
+			for _, ca := range bundles[td] {
+				if root.Equal(ca) {
+					return nil
+				}
+			}
+		}
+
+		return fmt.Errorf("spiffe: '%s' certificate is not issued by a '%s' trust bundle authority", id, td)
+	}
+}
@@ -0,0 +1,142 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package spiffe
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"testing"
+)
+
+// handshake runs a TLS handshake with the given client certificate and
+// returns the client identity verified by the server.
+func handshake(t *testing.T, serverConfig *tls.Config, cert *x509.Certificate, key interface{}) (string, error) {
+	t.Helper()
+
+	// Use a buffered transport to avoid handshake alert deadlocks
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	defer lis.Close()
+
+	clientConn, err := net.Dial("tcp", lis.Addr().String())
+	if err != nil {
+		t.Fatalf("unable to dial: %v", err)
+	}
+	defer clientConn.Close()
+
+	serverConn, err := lis.Accept()
+	if err != nil {
+		t.Fatalf("unable to accept: %v", err)
+	}
+	defer serverConn.Close()
+
+	client := tls.Client(clientConn, &tls.Config{
+		// Server authentication is out of scope
+		InsecureSkipVerify: true, // nolint:gosec // test only
+		Certificates: []tls.Certificate{
+			{Certificate: [][]byte{cert.Raw}, PrivateKey: key},
+		},
+	})
+	go func() {
+		// Errors are reported server-side
+		_ = client.Handshake()
+		_, _ = client.Read(make([]byte, 1))
+	}()
+
+	server := tls.Server(serverConn, serverConfig)
+	if err := server.Handshake(); err != nil {
+		return "", err
+	}
+
+	state := server.ConnectionState()
+	return IDFromCertificate(state.VerifiedChains[0][0])
+}
+
+func TestServerConfig(t *testing.T) {
+	serverCA := newAuthority(t, "server")
+	serverCert, serverKey := serverCA.issue(t, "harp-server")
+
+	base := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		Certificates: []tls.Certificate{
+			{Certificate: [][]byte{serverCert.Raw}, PrivateKey: serverKey},
+		},
+	}
+
+	exampleCA := newAuthority(t, "example.org")
+	evilCA := newAuthority(t, "evil.org")
+	rotatedCA := newAuthority(t, "example.org")
+
+	bundles := &Bundles{}
+	bundles.Set(map[string][]*x509.Certificate{
+		"spiffe://example.org": {exampleCA.cert},
+		"spiffe://evil.org":    {evilCA.cert},
+	})
+	cfg := ServerConfig(base, bundles)
+
+	t.Run("allowed", func(t *testing.T) {
+		cert, key := exampleCA.issue(t, "db", "spiffe://example.org/ns/prod/sa/db")
+		id, err := handshake(t, cfg, cert, key)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if id != "spiffe://example.org/ns/prod/sa/db" {
+			t.Errorf("unexpected client identity %q", id)
+		}
+	})
+
+	t.Run("untrusted authority", func(t *testing.T) {
+		cert, key := newAuthority(t, "unknown").issue(t, "db", "spiffe://example.org/ns/prod/sa/db")
+		if _, err := handshake(t, cfg, cert, key); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("foreign trust domain authority", func(t *testing.T) {
+		// evil.org authority can't issue example.org identities
+		cert, key := evilCA.issue(t, "db", "spiffe://example.org/ns/prod/sa/db")
+		if _, err := handshake(t, cfg, cert, key); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("rotated bundle", func(t *testing.T) {
+		cert, key := rotatedCA.issue(t, "db", "spiffe://example.org/ns/prod/sa/db")
+		if _, err := handshake(t, cfg, cert, key); err == nil {
+			t.Fatal("expected an error before rotation")
+		}
+
+		// Rotate example.org authority
+		bundles.Set(map[string][]*x509.Certificate{
+			"spiffe://example.org": {rotatedCA.cert},
+		})
+
+		if _, err := handshake(t, cfg, cert, key); err != nil {
+			t.Fatalf("unexpected error after rotation: %v", err)
+		}
+
+		previous, previousKey := exampleCA.issue(t, "db", "spiffe://example.org/ns/prod/sa/db")
+		if _, err := handshake(t, cfg, previous, previousKey); err == nil {
+			t.Fatal("expected an error for revoked authority")
+		}
+	})
+}
@@ -0,0 +1,300 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package spiffe
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/elastic/harp/pkg/sdk/log"
+)
+
+const (
+	// fetchX509BundlesMethod is the SPIFFE Workload API bundle streaming
+	// method.
+	fetchX509BundlesMethod = "/SpiffeWorkloadAPI/FetchX509Bundles"
+	// workloadHeader is the security header required by the Workload API.
+	workloadHeader = "workload.spiffe.io"
+	// retryDelay is the delay between Workload API reconnections.
+	retryDelay = 5 * time.Second
+)
+
+// WorkloadOption defines Workload API source functional option.
+type WorkloadOption func(*workloadOptions)
+
+type workloadOptions struct {
+	dialOptions []grpc.DialOption
+	timeout     time.Duration
+}
+
+// WithDialOptions appends gRPC dial options used to connect the Workload API.
+func WithDialOptions(opts ...grpc.DialOption) WorkloadOption {
+	return func(o *workloadOptions) {
+		o.dialOptions = append(o.dialOptions, opts...)
+	}
+}
+
+// WithInitialTimeout sets the maximum delay to wait for the first trust
+// bundle.
+func WithInitialTimeout(value time.Duration) WorkloadOption {
+	return func(o *workloadOptions) {
+		o.timeout = value
+	}
+}
+
+// WatchBundles connects to the SPIRE Workload API exposed by the given socket
+// ('unix:///path/to/agent.sock' or '/path/to/agent.sock') and returns a bundle
+// source updated on each trust bundle rotation until the context is done.
+// It blocks until the first trust bundle is received.
+func WatchBundles(ctx context.Context, socket string, opts ...WorkloadOption) (BundleSource, error) {
+	// Default options
+	dopts := &workloadOptions{
+		dialOptions: []grpc.DialOption{
+			grpc.WithInsecure(),
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", strings.TrimPrefix(socket, "unix://"))
+			}),
+		},
+		timeout: 30 * time.Second,
+	}
+
+	// Apply options
+	for _, o := range opts {
+		o(dopts)
+	}
+
+	// Connect to the Workload API, the socket is resolved by the dialer
+	conn, err := grpc.DialContext(ctx, "localhost", dopts.dialOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to workload api: %w", err)
+	}
+
+	source := &Bundles{}
+	ready := make(chan struct{})
+
+	go func() {
+		defer conn.Close()
+
+		var first = ready
+		for {
+			err := watch(ctx, conn, func(bundles map[string][]*x509.Certificate) {
+				source.Set(bundles)
+				log.For(ctx).Info("SPIFFE trust bundles updated", zap.Int("trust_domains", len(bundles)))
+
+				// Notify first update
+				if first != nil {
+					close(first)
+					first = nil
+				}
+			})
+			if ctx.Err() != nil {
+				return
+			}
+			log.For(ctx).Warn("SPIFFE workload api stream interrupted, retrying", zap.Error(err))
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retryDelay):
+			}
+		}
+	}()
+
+	// Wait for the first bundle
+	select {
+	case <-ready:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(dopts.timeout):
+		return nil, errors.New("unable to receive initial trust bundle from workload api: timeout")
+	}
+
+	// No error
+	return source, nil
+}
+
+// watch streams trust bundle updates until an error occurs.
+func watch(ctx context.Context, conn *grpc.ClientConn, update func(map[string][]*x509.Certificate)) error {
+	ctx, cancel := context.WithCancel(metadata.AppendToOutgoingContext(ctx, workloadHeader, "true"))
+	defer cancel()
+
+	// Open bundle stream
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, fetchX509BundlesMethod, grpc.ForceCodec(rawCodec{}))
+	if err != nil {
+		return fmt.Errorf("unable to open bundle stream: %w", err)
+	}
+	if err := stream.SendMsg(&rawMessage{}); err != nil {
+		return fmt.Errorf("unable to send bundle request: %w", err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		return fmt.Errorf("unable to close bundle request stream: %w", err)
+	}
+
+	for {
+		var msg rawMessage
+		if err := stream.RecvMsg(&msg); err != nil {
+			return fmt.Errorf("unable to receive bundle update: %w", err)
+		}
+
+		bundles, err := decodeX509BundlesResponse(msg)
+		if err != nil {
+			return err
+		}
+
+		update(bundles)
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// rawMessage is a protobuf encoded message.
+type rawMessage []byte
+
+// rawCodec is a gRPC codec transporting encoded protobuf messages as is.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	msg, ok := v.(*rawMessage)
+	if !ok {
+		return nil, fmt.Errorf("unable to marshal '%T' message", v)
+	}
+	return *msg, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	msg, ok := v.(*rawMessage)
+	if !ok {
+		return fmt.Errorf("unable to unmarshal '%T' message", v)
+	}
+	*msg = append((*msg)[:0], data...)
+	return nil
+}
+
+func (rawCodec) Name() string   { return "proto" }
+func (rawCodec) String() string { return "proto" }
+
+// X509BundlesResponse fields.
+//
+//	message X509BundlesResponse {
+//	  repeated bytes crl = 1;
+//	  map<string, bytes> bundles = 2;
+//	}
+const (
+	bundlesField  = 2
+	mapKeyField   = 1
+	mapValueField = 2
+)
+
+// errInvalidMessage is raised when the Workload API response can't be decoded.
+var errInvalidMessage = errors.New("spiffe: invalid X509BundlesResponse message")
+
+// decodeX509BundlesResponse decodes trust bundles from the Workload API
+// response, where each bundle is a set of concatenated DER certificates.
+func decodeX509BundlesResponse(msg []byte) (map[string][]*x509.Certificate, error) {
+	bundles := map[string][]*x509.Certificate{}
+
+	for len(msg) > 0 {
+		num, typ, n := protowire.ConsumeTag(msg)
+		if n < 0 {
+			return nil, errInvalidMessage
+		}
+		msg = msg[n:]
+
+		// Skip other fields
+		if num != bundlesField || typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, msg)
+			if n < 0 {
+				return nil, errInvalidMessage
+			}
+			msg = msg[n:]
+			continue
+		}
+
+		entry, n := protowire.ConsumeBytes(msg)
+		if n < 0 {
+			return nil, errInvalidMessage
+		}
+		msg = msg[n:]
+
+		// Decode map entry
+		td, der, err := decodeMapEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.Contains(td, "://") {
+			td = Scheme + "://" + td
+		}
+		td, err = TrustDomain(td)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bundle trust domain: %w", err)
+		}
+		certs, err := x509.ParseCertificates(der)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse '%s' trust bundle: %w", td, err)
+		}
+
+		bundles[td] = certs
+	}
+
+	// No error
+	return bundles, nil
+}
+
+func decodeMapEntry(entry []byte) (key string, value []byte, err error) {
+	for len(entry) > 0 {
+		num, typ, n := protowire.ConsumeTag(entry)
+		if n < 0 {
+			return "", nil, errInvalidMessage
+		}
+		entry = entry[n:]
+
+		switch {
+		case num == mapKeyField && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(entry)
+			if n < 0 {
+				return "", nil, errInvalidMessage
+			}
+			key, entry = v, entry[n:]
+		case num == mapValueField && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(entry)
+			if n < 0 {
+				return "", nil, errInvalidMessage
+			}
+			value, entry = v, entry[n:]
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, entry)
+			if n < 0 {
+				return "", nil, errInvalidMessage
+			}
+			entry = entry[n:]
+		}
+	}
+
+	return key, value, nil
+}
@@ -0,0 +1,166 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package spiffe
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// encodeX509BundlesResponse encodes a Workload API bundle response.
+func encodeX509BundlesResponse(bundles map[string][]*x509.Certificate) rawMessage {
+	var msg []byte
+	for td, certs := range bundles {
+		var der []byte
+		for _, c := range certs {
+			der = append(der, c.Raw...)
+		}
+
+		var entry []byte
+		entry = protowire.AppendTag(entry, mapKeyField, protowire.BytesType)
+		entry = protowire.AppendString(entry, td)
+		entry = protowire.AppendTag(entry, mapValueField, protowire.BytesType)
+		entry = protowire.AppendBytes(entry, der)
+
+		msg = protowire.AppendTag(msg, bundlesField, protowire.BytesType)
+		msg = protowire.AppendBytes(msg, entry)
+	}
+	return msg
+}
+
+// fakeWorkloadAPI streams bundle updates received from the given channel.
+func fakeWorkloadAPI(updates <-chan rawMessage) grpc.StreamHandler {
+	return func(_ interface{}, stream grpc.ServerStream) error {
+		// Check method and security header
+		method, _ := grpc.MethodFromServerStream(stream)
+		if method != fetchX509BundlesMethod {
+			return errors.New("unexpected method")
+		}
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		if v := md.Get(workloadHeader); len(v) != 1 || v[0] != "true" {
+			return errors.New("missing security header")
+		}
+
+		var req rawMessage
+		if err := stream.RecvMsg(&req); err != nil {
+			return err
+		}
+
+		for {
+			select {
+			case <-stream.Context().Done():
+				return nil
+			case msg := <-updates:
+				if err := stream.SendMsg(&msg); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+func TestWatchBundles(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Start fake Workload API
+	updates := make(chan rawMessage, 2)
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.CustomCodec(rawCodec{}), // nolint:staticcheck // raw messages
+		grpc.UnknownServiceHandler(fakeWorkloadAPI(updates)),
+	)
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	defer srv.Stop()
+
+	initialCA := newAuthority(t, "example.org")
+	rotatedCA := newAuthority(t, "example.org")
+
+	updates <- encodeX509BundlesResponse(map[string][]*x509.Certificate{
+		"example.org": {initialCA.cert},
+	})
+
+	source, err := WatchBundles(ctx, "unix:///run/spire/sockets/agent.sock",
+		WithInitialTimeout(5*time.Second),
+		WithDialOptions(grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		})),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Initial bundle
+	bundle := source.Bundles()["spiffe://example.org"]
+	if len(bundle) != 1 || !bundle[0].Equal(initialCA.cert) {
+		t.Fatalf("unexpected initial bundle: %v", bundle)
+	}
+
+	// Rotate bundle
+	updates <- encodeX509BundlesResponse(map[string][]*x509.Certificate{
+		"spiffe://example.org": {rotatedCA.cert},
+	})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		bundle = source.Bundles()["spiffe://example.org"]
+		if len(bundle) == 1 && bundle[0].Equal(rotatedCA.cert) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("bundle rotation not received")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWatchBundles_Timeout(t *testing.T) {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.CustomCodec(rawCodec{}), // nolint:staticcheck // raw messages
+		grpc.UnknownServiceHandler(fakeWorkloadAPI(make(chan rawMessage))),
+	)
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	defer srv.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := WatchBundles(ctx, "/run/spire/sockets/agent.sock",
+		WithInitialTimeout(100*time.Millisecond),
+		WithDialOptions(grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		})),
+	)
+	if err == nil {
+		t.Fatal("expected a timeout error")
+	}
+}
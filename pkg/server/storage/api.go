@@ -26,6 +26,12 @@ import (
 // ErrSecretNotFound is raised when trying to access non-existing secret.
 var ErrSecretNotFound = errors.New("engine: secret not found")
 
+// ErrAccessDenied is raised when the client is not allowed to access a secret.
+var ErrAccessDenied = errors.New("engine: access denied")
+
+// ErrSecretQuarantined is raised when trying to access a quarantined secret.
+var ErrSecretQuarantined = errors.New("engine: secret quarantined")
+
 // EngineFactoryFunc is the storage engine factory contract.
 type EngineFactoryFunc func(*url.URL) (Engine, error)
 
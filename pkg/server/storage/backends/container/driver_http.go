@@ -21,28 +21,28 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"net/http"
+
+	"github.com/elastic/harp/pkg/sdk/httpclient"
 )
 
 type httpLoader struct {
-	scheme string
-	host   string
+	scheme  string
+	host    string
+	maxRate int64
+	digest  string
 }
 
 // Reader returns the file Reader
 func (d *httpLoader) Reader(ctx context.Context, key string) (io.ReadCloser, error) {
-	// Prepare query
-	q, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s://%s/%s", d.scheme, d.host, key), nil)
-	if err != nil {
-		return nil, fmt.Errorf("http: unable to prepare http query: %w", err)
-	}
-
-	// Query
-	resp, err := http.DefaultClient.Do(q)
+	// Download the container to a temporary file
+	r, err := httpclient.Download(ctx, fmt.Sprintf("%s://%s/%s", d.scheme, d.host, key),
+		httpclient.WithMaxRate(d.maxRate),
+		httpclient.WithExpectedDigest(d.digest),
+	)
 	if err != nil {
-		return nil, fmt.Errorf("http: unable to query remote bundle URL: %w", err)
+		return nil, fmt.Errorf("http: unable to download remote bundle URL: %w", err)
 	}
 
 	// No error
-	return resp.Body, nil
+	return r, nil
 }
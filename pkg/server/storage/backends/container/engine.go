@@ -21,20 +21,47 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"strings"
+	"sync"
 
 	"github.com/spf13/afero"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/vfs"
+	"github.com/elastic/harp/pkg/server/storage"
 )
 
 type engine struct {
-	u  *url.URL
-	fs afero.Fs
+	u           *url.URL
+	fs          afero.Fs
+	mu          sync.RWMutex
+	mapped      *bundle.MappedBundle
+	closed      bool
+	digests     map[string]*storage.Digest
+	index       []string
+	quarantined map[string]string
 }
 
 // -----------------------------------------------------------------------------
 
 func (e *engine) Get(ctx context.Context, id string) ([]byte, error) {
+	// Check quarantine
+	if err := e.checkQuarantine(id); err != nil {
+		return nil, err
+	}
+
+	// Decode mapped package on demand
+	fs := e.fs
+	if e.mapped != nil {
+		var err error
+		if fs, err = e.packageFs(id); err != nil {
+			return nil, err
+		}
+	}
+
 	// Open and read all file content
-	out, err := afero.ReadFile(e.fs, id)
+	out, err := afero.ReadFile(fs, id)
 	if err != nil {
 		return nil, fmt.Errorf("bundle: unable to read file content: %v", err)
 	}
@@ -42,3 +69,79 @@ func (e *engine) Get(ctx context.Context, id string) ([]byte, error) {
 	// No error
 	return out, nil
 }
+
+func (e *engine) Digest(ctx context.Context, id string) (*storage.Digest, error) {
+	// Check quarantine
+	if err := e.checkQuarantine(id); err != nil {
+		return nil, err
+	}
+
+	d, ok := e.digests[id]
+	if !ok {
+		return nil, storage.ErrSecretNotFound
+	}
+
+	// Return a private copy
+	out := *d
+	return &out, nil
+}
+
+func (e *engine) List(ctx context.Context, prefix string, req storage.PageRequest) (*storage.Page, error) {
+	// Normalize as an absolute directory
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" {
+		prefix = fmt.Sprintf("/%s/", prefix)
+	} else {
+		prefix = "/"
+	}
+
+	// Delegate to sorted package index
+	return storage.Paginate(e.index, prefix, req)
+}
+
+// Close releases the mapped bundle once pending reads are complete.
+func (e *engine) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.mapped == nil || e.closed {
+		return nil
+	}
+	e.closed = true
+
+	return e.mapped.Close()
+}
+
+// -----------------------------------------------------------------------------
+
+// packageFs returns a filesystem holding the given mapped package only.
+func (e *engine) packageFs(id string) (afero.Fs, error) {
+	// Only indexed packages are served
+	if _, ok := e.digests[id]; !ok {
+		return nil, fmt.Errorf("bundle: unable to read file content: %w", storage.ErrSecretNotFound)
+	}
+
+	// Keep the mapping until the package is decoded
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closed {
+		return nil, fmt.Errorf("bundle: engine has been reloaded")
+	}
+
+	p, err := e.mapped.Package(strings.TrimPrefix(id, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("bundle: unable to decode package: %v", err)
+	}
+	if p == nil {
+		return nil, storage.ErrSecretNotFound
+	}
+
+	return vfs.FromBundle(&bundlev1.Bundle{Packages: []*bundlev1.Package{p}})
+}
+
+func (e *engine) checkQuarantine(id string) error {
+	if reason, ok := e.quarantined[id]; ok {
+		return fmt.Errorf("package '%s' is quarantined (%s): %w", id, reason, storage.ErrSecretQuarantined)
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package container
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/decryptor"
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+	"github.com/elastic/harp/pkg/sdk/value/encryption"
+	"github.com/elastic/harp/pkg/server/storage"
+)
+
+type bytesLoader []byte
+
+func (l bytesLoader) Reader(context.Context, string) (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(l)), nil
+}
+
+func TestEngine_Quarantined(t *testing.T) {
+	b := testbundle.New()
+	b.Package("app/production/security/harp/v1.0.0/server/database").
+		Secret("user", "admin")
+	b.Package("app/production/security/harp/v1.0.0/server/leaked").
+		Secret("token", "leaked-token").
+		Annotation(bundle.QuarantineAnnotation, "leaked in CI logs")
+
+	u, err := url.Parse("bundle:///fixture.bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := buildWithLoader(u, bytesLoader(testbundle.Container(t, b.Build())))
+	if err != nil {
+		t.Fatalf("unable to build engine: %v", err)
+	}
+	ctx := context.Background()
+
+	// Active package is served
+	if _, err := e.Get(ctx, "/app/production/security/harp/v1.0.0/server/database"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// Quarantined package is refused
+	_, err = e.Get(ctx, "/app/production/security/harp/v1.0.0/server/leaked")
+	if !errors.Is(err, storage.ErrSecretQuarantined) {
+		t.Errorf("expected ErrSecretQuarantined, got %v", err)
+	}
+	_, err = storage.GetDigest(ctx, e, "/app/production/security/harp/v1.0.0/server/leaked")
+	if !errors.Is(err, storage.ErrSecretQuarantined) {
+		t.Errorf("expected ErrSecretQuarantined, got %v", err)
+	}
+
+	// Quarantined package is not listed
+	page, err := storage.List(ctx, e, "/", storage.PageRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page.Keys) != 1 {
+		t.Errorf("quarantined package must not be listed, got %v", page.Keys)
+	}
+}
+
+func TestEngine_ExpiredPackages(t *testing.T) {
+	b := testbundle.New()
+	b.Package("app/production/security/harp/v1.0.0/server/database").
+		Secret("user", "admin")
+	b.Package("app/production/security/harp/v1.0.0/server/legacy").
+		Secret("token", "stale-token").
+		Annotation(bundle.ExpiresAnnotation, "2020-01-01T00:00:00Z")
+	raw := testbundle.Container(t, b.Build())
+
+	u, err := url.Parse("bundle:///fixture.bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer SetExpiredPackagePolicy(bundle.ExpiredPackageKeep)
+	ctx := context.Background()
+
+	// Expired package is not served
+	SetExpiredPackagePolicy(bundle.ExpiredPackageDrop)
+	e, err := buildWithLoader(u, bytesLoader(raw))
+	if err != nil {
+		t.Fatalf("unable to build engine: %v", err)
+	}
+	if _, err := e.Get(ctx, "/app/production/security/harp/v1.0.0/server/database"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := e.Get(ctx, "/app/production/security/harp/v1.0.0/server/legacy"); err == nil {
+		t.Error("expected expired package to be dropped")
+	}
+
+	// Container with expired packages is refused
+	SetExpiredPackagePolicy(bundle.ExpiredPackageError)
+	if _, err := buildWithLoader(u, bytesLoader(raw)); !errors.Is(err, bundle.ErrPackageExpired) {
+		t.Errorf("expected ErrPackageExpired, got %v", err)
+	}
+}
+
+func TestEngine_EncryptedValues(t *testing.T) {
+	const key = "aes-gcm:y8Jk4Lk9a8JZ0f8ELzWnUQu7Yr_f9-VFZbFPVWgzpJ4="
+
+	d, err := decryptor.AESGCM(key)
+	if err != nil {
+		t.Fatalf("unable to initialize decryptor: %v", err)
+	}
+	at, err := encryption.FromKey(key)
+	if err != nil {
+		t.Fatalf("unable to initialize transformer: %v", err)
+	}
+	ciphertext, err := at.To(context.Background(), []byte("s3cr3t"))
+	if err != nil {
+		t.Fatalf("unable to encrypt value: %v", err)
+	}
+
+	b := testbundle.New()
+	b.Package("app/production/security/harp/v1.0.0/server/database").
+		Secret("password", bundle.EncryptedValue(decryptor.SchemeAESGCM, ciphertext))
+	b.Package("app/production/security/harp/v1.0.0/server/legacy").
+		Secret("token", bundle.EncryptedValue("vault-transit", []byte("opaque")))
+	raw := testbundle.Container(t, b.Build())
+
+	u, err := url.Parse("bundle:///fixture.bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer SetValueDecryptor(nil, bundle.DecryptFailureError)
+	ctx := context.Background()
+
+	// Undecryptable package is hidden
+	SetValueDecryptor(d, bundle.DecryptFailureHide)
+	e, err := buildWithLoader(u, bytesLoader(raw))
+	if err != nil {
+		t.Fatalf("unable to build engine: %v", err)
+	}
+	out, err := e.Get(ctx, "/app/production/security/harp/v1.0.0/server/database")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Contains(out, []byte("s3cr3t")) {
+		t.Errorf("expected decrypted value, got %s", out)
+	}
+	if _, err := e.Get(ctx, "/app/production/security/harp/v1.0.0/server/legacy"); err == nil {
+		t.Error("expected undecryptable package to be hidden")
+	}
+
+	// Container with undecryptable values is refused
+	SetValueDecryptor(d, bundle.DecryptFailureError)
+	if _, err := buildWithLoader(u, bytesLoader(raw)); !errors.Is(err, bundle.ErrValueDecryption) {
+		t.Errorf("expected ErrValueDecryption, got %v", err)
+	}
+}
+
+func TestEngine_Mmap(t *testing.T) {
+	b := testbundle.New()
+	b.Package("app/production/security/harp/v1.0.0/server/database").
+		Secret("user", "admin")
+	b.Package("app/production/security/harp/v1.0.0/server/legacy").
+		Secret("token", "stale-token").
+		Annotation(bundle.ExpiresAnnotation, "2020-01-01T00:00:00Z")
+	b.Package("app/production/security/harp/v1.0.0/server/leaked").
+		Secret("token", "leaked-token").
+		Annotation(bundle.QuarantineAnnotation, "leaked in CI logs")
+	raw := testbundle.Container(t, b.Build())
+
+	u, err := url.Parse("bundle:///fixture.bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// Reference engine
+	SetExpiredPackagePolicy(bundle.ExpiredPackageDrop)
+	defer SetExpiredPackagePolicy(bundle.ExpiredPackageKeep)
+	want, err := buildWithLoader(u, bytesLoader(raw))
+	if err != nil {
+		t.Fatalf("unable to build engine: %v", err)
+	}
+
+	// Memory-mapped engine
+	cacheDir := t.TempDir()
+	SetMmap(true, cacheDir)
+	defer SetMmap(false, "")
+	e, err := buildWithLoader(u, bytesLoader(raw))
+	if err != nil {
+		t.Fatalf("unable to build engine: %v", err)
+	}
+	if e.(*engine).mapped == nil {
+		t.Fatal("expected a memory-mapped engine")
+	}
+
+	// Served content is identical
+	id := "/app/production/security/harp/v1.0.0/server/database"
+	expected, err := want.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out, err := e.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(out, expected) {
+		t.Errorf("expected %s, got %s", expected, out)
+	}
+	d, err := storage.GetDigest(ctx, e, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedDigest, err := storage.GetDigest(ctx, want, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.Value != expectedDigest.Value {
+		t.Error("expected identical package digest")
+	}
+
+	// Expired and quarantined packages are not served
+	if _, err := e.Get(ctx, "/app/production/security/harp/v1.0.0/server/legacy"); !errors.Is(err, storage.ErrSecretNotFound) {
+		t.Errorf("expected ErrSecretNotFound, got %v", err)
+	}
+	if _, err := e.Get(ctx, "/app/production/security/harp/v1.0.0/server/leaked"); !errors.Is(err, storage.ErrSecretQuarantined) {
+		t.Errorf("expected ErrSecretQuarantined, got %v", err)
+	}
+	page, err := storage.List(ctx, e, "/", storage.PageRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page.Keys) != 1 {
+		t.Errorf("expected one listed package, got %v", page.Keys)
+	}
+
+	// Closed engine releases the shared cache
+	if err := e.(io.Closer).Close(); err != nil {
+		t.Fatalf("unable to close engine: %v", err)
+	}
+	if _, err := e.Get(ctx, id); err == nil {
+		t.Error("expected closed engine to refuse reads")
+	}
+	if files, _ := filepath.Glob(filepath.Join(cacheDir, "harp-*.bundle")); runtime.GOOS != "windows" && len(files) != 0 {
+		t.Errorf("expected shared cache file to be removed, got %v", files)
+	}
+}
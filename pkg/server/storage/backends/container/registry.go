@@ -18,13 +18,18 @@
 package container
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/url"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -51,11 +56,39 @@ func SetKeyring(keys []string) {
 	containerKeyring = keys
 }
 
+// SetExpiredPackagePolicy assigns the expired package policy for bundle
+// loader.
+func SetExpiredPackagePolicy(policy bundle.ExpiredPackagePolicy) {
+	expiredPackagePolicy = policy
+}
+
+// SetValueDecryptor assigns the decryptor used for externally encrypted values,
+// and how decryption failures are handled by bundle loader.
+func SetValueDecryptor(d bundle.ValueDecryptor, policy bundle.DecryptFailurePolicy) {
+	valueDecryptor = d
+	decryptFailurePolicy = policy
+}
+
+// SetMmap enables memory-mapped bundle loading through a shared cache
+// directory, packages are decoded on demand.
+func SetMmap(enabled bool, cacheDir string) {
+	mmapEnabled = enabled
+	sharedCacheDir = cacheDir
+}
+
 // -----------------------------------------------------------------------------
 
+// ErrUnsealedContainer is raised when a sealed container is required.
+var ErrUnsealedContainer = errors.New("container: backend container is not sealed")
+
 var (
-	once             sync.Once
-	containerKeyring []string
+	once                 sync.Once
+	containerKeyring     []string
+	expiredPackagePolicy bundle.ExpiredPackagePolicy
+	valueDecryptor       bundle.ValueDecryptor
+	decryptFailurePolicy bundle.DecryptFailurePolicy
+	mmapEnabled          bool
+	sharedCacheDir       string
 )
 
 const (
@@ -75,6 +108,7 @@ func init() {
 		storage.MustRegister(schemeBundleDefault, build)
 		storage.MustRegister(schemeBundleFromFile, build)
 		storage.MustRegister(schemeBundleFromHTTP, build)
+		storage.MustRegister(schemeBundleFromHTTPS, build)
 		storage.MustRegister(schemeBundleFromS3, build)
 		storage.MustRegister(schemeBundleFromGCS, build)
 		storage.MustRegister(schemeBundleFromAzBlob, build)
@@ -96,6 +130,66 @@ func withDefault(q url.Values, key, defaultValue string) string {
 // To refactor implements strategy pattern and probably plugins extension
 // via named pipe or gRPC servers like TF providers.
 func build(u *url.URL) (storage.Engine, error) {
+	// Select the container loader
+	loader, err := loaderFor(u)
+	if err != nil {
+		return nil, err
+	}
+
+	// Delegate to loader
+	return buildWithLoader(u, loader)
+}
+
+// ReadSealed returns the raw content of the sealed container referenced by
+// the given bundle backend URL. Unsealed containers are refused, so that
+// secrets never leave the server in clear text.
+func ReadSealed(ctx context.Context, uri string) ([]byte, error) {
+	// Parse backend URL
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse backend url: %w", err)
+	}
+	if u.Scheme == schemeBundleStdin {
+		return nil, errors.New("stdin container can't be read again")
+	}
+
+	// Select the container loader
+	loader, err := loaderFor(u)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read container content
+	br, err := loader.Reader(ctx, u.Path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load container content: %w", err)
+	}
+	defer func() {
+		if errClose := br.Close(); errClose != nil {
+			log.For(ctx).Warn("unable to close container reader", zap.Error(errClose))
+		}
+	}()
+	raw, err := ioutil.ReadAll(br)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read container content: %w", err)
+	}
+
+	// Check container sealing
+	c, err := container.Load(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("unable to load secret container: %w", err)
+	}
+	if len(c.GetHeaders().GetRecipients()) == 0 {
+		return nil, ErrUnsealedContainer
+	}
+
+	// No error
+	return raw, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func loaderFor(u *url.URL) (Loader, error) {
 	q := u.Query()
 
 	switch u.Scheme {
@@ -110,44 +204,44 @@ func build(u *url.URL) (storage.Engine, error) {
 		if err != nil {
 			return nil, fmt.Errorf("unable to initialize session: %v", err)
 		}
-		// Delegate to loader
-		return buildWithLoader(u, &s3Loader{
+		return &s3Loader{
 			s3api:      s3.New(sess),
 			bucketName: opts.BucketName,
-		})
+		}, nil
 	case schemeBundleFromAzBlob:
 		azureConnString := os.Getenv("AZURE_CONNECTION_STRING")
 		if azureConnString == "" {
 			return nil, errors.New("AZURE_CONNECTION_STRING env. variable must be set for azblob backend")
 		}
-		return buildWithLoader(u, &azureBlobLoader{
+		return &azureBlobLoader{
 			bucketName: u.Hostname(),
 			prefix:     withDefault(q, "prefix", ""),
 			connString: azureConnString,
-		})
+		}, nil
 	case schemeBundleFromGCS:
-		return buildWithLoader(u, &gcsLoader{
+		return &gcsLoader{
 			bucketName: u.Hostname(),
 			prefix:     withDefault(q, "prefix", ""),
-		})
-	case schemeBundleFromHTTP:
-		return buildWithLoader(u, &httpLoader{
-			scheme: "http",
-			host:   u.Host,
-		})
-	case schemeBundleFromHTTPS:
-		return buildWithLoader(u, &httpLoader{
-			scheme: "https",
-			host:   u.Host,
-		})
+		}, nil
+	case schemeBundleFromHTTP, schemeBundleFromHTTPS:
+		maxRate, err := strconv.ParseInt(withDefault(q, "max_rate", "0"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max_rate value: %v", err)
+		}
+		return &httpLoader{
+			scheme:  strings.TrimPrefix(u.Scheme, "bundle+"),
+			host:    u.Host,
+			maxRate: maxRate,
+			digest:  q.Get("digest"),
+		}, nil
 	case schemeBundleDefault, schemeBundleFromFile:
 		fs := afero.NewOsFs()
 		fs = afero.NewReadOnlyFs(fs)
-		return buildWithLoader(u, &fileLoader{
+		return &fileLoader{
 			fs: fs,
-		})
+		}, nil
 	case schemeBundleStdin:
-		return buildWithLoader(u, &stdinLoader{})
+		return &stdinLoader{}, nil
 
 	default:
 	}
@@ -165,18 +259,58 @@ func buildWithLoader(u *url.URL, loader Loader) (storage.Engine, error) {
 	if errDriver != nil {
 		return nil, fmt.Errorf("unable to load container content: %v", errDriver)
 	}
+	defer func() {
+		if errClose := br.Close(); errClose != nil {
+			log.For(ctx).Warn("unable to close container reader", zap.Error(errClose))
+		}
+	}()
 
 	// Extract bundle container key form url
 	var (
 		q              = u.Query()
 		containerIDRaw = q.Get("cid")
 		unlockKeyRaw   = q.Get("unlock")
+		overlayPath    = q.Get("overlay")
 	)
 
+	// Locked bundles and overlays require a complete bundle
+	if mmapEnabled {
+		if overlayPath == "" && unlockKeyRaw == "" {
+			return buildMapped(ctx, u, br, containerIDRaw)
+		}
+		log.For(ctx).Info("Memory-mapped loading ignored for locked or layered bundle", zap.String("path", u.Path))
+	}
+
 	// Initialize bundle
 	b, err := getBundle(ctx, br, containerIDRaw, unlockKeyRaw)
 	if err != nil {
-		return nil, fmt.Errorf("unable to extract bundle: %v", err)
+		return nil, fmt.Errorf("unable to extract bundle: %w", err)
+	}
+
+	// Apply local overlay
+	if overlayPath != "" {
+		b, err = applyOverlay(ctx, b, overlayPath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to apply bundle overlay: %v", err)
+		}
+	}
+
+	// Archived packages are never served
+	b = bundle.WithoutArchived(b)
+
+	// Quarantined packages are kept to explain refusals
+	quarantined := map[string]string{}
+	for _, p := range b.Packages {
+		if reason, ok := bundle.QuarantineReason(p); ok {
+			quarantined[fmt.Sprintf("/%s", p.Name)] = reason
+		}
+	}
+	b = bundle.WithoutQuarantined(b)
+
+	// Compute package digests before the filesystem wipes locked values
+	digests, err := packageDigests(b)
+	if err != nil {
+		return nil, err
 	}
 
 	// Initialize virtual filesystem
@@ -187,72 +321,156 @@ func buildWithLoader(u *url.URL, loader Loader) (storage.Engine, error) {
 
 	// Build engine instance
 	return &engine{
-		u:  u,
-		fs: fs,
+		u:           u,
+		fs:          fs,
+		digests:     digests,
+		index:       packageIndex(digests),
+		quarantined: quarantined,
 	}, nil
 }
 
-func getBundle(ctx context.Context, br io.Reader, containerID, psk string) (*bundlev1.Bundle, error) {
-	// Check container key usage
-	var (
-		b   *bundlev1.Bundle
-		err error
-	)
+func packageDigests(b *bundlev1.Bundle) (map[string]*storage.Digest, error) {
+	digests := map[string]*storage.Digest{}
+	for _, p := range b.Packages {
+		// Skip when no secret chain is defined
+		if p.Secrets == nil {
+			continue
+		}
 
-	if containerID != "" {
-		// Load container
-		sealed, errLoad := container.Load(br)
-		if errLoad != nil {
-			return nil, fmt.Errorf("unable to load secret container: %v", errLoad)
+		d, err := bundle.PackageDigest(p)
+		if err != nil {
+			return nil, fmt.Errorf("unable to compute '%s' package digest: %v", p.Name, err)
+		}
+		digests[fmt.Sprintf("/%s", p.Name)] = &storage.Digest{
+			Value:   d,
+			Version: p.Secrets.Version,
 		}
+	}
 
-		// Append given key, and keyring
-		containerKeys := append([]string{}, containerID)
-		containerKeys = append(containerKeys, containerKeyring...)
+	return digests, nil
+}
 
-		var (
-			unsealed  *containerv1.Container
-			errUnseal error
-		)
-		for _, containerKeyRaw := range containerKeys {
-			// Decode private key
-			containerKey, errDecode := base64.RawURLEncoding.DecodeString(containerKeyRaw)
-			if errDecode != nil {
-				log.For(ctx).Warn("Invalid key, ignored for encoding error", zap.Error(err))
-				continue
-			}
-
-			// Unseal container
-			unsealed, errUnseal = container.Unseal(sealed, memguard.NewBufferFromBytes(containerKey))
-			if errUnseal != nil {
-				log.For(ctx).Warn("Unable to unseal container with given key, key is ignored", zap.Error(errUnseal))
-				continue
-			}
-
-			// Break if container is unsealed
-			if unsealed != nil {
-				break
-			}
+func buildMapped(ctx context.Context, u *url.URL, br io.Reader, containerID string) (storage.Engine, error) {
+	// Extract container
+	c, err := getContainer(ctx, br, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to extract container: %w", err)
+	}
+
+	// Map bundle content
+	opts := loadOptions(ctx, nil, nil)
+	opts = append(opts, bundle.WithMmap(), bundle.WithSharedCacheDir(sharedCacheDir))
+	m, err := bundle.OpenMapped(c, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to map bundle: %w", err)
+	}
+
+	// Index active packages, decoded packages are released after indexing
+	var (
+		quarantined = map[string]string{}
+		digests     = map[string]*storage.Digest{}
+		filtered    = 0
+	)
+	for _, name := range m.Names() {
+		p, err := m.Package(name)
+		if err != nil {
+			m.Close()
+			return nil, err
 		}
-		if errUnseal != nil {
-			return nil, fmt.Errorf("unable to unseal container: %v", errUnseal)
+
+		switch {
+		case p == nil:
+			filtered++
+			continue
+		case bundle.IsArchived(p):
+			continue
+		default:
 		}
-		if unsealed == nil {
-			return nil, fmt.Errorf("unable to unseal container: no key match")
+		if reason, ok := bundle.QuarantineReason(p); ok {
+			quarantined[fmt.Sprintf("/%s", p.Name)] = reason
+			continue
 		}
 
-		// Extract bundle
-		b, err = bundle.FromContainer(unsealed)
+		d, err := packageDigests(&bundlev1.Bundle{Packages: []*bundlev1.Package{p}})
 		if err != nil {
-			return nil, fmt.Errorf("unable to extract Bundle from sealed container: %v", err)
+			m.Close()
+			return nil, err
 		}
-	} else {
-		// No container key assume unsealed container.
-		b, err = bundle.FromContainerReader(br)
-		if err != nil {
-			return nil, fmt.Errorf("unable to extract Bundle from unsealed container: %v", err)
+		for id, v := range d {
+			digests[id] = v
 		}
 	}
+	if filtered > 0 {
+		log.For(ctx).Info("Packages filtered by load policies", zap.Int("count", filtered))
+	}
+
+	// Build engine instance
+	return &engine{
+		u:           u,
+		mapped:      m,
+		digests:     digests,
+		index:       packageIndex(digests),
+		quarantined: quarantined,
+	}, nil
+}
+
+// packageIndex returns the lexicographically sorted package identifiers.
+func packageIndex(digests map[string]*storage.Digest) []string {
+	index := make([]string, 0, len(digests))
+	for id := range digests {
+		index = append(index, id)
+	}
+	sort.Strings(index)
+
+	return index
+}
+
+func applyOverlay(ctx context.Context, base *bundlev1.Bundle, overlayPath string) (*bundlev1.Bundle, error) {
+	// Open overlay container
+	f, err := os.Open(overlayPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open overlay container: %v", err)
+	}
+	defer f.Close()
+
+	// Extract overlay bundle
+	overlay, err := bundle.FromContainerReader(f, loadOptions(ctx, nil, nil)...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to extract overlay bundle: %v", err)
+	}
+
+	// Combine layers
+	layered, err := bundle.Overlay(base, overlay)
+	if err != nil {
+		return nil, err
+	}
+
+	// No error
+	return layered.Bundle(), nil
+}
+
+func getBundle(ctx context.Context, br io.Reader, containerID, psk string) (*bundlev1.Bundle, error) {
+	// Handle expired packages and encrypted values
+	dropped, hidden := 0, 0
+	opts := loadOptions(ctx, &dropped, &hidden)
+
+	// Extract container
+	c, err := getContainer(ctx, br, containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Extract bundle
+	b, err := bundle.FromContainer(c, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to extract Bundle from container: %w", err)
+	}
+	if dropped > 0 {
+		log.For(ctx).Info("Expired packages dropped from container", zap.Int("count", dropped))
+	}
+	if hidden > 0 {
+		log.For(ctx).Warn("Packages hidden for value decryption failure", zap.Int("count", hidden))
+	}
 
 	// Decrypt encrypted bundle using PSK
 	if psk != "" {
@@ -272,3 +490,75 @@ func getBundle(ctx context.Context, br io.Reader, containerID, psk string) (*bun
 	// Return result bundle
 	return b, nil
 }
+
+// getContainer loads the container and unseals it when a container key is
+// given.
+func getContainer(ctx context.Context, br io.Reader, containerID string) (*containerv1.Container, error) {
+	// Load container
+	c, err := container.Load(br)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load secret container: %v", err)
+	}
+
+	// No container key assume unsealed container.
+	if containerID == "" {
+		return c, nil
+	}
+
+	// Append given key, and keyring
+	containerKeys := append([]string{}, containerID)
+	containerKeys = append(containerKeys, containerKeyring...)
+
+	var (
+		unsealed  *containerv1.Container
+		errUnseal error
+	)
+	for _, containerKeyRaw := range containerKeys {
+		// Decode private key
+		containerKey, errDecode := base64.RawURLEncoding.DecodeString(containerKeyRaw)
+		if errDecode != nil {
+			log.For(ctx).Warn("Invalid key, ignored for encoding error", zap.Error(errDecode))
+			continue
+		}
+
+		// Unseal container
+		unsealed, errUnseal = container.Unseal(c, memguard.NewBufferFromBytes(containerKey))
+		if errUnseal != nil {
+			log.For(ctx).Warn("Unable to unseal container with given key, key is ignored", zap.Error(errUnseal))
+			continue
+		}
+
+		// Break if container is unsealed
+		if unsealed != nil {
+			break
+		}
+	}
+	if errUnseal != nil {
+		return nil, fmt.Errorf("unable to unseal container: %v", errUnseal)
+	}
+	if unsealed == nil {
+		return nil, fmt.Errorf("unable to unseal container: no key match")
+	}
+
+	// No error
+	return unsealed, nil
+}
+
+// loadOptions returns the bundle loader options according to the registry
+// settings.
+func loadOptions(ctx context.Context, dropped, hidden *int) []bundle.LoadOption {
+	opts := []bundle.LoadOption{
+		bundle.WithLoadContext(ctx),
+		bundle.WithExpiredPackagePolicy(expiredPackagePolicy),
+		bundle.WithDroppedPackageCount(dropped),
+	}
+	if valueDecryptor != nil {
+		opts = append(opts,
+			bundle.WithValueDecryptor(valueDecryptor),
+			bundle.WithDecryptFailurePolicy(decryptFailurePolicy),
+			bundle.WithHiddenPackageCount(hidden),
+		)
+	}
+
+	return opts
+}
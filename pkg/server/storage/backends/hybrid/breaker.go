@@ -0,0 +1,82 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package hybrid
+
+import (
+	"sync"
+	"time"
+)
+
+// breaker is a consecutive failure circuit breaker. When open, upstream calls
+// are skipped until the cooldown expires, then a single trial call is allowed.
+type breaker struct {
+	sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	failures  int
+	openUntil time.Time
+	trial     bool
+}
+
+// Allow returns true if the upstream can be called.
+func (b *breaker) Allow() bool {
+	b.Lock()
+	defer b.Unlock()
+
+	// Closed
+	if b.failures < b.threshold {
+		return true
+	}
+
+	// Open
+	if b.now().Before(b.openUntil) {
+		return false
+	}
+
+	// Half-open, only one trial call at a time
+	if b.trial {
+		return false
+	}
+	b.trial = true
+
+	return true
+}
+
+// Success closes the circuit.
+func (b *breaker) Success() {
+	b.Lock()
+	defer b.Unlock()
+
+	b.failures = 0
+	b.trial = false
+}
+
+// Failure records an upstream failure and opens the circuit when the
+// threshold is reached.
+func (b *breaker) Failure() {
+	b.Lock()
+	defer b.Unlock()
+
+	b.failures++
+	b.trial = false
+	if b.failures >= b.threshold {
+		b.openUntil = b.now().Add(b.cooldown)
+	}
+}
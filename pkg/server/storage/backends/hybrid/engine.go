@@ -0,0 +1,285 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package hybrid
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/server/storage"
+	"github.com/elastic/harp/pkg/vault/kv"
+)
+
+const (
+	// SourceUpstream is reported when the secret has been read from Vault.
+	SourceUpstream = "vault"
+	// SourceCache is reported when the secret has been read from the cache.
+	SourceCache = "cache"
+	// SourceContainer is reported when the secret has been read from the
+	// fallback container.
+	SourceContainer = "container"
+	// SourceStaleCache is reported when the secret has been read from an
+	// expired cache entry.
+	SourceStaleCache = "stale-cache"
+)
+
+func init() {
+	// Register to storage factory
+	storage.MustRegister("hybrid", build)
+}
+
+// -----------------------------------------------------------------------------
+
+// Options defines hybrid engine settings.
+type Options struct {
+	TTL              time.Duration
+	FailureThreshold int
+	Cooldown         time.Duration
+}
+
+// New returns an engine reading from upstream, with a TTL cache, and falling
+// back to the container engine then to stale cache entries when upstream is
+// unavailable.
+func New(upstream, fallback storage.Engine, opts Options) storage.Engine {
+	return newEngine(upstream, fallback, opts, time.Now)
+}
+
+func newEngine(upstream, fallback storage.Engine, opts Options, now func() time.Time) *engine {
+	return &engine{
+		upstream: upstream,
+		fallback: fallback,
+		ttl:      opts.TTL,
+		now:      now,
+		cache:    map[string]cacheEntry{},
+		breaker: &breaker{
+			threshold: opts.FailureThreshold,
+			cooldown:  opts.Cooldown,
+			now:       now,
+		},
+	}
+}
+
+// build the engine from an URL like
+// hybrid:///secret?addr=https://vault:8200&container=bundle%2Bfile%3A%2F%2F%2Fsecrets.bundle
+func build(u *url.URL) (storage.Engine, error) {
+	q := u.Query()
+
+	// Parse options
+	opts, err := parseOptions(q)
+	if err != nil {
+		return nil, err
+	}
+
+	// Initialize fallback engine
+	containerURL := q.Get("container")
+	if containerURL == "" {
+		return nil, fmt.Errorf("hybrid: container parameter is mandatory")
+	}
+	fallback, err := storage.Build(containerURL)
+	if err != nil {
+		return nil, fmt.Errorf("hybrid: unable to initialize container engine: %w", err)
+	}
+
+	// Initialize Vault connection
+	config := api.DefaultConfig()
+	if addr := q.Get("addr"); addr != "" {
+		config.Address = addr
+	}
+	client, err := api.NewClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("hybrid: unable to initialize vault connection: %w", err)
+	}
+
+	// Retrieve a secret reader
+	reader, err := kv.New(client, u.Path)
+	if err != nil {
+		return nil, fmt.Errorf("hybrid: unable to initialize vault reader: %w", err)
+	}
+
+	// No error
+	return New(&upstream{service: reader}, fallback, opts), nil
+}
+
+func parseOptions(q url.Values) (Options, error) {
+	opts := Options{
+		TTL:              5 * time.Minute,
+		FailureThreshold: 3,
+		Cooldown:         30 * time.Second,
+	}
+
+	if v := q.Get("ttl"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return opts, fmt.Errorf("hybrid: invalid ttl '%s': %w", v, err)
+		}
+		opts.TTL = d
+	}
+	if v := q.Get("failure_threshold"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return opts, fmt.Errorf("hybrid: invalid failure threshold '%s'", v)
+		}
+		opts.FailureThreshold = n
+	}
+	if v := q.Get("cooldown"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return opts, fmt.Errorf("hybrid: invalid cooldown '%s': %w", v, err)
+		}
+		opts.Cooldown = d
+	}
+
+	return opts, nil
+}
+
+// -----------------------------------------------------------------------------
+
+type cacheEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+type engine struct {
+	upstream storage.Engine
+	fallback storage.Engine
+	ttl      time.Duration
+	now      func() time.Time
+	breaker  *breaker
+
+	cacheMu sync.RWMutex
+	cache   map[string]cacheEntry
+
+	listenersMu sync.RWMutex
+	listeners   []func(storage.Change)
+}
+
+func (e *engine) Get(ctx context.Context, id string) ([]byte, error) {
+	// Check fresh cache entry
+	entry, cached := e.cached(id)
+	if cached && e.now().Before(entry.expiresAt) {
+		storage.SetSource(ctx, SourceCache)
+		return entry.value, nil
+	}
+
+	// Query upstream if the circuit is closed
+	if e.breaker.Allow() {
+		value, err := e.upstream.Get(ctx, id)
+		switch {
+		case err == nil:
+			e.breaker.Success()
+			if cached && !bytes.Equal(entry.value, value) {
+				e.notify(storage.Change{ID: storage.ChangeID(id), Type: storage.ChangeUpdated, Digest: storage.ContentDigest(value).Value})
+			}
+			e.store(id, value)
+			storage.SetSource(ctx, SourceUpstream)
+			return value, nil
+		case errors.Is(err, storage.ErrSecretNotFound):
+			// Upstream is available and authoritative
+			e.breaker.Success()
+			if cached {
+				e.evict(id)
+				e.notify(storage.Change{ID: storage.ChangeID(id), Type: storage.ChangeDeleted})
+			}
+			storage.SetSource(ctx, SourceUpstream)
+			return nil, storage.ErrSecretNotFound
+		default:
+			e.breaker.Failure()
+			log.For(ctx).Warn("Upstream is unavailable, using fallback", zap.Error(err))
+		}
+	}
+
+	// Fallback to container
+	value, err := e.fallback.Get(ctx, id)
+	if err == nil {
+		storage.SetSource(ctx, SourceContainer)
+		return value, nil
+	}
+	if errors.Is(err, storage.ErrSecretQuarantined) {
+		// Never serve a quarantined secret from the cache
+		e.evict(id)
+		return nil, err
+	}
+
+	// Fallback to stale cache
+	if cached {
+		storage.SetSource(ctx, SourceStaleCache)
+		return entry.value, nil
+	}
+
+	return nil, err
+}
+
+// List delegates to the fallback container, upstream is not enumerated.
+func (e *engine) List(ctx context.Context, prefix string, req storage.PageRequest) (*storage.Page, error) {
+	return storage.List(ctx, e.fallback, prefix, req)
+}
+
+// OnChange registers a listener notified when upstream values are observed
+// to differ from the cached ones.
+func (e *engine) OnChange(listener func(storage.Change)) {
+	e.listenersMu.Lock()
+	defer e.listenersMu.Unlock()
+
+	e.listeners = append(e.listeners, listener)
+}
+
+// -----------------------------------------------------------------------------
+
+func (e *engine) notify(c storage.Change) {
+	e.listenersMu.RLock()
+	defer e.listenersMu.RUnlock()
+
+	for _, l := range e.listeners {
+		l(c)
+	}
+}
+
+func (e *engine) cached(id string) (cacheEntry, bool) {
+	e.cacheMu.RLock()
+	defer e.cacheMu.RUnlock()
+
+	entry, ok := e.cache[id]
+	return entry, ok
+}
+
+func (e *engine) store(id string, value []byte) {
+	e.cacheMu.Lock()
+	defer e.cacheMu.Unlock()
+
+	e.cache[id] = cacheEntry{
+		value:     value,
+		expiresAt: e.now().Add(e.ttl),
+	}
+}
+
+func (e *engine) evict(id string) {
+	e.cacheMu.Lock()
+	defer e.cacheMu.Unlock()
+
+	delete(e.cache, id)
+}
@@ -0,0 +1,207 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package hybrid
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/elastic/harp/pkg/server/storage"
+)
+
+var errUnavailable = errors.New("connection refused")
+
+// fakeEngine serves values from a map and fails when down.
+type fakeEngine struct {
+	values map[string]string
+	down   bool
+	calls  int
+}
+
+func (e *fakeEngine) Get(_ context.Context, id string) ([]byte, error) {
+	e.calls++
+	if e.down {
+		return nil, errUnavailable
+	}
+	v, ok := e.values[id]
+	if !ok {
+		return nil, storage.ErrSecretNotFound
+	}
+	return []byte(v), nil
+}
+
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time { return c.t }
+
+func get(t *testing.T, e storage.Engine, id string) (string, string, error) {
+	t.Helper()
+
+	ctx, source := storage.WithSource(context.Background())
+	v, err := e.Get(ctx, id)
+	return string(v), source.Get(), err
+}
+
+func TestEngine_Fallback(t *testing.T) {
+	c := &clock{t: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)}
+	up := &fakeEngine{values: map[string]string{"a": "vault-a", "b": "vault-b", "c": "vault-c"}}
+	fallback := &fakeEngine{values: map[string]string{"a": "bundle-a"}}
+	e := newEngine(up, fallback, Options{TTL: time.Minute, FailureThreshold: 2, Cooldown: 30 * time.Second}, c.now)
+
+	steps := []struct {
+		desc       string
+		advance    time.Duration
+		upDown     bool
+		id         string
+		wantValue  string
+		wantSource string
+		wantErr    error
+	}{
+		{desc: "upstream read", id: "a", wantValue: "vault-a", wantSource: SourceUpstream},
+		{desc: "upstream read b", id: "b", wantValue: "vault-b", wantSource: SourceUpstream},
+		{desc: "fresh cache", advance: 10 * time.Second, id: "a", wantValue: "vault-a", wantSource: SourceCache},
+		{desc: "upstream not found", id: "missing", wantErr: storage.ErrSecretNotFound, wantSource: SourceUpstream},
+		{desc: "upstream down, container wins over stale cache", advance: 2 * time.Minute, upDown: true, id: "a", wantValue: "bundle-a", wantSource: SourceContainer},
+		{desc: "upstream down, stale cache", upDown: true, id: "b", wantValue: "vault-b", wantSource: SourceStaleCache},
+		{desc: "circuit open, nothing available", upDown: true, id: "c", wantErr: storage.ErrSecretNotFound},
+		{desc: "cooldown expired, upstream recovered", advance: time.Minute, id: "c", wantValue: "vault-c", wantSource: SourceUpstream},
+	}
+	for _, s := range steps {
+		c.t = c.t.Add(s.advance)
+		up.down = s.upDown
+
+		got, source, err := get(t, e, s.id)
+		if s.wantErr != nil {
+			if !errors.Is(err, s.wantErr) {
+				t.Fatalf("%s: expected error %v, got %v", s.desc, s.wantErr, err)
+			}
+		} else if err != nil {
+			t.Fatalf("%s: unexpected error: %v", s.desc, err)
+		}
+		if got != s.wantValue {
+			t.Errorf("%s: expected value %q, got %q", s.desc, s.wantValue, got)
+		}
+		if source != s.wantSource {
+			t.Errorf("%s: expected source %q, got %q", s.desc, s.wantSource, source)
+		}
+	}
+}
+
+func TestEngine_CircuitBreaker(t *testing.T) {
+	c := &clock{t: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)}
+	up := &fakeEngine{values: map[string]string{"a": "vault-a"}, down: true}
+	fallback := &fakeEngine{values: map[string]string{"a": "bundle-a"}}
+	e := newEngine(up, fallback, Options{TTL: time.Minute, FailureThreshold: 3, Cooldown: 30 * time.Second}, c.now)
+
+	// Trip the breaker
+	for i := 0; i < 10; i++ {
+		if _, source, err := get(t, e, "a"); err != nil || source != SourceContainer {
+			t.Fatalf("container fallback expected, got %q, %v", source, err)
+		}
+	}
+	if up.calls != 3 {
+		t.Errorf("upstream must not be called once the circuit is open, got %d calls", up.calls)
+	}
+
+	// Half-open trial fails and reopens the circuit
+	c.t = c.t.Add(31 * time.Second)
+	get(t, e, "a")
+	get(t, e, "a")
+	if up.calls != 4 {
+		t.Errorf("a single trial call is expected, got %d calls", up.calls)
+	}
+
+	// Upstream recovery closes the circuit
+	up.down = false
+	c.t = c.t.Add(31 * time.Second)
+	if _, source, _ := get(t, e, "a"); source != SourceUpstream {
+		t.Errorf("upstream read expected after recovery, got %q", source)
+	}
+	c.t = c.t.Add(2 * time.Minute)
+	if _, source, _ := get(t, e, "a"); source != SourceUpstream {
+		t.Errorf("upstream read expected once closed, got %q", source)
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		query   string
+		wantErr bool
+	}{
+		{desc: "defaults", query: ""},
+		{desc: "valid", query: "ttl=1m&failure_threshold=5&cooldown=10s"},
+		{desc: "invalid ttl", query: "ttl=foo", wantErr: true},
+		{desc: "invalid threshold", query: "failure_threshold=0", wantErr: true},
+		{desc: "invalid cooldown", query: "cooldown=1", wantErr: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			u, _ := url.Parse("hybrid:///secret?" + tC.query)
+			_, err := parseOptions(u.Query())
+			if (err != nil) != tC.wantErr {
+				t.Errorf("error during the call, error = %v, wantErr %v", err, tC.wantErr)
+			}
+		})
+	}
+}
+
+func TestEngine_OnChange(t *testing.T) {
+	c := &clock{t: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)}
+	up := &fakeEngine{values: map[string]string{"app/a": "v1", "app/b": "v1"}}
+	e := newEngine(up, &fakeEngine{}, Options{TTL: time.Minute, FailureThreshold: 2, Cooldown: 30 * time.Second}, c.now)
+
+	changes := []storage.Change{}
+	e.OnChange(func(change storage.Change) {
+		changes = append(changes, change)
+	})
+
+	// First reads are not changes
+	for _, id := range []string{"app/a", "app/b"} {
+		if _, _, err := get(t, e, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Upstream changes are observed on cache refresh
+	up.values["app/a"] = "v2"
+	delete(up.values, "app/b")
+	c.t = c.t.Add(2 * time.Minute)
+	for _, id := range []string{"app/a", "app/b"} {
+		_, _, _ = get(t, e, id)
+	}
+
+	// Unchanged value
+	c.t = c.t.Add(2 * time.Minute)
+	if _, _, err := get(t, e, "app/a"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %v", changes)
+	}
+	if changes[0].ID != "/app/a" || changes[0].Type != storage.ChangeUpdated || changes[0].Digest != storage.ContentDigest([]byte("v2")).Value {
+		t.Errorf("unexpected update %v", changes[0])
+	}
+	if changes[1].ID != "/app/b" || changes[1].Type != storage.ChangeDeleted || changes[1].Digest != "" {
+		t.Errorf("unexpected deletion %v", changes[1])
+	}
+}
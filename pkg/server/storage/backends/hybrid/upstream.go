@@ -0,0 +1,58 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package hybrid
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/elastic/harp/pkg/server/storage"
+	"github.com/elastic/harp/pkg/vault/kv"
+)
+
+// upstream reads secrets from Vault using the same encoding as the vault
+// engine.
+type upstream struct {
+	service kv.SecretReader
+}
+
+func (u *upstream) Get(ctx context.Context, id string) ([]byte, error) {
+	// Read from Vault
+	secret, err := u.service.Read(ctx, id)
+	if errors.Is(err, kv.ErrPathNotFound) {
+		return []byte{}, storage.ErrSecretNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("hybrid: unable to read secret from vault server: %w", err)
+	}
+	if secret == nil {
+		return []byte{}, storage.ErrSecretNotFound
+	}
+
+	// Encode secret as json
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(secret); err != nil {
+		return nil, fmt.Errorf("hybrid: unable to encode secret: %w", err)
+	}
+
+	// Return secret
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,109 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package storage
+
+import (
+	"context"
+	"crypto/tls"
+
+	"github.com/elastic/harp/pkg/server/spiffe"
+)
+
+type clientKey struct{}
+
+// IdentityKind describes how a client identity has been authenticated.
+type IdentityKind int
+
+const (
+	// AnonymousIdentity is used for unauthenticated clients.
+	AnonymousIdentity IdentityKind = iota
+	// SPIFFEIdentity is the SPIFFE ID of a verified SVID, anchored in its
+	// trust domain bundle.
+	SPIFFEIdentity
+	// CommonNameIdentity is the subject common name of a verified client
+	// certificate without SPIFFE ID.
+	CommonNameIdentity
+)
+
+// Identity is an authenticated client identity.
+type Identity struct {
+	Kind IdentityKind
+	Name string
+}
+
+// SPIFFE returns a SPIFFE ID client identity.
+func SPIFFE(id string) Identity {
+	return Identity{Kind: SPIFFEIdentity, Name: id}
+}
+
+// CommonName returns a certificate common name client identity.
+func CommonName(name string) Identity {
+	return Identity{Kind: CommonNameIdentity, Name: name}
+}
+
+// SPIFFEID returns the client SPIFFE ID, or an empty string if the client is
+// not authenticated with a SVID.
+func (i Identity) SPIFFEID() string {
+	if i.Kind != SPIFFEIdentity {
+		return ""
+	}
+	return i.Name
+}
+
+// String returns the identity name.
+func (i Identity) String() string {
+	return i.Name
+}
+
+// WithClientIdentity returns a context holding the authenticated client
+// identity used to resolve response transformations.
+func WithClientIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, clientKey{}, identity)
+}
+
+// ClientIdentity returns the authenticated client identity, or an anonymous
+// identity.
+func ClientIdentity(ctx context.Context) Identity {
+	if id, ok := ctx.Value(clientKey{}).(Identity); ok {
+		return id
+	}
+	return Identity{}
+}
+
+// ClientIdentityFromTLS returns the SPIFFE ID of the verified client
+// certificate if any, its common name otherwise, or an anonymous identity if
+// the client is not authenticated.
+//
+// Common names are never considered as SPIFFE IDs, even if they look like
+// one, they are not checked against trust domain bundles.
+func ClientIdentityFromTLS(state *tls.ConnectionState) Identity {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return Identity{}
+	}
+
+	// Prefer SPIFFE ID from SVID
+	leaf := state.VerifiedChains[0][0]
+	if id, err := spiffe.IDFromCertificate(leaf); err == nil {
+		return SPIFFE(id)
+	}
+	if leaf.Subject.CommonName == "" {
+		return Identity{}
+	}
+
+	return CommonName(leaf.Subject.CommonName)
+}
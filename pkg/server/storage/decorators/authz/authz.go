@@ -0,0 +1,105 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/server/spiffe"
+	"github.com/elastic/harp/pkg/server/storage"
+)
+
+// SPIFFE returns a namespace authorization decorator allowing only clients
+// authenticated with a SPIFFE ID matched by one of the given patterns.
+func SPIFFE(namespace string, patterns []string) (func(storage.Engine) storage.Engine, error) {
+	// Check arguments
+	if len(patterns) == 0 {
+		return nil, errors.New("at least one SPIFFE ID pattern must be given")
+	}
+
+	// Compile patterns
+	matchers := make([]*spiffe.Pattern, 0, len(patterns))
+	for _, p := range patterns {
+		m, err := spiffe.ParsePattern(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed SPIFFE ID for backend '%s': %w", namespace, err)
+		}
+		matchers = append(matchers, m)
+	}
+
+	// Return decorator constructor
+	return func(engine storage.Engine) storage.Engine {
+		return &spiffeDecorator{
+			next:      engine,
+			namespace: namespace,
+			patterns:  matchers,
+		}
+	}, nil
+}
+
+// -----------------------------------------------------------------------------
+
+type spiffeDecorator struct {
+	next      storage.Engine
+	namespace string
+	patterns  []*spiffe.Pattern
+}
+
+func (d *spiffeDecorator) Get(ctx context.Context, id string) ([]byte, error) {
+	// Check client authorization
+	if err := d.authorize(ctx, id); err != nil {
+		return nil, err
+	}
+
+	// Delegate to original storage engine
+	return d.next.Get(ctx, id)
+}
+
+func (d *spiffeDecorator) List(ctx context.Context, prefix string, req storage.PageRequest) (*storage.Page, error) {
+	// Check client authorization
+	if err := d.authorize(ctx, prefix); err != nil {
+		return nil, err
+	}
+
+	// Delegate to original storage engine
+	return storage.List(ctx, d.next, prefix, req)
+}
+
+func (d *spiffeDecorator) authorize(ctx context.Context, id string) error {
+	identity := storage.ClientIdentity(ctx)
+
+	// Check client authorization, only SVID identities are matched
+	for _, p := range d.patterns {
+		if id := identity.SPIFFEID(); id != "" && p.Matches(id) {
+			return nil
+		}
+	}
+
+	log.For(ctx).Warn("Secret access denied",
+		zap.String("namespace", d.namespace),
+		zap.String("path", id),
+		zap.Stringer("client", identity),
+	)
+
+	return fmt.Errorf("client '%s' is not allowed to access '%s' namespace: %w", identity, d.namespace, storage.ErrAccessDenied)
+}
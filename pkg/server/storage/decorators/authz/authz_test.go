@@ -0,0 +1,91 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package authz
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/elastic/harp/pkg/server/storage"
+)
+
+type staticEngine map[string][]byte
+
+func (e staticEngine) Get(_ context.Context, id string) ([]byte, error) {
+	if v, ok := e[id]; ok {
+		return v, nil
+	}
+	return nil, storage.ErrSecretNotFound
+}
+
+func TestSPIFFE_Invalid(t *testing.T) {
+	if _, err := SPIFFE("secrets", nil); err == nil {
+		t.Error("expected an error for empty patterns")
+	}
+	if _, err := SPIFFE("secrets", []string{"https://example.org"}); err == nil {
+		t.Error("expected an error for invalid pattern")
+	}
+}
+
+func TestSPIFFE_Get(t *testing.T) {
+	d, err := SPIFFE("secrets", []string{
+		"spiffe://example.org/ns/prod/*",
+		"spiffe://partner.org",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	engine := d(staticEngine{
+		"/app/db": []byte(`{"password":"foo"}`),
+	})
+
+	testCases := []struct {
+		desc     string
+		identity storage.Identity
+		id       string
+		wantErr  error
+	}{
+		{desc: "prefix", identity: storage.SPIFFE("spiffe://example.org/ns/prod/sa/db"), id: "/app/db"},
+		{desc: "trust domain", identity: storage.SPIFFE("spiffe://partner.org/billing"), id: "/app/db"},
+		{desc: "not found", identity: storage.SPIFFE("spiffe://partner.org/billing"), id: "/app/missing", wantErr: storage.ErrSecretNotFound},
+		{desc: "denied", identity: storage.SPIFFE("spiffe://example.org/ns/dev/sa/db"), id: "/app/db", wantErr: storage.ErrAccessDenied},
+		{desc: "common name", identity: storage.CommonName("db"), id: "/app/db", wantErr: storage.ErrAccessDenied},
+		{desc: "common name SPIFFE ID", identity: storage.CommonName("spiffe://partner.org/billing"), id: "/app/db", wantErr: storage.ErrAccessDenied},
+		{desc: "anonymous", identity: storage.Identity{}, id: "/app/db", wantErr: storage.ErrAccessDenied},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctx := storage.WithClientIdentity(context.Background(), tC.identity)
+
+			got, err := engine.Get(ctx, tC.id)
+			if tC.wantErr != nil {
+				if !errors.Is(err, tC.wantErr) {
+					t.Fatalf("expected %v, got %v", tC.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != `{"password":"foo"}` {
+				t.Errorf("unexpected secret %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,289 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/elastic/harp/pkg/server/storage"
+)
+
+// DefaultMaxEntries is the cache size used when none is specified.
+const DefaultMaxEntries = 1024
+
+// fetchTimeout bounds the shared engine call of coalesced reads, it doesn't
+// depend on the request deadline of the first caller.
+const fetchTimeout = 30 * time.Second
+
+// ErrInvalidOptions is raised when cache settings are invalid.
+var ErrInvalidOptions = errors.New("cache: invalid options")
+
+// Options defines read cache settings.
+type Options struct {
+	// TTL defines the lifetime of a cached response.
+	TTL time.Duration
+	// MaxEntries defines the maximum number of cached responses, least
+	// recently used entries are evicted first.
+	MaxEntries int
+}
+
+// Validate the cache settings.
+func (o *Options) Validate() error {
+	if o.TTL <= 0 {
+		return fmt.Errorf("ttl must be strictly positive: %w", ErrInvalidOptions)
+	}
+	if o.MaxEntries < 0 {
+		return fmt.Errorf("max entries must not be negative: %w", ErrInvalidOptions)
+	}
+
+	// No error
+	return nil
+}
+
+// Stats holds cache usage counters.
+type Stats struct {
+	// Hits counts requests served from the cache.
+	Hits uint64
+	// Misses counts requests delegated to the storage engine.
+	Misses uint64
+	// Coalesced counts requests served by joining an in-flight engine call.
+	Coalesced uint64
+}
+
+// Decorator returns a read cache decorator for the given namespace.
+func Decorator(namespace string, opts Options) (func(storage.Engine) storage.Engine, error) {
+	// Check arguments
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Return decorator constructor
+	return func(engine storage.Engine) storage.Engine {
+		return New(namespace, engine, opts)
+	}, nil
+}
+
+// -----------------------------------------------------------------------------
+
+// Cache is a storage engine decorator which keeps the engine responses in
+// memory. Concurrent reads of the same missing path share a single engine
+// call.
+//
+// The cache is bound to the decorated engine instance, namespace reloads
+// build a new engine with an empty cache.
+type Cache struct {
+	next       storage.Engine
+	ttl        time.Duration
+	maxEntries int
+	clock      func() time.Time
+	group      singleflight.Group
+	metrics    *expvar.Map
+
+	hits      uint64
+	misses    uint64
+	coalesced uint64
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+type entry struct {
+	key     string
+	value   []byte
+	source  string
+	expires time.Time
+}
+
+// New wraps the given engine with a read cache.
+func New(namespace string, next storage.Engine, opts Options) *Cache {
+	maxEntries := opts.MaxEntries
+	if maxEntries == 0 {
+		maxEntries = DefaultMaxEntries
+	}
+
+	return &Cache{
+		next:       next,
+		ttl:        opts.TTL,
+		maxEntries: maxEntries,
+		clock:      time.Now,
+		metrics:    namespaceMetrics(namespace),
+		entries:    map[string]*list.Element{},
+		lru:        list.New(),
+	}
+}
+
+// Get returns the cached response or delegates to the decorated engine.
+func (c *Cache) Get(ctx context.Context, id string) ([]byte, error) {
+	// Lookup the cache
+	c.mu.Lock()
+	if el, ok := c.entries[id]; ok {
+		e := el.Value.(*entry)
+		if c.clock().Before(e.expires) {
+			c.lru.MoveToFront(el)
+			c.mu.Unlock()
+
+			c.count(&c.hits, "hits")
+			storage.SetSource(ctx, e.source)
+			return clone(e.value), nil
+		}
+
+		// Expired entry
+		c.remove(el)
+	}
+	c.mu.Unlock()
+
+	// Coalesce concurrent reads, the shared engine call is detached from the
+	// caller cancellation so that it can't fail the other callers.
+	leader := false
+	ch := c.group.DoChan(id, func() (interface{}, error) {
+		leader = true
+		c.count(&c.misses, "misses")
+
+		fctx, cancel := context.WithTimeout(detach(ctx), fetchTimeout)
+		defer cancel()
+
+		// Delegate to decorated engine
+		fctx, source := storage.WithSource(fctx)
+		out, err := c.next.Get(fctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		// Keep response
+		e := &entry{
+			key:     id,
+			value:   out,
+			source:  source.Get(),
+			expires: c.clock().Add(c.ttl),
+		}
+		c.store(e)
+
+		return e, nil
+	})
+
+	// Wait for the shared call or the caller cancellation
+	var res singleflight.Result
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res = <-ch:
+	}
+	if !leader {
+		c.count(&c.coalesced, "coalesced")
+	}
+	if res.Err != nil {
+		return nil, res.Err
+	}
+
+	// Return a private copy to the caller
+	e := res.Val.(*entry)
+	storage.SetSource(ctx, e.source)
+	return clone(e.value), nil
+}
+
+// List delegates to the decorated engine, listings are not cached.
+func (c *Cache) List(ctx context.Context, prefix string, req storage.PageRequest) (*storage.Page, error) {
+	return storage.List(ctx, c.next, prefix, req)
+}
+
+// Stats returns the cache usage counters.
+func (c *Cache) Stats() Stats {
+	return Stats{
+		Hits:      atomic.LoadUint64(&c.hits),
+		Misses:    atomic.LoadUint64(&c.misses),
+		Coalesced: atomic.LoadUint64(&c.coalesced),
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+func (c *Cache) store(e *entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Replace existing entry
+	if el, ok := c.entries[e.key]; ok {
+		c.remove(el)
+	}
+	c.entries[e.key] = c.lru.PushFront(e)
+
+	// Evict least recently used entries
+	for c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *Cache) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.entries, el.Value.(*entry).key)
+}
+
+func (c *Cache) count(counter *uint64, name string) {
+	atomic.AddUint64(counter, 1)
+	c.metrics.Add(name, 1)
+}
+
+// detachedContext keeps the parent context values without its cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func detach(ctx context.Context) context.Context {
+	return detachedContext{parent: ctx}
+}
+
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (d detachedContext) Value(key interface{}) interface{} { return d.parent.Value(key) }
+
+func clone(in []byte) []byte {
+	out := make([]byte, len(in))
+	copy(out, in)
+	return out
+}
+
+// -----------------------------------------------------------------------------
+
+var (
+	metricsMu sync.Mutex
+	metrics   = expvar.NewMap("harp_server_read_cache")
+)
+
+func namespaceMetrics(namespace string) *expvar.Map {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+
+	if m, ok := metrics.Get(namespace).(*expvar.Map); ok {
+		return m
+	}
+
+	m := new(expvar.Map).Init()
+	metrics.Set(namespace, m)
+	return m
+}
@@ -0,0 +1,320 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+	"github.com/elastic/harp/pkg/bundle/vfs"
+	"github.com/elastic/harp/pkg/server/storage"
+)
+
+type countingEngine struct {
+	calls   int64
+	source  string
+	release chan struct{}
+	values  map[string][]byte
+}
+
+func (e *countingEngine) Get(ctx context.Context, id string) ([]byte, error) {
+	atomic.AddInt64(&e.calls, 1)
+	if e.release != nil {
+		select {
+		case <-e.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	storage.SetSource(ctx, e.source)
+	if v, ok := e.values[id]; ok {
+		return v, nil
+	}
+	return nil, storage.ErrSecretNotFound
+}
+
+func (e *countingEngine) Calls() int64 {
+	return atomic.LoadInt64(&e.calls)
+}
+
+func TestOptions_Validate(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		opts    Options
+		wantErr bool
+	}{
+		{desc: "blank", wantErr: true},
+		{desc: "negative ttl", opts: Options{TTL: -time.Second}, wantErr: true},
+		{desc: "negative max entries", opts: Options{TTL: time.Second, MaxEntries: -1}, wantErr: true},
+		{desc: "default max entries", opts: Options{TTL: time.Second}},
+		{desc: "valid", opts: Options{TTL: time.Second, MaxEntries: 10}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			err := tC.opts.Validate()
+			if (err != nil) != tC.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tC.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidOptions) {
+				t.Errorf("error should wrap ErrInvalidOptions, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCache_Get(t *testing.T) {
+	ctx := context.Background()
+	next := &countingEngine{
+		source: "primary",
+		values: map[string][]byte{
+			"app/a": []byte(`{"user":"a"}`),
+			"app/b": []byte(`{"user":"b"}`),
+			"app/c": []byte(`{"user":"c"}`),
+		},
+	}
+
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := New("test-get", next, Options{TTL: time.Minute, MaxEntries: 2})
+	c.clock = func() time.Time { return now }
+
+	// First read is delegated, second one is cached
+	for i := 0; i < 2; i++ {
+		sctx, source := storage.WithSource(ctx)
+		got, err := c.Get(sctx, "app/a")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if diff := cmp.Diff(string(got), `{"user":"a"}`); diff != "" {
+			t.Errorf("%q. Get():\n-got/+want\ndiff %s", "read", diff)
+		}
+		if source.Get() != "primary" {
+			t.Errorf("source should be propagated, got %q", source.Get())
+		}
+
+		// Caller mutation must not alter the cache
+		got[0] = 'X'
+	}
+	if next.Calls() != 1 {
+		t.Errorf("engine should be called once, got %d", next.Calls())
+	}
+
+	// Errors are never cached
+	for i := 0; i < 2; i++ {
+		if _, err := c.Get(ctx, "app/missing"); !errors.Is(err, storage.ErrSecretNotFound) {
+			t.Errorf("expected not found error, got %v", err)
+		}
+	}
+	if next.Calls() != 3 {
+		t.Errorf("errors should not be cached, got %d calls", next.Calls())
+	}
+
+	// Expired entry is refreshed
+	now = now.Add(2 * time.Minute)
+	if _, err := c.Get(ctx, "app/a"); err != nil {
+		t.Fatal(err)
+	}
+	if next.Calls() != 4 {
+		t.Errorf("expired entry should be refreshed, got %d calls", next.Calls())
+	}
+
+	// Least recently used entry is evicted
+	if _, err := c.Get(ctx, "app/b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, "app/c"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, "app/a"); err != nil {
+		t.Fatal(err)
+	}
+	if next.Calls() != 7 {
+		t.Errorf("least recently used entry should be evicted, got %d calls", next.Calls())
+	}
+
+	want := Stats{Hits: 1, Misses: 7}
+	if diff := cmp.Diff(c.Stats(), want); diff != "" {
+		t.Errorf("%q. Stats():\n-got/+want\ndiff %s", "counters", diff)
+	}
+}
+
+func TestCache_Coalescing(t *testing.T) {
+	next := &countingEngine{
+		release: make(chan struct{}),
+		values: map[string][]byte{
+			"app/a": []byte(`{"user":"a"}`),
+		},
+	}
+	c := New("test-coalescing", next, Options{TTL: time.Minute})
+
+	const clients = 16
+	var (
+		wg      sync.WaitGroup
+		started sync.WaitGroup
+		errs    = make(chan error, clients)
+	)
+	started.Add(clients)
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			started.Done()
+			_, err := c.Get(context.Background(), "app/a")
+			errs <- err
+		}()
+	}
+
+	// Wait for all clients to be blocked on the leader call
+	started.Wait()
+	for atomic.LoadInt64(&next.calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(next.release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if next.Calls() != 1 {
+		t.Errorf("concurrent reads should share one engine call, got %d", next.Calls())
+	}
+	stats := c.Stats()
+	if stats.Misses != 1 || stats.Hits+stats.Coalesced != clients-1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestCache_CancelledLeader(t *testing.T) {
+	next := &countingEngine{
+		release: make(chan struct{}),
+		values: map[string][]byte{
+			"app/a": []byte(`{"user":"a"}`),
+		},
+	}
+	c := New("test-cancelled-leader", next, Options{TTL: time.Minute})
+
+	// Leader request is cancelled while the engine call is in flight
+	ctx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := c.Get(ctx, "app/a")
+		leaderErr <- err
+	}()
+	for next.Calls() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Follower joins the in-flight call
+	followerErr := make(chan error, 1)
+	go func() {
+		_, err := c.Get(context.Background(), "app/a")
+		followerErr <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	cancel()
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected leader cancellation, got %v", err)
+	}
+
+	// Shared call completes for the follower
+	close(next.release)
+	if err := <-followerErr; err != nil {
+		t.Fatalf("follower must not fail with the leader cancellation: %v", err)
+	}
+	if next.Calls() != 1 {
+		t.Errorf("follower should join the leader call, got %d calls", next.Calls())
+	}
+
+	// Response is cached
+	if _, err := c.Get(context.Background(), "app/a"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if next.Calls() != 1 {
+		t.Errorf("response should be cached, got %d calls", next.Calls())
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+type fsEngine struct {
+	fs afero.Fs
+}
+
+func (e *fsEngine) Get(_ context.Context, id string) ([]byte, error) {
+	return afero.ReadFile(e.fs, id)
+}
+
+func benchmarkEngine(b *testing.B) storage.Engine {
+	bundle := testbundle.New().
+		Package("app/production/database/credentials").
+		Secret("user", "app").
+		Secret("password", "very-secret-password").
+		Secret("host", "db.internal").
+		Secret("port", "5432").
+		Build()
+
+	fs, err := vfs.FromBundle(bundle)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return &fsEngine{fs: fs}
+}
+
+func BenchmarkGet(b *testing.B) {
+	ctx := context.Background()
+	id := "/app/production/database/credentials"
+
+	b.Run("uncached", func(b *testing.B) {
+		engine := benchmarkEngine(b)
+		b.ReportAllocs()
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if _, err := engine.Get(ctx, id); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	})
+
+	b.Run("cached", func(b *testing.B) {
+		engine := New("benchmark", benchmarkEngine(b), Options{TTL: time.Minute})
+		b.ReportAllocs()
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if _, err := engine.Get(ctx, id); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	})
+}
@@ -0,0 +1,214 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package mask
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/server/storage"
+)
+
+// Action defines the transformation applied to a secret field.
+type Action string
+
+const (
+	// ActionDrop removes the field from the response.
+	ActionDrop Action = "drop"
+	// ActionMask replaces the field value by a fixed placeholder.
+	ActionMask Action = "mask"
+	// ActionHash replaces the field value by its SHA256 digest.
+	ActionHash Action = "hash"
+)
+
+// Placeholder is the value used to replace masked fields.
+const Placeholder = "********"
+
+// ErrInvalidProfile is raised when a transformation profile is invalid.
+var ErrInvalidProfile = errors.New("mask: invalid transformation profile")
+
+// FieldRule associates a secret field to an action.
+type FieldRule struct {
+	Field  string
+	Action Action
+}
+
+// Profile describes the transformation applied to responses served to
+// matching clients.
+type Profile struct {
+	Name    string
+	Clients []string
+	Fields  []FieldRule
+}
+
+// Validate the profile settings.
+func (p *Profile) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("profile name must not be blank: %w", ErrInvalidProfile)
+	}
+	if len(p.Clients) == 0 {
+		return fmt.Errorf("profile '%s' must match at least one client: %w", p.Name, ErrInvalidProfile)
+	}
+	for _, c := range p.Clients {
+		if _, err := path.Match(c, ""); err != nil {
+			return fmt.Errorf("profile '%s' has an invalid client matcher '%s': %w", p.Name, c, ErrInvalidProfile)
+		}
+	}
+	for _, f := range p.Fields {
+		if f.Field == "" {
+			return fmt.Errorf("profile '%s' has a blank field name: %w", p.Name, ErrInvalidProfile)
+		}
+		switch f.Action {
+		case ActionDrop, ActionMask, ActionHash:
+		default:
+			return fmt.Errorf("profile '%s' has an unknown action '%s' for field '%s': %w", p.Name, f.Action, f.Field, ErrInvalidProfile)
+		}
+	}
+
+	// No error
+	return nil
+}
+
+// Matches returns true if the given client identity is matched by the
+// profile.
+func (p *Profile) Matches(identity string) bool {
+	for _, c := range p.Clients {
+		if ok, _ := path.Match(c, identity); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Transformer returns a response transformation decorator. Profiles are
+// evaluated in order and the first one matching the client identity is
+// applied.
+func Transformer(namespace string, profiles []Profile) (func(storage.Engine) storage.Engine, error) {
+	// Check arguments
+	for i := range profiles {
+		if err := profiles[i].Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Return decorator constructor
+	return func(engine storage.Engine) storage.Engine {
+		return &maskDecorator{
+			next:      engine,
+			namespace: namespace,
+			profiles:  profiles,
+		}
+	}, nil
+}
+
+// -----------------------------------------------------------------------------
+
+type maskDecorator struct {
+	next      storage.Engine
+	namespace string
+	profiles  []Profile
+}
+
+func (d *maskDecorator) Get(ctx context.Context, id string) ([]byte, error) {
+	// Delegate to original storage engine
+	secret, err := d.next.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve profile
+	identity := storage.ClientIdentity(ctx)
+	profile := d.resolve(identity.Name)
+	if profile == nil {
+		return secret, nil
+	}
+
+	// Apply transformations
+	out, err := apply(profile, secret)
+	if err != nil {
+		return nil, fmt.Errorf("unable to apply '%s' transformation profile: %w", profile.Name, err)
+	}
+
+	log.For(ctx).Info("Response transformation applied",
+		zap.String("namespace", d.namespace),
+		zap.String("path", id),
+		zap.Stringer("client", identity),
+		zap.String("profile", profile.Name),
+	)
+
+	// No error
+	return out, nil
+}
+
+func (d *maskDecorator) List(ctx context.Context, prefix string, req storage.PageRequest) (*storage.Page, error) {
+	return storage.List(ctx, d.next, prefix, req)
+}
+
+func (d *maskDecorator) resolve(identity string) *Profile {
+	for i := range d.profiles {
+		if d.profiles[i].Matches(identity) {
+			return &d.profiles[i]
+		}
+	}
+	return nil
+}
+
+func apply(profile *Profile, secret []byte) ([]byte, error) {
+	// Secret must be a JSON object to be transformed
+	var data map[string]interface{}
+	if err := json.Unmarshal(secret, &data); err != nil {
+		return nil, fmt.Errorf("unable to decode secret as a JSON object: %w", err)
+	}
+
+	for _, f := range profile.Fields {
+		v, ok := data[f.Field]
+		if !ok {
+			continue
+		}
+
+		switch f.Action {
+		case ActionDrop:
+			delete(data, f.Field)
+		case ActionMask:
+			data[f.Field] = Placeholder
+		case ActionHash:
+			raw, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("unable to encode field '%s': %w", f.Field, err)
+			}
+			if s, ok := v.(string); ok {
+				raw = []byte(s)
+			}
+			h := sha256.Sum256(raw)
+			data[f.Field] = fmt.Sprintf("sha256:%s", hex.EncodeToString(h[:]))
+		default:
+			return nil, fmt.Errorf("unknown action '%s' for field '%s': %w", f.Action, f.Field, ErrInvalidProfile)
+		}
+	}
+
+	// Encode result
+	return json.Marshal(data)
+}
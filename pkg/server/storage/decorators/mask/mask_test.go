@@ -0,0 +1,193 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package mask
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/elastic/harp/pkg/server/storage"
+)
+
+type staticEngine map[string][]byte
+
+func (e staticEngine) Get(_ context.Context, id string) ([]byte, error) {
+	if v, ok := e[id]; ok {
+		return v, nil
+	}
+	return nil, storage.ErrSecretNotFound
+}
+
+func TestProfile_Validate(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		profile Profile
+		wantErr bool
+	}{
+		{
+			desc:    "blank name",
+			profile: Profile{Clients: []string{"*"}},
+			wantErr: true,
+		},
+		{
+			desc:    "no client",
+			profile: Profile{Name: "analytics"},
+			wantErr: true,
+		},
+		{
+			desc:    "invalid client matcher",
+			profile: Profile{Name: "analytics", Clients: []string{"["}},
+			wantErr: true,
+		},
+		{
+			desc: "unknown action",
+			profile: Profile{Name: "analytics", Clients: []string{"*"}, Fields: []FieldRule{
+				{Field: "password", Action: "encrypt"},
+			}},
+			wantErr: true,
+		},
+		{
+			desc: "valid",
+			profile: Profile{Name: "analytics", Clients: []string{"analytics-*"}, Fields: []FieldRule{
+				{Field: "password", Action: ActionDrop},
+				{Field: "user", Action: ActionMask},
+				{Field: "token", Action: ActionHash},
+			}},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			err := tC.profile.Validate()
+			if (err != nil) != tC.wantErr {
+				t.Fatalf("error during the call, error = %v, wantErr %v", err, tC.wantErr)
+			}
+			if tC.wantErr && !errors.Is(err, ErrInvalidProfile) {
+				t.Errorf("expected ErrInvalidProfile, got %v", err)
+			}
+		})
+	}
+}
+
+func TestTransformer_UnknownAction(t *testing.T) {
+	_, err := Transformer("production", []Profile{
+		{Name: "broken", Clients: []string{"*"}, Fields: []FieldRule{{Field: "password", Action: "reveal"}}},
+	})
+	if !errors.Is(err, ErrInvalidProfile) {
+		t.Fatalf("expected ErrInvalidProfile, got %v", err)
+	}
+}
+
+func TestTransformer_ClientViews(t *testing.T) {
+	engine := staticEngine{
+		"/app/database": []byte(`{"host":"db.local","port":5432,"user":"admin","password":"Zq8#vL2!pX9@kR4"}`),
+		"/app/raw":      []byte(`not-json`),
+	}
+
+	decorator, err := Transformer("production", []Profile{
+		{
+			Name:    "analytics",
+			Clients: []string{"analytics.*"},
+			Fields: []FieldRule{
+				{Field: "password", Action: ActionDrop},
+				{Field: "user", Action: ActionMask},
+			},
+		},
+		{
+			Name:    "audit",
+			Clients: []string{"auditor"},
+			Fields: []FieldRule{
+				{Field: "password", Action: ActionHash},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	e := decorator(engine)
+
+	testCases := []struct {
+		desc     string
+		identity string
+		id       string
+		want     map[string]interface{}
+		wantErr  bool
+	}{
+		{
+			desc:     "unmatched client",
+			identity: "backend.internal",
+			id:       "/app/database",
+			want: map[string]interface{}{
+				"host": "db.local", "port": float64(5432), "user": "admin", "password": "Zq8#vL2!pX9@kR4",
+			},
+		},
+		{
+			desc:     "analytics client",
+			identity: "analytics.internal",
+			id:       "/app/database",
+			want: map[string]interface{}{
+				"host": "db.local", "port": float64(5432), "user": Placeholder,
+			},
+		},
+		{
+			desc:     "auditor client",
+			identity: "auditor",
+			id:       "/app/database",
+			want: map[string]interface{}{
+				"host": "db.local", "port": float64(5432), "user": "admin",
+				"password": "sha256:3d3a2f49de6a51680e8e9982c787fdc315bfb19df65be63b5a2a6a701d76be3f",
+			},
+		},
+		{
+			desc:     "non JSON secret",
+			identity: "analytics.internal",
+			id:       "/app/raw",
+			wantErr:  true,
+		},
+		{
+			desc:     "not found",
+			identity: "analytics.internal",
+			id:       "/app/missing",
+			wantErr:  true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctx := storage.WithClientIdentity(context.Background(), storage.CommonName(tC.identity))
+
+			out, err := e.Get(ctx, tC.id)
+			if (err != nil) != tC.wantErr {
+				t.Fatalf("error during the call, error = %v, wantErr %v", err, tC.wantErr)
+			}
+			if tC.wantErr {
+				return
+			}
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(out, &got); err != nil {
+				t.Fatalf("unable to decode response: %v", err)
+			}
+			if diff := cmp.Diff(got, tC.want); diff != "" {
+				t.Errorf("%q. Get():\n-got/+want\ndiff %s", tC.desc, diff)
+			}
+		})
+	}
+}
@@ -59,3 +59,7 @@ func (d *transformerDecorator) Get(ctx context.Context, id string) ([]byte, erro
 	// Delegate to transformer
 	return d.transformer.To(ctx, secret)
 }
+
+func (d *transformerDecorator) List(ctx context.Context, prefix string, req storage.PageRequest) (*storage.Page, error) {
+	return storage.List(ctx, d.next, prefix, req)
+}
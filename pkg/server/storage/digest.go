@@ -0,0 +1,84 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package storage
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Digest describes the content digest of a secret.
+type Digest struct {
+	Value   string `json:"digest"`
+	Version uint32 `json:"version,omitempty"`
+}
+
+// ETag returns the digest as a strong HTTP entity tag.
+func (d *Digest) ETag() string {
+	return fmt.Sprintf("%q", d.Value)
+}
+
+// DigestEngine is implemented by engines able to compute a secret digest
+// without reading the secret value.
+type DigestEngine interface {
+	Digest(ctx context.Context, id string) (*Digest, error)
+}
+
+// ContentDigest returns the digest of a secret value.
+func ContentDigest(value []byte) *Digest {
+	h := blake2b.Sum256(value)
+	return &Digest{
+		Value: hex.EncodeToString(h[:]),
+	}
+}
+
+// GetDigest returns the secret digest from the engine if supported, or
+// computed from the secret value.
+func GetDigest(ctx context.Context, e Engine, id string) (*Digest, error) {
+	if de, ok := e.(DigestEngine); ok {
+		return de.Digest(ctx, id)
+	}
+
+	value, err := e.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return ContentDigest(value), nil
+}
+
+// MatchETag returns true when the If-None-Match header value matches the
+// given entity tag.
+func MatchETag(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		// Weak comparison
+		if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
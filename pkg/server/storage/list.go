@@ -0,0 +1,166 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// NextCursorHeader is the response header used to expose the cursor of
+	// the next listing page.
+	NextCursorHeader = "X-Harp-Next-Cursor"
+	// MaxPageSize is the maximum allowed listing page size.
+	MaxPageSize = 10000
+)
+
+var (
+	// ErrListNotSupported is raised when the engine can't enumerate secrets.
+	ErrListNotSupported = errors.New("engine: listing not supported")
+	// ErrInvalidCursor is raised when the pagination cursor is malformed.
+	ErrInvalidCursor = errors.New("engine: invalid cursor")
+	// ErrInvalidLimit is raised when the page size is out of bounds.
+	ErrInvalidLimit = errors.New("engine: invalid page limit")
+)
+
+// PageRequest describes a listing page, a zero Limit returns all entries.
+type PageRequest struct {
+	After string
+	Limit int
+}
+
+// Page is a lexicographically ordered listing page.
+type Page struct {
+	Keys []string
+	// Next is the cursor of the next page, empty for the last page.
+	Next string
+}
+
+// ListEngine is implemented by engines able to enumerate secret identifiers.
+type ListEngine interface {
+	List(ctx context.Context, prefix string, req PageRequest) (*Page, error)
+}
+
+// List delegates to the engine when listing is supported.
+func List(ctx context.Context, e Engine, prefix string, req PageRequest) (*Page, error) {
+	le, ok := e.(ListEngine)
+	if !ok {
+		return nil, ErrListNotSupported
+	}
+
+	return le.List(ctx, prefix, req)
+}
+
+// PageRequestFromQuery builds a page request from `limit` and `after` query
+// parameters. Pagination is disabled when both are missing.
+func PageRequestFromQuery(q url.Values) (PageRequest, error) {
+	req := PageRequest{
+		After: q.Get("after"),
+	}
+
+	if raw := q.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > MaxPageSize {
+			return req, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidLimit, MaxPageSize)
+		}
+		req.Limit = limit
+	}
+
+	// No error
+	return req, nil
+}
+
+// Paginate returns the immediate children of the prefix from the given
+// sorted identifiers. Children containing deeper identifiers are suffixed
+// with "/", as Vault does.
+//
+// The cursor is the last child returned, it remains valid as long as the
+// identifier index doesn't change, whatever the engine instance.
+func Paginate(sorted []string, prefix string, req PageRequest) (*Page, error) {
+	// Check arguments
+	if req.Limit < 0 || req.Limit > MaxPageSize {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidLimit, MaxPageSize)
+	}
+	if err := validateCursor(req.After); err != nil {
+		return nil, err
+	}
+
+	// Ensure directory prefix
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	// Find the first candidate
+	start := sort.SearchStrings(sorted, prefix)
+	if req.After != "" {
+		from := prefix + req.After
+		if strings.HasSuffix(from, "/") {
+			// Skip the whole directory, '0' is the next character after '/'
+			from = strings.TrimSuffix(from, "/") + "0"
+		} else {
+			from += "\x00"
+		}
+		start = sort.SearchStrings(sorted, from)
+	}
+
+	page := &Page{
+		Keys: []string{},
+	}
+	for i := start; i < len(sorted) && strings.HasPrefix(sorted[i], prefix); i++ {
+		child := strings.TrimPrefix(sorted[i], prefix)
+		if idx := strings.Index(child, "/"); idx >= 0 {
+			child = child[:idx+1]
+		}
+
+		// Deduplicate directory entries
+		if n := len(page.Keys); n > 0 && page.Keys[n-1] == child {
+			continue
+		}
+
+		// Page is full
+		if req.Limit > 0 && len(page.Keys) == req.Limit {
+			page.Next = page.Keys[len(page.Keys)-1]
+			break
+		}
+
+		page.Keys = append(page.Keys, child)
+	}
+
+	// No error
+	return page, nil
+}
+
+func validateCursor(cursor string) error {
+	if cursor == "" {
+		return nil
+	}
+
+	// Cursor must be an immediate child name
+	name := strings.TrimSuffix(cursor, "/")
+	if name == "" || strings.Contains(name, "/") || strings.ContainsRune(name, 0) {
+		return fmt.Errorf("%w: '%s' is not a child identifier", ErrInvalidCursor, cursor)
+	}
+
+	return nil
+}
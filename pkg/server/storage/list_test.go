@@ -0,0 +1,136 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+var listIndex = []string{
+	"/app/a",
+	"/app/a-b",
+	"/app/a/x",
+	"/app/a/y/z",
+	"/app/b",
+	"/app/c/d",
+	"/infra/vault",
+}
+
+func TestPaginate(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		prefix   string
+		req      PageRequest
+		wantKeys []string
+		wantNext string
+		wantErr  error
+	}{
+		{desc: "root", prefix: "/", wantKeys: []string{"app/", "infra/"}},
+		{desc: "unpaginated", prefix: "/app", wantKeys: []string{"a", "a-b", "a/", "b", "c/"}},
+		{desc: "first page", prefix: "/app/", req: PageRequest{Limit: 2}, wantKeys: []string{"a", "a-b"}, wantNext: "a-b"},
+		{desc: "second page", prefix: "/app/", req: PageRequest{Limit: 2, After: "a-b"}, wantKeys: []string{"a/", "b"}, wantNext: "b"},
+		{desc: "last page", prefix: "/app/", req: PageRequest{Limit: 2, After: "b"}, wantKeys: []string{"c/"}},
+		{desc: "exact last page", prefix: "/app/", req: PageRequest{Limit: 1, After: "b"}, wantKeys: []string{"c/"}},
+		{desc: "after directory", prefix: "/app/", req: PageRequest{After: "a/"}, wantKeys: []string{"b", "c/"}},
+		{desc: "after missing key", prefix: "/app/", req: PageRequest{After: "a0"}, wantKeys: []string{"b", "c/"}},
+		{desc: "after last key", prefix: "/app/", req: PageRequest{After: "c/"}, wantKeys: []string{}},
+		{desc: "unknown prefix", prefix: "/unknown/", wantKeys: []string{}},
+		{desc: "nested cursor", prefix: "/app/", req: PageRequest{After: "a/x"}, wantErr: ErrInvalidCursor},
+		{desc: "absolute cursor", prefix: "/app/", req: PageRequest{After: "/a"}, wantErr: ErrInvalidCursor},
+		{desc: "negative limit", prefix: "/app/", req: PageRequest{Limit: -1}, wantErr: ErrInvalidLimit},
+		{desc: "too large limit", prefix: "/app/", req: PageRequest{Limit: MaxPageSize + 1}, wantErr: ErrInvalidLimit},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			page, err := Paginate(listIndex, tC.prefix, tC.req)
+			if !errors.Is(err, tC.wantErr) {
+				t.Fatalf("Paginate() error = %v, want %v", err, tC.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(page.Keys, tC.wantKeys) {
+				t.Errorf("Paginate() keys = %v, want %v", page.Keys, tC.wantKeys)
+			}
+			if page.Next != tC.wantNext {
+				t.Errorf("Paginate() next = %q, want %q", page.Next, tC.wantNext)
+			}
+		})
+	}
+}
+
+func TestPaginate_Walk(t *testing.T) {
+	index := make([]string, 0, 2500)
+	for i := 0; i < 2500; i++ {
+		index = append(index, fmt.Sprintf("/ns/pkg-%05d", i))
+	}
+
+	var (
+		req   = PageRequest{Limit: 1000}
+		seen  = []string{}
+		pages = 0
+	)
+	for {
+		page, err := Paginate(index, "/ns", req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		pages++
+		seen = append(seen, page.Keys...)
+		if page.Next == "" {
+			break
+		}
+		req.After = page.Next
+	}
+
+	if pages != 3 {
+		t.Errorf("expected 3 pages, got %d", pages)
+	}
+	if len(seen) != len(index) {
+		t.Fatalf("expected %d keys, got %d", len(index), len(seen))
+	}
+	for i, k := range seen {
+		if "/ns/"+k != index[i] {
+			t.Fatalf("unexpected key %q at %d", k, i)
+		}
+	}
+}
+
+func TestPageRequestFromQuery(t *testing.T) {
+	req, err := PageRequestFromQuery(url.Values{"limit": {"10"}, "after": {"foo"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Limit != 10 || req.After != "foo" {
+		t.Errorf("unexpected request %+v", req)
+	}
+
+	if req, err := PageRequestFromQuery(url.Values{}); err != nil || req.Limit != 0 {
+		t.Errorf("pagination should be disabled by default, got %+v, %v", req, err)
+	}
+
+	for _, invalid := range []string{"0", "-1", "abc", "10001"} {
+		if _, err := PageRequestFromQuery(url.Values{"limit": {invalid}}); !errors.Is(err, ErrInvalidLimit) {
+			t.Errorf("expected invalid limit error for %q, got %v", invalid, err)
+		}
+	}
+}
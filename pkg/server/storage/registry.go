@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"sort"
 	"sync"
 )
 
@@ -57,6 +58,16 @@ func MustRegister(name string, factory EngineFactoryFunc) {
 	}
 }
 
+// Schemes returns the sorted URL schemes of registered storage engines.
+func Schemes() []string {
+	res := make([]string, 0, len(engines))
+	for name := range engines {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
 // Build an engine instance with given URL.
 func Build(uri string) (Engine, error) {
 	// Parse URL
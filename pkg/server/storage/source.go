@@ -0,0 +1,58 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package storage
+
+import (
+	"context"
+	"sync"
+)
+
+// SourceHeader is the response header used to expose the secret data source.
+const SourceHeader = "X-Harp-Source"
+
+type sourceKey struct{}
+
+// Source holds the data source reported by an engine for a request.
+type Source struct {
+	mu    sync.Mutex
+	value string
+}
+
+// Get returns the reported data source.
+func (s *Source) Get() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.value
+}
+
+// WithSource returns a context used to collect the data source reported by
+// the engine.
+func WithSource(ctx context.Context) (context.Context, *Source) {
+	s := &Source{}
+	return context.WithValue(ctx, sourceKey{}, s), s
+}
+
+// SetSource reports the data source used to serve the request, if the
+// caller is interested.
+func SetSource(ctx context.Context, value string) {
+	if s, ok := ctx.Value(sourceKey{}).(*Source); ok {
+		s.mu.Lock()
+		s.value = value
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,139 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package storage
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// ChangeType describes a secret change kind.
+type ChangeType string
+
+const (
+	// ChangeCreated is raised when a secret is added.
+	ChangeCreated ChangeType = "created"
+	// ChangeUpdated is raised when a secret content is modified.
+	ChangeUpdated ChangeType = "updated"
+	// ChangeDeleted is raised when a secret is removed.
+	ChangeDeleted ChangeType = "deleted"
+)
+
+// Change describes a secret change, it never carries the secret value.
+type Change struct {
+	ID   string
+	Type ChangeType
+	// Digest is the new secret digest, blank for deletions.
+	Digest string
+}
+
+// ChangeNotifier is implemented by engines observing secret changes by
+// themselves, such as upstream backed engines.
+type ChangeNotifier interface {
+	OnChange(func(Change))
+}
+
+// Snapshot returns the digests of all secrets listed by the engine under the
+// given prefix, indexed by absolute secret identifier.
+func Snapshot(ctx context.Context, e Engine, prefix string) (map[string]string, error) {
+	res := map[string]string{}
+	if err := snapshot(ctx, e, DirPrefix(prefix), res); err != nil {
+		return nil, err
+	}
+
+	// No error
+	return res, nil
+}
+
+// Diff returns the changes between two snapshots, sorted by identifier.
+func Diff(before, after map[string]string) []Change {
+	changes := []Change{}
+
+	for id, digest := range after {
+		previous, ok := before[id]
+		switch {
+		case !ok:
+			changes = append(changes, Change{ID: id, Type: ChangeCreated, Digest: digest})
+		case previous != digest:
+			changes = append(changes, Change{ID: id, Type: ChangeUpdated, Digest: digest})
+		default:
+		}
+	}
+	for id := range before {
+		if _, ok := after[id]; !ok {
+			changes = append(changes, Change{ID: id, Type: ChangeDeleted})
+		}
+	}
+
+	// Ensure stable order
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].ID < changes[j].ID
+	})
+
+	return changes
+}
+
+// ChangeID returns the canonical identifier of a secret, as an absolute path.
+func ChangeID(id string) string {
+	return "/" + strings.TrimPrefix(id, "/")
+}
+
+// DirPrefix returns the given prefix as an absolute directory.
+func DirPrefix(prefix string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return "/"
+	}
+	return "/" + prefix + "/"
+}
+
+// -----------------------------------------------------------------------------
+
+func snapshot(ctx context.Context, e Engine, dir string, res map[string]string) error {
+	req := PageRequest{}
+	for {
+		// Retrieve immediate children
+		page, err := List(ctx, e, dir, req)
+		if err != nil {
+			return err
+		}
+
+		for _, child := range page.Keys {
+			// Walk sub-directories
+			if strings.HasSuffix(child, "/") {
+				if err := snapshot(ctx, e, dir+child, res); err != nil {
+					return err
+				}
+				continue
+			}
+
+			// Retrieve digest
+			d, err := GetDigest(ctx, e, dir+child)
+			if err != nil {
+				return err
+			}
+			res[dir+child] = d.Value
+		}
+
+		if page.Next == "" {
+			return nil
+		}
+		req.After = page.Next
+	}
+}
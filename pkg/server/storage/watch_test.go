@@ -0,0 +1,81 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package storage
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+type mapEngine map[string]string
+
+func (e mapEngine) Get(_ context.Context, id string) ([]byte, error) {
+	v, ok := e[id]
+	if !ok {
+		return nil, ErrSecretNotFound
+	}
+	return []byte(v), nil
+}
+
+func (e mapEngine) List(_ context.Context, prefix string, req PageRequest) (*Page, error) {
+	index := make([]string, 0, len(e))
+	for id := range e {
+		index = append(index, id)
+	}
+	sort.Strings(index)
+
+	// Force pagination
+	req.Limit = 1
+	return Paginate(index, prefix, req)
+}
+
+func TestSnapshotDiff(t *testing.T) {
+	before, err := Snapshot(context.Background(), mapEngine{
+		"/app/a":       "1",
+		"/app/b/c":     "2",
+		"/app/b/d/e":   "3",
+		"/infra/vault": "4",
+	}, "app")
+	if err != nil {
+		t.Fatalf("unable to snapshot engine: %v", err)
+	}
+	if len(before) != 3 || before["/app/b/d/e"] != ContentDigest([]byte("3")).Value {
+		t.Fatalf("unexpected snapshot %v", before)
+	}
+
+	after, err := Snapshot(context.Background(), mapEngine{
+		"/app/a":     "1",
+		"/app/b/c":   "updated",
+		"/app/f":     "5",
+		"/infra/tls": "6",
+	}, "/app/")
+	if err != nil {
+		t.Fatalf("unable to snapshot engine: %v", err)
+	}
+
+	want := []Change{
+		{ID: "/app/b/c", Type: ChangeUpdated, Digest: ContentDigest([]byte("updated")).Value},
+		{ID: "/app/b/d/e", Type: ChangeDeleted},
+		{ID: "/app/f", Type: ChangeCreated, Digest: ContentDigest([]byte("5")).Value},
+	}
+	if got := Diff(before, after); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected changes\ngot  %v\nwant %v", got, want)
+	}
+}
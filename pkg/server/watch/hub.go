@@ -0,0 +1,296 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package watch dispatches secret changes to subscribers.
+//
+// Subscribers never buffer more than one pending change per secret, changes
+// of a secret not consumed yet are coalesced. Subscribers not consuming their
+// pending changes within the stall timeout are disconnected.
+package watch
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/harp/pkg/server/storage"
+)
+
+var (
+	// ErrSlowConsumer is raised when a subscriber doesn't consume its pending
+	// changes in time.
+	ErrSlowConsumer = errors.New("watch: slow consumer")
+	// ErrClosed is raised when the subscription is closed.
+	ErrClosed = errors.New("watch: subscription closed")
+)
+
+// Hub dispatches published changes to topic subscribers.
+type Hub struct {
+	mu   sync.Mutex
+	subs map[string]map[*Subscription]struct{}
+}
+
+// NewHub returns an empty hub.
+func NewHub() *Hub {
+	return &Hub{
+		subs: map[string]map[*Subscription]struct{}{},
+	}
+}
+
+// Subscribe registers a subscriber for changes of the given topic matching
+// the given secret path prefix. A subscriber not consuming its pending
+// changes for the stall duration is disconnected, a zero stall duration
+// disables the check.
+func (h *Hub) Subscribe(topic, prefix string, stall time.Duration) *Subscription {
+	s := &Subscription{
+		hub:     h,
+		topic:   topic,
+		prefix:  storage.DirPrefix(prefix),
+		stall:   stall,
+		pending: map[string]storage.Change{},
+		ready:   make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subs[topic]; !ok {
+		h.subs[topic] = map[*Subscription]struct{}{}
+	}
+	h.subs[topic][s] = struct{}{}
+
+	return s
+}
+
+// Publish dispatches the given changes to topic subscribers. It never blocks.
+func (h *Hub) Publish(topic string, changes ...storage.Change) {
+	h.mu.Lock()
+	subs := make([]*Subscription, 0, len(h.subs[topic]))
+	for s := range h.subs[topic] {
+		subs = append(subs, s)
+	}
+	h.mu.Unlock()
+
+	for _, s := range subs {
+		s.push(changes)
+	}
+}
+
+func (h *Hub) remove(s *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.subs[s.topic], s)
+	if len(h.subs[s.topic]) == 0 {
+		delete(h.subs, s.topic)
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// Subscription represents a change subscriber.
+type Subscription struct {
+	hub    *Hub
+	topic  string
+	prefix string
+	stall  time.Duration
+
+	mu         sync.Mutex
+	pending    map[string]storage.Change
+	order      []string
+	ready      chan struct{}
+	done       chan struct{}
+	err        error
+	timer      *time.Timer
+	generation uint64
+	coalesced  uint64
+}
+
+// Next returns the next pending change, waiting for one when none is
+// pending.
+func (s *Subscription) Next(ctx context.Context) (storage.Change, error) {
+	for {
+		if c, ok, err := s.pop(); ok || err != nil {
+			return c, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return storage.Change{}, ctx.Err()
+		case <-s.done:
+		case <-s.ready:
+		}
+	}
+}
+
+// Done returns a channel closed when the subscription is closed.
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns the subscription closing reason.
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+// Coalesced returns the count of changes merged into a pending one.
+func (s *Subscription) Coalesced() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.coalesced
+}
+
+// Close unregisters the subscription.
+func (s *Subscription) Close() {
+	s.close(ErrClosed)
+}
+
+// -----------------------------------------------------------------------------
+
+func (s *Subscription) push(changes []storage.Change) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return
+	}
+
+	wasEmpty := len(s.order) == 0
+	for _, c := range changes {
+		if !strings.HasPrefix(c.ID, s.prefix) {
+			continue
+		}
+		s.enqueue(c)
+	}
+
+	// Start the stall timer on the first pending change
+	if wasEmpty && len(s.order) > 0 {
+		s.armTimer()
+	}
+
+	// Wake up the consumer
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Subscription) enqueue(c storage.Change) {
+	previous, ok := s.pending[c.ID]
+	if !ok {
+		s.pending[c.ID] = c
+		s.order = append(s.order, c.ID)
+		return
+	}
+
+	// Coalesce with the pending change
+	s.coalesced++
+	switch {
+	case previous.Type == storage.ChangeCreated && c.Type == storage.ChangeDeleted:
+		// Never observed by the consumer
+		delete(s.pending, c.ID)
+		s.unqueue(c.ID)
+		return
+	case previous.Type == storage.ChangeCreated:
+		c.Type = storage.ChangeCreated
+	case previous.Type == storage.ChangeDeleted && c.Type != storage.ChangeDeleted:
+		c.Type = storage.ChangeUpdated
+	default:
+	}
+	s.pending[c.ID] = c
+}
+
+func (s *Subscription) unqueue(id string) {
+	for i, candidate := range s.order {
+		if candidate == id {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *Subscription) pop() (storage.Change, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return storage.Change{}, false, s.err
+	}
+	if len(s.order) == 0 {
+		return storage.Change{}, false, nil
+	}
+
+	id := s.order[0]
+	s.order = s.order[1:]
+	c := s.pending[id]
+	delete(s.pending, id)
+
+	// Consumer is progressing
+	s.generation++
+	if len(s.order) > 0 {
+		s.armTimer()
+	} else if s.timer != nil {
+		s.timer.Stop()
+	}
+
+	return c, true, nil
+}
+
+func (s *Subscription) armTimer() {
+	if s.stall <= 0 {
+		return
+	}
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+
+	generation := s.generation
+	s.timer = time.AfterFunc(s.stall, func() {
+		s.mu.Lock()
+		stalled := s.generation == generation && len(s.order) > 0
+		s.mu.Unlock()
+
+		if stalled {
+			s.close(ErrSlowConsumer)
+		}
+	})
+}
+
+func (s *Subscription) close(reason error) {
+	s.mu.Lock()
+	if s.err != nil {
+		s.mu.Unlock()
+		return
+	}
+	s.err = reason
+	s.pending = map[string]storage.Change{}
+	s.order = nil
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	close(s.done)
+	s.mu.Unlock()
+
+	s.hub.remove(s)
+}
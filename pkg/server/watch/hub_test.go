@@ -0,0 +1,150 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package watch
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/elastic/harp/pkg/server/storage"
+)
+
+func drain(t *testing.T, s *Subscription) []storage.Change {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	res := []storage.Change{}
+	for {
+		c, err := s.Next(ctx)
+		if errors.Is(err, context.DeadlineExceeded) {
+			return res
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res = append(res, c)
+	}
+}
+
+func TestHub_Coalescing(t *testing.T) {
+	h := NewHub()
+	s := h.Subscribe("production", "app", 0)
+	defer s.Close()
+
+	// Consumer is not reading
+	h.Publish("production",
+		storage.Change{ID: "/app/database", Type: storage.ChangeUpdated, Digest: "1"},
+		storage.Change{ID: "/app/tls", Type: storage.ChangeCreated, Digest: "a"},
+		storage.Change{ID: "/app/database", Type: storage.ChangeUpdated, Digest: "2"},
+		storage.Change{ID: "/app/tls", Type: storage.ChangeUpdated, Digest: "b"},
+		storage.Change{ID: "/app/cache", Type: storage.ChangeCreated, Digest: "x"},
+		storage.Change{ID: "/app/cache", Type: storage.ChangeDeleted},
+		storage.Change{ID: "/ops/pager", Type: storage.ChangeCreated, Digest: "p"},
+	)
+	h.Publish("staging", storage.Change{ID: "/app/database", Type: storage.ChangeDeleted})
+	h.Publish("production", storage.Change{ID: "/app/database", Type: storage.ChangeUpdated, Digest: "3"})
+
+	// Only the latest state of each path is delivered, in first change order
+	want := []storage.Change{
+		{ID: "/app/database", Type: storage.ChangeUpdated, Digest: "3"},
+		{ID: "/app/tls", Type: storage.ChangeCreated, Digest: "b"},
+	}
+	if got := drain(t, s); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected changes\ngot  %v\nwant %v", got, want)
+	}
+	if s.Coalesced() != 4 {
+		t.Errorf("expected 4 coalesced changes, got %d", s.Coalesced())
+	}
+
+	// Deleted then recreated
+	h.Publish("production",
+		storage.Change{ID: "/app/tls", Type: storage.ChangeDeleted},
+		storage.Change{ID: "/app/tls", Type: storage.ChangeCreated, Digest: "c"},
+	)
+	want = []storage.Change{
+		{ID: "/app/tls", Type: storage.ChangeUpdated, Digest: "c"},
+	}
+	if got := drain(t, s); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected changes\ngot  %v\nwant %v", got, want)
+	}
+}
+
+func TestHub_SlowConsumer(t *testing.T) {
+	h := NewHub()
+	s := h.Subscribe("production", "", 20*time.Millisecond)
+	fast := h.Subscribe("production", "", 20*time.Millisecond)
+	defer fast.Close()
+
+	// Idle subscriptions are never disconnected
+	time.Sleep(40 * time.Millisecond)
+	if err := s.Err(); err != nil {
+		t.Fatalf("idle subscription should be kept, got %v", err)
+	}
+
+	h.Publish("production", storage.Change{ID: "/app/database", Type: storage.ChangeUpdated, Digest: "1"})
+	if _, err := fast.Next(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	select {
+	case <-s.Done():
+	case <-time.After(time.Second):
+		t.Fatal("slow consumer should be disconnected")
+	}
+	if !errors.Is(s.Err(), ErrSlowConsumer) {
+		t.Errorf("expected ErrSlowConsumer, got %v", s.Err())
+	}
+	if _, err := s.Next(context.Background()); !errors.Is(err, ErrSlowConsumer) {
+		t.Errorf("expected ErrSlowConsumer, got %v", err)
+	}
+	if err := fast.Err(); err != nil {
+		t.Errorf("fast consumer should be kept, got %v", err)
+	}
+
+	// Disconnected subscription is unregistered
+	h.mu.Lock()
+	count := len(h.subs["production"])
+	h.mu.Unlock()
+	if count != 1 {
+		t.Errorf("expected 1 registered subscription, got %d", count)
+	}
+}
+
+func TestSubscription_Close(t *testing.T) {
+	h := NewHub()
+	s := h.Subscribe("production", "", 0)
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := s.Next(context.Background())
+		errs <- err
+	}()
+
+	s.Close()
+	if err := <-errs; !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+
+	// Publishing to closed subscriptions is a no-op
+	h.Publish("production", storage.Change{ID: "/app/database", Type: storage.ChangeDeleted})
+}
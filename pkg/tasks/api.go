@@ -35,3 +35,15 @@ type WriterProvider func(ctx context.Context) (io.Writer, error)
 
 // ReadSeekerProvider describes io.ReadSeeker provider.
 type ReadSeekerProvider func(ctx context.Context) (io.ReadSeeker, error)
+
+// Capabilities describes the system resources required by a task.
+type Capabilities struct {
+	// Network is true when the task connects to remote services.
+	Network bool
+}
+
+// CapabilitiesDeclarer is implemented by tasks declaring their required
+// capabilities. Tasks without declaration are not restricted by the sandbox.
+type CapabilitiesDeclarer interface {
+	Capabilities() Capabilities
+}
@@ -0,0 +1,100 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/lint"
+	"github.com/elastic/harp/pkg/sdk/types"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// CompareAnomaliesTask implements bundle versions value anomaly detection task.
+type CompareAnomaliesTask struct {
+	OldReader    tasks.ReaderProvider
+	NewReader    tasks.ReaderProvider
+	OutputWriter tasks.WriterProvider
+	Thresholds   lint.AnomalyThresholds
+}
+
+// Capabilities returns the task required capabilities.
+func (t *CompareAnomaliesTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Run the task.
+func (t *CompareAnomaliesTask) Run(ctx context.Context) error {
+	// Check arguments
+	if types.IsNil(t.OldReader) {
+		return fmt.Errorf("unable to run task with a nil oldReader provider")
+	}
+	if types.IsNil(t.NewReader) {
+		return fmt.Errorf("unable to run task with a nil newReader provider")
+	}
+	if types.IsNil(t.OutputWriter) {
+		return fmt.Errorf("unable to run task with a nil outputWriter provider")
+	}
+
+	// Create input reader
+	readerOld, err := t.OldReader(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open old bundle: %w", err)
+	}
+
+	// Load old bundle
+	bOld, err := bundle.FromContainerReader(readerOld)
+	if err != nil {
+		return fmt.Errorf("unable to load old bundle content: %w", err)
+	}
+
+	// Create input reader
+	readerNew, err := t.NewReader(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open new bundle: %w", err)
+	}
+
+	// Load new bundle
+	bNew, err := bundle.FromContainerReader(readerNew)
+	if err != nil {
+		return fmt.Errorf("unable to load new bundle content: %w", err)
+	}
+
+	// Detect anomalies
+	findings, err := lint.CompareAnomalies(bOld, bNew, t.Thresholds)
+	if err != nil {
+		return fmt.Errorf("unable to detect value anomalies: %w", err)
+	}
+
+	// Create output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output writer: %w", err)
+	}
+
+	// Print report
+	if err := json.NewEncoder(writer).Encode(&lint.Report{Findings: findings}); err != nil {
+		return fmt.Errorf("unable to encode anomaly report: %w", err)
+	}
+
+	// No error
+	return nil
+}
@@ -0,0 +1,80 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/lint"
+)
+
+func Test_CompareAnomaliesTask(t *testing.T) {
+	oldBundle, err := bundle.FromMap(map[string]bundle.KV{
+		"app/production/security/harp/v1.0.0/server/database": {"password": "Zx8kQ2mN7pL4vR9t"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newBundle, err := bundle.FromMap(map[string]bundle.KV{
+		"app/production/security/harp/v1.0.0/server/database": {"password": ""},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Standalone task
+	var out bytes.Buffer
+	task := &CompareAnomaliesTask{
+		OldReader:    containerReader(t, oldBundle),
+		NewReader:    containerReader(t, newBundle),
+		OutputWriter: bufferWriter(&out),
+		Thresholds:   lint.DefaultAnomalyThresholds(),
+	}
+	if err := task.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var report lint.Report
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("unable to decode report: %v", err)
+	}
+	if len(report.Findings) != 1 || report.Findings[0].RuleID != lint.RuleEmptyValue {
+		t.Errorf("unexpected findings %+v", report.Findings)
+	}
+
+	// Diff integration
+	out.Reset()
+	diff := &DiffTask{
+		SourceReader:      containerReader(t, oldBundle),
+		DestinationReader: containerReader(t, newBundle),
+		OutputWriter:      bufferWriter(&out),
+		DetectAnomalies:   true,
+		AnomalyThresholds: lint.DefaultAnomalyThresholds(),
+	}
+	if err := diff.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "WARNING [HARP-AN-002] app/production/security/harp/v1.0.0/server/database#password: value became empty new_length=0 old_length=16") {
+		t.Errorf("anomaly warning expected in diff report, got %q", out.String())
+	}
+}
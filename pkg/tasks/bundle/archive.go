@@ -0,0 +1,87 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// ArchiveTask implements package archival task.
+type ArchiveTask struct {
+	ContainerReader tasks.ReaderProvider
+	OutputWriter    tasks.WriterProvider
+	Paths           []string
+	Restore         bool
+}
+
+// Capabilities returns the task required capabilities.
+func (t *ArchiveTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Run the task.
+func (t *ArchiveTask) Run(ctx context.Context) error {
+	// Check arguments
+	if len(t.Paths) == 0 {
+		return fmt.Errorf("at least one package path must be specified")
+	}
+
+	// Create input reader
+	reader, err := t.ContainerReader(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open input bundle: %w", err)
+	}
+
+	// Load bundle
+	b, err := bundle.FromContainerReader(reader)
+	if err != nil {
+		return fmt.Errorf("unable to load bundle content: %w", err)
+	}
+
+	// Update packages
+	now := time.Now()
+	for _, path := range t.Paths {
+		if t.Restore {
+			err = bundle.Unarchive(b, path)
+		} else {
+			err = bundle.Archive(b, path, now)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// Create output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output bundle: %w", err)
+	}
+
+	// Dump all content
+	if err := bundle.ToContainerWriter(writer, b); err != nil {
+		return fmt.Errorf("unable to dump bundle content: %w", err)
+	}
+
+	// No error
+	return nil
+}
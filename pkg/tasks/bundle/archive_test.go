@@ -0,0 +1,240 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+const (
+	activePath   = "app/staging/billing/api/v1.0.0/server/database"
+	archivedPath = "app/staging/billing/api/v1.0.0/server/legacy"
+)
+
+// archivedContainer returns a container holding an archived package.
+func archivedContainer(t *testing.T) []byte {
+	t.Helper()
+
+	b := testbundle.New()
+	b.Package(activePath).Secret("user", "admin")
+	b.Package(archivedPath).Secret("token", "legacy-token")
+
+	var out bytes.Buffer
+	task := &ArchiveTask{
+		ContainerReader: testbundle.Reader(t, b.Build()),
+		OutputWriter:    testbundle.Writer(&out),
+		Paths:           []string{archivedPath},
+	}
+	if err := task.Run(context.Background()); err != nil {
+		t.Fatalf("unable to archive package: %v", err)
+	}
+
+	return out.Bytes()
+}
+
+func bytesReader(content []byte) tasks.ReaderProvider {
+	return func(context.Context) (io.Reader, error) {
+		return bytes.NewReader(content), nil
+	}
+}
+
+func TestArchiveTask_Restore(t *testing.T) {
+	content := archivedContainer(t)
+
+	// Archived package is kept in the container
+	b := testbundle.Load(t, bytes.NewBuffer(content))
+	if len(b.Packages) != 2 {
+		t.Fatalf("archived package must be kept, got %d packages", len(b.Packages))
+	}
+
+	// Archiving twice is an error
+	err := (&ArchiveTask{
+		ContainerReader: bytesReader(content),
+		OutputWriter:    testbundle.Writer(&bytes.Buffer{}),
+		Paths:           []string{archivedPath},
+	}).Run(context.Background())
+	if !errors.Is(err, bundle.ErrPackageArchived) {
+		t.Fatalf("expected ErrPackageArchived, got %v", err)
+	}
+
+	// Restore
+	var out bytes.Buffer
+	err = (&ArchiveTask{
+		ContainerReader: bytesReader(content),
+		OutputWriter:    testbundle.Writer(&out),
+		Paths:           []string{archivedPath},
+		Restore:         true,
+	}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("unable to restore package: %v", err)
+	}
+
+	// Restored package is visible again
+	var read bytes.Buffer
+	err = (&ReadTask{
+		ContainerReader: bytesReader(out.Bytes()),
+		OutputWriter:    testbundle.Writer(&read),
+		PackageName:     archivedPath,
+		SecretKey:       "token",
+	}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("restored package must be readable: %v", err)
+	}
+	if read.String() != "legacy-token" {
+		t.Errorf("unexpected restored value %q", read.String())
+	}
+}
+
+func TestArchived_Visibility(t *testing.T) {
+	content := archivedContainer(t)
+
+	t.Run("read", func(t *testing.T) {
+		for _, include := range []bool{false, true} {
+			var out bytes.Buffer
+			err := (&ReadTask{
+				ContainerReader: bytesReader(content),
+				OutputWriter:    testbundle.Writer(&out),
+				PackageName:     archivedPath,
+				IncludeArchived: include,
+			}).Run(context.Background())
+			if (err == nil) != include {
+				t.Errorf("include=%v, unexpected error: %v", include, err)
+			}
+		}
+	})
+
+	t.Run("dump", func(t *testing.T) {
+		for _, include := range []bool{false, true} {
+			var out bytes.Buffer
+			err := (&DumpTask{
+				ContainerReader: bytesReader(content),
+				OutputWriter:    testbundle.Writer(&out),
+				PathOnly:        true,
+				IncludeArchived: include,
+			}).Run(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := strings.Contains(out.String(), archivedPath); got != include {
+				t.Errorf("include=%v, archived path visibility is %v:\n%s", include, got, out.String())
+			}
+			if !strings.Contains(out.String(), activePath) {
+				t.Errorf("active path must be visible")
+			}
+		}
+	})
+
+	t.Run("diff", func(t *testing.T) {
+		empty := testbundle.New()
+		empty.Package(activePath).Secret("user", "admin")
+
+		for _, include := range []bool{false, true} {
+			var out bytes.Buffer
+			err := (&DiffTask{
+				SourceReader:      testbundle.Reader(t, empty.Build()),
+				DestinationReader: bytesReader(content),
+				OutputWriter:      testbundle.Writer(&out),
+				IncludeArchived:   include,
+			}).Run(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := strings.Contains(out.String(), "legacy"); got != include {
+				t.Errorf("include=%v, archived path visibility is %v:\n%s", include, got, out.String())
+			}
+		}
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		var out bytes.Buffer
+		err := (&FilterTask{
+			ContainerReader: bytesReader(content),
+			OutputWriter:    testbundle.Writer(&out),
+			ExcludePaths:    []string{"database$"},
+		}).Run(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// Archived package is not selected but preserved
+		b := testbundle.Load(t, &out)
+		if len(b.Packages) != 1 || b.Packages[0].Name != archivedPath || !bundle.IsArchived(b.Packages[0]) {
+			t.Errorf("archived package must be preserved, got %v", b.Packages)
+		}
+		if len(bundle.WithoutArchived(b).Packages) != 0 {
+			t.Errorf("archived package must not be visible")
+		}
+	})
+
+	t.Run("promote", func(t *testing.T) {
+		var out, report bytes.Buffer
+		err := (&PromoteTask{
+			ContainerReader: bytesReader(content),
+			OutputWriter:    testbundle.Writer(&out),
+			ReportWriter:    testbundle.Writer(&report),
+			FromStage:       "staging",
+			ToStage:         "production",
+			Platform:        "billing",
+			MergeStrategy:   bundle.MergeStrategyFail,
+		}).Run(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		b := testbundle.Load(t, &out)
+		for _, p := range b.Packages {
+			if strings.HasSuffix(p.Name, "/legacy") {
+				t.Errorf("archived package must not be promoted")
+			}
+		}
+	})
+
+	t.Run("patch", func(t *testing.T) {
+		patch := `apiVersion: harp.elastic.co/v1
+kind: BundlePatch
+meta:
+  name: "archived"
+spec:
+  rules:
+  - selector:
+      matchPath:
+        regex: ".*"
+    package:
+      annotations:
+        add:
+          owner: security
+`
+		err := (&PatchTask{
+			PatchReader:     bytesReader([]byte(patch)),
+			ContainerReader: bytesReader(content),
+			OutputWriter:    testbundle.Writer(&bytes.Buffer{}),
+		}).Run(context.Background())
+		if !errors.Is(err, bundle.ErrPackageArchived) {
+			t.Errorf("expected ErrPackageArchived, got %v", err)
+		}
+	})
+}
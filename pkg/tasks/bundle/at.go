@@ -0,0 +1,86 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/sdk/types"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// AtTask implements historical bundle materialization task.
+type AtTask struct {
+	ContainerReader tasks.ReaderProvider
+	OutputWriter    tasks.WriterProvider
+	Time            time.Time
+}
+
+// Capabilities returns the task required capabilities.
+func (t *AtTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Run the task.
+func (t *AtTask) Run(ctx context.Context) error {
+	// Check arguments
+	if types.IsNil(t.ContainerReader) {
+		return fmt.Errorf("unable to run task with a nil containerReader provider")
+	}
+	if types.IsNil(t.OutputWriter) {
+		return fmt.Errorf("unable to run task with a nil outputWriter provider")
+	}
+	if t.Time.IsZero() {
+		return fmt.Errorf("unable to run task without cutoff time")
+	}
+
+	// Load bundle
+	b, err := loadBundle(ctx, t.ContainerReader)
+	if err != nil {
+		return err
+	}
+
+	// Select versions
+	hb, fallbacks, err := bundle.AsOf(b, t.Time)
+	if err != nil {
+		return fmt.Errorf("unable to select secret versions: %w", err)
+	}
+	for _, name := range fallbacks {
+		log.For(ctx).Warn("Secret versions without creation timestamp, version number order used", zap.String("path", name))
+	}
+
+	// Create output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output bundle: %w", err)
+	}
+
+	// Dump bundle
+	if err := bundle.ToContainerWriter(writer, hb); err != nil {
+		return fmt.Errorf("unable to dump bundle content: %w", err)
+	}
+
+	// No error
+	return nil
+}
@@ -0,0 +1,74 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+)
+
+func TestAtTask(t *testing.T) {
+	b := testbundle.New().
+		Package("app/production/database").Secret("password", "v0").
+		Package("app/production/cache").Secret("password", "v0").
+		Build()
+	b.Packages[0].Secrets.Annotations = map[string]string{bundle.VersionCreatedAnnotation: "2021-01-01T00:00:00Z"}
+	b.Packages[1].Secrets.Annotations = map[string]string{bundle.VersionCreatedAnnotation: "2021-07-01T00:00:00Z"}
+
+	t.Run("missing time", func(t *testing.T) {
+		var out bytes.Buffer
+		err := (&AtTask{
+			ContainerReader: testbundle.Reader(t, b),
+			OutputWriter:    testbundle.Writer(&out),
+		}).Run(context.Background())
+		if err == nil {
+			t.Error("error expected")
+		}
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		var out bytes.Buffer
+		err := (&AtTask{
+			ContainerReader: testbundle.Reader(t, b),
+			OutputWriter:    testbundle.Writer(&out),
+			Time:            time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+		}).Run(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		got := testbundle.Load(t, &out)
+		if len(got.Packages) != 1 || got.Packages[0].Name != "app/production/database" {
+			t.Errorf("unexpected packages: %v", packageNames(got))
+		}
+	})
+}
+
+func packageNames(b *bundlev1.Bundle) []string {
+	res := []string{}
+	for _, p := range b.Packages {
+		res = append(res, p.Name)
+	}
+	return res
+}
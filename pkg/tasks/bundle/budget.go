@@ -0,0 +1,152 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/budget"
+	"github.com/elastic/harp/pkg/sdk/types"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// ErrBudgetExceeded is raised when the budget report contains exceeded budgets.
+var ErrBudgetExceeded = errors.New("bundle exceeds size budget")
+
+// BudgetTask implements bundle size budget evaluation task.
+type BudgetTask struct {
+	ContainerReader tasks.ReaderProvider
+	PolicyReader    tasks.ReaderProvider
+	OutputWriter    tasks.WriterProvider
+	JSONOutput      bool
+}
+
+// Capabilities returns the task required capabilities.
+func (t *BudgetTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Run the task.
+func (t *BudgetTask) Run(ctx context.Context) error {
+	// Check arguments
+	if types.IsNil(t.ContainerReader) {
+		return fmt.Errorf("unable to run task with a nil containerReader provider")
+	}
+	if types.IsNil(t.PolicyReader) {
+		return fmt.Errorf("unable to run task with a nil policyReader provider")
+	}
+	if types.IsNil(t.OutputWriter) {
+		return fmt.Errorf("unable to run task with a nil outputWriter provider")
+	}
+
+	// Load policy
+	policyReader, err := t.PolicyReader(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open policy: %w", err)
+	}
+	policy, err := budget.ParsePolicy(policyReader)
+	if err != nil {
+		return fmt.Errorf("unable to load policy: %w", err)
+	}
+
+	// Create input reader
+	reader, err := t.ContainerReader(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open input bundle: %w", err)
+	}
+
+	// Load bundle
+	b, err := bundle.FromContainerReader(reader)
+	if err != nil {
+		return fmt.Errorf("unable to load bundle content: %w", err)
+	}
+
+	// Evaluate budgets
+	report, err := budget.Evaluate(b, policy)
+	if err != nil {
+		return fmt.Errorf("unable to evaluate budgets: %w", err)
+	}
+
+	// Create output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output writer: %w", err)
+	}
+
+	if t.JSONOutput {
+		// Export as JSON
+		enc := json.NewEncoder(writer)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return fmt.Errorf("unable to encode budget report: %w", err)
+		}
+	} else {
+		// Export as text
+		tw := tabwriter.NewWriter(writer, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "PREFIX\tPACKAGES\tBYTES\tSTATUS\tTOP OFFENDERS")
+		for _, u := range report.Budgets {
+			status := "ok"
+			if u.Exceeded {
+				status = "exceeded"
+			}
+			top := "-"
+			if len(u.TopOffenders) > 0 {
+				top = fmt.Sprintf("%s (%d)", u.TopOffenders[0].Name, u.TopOffenders[0].Bytes)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", displayPrefix(u.Prefix), usage(int64(u.Packages), int64(u.MaxPackages)), usage(u.Bytes, u.MaxBytes), status, top)
+			for i := 1; i < len(u.TopOffenders); i++ {
+				fmt.Fprintf(tw, "\t\t\t\t%s (%d)\n", u.TopOffenders[i].Name, u.TopOffenders[i].Bytes)
+			}
+		}
+		if err := tw.Flush(); err != nil {
+			return fmt.Errorf("unable to write budget report: %w", err)
+		}
+		if report.Unbudgeted > 0 {
+			fmt.Fprintf(writer, "\n%d package(s) not covered by any budget\n", report.Unbudgeted)
+		}
+	}
+
+	// Check budgets
+	if report.Exceeded() {
+		return ErrBudgetExceeded
+	}
+
+	// No error
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func displayPrefix(prefix string) string {
+	if prefix == "" {
+		return "*"
+	}
+	return prefix
+}
+
+func usage(current, limit int64) string {
+	if limit <= 0 {
+		return fmt.Sprintf("%d", current)
+	}
+	return fmt.Sprintf("%d/%d", current, limit)
+}
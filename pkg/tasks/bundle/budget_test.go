@@ -0,0 +1,107 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/budget"
+)
+
+func Test_BudgetTask(t *testing.T) {
+	b, err := bundle.FromMap(map[string]bundle.KV{
+		"app/production/billing/payments/1.0.0/api/database": {"password": "foo"},
+		"app/production/billing/payments/1.0.0/api/stripe":   {"api_key": "sk_live"},
+		"app/staging/billing/payments/1.0.0/api/database":    {"password": "bar"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		desc    string
+		policy  string
+		json    bool
+		wantErr error
+	}{
+		{
+			desc: "within budget",
+			policy: `apiVersion: harp.elastic.co/v1
+kind: BudgetPolicy
+spec:
+  budgets:
+  - prefix: app/production
+    maxPackages: 2
+  - prefix: app
+    maxPackages: 1
+`,
+		},
+		{
+			desc: "exceeded",
+			json: true,
+			policy: `apiVersion: harp.elastic.co/v1
+kind: BudgetPolicy
+spec:
+  budgets:
+  - prefix: app/production
+    maxPackages: 1
+`,
+			wantErr: ErrBudgetExceeded,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var out bytes.Buffer
+			task := &BudgetTask{
+				ContainerReader: containerReader(t, b),
+				PolicyReader: func(context.Context) (io.Reader, error) {
+					return strings.NewReader(tC.policy), nil
+				},
+				OutputWriter: bufferWriter(&out),
+				JSONOutput:   tC.json,
+			}
+
+			err := task.Run(context.Background())
+			if !errors.Is(err, tC.wantErr) {
+				t.Fatalf("unexpected error, got %v, want %v", err, tC.wantErr)
+			}
+
+			if !tC.json {
+				if !strings.Contains(out.String(), "app/production  2/2") {
+					t.Errorf("unexpected report:\n%s", out.String())
+				}
+				return
+			}
+
+			var report budget.Report
+			if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+				t.Fatalf("unable to decode report: %v", err)
+			}
+			if len(report.Budgets) != 1 || report.Budgets[0].Packages != 2 || len(report.Budgets[0].TopOffenders) != 2 {
+				t.Errorf("unexpected report %+v", report)
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"sigs.k8s.io/yaml"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	csov1 "github.com/elastic/harp/pkg/cso/v1"
+	"github.com/elastic/harp/pkg/sdk/types"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// DefaultMinConfidence is the default confidence threshold of suggested paths
+// written as rewrite rules.
+const DefaultMinConfidence = 0.8
+
+// CSOSuggestTask implements CSO path inference task. Non-compliant package
+// paths are matched against known stages, regions and the hint dictionary,
+// suggestions above the confidence threshold are written as BundlePatch
+// rewrite rules, the others are listed for review.
+type CSOSuggestTask struct {
+	ContainerReader tasks.ReaderProvider
+	HintsReader     tasks.ReaderProvider
+	RulesWriter     tasks.WriterProvider
+	ReviewWriter    tasks.WriterProvider
+	MinConfidence   float64
+
+	result *CSOSuggestResult
+}
+
+// CSOSuggestReview describes suggestions requiring a review.
+type CSOSuggestReview struct {
+	Review []*csov1.Suggestion `json:"review"`
+}
+
+// CSOSuggestResult describes a CSO path inference task execution.
+type CSOSuggestResult struct {
+	tasks.Result
+	Compliant int `json:"compliant"`
+	Rewritten int `json:"rewritten"`
+	Review    int `json:"review"`
+}
+
+// Capabilities returns the task required capabilities.
+func (t *CSOSuggestTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Result returns the last execution result.
+func (t *CSOSuggestTask) Result() interface{} {
+	return t.result
+}
+
+// Run the task.
+func (t *CSOSuggestTask) Run(ctx context.Context) error {
+	t.result = &CSOSuggestResult{}
+
+	// Check arguments
+	if types.IsNil(t.ContainerReader) {
+		return fmt.Errorf("unable to run task with a nil containerReader provider")
+	}
+	if types.IsNil(t.RulesWriter) {
+		return fmt.Errorf("unable to run task with a nil rulesWriter provider")
+	}
+	if types.IsNil(t.ReviewWriter) {
+		return fmt.Errorf("unable to run task with a nil reviewWriter provider")
+	}
+	if t.MinConfidence < 0 || t.MinConfidence > 1 {
+		return fmt.Errorf("minimum confidence must be between 0 and 1")
+	}
+
+	// Load hints
+	hints := &csov1.SuggestHints{}
+	if t.HintsReader != nil {
+		reader, err := t.HintsReader(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to open hints: %w", err)
+		}
+		content, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return fmt.Errorf("unable to read hints: %w", err)
+		}
+		if err := yaml.UnmarshalStrict(content, hints); err != nil {
+			return fmt.Errorf("unable to decode hints: %w", err)
+		}
+	}
+	suggester, err := csov1.NewSuggester(hints)
+	if err != nil {
+		return fmt.Errorf("invalid hints: %w", err)
+	}
+
+	// Load bundle
+	b, err := loadBundle(ctx, t.ContainerReader)
+	if err != nil {
+		return err
+	}
+
+	// Infer paths
+	renames, review := t.suggest(suggester, b)
+	t.result.Rewritten = len(renames)
+	t.result.Review = len(review)
+
+	// Write rewrite rules
+	out, err := protojson.Marshal(rewritePatch(renames))
+	if err != nil {
+		return fmt.Errorf("unable to encode rewrite rules: %w", err)
+	}
+	out, err = yaml.JSONToYAML(out)
+	if err != nil {
+		return fmt.Errorf("unable to encode rewrite rules: %w", err)
+	}
+	rulesWriter, err := t.RulesWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open rules writer: %w", err)
+	}
+	if _, err := rulesWriter.Write(out); err != nil {
+		return fmt.Errorf("unable to write rewrite rules: %w", err)
+	}
+
+	// Write review list
+	reviewWriter, err := t.ReviewWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open review writer: %w", err)
+	}
+	enc := json.NewEncoder(reviewWriter)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(&CSOSuggestReview{Review: review}); err != nil {
+		return fmt.Errorf("unable to encode review list: %w", err)
+	}
+
+	// No error
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (t *CSOSuggestTask) suggest(s *csov1.Suggester, b *bundlev1.Bundle) ([]PathRename, []*csov1.Suggestion) {
+	existing := map[string]bool{}
+	for _, p := range b.Packages {
+		existing[p.Name] = true
+	}
+
+	// Suggest paths of non-compliant packages
+	accepted := []*csov1.Suggestion{}
+	review := []*csov1.Suggestion{}
+	targets := map[string][]*csov1.Suggestion{}
+	for _, p := range b.Packages {
+		if err := csov1.Validate(p.Name); err == nil {
+			t.result.Compliant++
+			continue
+		}
+
+		sg := s.Suggest(p.Name)
+		if sg.Suggested == "" || sg.Confidence < t.MinConfidence {
+			review = append(review, sg)
+			continue
+		}
+		accepted = append(accepted, sg)
+		targets[sg.Suggested] = append(targets[sg.Suggested], sg)
+	}
+
+	// Colliding targets are reviewed
+	renames := []PathRename{}
+	for _, sg := range accepted {
+		switch {
+		case existing[sg.Suggested]:
+			sg.Reasons = append(sg.Reasons, "suggested path is already used by a package")
+			review = append(review, sg)
+		case len(targets[sg.Suggested]) > 1:
+			sg.Reasons = append(sg.Reasons, fmt.Sprintf("suggested path is shared by %d packages", len(targets[sg.Suggested])))
+			review = append(review, sg)
+		default:
+			renames = append(renames, PathRename{From: sg.Path, To: sg.Suggested})
+		}
+	}
+
+	// Ensure stable order
+	sort.Slice(renames, func(i, j int) bool {
+		return renames[i].From < renames[j].From
+	})
+	sort.SliceStable(review, func(i, j int) bool {
+		return review[i].Path < review[j].Path
+	})
+
+	return renames, review
+}
+
+// rewritePatch returns a self-contained BundlePatch rewriting each rename
+// source to its target. Paths are quoted as template string literals to
+// prevent template injection.
+func rewritePatch(renames []PathRename) *bundlev1.Patch {
+	spec := &bundlev1.Patch{
+		ApiVersion: "harp.elastic.co/v1",
+		Kind:       "BundlePatch",
+		Meta: &bundlev1.PatchMeta{
+			Name:        "cso-suggest",
+			Owner:       "harp",
+			Description: "Rewrite legacy package paths to their suggested CSO path",
+		},
+		Spec: &bundlev1.PatchSpec{
+			Rules: []*bundlev1.PatchRule{},
+		},
+	}
+
+	for _, r := range renames {
+		spec.Spec.Rules = append(spec.Spec.Rules, &bundlev1.PatchRule{
+			Selector: &bundlev1.PatchSelector{
+				MatchPath: &bundlev1.PatchSelectorMatchPath{
+					Strict: fmt.Sprintf("{{ %s }}", strconv.Quote(r.From)),
+				},
+			},
+			Package: &bundlev1.PatchPackage{
+				Path: &bundlev1.PatchPackagePath{
+					Template: fmt.Sprintf("{{ %s }}", strconv.Quote(r.To)),
+				},
+			},
+		})
+	}
+
+	return spec
+}
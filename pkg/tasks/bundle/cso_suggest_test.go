@@ -0,0 +1,121 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/patch"
+)
+
+const csoSuggestHints = `
+platforms:
+  customer1: [cust1]
+products:
+  billing:
+    aliases: [bill]
+    version: v1.0.0
+    components:
+      database: [db, pg]
+      api: []
+defaults:
+  platform: customer1
+`
+
+func Test_CSOSuggestTask(t *testing.T) {
+	b, err := bundle.FromMap(map[string]bundle.KV{
+		"prod/billing/db":             {"password": "prod"},
+		"stg/bill/api/stripe":         {"key": "stg"},
+		"legacy/unknown":              {"token": "unknown"},
+		"production/billing/database": {"user": "dup1"},
+		"prd/billing/pg":              {"user": "dup2"},
+		"app/production/customer1/billing/v1.0.0/api/secrets": {"token": "compliant"},
+		"prod-billing-api": {"token": "taken"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rules, review bytes.Buffer
+	task := &CSOSuggestTask{
+		ContainerReader: containerReader(t, b),
+		HintsReader:     bytesReader([]byte(csoSuggestHints)),
+		RulesWriter:     bufferWriter(&rules),
+		ReviewWriter:    bufferWriter(&review),
+		MinConfidence:   DefaultMinConfidence,
+	}
+	if err := task.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Review list
+	var got CSOSuggestReview
+	if err := json.Unmarshal(review.Bytes(), &got); err != nil {
+		t.Fatalf("unable to decode review list: %v", err)
+	}
+	reviewed := []string{}
+	for _, s := range got.Review {
+		reviewed = append(reviewed, s.Path)
+	}
+	want := []string{"legacy/unknown", "prd/billing/pg", "prod-billing-api", "prod/billing/db", "production/billing/database"}
+	if !reflect.DeepEqual(reviewed, want) {
+		t.Errorf("unexpected review list %v", reviewed)
+	}
+	res := task.Result().(*CSOSuggestResult)
+	if res.Compliant != 1 || res.Rewritten != 1 || res.Review != 5 {
+		t.Errorf("unexpected result %+v", res)
+	}
+
+	// Rules apply with the patch task
+	spec, err := patch.YAML(&rules)
+	if err != nil {
+		t.Fatalf("unable to load rewrite rules: %v", err)
+	}
+	if err := patch.Apply(spec, b, nil); err != nil {
+		t.Fatalf("unable to apply rewrite rules: %v", err)
+	}
+	if _, err := bundle.Read(b, "app/staging/customer1/billing/v1.0.0/api/stripe"); err != nil {
+		t.Errorf("expected package to be rewritten: %v", err)
+	}
+}
+
+func Test_CSOSuggestTask_InvalidHints(t *testing.T) {
+	b, err := bundle.FromMap(map[string]bundle.KV{
+		"prod/billing/db": {"password": "prod"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, hints := range []string{"unknown: field", "aliases: {live: unknown}"} {
+		task := &CSOSuggestTask{
+			ContainerReader: containerReader(t, b),
+			HintsReader:     bytesReader([]byte(hints)),
+			RulesWriter:     bufferWriter(&bytes.Buffer{}),
+			ReviewWriter:    bufferWriter(&bytes.Buffer{}),
+		}
+		if err := task.Run(context.Background()); err == nil {
+			t.Errorf("error should be raised for hints %q", hints)
+		}
+	}
+}
@@ -33,6 +33,14 @@ type DecryptTask struct {
 	ContainerReader tasks.ReaderProvider
 	OutputWriter    tasks.WriterProvider
 	Transformer     value.Transformer
+	// TransformerResolver returns the transformer for the algorithm recorded
+	// with locked values, used instead of Transformer when set.
+	TransformerResolver func(algorithm string) (value.Transformer, error)
+}
+
+// Capabilities returns the task required capabilities.
+func (t *DecryptTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
 }
 
 // Run the task.
@@ -57,7 +65,7 @@ func (t *DecryptTask) Run(ctx context.Context) error {
 	}
 
 	// Apply transformer to bundle
-	if err = bundle.UnLock(ctx, b, t.Transformer); err != nil {
+	if err = t.unlock(ctx, b); err != nil {
 		return fmt.Errorf("unable to apply bundle transformation: %w", err)
 	}
 
@@ -75,3 +83,54 @@ func (t *DecryptTask) Run(ctx context.Context) error {
 	// No error
 	return nil
 }
+
+// -----------------------------------------------------------------------------
+
+func (t *DecryptTask) unlock(ctx context.Context, b *bundlev1.Bundle) error {
+	if t.TransformerResolver == nil {
+		if err := bundle.UnLock(ctx, b, t.Transformer); err != nil {
+			return err
+		}
+		clearLockedAlgorithm(b.Packages)
+		return nil
+	}
+
+	// Group packages by encryption algorithm
+	groups := map[string][]*bundlev1.Package{}
+	algorithms := []string{}
+	for _, p := range b.Packages {
+		if p.Secrets == nil || p.Secrets.Locked == nil {
+			continue
+		}
+		algorithm := p.Secrets.Annotations[bundle.LockedAlgorithmAnnotation]
+		if _, ok := groups[algorithm]; !ok {
+			algorithms = append(algorithms, algorithm)
+		}
+		groups[algorithm] = append(groups[algorithm], p)
+	}
+
+	for _, algorithm := range algorithms {
+		transformer, err := t.TransformerResolver(algorithm)
+		if err != nil {
+			return fmt.Errorf("unable to initialize '%s' transformer: %w", algorithm, err)
+		}
+
+		// Packages are shared with the view
+		view := &bundlev1.Bundle{Packages: groups[algorithm]}
+		if err := bundle.UnLock(ctx, view, transformer); err != nil {
+			return err
+		}
+		clearLockedAlgorithm(view.Packages)
+	}
+
+	// No error
+	return nil
+}
+
+func clearLockedAlgorithm(packages []*bundlev1.Package) {
+	for _, p := range packages {
+		if p.Secrets != nil && p.Secrets.Locked == nil {
+			delete(p.Secrets.Annotations, bundle.LockedAlgorithmAnnotation)
+		}
+	}
+}
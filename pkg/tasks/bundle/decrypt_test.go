@@ -0,0 +1,80 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+	"github.com/elastic/harp/pkg/sdk/value"
+	"github.com/elastic/harp/pkg/sdk/value/encryption"
+)
+
+func TestDecryptTask_RecordedAlgorithm(t *testing.T) {
+	key := "aes-gcm:zQyPnNa-jlQsLW3Ypd87cX88ROMkdgnqv0a3y8LiISg="
+	b := testbundle.New().
+		Package("app/production/database").Secret("password", "foo").
+		Build()
+
+	// Encrypt with an explicit algorithm
+	transformer, err := encryption.ForAlgorithm(encryption.AlgorithmXChaCha, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var encrypted bytes.Buffer
+	if err := (&EncryptTask{
+		ContainerReader: testbundle.Reader(t, b),
+		OutputWriter:    testbundle.Writer(&encrypted),
+		Transformer:     transformer,
+		Algorithm:       encryption.AlgorithmXChaCha,
+	}).Run(context.Background()); err != nil {
+		t.Fatalf("unable to encrypt: %v", err)
+	}
+
+	locked := testbundle.Load(t, &encrypted)
+	if got := locked.Packages[0].Secrets.Annotations[bundle.LockedAlgorithmAnnotation]; got != encryption.AlgorithmXChaCha {
+		t.Fatalf("recorded algorithm = %q, want %q", got, encryption.AlgorithmXChaCha)
+	}
+
+	// Decrypt with the same key, the algorithm is auto-selected
+	var decrypted bytes.Buffer
+	if err := (&DecryptTask{
+		ContainerReader: testbundle.Reader(t, locked),
+		OutputWriter:    testbundle.Writer(&decrypted),
+		TransformerResolver: func(algorithm string) (value.Transformer, error) {
+			return encryption.ForAlgorithm(algorithm, key)
+		},
+	}).Run(context.Background()); err != nil {
+		t.Fatalf("unable to decrypt: %v", err)
+	}
+
+	got := testbundle.Load(t, &decrypted)
+	secrets, err := bundle.AsSecretMap(got.Packages[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secrets["password"] != "foo" {
+		t.Errorf("unexpected secret value: %v", secrets["password"])
+	}
+	if _, ok := got.Packages[0].Secrets.Annotations[bundle.LockedAlgorithmAnnotation]; ok {
+		t.Error("algorithm annotation must be removed after decryption")
+	}
+}
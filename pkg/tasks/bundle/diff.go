@@ -20,8 +20,14 @@ package bundle
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 
+	"github.com/google/go-cmp/cmp"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
 	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/lint"
 	"github.com/elastic/harp/pkg/tasks"
 )
 
@@ -30,10 +36,39 @@ type DiffTask struct {
 	SourceReader      tasks.ReaderProvider
 	DestinationReader tasks.ReaderProvider
 	OutputWriter      tasks.WriterProvider
+	DetectAnomalies   bool
+	AnomalyThresholds lint.AnomalyThresholds
+	IncludeArchived   bool
+
+	result *DiffResult
+}
+
+// DiffResult describes a diff task execution. Anomalies are reported as
+// warnings.
+type DiffResult struct {
+	tasks.Result
+	SourcePackages      int `json:"sourcePackages"`
+	DestinationPackages int `json:"destinationPackages"`
+	Added               int `json:"added"`
+	Removed             int `json:"removed"`
+	Modified            int `json:"modified"`
+	Quarantined         int `json:"quarantined"`
+}
+
+// Result returns the last execution result.
+func (t *DiffTask) Result() interface{} {
+	return t.result
+}
+
+// Capabilities returns the task required capabilities.
+func (t *DiffTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
 }
 
 // Run the task.
 func (t *DiffTask) Run(ctx context.Context) error {
+	t.result = &DiffResult{}
+
 	// Create input reader
 	readerSrc, err := t.SourceReader(ctx)
 	if err != nil {
@@ -52,17 +87,35 @@ func (t *DiffTask) Run(ctx context.Context) error {
 		return fmt.Errorf("unable to open destination bundle: %w", err)
 	}
 
-	// Load source bundle
-	bDst, err := bundle.Load(readerDst)
+	// Load destination bundle
+	bDst, err := bundle.FromContainerReader(readerDst)
 	if err != nil {
 		return fmt.Errorf("unable to load destination bundle content: %w", err)
 	}
 
+	// Hide archived packages
+	if !t.IncludeArchived {
+		bSrc = bundle.WithoutArchived(bSrc)
+		bDst = bundle.WithoutArchived(bDst)
+	}
+
 	// Calculate diff
 	report, err := bundle.Diff(bSrc, bDst)
 	if err != nil {
 		return fmt.Errorf("unable to calculate bundle difference: %w", err)
 	}
+	if err := t.count(bSrc, bDst); err != nil {
+		return fmt.Errorf("unable to count bundle differences: %w", err)
+	}
+
+	// Detect value anomalies
+	var anomalies []lint.Finding
+	if t.DetectAnomalies {
+		anomalies, err = lint.CompareAnomalies(bSrc, bDst, t.AnomalyThresholds)
+		if err != nil {
+			return fmt.Errorf("unable to detect value anomalies: %w", err)
+		}
+	}
 
 	// Create output writer
 	writer, err := t.OutputWriter(ctx)
@@ -70,9 +123,72 @@ func (t *DiffTask) Run(ctx context.Context) error {
 		return fmt.Errorf("unable to open output writer: %w", err)
 	}
 
+	// Print quarantined packages first
+	quarantined := lint.Quarantined(bDst)
+	t.result.Quarantined = len(quarantined)
+	for _, f := range quarantined {
+		fmt.Fprintf(writer, "QUARANTINED [%s] %s: %s\n", f.RuleID, f.Path, f.Message)
+	}
+
 	// Print report
 	fmt.Fprintln(writer, report)
 
+	// Print anomaly warnings
+	for _, f := range anomalies {
+		warning := formatAnomaly(&f)
+		t.result.Warn("%s", strings.TrimPrefix(warning, "WARNING "))
+		fmt.Fprintln(writer, warning)
+	}
+
 	// No error
 	return nil
 }
+
+// -----------------------------------------------------------------------------
+
+func (t *DiffTask) count(src, dst *bundlev1.Bundle) error {
+	srcMap, err := bundle.AsMap(src)
+	if err != nil {
+		return err
+	}
+	dstMap, err := bundle.AsMap(dst)
+	if err != nil {
+		return err
+	}
+
+	t.result.SourcePackages = len(srcMap)
+	t.result.DestinationPackages = len(dstMap)
+	for name, secrets := range dstMap {
+		before, ok := srcMap[name]
+		switch {
+		case !ok:
+			t.result.Added++
+		case !cmp.Equal(before, secrets):
+			t.result.Modified++
+		default:
+		}
+	}
+	for name := range srcMap {
+		if _, ok := dstMap[name]; !ok {
+			t.result.Removed++
+		}
+	}
+
+	return nil
+}
+
+func formatAnomaly(f *lint.Finding) string {
+	// Sort metric names for stable output
+	names := make([]string, 0, len(f.Metrics))
+	for k := range f.Metrics {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	metrics := make([]string, 0, len(names))
+	for _, k := range names {
+		metrics = append(metrics, fmt.Sprintf("%s=%g", k, f.Metrics[k]))
+	}
+
+	return strings.TrimSpace(fmt.Sprintf("WARNING [%s] %s#%s: %s %s", f.RuleID, f.Path, f.Key, f.Message, strings.Join(metrics, " ")))
+}
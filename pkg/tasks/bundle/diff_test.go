@@ -0,0 +1,128 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/lint"
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+)
+
+func TestDiffTask(t *testing.T) {
+	src := testbundle.New().
+		Package("app/production/security/harp/v1.0.0/server/database").
+		Secret("user", "admin").
+		Secret("password", "Zq8#vL2!pX9@kR4$tW7&mN1^").
+		Package("app/production/security/harp/v1.0.0/server/removed").
+		Secret("token", "foo").
+		Build()
+
+	dst := testbundle.New().
+		Package("app/production/security/harp/v1.0.0/server/database").
+		Secret("user", "admin").
+		Secret("password", "password").
+		Package("app/production/security/harp/v1.0.0/server/added").
+		Secret("token", "bar").
+		Build()
+
+	testCases := []struct {
+		desc      string
+		dst       *testbundle.Builder
+		anomalies bool
+		want      []string
+		wantNot   []string
+	}{
+		{
+			desc:    "identical",
+			wantNot: []string{"database", "WARNING"},
+		},
+		{
+			desc: "changes",
+			want: []string{"removed", "added", "password"},
+		},
+		{
+			desc:      "anomalies",
+			anomalies: true,
+			want:      []string{"WARNING [HARP-AN-"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			target := dst
+			if tC.desc == "identical" {
+				target = src
+			}
+
+			var out bytes.Buffer
+			task := &DiffTask{
+				SourceReader:      testbundle.Reader(t, src),
+				DestinationReader: testbundle.Reader(t, target),
+				OutputWriter:      testbundle.Writer(&out),
+				DetectAnomalies:   tC.anomalies,
+				AnomalyThresholds: lint.DefaultAnomalyThresholds(),
+			}
+			if err := task.Run(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for _, w := range tC.want {
+				if !strings.Contains(out.String(), w) {
+					t.Errorf("output must contain %q, got:\n%s", w, out.String())
+				}
+			}
+			for _, w := range tC.wantNot {
+				if strings.Contains(out.String(), w) {
+					t.Errorf("output must not contain %q, got:\n%s", w, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestDiffTask_Quarantined(t *testing.T) {
+	src := testbundle.New().
+		Package("app/production/security/harp/v1.0.0/server/database").
+		Secret("user", "admin").
+		Build()
+
+	dst := testbundle.New().
+		Package("app/production/security/harp/v1.0.0/server/database").
+		Secret("user", "admin").
+		Annotation(bundle.QuarantineAnnotation, "leaked in CI logs").
+		Build()
+
+	var out bytes.Buffer
+	task := &DiffTask{
+		SourceReader:      testbundle.Reader(t, src),
+		DestinationReader: testbundle.Reader(t, dst),
+		OutputWriter:      testbundle.Writer(&out),
+	}
+	if err := task.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "QUARANTINED [HARP-QU-001] app/production/security/harp/v1.0.0/server/database: package is quarantined: leaked in CI logs"
+	if !strings.HasPrefix(out.String(), want) {
+		t.Errorf("output must start with %q, got:\n%s", want, out.String())
+	}
+}
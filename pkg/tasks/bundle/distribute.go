@@ -0,0 +1,199 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/distribution"
+	"github.com/elastic/harp/pkg/container"
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/sdk/types"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// ErrOrphanPackages is raised when active packages are selected by no
+// distribution.
+var ErrOrphanPackages = errors.New("packages not selected by any distribution")
+
+// DistributeTask implements sealed sub-bundle production from a distribution
+// specification.
+type DistributeTask struct {
+	ContainerReader tasks.ReaderProvider
+	Spec            *distribution.Spec
+	BundleWriter    func(path string) tasks.WriterProvider
+	OutputWriter    tasks.WriterProvider
+	FailOnOrphans   bool
+}
+
+// DistributionManifest describes produced sub-bundles.
+type DistributionManifest struct {
+	Distributions []DistributedBundle `json:"distributions"`
+	// Orphans lists active packages selected by no distribution.
+	Orphans []string `json:"orphans"`
+}
+
+// DistributedBundle describes a sealed sub-bundle.
+type DistributedBundle struct {
+	Name   string `json:"name"`
+	Output string `json:"output"`
+	// Digest is the sealed container digest.
+	Digest      string   `json:"digest"`
+	Recipients  []string `json:"recipients"`
+	Packages    int      `json:"packages"`
+	RemovedKeys int      `json:"removedKeys"`
+}
+
+// Capabilities returns the task required capabilities.
+func (t *DistributeTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Run the task.
+func (t *DistributeTask) Run(ctx context.Context) error {
+	// Check arguments
+	if types.IsNil(t.ContainerReader) {
+		return fmt.Errorf("unable to run task with a nil containerReader provider")
+	}
+	if t.Spec == nil {
+		return fmt.Errorf("unable to run task with a nil specification")
+	}
+	if t.BundleWriter == nil {
+		return fmt.Errorf("unable to run task with a nil bundleWriter factory")
+	}
+	if types.IsNil(t.OutputWriter) {
+		return fmt.Errorf("unable to run task with a nil outputWriter provider")
+	}
+
+	// Create input reader
+	endLoad := cmdutil.TracePhase(ctx, "load")
+	reader, err := t.ContainerReader(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open input bundle: %w", err)
+	}
+
+	// Load bundle
+	b, err := bundle.FromContainerReader(reader)
+	if err != nil {
+		return fmt.Errorf("unable to load bundle content: %w", err)
+	}
+	endLoad()
+
+	// Build sub-bundles
+	res, err := distribution.Split(b, t.Spec)
+	if err != nil {
+		return fmt.Errorf("unable to split bundle: %w", err)
+	}
+
+	// Check orphans before producing anything
+	if len(res.Orphans) > 0 {
+		if t.FailOnOrphans {
+			return fmt.Errorf("%w: %s", ErrOrphanPackages, strings.Join(res.Orphans, ", "))
+		}
+		log.For(ctx).Warn("Packages not selected by any distribution", zap.Strings("packages", res.Orphans))
+	}
+
+	// Seal and write sub-bundles
+	endSeal := cmdutil.TracePhase(ctx, "seal")
+	manifest := &DistributionManifest{
+		Distributions: []DistributedBundle{},
+		Orphans:       res.Orphans,
+	}
+	for _, out := range res.Outputs {
+		digest, err := t.seal(ctx, out)
+		if err != nil {
+			return fmt.Errorf("unable to produce distribution '%s': %w", out.Distribution.Name, err)
+		}
+
+		manifest.Distributions = append(manifest.Distributions, DistributedBundle{
+			Name:        out.Distribution.Name,
+			Output:      out.Distribution.Output,
+			Digest:      digest,
+			Recipients:  out.Distribution.Recipients,
+			Packages:    len(out.Bundle.Packages),
+			RemovedKeys: out.RemovedKeys,
+		})
+	}
+	endSeal()
+
+	// Write the manifest
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output writer: %w", err)
+	}
+	enc := json.NewEncoder(writer)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(manifest); err != nil {
+		return fmt.Errorf("unable to encode distribution manifest: %w", err)
+	}
+
+	// No error
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+// seal writes the sealed sub-bundle and returns the container digest.
+func (t *DistributeTask) seal(ctx context.Context, out *distribution.Output) (string, error) {
+	// Decode recipients
+	peerPublicKeys := []*[32]byte{}
+	for _, r := range out.Distribution.Recipients {
+		pub, err := distribution.PublicKey(r)
+		if err != nil {
+			return "", err
+		}
+		peerPublicKeys = append(peerPublicKeys, pub)
+	}
+
+	// Prepare the container
+	c, err := bundle.ToContainer(out.Bundle)
+	if err != nil {
+		return "", fmt.Errorf("unable to prepare container: %w", err)
+	}
+	sealed, err := container.Seal(c, peerPublicKeys...)
+	if err != nil {
+		return "", fmt.Errorf("unable to seal container: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := container.Dump(&buf, sealed); err != nil {
+		return "", fmt.Errorf("unable to encode sealed container: %w", err)
+	}
+	digest := sha256.Sum256(buf.Bytes())
+
+	// Write the container
+	writer, err := t.BundleWriter(out.Distribution.Output)(ctx)
+	if err != nil {
+		return "", fmt.Errorf("unable to open output bundle: %w", err)
+	}
+	if _, err := writer.Write(buf.Bytes()); err != nil {
+		return "", fmt.Errorf("unable to write sealed container: %w", err)
+	}
+
+	return fmt.Sprintf("sha256:%s", hex.EncodeToString(digest[:])), nil
+}
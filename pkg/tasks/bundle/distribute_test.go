@@ -0,0 +1,158 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/awnumar/memguard"
+	"golang.org/x/crypto/nacl/box"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/distribution"
+	"github.com/elastic/harp/pkg/container"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+func Test_DistributeTask(t *testing.T) {
+	b, err := bundle.FromMap(map[string]bundle.KV{
+		"app/production/shop/frontend/1.0.0/web/config":  {"api_url": "https://shop.example.com", "session_key": "s3cr3t"},
+		"app/production/shop/backend/1.0.0/api/database": {"password": "p4ssw0rd"},
+		"infra/production/monitoring/agent":              {"token": "m0n1t0r"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub, priv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := distribution.ParseSpec(strings.NewReader(fmt.Sprintf(`apiVersion: harp.elastic.co/v1
+kind: DistributionSpec
+spec:
+  distributions:
+  - name: frontend
+    selectors: [{glob: "app/**/frontend/**"}]
+    keys: [{packages: "**", allow: [api_url]}]
+    recipients: [%[1]q]
+    output: frontend.bundle
+  - name: backend
+    selectors: [{cso: app/production/shop/backend}]
+    recipients: [%[1]q]
+    output: backend.bundle
+`, base64.RawURLEncoding.EncodeToString(pub[:]))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		desc          string
+		failOnOrphans bool
+		wantErr       error
+	}{
+		{
+			desc: "orphans reported",
+		},
+		{
+			desc:          "orphans rejected",
+			failOnOrphans: true,
+			wantErr:       ErrOrphanPackages,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var out bytes.Buffer
+			bundles := map[string]*bytes.Buffer{}
+			task := &DistributeTask{
+				ContainerReader: containerReader(t, b),
+				Spec:            spec,
+				BundleWriter: func(path string) tasks.WriterProvider {
+					bundles[path] = &bytes.Buffer{}
+					return bufferWriter(bundles[path])
+				},
+				OutputWriter:  bufferWriter(&out),
+				FailOnOrphans: tC.failOnOrphans,
+			}
+
+			err := task.Run(context.Background())
+			if tC.wantErr != nil {
+				if !errors.Is(err, tC.wantErr) {
+					t.Fatalf("expected %v, got %v", tC.wantErr, err)
+				}
+				if len(bundles) != 0 || out.Len() != 0 {
+					t.Fatalf("nothing must be written on failure")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var manifest DistributionManifest
+			if err := json.Unmarshal(out.Bytes(), &manifest); err != nil {
+				t.Fatalf("unable to decode manifest: %v", err)
+			}
+			if len(manifest.Orphans) != 1 || manifest.Orphans[0] != "infra/production/monitoring/agent" {
+				t.Fatalf("unexpected orphans %v", manifest.Orphans)
+			}
+			if len(manifest.Distributions) != 2 {
+				t.Fatalf("expected 2 distributions, got %d", len(manifest.Distributions))
+			}
+
+			for _, d := range manifest.Distributions {
+				raw := bundles[d.Output].Bytes()
+				digest := sha256.Sum256(raw)
+				if d.Digest != "sha256:"+hex.EncodeToString(digest[:]) {
+					t.Fatalf("%s: digest mismatch", d.Name)
+				}
+
+				// Recipients can unseal their sub-bundle
+				sealed, err := container.Load(bytes.NewReader(raw))
+				if err != nil {
+					t.Fatalf("%s: unable to load container: %v", d.Name, err)
+				}
+				identity := append([]byte{}, priv[:]...)
+				c, err := container.Unseal(sealed, memguard.NewBufferFromBytes(identity))
+				if err != nil {
+					t.Fatalf("%s: unable to unseal container: %v", d.Name, err)
+				}
+				sub, err := bundle.FromContainer(c)
+				if err != nil {
+					t.Fatalf("%s: unable to load bundle: %v", d.Name, err)
+				}
+				if len(sub.Packages) != d.Packages || d.Packages != 1 {
+					t.Fatalf("%s: expected a single package, got %d", d.Name, len(sub.Packages))
+				}
+				if d.Name == "frontend" && (d.RemovedKeys != 1 || len(sub.Packages[0].Secrets.Data) != 1) {
+					t.Fatalf("frontend: expected session_key to be removed")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/elastic/harp/pkg/bundle/docs"
+	"github.com/elastic/harp/pkg/sdk/types"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// DocsTask implements bundle documentation catalog generation task.
+type DocsTask struct {
+	ContainerReader tasks.ReaderProvider
+	OutputPath      string
+	ThemePath       string
+}
+
+// Capabilities returns the task required capabilities.
+func (t *DocsTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Run the task.
+func (t *DocsTask) Run(ctx context.Context) error {
+	// Check arguments
+	if types.IsNil(t.ContainerReader) {
+		return fmt.Errorf("unable to run task with a nil containerReader provider")
+	}
+	if t.OutputPath == "" {
+		return fmt.Errorf("unable to run task with a blank output path")
+	}
+
+	// Load bundle
+	b, err := loadBundle(ctx, t.ContainerReader)
+	if err != nil {
+		return err
+	}
+
+	// Select theme
+	theme := docs.DefaultTheme()
+	if t.ThemePath != "" {
+		theme, err = docs.LoadTheme(t.ThemePath)
+		if err != nil {
+			return fmt.Errorf("unable to load documentation theme: %w", err)
+		}
+	}
+
+	// Render catalog
+	files, err := docs.Render(docs.Build(b), theme)
+	if err != nil {
+		return fmt.Errorf("unable to render documentation catalog: %w", err)
+	}
+
+	// Write site
+	if err := docs.Write(t.OutputPath, files); err != nil {
+		return fmt.Errorf("unable to write documentation catalog: %w", err)
+	}
+
+	// No error
+	return nil
+}
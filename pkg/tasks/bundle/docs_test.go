@@ -0,0 +1,75 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	pkgbundle "github.com/elastic/harp/pkg/bundle"
+)
+
+func TestDocsTask(t *testing.T) {
+	b := &bundlev1.Bundle{
+		Packages: []*bundlev1.Package{
+			{
+				Name: "app/production/security/harp/v1.0.0/server/database",
+				Annotations: map[string]string{
+					pkgbundle.DescriptionAnnotation: "Database credentials",
+					pkgbundle.OwnerAnnotation:       "security",
+				},
+				Secrets: &bundlev1.SecretChain{
+					Data: []*bundlev1.KV{
+						{Key: "PASSWORD", Type: "string", Value: []byte("secret-value")},
+					},
+				},
+			},
+		},
+	}
+
+	// Missing output
+	if err := (&DocsTask{ContainerReader: containerReader(t, b)}).Run(context.Background()); err == nil {
+		t.Fatal("error should be raised for blank output path")
+	}
+
+	out := filepath.Join(t.TempDir(), "site")
+	task := &DocsTask{
+		ContainerReader: containerReader(t, b),
+		OutputPath:      out,
+	}
+	if err := task.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, name := range []string{"index.md", "index.html"} {
+		body, err := ioutil.ReadFile(filepath.Join(out, name))
+		if err != nil {
+			t.Fatalf("unable to read '%s': %v", name, err)
+		}
+		if !strings.Contains(string(body), "Database credentials") {
+			t.Errorf("'%s' must contain package description", name)
+		}
+		if strings.Contains(string(body), "secret-value") {
+			t.Errorf("'%s' must not contain secret values", name)
+		}
+	}
+}
@@ -22,23 +22,45 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
 
 	"github.com/jmespath/go-jmespath"
 
 	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
 	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/secret"
+	csov1 "github.com/elastic/harp/pkg/cso/v1"
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
 	"github.com/elastic/harp/pkg/sdk/types"
 	"github.com/elastic/harp/pkg/tasks"
 )
 
+const (
+	// PathSortLexical sorts listed paths lexicographically.
+	PathSortLexical = "lexical"
+	// PathSortCSO sorts listed paths using the canonical CSO order.
+	PathSortCSO = "cso"
+)
+
 // DumpTask implements secret-container dumping task.
 type DumpTask struct {
 	ContainerReader tasks.ReaderProvider
 	OutputWriter    tasks.WriterProvider
 	PathOnly        bool
+	PathSort        string
 	DataOnly        bool
 	MetadataOnly    bool
+	CodecOnly       bool
 	JMESPathFilter  string
+	IncludeArchived bool
+	// ValueDecryptor dumps externally encrypted values as cleartext.
+	ValueDecryptor       bundle.ValueDecryptor
+	DecryptFailurePolicy bundle.DecryptFailurePolicy
+}
+
+// Capabilities returns the task required capabilities.
+func (t *DumpTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
 }
 
 // Run the task.
@@ -50,18 +72,33 @@ func (t *DumpTask) Run(ctx context.Context) error {
 	)
 
 	// Create input reader
+	endLoad := cmdutil.TracePhase(ctx, "load")
 	reader, err = t.ContainerReader(ctx)
 	if err != nil {
 		return fmt.Errorf("unable to open input bundle: %w", err)
 	}
 
 	// Load bundle
-	b, err := bundle.FromContainerReader(reader)
+	opts := []bundle.LoadOption{bundle.WithLoadContext(ctx)}
+	if t.ValueDecryptor != nil {
+		opts = append(opts,
+			bundle.WithValueDecryptor(t.ValueDecryptor),
+			bundle.WithDecryptFailurePolicy(t.DecryptFailurePolicy),
+		)
+	}
+	b, err := bundle.FromContainerReader(reader, opts...)
 	if err != nil {
 		return fmt.Errorf("unable to load bundle content: %w", err)
 	}
+	endLoad()
+
+	// Hide archived packages
+	if !t.IncludeArchived {
+		b = bundle.WithoutArchived(b)
+	}
 
 	// Create output writer
+	defer cmdutil.TracePhase(ctx, "write")()
 	writer, err := t.OutputWriter(ctx)
 	if err != nil {
 		return fmt.Errorf("unable to open writer: %w", err)
@@ -79,6 +116,10 @@ func (t *DumpTask) Run(ctx context.Context) error {
 		return t.dumpPath(writer, b)
 	}
 
+	if t.CodecOnly {
+		return t.dumpCodec(writer, b)
+	}
+
 	if t.JMESPathFilter != "" {
 		return t.dumpFilter(writer, b)
 	}
@@ -177,6 +218,17 @@ func (t *DumpTask) dumpPath(writer io.Writer, b *bundlev1.Bundle) error {
 		return fmt.Errorf("unable to extract bundle paths: %w", err)
 	}
 
+	// Apply requested order, bundle order is kept by default
+	switch t.PathSort {
+	case "":
+	case PathSortLexical:
+		sort.Strings(paths)
+	case PathSortCSO:
+		csov1.SortPaths(paths)
+	default:
+		return fmt.Errorf("unsupported path sort order '%s'", t.PathSort)
+	}
+
 	// Print a xargs compatible list
 	for _, p := range paths {
 		_, err = fmt.Fprintf(writer, "%s\n", p)
@@ -188,6 +240,51 @@ func (t *DumpTask) dumpPath(writer io.Writer, b *bundlev1.Bundle) error {
 	return nil
 }
 
+func (t *DumpTask) dumpCodec(writer io.Writer, b *bundlev1.Bundle) error {
+	// Check arguments
+	if types.IsNil(writer) {
+		return fmt.Errorf("unable to process nil writer")
+	}
+	if b == nil {
+		return fmt.Errorf("unable to process nil bundle")
+	}
+
+	type codecReport struct {
+		secret.CodecInfo
+		Error string `json:"error,omitempty"`
+	}
+
+	codecMap := bundle.KV{}
+
+	// Inspect each package values
+	for _, p := range b.Packages {
+		values := map[string]codecReport{}
+		if p.Secrets != nil {
+			for _, s := range p.Secrets.Data {
+				info, err := secret.Inspect(s.Value)
+
+				// Report error as value information
+				r := codecReport{CodecInfo: info}
+				if err != nil {
+					r.Error = err.Error()
+				}
+
+				values[s.Key] = r
+			}
+		}
+
+		// Assign to package
+		codecMap[p.Name] = values
+	}
+
+	// Encode as JSON
+	if err := json.NewEncoder(writer).Encode(codecMap); err != nil {
+		return fmt.Errorf("unable to marshal JSON bundle codec information: %w", err)
+	}
+
+	return nil
+}
+
 func (t *DumpTask) dumpFilter(writer io.Writer, b *bundlev1.Bundle) error {
 	// Check arguments
 	if types.IsNil(writer) {
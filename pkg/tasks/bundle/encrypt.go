@@ -33,6 +33,12 @@ type EncryptTask struct {
 	ContainerReader tasks.ReaderProvider
 	OutputWriter    tasks.WriterProvider
 	Transformer     value.Transformer
+	Algorithm       string
+}
+
+// Capabilities returns the task required capabilities.
+func (t *EncryptTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
 }
 
 // Run the task.
@@ -61,6 +67,16 @@ func (t *EncryptTask) Run(ctx context.Context) error {
 		return fmt.Errorf("unable to apply bundle transformation: %w", err)
 	}
 
+	// Record the encryption algorithm
+	if t.Algorithm != "" {
+		for _, p := range b.Packages {
+			if p.Secrets.Annotations == nil {
+				p.Secrets.Annotations = map[string]string{}
+			}
+			p.Secrets.Annotations[bundle.LockedAlgorithmAnnotation] = t.Algorithm
+		}
+	}
+
 	// Create output writer
 	writer, err = t.OutputWriter(ctx)
 	if err != nil {
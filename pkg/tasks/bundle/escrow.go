@@ -0,0 +1,285 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/awnumar/memguard"
+	"github.com/jmespath/go-jmespath"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/escrow"
+	"github.com/elastic/harp/pkg/bundle/selector"
+	"github.com/elastic/harp/pkg/sdk/types"
+	"github.com/elastic/harp/pkg/tasks"
+	containertask "github.com/elastic/harp/pkg/tasks/container"
+)
+
+// EscrowTask implements secret escrow export task.
+type EscrowTask struct {
+	ContainerReader tasks.ReaderProvider
+	RecipientReader tasks.ReaderProvider
+	Signer          containertask.SignerProvider
+	OutputWriter    tasks.WriterProvider
+	// ManifestWriter optionally receives a copy of the signed manifest
+	// envelope, which is always attached to the escrow bundle.
+	ManifestWriter tasks.WriterProvider
+	// Selector is the JMESPath expression selecting escrowed packages.
+	Selector string
+
+	result *EscrowResult
+}
+
+// EscrowResult describes an escrow task execution.
+type EscrowResult struct {
+	tasks.Result
+	KeyID    string `json:"keyId"`
+	Packages int    `json:"packages"`
+	Entries  int    `json:"entries"`
+}
+
+// Result returns the last execution result.
+func (t *EscrowTask) Result() interface{} {
+	return t.result
+}
+
+// Capabilities returns the task required capabilities.
+func (t *EscrowTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Run the task.
+func (t *EscrowTask) Run(ctx context.Context) error {
+	t.result = &EscrowResult{}
+
+	// Check arguments
+	if types.IsNil(t.ContainerReader) {
+		return fmt.Errorf("unable to run task with a nil containerReader provider")
+	}
+	if types.IsNil(t.RecipientReader) {
+		return fmt.Errorf("unable to run task with a nil recipientReader provider")
+	}
+	if t.Signer == nil {
+		return fmt.Errorf("unable to run task with a nil signer provider")
+	}
+	if types.IsNil(t.OutputWriter) {
+		return fmt.Errorf("unable to run task with a nil outputWriter provider")
+	}
+	if t.Selector == "" {
+		return fmt.Errorf("escrow selector must be defined")
+	}
+
+	// Compile selector
+	exp, err := jmespath.Compile(t.Selector)
+	if err != nil {
+		return fmt.Errorf("unable to compile escrow selector '%s': %w", t.Selector, err)
+	}
+
+	// Load recipient
+	recipientReader, err := t.RecipientReader(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open recipient reader: %w", err)
+	}
+	rawRecipient, err := ioutil.ReadAll(recipientReader)
+	if err != nil {
+		return fmt.Errorf("unable to read recipient: %w", err)
+	}
+	recipient, err := escrow.ParseRecipient(rawRecipient)
+	if err != nil {
+		return err
+	}
+
+	// Load signing key
+	signer, err := t.Signer(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Load bundle
+	reader, err := t.ContainerReader(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open input bundle: %w", err)
+	}
+	b, err := bundle.FromContainerReader(reader)
+	if err != nil {
+		return fmt.Errorf("unable to load bundle content: %w", err)
+	}
+
+	// Export escrowed values
+	out, manifest, err := escrow.Export(b, selector.MatchJMESPath(exp), recipient)
+	if err != nil {
+		return fmt.Errorf("unable to export escrowed values: %w", err)
+	}
+	if len(manifest.Entries) == 0 {
+		t.result.Warn("no secret value matches the escrow selector")
+	}
+
+	// Sign manifest
+	envelope, err := escrow.Sign(manifest, signer)
+	if err != nil {
+		return fmt.Errorf("unable to sign escrow manifest: %w", err)
+	}
+	if err := escrow.Attach(out, envelope); err != nil {
+		return err
+	}
+
+	// Create output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output bundle: %w", err)
+	}
+
+	// Dump escrow bundle
+	if err := bundle.ToContainerWriter(writer, out); err != nil {
+		return fmt.Errorf("unable to dump escrow bundle: %w", err)
+	}
+
+	// Export manifest
+	if t.ManifestWriter != nil {
+		manifestWriter, err := t.ManifestWriter(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to open manifest writer: %w", err)
+		}
+		enc := json.NewEncoder(manifestWriter)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(envelope); err != nil {
+			return fmt.Errorf("unable to encode escrow manifest: %w", err)
+		}
+	}
+
+	t.result.KeyID = escrow.KeyID(recipient)
+	t.result.Packages = len(out.Packages)
+	t.result.Entries = len(manifest.Entries)
+
+	// No error
+	return nil
+}
+
+// EscrowRecoverTask implements escrowed secret recovery task.
+type EscrowRecoverTask struct {
+	ContainerReader tasks.ReaderProvider
+	// KeyResolver provides the operator keys used to verify the manifest.
+	KeyResolver  containertask.KeyResolverProvider
+	EscrowKey    *memguard.LockedBuffer
+	OutputWriter tasks.WriterProvider
+	Path         string
+	// SecretKey restricts recovery to a single value, all values of the
+	// package are recovered as JSON when not defined.
+	SecretKey string
+}
+
+// Capabilities returns the task required capabilities.
+func (t *EscrowRecoverTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Run the task.
+func (t *EscrowRecoverTask) Run(ctx context.Context) error {
+	// Check arguments
+	if types.IsNil(t.ContainerReader) {
+		return fmt.Errorf("unable to run task with a nil containerReader provider")
+	}
+	if t.KeyResolver == nil {
+		return fmt.Errorf("unable to run task with a nil keyResolver provider")
+	}
+	if t.EscrowKey == nil {
+		return fmt.Errorf("escrow key must be defined")
+	}
+	if types.IsNil(t.OutputWriter) {
+		return fmt.Errorf("unable to run task with a nil outputWriter provider")
+	}
+	if t.Path == "" {
+		return fmt.Errorf("secret path must be defined")
+	}
+
+	// Decode escrow key
+	priv, err := escrow.ParsePrivateKey(t.EscrowKey.String())
+	if err != nil {
+		return err
+	}
+	defer memguard.WipeBytes(priv[:])
+
+	// Load verification keys
+	resolver, err := t.KeyResolver(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Load escrow bundle
+	reader, err := t.ContainerReader(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open input bundle: %w", err)
+	}
+	b, err := bundle.FromContainerReader(reader)
+	if err != nil {
+		return fmt.Errorf("unable to load bundle content: %w", err)
+	}
+
+	// Verify manifest
+	envelope, err := escrow.Envelope(b)
+	if err != nil {
+		return err
+	}
+	manifest, err := escrow.Verify(envelope, resolver)
+	if err != nil {
+		return fmt.Errorf("unable to verify escrow manifest: %w", err)
+	}
+
+	// Select entries
+	keys := []string{}
+	for _, e := range manifest.Entries {
+		if e.Path == t.Path && (t.SecretKey == "" || e.Key == t.SecretKey) {
+			keys = append(keys, e.Key)
+		}
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("unable to recover '%s': %w", t.Path, escrow.ErrEntryNotFound)
+	}
+
+	// Recover values
+	values := map[string]interface{}{}
+	for _, k := range keys {
+		v, errRecover := escrow.Recover(b, manifest, t.Path, k, priv)
+		if errRecover != nil {
+			return errRecover
+		}
+		values[k] = v
+	}
+
+	// Prepare output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get output writer: %w", err)
+	}
+
+	if t.SecretKey != "" {
+		fmt.Fprintf(writer, "%s", values[t.SecretKey])
+	} else {
+		// Dump the secret value
+		if err := json.NewEncoder(writer).Encode(values); err != nil {
+			return fmt.Errorf("unable to encode secret value as json: %w", err)
+		}
+	}
+
+	// No error
+	return nil
+}
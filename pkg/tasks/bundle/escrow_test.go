@@ -0,0 +1,206 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/awnumar/memguard"
+	"golang.org/x/crypto/nacl/box"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/escrow"
+	"github.com/elastic/harp/pkg/bundle/secret"
+	"github.com/elastic/harp/pkg/container/attestation"
+)
+
+func escrowTestBundle(t *testing.T) *bundlev1.Bundle {
+	t.Helper()
+
+	pkg := func(name, annotation string, kv ...string) *bundlev1.Package {
+		p := &bundlev1.Package{
+			Name:    name,
+			Secrets: &bundlev1.SecretChain{},
+		}
+		if annotation != "" {
+			p.Annotations = map[string]string{"escrow": annotation}
+		}
+		for i := 0; i < len(kv); i += 2 {
+			packed, err := secret.Pack(kv[i+1])
+			if err != nil {
+				t.Fatalf("unable to pack secret: %v", err)
+			}
+			p.Secrets.Data = append(p.Secrets.Data, &bundlev1.KV{Key: kv[i], Type: "string", Value: packed})
+		}
+		return p
+	}
+
+	return &bundlev1.Bundle{
+		Packages: []*bundlev1.Package{
+			pkg("infra/pki/root-ca", "required", "key", "root-ca-key", "cert", "root-ca-cert"),
+			pkg("app/production/codesign", "required", "key", "codesign-key"),
+			pkg("app/production/database", "optional", "password", "db-password"),
+			pkg("app/production/cache", "", "password", "cache-password"),
+		},
+	}
+}
+
+func TestEscrowTask(t *testing.T) {
+	recipientPub, recipientPriv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	operatorPub, operatorPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer := func(context.Context) (crypto.Signer, error) {
+		return operatorPriv, nil
+	}
+	resolver := func(pub crypto.PublicKey) func(context.Context) (attestation.KeyResolver, error) {
+		return func(context.Context) (attestation.KeyResolver, error) {
+			return func(attestation.Signature) (crypto.PublicKey, error) {
+				return pub, nil
+			}, nil
+		}
+	}
+
+	// Export escrowed packages
+	escrowed := &bytes.Buffer{}
+	manifest := &bytes.Buffer{}
+	task := &EscrowTask{
+		ContainerReader: containerReader(t, escrowTestBundle(t)),
+		RecipientReader: bytesReader([]byte(base64.RawURLEncoding.EncodeToString(recipientPub[:]) + "\n")),
+		Signer:          signer,
+		OutputWriter:    bufferWriter(escrowed),
+		ManifestWriter:  bufferWriter(manifest),
+		Selector:        "annotations.escrow == 'required'",
+	}
+	if err := task.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	res := task.Result().(*EscrowResult)
+	if res.Packages != 2 || res.Entries != 3 {
+		t.Errorf("expected 2 packages and 3 entries, got %d and %d", res.Packages, res.Entries)
+	}
+	if res.KeyID != escrow.KeyID(recipientPub) {
+		t.Errorf("unexpected key id '%s'", res.KeyID)
+	}
+	if !strings.Contains(manifest.String(), escrow.ManifestPayloadType) {
+		t.Errorf("unexpected manifest '%s'", manifest.String())
+	}
+
+	// Only selected packages are extracted
+	out, err := bundle.FromContainerReader(bytes.NewReader(escrowed.Bytes()))
+	if err != nil {
+		t.Fatalf("unable to load escrow bundle: %v", err)
+	}
+	names := []string{}
+	for _, p := range out.Packages {
+		names = append(names, p.Name)
+	}
+	if strings.Join(names, ",") != "app/production/codesign,infra/pki/root-ca" {
+		t.Errorf("unexpected escrowed packages %v", names)
+	}
+
+	recover := func(path, key string, pub crypto.PublicKey) (string, error) {
+		buf := &bytes.Buffer{}
+		rt := &EscrowRecoverTask{
+			ContainerReader: bytesReader(escrowed.Bytes()),
+			KeyResolver:     resolver(pub),
+			EscrowKey:       memguard.NewBufferFromBytes([]byte(base64.RawURLEncoding.EncodeToString(recipientPriv[:]))),
+			OutputWriter:    bufferWriter(buf),
+			Path:            path,
+			SecretKey:       key,
+		}
+		err := rt.Run(context.Background())
+		return buf.String(), err
+	}
+
+	// Single entry recovery
+	v, err := recover("infra/pki/root-ca", "key", operatorPub)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v != "root-ca-key" {
+		t.Errorf("expected 'root-ca-key', got '%s'", v)
+	}
+
+	// Package recovery
+	v, err = recover("infra/pki/root-ca", "", operatorPub)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v != `{"cert":"root-ca-cert","key":"root-ca-key"}`+"\n" {
+		t.Errorf("unexpected package values '%s'", v)
+	}
+
+	// Non escrowed package
+	if _, err := recover("app/production/database", "password", operatorPub); !errors.Is(err, escrow.ErrEntryNotFound) {
+		t.Errorf("expected entry not found error, got %v", err)
+	}
+
+	// Manifest signed by another operator
+	if _, err := recover("infra/pki/root-ca", "key", otherPub); !errors.Is(err, attestation.ErrInvalidSignature) {
+		t.Errorf("expected invalid signature error, got %v", err)
+	}
+}
+
+func TestEscrowTask_NoMatch(t *testing.T) {
+	recipientPub, _, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, operatorPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task := &EscrowTask{
+		ContainerReader: containerReader(t, escrowTestBundle(t)),
+		RecipientReader: bytesReader([]byte(base64.RawURLEncoding.EncodeToString(recipientPub[:]))),
+		Signer: func(context.Context) (crypto.Signer, error) {
+			return operatorPriv, nil
+		},
+		OutputWriter: bufferWriter(&bytes.Buffer{}),
+		Selector:     "annotations.escrow == 'unknown'",
+	}
+	if err := task.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	res := task.Result().(*EscrowResult)
+	if res.Entries != 0 || len(res.Warnings) != 1 {
+		t.Errorf("expected no entry and a warning, got %+v", res)
+	}
+}
@@ -27,6 +27,7 @@ import (
 	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
 	"github.com/elastic/harp/pkg/bundle"
 	"github.com/elastic/harp/pkg/bundle/selector"
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
 	"github.com/elastic/harp/pkg/tasks"
 )
 
@@ -37,12 +38,38 @@ type FilterTask struct {
 	KeepPaths       []string
 	ExcludePaths    []string
 	JMESPath        string
+
+	result *FilterResult
+}
+
+// FilterResult describes a filter task execution.
+type FilterResult struct {
+	tasks.Result
+	// Packages is the active package count of the input bundle.
+	Packages int `json:"packages"`
+	Kept     int `json:"kept"`
+	Removed  int `json:"removed"`
+	// Archived packages are kept without being filtered.
+	Archived int `json:"archived"`
+}
+
+// Result returns the last execution result.
+func (t *FilterTask) Result() interface{} {
+	return t.result
+}
+
+// Capabilities returns the task required capabilities.
+func (t *FilterTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
 }
 
 // Run the task.
 //nolint:gocognit,gocyclo // to refactor
 func (t *FilterTask) Run(ctx context.Context) error {
+	t.result = &FilterResult{}
+
 	// Create input reader
+	endLoad := cmdutil.TracePhase(ctx, "load")
 	reader, err := t.ContainerReader(ctx)
 	if err != nil {
 		return fmt.Errorf("unable to open input bundle: %w", err)
@@ -53,6 +80,22 @@ func (t *FilterTask) Run(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("unable to load bundle content: %w", err)
 	}
+	endLoad()
+
+	// Archived packages are not filtered but kept in the container
+	endFilter := cmdutil.TracePhase(ctx, "filter")
+	archived := []*bundlev1.Package{}
+	active := []*bundlev1.Package{}
+	for _, p := range b.Packages {
+		if bundle.IsArchived(p) {
+			archived = append(archived, p)
+			continue
+		}
+		active = append(active, p)
+	}
+	b.Packages = active
+	t.result.Packages = len(active)
+	t.result.Archived = len(archived)
 
 	// Clean up bundle
 	if len(t.KeepPaths) > 0 {
@@ -61,7 +104,7 @@ func (t *FilterTask) Run(ctx context.Context) error {
 		for _, includePath := range t.KeepPaths {
 			includePathRegexp, errInclude := regexp.Compile(includePath)
 			if errInclude != nil {
-				return fmt.Errorf("unable to compile keep regexp '%s': %w", includePath, err)
+				return fmt.Errorf("unable to compile keep regexp '%s': %w", includePath, errInclude)
 			}
 
 			for _, p := range b.Packages {
@@ -81,7 +124,7 @@ func (t *FilterTask) Run(ctx context.Context) error {
 		for _, excludePath := range t.ExcludePaths {
 			excludePathRegexp, errExclude := regexp.Compile(excludePath)
 			if errExclude != nil {
-				return fmt.Errorf("unable to compile exclusion regexp '%s': %w", excludePath, err)
+				return fmt.Errorf("unable to compile exclusion regexp '%s': %w", excludePath, errExclude)
 			}
 
 			for _, p := range b.Packages {
@@ -101,7 +144,7 @@ func (t *FilterTask) Run(ctx context.Context) error {
 
 		// Compile expression first
 		exp, errJMESPath := jmespath.Compile(t.JMESPath)
-		if err != nil {
+		if errJMESPath != nil {
 			return fmt.Errorf("unable to compile JMESPath filter '%s': %w", t.JMESPath, errJMESPath)
 		}
 
@@ -119,7 +162,15 @@ func (t *FilterTask) Run(ctx context.Context) error {
 		b.Packages = pkgs
 	}
 
+	t.result.Kept = len(b.Packages)
+	t.result.Removed = t.result.Packages - t.result.Kept
+
+	// Restore archived packages
+	b.Packages = append(b.Packages, archived...)
+	endFilter()
+
 	// Create output writer
+	defer cmdutil.TracePhase(ctx, "write")()
 	writer, err := t.OutputWriter(ctx)
 	if err != nil {
 		return fmt.Errorf("unable to open output bundle: %w", err)
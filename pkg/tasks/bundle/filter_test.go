@@ -0,0 +1,147 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+)
+
+func filterFixture() *testbundle.Builder {
+	b := testbundle.New()
+	b.Package("app/production/security/harp/v1.0.0/server/database").
+		Secret("user", "admin").
+		Secret("password", testbundle.Random(32)).
+		Annotation("owner", "security")
+	b.Package("app/production/security/harp/v1.0.0/server/tls").
+		Secret("key", testbundle.Random(64)).
+		Annotation("owner", "platform")
+	b.Package("app/staging/security/harp/v1.0.0/server/database").
+		Secret("user", "admin").
+		Secret("password", testbundle.Random(32))
+	b.Package("platform/production/security/eu-central-1/postgres/dba").
+		Secret("password", testbundle.Random(32))
+	return b
+}
+
+func TestFilterTask(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		keep     []string
+		exclude  []string
+		jmesPath string
+		want     []string
+		wantErr  bool
+	}{
+		{
+			desc: "no filter",
+			want: []string{
+				"app/production/security/harp/v1.0.0/server/database",
+				"app/production/security/harp/v1.0.0/server/tls",
+				"app/staging/security/harp/v1.0.0/server/database",
+				"platform/production/security/eu-central-1/postgres/dba",
+			},
+		},
+		{
+			desc: "keep",
+			keep: []string{"^app/production/"},
+			want: []string{
+				"app/production/security/harp/v1.0.0/server/database",
+				"app/production/security/harp/v1.0.0/server/tls",
+			},
+		},
+		{
+			desc:    "keep and exclude",
+			keep:    []string{"database$"},
+			exclude: []string{"^app/staging/"},
+			want: []string{
+				"app/production/security/harp/v1.0.0/server/database",
+			},
+		},
+		{
+			desc:     "jmespath",
+			jmesPath: "annotations.owner == 'security'",
+			want: []string{
+				"app/production/security/harp/v1.0.0/server/database",
+			},
+		},
+		{
+			desc:    "invalid keep regexp",
+			keep:    []string{"("},
+			wantErr: true,
+		},
+		{
+			desc:     "invalid jmespath",
+			jmesPath: "annotations.[",
+			wantErr:  true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var out bytes.Buffer
+			task := &FilterTask{
+				ContainerReader: testbundle.Reader(t, filterFixture().Build()),
+				OutputWriter:    testbundle.Writer(&out),
+				KeepPaths:       tC.keep,
+				ExcludePaths:    tC.exclude,
+				JMESPath:        tC.jmesPath,
+			}
+
+			err := task.Run(context.Background())
+			if (err != nil) != tC.wantErr {
+				t.Fatalf("error during the call, error = %v, wantErr %v", err, tC.wantErr)
+			}
+			if tC.wantErr {
+				return
+			}
+
+			got := []string{}
+			for _, p := range testbundle.Load(t, &out).Packages {
+				got = append(got, p.Name)
+			}
+			sort.Strings(got)
+
+			if diff := cmp.Diff(got, tC.want); diff != "" {
+				t.Errorf("%q. FilterTask.Run():\n-got/+want\ndiff %s", tC.desc, diff)
+			}
+		})
+	}
+}
+
+func BenchmarkFilterTask(b *testing.B) {
+	reader := testbundle.Reader(b, testbundle.RandomPackages(1000, 1))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var out bytes.Buffer
+		task := &FilterTask{
+			ContainerReader: reader,
+			OutputWriter:    testbundle.Writer(&out),
+			KeepPaths:       []string{"^app/production/"},
+		}
+		if err := task.Run(context.Background()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/sdk/types"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// HistoryTask implements secret key history display task.
+type HistoryTask struct {
+	ContainerReader tasks.ReaderProvider
+	OutputWriter    tasks.WriterProvider
+	Path            string
+	Field           string
+	JSONOutput      bool
+}
+
+// FieldHistory describes the history of a secret key.
+type FieldHistory struct {
+	Field   string                `json:"field"`
+	Entries []bundle.HistoryEntry `json:"entries"`
+}
+
+// Capabilities returns the task required capabilities.
+func (t *HistoryTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Run the task.
+func (t *HistoryTask) Run(ctx context.Context) error {
+	// Check arguments
+	if types.IsNil(t.ContainerReader) {
+		return fmt.Errorf("unable to run task with a nil containerReader provider")
+	}
+	if types.IsNil(t.OutputWriter) {
+		return fmt.Errorf("unable to run task with a nil outputWriter provider")
+	}
+	if t.Path == "" {
+		return fmt.Errorf("package path must not be blank")
+	}
+
+	// Load bundle
+	b, err := loadBundle(ctx, t.ContainerReader)
+	if err != nil {
+		return err
+	}
+
+	// Retrieve package
+	path := strings.TrimPrefix(t.Path, "/")
+	var keys []string
+	histories := []FieldHistory{}
+	for _, p := range b.Packages {
+		if p == nil || p.Name != path {
+			continue
+		}
+
+		keys = bundle.HistoryKeys(p)
+		if t.Field != "" {
+			keys = []string{t.Field}
+		}
+
+		for _, k := range keys {
+			entries, err := bundle.History(p, k)
+			if err != nil {
+				return err
+			}
+			histories = append(histories, FieldHistory{Field: k, Entries: entries})
+		}
+	}
+	if keys == nil {
+		return fmt.Errorf("unable to retrieve '%s': %w", t.Path, bundle.ErrPackageNotFound)
+	}
+
+	// Create output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output writer: %w", err)
+	}
+
+	// Export as JSON
+	if t.JSONOutput {
+		enc := json.NewEncoder(writer)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(histories); err != nil {
+			return fmt.Errorf("unable to encode history: %w", err)
+		}
+		return nil
+	}
+
+	// Export as text
+	tw := tabwriter.NewWriter(writer, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FIELD\tTIMESTAMP\tACTOR\tOPERATION\tOLD DIGEST")
+	for _, h := range histories {
+		for _, e := range h.Entries {
+			old := e.OldDigest
+			if old == "" {
+				old = "-"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", h.Field, e.Timestamp, e.Actor, e.Operation, old)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("unable to write history: %w", err)
+	}
+
+	// No error
+	return nil
+}
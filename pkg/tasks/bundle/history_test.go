@@ -0,0 +1,191 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+func TestPatchTask_History(t *testing.T) {
+	b := testbundle.New().
+		Package("app/production/database").Secret("user", "admin").Secret("password", "v0").
+		Build()
+
+	spec := `apiVersion: harp.elastic.co/v1
+kind: BundlePatch
+meta:
+  name: rotate
+spec:
+  rules:
+    - selector:
+        matchPath:
+          strict: app/production/database
+      package:
+        data:
+          kv:
+            update:
+              password: "{{ .Values.password }}"
+`
+
+	// Apply two successive patches
+	for i, value := range []string{"v1", "v2"} {
+		var out bytes.Buffer
+		err := (&PatchTask{
+			PatchReader:     func(context.Context) (io.Reader, error) { return strings.NewReader(spec), nil },
+			ContainerReader: testbundle.Reader(t, b),
+			OutputWriter:    testbundle.Writer(&out),
+			Values:          map[string]interface{}{"password": value},
+			Actor:           "alice@host",
+		}).Run(context.Background())
+		if err != nil {
+			t.Fatalf("patch %d: unexpected error: %v", i, err)
+		}
+		b = testbundle.Load(t, &out)
+	}
+
+	// Display history
+	var out bytes.Buffer
+	err := (&HistoryTask{
+		ContainerReader: testbundle.Reader(t, b),
+		OutputWriter:    testbundle.Writer(&out),
+		Path:            "app/production/database",
+		JSONOutput:      true,
+	}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := []FieldHistory{}
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("unable to decode history: %v", err)
+	}
+	if len(got) != 1 || got[0].Field != "password" {
+		t.Fatalf("unexpected history: %v", got)
+	}
+	if len(got[0].Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(got[0].Entries))
+	}
+	for _, e := range got[0].Entries {
+		if e.Actor != "alice@host" || e.Operation != "patch" || e.OldDigest == "" {
+			t.Errorf("unexpected entry: %+v", e)
+		}
+	}
+	if got[0].Entries[0].OldDigest == got[0].Entries[1].OldDigest {
+		t.Error("expected distinct previous value digests")
+	}
+}
+
+func TestSetTask_History(t *testing.T) {
+	b := testbundle.New().
+		Package("app/production/api").Secret("config", `{"debug":true}`).Secret("token", "t0").
+		Build()
+
+	var out bytes.Buffer
+	err := (&SetTask{
+		ContainerReader: testbundle.Reader(t, b),
+		OutputWriter:    testbundle.Writer(&out),
+		Path:            "app/production/api",
+		Field:           "config",
+		MergePatch:      `{"debug":false}`,
+		Actor:           "alice@host",
+	}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	p := testbundle.Load(t, &out).Packages[0]
+	if keys := bundle.HistoryKeys(p); len(keys) != 1 || keys[0] != "config" {
+		t.Fatalf("unexpected history keys: %v", keys)
+	}
+	entries, err := bundle.History(p, "config")
+	if err != nil {
+		t.Fatalf("unable to read history: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	if e := entries[0]; e.Actor != "alice@host" || e.Operation != "set" || e.OldDigest == "" {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+}
+
+func TestMergeTask_History(t *testing.T) {
+	base := testbundle.New().
+		Package("app/production/database").Secret("user", "admin").Secret("password", "v0").
+		Build()
+	overrides := testbundle.New().
+		Package("app/production/database").Secret("password", "v1").
+		Package("app/production/cache").Secret("password", "c0").
+		Build()
+
+	var out bytes.Buffer
+	err := (&MergeTask{
+		ContainerReaders: []tasks.ReaderProvider{testbundle.Reader(t, base), testbundle.Reader(t, overrides)},
+		OutputWriter:     testbundle.Writer(&out),
+		MergeStrategy:    bundle.MergeStrategyOverwrite,
+		Actor:            "bob@host",
+	}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := map[string][]string{}
+	for _, p := range testbundle.Load(t, &out).Packages {
+		for _, k := range bundle.HistoryKeys(p) {
+			entries, err := bundle.History(p, k)
+			if err != nil {
+				t.Fatalf("unable to read history: %v", err)
+			}
+			if len(entries) != 1 || entries[0].Actor != "bob@host" || entries[0].Operation != "merge" {
+				t.Errorf("unexpected '%s' history of '%s': %+v", k, p.Name, entries)
+			}
+			got[p.Name] = append(got[p.Name], k)
+		}
+	}
+
+	// Unchanged keys have no history
+	want := map[string][]string{
+		"app/production/database": {"password"},
+		"app/production/cache":    {"password"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected recorded keys: got %v, want %v", got, want)
+	}
+}
+
+func TestHistoryTask_NotFound(t *testing.T) {
+	var out bytes.Buffer
+	err := (&HistoryTask{
+		ContainerReader: testbundle.Reader(t, testbundle.New().Package("app/a").Secret("k", "v").Build()),
+		OutputWriter:    testbundle.Writer(&out),
+		Path:            "app/b",
+	}).Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), bundle.ErrPackageNotFound.Error()) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
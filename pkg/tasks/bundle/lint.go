@@ -0,0 +1,280 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/lint"
+	"github.com/elastic/harp/pkg/sdk/types"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// ErrLintViolations is raised when the lint report contains unwaived findings.
+var ErrLintViolations = errors.New("bundle has lint policy violations")
+
+// LintTask implements bundle policy linter task.
+type LintTask struct {
+	ContainerReader    tasks.ReaderProvider
+	PolicyReader       tasks.ReaderProvider
+	OutputWriter       tasks.WriterProvider
+	BundleWriter       tasks.WriterProvider
+	QuarantineFindings bool
+	Actor              string
+	// FailureMode stops the report at the first package with violations when
+	// set to tasks.FailFast.
+	FailureMode tasks.FailureMode
+
+	result *LintResult
+}
+
+// LintResult describes a lint task execution. Unwaived findings are reported
+// as failures.
+type LintResult struct {
+	tasks.Result
+	Packages    int `json:"packages"`
+	Rules       int `json:"rules"`
+	Findings    int `json:"findings"`
+	Violations  int `json:"violations"`
+	Waived      int `json:"waived"`
+	Quarantined int `json:"quarantined"`
+	Collisions  int `json:"collisions"`
+}
+
+// Result returns the last execution result.
+func (t *LintTask) Result() interface{} {
+	return t.result
+}
+
+// Capabilities returns the task required capabilities.
+func (t *LintTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Run the task.
+func (t *LintTask) Run(ctx context.Context) error {
+	t.result = &LintResult{}
+
+	// Check arguments
+	if types.IsNil(t.ContainerReader) {
+		return fmt.Errorf("unable to run task with a nil containerReader provider")
+	}
+	if types.IsNil(t.OutputWriter) {
+		return fmt.Errorf("unable to run task with a nil outputWriter provider")
+	}
+	if t.QuarantineFindings && types.IsNil(t.BundleWriter) {
+		return fmt.Errorf("unable to quarantine findings with a nil bundleWriter provider")
+	}
+
+	// Load policy
+	policy := lint.DefaultPolicy()
+	if t.PolicyReader != nil {
+		policyReader, err := t.PolicyReader(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to open policy: %w", err)
+		}
+
+		policy, err = lint.ParsePolicy(policyReader)
+		if err != nil {
+			return tasks.Usage(fmt.Errorf("unable to load policy: %w", err))
+		}
+	}
+
+	// Build rules
+	rules, err := policy.Rules()
+	if err != nil {
+		return tasks.Usage(fmt.Errorf("unable to prepare policy rules: %w", err))
+	}
+	t.result.Rules = len(rules)
+
+	// Create input reader
+	reader, err := t.ContainerReader(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open input bundle: %w", err)
+	}
+
+	// Load bundle
+	b, err := bundle.FromContainerReader(reader)
+	if err != nil {
+		return fmt.Errorf("unable to load bundle content: %w", err)
+	}
+
+	// Evaluate policy
+	report, err := lint.Evaluate(ctx, b, rules, policy.Spec.Waivers)
+	if err != nil {
+		return fmt.Errorf("unable to evaluate policy: %w", err)
+	}
+	if t.FailureMode == tasks.FailFast {
+		report.Findings = untilFirstViolation(report.Findings)
+	}
+	t.result.Packages = len(b.Packages)
+	t.result.Collisions = len(report.Collisions)
+	for _, f := range report.Findings {
+		t.result.Findings++
+		if f.Waived {
+			t.result.Waived++
+			continue
+		}
+		t.result.Violations++
+
+		item := f.Path
+		if f.Key != "" {
+			item = fmt.Sprintf("%s#%s", f.Path, f.Key)
+		}
+		t.result.Fail(item, fmt.Errorf("[%s] %s", f.RuleID, f.Message))
+	}
+	t.recordPackages(b, report)
+
+	// Create output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output writer: %w", err)
+	}
+
+	// Print report
+	if err := json.NewEncoder(writer).Encode(report); err != nil {
+		return fmt.Errorf("unable to encode lint report: %w", err)
+	}
+
+	// Quarantine packages with violations
+	if t.QuarantineFindings {
+		if err := t.quarantine(ctx, b, report); err != nil {
+			return err
+		}
+	}
+
+	// Check violations
+	if report.HasViolations() {
+		return tasks.PolicyViolation(ErrLintViolations)
+	}
+
+	// No error
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+// untilFirstViolation drops findings of packages sorted after the first package
+// with an unwaived finding.
+func untilFirstViolation(findings []lint.Finding) []lint.Finding {
+	for i, f := range findings {
+		if f.Waived {
+			continue
+		}
+		end := i
+		for end < len(findings) && findings[end].Path == f.Path {
+			end++
+		}
+		return findings[:end]
+	}
+
+	return findings
+}
+
+// recordPackages records package statuses, packages sorted after the last
+// reported finding are skipped when failing fast.
+func (t *LintTask) recordPackages(b *bundlev1.Bundle, report *lint.Report) {
+	violations := map[string]int{}
+	last := ""
+	for _, f := range report.Findings {
+		if !f.Waived {
+			violations[f.Path]++
+		}
+		last = f.Path
+	}
+
+	names := make([]string, 0, len(b.Packages))
+	for _, p := range b.Packages {
+		names = append(names, p.Name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		switch {
+		case violations[name] > 0:
+			t.result.Items = append(t.result.Items, tasks.ItemStatus{
+				Item:   name,
+				Status: tasks.StatusFailed,
+				Error:  fmt.Sprintf("%d violation(s)", violations[name]),
+			})
+		case t.FailureMode == tasks.FailFast && report.HasViolations() && name > last:
+			t.result.Skip(name)
+		default:
+			t.result.Succeed(name)
+		}
+	}
+}
+
+func (t *LintTask) quarantine(ctx context.Context, b *bundlev1.Bundle, report *lint.Report) error {
+	// Collect violated rules by package
+	rules := map[string]map[string]struct{}{}
+	for _, f := range report.Findings {
+		if f.Waived || f.RuleID == lint.RuleQuarantined {
+			continue
+		}
+		if _, ok := rules[f.Path]; !ok {
+			rules[f.Path] = map[string]struct{}{}
+		}
+		rules[f.Path][f.RuleID] = struct{}{}
+	}
+
+	c := bundle.Change{
+		Actor: t.Actor,
+		Time:  time.Now(),
+	}
+	for _, p := range b.Packages {
+		ids, ok := rules[p.Name]
+		if !ok || bundle.IsQuarantined(p) {
+			continue
+		}
+
+		// Build a reason from violated rules
+		names := make([]string, 0, len(ids))
+		for id := range ids {
+			names = append(names, id)
+		}
+		sort.Strings(names)
+
+		if err := bundle.Quarantine(b, p.Name, fmt.Sprintf("lint findings: %s", strings.Join(names, ", ")), c); err != nil {
+			return fmt.Errorf("unable to quarantine '%s': %w", p.Name, err)
+		}
+		t.result.Quarantined++
+	}
+
+	// Create output writer
+	writer, err := t.BundleWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output bundle: %w", err)
+	}
+
+	// Dump all content
+	if err := bundle.ToContainerWriter(writer, b); err != nil {
+		return fmt.Errorf("unable to dump bundle content: %w", err)
+	}
+
+	// No error
+	return nil
+}
@@ -0,0 +1,275 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/lint"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+func Test_LintTask(t *testing.T) {
+	b, err := bundle.FromMap(map[string]bundle.KV{
+		"app/production/security/harp/v1.0.0/server/database": {
+			"password": "letmein",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy := `apiVersion: harp.elastic.co/v1
+kind: LintPolicy
+spec:
+  weakCredentials: {}
+  waivers:
+  - ruleId: HARP-WC-001
+    path: app/production/security/harp/**
+    key: password
+    reason: legacy service account
+`
+
+	testCases := []struct {
+		desc    string
+		policy  string
+		wantErr error
+	}{
+		{desc: "default policy", wantErr: ErrLintViolations},
+		{desc: "waived", policy: policy},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var out bytes.Buffer
+			task := &LintTask{
+				ContainerReader: containerReader(t, b),
+				OutputWriter:    bufferWriter(&out),
+			}
+			if tC.policy != "" {
+				task.PolicyReader = func(context.Context) (io.Reader, error) {
+					return strings.NewReader(tC.policy), nil
+				}
+			}
+
+			err := task.Run(context.Background())
+			if !errors.Is(err, tC.wantErr) {
+				t.Fatalf("unexpected error, got %v, want %v", err, tC.wantErr)
+			}
+
+			var report lint.Report
+			if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+				t.Fatalf("unable to decode report: %v", err)
+			}
+			if len(report.Findings) != 1 || report.Findings[0].RuleID != lint.RuleCommonPassword {
+				t.Errorf("unexpected findings %+v", report.Findings)
+			}
+		})
+	}
+}
+
+func Test_LintTask_FailureMode(t *testing.T) {
+	b, err := bundle.FromMap(map[string]bundle.KV{
+		"app/production/security/harp/v1.0.0/server/cache":    {"password": "bW9yZS1zZWN1cmUtcGFzc3dvcmQtdmFsdWU"},
+		"app/production/security/harp/v1.0.0/server/database": {"password": "letmein"},
+		"app/production/security/harp/v1.0.0/server/queue":    {"password": "123456"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		desc         string
+		mode         tasks.FailureMode
+		wantFindings int
+		wantStatuses []string
+	}{
+		{
+			desc:         "keep going",
+			mode:         tasks.KeepGoing,
+			wantFindings: 2,
+			wantStatuses: []string{tasks.StatusSucceeded, tasks.StatusFailed, tasks.StatusFailed},
+		},
+		{
+			desc:         "fail fast",
+			mode:         tasks.FailFast,
+			wantFindings: 1,
+			wantStatuses: []string{tasks.StatusSucceeded, tasks.StatusFailed, tasks.StatusSkipped},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var out bytes.Buffer
+			task := &LintTask{
+				ContainerReader: containerReader(t, b),
+				OutputWriter:    bufferWriter(&out),
+				FailureMode:     tC.mode,
+			}
+
+			err := task.Run(context.Background())
+			var policyErr *tasks.PolicyError
+			if !errors.As(err, &policyErr) || !errors.Is(err, ErrLintViolations) {
+				t.Fatalf("expected policy violation, got %v", err)
+			}
+
+			var report lint.Report
+			if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+				t.Fatalf("unable to decode report: %v", err)
+			}
+			if len(report.Findings) != tC.wantFindings {
+				t.Errorf("expected %d findings, got %+v", tC.wantFindings, report.Findings)
+			}
+
+			res, ok := task.Result().(*LintResult)
+			if !ok {
+				t.Fatalf("unexpected result %T", task.Result())
+			}
+			statuses := []string{}
+			for _, i := range res.Items {
+				statuses = append(statuses, i.Status)
+			}
+			if strings.Join(statuses, ",") != strings.Join(tC.wantStatuses, ",") {
+				t.Errorf("expected statuses %v, got %+v", tC.wantStatuses, res.Items)
+			}
+		})
+	}
+
+	// Invalid policies are usage errors
+	task := &LintTask{
+		ContainerReader: containerReader(t, b),
+		OutputWriter:    bufferWriter(&bytes.Buffer{}),
+		PolicyReader: func(context.Context) (io.Reader, error) {
+			return strings.NewReader("apiVersion: harp.elastic.co/v1\nkind: Unknown\n"), nil
+		},
+	}
+	var usageErr *tasks.UsageError
+	if err := task.Run(context.Background()); !errors.As(err, &usageErr) {
+		t.Fatalf("expected usage error, got %v", err)
+	}
+}
+
+func Test_LintTask_QuarantineFindings(t *testing.T) {
+	b, err := bundle.FromMap(map[string]bundle.KV{
+		"app/production/security/harp/v1.0.0/server/database": {
+			"password": "letmein",
+		},
+		"app/production/security/harp/v1.0.0/server/cache": {
+			"password": "bW9yZS1zZWN1cmUtcGFzc3dvcmQtdmFsdWU",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out, report bytes.Buffer
+	task := &LintTask{
+		ContainerReader:    containerReader(t, b),
+		OutputWriter:       bufferWriter(&report),
+		BundleWriter:       bufferWriter(&out),
+		QuarantineFindings: true,
+		Actor:              "scanner@ci",
+	}
+	if err := task.Run(context.Background()); !errors.Is(err, ErrLintViolations) {
+		t.Fatalf("unexpected error, got %v", err)
+	}
+
+	// Check output bundle
+	got, err := bundle.FromContainerReader(&out)
+	if err != nil {
+		t.Fatalf("unable to load output bundle: %v", err)
+	}
+	for _, p := range got.Packages {
+		reason, quarantined := bundle.QuarantineReason(p)
+		switch p.Name {
+		case "app/production/security/harp/v1.0.0/server/database":
+			if !quarantined || reason != "lint findings: HARP-WC-001" {
+				t.Errorf("package must be quarantined, got %v %q", quarantined, reason)
+			}
+			entries, err := bundle.History(p, bundle.QuarantineHistoryKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 || entries[0].Actor != "scanner@ci" {
+				t.Errorf("quarantine must be recorded in history, got %+v", entries)
+			}
+		default:
+			if quarantined {
+				t.Errorf("package '%s' must not be quarantined", p.Name)
+			}
+		}
+	}
+
+	// Quarantined packages are reported on next lint
+	report.Reset()
+	task = &LintTask{
+		ContainerReader: containerReader(t, got),
+		OutputWriter:    bufferWriter(&report),
+	}
+	if err := task.Run(context.Background()); !errors.Is(err, ErrLintViolations) {
+		t.Fatalf("unexpected error, got %v", err)
+	}
+	if !strings.Contains(report.String(), lint.RuleQuarantined) {
+		t.Errorf("quarantined package must be reported, got %s", report.String())
+	}
+}
+
+func Test_LintTask_PathCollisions(t *testing.T) {
+	b, err := bundle.FromMap(map[string]bundle.KV{
+		"app/production/security/harp/v1.0.0/server/database": {"user": "harp"},
+		"app/Production/security/harp/v1.0.0/server/database": {"user": "harp"},
+		cafeNFC: {"user": "cafe"},
+		cafeNFD: {"user": "cafe"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	task := &LintTask{
+		ContainerReader: containerReader(t, b),
+		OutputWriter:    bufferWriter(&out),
+	}
+	if err := task.Run(context.Background()); !errors.Is(err, ErrLintViolations) {
+		t.Fatalf("unexpected error, got %v", err)
+	}
+
+	var report lint.Report
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("unable to decode report: %v", err)
+	}
+	if len(report.Collisions) != 2 || task.result.Collisions != 2 {
+		t.Fatalf("unexpected collisions %+v", report.Collisions)
+	}
+	paths := []string{}
+	for _, f := range report.Findings {
+		if f.RuleID != lint.RulePathCollision {
+			t.Errorf("unexpected finding %+v", f)
+		}
+		paths = append(paths, f.Path)
+	}
+	if want := []string{"app/Production/security/harp/v1.0.0/server/database", cafeNFD}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("unexpected colliding paths %q, want %q", paths, want)
+	}
+}
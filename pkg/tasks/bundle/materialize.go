@@ -0,0 +1,102 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// MaterializeAnnotationsTask implements annotation defaults materialization
+// task.
+type MaterializeAnnotationsTask struct {
+	ContainerReader tasks.ReaderProvider
+	DefaultsReader  tasks.ReaderProvider
+	OutputWriter    tasks.WriterProvider
+	AttachOnly      bool
+}
+
+// Capabilities returns the task capabilities.
+func (t *MaterializeAnnotationsTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Run the task.
+func (t *MaterializeAnnotationsTask) Run(ctx context.Context) error {
+	// Check arguments
+	if t.AttachOnly && t.DefaultsReader == nil {
+		return fmt.Errorf("unable to attach annotation defaults without defaults manifest")
+	}
+
+	opts := []bundle.LoadOption{}
+	if t.DefaultsReader != nil {
+		// Create defaults reader
+		defaultsReader, err := t.DefaultsReader(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to open annotation defaults: %w", err)
+		}
+
+		// Parse defaults manifest
+		defaults, err := bundle.ParseAnnotationDefaults(defaultsReader)
+		if err != nil {
+			return fmt.Errorf("unable to parse annotation defaults: %w", err)
+		}
+		opts = append(opts, bundle.WithInheritedAnnotations(defaults))
+	}
+
+	// Create input reader
+	reader, err := t.ContainerReader(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open input bundle: %w", err)
+	}
+
+	// Load bundle
+	b, err := bundle.FromContainerReader(reader, opts...)
+	if err != nil {
+		return fmt.Errorf("unable to load bundle content: %w", err)
+	}
+
+	// Bake effective annotations
+	if !t.AttachOnly {
+		count, err := bundle.MaterializeAnnotations(b)
+		if err != nil {
+			return fmt.Errorf("unable to materialize annotations: %w", err)
+		}
+		log.For(ctx).Info("Inherited annotations materialized", zap.Int("packages", count))
+	}
+
+	// Create output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output writer: %w", err)
+	}
+
+	// Dump bundle
+	if err = bundle.ToContainerWriter(writer, b); err != nil {
+		return fmt.Errorf("unable to produce exported bundle: %w", err)
+	}
+
+	// No error
+	return nil
+}
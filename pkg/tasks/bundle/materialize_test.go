@@ -0,0 +1,106 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/elastic/harp/pkg/bundle"
+)
+
+const materializeDefaults = `apiVersion: harp.elastic.co/v1
+kind: AnnotationDefaults
+spec:
+  prefixes:
+  - prefix: app/production
+    annotations:
+      harp.elastic.co/v1/package#owner: platform
+`
+
+func TestMaterializeAnnotationsTask_Run(t *testing.T) {
+	b, err := bundle.FromMap(map[string]bundle.KV{
+		"app/production/a/database": {"user": "a"},
+		"app/staging/a/database":    {"user": "a"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaults := func(context.Context) (io.Reader, error) {
+		return strings.NewReader(materializeDefaults), nil
+	}
+
+	owners := func(t *testing.T, buf *bytes.Buffer) map[string]string {
+		out, err := bundle.FromContainerReader(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := map[string]string{}
+		for _, p := range out.Packages {
+			res[p.Name] = bundle.Owner(p)
+		}
+		return res
+	}
+
+	t.Run("attach", func(t *testing.T) {
+		var attached bytes.Buffer
+		task := &MaterializeAnnotationsTask{
+			ContainerReader: containerReader(t, b),
+			DefaultsReader:  defaults,
+			OutputWriter:    bufferWriter(&attached),
+			AttachOnly:      true,
+		}
+		if err := task.Run(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got := owners(t, &attached)
+		if _, ok := got[bundle.AnnotationDefaultsPackage]; !ok || got["app/production/a/database"] != "" {
+			t.Errorf("expected attached defaults only, got %v", got)
+		}
+	})
+
+	t.Run("materialize", func(t *testing.T) {
+		var materialized bytes.Buffer
+		task := &MaterializeAnnotationsTask{
+			ContainerReader: containerReader(t, b),
+			DefaultsReader:  defaults,
+			OutputWriter:    bufferWriter(&materialized),
+		}
+		if err := task.Run(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got := owners(t, &materialized)
+		if len(got) != 2 || got["app/production/a/database"] != "platform" || got["app/staging/a/database"] != "" {
+			t.Errorf("unexpected materialized owners %v", got)
+		}
+	})
+
+	t.Run("attach without defaults", func(t *testing.T) {
+		task := &MaterializeAnnotationsTask{
+			ContainerReader: containerReader(t, b),
+			OutputWriter:    bufferWriter(&bytes.Buffer{}),
+			AttachOnly:      true,
+		}
+		if err := task.Run(context.Background()); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
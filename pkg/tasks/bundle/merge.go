@@ -0,0 +1,135 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// MergeTask implements secret container merging task. Containers are merged
+// in order into the first one.
+type MergeTask struct {
+	ContainerReaders []tasks.ReaderProvider
+	OutputWriter     tasks.WriterProvider
+	MergeStrategy    bundle.MergeStrategy
+	Actor            string
+	HistoryLimit     int
+
+	result *MergeResult
+}
+
+// MergeResult describes a merge task execution.
+type MergeResult struct {
+	tasks.Result
+	Sources int `json:"sources"`
+	// Packages is the package count of the merged bundle.
+	Packages   int `json:"packages"`
+	Copied     int `json:"copied"`
+	Skipped    int `json:"skipped"`
+	Conflicted int `json:"conflicted"`
+}
+
+// Capabilities returns the task required capabilities.
+func (t *MergeTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Result returns the last execution result.
+func (t *MergeTask) Result() interface{} {
+	return t.result
+}
+
+// Run the task.
+func (t *MergeTask) Run(ctx context.Context) error {
+	t.result = &MergeResult{
+		Sources: len(t.ContainerReaders),
+	}
+
+	// Check arguments
+	if len(t.ContainerReaders) < 2 {
+		return fmt.Errorf("unable to merge less than 2 containers")
+	}
+	if t.OutputWriter == nil {
+		return fmt.Errorf("unable to run task with a nil outputWriter provider")
+	}
+	if _, err := bundle.ParseMergeStrategy(string(t.MergeStrategy)); err != nil {
+		return err
+	}
+
+	// Load destination bundle
+	dst, err := loadBundle(ctx, t.ContainerReaders[0])
+	if err != nil {
+		return fmt.Errorf("unable to load container #0: %w", err)
+	}
+
+	// Keep original state for history
+	before, _ := proto.Clone(dst).(*bundlev1.Bundle)
+
+	for i, rp := range t.ContainerReaders[1:] {
+		// Load source bundle
+		src, err := loadBundle(ctx, rp)
+		if err != nil {
+			return fmt.Errorf("unable to load container #%d: %w", i+1, err)
+		}
+
+		// Merge packages
+		report, err := bundle.Merge(dst, src, t.MergeStrategy)
+		if report != nil {
+			t.result.Copied += len(report.Copied)
+			t.result.Skipped += len(report.Skipped)
+			t.result.Conflicted += len(report.Conflicted)
+		}
+		if err != nil {
+			t.result.Fail(fmt.Sprintf("container #%d", i+1), err)
+			return fmt.Errorf("unable to merge container #%d: %w", i+1, err)
+		}
+	}
+	t.result.Packages = len(dst.Packages)
+
+	// Record changed secret keys
+	if err := bundle.RecordChanges(before, dst, bundle.Change{
+		Actor:     t.Actor,
+		Operation: "merge",
+		Time:      time.Now(),
+		Limit:     t.HistoryLimit,
+	}); err != nil {
+		return fmt.Errorf("unable to record secret history: %w", err)
+	}
+
+	// Create output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output bundle: %w", err)
+	}
+
+	// Dump all content
+	if err := bundle.ToContainerWriter(writer, dst); err != nil {
+		return fmt.Errorf("unable to dump bundle content: %w", err)
+	}
+
+	// No error
+	return nil
+}
@@ -0,0 +1,279 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/patch"
+	"github.com/elastic/harp/pkg/sdk/types"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// NormalizePathsTask implements package path normalization task. Package
+// paths are rewritten to their canonical form, and colliding packages are
+// merged into the suggested survivor.
+type NormalizePathsTask struct {
+	ContainerReader tasks.ReaderProvider
+	OutputWriter    tasks.WriterProvider
+	BundleWriter    tasks.WriterProvider
+	Apply           bool
+	MergeStrategy   bundle.MergeStrategy
+
+	result *NormalizePathsResult
+}
+
+// NormalizePathsPlan describes the package path rewrites.
+type NormalizePathsPlan struct {
+	Collisions []bundle.PathCollision `json:"collisions"`
+	Renames    []PathRename           `json:"renames"`
+}
+
+// PathRename describes a package path rewrite.
+type PathRename struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// NormalizePathsResult describes a path normalization task execution.
+type NormalizePathsResult struct {
+	tasks.Result
+	Collisions int  `json:"collisions"`
+	Renamed    int  `json:"renamed"`
+	Conflicted int  `json:"conflicted"`
+	Applied    bool `json:"applied"`
+}
+
+// Capabilities returns the task required capabilities.
+func (t *NormalizePathsTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Result returns the last execution result.
+func (t *NormalizePathsTask) Result() interface{} {
+	return t.result
+}
+
+// Run the task.
+func (t *NormalizePathsTask) Run(ctx context.Context) error {
+	t.result = &NormalizePathsResult{}
+
+	// Check arguments
+	if types.IsNil(t.ContainerReader) {
+		return fmt.Errorf("unable to run task with a nil containerReader provider")
+	}
+	if types.IsNil(t.OutputWriter) {
+		return fmt.Errorf("unable to run task with a nil outputWriter provider")
+	}
+	if t.Apply {
+		if types.IsNil(t.BundleWriter) {
+			return fmt.Errorf("unable to apply rewrites with a nil bundleWriter provider")
+		}
+		if _, err := bundle.ParseMergeStrategy(string(t.MergeStrategy)); err != nil {
+			return err
+		}
+	}
+
+	// Load bundle
+	b, err := loadBundle(ctx, t.ContainerReader)
+	if err != nil {
+		return err
+	}
+
+	// Prepare rewrites
+	plan := normalizePathsPlan(b)
+	t.result.Collisions = len(plan.Collisions)
+	t.result.Renamed = len(plan.Renames)
+
+	// Create output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output writer: %w", err)
+	}
+
+	// Print plan
+	if err := json.NewEncoder(writer).Encode(plan); err != nil {
+		return fmt.Errorf("unable to encode normalization plan: %w", err)
+	}
+
+	// Dry-run
+	if !t.Apply {
+		return nil
+	}
+
+	// Apply rewrites
+	if err := t.apply(b, plan); err != nil {
+		return err
+	}
+	t.result.Applied = true
+
+	// Create output writer
+	bundleWriter, err := t.BundleWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output bundle: %w", err)
+	}
+
+	// Dump all content
+	if err := bundle.ToContainerWriter(bundleWriter, b); err != nil {
+		return fmt.Errorf("unable to dump bundle content: %w", err)
+	}
+
+	// No error
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func normalizePathsPlan(b *bundlev1.Bundle) *NormalizePathsPlan {
+	plan := &NormalizePathsPlan{
+		Collisions: bundle.PathCollisions(b),
+		Renames:    []PathRename{},
+	}
+
+	// Colliding paths are rewritten to the survivor canonical path
+	targets := map[string]string{}
+	for _, c := range plan.Collisions {
+		for _, path := range c.Paths {
+			targets[path] = c.Canonical
+		}
+	}
+
+	// Other paths are rewritten to their own canonical path
+	for _, p := range b.Packages {
+		if _, ok := targets[p.Name]; !ok {
+			targets[p.Name] = bundle.CanonicalPath(p.Name)
+		}
+	}
+
+	for from, to := range targets {
+		if from != to {
+			plan.Renames = append(plan.Renames, PathRename{From: from, To: to})
+		}
+	}
+
+	// Ensure stable order
+	sort.Slice(plan.Renames, func(i, j int) bool {
+		return plan.Renames[i].From < plan.Renames[j].From
+	})
+
+	return plan
+}
+
+func (t *NormalizePathsTask) apply(b *bundlev1.Bundle, plan *NormalizePathsPlan) error {
+	if len(plan.Renames) == 0 {
+		return nil
+	}
+
+	// Index survivors
+	survivors := map[string]bool{}
+	for _, c := range plan.Collisions {
+		for _, path := range c.Paths {
+			survivors[path] = path == c.Survivor
+		}
+	}
+
+	// Split colliding packages from the others, so that they are merged
+	// once all paths are rewritten instead of overwriting each other.
+	dst, src := &bundlev1.Bundle{}, &bundlev1.Bundle{}
+	for _, p := range b.Packages {
+		survivor, colliding := survivors[p.Name]
+		if colliding && (p.Secrets == nil || p.Secrets.Locked != nil) {
+			return fmt.Errorf("unable to merge locked colliding package '%s'", p.Name)
+		}
+		if colliding && !survivor {
+			src.Packages = append(src.Packages, p)
+			continue
+		}
+		dst.Packages = append(dst.Packages, p)
+	}
+	sort.SliceStable(src.Packages, func(i, j int) bool {
+		return src.Packages[i].Name < src.Packages[j].Name
+	})
+
+	// Rewrite paths, targets are canonical paths and never rewritten again
+	spec, values := renamePatch(plan.Renames)
+	for _, part := range []*bundlev1.Bundle{dst, src} {
+		if err := patch.Apply(spec, part, values); err != nil {
+			return fmt.Errorf("unable to rewrite package paths: %w", err)
+		}
+	}
+
+	// Merge colliding packages into survivors
+	report, err := bundle.Merge(dst, src, t.MergeStrategy)
+	if report != nil {
+		t.result.Conflicted = len(report.Conflicted)
+		for _, e := range report.Conflicted {
+			t.result.Warn("secret key '%s' of package '%s' is conflicting (%s)", e.Key, e.Path, e.Reason)
+		}
+	}
+	if err != nil {
+		t.result.Fail("merge", err)
+		return fmt.Errorf("unable to merge colliding packages: %w", err)
+	}
+
+	b.Packages = dst.Packages
+
+	// No error
+	return nil
+}
+
+// renamePatch returns a BundlePatch rewriting each rename source to its
+// target. Paths are given as values to prevent template injection.
+func renamePatch(renames []PathRename) (*bundlev1.Patch, map[string]interface{}) {
+	spec := &bundlev1.Patch{
+		ApiVersion: "harp.elastic.co/v1",
+		Kind:       "BundlePatch",
+		Meta: &bundlev1.PatchMeta{
+			Name:        "normalize-paths",
+			Owner:       "harp",
+			Description: "Rewrite package paths to their canonical form",
+		},
+		Spec: &bundlev1.PatchSpec{
+			Rules: []*bundlev1.PatchRule{},
+		},
+	}
+
+	items := make([]interface{}, 0, len(renames))
+	for i, r := range renames {
+		items = append(items, map[string]interface{}{
+			"from": r.From,
+			"to":   r.To,
+		})
+		spec.Spec.Rules = append(spec.Spec.Rules, &bundlev1.PatchRule{
+			Selector: &bundlev1.PatchSelector{
+				MatchPath: &bundlev1.PatchSelectorMatchPath{
+					Strict: fmt.Sprintf(`{{ (index .Values.renames %d).from }}`, i),
+				},
+			},
+			Package: &bundlev1.PatchPackage{
+				Path: &bundlev1.PatchPackagePath{
+					Template: fmt.Sprintf(`{{ (index .Values.renames %d).to }}`, i),
+				},
+			},
+		})
+	}
+
+	return spec, map[string]interface{}{
+		"renames": items,
+	}
+}
@@ -0,0 +1,176 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+)
+
+const (
+	// "cafe" with an acute accent, encoded using NFC and NFD forms
+	cafeNFC = "app/production/caf\u00e9/harp/v1.0.0/server/database"
+	cafeNFD = "app/production/cafe\u0301/harp/v1.0.0/server/database"
+	// NFD encoded path without collision
+	stagingNFD = "app/staging/cafe\u0301/harp/v1.0.0/server/cache"
+)
+
+func normalizePathsFixture(t *testing.T, cafePassword string) *bundlev1.Bundle {
+	t.Helper()
+
+	b, err := bundle.FromMap(map[string]bundle.KV{
+		"app/production/security/harp/v1.0.0/server/database": {"user": "harp"},
+		"app/Production/security/harp/v1.0.0/server/database": {"password": "mixed-case-password"},
+		"app/PRODUCTION/security/harp/v1.0.0/server/database": {"user": "harp"},
+		cafeNFC:    {"user": "cafe", "password": "nfc-password"},
+		cafeNFD:    {"user": "cafe", "password": cafePassword},
+		stagingNFD: {"token": "staging-token"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
+
+func Test_NormalizePathsTask_Plan(t *testing.T) {
+	b := normalizePathsFixture(t, "nfc-password")
+
+	var plan bytes.Buffer
+	task := &NormalizePathsTask{
+		ContainerReader: containerReader(t, b),
+		OutputWriter:    bufferWriter(&plan),
+	}
+	if err := task.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got NormalizePathsPlan
+	if err := json.Unmarshal(plan.Bytes(), &got); err != nil {
+		t.Fatalf("unable to decode plan: %v", err)
+	}
+	want := []PathRename{
+		{From: "app/PRODUCTION/security/harp/v1.0.0/server/database", To: "app/production/security/harp/v1.0.0/server/database"},
+		{From: "app/Production/security/harp/v1.0.0/server/database", To: "app/production/security/harp/v1.0.0/server/database"},
+		{From: cafeNFD, To: cafeNFC},
+		{From: stagingNFD, To: "app/staging/caf\u00e9/harp/v1.0.0/server/cache"},
+	}
+	if !reflect.DeepEqual(got.Renames, want) {
+		t.Errorf("unexpected renames %+v", got.Renames)
+	}
+	if len(got.Collisions) != 2 {
+		t.Errorf("unexpected collisions %+v", got.Collisions)
+	}
+	if task.result.Applied {
+		t.Error("rewrites should not be applied")
+	}
+}
+
+func Test_NormalizePathsTask_Apply(t *testing.T) {
+	b := normalizePathsFixture(t, "nfc-password")
+
+	var out, plan bytes.Buffer
+	task := &NormalizePathsTask{
+		ContainerReader: containerReader(t, b),
+		OutputWriter:    bufferWriter(&plan),
+		BundleWriter:    bufferWriter(&out),
+		Apply:           true,
+		MergeStrategy:   bundle.MergeStrategyFail,
+	}
+	if err := task.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := bundle.FromContainerReader(&out)
+	if err != nil {
+		t.Fatalf("unable to load output bundle: %v", err)
+	}
+	kv, err := bundle.AsMap(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := bundle.KV{
+		"app/production/security/harp/v1.0.0/server/database": bundle.KV{"user": "harp", "password": "mixed-case-password"},
+		cafeNFC: bundle.KV{"user": "cafe", "password": "nfc-password"},
+		"app/staging/caf\u00e9/harp/v1.0.0/server/cache": bundle.KV{"token": "staging-token"},
+	}
+	if !reflect.DeepEqual(kv, want) {
+		t.Errorf("unexpected bundle content %+v", kv)
+	}
+	if len(bundle.PathCollisions(got)) != 0 {
+		t.Error("normalized bundle should not have collisions")
+	}
+}
+
+func Test_NormalizePathsTask_Conflict(t *testing.T) {
+	b := normalizePathsFixture(t, "nfd-password")
+
+	testCases := []struct {
+		strategy bundle.MergeStrategy
+		wantErr  bool
+		want     string
+	}{
+		{strategy: bundle.MergeStrategyFail, wantErr: true},
+		{strategy: bundle.MergeStrategyKeep, want: "nfc-password"},
+		{strategy: bundle.MergeStrategyOverwrite, want: "nfd-password"},
+	}
+	for _, tC := range testCases {
+		t.Run(string(tC.strategy), func(t *testing.T) {
+			var out, plan bytes.Buffer
+			task := &NormalizePathsTask{
+				ContainerReader: containerReader(t, b),
+				OutputWriter:    bufferWriter(&plan),
+				BundleWriter:    bufferWriter(&out),
+				Apply:           true,
+				MergeStrategy:   tC.strategy,
+			}
+			err := task.Run(context.Background())
+			if (err != nil) != tC.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tC.wantErr {
+				if out.Len() != 0 {
+					t.Error("bundle should not be written")
+				}
+				return
+			}
+
+			got, err := bundle.FromContainerReader(&out)
+			if err != nil {
+				t.Fatalf("unable to load output bundle: %v", err)
+			}
+			kv, err := bundle.AsMap(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if password := kv.Get(cafeNFC).(bundle.KV)["password"]; password != tC.want {
+				t.Errorf("unexpected password %v, want %v", password, tC.want)
+			}
+			if task.result.Conflicted != 1 || len(task.result.Warnings) != 1 {
+				t.Errorf("conflict should be reported, got %+v", task.result)
+			}
+		})
+	}
+}
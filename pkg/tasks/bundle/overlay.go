@@ -0,0 +1,85 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// OverlayTask implements secret container overlay materialization task.
+type OverlayTask struct {
+	BaseReader    tasks.ReaderProvider
+	OverlayReader tasks.ReaderProvider
+	OutputWriter  tasks.WriterProvider
+}
+
+// Capabilities returns the task required capabilities.
+func (t *OverlayTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Run the task.
+func (t *OverlayTask) Run(ctx context.Context) error {
+	// Create input reader
+	readerBase, err := t.BaseReader(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open base bundle: %w", err)
+	}
+
+	// Load base bundle
+	bBase, err := bundle.FromContainerReader(readerBase)
+	if err != nil {
+		return fmt.Errorf("unable to load base bundle content: %w", err)
+	}
+
+	// Create input reader
+	readerOverlay, err := t.OverlayReader(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open overlay bundle: %w", err)
+	}
+
+	// Load overlay bundle
+	bOverlay, err := bundle.FromContainerReader(readerOverlay)
+	if err != nil {
+		return fmt.Errorf("unable to load overlay bundle content: %w", err)
+	}
+
+	// Combine layers
+	layered, err := bundle.Overlay(bBase, bOverlay)
+	if err != nil {
+		return fmt.Errorf("unable to overlay bundles: %w", err)
+	}
+
+	// Create output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output writer: %w", err)
+	}
+
+	// Dump flattened bundle
+	if err = bundle.ToContainerWriter(writer, layered.Bundle()); err != nil {
+		return fmt.Errorf("unable to produce flattened bundle: %w", err)
+	}
+
+	// No error
+	return nil
+}
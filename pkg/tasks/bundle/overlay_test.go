@@ -0,0 +1,76 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/elastic/harp/pkg/bundle"
+)
+
+func TestOverlayTask_Run(t *testing.T) {
+	base, err := bundle.FromMap(map[string]bundle.KV{
+		"app/production/a/database": {"user": "base"},
+		"app/production/a/cache":    {"password": "base"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	overlay, err := bundle.FromMap(map[string]bundle.KV{
+		"app/production/a/database": {"user": "overlay"},
+		"app/production/a/cache":    {},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range overlay.Packages {
+		if p.Name == "app/production/a/cache" {
+			bundle.Annotate(p, bundle.TombstoneAnnotation, "true")
+		}
+	}
+
+	var buf bytes.Buffer
+	task := &OverlayTask{
+		BaseReader:    containerReader(t, base),
+		OverlayReader: containerReader(t, overlay),
+		OutputWriter:  bufferWriter(&buf),
+	}
+	if err := task.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Check flattened container
+	out, err := bundle.FromContainerReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := bundle.AsMap(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := bundle.KV{
+		"app/production/a/database": bundle.KV{"user": "overlay"},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("%q. Run():\n-got/+want\ndiff %s", "flatten", diff)
+	}
+}
@@ -20,7 +20,11 @@ package bundle
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"google.golang.org/protobuf/proto"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
 	"github.com/elastic/harp/pkg/bundle"
 	"github.com/elastic/harp/pkg/bundle/patch"
 	"github.com/elastic/harp/pkg/tasks"
@@ -32,6 +36,13 @@ type PatchTask struct {
 	ContainerReader tasks.ReaderProvider
 	OutputWriter    tasks.WriterProvider
 	Values          map[string]interface{}
+	Actor           string
+	HistoryLimit    int
+}
+
+// Capabilities returns the task required capabilities.
+func (t *PatchTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
 }
 
 // Run the task.
@@ -60,11 +71,24 @@ func (t *PatchTask) Run(ctx context.Context) error {
 		return fmt.Errorf("unable to load bundle content: %w", err)
 	}
 
+	// Keep original state for history
+	before, _ := proto.Clone(b).(*bundlev1.Bundle)
+
 	// Apply the patch speicification to generate an output bundle
 	if err = patch.Apply(spec, b, t.Values); err != nil {
 		return fmt.Errorf("unable to generate output bundle from patch: %w", err)
 	}
 
+	// Record changed secret keys
+	if err = bundle.RecordChanges(before, b, bundle.Change{
+		Actor:     t.Actor,
+		Operation: "patch",
+		Time:      time.Now(),
+		Limit:     t.HistoryLimit,
+	}); err != nil {
+		return fmt.Errorf("unable to record secret history: %w", err)
+	}
+
 	// Retrieve the container reader
 	outputWriter, err := t.OutputWriter(ctx)
 	if err != nil {
@@ -0,0 +1,126 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/sdk/types"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// PathsTask implements bundle package path listing task.
+type PathsTask struct {
+	ContainerReader tasks.ReaderProvider
+	OutputWriter    tasks.WriterProvider
+	// Prefix restricts the listing to the given subtree.
+	Prefix string
+	// Delimiter lists only the direct children of the prefix subtree, deeper
+	// paths are grouped as folders ending with the delimiter. All package
+	// paths are listed when blank.
+	Delimiter string
+}
+
+// Capabilities returns the task required capabilities.
+func (t *PathsTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Run the task.
+func (t *PathsTask) Run(ctx context.Context) error {
+	// Check arguments
+	if types.IsNil(t.ContainerReader) {
+		return fmt.Errorf("unable to run task with a nil containerReader provider")
+	}
+	if types.IsNil(t.OutputWriter) {
+		return fmt.Errorf("unable to run task with a nil outputWriter provider")
+	}
+
+	// Create input reader
+	reader, err := t.ContainerReader(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open input bundle: %w", err)
+	}
+
+	// Load bundle
+	b, err := bundle.FromContainerReader(reader)
+	if err != nil {
+		return fmt.Errorf("unable to load bundle content: %w", err)
+	}
+
+	// Build package tree
+	opts := []bundle.PackageTreeOption{}
+	if t.Delimiter != "" {
+		opts = append(opts, bundle.WithPathDelimiter(t.Delimiter))
+	}
+	tree, err := bundle.NewPackageTree(b, opts...)
+	if err != nil {
+		return fmt.Errorf("unable to build package tree: %w", err)
+	}
+
+	// Collect paths
+	paths := []string{}
+	if n := tree.Find(t.Prefix); n != nil {
+		paths = listPaths(n, t.Delimiter)
+	}
+
+	// Create output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output writer: %w", err)
+	}
+	for _, p := range paths {
+		if _, err := fmt.Fprintln(writer, p); err != nil {
+			return fmt.Errorf("unable to write path: %w", err)
+		}
+	}
+
+	// No error
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+// listPaths returns subtree package paths, or direct children when a
+// delimiter is given.
+func listPaths(n *bundle.TreeNode, delimiter string) []string {
+	res := []string{}
+	if delimiter == "" {
+		for _, p := range n.Packages() {
+			res = append(res, p.Name)
+		}
+		return res
+	}
+
+	// Package stored at the prefix itself
+	if n.Package != nil && n.Path != "" {
+		res = append(res, n.Path)
+	}
+	for _, c := range n.Children {
+		if c.Package != nil {
+			res = append(res, c.Path)
+		}
+		if !c.IsLeaf() {
+			res = append(res, c.Path+delimiter)
+		}
+	}
+
+	return res
+}
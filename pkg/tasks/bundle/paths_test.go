@@ -0,0 +1,95 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/elastic/harp/pkg/bundle"
+)
+
+func Test_PathsTask(t *testing.T) {
+	b, err := bundle.FromMap(map[string]bundle.KV{
+		"app/production/billing":                 {"owner": "billing"},
+		"app/production/billing/payments/api":    {"password": "foo"},
+		"app/production/billing/payments/stripe": {"api_key": "sk_live"},
+		"app/production/billing/invoices":        {"token": "bar"},
+		"app/staging/billing/payments/api":       {"password": "baz"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		desc      string
+		prefix    string
+		delimiter string
+		want      string
+	}{
+		{
+			desc: "all packages",
+			want: "app/production/billing\napp/production/billing/invoices\napp/production/billing/payments/api\napp/production/billing/payments/stripe\napp/staging/billing/payments/api\n",
+		},
+		{
+			desc:   "subtree",
+			prefix: "app/production/billing/payments",
+			want:   "app/production/billing/payments/api\napp/production/billing/payments/stripe\n",
+		},
+		{
+			desc:      "root folders",
+			delimiter: "/",
+			want:      "app/\n",
+		},
+		{
+			desc:      "package and folder at the same path",
+			prefix:    "app/production",
+			delimiter: "/",
+			want:      "app/production/billing\napp/production/billing/\n",
+		},
+		{
+			desc:      "direct children",
+			prefix:    "app/production/billing/",
+			delimiter: "/",
+			want:      "app/production/billing\napp/production/billing/invoices\napp/production/billing/payments/\n",
+		},
+		{
+			desc:   "unknown prefix",
+			prefix: "app/production/bill",
+			want:   "",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var out bytes.Buffer
+			task := &PathsTask{
+				ContainerReader: containerReader(t, b),
+				OutputWriter:    bufferWriter(&out),
+				Prefix:          tC.prefix,
+				Delimiter:       tC.delimiter,
+			}
+			if err := task.Run(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.String() != tC.want {
+				t.Fatalf("expected:\n%s\ngot:\n%s", tC.want, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	csov1 "github.com/elastic/harp/api/gen/go/cso/v1"
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	cso "github.com/elastic/harp/pkg/cso/v1"
+	"github.com/elastic/harp/pkg/sdk/types"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// NoPromoteAnnotation marks package secret keys as stage specific. The value
+// is a comma separated key list, or `*` to exclude the whole package.
+const NoPromoteAnnotation = "harp.elastic.co/v1/no-promote"
+
+// PromoteTask implements secret promotion between stages task.
+type PromoteTask struct {
+	ContainerReader tasks.ReaderProvider
+	TargetReader    tasks.ReaderProvider
+	OutputWriter    tasks.WriterProvider
+	ReportWriter    tasks.WriterProvider
+	FromStage       string
+	ToStage         string
+	Platform        string
+	Product         string
+	MergeStrategy   bundle.MergeStrategy
+	Actor           string
+	HistoryLimit    int
+}
+
+// Capabilities returns the task required capabilities.
+func (t *PromoteTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Run the task.
+func (t *PromoteTask) Run(ctx context.Context) error {
+	// Check arguments
+	if err := t.validate(); err != nil {
+		return err
+	}
+
+	// Create input reader
+	reader, err := t.ContainerReader(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open source bundle: %w", err)
+	}
+
+	// Load source bundle
+	src, err := bundle.FromContainerReader(reader)
+	if err != nil {
+		return fmt.Errorf("unable to load source bundle content: %w", err)
+	}
+
+	// Load target bundle
+	dst := &bundlev1.Bundle{}
+	if t.TargetReader != nil {
+		readerDst, errDst := t.TargetReader(ctx)
+		if errDst != nil {
+			return fmt.Errorf("unable to open target bundle: %w", errDst)
+		}
+
+		dst, err = bundle.FromContainerReader(readerDst)
+		if err != nil {
+			return fmt.Errorf("unable to load target bundle content: %w", err)
+		}
+	}
+
+	// Prepare promoted packages
+	promoted, skipped := t.promote(src)
+
+	// Keep original state for history
+	before, _ := proto.Clone(dst).(*bundlev1.Bundle)
+
+	// Merge with target
+	report, err := bundle.Merge(dst, promoted, t.MergeStrategy)
+	if err != nil {
+		return fmt.Errorf("unable to merge promoted packages: %w", err)
+	}
+
+	// Record changed secret keys
+	if err := bundle.RecordChanges(before, dst, bundle.Change{
+		Actor:     t.Actor,
+		Operation: "promote",
+		Time:      time.Now(),
+		Limit:     t.HistoryLimit,
+	}); err != nil {
+		return fmt.Errorf("unable to record secret history: %w", err)
+	}
+	report.Skipped = append(skipped, report.Skipped...)
+
+	// Create output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output bundle: %w", err)
+	}
+
+	// Dump all content
+	if err := bundle.ToContainerWriter(writer, dst); err != nil {
+		return fmt.Errorf("unable to dump bundle content: %w", err)
+	}
+
+	// Write promotion report
+	if t.ReportWriter != nil {
+		reportWriter, err := t.ReportWriter(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to open report writer: %w", err)
+		}
+
+		if err := json.NewEncoder(reportWriter).Encode(report); err != nil {
+			return fmt.Errorf("unable to encode promotion report: %w", err)
+		}
+	}
+
+	// No error
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (t *PromoteTask) validate() error {
+	if types.IsNil(t.ContainerReader) {
+		return fmt.Errorf("unable to run task with a nil containerReader provider")
+	}
+	if types.IsNil(t.OutputWriter) {
+		return fmt.Errorf("unable to run task with a nil outputWriter provider")
+	}
+	if cso.FromStageName(t.FromStage) < csov1.QualityLevel_QUALITY_LEVEL_PRODUCTION {
+		return fmt.Errorf("invalid source stage '%s'", t.FromStage)
+	}
+	if cso.FromStageName(t.ToStage) < csov1.QualityLevel_QUALITY_LEVEL_PRODUCTION {
+		return fmt.Errorf("invalid target stage '%s'", t.ToStage)
+	}
+	if strings.EqualFold(t.FromStage, t.ToStage) {
+		return fmt.Errorf("source and target stages must be different")
+	}
+	if t.Platform == "" {
+		return fmt.Errorf("platform name must not be blank")
+	}
+	if _, err := bundle.ParseMergeStrategy(string(t.MergeStrategy)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (t *PromoteTask) promote(src *bundlev1.Bundle) (*bundlev1.Bundle, []bundle.MergeEntry) {
+	var (
+		promoted = &bundlev1.Bundle{}
+		skipped  = []bundle.MergeEntry{}
+	)
+
+	for _, p := range src.Packages {
+		// Archived packages are not promoted
+		if bundle.IsArchived(p) {
+			skipped = append(skipped, bundle.MergeEntry{Path: p.Name, Reason: "archived"})
+			continue
+		}
+
+		// Select packages to promote
+		targetPath, ok := t.rewritePath(p.Name)
+		if !ok {
+			continue
+		}
+
+		// Extract stage specific keys
+		exclusions := types.StringArray{}
+		if v, ok := p.Annotations[NoPromoteAnnotation]; ok {
+			if strings.TrimSpace(v) == "*" {
+				skipped = append(skipped, bundle.MergeEntry{Path: p.Name, Reason: "no-promote"})
+				continue
+			}
+			for _, k := range strings.Split(v, ",") {
+				exclusions = append(exclusions, strings.TrimSpace(k))
+			}
+		}
+
+		// Clone package
+		np, _ := proto.Clone(p).(*bundlev1.Package)
+		np.Name = targetPath
+
+		// Remove stage specific keys
+		if np.Secrets != nil && len(exclusions) > 0 {
+			data := []*bundlev1.KV{}
+			for _, kv := range np.Secrets.Data {
+				if exclusions.Contains(kv.Key) {
+					skipped = append(skipped, bundle.MergeEntry{Path: p.Name, Key: kv.Key, Reason: "no-promote"})
+					continue
+				}
+				data = append(data, kv)
+			}
+			np.Secrets.Data = data
+		}
+
+		promoted.Packages = append(promoted.Packages, np)
+	}
+
+	return promoted, skipped
+}
+
+func (t *PromoteTask) rewritePath(name string) (string, bool) {
+	// Only CSO compliant paths are promotable
+	if err := cso.Validate(name); err != nil {
+		return "", false
+	}
+
+	parts := strings.Split(strings.TrimPrefix(name, "/"), "/")
+	switch {
+	case strings.EqualFold(parts[0], "app"):
+		// app/<stage>/<platform>/<product>/<version>/<component>/<key>
+		if !strings.EqualFold(parts[1], t.FromStage) || !strings.EqualFold(parts[2], t.Platform) {
+			return "", false
+		}
+		if t.Product != "" && !strings.EqualFold(parts[3], t.Product) {
+			return "", false
+		}
+	case strings.EqualFold(parts[0], "platform"):
+		// platform/<stage>/<name>/<region>/<service>/<key>
+		if t.Product != "" {
+			return "", false
+		}
+		if !strings.EqualFold(parts[1], t.FromStage) || !strings.EqualFold(parts[2], t.Platform) {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+
+	// Rewrite stage component only
+	parts[1] = strings.ToLower(t.ToStage)
+
+	return strings.Join(parts, "/"), true
+}
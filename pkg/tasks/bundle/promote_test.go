@@ -0,0 +1,212 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+)
+
+func containerReader(t *testing.T, b *bundlev1.Bundle) func(context.Context) (io.Reader, error) {
+	var buf bytes.Buffer
+	if err := bundle.ToContainerWriter(&buf, b); err != nil {
+		t.Fatalf("unable to prepare container: %v", err)
+	}
+	return func(context.Context) (io.Reader, error) {
+		return bytes.NewReader(buf.Bytes()), nil
+	}
+}
+
+func bufferWriter(buf *bytes.Buffer) func(context.Context) (io.Writer, error) {
+	return func(context.Context) (io.Writer, error) {
+		return buf, nil
+	}
+}
+
+func stageFixtures(t *testing.T) (staging, production *bundlev1.Bundle) {
+	var err error
+
+	staging, err = bundle.FromMap(map[string]bundle.KV{
+		"app/staging/billing/payments/1.0.0/api/database":    {"user": "payments", "password": "staging-password", "host": "db.staging"},
+		"app/staging/billing/payments/1.0.0/api/stripe":      {"api_key": "sk_test"},
+		"app/staging/billing/invoices/2.1.0/worker/queue":    {"token": "queue-token"},
+		"app/staging/security/payments/1.0.0/api/database":   {"user": "other"},
+		"platform/staging/billing/eu-central-1/postgres/dba": {"password": "dba-password"},
+		"app/qa/billing/payments/1.0.0/api/database":         {"user": "qa"},
+	})
+	if err != nil {
+		t.Fatalf("unable to prepare staging bundle: %v", err)
+	}
+	for _, p := range staging.Packages {
+		switch p.Name {
+		case "app/staging/billing/payments/1.0.0/api/database":
+			bundle.Annotate(p, NoPromoteAnnotation, "host")
+		case "app/staging/billing/payments/1.0.0/api/stripe":
+			bundle.Annotate(p, NoPromoteAnnotation, "*")
+		}
+	}
+
+	production, err = bundle.FromMap(map[string]bundle.KV{
+		"app/production/billing/payments/1.0.0/api/database": {"user": "payments", "password": "production-password", "host": "db.production"},
+		"app/production/billing/payments/0.9.0/api/legacy":   {"secret": "legacy"},
+	})
+	if err != nil {
+		t.Fatalf("unable to prepare production bundle: %v", err)
+	}
+
+	return staging, production
+}
+
+func Test_PromoteTask(t *testing.T) {
+	staging, production := stageFixtures(t)
+
+	testCases := []struct {
+		desc         string
+		product      string
+		strategy     bundle.MergeStrategy
+		wantErr      bool
+		wantPaths    []string
+		wantPassword string
+		wantReport   *bundle.MergeReport
+	}{
+		{
+			desc:     "same stages",
+			strategy: bundle.MergeStrategyKeep,
+			wantErr:  true,
+		},
+		{
+			desc:         "platform promotion",
+			strategy:     bundle.MergeStrategyKeep,
+			wantPassword: "production-password",
+			wantPaths: []string{
+				"platform/production/billing/eu-central-1/postgres/dba",
+				"app/production/billing/invoices/2.1.0/worker/queue",
+				"app/production/billing/payments/0.9.0/api/legacy",
+				"app/production/billing/payments/1.0.0/api/database",
+			},
+			wantReport: &bundle.MergeReport{
+				Copied: []bundle.MergeEntry{
+					{Path: "platform/production/billing/eu-central-1/postgres/dba", Key: "password"},
+					{Path: "app/production/billing/invoices/2.1.0/worker/queue", Key: "token"},
+				},
+				Skipped: []bundle.MergeEntry{
+					{Path: "app/staging/billing/payments/1.0.0/api/database", Key: "host", Reason: "no-promote"},
+					{Path: "app/staging/billing/payments/1.0.0/api/stripe", Reason: "no-promote"},
+					{Path: "app/production/billing/payments/1.0.0/api/database", Key: "user", Reason: "identical"},
+				},
+				Conflicted: []bundle.MergeEntry{
+					{Path: "app/production/billing/payments/1.0.0/api/database", Key: "password", Reason: "kept"},
+				},
+			},
+		},
+		{
+			desc:         "product promotion with overwrite",
+			product:      "payments",
+			strategy:     bundle.MergeStrategyOverwrite,
+			wantPassword: "staging-password",
+			wantPaths: []string{
+				"app/production/billing/payments/0.9.0/api/legacy",
+				"app/production/billing/payments/1.0.0/api/database",
+			},
+			wantReport: &bundle.MergeReport{
+				Copied: []bundle.MergeEntry{},
+				Skipped: []bundle.MergeEntry{
+					{Path: "app/staging/billing/payments/1.0.0/api/database", Key: "host", Reason: "no-promote"},
+					{Path: "app/staging/billing/payments/1.0.0/api/stripe", Reason: "no-promote"},
+					{Path: "app/production/billing/payments/1.0.0/api/database", Key: "user", Reason: "identical"},
+				},
+				Conflicted: []bundle.MergeEntry{
+					{Path: "app/production/billing/payments/1.0.0/api/database", Key: "password", Reason: "overwritten"},
+				},
+			},
+		},
+		{
+			desc:     "fail on conflict",
+			strategy: bundle.MergeStrategyFail,
+			wantErr:  true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var out, report bytes.Buffer
+
+			toStage := "production"
+			if tC.desc == "same stages" {
+				toStage = "staging"
+			}
+
+			task := &PromoteTask{
+				ContainerReader: containerReader(t, staging),
+				TargetReader:    containerReader(t, production),
+				OutputWriter:    bufferWriter(&out),
+				ReportWriter:    bufferWriter(&report),
+				FromStage:       "staging",
+				ToStage:         toStage,
+				Platform:        "billing",
+				Product:         tC.product,
+				MergeStrategy:   tC.strategy,
+			}
+
+			err := task.Run(context.Background())
+			if (err != nil) != tC.wantErr {
+				t.Fatalf("error during the call, error = %v, wantErr %v", err, tC.wantErr)
+			}
+			if tC.wantErr {
+				return
+			}
+
+			// Check output bundle
+			b, err := bundle.FromContainerReader(&out)
+			if err != nil {
+				t.Fatalf("unable to load output bundle: %v", err)
+			}
+			paths, _ := bundle.Paths(b)
+			if diff := cmp.Diff(paths, tC.wantPaths); diff != "" {
+				t.Errorf("paths mismatch (-got +want):\n%s", diff)
+			}
+
+			secrets, err := bundle.Read(b, "app/production/billing/payments/1.0.0/api/database")
+			if err != nil {
+				t.Fatalf("unable to read promoted package: %v", err)
+			}
+			if secrets["password"] != tC.wantPassword {
+				t.Errorf("expected password %q, got %q", tC.wantPassword, secrets["password"])
+			}
+			if secrets["host"] != "db.production" {
+				t.Errorf("stage specific key must not be promoted, got %q", secrets["host"])
+			}
+
+			// Check report
+			got := &bundle.MergeReport{}
+			if err := json.Unmarshal(report.Bytes(), got); err != nil {
+				t.Fatalf("unable to decode report: %v", err)
+			}
+			if diff := cmp.Diff(got, tC.wantReport); diff != "" {
+				t.Errorf("report mismatch (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+)
+
+func TestQuarantineTask(t *testing.T) {
+	b := testbundle.New()
+	b.Package(activePath).Secret("user", "admin")
+	b.Package(archivedPath).Secret("token", "legacy-token")
+
+	// Reason is required
+	err := (&QuarantineTask{
+		ContainerReader: testbundle.Reader(t, b.Build()),
+		OutputWriter:    testbundle.Writer(&bytes.Buffer{}),
+		Paths:           []string{activePath},
+	}).Run(context.Background())
+	if err == nil {
+		t.Fatal("error should be raised without reason")
+	}
+
+	// Quarantine
+	var quarantined bytes.Buffer
+	err = (&QuarantineTask{
+		ContainerReader: testbundle.Reader(t, b.Build()),
+		OutputWriter:    testbundle.Writer(&quarantined),
+		Paths:           []string{activePath},
+		Reason:          "leaked in CI logs",
+		Actor:           "alice@host",
+	}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	content := quarantined.Bytes()
+
+	got := testbundle.Load(t, bytes.NewBuffer(content))
+	if reason, ok := bundle.QuarantineReason(got.Packages[0]); !ok || reason != "leaked in CI logs" {
+		t.Errorf("package must be quarantined, got %v %q", ok, reason)
+	}
+
+	// Quarantine twice
+	err = (&QuarantineTask{
+		ContainerReader: bytesReader(content),
+		OutputWriter:    testbundle.Writer(&bytes.Buffer{}),
+		Paths:           []string{activePath},
+		Reason:          "again",
+	}).Run(context.Background())
+	if !errors.Is(err, bundle.ErrPackageQuarantined) {
+		t.Fatalf("expected ErrPackageQuarantined, got %v", err)
+	}
+
+	// Release
+	var released bytes.Buffer
+	err = (&QuarantineTask{
+		ContainerReader: bytesReader(content),
+		OutputWriter:    testbundle.Writer(&released),
+		Paths:           []string{activePath},
+		Release:         true,
+		Actor:           "bob@host",
+	}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got = testbundle.Load(t, &released)
+	if bundle.IsQuarantined(got.Packages[0]) {
+		t.Error("package must be released")
+	}
+	entries, err := bundle.History(got.Packages[0], bundle.QuarantineHistoryKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2 || entries[1].Operation != "release" || entries[1].Actor != "bob@host" {
+		t.Errorf("release must be recorded in history, got %+v", entries)
+	}
+}
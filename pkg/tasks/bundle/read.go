@@ -32,6 +32,12 @@ type ReadTask struct {
 	OutputWriter    tasks.WriterProvider
 	PackageName     string
 	SecretKey       string
+	IncludeArchived bool
+}
+
+// Capabilities returns the task required capabilities.
+func (t *ReadTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
 }
 
 // Run the task.
@@ -48,6 +54,11 @@ func (t *ReadTask) Run(ctx context.Context) error {
 		return fmt.Errorf("unable to load bundle content: %w", err)
 	}
 
+	// Hide archived packages
+	if !t.IncludeArchived {
+		b = bundle.WithoutArchived(b)
+	}
+
 	// Read a secret from bundle
 	s, err := bundle.Read(b, t.PackageName)
 	if err != nil {
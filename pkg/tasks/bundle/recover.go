@@ -0,0 +1,98 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/sdk/types"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// RecoverTask implements incremental bundle file recovery task.
+type RecoverTask struct {
+	IncrementalReader tasks.ReaderProvider
+	OutputWriter      tasks.WriterProvider
+
+	result *RecoverResult
+}
+
+// RecoverResult describes an incremental bundle recovery task execution.
+type RecoverResult struct {
+	tasks.Result
+	bundle.RecoveryReport
+}
+
+// Capabilities returns the task required capabilities.
+func (t *RecoverTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Result returns the last execution result.
+func (t *RecoverTask) Result() interface{} {
+	return t.result
+}
+
+// Run the task.
+func (t *RecoverTask) Run(ctx context.Context) error {
+	t.result = &RecoverResult{}
+
+	// Check arguments
+	if types.IsNil(t.IncrementalReader) {
+		return fmt.Errorf("unable to run task with a nil incrementalReader provider")
+	}
+	if types.IsNil(t.OutputWriter) {
+		return fmt.Errorf("unable to run task with a nil outputWriter provider")
+	}
+
+	// Create input reader
+	reader, err := t.IncrementalReader(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open incremental bundle: %w", err)
+	}
+
+	// Recover packages
+	b, report, err := bundle.Recover(reader)
+	if err != nil {
+		return fmt.Errorf("unable to recover incremental bundle: %w", err)
+	}
+	t.result.RecoveryReport = *report
+	if !report.Complete {
+		t.result.Warn("incremental bundle is truncated, %d package(s) salvaged, %d byte(s) discarded", report.Packages, report.DiscardedBytes)
+		log.For(ctx).Warn("Incremental bundle is truncated", zap.Int("packages", report.Packages), zap.Int64("discarded", report.DiscardedBytes))
+	}
+
+	// Create output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output bundle: %w", err)
+	}
+
+	// Dump bundle
+	if err := bundle.ToContainerWriter(writer, b); err != nil {
+		return fmt.Errorf("unable to dump bundle content: %w", err)
+	}
+
+	// No error
+	return nil
+}
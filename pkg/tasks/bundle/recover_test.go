@@ -0,0 +1,77 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+)
+
+func TestRecoverTask(t *testing.T) {
+	b := testbundle.New().
+		Package("app/production/database").Secret("password", "v0").
+		Package("app/production/cache").Secret("password", "v1").
+		Package("app/staging/cache").Secret("password", "v2").
+		Build()
+
+	// Write all packages
+	var in bytes.Buffer
+	iw, err := bundle.NewIncrementalWriter(&in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range b.Packages {
+		if err = iw.Append(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = iw.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Interrupted in the middle of the last frame
+	truncated := in.Bytes()[:in.Len()-3]
+
+	var out bytes.Buffer
+	task := &RecoverTask{
+		IncrementalReader: func(context.Context) (io.Reader, error) {
+			return bytes.NewReader(truncated), nil
+		},
+		OutputWriter: testbundle.Writer(&out),
+	}
+	if err := task.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := testbundle.Load(t, &out)
+	if diff := cmp.Diff([]string{"app/production/cache", "app/production/database"}, packageNames(got)); diff != "" {
+		t.Errorf("unexpected packages (-want +got):\n%s", diff)
+	}
+
+	res := task.result
+	if res.Complete || res.Packages != 2 || len(res.Warnings) != 1 {
+		t.Errorf("unexpected result %+v", res)
+	}
+}
@@ -0,0 +1,126 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/refs"
+	"github.com/elastic/harp/pkg/sdk/types"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// ErrDanglingReferences is raised when the bundle contains references to
+// missing paths.
+var ErrDanglingReferences = errors.New("bundle has dangling references")
+
+// CheckRefsTask implements referential integrity check task.
+type CheckRefsTask struct {
+	ContainerReader    tasks.ReaderProvider
+	CompanionReaders   []tasks.ReaderProvider
+	OutputWriter       tasks.WriterProvider
+	Extractors         []string
+	DisabledExtractors []string
+}
+
+// Capabilities returns the task required capabilities.
+func (t *CheckRefsTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Run the task.
+func (t *CheckRefsTask) Run(ctx context.Context) error {
+	// Check arguments
+	if types.IsNil(t.ContainerReader) {
+		return fmt.Errorf("unable to run task with a nil containerReader provider")
+	}
+	if types.IsNil(t.OutputWriter) {
+		return fmt.Errorf("unable to run task with a nil outputWriter provider")
+	}
+
+	// Prepare extractors
+	extractors, err := refs.Select(t.Extractors, t.DisabledExtractors)
+	if err != nil {
+		return fmt.Errorf("unable to prepare reference extractors: %w", err)
+	}
+
+	// Load bundle
+	b, err := loadBundle(ctx, t.ContainerReader)
+	if err != nil {
+		return err
+	}
+
+	// Load companion bundles
+	companions := []*bundlev1.Bundle{}
+	for _, cr := range t.CompanionReaders {
+		cb, err := loadBundle(ctx, cr)
+		if err != nil {
+			return fmt.Errorf("unable to load companion bundle: %w", err)
+		}
+		companions = append(companions, cb)
+	}
+
+	// Check references
+	report, err := refs.Check(b, companions, extractors)
+	if err != nil {
+		return fmt.Errorf("unable to check references: %w", err)
+	}
+
+	// Create output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output writer: %w", err)
+	}
+
+	// Print report
+	if err := json.NewEncoder(writer).Encode(report); err != nil {
+		return fmt.Errorf("unable to encode reference report: %w", err)
+	}
+
+	// Check dangling references
+	if report.HasDangling() {
+		return ErrDanglingReferences
+	}
+
+	// No error
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func loadBundle(ctx context.Context, rp tasks.ReaderProvider) (*bundlev1.Bundle, error) {
+	// Create input reader
+	reader, err := rp(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open input bundle: %w", err)
+	}
+
+	// Load bundle
+	b, err := bundle.FromContainerReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load bundle content: %w", err)
+	}
+
+	// No error
+	return b, nil
+}
@@ -0,0 +1,88 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/elastic/harp/pkg/bundle/refs"
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+func TestCheckRefsTask(t *testing.T) {
+	main := testbundle.New()
+	main.Package("app/production/security/harp/v1.0.0/server/web").
+		Secret("dsn", "postgres://{{secret:infra/aws/security/eu-central-1/rds/adminconsole}}@db").
+		Annotation(refs.AliasAnnotation, "app/production/security/harp/v1.0.0/server/legacy")
+
+	companion := testbundle.New()
+	companion.Package("infra/aws/security/eu-central-1/rds/adminconsole").Secret("password", "foo")
+
+	testCases := []struct {
+		desc       string
+		companions []tasks.ReaderProvider
+		disabled   []string
+		wantErr    error
+		want       string
+	}{
+		{
+			desc:    "dangling",
+			wantErr: ErrDanglingReferences,
+			want:    `"target":"infra/aws/security/eu-central-1/rds/adminconsole"`,
+		},
+		{
+			desc:       "companion resolution",
+			companions: []tasks.ReaderProvider{testbundle.Reader(t, companion.Build())},
+			wantErr:    ErrDanglingReferences,
+			want:       `"target":"app/production/security/harp/v1.0.0/server/legacy"`,
+		},
+		{
+			desc:       "alias disabled",
+			companions: []tasks.ReaderProvider{testbundle.Reader(t, companion.Build())},
+			disabled:   []string{"alias"},
+			want:       `"checked":1,"dangling":[]`,
+		},
+		{
+			desc:     "unknown extractor",
+			disabled: []string{"foo"},
+			wantErr:  refs.ErrExtractorNotFound,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var out bytes.Buffer
+			err := (&CheckRefsTask{
+				ContainerReader:    testbundle.Reader(t, main.Build()),
+				CompanionReaders:   tC.companions,
+				OutputWriter:       testbundle.Writer(&out),
+				DisabledExtractors: tC.disabled,
+			}).Run(context.Background())
+			if !errors.Is(err, tC.wantErr) {
+				t.Fatalf("expected error %v, got %v", tC.wantErr, err)
+			}
+			if !strings.Contains(out.String(), tC.want) {
+				t.Errorf("report must contain %q, got %s", tC.want, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+var updateReports = flag.Bool("update-reports", false, "update task report golden files")
+
+func TestTaskReports(t *testing.T) {
+	base := testbundle.New()
+	base.Package("app/production/security/harp/v1.0.0/server/database").
+		Secret("user", "admin").
+		Secret("password", "bW9yZS1zZWN1cmUtcGFzc3dvcmQtdmFsdWU")
+	base.Package("app/staging/security/harp/v1.0.0/server/database").
+		Secret("user", "admin").
+		Secret("password", "letmein")
+
+	overrides := testbundle.New()
+	overrides.Package("app/production/security/harp/v1.0.0/server/database").
+		Secret("user", "root").
+		Secret("port", "5432")
+	overrides.Package("app/production/security/harp/v1.0.0/server/cache").
+		Secret("password", "bW9yZS1zZWN1cmUtY2FjaGUtdmFsdWU")
+
+	testCases := []struct {
+		desc    string
+		task    tasks.Task
+		wantErr bool
+	}{
+		{
+			desc: "filter",
+			task: &FilterTask{
+				ContainerReader: testbundle.Reader(t, base.Build()),
+				OutputWriter:    testbundle.Writer(&bytes.Buffer{}),
+				KeepPaths:       []string{"^app/production/"},
+			},
+		},
+		{
+			desc: "merge",
+			task: &MergeTask{
+				ContainerReaders: []tasks.ReaderProvider{
+					testbundle.Reader(t, base.Build()),
+					testbundle.Reader(t, overrides.Build()),
+				},
+				OutputWriter:  testbundle.Writer(&bytes.Buffer{}),
+				MergeStrategy: bundle.MergeStrategyKeep,
+			},
+		},
+		{
+			desc: "merge-failed",
+			task: &MergeTask{
+				ContainerReaders: []tasks.ReaderProvider{
+					testbundle.Reader(t, base.Build()),
+					testbundle.Reader(t, overrides.Build()),
+				},
+				OutputWriter:  testbundle.Writer(&bytes.Buffer{}),
+				MergeStrategy: bundle.MergeStrategyFail,
+			},
+			wantErr: true,
+		},
+		{
+			desc: "diff",
+			task: &DiffTask{
+				SourceReader:      testbundle.Reader(t, base.Build()),
+				DestinationReader: testbundle.Reader(t, overrides.Build()),
+				OutputWriter:      testbundle.Writer(&bytes.Buffer{}),
+			},
+		},
+		{
+			desc: "lint-failed",
+			task: &LintTask{
+				ContainerReader: testbundle.Reader(t, base.Build()),
+				OutputWriter:    testbundle.Writer(&bytes.Buffer{}),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			err := tC.task.Run(context.Background())
+			if (err != nil) != tC.wantErr {
+				t.Fatalf("error during the call, error = %v, wantErr %v", err, tC.wantErr)
+			}
+
+			var out bytes.Buffer
+			if err := tasks.WriteReport(context.Background(), testbundle.Writer(&out), tasks.NewReport(tC.desc, tC.task, err, 0)); err != nil {
+				t.Fatalf("unable to write report: %v", err)
+			}
+
+			golden := filepath.Join("testdata", "reports", tC.desc+".json.golden")
+			if *updateReports {
+				if err := ioutil.WriteFile(golden, out.Bytes(), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("unable to read golden file: %v", err)
+			}
+			if diff := cmp.Diff(string(want), out.String()); diff != "" {
+				t.Errorf("%q. report mismatch (-want +got):\n%s", tC.desc, diff)
+			}
+		})
+	}
+}
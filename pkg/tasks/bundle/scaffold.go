@@ -0,0 +1,162 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/archetype"
+	"github.com/elastic/harp/pkg/sdk/types"
+	"github.com/elastic/harp/pkg/tasks"
+	"github.com/elastic/harp/pkg/template/engine"
+)
+
+// ScaffoldTask implements package scaffolding from archetypes.
+type ScaffoldTask struct {
+	ContainerReader tasks.ReaderProvider
+	PackReader      tasks.ReaderProvider
+	OutputWriter    tasks.WriterProvider
+	TemplateContext engine.Context
+	Archetype       string
+	Path            string
+	ListArchetypes  bool
+}
+
+// Capabilities returns the task required capabilities.
+func (t *ScaffoldTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Run the task.
+func (t *ScaffoldTask) Run(ctx context.Context) error {
+	// Check arguments
+	if types.IsNil(t.OutputWriter) {
+		return fmt.Errorf("unable to run task with a nil outputWriter provider")
+	}
+
+	// Prepare archetypes
+	registry := archetype.Builtin()
+	if t.PackReader != nil {
+		packReader, err := t.PackReader(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to open archetype pack: %w", err)
+		}
+
+		pack, err := archetype.ParsePack(packReader)
+		if err != nil {
+			return fmt.Errorf("unable to load archetype pack: %w", err)
+		}
+		if err := registry.Override(pack); err != nil {
+			return fmt.Errorf("unable to register archetype pack: %w", err)
+		}
+	}
+
+	// Display archetypes
+	if t.ListArchetypes {
+		return t.list(ctx, registry)
+	}
+
+	// Check arguments
+	if t.Archetype == "" {
+		return fmt.Errorf("archetype must not be blank")
+	}
+	if strings.Trim(t.Path, "/") == "" {
+		return fmt.Errorf("package path must not be blank")
+	}
+	if types.IsNil(t.TemplateContext) {
+		return fmt.Errorf("unable to run task with a nil template context")
+	}
+
+	a, err := registry.Get(t.Archetype)
+	if err != nil {
+		return err
+	}
+
+	// Load existing bundle
+	b := &bundlev1.Bundle{
+		Packages: []*bundlev1.Package{},
+	}
+	if t.ContainerReader != nil {
+		reader, err := t.ContainerReader(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to open input bundle: %w", err)
+		}
+
+		b, err = bundle.FromContainerReader(reader)
+		if err != nil {
+			return fmt.Errorf("unable to load bundle content: %w", err)
+		}
+	}
+
+	// Render package
+	p, err := a.Render(t.TemplateContext, t.Path)
+	if err != nil {
+		return fmt.Errorf("unable to scaffold package: %w", err)
+	}
+
+	// Check package conflict
+	for _, existing := range b.Packages {
+		if existing.Name == p.Name {
+			return fmt.Errorf("package '%s' already exists", p.Name)
+		}
+	}
+	b.Packages = append(b.Packages, p)
+
+	// Create output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output bundle: %w", err)
+	}
+
+	// Dump all content
+	if err := bundle.ToContainerWriter(writer, b); err != nil {
+		return fmt.Errorf("unable to dump bundle content: %w", err)
+	}
+
+	// No error
+	return nil
+}
+
+func (t *ScaffoldTask) list(ctx context.Context, registry *archetype.Registry) error {
+	// Create output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output writer: %w", err)
+	}
+
+	tw := tabwriter.NewWriter(writer, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ARCHETYPE\tREQUIRED KEYS\tDESCRIPTION")
+	for _, a := range registry.List() {
+		required := strings.Join(a.Schema.Required, ",")
+		if required == "" {
+			required = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", a.Name, required, a.Description)
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("unable to write archetypes: %w", err)
+	}
+
+	// No error
+	return nil
+}
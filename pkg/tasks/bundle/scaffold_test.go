@@ -0,0 +1,150 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/archetype"
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+	"github.com/elastic/harp/pkg/template/engine"
+)
+
+const scaffoldPath = "app/production/billing/api/v1.0.0/server/database"
+
+func TestScaffoldTask(t *testing.T) {
+	// Scaffold into a new container
+	var out bytes.Buffer
+	err := (&ScaffoldTask{
+		OutputWriter:    testbundle.Writer(&out),
+		TemplateContext: engine.NewContext(engine.WithValues(engine.Values{"host": "db.internal"})),
+		Archetype:       "database",
+		Path:            scaffoldPath,
+	}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	b := testbundle.Load(t, &out)
+	if len(b.Packages) != 1 || b.Packages[0].Name != scaffoldPath {
+		t.Fatalf("unexpected packages %v", b.Packages)
+	}
+	secrets, err := bundle.AsSecretMap(b.Packages[0])
+	if err != nil {
+		t.Fatalf("unable to unpack secrets: %v", err)
+	}
+	if secrets["host"] != "db.internal" {
+		t.Errorf("unexpected host %v", secrets["host"])
+	}
+	if got := b.Packages[0].Annotations[archetype.Annotation]; got != "database" {
+		t.Errorf("unexpected archetype annotation %q", got)
+	}
+
+	// Scaffold into an existing container
+	content := out.Bytes()
+	out = bytes.Buffer{}
+	err = (&ScaffoldTask{
+		ContainerReader: bytesReader(content),
+		OutputWriter:    testbundle.Writer(&out),
+		TemplateContext: engine.NewContext(),
+		Archetype:       "ssh-keypair",
+		Path:            "app/production/billing/api/v1.0.0/server/deploy",
+	}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b := testbundle.Load(t, &out); len(b.Packages) != 2 {
+		t.Errorf("expected 2 packages, got %d", len(b.Packages))
+	}
+
+	// Existing package must not be overwritten
+	err = (&ScaffoldTask{
+		ContainerReader: bytesReader(content),
+		OutputWriter:    testbundle.Writer(&bytes.Buffer{}),
+		TemplateContext: engine.NewContext(),
+		Archetype:       "database",
+		Path:            "/" + scaffoldPath,
+	}).Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected conflict error, got %v", err)
+	}
+}
+
+func TestScaffoldTask_Pack(t *testing.T) {
+	pack := []byte(`
+apiVersion: harp.elastic.co/v1
+kind: ArchetypePack
+spec:
+  archetypes:
+    - name: database
+      description: Organization database
+      template: '{"dsn": "postgres://{{ .Data.name }}.internal"}'
+      schema:
+        type: object
+        required: [dsn]
+`)
+
+	// List archetypes
+	var out bytes.Buffer
+	err := (&ScaffoldTask{
+		PackReader:     bytesReader(pack),
+		OutputWriter:   testbundle.Writer(&out),
+		ListArchetypes: true,
+	}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "Organization database") || !strings.Contains(out.String(), "ssh-keypair") {
+		t.Errorf("unexpected archetype list:\n%s", out.String())
+	}
+
+	// Organization archetype overrides the built-in one
+	out = bytes.Buffer{}
+	err = (&ScaffoldTask{
+		PackReader:      bytesReader(pack),
+		OutputWriter:    testbundle.Writer(&out),
+		TemplateContext: engine.NewContext(),
+		Archetype:       "database",
+		Path:            scaffoldPath,
+	}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	secrets, err := bundle.AsSecretMap(testbundle.Load(t, &out).Packages[0])
+	if err != nil {
+		t.Fatalf("unable to unpack secrets: %v", err)
+	}
+	if len(secrets) != 1 || secrets["dsn"] != "postgres://database.internal" {
+		t.Errorf("unexpected secrets %v", secrets)
+	}
+
+	// Unknown archetype
+	err = (&ScaffoldTask{
+		OutputWriter:    testbundle.Writer(&bytes.Buffer{}),
+		TemplateContext: engine.NewContext(),
+		Archetype:       "kafka",
+		Path:            scaffoldPath,
+	}).Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "unable to resolve 'kafka'") {
+		t.Errorf("expected unknown archetype error, got %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/bundle/search"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/sdk/types"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// SearchTask implements bundle content search task.
+type SearchTask struct {
+	ContainerReader tasks.ReaderProvider
+	OutputWriter    tasks.WriterProvider
+	PathPrefix      string
+	KeyGlob         string
+	ValueRegex      string
+	ValueHex        string
+	IncludeValues   bool
+	IncludeArchived bool
+	ContextSize     int
+	MaxResults      int
+	JSONOutput      bool
+}
+
+// Capabilities returns the task required capabilities.
+func (t *SearchTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Run the task.
+func (t *SearchTask) Run(ctx context.Context) error {
+	// Check arguments
+	if types.IsNil(t.ContainerReader) {
+		return fmt.Errorf("unable to run task with a nil containerReader provider")
+	}
+	if types.IsNil(t.OutputWriter) {
+		return fmt.Errorf("unable to run task with a nil outputWriter provider")
+	}
+
+	// Prepare query
+	q := &search.Query{
+		PathPrefix:      t.PathPrefix,
+		KeyGlob:         t.KeyGlob,
+		IncludeValues:   t.IncludeValues,
+		IncludeArchived: t.IncludeArchived,
+		ContextSize:     t.ContextSize,
+		MaxResults:      t.MaxResults,
+	}
+	if t.ValueRegex != "" {
+		re, err := regexp.Compile(t.ValueRegex)
+		if err != nil {
+			return fmt.Errorf("unable to compile value regex: %w", err)
+		}
+		q.ValueRegex = re
+	}
+	if t.ValueHex != "" {
+		raw, err := hex.DecodeString(t.ValueHex)
+		if err != nil {
+			return fmt.Errorf("unable to decode value hex pattern: %w", err)
+		}
+		q.ValueBytes = raw
+	}
+	if err := q.Validate(); err != nil {
+		return err
+	}
+
+	// Load bundle
+	b, err := loadBundle(ctx, t.ContainerReader)
+	if err != nil {
+		return err
+	}
+
+	// Create output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output writer: %w", err)
+	}
+
+	// Print matches as soon as found
+	count, err := search.Search(b, q, func(m *search.Match) error {
+		return printMatch(writer, m, t.JSONOutput)
+	})
+	if err != nil {
+		return fmt.Errorf("unable to search bundle: %w", err)
+	}
+	if t.MaxResults > 0 && count >= t.MaxResults {
+		log.For(ctx).Warn("Maximum result count reached, search has been stopped", zap.Int("max-results", t.MaxResults))
+	}
+
+	// No error
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func printMatch(w io.Writer, m *search.Match, jsonOutput bool) error {
+	if jsonOutput {
+		return json.NewEncoder(w).Encode(m)
+	}
+
+	location := m.Path
+	if m.Key != "" {
+		location = fmt.Sprintf("%s#%s", m.Path, m.Key)
+	}
+
+	_, err := fmt.Fprintf(w, "%s\t%s\t%s\n", location, m.Field, m.Context)
+	return err
+}
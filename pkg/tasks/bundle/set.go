@@ -0,0 +1,112 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/sdk/types"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// SetTask implements secret value edition task.
+type SetTask struct {
+	ContainerReader tasks.ReaderProvider
+	OutputWriter    tasks.WriterProvider
+	Path            string
+	Field           string
+	JSONPatch       string
+	MergePatch      string
+	Actor           string
+	HistoryLimit    int
+}
+
+// Capabilities returns the task required capabilities.
+func (t *SetTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Run the task.
+func (t *SetTask) Run(ctx context.Context) error {
+	// Check arguments
+	if types.IsNil(t.ContainerReader) {
+		return fmt.Errorf("unable to run task with a nil containerReader provider")
+	}
+	if types.IsNil(t.OutputWriter) {
+		return fmt.Errorf("unable to run task with a nil outputWriter provider")
+	}
+	if t.Path == "" || t.Field == "" {
+		return fmt.Errorf("package path and secret field must be specified")
+	}
+
+	var fn bundle.ValuePatchFunc
+	switch {
+	case t.JSONPatch != "" && t.MergePatch != "":
+		return fmt.Errorf("json patch and merge patch are mutually exclusive")
+	case t.JSONPatch != "":
+		fn = bundle.JSONPatch([]byte(t.JSONPatch))
+	case t.MergePatch != "":
+		fn = bundle.MergePatch([]byte(t.MergePatch))
+	default:
+		return fmt.Errorf("a json patch or a merge patch must be specified")
+	}
+
+	// Load bundle
+	b, err := loadBundle(ctx, t.ContainerReader)
+	if err != nil {
+		return err
+	}
+
+	// Keep original state for history
+	before, _ := proto.Clone(b).(*bundlev1.Bundle)
+
+	// Patch secret value
+	if err := bundle.PatchValue(b, t.Path, t.Field, fn); err != nil {
+		return err
+	}
+
+	// Record changed secret keys
+	if err := bundle.RecordChanges(before, b, bundle.Change{
+		Actor:     t.Actor,
+		Operation: "set",
+		Time:      time.Now(),
+		Limit:     t.HistoryLimit,
+	}); err != nil {
+		return fmt.Errorf("unable to record secret history: %w", err)
+	}
+
+	// Create output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output bundle: %w", err)
+	}
+
+	// Dump all content
+	if err := bundle.ToContainerWriter(writer, b); err != nil {
+		return fmt.Errorf("unable to dump bundle content: %w", err)
+	}
+
+	// No error
+	return nil
+}
@@ -0,0 +1,98 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+)
+
+func TestSetTask(t *testing.T) {
+	b := testbundle.New()
+	b.Package(activePath).Secret("config", `{"db":{"port":5432},"hosts":["a"]}`).Secret("password", "s3cr3t")
+
+	// Patch is required
+	err := (&SetTask{
+		ContainerReader: testbundle.Reader(t, b.Build()),
+		OutputWriter:    testbundle.Writer(&bytes.Buffer{}),
+		Path:            activePath,
+		Field:           "config",
+	}).Run(context.Background())
+	if err == nil {
+		t.Fatal("error should be raised without patch")
+	}
+
+	// JSON patch
+	var patched bytes.Buffer
+	err = (&SetTask{
+		ContainerReader: testbundle.Reader(t, b.Build()),
+		OutputWriter:    testbundle.Writer(&patched),
+		Path:            activePath,
+		Field:           "config",
+		JSONPatch:       `[{"op":"replace","path":"/db/port","value":5433},{"op":"add","path":"/hosts/-","value":"b"}]`,
+	}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	content := patched.Bytes()
+
+	got, err := bundle.Typed(testbundle.Load(t, bytes.NewBuffer(content)).Packages[0], "config")
+	if err != nil {
+		t.Fatalf("unable to read patched value: %v", err)
+	}
+	if got.String() != `{"db":{"port":5433},"hosts":["a","b"]}` {
+		t.Errorf("unexpected patched value %s", got.String())
+	}
+
+	// Merge patch
+	var merged bytes.Buffer
+	err = (&SetTask{
+		ContainerReader: bytesReader(content),
+		OutputWriter:    testbundle.Writer(&merged),
+		Path:            activePath,
+		Field:           "config",
+		MergePatch:      `{"hosts":null}`,
+	}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err = bundle.Typed(testbundle.Load(t, &merged).Packages[0], "config")
+	if err != nil {
+		t.Fatalf("unable to read merged value: %v", err)
+	}
+	if got.String() != `{"db":{"port":5433}}` {
+		t.Errorf("unexpected merged value %s", got.String())
+	}
+
+	// Non JSON value
+	err = (&SetTask{
+		ContainerReader: bytesReader(content),
+		OutputWriter:    testbundle.Writer(&bytes.Buffer{}),
+		Path:            activePath,
+		Field:           "password",
+		MergePatch:      `{}`,
+	}).Run(context.Background())
+	if !errors.Is(err, bundle.ErrValueNotJSON) {
+		t.Fatalf("expected ErrValueNotJSON, got %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/awnumar/memguard"
+	"golang.org/x/crypto/nacl/box"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/cache"
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+	"github.com/elastic/harp/pkg/container"
+	"github.com/elastic/harp/pkg/sdk/keyring"
+)
+
+func sealedFixture(t *testing.T) (io.Reader, *memguard.LockedBuffer) {
+	t.Helper()
+
+	b := testbundle.New()
+	b.Package("app/production/payments/api/database").Secret("password", "foo")
+	b.Package("app/production/billing/api/database").Secret("password", "bar")
+
+	c, err := bundle.ToContainer(b.Build())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub, priv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := container.Seal(c, pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := container.Dump(&buf, sealed); err != nil {
+		t.Fatal(err)
+	}
+
+	return &buf, memguard.NewBufferFromBytes([]byte(base64.RawURLEncoding.EncodeToString(priv[:])))
+}
+
+func reader(r io.Reader) func(context.Context) (io.Reader, error) {
+	return func(context.Context) (io.Reader, error) {
+		return r, nil
+	}
+}
+
+func TestCacheTasks(t *testing.T) {
+	dir := t.TempDir()
+	c, err := cache.Open(dir, keyring.File(filepath.Join(dir, "keyring")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sync
+	in, key := sealedFixture(t)
+	sync := &SyncTask{
+		ContainerReader: reader(in),
+		PathsReader:     reader(strings.NewReader("# Payments only\napp/production/payments/**\n")),
+		ContainerKey:    key,
+		Cache:           c,
+		TTL:             time.Hour,
+	}
+	if err := sync.Run(context.Background()); err != nil {
+		t.Fatalf("unable to sync cache: %v", err)
+	}
+	if res := sync.Result().(*SyncResult); res.Cached != 1 {
+		t.Errorf("expected 1 cached path, got %d", res.Cached)
+	}
+
+	// Read a field
+	var out bytes.Buffer
+	err = (&ReadTask{
+		Cache:        c,
+		OutputWriter: testbundle.Writer(&out),
+		Path:         "app/production/payments/api/database",
+		SecretKey:    "password",
+	}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("unable to read cache: %v", err)
+	}
+	if out.String() != "foo" {
+		t.Errorf("unexpected secret value %q", out.String())
+	}
+
+	// Denied path
+	err = (&ReadTask{
+		Cache:        c,
+		OutputWriter: testbundle.Writer(&out),
+		Path:         "app/production/billing/api/database",
+	}).Run(context.Background())
+	if !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	// Purge
+	purge := &PurgeTask{Cache: c}
+	if err := purge.Run(context.Background()); err != nil {
+		t.Fatalf("unable to purge cache: %v", err)
+	}
+	if res := purge.Result().(*PurgeResult); res.Purged != 1 {
+		t.Errorf("expected 1 purged path, got %d", res.Purged)
+	}
+}
+
+func TestSyncTask_InvalidKey(t *testing.T) {
+	dir := t.TempDir()
+	c, err := cache.Open(dir, keyring.File(filepath.Join(dir, "keyring")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in, _ := sealedFixture(t)
+	_, other := sealedFixture(t)
+	err = (&SyncTask{
+		ContainerReader: reader(in),
+		PathsReader:     reader(strings.NewReader("**\n")),
+		ContainerKey:    other,
+		Cache:           c,
+		TTL:             time.Hour,
+	}).Run(context.Background())
+	if err == nil {
+		t.Fatal("error should be raised with an invalid container key")
+	}
+	if _, err := c.Paths(); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("cache must not be written, got %v", err)
+	}
+}
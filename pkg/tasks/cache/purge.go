@@ -0,0 +1,66 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cache
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/elastic/harp/pkg/bundle/cache"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// PurgeTask implements local secret cache purge task.
+type PurgeTask struct {
+	Cache       *cache.Cache
+	ExpiredOnly bool
+
+	result *PurgeResult
+}
+
+// PurgeResult describes a cache purge task execution.
+type PurgeResult struct {
+	tasks.Result
+	Purged int `json:"purged"`
+}
+
+// Result returns the last execution result.
+func (t *PurgeTask) Result() interface{} {
+	return t.result
+}
+
+// Run the task.
+func (t *PurgeTask) Run(_ context.Context) error {
+	t.result = &PurgeResult{}
+
+	// Check arguments
+	if t.Cache == nil {
+		return fmt.Errorf("unable to run task with a nil cache")
+	}
+
+	// Purge entries
+	count, err := t.Cache.Purge(t.ExpiredOnly)
+	if err != nil {
+		t.result.Fail("purge", err)
+		return fmt.Errorf("unable to purge cache: %w", err)
+	}
+	t.result.Purged = count
+
+	// No error
+	return nil
+}
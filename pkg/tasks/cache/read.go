@@ -0,0 +1,78 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/elastic/harp/pkg/bundle/cache"
+	"github.com/elastic/harp/pkg/sdk/types"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// ReadTask implements local secret cache reading task.
+type ReadTask struct {
+	Cache        *cache.Cache
+	OutputWriter tasks.WriterProvider
+	Path         string
+	SecretKey    string
+}
+
+// Run the task.
+func (t *ReadTask) Run(ctx context.Context) error {
+	// Check arguments
+	if t.Cache == nil {
+		return fmt.Errorf("unable to run task with a nil cache")
+	}
+	if types.IsNil(t.OutputWriter) {
+		return fmt.Errorf("unable to run task with a nil outputWriter provider")
+	}
+	if t.Path == "" {
+		return fmt.Errorf("secret path must be defined")
+	}
+
+	// Read cached secrets
+	s, err := t.Cache.Read(t.Path)
+	if err != nil {
+		return fmt.Errorf("unable to read cached secret '%s': %w", t.Path, err)
+	}
+
+	// Prepare output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get output writer: %w", err)
+	}
+
+	if t.SecretKey != "" {
+		v, ok := s[t.SecretKey]
+		if !ok {
+			return fmt.Errorf("requested field does not exist '%s'", t.SecretKey)
+		}
+		fmt.Fprintf(writer, "%s", v)
+	} else {
+		// Dump the secret value
+		if err := json.NewEncoder(writer).Encode(s); err != nil {
+			return fmt.Errorf("unable to encode secret value as json: %w", err)
+		}
+	}
+
+	// No error
+	return nil
+}
@@ -0,0 +1,135 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cache
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/awnumar/memguard"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/cache"
+	"github.com/elastic/harp/pkg/container"
+	"github.com/elastic/harp/pkg/sdk/types"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// SyncTask implements local secret cache synchronization task.
+type SyncTask struct {
+	ContainerReader tasks.ReaderProvider
+	PathsReader     tasks.ReaderProvider
+	ContainerKey    *memguard.LockedBuffer
+	Cache           *cache.Cache
+	TTL             time.Duration
+
+	result *SyncResult
+}
+
+// SyncResult describes a cache synchronization task execution.
+type SyncResult struct {
+	tasks.Result
+	Cached    int        `json:"cached"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// Result returns the last execution result.
+func (t *SyncTask) Result() interface{} {
+	return t.result
+}
+
+// Run the task.
+func (t *SyncTask) Run(ctx context.Context) error {
+	t.result = &SyncResult{}
+
+	// Check arguments
+	if types.IsNil(t.ContainerReader) {
+		return fmt.Errorf("unable to run task with a nil containerReader provider")
+	}
+	if types.IsNil(t.PathsReader) {
+		return fmt.Errorf("unable to run task with a nil pathsReader provider")
+	}
+	if t.ContainerKey == nil {
+		return fmt.Errorf("container key must be defined")
+	}
+	if t.Cache == nil {
+		return fmt.Errorf("unable to run task with a nil cache")
+	}
+
+	// Read allowed paths
+	pathsReader, err := t.PathsReader(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open paths file: %w", err)
+	}
+	patterns, err := cache.ReadPatterns(pathsReader)
+	if err != nil {
+		return err
+	}
+
+	// Create input reader
+	reader, err := t.ContainerReader(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open input container: %w", err)
+	}
+
+	// Load input container
+	in, err := container.Load(reader)
+	if err != nil {
+		return fmt.Errorf("unable to read input container: %w", err)
+	}
+
+	// Decode container key
+	privateKeyRaw, err := base64.RawURLEncoding.DecodeString(t.ContainerKey.String())
+	if err != nil {
+		return fmt.Errorf("unable to decode container key: %w", err)
+	}
+	defer memguard.WipeBytes(privateKeyRaw)
+
+	// Unseal the container
+	out, err := container.Unseal(in, memguard.NewBufferFromBytes(privateKeyRaw))
+	if err != nil {
+		return fmt.Errorf("unable to unseal container: %w", err)
+	}
+
+	// Load bundle
+	b, err := bundle.FromContainer(out)
+	if err != nil {
+		return fmt.Errorf("unable to load bundle content: %w", err)
+	}
+
+	// Never cache archived or quarantined secrets
+	b = bundle.WithoutQuarantined(bundle.WithoutArchived(b))
+
+	// Synchronize cache
+	count, err := t.Cache.Sync(b, patterns, t.TTL)
+	if err != nil {
+		t.result.Fail("sync", err)
+		return fmt.Errorf("unable to synchronize cache: %w", err)
+	}
+	t.result.Cached = count
+	expiresAt := time.Now().Add(t.TTL).UTC()
+	t.result.ExpiresAt = &expiresAt
+	if count == 0 {
+		t.result.Warn("no package matched the allowed paths")
+	}
+
+	// No error
+	return nil
+}
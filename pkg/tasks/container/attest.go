@@ -0,0 +1,126 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/elastic/harp/pkg/container/attestation"
+	"github.com/elastic/harp/pkg/sdk/types"
+	"github.com/elastic/harp/pkg/tasks"
+	"github.com/elastic/harp/pkg/template/consumption"
+)
+
+// AttestTask implements secret container attestation task.
+type AttestTask struct {
+	ContainerReader tasks.ReaderProvider
+	// KeyReader provides a local signing key, ignored when Signer is set.
+	KeyReader    tasks.ReaderProvider
+	Signer       SignerProvider
+	OutputWriter tasks.WriterProvider
+	// ConsumptionReader optionally provides the consumption manifest of the
+	// render which produced the container, embedded in the statement.
+	ConsumptionReader tasks.ReaderProvider
+	SubjectName       string
+	BuilderID         string
+	Validity          time.Duration
+}
+
+// Capabilities returns the task required capabilities.
+func (t *AttestTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Run the task.
+func (t *AttestTask) Run(ctx context.Context) error {
+	// Check arguments
+	if types.IsNil(t.ContainerReader) {
+		return fmt.Errorf("unable to run task with a nil containerReader provider")
+	}
+	if t.Signer == nil {
+		if types.IsNil(t.KeyReader) {
+			return fmt.Errorf("unable to run task with a nil keyReader provider")
+		}
+		t.Signer = LocalSigner(t.KeyReader)
+	}
+	if types.IsNil(t.OutputWriter) {
+		return fmt.Errorf("unable to run task with a nil outputWriter provider")
+	}
+
+	// Load signing key
+	signer, err := t.Signer(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Read container
+	raw, err := readAll(ctx, t.ContainerReader)
+	if err != nil {
+		return fmt.Errorf("unable to read container: %w", err)
+	}
+
+	// Read consumption manifest
+	var manifest *consumption.Manifest
+	if t.ConsumptionReader != nil {
+		reader, errReader := t.ConsumptionReader(ctx)
+		if errReader != nil {
+			return fmt.Errorf("unable to open consumption manifest: %w", errReader)
+		}
+		manifest, err = consumption.ReadManifest(reader)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Build statement
+	statement, err := attestation.NewStatement(raw, attestation.Options{
+		Name:        t.SubjectName,
+		BuilderID:   t.BuilderID,
+		Validity:    t.Validity,
+		Now:         time.Now(),
+		Consumption: manifest,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to prepare attestation statement: %w", err)
+	}
+
+	// Sign statement
+	envelope, err := attestation.Sign(statement, signer)
+	if err != nil {
+		return fmt.Errorf("unable to sign attestation: %w", err)
+	}
+
+	// Create output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output writer: %w", err)
+	}
+
+	// Export envelope
+	enc := json.NewEncoder(writer)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(envelope); err != nil {
+		return fmt.Errorf("unable to encode attestation: %w", err)
+	}
+
+	// No error
+	return nil
+}
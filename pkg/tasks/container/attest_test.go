@@ -0,0 +1,110 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package container
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/container/attestation"
+	"github.com/elastic/harp/pkg/sdk/security/crypto"
+)
+
+func Test_AttestTask(t *testing.T) {
+	// Prepare container
+	b, err := bundle.FromMap(map[string]bundle.KV{
+		"app/production/billing/payments/1.0.0/api/database": {"password": "foo"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var container bytes.Buffer
+	if err := bundle.ToContainerWriter(&container, b); err != nil {
+		t.Fatal(err)
+	}
+
+	// Prepare keys
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privPEM, err := crypto.ToPEM(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubPEM, err := crypto.ToPEM(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Attest
+	var att bytes.Buffer
+	if err := (&AttestTask{
+		ContainerReader: bytesReader(container.Bytes()),
+		KeyReader:       bytesReader([]byte(privPEM)),
+		OutputWriter:    bufferWriter(&att),
+		SubjectName:     "secrets.harp",
+		BuilderID:       "ci@runner",
+	}).Run(context.Background()); err != nil {
+		t.Fatalf("unable to attest container: %v", err)
+	}
+
+	tampered := append([]byte{}, container.Bytes()...)
+	tampered[len(tampered)-1] ^= 0xff
+
+	forged := bytes.Replace(att.Bytes(), []byte(`"payload": "ey`), []byte(`"payload": "Ey`), 1)
+
+	testCases := []struct {
+		desc        string
+		container   []byte
+		attestation []byte
+		wantErr     error
+	}{
+		{desc: "valid", container: container.Bytes(), attestation: att.Bytes()},
+		{desc: "tampered container", container: tampered, attestation: att.Bytes(), wantErr: attestation.ErrDigestMismatch},
+		{desc: "tampered attestation", container: container.Bytes(), attestation: forged, wantErr: attestation.ErrInvalidSignature},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var out bytes.Buffer
+			err := (&VerifyAttestationTask{
+				ContainerReader:   bytesReader(tC.container),
+				AttestationReader: bytesReader(tC.attestation),
+				KeyReader:         bytesReader([]byte(pubPEM)),
+				OutputWriter:      bufferWriter(&out),
+			}).Run(context.Background())
+			if tC.wantErr == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !bytes.Contains(out.Bytes(), []byte("Builder: ci@runner")) {
+					t.Errorf("unexpected output:\n%s", out.String())
+				}
+				return
+			}
+			if !errors.Is(err, tC.wantErr) {
+				t.Errorf("expected %v, got %v", tC.wantErr, err)
+			}
+		})
+	}
+}
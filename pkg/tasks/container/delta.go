@@ -0,0 +1,140 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/elastic/harp/pkg/container/delta"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// DeltaTask implements container delta creation task.
+type DeltaTask struct {
+	BaseReader   tasks.ReaderProvider
+	TargetReader tasks.ReaderProvider
+	OutputWriter tasks.WriterProvider
+	StatsWriter  tasks.WriterProvider
+	ChunkOptions delta.ChunkOptions
+}
+
+// Capabilities returns the task required capabilities.
+func (t *DeltaTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Run the task.
+func (t *DeltaTask) Run(ctx context.Context) error {
+	// Read base content
+	base, err := readAll(ctx, t.BaseReader)
+	if err != nil {
+		return fmt.Errorf("unable to read base container: %w", err)
+	}
+
+	// Read target content
+	target, err := readAll(ctx, t.TargetReader)
+	if err != nil {
+		return fmt.Errorf("unable to read target container: %w", err)
+	}
+
+	// Create output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output writer: %w", err)
+	}
+
+	// Compute delta
+	stats, err := delta.Create(writer, base, target, t.ChunkOptions)
+	if err != nil {
+		return fmt.Errorf("unable to create container delta: %w", err)
+	}
+
+	// Display statistics
+	if t.StatsWriter != nil {
+		statsWriter, errStats := t.StatsWriter(ctx)
+		if errStats != nil {
+			return fmt.Errorf("unable to open statistics writer: %w", errStats)
+		}
+		if errStats = json.NewEncoder(statsWriter).Encode(stats); errStats != nil {
+			return fmt.Errorf("unable to encode delta statistics: %w", errStats)
+		}
+	}
+
+	// No error
+	return nil
+}
+
+// ApplyDeltaTask implements container delta application task.
+type ApplyDeltaTask struct {
+	BaseReader   tasks.ReaderProvider
+	DeltaReader  tasks.ReaderProvider
+	OutputWriter tasks.WriterProvider
+}
+
+// Capabilities returns the task required capabilities.
+func (t *ApplyDeltaTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Run the task.
+func (t *ApplyDeltaTask) Run(ctx context.Context) error {
+	// Read base content
+	base, err := readAll(ctx, t.BaseReader)
+	if err != nil {
+		return fmt.Errorf("unable to read base container: %w", err)
+	}
+
+	// Open delta reader
+	reader, err := t.DeltaReader(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open delta reader: %w", err)
+	}
+
+	// Reconstruct target
+	target, err := delta.Apply(base, reader)
+	if err != nil {
+		return fmt.Errorf("unable to apply container delta: %w", err)
+	}
+
+	// Create output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output writer: %w", err)
+	}
+
+	// Write reconstructed container
+	if _, err = writer.Write(target); err != nil {
+		return fmt.Errorf("unable to write reconstructed container: %w", err)
+	}
+
+	// No error
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func readAll(ctx context.Context, rp tasks.ReaderProvider) ([]byte, error) {
+	reader, err := rp(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(reader)
+}
@@ -0,0 +1,115 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package container
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/container/delta"
+)
+
+func containerBytes(t *testing.T, packages map[string]bundle.KV) []byte {
+	t.Helper()
+
+	b, err := bundle.FromMap(packages)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := bundle.ToContainerWriter(&buf, b); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func bytesReader(content []byte) func(context.Context) (io.Reader, error) {
+	return func(context.Context) (io.Reader, error) {
+		return bytes.NewReader(content), nil
+	}
+}
+
+func TestDeltaTasks_RoundTrip(t *testing.T) {
+	// Use low compressibility values
+	rnd := rand.New(rand.NewSource(1))
+	packages := map[string]bundle.KV{}
+	for i := 0; i < 200; i++ {
+		secret := make([]byte, 64)
+		rnd.Read(secret)
+		packages[fmt.Sprintf("app/production/team/service-%03d/1.0.0/api/config", i)] = bundle.KV{
+			"password": hex.EncodeToString(secret),
+		}
+	}
+	base := containerBytes(t, packages)
+
+	// Change one package
+	packages["app/production/team/service-100/1.0.0/api/config"] = bundle.KV{"password": "rotated"}
+	target := containerBytes(t, packages)
+
+	// Create delta
+	var deltaBuf, statsBuf bytes.Buffer
+	dt := &DeltaTask{
+		BaseReader:   bytesReader(base),
+		TargetReader: bytesReader(target),
+		OutputWriter: func(context.Context) (io.Writer, error) { return &deltaBuf, nil },
+		StatsWriter:  func(context.Context) (io.Writer, error) { return &statsBuf, nil },
+		ChunkOptions: delta.ChunkOptions{MinSize: 256, AvgSize: 1024, MaxSize: 8192},
+	}
+	if err := dt.Run(context.Background()); err != nil {
+		t.Fatalf("unable to create delta: %v", err)
+	}
+	if deltaBuf.Len() >= len(target)/2 {
+		t.Errorf("delta is too large: %d bytes for a %d bytes target", deltaBuf.Len(), len(target))
+	}
+	if !strings.Contains(statsBuf.String(), "copied_size") {
+		t.Errorf("statistics expected, got %q", statsBuf.String())
+	}
+
+	// Apply delta
+	var out bytes.Buffer
+	at := &ApplyDeltaTask{
+		BaseReader:   bytesReader(base),
+		DeltaReader:  bytesReader(deltaBuf.Bytes()),
+		OutputWriter: func(context.Context) (io.Writer, error) { return &out, nil },
+	}
+	if err := at.Run(context.Background()); err != nil {
+		t.Fatalf("unable to apply delta: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), target) {
+		t.Error("reconstructed container is not byte identical")
+	}
+
+	// Apply on the wrong base
+	at.BaseReader = bytesReader(target)
+	out.Reset()
+	if err := at.Run(context.Background()); err == nil {
+		t.Error("error expected when applying on the wrong base")
+	}
+	if out.Len() != 0 {
+		t.Error("nothing must be written on failure")
+	}
+}
@@ -0,0 +1,121 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/elastic/harp/pkg/container"
+	"github.com/elastic/harp/pkg/sdk/types"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// FeaturesTask implements secret container required features listing task.
+type FeaturesTask struct {
+	ContainerReader tasks.ReaderProvider
+	OutputWriter    tasks.WriterProvider
+	JSONOutput      bool
+}
+
+// FeaturesResult describes the features required by a container.
+type FeaturesResult struct {
+	RequiredFeatures []string `json:"requiredFeatures"`
+	MinVersion       string   `json:"minVersion,omitempty"`
+	Missing          []string `json:"missing"`
+}
+
+// Capabilities returns the task required capabilities.
+func (t *FeaturesTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Run the task.
+func (t *FeaturesTask) Run(ctx context.Context) error {
+	// Check arguments
+	if types.IsNil(t.ContainerReader) {
+		return fmt.Errorf("unable to run task with a nil containerReader provider")
+	}
+	if types.IsNil(t.OutputWriter) {
+		return fmt.Errorf("unable to run task with a nil outputWriter provider")
+	}
+
+	// Create input reader
+	reader, err := t.ContainerReader(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to read input container: %w", err)
+	}
+
+	// Load headers without checking requirements
+	h, err := container.LoadHeaders(reader)
+	if err != nil {
+		return fmt.Errorf("unable to load input container: %w", err)
+	}
+
+	res := &FeaturesResult{
+		RequiredFeatures: h.RequiredFeatures,
+		MinVersion:       h.MinVersion,
+		Missing:          container.MissingFeatures(h),
+	}
+	if res.RequiredFeatures == nil {
+		res.RequiredFeatures = []string{}
+	}
+
+	// Create output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output writer: %w", err)
+	}
+
+	// Export as JSON
+	if t.JSONOutput {
+		enc := json.NewEncoder(writer)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(res); err != nil {
+			return fmt.Errorf("unable to encode features: %w", err)
+		}
+		return nil
+	}
+
+	// Export as text
+	if len(res.RequiredFeatures) == 0 {
+		fmt.Fprintln(writer, "No required features")
+		return nil
+	}
+
+	missing := map[string]bool{}
+	for _, name := range res.Missing {
+		missing[name] = true
+	}
+	tw := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FEATURE\tSUPPORTED")
+	for _, name := range res.RequiredFeatures {
+		fmt.Fprintf(tw, "%s\t%v\n", name, !missing[name])
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("unable to display features: %w", err)
+	}
+	if res.MinVersion != "" {
+		fmt.Fprintf(writer, "\nMinimum version: %s\n", res.MinVersion)
+	}
+
+	// No error
+	return nil
+}
@@ -0,0 +1,158 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package container
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	containerv1 "github.com/elastic/harp/api/gen/go/harp/container/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/container"
+)
+
+func init() {
+	container.MustRegisterFeature("test-task-dedup", "v0.1.22")
+}
+
+func featuresContainer(t *testing.T) *containerv1.Container {
+	t.Helper()
+
+	b, err := bundle.FromMap(map[string]bundle.KV{
+		"app/production/database": {"user": "harp"},
+	})
+	if err != nil {
+		t.Fatalf("unable to build bundle: %v", err)
+	}
+	c, err := bundle.ToContainer(b)
+	if err != nil {
+		t.Fatalf("unable to build container: %v", err)
+	}
+
+	return c
+}
+
+func TestFeaturesTask(t *testing.T) {
+	dump := func(features ...string) []byte {
+		c := featuresContainer(t)
+		c.Headers.RequiredFeatures = features
+		if len(features) > 0 {
+			c.Headers.MinVersion = "v9.0.0"
+		}
+		var buf bytes.Buffer
+		if err := container.Dump(&buf, c); err != nil {
+			t.Fatalf("unable to dump container: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	testCases := []struct {
+		desc        string
+		features    []string
+		wantMissing []string
+		wantText    []string
+	}{
+		{
+			desc:        "no requirement",
+			wantMissing: []string{},
+			wantText:    []string{"No required features"},
+		},
+		{
+			desc:        "supported",
+			features:    []string{"test-task-dedup"},
+			wantMissing: []string{},
+			wantText:    []string{"test-task-dedup  true", "Minimum version: v9.0.0"},
+		},
+		{
+			desc:        "future features",
+			features:    []string{"future-zstd", "test-task-dedup"},
+			wantMissing: []string{"future-zstd"},
+			wantText:    []string{"future-zstd      false", "test-task-dedup  true"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			raw := dump(tC.features...)
+
+			// Text listing
+			var out bytes.Buffer
+			if err := (&FeaturesTask{
+				ContainerReader: bytesReader(raw),
+				OutputWriter:    bufferWriter(&out),
+			}).Run(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, want := range tC.wantText {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("expected %q in output:\n%s", want, out.String())
+				}
+			}
+
+			// JSON listing
+			out.Reset()
+			if err := (&FeaturesTask{
+				ContainerReader: bytesReader(raw),
+				OutputWriter:    bufferWriter(&out),
+				JSONOutput:      true,
+			}).Run(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var res FeaturesResult
+			if err := json.Unmarshal(out.Bytes(), &res); err != nil {
+				t.Fatalf("unable to decode result: %v", err)
+			}
+			if len(res.RequiredFeatures) != len(tC.features) {
+				t.Errorf("unexpected features %v", res.RequiredFeatures)
+			}
+			if !reflect.DeepEqual(res.Missing, tC.wantMissing) {
+				t.Errorf("expected missing %v, got %v", tC.wantMissing, res.Missing)
+			}
+
+			// Readers refuse unsupported features
+			_, err := bundle.FromContainerReader(bytes.NewReader(raw))
+			if len(tC.wantMissing) == 0 && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if len(tC.wantMissing) > 0 && !errors.Is(err, container.ErrUnsupportedFeatures) {
+				t.Errorf("expected unsupported features error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFeaturesTask_Mapped(t *testing.T) {
+	c := featuresContainer(t)
+	c.Headers = &containerv1.Header{
+		ContentEncoding:  c.Headers.ContentEncoding,
+		ContentType:      c.Headers.ContentType,
+		RequiredFeatures: []string{"future-partial-index"},
+	}
+
+	// In-memory containers are checked by the bundle loaders
+	if _, err := bundle.FromContainer(c); !errors.Is(err, container.ErrUnsupportedFeatures) {
+		t.Errorf("expected unsupported features error, got %v", err)
+	}
+	if _, err := bundle.OpenMapped(c); !errors.Is(err, container.ErrUnsupportedFeatures) {
+		t.Errorf("expected unsupported features error, got %v", err)
+	}
+}
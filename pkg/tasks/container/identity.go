@@ -29,6 +29,9 @@ import (
 	"gopkg.in/square/go-jose.v2"
 
 	"github.com/elastic/harp/pkg/container/identity"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/sdk/security/crypto"
+	"github.com/elastic/harp/pkg/sdk/security/piv"
 	"github.com/elastic/harp/pkg/tasks"
 	"github.com/elastic/harp/pkg/vault"
 )
@@ -45,6 +48,9 @@ type jsonWebKey struct {
 	Crv string `json:"crv"`
 	X   string `json:"x"`
 	D   string `json:"d"`
+
+	KeyOps []string         `json:"key_ops,omitempty"`
+	Usage  *crypto.KeyUsage `json:"harp.elastic.co/usage,omitempty"`
 }
 
 // IdentityTask implements secret container identity creation task.
@@ -54,9 +60,21 @@ type IdentityTask struct {
 	PassPhrase       *memguard.LockedBuffer
 	VaultTransitPath string
 	VaultTransitKey  string
+	PIVProvider      piv.Provider
+	PIVSlot          piv.Slot
+	// Store keeps the identity in a keychain with the given name instead of
+	// writing it to the output writer.
+	Store     *identity.Store
+	StoreName string
+}
+
+// Capabilities returns the task required capabilities.
+func (t *IdentityTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{Network: t.VaultTransitKey != ""}
 }
 
 // Run the task.
+//
 //nolint:gocyclo // to refactor
 func (t *IdentityTask) Run(ctx context.Context) error {
 	// Check arguments
@@ -64,6 +82,11 @@ func (t *IdentityTask) Run(ctx context.Context) error {
 		return fmt.Errorf("description must not be blank")
 	}
 
+	// Token backed identity
+	if t.PIVProvider != nil {
+		return t.fromPIV(ctx)
+	}
+
 	// Check exclusive parameters
 	if t.PassPhrase == nil && t.VaultTransitKey == "" {
 		return fmt.Errorf("passphrase or vaultTransitKey must be defined")
@@ -102,6 +125,53 @@ func (t *IdentityTask) Run(ctx context.Context) error {
 		return fmt.Errorf("invalid identity generated")
 	}
 
+	// Write identity
+	return t.write(ctx, id)
+}
+
+func (t *IdentityTask) fromPIV(ctx context.Context) error {
+	// Connect to the token
+	token, err := t.PIVProvider.Open()
+	if err != nil {
+		return fmt.Errorf("unable to open PIV token: %w", err)
+	}
+	defer log.SafeCloseCtx(ctx, token, "unable to close PIV token")
+
+	// Retrieve token identifier
+	serial, err := token.Serial()
+	if err != nil {
+		return fmt.Errorf("unable to retrieve PIV token serial: %w", err)
+	}
+
+	// Read slot public key
+	pub, err := token.PublicKey(t.PIVSlot)
+	if err != nil {
+		return fmt.Errorf("unable to read PIV slot %s public key: %w", t.PIVSlot, err)
+	}
+
+	// Create identity
+	id, err := identity.FromPublicKey(t.Description, pub, &identity.PrivateKey{
+		Encoding: "piv",
+		Content:  piv.Reference{Serial: serial, Slot: t.PIVSlot}.String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Write identity
+	return t.write(ctx, id)
+}
+
+func (t *IdentityTask) write(ctx context.Context, id *identity.Identity) error {
+	// Store in keychain
+	if t.Store != nil {
+		content, err := json.Marshal(id)
+		if err != nil {
+			return fmt.Errorf("unable to serialize final identity: %w", err)
+		}
+		return t.Store.Put(t.StoreName, content)
+	}
+
 	// Retrieve output writer
 	writer, err := t.OutputWriter(ctx)
 	if err != nil {
@@ -118,10 +188,16 @@ func (t *IdentityTask) Run(ctx context.Context) error {
 }
 
 func (t *IdentityTask) sealWithPassPhrase(_ context.Context, payload []byte) (*identity.PrivateKey, error) {
+	return sealPrivateKey(t.PassPhrase, payload)
+}
+
+// sealPrivateKey encrypts the identity private key JWK using the given
+// passphrase as a compact JWE.
+func sealPrivateKey(passPhrase *memguard.LockedBuffer, payload []byte) (*identity.PrivateKey, error) {
 	// Encrypt JWK using PBES2
 	recipient := jose.Recipient{
 		Algorithm:  jose.PBES2_HS512_A256KW,
-		Key:        t.PassPhrase.Bytes(),
+		Key:        passPhrase.Bytes(),
 		PBES2Count: PBKDF2Iterations,
 		PBES2Salt:  []byte(uniuri.NewLen(PBKDF2SaltSize)),
 	}
@@ -0,0 +1,79 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package container
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/elastic/harp/pkg/container/identity"
+	"github.com/elastic/harp/pkg/sdk/types"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// IdentityListTask implements keychain stored identity listing task.
+type IdentityListTask struct {
+	Store        *identity.Store
+	OutputWriter tasks.WriterProvider
+}
+
+// Run the task.
+func (t *IdentityListTask) Run(ctx context.Context) error {
+	// Check arguments
+	if t.Store == nil {
+		return errors.New("unable to run task with a nil store")
+	}
+	if types.IsNil(t.OutputWriter) {
+		return errors.New("unable to run task with a nil outputWriter provider")
+	}
+
+	names, err := t.Store.List()
+	if err != nil {
+		return fmt.Errorf("unable to list identities: %w", err)
+	}
+
+	// Create output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output writer: %w", err)
+	}
+
+	for _, name := range names {
+		fmt.Fprintln(writer, name)
+	}
+
+	// No error
+	return nil
+}
+
+// IdentityDeleteTask implements keychain stored identity deletion task.
+type IdentityDeleteTask struct {
+	Store *identity.Store
+	Name  string
+}
+
+// Run the task.
+func (t *IdentityDeleteTask) Run(_ context.Context) error {
+	// Check arguments
+	if t.Store == nil {
+		return errors.New("unable to run task with a nil store")
+	}
+
+	return t.Store.Delete(t.Name)
+}
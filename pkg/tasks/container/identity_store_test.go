@@ -0,0 +1,162 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package container
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/awnumar/memguard"
+
+	containerv1 "github.com/elastic/harp/api/gen/go/harp/container/v1"
+	"github.com/elastic/harp/pkg/container"
+	"github.com/elastic/harp/pkg/container/identity"
+	"github.com/elastic/harp/pkg/sdk/keyring"
+)
+
+func TestIdentityStore_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	store := identity.NewStore(keyring.File(filepath.Join(t.TempDir(), "keyring")))
+	passphrase := memguard.NewBufferFromBytes([]byte("test"))
+
+	// Store a new identity
+	it := &IdentityTask{
+		Description: "prod unsealer",
+		PassPhrase:  passphrase,
+		Store:       store,
+		StoreName:   "prod-unsealer",
+	}
+	if err := it.Run(ctx); err != nil {
+		t.Fatalf("unable to store identity: %v", err)
+	}
+
+	// Name collision
+	if err := it.Run(ctx); !errors.Is(err, identity.ErrAlreadyExists) {
+		t.Fatalf("expected ErrAlreadyExists, got %v", err)
+	}
+
+	// Use stored identity
+	content, err := store.Get("prod-unsealer")
+	if err != nil {
+		t.Fatalf("unable to retrieve identity: %v", err)
+	}
+	var out bytes.Buffer
+	rt := &RecoverTask{
+		JSONReader: func(context.Context) (io.Reader, error) {
+			return bytes.NewReader(content), nil
+		},
+		OutputWriter: func(context.Context) (io.Writer, error) {
+			return &out, nil
+		},
+		PassPhrase: passphrase,
+	}
+	if err := rt.Run(ctx); err != nil {
+		t.Fatalf("unable to recover container key: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "Container key : ") {
+		t.Errorf("unexpected output %q", out.String())
+	}
+
+	// Unseal a container sealed for the stored identity
+	id, err := identity.FromReader(bytes.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := base64.RawURLEncoding.DecodeString(id.Public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var publicKey [32]byte
+	copy(publicKey[:], pub)
+	sealed, err := container.Seal(&containerv1.Container{
+		Headers: &containerv1.Header{ContentType: "application/vnd.harp.v1.Bundle"},
+		Raw:     []byte("payload"),
+	}, &publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sealedBuf bytes.Buffer
+	if err := container.Dump(&sealedBuf, sealed); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	ut := &UnsealTask{
+		ContainerReader: func(context.Context) (io.Reader, error) {
+			return bytes.NewReader(sealedBuf.Bytes()), nil
+		},
+		OutputWriter: func(context.Context) (io.Writer, error) {
+			return &out, nil
+		},
+		IdentityReader: func(context.Context) (io.Reader, error) {
+			return bytes.NewReader(content), nil
+		},
+		IdentityPassPhrase: passphrase,
+	}
+	if err := ut.Run(ctx); err != nil {
+		t.Fatalf("unable to unseal container using stored identity: %v", err)
+	}
+	unsealed, err := container.Load(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(unsealed.Raw) != "payload" {
+		t.Errorf("unexpected unsealed content %q", unsealed.Raw)
+	}
+
+	// List
+	out.Reset()
+	lt := &IdentityListTask{
+		Store: store,
+		OutputWriter: func(context.Context) (io.Writer, error) {
+			return &out, nil
+		},
+	}
+	if err := lt.Run(ctx); err != nil {
+		t.Fatalf("unable to list identities: %v", err)
+	}
+	if out.String() != "prod-unsealer\n" {
+		t.Errorf("unexpected identity list %q", out.String())
+	}
+
+	// Delete
+	dt := &IdentityDeleteTask{Store: store, Name: "prod-unsealer"}
+	if err := dt.Run(ctx); err != nil {
+		t.Fatalf("unable to delete identity: %v", err)
+	}
+	if err := dt.Run(ctx); !errors.Is(err, identity.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := store.Get("prod-unsealer"); !errors.Is(err, identity.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	names, err := store.List()
+	if err != nil || len(names) != 0 {
+		t.Errorf("unexpected identities %v (%v)", names, err)
+	}
+
+	// Name can be reused once deleted
+	if err := it.Run(ctx); err != nil {
+		t.Fatalf("unable to store identity: %v", err)
+	}
+}
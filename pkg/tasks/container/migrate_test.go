@@ -0,0 +1,84 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package container
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/container"
+)
+
+func TestMigrateTask(t *testing.T) {
+	legacy, err := ioutil.ReadFile("../../../test/fixtures/bundles/legacy.bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Migrate the legacy container
+	var out bytes.Buffer
+	err = (&MigrateTask{
+		ContainerReader: bytesReader(legacy),
+		OutputWriter:    bufferWriter(&out),
+	}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	migrated := out.Bytes()
+
+	// Output uses the current container format
+	if v := uint16(migrated[4])<<8 | uint16(migrated[5]); v != container.FormatVersion() {
+		t.Errorf("unexpected container version %d", v)
+	}
+	c, err := container.Load(bytes.NewReader(migrated))
+	if err != nil {
+		t.Fatalf("unable to load migrated container: %v", err)
+	}
+	if bundle.IsLegacy(c) {
+		t.Fatal("migrated container must not be legacy")
+	}
+	b, err := bundle.FromContainer(c)
+	if err != nil {
+		t.Fatalf("unable to load migrated bundle: %v", err)
+	}
+	if len(b.Packages) != 5 {
+		t.Errorf("expected 5 packages, got %d", len(b.Packages))
+	}
+
+	// Migration is idempotent
+	out.Reset()
+	err = (&MigrateTask{
+		ContainerReader: bytesReader(migrated),
+		OutputWriter:    bufferWriter(&out),
+	}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	again, err := bundle.FromContainerReader(&out)
+	if err != nil {
+		t.Fatalf("unable to load migrated bundle: %v", err)
+	}
+	if !proto.Equal(b, again) {
+		t.Error("migrating a current bundle must not change it")
+	}
+}
@@ -0,0 +1,115 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/awnumar/memguard"
+	"gopkg.in/square/go-jose.v2"
+
+	"github.com/elastic/harp/pkg/container/identity"
+	"github.com/elastic/harp/pkg/sdk/security"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// PassphraseTask implements container identity passphrase rotation task.
+type PassphraseTask struct {
+	IdentityReader tasks.ReaderProvider
+	OutputWriter   tasks.WriterProvider
+	OldPassPhrase  *memguard.LockedBuffer
+	NewPassPhrase  *memguard.LockedBuffer
+}
+
+// Capabilities returns the task required capabilities.
+func (t *PassphraseTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Run the task.
+func (t *PassphraseTask) Run(ctx context.Context) error {
+	// Check arguments
+	if t.OldPassPhrase == nil || t.OldPassPhrase.Size() == 0 {
+		return fmt.Errorf("old passphrase must be defined")
+	}
+	if t.NewPassPhrase == nil || t.NewPassPhrase.Size() == 0 {
+		return fmt.Errorf("new passphrase must be defined")
+	}
+
+	// Create input reader
+	reader, err := t.IdentityReader(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to read input reader: %w", err)
+	}
+
+	// Extract from reader
+	input, err := identity.FromReader(reader)
+	if err != nil {
+		return err
+	}
+	if input.Private.Encoding != "jwe" {
+		return fmt.Errorf("identity private key encoding '%s' is not protected by a passphrase", input.Private.Encoding)
+	}
+
+	// Parse JWE Token
+	jwe, err := jose.ParseEncrypted(input.Private.Content)
+	if err != nil {
+		return fmt.Errorf("unable to parse JWE token")
+	}
+
+	// Try to decrypt with the old passphrase
+	payload, err := jwe.Decrypt(t.OldPassPhrase.Bytes())
+	if err != nil {
+		return fmt.Errorf("unable to decrypt JWE token")
+	}
+	defer memguard.WipeBytes(payload)
+
+	// Decode key to ensure it matches the identity
+	var key jsonWebKey
+	if err = json.Unmarshal(payload, &key); err != nil {
+		return fmt.Errorf("unable to decode payload as JSON: %w", err)
+	}
+	if !security.SecureCompareString(input.Public, key.X) {
+		return fmt.Errorf("invalid identity, key mismatch detected")
+	}
+
+	// Seal the unchanged payload with the new passphrase
+	pk, err := sealPrivateKey(t.NewPassPhrase, payload)
+	if err != nil {
+		return fmt.Errorf("unable to seal identity using passphrase: %w", err)
+	}
+
+	// Replace only the private key envelope
+	input.Private = pk
+
+	// Retrieve output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve output writer handle: %w", err)
+	}
+
+	// Create identity output
+	if err := json.NewEncoder(writer).Encode(input); err != nil {
+		return fmt.Errorf("unable to serialize final identity: %w", err)
+	}
+
+	// No error
+	return nil
+}
@@ -0,0 +1,134 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package container
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/awnumar/memguard"
+
+	"github.com/elastic/harp/pkg/container/identity"
+)
+
+func bufferReader(buf *bytes.Buffer) func(context.Context) (io.Reader, error) {
+	raw := append([]byte(nil), buf.Bytes()...)
+	return func(_ context.Context) (io.Reader, error) {
+		return bytes.NewReader(raw), nil
+	}
+}
+
+func bufferWriter(buf *bytes.Buffer) func(context.Context) (io.Writer, error) {
+	return func(_ context.Context) (io.Writer, error) {
+		return buf, nil
+	}
+}
+
+func TestPassphraseTask(t *testing.T) {
+	ctx := context.Background()
+
+	// Generate an identity protected by the old passphrase
+	var original bytes.Buffer
+	it := &IdentityTask{
+		OutputWriter: bufferWriter(&original),
+		Description:  "test",
+		PassPhrase:   memguard.NewBufferFromBytes([]byte("old-passphrase")),
+	}
+	if err := it.Run(ctx); err != nil {
+		t.Fatalf("unable to create identity: %v", err)
+	}
+
+	recoverKey := func(in *bytes.Buffer, passphrase string) (string, error) {
+		var out bytes.Buffer
+		rt := &RecoverTask{
+			JSONReader:   bufferReader(in),
+			OutputWriter: bufferWriter(&out),
+			PassPhrase:   memguard.NewBufferFromBytes([]byte(passphrase)),
+		}
+		err := rt.Run(ctx)
+		return out.String(), err
+	}
+
+	want, err := recoverKey(&original, "old-passphrase")
+	if err != nil {
+		t.Fatalf("unable to recover original identity: %v", err)
+	}
+
+	t.Run("wrong old passphrase", func(t *testing.T) {
+		var out bytes.Buffer
+		pt := &PassphraseTask{
+			IdentityReader: bufferReader(&original),
+			OutputWriter:   bufferWriter(&out),
+			OldPassPhrase:  memguard.NewBufferFromBytes([]byte("wrong")),
+			NewPassPhrase:  memguard.NewBufferFromBytes([]byte("new-passphrase")),
+		}
+		if err := pt.Run(ctx); err == nil {
+			t.Fatal("error should be raised")
+		}
+		if out.Len() != 0 {
+			t.Error("output should be empty on error")
+		}
+	})
+
+	// Rotate the passphrase
+	var rotated bytes.Buffer
+	pt := &PassphraseTask{
+		IdentityReader: bufferReader(&original),
+		OutputWriter:   bufferWriter(&rotated),
+		OldPassPhrase:  memguard.NewBufferFromBytes([]byte("old-passphrase")),
+		NewPassPhrase:  memguard.NewBufferFromBytes([]byte("new-passphrase")),
+	}
+	if err := pt.Run(ctx); err != nil {
+		t.Fatalf("unable to change passphrase: %v", err)
+	}
+
+	// Old passphrase must not work anymore
+	if _, err := recoverKey(&rotated, "old-passphrase"); err == nil {
+		t.Error("old passphrase should not decrypt the identity")
+	}
+
+	// New passphrase must recover the same container key
+	got, err := recoverKey(&rotated, "new-passphrase")
+	if err != nil {
+		t.Fatalf("unable to recover identity with new passphrase: %v", err)
+	}
+	if got != want {
+		t.Errorf("recovered container key mismatch, got %q, want %q", got, want)
+	}
+
+	// Public key and metadata must be preserved
+	before, err := identity.FromReader(bytes.NewReader(original.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := identity.FromReader(bytes.NewReader(rotated.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before.Public != after.Public {
+		t.Errorf("public key changed, got %q, want %q", after.Public, before.Public)
+	}
+	if before.Description != after.Description || !before.Timestamp.Equal(after.Timestamp) || before.APIVersion != after.APIVersion || before.Kind != after.Kind {
+		t.Error("identity metadata should be preserved")
+	}
+	if before.Private.Content == after.Private.Content {
+		t.Error("private key envelope should be re-encrypted")
+	}
+}
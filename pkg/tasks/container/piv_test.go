@@ -0,0 +1,141 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package container
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/awnumar/memguard"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/container/identity"
+	"github.com/elastic/harp/pkg/sdk/security/piv"
+)
+
+func simulatedToken(t *testing.T) *piv.Simulator {
+	t.Helper()
+
+	sim := piv.NewSimulator(12345678)
+	if _, err := sim.GenerateKey(bytes.NewReader(bytes.Repeat([]byte{0x42}, 32)), piv.SlotAuthentication); err != nil {
+		t.Fatal(err)
+	}
+
+	return sim
+}
+
+func Test_PIV_Seal_Unseal(t *testing.T) {
+	sim := simulatedToken(t)
+
+	// Create token backed identity
+	var idOut bytes.Buffer
+	if err := (&IdentityTask{
+		OutputWriter: bufferWriter(&idOut),
+		Description:  "security officer",
+		PIVProvider:  sim,
+		PIVSlot:      piv.SlotAuthentication,
+	}).Run(context.Background()); err != nil {
+		t.Fatalf("unable to create PIV identity: %v", err)
+	}
+
+	id, err := identity.FromReader(bytes.NewReader(idOut.Bytes()))
+	if err != nil {
+		t.Fatalf("unable to read identity: %v", err)
+	}
+	if id.Private.Encoding != "piv" || id.Private.Content != "12345678/9a" {
+		t.Fatalf("unexpected private key reference %+v", id.Private)
+	}
+
+	// Identity can't be recovered
+	if err := (&RecoverTask{
+		JSONReader:   bytesReader(idOut.Bytes()),
+		OutputWriter: bufferWriter(&bytes.Buffer{}),
+		PassPhrase:   memguard.NewBufferFromBytes([]byte("test")),
+	}).Run(context.Background()); err == nil {
+		t.Fatal("error should be raised when recovering a PIV identity")
+	}
+
+	// Prepare sealed container
+	b, err := bundle.FromMap(map[string]bundle.KV{
+		"app/production/billing/payments/1.0.0/api/database": {"password": "foo"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var container bytes.Buffer
+	if err := bundle.ToContainerWriter(&container, b); err != nil {
+		t.Fatal(err)
+	}
+	var sealed bytes.Buffer
+	if err := (&SealTask{
+		ContainerReader:          bytesReader(container.Bytes()),
+		SealedContainerWriter:    bufferWriter(&sealed),
+		OutputWriter:             bufferWriter(&bytes.Buffer{}),
+		Identities:               []string{id.Public},
+		DisableContainerIdentity: true,
+	}).Run(context.Background()); err != nil {
+		t.Fatalf("unable to seal container: %v", err)
+	}
+
+	testCases := []struct {
+		desc     string
+		pins     []string
+		attempts int
+		detach   bool
+		wantErr  error
+	}{
+		{desc: "valid pin", pins: []string{piv.DefaultPIN}},
+		{desc: "wrong static pin", pins: []string{"000000"}, wantErr: piv.ErrWrongPIN},
+		{desc: "prompt retry", pins: []string{"000000", piv.DefaultPIN}, attempts: 3},
+		{desc: "blocked pin", pins: []string{"000000"}, attempts: 5, wantErr: piv.ErrPINBlocked},
+		{desc: "token removed", pins: []string{piv.DefaultPIN}, detach: true, wantErr: piv.ErrNoToken},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			token := simulatedToken(t)
+			if tC.detach {
+				token.Detach()
+			}
+
+			pins := tC.pins
+			var out bytes.Buffer
+			err := (&UnsealTask{
+				ContainerReader: bytesReader(sealed.Bytes()),
+				OutputWriter:    bufferWriter(&out),
+				PIVProvider:     token,
+				PIVSlot:         piv.SlotAuthentication,
+				PIVPIN: func(_ int) (*memguard.LockedBuffer, error) {
+					pin := pins[0]
+					if len(pins) > 1 {
+						pins = pins[1:]
+					}
+					return memguard.NewBufferFromBytes([]byte(pin)), nil
+				},
+				PIVPINAttempts: tC.attempts,
+			}).Run(context.Background())
+			if !errors.Is(err, tC.wantErr) {
+				t.Fatalf("UnsealTask.Run() error = %v, want %v", err, tC.wantErr)
+			}
+			if tC.wantErr == nil && out.Len() == 0 {
+				t.Error("unsealed container should be written")
+			}
+		})
+	}
+}
@@ -29,6 +29,7 @@ import (
 
 	"github.com/elastic/harp/pkg/container/identity"
 	"github.com/elastic/harp/pkg/sdk/security"
+	"github.com/elastic/harp/pkg/sdk/security/crypto"
 	"github.com/elastic/harp/pkg/tasks"
 	"github.com/elastic/harp/pkg/vault"
 )
@@ -41,29 +42,66 @@ type RecoverTask struct {
 	VaultTransitPath string
 	VaultTransitKey  string
 	JSONOutput       bool
+	IgnoreKeyUsage   bool
+}
+
+// Capabilities returns the task required capabilities.
+func (t *RecoverTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{Network: t.VaultTransitKey != ""}
 }
 
 // Run the task.
-//nolint:gocyclo // To refactor
 func (t *RecoverTask) Run(ctx context.Context) error {
+	// Recover identity private key
+	key, err := t.recoverKey(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Get output writer
+	outputWriter, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve output writer: %w", err)
+	}
+
+	// Display as json
+	if t.JSONOutput {
+		if err := json.NewEncoder(outputWriter).Encode(map[string]interface{}{
+			"container_key": key.D,
+		}); err != nil {
+			return fmt.Errorf("unable to display as json: %w", err)
+		}
+	} else {
+		// Display container key
+		fmt.Fprintf(outputWriter, "Container key : %s\n", key.D)
+	}
+
+	// No error
+	return nil
+}
+
+// recoverKey decrypts the identity private key.
+//
+//nolint:gocyclo // To refactor
+func (t *RecoverTask) recoverKey(ctx context.Context) (*jsonWebKey, error) {
 	// Check exclusive parameters
 	if t.PassPhrase == nil && t.VaultTransitKey == "" {
-		return fmt.Errorf("passphrase or vaultTransitKey must be defined")
+		return nil, fmt.Errorf("passphrase or vaultTransitKey must be defined")
 	}
 	if t.PassPhrase != nil && t.PassPhrase.Size() > 0 && t.VaultTransitKey != "" {
-		return fmt.Errorf("passphrase and vaultTransitKey are mutually exclusive")
+		return nil, fmt.Errorf("passphrase and vaultTransitKey are mutually exclusive")
 	}
 
 	// Create input reader
 	reader, err := t.JSONReader(ctx)
 	if err != nil {
-		return fmt.Errorf("unable to read input reader: %v", err)
+		return nil, fmt.Errorf("unable to read input reader: %v", err)
 	}
 
 	// Extract from reader
 	input, err := identity.FromReader(reader)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	var (
@@ -75,54 +113,45 @@ func (t *RecoverTask) Run(ctx context.Context) error {
 		// Parse JWE Token
 		jwe, errParse := jose.ParseEncrypted(input.Private.Content)
 		if errParse != nil {
-			return fmt.Errorf("unable to parse JWE token")
+			return nil, fmt.Errorf("unable to parse JWE token")
 		}
 
 		// Try to decrypt with given passphrase
 		payload, errDecrypt = jwe.Decrypt(t.PassPhrase.Bytes())
 		if errDecrypt != nil {
-			return fmt.Errorf("unable to decrypt JWE token")
+			return nil, fmt.Errorf("unable to decrypt JWE token")
 		}
 	} else if strings.HasPrefix(input.Private.Encoding, "kms:vault:") {
 		payload, errDecrypt = t.unsealWithVaultTransitKey(ctx, input.Private.Content)
 		if errDecrypt != nil {
-			return fmt.Errorf("unable to decrypt using Vault")
+			return nil, fmt.Errorf("unable to decrypt using Vault")
 		}
+	} else if input.Private.Encoding == "piv" {
+		return nil, fmt.Errorf("identity private key is stored on PIV token '%s' and can't be recovered, use 'harp container unseal --piv' instead", input.Private.Content)
 	} else {
-		return fmt.Errorf("unknown private key encoding '%s'", input.Private.Encoding)
+		return nil, fmt.Errorf("unknown private key encoding '%s'", input.Private.Encoding)
+	}
+
+	// Enforce key usage policy
+	if !t.IgnoreKeyUsage {
+		if err = crypto.ValidateKeyUsage(payload, crypto.KeyUsageSeal); err != nil {
+			return nil, fmt.Errorf("unable to use identity private key: %w", err)
+		}
 	}
 
 	// Decode key
 	var key jsonWebKey
 	if err = json.NewDecoder(bytes.NewReader(payload)).Decode(&key); err != nil {
-		return fmt.Errorf("unable to decode payload as JSON: %v", err)
+		return nil, fmt.Errorf("unable to decode payload as JSON: %v", err)
 	}
 
 	// Check validity
 	if !security.SecureCompareString(input.Public, key.X) {
-		return fmt.Errorf("invalid identity, key mismatch detected")
-	}
-
-	// Get output writer
-	outputWriter, err := t.OutputWriter(ctx)
-	if err != nil {
-		return fmt.Errorf("unable to retrieve output writer: %w", err)
-	}
-
-	// Display as json
-	if t.JSONOutput {
-		if err := json.NewEncoder(outputWriter).Encode(map[string]interface{}{
-			"container_key": key.D,
-		}); err != nil {
-			return fmt.Errorf("unable to display as json: %w", err)
-		}
-	} else {
-		// Display container key
-		fmt.Fprintf(outputWriter, "Container key : %s\n", key.D)
+		return nil, fmt.Errorf("invalid identity, key mismatch detected")
 	}
 
 	// No error
-	return nil
+	return &key, nil
 }
 
 func (t *RecoverTask) unsealWithVaultTransitKey(ctx context.Context, cipherText string) ([]byte, error) {
@@ -0,0 +1,94 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package container
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/awnumar/memguard"
+
+	"github.com/elastic/harp/pkg/container/identity"
+	"github.com/elastic/harp/pkg/sdk/security/crypto"
+)
+
+func sealedIdentityReader(t *testing.T, passphrase *memguard.LockedBuffer, public, payload string) func(context.Context) (io.Reader, error) {
+	t.Helper()
+
+	// Seal the given key
+	it := &IdentityTask{PassPhrase: passphrase}
+	pk, err := it.sealWithPassPhrase(context.Background(), []byte(payload))
+	if err != nil {
+		t.Fatalf("unable to seal identity: %v", err)
+	}
+
+	// Serialize identity
+	raw, err := json.Marshal(&identity.Identity{
+		Public:  public,
+		Private: pk,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return func(_ context.Context) (io.Reader, error) {
+		return bytes.NewReader(raw), nil
+	}
+}
+
+func TestRecoverTask_KeyUsage(t *testing.T) {
+	passphrase := memguard.NewBufferFromBytes([]byte("test"))
+
+	testCases := []struct {
+		desc           string
+		payload        string
+		ignoreKeyUsage bool
+		wantErr        error
+	}{
+		{desc: "legacy identity without usage", payload: `{"kty":"OKP","crv":"X25519","x":"AAAA","d":"b"}`},
+		{desc: "sealing key", payload: `{"kty":"OKP","crv":"X25519","x":"AAAA","d":"b","key_ops":["deriveKey","deriveBits"],"harp.elastic.co/usage":{"use":"seal"}}`},
+		{desc: "signing key", payload: `{"kty":"OKP","crv":"X25519","x":"AAAA","d":"b","key_ops":["sign","verify"],"harp.elastic.co/usage":{"use":"sign"}}`, wantErr: crypto.ErrKeyUsageMismatch},
+		{desc: "signing key ignored", payload: `{"kty":"OKP","crv":"X25519","x":"AAAA","d":"b","key_ops":["sign","verify"],"harp.elastic.co/usage":{"use":"sign"}}`, ignoreKeyUsage: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var out bytes.Buffer
+			task := &RecoverTask{
+				JSONReader: sealedIdentityReader(t, passphrase, "AAAA", tC.payload),
+				OutputWriter: func(_ context.Context) (io.Writer, error) {
+					return &out, nil
+				},
+				PassPhrase:     passphrase,
+				IgnoreKeyUsage: tC.ignoreKeyUsage,
+			}
+
+			err := task.Run(context.Background())
+			if !errors.Is(err, tC.wantErr) {
+				t.Fatalf("Run() error = %v, want %v", err, tC.wantErr)
+			}
+			if err == nil && !strings.Contains(out.String(), "Container key : b") {
+				t.Errorf("unexpected output %q", out.String())
+			}
+		})
+	}
+}
@@ -23,37 +23,111 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"text/tabwriter"
 
 	"github.com/awnumar/memguard"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/blake2b"
 	"golang.org/x/crypto/nacl/box"
+	"google.golang.org/protobuf/proto"
 
+	containerv1 "github.com/elastic/harp/api/gen/go/harp/container/v1"
+	"github.com/elastic/harp/pkg/bundle"
 	"github.com/elastic/harp/pkg/container"
+	"github.com/elastic/harp/pkg/container/identity"
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
 	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/sdk/security/crypto"
 	"github.com/elastic/harp/pkg/sdk/security/crypto/x25519"
 	"github.com/elastic/harp/pkg/sdk/types"
 	"github.com/elastic/harp/pkg/tasks"
 )
 
+// Sealing recipient statuses.
+const (
+	RecipientValid       = "valid"
+	RecipientInvalid     = "invalid"
+	RecipientDuplicate   = "duplicate"
+	RecipientUnreachable = "unreachable"
+)
+
+// IdentityFile is an identity reference resolved when the task runs.
+type IdentityFile struct {
+	// Ref is the identity file path, URL or keychain reference.
+	Ref    string
+	Reader tasks.ReaderProvider
+}
+
 // SealTask implements secret container sealing task.
 type SealTask struct {
 	ContainerReader          tasks.ReaderProvider
 	SealedContainerWriter    tasks.WriterProvider
 	OutputWriter             tasks.WriterProvider
 	Identities               []string
+	IdentityFiles            []IdentityFile
 	DCKDMasterKey            *memguard.LockedBuffer
 	DCKDTarget               string
 	JSONOutput               bool
 	DisableContainerIdentity bool
+	// DryRun checks recipients and estimates the sealed container size
+	// without sealing. The sealed container writer is not used.
+	DryRun bool
+	// IgnoreKeyUsage accepts identity files not declaring a sealing key
+	// usage.
+	IgnoreKeyUsage bool
+
+	result *SealResult
+}
+
+// SealResult describes a container sealing task execution.
+type SealResult struct {
+	tasks.Result
+	DryRun            bool              `json:"dryRun"`
+	CipherSuite       string            `json:"cipherSuite"`
+	ContentType       string            `json:"contentType,omitempty"`
+	ContentEncoding   string            `json:"contentEncoding,omitempty"`
+	PackageCount      int               `json:"packageCount"`
+	InputSize         int64             `json:"inputSize"`
+	EstimatedSize     int64             `json:"estimatedSize"`
+	ContainerIdentity bool              `json:"containerIdentity"`
+	Recipients        []RecipientStatus `json:"recipients"`
+}
+
+// RecipientStatus describes a sealing recipient check.
+type RecipientStatus struct {
+	Recipient string `json:"recipient"`
+	PublicKey string `json:"publicKey,omitempty"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Capabilities returns the task required capabilities.
+func (t *SealTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Result returns the last execution result.
+func (t *SealTask) Result() interface{} {
+	return t.result
 }
 
 // Run the task.
+//
 //nolint:funlen,gocyclo,gocognit // To refactor
 func (t *SealTask) Run(ctx context.Context) error {
+	t.result = &SealResult{
+		DryRun:            t.DryRun,
+		CipherSuite:       container.SealCipherSuite,
+		ContainerIdentity: !t.DisableContainerIdentity,
+		Recipients:        []RecipientStatus{},
+	}
+
 	// Create input reader
+	endLoad := cmdutil.TracePhase(ctx, "load")
 	reader, err := t.ContainerReader(ctx)
 	if err != nil {
 		return fmt.Errorf("unable to open input reader: %w", err)
@@ -64,50 +138,27 @@ func (t *SealTask) Run(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("unable to read input container: %v", err)
 	}
+	endLoad()
 
-	// Open output file
-	writer, err := t.SealedContainerWriter(ctx)
+	// Check recipients
+	peerPublicKeys, err := t.recipients(ctx)
 	if err != nil {
-		return fmt.Errorf("unable to create output bundle: %w", err)
+		return err
 	}
 
-	// If using sealing seed
-	peerPublicKeys := []*[32]byte{}
-
-	// Given identities
-	if len(t.Identities) == 0 {
-		return fmt.Errorf("at least one sealing identity must be provided for recovery")
+	// Stop before sealing
+	if t.DryRun {
+		return t.dryRun(ctx, in, len(peerPublicKeys))
 	}
 
-	// Filter identities
-	var filteredIdentities types.StringArray
-	// Process identities (nizk proof of private key knowledge for private key ownership proof ?)
-	for _, id := range t.Identities {
-		// Check if identity is already added
-		if !filteredIdentities.AddIfNotContains(id) {
-			continue
-		}
-
-		// Check encoding
-		publicKeyRaw, errDecode := base64.RawURLEncoding.DecodeString(id)
-		if errDecode != nil {
-			return fmt.Errorf("invalid '%s' as public identity: %v", id, errDecode)
-		}
-
-		// Validate public key
-		if !x25519.IsValidPublicKey(publicKeyRaw) {
-			log.For(ctx).Warn("Public key ignored, it looks invalid", zap.String("key", id))
-			continue
-		}
-
-		// Copy public key
-		var publicKey [32]byte
-		copy(publicKey[:], publicKeyRaw[:32])
-
-		// Append to identity
-		peerPublicKeys = append(peerPublicKeys, &publicKey)
+	// Open output file
+	writer, err := t.SealedContainerWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to create output bundle: %w", err)
 	}
 
+	// If using sealing seed
+	endSeal := cmdutil.TracePhase(ctx, "seal")
 	var containerKey string
 
 	if !t.DisableContainerIdentity {
@@ -127,8 +178,10 @@ func (t *SealTask) Run(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("unable to seal container: %w", err)
 	}
+	endSeal()
 
 	// Dump to writer
+	defer cmdutil.TracePhase(ctx, "write")()
 	if err = container.Dump(writer, sealedContainer); err != nil {
 		return fmt.Errorf("unable to write sealed container: %v", err)
 	}
@@ -157,6 +210,232 @@ func (t *SealTask) Run(ctx context.Context) error {
 	return nil
 }
 
+// -----------------------------------------------------------------------------
+
+// recipients resolves identity files and returns the deduplicated recipient
+// public keys. Invalid and unreachable recipients are reported and skipped
+// during a dry run.
+func (t *SealTask) recipients(ctx context.Context) ([]*[32]byte, error) {
+	// Given identities
+	if len(t.Identities) == 0 && len(t.IdentityFiles) == 0 {
+		return nil, fmt.Errorf("at least one sealing identity must be provided for recovery")
+	}
+
+	var (
+		peerPublicKeys     []*[32]byte
+		filteredIdentities types.StringArray
+	)
+
+	// Process identities (nizk proof of private key knowledge for private key ownership proof ?)
+	add := func(ref, id string) error {
+		status := RecipientStatus{Recipient: ref, PublicKey: id, Status: RecipientValid}
+		defer func() {
+			t.result.Recipients = append(t.result.Recipients, status)
+		}()
+
+		// Check if identity is already added
+		if !filteredIdentities.AddIfNotContains(id) {
+			status.Status = RecipientDuplicate
+			t.result.Warn("recipient '%s' is a duplicate, it is sealed once", ref)
+			return nil
+		}
+
+		// Check encoding
+		publicKeyRaw, errDecode := base64.RawURLEncoding.DecodeString(id)
+		if errDecode != nil {
+			errDecode = fmt.Errorf("invalid '%s' as public identity: %v", id, errDecode)
+			status.Status, status.Error = RecipientInvalid, errDecode.Error()
+			if !t.DryRun {
+				return errDecode
+			}
+			t.result.Fail(ref, errDecode)
+			return nil
+		}
+
+		// Validate public key
+		if !x25519.IsValidPublicKey(publicKeyRaw) {
+			status.Status, status.Error = RecipientInvalid, "public key is not a valid X25519 key"
+			if t.DryRun {
+				t.result.Fail(ref, errors.New(status.Error))
+				return nil
+			}
+			t.result.Warn("recipient '%s' ignored, %s", ref, status.Error)
+			log.For(ctx).Warn("Public key ignored, it looks invalid", zap.String("key", id))
+			return nil
+		}
+
+		// Copy public key
+		var publicKey [32]byte
+		copy(publicKey[:], publicKeyRaw[:32])
+
+		// Append to identity
+		peerPublicKeys = append(peerPublicKeys, &publicKey)
+		return nil
+	}
+
+	for _, id := range t.Identities {
+		if err := add(id, id); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range t.IdentityFiles {
+		id, err := resolveIdentityFile(ctx, f)
+		if err != nil {
+			if !t.DryRun {
+				return nil, err
+			}
+			t.result.Recipients = append(t.result.Recipients, RecipientStatus{Recipient: f.Ref, Status: RecipientUnreachable, Error: err.Error()})
+			t.result.Fail(f.Ref, err)
+			continue
+		}
+
+		// Enforce key usage policy
+		if !t.IgnoreKeyUsage {
+			if err := checkIdentityUsage(f.Ref, id); err != nil {
+				if !t.DryRun {
+					return nil, err
+				}
+				t.result.Recipients = append(t.result.Recipients, RecipientStatus{Recipient: f.Ref, PublicKey: id.Public, Status: RecipientInvalid, Error: err.Error()})
+				t.result.Fail(f.Ref, err)
+				continue
+			}
+		}
+
+		if err := add(f.Ref, id.Public); err != nil {
+			return nil, err
+		}
+	}
+
+	// No error
+	return peerPublicKeys, nil
+}
+
+// dryRun reports the sealing plan without sealing the container.
+func (t *SealTask) dryRun(ctx context.Context, in *containerv1.Container, recipientCount int) error {
+	res := t.result
+	res.ContentType = in.Headers.ContentType
+	res.ContentEncoding = in.Headers.ContentEncoding
+	res.InputSize = int64(proto.Size(in))
+
+	// Check input container
+	if container.IsSealed(in) {
+		res.Warn("input container is already sealed")
+	}
+
+	// Count packages from the package index, packages are not decoded
+	if m, err := bundle.OpenMapped(in); err != nil {
+		res.Warn("unable to count packages: %v", err)
+	} else {
+		res.PackageCount = len(m.Names())
+		if err := m.Close(); err != nil {
+			return fmt.Errorf("unable to release bundle index: %w", err)
+		}
+	}
+
+	// Estimate sealed size
+	if !t.DisableContainerIdentity {
+		recipientCount++
+	}
+	if recipientCount == 0 {
+		res.Fail("recipients", errors.New("no valid recipient"))
+	} else {
+		size, err := container.SealedSize(in, recipientCount)
+		if err != nil {
+			return fmt.Errorf("unable to estimate sealed size: %w", err)
+		}
+		res.EstimatedSize = size
+	}
+
+	// Display the plan, the JSON report is written by the caller
+	if !t.JSONOutput && !types.IsNil(t.OutputWriter) {
+		outputWriter, err := t.OutputWriter(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to retrieve output writer: %w", err)
+		}
+		if err := writeSealPlan(outputWriter, res); err != nil {
+			return fmt.Errorf("unable to display sealing plan: %w", err)
+		}
+	}
+
+	if len(res.Failures) > 0 {
+		return fmt.Errorf("container can't be sealed, %d recipient check(s) failed", len(res.Failures))
+	}
+
+	// No error
+	return nil
+}
+
+// resolveIdentityFile reads the public identity of an identity file.
+func resolveIdentityFile(ctx context.Context, f IdentityFile) (*identity.Identity, error) {
+	// Check arguments
+	if f.Reader == nil {
+		return nil, fmt.Errorf("unable to resolve identity '%s' with a nil reader", f.Ref)
+	}
+
+	// Open for reading
+	r, err := f.Reader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read identity file '%s': %w", f.Ref, err)
+	}
+
+	// Decode identity
+	id, err := identity.FromReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode identity from file '%s': %w", f.Ref, err)
+	}
+
+	// No error
+	return id, nil
+}
+
+// checkIdentityUsage ensures the identity public key may be used as a sealing
+// recipient.
+func checkIdentityUsage(ref string, id *identity.Identity) error {
+	jwk, err := id.PublicJWK()
+	if err != nil {
+		return fmt.Errorf("unable to encode identity '%s' public key: %w", ref, err)
+	}
+	if err := crypto.ValidateKeyUsage(jwk, crypto.KeyUsageSeal); err != nil {
+		return fmt.Errorf("unable to seal for identity '%s': %w", ref, err)
+	}
+
+	// No error
+	return nil
+}
+
+func writeSealPlan(w io.Writer, res *SealResult) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Cipher suite\t%s\n", res.CipherSuite)
+	fmt.Fprintf(tw, "Content type\t%s\n", res.ContentType)
+	fmt.Fprintf(tw, "Content encoding\t%s\n", res.ContentEncoding)
+	fmt.Fprintf(tw, "Packages\t%d\n", res.PackageCount)
+	fmt.Fprintf(tw, "Input size\t%d\n", res.InputSize)
+	fmt.Fprintf(tw, "Estimated sealed size\t%d\n", res.EstimatedSize)
+	fmt.Fprintf(tw, "Container identity\t%v\n", res.ContainerIdentity)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RECIPIENT\tSTATUS\tERROR")
+	for _, r := range res.Recipients {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", r.Recipient, r.Status, r.Error)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, warning := range res.Warnings {
+		fmt.Fprintf(w, "\nWarning: %s", warning)
+	}
+	if len(res.Warnings) > 0 {
+		fmt.Fprintln(w)
+	}
+
+	return nil
+}
+
 func (t *SealTask) generateContainerKey() (*[32]byte, *[32]byte, error) {
 	// Generate random container key
 	seed := rand.Reader
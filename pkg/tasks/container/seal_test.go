@@ -0,0 +1,262 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package container
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/nacl/box"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/container/identity"
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/httpclient"
+	"github.com/elastic/harp/pkg/sdk/security/crypto"
+)
+
+func recipientKey(t *testing.T) string {
+	t.Helper()
+
+	pub, _, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(pub[:])
+}
+
+func identityFile(t *testing.T, ref, public string) IdentityFile {
+	t.Helper()
+	return usageIdentityFile(t, ref, public, nil)
+}
+
+func usageIdentityFile(t *testing.T, ref, public string, usage *crypto.KeyUsage) IdentityFile {
+	t.Helper()
+
+	raw, err := json.Marshal(&identity.Identity{
+		Public:  public,
+		Private: &identity.PrivateKey{Encoding: "jwe", Content: "sealed"},
+		Usage:   usage,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return IdentityFile{Ref: ref, Reader: bytesReader(raw)}
+}
+
+func sealFixture(t *testing.T) []byte {
+	t.Helper()
+
+	packages := map[string]bundle.KV{}
+	for i := 0; i < 25; i++ {
+		packages[fmt.Sprintf("app/production/customer%d/billing/database", i)] = bundle.KV{
+			"user":     fmt.Sprintf("user-%d", i),
+			"password": strings.Repeat("x", 64),
+		}
+	}
+	return containerBytes(t, packages)
+}
+
+func TestSealTask_DryRun(t *testing.T) {
+	fixture := sealFixture(t)
+
+	// Identity URL not found
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	cmdutil.SetDownloadOptions(httpclient.WithMaxRetries(0))
+	defer cmdutil.SetDownloadOptions()
+	missingURL := srv.URL + "/identity.json"
+	valid1, valid2 := recipientKey(t), recipientKey(t)
+
+	testCases := []struct {
+		desc          string
+		identities    []string
+		identityFiles []IdentityFile
+		wantErr       bool
+		wantStatuses  []string
+	}{
+		{
+			desc:         "valid",
+			identities:   []string{valid1, valid2},
+			wantStatuses: []string{RecipientValid, RecipientValid},
+		},
+		{
+			desc:         "invalid encoding",
+			identities:   []string{valid1, "not/base64!"},
+			wantErr:      true,
+			wantStatuses: []string{RecipientValid, RecipientInvalid},
+		},
+		{
+			desc:         "invalid key",
+			identities:   []string{base64.RawURLEncoding.EncodeToString(make([]byte, 16))},
+			wantErr:      true,
+			wantStatuses: []string{RecipientInvalid},
+		},
+		{
+			desc:          "duplicate",
+			identities:    []string{valid1},
+			identityFiles: []IdentityFile{identityFile(t, "security.json", valid1)},
+			wantStatuses:  []string{RecipientValid, RecipientDuplicate},
+		},
+		{
+			desc:       "unreachable",
+			identities: []string{valid1},
+			identityFiles: []IdentityFile{
+				{Ref: missingURL, Reader: cmdutil.FileReader(missingURL)},
+				{Ref: "keychain:missing", Reader: func(context.Context) (io.Reader, error) {
+					return nil, fmt.Errorf("identity not found")
+				}},
+			},
+			wantErr:      true,
+			wantStatuses: []string{RecipientValid, RecipientUnreachable, RecipientUnreachable},
+		},
+		{
+			desc:          "signing identity",
+			identityFiles: []IdentityFile{usageIdentityFile(t, "signer.json", valid1, &crypto.KeyUsage{Use: crypto.KeyUsageSign})},
+			wantErr:       true,
+			wantStatuses:  []string{RecipientInvalid},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var out bytes.Buffer
+			task := &SealTask{
+				ContainerReader: bytesReader(fixture),
+				SealedContainerWriter: func(context.Context) (io.Writer, error) {
+					t.Fatal("sealed container must not be written")
+					return nil, nil
+				},
+				OutputWriter:  bufferWriter(&out),
+				Identities:    tC.identities,
+				IdentityFiles: tC.identityFiles,
+				DryRun:        true,
+			}
+
+			err := task.Run(context.Background())
+			if (err != nil) != tC.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tC.wantErr)
+			}
+
+			res, ok := task.Result().(*SealResult)
+			if !ok {
+				t.Fatalf("unexpected result type %T", task.Result())
+			}
+			if len(res.Recipients) != len(tC.wantStatuses) {
+				t.Fatalf("expected %d recipients, got %+v", len(tC.wantStatuses), res.Recipients)
+			}
+			for i, want := range tC.wantStatuses {
+				if got := res.Recipients[i].Status; got != want {
+					t.Errorf("recipient %d: expected status '%s', got '%s' (%s)", i, want, got, res.Recipients[i].Error)
+				}
+			}
+			if res.PackageCount != 25 {
+				t.Errorf("expected 25 packages, got %d", res.PackageCount)
+			}
+			if !strings.Contains(out.String(), res.CipherSuite) {
+				t.Errorf("cipher suite not displayed:\n%s", out.String())
+			}
+		})
+	}
+}
+
+func TestSealTask_DryRunEstimation(t *testing.T) {
+	fixture := sealFixture(t)
+	identities := []string{recipientKey(t), recipientKey(t), recipientKey(t)}
+
+	// Dry run
+	dryRun := &SealTask{
+		ContainerReader: bytesReader(fixture),
+		OutputWriter:    bufferWriter(&bytes.Buffer{}),
+		Identities:      identities,
+		JSONOutput:      true,
+		DryRun:          true,
+	}
+	if err := dryRun.Run(context.Background()); err != nil {
+		t.Fatalf("unable to run dry run: %v", err)
+	}
+	estimated := dryRun.Result().(*SealResult).EstimatedSize
+
+	// Actual sealing
+	var sealed bytes.Buffer
+	task := &SealTask{
+		ContainerReader:       bytesReader(fixture),
+		SealedContainerWriter: bufferWriter(&sealed),
+		OutputWriter:          bufferWriter(&bytes.Buffer{}),
+		Identities:            identities,
+	}
+	if err := task.Run(context.Background()); err != nil {
+		t.Fatalf("unable to seal container: %v", err)
+	}
+
+	// Estimation must be within 1% of the actual size
+	diff := estimated - int64(sealed.Len())
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff*100 > int64(sealed.Len()) {
+		t.Errorf("estimated %d bytes, sealed container is %d bytes", estimated, sealed.Len())
+	}
+}
+
+func TestSealTask_KeyUsage(t *testing.T) {
+	fixture := sealFixture(t)
+	public := recipientKey(t)
+
+	testCases := []struct {
+		desc           string
+		usage          *crypto.KeyUsage
+		ignoreKeyUsage bool
+		wantErr        error
+	}{
+		{desc: "legacy identity without usage"},
+		{desc: "sealing key", usage: &crypto.KeyUsage{Use: crypto.KeyUsageSeal}},
+		{desc: "signing key", usage: &crypto.KeyUsage{Use: crypto.KeyUsageSign}, wantErr: crypto.ErrKeyUsageMismatch},
+		{desc: "signing key ignored", usage: &crypto.KeyUsage{Use: crypto.KeyUsageSign}, ignoreKeyUsage: true},
+		{desc: "expired key", usage: &crypto.KeyUsage{Use: crypto.KeyUsageSeal, ExpiresAt: 1}, wantErr: crypto.ErrKeyExpired},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var sealed bytes.Buffer
+			task := &SealTask{
+				ContainerReader:       bytesReader(fixture),
+				SealedContainerWriter: bufferWriter(&sealed),
+				OutputWriter:          bufferWriter(&bytes.Buffer{}),
+				IdentityFiles:         []IdentityFile{usageIdentityFile(t, "identity.json", public, tC.usage)},
+				IgnoreKeyUsage:        tC.ignoreKeyUsage,
+			}
+
+			err := task.Run(context.Background())
+			if !errors.Is(err, tC.wantErr) {
+				t.Fatalf("Run() error = %v, want %v", err, tC.wantErr)
+			}
+			if err == nil && sealed.Len() == 0 {
+				t.Error("sealed container expected")
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package container
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/elastic/harp/pkg/container/attestation"
+	hcrypto "github.com/elastic/harp/pkg/sdk/security/crypto"
+	"github.com/elastic/harp/pkg/tasks"
+	"github.com/elastic/harp/pkg/vault/transit"
+)
+
+const (
+	// SignerLocal is the local private key signer type.
+	SignerLocal = "local"
+	// SignerVaultTransit is the Vault transit signer type.
+	SignerVaultTransit = "vault-transit"
+	// DefaultTransitMountPath is the default Vault transit mount path.
+	DefaultTransitMountPath = "transit"
+)
+
+// SignerProvider returns the signer used to produce attestations.
+type SignerProvider func(ctx context.Context) (crypto.Signer, error)
+
+// KeyResolverProvider returns the key resolver used to verify attestations.
+type KeyResolverProvider func(ctx context.Context) (attestation.KeyResolver, error)
+
+// SignerSpec describes a signer declared as '<type>:<reference>'.
+type SignerSpec struct {
+	// Type is the signer type (local or vault-transit).
+	Type string
+	// Path is the key file path of a local signer.
+	Path string
+	// MountPath is the transit mount path of a Vault transit signer.
+	MountPath string
+	// KeyName is the transit key name of a Vault transit signer.
+	KeyName string
+}
+
+// ParseSignerSpec decodes a signer specification:
+//
+//	local:<key path>
+//	vault-transit:[<mount path>/]<key name>
+//
+// The transit mount path defaults to 'transit'.
+func ParseSignerSpec(spec string) (*SignerSpec, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, fmt.Errorf("invalid signer '%s', expected '<type>:<reference>'", spec)
+	}
+
+	switch parts[0] {
+	case SignerLocal:
+		return &SignerSpec{Type: SignerLocal, Path: parts[1]}, nil
+	case SignerVaultTransit:
+		mount, key := DefaultTransitMountPath, parts[1]
+		if idx := strings.LastIndex(key, "/"); idx >= 0 {
+			mount, key = strings.Trim(key[:idx], "/"), key[idx+1:]
+		}
+		if mount == "" {
+			mount = DefaultTransitMountPath
+		}
+		if key == "" {
+			return nil, fmt.Errorf("invalid signer '%s', transit key name must not be blank", spec)
+		}
+		return &SignerSpec{Type: SignerVaultTransit, MountPath: mount, KeyName: key}, nil
+	default:
+	}
+
+	return nil, fmt.Errorf("unsupported signer type '%s'", parts[0])
+}
+
+// -----------------------------------------------------------------------------
+
+// LocalSigner returns a signer provider using the private key (PEM or JWK)
+// read from the given reader.
+func LocalSigner(keyReader tasks.ReaderProvider, opts ...hcrypto.ParseOption) SignerProvider {
+	return func(ctx context.Context) (crypto.Signer, error) {
+		raw, err := readAll(ctx, keyReader)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read signing key: %w", err)
+		}
+		signer, err := hcrypto.ParseSigningKey(raw, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode signing key: %w", err)
+		}
+		return signer, nil
+	}
+}
+
+// LocalKeyResolver returns a key resolver provider using the public key (PEM,
+// certificate or JWK) read from the given reader for all signatures.
+func LocalKeyResolver(keyReader tasks.ReaderProvider, opts ...hcrypto.ParseOption) KeyResolverProvider {
+	return func(ctx context.Context) (attestation.KeyResolver, error) {
+		raw, err := readAll(ctx, keyReader)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read verification key: %w", err)
+		}
+		pub, err := hcrypto.ParseVerificationKey(raw, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode verification key: %w", err)
+		}
+		return func(attestation.Signature) (crypto.PublicKey, error) {
+			return pub, nil
+		}, nil
+	}
+}
+
+// VaultTransitSigner returns a signer provider using the given transit key.
+// The latest key version is pinned when the signer is built, the key name
+// and version are recorded in the signature.
+func VaultTransitSigner(s transit.Signer, keyName string) SignerProvider {
+	return func(ctx context.Context) (crypto.Signer, error) {
+		pub, version, err := s.PublicKey(ctx, 0)
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve transit public key: %w", err)
+		}
+		switch pub.(type) {
+		case ed25519.PublicKey, *ecdsa.PublicKey:
+		default:
+			return nil, fmt.Errorf("transit key '%s' type %T can't be used to sign", keyName, pub)
+		}
+
+		return &transitSigner{
+			ctx:     ctx,
+			service: s,
+			keyName: keyName,
+			version: version,
+			pub:     pub,
+		}, nil
+	}
+}
+
+// VaultTransitKeyResolver returns a key resolver provider retrieving public
+// keys from the given transit key. Signatures must have been produced by the
+// same key, the public key of the recorded version is used.
+func VaultTransitKeyResolver(s transit.Signer, keyName string) KeyResolverProvider {
+	return func(ctx context.Context) (attestation.KeyResolver, error) {
+		return func(sig attestation.Signature) (crypto.PublicKey, error) {
+			// Check signer
+			if sig.Signer == nil || sig.Signer.Type != SignerVaultTransit {
+				return nil, fmt.Errorf("signature has not been produced by a vault transit key")
+			}
+			if sig.Signer.KeyName != keyName {
+				return nil, fmt.Errorf("signature has been produced by transit key '%s', expected '%s'", sig.Signer.KeyName, keyName)
+			}
+			if sig.Signer.KeyVersion <= 0 {
+				return nil, fmt.Errorf("signature doesn't record the transit key version")
+			}
+
+			// Retrieve recorded key version
+			pub, _, err := s.PublicKey(ctx, sig.Signer.KeyVersion)
+			if err != nil {
+				return nil, fmt.Errorf("unable to retrieve transit public key: %w", err)
+			}
+			return pub, nil
+		}, nil
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// transitSigner adapts a transit key to the crypto.Signer interface.
+type transitSigner struct {
+	ctx     context.Context
+	service transit.Signer
+	keyName string
+	version int
+	pub     crypto.PublicKey
+}
+
+var _ attestation.RemoteSigner = (*transitSigner)(nil)
+
+func (s *transitSigner) Public() crypto.PublicKey {
+	return s.pub
+}
+
+func (s *transitSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	// Map hash function
+	o := transit.SignOptions{KeyVersion: s.version}
+	switch opts.HashFunc() {
+	case crypto.Hash(0):
+	case crypto.SHA256:
+		o.HashAlgorithm = "sha2-256"
+	case crypto.SHA384:
+		o.HashAlgorithm = "sha2-384"
+	case crypto.SHA512:
+		o.HashAlgorithm = "sha2-512"
+	default:
+		return nil, fmt.Errorf("unsupported hash function %v", opts.HashFunc())
+	}
+
+	// Delegate to Vault
+	sig, err := s.service.Sign(s.ctx, digest, o)
+	if err != nil {
+		return nil, err
+	}
+	if sig.KeyVersion != s.version {
+		return nil, fmt.Errorf("transit signed with key version %d, expected %d", sig.KeyVersion, s.version)
+	}
+
+	return sig.Value, nil
+}
+
+func (s *transitSigner) SignerInfo() *attestation.SignerInfo {
+	return &attestation.SignerInfo{
+		Type:       SignerVaultTransit,
+		KeyName:    s.keyName,
+		KeyVersion: s.version,
+	}
+}
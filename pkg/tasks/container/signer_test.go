@@ -0,0 +1,320 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package container
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/container/attestation"
+	hcrypto "github.com/elastic/harp/pkg/sdk/security/crypto"
+	"github.com/elastic/harp/pkg/vault/transit"
+)
+
+// fakeTransit emulates the transit sign and key read endpoints for a single
+// key.
+type fakeTransit struct {
+	keyType string
+	keys    []crypto.Signer
+}
+
+func (f *fakeTransit) rotate(t *testing.T) {
+	t.Helper()
+
+	var (
+		key crypto.Signer
+		err error
+	)
+	switch f.keyType {
+	case "ed25519":
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	case "ecdsa-p256":
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		t.Fatalf("unsupported key type %s", f.keyType)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.keys = append(f.keys, key)
+}
+
+func (f *fakeTransit) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	reply := func(data map[string]interface{}) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}
+
+	switch r.URL.Path {
+	case "/v1/transit/keys/harp-signing":
+		keys := map[string]interface{}{}
+		for i, k := range f.keys {
+			var encoded string
+			switch pub := k.Public().(type) {
+			case ed25519.PublicKey:
+				encoded = base64.StdEncoding.EncodeToString(pub)
+			default:
+				der, _ := x509.MarshalPKIXPublicKey(pub)
+				encoded = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+			}
+			keys[strconv.Itoa(i+1)] = map[string]interface{}{"public_key": encoded}
+		}
+		reply(map[string]interface{}{"type": f.keyType, "latest_version": len(f.keys), "keys": keys})
+	case "/v1/transit/sign/harp-signing":
+		var req struct {
+			Input         string `json:"input"`
+			KeyVersion    int    `json:"key_version"`
+			Prehashed     bool   `json:"prehashed"`
+			HashAlgorithm string `json:"hash_algorithm"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		version := req.KeyVersion
+		if version == 0 {
+			version = len(f.keys)
+		}
+		if version < 1 || version > len(f.keys) {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"errors":["invalid key version"]}`)
+			return
+		}
+		input, _ := base64.StdEncoding.DecodeString(req.Input)
+
+		var opts crypto.SignerOpts = crypto.Hash(0)
+		if f.keyType == "ecdsa-p256" {
+			if !req.Prehashed || req.HashAlgorithm != "sha2-256" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			opts = crypto.SHA256
+		}
+		sig, err := f.keys[version-1].Sign(rand.Reader, input, opts)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		reply(map[string]interface{}{"signature": fmt.Sprintf("vault:v%d:%s", version, base64.StdEncoding.EncodeToString(sig))})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func transitService(t *testing.T, f *fakeTransit) transit.Service {
+	t.Helper()
+
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	client, err := api.NewClient(&api.Config{Address: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetToken("token")
+
+	s, err := transit.New(client, "transit", "harp-signing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestParseSignerSpec(t *testing.T) {
+	testCases := []struct {
+		spec    string
+		want    SignerSpec
+		wantErr bool
+	}{
+		{spec: "local:key.pem", want: SignerSpec{Type: SignerLocal, Path: "key.pem"}},
+		{spec: "local:/etc/harp/key:1.pem", want: SignerSpec{Type: SignerLocal, Path: "/etc/harp/key:1.pem"}},
+		{spec: "vault-transit:harp-signing", want: SignerSpec{Type: SignerVaultTransit, MountPath: "transit", KeyName: "harp-signing"}},
+		{spec: "vault-transit:security/transit/harp-signing", want: SignerSpec{Type: SignerVaultTransit, MountPath: "security/transit", KeyName: "harp-signing"}},
+		{spec: "key.pem", wantErr: true},
+		{spec: "local:", wantErr: true},
+		{spec: "vault-transit:transit/", wantErr: true},
+		{spec: "kms:key", wantErr: true},
+	}
+	for _, tC := range testCases {
+		got, err := ParseSignerSpec(tC.spec)
+		if (err != nil) != tC.wantErr {
+			t.Errorf("ParseSignerSpec(%q) error = %v, wantErr %v", tC.spec, err, tC.wantErr)
+			continue
+		}
+		if err == nil && *got != tC.want {
+			t.Errorf("ParseSignerSpec(%q) = %+v, want %+v", tC.spec, *got, tC.want)
+		}
+	}
+}
+
+func TestVaultTransitSigner(t *testing.T) {
+	ctx := context.Background()
+
+	// Prepare container
+	b, err := bundle.FromMap(map[string]bundle.KV{
+		"app/production/billing/payments/1.0.0/api/database": {"password": "foo"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var container bytes.Buffer
+	if err := bundle.ToContainerWriter(&container, b); err != nil {
+		t.Fatal(err)
+	}
+
+	attest := func(t *testing.T, s transit.Service) []byte {
+		t.Helper()
+
+		var att bytes.Buffer
+		if err := (&AttestTask{
+			ContainerReader: bytesReader(container.Bytes()),
+			Signer:          VaultTransitSigner(s, "harp-signing"),
+			OutputWriter:    bufferWriter(&att),
+			SubjectName:     "secrets.harp",
+			BuilderID:       "ci@runner",
+		}).Run(ctx); err != nil {
+			t.Fatalf("unable to attest container: %v", err)
+		}
+		return att.Bytes()
+	}
+	verify := func(att []byte, resolver KeyResolverProvider) error {
+		var out bytes.Buffer
+		return (&VerifyAttestationTask{
+			ContainerReader:   bytesReader(container.Bytes()),
+			AttestationReader: bytesReader(att),
+			KeyResolver:       resolver,
+			OutputWriter:      bufferWriter(&out),
+		}).Run(ctx)
+	}
+
+	for _, keyType := range []string{"ed25519", "ecdsa-p256"} {
+		t.Run(keyType, func(t *testing.T) {
+			f := &fakeTransit{keyType: keyType}
+			f.rotate(t)
+			s := transitService(t, f)
+
+			// Sign with version 1, then rotate and sign with version 2
+			v1 := attest(t, s)
+			exported, err := hcrypto.ToPEM(f.keys[0].Public())
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.rotate(t)
+			v2 := attest(t, s)
+
+			// Signature metadata records the key
+			var envelope attestation.Envelope
+			if err := json.Unmarshal(v2, &envelope); err != nil {
+				t.Fatal(err)
+			}
+			if info := envelope.Signatures[0].Signer; info == nil || info.Type != SignerVaultTransit || info.KeyName != "harp-signing" || info.KeyVersion != 2 {
+				t.Fatalf("unexpected signer metadata %+v", info)
+			}
+
+			// Both signatures verify with the recorded key version
+			for _, att := range [][]byte{v1, v2} {
+				if err := verify(att, VaultTransitKeyResolver(s, "harp-signing")); err != nil {
+					t.Errorf("unable to verify with transit: %v", err)
+				}
+			}
+
+			// Exported public key verifies the matching version only
+			if err := verify(v1, LocalKeyResolver(bytesReader([]byte(exported)))); err != nil {
+				t.Errorf("unable to verify with exported key: %v", err)
+			}
+			if err := verify(v2, LocalKeyResolver(bytesReader([]byte(exported)))); !errors.Is(err, attestation.ErrInvalidSignature) {
+				t.Errorf("expected ErrInvalidSignature, got %v", err)
+			}
+
+			// Signature from another transit key is rejected
+			if err := verify(v1, VaultTransitKeyResolver(s, "other")); !errors.Is(err, attestation.ErrInvalidSignature) {
+				t.Errorf("expected ErrInvalidSignature, got %v", err)
+			}
+		})
+	}
+}
+
+func TestVaultTransitKeyResolver_LocalSignature(t *testing.T) {
+	f := &fakeTransit{keyType: "ed25519"}
+	f.rotate(t)
+
+	resolve, err := VaultTransitKeyResolver(transitService(t, f), "harp-signing")(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resolve(attestation.Signature{KeyID: "local"}); err == nil {
+		t.Fatal("expected local signature to be rejected")
+	}
+}
+
+func TestLocalSigner_KeyUsage(t *testing.T) {
+	ctx := context.Background()
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwk, err := hcrypto.ToJWKWithUsage(priv, hcrypto.KeyUsageEncrypt, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Key usage policy is enforced by default
+	if _, err := LocalSigner(bytesReader([]byte(jwk)))(ctx); !errors.Is(err, hcrypto.ErrKeyUsageMismatch) {
+		t.Errorf("expected ErrKeyUsageMismatch, got %v", err)
+	}
+	if _, err := LocalKeyResolver(bytesReader([]byte(jwk)))(ctx); !errors.Is(err, hcrypto.ErrKeyUsageMismatch) {
+		t.Errorf("expected ErrKeyUsageMismatch, got %v", err)
+	}
+
+	// Opt-out
+	signer, err := LocalSigner(bytesReader([]byte(jwk)), hcrypto.IgnoreKeyUsage())(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resolve, err := LocalKeyResolver(bytesReader([]byte(jwk)), hcrypto.IgnoreKeyUsage())(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pub, err := resolve(attestation.Signature{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := priv.Public().(ed25519.PublicKey)
+	if !expected.Equal(pub) || !expected.Equal(signer.Public()) {
+		t.Error("unexpected key pair")
+	}
+}
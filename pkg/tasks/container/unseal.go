@@ -24,7 +24,11 @@ import (
 
 	"github.com/awnumar/memguard"
 
+	containerv1 "github.com/elastic/harp/api/gen/go/harp/container/v1"
 	"github.com/elastic/harp/pkg/container"
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/sdk/security/piv"
 	"github.com/elastic/harp/pkg/tasks"
 )
 
@@ -33,11 +37,27 @@ type UnsealTask struct {
 	ContainerReader tasks.ReaderProvider
 	OutputWriter    tasks.WriterProvider
 	ContainerKey    *memguard.LockedBuffer
+	PIVProvider     piv.Provider
+	PIVSlot         piv.Slot
+	PIVPIN          piv.PINPrompt
+	PIVPINAttempts  int
+	// IdentityReader provides the identity used to recover the container key
+	// when no container key is given, its private key is decrypted using
+	// IdentityPassPhrase.
+	IdentityReader     tasks.ReaderProvider
+	IdentityPassPhrase *memguard.LockedBuffer
+	IgnoreKeyUsage     bool
+}
+
+// Capabilities returns the task required capabilities.
+func (t *UnsealTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
 }
 
 // Run the task.
 func (t *UnsealTask) Run(ctx context.Context) error {
 	// Create input reader
+	endLoad := cmdutil.TracePhase(ctx, "load")
 	reader, err := t.ContainerReader(ctx)
 	if err != nil {
 		return fmt.Errorf("unable to open input bundle reader: %w", err)
@@ -48,21 +68,23 @@ func (t *UnsealTask) Run(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("unable to read input container: %v", err)
 	}
-
-	// Decode container key
-	privateKeyRaw, err := base64.RawURLEncoding.DecodeString(t.ContainerKey.String())
-	if err != nil {
-		return fmt.Errorf("unable to decode container key: %w", err)
-	}
-	defer memguard.WipeBytes(privateKeyRaw)
+	endLoad()
 
 	// Unseal the bundle
-	out, err := container.Unseal(in, memguard.NewBufferFromBytes(privateKeyRaw))
+	endUnseal := cmdutil.TracePhase(ctx, "unseal")
+	var out *containerv1.Container
+	if t.PIVProvider != nil {
+		out, err = t.unsealWithPIV(ctx, in)
+	} else {
+		out, err = t.unsealWithKey(ctx, in)
+	}
 	if err != nil {
 		return fmt.Errorf("unable to unseal bundle content: %w", err)
 	}
+	endUnseal()
 
 	// Create output writer
+	defer cmdutil.TracePhase(ctx, "write")()
 	writer, err := t.OutputWriter(ctx)
 	if err != nil {
 		return fmt.Errorf("unable to open output bundle: %w", err)
@@ -76,3 +98,54 @@ func (t *UnsealTask) Run(ctx context.Context) error {
 	// No error
 	return nil
 }
+
+func (t *UnsealTask) unsealWithKey(ctx context.Context, in *containerv1.Container) (*containerv1.Container, error) {
+	containerKey := t.ContainerKey
+
+	// Recover container key from identity
+	if containerKey == nil && t.IdentityReader != nil {
+		rt := &RecoverTask{
+			JSONReader:     t.IdentityReader,
+			PassPhrase:     t.IdentityPassPhrase,
+			IgnoreKeyUsage: t.IgnoreKeyUsage,
+		}
+		key, err := rt.recoverKey(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to recover container key from identity: %w", err)
+		}
+		containerKey = memguard.NewBufferFromBytes([]byte(key.D))
+		defer containerKey.Destroy()
+	}
+
+	// Check arguments
+	if containerKey == nil {
+		return nil, fmt.Errorf("container key must be defined")
+	}
+
+	// Decode container key
+	privateKeyRaw, err := base64.RawURLEncoding.DecodeString(containerKey.String())
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode container key: %w", err)
+	}
+	defer memguard.WipeBytes(privateKeyRaw)
+
+	// Unseal the bundle
+	return container.Unseal(in, memguard.NewBufferFromBytes(privateKeyRaw))
+}
+
+func (t *UnsealTask) unsealWithPIV(ctx context.Context, in *containerv1.Container) (*containerv1.Container, error) {
+	// Connect to the token
+	token, err := t.PIVProvider.Open()
+	if err != nil {
+		return nil, fmt.Errorf("unable to open PIV token: %w", err)
+	}
+	defer log.SafeCloseCtx(ctx, token, "unable to close PIV token")
+
+	// Delegate key agreement to the token
+	return container.UnsealWithKeyAgreement(in, &piv.Agreement{
+		Token:    token,
+		Slot:     t.PIVSlot,
+		PIN:      t.PIVPIN,
+		Attempts: t.PIVPINAttempts,
+	})
+}
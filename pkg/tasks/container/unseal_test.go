@@ -0,0 +1,92 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package container
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/awnumar/memguard"
+	"golang.org/x/crypto/nacl/box"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/sdk/security/crypto"
+)
+
+func TestUnsealTask_KeyUsage(t *testing.T) {
+	passphrase := memguard.NewBufferFromBytes([]byte("test"))
+
+	// Seal a container for a generated recipient
+	pub, priv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public := base64.RawURLEncoding.EncodeToString(pub[:])
+	private := base64.RawURLEncoding.EncodeToString(priv[:])
+
+	var sealed bytes.Buffer
+	st := &SealTask{
+		ContainerReader:          bytesReader(containerBytes(t, map[string]bundle.KV{"app/test": {"key": "value"}})),
+		SealedContainerWriter:    bufferWriter(&sealed),
+		OutputWriter:             bufferWriter(&bytes.Buffer{}),
+		Identities:               []string{public},
+		DisableContainerIdentity: true,
+	}
+	if err := st.Run(context.Background()); err != nil {
+		t.Fatalf("unable to seal container: %v", err)
+	}
+
+	testCases := []struct {
+		desc           string
+		usage          string
+		ignoreKeyUsage bool
+		wantErr        error
+	}{
+		{desc: "legacy identity without usage"},
+		{desc: "sealing key", usage: `,"key_ops":["deriveKey","deriveBits"],"harp.elastic.co/usage":{"use":"seal"}`},
+		{desc: "signing key", usage: `,"key_ops":["sign","verify"],"harp.elastic.co/usage":{"use":"sign"}`, wantErr: crypto.ErrKeyUsageMismatch},
+		{desc: "signing key ignored", usage: `,"key_ops":["sign","verify"],"harp.elastic.co/usage":{"use":"sign"}`, ignoreKeyUsage: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			payload := fmt.Sprintf(`{"kty":"OKP","crv":"X25519","x":%q,"d":%q%s}`, public, private, tC.usage)
+
+			var out bytes.Buffer
+			task := &UnsealTask{
+				ContainerReader:    bytesReader(sealed.Bytes()),
+				OutputWriter:       bufferWriter(&out),
+				IdentityReader:     sealedIdentityReader(t, passphrase, public, payload),
+				IdentityPassPhrase: passphrase,
+				IgnoreKeyUsage:     tC.ignoreKeyUsage,
+			}
+
+			err := task.Run(context.Background())
+			if !errors.Is(err, tC.wantErr) {
+				t.Fatalf("Run() error = %v, want %v", err, tC.wantErr)
+			}
+			if err == nil && out.Len() == 0 {
+				t.Error("unsealed container expected")
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/elastic/harp/pkg/container/attestation"
+	"github.com/elastic/harp/pkg/sdk/types"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// VerifyAttestationTask implements secret container attestation verification
+// task.
+type VerifyAttestationTask struct {
+	ContainerReader   tasks.ReaderProvider
+	AttestationReader tasks.ReaderProvider
+	// KeyReader provides a local verification key, ignored when KeyResolver
+	// is set.
+	KeyReader    tasks.ReaderProvider
+	KeyResolver  KeyResolverProvider
+	OutputWriter tasks.WriterProvider
+	JSONOutput   bool
+}
+
+// Capabilities returns the task required capabilities.
+func (t *VerifyAttestationTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Run the task.
+func (t *VerifyAttestationTask) Run(ctx context.Context) error {
+	// Check arguments
+	if types.IsNil(t.ContainerReader) {
+		return fmt.Errorf("unable to run task with a nil containerReader provider")
+	}
+	if types.IsNil(t.AttestationReader) {
+		return fmt.Errorf("unable to run task with a nil attestationReader provider")
+	}
+	if t.KeyResolver == nil {
+		if types.IsNil(t.KeyReader) {
+			return fmt.Errorf("unable to run task with a nil keyReader provider")
+		}
+		t.KeyResolver = LocalKeyResolver(t.KeyReader)
+	}
+	if types.IsNil(t.OutputWriter) {
+		return fmt.Errorf("unable to run task with a nil outputWriter provider")
+	}
+
+	// Load verification keys
+	resolver, err := t.KeyResolver(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Decode envelope
+	rawEnvelope, err := readAll(ctx, t.AttestationReader)
+	if err != nil {
+		return fmt.Errorf("unable to read attestation: %w", err)
+	}
+	var envelope attestation.Envelope
+	if err := json.Unmarshal(rawEnvelope, &envelope); err != nil {
+		return fmt.Errorf("unable to decode attestation envelope: %w", err)
+	}
+
+	// Verify signature
+	statement, err := attestation.VerifyWith(&envelope, resolver)
+	if err != nil {
+		return fmt.Errorf("unable to verify attestation: %w", err)
+	}
+
+	// Check container against statement
+	raw, err := readAll(ctx, t.ContainerReader)
+	if err != nil {
+		return fmt.Errorf("unable to read container: %w", err)
+	}
+	if err := statement.Check(raw, time.Now()); err != nil {
+		return fmt.Errorf("container doesn't match attestation: %w", err)
+	}
+
+	// Create output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output writer: %w", err)
+	}
+
+	// Export as JSON
+	if t.JSONOutput {
+		enc := json.NewEncoder(writer)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(statement); err != nil {
+			return fmt.Errorf("unable to encode statement: %w", err)
+		}
+		return nil
+	}
+
+	// Export as text
+	p := statement.Predicate
+	fmt.Fprintf(writer, "Verified attestation for %s (%s:%s)\n", statement.Subject[0].Name, attestation.DigestSHA256, statement.Subject[0].Digest[attestation.DigestSHA256])
+	fmt.Fprintf(writer, "Builder: %s\n", p.Builder.ID)
+	fmt.Fprintf(writer, "Issued on: %s\n", p.Metadata.IssuedOn.Format(time.RFC3339))
+	fmt.Fprintf(writer, "Packages: %d, secrets: %d\n", p.Bundle.PackageCount, p.Bundle.SecretCount)
+
+	// No error
+	return nil
+}
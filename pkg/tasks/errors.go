@@ -0,0 +1,105 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tasks
+
+import (
+	"fmt"
+)
+
+// FailureMode defines how multi-item tasks handle item failures.
+type FailureMode int
+
+const (
+	// KeepGoing processes all items and reports aggregated failures.
+	KeepGoing FailureMode = iota
+	// FailFast stops processing items at the first failure.
+	FailFast
+)
+
+// UsageError describes an invalid task usage or configuration.
+type UsageError struct {
+	Err error
+}
+
+// Usage wraps the given error as a usage or configuration error.
+func Usage(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &UsageError{Err: err}
+}
+
+// Error returns the error message.
+func (e *UsageError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+// PolicyError describes policy violations detected by a task.
+type PolicyError struct {
+	Err error
+}
+
+// PolicyViolation wraps the given error as a policy violation.
+func PolicyViolation(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PolicyError{Err: err}
+}
+
+// Error returns the error message.
+func (e *PolicyError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *PolicyError) Unwrap() error {
+	return e.Err
+}
+
+// ItemsError describes item failures of a multi-item task.
+type ItemsError struct {
+	Succeeded int
+	Failed    int
+	Skipped   int
+	Err       error
+}
+
+// Error returns the error message.
+func (e *ItemsError) Error() string {
+	msg := fmt.Sprintf("%d item(s) failed, %d succeeded, %d skipped", e.Failed, e.Succeeded, e.Skipped)
+	if e.Err != nil {
+		msg = fmt.Sprintf("%s: %v", msg, e.Err)
+	}
+	return msg
+}
+
+// Unwrap returns the wrapped error.
+func (e *ItemsError) Unwrap() error {
+	return e.Err
+}
+
+// Partial returns true when at least one item succeeded.
+func (e *ItemsError) Partial() bool {
+	return e.Succeeded > 0
+}
@@ -0,0 +1,181 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package from
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/awnumar/memguard"
+	"go.uber.org/zap"
+	xacme "golang.org/x/crypto/acme"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/acme"
+	"github.com/elastic/harp/pkg/container/identity"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// ACMETask implements secret-container building from an ACME certificate
+// order.
+type ACMETask struct {
+	OutputWriter tasks.WriterProvider
+	BudgetReader tasks.ReaderProvider
+	// AccountReader loads an existing account key identity, a new account key
+	// is created and written to AccountWriter when nil.
+	AccountReader    tasks.ReaderProvider
+	AccountWriter    tasks.WriterProvider
+	PassPhrase       *memguard.LockedBuffer
+	DirectoryURL     string
+	Email            string
+	Domains          []string
+	PackagePath      string
+	Solver           acme.Solver
+	KeyType          string
+	MaxRateLimitWait time.Duration
+}
+
+// Capabilities returns the task required capabilities.
+func (t *ACMETask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{Network: true}
+}
+
+// Run the task.
+func (t *ACMETask) Run(ctx context.Context) error {
+	// Check arguments
+	if t.DirectoryURL == "" {
+		return errors.New("directory URL must not be blank")
+	}
+	if t.Solver == nil {
+		return errors.New("unable to run task with a nil solver")
+	}
+	if t.OutputWriter == nil {
+		return errors.New("unable to run task with a nil outputWriter provider")
+	}
+	if t.PassPhrase == nil || t.PassPhrase.Size() == 0 {
+		return errors.New("account key passphrase must be defined")
+	}
+
+	// Load account key
+	key, err := t.accountKey(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Initialize client
+	client, err := acme.NewClient(&xacme.Client{
+		Key:          key,
+		DirectoryURL: t.DirectoryURL,
+		UserAgent:    "harp",
+	}, t.MaxRateLimitWait)
+	if err != nil {
+		return fmt.Errorf("unable to initialize ACME client: %w", err)
+	}
+	if err = client.Register(ctx, t.Email); err != nil {
+		return err
+	}
+
+	// Prepare options
+	opts := []acme.Option{}
+	if t.KeyType != "" {
+		opts = append(opts, acme.WithKeyType(t.KeyType))
+	}
+
+	// Order certificate
+	p, err := client.Issue(ctx, t.PackagePath, t.Domains, t.Solver, opts...)
+	if err != nil {
+		return fmt.Errorf("unable to issue certificate: %w", err)
+	}
+
+	// Display summary
+	log.For(ctx).Info("ACME certificate issued",
+		zap.String("path", p.Name),
+		zap.Strings("domains", t.Domains),
+		zap.String("order", p.Annotations[acme.OrderAnnotation]),
+		zap.String("expiry", p.Annotations[acme.ExpiryAnnotation]),
+	)
+
+	b := &bundlev1.Bundle{
+		Packages: []*bundlev1.Package{p},
+	}
+
+	// Check size budgets
+	if err = enforceBudget(ctx, t.BudgetReader, b); err != nil {
+		return err
+	}
+
+	// Create output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output bundle: %w", err)
+	}
+
+	// Dump bundle
+	if err = bundle.ToContainerWriter(writer, b); err != nil {
+		return fmt.Errorf("unable to produce exported bundle: %w", err)
+	}
+
+	// No error
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (t *ACMETask) accountKey(ctx context.Context) (*ecdsa.PrivateKey, error) {
+	// Load existing account
+	if t.AccountReader != nil {
+		reader, err := t.AccountReader(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open account key: %w", err)
+		}
+		id, err := identity.FromReader(reader)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load account key: %w", err)
+		}
+		return acme.AccountKey(id, t.PassPhrase.Bytes())
+	}
+
+	// Check arguments
+	if t.AccountWriter == nil {
+		return nil, errors.New("account key reader or writer must be defined")
+	}
+
+	// Create a new account key
+	id, key, err := acme.NewAccountKey(fmt.Sprintf("ACME account for %s", t.DirectoryURL), t.PassPhrase.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	// Retrieve output writer
+	writer, err := t.AccountWriter(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open account key writer: %w", err)
+	}
+	if err := json.NewEncoder(writer).Encode(id); err != nil {
+		return nil, fmt.Errorf("unable to serialize account key: %w", err)
+	}
+
+	// No error
+	return key, nil
+}
@@ -0,0 +1,49 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package from
+
+import (
+	"context"
+	"fmt"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle/budget"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// enforceBudget checks the bundle against the optional budget policy.
+func enforceBudget(ctx context.Context, provider tasks.ReaderProvider, b *bundlev1.Bundle) error {
+	if provider == nil {
+		return nil
+	}
+
+	// Create the reader
+	reader, err := provider(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open budget policy: %w", err)
+	}
+
+	// Parse the policy
+	policy, err := budget.ParsePolicy(reader)
+	if err != nil {
+		return fmt.Errorf("unable to load budget policy: %w", err)
+	}
+
+	// Evaluate budgets
+	return budget.Enforce(b, policy)
+}
@@ -0,0 +1,110 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package from
+
+import (
+	"context"
+	"fmt"
+
+	sm "cloud.google.com/go/secretmanager/apiv1"
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/secretmanager"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// GCPSecretManagerTask implements secret-container building from GCP Secret
+// Manager.
+type GCPSecretManagerTask struct {
+	OutputWriter  tasks.WriterProvider
+	MappingReader tasks.ReaderProvider
+	BudgetReader  tasks.ReaderProvider
+	Project       string
+	Filter        string
+	PathTemplate  string
+	AllVersions   bool
+}
+
+// Capabilities returns the task required capabilities.
+func (t *GCPSecretManagerTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{Network: true}
+}
+
+// Run the task.
+func (t *GCPSecretManagerTask) Run(ctx context.Context) error {
+	// Load path mapping
+	m, err := loadMapping(ctx, t.MappingReader)
+	if err != nil {
+		return err
+	}
+
+	// Initialize client
+	client, err := sm.NewClient(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to initialize Secret Manager client: %w", err)
+	}
+	defer client.Close()
+
+	// Prepare options
+	opts := []secretmanager.Option{
+		secretmanager.WithFilter(t.Filter),
+		secretmanager.WithAllVersions(t.AllVersions),
+	}
+	if t.PathTemplate != "" {
+		opts = append(opts, secretmanager.WithPathTemplate(t.PathTemplate))
+	}
+	if m != nil {
+		opts = append(opts, secretmanager.WithMapping(m))
+	}
+
+	// Call importer
+	b, summary, err := secretmanager.Pull(ctx, client, t.Project, opts...)
+	if err != nil {
+		return fmt.Errorf("error occurs during Secret Manager import: %w", err)
+	}
+
+	// Display summary
+	log.For(ctx).Info("Secret Manager import completed",
+		zap.String("project", t.Project),
+		zap.Int("listed", summary.Listed),
+		zap.Int("imported", summary.Imported),
+		zap.Int("skipped", summary.Skipped),
+		zap.Strings("warnings", summary.Warnings),
+	)
+
+	// Check size budgets
+	if err = enforceBudget(ctx, t.BudgetReader, b); err != nil {
+		return err
+	}
+
+	// Create output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output bundle: %w", err)
+	}
+
+	// Dump bundle
+	if err = bundle.ToContainerWriter(writer, b); err != nil {
+		return fmt.Errorf("unable to produce exported bundle: %w", err)
+	}
+
+	// No error
+	return nil
+}
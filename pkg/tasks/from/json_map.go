@@ -30,8 +30,15 @@ import (
 
 // JSONMapTask implements secret-container creation from JSON Map.
 type JSONMapTask struct {
-	JSONReader   tasks.ReaderProvider
-	OutputWriter tasks.WriterProvider
+	JSONReader    tasks.ReaderProvider
+	MappingReader tasks.ReaderProvider
+	BudgetReader  tasks.ReaderProvider
+	OutputWriter  tasks.WriterProvider
+}
+
+// Capabilities returns the task required capabilities.
+func (t *JSONMapTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
 }
 
 // Run the task.
@@ -61,6 +68,22 @@ func (t *JSONMapTask) Run(ctx context.Context) error {
 		return fmt.Errorf("unable to create container from map: %w", err)
 	}
 
+	// Apply path mapping
+	m, err := loadMapping(ctx, t.MappingReader)
+	if err != nil {
+		return err
+	}
+	if m != nil {
+		if err = m.Apply(b); err != nil {
+			return fmt.Errorf("unable to map package paths: %w", err)
+		}
+	}
+
+	// Check size budgets
+	if err = enforceBudget(ctx, t.BudgetReader, b); err != nil {
+		return err
+	}
+
 	// Create output writer
 	writer, err = t.OutputWriter(ctx)
 	if err != nil {
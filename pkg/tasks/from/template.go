@@ -25,8 +25,10 @@ import (
 	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
 	"github.com/elastic/harp/pkg/bundle"
 	"github.com/elastic/harp/pkg/bundle/template"
+	"github.com/elastic/harp/pkg/bundle/template/dryrun"
 	"github.com/elastic/harp/pkg/bundle/template/visitor/secretbuilder"
 	"github.com/elastic/harp/pkg/tasks"
+	"github.com/elastic/harp/pkg/template/consumption"
 	"github.com/elastic/harp/pkg/template/engine"
 )
 
@@ -34,8 +36,21 @@ import (
 // manifest.
 type BundleTemplateTask struct {
 	TemplateReader  tasks.ReaderProvider
+	BudgetReader    tasks.ReaderProvider
 	OutputWriter    tasks.WriterProvider
 	TemplateContext engine.Context
+	// ConsumptionWriter optionally receives the manifest of existing secret
+	// values read during the render.
+	ConsumptionWriter tasks.WriterProvider
+	// RemoteSecrets is true when secrets are read from remote services.
+	RemoteSecrets bool
+	DryRun        bool
+	JSONOutput    bool
+}
+
+// Capabilities returns the task required capabilities.
+func (t *BundleTemplateTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{Network: t.RemoteSecrets}
 }
 
 // Run the task.
@@ -59,19 +74,40 @@ func (t *BundleTemplateTask) Run(ctx context.Context) error {
 		return fmt.Errorf("unable to parse template: %w", err)
 	}
 
+	// Report expected packages without generating secrets
+	if t.DryRun {
+		return t.dryRun(ctx, spec)
+	}
+
 	// Initialize output
 	b := &bundlev1.Bundle{
 		Template: spec,
 	}
 
+	// Track consumed secrets, values already consumed in the task context
+	// are part of the manifest
+	templateContext := t.TemplateContext
+	tracker := consumption.TrackerFrom(ctx)
+	if tracker == nil {
+		tracker = &consumption.Tracker{}
+	}
+	if t.ConsumptionWriter != nil {
+		templateContext = engine.Tracking(templateContext, tracker)
+	}
+
 	// Initialize a bundle creator
-	v := secretbuilder.New(b, t.TemplateContext)
+	v := secretbuilder.New(b, templateContext)
 
 	// Execute the template to generate an output bundle
 	if err = template.Execute(spec, v); err != nil {
 		return fmt.Errorf("unable to generate output bundle from template: %w", err)
 	}
 
+	// Check size budgets
+	if err = enforceBudget(ctx, t.BudgetReader, b); err != nil {
+		return err
+	}
+
 	// Create output writer
 	writer, err = t.OutputWriter(ctx)
 	if err != nil {
@@ -83,6 +119,44 @@ func (t *BundleTemplateTask) Run(ctx context.Context) error {
 		return fmt.Errorf("unable to dump bundle content: %w", err)
 	}
 
+	// Export consumption manifest
+	if t.ConsumptionWriter != nil {
+		consumptionWriter, err := t.ConsumptionWriter(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to open consumption manifest writer: %w", err)
+		}
+		if err := tracker.Manifest().WriteJSON(consumptionWriter); err != nil {
+			return fmt.Errorf("unable to write consumption manifest: %w", err)
+		}
+	}
+
+	// No error
+	return nil
+}
+
+func (t *BundleTemplateTask) dryRun(ctx context.Context, spec *bundlev1.Template) error {
+	// Analyze the template
+	report, err := dryrun.Analyze(spec, t.TemplateContext)
+	if err != nil {
+		return fmt.Errorf("unable to analyze template: %w", err)
+	}
+
+	// Create output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output writer: %w", err)
+	}
+
+	// Display the report
+	if t.JSONOutput {
+		err = report.WriteJSON(writer)
+	} else {
+		err = report.WriteText(writer)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to write dry-run report: %w", err)
+	}
+
 	// No error
 	return nil
 }
@@ -0,0 +1,207 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package from
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/api"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/secret"
+	"github.com/elastic/harp/pkg/template/consumption"
+	"github.com/elastic/harp/pkg/template/engine"
+	"github.com/elastic/harp/pkg/vault/kv"
+)
+
+const consumptionSpec = `apiVersion: harp.elastic.co/v1
+kind: BundleTemplate
+meta:
+  name: "consumption"
+  owner: security@elastic.co
+  description: "Consumption test case"
+spec:
+  selector:
+    quality: "production"
+  namespaces:
+    infrastructure:
+    - provider: "aws"
+      account: "test"
+      regions:
+      - name: "us-east-1"
+        services:
+        - type: "rds"
+          name: "database"
+          secrets:
+          - suffix: "accounts/root_credentials"
+            template: |-
+              {
+                "user": "{{ (secret "infra/aws/test/us-east-1/rds/database/seed").user }}",
+                "password": "{{ (secret "legacy/database").password }}"
+              }
+`
+
+func consumptionVault(t *testing.T) *api.Client {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/sys/internal/ui/mounts/legacy/database":
+			fmt.Fprintf(w, `{"data":{"type":"kv", "path":"legacy/", "options":{"version": "2"}}}`)
+		case "/v1/legacy/data/database":
+			fmt.Fprintf(w, `{"data":{"data":{"password":"vault-password"}}}`)
+		default:
+			w.WriteHeader(404)
+			fmt.Fprintf(w, `{}`)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := api.NewClient(&api.Config{
+		Address:    server.URL,
+		Timeout:    time.Second * 1,
+		MaxRetries: 1,
+		HttpClient: &http.Client{Transport: cleanhttp.DefaultTransport(), Timeout: time.Second * 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client
+}
+
+func consumptionContainer(t *testing.T) *bundlev1.Bundle {
+	t.Helper()
+
+	pkg := func(name string, kv map[string]string) *bundlev1.Package {
+		p := &bundlev1.Package{
+			Name:    name,
+			Secrets: &bundlev1.SecretChain{},
+		}
+		for k, v := range kv {
+			packed, err := secret.Pack(v)
+			if err != nil {
+				t.Fatalf("unable to pack secret: %v", err)
+			}
+			p.Secrets.Data = append(p.Secrets.Data, &bundlev1.KV{Key: k, Type: "string", Value: packed})
+		}
+		return p
+	}
+
+	return &bundlev1.Bundle{
+		Packages: []*bundlev1.Package{
+			pkg("infra/aws/test/us-east-1/rds/database/seed", map[string]string{"user": "admin"}),
+			pkg("app/production/api/credentials", map[string]string{"token": "api-token"}),
+		},
+	}
+}
+
+func digest(t *testing.T, value string) string {
+	t.Helper()
+
+	d, err := consumption.Digest(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestBundleTemplateTask_ConsumptionManifest(t *testing.T) {
+	container := consumptionContainer(t)
+	tracker := &consumption.Tracker{}
+
+	// Prepare task
+	out := &bytes.Buffer{}
+	manifest := &bytes.Buffer{}
+	task := &BundleTemplateTask{
+		TemplateReader: func(context.Context) (io.Reader, error) {
+			return strings.NewReader(consumptionSpec), nil
+		},
+		OutputWriter: func(context.Context) (io.Writer, error) {
+			return out, nil
+		},
+		ConsumptionWriter: func(context.Context) (io.Writer, error) {
+			return manifest, nil
+		},
+		TemplateContext: engine.NewContext(
+			engine.WithSecretSource(consumption.ContainerSource("secrets.bundle"), bundle.SecretReader(container)),
+			engine.WithSecretSource(consumption.SourceVault, kv.SecretGetter(consumptionVault(t))),
+		),
+	}
+	ctx := consumption.WithTracker(context.Background(), tracker)
+
+	// Resolve package references used as template values
+	results, err := bundle.ResolveAll(ctx, container, []bundle.SecretRequest{
+		{Path: "app/production/api/credentials", Key: "token"},
+	})
+	if err != nil || results[0].Err != nil {
+		t.Fatalf("unable to resolve references: %v / %v", err, results[0].Err)
+	}
+
+	// Render the specification
+	if err := task.Run(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Rendered bundle uses consumed values
+	b, err := bundle.FromContainerReader(out)
+	if err != nil {
+		t.Fatalf("unable to load rendered bundle: %v", err)
+	}
+	values, err := bundle.Read(b, "infra/aws/test/us-east-1/rds/database/accounts/root_credentials")
+	if err != nil {
+		t.Fatalf("unable to read rendered secret: %v", err)
+	}
+	if values["user"] != "admin" || values["password"] != "vault-password" {
+		t.Errorf("unexpected rendered values %v", values)
+	}
+
+	// Check manifest
+	got, err := consumption.ReadManifest(manifest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := &consumption.Manifest{
+		Records: []consumption.Record{
+			{Source: "container:secrets.bundle", Path: "infra/aws/test/us-east-1/rds/database/seed", Key: "user", Digest: digest(t, "admin")},
+			{Source: "reference", Path: "app/production/api/credentials", Key: "token", Digest: digest(t, "api-token")},
+			{Source: "vault", Path: "legacy/database", Key: "password", Digest: digest(t, "vault-password")},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected manifest (-want +got):\n%s", diff)
+	}
+
+	// Values are never recorded
+	for _, v := range []string{"admin", "vault-password", "api-token"} {
+		if strings.Contains(manifest.String(), v) {
+			t.Errorf("manifest leaks '%s' value", v)
+		}
+	}
+}
@@ -22,22 +22,58 @@ import (
 	"fmt"
 
 	"github.com/hashicorp/vault/api"
+	"go.uber.org/zap"
 
 	"github.com/elastic/harp/pkg/bundle"
 	bundlevault "github.com/elastic/harp/pkg/bundle/vault"
+	"github.com/elastic/harp/pkg/sdk/log"
 	"github.com/elastic/harp/pkg/tasks"
 )
 
 // VaultTask implements secret-container building from Vault K/V.
 type VaultTask struct {
-	OutputWriter   tasks.WriterProvider
-	SecretPaths    []string
-	VaultNamespace string
-	WithMetadata   bool
+	OutputWriter      tasks.WriterProvider
+	IncrementalWriter tasks.WriterProvider
+	MappingReader     tasks.ReaderProvider
+	BudgetReader      tasks.ReaderProvider
+	SecretPaths       []string
+	VaultNamespace    string
+	WithMetadata      bool
+
+	result *VaultResult
+}
+
+// VaultResult describes a Vault extraction task execution.
+type VaultResult struct {
+	tasks.Result
+	Paths       int `json:"paths"`
+	Packages    int `json:"packages"`
+	Secrets     int `json:"secrets"`
+	Incremental int `json:"incremental,omitempty"`
+}
+
+// Result returns the last execution result.
+func (t *VaultTask) Result() interface{} {
+	return t.result
+}
+
+// Capabilities returns the task required capabilities.
+func (t *VaultTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{Network: true}
 }
 
 // Run the task.
 func (t *VaultTask) Run(ctx context.Context) error {
+	t.result = &VaultResult{
+		Paths: len(t.SecretPaths),
+	}
+
+	// Load path mapping
+	m, err := loadMapping(ctx, t.MappingReader)
+	if err != nil {
+		return err
+	}
+
 	// Initialize vault connection
 	client, err := api.NewClient(api.DefaultConfig())
 	if err != nil {
@@ -49,14 +85,64 @@ func (t *VaultTask) Run(ctx context.Context) error {
 		client.SetNamespace(t.VaultNamespace)
 	}
 
-	// Call exporter
-	b, err := bundlevault.Pull(ctx, client, t.SecretPaths,
+	pullOpts := []bundlevault.Option{
 		bundlevault.WithMetadata(t.WithMetadata),
-	)
+	}
+
+	// Stream pulled packages to the incremental output
+	var iw *bundle.IncrementalWriter
+	if t.IncrementalWriter != nil {
+		writer, errWriter := t.IncrementalWriter(ctx)
+		if errWriter != nil {
+			return fmt.Errorf("unable to open incremental output: %w", errWriter)
+		}
+		iw, err = bundle.NewIncrementalWriter(writer)
+		if err != nil {
+			return fmt.Errorf("unable to initialize incremental output: %w", err)
+		}
+		pullOpts = append(pullOpts, bundlevault.WithPackageObserver(iw.Append))
+	}
+
+	// Call exporter
+	b, err := bundlevault.Pull(ctx, client, t.SecretPaths, pullOpts...)
 	if err != nil {
+		if iw != nil {
+			// Keep received packages recoverable
+			t.result.Incremental = iw.Count()
+			if errCheckpoint := iw.Checkpoint(); errCheckpoint != nil {
+				log.For(ctx).Error("unable to checkpoint incremental output", zap.Error(errCheckpoint))
+			}
+		}
 		return fmt.Errorf("error occurs during vault export: %w", err)
 	}
 
+	// Finalize incremental output
+	if iw != nil {
+		t.result.Incremental = iw.Count()
+		if err = iw.Close(); err != nil {
+			return fmt.Errorf("unable to finalize incremental output: %w", err)
+		}
+	}
+
+	// Apply path mapping
+	if m != nil {
+		if err = m.Apply(b); err != nil {
+			return fmt.Errorf("unable to map package paths: %w", err)
+		}
+	}
+
+	t.result.Packages = len(b.Packages)
+	for _, p := range b.Packages {
+		if p.Secrets != nil {
+			t.result.Secrets += len(p.Secrets.Data)
+		}
+	}
+
+	// Check size budgets
+	if err = enforceBudget(ctx, t.BudgetReader, b); err != nil {
+		return err
+	}
+
 	// Create output writer
 	writer, err := t.OutputWriter(ctx)
 	if err != nil {
@@ -0,0 +1,57 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tasks
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/sdk/log"
+)
+
+// WithLogger returns a task running the given one with the logger attached to
+// its context, so that task logs are routed to it instead of the process
+// default logger. Declared capabilities are preserved.
+func WithLogger(t Task, logger *zap.Logger) Task {
+	lt := &loggerTask{next: t, logger: logger}
+	if d, ok := t.(CapabilitiesDeclarer); ok {
+		return &loggerDeclarerTask{loggerTask: lt, declarer: d}
+	}
+	return lt
+}
+
+// -----------------------------------------------------------------------------
+
+type loggerTask struct {
+	next   Task
+	logger *zap.Logger
+}
+
+func (t *loggerTask) Run(ctx context.Context) error {
+	return t.next.Run(log.WithLogger(ctx, t.logger))
+}
+
+type loggerDeclarerTask struct {
+	*loggerTask
+	declarer CapabilitiesDeclarer
+}
+
+func (t *loggerDeclarerTask) Capabilities() Capabilities {
+	return t.declarer.Capabilities()
+}
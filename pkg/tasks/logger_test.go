@@ -0,0 +1,72 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tasks
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/elastic/harp/pkg/sdk/log"
+)
+
+type logTask struct{}
+
+func (logTask) Run(ctx context.Context) error {
+	log.For(ctx).Info("running")
+	return nil
+}
+
+type networkLogTask struct {
+	logTask
+}
+
+func (networkLogTask) Capabilities() Capabilities {
+	return Capabilities{Network: true}
+}
+
+func TestWithLogger(t *testing.T) {
+	core, observed := observer.New(zapcore.InfoLevel)
+	logger := zap.New(core)
+
+	// Undeclared capabilities
+	task := WithLogger(logTask{}, logger)
+	if _, ok := task.(CapabilitiesDeclarer); ok {
+		t.Error("task without declaration must not declare capabilities")
+	}
+	if err := task.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Declared capabilities
+	task = WithLogger(networkLogTask{}, logger)
+	d, ok := task.(CapabilitiesDeclarer)
+	if !ok || !d.Capabilities().Network {
+		t.Error("declared capabilities must be preserved")
+	}
+	if err := task.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n := observed.FilterMessage("running").Len(); n != 2 {
+		t.Errorf("expected 2 entries in injected logger, got %d", n)
+	}
+}
@@ -0,0 +1,138 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package mapping
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/elastic/harp/pkg/bundle/mapping"
+	"github.com/elastic/harp/pkg/sdk/types"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// TestTask implements mapping profile evaluation for a source name.
+type TestTask struct {
+	ProfileReader tasks.ReaderProvider
+	OutputWriter  tasks.WriterProvider
+	SourceName    string
+	Metadata      map[string]string
+	Reverse       bool
+	JSONOutput    bool
+}
+
+// Capabilities returns the task required capabilities.
+func (t *TestTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Run the task.
+func (t *TestTask) Run(ctx context.Context) error {
+	// Check arguments
+	if types.IsNil(t.ProfileReader) {
+		return fmt.Errorf("unable to run task with a nil profileReader provider")
+	}
+	if types.IsNil(t.OutputWriter) {
+		return fmt.Errorf("unable to run task with a nil outputWriter provider")
+	}
+	if t.SourceName == "" {
+		return fmt.Errorf("source name must not be blank")
+	}
+
+	// Load profile
+	reader, err := t.ProfileReader(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open profile reader: %w", err)
+	}
+	load := mapping.Load
+	if t.Reverse {
+		load = mapping.LoadReverse
+	}
+	e, err := load(reader)
+	if err != nil {
+		return fmt.Errorf("unable to load mapping profile: %w", err)
+	}
+
+	// Evaluate rules
+	res, err := e.Map(t.SourceName, t.Metadata)
+	if err != nil {
+		return err
+	}
+
+	// Create output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output writer: %w", err)
+	}
+
+	if t.JSONOutput {
+		enc := json.NewEncoder(writer)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(res); err != nil {
+			return fmt.Errorf("unable to encode result: %w", err)
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(writer, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "SOURCE\t%s\n", t.SourceName)
+	fmt.Fprintf(w, "RULE\t%s\n", res.Rule)
+	fmt.Fprintf(w, "PATH\t%s\n", res.Path)
+	for _, k := range sortedKeys(res.Captures) {
+		fmt.Fprintf(w, "CAPTURE\t%s=%s\n", k, res.Captures[k])
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "EVALUATED RULE\tMATCHED\tREASON")
+	for _, tr := range res.Trace {
+		reason := tr.Reason
+		if reason == "" {
+			reason = "-"
+		}
+		fmt.Fprintf(w, "%s\t%t\t%s\n", tr.Rule, tr.Matched, reason)
+	}
+
+	return w.Flush()
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		// Numbered captures first
+		if ni, nj := isNumber(keys[i]), isNumber(keys[j]); ni != nj {
+			return ni
+		}
+		if len(keys[i]) != len(keys[j]) && isNumber(keys[i]) {
+			return len(keys[i]) < len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+
+	return keys
+}
+
+func isNumber(s string) bool {
+	return strings.Trim(s, "0123456789") == ""
+}
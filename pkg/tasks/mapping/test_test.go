@@ -0,0 +1,167 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package mapping
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/elastic/harp/pkg/bundle/mapping"
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+)
+
+const profile = `apiVersion: harp.elastic.co/v1
+kind: MappingProfile
+spec:
+  strict: true
+  rules:
+    - name: payments
+      match:
+        regex: "^db-(?P<env>[a-z]+)-(.+)$"
+        metadata:
+          team: payments
+      target: "app/{{ .Captures.env }}/payments/{{ index .Captures \"2\" }}"
+    - name: database
+      match:
+        glob: "db-*-*"
+      target: "app/{{ index .Captures \"1\" }}/shared/{{ index .Captures \"2\" }}"
+`
+
+func profileReader(context.Context) (io.Reader, error) {
+	return strings.NewReader(profile), nil
+}
+
+func TestTestTask(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		name     string
+		metadata map[string]string
+		want     string
+		wantErr  bool
+	}{
+		{
+			desc:     "first rule",
+			name:     "db-production-orders",
+			metadata: map[string]string{"team": "payments"},
+			want: `SOURCE   db-production-orders
+RULE     payments
+PATH     app/production/payments/orders
+CAPTURE  0=db-production-orders
+CAPTURE  1=production
+CAPTURE  2=orders
+CAPTURE  env=production
+
+EVALUATED RULE  MATCHED  REASON
+payments        true     -
+`,
+		},
+		{
+			desc: "fallback rule",
+			name: "db-staging-orders",
+			want: `SOURCE   db-staging-orders
+RULE     database
+PATH     app/staging/shared/orders
+CAPTURE  0=db-staging-orders
+CAPTURE  1=staging
+CAPTURE  2=orders
+
+EVALUATED RULE  MATCHED  REASON
+payments        false    metadata 'team' is missing
+database        true     -
+`,
+		},
+		{desc: "strict", name: "cache", wantErr: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var out bytes.Buffer
+			err := (&TestTask{
+				ProfileReader: profileReader,
+				OutputWriter:  testbundle.Writer(&out),
+				SourceName:    tC.name,
+				Metadata:      tC.metadata,
+			}).Run(context.Background())
+			if (err != nil) != tC.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tC.wantErr)
+			}
+			if diff := cmp.Diff(out.String(), tC.want); diff != "" {
+				t.Errorf("%q. TestTask.Run():\n-got/+want\ndiff %s", tC.desc, diff)
+			}
+		})
+	}
+}
+
+func TestTestTask_JSON(t *testing.T) {
+	var out bytes.Buffer
+	err := (&TestTask{
+		ProfileReader: profileReader,
+		OutputWriter:  testbundle.Writer(&out),
+		SourceName:    "db-staging-orders",
+		JSONOutput:    true,
+	}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var res mapping.Result
+	if err := json.Unmarshal(out.Bytes(), &res); err != nil {
+		t.Fatalf("unable to decode output: %v", err)
+	}
+	if res.Path != "app/staging/shared/orders" || res.Rule != "database" || len(res.Trace) != 2 {
+		t.Errorf("unexpected result %+v", res)
+	}
+}
+
+func TestTestTask_Reverse(t *testing.T) {
+	pairs := func(context.Context) (io.Reader, error) {
+		return strings.NewReader(`apiVersion: harp.elastic.co/v1
+kind: MappingProfile
+spec:
+  pairs:
+    - name: teams
+      source: "secret/teams/{team}/{app}/{env}/{name}"
+      target: "app/{env}/{team}/{app}/v1.0.0/{name}"
+`), nil
+	}
+
+	var out bytes.Buffer
+	err := (&TestTask{
+		ProfileReader: pairs,
+		OutputWriter:  testbundle.Writer(&out),
+		SourceName:    "app/production/payments/billing/v1.0.0/database",
+		Reverse:       true,
+		JSONOutput:    true,
+	}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var res mapping.Result
+	if err := json.Unmarshal(out.Bytes(), &res); err != nil {
+		t.Fatalf("unable to decode output: %v", err)
+	}
+	if res.Path != "secret/teams/payments/billing/production/database" || res.Rule != "teams" {
+		t.Errorf("unexpected result %+v", res)
+	}
+}
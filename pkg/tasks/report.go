@@ -0,0 +1,159 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ReportSchemaVersion is the task report schema version. It is incremented
+// when a field is removed or changes meaning.
+const ReportSchemaVersion = 1
+
+// Task execution statuses.
+const (
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusSkipped   = "skipped"
+)
+
+// Reporter is implemented by tasks producing a structured result. The result
+// describes the last execution, it is partially populated when the task
+// fails.
+type Reporter interface {
+	Result() interface{}
+}
+
+// Result holds the result fields shared by all tasks.
+type Result struct {
+	Warnings []string  `json:"warnings,omitempty"`
+	Failures []Failure `json:"failures,omitempty"`
+	// Items lists item statuses of multi-item tasks.
+	Items []ItemStatus `json:"items,omitempty"`
+}
+
+// Failure describes a failed item.
+type Failure struct {
+	Item  string `json:"item"`
+	Error string `json:"error"`
+}
+
+// ItemStatus describes an item processing status.
+type ItemStatus struct {
+	Item   string `json:"item"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Warn adds a warning to the result.
+func (r *Result) Warn(format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// Fail adds an item failure to the result.
+func (r *Result) Fail(item string, err error) {
+	r.Failures = append(r.Failures, Failure{Item: item, Error: err.Error()})
+}
+
+// Succeed records a processed item.
+func (r *Result) Succeed(item string) {
+	r.Items = append(r.Items, ItemStatus{Item: item, Status: StatusSucceeded})
+}
+
+// FailItem records a failed item, the failure is also added to the result.
+func (r *Result) FailItem(item string, err error) {
+	r.Fail(item, err)
+	r.Items = append(r.Items, ItemStatus{Item: item, Status: StatusFailed, Error: err.Error()})
+}
+
+// Skip records an item not processed after a failure.
+func (r *Result) Skip(item string) {
+	r.Items = append(r.Items, ItemStatus{Item: item, Status: StatusSkipped})
+}
+
+// ItemsError returns an *ItemsError wrapping the given error and counting
+// recorded item statuses, nil when no item failed.
+func (r *Result) ItemsError(err error) error {
+	e := &ItemsError{Err: err}
+	for _, i := range r.Items {
+		switch i.Status {
+		case StatusSucceeded:
+			e.Succeeded++
+		case StatusFailed:
+			e.Failed++
+		case StatusSkipped:
+			e.Skipped++
+		}
+	}
+	if e.Failed == 0 {
+		return err
+	}
+
+	return e
+}
+
+// Report is the machine readable summary of a task execution.
+type Report struct {
+	SchemaVersion int         `json:"schemaVersion"`
+	Task          string      `json:"task"`
+	Status        string      `json:"status"`
+	Error         string      `json:"error,omitempty"`
+	DurationMs    int64       `json:"durationMs"`
+	Result        interface{} `json:"result,omitempty"`
+}
+
+// NewReport builds the execution report of the given task. The task result is
+// attached when the task is a Reporter.
+func NewReport(name string, t Task, err error, duration time.Duration) *Report {
+	r := &Report{
+		SchemaVersion: ReportSchemaVersion,
+		Task:          name,
+		Status:        StatusSucceeded,
+		DurationMs:    duration.Milliseconds(),
+	}
+	if err != nil {
+		r.Status = StatusFailed
+		r.Error = err.Error()
+	}
+	if rep, ok := t.(Reporter); ok {
+		r.Result = rep.Result()
+	}
+
+	return r
+}
+
+// WriteReport encodes the given report as JSON.
+func WriteReport(ctx context.Context, wp WriterProvider, r *Report) error {
+	// Create output writer
+	writer, err := wp(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open report writer: %w", err)
+	}
+
+	enc := json.NewEncoder(writer)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		return fmt.Errorf("unable to encode task report: %w", err)
+	}
+
+	// No error
+	return nil
+}
@@ -0,0 +1,329 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package to
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/awnumar/memguard"
+	"sigs.k8s.io/yaml"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/sdk/security/crypto"
+	"github.com/elastic/harp/pkg/sdk/security/crypto/keystore"
+	"github.com/elastic/harp/pkg/sdk/types"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+const (
+	// KeystoreFormatPKCS12 exports a PKCS#12 keystore.
+	KeystoreFormatPKCS12 = "pkcs12"
+	// KeystoreFormatJKS exports a legacy JKS keystore.
+	KeystoreFormatJKS = "jks"
+)
+
+// KeystoreMapping describes how package secrets are exported as keystore
+// entries.
+type KeystoreMapping struct {
+	Entries []KeystoreEntry `json:"entries"`
+}
+
+// KeystoreEntry binds package secret keys to a keystore alias. Cert key
+// holds the PEM encoded leaf certificate, optionally followed by its chain.
+// Entries without private key are exported as trusted certificates. When the
+// cert key is blank, certificate and private key are selected according to
+// secret inferred types.
+type KeystoreEntry struct {
+	Alias   string   `json:"alias"`
+	Package string   `json:"package"`
+	Cert    string   `json:"cert"`
+	Key     string   `json:"key,omitempty"`
+	Chain   []string `json:"chain,omitempty"`
+}
+
+// KeystoreTask implements secret export as a JVM keystore.
+type KeystoreTask struct {
+	ContainerReader    tasks.ReaderProvider
+	MappingReader      tasks.ReaderProvider
+	OutputWriter       tasks.WriterProvider
+	Passphrase         *memguard.LockedBuffer
+	Format             string
+	IncludeQuarantined bool
+}
+
+// Capabilities returns the task required capabilities.
+func (t *KeystoreTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Run the task.
+func (t *KeystoreTask) Run(ctx context.Context) error {
+	// Check arguments
+	if types.IsNil(t.ContainerReader) {
+		return errors.New("unable to run task with a nil containerReader provider")
+	}
+	if types.IsNil(t.MappingReader) {
+		return errors.New("unable to run task with a nil mappingReader provider")
+	}
+	if types.IsNil(t.OutputWriter) {
+		return errors.New("unable to run task with a nil outputWriter provider")
+	}
+	if t.Passphrase == nil || t.Passphrase.Size() == 0 {
+		return errors.New("keystore passphrase must not be blank")
+	}
+	if t.Format != KeystoreFormatPKCS12 && t.Format != KeystoreFormatJKS {
+		return fmt.Errorf("unsupported keystore format '%s'", t.Format)
+	}
+
+	// Load mapping
+	mapping, err := t.loadMapping(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Create the reader
+	reader, err := t.ContainerReader(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open input bundle reader: %w", err)
+	}
+
+	// Extract bundle from container
+	b, err := bundle.FromContainerReader(reader)
+	if err != nil {
+		return fmt.Errorf("unable to load bundle: %w", err)
+	}
+
+	// Index packages, archived packages are never exported
+	packages := map[string]*bundlev1.Package{}
+	for _, p := range skipQuarantined(ctx, bundle.WithoutArchived(b), t.IncludeQuarantined).Packages {
+		packages[p.Name] = p
+	}
+
+	// Resolve entries
+	entries := make([]keystore.Entry, 0, len(mapping.Entries))
+	for i := range mapping.Entries {
+		e, errEntry := keystoreEntry(&mapping.Entries[i], packages)
+		if errEntry != nil {
+			return errEntry
+		}
+		entries = append(entries, *e)
+	}
+
+	// Encode keystore
+	var out []byte
+	switch t.Format {
+	case KeystoreFormatJKS:
+		out, err = keystore.EncodeJKS(rand.Reader, entries, t.Passphrase.Bytes(), time.Now())
+	default:
+		out, err = keystore.EncodePKCS12(rand.Reader, entries, t.Passphrase.Bytes())
+	}
+	if err != nil {
+		return fmt.Errorf("unable to encode keystore: %w", err)
+	}
+
+	// Create output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open output writer: %w", err)
+	}
+
+	// Write keystore
+	if _, err := writer.Write(out); err != nil {
+		return fmt.Errorf("unable to write keystore: %w", err)
+	}
+
+	// No error
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (t *KeystoreTask) loadMapping(ctx context.Context) (*KeystoreMapping, error) {
+	reader, err := t.MappingReader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open mapping reader: %w", err)
+	}
+
+	content, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read mapping: %w", err)
+	}
+
+	var mapping KeystoreMapping
+	if err := yaml.UnmarshalStrict(content, &mapping); err != nil {
+		return nil, fmt.Errorf("unable to decode mapping: %w", err)
+	}
+	if len(mapping.Entries) == 0 {
+		return nil, errors.New("mapping must declare at least one entry")
+	}
+
+	return &mapping, nil
+}
+
+func keystoreEntry(m *KeystoreEntry, packages map[string]*bundlev1.Package) (*keystore.Entry, error) {
+	// Retrieve package secrets
+	p, ok := packages[m.Package]
+	if !ok {
+		return nil, fmt.Errorf("package '%s' of alias '%s' not found in bundle", m.Package, m.Alias)
+	}
+	secrets, err := bundle.AsSecretMap(p)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode package '%s' secrets: %w", m.Package, err)
+	}
+
+	// Leaf certificate and optional bundled chain
+	if m.Cert == "" {
+		if err := inferKeystoreKeys(m, p); err != nil {
+			return nil, err
+		}
+	}
+	certs, err := secretCertificates(secrets, m.Package, m.Cert)
+	if err != nil {
+		return nil, err
+	}
+	e := &keystore.Entry{
+		Alias:       m.Alias,
+		Certificate: certs[0],
+		Chain:       certs[1:],
+	}
+
+	// Additional chain certificates
+	for _, key := range m.Chain {
+		chain, errChain := secretCertificates(secrets, m.Package, key)
+		if errChain != nil {
+			return nil, errChain
+		}
+		e.Chain = append(e.Chain, chain...)
+	}
+
+	// Private key
+	if m.Key != "" {
+		raw, errValue := secretBytes(secrets, m.Package, m.Key)
+		if errValue != nil {
+			return nil, errValue
+		}
+		e.PrivateKey, err = crypto.ParsePrivateKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse '%s' private key of '%s': %w", m.Key, m.Package, err)
+		}
+	}
+
+	// No error
+	return e, nil
+}
+
+// inferKeystoreKeys selects certificate and private key secret keys of the
+// given entry according to their inferred type.
+func inferKeystoreKeys(m *KeystoreEntry, p *bundlev1.Package) error {
+	certs, keys := []string{}, []string{}
+	for _, kv := range p.Secrets.GetData() {
+		v, err := bundle.Typed(p, kv.Key)
+		if err != nil {
+			return fmt.Errorf("unable to infer secret types of '%s': %w", m.Package, err)
+		}
+		switch v.Kind {
+		case bundle.KindPEMCertificate:
+			certs = append(certs, kv.Key)
+		case bundle.KindPEMPrivateKey:
+			keys = append(keys, kv.Key)
+		default:
+		}
+	}
+	sort.Strings(certs)
+	sort.Strings(keys)
+
+	// Certificate
+	switch len(certs) {
+	case 0:
+		return fmt.Errorf("alias '%s' must declare a certificate key, no certificate found in '%s'", m.Alias, m.Package)
+	case 1:
+		m.Cert = certs[0]
+	default:
+		return fmt.Errorf("alias '%s' must declare a certificate key, '%s' holds several certificates %v", m.Alias, m.Package, certs)
+	}
+
+	// Private key is optional for trusted certificates
+	if m.Key == "" {
+		switch len(keys) {
+		case 0:
+		case 1:
+			m.Key = keys[0]
+		default:
+			return fmt.Errorf("alias '%s' must declare a private key, '%s' holds several private keys %v", m.Alias, m.Package, keys)
+		}
+	}
+
+	// No error
+	return nil
+}
+
+func secretBytes(secrets bundle.KV, pkg, key string) ([]byte, error) {
+	v, ok := secrets[key]
+	if !ok {
+		return nil, fmt.Errorf("secret key '%s' not found in package '%s'", key, pkg)
+	}
+
+	switch value := v.(type) {
+	case string:
+		return []byte(value), nil
+	case []byte:
+		return value, nil
+	default:
+	}
+
+	return nil, fmt.Errorf("secret key '%s' of '%s' must be a PEM string, got %T", key, pkg, v)
+}
+
+func secretCertificates(secrets bundle.KV, pkg, key string) ([]*x509.Certificate, error) {
+	raw, err := secretBytes(secrets, pkg, key)
+	if err != nil {
+		return nil, err
+	}
+
+	certs := []*x509.Certificate{}
+	for {
+		var block *pem.Block
+		block, raw = pem.Decode(raw)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, errParse := x509.ParseCertificate(block.Bytes)
+		if errParse != nil {
+			return nil, fmt.Errorf("unable to parse '%s' certificate of '%s': %w", key, pkg, errParse)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("secret key '%s' of '%s' doesn't contain any PEM certificate", key, pkg)
+	}
+
+	return certs, nil
+}
@@ -0,0 +1,247 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package to
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/awnumar/memguard"
+	"software.sslmate.com/src/go-pkcs12"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+)
+
+func testCertificate(t *testing.T, cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert, key, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: raw}))
+}
+
+func TestKeystoreTask(t *testing.T) {
+	ca, caKey, caPEM := testCertificate(t, "ca", nil, nil)
+	leaf, leafKey, leafPEM := testCertificate(t, "web", ca, caKey)
+	_, otherKey, _ := testCertificate(t, "other", nil, nil)
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(leafKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherDER, err := x509.MarshalECPrivateKey(otherKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	packages := map[string]bundle.KV{
+		"app/production/web/tls": {
+			"cert":  leafPEM,
+			"key":   string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})),
+			"ca":    caPEM,
+			"other": string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: otherDER})),
+		},
+		"app/production/web/auto": {
+			"tls.crt": leafPEM + caPEM,
+			"tls.key": string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})),
+			"port":    "443",
+		},
+	}
+
+	testCases := []struct {
+		desc       string
+		mapping    string
+		format     string
+		passphrase string
+		wantErr    string
+	}{
+		{
+			desc: "pkcs12",
+			mapping: `entries:
+  - alias: web
+    package: app/production/web/tls
+    cert: cert
+    key: key
+    chain: [ca]
+`,
+			format:     KeystoreFormatPKCS12,
+			passphrase: "changeit",
+		},
+		{
+			desc: "jks",
+			mapping: `entries:
+  - alias: web
+    package: app/production/web/tls
+    cert: cert
+    key: key
+    chain: [ca]
+  - alias: ca
+    package: app/production/web/tls
+    cert: ca
+`,
+			format:     KeystoreFormatJKS,
+			passphrase: "changeit",
+		},
+		{
+			desc: "pkcs12 mixed entries",
+			mapping: `entries:
+  - alias: web
+    package: app/production/web/tls
+    cert: cert
+    key: key
+  - alias: ca
+    package: app/production/web/tls
+    cert: ca
+`,
+			format:     KeystoreFormatPKCS12,
+			passphrase: "changeit",
+			wantErr:    "single private key entry or trusted certificates only",
+		},
+		{
+			desc:       "inferred keys",
+			mapping:    "entries: [{alias: web, package: app/production/web/auto}]",
+			format:     KeystoreFormatPKCS12,
+			passphrase: "changeit",
+		},
+		{
+			desc:       "ambiguous inferred certificate",
+			mapping:    "entries: [{alias: web, package: app/production/web/tls}]",
+			format:     KeystoreFormatPKCS12,
+			passphrase: "changeit",
+			wantErr:    "holds several certificates [ca cert]",
+		},
+		{
+			desc:       "blank passphrase",
+			mapping:    "entries: [{alias: ca, package: app/production/web/tls, cert: ca}]",
+			format:     KeystoreFormatPKCS12,
+			passphrase: "",
+			wantErr:    "passphrase must not be blank",
+		},
+		{
+			desc:       "unknown field",
+			mapping:    "entries: [{alias: ca, package: app/production/web/tls, certificate: ca}]",
+			format:     KeystoreFormatPKCS12,
+			passphrase: "changeit",
+			wantErr:    "unable to decode mapping",
+		},
+		{
+			desc:       "package not found",
+			mapping:    "entries: [{alias: ca, package: app/production/web/missing, cert: ca}]",
+			format:     KeystoreFormatPKCS12,
+			passphrase: "changeit",
+			wantErr:    "not found in bundle",
+		},
+		{
+			desc:       "key mismatch",
+			mapping:    "entries: [{alias: web, package: app/production/web/tls, cert: cert, key: other}]",
+			format:     KeystoreFormatPKCS12,
+			passphrase: "changeit",
+			wantErr:    "private key doesn't match the certificate",
+		},
+		{
+			desc:       "invalid chain",
+			mapping:    "entries: [{alias: web, package: app/production/web/tls, cert: cert, key: key, chain: [cert]}]",
+			format:     KeystoreFormatPKCS12,
+			passphrase: "changeit",
+			wantErr:    "is not the issuer",
+		},
+		{
+			desc:       "duplicate alias",
+			mapping:    "entries: [{alias: ca, package: app/production/web/tls, cert: ca}, {alias: CA, package: app/production/web/tls, cert: ca}]",
+			format:     KeystoreFormatPKCS12,
+			passphrase: "changeit",
+			wantErr:    "duplicate alias",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var out bytes.Buffer
+			err := (&KeystoreTask{
+				ContainerReader: containerReader(t, packages),
+				MappingReader:   stringReader(tC.mapping),
+				OutputWriter:    testbundle.Writer(&out),
+				Passphrase:      memguard.NewBufferFromBytes([]byte(tC.passphrase)),
+				Format:          tC.format,
+			}).Run(context.Background())
+			if tC.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tC.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tC.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.Len() == 0 {
+				t.Fatal("empty keystore")
+			}
+
+			if tC.format != KeystoreFormatPKCS12 {
+				return
+			}
+
+			key, cert, chain, err := pkcs12.DecodeChain(out.Bytes(), tC.passphrase)
+			if err != nil {
+				t.Fatalf("unable to decode keystore: %v", err)
+			}
+			if ecKey, ok := key.(*ecdsa.PrivateKey); !ok || !ecKey.Equal(leafKey) {
+				t.Error("private key mismatch")
+			}
+			if !cert.Equal(leaf) {
+				t.Error("leaf certificate mismatch")
+			}
+			if len(chain) != 1 || !chain[0].Equal(ca) {
+				t.Error("chain mismatch")
+			}
+		})
+	}
+}
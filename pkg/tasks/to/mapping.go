@@ -0,0 +1,47 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package to
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/elastic/harp/pkg/bundle/mapping"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// loadMapping compiles the optional mapping profile.
+func loadMapping(ctx context.Context, provider tasks.ReaderProvider) (*mapping.Engine, error) {
+	if provider == nil {
+		return nil, nil
+	}
+
+	// Create the reader
+	reader, err := provider(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open mapping profile: %w", err)
+	}
+
+	// Compile the profile, bidirectional profiles are applied in reverse
+	e, err := mapping.LoadReverse(reader)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load mapping profile: %w", err)
+	}
+
+	return e, nil
+}
@@ -0,0 +1,44 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package to
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/sdk/log"
+)
+
+// skipQuarantined returns a bundle view without quarantined packages unless
+// explicitly included. Each skipped package is logged.
+func skipQuarantined(ctx context.Context, b *bundlev1.Bundle, include bool) *bundlev1.Bundle {
+	if include || b == nil {
+		return b
+	}
+
+	for _, p := range b.Packages {
+		if reason, ok := bundle.QuarantineReason(p); ok {
+			log.For(ctx).Warn("Quarantined package skipped", zap.String("path", p.Name), zap.String("reason", reason))
+		}
+	}
+
+	return bundle.WithoutQuarantined(b)
+}
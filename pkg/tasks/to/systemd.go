@@ -0,0 +1,348 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package to
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+	"sigs.k8s.io/yaml"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/sdk/escape"
+	"github.com/elastic/harp/pkg/sdk/fsutil"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+const (
+	// systemdDropInName is the generated drop-in file name.
+	systemdDropInName = "harp-credentials.conf"
+)
+
+// SystemdMapping describes how packages are exported as systemd credentials.
+type SystemdMapping struct {
+	Mappings []SystemdPackageMapping `json:"mappings"`
+}
+
+// SystemdPackageMapping binds a package to a systemd service. Credentials
+// maps secret keys to credential names, all keys are exported using their
+// sanitized name when empty.
+type SystemdPackageMapping struct {
+	Package     string            `json:"package"`
+	Service     string            `json:"service"`
+	Credentials map[string]string `json:"credentials,omitempty"`
+}
+
+// SystemdTask implements secret export as systemd LoadCredential files.
+type SystemdTask struct {
+	ContainerReader    tasks.ReaderProvider
+	MappingReader      tasks.ReaderProvider
+	OutputPath         string
+	TmpfsCheck         bool
+	IncludeQuarantined bool
+}
+
+type systemdCredential struct {
+	name  string
+	path  string
+	value []byte
+}
+
+// Capabilities returns the task required capabilities.
+func (t *SystemdTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Run the task.
+func (t *SystemdTask) Run(ctx context.Context) error {
+	// Check arguments
+	if t.OutputPath == "" {
+		return fmt.Errorf("output path must not be blank")
+	}
+
+	// Load mapping
+	mapping, err := t.loadMapping(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Create the reader
+	reader, err := t.ContainerReader(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open input bundle reader: %w", err)
+	}
+
+	// Extract bundle from container
+	b, err := bundle.FromContainerReader(reader)
+	if err != nil {
+		return fmt.Errorf("unable to load bundle: %w", err)
+	}
+	// Index packages, archived packages are never exported
+	packages := map[string]*bundlev1.Package{}
+	for _, p := range skipQuarantined(ctx, bundle.WithoutArchived(b), t.IncludeQuarantined).Packages {
+		packages[p.Name] = p
+	}
+
+	// Resolve output directory
+	root, err := filepath.Abs(t.OutputPath)
+	if err != nil {
+		return fmt.Errorf("unable to resolve output path: %w", err)
+	}
+
+	// Resolve credentials by service
+	services, err := systemdCredentials(mapping, packages, root)
+	if err != nil {
+		return err
+	}
+
+	// Prepare output directory
+	if err := os.MkdirAll(root, 0o700); err != nil {
+		return fmt.Errorf("unable to create output directory: %w", err)
+	}
+	if t.TmpfsCheck {
+		if ok, errCheck := isRAMFilesystem(root); errCheck != nil {
+			log.For(ctx).Warn("Unable to check output filesystem type", zap.Error(errCheck))
+		} else if !ok {
+			log.For(ctx).Warn("Output directory is not on a ramdisk, plaintext secrets will be persisted", zap.String("path", root))
+		}
+	}
+
+	// Write credentials and unit snippets
+	for service, creds := range services {
+		if err := writeSystemdService(root, service, creds); err != nil {
+			return err
+		}
+	}
+
+	// No error
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (t *SystemdTask) loadMapping(ctx context.Context) (*SystemdMapping, error) {
+	reader, err := t.MappingReader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open mapping reader: %w", err)
+	}
+
+	content, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read mapping: %w", err)
+	}
+
+	var mapping SystemdMapping
+	if err := yaml.UnmarshalStrict(content, &mapping); err != nil {
+		return nil, fmt.Errorf("unable to decode mapping: %w", err)
+	}
+
+	return &mapping, nil
+}
+
+func systemdCredentials(mapping *SystemdMapping, packages map[string]*bundlev1.Package, root string) (map[string][]systemdCredential, error) {
+	services := map[string][]systemdCredential{}
+	used := map[string]string{}
+
+	for _, m := range mapping.Mappings {
+		// Check service name
+		if err := validateUnitName(m.Service); err != nil {
+			return nil, err
+		}
+
+		// Retrieve package secrets
+		p, ok := packages[m.Package]
+		if !ok {
+			return nil, fmt.Errorf("package '%s' not found in bundle", m.Package)
+		}
+		secrets, err := bundle.AsSecretMap(p)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode package '%s' secrets: %w", m.Package, err)
+		}
+
+		// Resolve credential names
+		names := m.Credentials
+		if len(names) == 0 {
+			names = map[string]string{}
+			for key := range secrets {
+				names[key] = SanitizeCredentialName(key)
+			}
+		}
+
+		// Sort keys for reproducible errors
+		keys := make([]string, 0, len(names))
+		for key := range names {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			name := names[key]
+			if err := ValidateCredentialName(name); err != nil {
+				return nil, fmt.Errorf("invalid credential name for '%s.%s': %w", m.Package, key, err)
+			}
+
+			value, ok := secrets[key]
+			if !ok {
+				return nil, fmt.Errorf("secret key '%s' not found in package '%s'", key, m.Package)
+			}
+
+			// Check collisions
+			id := fmt.Sprintf("%s/%s", m.Service, name)
+			source := fmt.Sprintf("%s.%s", m.Package, key)
+			if previous, ok := used[id]; ok {
+				return nil, fmt.Errorf("credential '%s' of service '%s' is defined by '%s' and '%s'", name, m.Service, previous, source)
+			}
+			used[id] = source
+
+			// Encode value
+			content, err := credentialValue(value)
+			if err != nil {
+				return nil, fmt.Errorf("unable to encode '%s': %w", source, err)
+			}
+
+			services[m.Service] = append(services[m.Service], systemdCredential{
+				name:  name,
+				path:  filepath.Join(root, m.Service, name),
+				value: content,
+			})
+		}
+	}
+
+	return services, nil
+}
+
+func writeSystemdService(root, service string, creds []systemdCredential) error {
+	sort.Slice(creds, func(i, j int) bool {
+		return creds[i].name < creds[j].name
+	})
+
+	// Render drop-in before writing anything
+	dropIn, err := systemdDropIn(creds)
+	if err != nil {
+		return fmt.Errorf("unable to generate drop-in for '%s': %w", service, err)
+	}
+
+	// Write credential files
+	if err := os.MkdirAll(filepath.Join(root, service), 0o700); err != nil {
+		return fmt.Errorf("unable to create credential directory for '%s': %w", service, err)
+	}
+	for _, c := range creds {
+		if err := writeFileAtomic(c.path, 0o600, c.value); err != nil {
+			return fmt.Errorf("unable to write credential '%s' of '%s': %w", c.name, service, err)
+		}
+	}
+
+	// Write unit drop-in
+	dropInDir := filepath.Join(root, fmt.Sprintf("%s.d", unitFileName(service)))
+	if err := os.MkdirAll(dropInDir, 0o755); err != nil {
+		return fmt.Errorf("unable to create drop-in directory for '%s': %w", service, err)
+	}
+	if err := writeFileAtomic(filepath.Join(dropInDir, systemdDropInName), 0o644, dropIn); err != nil {
+		return fmt.Errorf("unable to write drop-in for '%s': %w", service, err)
+	}
+
+	return nil
+}
+
+// systemdDropIn generates the unit snippet loading the given credentials.
+func systemdDropIn(creds []systemdCredential) ([]byte, error) {
+	var sb strings.Builder
+	sb.WriteString("# Generated by harp, do not edit.\n")
+	sb.WriteString("[Service]\n")
+	for _, c := range creds {
+		id, err := escape.SystemdCredentialID(c.name)
+		if err != nil {
+			return nil, err
+		}
+		path, err := escape.SystemdUnitValue(c.path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to escape credential path '%s': %w", c.path, err)
+		}
+		fmt.Fprintf(&sb, "LoadCredential=%s:%s\n", id, path)
+	}
+	return []byte(sb.String()), nil
+}
+
+func writeFileAtomic(path string, mode os.FileMode, content []byte) error {
+	f, err := fsutil.CreateAtomic(path, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(content); err != nil {
+		_ = f.Abort()
+		return err
+	}
+	return f.Commit()
+}
+
+func credentialValue(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	default:
+		return json.Marshal(v)
+	}
+}
+
+func unitFileName(service string) string {
+	if strings.Contains(service, ".") {
+		return service
+	}
+	return fmt.Sprintf("%s.service", service)
+}
+
+// validateUnitName checks service names, they are used as credential
+// directory names and must not escape the output directory.
+func validateUnitName(service string) error {
+	if service == "" {
+		return fmt.Errorf("service name must not be blank")
+	}
+	for _, r := range service {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune(":-_.@", r)) {
+			return fmt.Errorf("service name '%s' contains invalid character %q", service, r)
+		}
+	}
+	// Apply credential file name rules, ':' is valid in unit names
+	if err := escape.ValidateSystemdCredentialName(strings.ReplaceAll(service, ":", "_")); err != nil {
+		return fmt.Errorf("invalid service name '%s': %w", service, err)
+	}
+	return nil
+}
+
+// ValidateCredentialName checks systemd credential name restrictions: a
+// valid file name, made of printable ASCII characters without ':'.
+func ValidateCredentialName(name string) error {
+	return escape.ValidateSystemdCredentialName(name)
+}
+
+// SanitizeCredentialName converts the given key to a valid credential name.
+// Characters other than letters, digits, '.', '-' and '_' are replaced by '_'.
+func SanitizeCredentialName(key string) string {
+	return escape.SystemdCredentialName(key)
+}
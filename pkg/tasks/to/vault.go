@@ -19,25 +19,81 @@ package to
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"sync"
 
 	"github.com/hashicorp/vault/api"
 
 	"github.com/elastic/harp/pkg/bundle"
 	bundlevault "github.com/elastic/harp/pkg/bundle/vault"
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
 	"github.com/elastic/harp/pkg/tasks"
+	"github.com/elastic/harp/pkg/vault/kv"
 )
 
 // VaultTask implements secret-container publication process to Vault.
 type VaultTask struct {
-	ContainerReader tasks.ReaderProvider
-	BackendPrefix   string
-	PushMetadata    bool
-	VaultNamespace  string
+	ContainerReader    tasks.ReaderProvider
+	MappingReader      tasks.ReaderProvider
+	BackendPrefix      string
+	PushMetadata       bool
+	VaultNamespace     string
+	IncludeQuarantined bool
+	// CheckAndSet refuses to overwrite KV v2 secrets modified since the
+	// publication started.
+	CheckAndSet bool
+	// CustomMetadataPrefixes lists the package annotation prefixes published
+	// as KV v2 custom metadata.
+	CustomMetadataPrefixes []string
+	// ExpiredPackagePolicy drops expired packages or refuses to publish them.
+	ExpiredPackagePolicy bundle.ExpiredPackagePolicy
+	// ValueDecryptor publishes externally encrypted values as cleartext.
+	ValueDecryptor bundle.ValueDecryptor
+	// DecryptFailurePolicy hides packages with undecryptable values or
+	// refuses to publish them.
+	DecryptFailurePolicy bundle.DecryptFailurePolicy
+	// FailureMode stops the publication at the first write failure when set
+	// to tasks.FailFast.
+	FailureMode tasks.FailureMode
+
+	mu     sync.Mutex
+	result *VaultResult
+}
+
+// VaultResult describes a Vault publication task execution. Failed secret
+// writes are reported as failures.
+type VaultResult struct {
+	tasks.Result
+	// Packages is the package count selected for publication.
+	Packages    int `json:"packages"`
+	Written     int `json:"written"`
+	Failed      int `json:"failed"`
+	Quarantined int `json:"quarantined"`
+	// Expired is the count of expired packages dropped at load time.
+	Expired int `json:"expired"`
+	// Hidden is the count of packages hidden for value decryption failure.
+	Hidden int `json:"hidden"`
+	// Drifted is the count of secrets modified in Vault since the
+	// publication started, they are also counted as failed.
+	Drifted int `json:"drifted"`
+}
+
+// Result returns the last execution result.
+func (t *VaultTask) Result() interface{} {
+	return t.result
+}
+
+// Capabilities returns the task required capabilities.
+func (t *VaultTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{Network: true}
 }
 
 // Run the task.
 func (t *VaultTask) Run(ctx context.Context) error {
+	t.result = &VaultResult{}
+
 	// Initialize vault connection
 	client, err := api.NewClient(api.DefaultConfig())
 	if err != nil {
@@ -50,25 +106,107 @@ func (t *VaultTask) Run(ctx context.Context) error {
 	}
 
 	// Create the reader
+	endLoad := cmdutil.TracePhase(ctx, "load")
 	reader, err := t.ContainerReader(ctx)
 	if err != nil {
 		return fmt.Errorf("unable to open input bundle reader: %w", err)
 	}
 
 	// Extract bundle from container
-	b, err := bundle.FromContainerReader(reader)
+	opts := []bundle.LoadOption{
+		bundle.WithLoadContext(ctx),
+		bundle.WithExpiredPackagePolicy(t.ExpiredPackagePolicy),
+		bundle.WithDroppedPackageCount(&t.result.Expired),
+	}
+	if t.ValueDecryptor != nil {
+		opts = append(opts,
+			bundle.WithValueDecryptor(t.ValueDecryptor),
+			bundle.WithDecryptFailurePolicy(t.DecryptFailurePolicy),
+			bundle.WithHiddenPackageCount(&t.result.Hidden),
+		)
+	}
+	b, err := bundle.FromContainerReader(reader, opts...)
 	if err != nil {
 		return fmt.Errorf("unable to load bundle: %w", err)
 	}
 
+	// Archived packages are never exported
+	b = bundle.WithoutArchived(b)
+
+	// Skip quarantined packages
+	count := len(b.Packages)
+	b = skipQuarantined(ctx, b, t.IncludeQuarantined)
+	t.result.Quarantined = count - len(b.Packages)
+	t.result.Packages = len(b.Packages)
+
+	// Apply path mapping
+	m, err := loadMapping(ctx, t.MappingReader)
+	if err != nil {
+		return err
+	}
+	if m != nil {
+		if err := m.Apply(b); err != nil {
+			return fmt.Errorf("unable to map package paths: %w", err)
+		}
+	}
+	endLoad()
+
 	// Process push operation
-	if err := bundlevault.Push(ctx, b, client,
+	endPush := cmdutil.TracePhase(ctx, "push")
+	err = bundlevault.Push(ctx, b, client,
 		bundlevault.WithPrefix(t.BackendPrefix),
 		bundlevault.WithMetadata(t.PushMetadata),
-	); err != nil {
-		return fmt.Errorf("error occurs during vault export (prefix: '%s'): %w", t.BackendPrefix, err)
+		bundlevault.WithWriteObserver(t.observe),
+		bundlevault.WithCheckAndSet(t.CheckAndSet),
+		bundlevault.WithCustomMetadata(t.CustomMetadataPrefixes...),
+		bundlevault.WithKeepGoing(t.FailureMode == tasks.KeepGoing),
+	)
+	endPush()
+
+	// Secrets not written after a failure are skipped
+	written := map[string]struct{}{}
+	for _, i := range t.result.Items {
+		written[i.Item] = struct{}{}
+	}
+	for _, p := range b.Packages {
+		secretPath := p.Name
+		if t.BackendPrefix != "" {
+			secretPath = fmt.Sprintf("%s/%s", t.BackendPrefix, p.Name)
+		}
+		if _, ok := written[secretPath]; !ok && p.Secrets != nil {
+			t.result.Skip(secretPath)
+		}
+	}
+
+	// Writes are concurrent, sort failures for stable reports
+	sort.Slice(t.result.Failures, func(i, j int) bool {
+		return t.result.Failures[i].Item < t.result.Failures[j].Item
+	})
+	sort.Slice(t.result.Items, func(i, j int) bool {
+		return t.result.Items[i].Item < t.result.Items[j].Item
+	})
+	if err != nil {
+		return t.result.ItemsError(fmt.Errorf("error occurs during vault export (prefix: '%s'): %w", t.BackendPrefix, err))
 	}
 
 	// No error
 	return nil
 }
+
+// -----------------------------------------------------------------------------
+
+func (t *VaultTask) observe(secretPath string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err != nil {
+		if errors.Is(err, kv.ErrCASMismatch) {
+			t.result.Drifted++
+		}
+		t.result.Failed++
+		t.result.FailItem(secretPath, err)
+		return
+	}
+	t.result.Written++
+	t.result.Succeed(secretPath)
+}
@@ -0,0 +1,141 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package to
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/sdk/log"
+	"github.com/elastic/harp/pkg/sdk/types"
+	"github.com/elastic/harp/pkg/tasks"
+	"github.com/elastic/harp/pkg/vault/policy"
+)
+
+// VaultPolicyTask implements Vault policy generation from package ownership.
+type VaultPolicyTask struct {
+	ContainerReader    tasks.ReaderProvider
+	OutputPath         string
+	Mount              string
+	KVVersion          int
+	WithList           bool
+	IncludeQuarantined bool
+
+	result *VaultPolicyResult
+}
+
+// VaultPolicyResult describes a Vault policy generation task execution.
+type VaultPolicyResult struct {
+	tasks.Result
+	Policies []VaultPolicySummary `json:"policies"`
+	// Unowned lists package paths not readable through any generated policy.
+	Unowned []string `json:"unowned"`
+}
+
+// VaultPolicySummary describes a generated policy file.
+type VaultPolicySummary struct {
+	Name  string `json:"name"`
+	File  string `json:"file"`
+	Rules int    `json:"rules"`
+}
+
+// Result returns the last execution result.
+func (t *VaultPolicyTask) Result() interface{} {
+	return t.result
+}
+
+// Capabilities returns the task required capabilities.
+func (t *VaultPolicyTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Run the task.
+func (t *VaultPolicyTask) Run(ctx context.Context) error {
+	t.result = &VaultPolicyResult{}
+
+	// Check arguments
+	if types.IsNil(t.ContainerReader) {
+		return errors.New("unable to run task with a nil containerReader provider")
+	}
+	if t.OutputPath == "" {
+		return errors.New("unable to run task with a blank output path")
+	}
+
+	// Create the reader
+	reader, err := t.ContainerReader(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open input bundle reader: %w", err)
+	}
+
+	// Extract bundle from container
+	b, err := bundle.FromContainerReader(reader)
+	if err != nil {
+		return fmt.Errorf("unable to load bundle: %w", err)
+	}
+
+	// Resolve inherited ownership annotations
+	annotations, err := bundle.InheritedAnnotations(b)
+	if err != nil {
+		return fmt.Errorf("unable to resolve inherited annotations: %w", err)
+	}
+
+	// Only published packages are granted
+	b = skipQuarantined(ctx, bundle.WithoutArchived(b), t.IncludeQuarantined)
+
+	// Generate policies
+	gen, err := policy.Generate(b.Packages, policy.GenerateOptions{
+		Mount:       t.Mount,
+		KVVersion:   t.KVVersion,
+		WithList:    t.WithList,
+		Annotations: annotations,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to generate policies: %w", err)
+	}
+
+	// Write policies
+	if err := os.MkdirAll(t.OutputPath, 0o755); err != nil {
+		return fmt.Errorf("unable to create output directory: %w", err)
+	}
+	for _, p := range gen.Policies {
+		if err := ioutil.WriteFile(filepath.Join(t.OutputPath, p.FileName()), p.HCL(), 0o644); err != nil {
+			return fmt.Errorf("unable to write policy '%s': %w", p.Name, err)
+		}
+		t.result.Policies = append(t.result.Policies, VaultPolicySummary{
+			Name:  p.Name,
+			File:  p.FileName(),
+			Rules: len(p.Paths),
+		})
+	}
+
+	// Report unowned packages
+	t.result.Unowned = gen.Unowned
+	for _, path := range gen.Unowned {
+		log.For(ctx).Warn("Package not granted by any policy, no owner nor ACL", zap.String("path", path))
+	}
+
+	// No error
+	return nil
+}
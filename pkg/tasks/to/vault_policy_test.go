@@ -0,0 +1,84 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package to
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+)
+
+func TestVaultPolicyTask(t *testing.T) {
+	b := testbundle.New()
+	b.Package("app/production/billing/database").Annotation(bundle.OwnerAnnotation, "billing").Secret("user", "foo")
+	b.Package("app/production/billing/recurly").Annotation(bundle.OwnerAnnotation, "billing").Secret("key", "bar")
+	b.Package("app/production/billing/legacy").Annotation(bundle.ArchivedAnnotation, "true").Secret("key", "baz")
+	b.Package("app/production/search/elasticsearch").Annotation(bundle.OwnerAnnotation, "team/search").Secret("password", "qux")
+	b.Package("infra/dns").Secret("token", "quux")
+
+	out := t.TempDir()
+	task := &VaultPolicyTask{
+		ContainerReader: testbundle.Reader(t, b.Build()),
+		OutputPath:      out,
+		Mount:           "secret",
+	}
+	if err := task.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	res, ok := task.Result().(*VaultPolicyResult)
+	if !ok {
+		t.Fatalf("unexpected result %T", task.Result())
+	}
+	if !reflect.DeepEqual(res.Policies, []VaultPolicySummary{
+		{Name: "billing", File: "billing.hcl", Rules: 1},
+		{Name: "team/search", File: "team-search.hcl", Rules: 1},
+	}) {
+		t.Errorf("unexpected policies %+v", res.Policies)
+	}
+	if !reflect.DeepEqual(res.Unowned, []string{"infra/dns"}) {
+		t.Errorf("unexpected unowned paths %v", res.Unowned)
+	}
+
+	// Archived packages are not foreign, the folder is collapsed
+	got, err := ioutil.ReadFile(filepath.Join(out, "billing.hcl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# Policy "billing" generated from bundle ownership annotations.
+
+path "secret/data/app/production/billing/*" {
+  capabilities = ["read"]
+}
+`
+	if string(got) != want {
+		t.Errorf("unexpected policy, got:\n%s", got)
+	}
+
+	// Mount is required
+	task.Mount = ""
+	task.ContainerReader = testbundle.Reader(t, b.Build())
+	if err := task.Run(context.Background()); err == nil {
+		t.Error("error should be raised for blank mount")
+	}
+}
@@ -0,0 +1,134 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package to
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+	"github.com/elastic/harp/pkg/sdk/cmdutil"
+	"github.com/elastic/harp/pkg/tasks"
+)
+
+// vaultServer emulates a KV v2 backend mounted on 'secret/', writes to paths
+// containing 'broken' are rejected.
+func vaultServer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/v1/sys/internal/ui/mounts/"):
+			fmt.Fprint(w, `{"data":{"type":"kv","path":"secret/","options":{"version":"2"}}}`)
+		case strings.HasPrefix(r.URL.Path, "/v1/secret/data/") && !strings.Contains(r.URL.Path, "broken"):
+			fmt.Fprint(w, `{"data":{"version":1}}`)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"errors":["permission denied"]}`)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	previous, ok := os.LookupEnv("VAULT_ADDR")
+	os.Setenv("VAULT_ADDR", srv.URL)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv("VAULT_ADDR", previous)
+			return
+		}
+		os.Unsetenv("VAULT_ADDR")
+	})
+}
+
+func TestVaultTask_FailureMode(t *testing.T) {
+	vaultServer(t)
+
+	testCases := []struct {
+		desc     string
+		packages []string
+		mode     tasks.FailureMode
+		wantCode int
+	}{
+		{
+			desc:     "all written",
+			packages: []string{"app/production/billing/database", "app/production/billing/stripe"},
+			wantCode: cmdutil.ExitOK,
+		},
+		{
+			desc:     "partial failure",
+			packages: []string{"app/production/billing/database", "app/production/billing/broken", "app/production/billing/stripe"},
+			wantCode: cmdutil.ExitPartialFailure,
+		},
+		{
+			desc:     "total failure",
+			packages: []string{"app/production/billing/broken", "app/production/search/broken"},
+			wantCode: cmdutil.ExitFailure,
+		},
+		{
+			desc:     "fail fast",
+			packages: []string{"app/production/billing/broken"},
+			mode:     tasks.FailFast,
+			wantCode: cmdutil.ExitFailure,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			b := testbundle.New()
+			for _, p := range tC.packages {
+				b.Package(p).Secret("key", "value")
+			}
+
+			task := &VaultTask{
+				ContainerReader: testbundle.Reader(t, b.Build()),
+				BackendPrefix:   "secret",
+				FailureMode:     tC.mode,
+			}
+			err := task.Run(context.Background())
+			if got := cmdutil.ExitCode(err); got != tC.wantCode {
+				t.Fatalf("expected exit code %d, got %d (%v)", tC.wantCode, got, err)
+			}
+
+			// All items have a status
+			res, ok := task.Result().(*VaultResult)
+			if !ok {
+				t.Fatalf("unexpected result %T", task.Result())
+			}
+			if len(res.Items) != len(tC.packages) {
+				t.Fatalf("expected %d item statuses, got %+v", len(tC.packages), res.Items)
+			}
+			for _, i := range res.Items {
+				wantStatus := tasks.StatusSucceeded
+				if strings.Contains(i.Item, "broken") {
+					wantStatus = tasks.StatusFailed
+				}
+				if i.Status != wantStatus {
+					t.Errorf("expected %s status for %s, got %s", wantStatus, i.Item, i.Status)
+				}
+			}
+
+			var itemsErr *tasks.ItemsError
+			if tC.wantCode != cmdutil.ExitOK && !errors.As(err, &itemsErr) {
+				t.Fatalf("expected item failures, got %v", err)
+			}
+		})
+	}
+}
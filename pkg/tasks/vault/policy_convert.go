@@ -0,0 +1,119 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package vault
+
+import (
+	"context"
+	"fmt"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/sdk/types"
+	"github.com/elastic/harp/pkg/tasks"
+	"github.com/elastic/harp/pkg/vault/policy"
+)
+
+// PolicyConvertTask implements Vault policies to harp ACL conversion task.
+type PolicyConvertTask struct {
+	PolicyDir       string
+	Mount           string
+	KVVersion       int
+	OutputWriter    tasks.WriterProvider
+	ContainerReader tasks.ReaderProvider
+	BundleWriter    tasks.WriterProvider
+}
+
+// Capabilities returns the task required capabilities.
+func (t *PolicyConvertTask) Capabilities() tasks.Capabilities {
+	return tasks.Capabilities{}
+}
+
+// Run the task.
+func (t *PolicyConvertTask) Run(ctx context.Context) error {
+	// Check arguments
+	if types.IsNil(t.OutputWriter) {
+		return fmt.Errorf("unable to run task with a nil outputWriter provider")
+	}
+	if (t.ContainerReader == nil) != (t.BundleWriter == nil) {
+		return fmt.Errorf("unable to run task: bundle input and output must be set together")
+	}
+
+	// Load policies
+	policies, err := policy.LoadDir(t.PolicyDir)
+	if err != nil {
+		return fmt.Errorf("unable to load policies: %w", err)
+	}
+
+	// Convert policies
+	doc, err := policy.Convert(policies, policy.Options{
+		Mount:     t.Mount,
+		KVVersion: t.KVVersion,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to convert policies: %w", err)
+	}
+
+	// Encode result
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("unable to encode converted policies: %w", err)
+	}
+
+	// Create output writer
+	writer, err := t.OutputWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open writer: %w", err)
+	}
+	if _, err := writer.Write(out); err != nil {
+		return fmt.Errorf("unable to write converted policies: %w", err)
+	}
+
+	// Apply to bundle
+	if t.ContainerReader == nil {
+		return nil
+	}
+
+	reader, err := t.ContainerReader(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open input bundle: %w", err)
+	}
+
+	// Load bundle
+	b, err := bundle.FromContainerReader(reader)
+	if err != nil {
+		return fmt.Errorf("unable to load bundle content: %w", err)
+	}
+
+	if err := policy.Apply(b, doc); err != nil {
+		return fmt.Errorf("unable to apply converted policies: %w", err)
+	}
+
+	bundleWriter, err := t.BundleWriter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open bundle writer: %w", err)
+	}
+
+	// Dump all content
+	if err := bundle.ToContainerWriter(bundleWriter, b); err != nil {
+		return fmt.Errorf("unable to dump bundle content: %w", err)
+	}
+
+	// No error
+	return nil
+}
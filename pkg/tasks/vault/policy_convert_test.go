@@ -0,0 +1,93 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package vault
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/refs"
+	"github.com/elastic/harp/pkg/bundle/testbundle"
+)
+
+func TestPolicyConvertTask(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "reader.hcl"), []byte(`
+path "secret/data/app/*" { capabilities = ["read"] }
+path "secret/data/app/+/+/+/+/+/root" { capabilities = ["deny"] }
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	input := &bundlev1.Bundle{
+		Packages: []*bundlev1.Package{
+			{Name: "app/production/security/harp/v1.0.0/server/web", Secrets: &bundlev1.SecretChain{}},
+			{Name: "app/production/security/harp/v1.0.0/server/root", Secrets: &bundlev1.SecretChain{}},
+		},
+	}
+
+	// Bundle input without output
+	if err := (&PolicyConvertTask{
+		PolicyDir:       dir,
+		Mount:           "secret/",
+		OutputWriter:    testbundle.Writer(&bytes.Buffer{}),
+		ContainerReader: testbundle.Reader(t, input),
+	}).Run(context.Background()); err == nil {
+		t.Fatal("error should be raised for missing bundle output")
+	}
+
+	var out, bundleOut bytes.Buffer
+	task := &PolicyConvertTask{
+		PolicyDir:       dir,
+		Mount:           "secret/",
+		OutputWriter:    testbundle.Writer(&out),
+		ContainerReader: testbundle.Reader(t, input),
+		BundleWriter:    testbundle.Writer(&bundleOut),
+	}
+	if err := task.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.Contains(out.String(), "kind: ACLPolicies") || !strings.Contains(out.String(), "deny overrides") {
+		t.Errorf("unexpected conversion output:\n%s", out.String())
+	}
+
+	b, err := bundle.FromContainerReader(&bundleOut)
+	if err != nil {
+		t.Fatalf("unable to load output bundle: %v", err)
+	}
+	if len(b.Packages) != 3 {
+		t.Fatalf("unexpected packages count %d", len(b.Packages))
+	}
+	acls := map[string]string{}
+	for _, p := range b.Packages {
+		acls[p.Name] = p.Annotations[refs.ACLAnnotation]
+	}
+	if got := acls["app/production/security/harp/v1.0.0/server/web"]; got != "meta/acl/reader" {
+		t.Errorf("unexpected web acl %q", got)
+	}
+	if got := acls["app/production/security/harp/v1.0.0/server/root"]; got != "" {
+		t.Errorf("denied package must not reference the policy, got %q", got)
+	}
+}
@@ -0,0 +1,236 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package analysis provides static inspection of template sources without
+// executing them.
+package analysis
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/elastic/harp/pkg/template/engine"
+)
+
+// Call describes a template function invocation.
+type Call struct {
+	Name     string   `json:"name"`
+	Args     []string `json:"args,omitempty"`
+	Location string   `json:"location"`
+}
+
+// Action describes a template action with the JSON object key it renders,
+// if any.
+type Action struct {
+	Key      string   `json:"key,omitempty"`
+	Location string   `json:"location"`
+	Calls    []Call   `json:"calls,omitempty"`
+	Values   []string `json:"values,omitempty"`
+}
+
+// Report holds the inspection result.
+type Report struct {
+	Actions []Action `json:"actions"`
+}
+
+// Inspect parses the given template source and walks its AST, including
+// invoked named templates, to collect function calls and value references.
+func Inspect(templateContext engine.Context, input string) (*Report, error) {
+	// Retrieve delimiters
+	leftDelim, rightDelim := templateContext.Delims()
+
+	// Parse the template
+	t, err := template.New(templateContext.Name()).
+		Delims(leftDelim, rightDelim).
+		Funcs(engine.FuncMap(nil)).
+		Parse(input)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse template: %w", err)
+	}
+
+	// Walk the tree
+	w := &walker{
+		set:      t,
+		report:   &Report{Actions: []Action{}},
+		visiting: map[string]bool{t.Name(): true},
+	}
+	if t.Tree != nil {
+		w.walk(t.Tree, t.Tree.Root)
+	}
+
+	// No error
+	return w.report, nil
+}
+
+// Generators returns generator function calls.
+func (r *Report) Generators() []Call {
+	res := []Call{}
+	for _, a := range r.Actions {
+		for _, c := range a.Calls {
+			if engine.IsGenerator(c.Name) {
+				res = append(res, c)
+			}
+		}
+	}
+	return res
+}
+
+// Values returns the sorted unique value references.
+func (r *Report) Values() []string {
+	seen := map[string]struct{}{}
+	res := []string{}
+	for _, a := range r.Actions {
+		for _, v := range a.Values {
+			if _, ok := seen[v]; ok {
+				continue
+			}
+			seen[v] = struct{}{}
+			res = append(res, v)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// ForKey returns actions rendering the given JSON object key.
+func (r *Report) ForKey(key string) []Action {
+	res := []Action{}
+	for _, a := range r.Actions {
+		if a.Key == key {
+			res = append(res, a)
+		}
+	}
+	return res
+}
+
+// -----------------------------------------------------------------------------
+
+// keyPattern matches a JSON object key opened just before an action.
+var keyPattern = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"\s*:\s*(?:"[^"]*)?$`)
+
+type walker struct {
+	set      *template.Template
+	report   *Report
+	key      string
+	visiting map[string]bool
+}
+
+func (w *walker) walk(tree *parse.Tree, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, item := range n.Nodes {
+			w.walk(tree, item)
+		}
+	case *parse.TextNode:
+		text := string(n.Text)
+		if m := keyPattern.FindStringSubmatch(text); m != nil {
+			w.key = m[1]
+		} else if strings.ContainsAny(text, ",{}") {
+			w.key = ""
+		}
+	case *parse.ActionNode:
+		w.pipe(tree, n, n.Pipe)
+	case *parse.IfNode:
+		w.branch(tree, n, &n.BranchNode)
+	case *parse.RangeNode:
+		w.branch(tree, n, &n.BranchNode)
+	case *parse.WithNode:
+		w.branch(tree, n, &n.BranchNode)
+	case *parse.TemplateNode:
+		w.pipe(tree, n, n.Pipe)
+
+		// Follow named template once per call chain
+		if w.visiting[n.Name] {
+			return
+		}
+		if tmpl := w.set.Lookup(n.Name); tmpl != nil && tmpl.Tree != nil {
+			w.visiting[n.Name] = true
+			w.walk(tmpl.Tree, tmpl.Tree.Root)
+			delete(w.visiting, n.Name)
+		}
+	default:
+	}
+}
+
+func (w *walker) branch(tree *parse.Tree, node parse.Node, n *parse.BranchNode) {
+	w.pipe(tree, node, n.Pipe)
+	w.walk(tree, n.List)
+	w.walk(tree, n.ElseList)
+}
+
+func (w *walker) pipe(tree *parse.Tree, node parse.Node, p *parse.PipeNode) {
+	if p == nil {
+		return
+	}
+
+	location, _ := tree.ErrorContext(node)
+	a := Action{
+		Key:      w.key,
+		Location: location,
+	}
+	w.collect(tree, &a, p)
+
+	// Keep only meaningful actions
+	if len(a.Calls) > 0 || len(a.Values) > 0 {
+		w.report.Actions = append(w.report.Actions, a)
+	}
+}
+
+func (w *walker) collect(tree *parse.Tree, a *Action, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			w.collect(tree, a, cmd)
+		}
+	case *parse.CommandNode:
+		if len(n.Args) == 0 {
+			return
+		}
+		if id, ok := n.Args[0].(*parse.IdentifierNode); ok {
+			location, _ := tree.ErrorContext(n)
+			c := Call{Name: id.Ident, Location: location}
+			for _, arg := range n.Args[1:] {
+				c.Args = append(c.Args, arg.String())
+			}
+			a.Calls = append(a.Calls, c)
+		}
+		for _, arg := range n.Args {
+			w.collect(tree, a, arg)
+		}
+	case *parse.ChainNode:
+		w.collect(tree, a, n.Node)
+	case *parse.FieldNode:
+		if len(n.Ident) > 0 && n.Ident[0] == "Values" {
+			a.Values = append(a.Values, "."+strings.Join(n.Ident, "."))
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" && n.Ident[1] == "Values" {
+			a.Values = append(a.Values, "."+strings.Join(n.Ident[1:], "."))
+		}
+	default:
+	}
+}
@@ -0,0 +1,118 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package analysis
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/elastic/harp/pkg/template/engine"
+)
+
+func TestInspect(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		input          string
+		wantErr        bool
+		wantGenerators []string
+		wantValues     []string
+		wantKeys       map[string][]string
+	}{
+		{
+			desc:    "invalid syntax",
+			input:   `{{ .Values.user `,
+			wantErr: true,
+		},
+		{
+			desc:    "unknown function",
+			input:   `{{ unknownFunc }}`,
+			wantErr: true,
+		},
+		{
+			desc:           "literal",
+			input:          `{"user": "admin"}`,
+			wantGenerators: []string{},
+			wantValues:     []string{},
+			wantKeys:       map[string][]string{},
+		},
+		{
+			desc: "json object",
+			input: `{
+  "user": "{{ .Values.user | lower }}",
+  "password": "{{ paranoidPassword | b64enc }}",
+  "key": {{ $k := cryptoPair "rsa" }}{{ $k.Private | toJwk | toJson }}
+}`,
+			wantGenerators: []string{"paranoidPassword", "cryptoPair"},
+			wantValues:     []string{".Values.user"},
+			wantKeys: map[string][]string{
+				"user":     {"lower"},
+				"password": {"paranoidPassword", "b64enc"},
+				"key":      {"cryptoPair", "toJwk", "toJson"},
+			},
+		},
+		{
+			desc: "loops and named templates",
+			input: `{{- define "pass" }}{{ strongPassword }}{{ end -}}
+{
+  {{- range $i, $n := $.Values.replicas }}
+  "{{ $n }}": "{{ randAlphaNum 8 }}",
+  {{- end }}
+  "admin": "{{ template "pass" }}"
+}`,
+			wantGenerators: []string{"randAlphaNum", "strongPassword"},
+			wantValues:     []string{".Values.replicas"},
+			wantKeys: map[string][]string{
+				"admin": {"strongPassword"},
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			got, err := Inspect(engine.NewContext(), tC.input)
+			if (err != nil) != tC.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tC.wantErr)
+			}
+			if tC.wantErr {
+				return
+			}
+
+			generators := []string{}
+			for _, c := range got.Generators() {
+				generators = append(generators, c.Name)
+			}
+			if diff := cmp.Diff(generators, tC.wantGenerators); diff != "" {
+				t.Errorf("%q. Generators():\n-got/+want\ndiff %s", tC.desc, diff)
+			}
+			if diff := cmp.Diff(got.Values(), tC.wantValues); diff != "" {
+				t.Errorf("%q. Values():\n-got/+want\ndiff %s", tC.desc, diff)
+			}
+			for key, want := range tC.wantKeys {
+				calls := []string{}
+				for _, a := range got.ForKey(key) {
+					for _, c := range a.Calls {
+						calls = append(calls, c.Name)
+					}
+				}
+				if diff := cmp.Diff(calls, want); diff != "" {
+					t.Errorf("%q. ForKey(%q):\n-got/+want\ndiff %s", tC.desc, key, diff)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package consumption records the existing secret values read while
+// rendering a template. Values are identified by their digest only, the
+// resulting manifest never holds secret values.
+package consumption
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+const (
+	// SourceVault identifies values read from Vault.
+	SourceVault = "vault"
+	// SourceReference identifies values resolved from package references.
+	SourceReference = "reference"
+)
+
+// ContainerSource returns the source name of values read from the given
+// secret container.
+func ContainerSource(name string) string {
+	return fmt.Sprintf("container:%s", name)
+}
+
+// Record describes a consumed secret value.
+type Record struct {
+	Source string `json:"source"`
+	Path   string `json:"path"`
+	Key    string `json:"key"`
+	Digest string `json:"digest"`
+}
+
+// Manifest holds all secret values consumed by a render.
+type Manifest struct {
+	Records []Record `json:"records"`
+}
+
+// WriteJSON writes the manifest as JSON.
+func (m *Manifest) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
+// ReadManifest decodes a JSON manifest.
+func ReadManifest(r io.Reader) (*Manifest, error) {
+	m := &Manifest{}
+	if err := json.NewDecoder(r).Decode(m); err != nil {
+		return nil, fmt.Errorf("unable to decode consumption manifest: %w", err)
+	}
+
+	// No error
+	return m, nil
+}
+
+// Digest returns the digest of the given secret value. Textual and binary
+// values are hashed as is, other values are hashed using their JSON
+// encoding.
+func Digest(value interface{}) (string, error) {
+	var payload []byte
+	switch v := value.(type) {
+	case string:
+		payload = []byte(v)
+	case []byte:
+		payload = v
+	default:
+		// Map keys are sorted by the encoder
+		var err error
+		payload, err = json.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("unable to encode %T value: %w", value, err)
+		}
+	}
+	digest := sha256.Sum256(payload)
+
+	return fmt.Sprintf("sha256:%s", hex.EncodeToString(digest[:])), nil
+}
+
+// Tracker collects consumed secret values.
+type Tracker struct {
+	mu      sync.Mutex
+	records map[Record]struct{}
+}
+
+// Track records the consumption of the given secret value.
+func (t *Tracker) Track(source, path, key string, value interface{}) error {
+	digest, err := Digest(value)
+	if err != nil {
+		return fmt.Errorf("unable to compute '%s' digest of '%s': %w", key, path, err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.records == nil {
+		t.records = map[Record]struct{}{}
+	}
+	t.records[Record{
+		Source: source,
+		Path:   path,
+		Key:    key,
+		Digest: digest,
+	}] = struct{}{}
+
+	// No error
+	return nil
+}
+
+// TrackAll records the consumption of all values of the given secret map.
+func (t *Tracker) TrackAll(source, path string, secrets map[string]interface{}) error {
+	for k, v := range secrets {
+		if err := t.Track(source, path, k, v); err != nil {
+			return err
+		}
+	}
+
+	// No error
+	return nil
+}
+
+// Manifest returns recorded values sorted by source, path and key.
+func (t *Tracker) Manifest() *Manifest {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	res := &Manifest{
+		Records: make([]Record, 0, len(t.records)),
+	}
+	for r := range t.records {
+		res.Records = append(res.Records, r)
+	}
+	sort.Slice(res.Records, func(i, j int) bool {
+		a, b := res.Records[i], res.Records[j]
+		switch {
+		case a.Source != b.Source:
+			return a.Source < b.Source
+		case a.Path != b.Path:
+			return a.Path < b.Path
+		case a.Key != b.Key:
+			return a.Key < b.Key
+		default:
+			return a.Digest < b.Digest
+		}
+	})
+
+	return res
+}
+
+// -----------------------------------------------------------------------------
+
+type contextKey string
+
+const trackerKey = contextKey("tracker")
+
+// WithTracker returns a context recording consumed secret values with the
+// given tracker.
+func WithTracker(ctx context.Context, t *Tracker) context.Context {
+	return context.WithValue(ctx, trackerKey, t)
+}
+
+// TrackerFrom returns the context consumption tracker if any.
+func TrackerFrom(ctx context.Context) *Tracker {
+	t, _ := ctx.Value(trackerKey).(*Tracker)
+	return t
+}
@@ -0,0 +1,134 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package consumption
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDigest(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		value interface{}
+		want  string
+	}{
+		{
+			desc:  "string",
+			value: "value",
+			want:  "sha256:cd42404d52ad55ccfa9aca4adc828aa5800ad9d385a0671fbcbf724118320619",
+		},
+		{
+			desc:  "bytes",
+			value: []byte("value"),
+			want:  "sha256:cd42404d52ad55ccfa9aca4adc828aa5800ad9d385a0671fbcbf724118320619",
+		},
+		{
+			desc:  "map",
+			value: map[string]interface{}{"b": 2, "a": 1},
+			// sha256(`{"a":1,"b":2}`)
+			want: "sha256:43258cff783fe7036d8a43033f830adfc60ec037382473548ac742b888292777",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			got, err := Digest(tC.value)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tC.want {
+				t.Errorf("expected '%s', got '%s'", tC.want, got)
+			}
+		})
+	}
+
+	if _, err := Digest(func() {}); err == nil {
+		t.Error("expected error for unsupported value")
+	}
+}
+
+func TestTracker(t *testing.T) {
+	tracker := &Tracker{}
+
+	// Concurrent tracking, duplicates are recorded once
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := tracker.TrackAll(SourceVault, "app/production/db", map[string]interface{}{
+				"user":     "admin",
+				"password": "secret",
+			}); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := tracker.Track(ContainerSource("secrets.bundle"), "app/production/db", "user", "admin"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	m := tracker.Manifest()
+	got := []string{}
+	for _, r := range m.Records {
+		got = append(got, r.Source+"|"+r.Path+"|"+r.Key)
+	}
+	want := []string{
+		"container:secrets.bundle|app/production/db|user",
+		"vault|app/production/db|password",
+		"vault|app/production/db|user",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected records (-want +got):\n%s", diff)
+	}
+
+	// Values are never written
+	var buf bytes.Buffer
+	if err := m.WriteJSON(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(buf.String(), "secret\"") || strings.Contains(buf.String(), "admin") {
+		t.Errorf("manifest leaks secret values: %s", buf.String())
+	}
+
+	// Roundtrip
+	decoded, err := ReadManifest(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(m, decoded); diff != "" {
+		t.Errorf("unexpected manifest (-want +got):\n%s", diff)
+	}
+}
+
+func TestTrackerFrom(t *testing.T) {
+	if TrackerFrom(context.Background()) != nil {
+		t.Error("expected no tracker")
+	}
+
+	tracker := &Tracker{}
+	if TrackerFrom(WithTracker(context.Background(), tracker)) != tracker {
+		t.Error("expected the context tracker")
+	}
+}
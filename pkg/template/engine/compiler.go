@@ -18,9 +18,10 @@
 package engine
 
 import (
-	"bytes"
 	"fmt"
 	"text/template"
+
+	"github.com/elastic/harp/pkg/sdk/security"
 )
 
 // -----------------------------------------------------------------------------
@@ -35,19 +36,32 @@ func Render(input string, data interface{}) (content string, err error) {
 	}()
 
 	// Prepare the template
-	t, err := template.New("root").
-		Funcs(FuncMap(nil)).
+	g := newGuard("root", DefaultLimits())
+	t := template.New("root")
+	t, err = t.Funcs(FuncMap(nil)).
+		Funcs(g.funcs()).
+		Funcs(template.FuncMap{
+			includeName: include(t, g),
+		}).
 		Parse(input)
 	if err != nil {
 		return "", fmt.Errorf("unable to compile attribute template '%s': %w", input, err)
 	}
 
+	// Inject resource limit checkpoints
+	instrument(t)
+
 	// Fail on missing key
 	t.Option("missingkey=error")
 
 	// Merge with values
-	var out bytes.Buffer
-	if err := t.Execute(&out, data); err != nil {
+	var out security.SensitiveBuffer
+	defer out.Destroy()
+	if err := t.Execute(g.writer(&out), data); err != nil {
+		// Report resource limit violation as is
+		if g.err != nil {
+			err = g.err
+		}
 		return "", fmt.Errorf("unable to merge data with template '%s': %w", input, err)
 	}
 
@@ -72,19 +86,42 @@ func RenderContextWithData(templateContext Context, input string, data interface
 	// Retrieve delimiters
 	leftDelim, rightDelim := templateContext.Delims()
 
+	// Prepare functions
+	funcs := FuncMap(contextSecretReaders(templateContext))
+	funcs["generate"] = generate(generatorContext(templateContext))
+	funcs["remoteJWKS"] = remoteJWKS(templateContext.Network(), templateContext.ProvenanceRecorder())
+
 	// Prepare the template
-	t, err := template.New(templateContext.Name()).
-		Delims(leftDelim, rightDelim).
-		Funcs(FuncMap(templateContext.SecretReaders())).
+	g := newGuard(templateContext.Name(), templateContext.Limits())
+	t := template.New(templateContext.Name())
+	t, err = t.Delims(leftDelim, rightDelim).
+		Funcs(funcs).
+		Funcs(g.funcs()).
+		Funcs(template.FuncMap{
+			includeName: include(t, g),
+		}).
 		Parse(input)
 	if err != nil {
 		return "", fmt.Errorf("unable to compile attribute template '%s': %w", input, err)
 	}
 
+	// Inject resource limit checkpoints
+	instrument(t)
+
+	// Replace value generators by placeholders
+	if dr := templateContext.DryRun(); dr != nil {
+		for name, fn := range dr.funcs() {
+			funcs[name] = fn
+		}
+		t.Funcs(funcs)
+	}
+
 	// Check strict mode
 	if templateContext.StrictMode() {
 		// Fail on missing key
 		t.Option("missingkey=error")
+		// Fail on nil value generator arguments
+		t.Funcs(strictFuncs(funcs))
 	} else {
 		// Not that zero will attempt to add default values for types it knows,
 		// but will still emit <no value> for others. We mitigate that later.
@@ -92,15 +129,27 @@ func RenderContextWithData(templateContext Context, input string, data interface
 	}
 
 	// Merge with values
-	var out bytes.Buffer
-	if err := t.Execute(&out, map[string]interface{}{
+	var out security.SensitiveBuffer
+	defer out.Destroy()
+	if err := t.Execute(g.writer(&out), map[string]interface{}{
 		"Data":   data,
 		"Values": templateContext.Values(),
 		"Files":  templateContext.Files(),
 	}); err != nil {
+		// Report resource limit violation as is
+		if g.err != nil {
+			err = g.err
+		}
 		return "", fmt.Errorf("unable to merge values with template '%s': %w", input, err)
 	}
 
+	// Detect missing values not reported by the template engine
+	if templateContext.StrictMode() {
+		if err := checkNoValue(templateContext.Name(), out.String()); err != nil {
+			return "", err
+		}
+	}
+
 	// No error
 	return out.String(), nil
 }
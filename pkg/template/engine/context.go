@@ -17,14 +17,29 @@
 
 package engine
 
+import (
+	"fmt"
+	"io"
+
+	"github.com/elastic/harp/pkg/template/consumption"
+	"github.com/elastic/harp/pkg/template/generators"
+)
+
 // Context describes engine rendering context contract.
 type Context interface {
 	Name() string
 	StrictMode() bool
 	Delims() (string, string)
 	SecretReaders() []SecretReaderFunc
+	SecretSources() []string
 	Values() Values
 	Files() Files
+	Entropy() io.Reader
+	ProvenanceRecorder() *generators.Recorder
+	ConsumptionTracker() *consumption.Tracker
+	DryRun() *DryRun
+	Limits() Limits
+	Network() Network
 }
 
 // -----------------------------------------------------------------------------
@@ -60,10 +75,21 @@ func WithSecretReaders(values ...SecretReaderFunc) ContextOption {
 	return func(ctx *context) {
 		if len(values) > 0 {
 			ctx.secretReaders = values
+			ctx.secretSources = nil
 		}
 	}
 }
 
+// WithSecretSource appends a secret resolver function used by `secret`
+// template function. The source name identifies consumed values in the
+// consumption manifest.
+func WithSecretSource(source string, reader SecretReaderFunc) ContextOption {
+	return func(ctx *context) {
+		ctx.secretSources = append(ctx.SecretSources(), source)
+		ctx.secretReaders = append(ctx.secretReaders, reader)
+	}
+}
+
 // WithValues defines template values injected via CLI.
 func WithValues(values Values) ContextOption {
 	return func(ctx *context) {
@@ -78,6 +104,20 @@ func WithFiles(files Files) ContextOption {
 	}
 }
 
+// WithLimits defines rendering resource limits.
+func WithLimits(limits Limits) ContextOption {
+	return func(ctx *context) {
+		ctx.limits = limits
+	}
+}
+
+// WithNetwork defines network template function settings.
+func WithNetwork(value Network) ContextOption {
+	return func(ctx *context) {
+		ctx.network = value
+	}
+}
+
 // NewContext returns a template rendering context.
 func NewContext(opts ...ContextOption) Context {
 	defaultContext := &context{
@@ -86,6 +126,7 @@ func NewContext(opts ...ContextOption) Context {
 		name:          "root",
 		secretReaders: []SecretReaderFunc{},
 		strictMode:    true,
+		limits:        DefaultLimits(),
 	}
 
 	// Apply functions
@@ -97,6 +138,91 @@ func NewContext(opts ...ContextOption) Context {
 	return defaultContext
 }
 
+// WithEntropy defines the entropy source used by value generators.
+func WithEntropy(r io.Reader) ContextOption {
+	return func(ctx *context) {
+		ctx.entropy = r
+	}
+}
+
+// WithDryRun replaces value generators by placeholders recorded in the
+// given dry-run.
+func WithDryRun(dr *DryRun) ContextOption {
+	return func(ctx *context) {
+		ctx.dryRun = dr
+	}
+}
+
+// WithProvenanceRecorder defines the recorder collecting value generator
+// provenances.
+func WithProvenanceRecorder(rec *generators.Recorder) ContextOption {
+	return func(ctx *context) {
+		ctx.recorder = rec
+	}
+}
+
+// WithConsumptionTracker defines the tracker recording secret values read by
+// `secret` template function.
+func WithConsumptionTracker(t *consumption.Tracker) ContextOption {
+	return func(ctx *context) {
+		ctx.tracker = t
+	}
+}
+
+// Recording returns a context wrapping the given one and recording value
+// generator provenances with the given recorder.
+func Recording(templateContext Context, rec *generators.Recorder) Context {
+	return &recordingContext{
+		Context:  templateContext,
+		recorder: rec,
+	}
+}
+
+// DryRunning returns a context wrapping the given one and replacing value
+// generators by placeholders recorded in the given dry-run.
+func DryRunning(templateContext Context, dr *DryRun) Context {
+	return &dryRunContext{
+		Context: templateContext,
+		dryRun:  dr,
+	}
+}
+
+// Tracking returns a context wrapping the given one and recording consumed
+// secret values with the given tracker.
+func Tracking(templateContext Context, t *consumption.Tracker) Context {
+	return &trackingContext{
+		Context: templateContext,
+		tracker: t,
+	}
+}
+
+type dryRunContext struct {
+	Context
+	dryRun *DryRun
+}
+
+func (ctx *dryRunContext) DryRun() *DryRun {
+	return ctx.dryRun
+}
+
+type recordingContext struct {
+	Context
+	recorder *generators.Recorder
+}
+
+func (ctx *recordingContext) ProvenanceRecorder() *generators.Recorder {
+	return ctx.recorder
+}
+
+type trackingContext struct {
+	Context
+	tracker *consumption.Tracker
+}
+
+func (ctx *trackingContext) ConsumptionTracker() *consumption.Tracker {
+	return ctx.tracker
+}
+
 // -----------------------------------------------------------------------------
 
 // Context describes rendering context.
@@ -106,8 +232,15 @@ type context struct {
 	delimLeft     string
 	delimRight    string
 	secretReaders []SecretReaderFunc
+	secretSources []string
 	values        Values
 	files         Files
+	entropy       io.Reader
+	recorder      *generators.Recorder
+	tracker       *consumption.Tracker
+	dryRun        *DryRun
+	limits        Limits
+	network       Network
 }
 
 // Name returns template name
@@ -130,6 +263,20 @@ func (ctx *context) SecretReaders() []SecretReaderFunc {
 	return ctx.secretReaders
 }
 
+// SecretSources returns the source name of each secret reader, unnamed
+// readers are identified by their position.
+func (ctx *context) SecretSources() []string {
+	sources := make([]string, len(ctx.secretReaders))
+	for i := range sources {
+		if i < len(ctx.secretSources) && ctx.secretSources[i] != "" {
+			sources[i] = ctx.secretSources[i]
+		} else {
+			sources[i] = fmt.Sprintf("reader#%d", i)
+		}
+	}
+	return sources
+}
+
 // Values returns binded values from rendering context.
 func (ctx *context) Values() Values {
 	return ctx.values
@@ -139,3 +286,33 @@ func (ctx *context) Values() Values {
 func (ctx *context) Files() Files {
 	return ctx.files
 }
+
+// Entropy returns the entropy source used by value generators.
+func (ctx *context) Entropy() io.Reader {
+	return ctx.entropy
+}
+
+// ProvenanceRecorder returns the value generator provenance recorder.
+func (ctx *context) ProvenanceRecorder() *generators.Recorder {
+	return ctx.recorder
+}
+
+// ConsumptionTracker returns the consumed secret value tracker.
+func (ctx *context) ConsumptionTracker() *consumption.Tracker {
+	return ctx.tracker
+}
+
+// DryRun returns the dry-run recorder if enabled.
+func (ctx *context) DryRun() *DryRun {
+	return ctx.dryRun
+}
+
+// Limits returns rendering resource limits.
+func (ctx *context) Limits() Limits {
+	return ctx.limits
+}
+
+// Network returns network template function settings.
+func (ctx *context) Network() Network {
+	return ctx.network
+}
@@ -0,0 +1,174 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"text/template"
+)
+
+// generatorFuncs lists template functions minting secret values.
+var generatorFuncs = map[string]bool{
+	// Harp
+	"generate":         false,
+	"customPassword":   false,
+	"paranoidPassword": false,
+	"noSymbolPassword": false,
+	"strongPassword":   false,
+	"customDiceware":   false,
+	"basicDiceware":    false,
+	"strongDiceware":   false,
+	"paranoidDiceware": false,
+	"cryptoKey":        false,
+	"cryptoPair":       true,
+	// Sprig
+	"randAlphaNum":      false,
+	"randAlpha":         false,
+	"randAscii":         false,
+	"randNumeric":       false,
+	"randBytes":         false,
+	"randInt":           false,
+	"uuidv4":            false,
+	"genPrivateKey":     false,
+	"genCA":             true,
+	"genSelfSignedCert": true,
+	"genSignedCert":     true,
+}
+
+// keyEncoderFuncs lists template functions encoding generated keys.
+var keyEncoderFuncs = []string{"toJwk", "toJwkUsage", "toPem", "toSSH", "sshFingerprint", "encryptPem"}
+
+// IsGenerator returns true if the given template function mints secret
+// values.
+func IsGenerator(name string) bool {
+	_, ok := generatorFuncs[name]
+	return ok
+}
+
+// Generators returns the sorted template function names minting secret
+// values.
+func Generators() []string {
+	res := make([]string, 0, len(generatorFuncs))
+	for name := range generatorFuncs {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// -----------------------------------------------------------------------------
+
+var placeholderPattern = regexp.MustCompile(`@generated:(\d+)@`)
+
+// GeneratorCall describes a value generator invocation replaced during a
+// dry-run.
+type GeneratorCall struct {
+	Scope string
+	Name  string
+	Args  []interface{}
+}
+
+// DryRun records value generator invocations and replaces their results by
+// placeholders, no secret value is generated.
+type DryRun struct {
+	mu    sync.Mutex
+	scope string
+	calls []GeneratorCall
+}
+
+// Scope sets the scope attached to the following invocations.
+func (d *DryRun) Scope(name string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.scope = name
+}
+
+// Calls returns all recorded invocations, indexed by placeholder.
+func (d *DryRun) Calls() []GeneratorCall {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]GeneratorCall(nil), d.calls...)
+}
+
+// Placeholders returns the invocation indexes referenced by placeholders in
+// the given value.
+func (d *DryRun) Placeholders(value string) []int {
+	res := []int{}
+	for _, m := range placeholderPattern.FindAllStringSubmatch(value, -1) {
+		idx, err := strconv.Atoi(m[1])
+		if err != nil {
+			continue
+		}
+		res = append(res, idx)
+	}
+	return res
+}
+
+func (d *DryRun) record(name string, args []interface{}) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.calls = append(d.calls, GeneratorCall{
+		Scope: d.scope,
+		Name:  name,
+		Args:  args,
+	})
+
+	return fmt.Sprintf("@generated:%d@", len(d.calls)-1)
+}
+
+func (d *DryRun) funcs() template.FuncMap {
+	fm := template.FuncMap{}
+
+	// Generators
+	for name, isKey := range generatorFuncs {
+		fname, keyPair := name, isKey
+		fm[fname] = func(args ...interface{}) (interface{}, error) {
+			placeholder := d.record(fname, args)
+			if keyPair {
+				// Support key and certificate field access
+				return map[string]interface{}{
+					"Private": placeholder,
+					"Public":  placeholder,
+					"Cert":    placeholder,
+					"Key":     placeholder,
+				}, nil
+			}
+			return placeholder, nil
+		}
+	}
+
+	// Key encoders forward generated key placeholders
+	for _, name := range keyEncoderFuncs {
+		fname := name
+		fm[fname] = func(args ...interface{}) (string, error) {
+			for _, arg := range args {
+				if m := placeholderPattern.FindString(fmt.Sprint(arg)); m != "" {
+					return m, nil
+				}
+			}
+			return "", fmt.Errorf("dry-run: '%s' expects a generated key", fname)
+		}
+	}
+
+	return fm
+}
@@ -18,14 +18,20 @@
 package engine
 
 import (
+	gocontext "context"
+	"fmt"
 	"text/template"
+	"time"
 
 	"github.com/Masterminds/sprig/v3"
 
+	"github.com/elastic/harp/pkg/sdk/escape"
 	"github.com/elastic/harp/pkg/sdk/security/crypto"
 	"github.com/elastic/harp/pkg/sdk/security/diceware"
 	"github.com/elastic/harp/pkg/sdk/security/password"
+	"github.com/elastic/harp/pkg/template/consumption"
 	"github.com/elastic/harp/pkg/template/engine/internal/codec"
+	"github.com/elastic/harp/pkg/template/generators"
 )
 
 // FuncMap returns a mapping of all of the functions that Temmplate has.
@@ -52,13 +58,27 @@ func FuncMap(secretReaders []SecretReaderFunc) template.FuncMap {
 		"toJson":        codec.ToJSON,
 		"fromJson":      codec.FromJSON,
 		"fromJsonArray": codec.FromJSONArray,
+		// Escaping
+		"shellQuote":       escaper(escape.ShellSingleQuote),
+		"shellDoubleQuote": escaper(escape.ShellDoubleQuote),
+		"dotenvQuote":      escaper(escape.Dotenv),
+		"propertiesKey":    escaper(escape.PropertiesKey),
+		"propertiesValue":  escaper(escape.PropertiesValue),
+		"yamlQuote":        escaper(escape.YAMLScalar),
+		"systemdEscape":    escaper(escape.SystemdUnitValue),
 		// Crypto
-		"toJwk":      crypto.ToJWK,
-		"toPem":      crypto.ToPEM,
-		"encryptPem": crypto.EncryptPEM,
-		"toSSH":      crypto.ToSSH,
-		"cryptoKey":  crypto.Key,
-		"cryptoPair": crypto.Keypair,
+		"toJwk":          crypto.ToJWK,
+		"toJwkUsage":     toJWKUsage,
+		"toPem":          crypto.ToPEM,
+		"encryptPem":     crypto.EncryptPEM,
+		"toSSH":          crypto.ToSSH,
+		"sshFingerprint": sshFingerprint,
+		"cryptoKey":      crypto.Key,
+		"cryptoPair":     crypto.Keypair,
+		// Generator
+		"generate": generate(gocontext.Background()),
+		// Network
+		"remoteJWKS": remoteJWKS(Network{}, nil),
 		// Secret
 		"secret": SecretReaders(secretReaders),
 	}
@@ -69,3 +89,77 @@ func FuncMap(secretReaders []SecretReaderFunc) template.FuncMap {
 
 	return f
 }
+
+// -----------------------------------------------------------------------------
+
+// toJWKUsage encodes the given key as JWK with usage declaration and an
+// optional expiration delay ("" for no expiration).
+func toJWKUsage(usage, ttl string, key interface{}) (string, error) {
+	var expiresAt time.Time
+
+	// Parse expiration delay
+	if ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			return "", fmt.Errorf("unable to parse key usage expiration '%s': %w", ttl, err)
+		}
+		expiresAt = time.Now().Add(d)
+	}
+
+	return crypto.ToJWKWithUsage(key, usage, expiresAt)
+}
+
+// sshFingerprint returns the OpenSSH fingerprint of the given key using the
+// given algorithm ("sha256" or "md5").
+func sshFingerprint(algo string, key interface{}) (string, error) {
+	return crypto.SSHFingerprint(key, algo)
+}
+
+// escaper returns a template function applying the given encoder to the
+// string representation of its argument.
+func escaper(encode func(string) (string, error)) func(interface{}) (string, error) {
+	return func(value interface{}) (string, error) {
+		switch v := value.(type) {
+		case string:
+			return encode(v)
+		case []byte:
+			return encode(string(v))
+		default:
+			return encode(fmt.Sprint(v))
+		}
+	}
+}
+
+// generate returns the `generate` template function bound to the given
+// context.
+func generate(ctx gocontext.Context) func(string, ...map[string]interface{}) (interface{}, error) {
+	return func(name string, params ...map[string]interface{}) (interface{}, error) {
+		// Check arguments
+		if len(params) > 1 {
+			return nil, fmt.Errorf("generator '%s' accepts only one parameter map", name)
+		}
+
+		p := generators.Params{}
+		if len(params) == 1 {
+			p = params[0]
+		}
+
+		return generators.Generate(ctx, name, p)
+	}
+}
+
+// generatorContext returns the value generator context from the template
+// rendering context.
+func generatorContext(templateContext Context) gocontext.Context {
+	ctx := gocontext.Background()
+	if r := templateContext.Entropy(); r != nil {
+		ctx = generators.WithEntropy(ctx, r)
+	}
+	if rec := templateContext.ProvenanceRecorder(); rec != nil {
+		ctx = generators.WithRecorder(ctx, rec)
+	}
+	if tracker := templateContext.ConsumptionTracker(); tracker != nil {
+		ctx = consumption.WithTracker(ctx, tracker)
+	}
+	return ctx
+}
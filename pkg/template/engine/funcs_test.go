@@ -18,11 +18,17 @@
 package engine
 
 import (
+	"bytes"
+	"fmt"
 	"strings"
+	"sync"
 	"testing"
 	"text/template"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/harp/pkg/template/consumption"
+	"github.com/elastic/harp/pkg/template/generators"
 )
 
 func TestFuncs(t *testing.T) {
@@ -94,6 +100,29 @@ func TestFuncs(t *testing.T) {
 		tpl:    `{{ fromYamlArray . }}`,
 		expect: `[error unmarshaling JSON: while decoding JSON: json: cannot unmarshal object into Go value of type []interface {}]`,
 		vars:   `hello: world`,
+	}, {
+		tpl:    `{{ . | sshFingerprint "sha256" }}`,
+		expect: `SHA256:1gsmzmgwtiIyaGT060LgkoeB66L1TtWLjRHcFewV0C8`,
+		vars:   `ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIDXUe02EbHbVbQib/wykA9jzRL3+TPVCp0cZJGhRwkfY fixture@harp`,
+	}, {
+		tpl:    `{{ (cryptoPair "ssh").Public | sshFingerprint "md5" | len }}`,
+		expect: `51`,
+	}, {
+		tpl:    `{{ shellQuote . }}`,
+		expect: `'it'\''s $(id)'`,
+		vars:   `it's $(id)`,
+	}, {
+		tpl:    `{{ dotenvQuote . }}`,
+		expect: `"\` + "`id\\`" + ` \$HOME\nline"`,
+		vars:   "`id` $HOME\nline",
+	}, {
+		tpl:    `{{ propertiesKey "db url" }}={{ propertiesValue . }}`,
+		expect: `db\ url=jdbc\:h2\:mem\:caf\u00E9`,
+		vars:   "jdbc:h2:mem:café",
+	}, {
+		tpl:    `{{ yamlQuote . }}`,
+		expect: `"42"`,
+		vars:   42,
 	}}
 
 	for _, tt := range tests {
@@ -103,3 +132,58 @@ func TestFuncs(t *testing.T) {
 		assert.Equal(t, tt.expect, b.String(), tt.tpl)
 	}
 }
+
+func TestFuncs_Generate(t *testing.T) {
+	rec := &generators.Recorder{}
+	render := func() string {
+		out, err := RenderContext(NewContext(
+			WithEntropy(bytes.NewReader(bytes.Repeat([]byte{0x01}, 1024))),
+			WithProvenanceRecorder(rec),
+		), `{{ generate "harp.bytes" (dict "size" 4 "encoding" "hex") }}`)
+		assert.NoError(t, err)
+		return out
+	}
+
+	assert.Equal(t, "01010101", render())
+	assert.Len(t, rec.Entries(), 1)
+	assert.Equal(t, "harp.bytes", rec.Entries()[0].Generator)
+
+	_, err := RenderContext(NewContext(), `{{ generate "unknown.generator" }}`)
+	assert.Error(t, err)
+}
+
+func TestFuncs_SecretConsumption(t *testing.T) {
+	base := NewContext(
+		WithSecretReaders(func(path string) (map[string]interface{}, error) {
+			return nil, fmt.Errorf("no secret")
+		}),
+		WithSecretSource("static", func(path string) (map[string]interface{}, error) {
+			return map[string]interface{}{"key": path}, nil
+		}),
+	)
+	assert.Equal(t, []string{"reader#0", "static"}, base.SecretSources())
+
+	// Concurrent renders don't mix records
+	trackers := make([]*consumption.Tracker, 10)
+	var wg sync.WaitGroup
+	for i := range trackers {
+		trackers[i] = &consumption.Tracker{}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			out, err := RenderContext(Tracking(base, trackers[i]), fmt.Sprintf(`{{ (secret "app/%d").key }}`, i))
+			assert.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf("app/%d", i), out)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, tracker := range trackers {
+		records := tracker.Manifest().Records
+		if assert.Len(t, records, 1) {
+			assert.Equal(t, "static", records[0].Source)
+			assert.Equal(t, fmt.Sprintf("app/%d", i), records[0].Path)
+			assert.Equal(t, "key", records[0].Key)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
+
+const (
+	// DefaultMaxOutputSize defines the default maximum rendered output size
+	// in bytes.
+	DefaultMaxOutputSize = 16 << 20
+	// DefaultMaxPackages defines the default maximum count of packages
+	// generated by a template render.
+	DefaultMaxPackages = 10000
+	// DefaultMaxDepth defines the default maximum template / include nesting
+	// depth.
+	DefaultMaxDepth = 64
+	// DefaultTimeout defines the default wall-clock rendering timeout.
+	DefaultTimeout = 30 * time.Second
+)
+
+// Limit names used in LimitError.
+const (
+	LimitOutputSize = "max-output-size"
+	LimitPackages   = "max-packages"
+	LimitDepth      = "max-depth"
+	LimitTimeout    = "render-timeout"
+)
+
+// Limits describes resource limits enforced during template rendering. A zero
+// value disables the corresponding limit.
+type Limits struct {
+	MaxOutputSize int64
+	MaxPackages   int
+	MaxDepth      int
+	Timeout       time.Duration
+}
+
+// DefaultLimits returns the default rendering resource limits.
+func DefaultLimits() Limits {
+	return Limits{
+		MaxOutputSize: DefaultMaxOutputSize,
+		MaxPackages:   DefaultMaxPackages,
+		MaxDepth:      DefaultMaxDepth,
+		Timeout:       DefaultTimeout,
+	}
+}
+
+// LimitError is raised when a rendering resource limit is exceeded.
+type LimitError struct {
+	Limit    string
+	Value    interface{}
+	Template string
+	Position string
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("template '%s' exceeded %s limit (%v) at %s", e.Template, e.Limit, e.Value, e.Position)
+}
+
+// -----------------------------------------------------------------------------
+
+// Template function names used by limit checkpoints injected in the parsed
+// template tree.
+const (
+	checkFuncName = "harpLimitCheck"
+	enterFuncName = "harpLimitEnter"
+	leaveFuncName = "harpLimitLeave"
+	includeName   = "include"
+)
+
+// now is the clock used to enforce the rendering timeout.
+var now = time.Now
+
+// guard tracks resource usage of a single template execution.
+type guard struct {
+	limits   Limits
+	name     string
+	deadline time.Time
+	position string
+	depth    int
+	err      error
+}
+
+func newGuard(name string, limits Limits) *guard {
+	g := &guard{
+		limits:   limits,
+		name:     name,
+		position: name,
+	}
+	if limits.Timeout > 0 {
+		g.deadline = now().Add(limits.Timeout)
+	}
+	return g
+}
+
+func (g *guard) fail(limit string, value interface{}) error {
+	if g.err == nil {
+		g.err = &LimitError{
+			Limit:    limit,
+			Value:    value,
+			Template: g.name,
+			Position: g.position,
+		}
+	}
+	return g.err
+}
+
+// check validates the rendering deadline.
+func (g *guard) check() error {
+	if !g.deadline.IsZero() && now().After(g.deadline) {
+		return g.fail(LimitTimeout, g.limits.Timeout)
+	}
+	return nil
+}
+
+// funcs returns checkpoint template functions.
+func (g *guard) funcs() template.FuncMap {
+	return template.FuncMap{
+		checkFuncName: func(position string) (string, error) {
+			g.position = position
+			return "", g.check()
+		},
+		enterFuncName: func(position string) (string, error) {
+			g.position = position
+			g.depth++
+			if g.limits.MaxDepth > 0 && g.depth > g.limits.MaxDepth {
+				return "", g.fail(LimitDepth, g.limits.MaxDepth)
+			}
+			return "", g.check()
+		},
+		leaveFuncName: func() string {
+			g.depth--
+			return ""
+		},
+	}
+}
+
+// writer wraps the given writer to enforce output size and deadline.
+func (g *guard) writer(w io.Writer) io.Writer {
+	return &limitedWriter{guard: g, w: w}
+}
+
+type limitedWriter struct {
+	guard   *guard
+	w       io.Writer
+	written int64
+}
+
+func (lw *limitedWriter) Write(p []byte) (int, error) {
+	if err := lw.guard.check(); err != nil {
+		return 0, err
+	}
+	if max := lw.guard.limits.MaxOutputSize; max > 0 && lw.written+int64(len(p)) > max {
+		return 0, lw.guard.fail(LimitOutputSize, max)
+	}
+
+	n, err := lw.w.Write(p)
+	lw.written += int64(n)
+	return n, err
+}
+
+// -----------------------------------------------------------------------------
+
+// include returns the `include` template function rendering the named
+// template to a string, bound to the given template set.
+func include(t *template.Template, g *guard) func(string, interface{}) (string, error) {
+	return func(name string, data interface{}) (string, error) {
+		var out strings.Builder
+		if err := t.ExecuteTemplate(g.writer(&out), name, data); err != nil {
+			return "", err
+		}
+		return out.String(), nil
+	}
+}
+
+// instrument injects limit checkpoints in all template trees of the set :
+// at the start of each range iteration, and around each template / include
+// invocation.
+func instrument(t *template.Template) {
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree == nil || tmpl.Tree.Root == nil {
+			continue
+		}
+		instrumentList(tmpl.Tree, tmpl.Tree.Root)
+	}
+}
+
+func instrumentList(tree *parse.Tree, list *parse.ListNode) {
+	if list == nil {
+		return
+	}
+
+	nodes := make([]parse.Node, 0, len(list.Nodes))
+	for _, n := range list.Nodes {
+		var nested bool
+
+		switch node := n.(type) {
+		case *parse.IfNode:
+			nested = callsInclude(node.Pipe)
+			instrumentList(tree, node.List)
+			instrumentList(tree, node.ElseList)
+		case *parse.WithNode:
+			nested = callsInclude(node.Pipe)
+			instrumentList(tree, node.List)
+			instrumentList(tree, node.ElseList)
+		case *parse.RangeNode:
+			nested = callsInclude(node.Pipe)
+			if node.List != nil {
+				check := checkpoint(tree, checkFuncName, locate(tree, n))
+				instrumentList(tree, node.List)
+				node.List.Nodes = append([]parse.Node{check}, node.List.Nodes...)
+			}
+			instrumentList(tree, node.ElseList)
+		case *parse.ActionNode:
+			nested = callsInclude(node.Pipe)
+		case *parse.TemplateNode:
+			nested = true
+		}
+
+		if !nested {
+			nodes = append(nodes, n)
+			continue
+		}
+
+		nodes = append(nodes,
+			checkpoint(tree, enterFuncName, locate(tree, n)),
+			n,
+			checkpoint(tree, leaveFuncName, ""),
+		)
+	}
+
+	list.Nodes = nodes
+}
+
+// locate returns the quoted "template:line:col" location of the given node.
+func locate(tree *parse.Tree, n parse.Node) string {
+	location, _ := tree.ErrorContext(n)
+	return strconv.Quote(location)
+}
+
+// checkpoint builds an action node calling the given function with the given
+// arguments.
+func checkpoint(tree *parse.Tree, fn string, args string) parse.Node {
+	// Parse the checkpoint so that nodes are bound to a tree
+	cp, err := parse.New(tree.ParseName).Parse(fmt.Sprintf("{{ %s %s }}", fn, args), "{{", "}}", map[string]*parse.Tree{}, map[string]interface{}{
+		fn: true,
+	})
+	if err != nil {
+		panic(fmt.Errorf("unable to build limit checkpoint: %w", err))
+	}
+
+	return cp.Root.Nodes[0]
+}
+
+// callsInclude returns true if the pipeline invokes the include function.
+func callsInclude(pipe *parse.PipeNode) bool {
+	if pipe == nil {
+		return false
+	}
+	for _, cmd := range pipe.Cmds {
+		for _, arg := range cmd.Args {
+			switch a := arg.(type) {
+			case *parse.IdentifierNode:
+				if a.Ident == includeName {
+					return true
+				}
+			case *parse.PipeNode:
+				if callsInclude(a) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
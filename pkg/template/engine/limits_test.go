@@ -0,0 +1,113 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRenderContext_Limits(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		input        string
+		limits       Limits
+		wantLimit    string
+		wantPosition string
+		want         string
+	}{
+		{
+			desc:   "within limits",
+			input:  `{{ define "item" }}[{{ . }}]{{ end }}{{ range until 3 }}{{ include "item" . }}{{ end }}`,
+			limits: DefaultLimits(),
+			want:   "[0][1][2]",
+		},
+		{
+			desc:         "output size",
+			input:        "{{ range until 10 }}0123456789{{ end }}",
+			limits:       Limits{MaxOutputSize: 32},
+			wantLimit:    LimitOutputSize,
+			wantPosition: "root:1:9",
+		},
+		{
+			desc:         "output size in include",
+			input:        `{{ define "big" }}{{ repeat 64 "a" }}{{ end }}{{ include "big" . | len }}`,
+			limits:       Limits{MaxOutputSize: 32},
+			wantLimit:    LimitOutputSize,
+			wantPosition: "root:1:49",
+		},
+		{
+			desc:         "template recursion",
+			input:        `{{ define "loop" }}{{ template "loop" . }}{{ end }}{{ template "loop" . }}`,
+			limits:       Limits{MaxDepth: 5},
+			wantLimit:    LimitDepth,
+			wantPosition: "root:1:31",
+		},
+		{
+			desc:         "include recursion",
+			input:        `{{ define "loop" }}{{ include "loop" . }}{{ end }}{{ include "loop" . }}`,
+			limits:       Limits{MaxDepth: 5},
+			wantLimit:    LimitDepth,
+			wantPosition: "root:1:22",
+		},
+		{
+			desc:         "timeout in range",
+			input:        "{{ range until 1000 }}{{ end }}",
+			limits:       Limits{Timeout: 10 * time.Second},
+			wantLimit:    LimitTimeout,
+			wantPosition: "root:1:9",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// Simulate a clock advancing by one second at each reading
+			clock := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+			now = func() time.Time {
+				clock = clock.Add(time.Second)
+				return clock
+			}
+			defer func() { now = time.Now }()
+
+			got, err := RenderContext(NewContext(WithLimits(tC.limits)), tC.input)
+			if tC.wantLimit == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got != tC.want {
+					t.Errorf("expected %q, got %q", tC.want, got)
+				}
+				return
+			}
+
+			var limitErr *LimitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("expected a limit error, got %v", err)
+			}
+			if limitErr.Limit != tC.wantLimit {
+				t.Errorf("expected limit %q, got %q", tC.wantLimit, limitErr.Limit)
+			}
+			if limitErr.Template != "root" {
+				t.Errorf("expected template name 'root', got %q", limitErr.Template)
+			}
+			if limitErr.Position != tC.wantPosition {
+				t.Errorf("expected position %q, got %q", tC.wantPosition, limitErr.Position)
+			}
+		})
+	}
+}
@@ -0,0 +1,279 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	jose "gopkg.in/square/go-jose.v2"
+
+	"github.com/elastic/harp/pkg/template/generators"
+)
+
+const (
+	// DefaultMaxRemoteSize defines the default maximum size in bytes of a
+	// document fetched by a network template function.
+	DefaultMaxRemoteSize = 1 << 20
+	// DefaultRemoteTimeout defines the default timeout of a network template
+	// function request.
+	DefaultRemoteTimeout = 10 * time.Second
+)
+
+// ErrNetworkDisabled is raised when a network template function is called
+// while network functions are not allowed.
+var ErrNetworkDisabled = errors.New("network template functions are not allowed")
+
+// Network describes network template function settings. Network functions
+// are disabled by default to keep rendering hermetic.
+type Network struct {
+	// Enabled allows network template functions to fetch remote documents.
+	Enabled bool
+	// Offline reads remote documents from SnapshotDir instead of fetching
+	// them, network access is not required.
+	Offline bool
+	// SnapshotDir holds remote document snapshots. Fetched documents are
+	// written to it when set.
+	SnapshotDir string
+	// MaxSize is the maximum remote document size in bytes.
+	MaxSize int64
+	// Client is the HTTP client used to fetch remote documents.
+	Client *http.Client
+}
+
+// Allowed returns true when network template functions can be called.
+func (n Network) Allowed() bool {
+	return n.Enabled || n.Offline
+}
+
+// RequiresNetwork returns true when network template functions fetch remote
+// documents.
+func (n Network) RequiresNetwork() bool {
+	return n.Enabled && !n.Offline
+}
+
+// SnapshotPath returns the snapshot file path of the given remote document.
+func (n Network) SnapshotPath(u string) string {
+	h := sha256.Sum256([]byte(u))
+	return filepath.Join(n.SnapshotDir, fmt.Sprintf("%s.json", hex.EncodeToString(h[:])))
+}
+
+// -----------------------------------------------------------------------------
+
+// jwksMediaTypes lists accepted JWKS document media types.
+var jwksMediaTypes = map[string]bool{
+	"application/jwk-set+json": true,
+	"application/json":         true,
+}
+
+// remoteJWKS returns the `remoteJWKS` template function. It returns the
+// public keys of the JWKS document published at the given URL, optionally
+// filtered by `kid` and `use`, as a JSON encoded key set. The document URL
+// and digest are recorded as provenance.
+func remoteJWKS(n Network, rec *generators.Recorder) func(string, ...map[string]interface{}) (string, error) {
+	return func(u string, filters ...map[string]interface{}) (string, error) {
+		// Check arguments
+		if !n.Allowed() {
+			return "", fmt.Errorf("unable to fetch '%s': %w", u, ErrNetworkDisabled)
+		}
+		if len(filters) > 1 {
+			return "", errors.New("remoteJWKS accepts only one filter map")
+		}
+		endpoint, err := url.Parse(u)
+		if err != nil {
+			return "", fmt.Errorf("unable to parse jwks url: %w", err)
+		}
+		if endpoint.Scheme != "https" || endpoint.Host == "" {
+			return "", fmt.Errorf("jwks url '%s' must be an absolute https url", u)
+		}
+
+		// Retrieve the document
+		var body []byte
+		if n.Offline {
+			body, err = n.readSnapshot(u)
+		} else {
+			body, err = n.fetch(u, jwksMediaTypes)
+		}
+		if err != nil {
+			return "", err
+		}
+
+		// Validate the key set
+		var jwks jose.JSONWebKeySet
+		if err := json.Unmarshal(body, &jwks); err != nil {
+			return "", fmt.Errorf("unable to decode jwks document from '%s': %w", u, err)
+		}
+		if len(jwks.Keys) == 0 {
+			return "", fmt.Errorf("jwks document from '%s' has no key", u)
+		}
+		for i := range jwks.Keys {
+			k := jwks.Keys[i]
+			if !k.Valid() {
+				return "", fmt.Errorf("jwks document from '%s' has an invalid key (kid '%s')", u, k.KeyID)
+			}
+			if !k.IsPublic() {
+				return "", fmt.Errorf("jwks document from '%s' exposes a private key (kid '%s')", u, k.KeyID)
+			}
+		}
+
+		// Filter keys
+		keys, err := filterJWKS(jwks.Keys, filters)
+		if err != nil {
+			return "", err
+		}
+		if len(keys) == 0 {
+			return "", fmt.Errorf("no key of jwks document from '%s' matches the filter", u)
+		}
+
+		// Keep the snapshot of a fetched document
+		if n.SnapshotDir != "" && !n.Offline {
+			if err := ioutil.WriteFile(n.SnapshotPath(u), body, 0o600); err != nil {
+				return "", fmt.Errorf("unable to write jwks snapshot: %w", err)
+			}
+		}
+
+		// Record provenance
+		if rec != nil {
+			rec.Record(generators.NewContentProvenance("remoteJWKS", u, body))
+		}
+
+		// Encode the pinned keys
+		out, err := json.Marshal(jose.JSONWebKeySet{Keys: keys})
+		if err != nil {
+			return "", fmt.Errorf("unable to encode jwks: %w", err)
+		}
+
+		// No error
+		return string(out), nil
+	}
+}
+
+// filterJWKS returns keys matching all `kid` and `use` filter values.
+func filterJWKS(keys []jose.JSONWebKey, filters []map[string]interface{}) ([]jose.JSONWebKey, error) {
+	if len(filters) == 0 {
+		return keys, nil
+	}
+
+	// Check filter
+	for k, v := range filters[0] {
+		if k != "kid" && k != "use" {
+			return nil, fmt.Errorf("unsupported jwks filter '%s', expected 'kid' or 'use'", k)
+		}
+		if _, ok := v.(string); !ok {
+			return nil, fmt.Errorf("jwks filter '%s' must be a string", k)
+		}
+	}
+	kid, _ := filters[0]["kid"].(string)
+	use, _ := filters[0]["use"].(string)
+
+	res := []jose.JSONWebKey{}
+	for i := range keys {
+		if kid != "" && keys[i].KeyID != kid {
+			continue
+		}
+		if use != "" && keys[i].Use != use {
+			continue
+		}
+		res = append(res, keys[i])
+	}
+
+	return res, nil
+}
+
+// fetch retrieves the document published at the given URL, the response
+// media type must be one of the accepted ones.
+func (n Network) fetch(u string, mediaTypes map[string]bool) ([]byte, error) {
+	client := n.Client
+	if client == nil {
+		client = &http.Client{Timeout: DefaultRemoteTimeout}
+	}
+
+	// Send the request
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to prepare request: %w", err)
+	}
+	accept := make([]string, 0, len(mediaTypes))
+	for mediaType := range mediaTypes {
+		accept = append(accept, mediaType)
+	}
+	sort.Strings(accept)
+	req.Header.Set("Accept", strings.Join(accept, ", "))
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch '%s': %w", u, err)
+	}
+	defer resp.Body.Close()
+
+	// Check response
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch '%s': unexpected status %d", u, resp.StatusCode)
+	}
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || !mediaTypes[mediaType] {
+		return nil, fmt.Errorf("unable to fetch '%s': unexpected content type '%s'", u, resp.Header.Get("Content-Type"))
+	}
+
+	return n.readLimited(u, resp.Body)
+}
+
+// readSnapshot reads the previously fetched document from the snapshot
+// directory.
+func (n Network) readSnapshot(u string) ([]byte, error) {
+	if n.SnapshotDir == "" {
+		return nil, fmt.Errorf("unable to read '%s' snapshot: no snapshot directory in offline mode", u)
+	}
+
+	f, err := os.Open(n.SnapshotPath(u))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read '%s' snapshot: %w", u, err)
+	}
+	defer f.Close()
+
+	return n.readLimited(u, f)
+}
+
+func (n Network) readLimited(u string, r io.Reader) ([]byte, error) {
+	maxSize := n.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxRemoteSize
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read '%s': %w", u, err)
+	}
+	if int64(len(body)) > maxSize {
+		return nil, fmt.Errorf("document from '%s' exceeds the maximum size of %d bytes", u, maxSize)
+	}
+
+	return body, nil
+}
@@ -0,0 +1,295 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	jose "gopkg.in/square/go-jose.v2"
+
+	"github.com/elastic/harp/pkg/template/generators"
+)
+
+func testJWKS(t *testing.T, private bool) string {
+	t.Helper()
+
+	keys := []jose.JSONWebKey{}
+	for _, k := range []struct{ kid, use string }{{"sig-1", "sig"}, {"sig-2", "sig"}, {"enc-1", "enc"}} {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("unable to generate key: %v", err)
+		}
+		var key interface{} = pub
+		if private {
+			key = priv
+		}
+		keys = append(keys, jose.JSONWebKey{Key: key, KeyID: k.kid, Use: k.use, Algorithm: "EdDSA"})
+	}
+
+	out, err := json.Marshal(jose.JSONWebKeySet{Keys: keys})
+	if err != nil {
+		t.Fatalf("unable to encode jwks: %v", err)
+	}
+	return string(out)
+}
+
+func TestRemoteJWKS(t *testing.T) {
+	jwks := testJWKS(t, false)
+	documents := map[string]struct {
+		contentType string
+		body        string
+	}{
+		"/jwks.json":     {contentType: "application/jwk-set+json", body: jwks},
+		"/plain.json":    {contentType: "application/json; charset=utf-8", body: jwks},
+		"/text":          {contentType: "text/plain", body: jwks},
+		"/oversized":     {contentType: "application/json", body: fmt.Sprintf(`{"keys":[],"padding":%q}`, strings.Repeat("a", 2048))},
+		"/invalid":       {contentType: "application/json", body: `{"keys":`},
+		"/empty":         {contentType: "application/json", body: `{"keys":[]}`},
+		"/private":       {contentType: "application/json", body: testJWKS(t, true)},
+		"/unsupported":   {contentType: "application/json", body: `{"keys":[{"kty":"foo","kid":"x"}]}`},
+		"/missing-field": {contentType: "application/json", body: `{"keys":[{"kty":"EC","crv":"P-256","kid":"x"}]}`},
+	}
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		doc, ok := documents[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", doc.contentType)
+		fmt.Fprint(w, doc.body)
+	}))
+	defer srv.Close()
+
+	network := Network{
+		Enabled: true,
+		MaxSize: 1024,
+		Client:  srv.Client(),
+	}
+
+	testCases := []struct {
+		name     string
+		network  Network
+		tpl      string
+		wantKids []string
+		wantErr  string
+	}{
+		{
+			name:    "disabled",
+			tpl:     fmt.Sprintf(`{{ remoteJWKS %q }}`, srv.URL+"/jwks.json"),
+			wantErr: ErrNetworkDisabled.Error(),
+		},
+		{
+			name:     "all keys",
+			network:  network,
+			tpl:      fmt.Sprintf(`{{ remoteJWKS %q }}`, srv.URL+"/jwks.json"),
+			wantKids: []string{"sig-1", "sig-2", "enc-1"},
+		},
+		{
+			name:     "json media type",
+			network:  network,
+			tpl:      fmt.Sprintf(`{{ remoteJWKS %q }}`, srv.URL+"/plain.json"),
+			wantKids: []string{"sig-1", "sig-2", "enc-1"},
+		},
+		{
+			name:     "filter by use",
+			network:  network,
+			tpl:      fmt.Sprintf(`{{ remoteJWKS %q (dict "use" "sig") }}`, srv.URL+"/jwks.json"),
+			wantKids: []string{"sig-1", "sig-2"},
+		},
+		{
+			name:     "filter by kid",
+			network:  network,
+			tpl:      fmt.Sprintf(`{{ remoteJWKS %q (dict "kid" "enc-1" "use" "enc") }}`, srv.URL+"/jwks.json"),
+			wantKids: []string{"enc-1"},
+		},
+		{
+			name:    "no matching key",
+			network: network,
+			tpl:     fmt.Sprintf(`{{ remoteJWKS %q (dict "kid" "unknown") }}`, srv.URL+"/jwks.json"),
+			wantErr: "matches the filter",
+		},
+		{
+			name:    "unsupported filter",
+			network: network,
+			tpl:     fmt.Sprintf(`{{ remoteJWKS %q (dict "alg" "EdDSA") }}`, srv.URL+"/jwks.json"),
+			wantErr: "unsupported jwks filter",
+		},
+		{
+			name:    "plain http",
+			network: network,
+			tpl:     `{{ remoteJWKS "http://idp.example.com/jwks.json" }}`,
+			wantErr: "must be an absolute https url",
+		},
+		{
+			name:    "untrusted certificate",
+			network: Network{Enabled: true},
+			tpl:     fmt.Sprintf(`{{ remoteJWKS %q }}`, srv.URL+"/jwks.json"),
+			wantErr: "certificate",
+		},
+		{
+			name:    "unexpected content type",
+			network: network,
+			tpl:     fmt.Sprintf(`{{ remoteJWKS %q }}`, srv.URL+"/text"),
+			wantErr: "unexpected content type",
+		},
+		{
+			name:    "not found",
+			network: network,
+			tpl:     fmt.Sprintf(`{{ remoteJWKS %q }}`, srv.URL+"/missing"),
+			wantErr: "unexpected status 404",
+		},
+		{
+			name:    "oversized",
+			network: network,
+			tpl:     fmt.Sprintf(`{{ remoteJWKS %q }}`, srv.URL+"/oversized"),
+			wantErr: "exceeds the maximum size",
+		},
+		{
+			name:    "invalid document",
+			network: network,
+			tpl:     fmt.Sprintf(`{{ remoteJWKS %q }}`, srv.URL+"/invalid"),
+			wantErr: "unable to decode jwks document",
+		},
+		{
+			name:    "empty key set",
+			network: network,
+			tpl:     fmt.Sprintf(`{{ remoteJWKS %q }}`, srv.URL+"/empty"),
+			wantErr: "has no key",
+		},
+		{
+			name:    "private keys",
+			network: network,
+			tpl:     fmt.Sprintf(`{{ remoteJWKS %q }}`, srv.URL+"/private"),
+			wantErr: "exposes a private key",
+		},
+		{
+			name:    "unsupported key type",
+			network: network,
+			tpl:     fmt.Sprintf(`{{ remoteJWKS %q }}`, srv.URL+"/unsupported"),
+			wantErr: "unable to decode jwks document",
+		},
+		{
+			name:    "incomplete key",
+			network: network,
+			tpl:     fmt.Sprintf(`{{ remoteJWKS %q }}`, srv.URL+"/missing-field"),
+			wantErr: "unable to decode jwks document",
+		},
+	}
+
+	for _, tc := range testCases {
+		out, err := RenderContext(NewContext(WithNetwork(tc.network)), tc.tpl)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("%s: expected error containing %q, got %v", tc.name, tc.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+
+		var got jose.JSONWebKeySet
+		if err := json.Unmarshal([]byte(out), &got); err != nil {
+			t.Fatalf("%s: unable to decode rendered jwks: %v", tc.name, err)
+		}
+		kids := []string{}
+		for _, k := range got.Keys {
+			kids = append(kids, k.KeyID)
+		}
+		if strings.Join(kids, ",") != strings.Join(tc.wantKids, ",") {
+			t.Fatalf("%s: expected keys %v, got %v", tc.name, tc.wantKids, kids)
+		}
+	}
+}
+
+func TestRemoteJWKS_Snapshot(t *testing.T) {
+	jwks := testJWKS(t, false)
+	var requests int
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, jwks)
+	}))
+	defer srv.Close()
+
+	var (
+		u    = srv.URL + "/.well-known/jwks.json"
+		tpl  = fmt.Sprintf(`{{ remoteJWKS %q (dict "use" "sig") }}`, u)
+		dir  = t.TempDir()
+		want = generators.NewContentProvenance("remoteJWKS", u, []byte(jwks)).String()
+	)
+
+	// Fetch and keep the snapshot
+	online := &generators.Recorder{}
+	fetched, err := RenderContext(Recording(NewContext(WithNetwork(Network{
+		Enabled:     true,
+		SnapshotDir: dir,
+		Client:      srv.Client(),
+	})), online), tpl)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if online.Annotation() != want {
+		t.Fatalf("expected provenance %q, got %q", want, online.Annotation())
+	}
+	snapshot, err := ioutil.ReadFile(Network{SnapshotDir: dir}.SnapshotPath(u))
+	if err != nil || string(snapshot) != jwks {
+		t.Fatalf("expected the fetched document as snapshot, got %q (%v)", snapshot, err)
+	}
+
+	// Render offline from the snapshot
+	srv.Close()
+	offline := &generators.Recorder{}
+	rendered, err := RenderContext(Recording(NewContext(WithNetwork(Network{
+		Offline:     true,
+		SnapshotDir: dir,
+	})), offline), tpl)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rendered != fetched {
+		t.Fatalf("offline rendering differs from the online one: %s != %s", rendered, fetched)
+	}
+	if offline.Annotation() != want {
+		t.Fatalf("expected provenance %q, got %q", want, offline.Annotation())
+	}
+	if requests != 1 {
+		t.Fatalf("expected a single remote request, got %d", requests)
+	}
+
+	// Missing snapshot
+	_, err = RenderContext(NewContext(WithNetwork(Network{
+		Offline:     true,
+		SnapshotDir: t.TempDir(),
+	})), tpl)
+	if err == nil || !strings.Contains(err.Error(), "snapshot") {
+		t.Fatalf("expected missing snapshot error, got %v", err)
+	}
+	if errors.Is(err, ErrNetworkDisabled) {
+		t.Fatalf("offline mode must not require network functions: %v", err)
+	}
+}
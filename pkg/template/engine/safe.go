@@ -0,0 +1,191 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Masterminds/sprig/v3"
+
+	"github.com/elastic/harp/pkg/sdk/escape"
+	"github.com/elastic/harp/pkg/sdk/security"
+	"github.com/elastic/harp/pkg/template/engine/internal/codec"
+)
+
+// maxSafeListSize bounds the lists built by `until` and `untilStep`, a single
+// function call can't be interrupted by the rendering deadline.
+const maxSafeListSize = 1 << 16
+
+// safeFuncNames lists template functions without filesystem, network,
+// environment or random source access.
+var safeFuncNames = []string{
+	// Strings
+	"trim", "trimAll", "trimPrefix", "trimSuffix", "upper", "lower", "title",
+	"untitle", "repeat", "substr", "nospace", "trunc", "abbrev", "initials",
+	"wrap", "wrapWith", "contains", "hasPrefix", "hasSuffix", "quote", "squote",
+	"cat", "indent", "nindent", "replace", "plural", "snakecase", "camelcase",
+	"kebabcase", "swapcase", "toString", "toStrings", "join", "split",
+	"splitList", "splitn", "printf",
+	// Encoding
+	"b64enc", "b64dec", "b32enc", "b32dec", "sha1sum", "sha256sum",
+	// Defaults and flow
+	"default", "empty", "coalesce", "ternary", "fail",
+	// Conversion
+	"atoi", "int", "int64", "float64", "toDecimal",
+	// Math
+	"add", "add1", "sub", "mul", "div", "mod", "max", "min",
+	// Lists
+	"list", "first", "rest", "last", "initial", "append", "prepend", "concat",
+	"reverse", "uniq", "without", "has", "compact", "slice", "sortAlpha",
+	"until", "untilStep",
+	// Dictionaries
+	"dict", "get", "set", "unset", "hasKey", "pluck", "keys", "pick", "omit",
+	"merge", "mergeOverwrite", "values",
+	// Regexp
+	"regexMatch", "regexFind", "regexFindAll", "regexReplaceAll",
+	"regexReplaceAllLiteral", "regexSplit",
+}
+
+// SafeFuncMap returns a restricted function mapping usable to render
+// untrusted templates. Only pure functions and secret readers are exposed.
+func SafeFuncMap(secretReaders []SecretReaderFunc) template.FuncMap {
+	all := sprig.HermeticTxtFuncMap()
+
+	f := template.FuncMap{}
+	for _, name := range safeFuncNames {
+		if fn, ok := all[name]; ok {
+			f[name] = fn
+		}
+	}
+
+	// Add some extra functionality
+	extra := template.FuncMap{
+		// Encoder
+		"toYaml":   codec.ToYAML,
+		"fromYaml": codec.FromYAML,
+		"toJson":   codec.ToJSON,
+		"fromJson": codec.FromJSON,
+		// Escaping
+		"shellQuote":       escaper(escape.ShellSingleQuote),
+		"shellDoubleQuote": escaper(escape.ShellDoubleQuote),
+		"dotenvQuote":      escaper(escape.Dotenv),
+		"propertiesKey":    escaper(escape.PropertiesKey),
+		"propertiesValue":  escaper(escape.PropertiesValue),
+		"yamlQuote":        escaper(escape.YAMLScalar),
+		"systemdEscape":    escaper(escape.SystemdUnitValue),
+		// Secret
+		"secret": SecretReaders(secretReaders),
+	}
+
+	for k, v := range extra {
+		f[k] = v
+	}
+
+	// Bound builders
+	f["until"] = func(count int) ([]int, error) {
+		step := 1
+		if count < 0 {
+			step = -1
+		}
+		return safeUntilStep(0, count, step)
+	}
+	f["untilStep"] = safeUntilStep
+	f["repeat"] = func(count int, str string) (string, error) {
+		if count > 0 && len(str) > 0 && count > DefaultMaxOutputSize/len(str) {
+			return "", fmt.Errorf("repeated string exceeds %d bytes", DefaultMaxOutputSize)
+		}
+		return strings.Repeat(str, count), nil
+	}
+
+	return f
+}
+
+// RenderSafe renders the given template with the restricted function set.
+// Rendering is aborted with a LimitError when the timeout or the default
+// output size and depth limits are exceeded. Limits are checked at each range
+// iteration, template invocation and output write, builder functions are
+// bounded so that no single call outlasts the deadline.
+func RenderSafe(name, input string, secretReaders []SecretReaderFunc, data interface{}, timeout time.Duration) (content string, err error) {
+	// Check argument
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("template rendering failed: %v", r)
+		}
+	}()
+
+	// Prepare the template
+	g := newGuard(name, Limits{
+		MaxOutputSize: DefaultMaxOutputSize,
+		MaxDepth:      DefaultMaxDepth,
+		Timeout:       timeout,
+	})
+	t, err := template.New(name).
+		Funcs(SafeFuncMap(secretReaders)).
+		Funcs(g.funcs()).
+		Option("missingkey=error").
+		Parse(input)
+	if err != nil {
+		return "", fmt.Errorf("unable to compile template '%s': %w", name, err)
+	}
+
+	// Inject resource limit checkpoints
+	instrument(t)
+
+	// Merge with values
+	var out security.SensitiveBuffer
+	defer out.Destroy()
+	if err := t.Execute(g.writer(&out), data); err != nil {
+		// Report resource limit violation as is
+		if g.err != nil {
+			err = g.err
+		}
+		return "", fmt.Errorf("unable to render template '%s': %w", name, err)
+	}
+
+	// No error
+	return out.String(), nil
+}
+
+// -----------------------------------------------------------------------------
+
+// safeUntilStep returns the integers from start to stop (excluded) by step,
+// up to maxSafeListSize elements.
+func safeUntilStep(start, stop, step int) ([]int, error) {
+	res := []int{}
+	if step == 0 || (step > 0 && start >= stop) || (step < 0 && start <= stop) {
+		return res, nil
+	}
+
+	// Check list size
+	span, stride := int64(stop)-int64(start), int64(step)
+	if span < 0 {
+		span, stride = -span, -stride
+	}
+	if (span+stride-1)/stride > maxSafeListSize {
+		return nil, fmt.Errorf("list exceeds %d elements", maxSafeListSize)
+	}
+
+	for i := start; (step > 0 && i < stop) || (step < 0 && i > stop); i += step {
+		res = append(res, i)
+	}
+
+	return res, nil
+}
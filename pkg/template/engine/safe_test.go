@@ -0,0 +1,139 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderSafe(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		input     string
+		timeout   time.Duration
+		wantLimit string
+		wantErr   string
+		want      string
+	}{
+		{
+			desc:    "valid",
+			input:   `{{ range until 3 }}{{ . }}{{ end }}{{ repeat 2 "a" }}`,
+			timeout: time.Second,
+			want:    "012aa",
+		},
+		{
+			desc:      "timeout",
+			input:     `{{ range until 65536 }}{{ range until 65536 }}{{ end }}{{ end }}`,
+			timeout:   50 * time.Millisecond,
+			wantLimit: LimitTimeout,
+		},
+		{
+			desc:      "output size",
+			input:     `{{ range until 65536 }}{{ repeat 1024 "a" }}{{ end }}`,
+			timeout:   time.Minute,
+			wantLimit: LimitOutputSize,
+		},
+		{
+			desc:    "oversized list",
+			input:   `{{ range until 100000000 }}{{ end }}`,
+			timeout: time.Minute,
+			wantErr: "list exceeds",
+		},
+		{
+			desc:    "oversized list step",
+			input:   `{{ range untilStep 100000000 0 -1 }}{{ end }}`,
+			timeout: time.Minute,
+			wantErr: "list exceeds",
+		},
+		{
+			desc:    "oversized string",
+			input:   `{{ repeat 100000000 "aa" }}`,
+			timeout: time.Minute,
+			wantErr: "repeated string exceeds",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			start := time.Now()
+			got, err := RenderSafe("config", tC.input, nil, nil, tC.timeout)
+
+			// Rendering is stopped when the call returns
+			if elapsed := time.Since(start); elapsed > 10*time.Second {
+				t.Errorf("rendering took %s", elapsed)
+			}
+
+			switch {
+			case tC.wantLimit != "":
+				var le *LimitError
+				if !errors.As(err, &le) || le.Limit != tC.wantLimit {
+					t.Fatalf("expected %s limit error, got %v", tC.wantLimit, err)
+				}
+			case tC.wantErr != "":
+				if err == nil || !strings.Contains(err.Error(), tC.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tC.wantErr, err)
+				}
+			case err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case got != tC.want:
+				t.Errorf("expected %q, got %q", tC.want, got)
+			}
+		})
+	}
+}
+
+func TestSafeUntilStep(t *testing.T) {
+	testCases := []struct {
+		start, stop, step int
+		want              []int
+	}{
+		{start: 0, stop: 3, step: 1, want: []int{0, 1, 2}},
+		{start: 0, stop: -3, step: -1, want: []int{0, -1, -2}},
+		{start: 0, stop: 7, step: 3, want: []int{0, 3, 6}},
+		{start: 3, stop: 0, step: 1, want: []int{}},
+		{start: 0, stop: 3, step: 0, want: []int{}},
+		{start: 0, stop: maxSafeListSize, step: 1},
+	}
+	for _, tC := range testCases {
+		got, err := safeUntilStep(tC.start, tC.stop, tC.step)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if tC.want == nil {
+			if len(got) != maxSafeListSize {
+				t.Errorf("expected %d elements, got %d", maxSafeListSize, len(got))
+			}
+			continue
+		}
+		if len(got) != len(tC.want) {
+			t.Fatalf("untilStep(%d, %d, %d): expected %v, got %v", tC.start, tC.stop, tC.step, tC.want, got)
+		}
+		for i := range got {
+			if got[i] != tC.want[i] {
+				t.Errorf("untilStep(%d, %d, %d): expected %v, got %v", tC.start, tC.stop, tC.step, tC.want, got)
+				break
+			}
+		}
+	}
+
+	if _, err := safeUntilStep(0, maxSafeListSize+1, 1); err == nil {
+		t.Error("error expected for oversized list")
+	}
+}
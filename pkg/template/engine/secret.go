@@ -19,6 +19,8 @@ package engine
 
 import (
 	"fmt"
+
+	"github.com/elastic/harp/pkg/template/consumption"
 )
 
 // SecretReaderFunc is a function to retrieve a secret from a given path.
@@ -27,11 +29,14 @@ type SecretReaderFunc func(path string) (map[string]interface{}, error)
 // SecretReaders uses given secret reader funcs to resolve secret path.
 func SecretReaders(secretReaders []SecretReaderFunc) func(string) (map[string]interface{}, error) {
 	return func(secretPath string) (map[string]interface{}, error) {
+		var lastErr error
+
 		// For all secret readers
 		for _, sr := range secretReaders {
 			value, err := sr(secretPath)
 			if err != nil {
 				// Check next secret reader
+				lastErr = err
 				continue
 			}
 
@@ -40,6 +45,49 @@ func SecretReaders(secretReaders []SecretReaderFunc) func(string) (map[string]in
 		}
 
 		// Return error
+		if lastErr != nil {
+			return nil, fmt.Errorf("no value found for '%s', check secret path or secret reader settings: %w", secretPath, lastErr)
+		}
 		return nil, fmt.Errorf("no value found for '%s', check secret path or secret reader settings", secretPath)
 	}
 }
+
+// -----------------------------------------------------------------------------
+
+// contextSecretReaders returns the secret readers of the given context,
+// recording read secret values when a consumption tracker is defined.
+func contextSecretReaders(templateContext Context) []SecretReaderFunc {
+	readers := templateContext.SecretReaders()
+
+	tracker := templateContext.ConsumptionTracker()
+	if tracker == nil {
+		return readers
+	}
+
+	sources := templateContext.SecretSources()
+	tracked := make([]SecretReaderFunc, len(readers))
+	for i, sr := range readers {
+		tracked[i] = trackedSecretReader(tracker, sources[i], sr)
+	}
+
+	return tracked
+}
+
+// trackedSecretReader returns a secret reader recording all values of read
+// secrets, template usage of each value can't be known.
+func trackedSecretReader(tracker *consumption.Tracker, source string, sr SecretReaderFunc) SecretReaderFunc {
+	return func(secretPath string) (map[string]interface{}, error) {
+		value, err := sr(secretPath)
+		if err != nil {
+			return nil, err
+		}
+
+		// Record consumed values
+		if err := tracker.TrackAll(source, secretPath, value); err != nil {
+			return nil, fmt.Errorf("unable to record secret consumption: %w", err)
+		}
+
+		// No error
+		return value, nil
+	}
+}
@@ -0,0 +1,144 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+// NoValueSentinel is the text emitted by the template engine for missing
+// values.
+const NoValueSentinel = "<no value>"
+
+// NilArgumentError is raised when a value generator or a key encoder is
+// called with a nil argument in strict mode.
+type NilArgumentError struct {
+	Func     string
+	Position int
+}
+
+// Error returns the error message.
+func (e *NilArgumentError) Error() string {
+	return fmt.Sprintf("argument %d of '%s' is nil, check the referenced value exists", e.Position, e.Func)
+}
+
+// NoValueError is raised when the rendered output contains the missing value
+// sentinel in strict mode.
+type NoValueError struct {
+	Template string
+	Line     int
+}
+
+// Error returns the error message.
+func (e *NoValueError) Error() string {
+	return fmt.Sprintf("template '%s' rendered a missing value as %q at output line %d", e.Template, NoValueSentinel, e.Line)
+}
+
+// -----------------------------------------------------------------------------
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// strictFuncs wraps value generators and key encoders of the given function
+// map to reject nil arguments.
+func strictFuncs(fm template.FuncMap) template.FuncMap {
+	res := template.FuncMap{}
+	for name := range generatorFuncs {
+		if fn, ok := fm[name]; ok {
+			res[name] = rejectNilArgs(name, fn)
+		}
+	}
+	for _, name := range keyEncoderFuncs {
+		if fn, ok := fm[name]; ok {
+			res[name] = rejectNilArgs(name, fn)
+		}
+	}
+
+	return res
+}
+
+// rejectNilArgs returns a function with the same signature as the given one,
+// failing when called with a nil argument. The failure is returned as the
+// function error when declared, or raised as a panic recovered and reported
+// by the template engine with the template name and position.
+func rejectNilArgs(name string, fn interface{}) interface{} {
+	fv := reflect.ValueOf(fn)
+	ft := fv.Type()
+	returnsErr := ft.NumOut() == 2 && ft.Out(1) == errorType
+
+	return reflect.MakeFunc(ft, func(args []reflect.Value) []reflect.Value {
+		// Check arguments
+		position := 0
+		for i, arg := range args {
+			// Check variadic arguments individually
+			if ft.IsVariadic() && i == len(args)-1 {
+				for j := 0; j < arg.Len(); j++ {
+					position++
+					if isNilValue(arg.Index(j)) {
+						return failCall(ft, returnsErr, &NilArgumentError{Func: name, Position: position})
+					}
+				}
+				continue
+			}
+
+			position++
+			if isNilValue(arg) {
+				return failCall(ft, returnsErr, &NilArgumentError{Func: name, Position: position})
+			}
+		}
+
+		// Delegate to the wrapped function
+		if ft.IsVariadic() {
+			return fv.CallSlice(args)
+		}
+		return fv.Call(args)
+	}).Interface()
+}
+
+// isNilValue returns true for missing values given as untyped arguments. Nil
+// slices and maps are valid empty collections.
+func isNilValue(v reflect.Value) bool {
+	return v.Kind() == reflect.Interface && v.IsNil()
+}
+
+func failCall(ft reflect.Type, returnsErr bool, err error) []reflect.Value {
+	if !returnsErr {
+		panic(err)
+	}
+
+	return []reflect.Value{
+		reflect.Zero(ft.Out(0)),
+		reflect.ValueOf(&err).Elem(),
+	}
+}
+
+// checkNoValue returns an error when the rendered output contains the missing
+// value sentinel.
+func checkNoValue(name, out string) error {
+	idx := strings.Index(out, NoValueSentinel)
+	if idx < 0 {
+		return nil
+	}
+
+	return &NoValueError{
+		Template: name,
+		Line:     strings.Count(out[:idx], "\n") + 1,
+	}
+}
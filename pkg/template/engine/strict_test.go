@@ -0,0 +1,156 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package engine
+
+import (
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestRenderContext_Strict(t *testing.T) {
+	values := Values{
+		"name":  "harp",
+		"empty": nil,
+		"nested": map[string]interface{}{
+			"key": nil,
+		},
+	}
+
+	testCases := []struct {
+		desc     string
+		input    string
+		strict   bool
+		want     string
+		wantErr  string
+		wantNil  bool
+		wantNone bool
+	}{
+		{
+			desc:    "missing key",
+			input:   "{{ .Values.password }}",
+			strict:  true,
+			wantErr: `map has no entry for key "password"`,
+		},
+		{
+			desc:     "nil pipeline result",
+			input:    "user: {{ .Values.name }}\npassword: {{ .Values.empty }}",
+			strict:   true,
+			wantNone: true,
+		},
+		{
+			desc:     "nil nested value",
+			input:    "{{ .Values.nested.key }}",
+			strict:   true,
+			wantNone: true,
+		},
+		{
+			desc:    "nil generator argument",
+			input:   "{\n  \"key\": {{ toJwk .Values.nested.key | quote }}\n}",
+			strict:  true,
+			wantErr: "template: fixture.yaml:2:",
+			wantNil: true,
+		},
+		{
+			desc:    "nil key encoder argument",
+			input:   `{{ sshFingerprint "sha256" .Values.empty }}`,
+			strict:  true,
+			wantErr: "argument 2 of 'sshFingerprint' is nil",
+			wantNil: true,
+		},
+		{
+			desc:   "valid",
+			input:  "{{ .Values.name }}",
+			strict: true,
+			want:   "harp",
+		},
+		{
+			desc:  "lenient",
+			input: "{{ .Values.password }}/{{ .Values.empty }}",
+			want:  "<no value>/<no value>",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctx := NewContext(
+				WithName("fixture.yaml"),
+				WithValues(values),
+				WithStrictMode(tC.strict),
+			)
+
+			got, err := RenderContext(ctx, tC.input)
+			switch {
+			case tC.wantNone:
+				var noValue *NoValueError
+				if !errors.As(err, &noValue) {
+					t.Fatalf("expected a missing value error, got %v", err)
+				}
+				if noValue.Template != "fixture.yaml" {
+					t.Errorf("unexpected template name '%s'", noValue.Template)
+				}
+			case tC.wantErr != "":
+				if err == nil || !strings.Contains(err.Error(), tC.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tC.wantErr, err)
+				}
+				var nilArg *NilArgumentError
+				if tC.wantNil && !errors.As(err, &nilArg) {
+					t.Errorf("expected a nil argument error, got %v", err)
+				}
+			case err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case got != tC.want:
+				t.Errorf("expected %q, got %q", tC.want, got)
+			}
+		})
+	}
+}
+
+func TestRejectNilArgs(t *testing.T) {
+	// Function without error result
+	fn := rejectNilArgs("describe", func(prefix string, values ...interface{}) string {
+		return prefix
+	})
+	tpl := template.Must(template.New("fixture.yaml").Funcs(template.FuncMap{"describe": fn}).Parse(`{{ describe "key" "a" .missing }}`))
+
+	err := tpl.Execute(ioutil.Discard, map[string]interface{}{"missing": nil})
+	if err == nil || !strings.Contains(err.Error(), "template: fixture.yaml:1:3") || !strings.Contains(err.Error(), "argument 3 of 'describe' is nil") {
+		t.Fatalf("expected a positioned nil argument error, got %v", err)
+	}
+
+	// Valid arguments
+	if err := tpl.Execute(ioutil.Discard, map[string]interface{}{"missing": "b"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestCheckNoValue(t *testing.T) {
+	err := checkNoValue("fixture.yaml", "user: harp\npassword: <no value>\n")
+
+	var noValue *NoValueError
+	if !errors.As(err, &noValue) {
+		t.Fatalf("expected a missing value error, got %v", err)
+	}
+	if noValue.Line != 2 {
+		t.Errorf("expected line 2, got %d", noValue.Line)
+	}
+	if err := checkNoValue("fixture.yaml", "user: harp\n"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package generators provides the value generator registry used by the
+// `generate` template function.
+package generators
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrGeneratorAlreadyRegistered is raised when a generator name is already used.
+	ErrGeneratorAlreadyRegistered = errors.New("generators: generator already registered")
+	// ErrGeneratorNotFound is raised when the requested generator is not registered.
+	ErrGeneratorNotFound = errors.New("generators: generator not found")
+	// ErrInvalidParams is raised when parameters don't match the generator schema.
+	ErrInvalidParams = errors.New("generators: invalid parameters")
+)
+
+// Params describes generator parameters.
+type Params map[string]interface{}
+
+// Generator describes value generator contract.
+type Generator interface {
+	Generate(ctx context.Context, params Params) (interface{}, error)
+}
+
+// GeneratorFunc wraps a function as a Generator.
+type GeneratorFunc func(ctx context.Context, params Params) (interface{}, error)
+
+// Generate calls the wrapped function.
+func (f GeneratorFunc) Generate(ctx context.Context, params Params) (interface{}, error) {
+	return f(ctx, params)
+}
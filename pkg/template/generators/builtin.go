@@ -0,0 +1,91 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package generators
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/sethvargo/go-password/password"
+)
+
+const (
+	// maxBytesSize defines the upper bound for random bytes generation.
+	maxBytesSize = 1024
+)
+
+func init() {
+	MustRegister("harp.password", GeneratorFunc(generatePassword), Schema{
+		{Name: "length", Type: TypeInt, Default: 32},
+		{Name: "digits", Type: TypeInt, Default: 10},
+		{Name: "symbols", Type: TypeInt, Default: 10},
+		{Name: "noUpper", Type: TypeBool, Default: false},
+		{Name: "allowRepeat", Type: TypeBool, Default: true},
+	})
+	MustRegister("harp.bytes", GeneratorFunc(generateBytes), Schema{
+		{Name: "size", Type: TypeInt, Default: 32},
+		{Name: "encoding", Type: TypeString, Default: "base64"},
+	})
+}
+
+// -----------------------------------------------------------------------------
+
+func generatePassword(ctx context.Context, params Params) (interface{}, error) {
+	g, err := password.NewGenerator(&password.GeneratorInput{
+		Reader: Entropy(ctx),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize password generator: %w", err)
+	}
+
+	return g.Generate(
+		params["length"].(int),
+		params["digits"].(int),
+		params["symbols"].(int),
+		params["noUpper"].(bool),
+		params["allowRepeat"].(bool),
+	)
+}
+
+func generateBytes(ctx context.Context, params Params) (interface{}, error) {
+	size := params["size"].(int)
+	if size <= 0 || size > maxBytesSize {
+		return nil, fmt.Errorf("size must be between 1 and %d", maxBytesSize)
+	}
+
+	// Read entropy
+	raw := make([]byte, size)
+	if _, err := io.ReadFull(Entropy(ctx), raw); err != nil {
+		return nil, fmt.Errorf("unable to read entropy: %w", err)
+	}
+
+	// Encode result
+	switch params["encoding"].(string) {
+	case "base64":
+		return base64.StdEncoding.EncodeToString(raw), nil
+	case "base64url":
+		return base64.RawURLEncoding.EncodeToString(raw), nil
+	case "hex":
+		return hex.EncodeToString(raw), nil
+	default:
+		return nil, fmt.Errorf("unsupported encoding '%s'", params["encoding"])
+	}
+}
@@ -0,0 +1,56 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package generators
+
+import (
+	"context"
+	"crypto/rand"
+	"io"
+)
+
+type contextKey string
+
+const (
+	entropyKey  = contextKey("entropy")
+	recorderKey = contextKey("recorder")
+)
+
+// WithEntropy returns a context using the given entropy source for value
+// generation. It is used to get deterministic values.
+func WithEntropy(ctx context.Context, r io.Reader) context.Context {
+	return context.WithValue(ctx, entropyKey, r)
+}
+
+// Entropy returns the context entropy source, or the system CSPRNG.
+func Entropy(ctx context.Context) io.Reader {
+	if r, ok := ctx.Value(entropyKey).(io.Reader); ok && r != nil {
+		return r
+	}
+	return rand.Reader
+}
+
+// WithRecorder returns a context recording generator provenances.
+func WithRecorder(ctx context.Context, rec *Recorder) context.Context {
+	return context.WithValue(ctx, recorderKey, rec)
+}
+
+// RecorderFrom returns the context provenance recorder if any.
+func RecorderFrom(ctx context.Context) *Recorder {
+	rec, _ := ctx.Value(recorderKey).(*Recorder)
+	return rec
+}
@@ -0,0 +1,74 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package dbcredentials is an example of an external value generator.
+//
+// Import it for side effects to expose the `myorg.dbCredentials` generator:
+//
+//	import _ "github.com/elastic/harp/pkg/template/generators/dbcredentials"
+//
+// Then use it from templates:
+//
+//	{{ generate "myorg.dbCredentials" (dict "role" "readonly") }}
+package dbcredentials
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sethvargo/go-password/password"
+
+	"github.com/elastic/harp/pkg/template/generators"
+)
+
+// Name is the generator name.
+const Name = "myorg.dbCredentials"
+
+func init() {
+	generators.MustRegister(Name, generators.GeneratorFunc(generate), generators.Schema{
+		{Name: "role", Type: generators.TypeString, Required: true},
+		{Name: "prefix", Type: generators.TypeString, Default: "app"},
+	})
+}
+
+// generate returns a database user bound to the given role.
+func generate(ctx context.Context, params generators.Params) (interface{}, error) {
+	role := params["role"].(string)
+	switch role {
+	case "readonly", "readwrite", "admin":
+	default:
+		return nil, fmt.Errorf("unsupported role '%s'", role)
+	}
+
+	// Generate password from context entropy
+	g, err := password.NewGenerator(&password.GeneratorInput{
+		Reader: generators.Entropy(ctx),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize password generator: %w", err)
+	}
+	pass, err := g.Generate(32, 8, 0, false, true)
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate password: %w", err)
+	}
+
+	return map[string]interface{}{
+		"username": fmt.Sprintf("%s_%s", params["prefix"], role),
+		"password": pass,
+		"role":     role,
+	}, nil
+}
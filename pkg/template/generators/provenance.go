@@ -0,0 +1,114 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package generators
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ProvenanceAnnotation holds generators used to build a secret.
+const ProvenanceAnnotation = "harp.elastic.co/v1/generator#provenance"
+
+// Provenance describes a generator invocation.
+type Provenance struct {
+	Generator    string
+	ParamsDigest string
+}
+
+// NewProvenance returns the provenance of a generator call.
+func NewProvenance(name string, params Params) (Provenance, error) {
+	// Map keys are sorted by the encoder
+	payload, err := json.Marshal(params)
+	if err != nil {
+		return Provenance{}, fmt.Errorf("unable to encode '%s' parameters: %w", name, err)
+	}
+	digest := sha256.Sum256(payload)
+
+	return Provenance{
+		Generator:    name,
+		ParamsDigest: fmt.Sprintf("sha256:%s", hex.EncodeToString(digest[:])),
+	}, nil
+}
+
+// NewContentProvenance returns the provenance of a document retrieved by a
+// template function, identified by its source and content digest.
+func NewContentProvenance(name, source string, content []byte) Provenance {
+	digest := sha256.Sum256(content)
+
+	return Provenance{
+		Generator:    fmt.Sprintf("%s(%s)", name, source),
+		ParamsDigest: fmt.Sprintf("sha256:%s", hex.EncodeToString(digest[:])),
+	}
+}
+
+// String returns the provenance as `<generator>@<params digest>`.
+func (p Provenance) String() string {
+	return fmt.Sprintf("%s@%s", p.Generator, p.ParamsDigest)
+}
+
+// Recorder collects generator provenances.
+type Recorder struct {
+	mu      sync.Mutex
+	entries map[Provenance]struct{}
+}
+
+// Record the given provenance.
+func (r *Recorder) Record(p Provenance) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.entries == nil {
+		r.entries = map[Provenance]struct{}{}
+	}
+	r.entries[p] = struct{}{}
+}
+
+// Entries returns recorded provenances sorted by their string form.
+func (r *Recorder) Entries() []Provenance {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	res := make([]Provenance, 0, len(r.entries))
+	for p := range r.entries {
+		res = append(res, p)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].String() < res[j].String()
+	})
+
+	return res
+}
+
+// Annotation returns the provenance annotation value, or an empty string
+// when nothing has been recorded.
+func (r *Recorder) Annotation() string {
+	entries := r.Entries()
+
+	values := make([]string, len(entries))
+	for i, p := range entries {
+		values[i] = p.String()
+	}
+
+	return strings.Join(values, ",")
+}
@@ -0,0 +1,117 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package generators
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+type registration struct {
+	generator Generator
+	schema    Schema
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]registration{}
+)
+
+// Register a generator with its parameter schema.
+func Register(name string, g Generator, schema Schema) error {
+	// Check arguments
+	if name == "" {
+		return fmt.Errorf("unable to register a generator with a blank name")
+	}
+	if g == nil {
+		return fmt.Errorf("unable to register a nil generator for '%s'", name)
+	}
+	if err := schema.Validate(); err != nil {
+		return fmt.Errorf("unable to register generator '%s': %w", name, err)
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, ok := registry[name]; ok {
+		return fmt.Errorf("unable to register generator '%s': %w", name, ErrGeneratorAlreadyRegistered)
+	}
+
+	registry[name] = registration{generator: g, schema: schema}
+
+	return nil
+}
+
+// MustRegister a generator and panic on error.
+func MustRegister(name string, g Generator, schema Schema) {
+	if err := Register(name, g, schema); err != nil {
+		panic(err)
+	}
+}
+
+// Names returns sorted registered generator names.
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	res := make([]string, 0, len(registry))
+	for name := range registry {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+// Generate a value using the named generator. Parameters are validated
+// against the generator schema before the call, and the provenance is added
+// to the context recorder if any.
+func Generate(ctx context.Context, name string, params Params) (interface{}, error) {
+	registryMu.RLock()
+	reg, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unable to generate value with '%s': %w", name, ErrGeneratorNotFound)
+	}
+
+	// Validate parameters
+	normalized, err := reg.schema.Apply(params)
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate value with '%s': %w", name, err)
+	}
+
+	// Delegate to generator
+	value, err := reg.generator.Generate(ctx, normalized)
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate value with '%s': %w", name, err)
+	}
+
+	// Record provenance
+	if rec := RecorderFrom(ctx); rec != nil {
+		p, err := NewProvenance(name, normalized)
+		if err != nil {
+			return nil, err
+		}
+		rec.Record(p)
+	}
+
+	// No error
+	return value, nil
+}
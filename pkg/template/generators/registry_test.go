@@ -0,0 +1,160 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package generators_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/elastic/harp/pkg/template/generators"
+	"github.com/elastic/harp/pkg/template/generators/dbcredentials"
+)
+
+func constant(value interface{}) generators.Generator {
+	return generators.GeneratorFunc(func(context.Context, generators.Params) (interface{}, error) {
+		return value, nil
+	})
+}
+
+func TestRegister(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		name    string
+		gen     generators.Generator
+		schema  generators.Schema
+		wantErr error
+	}{
+		{desc: "blank name", name: "", gen: constant(1)},
+		{desc: "nil generator", name: "test.nil", gen: nil},
+		{desc: "duplicate parameter", name: "test.dup", gen: constant(1), schema: generators.Schema{
+			{Name: "a", Type: generators.TypeInt},
+			{Name: "a", Type: generators.TypeString},
+		}},
+		{desc: "unsupported type", name: "test.type", gen: constant(1), schema: generators.Schema{
+			{Name: "a", Type: "float"},
+		}},
+		{desc: "invalid default", name: "test.default", gen: constant(1), schema: generators.Schema{
+			{Name: "a", Type: generators.TypeInt, Default: "1"},
+		}},
+		{desc: "required with default", name: "test.required", gen: constant(1), schema: generators.Schema{
+			{Name: "a", Type: generators.TypeInt, Required: true, Default: 1},
+		}},
+		{desc: "builtin conflict", name: "harp.password", gen: constant(1), wantErr: generators.ErrGeneratorAlreadyRegistered},
+		{desc: "external conflict", name: dbcredentials.Name, gen: constant(1), wantErr: generators.ErrGeneratorAlreadyRegistered},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			err := generators.Register(tC.name, tC.gen, tC.schema)
+			if err == nil {
+				t.Fatal("error expected")
+			}
+			if tC.wantErr != nil && !errors.Is(err, tC.wantErr) {
+				t.Errorf("expected %v, got %v", tC.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	generators.MustRegister("test.echo", generators.GeneratorFunc(func(_ context.Context, p generators.Params) (interface{}, error) {
+		return p, nil
+	}), generators.Schema{
+		{Name: "name", Type: generators.TypeString, Required: true},
+		{Name: "count", Type: generators.TypeInt, Default: 2},
+		{Name: "enabled", Type: generators.TypeBool},
+	})
+
+	testCases := []struct {
+		desc    string
+		name    string
+		params  generators.Params
+		want    interface{}
+		wantErr error
+	}{
+		{desc: "unknown generator", name: "test.unknown", wantErr: generators.ErrGeneratorNotFound},
+		{desc: "missing required", name: "test.echo", params: generators.Params{}, wantErr: generators.ErrInvalidParams},
+		{desc: "unknown parameter", name: "test.echo", params: generators.Params{"name": "a", "other": 1}, wantErr: generators.ErrInvalidParams},
+		{desc: "invalid type", name: "test.echo", params: generators.Params{"name": "a", "count": "2"}, wantErr: generators.ErrInvalidParams},
+		{desc: "defaults", name: "test.echo", params: generators.Params{"name": "a"}, want: generators.Params{"name": "a", "count": 2}},
+		{desc: "float integer", name: "test.echo", params: generators.Params{"name": "a", "count": float64(5), "enabled": true}, want: generators.Params{"name": "a", "count": 5, "enabled": true}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			got, err := generators.Generate(context.Background(), tC.name, tC.params)
+			if tC.wantErr != nil {
+				if !errors.Is(err, tC.wantErr) {
+					t.Fatalf("expected %v, got %v", tC.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(got, tC.want); diff != "" {
+				t.Errorf("%q. Generate():\n-got/+want\ndiff %s", tC.desc, diff)
+			}
+		})
+	}
+}
+
+func TestGenerate_DeterministicEntropy(t *testing.T) {
+	seed := bytes.Repeat([]byte{0x42}, 4096)
+	generate := func() interface{} {
+		ctx := generators.WithEntropy(context.Background(), bytes.NewReader(seed))
+		v, err := generators.Generate(ctx, dbcredentials.Name, generators.Params{"role": "readonly"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return v
+	}
+
+	first, second := generate(), generate()
+	if diff := cmp.Diff(first, second); diff != "" {
+		t.Errorf("values must be identical with the same entropy source:\n%s", diff)
+	}
+	if got := first.(map[string]interface{})["username"]; got != "app_readonly" {
+		t.Errorf("unexpected username %v", got)
+	}
+}
+
+func TestGenerate_Provenance(t *testing.T) {
+	rec := &generators.Recorder{}
+	ctx := generators.WithRecorder(context.Background(), rec)
+
+	for _, params := range []generators.Params{{"size": 16}, {"size": 16}, {"size": 16, "encoding": "hex"}} {
+		if _, err := generators.Generate(ctx, "harp.bytes", params); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// Explicit default value has the same provenance
+	p1, _ := generators.NewProvenance("harp.bytes", generators.Params{"size": 16, "encoding": "base64"})
+	p2, _ := generators.NewProvenance("harp.bytes", generators.Params{"size": 16, "encoding": "hex"})
+	want := []generators.Provenance{p1, p2}
+	if p2.String() < p1.String() {
+		want = []generators.Provenance{p2, p1}
+	}
+
+	if diff := cmp.Diff(rec.Entries(), want); diff != "" {
+		t.Errorf("Entries():\n-got/+want\ndiff %s", diff)
+	}
+}
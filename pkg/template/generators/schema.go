@@ -0,0 +1,157 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package generators
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ParamType describes a parameter value type.
+type ParamType string
+
+const (
+	// TypeString describes a string parameter.
+	TypeString ParamType = "string"
+	// TypeInt describes an integer parameter.
+	TypeInt ParamType = "int"
+	// TypeBool describes a boolean parameter.
+	TypeBool ParamType = "bool"
+)
+
+// ParamSpec describes a generator parameter.
+type ParamSpec struct {
+	Name     string
+	Type     ParamType
+	Required bool
+	Default  interface{}
+}
+
+// Schema describes all parameters accepted by a generator.
+type Schema []ParamSpec
+
+// Validate the schema definition.
+func (s Schema) Validate() error {
+	names := map[string]struct{}{}
+	for _, spec := range s {
+		// Check name
+		if spec.Name == "" {
+			return fmt.Errorf("parameter name must not be blank")
+		}
+		if _, ok := names[spec.Name]; ok {
+			return fmt.Errorf("parameter '%s' is declared more than once", spec.Name)
+		}
+		names[spec.Name] = struct{}{}
+
+		// Check type
+		switch spec.Type {
+		case TypeString, TypeInt, TypeBool:
+		default:
+			return fmt.Errorf("parameter '%s' has an unsupported type '%s'", spec.Name, spec.Type)
+		}
+
+		// Check default value
+		if spec.Default != nil {
+			if spec.Required {
+				return fmt.Errorf("required parameter '%s' must not have a default value", spec.Name)
+			}
+			if _, err := coerce(spec.Type, spec.Default); err != nil {
+				return fmt.Errorf("parameter '%s' default value is invalid: %w", spec.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Apply validates the given parameters and returns them normalized with
+// default values.
+func (s Schema) Apply(params Params) (Params, error) {
+	res := Params{}
+
+	// Check unknown parameters
+	specs := map[string]ParamSpec{}
+	for _, spec := range s {
+		specs[spec.Name] = spec
+	}
+	unknown := []string{}
+	for name := range params {
+		if _, ok := specs[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown parameter(s) '%s': %w", strings.Join(unknown, "', '"), ErrInvalidParams)
+	}
+
+	for _, spec := range s {
+		value, ok := params[spec.Name]
+		if !ok || value == nil {
+			if spec.Required {
+				return nil, fmt.Errorf("parameter '%s' is required: %w", spec.Name, ErrInvalidParams)
+			}
+			if spec.Default != nil {
+				v, _ := coerce(spec.Type, spec.Default)
+				res[spec.Name] = v
+			}
+			continue
+		}
+
+		// Check value type
+		v, err := coerce(spec.Type, value)
+		if err != nil {
+			return nil, fmt.Errorf("parameter '%s' is invalid: %v: %w", spec.Name, err, ErrInvalidParams)
+		}
+		res[spec.Name] = v
+	}
+
+	return res, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func coerce(t ParamType, value interface{}) (interface{}, error) {
+	switch t {
+	case TypeString:
+		if v, ok := value.(string); ok {
+			return v, nil
+		}
+	case TypeBool:
+		if v, ok := value.(bool); ok {
+			return v, nil
+		}
+	case TypeInt:
+		switch v := value.(type) {
+		case int:
+			return v, nil
+		case int32:
+			return int(v), nil
+		case int64:
+			return int(v), nil
+		case float64:
+			if v == math.Trunc(v) {
+				return int(v), nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("%T value is not a valid %s", value, t)
+}
@@ -27,6 +27,9 @@ var (
 	ErrPathNotFound = errors.New("path not found")
 	// ErrNoData is raised when gievn secret path doesn't contains data.
 	ErrNoData = errors.New("no data")
+	// ErrCASMismatch is raised when a check-and-set write is refused because
+	// the secret has been modified since the expected version.
+	ErrCASMismatch = errors.New("check-and-set version mismatch")
 )
 
 // Secrets is a secret body
@@ -47,6 +50,25 @@ type SecretWriter interface {
 	Write(ctx context.Context, path string, secrets Secrets) error
 }
 
+// SecretVersionReader represents secret current version reader feature
+// contract. A missing secret has version 0.
+type SecretVersionReader interface {
+	ReadVersion(ctx context.Context, path string) (uint64, error)
+}
+
+// SecretCASWriter represents check-and-set secret writer feature contract.
+// The write is refused with ErrCASMismatch when the current secret version
+// doesn't match the given one.
+type SecretCASWriter interface {
+	WriteWithCAS(ctx context.Context, path string, secrets Secrets, version uint64) error
+}
+
+// SecretMetadataWriter represents secret custom metadata writer feature
+// contract.
+type SecretMetadataWriter interface {
+	WriteCustomMetadata(ctx context.Context, path string, metadata map[string]string) error
+}
+
 // Service declares vault service contract.
 type Service interface {
 	SecretLister
@@ -19,7 +19,14 @@ package kv
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
 
 	"github.com/elastic/harp/pkg/vault/logical"
 	vpath "github.com/elastic/harp/pkg/vault/path"
@@ -30,7 +37,8 @@ type kvv2Backend struct {
 	mountPath string
 }
 
-// V2 returns a K/V v2 backend service instance.
+// V2 returns a K/V v2 backend service instance. It also implements
+// SecretVersionReader, SecretCASWriter and SecretMetadataWriter.
 func V2(l logical.Logical, mountPath string) Service {
 	return &kvv2Backend{
 		logical:   l,
@@ -132,3 +140,94 @@ func (s *kvv2Backend) Write(ctx context.Context, path string, data Secrets) erro
 
 	return nil
 }
+
+func (s *kvv2Backend) ReadVersion(ctx context.Context, path string) (uint64, error) {
+	// Clean path first
+	secretPath := vpath.SanitizePath(path)
+	if secretPath == "" {
+		return 0, fmt.Errorf("unable to query with empty path")
+	}
+
+	// Retrieve secret metadata
+	secret, err := s.logical.Read(vpath.AddPrefixToVKVPath(secretPath, s.mountPath, "metadata"))
+	if err != nil {
+		return 0, fmt.Errorf("unable to retrieve secret metadata for path '%s': %w", path, err)
+	}
+	if secret == nil || secret.Data == nil {
+		// Secret doesn't exist
+		return 0, nil
+	}
+
+	// Extract current version
+	switch v := secret.Data["current_version"].(type) {
+	case json.Number:
+		version, err := strconv.ParseUint(v.String(), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid current version for path '%s': %w", path, err)
+		}
+		return version, nil
+	case float64:
+		return uint64(v), nil
+	case nil:
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("invalid current version type for path '%s' (%T)", path, v)
+	}
+}
+
+func (s *kvv2Backend) WriteWithCAS(ctx context.Context, path string, data Secrets, version uint64) error {
+	// Clean path first
+	secretPath := vpath.SanitizePath(path)
+	if secretPath == "" {
+		return fmt.Errorf("unable to query with empty path")
+	}
+
+	// Create a logical client
+	_, err := s.logical.Write(vpath.AddPrefixToVKVPath(secretPath, s.mountPath, "data"), map[string]interface{}{
+		"data": data,
+		"options": map[string]interface{}{
+			"cas": version,
+		},
+	})
+	if err != nil {
+		if isCASMismatch(err) {
+			return fmt.Errorf("unable to write secret data for path '%s' at version %d: %w", path, version, ErrCASMismatch)
+		}
+		return fmt.Errorf("unable to write secret data for path '%s': %w", path, err)
+	}
+
+	return nil
+}
+
+func (s *kvv2Backend) WriteCustomMetadata(ctx context.Context, path string, metadata map[string]string) error {
+	// Clean path first
+	secretPath := vpath.SanitizePath(path)
+	if secretPath == "" {
+		return fmt.Errorf("unable to query with empty path")
+	}
+
+	// Update metadata only, other settings are preserved
+	_, err := s.logical.Write(vpath.AddPrefixToVKVPath(secretPath, s.mountPath, "metadata"), map[string]interface{}{
+		"custom_metadata": metadata,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to write secret metadata for path '%s': %w", path, err)
+	}
+
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func isCASMismatch(err error) bool {
+	var respErr *api.ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode != http.StatusBadRequest {
+		return false
+	}
+	for _, msg := range respErr.Errors {
+		if strings.Contains(msg, "check-and-set") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/refs"
+	"github.com/elastic/harp/pkg/bundle/secret"
+)
+
+// ACLPackagePrefix prefixes the meta packages holding converted policy rules.
+const ACLPackagePrefix = "meta/acl/"
+
+// Apply converted policies to the given bundle.
+//
+// Each policy is stored as a 'meta/acl/<policy>' package holding its rules,
+// and packages readable through a policy reference it using the ACL
+// annotation. The highest priority rule matching a package path decides, as
+// Vault does.
+func Apply(b *bundlev1.Bundle, doc *Document) error {
+	// Check arguments
+	if b == nil {
+		return errors.New("unable to apply policies to a nil bundle")
+	}
+	if doc == nil {
+		return errors.New("unable to apply nil policies")
+	}
+
+	for _, acl := range doc.Spec.Policies {
+		// Compile rules
+		matchers := make([]*regexp.Regexp, len(acl.Rules))
+		for i, r := range acl.Rules {
+			re, err := regexp.Compile(r.Regex)
+			if err != nil {
+				return fmt.Errorf("unable to compile rule '%s' of policy '%s': %w", r.Path, acl.Name, err)
+			}
+			matchers[i] = re
+		}
+
+		// Annotate readable packages
+		aclPath := ACLPackagePrefix + acl.Name
+		for _, p := range b.Packages {
+			if p == nil || strings.HasPrefix(p.Name, "meta/") {
+				continue
+			}
+			if r := matchRule(acl.Rules, matchers, p.Name); r != nil && hasCapability(r, CapabilityRead) {
+				addACL(p, aclPath)
+			}
+		}
+
+		// Store policy rules
+		if err := upsertACLPackage(b, aclPath, acl); err != nil {
+			return err
+		}
+	}
+
+	// No error
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+// matchRule returns the highest priority rule matching the package path.
+func matchRule(rules []*Rule, matchers []*regexp.Regexp, name string) *Rule {
+	var best *Rule
+	for i, r := range rules {
+		if !matchers[i].MatchString(name) {
+			continue
+		}
+		if best == nil || HigherPriority(r.Path, best.Path) {
+			best = r
+		}
+	}
+	return best
+}
+
+func hasCapability(r *Rule, name string) bool {
+	for _, c := range r.Capabilities {
+		if c == CapabilityDeny {
+			return false
+		}
+	}
+	for _, c := range r.Capabilities {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+func addACL(p *bundlev1.Package, aclPath string) {
+	if p.Annotations == nil {
+		p.Annotations = map[string]string{}
+	}
+
+	paths := []string{}
+	for _, s := range strings.Split(p.Annotations[refs.ACLAnnotation], ",") {
+		s = strings.TrimSpace(s)
+		if s == aclPath {
+			return
+		}
+		if s != "" {
+			paths = append(paths, s)
+		}
+	}
+	paths = append(paths, aclPath)
+	sort.Strings(paths)
+
+	p.Annotations[refs.ACLAnnotation] = strings.Join(paths, ",")
+}
+
+func upsertACLPackage(b *bundlev1.Bundle, name string, acl *ACL) error {
+	data := []*bundlev1.KV{}
+	for _, r := range acl.Rules {
+		value, err := secret.Pack(strings.Join(r.Capabilities, ","))
+		if err != nil {
+			return fmt.Errorf("unable to pack rule '%s' of policy '%s': %w", r.Path, acl.Name, err)
+		}
+		data = append(data, &bundlev1.KV{Key: r.Path, Type: "string", Value: value})
+	}
+
+	// Replace existing package content
+	for _, p := range b.Packages {
+		if p != nil && p.Name == name {
+			if p.Secrets != nil && p.Secrets.Locked != nil {
+				return fmt.Errorf("unable to update '%s': %w", name, bundle.ErrPackageLocked)
+			}
+			p.Secrets = &bundlev1.SecretChain{Data: data}
+			return nil
+		}
+	}
+
+	b.Packages = append(b.Packages, &bundlev1.Package{
+		Name: name,
+		Annotations: map[string]string{
+			bundle.DescriptionAnnotation: fmt.Sprintf("Access control rules converted from Vault policy '%s'", acl.Name),
+		},
+		Secrets: &bundlev1.SecretChain{Data: data},
+	})
+
+	return nil
+}
@@ -0,0 +1,375 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// APIVersion is the converted document api version.
+	APIVersion = "harp.elastic.co/v1"
+	// Kind is the converted document kind.
+	Kind = "ACLPolicies"
+)
+
+const (
+	// CapabilityRead allows package secrets to be read.
+	CapabilityRead = "read"
+	// CapabilityList allows package paths to be listed.
+	CapabilityList = "list"
+	// CapabilityDeny denies all access, it overrides all other capabilities.
+	CapabilityDeny = "deny"
+)
+
+// Document is the conversion result.
+type Document struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Spec       Spec   `json:"spec"`
+}
+
+// Spec holds converted policies and conversion warnings.
+type Spec struct {
+	Mount    string     `json:"mount"`
+	Policies []*ACL     `json:"policies"`
+	Warnings []*Warning `json:"warnings,omitempty"`
+}
+
+// ACL describes the access control rules of a policy.
+type ACL struct {
+	Name  string  `json:"name"`
+	Rules []*Rule `json:"rules"`
+}
+
+// Rule describes a package path access rule.
+type Rule struct {
+	// Path is the package path glob, using Vault wildcards.
+	Path string `json:"path"`
+	// Regex is the anchored regular expression matching package paths.
+	Regex string `json:"regex"`
+	// Capabilities granted (or denied) on matching packages.
+	Capabilities []string `json:"capabilities"`
+	// Source is the originating Vault policy path.
+	Source string `json:"source"`
+}
+
+// Warning describes a policy construct which can't be converted faithfully.
+type Warning struct {
+	Policy  string `json:"policy"`
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// Options drives the policy conversion.
+type Options struct {
+	// Mount is the Vault KV mount path (i.e. "secret/").
+	Mount string
+	// KVVersion is the mount KV engine version, 2 by default.
+	KVVersion int
+}
+
+// LoadDir parses all '*.hcl' policies from the given directory. Policy names
+// are derived from file names.
+func LoadDir(dir string) ([]*Policy, error) {
+	// Check arguments
+	if dir == "" {
+		return nil, errors.New("unable to load policies from a blank directory")
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.hcl"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to list policies: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no policy found in '%s'", dir)
+	}
+	sort.Strings(files)
+
+	policies := []*Policy{}
+	for _, f := range files {
+		body, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read policy '%s': %w", f, err)
+		}
+
+		p, err := Parse(strings.TrimSuffix(filepath.Base(f), ".hcl"), body)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, p)
+	}
+
+	// No error
+	return policies, nil
+}
+
+// Convert Vault policies to harp ACL rules. Only read, list and deny
+// capabilities are relevant for secret bundles, other capabilities are
+// ignored.
+func Convert(policies []*Policy, opts Options) (*Document, error) {
+	// Check arguments
+	mount := strings.Trim(opts.Mount, "/")
+	if mount == "" {
+		return nil, errors.New("unable to convert policies without mount path")
+	}
+	if opts.KVVersion == 0 {
+		opts.KVVersion = 2
+	}
+	if opts.KVVersion != 1 && opts.KVVersion != 2 {
+		return nil, fmt.Errorf("unsupported KV version %d", opts.KVVersion)
+	}
+
+	doc := &Document{
+		APIVersion: APIVersion,
+		Kind:       Kind,
+		Spec: Spec{
+			Mount:    mount + "/",
+			Policies: []*ACL{},
+			Warnings: []*Warning{},
+		},
+	}
+
+	for _, p := range policies {
+		if p == nil {
+			continue
+		}
+
+		c := &converter{
+			policy: p.Name,
+			mount:  mount,
+			kv:     opts.KVVersion,
+			rules:  map[string]*Rule{},
+		}
+		for _, pr := range p.Paths {
+			c.convert(pr)
+		}
+
+		doc.Spec.Policies = append(doc.Spec.Policies, &ACL{
+			Name:  p.Name,
+			Rules: c.sortedRules(),
+		})
+		doc.Spec.Warnings = append(doc.Spec.Warnings, c.warnings...)
+	}
+
+	// No error
+	return doc, nil
+}
+
+// -----------------------------------------------------------------------------
+
+type converter struct {
+	policy   string
+	mount    string
+	kv       int
+	rules    map[string]*Rule
+	warnings []*Warning
+}
+
+func (c *converter) warn(path, format string, args ...interface{}) {
+	c.warnings = append(c.warnings, &Warning{
+		Policy:  c.policy,
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *converter) convert(pr *PathRule) {
+	caps := capabilitySet(pr.Capabilities)
+
+	// Templated paths depend on the token identity
+	if strings.Contains(pr.Path, "{{") {
+		if caps.relevant() {
+			c.warn(pr.Path, "templated path depends on the requesting identity and can't be converted, rule skipped")
+		}
+		return
+	}
+
+	// Resolve path relative to the mount
+	rel, spans, ok := relativePath(pr.Path, c.mount)
+	if !ok {
+		return
+	}
+	if !caps.relevant() {
+		return
+	}
+	if spans {
+		c.warn(pr.Path, "path wildcard spans several mounts, converted for '%s/' only", c.mount)
+	}
+
+	// Flag unsupported constructs
+	if caps[CapabilityDeny] {
+		c.warn(pr.Path, "deny overrides capabilities granted by other policies attached to the same token in Vault, harp evaluates each policy independently")
+	}
+	if caps["sudo"] {
+		c.warn(pr.Path, "sudo capability has no harp equivalent and is ignored")
+	}
+	for _, attr := range pr.Unsupported {
+		c.warn(pr.Path, "'%s' constraint has no harp equivalent and is ignored", attr)
+	}
+
+	// KV v1 paths map to packages directly
+	if c.kv == 1 {
+		c.add(pr.Path, rel, caps.granted(CapabilityRead, CapabilityList))
+		return
+	}
+
+	// KV v2 paths are split between data and metadata sub-paths
+	for _, sp := range kv2Scopes(rel) {
+		if sp.spans {
+			c.warn(pr.Path, "path wildcard spans KV v2 '%s/' sub-path", sp.scope)
+		}
+
+		switch sp.scope {
+		case "data":
+			c.add(pr.Path, sp.rest, caps.granted(CapabilityRead))
+		case "metadata":
+			if caps[CapabilityDeny] {
+				c.warn(pr.Path, "deny on KV v2 metadata only blocks metadata access in Vault, converted as a full deny")
+			}
+			c.add(pr.Path, sp.rest, caps.granted(CapabilityList))
+		default:
+		}
+	}
+}
+
+func (c *converter) add(source, path string, caps []string) {
+	if len(caps) == 0 {
+		return
+	}
+
+	// Merge rules sharing the same path, deny wins
+	r, ok := c.rules[path]
+	if !ok {
+		r = &Rule{
+			Path:   path,
+			Regex:  GlobRegex(path),
+			Source: source,
+		}
+		c.rules[path] = r
+	}
+	set := capabilitySet(append(r.Capabilities, caps...))
+	if set[CapabilityDeny] {
+		r.Capabilities = []string{CapabilityDeny}
+		return
+	}
+	r.Capabilities = set.granted(CapabilityRead, CapabilityList)
+}
+
+// sortedRules returns rules by descending priority.
+func (c *converter) sortedRules() []*Rule {
+	rules := make([]*Rule, 0, len(c.rules))
+	for _, r := range c.rules {
+		rules = append(rules, r)
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		return HigherPriority(rules[i].Path, rules[j].Path)
+	})
+
+	return rules
+}
+
+type capabilities map[string]bool
+
+func capabilitySet(caps []string) capabilities {
+	set := capabilities{}
+	for _, c := range caps {
+		set[c] = true
+	}
+	return set
+}
+
+// relevant returns true when capabilities affect secret read access.
+func (c capabilities) relevant() bool {
+	return c[CapabilityRead] || c[CapabilityList] || c[CapabilityDeny]
+}
+
+// granted returns the given capabilities when set, deny supersedes them.
+func (c capabilities) granted(names ...string) []string {
+	if c[CapabilityDeny] {
+		return []string{CapabilityDeny}
+	}
+
+	res := []string{}
+	for _, n := range names {
+		if c[n] {
+			res = append(res, n)
+		}
+	}
+
+	return res
+}
+
+// relativePath returns the path relative to the mount. spans is set when the
+// path uses wildcards matching the mount and others.
+func relativePath(path, mount string) (rel string, spans, ok bool) {
+	// Prefix glob covering the whole mount (i.e. "*", "sec*")
+	if strings.HasSuffix(path, "*") {
+		literal := strings.TrimSuffix(path, "*")
+		if strings.HasPrefix(mount+"/", literal) && len(literal) <= len(mount) {
+			return "*", true, true
+		}
+	}
+
+	segments := strings.Split(path, "/")
+	mountSegments := strings.Split(mount, "/")
+	if len(segments) <= len(mountSegments) {
+		return "", false, false
+	}
+
+	for i, ms := range mountSegments {
+		switch segments[i] {
+		case ms:
+		case "+":
+			spans = true
+		default:
+			return "", false, false
+		}
+	}
+
+	return strings.Join(segments[len(mountSegments):], "/"), spans, true
+}
+
+type kv2Scope struct {
+	scope string
+	rest  string
+	spans bool
+}
+
+// kv2Scopes splits a KV v2 mount relative path by sub-path.
+func kv2Scopes(rel string) []kv2Scope {
+	res := []kv2Scope{}
+	for _, scope := range []string{"data", "metadata"} {
+		switch {
+		case strings.HasPrefix(rel, scope+"/"):
+			res = append(res, kv2Scope{scope: scope, rest: strings.TrimPrefix(rel, scope+"/")})
+		case strings.HasPrefix(rel, "+/"):
+			res = append(res, kv2Scope{scope: scope, rest: strings.TrimPrefix(rel, "+/"), spans: true})
+		case strings.HasSuffix(rel, "*") && strings.HasPrefix(scope+"/", strings.TrimSuffix(rel, "*")):
+			res = append(res, kv2Scope{scope: scope, rest: "*", spans: true})
+		default:
+		}
+	}
+
+	return res
+}
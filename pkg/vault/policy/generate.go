@@ -0,0 +1,343 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package policy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/refs"
+)
+
+// GenerateOptions drives the policy generation.
+type GenerateOptions struct {
+	// Mount is the Vault KV mount path (i.e. "secret/").
+	Mount string
+	// KVVersion is the mount KV engine version, 2 by default.
+	KVVersion int
+	// WithList grants list capability on parent folders of owned paths.
+	WithList bool
+	// Annotations resolves ownership annotations inherited from the bundle
+	// annotation defaults, package annotations are used as is when nil.
+	Annotations *bundle.AnnotationResolver
+}
+
+// Generated is the policy generation result.
+type Generated struct {
+	// Policies sorted by name.
+	Policies []*Policy
+	// Unowned lists package paths without owner nor ACL, they are not
+	// readable through any generated policy.
+	Unowned []string
+}
+
+// Generate Vault policies from package ownership annotations.
+//
+// A policy is generated for each owner and each 'meta/acl/<policy>' ACL
+// reference, granting read access to exactly the packages it owns. Sibling
+// paths are collapsed as a '<prefix>/*' rule only when no foreign package
+// lives under the prefix.
+func Generate(packages []*bundlev1.Package, opts GenerateOptions) (*Generated, error) {
+	// Check arguments
+	mount := strings.Trim(opts.Mount, "/")
+	if mount == "" {
+		return nil, errors.New("unable to generate policies without mount path")
+	}
+	if opts.KVVersion == 0 {
+		opts.KVVersion = 2
+	}
+	if opts.KVVersion != 1 && opts.KVVersion != 2 {
+		return nil, fmt.Errorf("unsupported KV version %d", opts.KVVersion)
+	}
+
+	res := &Generated{
+		Policies: []*Policy{},
+		Unowned:  []string{},
+	}
+
+	// Index owned paths by policy
+	root := newPathNode()
+	owned := map[string]map[string]bool{}
+	for _, p := range packages {
+		if p == nil || strings.HasPrefix(p.Name, "meta/") {
+			continue
+		}
+		name := strings.Trim(p.Name, "/")
+		if name == "" {
+			continue
+		}
+		root.insert(name)
+
+		owners := packageOwners(opts.Annotations, p)
+		if len(owners) == 0 {
+			res.Unowned = append(res.Unowned, name)
+			continue
+		}
+		for _, o := range owners {
+			if owned[o] == nil {
+				owned[o] = map[string]bool{}
+			}
+			owned[o][name] = true
+		}
+	}
+	sort.Strings(res.Unowned)
+
+	names := make([]string, 0, len(owned))
+	for name := range owned {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		// Minimize owned paths
+		globs := []string{}
+		root.collapse("", owned[name], &globs)
+
+		// Translate to mount paths
+		g := &generator{mount: mount, kv: opts.KVVersion, rules: map[string]capabilities{}}
+		for _, glob := range globs {
+			g.grant(glob, opts.WithList)
+		}
+
+		res.Policies = append(res.Policies, &Policy{
+			Name:  name,
+			Paths: g.sortedRules(),
+		})
+	}
+
+	// No error
+	return res, nil
+}
+
+// HCL encodes the policy as a Vault HCL policy document.
+func (p *Policy) HCL() []byte {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "# Policy %q generated from bundle ownership annotations.\n", p.Name)
+	for _, pr := range p.Paths {
+		caps := make([]string, len(pr.Capabilities))
+		for i, c := range pr.Capabilities {
+			caps[i] = fmt.Sprintf("%q", c)
+		}
+		fmt.Fprintf(&buf, "\npath %q {\n  capabilities = [%s]\n}\n", pr.Path, strings.Join(caps, ", "))
+	}
+
+	return buf.Bytes()
+}
+
+// FileName returns a file system safe policy file name.
+func (p *Policy) FileName() string {
+	return unsafeFileChars.ReplaceAllString(p.Name, "-") + ".hcl"
+}
+
+// -----------------------------------------------------------------------------
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// packageOwners returns the policies granted to read the package, the owner
+// and all ACL referenced policies.
+func packageOwners(annotations *bundle.AnnotationResolver, p *bundlev1.Package) []string {
+	set := map[string]bool{}
+	if owner := annotations.Owner(p); owner != "" {
+		set[owner] = true
+	}
+	acl, _ := annotations.Lookup(p, refs.ACLAnnotation)
+	for _, s := range strings.Split(acl, ",") {
+		s = strings.TrimSpace(s)
+		if strings.HasPrefix(s, ACLPackagePrefix) && len(s) > len(ACLPackagePrefix) {
+			set[strings.TrimPrefix(s, ACLPackagePrefix)] = true
+		}
+	}
+
+	res := make([]string, 0, len(set))
+	for o := range set {
+		res = append(res, o)
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+// pathNode is a package path segment tree node.
+type pathNode struct {
+	// leaf is set when a package uses the node path.
+	leaf     bool
+	children map[string]*pathNode
+}
+
+func newPathNode() *pathNode {
+	return &pathNode{children: map[string]*pathNode{}}
+}
+
+func (n *pathNode) insert(path string) {
+	for _, s := range strings.Split(path, "/") {
+		child, ok := n.children[s]
+		if !ok {
+			child = newPathNode()
+			n.children[s] = child
+		}
+		n = child
+	}
+	n.leaf = true
+}
+
+// descendants returns the package paths below the node, excluding the node
+// itself.
+func (n *pathNode) descendants(path string, res []string) []string {
+	for _, s := range sortedChildren(n) {
+		child := joinPath(path, s)
+		if n.children[s].leaf {
+			res = append(res, child)
+		}
+		res = n.children[s].descendants(child, res)
+	}
+	return res
+}
+
+// collapse appends the minimal path globs matching exactly the owned
+// packages below the node.
+//
+// A '<path>/*' glob also matches future packages, it is emitted for the
+// deepest folder holding several owned packages when all packages under it
+// are owned. The root folder is never collapsed, it would grant the whole
+// mount.
+func (n *pathNode) collapse(path string, owned map[string]bool, globs *[]string) {
+	for _, s := range sortedChildren(n) {
+		child := n.children[s]
+		childPath := joinPath(path, s)
+
+		if child.leaf && owned[childPath] {
+			*globs = append(*globs, childPath)
+		}
+
+		below := child.descendants(childPath, nil)
+		count := 0
+		for _, d := range below {
+			if owned[d] {
+				count++
+			}
+		}
+
+		switch {
+		case count == 0:
+			// Nothing owned below
+		case count == len(below) && count > 1 && !child.singleBranch():
+			*globs = append(*globs, childPath+"/*")
+		default:
+			child.collapse(childPath, owned, globs)
+		}
+	}
+}
+
+// singleBranch returns true when all packages below the node live under the
+// same child folder, so a deeper prefix covers the same packages.
+func (n *pathNode) singleBranch() bool {
+	if len(n.children) != 1 {
+		return false
+	}
+	for _, child := range n.children {
+		return !child.leaf
+	}
+	return false
+}
+
+func sortedChildren(n *pathNode) []string {
+	res := make([]string, 0, len(n.children))
+	for s := range n.children {
+		res = append(res, s)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func joinPath(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "/" + name
+}
+
+type generator struct {
+	mount string
+	kv    int
+	rules map[string]capabilities
+}
+
+func (g *generator) add(path, capability string) {
+	if g.rules[path] == nil {
+		g.rules[path] = capabilities{}
+	}
+	g.rules[path][capability] = true
+}
+
+// grant read access to the given package glob, and optionally list access to
+// its parent folders.
+func (g *generator) grant(glob string, withList bool) {
+	data, metadata := g.mount+"/", g.mount+"/"
+	if g.kv == 2 {
+		data, metadata = g.mount+"/data/", g.mount+"/metadata/"
+	}
+
+	g.add(data+glob, CapabilityRead)
+	if !withList {
+		return
+	}
+
+	// Collapsed folders are listed recursively
+	if strings.HasSuffix(glob, "/*") {
+		g.add(metadata+glob, CapabilityList)
+	}
+
+	// Vault list requests target folder paths with a trailing slash
+	folder := ""
+	segments := strings.Split(strings.TrimSuffix(glob, "/*"), "/")
+	if !strings.HasSuffix(glob, "/*") {
+		segments = segments[:len(segments)-1]
+	}
+	g.add(metadata, CapabilityList)
+	for _, s := range segments {
+		folder += s + "/"
+		g.add(metadata+folder, CapabilityList)
+	}
+}
+
+// sortedRules returns rules ordered by path.
+func (g *generator) sortedRules() []*PathRule {
+	paths := make([]string, 0, len(g.rules))
+	for p := range g.rules {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	res := make([]*PathRule, 0, len(paths))
+	for _, p := range paths {
+		res = append(res, &PathRule{
+			Path:         p,
+			Capabilities: g.rules[p].granted(CapabilityRead, CapabilityList),
+			Unsupported:  []string{},
+		})
+	}
+
+	return res
+}
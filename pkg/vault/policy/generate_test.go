@@ -0,0 +1,292 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package policy
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/refs"
+)
+
+var updatePolicies = flag.Bool("update-policies", false, "update generated policy golden files")
+
+func owned(name, owner string) *bundlev1.Package {
+	p := &bundlev1.Package{Name: name}
+	if owner != "" {
+		p.Annotations = map[string]string{bundle.OwnerAnnotation: owner}
+	}
+	return p
+}
+
+func TestGenerate_Golden(t *testing.T) {
+	testCases := map[string]struct {
+		packages []*bundlev1.Package
+		opts     GenerateOptions
+		unowned  []string
+	}{
+		"collapse": {
+			packages: []*bundlev1.Package{
+				owned("app/production/billing/database", "billing"),
+				owned("app/production/billing/recurly", "billing"),
+				owned("app/production/billing/stripe", "billing"),
+				owned("app/production/search/elasticsearch", "search"),
+				owned("infra/dns", ""),
+			},
+			opts:    GenerateOptions{Mount: "secret"},
+			unowned: []string{"infra/dns"},
+		},
+		"collapse-with-list": {
+			packages: []*bundlev1.Package{
+				owned("app/production/billing/database", "billing"),
+				owned("app/production/billing/recurly", "billing"),
+				owned("app/production/search/elasticsearch", "search"),
+			},
+			opts:    GenerateOptions{Mount: "secret/", WithList: true},
+			unowned: []string{},
+		},
+		"collapse-kv1": {
+			packages: []*bundlev1.Package{
+				owned("app/production/billing/database", "billing"),
+				owned("app/production/billing/recurly", "billing"),
+				owned("app/staging/billing/database", "billing"),
+			},
+			opts:    GenerateOptions{Mount: "kv", KVVersion: 1, WithList: true},
+			unowned: []string{},
+		},
+		"foreign-sibling": {
+			packages: []*bundlev1.Package{
+				owned("app/production/billing/database", "billing"),
+				owned("app/production/billing/recurly", "billing"),
+				owned("app/production/billing/admin", "security"),
+			},
+			opts:    GenerateOptions{Mount: "secret"},
+			unowned: []string{},
+		},
+		"foreign-nested": {
+			packages: []*bundlev1.Package{
+				owned("app/production/billing/database", "billing"),
+				owned("app/production/billing/recurly", "billing"),
+				owned("app/production/billing/vendors/stripe", "billing"),
+				owned("app/production/billing/vendors/internal/audit", "security"),
+			},
+			opts:    GenerateOptions{Mount: "secret"},
+			unowned: []string{},
+		},
+		"unowned-sibling": {
+			packages: []*bundlev1.Package{
+				owned("app/production/billing/database", "billing"),
+				owned("app/production/billing/recurly", "billing"),
+				owned("app/production/billing/legacy", ""),
+			},
+			opts:    GenerateOptions{Mount: "secret"},
+			unowned: []string{"app/production/billing/legacy"},
+		},
+		"foreign-folder-package": {
+			// 'billing/*' doesn't match the 'billing' package itself, and
+			// 'billing-v2' is not under the 'billing/' folder.
+			packages: []*bundlev1.Package{
+				owned("app/production/billing", "security"),
+				owned("app/production/billing/database", "billing"),
+				owned("app/production/billing/recurly", "billing"),
+				owned("app/production/billing-v2/database", "security"),
+			},
+			opts:    GenerateOptions{Mount: "secret"},
+			unowned: []string{},
+		},
+		"acl": {
+			packages: []*bundlev1.Package{
+				owned("app/production/billing/database", "billing"),
+				{
+					Name: "app/production/billing/recurly",
+					Annotations: map[string]string{
+						bundle.OwnerAnnotation: "billing",
+						refs.ACLAnnotation:     "meta/acl/finance, meta/acl/billing",
+					},
+				},
+				{
+					Name:        "app/production/billing/stripe",
+					Annotations: map[string]string{refs.ACLAnnotation: "meta/acl/finance"},
+				},
+				{Name: "meta/acl/finance"},
+			},
+			opts:    GenerateOptions{Mount: "secret"},
+			unowned: []string{},
+		},
+		"inherited": {
+			packages: []*bundlev1.Package{
+				owned("app/production/billing/database", ""),
+				owned("app/production/billing/recurly", ""),
+				owned("app/production/billing/admin", "security"),
+				owned("app/production/search/elasticsearch", ""),
+			},
+			opts: GenerateOptions{
+				Mount: "secret",
+				Annotations: bundle.NewAnnotationResolver(&bundle.AnnotationDefaults{
+					Spec: bundle.AnnotationDefaultsSpec{
+						Prefixes: []bundle.PrefixAnnotations{
+							{Prefix: "app/production", Annotations: map[string]string{refs.ACLAnnotation: "meta/acl/finance"}},
+							{Prefix: "app/production/billing", Annotations: map[string]string{bundle.OwnerAnnotation: "billing"}},
+						},
+					},
+				}),
+			},
+			unowned: []string{},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			res, err := Generate(tc.packages, tc.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(res.Unowned, tc.unowned) {
+				t.Errorf("unexpected unowned paths %v", res.Unowned)
+			}
+
+			dir := filepath.Join("testdata", "generate", name)
+			if *updatePolicies {
+				if err := os.RemoveAll(dir); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.MkdirAll(dir, 0o755); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			files := []string{}
+			for _, p := range res.Policies {
+				files = append(files, p.FileName())
+				golden := filepath.Join(dir, p.FileName())
+				if *updatePolicies {
+					if err := ioutil.WriteFile(golden, p.HCL(), 0o644); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				want, err := ioutil.ReadFile(golden)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := p.HCL(); string(got) != string(want) {
+					t.Errorf("unexpected '%s' policy, got:\n%s", p.Name, got)
+				}
+			}
+
+			// No stale golden file
+			existing, err := filepath.Glob(filepath.Join(dir, "*.hcl"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(existing) != len(files) {
+				t.Errorf("expected %d policies, golden files are %v", len(files), existing)
+			}
+		})
+	}
+}
+
+// TestGenerate_ExactAccess checks that each generated policy matches exactly
+// the packages owned by its team, using Vault glob semantics.
+func TestGenerate_ExactAccess(t *testing.T) {
+	owners := []string{"billing", "search", "security", ""}
+	paths := []string{
+		"app/production/billing",
+		"app/production/billing/database",
+		"app/production/billing/recurly",
+		"app/production/billing/vendors/stripe",
+		"app/production/billing/vendors/recurly",
+		"app/production/billing/vendors/internal/audit",
+		"app/production/billing-v2/database",
+		"app/production/search/elasticsearch",
+		"app/production/search/kibana",
+		"app/staging/billing/database",
+		"app/staging/search/elasticsearch",
+		"infra/dns",
+		"infra/pki/root",
+	}
+
+	// Pseudo random owner assignments
+	for seed := 0; seed < 512; seed++ {
+		packages := make([]*bundlev1.Package, len(paths))
+		want := map[string][]string{}
+		for i, path := range paths {
+			owner := owners[(seed>>uint(i%9)+i*seed)%len(owners)]
+			packages[i] = owned(path, owner)
+			if owner != "" {
+				want[owner] = append(want[owner], path)
+			}
+		}
+
+		res, err := Generate(packages, GenerateOptions{Mount: "secret", WithList: true})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for _, p := range res.Policies {
+			// Round trip through the policy parser
+			parsed, err := Parse(p.Name, p.HCL())
+			if err != nil {
+				t.Fatalf("unable to parse generated policy: %v", err)
+			}
+
+			readable := []string{}
+			for _, path := range paths {
+				if canRead(parsed, "secret/data/"+path) {
+					readable = append(readable, path)
+				}
+			}
+			sort.Strings(readable)
+			sort.Strings(want[p.Name])
+			if !reflect.DeepEqual(readable, want[p.Name]) {
+				t.Fatalf("seed %d: policy '%s' grants %v, owned %v\n%s", seed, p.Name, readable, want[p.Name], p.HCL())
+			}
+		}
+	}
+}
+
+func canRead(p *Policy, path string) bool {
+	for _, pr := range p.Paths {
+		if !strings.Contains(strings.Join(pr.Capabilities, ","), CapabilityRead) {
+			continue
+		}
+		if regexp.MustCompile(GlobRegex(pr.Path)).MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestGenerate_Invalid(t *testing.T) {
+	if _, err := Generate(nil, GenerateOptions{}); err == nil {
+		t.Error("error should be raised for blank mount")
+	}
+	if _, err := Generate(nil, GenerateOptions{Mount: "kv", KVVersion: 3}); err == nil {
+		t.Error("error should be raised for unsupported KV version")
+	}
+}
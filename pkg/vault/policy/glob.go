@@ -0,0 +1,105 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package policy
+
+import (
+	"regexp"
+	"strings"
+)
+
+// GlobRegex translates a Vault policy path to an anchored regular expression.
+//
+// Vault only supports two wildcards: a trailing '*' matching any suffix, and a
+// '+' path segment matching exactly one segment. Any other character,
+// including a non trailing '*', is matched literally.
+func GlobRegex(path string) string {
+	prefix := strings.HasSuffix(path, "*")
+	path = strings.TrimSuffix(path, "*")
+
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if s == "+" {
+			segments[i] = `[^/]*`
+			continue
+		}
+		segments[i] = regexp.QuoteMeta(s)
+	}
+
+	expr := strings.Join(segments, "/")
+	if prefix {
+		expr += ".*"
+	}
+
+	return "^" + expr + "$"
+}
+
+// HigherPriority returns true when path a takes precedence over path b when
+// both match the same secret path, following Vault's ordering:
+//
+//  1. the path whose first wildcard ('+' or '*') occurs later wins;
+//  2. a path without trailing '*' wins over one with;
+//  3. the path with less '+' segments wins;
+//  4. the longer path wins;
+//  5. the lexicographically greater path wins.
+func HigherPriority(a, b string) bool {
+	if wa, wb := firstWildcard(a), firstWildcard(b); wa != wb {
+		return wa > wb
+	}
+	if pa, pb := strings.HasSuffix(a, "*"), strings.HasSuffix(b, "*"); pa != pb {
+		return pb
+	}
+	if sa, sb := segmentWildcards(a), segmentWildcards(b); sa != sb {
+		return sa < sb
+	}
+	if len(a) != len(b) {
+		return len(a) > len(b)
+	}
+
+	return a > b
+}
+
+// -----------------------------------------------------------------------------
+
+// firstWildcard returns the position of the first wildcard, paths without
+// wildcard sort after all others.
+func firstWildcard(path string) int {
+	pos := len(path) + 1
+	if strings.HasSuffix(path, "*") {
+		pos = len(path) - 1
+	}
+
+	offset := 0
+	for _, s := range strings.Split(path, "/") {
+		if s == "+" && offset < pos {
+			return offset
+		}
+		offset += len(s) + 1
+	}
+
+	return pos
+}
+
+func segmentWildcards(path string) int {
+	count := 0
+	for _, s := range strings.Split(strings.TrimSuffix(path, "*"), "/") {
+		if s == "+" {
+			count++
+		}
+	}
+	return count
+}
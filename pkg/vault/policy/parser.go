@@ -0,0 +1,159 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package policy translates Vault policies to harp access control rules, and
+// generates Vault policies from bundle package ownership.
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+)
+
+// Policy describes a parsed Vault policy.
+type Policy struct {
+	Name  string
+	Paths []*PathRule
+}
+
+// PathRule describes a Vault policy path block.
+type PathRule struct {
+	Path         string
+	Capabilities []string
+	// Unsupported lists path block attributes without harp equivalent.
+	Unsupported []string
+}
+
+// pathBlock is the decoded content of a policy path block.
+type pathBlock struct {
+	Policy       string   `hcl:"policy"`
+	Capabilities []string `hcl:"capabilities"`
+}
+
+// Legacy policy values expanded as capabilities, as Vault does.
+var legacyCapabilities = map[string][]string{
+	"deny":  {"deny"},
+	"read":  {"read", "list"},
+	"write": {"create", "read", "update", "delete", "list"},
+	"sudo":  {"create", "read", "update", "delete", "list", "sudo"},
+}
+
+// Parse a Vault HCL policy.
+func Parse(name string, body []byte) (*Policy, error) {
+	// Check arguments
+	if strings.TrimSpace(name) == "" {
+		return nil, fmt.Errorf("policy name must not be blank")
+	}
+
+	// Parse HCL document
+	root, err := hcl.ParseBytes(body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse policy '%s': %w", name, err)
+	}
+	list, ok := root.Node.(*ast.ObjectList)
+	if !ok {
+		return nil, fmt.Errorf("unable to parse policy '%s': policy doesn't contain a root object", name)
+	}
+
+	p := &Policy{
+		Name:  name,
+		Paths: []*PathRule{},
+	}
+
+	// Top level attributes
+	for _, item := range list.Items {
+		if key := itemKey(item); key != "path" && key != "name" {
+			return nil, fmt.Errorf("unable to parse policy '%s': unsupported attribute '%s'", name, key)
+		}
+	}
+
+	// Path blocks
+	for _, item := range list.Filter("path").Items {
+		rule, err := parsePath(item)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse policy '%s': %w", name, err)
+		}
+		p.Paths = append(p.Paths, rule)
+	}
+
+	// No error
+	return p, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func parsePath(item *ast.ObjectItem) (*PathRule, error) {
+	if len(item.Keys) != 1 {
+		return nil, fmt.Errorf("path block at line %d must declare a single path", item.Pos().Line)
+	}
+	path, ok := item.Keys[0].Token.Value().(string)
+	if !ok {
+		return nil, fmt.Errorf("path block at line %d must declare a string path", item.Pos().Line)
+	}
+	obj, ok := item.Val.(*ast.ObjectType)
+	if !ok {
+		return nil, fmt.Errorf("path '%s' must be a block", path)
+	}
+
+	// Decode supported attributes
+	var block pathBlock
+	if err := hcl.DecodeObject(&block, item.Val); err != nil {
+		return nil, fmt.Errorf("unable to decode path '%s': %w", path, err)
+	}
+
+	rule := &PathRule{
+		Path:         strings.TrimPrefix(path, "/"),
+		Capabilities: []string{},
+		Unsupported:  []string{},
+	}
+
+	// Collect unsupported attributes
+	for _, attr := range obj.List.Items {
+		switch key := itemKey(attr); key {
+		case "policy", "capabilities":
+		default:
+			rule.Unsupported = append(rule.Unsupported, key)
+		}
+	}
+	sort.Strings(rule.Unsupported)
+
+	// Capabilities
+	if block.Policy != "" {
+		caps, ok := legacyCapabilities[strings.ToLower(block.Policy)]
+		if !ok {
+			return nil, fmt.Errorf("path '%s' has an invalid policy '%s'", path, block.Policy)
+		}
+		rule.Capabilities = append(rule.Capabilities, caps...)
+	}
+	for _, c := range block.Capabilities {
+		rule.Capabilities = append(rule.Capabilities, strings.ToLower(strings.TrimSpace(c)))
+	}
+
+	// No error
+	return rule, nil
+}
+
+func itemKey(item *ast.ObjectItem) string {
+	if len(item.Keys) == 0 {
+		return ""
+	}
+	return item.Keys[0].Token.Text
+}
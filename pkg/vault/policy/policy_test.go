@@ -0,0 +1,272 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package policy
+
+import (
+	"io/ioutil"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+
+	bundlev1 "github.com/elastic/harp/api/gen/go/harp/bundle/v1"
+	"github.com/elastic/harp/pkg/bundle"
+	"github.com/elastic/harp/pkg/bundle/refs"
+)
+
+func TestParse(t *testing.T) {
+	p, err := Parse("test", []byte(`
+path "/secret/data/app/*" {
+  policy = "write"
+}
+path "secret/data/db" {
+  capabilities = ["READ", "deny"]
+  denied_parameters = { "*" = [] }
+  max_wrapping_ttl = "1h"
+}
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []*PathRule{
+		{Path: "secret/data/app/*", Capabilities: []string{"create", "read", "update", "delete", "list"}, Unsupported: []string{}},
+		{Path: "secret/data/db", Capabilities: []string{"read", "deny"}, Unsupported: []string{"denied_parameters", "max_wrapping_ttl"}},
+	}
+	if !reflect.DeepEqual(p.Paths, want) {
+		t.Errorf("unexpected paths %+v", p.Paths)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	testCases := map[string]string{
+		"syntax":         `path "secret/*" {`,
+		"unknown policy": `path "secret/*" { policy = "admin" }`,
+		"unknown field":  `rules = "secret/*"`,
+		"path attribute": `path = "secret/*"`,
+	}
+	for name, body := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Parse("test", []byte(body)); err == nil {
+				t.Error("error should be raised")
+			}
+		})
+	}
+
+	if _, err := Parse("", []byte(`path "secret/*" {}`)); err == nil {
+		t.Error("error should be raised for blank name")
+	}
+}
+
+func TestGlobRegex(t *testing.T) {
+	testCases := []struct {
+		glob     string
+		match    []string
+		mismatch []string
+	}{
+		{
+			glob:     "app/production/*",
+			match:    []string{"app/production/", "app/production/a/b/c"},
+			mismatch: []string{"app/production", "app/staging/a"},
+		},
+		{
+			glob:     "app/+/security",
+			match:    []string{"app/production/security", "app//security"},
+			mismatch: []string{"app/production/eu/security", "app/production/security/x"},
+		},
+		{
+			glob:     "app/+/sec*",
+			match:    []string{"app/production/security", "app/x/sec/y"},
+			mismatch: []string{"app/a/b/security"},
+		},
+		{
+			// '*' is only a wildcard at the end, '+' only as a full segment
+			glob:     "app/*/v1.0+",
+			match:    []string{"app/*/v1.0+"},
+			mismatch: []string{"app/production/v1.0+", "app/*/v1x0", "app/*/v1.00"},
+		},
+		{
+			glob:     "+",
+			match:    []string{"app"},
+			mismatch: []string{"app/production"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.glob, func(t *testing.T) {
+			re := regexp.MustCompile(GlobRegex(tc.glob))
+			for _, s := range tc.match {
+				if !re.MatchString(s) {
+					t.Errorf("'%s' should match '%s'", tc.glob, s)
+				}
+			}
+			for _, s := range tc.mismatch {
+				if re.MatchString(s) {
+					t.Errorf("'%s' should not match '%s'", tc.glob, s)
+				}
+			}
+		})
+	}
+}
+
+func TestHigherPriority(t *testing.T) {
+	testCases := []struct {
+		high, low string
+	}{
+		{high: "app/production/web", low: "app/production/*"},
+		{high: "app/production/*", low: "app/*"},
+		{high: "app/production/+/web", low: "app/+/+/web"},
+		{high: "app/production/+", low: "app/production/*"},
+		{high: "app/+/+/+/database", low: "app/+/+/*"},
+		{high: "app/+/web/+", low: "app/+/+/web"},
+		{high: "app/production/webapp", low: "app/production/web"},
+		{high: "app/production/b", low: "app/production/a"},
+	}
+	for _, tc := range testCases {
+		if !HigherPriority(tc.high, tc.low) {
+			t.Errorf("'%s' should take precedence over '%s'", tc.high, tc.low)
+		}
+		if HigherPriority(tc.low, tc.high) {
+			t.Errorf("'%s' should not take precedence over '%s'", tc.low, tc.high)
+		}
+	}
+}
+
+func TestConvert_Corpus(t *testing.T) {
+	policies, err := LoadDir("testdata/policies")
+	if err != nil {
+		t.Fatalf("unable to load policies: %v", err)
+	}
+
+	doc, err := Convert(policies, Options{Mount: "secret/"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := yaml.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := ioutil.ReadFile("testdata/acl.yaml.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("unexpected conversion result, got:\n%s", got)
+	}
+}
+
+func TestConvert_KV1(t *testing.T) {
+	p, err := Parse("kv1", []byte(`
+path "kv/app/*" { capabilities = ["read", "list"] }
+path "kv/app/production/web" { capabilities = ["deny"] }
+path "secret/app/*" { capabilities = ["read"] }
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := Convert([]*Policy{p}, Options{Mount: "/kv", KVVersion: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rules := doc.Spec.Policies[0].Rules
+	if len(rules) != 2 {
+		t.Fatalf("unexpected rules %+v", rules)
+	}
+	if rules[0].Path != "app/production/web" || !reflect.DeepEqual(rules[0].Capabilities, []string{"deny"}) {
+		t.Errorf("unexpected rule %+v", rules[0])
+	}
+	if rules[1].Path != "app/*" || !reflect.DeepEqual(rules[1].Capabilities, []string{"read", "list"}) {
+		t.Errorf("unexpected rule %+v", rules[1])
+	}
+
+	if _, err := Convert([]*Policy{p}, Options{}); err == nil {
+		t.Error("error should be raised for blank mount")
+	}
+	if _, err := Convert([]*Policy{p}, Options{Mount: "kv", KVVersion: 3}); err == nil {
+		t.Error("error should be raised for unsupported KV version")
+	}
+}
+
+func TestApply(t *testing.T) {
+	policies, err := LoadDir("testdata/policies")
+	if err != nil {
+		t.Fatalf("unable to load policies: %v", err)
+	}
+	doc, err := Convert(policies, Options{Mount: "secret/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const (
+		web      = "app/production/security/harp/v1.0.0/server/web"
+		database = "app/production/security/harp/v1.0.0/server/database"
+		staging  = "app/staging/security/harp/v1.0.0/server/database"
+		aws      = "infra/aws/security/eu-central-1/rds/adminconsole"
+		gcp      = "infra/gcp/security/europe-west1/sql/adminconsole"
+	)
+	b := &bundlev1.Bundle{}
+	for _, name := range []string{web, database, staging, aws, gcp} {
+		b.Packages = append(b.Packages, &bundlev1.Package{Name: name, Secrets: &bundlev1.SecretChain{}})
+	}
+	b.Packages[0].Annotations = map[string]string{refs.ACLAnnotation: "meta/acl/custom"}
+
+	if err := Apply(b, doc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]string{
+		web:      "meta/acl/app-reader,meta/acl/custom",
+		database: "meta/acl/dba",
+		staging:  "meta/acl/dba",
+		aws:      "",
+		gcp:      "meta/acl/legacy",
+	}
+	for _, p := range b.Packages {
+		if strings.HasPrefix(p.Name, ACLPackagePrefix) {
+			continue
+		}
+		if got := p.Annotations[refs.ACLAnnotation]; got != want[p.Name] {
+			t.Errorf("unexpected acl for '%s', got %q want %q", p.Name, got, want[p.Name])
+		}
+	}
+
+	// Policy rules are stored as meta packages
+	acl, err := bundle.Read(b, ACLPackagePrefix+"app-reader")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(acl, map[string]interface{}{
+		"app/production/+/+/+/+/database": "deny",
+		"app/production/*":                "read,list",
+	}) {
+		t.Errorf("unexpected acl package content %v", acl)
+	}
+
+	// Apply is idempotent
+	count := len(b.Packages)
+	if err := Apply(b, doc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(b.Packages) != count || b.Packages[0].Annotations[refs.ACLAnnotation] != want[web] {
+		t.Error("apply should be idempotent")
+	}
+}
@@ -0,0 +1,9 @@
+# Policy "finance" generated from bundle ownership annotations.
+
+path "secret/data/app/production/billing/recurly" {
+  capabilities = ["read"]
+}
+
+path "secret/data/app/production/billing/stripe" {
+  capabilities = ["read"]
+}
@@ -0,0 +1,13 @@
+# Policy "billing" generated from bundle ownership annotations.
+
+path "kv/" {
+  capabilities = ["list"]
+}
+
+path "kv/app/" {
+  capabilities = ["list"]
+}
+
+path "kv/app/*" {
+  capabilities = ["read", "list"]
+}
@@ -0,0 +1,25 @@
+# Policy "billing" generated from bundle ownership annotations.
+
+path "secret/data/app/production/billing/*" {
+  capabilities = ["read"]
+}
+
+path "secret/metadata/" {
+  capabilities = ["list"]
+}
+
+path "secret/metadata/app/" {
+  capabilities = ["list"]
+}
+
+path "secret/metadata/app/production/" {
+  capabilities = ["list"]
+}
+
+path "secret/metadata/app/production/billing/" {
+  capabilities = ["list"]
+}
+
+path "secret/metadata/app/production/billing/*" {
+  capabilities = ["list"]
+}
@@ -0,0 +1,21 @@
+# Policy "search" generated from bundle ownership annotations.
+
+path "secret/data/app/production/search/elasticsearch" {
+  capabilities = ["read"]
+}
+
+path "secret/metadata/" {
+  capabilities = ["list"]
+}
+
+path "secret/metadata/app/" {
+  capabilities = ["list"]
+}
+
+path "secret/metadata/app/production/" {
+  capabilities = ["list"]
+}
+
+path "secret/metadata/app/production/search/" {
+  capabilities = ["list"]
+}
@@ -0,0 +1,5 @@
+# Policy "billing" generated from bundle ownership annotations.
+
+path "secret/data/app/production/billing/*" {
+  capabilities = ["read"]
+}
@@ -0,0 +1,5 @@
+# Policy "search" generated from bundle ownership annotations.
+
+path "secret/data/app/production/search/elasticsearch" {
+  capabilities = ["read"]
+}
@@ -0,0 +1,9 @@
+# Policy "security" generated from bundle ownership annotations.
+
+path "secret/data/app/production/billing" {
+  capabilities = ["read"]
+}
+
+path "secret/data/app/production/billing-v2/database" {
+  capabilities = ["read"]
+}
@@ -0,0 +1,13 @@
+# Policy "billing" generated from bundle ownership annotations.
+
+path "secret/data/app/production/billing/database" {
+  capabilities = ["read"]
+}
+
+path "secret/data/app/production/billing/recurly" {
+  capabilities = ["read"]
+}
+
+path "secret/data/app/production/billing/vendors/stripe" {
+  capabilities = ["read"]
+}
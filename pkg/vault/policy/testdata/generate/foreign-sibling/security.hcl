@@ -0,0 +1,5 @@
+# Policy "security" generated from bundle ownership annotations.
+
+path "secret/data/app/production/billing/admin" {
+  capabilities = ["read"]
+}
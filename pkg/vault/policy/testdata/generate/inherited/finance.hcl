@@ -0,0 +1,5 @@
+# Policy "finance" generated from bundle ownership annotations.
+
+path "secret/data/app/production/*" {
+  capabilities = ["read"]
+}
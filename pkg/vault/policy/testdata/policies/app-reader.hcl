@@ -0,0 +1,17 @@
+# Read access to production application secrets
+path "secret/data/app/production/*" {
+  capabilities = ["read"]
+}
+
+path "secret/metadata/app/production/*" {
+  capabilities = ["list"]
+}
+
+# Database credentials are restricted to the dba team
+path "secret/data/app/production/+/+/+/+/database" {
+  capabilities = ["deny"]
+}
+
+path "secret/data/app/production/security/harp/v1.0.0/server/database" {
+  capabilities = ["create", "update"]
+}
@@ -0,0 +1,14 @@
+path "secret/data/app/+/+/+/+/+/database" {
+  capabilities = ["read", "update"]
+  allowed_parameters = {
+    "password" = []
+  }
+}
+
+path "secret/metadata/*" {
+  capabilities = ["list", "deny"]
+}
+
+path "sys/leases/*" {
+  capabilities = ["sudo", "update"]
+}
@@ -0,0 +1,7 @@
+path "secret/data/infra/*" {
+  policy = "read"
+}
+
+path "secret/data/infra/aws/*" {
+  policy = "deny"
+}
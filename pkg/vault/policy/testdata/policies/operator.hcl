@@ -0,0 +1,16 @@
+path "+/data/platform/*" {
+  capabilities = ["read", "list"]
+}
+
+path "secret/data/users/{{identity.entity.name}}/*" {
+  capabilities = ["read"]
+}
+
+path "secret/data/platform/production/security/harp/v1.0.0/ops/root" {
+  capabilities = ["read", "sudo"]
+  required_parameters = ["reason"]
+}
+
+path "auth/token/create" {
+  capabilities = ["update"]
+}
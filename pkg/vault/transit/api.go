@@ -17,7 +17,10 @@
 
 package transit
 
-import "context"
+import (
+	"context"
+	"crypto"
+)
 
 // Encryptor describes encryption operations contract.
 type Encryptor interface {
@@ -29,8 +32,36 @@ type Decryptor interface {
 	Decrypt(ctx context.Context, encrypted []byte) ([]byte, error)
 }
 
+// SignOptions holds transit signature settings.
+type SignOptions struct {
+	// KeyVersion pins the signing key version, the latest version is used
+	// when 0.
+	KeyVersion int
+	// HashAlgorithm is the transit name of the hash function used to compute
+	// a prehashed input (sha2-256, sha2-384, sha2-512). The input is signed
+	// as is when blank.
+	HashAlgorithm string
+}
+
+// Signature is a transit signature.
+type Signature struct {
+	// KeyVersion is the version of the key used to sign.
+	KeyVersion int
+	// Value is the raw signature (ASN.1 for ECDSA keys).
+	Value []byte
+}
+
+// Signer describes signature operations contract.
+type Signer interface {
+	Sign(ctx context.Context, input []byte, opts SignOptions) (*Signature, error)
+	// PublicKey returns the public key of the given version, and the latest
+	// version of the key when version is 0.
+	PublicKey(ctx context.Context, version int) (crypto.PublicKey, int, error)
+}
+
 // Service represents the Vault Transit backend operation service contract.
 type Service interface {
 	Encryptor
 	Decryptor
+	Signer
 }
@@ -19,11 +19,18 @@ package transit
 
 import (
 	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/x509"
 	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"net/url"
 	"path"
+	"strconv"
+	"strings"
 
 	"github.com/hashicorp/vault/api"
 
@@ -97,3 +104,132 @@ func (s *service) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error
 	// Return error.
 	return nil, errors.New("could not decrypt given data")
 }
+
+func (s *service) Sign(ctx context.Context, input []byte, opts SignOptions) (*Signature, error) {
+	// Prepare query
+	signPath := vpath.SanitizePath(path.Join(url.PathEscape(s.mountPath), "sign", url.PathEscape(s.keyName)))
+	data := map[string]interface{}{
+		"input": base64.StdEncoding.EncodeToString(input),
+	}
+	if opts.KeyVersion > 0 {
+		data["key_version"] = opts.KeyVersion
+	}
+	if opts.HashAlgorithm != "" {
+		data["prehashed"] = true
+		data["hash_algorithm"] = opts.HashAlgorithm
+	}
+
+	// Send to Vault.
+	secret, err := s.logical.Write(signPath, data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to sign with %s key: %w", s.keyName, err)
+	}
+	if secret == nil {
+		return nil, errors.New("could not sign given data")
+	}
+
+	// Parse server response.
+	raw, ok := secret.Data["signature"].(string)
+	if !ok || raw == "" {
+		return nil, errors.New("could not sign given data")
+	}
+
+	// Signature is encoded as 'vault:v<version>:<base64>'
+	parts := strings.SplitN(raw, ":", 3)
+	if len(parts) != 3 || parts[0] != "vault" || !strings.HasPrefix(parts[1], "v") {
+		return nil, fmt.Errorf("unexpected signature format")
+	}
+	version, err := strconv.Atoi(strings.TrimPrefix(parts[1], "v"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode signature key version: %w", err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode signature: %w", err)
+	}
+
+	// No error
+	return &Signature{
+		KeyVersion: version,
+		Value:      sig,
+	}, nil
+}
+
+func (s *service) PublicKey(ctx context.Context, version int) (crypto.PublicKey, int, error) {
+	// Prepare query
+	keyPath := vpath.SanitizePath(path.Join(url.PathEscape(s.mountPath), "keys", url.PathEscape(s.keyName)))
+
+	// Send to Vault.
+	secret, err := s.logical.Read(keyPath)
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to read %s key: %w", s.keyName, err)
+	}
+	if secret == nil {
+		return nil, 0, fmt.Errorf("key %s not found", s.keyName)
+	}
+
+	// Resolve version
+	if version <= 0 {
+		version, err = intValue(secret.Data["latest_version"])
+		if err != nil {
+			return nil, 0, fmt.Errorf("unable to decode %s key latest version: %w", s.keyName, err)
+		}
+	}
+
+	// Extract public key
+	keys, ok := secret.Data["keys"].(map[string]interface{})
+	if !ok {
+		return nil, 0, fmt.Errorf("key %s doesn't expose public keys", s.keyName)
+	}
+	entry, ok := keys[strconv.Itoa(version)].(map[string]interface{})
+	if !ok {
+		return nil, 0, fmt.Errorf("version %d of %s key not found", version, s.keyName)
+	}
+	encoded, ok := entry["public_key"].(string)
+	if !ok || encoded == "" {
+		return nil, 0, fmt.Errorf("version %d of %s key has no public key", version, s.keyName)
+	}
+
+	// Decode according to key type
+	keyType, _ := secret.Data["type"].(string)
+	switch keyType {
+	case "ed25519":
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, 0, fmt.Errorf("unable to decode ed25519 public key: %w", err)
+		}
+		if len(raw) != ed25519.PublicKeySize {
+			return nil, 0, fmt.Errorf("invalid ed25519 public key size %d", len(raw))
+		}
+		return ed25519.PublicKey(raw), version, nil
+	case "ecdsa-p256", "ecdsa-p384", "ecdsa-p521":
+		block, _ := pem.Decode([]byte(encoded))
+		if block == nil {
+			return nil, 0, fmt.Errorf("unable to decode %s public key", keyType)
+		}
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, 0, fmt.Errorf("unable to parse %s public key: %w", keyType, err)
+		}
+		return pub, version, nil
+	default:
+	}
+
+	return nil, 0, fmt.Errorf("unsupported signing key type '%s'", keyType)
+}
+
+// -----------------------------------------------------------------------------
+
+func intValue(v interface{}) (int, error) {
+	switch n := v.(type) {
+	case json.Number:
+		i, err := n.Int64()
+		return int(i), err
+	case float64:
+		return int(n), nil
+	case int:
+		return n, nil
+	default:
+	}
+	return 0, fmt.Errorf("unexpected value type %T", v)
+}
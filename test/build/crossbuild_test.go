@@ -0,0 +1,103 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package build_test
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// platformPackages lists packages holding platform dependent code paths with
+// their fallbacks. They must build, with their tests, on all targets.
+var platformPackages = []string{
+	"./pkg/sdk/fsutil",
+	"./pkg/sdk/keyring",
+	"./pkg/sdk/sandbox",
+	"./pkg/sdk/tty",
+}
+
+// binaryTargets lists the targets harp binaries are released for. js/wasm is
+// excluded, memguard requires golang.org/x/sys/unix.
+var binaryTargets = []string{
+	"darwin/amd64",
+	"darwin/arm64",
+	"freebsd/amd64",
+	"linux/amd64",
+	"linux/arm64",
+	"windows/amd64",
+}
+
+func TestCrossCompile_PlatformPackages(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping cross compilation in short mode")
+	}
+
+	targets := append([]string{
+		"linux/386",
+		"linux/riscv64",
+		"netbsd/amd64",
+		"openbsd/amd64",
+		"solaris/amd64",
+		"js/wasm",
+	}, binaryTargets...)
+
+	for _, target := range targets {
+		target := target
+		t.Run(target, func(t *testing.T) {
+			t.Parallel()
+
+			// Vet type-checks package and test files for the target
+			goCommand(t, target, append([]string{"vet"}, platformPackages...)...)
+		})
+	}
+}
+
+func TestCrossCompile_Binary(t *testing.T) {
+	if os.Getenv("HARP_CROSS_BUILD") == "" {
+		t.Skip("HARP_CROSS_BUILD is not set")
+	}
+
+	for _, target := range binaryTargets {
+		target := target
+		t.Run(target, func(t *testing.T) {
+			goCommand(t, target, "build", "-o", os.DevNull, "./cmd/harp")
+		})
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+func goCommand(t *testing.T, target string, args ...string) {
+	t.Helper()
+
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go toolchain is not available")
+	}
+
+	parts := strings.SplitN(target, "/", 2)
+	cmd := exec.Command(goBin, args...)
+	cmd.Dir = "../.."
+	cmd.Env = append(os.Environ(), "GOOS="+parts[0], "GOARCH="+parts[1], "CGO_ENABLED=0")
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go %s failed for %s: %v\n%s", strings.Join(args, " "), target, err, out)
+	}
+}
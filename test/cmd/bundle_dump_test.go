@@ -50,12 +50,12 @@ var _ = Describe("Harp CLI", func() {
 					cmdParams = []string{"bundle", "encrypt"}
 				})
 
-				It("exits with status code 0", func() {
-					Eventually(session).Should(gexec.Exit(0))
+				It("exits with status code 1", func() {
+					Eventually(session).Should(gexec.Exit(1))
 				})
 
 				It("should emit required content", func() {
-					Eventually(session.Err).Should(gbytes.Say(`Error: required flag\(s\) "key" not set`))
+					Eventually(session.Err).Should(gbytes.Say(`Error: 1 invalid flag usage\(s\) found\n  - --key is required`))
 				})
 			})
 
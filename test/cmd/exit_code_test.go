@@ -0,0 +1,167 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gexec"
+
+	"github.com/elastic/harp/pkg/bundle"
+)
+
+const (
+	compliantPath    = "app/production/customer1/ece/v1.0.0/adminconsole/database/usage_credentials"
+	nonCompliantPath = "foo/bar"
+)
+
+var _ = Describe("Harp CLI", func() {
+	Describe("exit codes", func() {
+		var (
+			cmdParams []string
+			workDir   string
+			session   *gexec.Session
+		)
+
+		BeforeEach(func() {
+			workDir = tmpPath("exit-codes")
+			Expect(os.MkdirAll(workDir, 0o777)).To(Succeed())
+		})
+
+		JustBeforeEach(func() {
+			session = startHarp(workDir, cmdParams...)
+		})
+
+		Context("when flags are invalid", func() {
+			BeforeEach(func() {
+				cmdParams = []string{"cso", "validate", "--unknown"}
+			})
+
+			It("exits with status code 1", func() {
+				Eventually(session).Should(gexec.Exit(1))
+			})
+		})
+
+		Context("when validating paths", func() {
+			Context("when all paths are compliant", func() {
+				BeforeEach(func() {
+					cmdParams = []string{"cso", "validate", "--path", compliantPath}
+				})
+
+				It("exits with status code 0", func() {
+					Eventually(session).Should(gexec.Exit(0))
+				})
+			})
+
+			Context("when some paths are not compliant", func() {
+				BeforeEach(func() {
+					cmdParams = []string{"cso", "validate", "--path", compliantPath, "--path", nonCompliantPath}
+				})
+
+				It("exits with status code 2", func() {
+					Eventually(session).Should(gexec.Exit(2))
+				})
+			})
+
+			Context("when no path is compliant", func() {
+				BeforeEach(func() {
+					cmdParams = []string{"cso", "validate", "--path", nonCompliantPath, "--path", "foo"}
+				})
+
+				It("exits with status code 3", func() {
+					Eventually(session).Should(gexec.Exit(3))
+				})
+			})
+
+			Context("when failing fast", func() {
+				BeforeEach(func() {
+					cmdParams = []string{"cso", "validate", "--fail-fast", "--path", nonCompliantPath, "--path", compliantPath}
+				})
+
+				It("exits with status code 3", func() {
+					Eventually(session).Should(gexec.Exit(3))
+				})
+
+				It("skips remaining paths", func() {
+					Eventually(session).Should(gexec.Exit())
+					Expect(string(session.Out.Contents())).NotTo(ContainSubstring(compliantPath))
+				})
+			})
+
+			Context("when failure modes are both set", func() {
+				BeforeEach(func() {
+					cmdParams = []string{"cso", "validate", "--fail-fast", "--keep-going", "--path", compliantPath}
+				})
+
+				It("exits with status code 1", func() {
+					Eventually(session).Should(gexec.Exit(1))
+				})
+			})
+		})
+
+		Context("when linting a bundle", func() {
+			BeforeEach(func() {
+				b, err := bundle.FromMap(map[string]bundle.KV{
+					compliantPath: {"password": "letmein"},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				f, err := os.Create(filepath.Join(workDir, "weak.bundle"))
+				Expect(err).NotTo(HaveOccurred())
+				defer f.Close()
+				Expect(bundle.ToContainerWriter(f, b)).To(Succeed())
+			})
+
+			Context("with policy violations", func() {
+				BeforeEach(func() {
+					cmdParams = []string{"bundle", "lint", "--in", "weak.bundle", "--out", "-"}
+				})
+
+				It("exits with status code 4", func() {
+					Eventually(session).Should(gexec.Exit(4))
+				})
+			})
+
+			Context("with an invalid policy", func() {
+				BeforeEach(func() {
+					Expect(ioutil.WriteFile(filepath.Join(workDir, "policy.yaml"), []byte("kind: Unknown\n"), 0o600)).To(Succeed())
+					cmdParams = []string{"bundle", "lint", "--in", "weak.bundle", "--out", "-", "--policy", "policy.yaml"}
+				})
+
+				It("exits with status code 1", func() {
+					Eventually(session).Should(gexec.Exit(1))
+				})
+			})
+
+			Context("with an unreadable bundle", func() {
+				BeforeEach(func() {
+					Expect(ioutil.WriteFile(filepath.Join(workDir, "broken.bundle"), []byte("broken"), 0o600)).To(Succeed())
+					cmdParams = []string{"bundle", "lint", "--in", "broken.bundle", "--out", "-"}
+				})
+
+				It("exits with status code 3", func() {
+					Eventually(session).Should(gexec.Exit(3))
+				})
+			})
+		})
+	})
+})